@@ -11,13 +11,17 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/spf13/cobra"
 	"pgedge-postgres-mcp/internal/kbchunker"
 	"pgedge-postgres-mcp/internal/kbconfig"
@@ -26,6 +30,7 @@ import (
 	"pgedge-postgres-mcp/internal/kbembed"
 	"pgedge-postgres-mcp/internal/kbsource"
 	"pgedge-postgres-mcp/internal/kbtypes"
+	"pgedge-postgres-mcp/internal/netproxy"
 )
 
 var (
@@ -34,13 +39,17 @@ var (
 	skipUpdates          bool
 	addMissingEmbeddings bool
 	clearEmbeddings      string
+	watch                bool
+	watchInterval        time.Duration
+	concurrency          int
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "kb-builder",
 	Short: "pgEdge Knowledgebase Builder - Build searchable documentation databases",
 	Long: `kb-builder processes documentation from various sources (Git repos, local paths)
-and builds a searchable SQLite database with vector embeddings for use with the
+and builds a searchable SQLite database (or, with backend: postgres, a schema in
+a PostgreSQL database with pgvector) with vector embeddings for use with the
 pgEdge PostgreSQL MCP server.
 
 The tool converts documents from multiple formats (Markdown, HTML, RST, SGML),
@@ -60,6 +69,12 @@ func init() {
 		"Add missing embeddings to existing database instead of rebuilding")
 	rootCmd.Flags().StringVar(&clearEmbeddings, "clear-embeddings", "",
 		"Clear embeddings for specified provider (openai, voyage, or ollama)")
+	rootCmd.Flags().BoolVar(&watch, "watch", false,
+		"Keep running, updating the knowledgebase from the sources every --watch-interval")
+	rootCmd.Flags().DurationVar(&watchInterval, "watch-interval", 15*time.Minute,
+		"Time between updates in --watch mode")
+	rootCmd.Flags().IntVarP(&concurrency, "concurrency", "j", 0,
+		"Number of files to process at once (overrides config file; default: number of CPUs)")
 }
 
 func main() {
@@ -90,10 +105,18 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	// Route embedding API calls and Git fetches through the configured proxy
+	if err := netproxy.Configure(config.Proxy); err != nil {
+		return fmt.Errorf("invalid proxy configuration: %w", err)
+	}
+
 	// Override database path if specified on command line
 	if databasePath != "" {
 		config.DatabasePath = databasePath
 	}
+	if concurrency > 0 {
+		config.Concurrency = concurrency
+	}
 
 	// If --add-missing-embeddings is specified, run that and exit
 	if addMissingEmbeddings {
@@ -105,7 +128,7 @@ func run(cmd *cobra.Command, args []string) error {
 		return runClearEmbeddings(config, clearEmbeddings)
 	}
 
-	fmt.Printf("Output database: %s\n", config.DatabasePath)
+	fmt.Printf("Output database: %s\n", databaseLocation(config))
 	fmt.Printf("Doc source path: %s\n", config.DocSourcePath)
 	fmt.Printf("Number of sources: %d\n", len(config.Sources))
 
@@ -123,52 +146,165 @@ func run(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Enabled embedding providers: %v\n", enabledProviders)
 
 	// Open database early for checksum checking
-	db, err := kbdatabase.Open(config.DatabasePath)
+	db, err := openDatabase(config)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
-	// Fetch all documentation sources
-	fmt.Println("\n=== Fetching Documentation Sources ===")
+	if watch {
+		if watchInterval <= 0 {
+			return fmt.Errorf("--watch-interval must be positive")
+		}
+		return runWatch(config, db, watchInterval)
+	}
+
 	if skipUpdates {
 		fmt.Println("Note: Skipping git pull updates for existing repositories")
 	}
+	result, err := buildKnowledgebase(config, db, skipUpdates)
+	if err != nil {
+		return err
+	}
+	if len(result.embeddingErrors) > 0 {
+		fmt.Println("\nContinuing with partial embeddings. The next run resumes the missing ones.")
+	}
+
+	if err := printStats(db); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ Knowledgebase successfully built: %s\n", databaseLocation(config))
+
+	return nil
+}
+
+// buildResult summarizes an update of the knowledgebase
+type buildResult struct {
+	chunks          int              // Chunks of new and changed files
+	resumed         int              // Chunks whose embeddings an earlier run left pending
+	embeddingErrors map[string]error // Providers that failed, by name
+}
+
+// buildKnowledgebase fetches the sources and stores the chunks of the files
+// that are new or have changed since the last build, then generates their
+// embeddings. Chunks of files that no longer exist are removed.
+//
+// The chunks are stored before they are embedded, marked as pending for each
+// provider, and each batch of embeddings clears its pending marks as it is
+// saved. Embeddings still pending from an interrupted or failed run are
+// generated along with the new ones.
+func buildKnowledgebase(config *kbconfig.Config, db kbdatabase.Store, skipUpdates bool) (buildResult, error) {
+	var result buildResult
+
+	// Fetch all documentation sources
+	fmt.Println("\n=== Fetching Documentation Sources ===")
 	sources, err := kbsource.FetchAll(config, skipUpdates)
 	if err != nil {
-		return fmt.Errorf("failed to fetch sources: %w", err)
+		return result, fmt.Errorf("failed to fetch sources: %w", err)
 	}
 
 	// Process all documents (with incremental processing)
 	fmt.Println("\n=== Processing Documents ===")
-	allChunks, err := processAllDocuments(sources, db)
+	allChunks, err := processAllDocuments(sources, db, config.Concurrency)
 	if err != nil {
-		return fmt.Errorf("failed to process documents: %w", err)
+		return result, fmt.Errorf("failed to process documents: %w", err)
 	}
+	result.chunks = len(allChunks)
 
 	fmt.Printf("\nTotal chunks created/reused: %d\n", len(allChunks))
 
-	// Generate embeddings
-	fmt.Println("\n=== Generating Embeddings ===")
+	// Store the chunks, recording the embeddings they still need
 	embedGen := kbembed.NewEmbeddingGenerator(config, db)
-	embeddingErrors := embedGen.GenerateEmbeddings(allChunks)
+	if len(allChunks) > 0 {
+		fmt.Println("\n=== Storing in Database ===")
+		if err := db.InsertChunks(allChunks); err != nil {
+			return result, fmt.Errorf("failed to insert chunks: %w", err)
+		}
+		if err := embedGen.MarkPending(allChunks); err != nil {
+			return result, err
+		}
+	}
+
+	pending, err := embedGen.PendingChunks()
+	if err != nil {
+		return result, err
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	// Generate embeddings; each batch is saved as it completes
+	fmt.Println("\n=== Generating Embeddings ===")
+	stored := make(map[int]bool, len(allChunks))
+	for _, chunk := range allChunks {
+		stored[chunk.ID] = true
+	}
+	for _, chunk := range pending {
+		if !stored[chunk.ID] {
+			result.resumed++
+		}
+	}
+	if result.resumed > 0 {
+		fmt.Printf("Resuming embeddings of %d chunks left pending by an earlier run\n", result.resumed)
+	}
+	result.embeddingErrors = embedGen.GenerateEmbeddings(pending)
 
 	// Report any embedding failures
-	if len(embeddingErrors) > 0 {
+	if len(result.embeddingErrors) > 0 {
 		fmt.Println("\n⚠️  Warning: Some embedding providers failed:")
-		for provider, err := range embeddingErrors {
+		for provider, err := range result.embeddingErrors {
 			fmt.Printf("  - %s: %v\n", provider, err)
 		}
-		fmt.Println("\nContinuing with partial embeddings. Use --add-missing-embeddings later to complete them.")
 	}
 
-	// Store in database
-	fmt.Println("\n=== Storing in Database ===")
-	if err := db.InsertChunks(allChunks); err != nil {
-		return fmt.Errorf("failed to insert chunks: %w", err)
+	return result, nil
+}
+
+// runWatch updates the knowledgebase from the sources every interval until
+// interrupted. Only files whose checksum changed are chunked and embedded
+// again. Failed updates are reported and retried at the next interval, and
+// embeddings that failed to generate stay pending for the next update.
+func runWatch(config *kbconfig.Config, db kbdatabase.Store, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// A second interrupt aborts the current update
+		stop()
+		fmt.Println("\nStopping (interrupt again to abort an update in progress)")
+	}()
+
+	fmt.Printf("Watching sources, updating every %s\n", interval)
+	if skipUpdates {
+		fmt.Println("Note: Skipping git pull updates for existing repositories; only local sources are updated")
+	}
+
+	for {
+		fmt.Printf("\n=== Update at %s ===\n", time.Now().Format(time.RFC3339))
+		result, err := buildKnowledgebase(config, db, skipUpdates)
+		switch {
+		case err != nil:
+			fmt.Printf("\n⚠️  Update failed, retrying in %s: %v\n", interval, err)
+		case result.chunks == 0 && result.resumed == 0:
+			fmt.Println("\nNo changes")
+		default:
+			if err := printStats(db); err != nil {
+				fmt.Printf("⚠️  %v\n", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("✓ Stopped watching; knowledgebase: %s\n", databaseLocation(config))
+			return nil
+		case <-time.After(interval):
+		}
 	}
+}
 
-	// Print stats
+// printStats prints the knowledgebase's chunk counts per project
+func printStats(db kbdatabase.Store) error {
 	fmt.Println("\n=== Database Statistics ===")
 	stats, err := db.GetStats()
 	if err != nil {
@@ -185,27 +321,50 @@ func run(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  - %s %s: %d chunks\n",
 			project["name"], project["version"], project["chunks"])
 	}
-
-	fmt.Printf("\n✓ Knowledgebase successfully built: %s\n", config.DatabasePath)
-
 	return nil
 }
 
 func runAddMissingEmbeddings(config *kbconfig.Config) error {
-	fmt.Printf("Adding missing embeddings to: %s\n\n", config.DatabasePath)
+	fmt.Printf("Adding missing embeddings to: %s\n\n", databaseLocation(config))
 
 	// Open database
-	db, err := kbdatabase.Open(config.DatabasePath)
+	db, err := openDatabase(config)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
+	missing, err := completeMissingEmbeddings(config, db)
+	if err != nil {
+		return err
+	}
+	if missing == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n✓ Successfully updated embeddings in: %s\n", databaseLocation(config))
+
+	// Print final stats
+	stats, err := db.GetStats()
+	if err != nil {
+		return fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	fmt.Printf("\nTotal chunks: %v\n", stats["total_chunks"])
+
+	return nil
+}
+
+// completeMissingEmbeddings generates the embeddings that stored chunks lack
+// for the enabled providers, including those of databases built before
+// pending embeddings were recorded. It returns the number of chunks that
+// lacked embeddings.
+func completeMissingEmbeddings(config *kbconfig.Config, db kbdatabase.Store) (int, error) {
 	// Get all chunks from database
 	fmt.Println("Loading existing chunks from database...")
 	chunks, err := db.GetAllChunks()
 	if err != nil {
-		return fmt.Errorf("failed to load chunks: %w", err)
+		return 0, fmt.Errorf("failed to load chunks: %w", err)
 	}
 	fmt.Printf("Loaded %d chunks\n", len(chunks))
 
@@ -231,12 +390,13 @@ func runAddMissingEmbeddings(config *kbconfig.Config) error {
 
 	if len(chunksNeedingEmbeddings) == 0 {
 		fmt.Println("\n✓ All chunks already have embeddings for enabled providers")
-		return nil
+		return 0, nil
 	}
 
 	fmt.Printf("\nFound %d chunks with missing embeddings\n", len(chunksNeedingEmbeddings))
 
 	// Generate missing embeddings
+	// Embeddings are saved incrementally during generation, no final update needed
 	fmt.Println("\n=== Generating Missing Embeddings ===")
 	embedGen := kbembed.NewEmbeddingGenerator(config, db)
 	embeddingErrors := embedGen.GenerateEmbeddings(chunksNeedingEmbeddings)
@@ -248,27 +408,14 @@ func runAddMissingEmbeddings(config *kbconfig.Config) error {
 			fmt.Printf("  - %s: %v\n", provider, err)
 		}
 	}
-
-	// Note: Embeddings are saved incrementally during generation, no final update needed
-
-	fmt.Printf("\n✓ Successfully updated embeddings in: %s\n", config.DatabasePath)
-
-	// Print final stats
-	stats, err := db.GetStats()
-	if err != nil {
-		return fmt.Errorf("failed to get stats: %w", err)
-	}
-
-	fmt.Printf("\nTotal chunks: %v\n", stats["total_chunks"])
-
-	return nil
+	return len(chunksNeedingEmbeddings), nil
 }
 
 func runClearEmbeddings(config *kbconfig.Config, provider string) error {
-	fmt.Printf("Clearing %s embeddings from: %s\n\n", provider, config.DatabasePath)
+	fmt.Printf("Clearing %s embeddings from: %s\n\n", provider, databaseLocation(config))
 
 	// Open database
-	db, err := kbdatabase.Open(config.DatabasePath)
+	db, err := openDatabase(config)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -285,14 +432,36 @@ func runClearEmbeddings(config *kbconfig.Config, provider string) error {
 	return nil
 }
 
-func processAllDocuments(sources []kbsource.SourceInfo, db *kbdatabase.Database) ([]*kbtypes.Chunk, error) {
+// openDatabase opens the knowledgebase in the configured backend
+func openDatabase(config *kbconfig.Config) (kbdatabase.Store, error) {
+	if config.Backend == kbconfig.BackendPostgres {
+		return kbdatabase.OpenPostgres(config.ConnectionString, config.Schema)
+	}
+	return kbdatabase.Open(config.DatabasePath)
+}
+
+// databaseLocation describes where the knowledgebase is stored, without
+// the credentials of a connection string
+func databaseLocation(config *kbconfig.Config) string {
+	if config.Backend != kbconfig.BackendPostgres {
+		return config.DatabasePath
+	}
+	connConfig, err := pgx.ParseConfig(config.ConnectionString)
+	if err != nil {
+		return fmt.Sprintf("PostgreSQL schema %s", config.Schema)
+	}
+	return fmt.Sprintf("PostgreSQL %s@%s:%d/%s, schema %s", connConfig.User, connConfig.Host,
+		connConfig.Port, connConfig.Database, config.Schema)
+}
+
+func processAllDocuments(sources []kbsource.SourceInfo, db kbdatabase.Store, concurrency int) ([]*kbtypes.Chunk, error) {
 	var allChunks []*kbtypes.Chunk
 
 	for i := range sources {
 		source := &sources[i]
 		fmt.Printf("\nProcessing %s %s...\n", source.Source.ProjectName, source.Source.ProjectVersion)
 
-		chunks, err := processSource(*source, db)
+		chunks, err := processSource(*source, db, concurrency)
 		if err != nil {
 			return nil, fmt.Errorf("failed to process source %s: %w", source.Source.ProjectName, err)
 		}
@@ -304,7 +473,10 @@ func processAllDocuments(sources []kbsource.SourceInfo, db *kbdatabase.Database)
 	return allChunks, nil
 }
 
-func processSource(source kbsource.SourceInfo, db *kbdatabase.Database) ([]*kbtypes.Chunk, error) {
+// processSource converts and chunks the supported files of a source, with
+// up to concurrency files processed at once. Progress is printed in file
+// order.
+func processSource(source kbsource.SourceInfo, db kbdatabase.Store, concurrency int) ([]*kbtypes.Chunk, error) {
 	var chunks []*kbtypes.Chunk
 	var validChecksums []string
 
@@ -327,46 +499,43 @@ func processSource(source kbsource.SourceInfo, db *kbdatabase.Database) ([]*kbty
 	fmt.Printf("  Found %d supported files\n", len(supportedFiles))
 
 	// Second pass: process files with progress
-	processedCount := 0
-	for _, path := range supportedFiles {
-		processedCount++
-
+	process := func(path string) fileResult {
+		// Process the file (with checksum-based incremental processing)
+		startTime := time.Now()
+		result := processFile(path, source, db)
+		result.elapsed = time.Since(startTime)
+		return result
+	}
+	processFiles(supportedFiles, concurrency, process, func(i int, result fileResult) {
 		// Show progress every file, but with relative path for readability
-		relPath, err := filepath.Rel(source.BasePath, path)
+		relPath, err := filepath.Rel(source.BasePath, supportedFiles[i])
 		if err != nil || relPath == "" {
-			relPath = filepath.Base(path)
+			relPath = filepath.Base(supportedFiles[i])
 		}
+		fmt.Printf("  [%d/%d] Processing: %s", i+1, len(supportedFiles), relPath)
 
-		startTime := time.Now()
-		fmt.Printf("  [%d/%d] Processing: %s", processedCount, len(supportedFiles), relPath)
-
-		// Process the file (with checksum-based incremental processing)
-		fileChunks, skipped, checksum, err := processFile(path, source, db)
-		elapsed := time.Since(startTime)
-
-		if err != nil {
-			fmt.Printf(" - ERROR (%.2fs): %v\n", elapsed.Seconds(), err)
-			continue // Continue processing other files
+		if result.err != nil {
+			fmt.Printf(" - ERROR (%.2fs): %v\n", result.elapsed.Seconds(), result.err)
+			return // Continue processing other files
 		}
 
 		// Track this checksum as valid for cleanup
-		if checksum != "" {
-			validChecksums = append(validChecksums, checksum)
+		if result.checksum != "" {
+			validChecksums = append(validChecksums, result.checksum)
 		}
 
-		if skipped {
+		if result.skipped {
 			fmt.Printf(" - skipped (unchanged)\n")
-		} else if len(fileChunks) > 0 {
-			fmt.Printf(" - %d chunks (%.2fs)\n", len(fileChunks), elapsed.Seconds())
 		} else {
-			fmt.Printf(" - 0 chunks (%.2fs)\n", elapsed.Seconds())
+			fmt.Printf(" - %d chunks (%.2fs)\n", len(result.chunks), result.elapsed.Seconds())
+		}
+		if result.timing != "" {
+			fmt.Printf("           [Slow file - %s]\n", result.timing)
 		}
 
 		// Add chunks to the collection
-		if len(fileChunks) > 0 {
-			chunks = append(chunks, fileChunks...)
-		}
-	}
+		chunks = append(chunks, result.chunks...)
+	})
 
 	// Cleanup stale chunks from previous runs (files that no longer exist)
 	if len(validChecksums) > 0 {
@@ -379,13 +548,25 @@ func processSource(source kbsource.SourceInfo, db *kbdatabase.Database) ([]*kbty
 	return chunks, nil
 }
 
-func processFile(filePath string, source kbsource.SourceInfo, db *kbdatabase.Database) ([]*kbtypes.Chunk, bool, string, error) {
+// fileResult is the outcome of processing one file
+type fileResult struct {
+	chunks   []*kbtypes.Chunk
+	skipped  bool   // The file is unchanged since it was last processed
+	checksum string // Checksum of the file's content, if it was read
+	timing   string // Time taken by each step, for slow files
+	elapsed  time.Duration
+	err      error
+}
+
+// processFile converts and chunks a file, or reuses the chunks of a file
+// with the same content in another version. It is called concurrently.
+func processFile(filePath string, source kbsource.SourceInfo, db kbdatabase.Store) fileResult {
 	stepStart := time.Now()
 
 	// Read file
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, false, "", fmt.Errorf("failed to read file: %w", err)
+		return fileResult{err: fmt.Errorf("failed to read file: %w", err)}
 	}
 	readTime := time.Since(stepStart)
 
@@ -396,19 +577,19 @@ func processFile(filePath string, source kbsource.SourceInfo, db *kbdatabase.Dat
 	// Check if this file needs processing for this project/version
 	needsProcessing, err := db.FileNeedsProcessing(checksum, source.Source.ProjectName, source.Source.ProjectVersion)
 	if err != nil {
-		return nil, false, checksum, fmt.Errorf("failed to check if file needs processing: %w", err)
+		return fileResult{checksum: checksum, err: fmt.Errorf("failed to check if file needs processing: %w", err)}
 	}
 
 	// If file doesn't need processing (already processed for this project/version), skip it
 	if !needsProcessing {
 		// Return empty chunks - they're already in the database, no need to re-insert
-		return nil, true, checksum, nil
+		return fileResult{skipped: true, checksum: checksum}
 	}
 
 	// Check if this file exists in another version (deduplication)
 	existingChunks, err := db.GetChunksForChecksum(checksum)
 	if err != nil {
-		return nil, false, checksum, fmt.Errorf("failed to check for existing chunks: %w", err)
+		return fileResult{checksum: checksum, err: fmt.Errorf("failed to check for existing chunks: %w", err)}
 	}
 
 	// If chunks exist for this checksum in another version, clone them with new project/version
@@ -429,7 +610,7 @@ func processFile(filePath string, source kbsource.SourceInfo, db *kbdatabase.Dat
 			}
 			chunks = append(chunks, chunk)
 		}
-		return chunks, false, checksum, nil
+		return fileResult{chunks: chunks, checksum: checksum}
 	}
 
 	// File needs processing - process it from scratch
@@ -442,8 +623,8 @@ func processFile(filePath string, source kbsource.SourceInfo, db *kbdatabase.Dat
 	stepStart = time.Now()
 	markdown, title, err := kbconverter.Convert(content, docType)
 	if err != nil {
-		return nil, false, checksum, fmt.Errorf("failed to convert document (read: %.2fs, detect: %.2fs, convert: %.2fs): %w",
-			readTime.Seconds(), detectTime.Seconds(), time.Since(stepStart).Seconds(), err)
+		return fileResult{checksum: checksum, err: fmt.Errorf("failed to convert document (read: %.2fs, detect: %.2fs, convert: %.2fs): %w",
+			readTime.Seconds(), detectTime.Seconds(), time.Since(stepStart).Seconds(), err)}
 	}
 	convertTime := time.Since(stepStart)
 
@@ -462,8 +643,8 @@ func processFile(filePath string, source kbsource.SourceInfo, db *kbdatabase.Dat
 	stepStart = time.Now()
 	chunks, err := kbchunker.ChunkDocument(doc)
 	if err != nil {
-		return nil, false, checksum, fmt.Errorf("failed to chunk document (read: %.2fs, detect: %.2fs, convert: %.2fs, chunk: %.2fs): %w",
-			readTime.Seconds(), detectTime.Seconds(), convertTime.Seconds(), time.Since(stepStart).Seconds(), err)
+		return fileResult{checksum: checksum, err: fmt.Errorf("failed to chunk document (read: %.2fs, detect: %.2fs, convert: %.2fs, chunk: %.2fs): %w",
+			readTime.Seconds(), detectTime.Seconds(), convertTime.Seconds(), time.Since(stepStart).Seconds(), err)}
 	}
 	chunkTime := time.Since(stepStart)
 
@@ -472,12 +653,13 @@ func processFile(filePath string, source kbsource.SourceInfo, db *kbdatabase.Dat
 		chunk.SourceFileChecksum = checksum
 	}
 
-	// Log timing breakdown if file took more than 1 second
+	// Report the timing breakdown if the file took more than 1 second
+	timing := ""
 	totalTime := readTime + detectTime + convertTime + chunkTime
 	if totalTime.Seconds() > 1.0 {
-		fmt.Printf("           [Slow file - read: %.2fs, detect: %.2fs, convert: %.2fs, chunk: %.2fs]\n",
+		timing = fmt.Sprintf("read: %.2fs, detect: %.2fs, convert: %.2fs, chunk: %.2fs",
 			readTime.Seconds(), detectTime.Seconds(), convertTime.Seconds(), chunkTime.Seconds())
 	}
 
-	return chunks, false, checksum, nil
+	return fileResult{chunks: chunks, checksum: checksum, timing: timing}
 }
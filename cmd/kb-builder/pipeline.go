@@ -0,0 +1,67 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import "sync"
+
+// processFiles runs process on each file with up to workers files in
+// progress at once, and calls report with each result in file order, as
+// soon as it and the results of all earlier files are available. report is
+// called from the calling goroutine only.
+func processFiles(files []string, workers int, process func(path string) fileResult, report func(i int, result fileResult)) {
+	if workers < 1 {
+		workers = 1
+	}
+	workers = min(workers, len(files))
+
+	type done struct {
+		index  int
+		result fileResult
+	}
+	jobs := make(chan int)
+	results := make(chan done, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- done{i, process(files[i])}
+			}
+		}()
+	}
+	go func() {
+		for i := range files {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	// Hold results that finish before earlier files until they can be
+	// reported in order
+	pending := make(map[int]fileResult)
+	next := 0
+	for d := range results {
+		pending[d.index] = d.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			report(next, result)
+			next++
+		}
+	}
+}
@@ -16,11 +16,27 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"golang.org/x/term"
+
 	"pgedge-postgres-mcp/internal/chat"
+	"pgedge-postgres-mcp/internal/netproxy"
 )
 
+// promptList collects the prompts given with repeated -e flags
+type promptList []string
+
+func (p *promptList) String() string {
+	return strings.Join(*p, "; ")
+}
+
+func (p *promptList) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
 func main() {
 	// Command line flags
 	configFile := flag.String("config", "", "Path to configuration file")
@@ -33,12 +49,18 @@ func main() {
 	mcpToken := flag.String("mcp-token", "", "MCP server authentication token (for token mode)")
 	mcpUsername := flag.String("mcp-username", "", "MCP server username (for user mode)")
 	mcpPassword := flag.String("mcp-password", "", "MCP server password (for user mode)")
-	llmProvider := flag.String("llm-provider", "", "LLM provider: anthropic, openai, or ollama (default: anthropic)")
+	llmProvider := flag.String("llm-provider", "", "LLM provider: anthropic, openai, azure, bedrock, or ollama (default: anthropic)")
 	llmModel := flag.String("llm-model", "", "LLM model to use")
 	anthropicAPIKey := flag.String("anthropic-api-key", "", "API key for Anthropic")
 	openaiAPIKey := flag.String("openai-api-key", "", "API key for OpenAI")
 	ollamaURL := flag.String("ollama-url", "", "Ollama server URL (default: http://localhost:11434)")
 	noColor := flag.Bool("no-color", false, "Disable colored output")
+	approveWrites := flag.Bool("approve-writes", false, "Ask before running tool calls that modify the database")
+	var executePrompts promptList
+	flag.Var(&executePrompts, "e", "Run a prompt non-interactively and exit (may be repeated)")
+	flag.Var(&executePrompts, "execute", "Same as -e")
+	jsonOutput := flag.Bool("json", false, "Print non-interactive results as JSON, including the tool trace")
+	database := flag.String("database", "", "Database to use for non-interactive prompts")
 
 	flag.Parse()
 
@@ -104,6 +126,9 @@ func main() {
 	if *noColor {
 		cfg.UI.NoColor = true
 	}
+	if *approveWrites {
+		cfg.MCP.ApproveWrites = true
+	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -111,6 +136,29 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Route LLM provider calls through the configured proxy
+	if err := netproxy.Configure(cfg.Proxy); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Prompts given with -e, or piped on stdin, are run non-interactively
+	var prompts []string
+	nonInteractive := len(executePrompts) > 0 || !term.IsTerminal(int(os.Stdin.Fd()))
+	if len(executePrompts) > 0 {
+		prompts = executePrompts
+	} else if nonInteractive {
+		prompts, err = chat.ReadScript(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading prompts from stdin: %v\n", err)
+			os.Exit(chat.ExitUsage)
+		}
+	}
+	if !nonInteractive && (*jsonOutput || *database != "") {
+		fmt.Fprintf(os.Stderr, "Configuration error: -json and -database require -e or prompts on stdin\n")
+		os.Exit(chat.ExitUsage)
+	}
+
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -120,7 +168,11 @@ func main() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		fmt.Println("\n\nReceived interrupt signal. Shutting down...")
+		if nonInteractive {
+			fmt.Fprintln(os.Stderr, "Received interrupt signal. Shutting down...")
+		} else {
+			fmt.Println("\n\nReceived interrupt signal. Shutting down...")
+		}
 		cancel()
 	}()
 
@@ -138,6 +190,22 @@ func main() {
 		}
 	}()
 
+	if nonInteractive {
+		err := client.RunScript(ctx, prompts, chat.ScriptOptions{
+			JSON:     *jsonOutput,
+			Database: *database,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if err := client.SavePreferences(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save preferences: %v\n", err)
+		}
+		// os.Exit skips deferred calls, so cancel explicitly
+		cancel()
+		os.Exit(chat.ExitCode(err))
+	}
+
 	if err := client.Run(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error running chat client: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,36 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"path/filepath"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// acmeConfig returns the automatic certificate settings, keeping the
+// account key and certificates in the acme directory under the data
+// directory unless another cache directory is configured
+func acmeConfig(cfg *config.Config, execPath string) *mcp.ACMEConfig {
+	acme := cfg.HTTP.TLS.ACME
+	cacheDir := acme.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(conversationDataDir(cfg, execPath), "acme")
+	}
+	return &mcp.ACMEConfig{
+		Domains:           acme.Domains,
+		CacheDir:          cacheDir,
+		DirectoryURL:      acme.DirectoryURL,
+		Email:             acme.Email,
+		HTTPChallengeAddr: acme.HTTPChallengeAddress,
+	}
+}
@@ -0,0 +1,57 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/crypto"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// sessionAffinityConfig builds the session affinity settings, defaulting
+// the replica ID to the host name. The cookie is signed with the server
+// secret, which is generated if it doesn't exist; every replica must use
+// the same secret file.
+func sessionAffinityConfig(cfg *config.Config, execPath string) (*mcp.AffinityConfig, error) {
+	replicaID := cfg.HTTP.SessionAffinity.ReplicaID
+	if replicaID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the replica ID: %w; set http.session_affinity.replica_id", err)
+		}
+		replicaID, _, _ = strings.Cut(hostname, ".")
+	}
+	if err := mcp.ValidateReplicaID(replicaID); err != nil {
+		return nil, fmt.Errorf("%w; set http.session_affinity.replica_id", err)
+	}
+
+	secretPath := cfg.SecretFile
+	if secretPath == "" {
+		secretPath = config.GetDefaultSecretPath(execPath)
+	}
+	secret, generated, err := crypto.LoadOrGenerateKey(secretPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server secret: %w", err)
+	}
+	if generated {
+		fmt.Fprintf(os.Stderr, "Generated server secret: %s (copy it to every replica)\n", secretPath)
+	}
+
+	return &mcp.AffinityConfig{
+		ReplicaID:  replicaID,
+		CookieName: cfg.HTTP.SessionAffinity.CookieName,
+		Secret:     secret,
+	}, nil
+}
@@ -0,0 +1,31 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"time"
+
+	"pgedge-postgres-mcp/internal/autoanalyze"
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// newAutoAnalyzer creates the background ANALYZE queue from the
+// configuration, which has already been validated
+func newAutoAnalyzer(cfg config.AutoAnalyzeConfig) *autoanalyze.Analyzer {
+	window, _ := autoanalyze.ParseWindow(cfg.Window) //nolint:errcheck // validated when the configuration was loaded
+	return autoanalyze.New(autoanalyze.Config{
+		MinRatio:      float64(cfg.EstimateRatio),
+		MinRows:       float64(cfg.MinRows),
+		TableInterval: time.Duration(cfg.TableIntervalHours) * time.Hour,
+		MaxPerHour:    cfg.MaxPerHour,
+		Window:        window,
+	})
+}
@@ -0,0 +1,196 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/conversations"
+	"pgedge-postgres-mcp/internal/crypto"
+)
+
+// conversationDataDir returns the configured data directory, or a directory
+// next to the executable
+func conversationDataDir(cfg *config.Config, execPath string) string {
+	if cfg.DataDir != "" {
+		return cfg.DataDir
+	}
+	return config.GetDefaultDataDir(execPath)
+}
+
+// retentionPolicy converts conversation configuration to a purge policy
+func retentionPolicy(c config.ConversationsConfig) conversations.RetentionPolicy {
+	policy := conversations.RetentionPolicy{
+		MaxAge:     time.Duration(c.MaxAgeDays) * 24 * time.Hour,
+		MaxPerUser: c.MaxPerUser,
+	}
+	if c.UsesPostgres() {
+		policy.DeletedMaxAge = time.Duration(c.DeletedRetentionDays) * 24 * time.Hour
+	}
+	return policy
+}
+
+// openPostgresConversations opens the conversation store in the configured
+// PostgreSQL database. The store uses its own connection, as tool
+// connections are read-only.
+func openPostgresConversations(ctx context.Context, cfg *config.Config) (*conversations.PostgresStore, *config.NamedDatabaseConfig, error) {
+	var db *config.NamedDatabaseConfig
+	if cfg.Conversations.Database != "" {
+		db = cfg.GetDatabaseByName(cfg.Conversations.Database)
+	} else if len(cfg.Databases) > 0 {
+		db = &cfg.Databases[0]
+	}
+	if db == nil {
+		return nil, nil, fmt.Errorf("no database configured for the conversation store")
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(db.BuildConnectionString())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid connection settings for database %q: %w", db.Name, err)
+	}
+	poolConfig.MaxConns = 4
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = "pgedge-postgres-mcp conversations"
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database %q: %w", db.Name, err)
+	}
+
+	store, err := conversations.NewPostgresStore(ctx, pool)
+	if err != nil {
+		pool.Close()
+		return nil, nil, fmt.Errorf("failed to open conversation store in database %q: %w", db.Name, err)
+	}
+	return store, db, nil
+}
+
+// configureConversationEncryption loads the server secret and sets the
+// store's encryption mode, generating the secret if encryption is enabled
+// and none exists yet
+func configureConversationEncryption(store conversations.ConversationStore, cfg *config.Config, execPath string) error {
+	secretPath := cfg.SecretFile
+	if secretPath == "" {
+		secretPath = config.GetDefaultSecretPath(execPath)
+	}
+
+	mode := conversations.EncryptionNone
+	var secret *crypto.EncryptionKey
+	if cfg.Conversations.EncryptionEnabled() {
+		mode = conversations.EncryptionServerKey
+		if cfg.Conversations.PerUserKeys {
+			mode = conversations.EncryptionPerUserKey
+		}
+
+		var generated bool
+		var err error
+		secret, generated, err = crypto.LoadOrGenerateKey(secretPath)
+		if err != nil {
+			return fmt.Errorf("failed to load encryption secret: %w", err)
+		}
+		if generated {
+			fmt.Fprintf(os.Stderr, "Generated encryption secret: %s\n", secretPath)
+		}
+	} else {
+		// Keep previously encrypted conversations readable so they can be
+		// decrypted
+		var err error
+		secret, err = crypto.LoadKeyFromFile(secretPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to load encryption secret: %w", err)
+		}
+	}
+
+	migrated, err := store.SetEncryption(secret, mode)
+	if err != nil {
+		return fmt.Errorf("failed to configure conversation encryption: %w", err)
+	}
+	if migrated > 0 {
+		fmt.Fprintf(os.Stderr, "Re-encoded %d stored conversation(s) for the current encryption settings\n", migrated)
+	}
+
+	switch mode {
+	case conversations.EncryptionPerUserKey:
+		fmt.Fprintf(os.Stderr, "Conversation encryption: ENABLED (per-user keys)\n")
+	case conversations.EncryptionServerKey:
+		fmt.Fprintf(os.Stderr, "Conversation encryption: ENABLED\n")
+	default:
+		fmt.Fprintf(os.Stderr, "Conversation encryption: DISABLED\n")
+	}
+	return nil
+}
+
+// purgeConversationsCommand handles the purge-conversations command. With a
+// username it permanently deletes all of that user's conversations,
+// otherwise it applies the configured retention policy.
+func purgeConversationsCommand(cfg *config.Config, execPath, username string) error {
+	policy := retentionPolicy(cfg.Conversations)
+	if username == "" && !policy.IsEnabled() {
+		return fmt.Errorf("no retention policy configured: set conversations.max_age_days or conversations.max_per_user, or pass -username")
+	}
+
+	var store conversations.ConversationStore
+	if cfg.Conversations.UsesPostgres() {
+		pgStore, _, err := openPostgresConversations(context.Background(), cfg)
+		if err != nil {
+			return err
+		}
+		store = pgStore
+	} else {
+		dataDir := conversationDataDir(cfg, execPath)
+		if _, err := os.Stat(filepath.Join(dataDir, "conversations.db")); os.IsNotExist(err) {
+			return fmt.Errorf("no conversation store found in %s", dataDir)
+		}
+
+		sqliteStore, err := conversations.NewStore(dataDir)
+		if err != nil {
+			return fmt.Errorf("failed to open conversation store: %w", err)
+		}
+		store = sqliteStore
+	}
+	defer store.Close()
+
+	if username == "" {
+		purged, err := store.Purge(policy, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Purged %d conversation(s) outside the retention policy\n", purged)
+		return nil
+	}
+
+	// Confirm deletion
+	fmt.Printf("Are you sure you want to delete all conversations for user '%s'? (y/N): ", username)
+	reader := bufio.NewReader(os.Stdin)
+	if input, err := reader.ReadString('\n'); err == nil {
+		response := strings.TrimSpace(strings.ToLower(input))
+		if response != "y" && response != "yes" {
+			fmt.Println("Purge canceled")
+			return nil
+		}
+	}
+
+	purged, err := store.PurgeUser(username)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d conversation(s) for user '%s'\n", purged, username)
+	return nil
+}
@@ -0,0 +1,74 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"path/filepath"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/embedding"
+	"pgedge-postgres-mcp/internal/metrics"
+)
+
+// embeddingCachePath returns the configured cache file, or
+// embedding_cache.db in the data directory
+func embeddingCachePath(cfg *config.Config, execPath string) string {
+	if cfg.Embedding.Cache.Path != "" {
+		return cfg.Embedding.Cache.Path
+	}
+	return filepath.Join(conversationDataDir(cfg, execPath), "embedding_cache.db")
+}
+
+// newEmbeddingCache creates the embedding cache from configuration, or
+// returns nil if caching is disabled
+func newEmbeddingCache(cfg *config.Config, execPath string) (*embedding.Cache, error) {
+	if !cfg.Embedding.Cache.IsEnabled() {
+		return nil, nil
+	}
+	path := ""
+	if cfg.Embedding.Cache.Persist {
+		path = embeddingCachePath(cfg, execPath)
+	}
+	return embedding.NewCache(cfg.Embedding.Cache.MaxEntries, path)
+}
+
+// embeddingCacheMetrics reports the cache's counters
+func embeddingCacheMetrics(cache *embedding.Cache) metrics.Collector {
+	return func() []metrics.Sample {
+		stats := cache.Stats()
+		return []metrics.Sample{
+			{
+				Name:  "pgedge_mcp_embedding_cache_hits_total",
+				Help:  "Embedding requests answered from the cache.",
+				Type:  metrics.Counter,
+				Value: float64(stats.Hits),
+			},
+			{
+				Name:  "pgedge_mcp_embedding_cache_misses_total",
+				Help:  "Embedding requests sent to the embedding provider.",
+				Type:  metrics.Counter,
+				Value: float64(stats.Misses),
+			},
+			{
+				Name:  "pgedge_mcp_embedding_cache_evictions_total",
+				Help:  "Embeddings removed from memory to stay within max_entries.",
+				Type:  metrics.Counter,
+				Value: float64(stats.Evictions),
+			},
+			{
+				Name:  "pgedge_mcp_embedding_cache_entries",
+				Help:  "Embeddings held in memory.",
+				Type:  metrics.Gauge,
+				Value: float64(stats.Entries),
+			},
+		}
+	}
+}
@@ -0,0 +1,169 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/healthprobe"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/notify"
+)
+
+// healthProbeComponent is the name of a probe's health check component
+func healthProbeComponent(name string) string {
+	return "probe:" + name
+}
+
+// healthProbeState maps a probe result to a health check component
+func healthProbeState(result healthprobe.Result) *mcp.HealthComponent {
+	component := &mcp.HealthComponent{Reason: result.Message}
+	switch result.Status {
+	case healthprobe.StatusOK:
+		component.Status = mcp.HealthStatusOK
+	case healthprobe.StatusWarn:
+		component.Status = mcp.HealthStatusWarning
+	case healthprobe.StatusCrit:
+		component.Status = mcp.HealthStatusCritical
+	default:
+		component.Status = mcp.HealthStatusUnavailable
+	}
+	return component
+}
+
+// startHealthProbes starts the configured health probes, which report their
+// results as health check components and announce each change of status. A
+// probe that reports crit also fails the readiness probe.
+// Probes use their own connections, one small pool per database. The
+// returned function stops the probes and closes the connections.
+func startHealthProbes(ctx context.Context, cfg *config.Config, server *mcp.Server) (func(), error) {
+	pools := make(map[string]*pgxpool.Pool)
+	closePools := func() {
+		for _, pool := range pools {
+			pool.Close()
+		}
+	}
+
+	probes := make([]healthprobe.Probe, 0, len(cfg.HealthProbes))
+	for _, pc := range cfg.HealthProbes {
+		var db *config.NamedDatabaseConfig
+		if pc.Database != "" {
+			db = cfg.GetDatabaseByName(pc.Database)
+		} else if len(cfg.Databases) > 0 {
+			db = &cfg.Databases[0]
+		}
+		if db == nil {
+			closePools()
+			return nil, fmt.Errorf("no database configured for health probe %q", pc.Name)
+		}
+
+		pool, ok := pools[db.Name]
+		if !ok {
+			poolConfig, err := pgxpool.ParseConfig(db.BuildConnectionString())
+			if err != nil {
+				closePools()
+				return nil, fmt.Errorf("invalid connection settings for database %q: %w", db.Name, err)
+			}
+			poolConfig.MaxConns = 2
+			poolConfig.ConnConfig.RuntimeParams["application_name"] = "pgedge-postgres-mcp health probes"
+			pool, err = pgxpool.NewWithConfig(ctx, poolConfig)
+			if err != nil {
+				closePools()
+				return nil, fmt.Errorf("failed to connect to database %q: %w", db.Name, err)
+			}
+			pools[db.Name] = pool
+		}
+
+		probes = append(probes, healthprobe.Probe{
+			Name:     pc.Name,
+			Database: db.Name,
+			SQL:      pc.SQL,
+			Interval: time.Duration(pc.IntervalSeconds) * time.Second,
+			Timeout:  time.Duration(pc.TimeoutSeconds) * time.Second,
+			Pool:     pool,
+		})
+	}
+
+	runner := healthprobe.New(healthprobe.Config{
+		Probes: probes,
+		OnChange: func(previous *healthprobe.Result, current healthprobe.Result) {
+			server.SetHealthComponent(healthProbeComponent(current.Probe), healthProbeState(current))
+			reportHealthProbeChange(previous, current)
+		},
+	})
+	healthprobe.SetShared(runner)
+	server.SetReadinessCheck("health_probes", healthProbeReadiness)
+
+	probeCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer closePools()
+		runner.Run(probeCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Health probes: %d in %d databases\n", len(probes), len(pools))
+	return func() {
+		stop()
+		<-done
+		server.SetReadinessCheck("health_probes", nil)
+		healthprobe.SetShared(nil)
+	}, nil
+}
+
+// healthProbeSeverities map probe statuses to notification severities
+var healthProbeSeverities = map[healthprobe.Status]string{
+	healthprobe.StatusOK:    notify.SeverityInfo,
+	healthprobe.StatusWarn:  notify.SeverityWarning,
+	healthprobe.StatusCrit:  notify.SeverityCritical,
+	healthprobe.StatusError: notify.SeverityWarning,
+}
+
+// reportHealthProbeChange announces a probe's change of status, and sends a
+// notification of it; a probe's first result is only announced when it is
+// not ok
+func reportHealthProbeChange(previous *healthprobe.Result, current healthprobe.Result) {
+	from := "none"
+	if previous != nil {
+		from = string(previous.Status)
+	} else if current.Status == healthprobe.StatusOK {
+		return
+	}
+	logging.Warn("health_probe_state_changed",
+		"probe", current.Probe,
+		"database", current.Database,
+		"from", from,
+		"to", string(current.Status),
+		"message", current.Message,
+	)
+	fmt.Fprintf(os.Stderr, "Health probe %s (database %s): %s -> %s: %s\n",
+		current.Probe, current.Database, from, current.Status, current.Message)
+
+	title := fmt.Sprintf("Health probe %s is %s", current.Probe, current.Status)
+	if current.Status == healthprobe.StatusOK {
+		title = fmt.Sprintf("Health probe %s recovered", current.Probe)
+	}
+	notify.Send(notify.Event{
+		Category: notify.CategoryHealth,
+		Severity: healthProbeSeverities[current.Status],
+		Title:    title,
+		Message:  current.Message,
+		Fields:   map[string]string{"probe": current.Probe, "database": current.Database, "previous": from},
+		Key:      fmt.Sprintf("probe:%s:%s", current.Probe, current.Status),
+	})
+}
@@ -0,0 +1,84 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/tools"
+)
+
+// knowledgebaseCapabilityName is the experimental capability describing the
+// knowledgebase's availability, also used as its health check component
+const knowledgebaseCapabilityName = "pgedge/knowledgebase"
+
+// reportKnowledgebaseStatus publishes whether the knowledgebase can be
+// searched in the initialize capabilities, the HTTP health check and the
+// readiness probe
+func reportKnowledgebaseStatus(server *mcp.Server, provider *tools.ContextAwareProvider) {
+	inUse, kbErr := provider.KnowledgebaseStatus()
+	if !inUse {
+		server.SetExperimentalCapability(knowledgebaseCapabilityName, nil)
+		server.SetHealthComponent("knowledgebase", nil)
+		server.SetReadinessCheck("knowledgebase", nil)
+		return
+	}
+	server.SetReadinessCheck("knowledgebase", func(context.Context) error {
+		_, err := provider.KnowledgebaseStatus()
+		return err
+	})
+
+	capability := map[string]interface{}{"available": kbErr == nil}
+	component := &mcp.HealthComponent{Status: mcp.HealthStatusOK}
+	if kbErr == nil {
+		embeddingProvider, embeddingModel := provider.KnowledgebaseEmbedding()
+		capability["embedding_provider"] = embeddingProvider
+		if embeddingModel != "" {
+			capability["embedding_model"] = embeddingModel
+		}
+	} else {
+		capability["reason"] = kbErr.Error()
+		component = &mcp.HealthComponent{Status: mcp.HealthStatusUnavailable, Reason: kbErr.Error()}
+	}
+	server.SetExperimentalCapability(knowledgebaseCapabilityName, capability)
+	server.SetHealthComponent("knowledgebase", component)
+}
+
+// monitorKnowledgebase checks an unavailable knowledgebase again every
+// knowledgebase.retry_interval_seconds (0 = never) until ctx is cancelled,
+// so a knowledgebase that is restored or finishes building is picked up
+// without a restart
+func monitorKnowledgebase(ctx context.Context, server *mcp.Server, provider *tools.ContextAwareProvider) {
+	for {
+		interval := provider.KnowledgebaseRetryInterval()
+		if interval <= 0 {
+			// Retries may be enabled by a reload
+			interval = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if provider.KnowledgebaseRetryInterval() <= 0 {
+			continue
+		}
+		if _, kbErr := provider.KnowledgebaseStatus(); kbErr == nil {
+			continue
+		}
+		provider.CheckKnowledgebase()
+		reportKnowledgebaseStatus(server, provider)
+	}
+}
@@ -17,24 +17,31 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"os/signal"
-	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
 	"pgedge-postgres-mcp/internal/api"
 	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/autoanalyze"
 	"pgedge-postgres-mcp/internal/compactor"
 	"pgedge-postgres-mcp/internal/config"
 	"pgedge-postgres-mcp/internal/conversations"
 	"pgedge-postgres-mcp/internal/database"
 	"pgedge-postgres-mcp/internal/definitions"
+	"pgedge-postgres-mcp/internal/embedding"
+	"pgedge-postgres-mcp/internal/export"
 	"pgedge-postgres-mcp/internal/llmproxy"
+	"pgedge-postgres-mcp/internal/logging"
 	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/metrics"
+	"pgedge-postgres-mcp/internal/netproxy"
 	"pgedge-postgres-mcp/internal/prompts"
 	"pgedge-postgres-mcp/internal/resources"
+	"pgedge-postgres-mcp/internal/scheduler"
+	"pgedge-postgres-mcp/internal/sigv4"
 	"pgedge-postgres-mcp/internal/tools"
+	"pgedge-postgres-mcp/internal/upload"
 )
 
 const (
@@ -53,7 +60,7 @@ func main() {
 	defaultConfigPath := config.GetDefaultConfigPath(execPath)
 
 	// Command line flags
-	configFile := flag.String("config", defaultConfigPath, "Path to configuration file")
+	configFile := flag.String("config", defaultConfigPath, "Path to configuration file (- reads it from standard input)")
 	httpMode := flag.Bool("http", false, "Enable HTTP transport mode (default: stdio)")
 	httpAddr := flag.String("addr", "", "HTTP server address")
 	tlsMode := flag.Bool("tls", false, "Enable TLS/HTTPS (requires -http)")
@@ -62,6 +69,7 @@ func main() {
 	chainFile := flag.String("chain", "", "Path to TLS certificate chain file (optional)")
 	noAuth := flag.Bool("no-auth", false, "Disable API token authentication in HTTP mode")
 	debug := flag.Bool("debug", false, "Enable debug logging (logs HTTP requests/responses)")
+	offline := flag.Bool("offline", false, "Offline mode: disable cloud LLM and embedding providers")
 	tokenFilePath := flag.String("token-file", "", "Path to API token file")
 
 	// Database connection flags
@@ -79,6 +87,7 @@ func main() {
 	tokenNote := flag.String("token-note", "", "Annotation for the new token (used with -add-token)")
 	tokenExpiry := flag.String("token-expiry", "", "Token expiry duration: '30d', '1y', '2w', '12h', 'never' (used with -add-token)")
 	tokenDatabase := flag.String("token-database", "", "Bind token to specific database name (used with -add-token, empty = first configured database)")
+	tokenTools := flag.String("token-tools", "", "Comma-separated list of tools the token may use (used with -add-token, empty = all tools)")
 
 	// User management commands
 	userFilePath := flag.String("user-file", "", "Path to user file")
@@ -92,8 +101,47 @@ func main() {
 	userPassword := flag.String("password", "", "Password for user management commands (prompted if not provided)")
 	userNote := flag.String("user-note", "", "Annotation for the new user (used with -add-user)")
 
+	// Conversation store commands
+	purgeConversationsCmd := flag.Bool("purge-conversations", false, "Purge stored conversations outside the retention policy, or all of one user's with -username")
+
+	// Windows service commands
+	installServiceCmd := flag.Bool("install-service", false, "Install the server as a Windows service that runs with the other flags given")
+	uninstallServiceCmd := flag.Bool("uninstall-service", false, "Stop and remove the Windows service")
+	serviceName := flag.String("service-name", defaultServiceName, "Name of the Windows service")
+
 	flag.Parse()
 
+	// Handle Windows service commands
+	if *installServiceCmd {
+		args, err := serviceArgs()
+		if err == nil {
+			err = installService(*serviceName, execPath, args)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Service %s installed; start it with: sc start %s\n", *serviceName, *serviceName)
+		return
+	}
+	if *uninstallServiceCmd {
+		if err := uninstallService(*serviceName); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Service %s removed\n", *serviceName)
+		return
+	}
+
+	// Under the Windows service manager, report the service's state until
+	// main returns
+	runningAsService, err := startService(*serviceName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Failed to start the Windows service: %v\n", err)
+		os.Exit(1)
+	}
+	defer stopService()
+
 	// Handle token management commands
 	if *addTokenCmd || *removeTokenCmd != "" || *listTokensCmd {
 		defaultTokenPath := auth.GetDefaultTokenPath(execPath)
@@ -147,7 +195,7 @@ func main() {
 				availableDatabases = append(availableDatabases, cfg.Databases[i].Name)
 			}
 
-			if err := addTokenCommand(tokenFile, *tokenNote, *tokenDatabase, expiry, availableDatabases); err != nil {
+			if err := addTokenCommand(tokenFile, *tokenNote, *tokenDatabase, splitList(*tokenTools), expiry, availableDatabases); err != nil {
 				fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 				os.Exit(1)
 			}
@@ -228,6 +276,24 @@ func main() {
 		}
 	}
 
+	// Handle conversation store commands
+	if *purgeConversationsCmd {
+		configPathForLoad := ""
+		if config.ConfigFileExists(*configFile) {
+			configPathForLoad = *configFile
+		}
+		cfg, err := config.LoadConfig(configPathForLoad, config.CLIFlags{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Failed to load configuration: %v\n", err)
+			os.Exit(1)
+		}
+		if err := purgeConversationsCommand(cfg, execPath, *username); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Track which flags were explicitly set
 	cliFlags := config.CLIFlags{}
 	flag.Visit(func(f *flag.Flag) {
@@ -280,6 +346,9 @@ func main() {
 		case "db-sslmode":
 			cliFlags.DBSSLSet = true
 			cliFlags.DBSSLMode = *dbSSLMode
+		case "offline":
+			cliFlags.OfflineSet = true
+			cliFlags.Offline = *offline
 		}
 	})
 
@@ -310,13 +379,50 @@ func main() {
 		os.Exit(1)
 	}
 
+	// In stdio mode standard input carries the MCP protocol
+	if configPath == config.StdinConfigPath && !cfg.HTTP.Enabled {
+		fmt.Fprintf(os.Stderr, "ERROR: Reading the configuration from standard input (-config -) requires HTTP mode\n")
+		os.Exit(1)
+	}
+	if runningAsService && !cfg.HTTP.Enabled {
+		fmt.Fprintf(os.Stderr, "ERROR: The Windows service requires HTTP mode (-http or http.enabled)\n")
+		os.Exit(1)
+	}
+
+	// Route outbound LLM/embedding calls through the configured proxy
+	if err := netproxy.Configure(cfg.Proxy); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Alerts pushed to Slack or webhooks by the subsystems below
+	applyNotifications(cfg)
+	if n := len(cfg.Notifications.Endpoints); n > 0 {
+		fmt.Fprintf(os.Stderr, "Notifications: %d endpoint(s)\n", n)
+	}
+
+	// Spans of the request path exported to an OTLP collector; changing
+	// the tracing settings requires a restart
+	if cfg.Tracing.Enabled {
+		stopTracing := startTracing(cfg)
+		defer stopTracing()
+		fmt.Fprintf(os.Stderr, "Tracing: exporting to %s (sample ratio %g)\n", cfg.Tracing.Endpoint, cfg.Tracing.SampleRatio)
+	}
+
+	// Offline mode also blocks hosted providers at the transport level
+	netproxy.SetOffline(cfg.Offline)
+	if cfg.Offline {
+		fmt.Fprintf(os.Stderr, "Offline mode: ENABLED (disabled tools: %v, LLM proxy disabled: %t)\n",
+			cfg.OfflineDisabledTools(), cfg.OfflineDisablesLLM())
+	}
+
 	// Set default token file path if not specified and HTTP is enabled
 	if cfg.HTTP.Enabled && cfg.HTTP.Auth.TokenFile == "" {
 		cfg.HTTP.Auth.TokenFile = auth.GetDefaultTokenPath(execPath)
 	}
 
-	// Verify TLS files exist if HTTPS is enabled
-	if cfg.HTTP.TLS.Enabled {
+	// Verify TLS files exist if HTTPS is enabled (ACME obtains its own)
+	if cfg.HTTP.TLS.Enabled && !cfg.HTTP.TLS.ACME.Enabled {
 		if _, err := os.Stat(cfg.HTTP.TLS.CertFile); err != nil {
 			fmt.Fprintf(os.Stderr, "ERROR: Certificate file not found: %s\n", cfg.HTTP.TLS.CertFile)
 			os.Exit(1)
@@ -404,6 +510,17 @@ func main() {
 		}
 	}
 
+	// Create per-token quota limiter if any quota is configured
+	var quotaLimiter *auth.QuotaLimiter
+	if cfg.HTTP.Enabled && cfg.HTTP.Auth.Enabled {
+		quotaLimiter = auth.NewQuotaLimiter(quotaLimits(cfg.HTTP.Auth.Quotas))
+		if quotaLimiter.Limits().IsEnabled() {
+			fmt.Fprintf(os.Stderr, "Token quotas enabled: %d tool calls/minute, %d rows/hour, %d concurrent queries (0 = unlimited)\n",
+				cfg.HTTP.Auth.Quotas.ToolCallsPerMinute, cfg.HTTP.Auth.Quotas.RowsPerHour, cfg.HTTP.Auth.Quotas.MaxConcurrentQueries)
+		}
+		defer quotaLimiter.Stop()
+	}
+
 	// Create a cancellable context for graceful shutdown of background goroutines
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel() // Ensure background goroutines are stopped on exit
@@ -413,6 +530,9 @@ func main() {
 		defer rateLimiter.Stop()
 	}
 
+	// Databases added at runtime are kept in the data directory
+	mergeStoredDatabases(cfg)
+
 	// Get the first database configuration (if any)
 	var firstDB *config.NamedDatabaseConfig
 	if len(cfg.Databases) > 0 {
@@ -422,6 +542,13 @@ func main() {
 	// Initialize client manager for database connections with all database configurations
 	clientManager := database.NewClientManager(cfg.Databases)
 
+	// Reload schema metadata periodically, so tables created or dropped
+	// outside the server show up
+	clientManager.SetMetadataRefreshInterval(time.Duration(cfg.MetadataRefreshIntervalSeconds) * time.Second)
+	go clientManager.RunMetadataRefresher(ctx, func(refreshed int) {
+		logging.Debug("metadata_refreshed", "connections", refreshed)
+	})
+
 	// Determine authentication mode
 	authEnabled := cfg.HTTP.Enabled && cfg.HTTP.Auth.Enabled
 
@@ -440,7 +567,7 @@ func main() {
 		}
 
 		// Load metadata
-		if err := fallbackClient.LoadMetadata(); err != nil {
+		if err := fallbackClient.LoadInitialMetadata(); err != nil {
 			// Close the connection before exiting to avoid connection leak
 			fallbackClient.Close()
 			fmt.Fprintf(os.Stderr, "ERROR: Failed to load database metadata: %v\n", err)
@@ -480,6 +607,9 @@ func main() {
 
 	// Context-aware tool provider
 	contextAwareToolProvider := tools.NewContextAwareProvider(clientManager, contextAwareResourceProvider, authEnabled, fallbackClient, cfg, userStore, userFilePathForTools, rateLimiter, cfg.HTTP.Auth.MaxFailedAttemptsBeforeLockout, accessChecker)
+	if quotaLimiter != nil {
+		contextAwareToolProvider.SetQuotaLimiter(quotaLimiter)
+	}
 	if err := contextAwareToolProvider.RegisterTools(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: Failed to register tools: %v\n", err)
 		os.Exit(1)
@@ -488,6 +618,15 @@ func main() {
 	// Create MCP server with context-aware providers
 	server := mcp.NewServer(contextAwareToolProvider)
 	server.SetResourceProvider(contextAwareResourceProvider)
+	server.SetCompletionProvider(contextAwareToolProvider)
+	server.SetExperimentalCapability(offlineCapabilityName, offlineCapability(cfg))
+
+	// Report, and keep retrying, a knowledgebase that can't be opened
+	reportKnowledgebaseStatus(server, contextAwareToolProvider)
+	go monitorKnowledgebase(ctx, server, contextAwareToolProvider)
+
+	// Notify subscribed clients when resources such as schema listings change
+	server.StartResourceWatcher(time.Duration(cfg.ResourcePollIntervalSeconds) * time.Second)
 
 	// Set up database provider based on mode
 	// For STDIO mode, use a fixed session key
@@ -502,18 +641,7 @@ func main() {
 
 	// Register prompts (only enabled ones)
 	promptRegistry := prompts.NewRegistry()
-	if cfg.Builtins.Prompts.IsPromptEnabled("explore-database") {
-		promptRegistry.Register("explore-database", prompts.ExploreDatabase())
-	}
-	if cfg.Builtins.Prompts.IsPromptEnabled("setup-semantic-search") {
-		promptRegistry.Register("setup-semantic-search", prompts.SetupSemanticSearch())
-	}
-	if cfg.Builtins.Prompts.IsPromptEnabled("diagnose-query-issue") {
-		promptRegistry.Register("diagnose-query-issue", prompts.DiagnoseQueryIssue())
-	}
-	if cfg.Builtins.Prompts.IsPromptEnabled("design-schema") {
-		promptRegistry.Register("design-schema", prompts.DesignSchema())
-	}
+	applyBuiltinPrompts(promptRegistry, cfg)
 	server.SetPromptProvider(promptRegistry)
 
 	// Load custom definitions if configured
@@ -554,8 +682,12 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Loaded %d custom prompt(s) and %d custom resource(s)\n", len(defs.Prompts), len(defs.Resources))
 	}
 
-	// Start periodic cleanup of expired tokens if auth is enabled
+	// Start periodic cleanup of expired tokens if auth is enabled, warning
+	// of tokens about to expire
+	tokenWarner := newTokenExpiryWarner(cfg.Notifications.TokenExpiryWarningDays)
 	if cfg.HTTP.Enabled && cfg.HTTP.Auth.Enabled {
+		tokenWarner.check(tokenStore, time.Now())
+
 		// Clean up expired tokens on startup (no connections exist yet)
 		if removed, _ := tokenStore.CleanupExpiredTokens(); removed > 0 {
 			fmt.Fprintf(os.Stderr, "Removed %d expired token(s)\n", removed)
@@ -574,6 +706,7 @@ func main() {
 				case <-ctx.Done():
 					return
 				case <-ticker.C:
+					tokenWarner.check(tokenStore, time.Now())
 					if removed, hashes := tokenStore.CleanupExpiredTokens(); removed > 0 {
 						fmt.Fprintf(os.Stderr, "Removed %d expired token(s)\n", removed)
 
@@ -607,6 +740,13 @@ func main() {
 			}
 		}()
 
+		// Close the connections of tokens that stop sending requests, so
+		// many users don't each keep a pool open
+		clientManager.SetIdleTimeout(time.Duration(cfg.ClientIdleTimeoutSeconds) * time.Second)
+		go clientManager.RunIdleReaper(ctx, func(closed int) {
+			fmt.Fprintf(os.Stderr, "Closed %d idle database client(s)\n", closed)
+		})
+
 		fmt.Fprintf(os.Stderr, "Authentication: ENABLED\n")
 	} else if cfg.HTTP.Enabled {
 		fmt.Fprintf(os.Stderr, "Authentication: DISABLED\n")
@@ -614,14 +754,14 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Mode: STDIO\n")
 	}
 
-	// Initialize conversation store for HTTP mode with auth
+	// Initialize conversation store for HTTP mode with auth. Memories and
+	// preferences are kept in the SQLite store; conversations are kept there
+	// too, or in PostgreSQL with the postgres backend.
 	var convStore *conversations.Store
+	var convHistory conversations.ConversationStore
 	if cfg.HTTP.Enabled && cfg.HTTP.Auth.Enabled && userStore != nil {
 		// Use configured data directory, or default to a directory next to the executable
-		dataDir := cfg.DataDir
-		if dataDir == "" {
-			dataDir = filepath.Join(filepath.Dir(execPath), "data")
-		}
+		dataDir := conversationDataDir(cfg, execPath)
 		var err error
 		convStore, err = conversations.NewStore(dataDir)
 		if err != nil {
@@ -630,11 +770,126 @@ func main() {
 		} else {
 			fmt.Fprintf(os.Stderr, "Conversation store: %s/conversations.db\n", dataDir)
 			defer convStore.Close()
+
+			convHistory = convStore
+			if cfg.Conversations.UsesPostgres() {
+				pgStore, db, err := openPostgresConversations(ctx, cfg)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+					os.Exit(1)
+				}
+				defer pgStore.Close()
+				fmt.Fprintf(os.Stderr, "Conversation history: database %s, schema %s\n", db.Name, conversations.PostgresSchema)
+				convHistory = pgStore
+			}
+
+			// Refuse to start rather than write conversations with a
+			// different key than existing ones
+			if err := configureConversationEncryption(convHistory, cfg, execPath); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Apply the retention policy in the background
+			policy := retentionPolicy(cfg.Conversations)
+			if policy.IsEnabled() {
+				interval := time.Duration(cfg.Conversations.PurgeIntervalMinutes) * time.Minute
+				go convHistory.RunPurger(ctx, policy, interval, func(purged int64, err error) {
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "WARNING: Failed to purge conversations: %v\n", err)
+					} else {
+						fmt.Fprintf(os.Stderr, "Purged %d conversation(s) outside the retention policy\n", purged)
+					}
+				})
+			}
 		}
 	}
 
+	// Cache embeddings of repeated search queries
+	if cache, err := newEmbeddingCache(cfg, execPath); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to initialize embedding cache: %v\n", err)
+	} else if cache != nil {
+		defer cache.Close()
+		embedding.SetCache(cache)
+		metrics.Default.Register("embedding_cache", embeddingCacheMetrics(cache))
+		if cfg.Embedding.Cache.Persist {
+			fmt.Fprintf(os.Stderr, "Embedding cache: %s\n", embeddingCachePath(cfg, execPath))
+		}
+	}
+
+	// Tool and connection pool metrics for the metrics endpoint and export
+	metrics.Default.Register("tools", metrics.Tools.Collect)
+	metrics.Default.Register("pools", metrics.PoolCollector(clientManager.PoolStats))
+	if cfg.Metrics.Export.Enabled {
+		stopExport, err := startMetricsExport(ctx, cfg, clientManager)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Failed to start metrics export: %v\n", err)
+		} else {
+			defer stopExport()
+		}
+	}
+
+	// Refresh statistics of tables with badly estimated plans
+	if cfg.AutoAnalyze.Enabled {
+		analyzer := newAutoAnalyzer(cfg.AutoAnalyze)
+		autoanalyze.SetShared(analyzer)
+		go analyzer.Run(ctx)
+		window := "any time"
+		if cfg.AutoAnalyze.Window != "" {
+			window = cfg.AutoAnalyze.Window
+		}
+		fmt.Fprintf(os.Stderr, "Background ANALYZE: estimates off by %dx or more, up to %d per hour, %s\n",
+			cfg.AutoAnalyze.EstimateRatio, cfg.AutoAnalyze.MaxPerHour, window)
+	}
+
+	// Operator-defined health probes, reported by the health check
+	if len(cfg.HealthProbes) > 0 {
+		stopProbes, err := startHealthProbes(ctx, cfg, server)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Failed to start health probes: %v\n", err)
+		} else {
+			defer stopProbes()
+		}
+	}
+
+	// Queries and tool pipelines run on a schedule
+	var jobScheduler *scheduler.Scheduler
+	if cfg.Scheduler.Enabled {
+		s, stopScheduler, err := startScheduler(ctx, cfg, execPath, contextAwareToolProvider)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Failed to start scheduled jobs: %v\n", err)
+		} else {
+			jobScheduler = s
+			defer stopScheduler()
+		}
+	}
+
+	// Drain in-flight requests on SIGTERM/SIGINT before closing connections
+	shutdownDone := make(chan struct{})
+	go handleShutdownSignals(server, time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second, shutdownDone)
+
 	if cfg.HTTP.Enabled {
 		// HTTP/HTTPS mode
+		// LLM proxy configuration (nil when disabled); replaced on reload
+		// Chat requests include a schema summary for the caller's database
+		schemaSource := llmproxy.NewClientManagerSchemaSource(clientManager, authEnabled)
+		// Chat requests from logged-in users also include their memories
+		var memorySource llmproxy.MemorySource
+		if convStore != nil {
+			memorySource = convStore
+		}
+		llmConfigStore := llmproxy.NewConfigStore(llmProxyConfig(cfg, schemaSource, memorySource))
+
+		// The readiness probe checks each database, the token store, the
+		// knowledgebase and the health probes
+		dbReadiness := newDatabaseReadiness(server)
+		dbReadiness.apply(clientManager.GetDatabaseConfigs())
+		clientManager.SetDatabasesChangedFunc(dbReadiness.apply)
+		defer dbReadiness.close()
+		if authEnabled {
+			server.SetReadinessCheck("token_store", tokenStoreReadiness(tokenStore))
+		}
+
 		// Create HTTP server configuration
 		httpConfig := &mcp.HTTPConfig{
 			Addr:        cfg.HTTP.Address,
@@ -646,6 +901,31 @@ func main() {
 			TokenStore:  tokenStore,
 			UserStore:   userStore,
 			Debug:       *debug,
+			CORS: &mcp.CORSConfig{
+				AllowedOrigins:   cfg.HTTP.CORS.AllowedOrigins,
+				AllowedHeaders:   cfg.HTTP.CORS.AllowedHeaders,
+				AllowCredentials: cfg.HTTP.CORS.AllowCredentials,
+				MaxAgeSeconds:    cfg.HTTP.CORS.MaxAgeSeconds,
+			},
+			MaxHeaderBytes: cfg.HTTP.Limits.MaxHeaderBytes,
+			MaxBodyBytes:   int64(cfg.HTTP.Limits.MaxBodyMB) << 20,
+			ReadTimeout:    time.Duration(cfg.HTTP.Limits.ReadTimeoutSeconds) * time.Second,
+			WriteTimeout:   time.Duration(cfg.HTTP.Limits.WriteTimeoutSeconds) * time.Second,
+			IdleTimeout:    time.Duration(cfg.HTTP.Limits.IdleTimeoutSeconds) * time.Second,
+		}
+		if cfg.HTTP.TLS.Enabled && cfg.HTTP.TLS.ACME.Enabled {
+			httpConfig.ACME = acmeConfig(cfg, execPath)
+		}
+
+		// Keep each session on the replica that created it
+		if cfg.HTTP.SessionAffinity.Enabled {
+			affinity, err := sessionAffinityConfig(cfg, execPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Session affinity: %v\n", err)
+				os.Exit(1)
+			}
+			httpConfig.Affinity = affinity
+			fmt.Fprintf(os.Stderr, "Session affinity: ENABLED (replica %s)\n", affinity.ReplicaID)
 		}
 
 		// Setup additional HTTP handlers
@@ -673,14 +953,17 @@ func main() {
 					}
 
 					// Try API token first, then session token
+					ctx := r.Context()
 					if _, err := tokenStore.ValidateToken(token); err != nil {
 						// Try session token if user auth is enabled
 						if userStore != nil {
-							if _, err := userStore.ValidateSessionToken(token); err != nil {
+							username, err := userStore.ValidateSessionToken(token)
+							if err != nil {
 								http.Error(w, "Invalid or expired token",
 									http.StatusUnauthorized)
 								return
 							}
+							ctx = context.WithValue(ctx, auth.UsernameContextKey, username)
 						} else {
 							http.Error(w, "Invalid or expired token",
 								http.StatusUnauthorized)
@@ -688,14 +971,20 @@ func main() {
 						}
 					}
 
-					// Token valid, proceed with handler
-					handler(w, r)
+					// Token valid, proceed with handler; the token hash identifies
+					// the caller's database connection (e.g. for the LLM schema context)
+					ctx = context.WithValue(ctx, auth.TokenHashContextKey, auth.HashToken(token))
+					handler(w, r.WithContext(ctx))
 				}
 			}
 
+			// Operational metrics in the Prometheus text format
+			mux.HandleFunc(metrics.Path, authWrapper(metrics.Default.Handler))
+
 			// Chat history compaction endpoint - requires auth when enabled
+			// Dropped messages are summarized by the LLM when configured
 			mux.HandleFunc("/api/chat/compact",
-				authWrapper(compactor.HandleCompact))
+				authWrapper(compactor.NewHandler(llmproxy.NewSummarizer(llmConfigStore))))
 
 			// User info endpoint - returns auth status (no error if not logged in)
 			mux.HandleFunc("/api/user/info", func(w http.ResponseWriter, r *http.Request) {
@@ -741,34 +1030,13 @@ func main() {
 				})
 			})
 
-			// Add LLM proxy handlers if enabled
-			if cfg.LLM.Enabled {
-				// Create LLM proxy configuration
-				llmConfig := &llmproxy.Config{
-					Provider:        cfg.LLM.Provider,
-					Model:           cfg.LLM.Model,
-					AnthropicAPIKey: cfg.LLM.AnthropicAPIKey,
-					OpenAIAPIKey:    cfg.LLM.OpenAIAPIKey,
-					OllamaURL:       cfg.LLM.OllamaURL,
-					MaxTokens:       cfg.LLM.MaxTokens,
-					Temperature:     cfg.LLM.Temperature,
-				}
-
-				// Provider/model listing don't require auth (needed for login page)
-				mux.HandleFunc("/api/llm/providers",
-					func(w http.ResponseWriter, r *http.Request) {
-						llmproxy.HandleProviders(w, r, llmConfig)
-					})
-				mux.HandleFunc("/api/llm/models",
-					func(w http.ResponseWriter, r *http.Request) {
-						llmproxy.HandleModels(w, r, llmConfig)
-					})
-				// Chat endpoint requires auth (makes actual LLM API calls)
-				mux.HandleFunc("/api/llm/chat",
-					authWrapper(func(w http.ResponseWriter, r *http.Request) {
-						llmproxy.HandleChat(w, r, llmConfig)
-					}))
-			}
+			// LLM proxy handlers are always registered so the proxy can be
+			// enabled on reload; they return 404 while it is disabled
+			// Provider/model listing don't require auth (needed for login page)
+			mux.HandleFunc("/api/llm/providers", llmConfigStore.Handler(llmproxy.HandleProviders))
+			mux.HandleFunc("/api/llm/models", llmConfigStore.Handler(llmproxy.HandleModels))
+			// Chat endpoint requires auth (makes actual LLM API calls)
+			mux.HandleFunc("/api/llm/chat", authWrapper(llmConfigStore.Handler(llmproxy.HandleChat)))
 
 			// Database listing and selection endpoints
 			accessChecker := auth.NewDatabaseAccessChecker(tokenStore, authEnabled, false)
@@ -776,17 +1044,43 @@ func main() {
 			mux.HandleFunc("/api/databases", authWrapper(dbHandler.HandleListDatabases))
 			mux.HandleFunc("/api/databases/select", authWrapper(dbHandler.HandleSelectDatabase))
 
+			// Query result export downloads
+			if cfg.IsToolAvailable("export_query_results") {
+				mux.HandleFunc(export.DownloadPath, authWrapper(export.NewStoreFromConfig(cfg).HandleDownload))
+			}
+
+			// File uploads for data imports and knowledgebase ingestion
+			if cfg.Uploads.Enabled {
+				uploads := upload.NewStoreFromConfig(cfg)
+				mux.HandleFunc(upload.Path, authWrapper(uploads.HandleUpload))
+				// Upload bodies have their own, larger limit
+				httpConfig.BodyLimits = map[string]int64{upload.Path: uploads.MaxRequestBytes()}
+				fmt.Fprintf(os.Stderr, "File uploads: ENABLED\n")
+			}
+
 			// Conversation history endpoints (only if store is available)
 			if convStore != nil && userStore != nil {
 				convHandler := conversations.NewHandler(convStore, userStore)
+				convHandler.SetConversationStore(convHistory)
 				convHandler.RegisterRoutes(mux, authWrapper)
 				fmt.Fprintf(os.Stderr, "Conversation history: ENABLED\n")
 			}
 
+			// Scheduled job management (only if the scheduler is running)
+			if jobScheduler != nil {
+				jobsHandler := api.NewJobsHandler(jobScheduler, canManageJobs(authEnabled, tokenStore),
+					func(name string) bool { return clientManager.GetDatabaseConfig(name) != nil })
+				jobsHandler.RegisterRoutes(mux, authWrapper)
+			}
+
 			return nil
 		}
 
-		if cfg.HTTP.TLS.Enabled {
+		if httpConfig.ACME != nil {
+			fmt.Fprintf(os.Stderr, "Starting MCP server in HTTPS mode on %s\n", cfg.HTTP.Address)
+			fmt.Fprintf(os.Stderr, "Certificates: ACME for %s (cache: %s)\n",
+				strings.Join(httpConfig.ACME.Domains, ", "), httpConfig.ACME.CacheDir)
+		} else if cfg.HTTP.TLS.Enabled {
 			fmt.Fprintf(os.Stderr, "Starting MCP server in HTTPS mode on %s\n", cfg.HTTP.Address)
 			fmt.Fprintf(os.Stderr, "Certificate: %s\n", cfg.HTTP.TLS.CertFile)
 			fmt.Fprintf(os.Stderr, "Key: %s\n", cfg.HTTP.TLS.KeyFile)
@@ -803,7 +1097,9 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Authentication: DISABLED (warning: server is not secured)\n")
 		}
 
-		if cfg.LLM.Enabled {
+		if cfg.OfflineDisablesLLM() {
+			fmt.Fprintf(os.Stderr, "LLM Proxy: DISABLED (offline mode, provider: %s)\n", cfg.LLM.Provider)
+		} else if cfg.LLM.Enabled {
 			fmt.Fprintf(os.Stderr, "LLM Proxy: ENABLED (provider: %s, model: %s)\n", cfg.LLM.Provider, cfg.LLM.Model)
 		} else {
 			fmt.Fprintf(os.Stderr, "LLM Proxy: DISABLED\n")
@@ -816,8 +1112,16 @@ func main() {
 			} else if cfg.Knowledgebase.EmbeddingOpenAIAPIKey != "" {
 				apiKeyStatus = "loaded"
 			}
-			fmt.Fprintf(os.Stderr, "Knowledgebase: ENABLED (provider: %s, model: %s, API key: %s)\n",
-				cfg.Knowledgebase.EmbeddingProvider, cfg.Knowledgebase.EmbeddingModel, apiKeyStatus)
+			availability := ""
+			if _, kbErr := contextAwareToolProvider.KnowledgebaseStatus(); kbErr != nil {
+				availability = ", UNAVAILABLE"
+			}
+			embeddingProvider, embeddingModel := contextAwareToolProvider.KnowledgebaseEmbedding()
+			if embeddingModel == "" {
+				embeddingModel = "provider default"
+			}
+			fmt.Fprintf(os.Stderr, "Knowledgebase: ENABLED (backend: %s, provider: %s, model: %s, API key: %s%s)\n",
+				cfg.Knowledgebase.Backend, embeddingProvider, embeddingModel, apiKeyStatus, availability)
 		} else {
 			fmt.Fprintf(os.Stderr, "Knowledgebase: DISABLED\n")
 		}
@@ -834,17 +1138,58 @@ func main() {
 			DBUser:     *dbUser,
 			DBPassword: *dbPassword,
 			DBSSLMode:  *dbSSLMode,
+			Offline:    *offline,
+			OfflineSet: *offline, // Keep -offline in effect across reloads
 		}
 		reloadableCfg := config.NewReloadableConfig(cfg, configPath, cliFlags)
 
-		// Register callback to update client manager when databases change
+		// Register callback to apply reloaded settings to running components
 		reloadableCfg.OnReload(func(newCfg *config.Config) {
-			clientManager.UpdateDatabaseConfigs(newCfg.Databases)
+			mergeStoredDatabases(newCfg)
+			clientManager.UpdateDatabaseConfigs(newCfg.Databases) // Also updates the readiness checks
+			if newCfg.HTTP.Enabled && newCfg.HTTP.Auth.Enabled {
+				clientManager.SetIdleTimeout(time.Duration(newCfg.ClientIdleTimeoutSeconds) * time.Second)
+			}
+			clientManager.SetMetadataRefreshInterval(time.Duration(newCfg.MetadataRefreshIntervalSeconds) * time.Second)
+
+			// Builtin toggles, knowledgebase settings and masking rules
+			contextAwareToolProvider.Reload(newCfg)
+			reportKnowledgebaseStatus(server, contextAwareToolProvider)
+			contextAwareResourceProvider.SetConfig(newCfg)
+			applyBuiltinPrompts(promptRegistry, newCfg)
+
+			llmConfigStore.Set(llmProxyConfig(newCfg, schemaSource, memorySource))
+
+			// Swap the TLS certificate (enabling/disabling TLS requires a
+			// restart); ACME renews certificates itself
+			if cfg.HTTP.TLS.Enabled && newCfg.HTTP.TLS.Enabled && !cfg.HTTP.TLS.ACME.Enabled && !newCfg.HTTP.TLS.ACME.Enabled {
+				if err := server.ReloadTLSCertificate(newCfg.HTTP.TLS.CertFile, newCfg.HTTP.TLS.KeyFile, newCfg.HTTP.TLS.ChainFile); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: Failed to reload TLS certificate (keeping current certificate): %v\n", err)
+				}
+			}
+
+			if quotaLimiter != nil {
+				quotaLimiter.SetLimits(quotaLimits(newCfg.HTTP.Auth.Quotas))
+			}
+			// Proxy settings are read per request, so they apply immediately
+			if err := netproxy.Configure(newCfg.Proxy); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Failed to apply proxy settings: %v\n", err)
+			}
+			netproxy.SetOffline(newCfg.Offline)
+			server.SetExperimentalCapability(offlineCapabilityName, offlineCapability(newCfg))
+
+			applyNotifications(newCfg)
+			tokenWarner.days.Store(int64(newCfg.Notifications.TokenExpiryWarningDays))
+
+			// Enabling or disabling the scheduler requires a restart
+			if jobScheduler != nil {
+				jobScheduler.SetConfigJobs(newCfg.Scheduler.SchedulerJobs())
+			}
 		})
 
 		// Start SIGHUP listener
 		sighup := make(chan os.Signal, 1)
-		signal.Notify(sighup, syscall.SIGHUP)
+		notifySignals(sighup, syscall.SIGHUP)
 		go func() {
 			for range sighup {
 				fmt.Fprintf(os.Stderr, "Received SIGHUP, reloading configuration...\n")
@@ -865,7 +1210,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Wait for in-flight requests to drain before closing their connections
+	select {
+	case <-server.ShuttingDown():
+		<-shutdownDone
+	default:
+	}
+
 	// Cleanup
+	// Roll back open transactions first; pools wait for the connections
+	// they hold before closing
+	contextAwareToolProvider.RollbackTransactions()
 	if clientManager != nil {
 		// Close all per-token connections
 		if err := clientManager.CloseAll(); err != nil {
@@ -881,3 +1236,112 @@ func main() {
 		userStore.StopWatching()
 	}
 }
+
+// handleShutdownSignals shuts the server down gracefully on SIGTERM or SIGINT
+// A second signal exits immediately. done is closed once draining has finished.
+func handleShutdownSignals(server *mcp.Server, timeout time.Duration, done chan<- struct{}) {
+	sigs := make(chan os.Signal, 2)
+	notifySignals(sigs, syscall.SIGTERM, os.Interrupt)
+
+	sig := <-sigs
+	fmt.Fprintf(os.Stderr, "Received %s, shutting down (waiting up to %s for in-flight requests)...\n", sig, timeout)
+	go func() {
+		sig := <-sigs
+		fmt.Fprintf(os.Stderr, "Received %s again, exiting immediately\n", sig)
+		os.Exit(1)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %v\n", err)
+	}
+	close(done)
+}
+
+// quotaLimits converts quota configuration to auth limits
+func quotaLimits(q config.QuotaConfig) auth.QuotaLimits {
+	return auth.QuotaLimits{
+		ToolCallsPerMinute:   q.ToolCallsPerMinute,
+		RowsPerHour:          q.RowsPerHour,
+		MaxConcurrentQueries: q.MaxConcurrentQueries,
+	}
+}
+
+// builtinPrompts maps built-in prompt names to their constructors
+var builtinPrompts = map[string]func() prompts.Prompt{
+	"explore-database":      prompts.ExploreDatabase,
+	"setup-semantic-search": prompts.SetupSemanticSearch,
+	"diagnose-query-issue":  prompts.DiagnoseQueryIssue,
+	"design-schema":         prompts.DesignSchema,
+}
+
+// applyBuiltinPrompts registers enabled built-in prompts and removes disabled ones
+func applyBuiltinPrompts(registry *prompts.Registry, cfg *config.Config) {
+	for name, newPrompt := range builtinPrompts {
+		if cfg.Builtins.Prompts.IsPromptEnabled(name) {
+			registry.Register(name, newPrompt())
+		} else {
+			registry.Unregister(name)
+		}
+	}
+}
+
+// llmProxyConfig builds the LLM proxy configuration, or nil if the proxy is disabled
+func llmProxyConfig(cfg *config.Config, schema llmproxy.SchemaSource, memory llmproxy.MemorySource) *llmproxy.Config {
+	if !cfg.LLM.Enabled || cfg.OfflineDisablesLLM() {
+		return nil
+	}
+	var fallback []llmproxy.Route
+	for _, route := range cfg.LLM.Fallback {
+		// Cloud providers cannot be reached in offline mode
+		if cfg.Offline && config.IsCloudProvider(route.Provider) {
+			continue
+		}
+		fallback = append(fallback, llmproxy.Route{Provider: route.Provider, Model: route.Model})
+	}
+	return &llmproxy.Config{
+		Provider:        cfg.LLM.Provider,
+		Model:           cfg.LLM.Model,
+		AnthropicAPIKey: cfg.LLM.AnthropicAPIKey,
+		OpenAIAPIKey:    cfg.LLM.OpenAIAPIKey,
+		OllamaURL:       cfg.LLM.OllamaURL,
+		MaxTokens:       cfg.LLM.MaxTokens,
+		Temperature:     cfg.LLM.Temperature,
+		Schema:          schema,
+		Memory:          memory,
+
+		AzureEndpoint:      cfg.LLM.AzureEndpoint,
+		AzureAPIKey:        cfg.LLM.AzureAPIKey,
+		AzureAPIVersion:    cfg.LLM.AzureAPIVersion,
+		BedrockRegion:      cfg.LLM.BedrockRegion,
+		BedrockEndpoint:    cfg.LLM.BedrockEndpoint,
+		BedrockCredentials: sigv4.CredentialsFromEnv(),
+
+		CompactionSummaries: cfg.LLM.CompactionSummaries,
+		ContextWindow:       cfg.LLM.ContextWindow,
+
+		Fallback:         fallback,
+		FailoverCooldown: time.Duration(cfg.LLM.FailoverCooldownSeconds) * time.Second,
+	}
+}
+
+// offlineCapabilityName is the experimental capability describing offline mode
+const offlineCapabilityName = "pgedge/offline"
+
+// offlineCapability describes the capabilities disabled by offline mode,
+// or returns nil when the server is online
+func offlineCapability(cfg *config.Config) interface{} {
+	if !cfg.Offline {
+		return nil
+	}
+	disabledTools := cfg.OfflineDisabledTools()
+	if disabledTools == nil {
+		disabledTools = []string{}
+	}
+	return map[string]interface{}{
+		"enabled":          true,
+		"disabledTools":    disabledTools,
+		"llmProxyDisabled": cfg.OfflineDisablesLLM(),
+	}
+}
@@ -0,0 +1,93 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/metrics"
+)
+
+// metricsServerName identifies this server in exported metrics: the
+// session affinity replica ID, or the host name
+func metricsServerName(cfg *config.Config) string {
+	if cfg.HTTP.SessionAffinity.ReplicaID != "" {
+		return cfg.HTTP.SessionAffinity.ReplicaID
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	name, _, _ := strings.Cut(hostname, ".")
+	return name
+}
+
+// startMetricsExport starts writing metrics snapshots to the configured
+// database. The export uses its own connection, as tool connections are
+// read-only. The returned function stops the export after writing the last
+// snapshot.
+func startMetricsExport(ctx context.Context, cfg *config.Config, clientManager *database.ClientManager) (func(), error) {
+	export := cfg.Metrics.Export
+	var db *config.NamedDatabaseConfig
+	if export.Database != "" {
+		db = cfg.GetDatabaseByName(export.Database)
+	} else if len(cfg.Databases) > 0 {
+		db = &cfg.Databases[0]
+	}
+	if db == nil {
+		return nil, fmt.Errorf("no database configured for the metrics export")
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(db.BuildConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection settings for database %q: %w", db.Name, err)
+	}
+	poolConfig.MaxConns = 1
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = "pgedge-postgres-mcp metrics"
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database %q: %w", db.Name, err)
+	}
+
+	exporter := metrics.NewExporter(pool, metrics.ExporterConfig{
+		Server:    metricsServerName(cfg),
+		Interval:  time.Duration(export.IntervalSeconds) * time.Second,
+		Retention: time.Duration(export.RetentionDays) * 24 * time.Hour,
+		Tools:     metrics.Tools,
+		Registry:  metrics.Default,
+		Pools:     clientManager.PoolStats,
+	})
+
+	exportCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer pool.Close()
+		exporter.Run(exportCtx, func(err error) {
+			fmt.Fprintf(os.Stderr, "WARNING: Metrics export failed: %v\n", err)
+		})
+	}()
+
+	fmt.Fprintf(os.Stderr, "Metrics export: database %s, schema %s, every %ds\n",
+		db.Name, metrics.Schema, export.IntervalSeconds)
+	return func() {
+		stop()
+		<-done
+	}, nil
+}
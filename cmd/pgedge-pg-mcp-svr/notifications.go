@@ -0,0 +1,99 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/notify"
+)
+
+// applyNotifications sets the notifier that subsystems send alerts with;
+// without endpoints, alerts are not sent
+func applyNotifications(cfg *config.Config) {
+	endpoints := cfg.Notifications.NotifyEndpoints()
+	if len(endpoints) == 0 {
+		notify.SetShared(nil)
+		return
+	}
+	notify.SetShared(notify.New(notify.Config{
+		Endpoints: endpoints,
+		Cooldown:  time.Duration(cfg.Notifications.CooldownMinutes) * time.Minute,
+	}))
+}
+
+// tokenExpiryWarner sends a notification once for each API token that
+// expires within the warning period
+type tokenExpiryWarner struct {
+	days   atomic.Int64         // Warning period in days; changed on reload
+	warned map[string]time.Time // Expiry each token was warned of, by token ID
+}
+
+// newTokenExpiryWarner creates a warner with a warning period in days
+func newTokenExpiryWarner(days int) *tokenExpiryWarner {
+	w := &tokenExpiryWarner{warned: make(map[string]time.Time)}
+	w.days.Store(int64(days))
+	return w
+}
+
+// check warns of the tokens that expire within the warning period. A token
+// whose expiry changes is warned of again. Checks must not run
+// concurrently.
+func (w *tokenExpiryWarner) check(store *auth.TokenStore, now time.Time) {
+	days := int(w.days.Load())
+	if days <= 0 {
+		return
+	}
+	horizon := now.AddDate(0, 0, days)
+	for _, token := range store.ListTokens() {
+		if token.ExpiresAt == nil || token.Expired || token.ExpiresAt.After(horizon) {
+			continue
+		}
+		if warned, ok := w.warned[token.ID]; ok && warned.Equal(*token.ExpiresAt) {
+			continue
+		}
+		w.warned[token.ID] = *token.ExpiresAt
+
+		fields := map[string]string{
+			"token":      token.ID,
+			"expires_at": token.ExpiresAt.UTC().Format(time.RFC3339),
+		}
+		if token.Annotation != "" {
+			fields["annotation"] = token.Annotation
+		}
+		notify.Send(notify.Event{
+			Category: notify.CategoryAuth,
+			Severity: notify.SeverityWarning,
+			Title:    fmt.Sprintf("API token %s expires in %s", token.ID, formatDaysLeft(token.ExpiresAt.Sub(now))),
+			Message:  "Create a replacement with -add-token before it expires; clients using it will be refused afterwards.",
+			Fields:   fields,
+			Key:      "token-expiry:" + token.ID,
+		})
+		fmt.Fprintf(os.Stderr, "WARNING: API token %s expires at %s\n", token.ID, fields["expires_at"])
+	}
+}
+
+// formatDaysLeft describes the time until a token expires
+func formatDaysLeft(d time.Duration) string {
+	switch days := int(d.Hours() / 24); {
+	case days >= 2:
+		return fmt.Sprintf("%d days", days)
+	case d >= time.Hour:
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	default:
+		return "less than an hour"
+	}
+}
@@ -0,0 +1,190 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/healthprobe"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// databaseReadinessCheck is the name of a database's readiness check
+func databaseReadinessCheck(name string) string {
+	return "database:" + name
+}
+
+// databaseReadiness checks that each configured database accepts
+// connections for the readiness probe. Checks use their own connections,
+// one per database, so that a busy tool pool doesn't fail the probe.
+type databaseReadiness struct {
+	server *mcp.Server
+
+	mu     sync.Mutex
+	pools  map[string]*pgxpool.Pool // By database name
+	conns  map[string]string        // Connection string of each database
+	failed map[string]bool          // Databases whose last check failed
+}
+
+// newDatabaseReadiness creates the database readiness checks of a server
+func newDatabaseReadiness(server *mcp.Server) *databaseReadiness {
+	return &databaseReadiness{
+		server: server,
+		pools:  make(map[string]*pgxpool.Pool),
+		conns:  make(map[string]string),
+		failed: make(map[string]bool),
+	}
+}
+
+// apply sets a readiness check for each of the databases, and removes those
+// of databases that are no longer configured. It is called with the
+// databases of the client manager whenever they change, so databases added
+// at runtime are checked too.
+func (d *databaseReadiness) apply(databases []config.NamedDatabaseConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	configured := make(map[string]string, len(databases))
+	for i := range databases {
+		db := &databases[i]
+		configured[db.Name] = db.BuildConnectionString()
+		d.server.SetReadinessCheck(databaseReadinessCheck(db.Name), d.check(db.Name))
+	}
+	for name, connStr := range d.conns {
+		if newConnStr, ok := configured[name]; ok && newConnStr == connStr {
+			continue
+		}
+		// The database was removed or its settings changed
+		if pool := d.pools[name]; pool != nil {
+			pool.Close()
+		}
+		delete(d.pools, name)
+		delete(d.failed, name)
+		if _, ok := configured[name]; !ok {
+			d.server.SetReadinessCheck(databaseReadinessCheck(name), nil)
+		}
+	}
+	d.conns = configured
+}
+
+// check returns the readiness check of a database, which connects on
+// first use
+func (d *databaseReadiness) check(name string) mcp.ReadinessCheck {
+	return func(ctx context.Context) error {
+		pool, err := d.pool(ctx, name)
+		if err == nil {
+			err = pool.Ping(ctx)
+		}
+		return d.report(name, err)
+	}
+}
+
+// pool returns the connection pool of a database's check
+func (d *databaseReadiness) pool(ctx context.Context, name string) (*pgxpool.Pool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if pool := d.pools[name]; pool != nil {
+		return pool, nil
+	}
+	connStr, ok := d.conns[name]
+	if !ok {
+		return nil, fmt.Errorf("database %q is not configured", name)
+	}
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection settings: %w", err)
+	}
+	poolConfig.MaxConns = 1
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = "pgedge-postgres-mcp readiness"
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	d.pools[name] = pool
+	return pool, nil
+}
+
+// report logs a database's change of readiness, and returns the error
+// reported by the probe. The probe bypasses authentication, so connection
+// details only go to the log.
+func (d *databaseReadiness) report(name string, err error) error {
+	d.mu.Lock()
+	changed := d.failed[name] != (err != nil)
+	if _, ok := d.conns[name]; ok {
+		d.failed[name] = err != nil
+	}
+	d.mu.Unlock()
+
+	if err == nil {
+		if changed {
+			logging.Info("readiness_database_recovered", "database", name)
+		}
+		return nil
+	}
+	if changed {
+		logging.Warn("readiness_database_unreachable", "database", name, "error", err.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.New("database did not respond in time")
+	}
+	return errors.New("database is not reachable")
+}
+
+// close closes the connections of the database checks
+func (d *databaseReadiness) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for name, pool := range d.pools {
+		pool.Close()
+		delete(d.pools, name)
+	}
+}
+
+// tokenStoreReadiness checks that the API token file is loaded: a token
+// file that can't be reloaded would also stop the server from starting
+func tokenStoreReadiness(store *auth.TokenStore) mcp.ReadinessCheck {
+	return func(context.Context) error {
+		if store == nil {
+			return errors.New("token store is not loaded")
+		}
+		if err := store.LoadError(); err != nil {
+			return errors.New("token file could not be reloaded; serving the previous tokens")
+		}
+		return nil
+	}
+}
+
+// healthProbeReadiness fails while a health probe reports crit
+func healthProbeReadiness(context.Context) error {
+	runner := healthprobe.Shared()
+	if runner == nil {
+		return nil
+	}
+	var critical []string
+	for _, result := range runner.Results() {
+		if result.Status == healthprobe.StatusCrit {
+			critical = append(critical, result.Probe)
+		}
+	}
+	if len(critical) > 0 {
+		return fmt.Errorf("health probes critical: %s", strings.Join(critical, ", "))
+	}
+	return nil
+}
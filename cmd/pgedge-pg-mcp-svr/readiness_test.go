@@ -0,0 +1,123 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// readinessChecks fetches /readyz and returns its status code and checks
+func readinessChecks(t *testing.T, url string) (int, map[string]mcp.HealthComponent) {
+	t.Helper()
+	resp, err := http.Get(url) //nolint:gosec,noctx // Test server URL
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Checks map[string]mcp.HealthComponent `json:"checks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding readiness response: %v", err)
+	}
+	return resp.StatusCode, body.Checks
+}
+
+func TestDatabaseReadiness_RuntimeDatabases(t *testing.T) {
+	// Find a free port for the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server := mcp.NewServer(nil)
+	dbReadiness := newDatabaseReadiness(server)
+	defer dbReadiness.close()
+
+	clientManager := database.NewClientManager(nil)
+	defer clientManager.CloseAll()
+	dbReadiness.apply(clientManager.GetDatabaseConfigs())
+	clientManager.SetDatabasesChangedFunc(dbReadiness.apply)
+
+	go func() {
+		if err := server.RunHTTP(&mcp.HTTPConfig{Addr: addr}); err != nil {
+			t.Errorf("RunHTTP: %v", err)
+		}
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx) //nolint:errcheck // Test cleanup
+	}()
+
+	url := "http://" + addr + "/readyz"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url) //nolint:gosec,noctx // Test server URL
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if status, checks := readinessChecks(t, url); status != http.StatusOK || len(checks) != 0 {
+		t.Fatalf("expected ready with no checks, got %d %v", status, checks)
+	}
+
+	// A database added at runtime that isn't reachable makes the server not ready
+	err = clientManager.AddDatabaseConfig(config.NamedDatabaseConfig{
+		Name:     "runtime",
+		Host:     "127.0.0.1",
+		Port:     1,
+		Database: "postgres",
+		User:     "postgres",
+		SSLMode:  "disable",
+	})
+	if err != nil {
+		t.Fatalf("AddDatabaseConfig: %v", err)
+	}
+	status, checks := readinessChecks(t, url)
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", status)
+	}
+	check, ok := checks[databaseReadinessCheck("runtime")]
+	if !ok {
+		t.Fatalf("expected a check for the added database, got %v", checks)
+	}
+	if check.Status != mcp.HealthStatusUnavailable {
+		t.Errorf("expected the check to be unavailable, got %q", check.Status)
+	}
+
+	// Removing the database removes its check
+	clientManager.RemoveDatabaseConfig("runtime")
+	status, checks = readinessChecks(t, url)
+	if status != http.StatusOK {
+		t.Errorf("expected status 200, got %d", status)
+	}
+	if _, ok := checks[databaseReadinessCheck("runtime")]; ok {
+		t.Errorf("expected no check for the removed database, got %v", checks)
+	}
+}
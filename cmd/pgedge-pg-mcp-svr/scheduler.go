@@ -0,0 +1,104 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/scheduler"
+	"pgedge-postgres-mcp/internal/tools"
+)
+
+// schedulerClientKey identifies the database connections of scheduled jobs,
+// which are kept apart from those of callers
+const schedulerClientKey = "scheduler"
+
+// jobStoreDir returns the directory of the jobs added through the API and
+// of the history of runs
+func jobStoreDir(cfg *config.Config, execPath string) string {
+	return filepath.Join(conversationDataDir(cfg, execPath), "jobs")
+}
+
+// jobExecutor runs a job's tools through the tool provider, so a job gets
+// the same read-only transactions, guards and masking as a caller. Jobs run
+// without an API token, so admin tools are refused.
+func jobExecutor(provider *tools.ContextAwareProvider) scheduler.Executor {
+	return func(ctx context.Context, database, tool string, args map[string]interface{}) (string, bool, error) {
+		ctx = context.WithValue(ctx, auth.TokenHashContextKey, schedulerClientKey)
+		ctx = tools.WithDatabase(ctx, database)
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		response, err := provider.Execute(ctx, tool, args)
+		if err != nil {
+			return "", false, err
+		}
+		texts := make([]string, 0, len(response.Content))
+		for _, item := range response.Content {
+			if item.Text != "" {
+				texts = append(texts, item.Text)
+			}
+		}
+		return strings.Join(texts, "\n"), response.IsError, nil
+	}
+}
+
+// startScheduler starts the scheduled jobs, which run until ctx is
+// cancelled. The returned function stops the scheduler and waits for
+// running jobs.
+func startScheduler(ctx context.Context, cfg *config.Config, execPath string, provider *tools.ContextAwareProvider) (*scheduler.Scheduler, func(), error) {
+	dir := jobStoreDir(cfg, execPath)
+	s, err := scheduler.New(scheduler.Config{
+		Jobs:    cfg.Scheduler.SchedulerJobs(),
+		Store:   scheduler.NewStore(dir, cfg.Scheduler.HistorySize),
+		Execute: jobExecutor(provider),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	scheduler.SetShared(s)
+
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(runCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Scheduled jobs: %d (history: %s)\n", len(s.Jobs()), dir)
+	return s, func() {
+		stop()
+		<-done
+		scheduler.SetShared(nil)
+	}, nil
+}
+
+// canManageJobs reports whether the caller of the jobs API may change and
+// run jobs: without authentication anyone may, otherwise only API tokens
+// whose allowed_tools include manage_jobs
+func canManageJobs(authEnabled bool, tokenStore *auth.TokenStore) func(ctx context.Context) bool {
+	return func(ctx context.Context) bool {
+		if !authEnabled {
+			return true
+		}
+		if tokenStore == nil {
+			return false
+		}
+		token := tokenStore.GetTokenByHash(auth.GetTokenHashFromContext(ctx))
+		return token != nil && token.AllowsTool("manage_jobs")
+	}
+}
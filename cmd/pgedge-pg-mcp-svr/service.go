@@ -0,0 +1,73 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// defaultServiceName is the name the Windows service is installed under
+const defaultServiceName = "pgedge-postgres-mcp"
+
+// serviceFileFlags are the flags naming files. The service starts in the
+// system directory, so they are made absolute.
+var serviceFileFlags = map[string]bool{
+	"config":     true,
+	"cert":       true,
+	"key":        true,
+	"chain":      true,
+	"token-file": true,
+	"user-file":  true,
+}
+
+// serviceSecretFlags are the flags that can't be installed: the command
+// line of a service can be read by any local user
+var serviceSecretFlags = map[string]string{
+	"db-password": "set the password in the configuration file or PGEDGE_DB_PASSWORD",
+	"password":    "it is only used by the user management commands",
+}
+
+// serviceArgs returns the command line the service runs with: the flags
+// given with -install-service, other than the service commands
+func serviceArgs() ([]string, error) {
+	var args []string
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch f.Name {
+		case "install-service", "uninstall-service":
+			return
+		}
+		if hint, ok := serviceSecretFlags[f.Name]; ok {
+			err = fmt.Errorf("-%s can't be installed in the service's command line: %s", f.Name, hint)
+			return
+		}
+		value := f.Value.String()
+		if serviceFileFlags[f.Name] && value != "" {
+			if f.Name == "config" && value == config.StdinConfigPath {
+				err = fmt.Errorf("the service can't read its configuration from standard input")
+				return
+			}
+			if value, err = filepath.Abs(value); err != nil {
+				err = fmt.Errorf("invalid path for -%s: %w", f.Name, err)
+				return
+			}
+		}
+		args = append(args, "-"+f.Name+"="+value)
+	})
+	return args, err
+}
@@ -0,0 +1,48 @@
+//go:build !windows
+
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+)
+
+// errServiceUnsupported is returned by the Windows service commands on
+// other platforms
+var errServiceUnsupported = errors.New("service installation is only supported on Windows; use systemd elsewhere")
+
+// notifySignals relays the given signals to c
+func notifySignals(c chan<- os.Signal, sigs ...os.Signal) {
+	signal.Notify(c, sigs...)
+}
+
+// startService reports whether the process runs as a Windows service,
+// which it never does here
+func startService(string) (bool, error) {
+	return false, nil
+}
+
+// stopService reports to the Windows service manager that the service
+// has stopped
+func stopService() {}
+
+// installService registers the server as a Windows service
+func installService(string, string, []string) error {
+	return errServiceUnsupported
+}
+
+// uninstallService removes the Windows service
+func uninstallService(string) error {
+	return errServiceUnsupported
+}
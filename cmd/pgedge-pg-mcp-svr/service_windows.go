@@ -0,0 +1,239 @@
+//go:build windows
+
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+const (
+	serviceDisplayName = "pgEdge Postgres MCP Server"
+	serviceDescription = "MCP server and Natural Language Agent for PostgreSQL"
+
+	// serviceStopWaitHint tells the service manager how long stopping may
+	// take; it covers the default shutdown_timeout_seconds
+	serviceStopWaitHint = 40 * time.Second
+
+	// serviceExitTimeout bounds the wait for the service manager to
+	// acknowledge that the service has stopped
+	serviceExitTimeout = 5 * time.Second
+)
+
+// service holds the state of a process run by the service manager
+var service struct {
+	mu      sync.Mutex
+	notify  map[os.Signal][]chan<- os.Signal // Channels by relayed signal
+	exited  chan struct{}                    // Closed when the server has stopped
+	stopped chan struct{}                    // Closed when svc.Run returns
+}
+
+// notifySignals relays the given signals to c. The stop and reload
+// requests of the service manager are relayed as SIGTERM and SIGHUP.
+// A service ignores console events: the runtime reports a user logging
+// off as SIGTERM.
+func notifySignals(c chan<- os.Signal, sigs ...os.Signal) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if service.exited == nil {
+		signal.Notify(c, sigs...)
+	}
+	if service.notify == nil {
+		service.notify = make(map[os.Signal][]chan<- os.Signal)
+	}
+	for _, sig := range sigs {
+		service.notify[sig] = append(service.notify[sig], c)
+	}
+}
+
+// relaySignal sends sig to the channels registered for it, without
+// blocking, like the signal package
+func relaySignal(sig os.Signal) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	for _, c := range service.notify[sig] {
+		select {
+		case c <- sig:
+		default:
+		}
+	}
+}
+
+// startService reports whether the process was started by the service
+// manager, and if so sends its output to the service's log file and
+// starts reporting its state
+func startService(name string) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, err
+	}
+	if err := redirectServiceLog(name); err != nil {
+		return true, err
+	}
+
+	service.exited = make(chan struct{})
+	service.stopped = make(chan struct{})
+	go func() {
+		defer close(service.stopped)
+		if err := svc.Run(name, serviceHandler{}); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Windows service %s failed: %v\n", name, err)
+			os.Exit(1)
+		}
+	}()
+	return true, nil
+}
+
+// stopService reports to the service manager that the service has
+// stopped. A process that exits without it is reported as failed, and
+// restarted by the recovery actions.
+func stopService() {
+	if service.exited == nil {
+		return
+	}
+	close(service.exited)
+	select {
+	case <-service.stopped:
+	case <-time.After(serviceExitTimeout):
+	}
+}
+
+// serviceLogPath returns the log file of a service. Services have no
+// console, so their output goes there.
+func serviceLogPath(name string) string {
+	return filepath.Join(config.SystemConfigDir(), "postgres-mcp", "logs", name+".log")
+}
+
+// redirectServiceLog appends the process's standard output and error,
+// including the runtime's crash reports, to the service's log file
+func redirectServiceLog(name string) error {
+	path := serviceLogPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open service log file: %w", err)
+	}
+	if err := windows.SetStdHandle(windows.STD_ERROR_HANDLE, windows.Handle(f.Fd())); err != nil {
+		return fmt.Errorf("failed to redirect standard error: %w", err)
+	}
+	if err := windows.SetStdHandle(windows.STD_OUTPUT_HANDLE, windows.Handle(f.Fd())); err != nil {
+		return fmt.Errorf("failed to redirect standard output: %w", err)
+	}
+	os.Stderr = f
+	os.Stdout = f
+	return nil
+}
+
+// serviceHandler answers the requests of the service manager
+type serviceHandler struct{}
+
+// Execute reports the service as running until the server stops, and
+// turns stop and parameter change requests into SIGTERM and SIGHUP, so
+// that they shut down gracefully and reload the configuration
+func (serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{
+		State:   svc.Running,
+		Accepts: svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange,
+	}
+	for {
+		select {
+		case <-service.exited:
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(serviceStopWaitHint / time.Millisecond)}
+				relaySignal(syscall.SIGTERM)
+			case svc.ParamChange:
+				status <- request.CurrentStatus
+				relaySignal(syscall.SIGHUP)
+			}
+		}
+	}
+}
+
+// installService registers the server as a Windows service that starts
+// with the system, runs execPath with args and is restarted if it fails
+func installService(name, execPath string, args []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager (run as Administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s is already installed", name)
+	}
+
+	s, err := m.CreateService(name, execPath, mgr.Config{
+		DisplayName:      serviceDisplayName,
+		Description:      serviceDescription,
+		StartType:        mgr.StartAutomatic,
+		DelayedAutoStart: true, // Let local databases start first
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("failed to create service %s: %w", name, err)
+	}
+	defer s.Close()
+
+	err = s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 30 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to set the recovery actions of service %s: %w", name, err)
+	}
+	return nil
+}
+
+// uninstallService stops the Windows service and removes it
+func uninstallService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager (run as Administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", name, err)
+	}
+	defer s.Close()
+
+	// The service is removed once it has stopped
+	if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+		if _, err := s.Control(svc.Stop); err != nil {
+			return fmt.Errorf("failed to stop service %s: %w", name, err)
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("failed to remove service %s: %w", name, err)
+	}
+	return nil
+}
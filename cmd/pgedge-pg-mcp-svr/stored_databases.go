@@ -0,0 +1,35 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/dbstore"
+)
+
+// mergeStoredDatabases adds the databases registered with
+// add_database_connection to the configured ones. A store that cannot be
+// read is reported and the configured databases are used alone.
+func mergeStoredDatabases(cfg *config.Config) {
+	store := dbstore.NewStoreFromConfig(cfg)
+	merged, err := store.Merge(cfg.Databases)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Added databases not loaded: %v\n", err)
+		return
+	}
+	if added := len(merged) - len(cfg.Databases); added > 0 {
+		fmt.Fprintf(os.Stderr, "Added databases: %d loaded from %s\n", added, store.Path)
+	}
+	cfg.Databases = merged
+}
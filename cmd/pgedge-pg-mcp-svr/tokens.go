@@ -22,8 +22,9 @@ import (
 
 // addTokenCommand handles the add-token command
 // database parameter specifies the database this token is bound to (empty = prompt or use first)
+// tools limits the token to the listed tools (empty = all tools)
 // availableDatabases is the list of configured database names for interactive selection
-func addTokenCommand(tokenFile, annotation, database string, expiresIn time.Duration, availableDatabases []string) error {
+func addTokenCommand(tokenFile, annotation, database string, tools []string, expiresIn time.Duration, availableDatabases []string) error {
 	// Load or create token store
 	var store *auth.TokenStore
 	var err error
@@ -127,6 +128,11 @@ func addTokenCommand(tokenFile, annotation, database string, expiresIn time.Dura
 	if err := store.AddToken(tokenID, hash, annotation, expiresAt, database); err != nil {
 		return fmt.Errorf("failed to add token: %w", err)
 	}
+	if len(tools) > 0 {
+		if err := store.SetAllowedTools(tokenID, tools); err != nil {
+			return fmt.Errorf("failed to set token tools: %w", err)
+		}
+	}
 
 	// Save token store
 	if err := auth.SaveTokenStore(tokenFile, store); err != nil {
@@ -148,6 +154,9 @@ func addTokenCommand(tokenFile, annotation, database string, expiresIn time.Dura
 	} else {
 		fmt.Println("Database: (first configured)")
 	}
+	if len(tools) > 0 {
+		fmt.Printf("Tools: %s\n", strings.Join(tools, ", "))
+	}
 	if expiresAt != nil {
 		fmt.Printf("Expires: %s\n", expiresAt.Format(time.RFC3339))
 	} else {
@@ -237,12 +246,26 @@ func listTokensCommand(tokenFile string) error {
 			expiryStr,
 			status,
 			annotation)
+		if len(token.Tools) > 0 {
+			fmt.Printf("%-20s Tools: %s\n", "", strings.Join(token.Tools, ", "))
+		}
 	}
 	fmt.Println(strings.Repeat("=", 100) + "\n")
 
 	return nil
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // parseDuration parses durations like "30d", "1y", "2w", "12h"
 func parseDuration(s string) (time.Duration, error) {
 	if len(s) < 2 {
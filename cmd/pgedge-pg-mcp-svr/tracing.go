@@ -0,0 +1,41 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/tracing"
+)
+
+// tracingShutdownTimeout bounds the export of the last spans on exit
+const tracingShutdownTimeout = 5 * time.Second
+
+// startTracing sets the tracer that records the spans of MCP requests,
+// tool calls, SQL queries and LLM calls. The returned function exports the
+// spans still waiting and stops the tracer.
+func startTracing(cfg *config.Config) func() {
+	tracer := tracing.New(cfg.Tracing.TracerConfig(mcp.ServerVersion))
+	tracing.SetShared(tracer)
+	return func() {
+		tracing.SetShared(nil)
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := tracer.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Failed to export the last trace spans: %v\n", err)
+		}
+	}
+}
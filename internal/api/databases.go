@@ -27,6 +27,11 @@ type DatabaseInfo struct {
 	Database string `json:"database"`
 	User     string `json:"user"`
 	SSLMode  string `json:"sslmode"`
+
+	Environment string   `json:"environment,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+	ReadOnly    bool     `json:"read_only,omitempty"` // Writes are disabled
 }
 
 // ListDatabasesResponse is the response for GET /api/databases
@@ -103,6 +108,11 @@ func (h *DatabaseHandler) HandleListDatabases(w http.ResponseWriter, r *http.Req
 			Database: cfg.Database,
 			User:     cfg.User,
 			SSLMode:  cfg.SSLMode,
+
+			Environment: cfg.Environment,
+			Tags:        cfg.Tags,
+			Description: cfg.Description,
+			ReadOnly:    !cfg.WritesAllowed(),
 		})
 	}
 
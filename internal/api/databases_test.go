@@ -33,6 +33,10 @@ func createTestClientManager() *database.ClientManager {
 			Database: "db1",
 			User:     "user1",
 			SSLMode:  "disable",
+
+			Environment: "prod",
+			Tags:        []string{"eu"},
+			Description: "Orders",
 		},
 		{
 			Name:     "testdb2",
@@ -90,6 +94,16 @@ func TestHandleListDatabases_Success(t *testing.T) {
 	found := make(map[string]bool)
 	for _, db := range response.Databases {
 		found[db.Name] = true
+		switch db.Name {
+		case "testdb1":
+			if db.Environment != "prod" || len(db.Tags) != 1 || db.Description != "Orders" || !db.ReadOnly {
+				t.Errorf("expected the labels of testdb1 and writes disabled, got %+v", db)
+			}
+		case "testdb2":
+			if db.Environment != "" || db.ReadOnly {
+				t.Errorf("expected testdb2 without labels, got %+v", db)
+			}
+		}
 	}
 	if !found["testdb1"] || !found["testdb2"] {
 		t.Error("expected both testdb1 and testdb2 in response")
@@ -0,0 +1,196 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/scheduler"
+)
+
+// JobsPath is the path of the scheduled jobs API
+const JobsPath = "/api/jobs"
+
+// ListJobsResponse is the response for GET /api/jobs
+type ListJobsResponse struct {
+	Jobs []scheduler.JobStatus `json:"jobs"`
+}
+
+// JobResponse is the response for GET /api/jobs/{name} and POST /api/jobs
+type JobResponse struct {
+	Job  scheduler.JobStatus `json:"job"`
+	Runs []scheduler.Run     `json:"runs,omitempty"`
+}
+
+// JobErrorResponse is the response for a failed jobs API request
+type JobErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// JobsHandler handles the scheduled jobs API:
+//
+//	GET    /api/jobs                 list the jobs
+//	POST   /api/jobs                 add or replace a job
+//	GET    /api/jobs/{name}?limit=n  a job with its recent runs
+//	DELETE /api/jobs/{name}          remove a job added through the API
+//	POST   /api/jobs/{name}/run      run a job now
+//
+// Listing is open to every authenticated caller; changes require canManage.
+type JobsHandler struct {
+	scheduler      *scheduler.Scheduler
+	canManage      func(ctx context.Context) bool
+	databaseExists func(name string) bool
+}
+
+// NewJobsHandler creates a jobs API handler. canManage reports whether the
+// caller may add, remove and run jobs; databaseExists checks the database
+// of a new job.
+func NewJobsHandler(s *scheduler.Scheduler, canManage func(ctx context.Context) bool, databaseExists func(name string) bool) *JobsHandler {
+	return &JobsHandler{scheduler: s, canManage: canManage, databaseExists: databaseExists}
+}
+
+// RegisterRoutes registers the jobs API with the given mux
+func (h *JobsHandler) RegisterRoutes(mux *http.ServeMux, authWrapper func(http.HandlerFunc) http.HandlerFunc) {
+	mux.HandleFunc(JobsPath, authWrapper(h.HandleJobs))
+	mux.HandleFunc(JobsPath+"/", authWrapper(h.HandleJob))
+}
+
+// HandleJobs handles GET and POST /api/jobs
+func (h *JobsHandler) HandleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJobsJSON(w, http.StatusOK, ListJobsResponse{Jobs: h.scheduler.Jobs()})
+
+	case http.MethodPost:
+		if !h.canManage(r.Context()) {
+			writeJobsError(w, http.StatusForbidden, "Managing jobs requires an API token whose allowed_tools include manage_jobs")
+			return
+		}
+		var job scheduler.Job
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&job); err != nil {
+			writeJobsError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		if err := job.Validate(); err != nil {
+			writeJobsError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if job.Database != "" && !h.databaseExists(job.Database) {
+			writeJobsError(w, http.StatusBadRequest, fmt.Sprintf("Database %q is not configured", job.Database))
+			return
+		}
+		if err := h.scheduler.SaveJob(job); err != nil {
+			writeJobsError(w, jobErrorStatus(err), err.Error())
+			return
+		}
+		status, _ := h.scheduler.Job(job.Name)
+		writeJobsJSON(w, http.StatusCreated, JobResponse{Job: status})
+
+	default:
+		writeJobsError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleJob handles the requests for one job
+func (h *JobsHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, JobsPath+"/"), "/")
+	if name == "" {
+		writeJobsError(w, http.StatusNotFound, "Job name is required")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		status, ok := h.scheduler.Job(name)
+		if !ok {
+			writeJobsError(w, http.StatusNotFound, fmt.Sprintf("Job %q not found", name))
+			return
+		}
+		limit := 20
+		if value := r.URL.Query().Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				writeJobsError(w, http.StatusBadRequest, "limit must be a positive number")
+				return
+			}
+			limit = n
+		}
+		runs, err := h.scheduler.History(name, limit)
+		if err != nil {
+			writeJobsError(w, jobErrorStatus(err), err.Error())
+			return
+		}
+		writeJobsJSON(w, http.StatusOK, JobResponse{Job: status, Runs: runs})
+
+	case action == "" && r.Method == http.MethodDelete:
+		if !h.canManage(r.Context()) {
+			writeJobsError(w, http.StatusForbidden, "Managing jobs requires an API token whose allowed_tools include manage_jobs")
+			return
+		}
+		if err := h.scheduler.DeleteJob(name); err != nil {
+			writeJobsError(w, jobErrorStatus(err), err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case action == "run" && r.Method == http.MethodPost:
+		if !h.canManage(r.Context()) {
+			writeJobsError(w, http.StatusForbidden, "Managing jobs requires an API token whose allowed_tools include manage_jobs")
+			return
+		}
+		if err := h.scheduler.RunNow(name); err != nil {
+			writeJobsError(w, jobErrorStatus(err), err.Error())
+			return
+		}
+		status, _ := h.scheduler.Job(name)
+		writeJobsJSON(w, http.StatusAccepted, JobResponse{Job: status})
+
+	case action == "" || action == "run":
+		writeJobsError(w, http.StatusMethodNotAllowed, "Method not allowed")
+
+	default:
+		writeJobsError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// jobErrorStatus maps a scheduler error to an HTTP status
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, scheduler.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, scheduler.ErrConfigJob), errors.Is(err, scheduler.ErrRunning):
+		return http.StatusConflict
+	case errors.Is(err, scheduler.ErrNotStarted):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeJobsJSON writes a JSON response
+func writeJobsJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	//nolint:errcheck // Error would only occur if connection is closed
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeJobsError writes a JSON error response
+func writeJobsError(w http.ResponseWriter, status int, message string) {
+	writeJobsJSON(w, status, JobErrorResponse{Error: message})
+}
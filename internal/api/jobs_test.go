@@ -0,0 +1,134 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/scheduler"
+)
+
+func newTestJobsHandler(t *testing.T, canManage bool) *JobsHandler {
+	t.Helper()
+	s, err := scheduler.New(scheduler.Config{
+		Jobs:  []scheduler.Job{{Name: "from_config", Schedule: "@daily", SQL: "SELECT 1"}},
+		Store: scheduler.NewStore(t.TempDir(), 0),
+		Execute: func(ctx context.Context, database, tool string, args map[string]interface{}) (string, bool, error) {
+			return "", false, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("scheduler.New failed: %v", err)
+	}
+	return NewJobsHandler(s,
+		func(ctx context.Context) bool { return canManage },
+		func(name string) bool { return name == "testdb1" })
+}
+
+func serveJobs(h *JobsHandler, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux, func(handler http.HandlerFunc) http.HandlerFunc { return handler })
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestJobsHandler(t *testing.T) {
+	h := newTestJobsHandler(t, true)
+
+	w := serveJobs(h, http.MethodPost, JobsPath,
+		`{"name": "bloat", "schedule": "0 2 * * *", "database": "testdb1", "sql": "SELECT 1", "webhook": "https://hooks.example.com/x"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, body %s", w.Code, w.Body.String())
+	}
+	var created JobResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if created.Job.Job.Source != scheduler.SourceAPI || created.Job.NextRun.IsZero() {
+		t.Errorf("unexpected job: %+v", created.Job)
+	}
+
+	w = serveJobs(h, http.MethodGet, JobsPath, "")
+	var list ListJobsResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if w.Code != http.StatusOK || len(list.Jobs) != 2 {
+		t.Errorf("GET status = %d, jobs %+v", w.Code, list.Jobs)
+	}
+
+	if w = serveJobs(h, http.MethodGet, JobsPath+"/bloat?limit=5", ""); w.Code != http.StatusOK {
+		t.Errorf("GET job status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w = serveJobs(h, http.MethodPost, JobsPath+"/bloat/run", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("run before the scheduler started: status = %d, want 503", w.Code)
+	}
+	if w = serveJobs(h, http.MethodDelete, JobsPath+"/from_config", ""); w.Code != http.StatusConflict {
+		t.Errorf("DELETE configured job status = %d, want 409", w.Code)
+	}
+	if w = serveJobs(h, http.MethodDelete, JobsPath+"/bloat", ""); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w = serveJobs(h, http.MethodGet, JobsPath+"/bloat", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted job status = %d, want 404", w.Code)
+	}
+}
+
+func TestJobsHandlerErrors(t *testing.T) {
+	h := newTestJobsHandler(t, true)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"invalid json", http.MethodPost, JobsPath, `{`, http.StatusBadRequest},
+		{"unknown field", http.MethodPost, JobsPath, `{"name": "a", "schedule": "@daily", "sql": "SELECT 1", "cron": "x"}`, http.StatusBadRequest},
+		{"invalid schedule", http.MethodPost, JobsPath, `{"name": "a", "schedule": "nightly", "sql": "SELECT 1"}`, http.StatusBadRequest},
+		{"unknown database", http.MethodPost, JobsPath, `{"name": "a", "schedule": "@daily", "database": "other", "sql": "SELECT 1"}`, http.StatusBadRequest},
+		{"replace configured job", http.MethodPost, JobsPath, `{"name": "from_config", "schedule": "@daily", "sql": "SELECT 2"}`, http.StatusConflict},
+		{"bad limit", http.MethodGet, JobsPath + "/from_config?limit=0", "", http.StatusBadRequest},
+		{"missing job", http.MethodPost, JobsPath + "/missing/run", "", http.StatusNotFound},
+		{"wrong method", http.MethodPut, JobsPath, "", http.StatusMethodNotAllowed},
+		{"unknown action", http.MethodPost, JobsPath + "/from_config/stop", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := serveJobs(h, tt.method, tt.path, tt.body); w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d (body %s)", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestJobsHandlerRequiresManage(t *testing.T) {
+	h := newTestJobsHandler(t, false)
+
+	if w := serveJobs(h, http.MethodGet, JobsPath, ""); w.Code != http.StatusOK {
+		t.Errorf("GET status = %d, want 200", w.Code)
+	}
+	for _, req := range [][2]string{
+		{http.MethodPost, JobsPath},
+		{http.MethodDelete, JobsPath + "/from_config"},
+		{http.MethodPost, JobsPath + "/from_config/run"},
+	} {
+		w := serveJobs(h, req[0], req[1], `{"name": "a", "schedule": "@daily", "sql": "SELECT 1"}`)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: status = %d, want 403", req[0], req[1], w.Code)
+		}
+	}
+}
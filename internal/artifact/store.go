@@ -0,0 +1,217 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package artifact caches generated artifacts, such as reports, so that
+// repeated requests return the stored result instead of generating it again
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// ErrTooLarge is returned when an artifact is larger than the cache
+var ErrTooLarge = errors.New("artifact exceeds the cache size limit")
+
+var (
+	// validKind matches artifact kinds, which prefix the file names
+	validKind = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+	// artifactName matches the names of files created by the store
+	artifactName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}-[0-9a-f]{64}$`)
+)
+
+// Store is a content-addressed cache of artifacts in a directory. An
+// artifact's key is a hash of everything it was generated from, so a stored
+// artifact is only reused for identical requests.
+type Store struct {
+	Dir      string
+	MaxAge   time.Duration // Age after which artifacts are not reused and are deleted
+	MaxBytes int64         // Total size of the stored artifacts (0 = unlimited)
+}
+
+// NewStoreFromConfig returns the store in the artifacts directory under the
+// configured data directory, or nil if the cache is disabled
+func NewStoreFromConfig(cfg *config.Config) *Store {
+	if !cfg.Artifacts.CacheEnabled() || cfg.Artifacts.MaxAgeMinutes <= 0 {
+		return nil
+	}
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		execPath, err := os.Executable()
+		if err != nil {
+			execPath = "."
+		}
+		dataDir = config.GetDefaultDataDir(execPath)
+	}
+	return &Store{
+		Dir:      filepath.Join(dataDir, "artifacts"),
+		MaxAge:   time.Duration(cfg.Artifacts.MaxAgeMinutes) * time.Minute,
+		MaxBytes: int64(cfg.Artifacts.MaxSizeMB) << 20,
+	}
+}
+
+// Key returns the key of an artifact generated from the given inputs while
+// the metadata had the given version; reloading the metadata changes the
+// key, which invalidates artifacts derived from it
+func Key(kind string, metadataVersion uint64, inputs ...string) string {
+	h := sha256.New()
+	var n [8]byte
+	write := func(s string) {
+		binary.BigEndian.PutUint64(n[:], uint64(len(s)))
+		h.Write(n[:])
+		h.Write([]byte(s))
+	}
+	write(kind)
+	binary.BigEndian.PutUint64(n[:], metadataVersion)
+	h.Write(n[:])
+	for _, input := range inputs {
+		write(input)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Artifact is a stored artifact
+type Artifact struct {
+	Content   string
+	CreatedAt time.Time
+}
+
+// path returns the file of an artifact, or an error for invalid names
+func (s *Store) path(kind, key string) (string, error) {
+	name := kind + "-" + key
+	if !validKind.MatchString(kind) || !artifactName.MatchString(name) {
+		return "", fmt.Errorf("invalid artifact %q", name)
+	}
+	return filepath.Join(s.Dir, name), nil
+}
+
+// Get returns a stored artifact that is younger than the maximum age
+func (s *Store) Get(kind, key string, now time.Time) (*Artifact, bool) {
+	path, err := s.path(kind, key)
+	if err != nil {
+		return nil, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || now.Sub(info.ModTime()) > s.MaxAge {
+		return nil, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return &Artifact{Content: string(content), CreatedAt: info.ModTime()}, true
+}
+
+// Put stores an artifact, replacing one with the same key, after deleting
+// expired artifacts and, if needed, the oldest ones to stay within the size
+// limit
+func (s *Store) Put(kind, key, content string, now time.Time) error {
+	path, err := s.path(kind, key)
+	if err != nil {
+		return err
+	}
+	if s.MaxBytes > 0 && int64(len(content)) > s.MaxBytes {
+		return ErrTooLarge
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	s.cleanup(now, int64(len(content)))
+
+	// Write to a temporary file so readers never see a partial artifact
+	f, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create artifact: %w", err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()           //nolint:errcheck // the file is being discarded
+		_ = os.Remove(f.Name()) //nolint:errcheck // best effort cleanup
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name()) //nolint:errcheck // best effort cleanup
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.Chtimes(f.Name(), now, now); err != nil {
+		_ = os.Remove(f.Name()) //nolint:errcheck // best effort cleanup
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		_ = os.Remove(f.Name()) //nolint:errcheck // best effort cleanup
+		return fmt.Errorf("failed to store artifact: %w", err)
+	}
+	return nil
+}
+
+// Cleanup deletes expired artifacts, then the oldest ones until the rest
+// fit in the size limit, and returns the number deleted
+func (s *Store) Cleanup(now time.Time) int {
+	return s.cleanup(now, 0)
+}
+
+// cleanup is Cleanup leaving room for an artifact of the given size
+func (s *Store) cleanup(now time.Time, reserve int64) int {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return 0
+	}
+
+	type stored struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var kept []stored
+	var total int64
+	deleted := 0
+	for _, entry := range entries {
+		if !artifactName.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if now.Sub(info.ModTime()) > s.MaxAge {
+			if os.Remove(filepath.Join(s.Dir, entry.Name())) == nil {
+				deleted++
+			}
+			continue
+		}
+		kept = append(kept, stored{entry.Name(), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	if s.MaxBytes <= 0 {
+		return deleted
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].modTime.Before(kept[j].modTime) })
+	for _, a := range kept {
+		if total+reserve <= s.MaxBytes {
+			break
+		}
+		if os.Remove(filepath.Join(s.Dir, a.name)) == nil {
+			deleted++
+			total -= a.size
+		}
+	}
+	return deleted
+}
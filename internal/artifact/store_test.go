@@ -0,0 +1,123 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package artifact
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+func TestKey(t *testing.T) {
+	key := Key("health_report", 3, "postgres://db", "public")
+	if len(key) != 64 {
+		t.Fatalf("Key() = %q, want a SHA-256 hex digest", key)
+	}
+	if key != Key("health_report", 3, "postgres://db", "public") {
+		t.Error("Key() is not deterministic")
+	}
+	for _, other := range []string{
+		Key("health_report", 4, "postgres://db", "public"),
+		Key("schema_doc", 3, "postgres://db", "public"),
+		Key("health_report", 3, "postgres://db", "publi", "c"),
+		Key("health_report", 3, "postgres://dbpublic"),
+	} {
+		if other == key {
+			t.Error("different inputs produced the same key")
+		}
+	}
+}
+
+func TestStoreGetPut(t *testing.T) {
+	store := &Store{Dir: filepath.Join(t.TempDir(), "artifacts"), MaxAge: 10 * time.Minute}
+	now := time.Now()
+	key := Key("health_report", 1, "db")
+
+	if _, ok := store.Get("health_report", key, now); ok {
+		t.Fatal("Get() found an artifact in an empty store")
+	}
+	if err := store.Put("health_report", key, "report", now); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	a, ok := store.Get("health_report", key, now.Add(time.Minute))
+	if !ok || a.Content != "report" {
+		t.Fatalf("Get() = %+v, %v; want the stored report", a, ok)
+	}
+	if a.CreatedAt.Sub(now).Abs() > time.Second {
+		t.Errorf("CreatedAt = %v, want %v", a.CreatedAt, now)
+	}
+	if _, ok := store.Get("health_report", key, now.Add(11*time.Minute)); ok {
+		t.Error("Get() returned an expired artifact")
+	}
+	if _, ok := store.Get("schema_doc", key, now); ok {
+		t.Error("Get() returned an artifact of another kind")
+	}
+
+	if err := store.Put("../evil", key, "x", now); err == nil {
+		t.Error("Put() accepted an invalid kind")
+	}
+	if err := store.Put("health_report", "../../etc/passwd", "x", now); err == nil {
+		t.Error("Put() accepted an invalid key")
+	}
+}
+
+func TestStoreCleanup(t *testing.T) {
+	store := &Store{Dir: t.TempDir(), MaxAge: time.Hour, MaxBytes: 10}
+	now := time.Now()
+	old, older, newest := Key("r", 1, "old"), Key("r", 1, "older"), Key("r", 1, "new")
+
+	if err := store.Put("r", older, "aaaa", now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put("r", old, "bbbbbb", now.Add(-time.Minute)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	other := filepath.Join(store.Dir, "notes.txt")
+	if err := os.WriteFile(other, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The expired artifact is deleted, then the oldest to make room
+	if err := store.Put("r", newest, "cccccc", now); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	for key, want := range map[string]bool{older: false, old: false, newest: true} {
+		if _, ok := store.Get("r", key, now); ok != want {
+			t.Errorf("Get(%s...) found = %v, want %v", key[:8], ok, want)
+		}
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Error("Cleanup() deleted a file the store did not create")
+	}
+
+	if err := store.Put("r", newest, "01234567890", now); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Put() error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestNewStoreFromConfig(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{DataDir: "/data", Artifacts: config.ArtifactsConfig{MaxAgeMinutes: 5, MaxSizeMB: 2}}
+	store := NewStoreFromConfig(cfg)
+	if store == nil || store.Dir != filepath.Join("/data", "artifacts") || store.MaxAge != 5*time.Minute || store.MaxBytes != 2<<20 {
+		t.Errorf("NewStoreFromConfig() = %+v", store)
+	}
+
+	cfg.Artifacts.Cache = &disabled
+	if store := NewStoreFromConfig(cfg); store != nil {
+		t.Errorf("NewStoreFromConfig() = %+v, want nil when the cache is disabled", store)
+	}
+}
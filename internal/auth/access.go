@@ -108,6 +108,33 @@ func (dac *DatabaseAccessChecker) GetBoundDatabase(ctx context.Context) string {
 	return token.Database
 }
 
+// CanUseTool checks whether the current request context may list and call
+// a tool. Only API tokens with allowed_tools are limited; session users,
+// STDIO mode and --no-auth mode may use every tool, except that admin tools
+// are only available to tokens that name them.
+func (dac *DatabaseAccessChecker) CanUseTool(ctx context.Context, name string) bool {
+	if dac.isSTDIO || !dac.authEnabled || dac.tokenStore == nil {
+		return true
+	}
+	if !IsAPITokenFromContext(ctx) {
+		return !IsAdminTool(name)
+	}
+
+	// A token removed since the request was authenticated may use nothing
+	token := dac.tokenStore.GetTokenByHash(GetTokenHashFromContext(ctx))
+	return token != nil && token.AllowsTool(name)
+}
+
+// IsReadOnlyTool checks whether the current request context may use a tool
+// only with the read scope. Only API tokens are limited this way.
+func (dac *DatabaseAccessChecker) IsReadOnlyTool(ctx context.Context, name string) bool {
+	if dac.isSTDIO || !dac.authEnabled || dac.tokenStore == nil || !IsAPITokenFromContext(ctx) {
+		return false
+	}
+	token := dac.tokenStore.GetTokenByHash(GetTokenHashFromContext(ctx))
+	return token != nil && token.ReadOnlyTool(name)
+}
+
 // GetAccessibleDatabases returns the list of databases accessible to the current context
 // For API tokens, returns only the bound database (or first if unbound)
 // For session users, filters by available_to_users
@@ -18,10 +18,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"pgedge-postgres-mcp/internal/config"
 )
 
 // Token represents an API token with metadata
@@ -31,6 +34,51 @@ type Token struct {
 	Annotation string     `yaml:"annotation"`         // User note/description
 	CreatedAt  time.Time  `yaml:"created_at"`         // When the token was created
 	Database   string     `yaml:"database,omitempty"` // Bound database name (empty = first configured database)
+
+	// Tools the token may list and call (empty = all). A "(read)" suffix, as
+	// in "query_database(read)", refuses calls that can modify the database.
+	AllowedTools []string `yaml:"allowed_tools,omitempty"`
+}
+
+// readOnlyScope qualifies a tool in AllowedTools that may only read data
+const readOnlyScope = "(read)"
+
+// adminTools change the server itself, so a token may only use them when
+// its allowed_tools names them, and session users may not use them.
+// manage_jobs is not a tool: it grants changes through /api/jobs.
+var adminTools = map[string]bool{"add_database_connection": true, "manage_jobs": true}
+
+// IsAdminTool reports whether a tool must be granted explicitly
+func IsAdminTool(name string) bool {
+	return adminTools[name]
+}
+
+// AllowsTool reports whether the token may list and call a tool
+func (t *Token) AllowsTool(name string) bool {
+	if len(t.AllowedTools) == 0 {
+		return !adminTools[name]
+	}
+	for _, scope := range t.AllowedTools {
+		if strings.TrimSuffix(strings.TrimSpace(scope), readOnlyScope) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadOnlyTool reports whether the token may use a tool only with the read
+// scope, so its calls that can modify the database must be refused
+func (t *Token) ReadOnlyTool(name string) bool {
+	readOnly := false
+	for _, scope := range t.AllowedTools {
+		switch strings.TrimSpace(scope) {
+		case name:
+			return false
+		case name + readOnlyScope:
+			readOnly = true
+		}
+	}
+	return readOnly
 }
 
 // TokenStore manages API tokens
@@ -39,6 +87,7 @@ type TokenStore struct {
 	Tokens  map[string]*Token `yaml:"tokens"` // key is a unique identifier
 	path    string            // File path for auto-reloading
 	watcher *FileWatcher      // File watcher for auto-reloading
+	loadErr error             // Why the last reload failed, nil if it succeeded
 }
 
 // GenerateToken creates a new random API token
@@ -81,8 +130,26 @@ func LoadTokenStore(path string) (*TokenStore, error) {
 	return &store, nil
 }
 
-// Reload reloads the token store from disk
+// Reload reloads the token store from disk. On failure the store keeps its
+// tokens and LoadError reports the error until a reload succeeds.
 func (s *TokenStore) Reload() error {
+	err := s.reload()
+	s.mu.Lock()
+	s.loadErr = err
+	s.mu.Unlock()
+	return err
+}
+
+// LoadError returns why the token file could not be reloaded, or nil if
+// the store holds the file's current tokens
+func (s *TokenStore) LoadError() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loadErr
+}
+
+// reload reads and parses the token file and replaces the store's tokens
+func (s *TokenStore) reload() error {
 	if s.path == "" {
 		return fmt.Errorf("no path set for token store")
 	}
@@ -154,6 +221,20 @@ func (s *TokenStore) AddToken(tokenID, hash, annotation string, expiresAt *time.
 	return nil
 }
 
+// SetAllowedTools limits the tools a token may list and call; an empty
+// list allows all tools
+func (s *TokenStore) SetAllowedTools(tokenID string, tools []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, exists := s.Tokens[tokenID]
+	if !exists {
+		return fmt.Errorf("token with ID '%s' not found", tokenID)
+	}
+	token.AllowedTools = tools
+	return nil
+}
+
 // GetTokenByHash returns the token with the given hash, or nil if not found
 func (s *TokenStore) GetTokenByHash(hash string) *Token {
 	s.mu.RLock()
@@ -245,6 +326,7 @@ func (s *TokenStore) ListTokens() []*TokenInfo {
 			CreatedAt:  token.CreatedAt,
 			Expired:    expired,
 			Database:   token.Database,
+			Tools:      token.AllowedTools,
 		})
 	}
 
@@ -259,13 +341,15 @@ type TokenInfo struct {
 	Annotation string
 	CreatedAt  time.Time
 	Expired    bool
-	Database   string // Bound database name (empty = first configured database)
+	Database   string   // Bound database name (empty = first configured database)
+	Tools      []string // Tools the token may use (empty = all)
 }
 
 // GetDefaultTokenPath returns the default token file path
-// Searches /etc/pgedge/postgres-mcp/ first, then binary directory
+// Searches the postgres-mcp subdirectory of the system configuration
+// directory first, then binary directory
 func GetDefaultTokenPath(binaryPath string) string {
-	systemPath := "/etc/pgedge/postgres-mcp/pgedge-postgres-mcp-tokens.yaml"
+	systemPath := filepath.Join(config.SystemConfigDir(), "postgres-mcp", "pgedge-postgres-mcp-tokens.yaml")
 	if _, err := os.Stat(systemPath); err == nil {
 		return systemPath
 	}
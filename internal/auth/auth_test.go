@@ -11,6 +11,7 @@
 package auth
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -477,6 +478,123 @@ func TestSaveAndLoadTokenStore(t *testing.T) {
 			t.Fatal("Expected error for non-existent file")
 		}
 	})
+
+	t.Run("failed reload keeps tokens and reports the error", func(t *testing.T) {
+		tokenFile := filepath.Join(t.TempDir(), "tokens.yaml")
+		store := InitializeTokenStore()
+		store.AddToken("token-123", "abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890", "note", nil, "")
+		if err := SaveTokenStore(tokenFile, store); err != nil {
+			t.Fatalf("Failed to save token store: %v", err)
+		}
+		loadedStore, err := LoadTokenStore(tokenFile)
+		if err != nil {
+			t.Fatalf("Failed to load token store: %v", err)
+		}
+
+		if err := os.WriteFile(tokenFile, []byte("tokens: [unclosed"), 0600); err != nil {
+			t.Fatalf("Failed to write token file: %v", err)
+		}
+		if err := loadedStore.Reload(); err == nil {
+			t.Fatal("Expected error for invalid token file")
+		}
+		if loadedStore.LoadError() == nil || len(loadedStore.Tokens) != 1 {
+			t.Errorf("Expected the old tokens and a load error, got %d tokens, error %v", len(loadedStore.Tokens), loadedStore.LoadError())
+		}
+
+		if err := SaveTokenStore(tokenFile, store); err != nil {
+			t.Fatalf("Failed to save token store: %v", err)
+		}
+		if err := loadedStore.Reload(); err != nil || loadedStore.LoadError() != nil {
+			t.Errorf("Expected a successful reload to clear the error, got %v", loadedStore.LoadError())
+		}
+	})
+}
+
+func TestTokenAllowsTool(t *testing.T) {
+	unscoped := &Token{}
+	if !unscoped.AllowsTool("query_database") {
+		t.Error("expected a token without allowed tools to allow every tool")
+	}
+
+	scoped := &Token{AllowedTools: []string{"get_schema_info", "query_database(read)"}}
+	for name, want := range map[string]bool{
+		"get_schema_info":   true,
+		"query_database":    true,
+		"similarity_search": false,
+	} {
+		if got := scoped.AllowsTool(name); got != want {
+			t.Errorf("AllowsTool(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	// The read scope limits a tool unless the tool is also named without it
+	if !scoped.ReadOnlyTool("query_database") || scoped.ReadOnlyTool("get_schema_info") || unscoped.ReadOnlyTool("query_database") {
+		t.Error("expected only query_database(read) to be read-only")
+	}
+	both := &Token{AllowedTools: []string{"query_database(read)", "query_database"}}
+	if both.ReadOnlyTool("query_database") {
+		t.Error("expected an unscoped grant to lift the read scope")
+	}
+
+	// Admin tools must be named
+	if unscoped.AllowsTool("add_database_connection") {
+		t.Error("expected a token without allowed tools not to allow admin tools")
+	}
+	admin := &Token{AllowedTools: []string{"add_database_connection"}}
+	if !admin.AllowsTool("add_database_connection") {
+		t.Error("expected a token naming an admin tool to allow it")
+	}
+	if unscoped.AllowsTool("manage_jobs") || admin.AllowsTool("manage_jobs") {
+		t.Error("expected manage_jobs to require an explicit grant")
+	}
+}
+
+func TestCanUseTool(t *testing.T) {
+	store := InitializeTokenStore()
+	store.AddToken("scoped", HashToken("scoped-token"), "", nil, "")
+	if err := store.SetAllowedTools("scoped", []string{"get_schema_info"}); err != nil {
+		t.Fatal(err)
+	}
+	store.AddToken("unscoped", HashToken("unscoped-token"), "", nil, "")
+	if err := store.SetAllowedTools("missing", nil); err == nil {
+		t.Error("expected an error for an unknown token")
+	}
+
+	tokenContext := func(token string) context.Context {
+		ctx := context.WithValue(context.Background(), TokenHashContextKey, HashToken(token))
+		return context.WithValue(ctx, IsAPITokenContextKey, true)
+	}
+	sessionContext := context.WithValue(context.Background(), UsernameContextKey, "alice")
+
+	checker := NewDatabaseAccessChecker(store, true, false)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		tool string
+		want bool
+	}{
+		{"allowed tool", tokenContext("scoped-token"), "get_schema_info", true},
+		{"other tool", tokenContext("scoped-token"), "query_database", false},
+		{"unscoped token", tokenContext("unscoped-token"), "query_database", true},
+		{"removed token", tokenContext("removed-token"), "get_schema_info", false},
+		{"session user", sessionContext, "query_database", true},
+		{"session user admin tool", sessionContext, "add_database_connection", false},
+		{"unscoped token admin tool", tokenContext("unscoped-token"), "add_database_connection", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checker.CanUseTool(tt.ctx, tt.tool); got != tt.want {
+				t.Errorf("CanUseTool() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !NewDatabaseAccessChecker(store, true, true).CanUseTool(tokenContext("scoped-token"), "query_database") {
+		t.Error("expected STDIO mode to allow every tool")
+	}
+	if !NewDatabaseAccessChecker(store, true, true).CanUseTool(sessionContext, "add_database_connection") {
+		t.Error("expected STDIO mode to allow admin tools")
+	}
 }
 
 func TestGetDefaultTokenPath(t *testing.T) {
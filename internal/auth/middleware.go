@@ -38,6 +38,12 @@ const (
 	// HealthCheckPath is the path for the health check endpoint (bypasses authentication)
 	HealthCheckPath = "/health"
 
+	// LivenessPath is the path for the liveness probe endpoint (bypasses authentication)
+	LivenessPath = "/healthz"
+
+	// ReadinessPath is the path for the readiness probe endpoint (bypasses authentication)
+	ReadinessPath = "/readyz"
+
 	// UserInfoPath is the path for the user info endpoint (bypasses auth to return auth status)
 	UserInfoPath = "/api/user/info"
 )
@@ -118,7 +124,7 @@ func AuthMiddleware(tokenStore *TokenStore, userStore *UserStore, enabled bool)
 
 			// Skip authentication for public endpoints (needed before login)
 			switch r.URL.Path {
-			case HealthCheckPath, UserInfoPath:
+			case HealthCheckPath, LivenessPath, ReadinessPath, UserInfoPath:
 				next.ServeHTTP(w, r)
 				return
 			}
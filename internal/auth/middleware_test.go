@@ -71,6 +71,15 @@ func TestAuthMiddleware_HealthCheck(t *testing.T) {
 	if body := rr.Body.String(); body != "healthy" {
 		t.Errorf("Expected 'healthy', got %q", body)
 	}
+
+	// The liveness and readiness probes also bypass auth
+	for _, path := range []string{LivenessPath, ReadinessPath} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status OK for %s, got %d", path, rr.Code)
+		}
+	}
 }
 
 // TestAuthMiddleware_MissingAuthHeader tests rejection of requests without Authorization header
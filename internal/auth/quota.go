@@ -0,0 +1,253 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// toolCallWindow is the sliding window for the tool calls limit
+	toolCallWindow = time.Minute
+	// rowWindow is the sliding window for the rows returned limit
+	rowWindow = time.Hour
+)
+
+// QuotaLimits holds per-token usage limits (0 = unlimited)
+type QuotaLimits struct {
+	ToolCallsPerMinute   int // Maximum tool calls per token per minute
+	RowsPerHour          int // Maximum rows returned per token per hour
+	MaxConcurrentQueries int // Maximum in-flight tool calls per token
+}
+
+// IsEnabled returns true if any limit is set
+func (l QuotaLimits) IsEnabled() bool {
+	return l.ToolCallsPerMinute > 0 || l.RowsPerHour > 0 || l.MaxConcurrentQueries > 0
+}
+
+// QuotaExceededError is returned when a token exceeds one of its limits
+// It is surfaced to MCP clients as a rate limit (HTTP 429 style) error
+type QuotaExceededError struct {
+	Limit      string        // Name of the limit that was exceeded
+	RetryAfter time.Duration // Suggested wait before retrying (0 = retry when a query finishes)
+}
+
+func (e *QuotaExceededError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limit exceeded: %s (retry after %d seconds)", e.Limit, retryAfterSeconds(e.RetryAfter))
+	}
+	return fmt.Sprintf("rate limit exceeded: %s", e.Limit)
+}
+
+// RetryAfterSeconds returns the retry delay rounded up to whole seconds
+func (e *QuotaExceededError) RetryAfterSeconds() int {
+	return retryAfterSeconds(e.RetryAfter)
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// rowEntry records rows returned by a single tool call
+type rowEntry struct {
+	at   time.Time
+	rows int
+}
+
+// QuotaLimiter enforces per-token tool usage limits
+// Like RateLimiter it uses sliding windows and cleans up old entries in the background
+type QuotaLimiter struct {
+	mu              sync.Mutex
+	limits          QuotaLimits
+	calls           map[string][]time.Time // token hash -> tool call timestamps
+	rows            map[string][]rowEntry  // token hash -> rows returned
+	active          map[string]int         // token hash -> in-flight calls
+	cleanupInterval time.Duration
+	stopCleanup     chan bool
+	now             func() time.Time // Clock (overridable in tests)
+}
+
+// NewQuotaLimiter creates a quota limiter with the given limits
+// cleanupInterval specifies how often to clean up old entries (0 = default of 1 minute)
+func NewQuotaLimiter(limits QuotaLimits, cleanupInterval ...time.Duration) *QuotaLimiter {
+	cleanup := time.Minute
+	if len(cleanupInterval) > 0 && cleanupInterval[0] > 0 {
+		cleanup = cleanupInterval[0]
+	}
+
+	q := &QuotaLimiter{
+		limits:          limits,
+		calls:           make(map[string][]time.Time),
+		rows:            make(map[string][]rowEntry),
+		active:          make(map[string]int),
+		cleanupInterval: cleanup,
+		stopCleanup:     make(chan bool),
+		now:             time.Now,
+	}
+
+	go q.cleanupLoop()
+
+	return q
+}
+
+// Limits returns the configured limits
+func (q *QuotaLimiter) Limits() QuotaLimits {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.limits
+}
+
+// SetLimits replaces the configured limits (e.g. on configuration reload)
+func (q *QuotaLimiter) SetLimits(limits QuotaLimits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limits = limits
+}
+
+// Acquire checks all limits for a token and, if allowed, records a tool call
+// and reserves a concurrency slot. The returned release function must be
+// called when the tool call completes.
+func (q *QuotaLimiter) Acquire(tokenHash string) (func(), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+
+	if q.limits.MaxConcurrentQueries > 0 && q.active[tokenHash] >= q.limits.MaxConcurrentQueries {
+		return nil, &QuotaExceededError{
+			Limit: fmt.Sprintf("%d concurrent queries per token", q.limits.MaxConcurrentQueries),
+		}
+	}
+
+	if q.limits.ToolCallsPerMinute > 0 {
+		calls := pruneTimes(q.calls[tokenHash], now.Add(-toolCallWindow))
+		q.calls[tokenHash] = calls
+		if len(calls) >= q.limits.ToolCallsPerMinute {
+			return nil, &QuotaExceededError{
+				Limit:      fmt.Sprintf("%d tool calls per minute", q.limits.ToolCallsPerMinute),
+				RetryAfter: calls[0].Add(toolCallWindow).Sub(now),
+			}
+		}
+	}
+
+	if q.limits.RowsPerHour > 0 {
+		entries := pruneRows(q.rows[tokenHash], now.Add(-rowWindow))
+		q.rows[tokenHash] = entries
+		total := 0
+		for _, e := range entries {
+			total += e.rows
+		}
+		if total >= q.limits.RowsPerHour {
+			return nil, &QuotaExceededError{
+				Limit:      fmt.Sprintf("%d rows per hour", q.limits.RowsPerHour),
+				RetryAfter: entries[0].at.Add(rowWindow).Sub(now),
+			}
+		}
+	}
+
+	if q.limits.ToolCallsPerMinute > 0 {
+		q.calls[tokenHash] = append(q.calls[tokenHash], now)
+	}
+	q.active[tokenHash]++
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			if q.active[tokenHash] <= 1 {
+				delete(q.active, tokenHash)
+			} else {
+				q.active[tokenHash]--
+			}
+		})
+	}
+	return release, nil
+}
+
+// RecordRows adds rows returned by a tool call to the token's hourly total
+func (q *QuotaLimiter) RecordRows(tokenHash string, rows int) {
+	if rows <= 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.limits.RowsPerHour <= 0 {
+		return
+	}
+	q.rows[tokenHash] = append(q.rows[tokenHash], rowEntry{at: q.now(), rows: rows})
+}
+
+// pruneTimes drops timestamps at or before the cutoff
+func pruneTimes(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// pruneRows drops row entries at or before the cutoff
+func pruneRows(entries []rowEntry, cutoff time.Time) []rowEntry {
+	i := 0
+	for i < len(entries) && !entries[i].at.After(cutoff) {
+		i++
+	}
+	return entries[i:]
+}
+
+// cleanupLoop periodically removes entries that are outside their windows
+func (q *QuotaLimiter) cleanupLoop() {
+	ticker := time.NewTicker(q.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.cleanup()
+		case <-q.stopCleanup:
+			return
+		}
+	}
+}
+
+// cleanup removes old entries for all tokens
+func (q *QuotaLimiter) cleanup() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	for token, calls := range q.calls {
+		if calls = pruneTimes(calls, now.Add(-toolCallWindow)); len(calls) > 0 {
+			q.calls[token] = calls
+		} else {
+			delete(q.calls, token)
+		}
+	}
+	for token, entries := range q.rows {
+		if entries = pruneRows(entries, now.Add(-rowWindow)); len(entries) > 0 {
+			q.rows[token] = entries
+		} else {
+			delete(q.rows, token)
+		}
+	}
+}
+
+// Stop stops the cleanup goroutine
+// Should be called when shutting down the server
+func (q *QuotaLimiter) Stop() {
+	close(q.stopCleanup)
+}
@@ -0,0 +1,187 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package auth
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for quota tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newQuotaLimiterForTest creates a quota limiter driven by a fake clock
+// The clock is installed before the cleanup goroutine starts to avoid data races
+func newQuotaLimiterForTest(limits QuotaLimits) (*QuotaLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	q := &QuotaLimiter{
+		limits:          limits,
+		calls:           make(map[string][]time.Time),
+		rows:            make(map[string][]rowEntry),
+		active:          make(map[string]int),
+		cleanupInterval: time.Hour,
+		stopCleanup:     make(chan bool),
+		now:             clock.Now,
+	}
+	go q.cleanupLoop()
+	return q, clock
+}
+
+func TestQuotaLimits_IsEnabled(t *testing.T) {
+	if (QuotaLimits{}).IsEnabled() {
+		t.Error("Empty limits should not be enabled")
+	}
+	if !(QuotaLimits{RowsPerHour: 10}).IsEnabled() {
+		t.Error("Limits with rows per hour should be enabled")
+	}
+}
+
+func TestQuotaLimiter_ToolCallsPerMinute(t *testing.T) {
+	q, clock := newQuotaLimiterForTest(QuotaLimits{ToolCallsPerMinute: 2})
+	defer q.Stop()
+
+	for i := 0; i < 2; i++ {
+		release, err := q.Acquire("token1")
+		if err != nil {
+			t.Fatalf("Call %d should be allowed: %v", i+1, err)
+		}
+		release()
+	}
+
+	_, err := q.Acquire("token1")
+	var quotaErr *QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("Expected QuotaExceededError, got %v", err)
+	}
+	if quotaErr.RetryAfterSeconds() != 60 {
+		t.Errorf("RetryAfterSeconds = %d, want 60", quotaErr.RetryAfterSeconds())
+	}
+
+	// Other tokens are unaffected
+	if _, err := q.Acquire("token2"); err != nil {
+		t.Errorf("Different token should be allowed: %v", err)
+	}
+
+	// After the window passes, calls are allowed again
+	clock.Advance(61 * time.Second)
+	if _, err := q.Acquire("token1"); err != nil {
+		t.Errorf("Call after window should be allowed: %v", err)
+	}
+}
+
+func TestQuotaLimiter_RowsPerHour(t *testing.T) {
+	q, clock := newQuotaLimiterForTest(QuotaLimits{RowsPerHour: 100})
+	defer q.Stop()
+
+	release, err := q.Acquire("token1")
+	if err != nil {
+		t.Fatalf("First call should be allowed: %v", err)
+	}
+	q.RecordRows("token1", 60)
+	release()
+
+	// Still under the limit, so the next call is allowed (and may overshoot)
+	release, err = q.Acquire("token1")
+	if err != nil {
+		t.Fatalf("Second call should be allowed: %v", err)
+	}
+	clock.Advance(10 * time.Minute)
+	q.RecordRows("token1", 50)
+	release()
+
+	_, err = q.Acquire("token1")
+	var quotaErr *QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("Expected QuotaExceededError, got %v", err)
+	}
+	if quotaErr.RetryAfter != 50*time.Minute {
+		t.Errorf("RetryAfter = %v, want 50m", quotaErr.RetryAfter)
+	}
+
+	// Once the first entry leaves the window the token is under the limit again
+	clock.Advance(51 * time.Minute)
+	if _, err := q.Acquire("token1"); err != nil {
+		t.Errorf("Call after first entry expired should be allowed: %v", err)
+	}
+}
+
+func TestQuotaLimiter_MaxConcurrentQueries(t *testing.T) {
+	q, _ := newQuotaLimiterForTest(QuotaLimits{MaxConcurrentQueries: 1})
+	defer q.Stop()
+
+	release, err := q.Acquire("token1")
+	if err != nil {
+		t.Fatalf("First call should be allowed: %v", err)
+	}
+
+	if _, err := q.Acquire("token1"); err == nil {
+		t.Fatal("Second concurrent call should be rejected")
+	}
+
+	release()
+	release() // Releasing twice must not free an extra slot
+
+	release2, err := q.Acquire("token1")
+	if err != nil {
+		t.Fatalf("Call after release should be allowed: %v", err)
+	}
+	if _, err := q.Acquire("token1"); err == nil {
+		t.Error("Double release should not allow two concurrent calls")
+	}
+	release2()
+}
+
+func TestQuotaLimiter_Cleanup(t *testing.T) {
+	q, clock := newQuotaLimiterForTest(QuotaLimits{ToolCallsPerMinute: 10, RowsPerHour: 10})
+	defer q.Stop()
+
+	release, err := q.Acquire("token1")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	q.RecordRows("token1", 5)
+	release()
+
+	clock.Advance(2 * time.Hour)
+	q.cleanup()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.calls) != 0 || len(q.rows) != 0 || len(q.active) != 0 {
+		t.Errorf("Expected all entries to be cleaned up: calls=%d rows=%d active=%d",
+			len(q.calls), len(q.rows), len(q.active))
+	}
+}
+
+func TestQuotaExceededError_Message(t *testing.T) {
+	err := &QuotaExceededError{Limit: "5 tool calls per minute", RetryAfter: 1500 * time.Millisecond}
+	want := "rate limit exceeded: 5 tool calls per minute (retry after 2 seconds)"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
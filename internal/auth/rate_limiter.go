@@ -11,8 +11,11 @@
 package auth
 
 import (
+	"fmt"
 	"sync"
 	"time"
+
+	"pgedge-postgres-mcp/internal/notify"
 )
 
 // RateLimiter tracks failed authentication attempts per IP address
@@ -83,6 +86,25 @@ func (rl *RateLimiter) RecordFailedAttempt(ipAddress string) {
 	} else {
 		rl.attempts[ipAddress] = append(rl.attempts[ipAddress], now)
 	}
+
+	// Announce the attempt that blocks the address
+	cutoff := now.Add(-rl.windowDuration)
+	recent := 0
+	for _, timestamp := range rl.attempts[ipAddress] {
+		if timestamp.After(cutoff) {
+			recent++
+		}
+	}
+	if recent == rl.maxAttempts {
+		notify.Send(notify.Event{
+			Category: notify.CategoryAuth,
+			Severity: notify.SeverityWarning,
+			Title:    fmt.Sprintf("Login attempts from %s blocked", ipAddress),
+			Message: fmt.Sprintf("%d failed login attempts within %s; further attempts are refused until they age out.",
+				recent, rl.windowDuration),
+			Fields: map[string]string{"ip_address": ipAddress},
+		})
+	}
 }
 
 // Reset clears all failed attempts for an IP address
@@ -70,6 +70,19 @@ func TestRateLimiter_IsAllowed(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_BlockNotification(t *testing.T) {
+	sent := captureNotifications(t)
+	rl := NewRateLimiter(1, 3)
+	defer rl.Stop()
+
+	for i := 0; i < 5; i++ {
+		rl.RecordFailedAttempt("192.168.1.100")
+	}
+	if titles := sent(); len(titles) != 1 || titles[0] != "Login attempts from 192.168.1.100 blocked" {
+		t.Errorf("notifications = %v, want one for the attempt that blocks the address", titles)
+	}
+}
+
 func TestRateLimiter_MultipleIPs(t *testing.T) {
 	rl := NewRateLimiter(1, 2) // 1 minute window, 2 attempts max
 	defer rl.Stop()
@@ -21,6 +21,9 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/yaml.v3"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/notify"
 )
 
 const (
@@ -269,6 +272,14 @@ func (s *UserStore) AuthenticateUser(username, password string, maxFailedAttempt
 		// Lock account if threshold is reached (only if maxFailedAttempts > 0)
 		if maxFailedAttempts > 0 && user.FailedAttempts >= maxFailedAttempts {
 			user.Enabled = false
+			notify.Send(notify.Event{
+				Category: notify.CategoryAuth,
+				Severity: notify.SeverityWarning,
+				Title:    fmt.Sprintf("User account %s locked", username),
+				Message: fmt.Sprintf("The account was disabled after %d failed login attempts. Re-enable it with -enable-user.",
+					user.FailedAttempts),
+				Fields: map[string]string{"user": username},
+			})
 		}
 
 		return "", time.Time{}, fmt.Errorf("invalid username or password")
@@ -386,9 +397,9 @@ type UserInfo struct {
 }
 
 // GetDefaultUserPath returns the default user file path
-// Searches /etc/pgedge/ first, then binary directory
+// Searches the system configuration directory first, then binary directory
 func GetDefaultUserPath(binaryPath string) string {
-	systemPath := "/etc/pgedge/pgedge-postgres-mcp-users.yaml"
+	systemPath := filepath.Join(config.SystemConfigDir(), "pgedge-postgres-mcp-users.yaml")
 	if _, err := os.Stat(systemPath); err == nil {
 		return systemPath
 	}
@@ -11,10 +11,14 @@
 package auth
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
+
+	"pgedge-postgres-mcp/internal/notify"
 )
 
 // TestHashPassword tests password hashing
@@ -680,6 +684,50 @@ func TestAuthenticateUser_WithFailedAttempts(t *testing.T) {
 }
 
 // TestAuthenticateUser_WithAccountLockout tests account lockout feature
+// captureNotifications records the titles of notifications sent until the
+// test ends
+func captureNotifications(t *testing.T) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var titles []string
+	n := notify.New(notify.Config{
+		Endpoints: []notify.Endpoint{{Name: "test"}},
+		Post: func(ctx context.Context, endpoint notify.Endpoint, event notify.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			titles = append(titles, event.Title)
+			return nil
+		},
+	})
+	notify.SetShared(n)
+	t.Cleanup(func() { notify.SetShared(nil) })
+	return func() []string {
+		n.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), titles...)
+	}
+}
+
+func TestAuthenticateUser_LockoutNotification(t *testing.T) {
+	sent := captureNotifications(t)
+	store := InitializeUserStore()
+	if err := store.AddUser("alice", "password123", "Alice"); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		store.AuthenticateUser("alice", "wrongpassword", 3) //nolint:errcheck // failures expected
+	}
+	if titles := sent(); len(titles) != 0 {
+		t.Fatalf("notified before the lockout: %v", titles)
+	}
+	store.AuthenticateUser("alice", "wrongpassword", 3) //nolint:errcheck // failure expected
+	if titles := sent(); len(titles) != 1 || titles[0] != "User account alice locked" {
+		t.Errorf("notifications = %v, want the lockout", titles)
+	}
+}
+
 func TestAuthenticateUser_WithAccountLockout(t *testing.T) {
 	store := InitializeUserStore()
 
@@ -0,0 +1,246 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package autoanalyze runs ANALYZE in the background on tables whose row
+// estimates were badly wrong in plans examined by the assistant, so that
+// later plans use fresh statistics. ANALYZEs are throttled per table and
+// overall, and can be limited to an off-peak window.
+package autoanalyze
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/logging"
+)
+
+const (
+	// maxPending limits the tables waiting for ANALYZE
+	maxPending = 100
+
+	// checkInterval is how often queued tables are checked against the
+	// window and rate limit
+	checkInterval = time.Minute
+
+	// analyzeTimeout bounds a single ANALYZE
+	analyzeTimeout = 10 * time.Minute
+
+	// lockTimeout stops ANALYZE from waiting behind DDL or a running VACUUM
+	lockTimeout = "5s"
+)
+
+// Table is a table to analyze
+type Table struct {
+	Database string        // Identifies the database in logs, e.g. the sanitized connection string
+	Name     string        // Table name as output by regclass, so safe to use in SQL
+	Pool     *pgxpool.Pool // Pool of the database the table is in
+}
+
+// Config configures an Analyzer
+type Config struct {
+	MinRatio      float64       // Estimated and actual rows differing by this factor are misestimates
+	MinRows       float64       // Ignore misestimates where both counts are below this
+	TableInterval time.Duration // Minimum time between ANALYZEs of one table
+	MaxPerHour    int           // ANALYZEs started in any hour (0 = unlimited)
+	Window        *Window       // Time of day to run in (nil = any time)
+
+	// Analyze runs ANALYZE on a table (nil = run it in the table's pool);
+	// replaceable for tests
+	Analyze func(ctx context.Context, t Table) error
+
+	// Now returns the current time (nil = time.Now); replaceable for tests
+	Now func() time.Time
+}
+
+// Analyzer queues tables and runs ANALYZE on them in the background
+type Analyzer struct {
+	cfg  Config
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pending []Table
+	queued  map[string]bool
+	last    map[string]time.Time // When each table was last queued
+	started []time.Time          // ANALYZEs started in the last hour
+}
+
+// New creates an analyzer; call Run to process requests
+func New(cfg Config) *Analyzer {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	a := &Analyzer{
+		cfg:    cfg,
+		wake:   make(chan struct{}, 1),
+		queued: make(map[string]bool),
+		last:   make(map[string]time.Time),
+	}
+	if a.cfg.Analyze == nil {
+		a.cfg.Analyze = a.analyzeTable
+	}
+	return a
+}
+
+// shared is the analyzer used by the tools, set with SetShared
+var shared atomic.Pointer[Analyzer]
+
+// SetShared sets the analyzer that tools send requests to; nil disables
+// background ANALYZE
+func SetShared(a *Analyzer) {
+	shared.Store(a)
+}
+
+// Shared returns the analyzer set with SetShared, or nil
+func Shared() *Analyzer {
+	return shared.Load()
+}
+
+// Misestimated returns the tables of a plan with badly estimated scans,
+// using the analyzer's thresholds
+func (a *Analyzer) Misestimated(plan string, format string) []string {
+	return Misestimates(plan, format, a.cfg.MinRatio, a.cfg.MinRows)
+}
+
+// Request queues tables for ANALYZE and returns the names of those queued.
+// Tables already queued, or queued within the table interval, are skipped,
+// as are tables beyond the queue limit.
+func (a *Analyzer) Request(tables ...Table) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.cfg.Now()
+	var added []string
+	for _, t := range tables {
+		key := t.Database + "\x00" + t.Name
+		if a.queued[key] || len(a.pending) >= maxPending {
+			continue
+		}
+		if last, ok := a.last[key]; ok && now.Sub(last) < a.cfg.TableInterval {
+			continue
+		}
+		a.pending = append(a.pending, t)
+		a.queued[key] = true
+		a.last[key] = now
+		added = append(added, t.Name)
+	}
+
+	if len(added) > 0 {
+		select {
+		case a.wake <- struct{}{}:
+		default:
+		}
+	}
+	return added
+}
+
+// Run processes queued tables until ctx is cancelled
+func (a *Analyzer) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		a.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-a.wake:
+		}
+	}
+}
+
+// RunDue analyzes the queued tables allowed by the window and the rate
+// limit now, and returns how many were analyzed
+func (a *Analyzer) RunDue(ctx context.Context) int {
+	n := 0
+	for ctx.Err() == nil {
+		t, ok := a.next()
+		if !ok {
+			break
+		}
+		start := time.Now()
+		if err := a.cfg.Analyze(ctx, t); err != nil {
+			logging.Warn("auto_analyze_failed", "database", t.Database, "table", t.Name, "error", err)
+			continue
+		}
+		logging.Info("auto_analyze_completed", "database", t.Database, "table", t.Name,
+			"duration_ms", time.Since(start).Milliseconds())
+		n++
+	}
+	return n
+}
+
+// next takes the next table from the queue if one may be analyzed now
+func (a *Analyzer) next() (Table, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.cfg.Now()
+	if len(a.pending) == 0 || !a.cfg.Window.Contains(now) {
+		return Table{}, false
+	}
+
+	recent := a.started[:0]
+	for _, s := range a.started {
+		if now.Sub(s) < time.Hour {
+			recent = append(recent, s)
+		}
+	}
+	a.started = recent
+	if a.cfg.MaxPerHour > 0 && len(a.started) >= a.cfg.MaxPerHour {
+		return Table{}, false
+	}
+
+	t := a.pending[0]
+	a.pending = a.pending[1:]
+	delete(a.queued, t.Database+"\x00"+t.Name)
+	a.started = append(a.started, now)
+	return t, true
+}
+
+// analyzeTable runs ANALYZE on a table, unless autovacuum or someone else
+// analyzed it within the table interval
+func (a *Analyzer) analyzeTable(ctx context.Context, t Table) error {
+	ctx, cancel := context.WithTimeout(ctx, analyzeTimeout)
+	defer cancel()
+
+	// Connections default to read-only transactions
+	tx, err := t.Pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // No-op after commit
+
+	var recent bool
+	err = tx.QueryRow(ctx, `SELECT coalesce(greatest(last_analyze, last_autoanalyze) > now() - make_interval(secs => $2), false)
+		FROM pg_catalog.pg_stat_all_tables WHERE relid = to_regclass($1)`,
+		t.Name, a.cfg.TableInterval.Seconds()).Scan(&recent)
+	if err != nil {
+		return fmt.Errorf("failed to check when the table was last analyzed: %w", err)
+	}
+	if recent {
+		logging.Info("auto_analyze_skipped", "database", t.Database, "table", t.Name, "reason", "recently analyzed")
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, "SET LOCAL lock_timeout = '"+lockTimeout+"'"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "ANALYZE "+t.Name); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
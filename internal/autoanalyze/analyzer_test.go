@@ -0,0 +1,154 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package autoanalyze
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const textPlan = `Hash Join  (cost=30.50..5120.75 rows=12 width=16) (actual time=0.410..41.220 rows=48210 loops=1)
+  Hash Cond: (o.customer_id = c.id)
+  ->  Seq Scan on orders o  (cost=0.00..4810.00 rows=50 width=12) (actual time=0.010..20.140 rows=48210 loops=1)
+        Filter: (status = 'late'::text)
+  ->  Hash  (cost=18.00..18.00 rows=1000 width=8) (actual time=0.390..0.391 rows=1000 loops=1)
+        ->  Index Scan using "Customers_pkey" on "Customers" c  (cost=0.28..18.00 rows=1000 width=8) (actual time=0.010..0.200 rows=1000 loops=1)
+  ->  Bitmap Heap Scan on sales.items i  (cost=4.30..8.00 rows=20000 width=8) (actual rows=3.00 loops=2)
+  ->  Seq Scan on returns r  (cost=0.00..1.00 rows=5000 width=8) (never executed)
+  ->  Seq Scan on tiny t  (cost=0.00..1.00 rows=1 width=8) (actual time=0.010..0.020 rows=400 loops=1)`
+
+func TestMisestimates(t *testing.T) {
+	got := Misestimates(textPlan, "text", 10, 1000)
+	want := []string{"orders", "sales.items"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Misestimates(text) = %v, want %v", got, want)
+	}
+
+	jsonPlan := `[{"Plan": {"Node Type": "Nested Loop", "Plan Rows": 10, "Actual Rows": 9000, "Actual Loops": 1, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "Orders", "Schema": "sales", "Plan Rows": 10, "Actual Rows": 9000, "Actual Loops": 1},
+		{"Node Type": "Index Scan", "Relation Name": "customers", "Plan Rows": 1, "Actual Rows": 1, "Actual Loops": 9000},
+		{"Node Type": "Seq Scan", "Relation Name": "returns", "Plan Rows": 5000, "Actual Rows": 0, "Actual Loops": 0}
+	]}}]`
+	got = Misestimates(jsonPlan, "json", 10, 1000)
+	want = []string{`"sales"."Orders"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Misestimates(json) = %v, want %v", got, want)
+	}
+
+	if got := Misestimates("not json", "json", 10, 1000); got != nil {
+		t.Errorf("Misestimates(invalid json) = %v, want nil", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 6, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	w, err := ParseWindow("01:00-05:30")
+	if err != nil {
+		t.Fatalf("ParseWindow() error = %v", err)
+	}
+	if !w.Contains(at(1, 0)) || !w.Contains(at(5, 29)) || w.Contains(at(5, 30)) || w.Contains(at(0, 59)) {
+		t.Errorf("unexpected containment for %s", w)
+	}
+
+	w, err = ParseWindow("22:00 - 02:00")
+	if err != nil {
+		t.Fatalf("ParseWindow() error = %v", err)
+	}
+	if !w.Contains(at(23, 0)) || !w.Contains(at(1, 59)) || w.Contains(at(12, 0)) {
+		t.Errorf("unexpected containment for %s", w)
+	}
+	if w.String() != "22:00-02:00" {
+		t.Errorf("String() = %q", w.String())
+	}
+
+	if w, err := ParseWindow(""); w != nil || err != nil || !w.Contains(at(12, 0)) {
+		t.Errorf("ParseWindow(\"\") = %v, %v; want no window", w, err)
+	}
+	for _, s := range []string{"01:00", "1am-5am", "25:00-02:00", "03:00-03:00"} {
+		if _, err := ParseWindow(s); err == nil {
+			t.Errorf("ParseWindow(%q) should fail", s)
+		}
+	}
+}
+
+func TestAnalyzer(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	var analyzed []string
+	a := New(Config{
+		TableInterval: 24 * time.Hour,
+		MaxPerHour:    2,
+		Now:           func() time.Time { return now },
+		Analyze: func(ctx context.Context, t Table) error {
+			analyzed = append(analyzed, t.Name)
+			if t.Name == "broken" {
+				return errors.New("permission denied")
+			}
+			return nil
+		},
+	})
+
+	queued := a.Request(Table{Database: "db", Name: "orders"}, Table{Database: "db", Name: "orders"},
+		Table{Database: "db", Name: "broken"}, Table{Database: "db", Name: "items"}, Table{Database: "other", Name: "orders"})
+	if want := []string{"orders", "broken", "items", "orders"}; !reflect.DeepEqual(queued, want) {
+		t.Fatalf("Request() = %v, want %v", queued, want)
+	}
+
+	// Two per hour, failures included
+	if n := a.RunDue(context.Background()); n != 1 {
+		t.Errorf("RunDue() = %d, want 1", n)
+	}
+	if want := []string{"orders", "broken"}; !reflect.DeepEqual(analyzed, want) {
+		t.Errorf("analyzed %v, want %v", analyzed, want)
+	}
+
+	// Tables are not queued again within the table interval
+	now = now.Add(time.Hour)
+	if queued := a.Request(Table{Database: "db", Name: "orders"}); len(queued) != 0 {
+		t.Errorf("Request() = %v, want nothing within the table interval", queued)
+	}
+	if n := a.RunDue(context.Background()); n != 2 {
+		t.Errorf("RunDue() = %d, want 2", n)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if queued := a.Request(Table{Database: "db", Name: "orders"}); len(queued) != 1 {
+		t.Errorf("Request() = %v, want orders after the table interval", queued)
+	}
+}
+
+func TestAnalyzerWindow(t *testing.T) {
+	window, err := ParseWindow("01:00-05:00")
+	if err != nil {
+		t.Fatalf("ParseWindow() error = %v", err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	runs := 0
+	a := New(Config{
+		Window:  window,
+		Now:     func() time.Time { return now },
+		Analyze: func(ctx context.Context, t Table) error { runs++; return nil },
+	})
+
+	a.Request(Table{Database: "db", Name: "orders"})
+	if n := a.RunDue(context.Background()); n != 0 || runs != 0 {
+		t.Errorf("RunDue() outside the window analyzed %d tables", runs)
+	}
+	now = time.Date(2025, 6, 2, 2, 0, 0, 0, time.Local)
+	if n := a.RunDue(context.Background()); n != 1 {
+		t.Errorf("RunDue() inside the window = %d, want 1", n)
+	}
+}
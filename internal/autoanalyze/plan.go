@@ -0,0 +1,177 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package autoanalyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// planIdent matches a relation or index name as shown by EXPLAIN, which
+// quotes names when needed
+const planIdent = `(?:"(?:[^"]|"")+"|[^\s".]+)`
+
+var (
+	// scanRe matches plan nodes that scan a table, capturing the table
+	scanRe = regexp.MustCompile(`(?:Seq Scan|Index Scan(?: Backward)? using ` + planIdent +
+		`|Index Only Scan(?: Backward)? using ` + planIdent +
+		`|Bitmap Heap Scan|Tid Scan|Tid Range Scan|Sample Scan) on (` + planIdent + `(?:\.` + planIdent + `)?)`)
+
+	// estimateRe and actualRe capture the estimated and actual rows
+	estimateRe = regexp.MustCompile(`\(cost=[\d.]+\.\.[\d.]+ rows=(\d+)`)
+	actualRe   = regexp.MustCompile(`\(actual (?:time=[\d.]+\.\.[\d.]+ )?rows=([\d.]+) loops=(\d+)\)`)
+)
+
+// Misestimates returns the tables scanned by an EXPLAIN ANALYZE plan whose
+// estimated and actual rows (per loop) differ by at least minRatio, where
+// the larger count is at least minRows. Names are as shown in the plan, so
+// they resolve through the search path of the session that ran it.
+func Misestimates(plan string, format string, minRatio, minRows float64) []string {
+	var tables []string
+	seen := make(map[string]bool)
+	add := func(name string, estimated, actual float64) {
+		if seen[name] || !misestimated(estimated, actual, minRatio, minRows) {
+			return
+		}
+		seen[name] = true
+		tables = append(tables, name)
+	}
+
+	if format == "json" {
+		var root []struct {
+			Plan json.RawMessage `json:"Plan"`
+		}
+		if err := json.Unmarshal([]byte(plan), &root); err != nil {
+			return nil
+		}
+		for _, r := range root {
+			walkJSONPlan(r.Plan, add)
+		}
+		return tables
+	}
+
+	for _, line := range strings.Split(plan, "\n") {
+		scan := scanRe.FindStringSubmatch(line)
+		estimate := estimateRe.FindStringSubmatch(line)
+		actual := actualRe.FindStringSubmatch(line)
+		if scan == nil || estimate == nil || actual == nil || actual[2] == "0" {
+			continue
+		}
+		estimated, err1 := strconv.ParseFloat(estimate[1], 64)
+		actualRows, err2 := strconv.ParseFloat(actual[1], 64)
+		if err1 == nil && err2 == nil {
+			add(scan[1], estimated, actualRows)
+		}
+	}
+	return tables
+}
+
+// jsonPlanNode is what Misestimates needs from a JSON plan node
+type jsonPlanNode struct {
+	RelationName string            `json:"Relation Name"`
+	Schema       string            `json:"Schema"`
+	PlanRows     float64           `json:"Plan Rows"`
+	ActualRows   *float64          `json:"Actual Rows"`
+	ActualLoops  float64           `json:"Actual Loops"`
+	Plans        []json.RawMessage `json:"Plans"`
+}
+
+// walkJSONPlan calls add for each scan of a table in a JSON plan node and
+// its children
+func walkJSONPlan(raw json.RawMessage, add func(name string, estimated, actual float64)) {
+	var node jsonPlanNode
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return
+	}
+	if node.RelationName != "" && node.ActualRows != nil && node.ActualLoops > 0 {
+		name := pgx.Identifier{node.RelationName}
+		if node.Schema != "" {
+			name = pgx.Identifier{node.Schema, node.RelationName}
+		}
+		add(name.Sanitize(), node.PlanRows, *node.ActualRows)
+	}
+	for _, child := range node.Plans {
+		walkJSONPlan(child, add)
+	}
+}
+
+// misestimated reports whether estimated and actual rows differ enough
+func misestimated(estimated, actual, minRatio, minRows float64) bool {
+	high, low := math.Max(estimated, actual), math.Min(estimated, actual)
+	return high >= minRows && high/math.Max(low, 1) >= minRatio
+}
+
+// Window is a daily time range, in local time, during which ANALYZE may run
+type Window struct {
+	start, end time.Duration // Offsets from midnight; end before start wraps past midnight
+}
+
+// ParseWindow parses a window such as "01:00-05:00"; an empty string is no
+// window (nil)
+func ParseWindow(s string) (*Window, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid window %q: expected HH:MM-HH:MM", s)
+	}
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("invalid window %q: start and end are the same", s)
+	}
+	return &Window{start: start, end: end}, nil
+}
+
+// parseTimeOfDay parses HH:MM as an offset from midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day (HH:MM)", strings.TrimSpace(s))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t falls within the window; a nil window
+// contains every time
+func (w *Window) Contains(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	if w.start < w.end {
+		return offset >= w.start && offset < w.end
+	}
+	return offset >= w.start || offset < w.end
+}
+
+// String formats the window as HH:MM-HH:MM
+func (w *Window) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return format(w.start) + "-" + format(w.end)
+}
@@ -0,0 +1,158 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// writeTools maps the tools that can modify the database to a function
+// returning what a call would run: its SQL where the call carries it, or a
+// description of the change otherwise. Calls that only preview their
+// changes with dry_run return false.
+var writeTools = map[string]func(args map[string]interface{}) (string, bool){
+	"query_database": func(args map[string]interface{}) (string, bool) {
+		// A query naming another database can set up its foreign server
+		db := stringArg(args, "database")
+		if db == "" {
+			return "", false
+		}
+		return fmt.Sprintf("-- Set up database %s as a foreign server if it is not set up yet\n%s", db, stringArg(args, "query")), true
+	},
+	"execute_script": func(args map[string]interface{}) (string, bool) {
+		if boolArg(args, "dry_run", false) {
+			return "", false
+		}
+		return stringArg(args, "script"), true
+	},
+	"apply_migration": func(args map[string]interface{}) (string, bool) {
+		if boolArg(args, "dry_run", true) {
+			return "", false
+		}
+		name := stringArg(args, "name")
+		if stringArg(args, "direction") == "down" {
+			if down := stringArg(args, "down"); down != "" {
+				return down, true
+			}
+			return fmt.Sprintf("-- Roll back the last applied migration %q", name), true
+		}
+		return stringArg(args, "up"), true
+	},
+	"create_vector_index": func(args map[string]interface{}) (string, bool) {
+		if boolArg(args, "dry_run", true) {
+			return "", false
+		}
+		target := stringArg(args, "table_name")
+		if column := stringArg(args, "column_name"); column != "" {
+			target += " (" + column + ")"
+		}
+		return fmt.Sprintf("-- Build a vector index on %s, blocking writes to the table until it is built", target), true
+	},
+	"install_extension": func(args map[string]interface{}) (string, bool) {
+		stmt := "CREATE EXTENSION IF NOT EXISTS " + stringArg(args, "name")
+		if schema := stringArg(args, "schema"); schema != "" {
+			stmt += " WITH SCHEMA " + schema
+		}
+		return stmt + " CASCADE;", true
+	},
+	"setup_semantic_search": func(args map[string]interface{}) (string, bool) {
+		if boolArg(args, "dry_run", true) {
+			return "", false
+		}
+		return fmt.Sprintf("-- Add an embedding column for %s.%s, fill it with embeddings and index it",
+			stringArg(args, "table_name"), stringArg(args, "text_column")), true
+	},
+	"setup_foreign_server": func(args map[string]interface{}) (string, bool) {
+		if boolArg(args, "dry_run", true) {
+			return "", false
+		}
+		return fmt.Sprintf("-- Set up database %s as a postgres_fdw foreign server and import its tables", stringArg(args, "database")), true
+	},
+	"restore_schema_snapshot": func(args map[string]interface{}) (string, bool) {
+		schema := stringArg(args, "target_schema")
+		description := fmt.Sprintf("-- Restore schema snapshot %q into schema %s", stringArg(args, "name"), schema)
+		if boolArg(args, "replace", false) {
+			description = fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE;\n%s", schema, description)
+		}
+		return description, true
+	},
+	"add_database_connection": func(args map[string]interface{}) (string, bool) {
+		host := stringArg(args, "host")
+		if host == "" {
+			host = "localhost"
+		}
+		return fmt.Sprintf("-- Add database %s (%s@%s/%s) to the server's configuration",
+			stringArg(args, "name"), stringArg(args, "user"), host, stringArg(args, "database")), true
+	},
+	"commit_transaction": func(map[string]interface{}) (string, bool) {
+		return "COMMIT; -- Make the changes of the open transaction permanent", true
+	},
+}
+
+// writeStatement returns what a tool call would run if it can modify the
+// database, and false for calls that only read
+func writeStatement(toolUse ToolUse) (string, bool) {
+	describe, ok := writeTools[toolUse.Name]
+	if !ok {
+		return "", false
+	}
+	statement, ok := describe(toolUse.Input)
+	return strings.TrimSpace(statement), ok
+}
+
+// approveWrites asks approve to confirm each tool call that can modify the
+// database, when MCP.ApproveWrites is set. It returns the results of the
+// calls that were declined, which are not run, by their index. A nil
+// approve declines every write, since nobody can be asked.
+func (c *Client) approveWrites(toolUses []ToolUse, approve func(ToolUse, string) bool) map[int]ToolResult {
+	if !c.config.MCP.ApproveWrites {
+		return nil
+	}
+	declined := make(map[int]ToolResult)
+	for i, toolUse := range toolUses {
+		statement, ok := writeStatement(toolUse)
+		if !ok {
+			continue
+		}
+		var reason string
+		switch {
+		case approve == nil:
+			reason = "Error: this tool call modifies the database and needs the user's approval, which cannot be given in non-interactive mode; it was not run"
+		case !approve(toolUse, statement):
+			reason = "Error: the user declined this tool call, so it was not run"
+		default:
+			continue
+		}
+		declined[i] = ToolResult{
+			Type:      "tool_result",
+			ToolUseID: toolUse.ID,
+			Content:   reason,
+			IsError:   true,
+		}
+	}
+	return declined
+}
+
+// stringArg returns a string argument, or "" if it is missing
+func stringArg(args map[string]interface{}, name string) string {
+	value, _ := args[name].(string) //nolint:errcheck // Missing means empty
+	return value
+}
+
+// boolArg returns a boolean argument, or defaultValue if it is missing
+func boolArg(args map[string]interface{}, name string, defaultValue bool) bool {
+	value, ok := args[name].(bool)
+	if !ok {
+		return defaultValue
+	}
+	return value
+}
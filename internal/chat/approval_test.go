@@ -0,0 +1,85 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteStatement(t *testing.T) {
+	tests := []struct {
+		name      string
+		toolUse   ToolUse
+		wantWrite bool
+		want      string
+	}{
+		{"query", ToolUse{Name: "query_database", Input: map[string]interface{}{"query": "SELECT 1"}}, false, ""},
+		{"query another database", ToolUse{Name: "query_database", Input: map[string]interface{}{"query": "SELECT * FROM analytics.visits", "database": "analytics"}}, true, "-- Set up database analytics as a foreign server if it is not set up yet\nSELECT * FROM analytics.visits"},
+		{"foreign server dry run by default", ToolUse{Name: "setup_foreign_server", Input: map[string]interface{}{"database": "analytics"}}, false, ""},
+		{"foreign server", ToolUse{Name: "setup_foreign_server", Input: map[string]interface{}{"database": "analytics", "dry_run": false}}, true, "-- Set up database analytics as a postgres_fdw foreign server and import its tables"},
+		{"script", ToolUse{Name: "execute_script", Input: map[string]interface{}{"script": " UPDATE t SET a = 1; "}}, true, "UPDATE t SET a = 1;"},
+		{"script dry run", ToolUse{Name: "execute_script", Input: map[string]interface{}{"script": "UPDATE t SET a = 1", "dry_run": true}}, false, ""},
+		{"migration dry run by default", ToolUse{Name: "apply_migration", Input: map[string]interface{}{"name": "m1", "up": "CREATE TABLE t ()"}}, false, ""},
+		{"migration", ToolUse{Name: "apply_migration", Input: map[string]interface{}{"name": "m1", "up": "CREATE TABLE t ()", "dry_run": false}}, true, "CREATE TABLE t ()"},
+		{"migration down", ToolUse{Name: "apply_migration", Input: map[string]interface{}{"name": "m1", "direction": "down", "dry_run": false}}, true, `-- Roll back the last applied migration "m1"`},
+		{"vector index", ToolUse{Name: "create_vector_index", Input: map[string]interface{}{"table_name": "docs", "column_name": "embedding", "dry_run": false}}, true, "-- Build a vector index on docs (embedding), blocking writes to the table until it is built"},
+		{"extension", ToolUse{Name: "install_extension", Input: map[string]interface{}{"name": "vector"}}, true, "CREATE EXTENSION IF NOT EXISTS vector CASCADE;"},
+		{"semantic search dry run by default", ToolUse{Name: "setup_semantic_search", Input: map[string]interface{}{"table_name": "docs", "text_column": "body"}}, false, ""},
+		{"semantic search", ToolUse{Name: "setup_semantic_search", Input: map[string]interface{}{"table_name": "docs", "text_column": "body", "dry_run": false}}, true, "-- Add an embedding column for docs.body, fill it with embeddings and index it"},
+		{"add database", ToolUse{Name: "add_database_connection", Input: map[string]interface{}{"name": "analytics", "host": "db2", "database": "warehouse", "user": "reporter", "password": "secret"}}, true, "-- Add database analytics (reporter@db2/warehouse) to the server's configuration"},
+		{"commit", ToolUse{Name: "commit_transaction", Input: map[string]interface{}{}}, true, "COMMIT; -- Make the changes of the open transaction permanent"},
+		{"restore replace", ToolUse{Name: "restore_schema_snapshot", Input: map[string]interface{}{"name": "s1", "target_schema": "old", "replace": true}}, true, "DROP SCHEMA IF EXISTS old CASCADE;\n-- Restore schema snapshot \"s1\" into schema old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, write := writeStatement(tt.toolUse)
+			if write != tt.wantWrite || got != tt.want {
+				t.Errorf("writeStatement() = %q, %v; want %q, %v", got, write, tt.want, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestApproveWrites(t *testing.T) {
+	toolUses := []ToolUse{
+		{ID: "read", Name: "query_database", Input: map[string]interface{}{"query": "SELECT 1"}},
+		{ID: "first", Name: "execute_script", Input: map[string]interface{}{"script": "DELETE FROM a"}},
+		{ID: "second", Name: "execute_script", Input: map[string]interface{}{"script": "DELETE FROM b"}},
+	}
+	client := &Client{config: &Config{}}
+
+	// Without approval mode nothing is asked
+	asked := 0
+	approve := func(toolUse ToolUse, statement string) bool {
+		asked++
+		return statement == "DELETE FROM a"
+	}
+	if declined := client.approveWrites(toolUses, approve); len(declined) != 0 || asked != 0 {
+		t.Fatalf("expected no approval without approve_writes, got %v", declined)
+	}
+
+	// Each write is shown with its SQL, and declined calls get an error result
+	client.config.MCP.ApproveWrites = true
+	declined := client.approveWrites(toolUses, approve)
+	if asked != 2 {
+		t.Errorf("expected to be asked about 2 writes, got %d", asked)
+	}
+	if len(declined) != 1 || declined[2].ToolUseID != "second" || !declined[2].IsError {
+		t.Errorf("expected the second write to be declined, got %v", declined)
+	}
+
+	// Without anyone to ask, every write is declined
+	declined = client.approveWrites(toolUses, nil)
+	if len(declined) != 2 || !strings.Contains(declined[1].Content.(string), "non-interactive") {
+		t.Errorf("expected both writes to be declined, got %v", declined)
+	}
+}
@@ -0,0 +1,435 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/embedding"
+	"pgedge-postgres-mcp/internal/netproxy"
+	"pgedge-postgres-mcp/internal/sigv4"
+	"pgedge-postgres-mcp/internal/toolschema"
+)
+
+// DefaultBedrockModel is the Bedrock model used when none is configured
+const DefaultBedrockModel = "anthropic.claude-3-5-sonnet-20240620-v1:0"
+
+// bedrockClient implements LLMClient for Amazon Bedrock using the Converse
+// API, which serves Anthropic Claude, Amazon Titan and other models with
+// one request format. Requests are signed with AWS Signature Version 4.
+type bedrockClient struct {
+	region      string
+	credentials sigv4.Credentials
+	runtimeURL  string // Base URL of the runtime API, which serves chat
+	controlURL  string // Base URL of the control plane API, which lists models
+	model       string
+	maxTokens   int
+	temperature float64
+	debug       bool
+	client      *http.Client
+}
+
+// NewBedrockClient creates a new Amazon Bedrock client. endpoint overrides
+// the regional runtime endpoint, for example with a VPC interface endpoint.
+func NewBedrockClient(region, endpoint string, credentials sigv4.Credentials, model string, maxTokens int, temperature float64, debug bool) LLMClient {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+	}
+	return &bedrockClient{
+		region:      region,
+		credentials: credentials,
+		runtimeURL:  strings.TrimSuffix(endpoint, "/"),
+		controlURL:  fmt.Sprintf("https://bedrock.%s.amazonaws.com", region),
+		model:       model,
+		maxTokens:   maxTokens,
+		temperature: temperature,
+		debug:       debug,
+		client:      netproxy.NewClient(netproxy.ProviderBedrock, 0),
+	}
+}
+
+type bedrockText struct {
+	Text string `json:"text"`
+}
+
+type bedrockContentBlock struct {
+	Text       string             `json:"text,omitempty"`
+	ToolUse    *bedrockToolUse    `json:"toolUse,omitempty"`
+	ToolResult *bedrockToolResult `json:"toolResult,omitempty"`
+}
+
+type bedrockToolUse struct {
+	ToolUseID string                 `json:"toolUseId"`
+	Name      string                 `json:"name"`
+	Input     map[string]interface{} `json:"input"`
+}
+
+type bedrockToolResult struct {
+	ToolUseID string        `json:"toolUseId"`
+	Content   []bedrockText `json:"content"`
+	Status    string        `json:"status,omitempty"`
+}
+
+type bedrockMessage struct {
+	Role    string                `json:"role"`
+	Content []bedrockContentBlock `json:"content"`
+}
+
+type bedrockInferenceConfig struct {
+	MaxTokens   int     `json:"maxTokens,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+type bedrockToolConfig struct {
+	Tools []map[string]interface{} `json:"tools"`
+}
+
+type bedrockRequest struct {
+	Messages        []bedrockMessage       `json:"messages"`
+	System          []bedrockText          `json:"system,omitempty"`
+	InferenceConfig bedrockInferenceConfig `json:"inferenceConfig"`
+	ToolConfig      *bedrockToolConfig     `json:"toolConfig,omitempty"`
+}
+
+type bedrockUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+	TotalTokens  int `json:"totalTokens"`
+}
+
+type bedrockResponse struct {
+	Output struct {
+		Message bedrockMessage `json:"message"`
+	} `json:"output"`
+	StopReason string       `json:"stopReason"`
+	Usage      bedrockUsage `json:"usage"`
+}
+
+// extractBedrockErrorMessage parses Bedrock's error response to get a user-friendly message
+func extractBedrockErrorMessage(statusCode int, body []byte) string {
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Message != "" {
+		return fmt.Sprintf("API error (%d): %s", statusCode, errResp.Message)
+	}
+	return fmt.Sprintf("API error (%d): %s", statusCode, string(body))
+}
+
+// supportsTools reports whether the model accepts system prompts and tools
+// through the Converse API; Amazon Titan text models accept neither
+func (c *bedrockClient) supportsTools() bool {
+	return !strings.Contains(c.model, "amazon.titan")
+}
+
+// bedrockMessages converts conversation messages to Converse messages.
+// Messages without content are dropped, since Bedrock rejects them.
+func bedrockMessages(messages []Message) []bedrockMessage {
+	result := make([]bedrockMessage, 0, len(messages))
+	for _, msg := range messages {
+		var blocks []bedrockContentBlock
+		switch content := msg.Content.(type) {
+		case string:
+			if content != "" {
+				blocks = append(blocks, bedrockContentBlock{Text: content})
+			}
+		case []ToolResult:
+			for _, v := range content {
+				blocks = append(blocks, bedrockToolResultBlock(v.ToolUseID, v.Content, v.IsError))
+			}
+		case []interface{}:
+			for _, item := range content {
+				switch v := item.(type) {
+				case TextContent:
+					if v.Text != "" {
+						blocks = append(blocks, bedrockContentBlock{Text: v.Text})
+					}
+				case ToolUse:
+					blocks = append(blocks, bedrockToolUseBlock(v.ID, v.Name, v.Input))
+				case ToolResult:
+					blocks = append(blocks, bedrockToolResultBlock(v.ToolUseID, v.Content, v.IsError))
+				case map[string]interface{}:
+					// Items unmarshaled from JSON
+					switch v["type"] {
+					case "text":
+						if text, ok := v["text"].(string); ok && text != "" {
+							blocks = append(blocks, bedrockContentBlock{Text: text})
+						}
+					case "tool_use":
+						id, ok1 := v["id"].(string)
+						name, ok2 := v["name"].(string)
+						if !ok1 || !ok2 {
+							continue
+						}
+						input, _ := v["input"].(map[string]interface{}) //nolint:errcheck // Missing input is sent as empty
+						blocks = append(blocks, bedrockToolUseBlock(id, name, input))
+					case "tool_result":
+						id, ok := v["tool_use_id"].(string)
+						if !ok {
+							continue
+						}
+						isError, _ := v["is_error"].(bool) //nolint:errcheck // Unset means success
+						blocks = append(blocks, bedrockToolResultBlock(id, v["content"], isError))
+					}
+				}
+			}
+		}
+		if len(blocks) > 0 {
+			result = append(result, bedrockMessage{Role: msg.Role, Content: blocks})
+		}
+	}
+	return result
+}
+
+// bedrockToolUseBlock returns a tool use content block
+func bedrockToolUseBlock(id, name string, input map[string]interface{}) bedrockContentBlock {
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	return bedrockContentBlock{ToolUse: &bedrockToolUse{ToolUseID: id, Name: name, Input: input}}
+}
+
+// bedrockToolResultBlock returns a tool result content block
+func bedrockToolResultBlock(id string, content interface{}, isError bool) bedrockContentBlock {
+	text := extractTextFromContent(content)
+	if text == "" {
+		text = "{}"
+	}
+	result := &bedrockToolResult{ToolUseID: id, Content: []bedrockText{{Text: text}}}
+	if isError {
+		result.Status = "error"
+	}
+	return bedrockContentBlock{ToolResult: result}
+}
+
+// converseURL returns the Converse endpoint of the model. The model ID is
+// escaped in full, since IDs such as "anthropic.claude-v2:1" contain
+// colons.
+func (c *bedrockClient) converseURL() string {
+	return fmt.Sprintf("%s/model/%s/converse", c.runtimeURL,
+		strings.ReplaceAll(neturl.PathEscape(c.model), ":", "%3A"))
+}
+
+// Chat sends messages with the Converse API. Bedrock streams responses in
+// AWS's binary event stream format, so when a text stream is requested the
+// complete text is passed to it once the response arrives.
+func (c *bedrockClient) Chat(ctx context.Context, messages []Message, tools interface{}) (LLMResponse, error) {
+	startTime := time.Now()
+	operation := "chat"
+	url := c.converseURL()
+
+	embedding.LogLLMCallDetails("bedrock", c.model, operation, url, len(messages))
+
+	// Convert interface{} tools to tool definitions via JSON
+	mcpTools, err := toolschema.FromAny(tools)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+
+	// System messages in the conversation, such as compaction summaries,
+	// are added to the system prompt
+	messages, systemTexts := splitSystemMessages(messages)
+	system := []bedrockText{{Text: systemPrompt}}
+	if extra := systemContextFrom(ctx); extra != "" {
+		system = append(system, bedrockText{Text: extra})
+	}
+	for _, text := range systemTexts {
+		system = append(system, bedrockText{Text: text})
+	}
+
+	req := bedrockRequest{
+		Messages: bedrockMessages(messages),
+		InferenceConfig: bedrockInferenceConfig{
+			MaxTokens:   c.maxTokens,
+			Temperature: c.temperature,
+		},
+	}
+	if c.supportsTools() {
+		req.System = system
+		if bedrockTools := toolschema.Bedrock(mcpTools); len(bedrockTools) > 0 {
+			req.ToolConfig = &bedrockToolConfig{Tools: bedrockTools}
+		}
+	} else if len(req.Messages) > 0 {
+		// Without system prompt support the instructions start the first
+		// user message
+		var texts []string
+		for _, block := range system {
+			texts = append(texts, block.Text)
+		}
+		first := &req.Messages[0]
+		first.Content = append([]bedrockContentBlock{{Text: strings.Join(texts, "\n\n")}}, first.Content...)
+	}
+
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return LLMResponse{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	embedding.LogLLMRequestTrace("bedrock", c.model, operation, string(reqData))
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqData))
+	if err != nil {
+		return LLMResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	sigv4.Sign(httpReq, reqData, c.credentials, c.region, "bedrock", time.Now())
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		embedding.LogConnectionError("bedrock", url, err)
+		duration := time.Since(startTime)
+		embedding.LogLLMCall("bedrock", c.model, operation, 0, 0, duration, err)
+		return LLMResponse{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		duration := time.Since(startTime)
+		readErr := fmt.Errorf("failed to read response body: %w", err)
+		embedding.LogLLMCall("bedrock", c.model, operation, 0, 0, duration, readErr)
+		return LLMResponse{}, readErr
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// Check if this is a rate limit error
+		if resp.StatusCode == 429 {
+			embedding.LogRateLimitError("bedrock", c.model, resp.StatusCode, string(body))
+		}
+
+		duration := time.Since(startTime)
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: extractBedrockErrorMessage(resp.StatusCode, body)}
+		embedding.LogLLMCall("bedrock", c.model, operation, 0, 0, duration, apiErr)
+		return LLMResponse{}, apiErr
+	}
+
+	var bedrockResp bedrockResponse
+	if err := json.Unmarshal(body, &bedrockResp); err != nil {
+		duration := time.Since(startTime)
+		embedding.LogLLMCall("bedrock", c.model, operation, 0, 0, duration, err)
+		return LLMResponse{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Convert response content to typed structs
+	var text strings.Builder
+	content := make([]interface{}, 0, len(bedrockResp.Output.Message.Content))
+	for _, block := range bedrockResp.Output.Message.Content {
+		switch {
+		case block.ToolUse != nil:
+			input := block.ToolUse.Input
+			if input == nil {
+				input = make(map[string]interface{})
+			}
+			content = append(content, ToolUse{
+				Type:  "tool_use",
+				ID:    block.ToolUse.ToolUseID,
+				Name:  block.ToolUse.Name,
+				Input: input,
+			})
+		case block.Text != "":
+			text.WriteString(block.Text)
+			content = append(content, TextContent{
+				Type: "text",
+				Text: block.Text,
+			})
+		}
+	}
+	if onText := textStreamFrom(ctx); onText != nil && text.Len() > 0 {
+		onText(text.String())
+	}
+
+	duration := time.Since(startTime)
+	usage := bedrockResp.Usage
+	embedding.LogLLMResponseTrace("bedrock", c.model, operation, resp.StatusCode, bedrockResp.StopReason)
+	embedding.LogLLMCall("bedrock", c.model, operation, usage.InputTokens, usage.OutputTokens, duration, nil)
+
+	// Build token usage for debug
+	var tokenUsage *TokenUsage
+	if c.debug {
+		tokenUsage = &TokenUsage{
+			Provider:         "bedrock",
+			PromptTokens:     usage.InputTokens,
+			CompletionTokens: usage.OutputTokens,
+			TotalTokens:      usage.TotalTokens,
+		}
+
+		// Log to stderr for CLI
+		fmt.Fprintf(os.Stderr, "\r\n[LLM] [DEBUG] Bedrock - Tokens: Input %d, Output %d, Total %d\n",
+			usage.InputTokens,
+			usage.OutputTokens,
+			usage.TotalTokens,
+		)
+	}
+
+	return LLMResponse{
+		Content:    content,
+		StopReason: bedrockResp.StopReason,
+		TokenUsage: tokenUsage,
+	}, nil
+}
+
+// ListModels returns the on-demand text models available in the region
+// Inference profiles, which newer models require, are not listed but can be
+// configured as the model
+func (c *bedrockClient) ListModels(ctx context.Context) ([]string, error) {
+	url := c.controlURL + "/foundation-models?byInferenceType=ON_DEMAND&byOutputModality=TEXT"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	sigv4.Sign(req, nil, c.credentials, c.region, "bedrock", time.Now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck // Error response body read is best effort
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response: {"modelSummaries": [{"modelId": "amazon.titan-text-express-v1", ...}, ...]}
+	var response struct {
+		ModelSummaries []struct {
+			ModelID string `json:"modelId"`
+		} `json:"modelSummaries"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]string, 0, len(response.ModelSummaries))
+	for _, model := range response.ModelSummaries {
+		// Exclude embedding models
+		if strings.Contains(model.ModelID, "embed") {
+			continue
+		}
+		models = append(models, model.ModelID)
+	}
+
+	return models, nil
+}
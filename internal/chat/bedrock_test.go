@@ -0,0 +1,193 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/sigv4"
+)
+
+var testAWSCredentials = sigv4.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}
+
+func TestBedrockMessages(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "How many orders?"},
+		{Role: "assistant", Content: []interface{}{
+			TextContent{Type: "text", Text: "Let me check."},
+			ToolUse{Type: "tool_use", ID: "tool_1", Name: "query_database", Input: map[string]interface{}{"query": "SELECT 1"}},
+		}},
+		{Role: "user", Content: []ToolResult{{Type: "tool_result", ToolUseID: "tool_1", Content: "1", IsError: true}}},
+		// Content unmarshaled from JSON
+		{Role: "assistant", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": ""},
+			map[string]interface{}{"type": "tool_use", "id": "tool_2", "name": "list_tables"},
+		}},
+		{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "tool_result", "tool_use_id": "tool_2", "content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "orders"},
+			}},
+		}},
+		{Role: "assistant", Content: ""},
+	}
+
+	got := bedrockMessages(messages)
+	if len(got) != 5 {
+		t.Fatalf("Expected the empty message to be dropped, got %d messages", len(got))
+	}
+	if len(got[1].Content) != 2 || got[1].Content[1].ToolUse == nil || got[1].Content[1].ToolUse.Input["query"] != "SELECT 1" {
+		t.Errorf("Unexpected tool use message: %+v", got[1])
+	}
+	result := got[2].Content[0].ToolResult
+	if result == nil || result.ToolUseID != "tool_1" || result.Status != "error" || result.Content[0].Text != "1" {
+		t.Errorf("Unexpected tool result: %+v", got[2])
+	}
+	if len(got[3].Content) != 1 || got[3].Content[0].ToolUse.Input == nil {
+		t.Errorf("Expected the empty text to be dropped and the input to be set: %+v", got[3])
+	}
+	if got[4].Content[0].ToolResult.Content[0].Text != "orders" {
+		t.Errorf("Unexpected tool result: %+v", got[4])
+	}
+}
+
+func TestBedrockClient_Chat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The colon of the model ID is escaped
+		if r.RequestURI != "/model/anthropic.example-model-v1%3A0/converse" {
+			t.Errorf("Unexpected request URI %s", r.RequestURI)
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/us-east-1/bedrock/aws4_request") {
+			t.Errorf("Unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+
+		var req bedrockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if len(req.System) != 2 || req.System[1].Text != "Schema context" {
+			t.Errorf("Unexpected system prompt: %+v", req.System)
+		}
+		if req.ToolConfig == nil || len(req.ToolConfig.Tools) != 1 {
+			t.Errorf("Expected the tool config, got %+v", req.ToolConfig)
+		}
+		if req.InferenceConfig.MaxTokens != 1024 {
+			t.Errorf("Unexpected inference config: %+v", req.InferenceConfig)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"output": {"message": {"role": "assistant", "content": [
+				{"text": "Let me check."},
+				{"toolUse": {"toolUseId": "tool_1", "name": "query_database", "input": {"query": "SELECT 1"}}}
+			]}},
+			"stopReason": "tool_use",
+			"usage": {"inputTokens": 20, "outputTokens": 10, "totalTokens": 30}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewBedrockClient("us-east-1", server.URL, testAWSCredentials, "anthropic.example-model-v1:0", 1024, 0.7, true)
+	ctx := WithSystemContext(context.Background(), "Schema context")
+	var streamed string
+	ctx = WithTextStream(ctx, func(text string) { streamed += text })
+	tools := []mcp.Tool{{Name: "query_database", Description: "Run a query", InputSchema: mcp.InputSchema{Type: "object"}}}
+
+	response, err := client.Chat(ctx, []Message{{Role: "user", Content: "Hello"}}, tools)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if response.StopReason != "tool_use" || len(response.Content) != 2 {
+		t.Fatalf("Unexpected response: %+v", response)
+	}
+	toolUse, ok := response.Content[1].(ToolUse)
+	if !ok || toolUse.ID != "tool_1" || toolUse.Input["query"] != "SELECT 1" {
+		t.Errorf("Unexpected tool use: %+v", response.Content[1])
+	}
+	if streamed != "Let me check." {
+		t.Errorf("Expected the text to be streamed once complete, got %q", streamed)
+	}
+	if response.TokenUsage == nil || response.TokenUsage.TotalTokens != 30 {
+		t.Errorf("Unexpected token usage: %+v", response.TokenUsage)
+	}
+}
+
+func TestBedrockClient_Titan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req bedrockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		// Titan accepts neither a system prompt nor tools, so the
+		// instructions start the first message
+		if len(req.System) != 0 || req.ToolConfig != nil {
+			t.Errorf("Expected no system prompt or tools, got %+v", req)
+		}
+		if len(req.Messages[0].Content) != 2 || !strings.Contains(req.Messages[0].Content[0].Text, "PostgreSQL") {
+			t.Errorf("Expected the instructions in the first message, got %+v", req.Messages[0])
+		}
+		w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[{"text":"Hi"}]}},"stopReason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	client := NewBedrockClient("us-east-1", server.URL, testAWSCredentials, "amazon.titan-text-express-v1", 512, 0, false)
+	tools := []mcp.Tool{{Name: "query_database", InputSchema: mcp.InputSchema{Type: "object"}}}
+	if _, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "Hello"}}, tools); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+}
+
+func TestBedrockClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-amzn-ErrorType", "ThrottlingException")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"message":"Too many requests, please wait before trying again."}`))
+	}))
+	defer server.Close()
+
+	client := NewBedrockClient("us-east-1", server.URL, testAWSCredentials, "example-model", 512, 0, false)
+	_, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "Hello"}}, []mcp.Tool{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || !strings.Contains(err.Error(), "Too many requests") {
+		t.Errorf("Expected a rate limit API error, got %v", err)
+	}
+}
+
+func TestBedrockClient_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foundation-models" || r.URL.Query().Get("byOutputModality") != "TEXT" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"modelSummaries":[
+			{"modelId":"anthropic.example-model-v1:0"},
+			{"modelId":"amazon.titan-embed-text-v2:0"},
+			{"modelId":"amazon.titan-text-express-v1"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewBedrockClient("us-east-1", "", testAWSCredentials, "", 0, 0, false).(*bedrockClient)
+	client.controlURL = server.URL
+	models, err := client.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 2 || models[0] != "anthropic.example-model-v1:0" || models[1] != "amazon.titan-text-express-v1" {
+		t.Errorf("ListModels() = %v", models)
+	}
+}
@@ -21,9 +21,13 @@ import (
 	"os/exec"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/sigv4"
+	"pgedge-postgres-mcp/internal/tokenbudget"
 
 	"github.com/chzyer/readline"
 )
@@ -41,6 +45,11 @@ type Client struct {
 	preferences           *Preferences
 	conversations         *ConversationsClient
 	currentConversationID string
+	memories              *MemoriesClient
+	preferencesSync       *PreferencesClient // nil when preferences are not synced
+	overrides             ConfigOverrides
+	memoryContext         string // Remembered facts added to the system prompt
+	nonInteractive        bool   // Never prompt on the terminal (scripted mode)
 }
 
 // NewClient creates a new chat client
@@ -73,7 +82,7 @@ func NewClient(cfg *Config, overrides *ConfigOverrides) (*Client, error) {
 			// Use first configured provider (anthropic > openai > ollama)
 			configuredProviders := cfg.GetConfiguredProviders()
 			if len(configuredProviders) == 0 {
-				return nil, fmt.Errorf("no LLM provider configured (set API key for anthropic, openai, or ollama URL)")
+				return nil, fmt.Errorf("no LLM provider configured (set API key for anthropic, openai, or azure, AWS credentials for bedrock, or ollama URL)")
 			}
 			cfg.LLM.Provider = configuredProviders[0]
 		}
@@ -96,6 +105,7 @@ func NewClient(cfg *Config, overrides *ConfigOverrides) (*Client, error) {
 		ui:          ui,
 		messages:    []Message{},
 		preferences: prefs,
+		overrides:   *overrides,
 	}, nil
 }
 
@@ -116,11 +126,39 @@ func (c *Client) Run(ctx context.Context) error {
 	// This fixes issues if a previous run exited without restoring terminal settings
 	c.sanitizeTerminal()
 
+	err := c.start(ctx)
+	if c.mcp != nil {
+		defer c.mcp.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	// Print welcome message with version info
+	serverName, serverVersion := c.mcp.GetServerInfo()
+	c.ui.PrintWelcome(ClientVersion, serverVersion)
+	c.ui.PrintSystemMessage(fmt.Sprintf("Connected to %s (%d tools, %d resources, %d prompts)", serverName, len(c.tools), len(c.resources), len(c.prompts)))
+	c.ui.PrintSystemMessage(fmt.Sprintf("Using LLM: %s (%s)", c.config.LLM.Provider, c.config.LLM.Model))
+
+	// Display current database
+	if databases, current, err := c.mcp.ListDatabases(ctx); err == nil && len(databases) > 0 {
+		c.ui.PrintSystemMessage(fmt.Sprintf("Database: %s", current))
+	}
+
+	c.ui.PrintSeparator()
+
+	// Start chat loop
+	return c.chatLoop(ctx)
+}
+
+// start connects to the MCP server, discovers its capabilities and
+// initializes the LLM client. The caller must close c.mcp if it was set,
+// even when an error is returned.
+func (c *Client) start(ctx context.Context) error {
 	// Connect to MCP server
 	if err := c.connectToMCP(ctx); err != nil {
 		return fmt.Errorf("failed to connect to MCP server: %w", err)
 	}
-	defer c.mcp.Close()
 
 	// Initialize MCP connection
 	if err := c.mcp.Initialize(ctx); err != nil {
@@ -160,29 +198,24 @@ func (c *Client) Run(ctx context.Context) error {
 		c.prompts = prompts
 	}
 
+	// Merge preferences saved from other clients (only available with
+	// session authentication)
+	c.pullPreferences(ctx)
+
 	// Restore saved database preference for this server
 	c.restoreDatabasePreference(ctx)
 
+	// Load remembered facts (only available with session authentication)
+	if err := c.loadMemories(ctx); err != nil && c.config.UI.Debug {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load memories: %v\n", err)
+	}
+
 	// Initialize LLM client
 	if err := c.initializeLLM(); err != nil {
 		return fmt.Errorf("failed to initialize LLM: %w", err)
 	}
 
-	// Print welcome message with version info
-	serverName, serverVersion := c.mcp.GetServerInfo()
-	c.ui.PrintWelcome(ClientVersion, serverVersion)
-	c.ui.PrintSystemMessage(fmt.Sprintf("Connected to %s (%d tools, %d resources, %d prompts)", serverName, len(c.tools), len(c.resources), len(c.prompts)))
-	c.ui.PrintSystemMessage(fmt.Sprintf("Using LLM: %s (%s)", c.config.LLM.Provider, c.config.LLM.Model))
-
-	// Display current database
-	if databases, current, err := c.mcp.ListDatabases(ctx); err == nil && len(databases) > 0 {
-		c.ui.PrintSystemMessage(fmt.Sprintf("Database: %s", current))
-	}
-
-	c.ui.PrintSeparator()
-
-	// Start chat loop
-	return c.chatLoop(ctx)
+	return nil
 }
 
 // connectToMCP establishes connection to the MCP server
@@ -200,6 +233,10 @@ func (c *Client) connectToMCP(ctx context.Context) error {
 			username := c.config.MCP.Username
 			password := c.config.MCP.Password
 
+			if c.nonInteractive && (username == "" || password == "") {
+				return fmt.Errorf("username and password are required for user authentication in non-interactive mode")
+			}
+
 			// Prompt for username if not provided
 			if username == "" {
 				var err error
@@ -235,6 +272,9 @@ func (c *Client) connectToMCP(ctx context.Context) error {
 		} else {
 			// Token authentication mode (default for non-"none", non-"user")
 			token = c.config.MCP.Token
+			if token == "" && c.nonInteractive {
+				return fmt.Errorf("authentication token is required for HTTP mode")
+			}
 			if token == "" {
 				// Prompt for token
 				token = c.ui.PromptForToken()
@@ -265,6 +305,8 @@ func (c *Client) connectToMCP(ctx context.Context) error {
 		c.mcp = NewHTTPClient(url, token)
 		// Initialize conversations client for HTTP mode with authentication
 		c.conversations = NewConversationsClient(url, token)
+		c.memories = NewMemoriesClient(url, token)
+		c.preferencesSync = NewPreferencesClient(url, token)
 	} else {
 		// Stdio mode
 		mcpClient, err := NewStdioClient(c.config.MCP.ServerPath, c.config.MCP.ServerConfigPath)
@@ -358,6 +400,15 @@ func (c *Client) initializeLLM() error {
 	case "openai":
 		tempClient = NewOpenAIClient(
 			c.config.LLM.OpenAIAPIKey, "", 0, 0, false)
+	case "azure":
+		// Deployments cannot be listed, so the configured one is offered
+		tempClient = NewAzureOpenAIClient(
+			c.config.LLM.AzureEndpoint, c.config.LLM.AzureAPIVersion,
+			c.config.LLM.AzureAPIKey, c.config.LLM.AzureDeployment, 0, 0, false)
+	case "bedrock":
+		tempClient = NewBedrockClient(
+			c.config.LLM.BedrockRegion, c.config.LLM.BedrockEndpoint,
+			sigv4.CredentialsFromEnv(), "", 0, 0, false)
 	case "ollama":
 		tempClient = NewOllamaClient(
 			c.config.LLM.OllamaURL, "", false)
@@ -396,7 +447,7 @@ func (c *Client) initializeLLM() error {
 	shouldSave := !selection.hadSavedPref || selection.usedFamilyMatch
 	if shouldSave {
 		c.preferences.SetModelForProvider(provider, selection.model)
-		if err := SavePreferences(c.preferences); err != nil {
+		if err := c.savePreferences(); err != nil {
 			if c.config.UI.Debug {
 				fmt.Fprintf(os.Stderr, "Warning: Failed to save preferences: %v\n", err)
 			}
@@ -421,6 +472,26 @@ func (c *Client) initializeLLM() error {
 			c.config.LLM.Temperature,
 			c.config.UI.Debug,
 		)
+	case "azure":
+		c.llm = NewAzureOpenAIClient(
+			c.config.LLM.AzureEndpoint,
+			c.config.LLM.AzureAPIVersion,
+			c.config.LLM.AzureAPIKey,
+			c.config.LLM.Model,
+			c.config.LLM.MaxTokens,
+			c.config.LLM.Temperature,
+			c.config.UI.Debug,
+		)
+	case "bedrock":
+		c.llm = NewBedrockClient(
+			c.config.LLM.BedrockRegion,
+			c.config.LLM.BedrockEndpoint,
+			sigv4.CredentialsFromEnv(),
+			c.config.LLM.Model,
+			c.config.LLM.MaxTokens,
+			c.config.LLM.Temperature,
+			c.config.UI.Debug,
+		)
 	case "ollama":
 		c.llm = NewOllamaClient(
 			c.config.LLM.OllamaURL,
@@ -456,6 +527,10 @@ func (c *Client) chatLoop(ctx context.Context) error {
 	// Use history file from config
 	historyFile := c.config.HistoryFile
 
+	// Lines ended with Alt+Enter continue on the next line
+	var continued atomic.Bool
+	var input multilineInput
+
 	// Configure readline with custom prompt
 	rl, err := readline.NewEx(&readline.Config{
 		Prompt:                 c.ui.GetPrompt(),
@@ -467,6 +542,14 @@ func (c *Client) chatLoop(ctx context.Context) error {
 		HistorySearchFold:      true, // Enable case-insensitive history search
 		// Unfortunately, chzyer/readline doesn't support prefix-based history filtering
 		// on up/down arrows natively. Users can use Ctrl+R for reverse search.
+		Stdin: readline.NewCancelableStdin(&altEnterReader{r: os.Stdin}),
+		FuncFilterInputRune: func(r rune) (rune, bool) {
+			if r == altEnterRune {
+				continued.Store(true)
+				return r, false
+			}
+			return r, true
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to initialize readline: %w", err)
@@ -481,10 +564,22 @@ func (c *Client) chatLoop(ctx context.Context) error {
 
 	// Main readline loop
 	for {
+		if input.active() {
+			rl.SetPrompt(c.ui.GetContinuationPrompt())
+		} else {
+			rl.SetPrompt(c.ui.GetPrompt())
+		}
+
 		// This blocks until user provides input
 		line, err := rl.Readline()
 
 		if err != nil {
+			// Ctrl+C discards a multi-line prompt rather than exiting
+			if err == readline.ErrInterrupt && input.active() {
+				input.reset()
+				continued.Store(false)
+				continue
+			}
 			// Handle various exit conditions
 			if err == readline.ErrInterrupt || err == io.EOF {
 				fmt.Println()
@@ -500,7 +595,11 @@ func (c *Client) chatLoop(ctx context.Context) error {
 			return fmt.Errorf("readline error: %w", err)
 		}
 
-		userInput := strings.TrimSpace(line)
+		userInput, complete := input.add(line, continued.Swap(false))
+		if !complete {
+			continue
+		}
+		userInput = strings.TrimSpace(userInput)
 		if userInput == "" {
 			continue
 		}
@@ -571,80 +670,119 @@ type CompactionInfo struct {
 	CompressionRatio float64 `json:"compression_ratio"`
 }
 
-// estimateTokens estimates the number of tokens in a string.
-// Uses a rough heuristic of ~3.5 characters per token.
-func estimateTokens(text string) int {
-	if text == "" {
-		return 0
-	}
-	// Rough heuristic: ~4 characters per token for English, ~3 for code/JSON
-	// Use 3.5 as a middle ground to be conservative
-	return (len(text) + 2) / 3 // Rounds up, slightly more conservative than /3.5
+// budget returns the token budget of the current provider and model
+func (c *Client) budget() *tokenbudget.Budget {
+	return tokenbudget.New(c.config.LLM.Provider, c.config.LLM.Model, c.config.LLM.ContextWindow)
 }
 
-// estimateTotalTokens estimates the total tokens in a message array.
-func estimateTotalTokens(messages []Message) int {
-	total := 0
+// CountMessageTokens counts the tokens of a conversation with a provider's
+// tokenizer, including the overhead of each message and of the request
+func CountMessageTokens(tokenizer *tokenbudget.Tokenizer, messages []Message) int {
+	if len(messages) == 0 {
+		return 0
+	}
+	total := tokenizer.RequestOverhead
 	for _, msg := range messages {
+		total += tokenizer.MessageOverhead
 		switch content := msg.Content.(type) {
 		case string:
-			total += estimateTokens(content)
+			total += tokenizer.Count(content)
 		case []interface{}:
-			// Handle tool_use and tool_result arrays
+			// Handle text, tool_use and tool_result blocks, as typed values
+			// or as decoded JSON
 			for _, item := range content {
-				if m, ok := item.(map[string]interface{}); ok {
-					if text, ok := m["text"].(string); ok {
-						total += estimateTokens(text)
-					}
-					if input, ok := m["input"]; ok {
-						if jsonBytes, err := json.Marshal(input); err == nil {
-							total += estimateTokens(string(jsonBytes))
-						}
-					}
-					if c, ok := m["content"]; ok {
-						if text, ok := c.(string); ok {
-							total += estimateTokens(text)
-						}
-					}
-				}
+				total += countBlockTokens(tokenizer, item)
 			}
 		case []ToolResult:
 			for _, tr := range content {
-				switch c := tr.Content.(type) {
-				case []mcp.ContentItem:
-					for _, item := range c {
-						total += estimateTokens(item.Text)
-					}
-				case string:
-					total += estimateTokens(c)
-				}
+				total += countToolResultTokens(tokenizer, tr.Content)
 			}
 		}
-		// Add overhead for message structure (~10 tokens per message)
-		total += 10
 	}
 	return total
 }
 
+// countBlockTokens counts the tokens of one content block
+func countBlockTokens(tokenizer *tokenbudget.Tokenizer, block interface{}) int {
+	switch b := block.(type) {
+	case TextContent:
+		return tokenizer.Count(b.Text)
+	case ToolUse:
+		return tokenizer.Count(b.Name) + countJSONTokens(tokenizer, b.Input)
+	case ToolResult:
+		return countToolResultTokens(tokenizer, b.Content)
+	case map[string]interface{}:
+		total := 0
+		if text, ok := b["text"].(string); ok {
+			total += tokenizer.Count(text)
+		}
+		if name, ok := b["name"].(string); ok {
+			total += tokenizer.Count(name)
+		}
+		if input, ok := b["input"]; ok {
+			total += countJSONTokens(tokenizer, input)
+		}
+		if content, ok := b["content"]; ok {
+			total += countToolResultTokens(tokenizer, content)
+		}
+		return total
+	default:
+		return 0
+	}
+}
+
+// countToolResultTokens counts the tokens of a tool result's content
+func countToolResultTokens(tokenizer *tokenbudget.Tokenizer, content interface{}) int {
+	switch c := content.(type) {
+	case string:
+		return tokenizer.Count(c)
+	case []mcp.ContentItem:
+		total := 0
+		for _, item := range c {
+			total += tokenizer.Count(item.Text)
+		}
+		return total
+	case []interface{}:
+		total := 0
+		for _, item := range c {
+			if m, ok := item.(map[string]interface{}); ok {
+				if text, ok := m["text"].(string); ok {
+					total += tokenizer.Count(text)
+				}
+			}
+		}
+		return total
+	default:
+		return 0
+	}
+}
+
+// countJSONTokens counts the tokens of a value sent as JSON
+func countJSONTokens(tokenizer *tokenbudget.Tokenizer, value interface{}) int {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return tokenizer.Count(string(data))
+}
+
 // compactMessages reduces the message history to prevent token overflow.
 // It tries to use the server-side smart compaction if available in HTTP mode,
 // falling back to local basic compaction if needed.
 func (c *Client) compactMessages(messages []Message) []Message {
 	const maxRecentMessages = 10
-	const maxTokens = 100000
-	// Compact if estimated tokens exceed this threshold.
-	// Note: Anthropic rate limits are typically 30k-60k input tokens/minute cumulative.
-	// Setting lower allows multiple requests within the rate limit window.
-	const tokenCompactionThreshold = 15000
 
 	const minMessagesForCompaction = 15 // Don't compact unless we have at least 15 messages
 	const minSavingsThreshold = 5       // Only compact if we can save at least 5 messages
 
-	// Estimate total tokens in the conversation
-	estimatedTokens := estimateTotalTokens(messages)
+	// Count the tokens in the conversation with the model's tokenizer; the
+	// budget compacts well below the context window so requests also fit
+	// the providers' input token rate limits
+	budget := c.budget()
+	usage := budget.Usage(CountMessageTokens(budget.Tokenizer, messages))
 
 	// Check if we should compact based on token count OR message count
-	shouldCompactByTokens := estimatedTokens > tokenCompactionThreshold
+	shouldCompactByTokens := usage.ShouldCompact
 	shouldCompactByMessages := len(messages) >= minMessagesForCompaction
 
 	// If neither threshold is met, skip compaction
@@ -655,8 +793,8 @@ func (c *Client) compactMessages(messages []Message) []Message {
 	// Log why we're compacting (for debugging)
 	if c.config.UI.Debug {
 		if shouldCompactByTokens {
-			fmt.Fprintf(os.Stderr, "[DEBUG] Compaction triggered by token count: ~%d tokens (threshold: %d)\n",
-				estimatedTokens, tokenCompactionThreshold)
+			fmt.Fprintf(os.Stderr, "[DEBUG] Compaction triggered by token count: ~%d tokens (threshold: %d, %.0f%% of the %d token context window)\n",
+				usage.Tokens, usage.CompactAt, usage.Percent, usage.ContextWindow)
 		} else {
 			fmt.Fprintf(os.Stderr, "[DEBUG] Compaction triggered by message count: %d messages (threshold: %d)\n",
 				len(messages), minMessagesForCompaction)
@@ -672,7 +810,7 @@ func (c *Client) compactMessages(messages []Message) []Message {
 	}
 
 	// Try server-side smart compaction if in HTTP mode
-	if compacted, ok := c.tryServerCompaction(messages, maxTokens, maxRecentMessages, minSavingsThreshold); ok {
+	if compacted, ok := c.tryServerCompaction(messages, budget.Target, maxRecentMessages, minSavingsThreshold); ok {
 		return compacted
 	}
 
@@ -859,8 +997,6 @@ func (c *Client) hasToolResults(msg Message) bool {
 }
 
 func (c *Client) processQuery(ctx context.Context, query string) error {
-	const maxAgenticLoops = 50 // Maximum iterations to prevent infinite loops
-
 	// Add user message to conversation history (skip if empty, used for prompts)
 	if query != "" {
 		c.messages = append(c.messages, Message{
@@ -873,6 +1009,9 @@ func (c *Client) processQuery(ctx context.Context, query string) error {
 	// This allows the user to cancel with Escape key
 	reqCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	if c.memoryContext != "" {
+		reqCtx = WithSystemContext(reqCtx, c.memoryContext)
+	}
 
 	// Start thinking animation
 	thinkingDone := make(chan struct{})
@@ -881,39 +1020,72 @@ func (c *Client) processQuery(ctx context.Context, query string) error {
 	// Start listening for Escape key to cancel the request
 	go ListenForEscape(ctx, thinkingDone, cancel)
 
+	finalText, err := c.runAgenticLoop(reqCtx, func(toolUse ToolUse) {
+		close(thinkingDone)
+		// Give the thinking animation goroutine time to clear the line
+		time.Sleep(50 * time.Millisecond)
+		c.ui.PrintToolExecution(toolUse.Name, toolUse.Input)
+		thinkingDone = make(chan struct{})
+		go c.ui.ShowThinking(reqCtx, thinkingDone)
+		// Start new Escape listener for this tool execution
+		go ListenForEscape(ctx, thinkingDone, cancel)
+	}, nil, func(toolUse ToolUse, statement string) bool {
+		// Stop the animation and the Escape listener, which reads the
+		// terminal, while the user answers
+		close(thinkingDone)
+		time.Sleep(50 * time.Millisecond)
+		approved := c.ui.PromptForApproval(reqCtx, toolUse.Name, statement)
+		thinkingDone = make(chan struct{})
+		go c.ui.ShowThinking(reqCtx, thinkingDone)
+		go ListenForEscape(ctx, thinkingDone, cancel)
+		return approved
+	})
+
+	close(thinkingDone)
+	// Wait for ListenForEscape to restore terminal from raw mode
+	time.Sleep(50 * time.Millisecond)
+
+	if err != nil {
+		// Check if this was a user cancellation (Escape key)
+		if reqCtx.Err() == context.Canceled && ctx.Err() == nil {
+			// User canceled with Escape - keep the query in history
+			// but don't save the Escape keypress
+			c.ui.PrintCanceled()
+			return nil // Return without error to continue the chat loop
+		}
+		return err
+	}
+
+	c.ui.PrintAssistantResponse(finalText)
+	return nil
+}
+
+// runAgenticLoop sends the conversation to the LLM, executing any tools it
+// asks for, until it produces a final answer. The answer is added to the
+// conversation history and returned. beforeTool and afterTool, when set,
+// are called around each tool execution; approve confirms tool calls that
+// modify the database (see approveWrites).
+func (c *Client) runAgenticLoop(ctx context.Context, beforeTool func(ToolUse), afterTool func(ToolUse, ToolResult), approve func(ToolUse, string) bool) (string, error) {
+	const maxAgenticLoops = 50 // Maximum iterations to prevent infinite loops
+
 	// Agentic loop (allow up to maxAgenticLoops iterations for complex queries)
 	for iteration := 0; iteration < maxAgenticLoops; iteration++ {
 		// Compact message history to prevent token overflow
 		compactedMessages := c.compactMessages(c.messages)
 
 		// Get response from LLM with compacted history
-		response, err := c.llm.Chat(reqCtx, compactedMessages, c.tools)
+		response, err := c.llm.Chat(ctx, compactedMessages, c.tools)
 		if err != nil {
-			close(thinkingDone)
-			// Wait for ListenForEscape to restore terminal from raw mode
-			time.Sleep(50 * time.Millisecond)
-			// Check if this was a user cancellation (Escape key)
-			if reqCtx.Err() == context.Canceled && ctx.Err() == nil {
-				// User canceled with Escape - keep the query in history
-				// but don't save the Escape keypress
-				c.ui.PrintCanceled()
-				return nil // Return without error to continue the chat loop
-			}
-			return fmt.Errorf("LLM error: %w", err)
+			return "", fmt.Errorf("LLM error: %w", err)
 		}
 
 		// Check if LLM wants to use tools
 		if response.StopReason == "tool_use" {
-			// Extract tool uses and text content
+			// Extract tool uses
 			var toolUses []ToolUse
-			var textParts []string
-
 			for _, item := range response.Content {
-				switch v := item.(type) {
-				case ToolUse:
+				if v, ok := item.(ToolUse); ok {
 					toolUses = append(toolUses, v)
-				case TextContent:
-					_ = append(textParts, v.Text) // Not used in this context, just checking type
 				}
 			}
 
@@ -924,48 +1096,13 @@ func (c *Client) processQuery(ctx context.Context, query string) error {
 			})
 
 			// Execute all tool calls
-			toolResults := []ToolResult{}
-			for _, toolUse := range toolUses {
-				close(thinkingDone)
-				// Give the thinking animation goroutine time to clear the line
-				time.Sleep(50 * time.Millisecond)
-				c.ui.PrintToolExecution(toolUse.Name, toolUse.Input)
-				thinkingDone = make(chan struct{})
-				go c.ui.ShowThinking(reqCtx, thinkingDone)
-				// Start new Escape listener for this tool execution
-				go ListenForEscape(ctx, thinkingDone, cancel)
-
-				result, err := c.mcp.CallTool(reqCtx, toolUse.Name, toolUse.Input)
-				if err != nil {
-					// Check if this was a user cancellation (Escape key)
-					if reqCtx.Err() == context.Canceled && ctx.Err() == nil {
-						close(thinkingDone)
-						// User canceled with Escape - keep the query in history
-						// but don't save the Escape keypress
-						c.ui.PrintCanceled()
-						return nil
-					}
-					toolResults = append(toolResults, ToolResult{
-						Type:      "tool_result",
-						ToolUseID: toolUse.ID,
-						Content:   fmt.Sprintf("Error: %v", err),
-						IsError:   true,
-					})
-				} else {
-					toolResults = append(toolResults, ToolResult{
-						Type:      "tool_result",
-						ToolUseID: toolUse.ID,
-						Content:   result.Content,
-						IsError:   result.IsError,
-					})
-
-					// Refresh tool list after successful manage_connections operation
-					// This ensures we get the updated tool list when database connection changes
-					if toolUse.Name == "manage_connections" && !result.IsError {
-						if newTools, err := c.mcp.ListTools(reqCtx); err == nil {
-							c.tools = newTools
-						}
-					}
+			toolResults, err := c.executeTools(ctx, toolUses, beforeTool, approve)
+			if err != nil {
+				return "", err
+			}
+			if afterTool != nil {
+				for i, toolUse := range toolUses {
+					afterTool(toolUse, toolResults[i])
 				}
 			}
 
@@ -979,21 +1116,14 @@ func (c *Client) processQuery(ctx context.Context, query string) error {
 			continue
 		}
 
-		// Got final response
-		close(thinkingDone)
-		// Wait for ListenForEscape to restore terminal from raw mode
-		time.Sleep(50 * time.Millisecond)
-
-		// Extract and display text content
+		// Got final response - extract the text content
 		var textParts []string
 		for _, item := range response.Content {
 			if text, ok := item.(TextContent); ok {
 				textParts = append(textParts, text.Text)
 			}
 		}
-
 		finalText := strings.Join(textParts, "\n")
-		c.ui.PrintAssistantResponse(finalText)
 
 		// Add assistant's response to history
 		c.messages = append(c.messages, Message{
@@ -1001,16 +1131,143 @@ func (c *Client) processQuery(ctx context.Context, query string) error {
 			Content: finalText,
 		})
 
-		return nil
+		return finalText, nil
 	}
 
-	close(thinkingDone)
-	// Wait for ListenForEscape to restore terminal from raw mode
-	time.Sleep(50 * time.Millisecond)
-	return fmt.Errorf("reached maximum number of tool calls (%d)", maxAgenticLoops)
+	return "", fmt.Errorf("reached maximum number of tool calls (%d)", maxAgenticLoops)
+}
+
+// executeTools runs the tool calls from one LLM turn and returns their
+// results in the same order. Writes that need approval are confirmed
+// before any call runs. Up to MCP.MaxParallelTools calls run at once; a
+// turn that switches connections with manage_connections runs in order.
+func (c *Client) executeTools(ctx context.Context, toolUses []ToolUse, beforeTool func(ToolUse), approve func(ToolUse, string) bool) ([]ToolResult, error) {
+	toolResults := make([]ToolResult, len(toolUses))
+	declined := c.approveWrites(toolUses, approve)
+	for i, result := range declined {
+		toolResults[i] = result
+	}
+
+	parallel := c.config.MCP.MaxParallelTools > 1 && len(toolUses) > 1
+	for _, toolUse := range toolUses {
+		if toolUse.Name == "manage_connections" {
+			parallel = false
+		}
+	}
+
+	if parallel {
+		if beforeTool != nil {
+			for i, toolUse := range toolUses {
+				if _, ok := declined[i]; !ok {
+					beforeTool(toolUse)
+				}
+			}
+		}
+
+		sem := make(chan struct{}, c.config.MCP.MaxParallelTools)
+		var wg sync.WaitGroup
+		for i, toolUse := range toolUses {
+			if _, ok := declined[i]; ok {
+				continue
+			}
+			wg.Add(1)
+			go func(i int, toolUse ToolUse) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				toolResults[i] = c.callTool(ctx, toolUse)
+			}(i, toolUse)
+		}
+		wg.Wait()
+	} else {
+		for i, toolUse := range toolUses {
+			if _, ok := declined[i]; ok {
+				continue
+			}
+			if beforeTool != nil {
+				beforeTool(toolUse)
+			}
+			toolResults[i] = c.callTool(ctx, toolUse)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+
+	// A canceled request ends the loop rather than being reported to the
+	// LLM as tool failures
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// Refresh tool list after successful manage_connections operation
+	// This ensures we get the updated tool list when database connection changes
+	for i, toolUse := range toolUses {
+		if toolUse.Name == "manage_connections" && !toolResults[i].IsError {
+			if newTools, err := c.mcp.ListTools(ctx); err == nil {
+				c.tools = newTools
+			}
+			break
+		}
+	}
+
+	return toolResults, nil
+}
+
+// callTool executes a single tool call on the MCP server. A call that
+// runs longer than MCP.ToolTimeoutSeconds is reported as failed without
+// waiting for it: over HTTP its request is canceled, while in stdio mode
+// the server's late response is read and discarded before the next request.
+func (c *Client) callTool(ctx context.Context, toolUse ToolUse) ToolResult {
+	timeout := time.Duration(c.config.MCP.ToolTimeoutSeconds) * time.Second
+	var callCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		callCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	type outcome struct {
+		result mcp.ToolResponse
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := c.mcp.CallTool(callCtx, toolUse.Name, toolUse.Input)
+		done <- outcome{result, err}
+	}()
+
+	var result mcp.ToolResponse
+	var err error
+	select {
+	case o := <-done:
+		result, err = o.result, o.err
+	case <-callCtx.Done():
+		err = callCtx.Err()
+	}
+	if err != nil && ctx.Err() == nil && callCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%s timed out after %s", toolUse.Name, timeout)
+	}
+	if err != nil {
+		return ToolResult{
+			Type:      "tool_result",
+			ToolUseID: toolUse.ID,
+			Content:   fmt.Sprintf("Error: %v", err),
+			IsError:   true,
+		}
+	}
+	return ToolResult{
+		Type:      "tool_result",
+		ToolUseID: toolUse.ID,
+		Content:   result.Content,
+		IsError:   result.IsError,
+	}
 }
 
-// SavePreferences saves the current preferences to disk
+// SavePreferences saves the current preferences to disk and, when synced,
+// to the server
 func (c *Client) SavePreferences() error {
 	if c.preferences == nil {
 		return nil
@@ -1020,7 +1277,7 @@ func (c *Client) SavePreferences() error {
 	// c.preferences and c.config, and save immediately. We don't want to
 	// overwrite c.preferences.LastProvider from c.config here because
 	// c.config may have been loaded from file with different values.
-	return SavePreferences(c.preferences)
+	return c.savePreferences()
 }
 
 // modelSelectionResult contains the result of model selection
@@ -1209,6 +1466,8 @@ func getDefaultModelForProvider(provider string) string {
 		return "claude-sonnet-4-5-20250929"
 	case "openai":
 		return "gpt-4o"
+	case "bedrock":
+		return DefaultBedrockModel
 	case "ollama":
 		return "qwen3-coder:latest"
 	default:
@@ -13,9 +13,12 @@ package chat
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -1030,3 +1033,300 @@ func TestClient_ConnectToMCP_URLFormatting(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_ExecuteTools_Parallel(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	// Mock server whose tool calls take a while, tracking how many run at once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		switch req["method"] {
+		case "initialize":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      req["id"],
+				"result": map[string]interface{}{
+					"protocolVersion": "1.0.0",
+					"serverInfo":      map[string]interface{}{"name": "test-server", "version": "1.0.0"},
+				},
+			})
+		case "tools/list":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      req["id"],
+				"result":  map[string]interface{}{"tools": []interface{}{}},
+			})
+		case "tools/call":
+			current := atomic.AddInt32(&inFlight, 1)
+			for {
+				seen := atomic.LoadInt32(&maxInFlight)
+				if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+
+			args := req["params"].(map[string]interface{})["arguments"].(map[string]interface{})
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      req["id"],
+				"result": map[string]interface{}{
+					"content": []interface{}{
+						map[string]interface{}{"type": "text", "text": "rows for " + args["table"].(string)},
+					},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client := newScriptTestClient(t, server.URL, &mockLLMClient{})
+	defer client.mcp.Close()
+	client.config.MCP.MaxParallelTools = 2
+
+	var toolUses []ToolUse
+	for i, table := range []string{"orders", "customers", "products", "invoices"} {
+		toolUses = append(toolUses, ToolUse{
+			Type:  "tool_use",
+			ID:    fmt.Sprintf("tool_%d", i),
+			Name:  "count_rows",
+			Input: map[string]interface{}{"table": table},
+		})
+	}
+
+	var announced []string
+	results, err := client.executeTools(context.Background(), toolUses, func(toolUse ToolUse) {
+		announced = append(announced, toolUse.ID)
+	}, nil)
+	if err != nil {
+		t.Fatalf("executeTools() error = %v", err)
+	}
+
+	if got := atomic.LoadInt32(&maxInFlight); got != 2 {
+		t.Errorf("expected 2 tool calls in flight at once, got %d", got)
+	}
+	if len(announced) != len(toolUses) {
+		t.Errorf("expected every tool call to be announced, got %v", announced)
+	}
+
+	// Results come back in the order the LLM asked for them
+	for i, result := range results {
+		if result.ToolUseID != toolUses[i].ID {
+			t.Errorf("result %d has ToolUseID %s, want %s", i, result.ToolUseID, toolUses[i].ID)
+		}
+		want := "rows for " + toolUses[i].Input["table"].(string)
+		if text := toolResultText(result.Content); text != want {
+			t.Errorf("result %d = %q, want %q", i, text, want)
+		}
+	}
+
+	// Without parallelism the calls run one at a time
+	atomic.StoreInt32(&maxInFlight, 0)
+	client.config.MCP.MaxParallelTools = 1
+	if _, err := client.executeTools(context.Background(), toolUses[:2], nil, nil); err != nil {
+		t.Fatalf("executeTools() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got != 1 {
+		t.Errorf("expected sequential tool calls, got %d in flight", got)
+	}
+}
+
+func TestClient_ExecuteTools_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		var result interface{}
+		switch req["method"] {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": "1.0.0",
+				"serverInfo":      map[string]interface{}{"name": "test-server", "version": "1.0.0"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []interface{}{}}
+		case "tools/call":
+			// The slow tool runs until its request is canceled
+			args := req["params"].(map[string]interface{})["arguments"].(map[string]interface{})
+			if args["table"] == "slow" {
+				<-r.Context().Done()
+				return
+			}
+			result = map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": "done"}},
+			}
+		default:
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req["id"], "result": result})
+	}))
+	defer server.Close()
+
+	client := newScriptTestClient(t, server.URL, &mockLLMClient{})
+	defer client.mcp.Close()
+	client.config.MCP.MaxParallelTools = 2
+	client.config.MCP.ToolTimeoutSeconds = 1
+
+	toolUses := []ToolUse{
+		{Type: "tool_use", ID: "tool_slow", Name: "count_rows", Input: map[string]interface{}{"table": "slow"}},
+		{Type: "tool_use", ID: "tool_fast", Name: "count_rows", Input: map[string]interface{}{"table": "orders"}},
+	}
+	results, err := client.executeTools(context.Background(), toolUses, nil, nil)
+	if err != nil {
+		t.Fatalf("executeTools() error = %v", err)
+	}
+
+	// The slow call fails with a timeout without affecting the other call
+	if !results[0].IsError || results[0].ToolUseID != "tool_slow" || !strings.Contains(toolResultText(results[0].Content), "timed out after 1s") {
+		t.Errorf("expected the slow call to time out, got %+v", results[0])
+	}
+	if results[1].IsError || results[1].ToolUseID != "tool_fast" || toolResultText(results[1].Content) != "done" {
+		t.Errorf("expected the fast call to succeed, got %+v", results[1])
+	}
+}
+
+func TestClient_ExecuteTools_Approval(t *testing.T) {
+	var called []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		var result interface{}
+		switch req["method"] {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": "1.0.0",
+				"serverInfo":      map[string]interface{}{"name": "test-server", "version": "1.0.0"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []interface{}{}}
+		case "tools/call":
+			args := req["params"].(map[string]interface{})["arguments"].(map[string]interface{})
+			mu.Lock()
+			called = append(called, args["script"].(string))
+			mu.Unlock()
+			result = map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": "done"}},
+			}
+		default:
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req["id"], "result": result})
+	}))
+	defer server.Close()
+
+	client := newScriptTestClient(t, server.URL, &mockLLMClient{})
+	defer client.mcp.Close()
+	client.config.MCP.MaxParallelTools = 2
+	client.config.MCP.ApproveWrites = true
+
+	toolUses := []ToolUse{
+		{Type: "tool_use", ID: "tool_a", Name: "execute_script", Input: map[string]interface{}{"script": "DELETE FROM a"}},
+		{Type: "tool_use", ID: "tool_b", Name: "execute_script", Input: map[string]interface{}{"script": "DELETE FROM b"}},
+	}
+	var announced []string
+	results, err := client.executeTools(context.Background(), toolUses, func(toolUse ToolUse) {
+		announced = append(announced, toolUse.ID)
+	}, func(toolUse ToolUse, statement string) bool {
+		return statement == "DELETE FROM b"
+	})
+	if err != nil {
+		t.Fatalf("executeTools() error = %v", err)
+	}
+
+	// Only the approved call runs; the declined one is reported to the LLM
+	if len(called) != 1 || called[0] != "DELETE FROM b" || len(announced) != 1 || announced[0] != "tool_b" {
+		t.Errorf("expected only the approved call to run, got %v (announced %v)", called, announced)
+	}
+	if !results[0].IsError || results[0].ToolUseID != "tool_a" || !strings.Contains(toolResultText(results[0].Content), "declined") {
+		t.Errorf("expected the first call to be declined, got %+v", results[0])
+	}
+	if results[1].IsError || toolResultText(results[1].Content) != "done" {
+		t.Errorf("expected the second call to succeed, got %+v", results[1])
+	}
+}
+
+func TestClient_HandleSQLCommand(t *testing.T) {
+	var calls []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		var result interface{}
+		switch req["method"] {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": "1.0.0",
+				"serverInfo":      map[string]interface{}{"name": "test-server", "version": "1.0.0"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []interface{}{}}
+		case "tools/call":
+			params := req["params"].(map[string]interface{})
+			args := params["arguments"].(map[string]interface{})
+			args["tool"] = params["name"]
+			calls = append(calls, args)
+			result = map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{
+					"type": "text",
+					"text": "SQL Query:\nSELECT id FROM users\n\nResults (50 rows shown, more available - use offset=50 for next page or count_rows for total):\nid\n1",
+				}},
+			}
+		default:
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req["id"], "result": result})
+	}))
+	defer server.Close()
+
+	llm := &mockLLMClient{}
+	client := newScriptTestClient(t, server.URL, llm)
+	defer client.mcp.Close()
+	client.nonInteractive = true
+
+	if !client.HandleSlashCommand(context.Background(), ParseSlashCommand(`/sql SELECT id FROM users WHERE name = "alice"`)) {
+		t.Fatal("expected /sql to be handled")
+	}
+
+	// The query is sent as typed, one page at a time; without a terminal
+	// only the first page is shown
+	if len(calls) != 1 {
+		t.Fatalf("expected one tool call, got %d", len(calls))
+	}
+	if calls[0]["tool"] != "query_database" || calls[0]["query"] != `SELECT id FROM users WHERE name = "alice"` {
+		t.Errorf("unexpected tool call: %v", calls[0])
+	}
+	if calls[0]["limit"] != float64(sqlPageSize) || calls[0]["offset"] != float64(0) {
+		t.Errorf("unexpected paging: %v", calls[0])
+	}
+
+	// The LLM is not involved and the conversation is unchanged
+	if llm.callCount != 0 || len(client.messages) != 0 {
+		t.Errorf("expected no LLM calls or messages, got %d calls and %d messages", llm.callCount, len(client.messages))
+	}
+}
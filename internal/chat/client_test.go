@@ -14,47 +14,28 @@ import (
 	"testing"
 
 	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/tokenbudget"
 )
 
-func TestEstimateTokens(t *testing.T) {
-	tests := []struct {
-		name string
-		text string
-		want int
-	}{
-		{"empty string", "", 0},
-		{"short string", "hello", 2},                                    // (5 + 2) / 3 = 2
-		{"medium string", "hello world", 4},                             // (11 + 2) / 3 = 4
-		{"long string", "This is a longer string with more words.", 14}, // (42 + 2) / 3 = 14
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := estimateTokens(tt.text)
-			if got != tt.want {
-				t.Errorf("estimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
-			}
-		})
-	}
-}
+func TestCountMessageTokens(t *testing.T) {
+	tokenizer := tokenbudget.ForProvider("openai")
 
-func TestEstimateTotalTokens(t *testing.T) {
 	tests := []struct {
 		name     string
 		messages []Message
-		wantMin  int // We check for minimum since estimation includes overhead
+		want     int
 	}{
 		{
 			name:     "empty messages",
 			messages: []Message{},
-			wantMin:  0,
+			want:     0,
 		},
 		{
 			name: "single user message",
 			messages: []Message{
 				{Role: "user", Content: "hello"},
 			},
-			wantMin: 10, // 2 tokens for "hello" + 10 overhead
+			want: 3 + 4 + 1, // request + message overhead + "hello"
 		},
 		{
 			name: "multiple messages",
@@ -62,15 +43,15 @@ func TestEstimateTotalTokens(t *testing.T) {
 				{Role: "user", Content: "hello"},
 				{Role: "assistant", Content: "hi there"},
 			},
-			wantMin: 20, // 2 + 10 + 3 + 10 = 25, but we just check minimum
+			want: 3 + 4 + 1 + 4 + 2,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := estimateTotalTokens(tt.messages)
-			if got < tt.wantMin {
-				t.Errorf("estimateTotalTokens() = %d, want at least %d", got, tt.wantMin)
+			got := CountMessageTokens(tokenizer, tt.messages)
+			if got != tt.want {
+				t.Errorf("CountMessageTokens() = %d, want %d", got, tt.want)
 			}
 		})
 	}
@@ -500,7 +481,7 @@ func TestLocalCompactMessages(t *testing.T) {
 	}
 }
 
-func TestEstimateTotalTokensWithToolContent(t *testing.T) {
+func TestCountMessageTokensWithToolContent(t *testing.T) {
 	// Test with tool result content
 	messages := []Message{
 		{
@@ -517,9 +498,23 @@ func TestEstimateTotalTokensWithToolContent(t *testing.T) {
 		},
 	}
 
-	tokens := estimateTotalTokens(messages)
-	// Should have some tokens for the content
-	if tokens < 10 {
-		t.Errorf("Expected at least 10 tokens, got %d", tokens)
+	tokenizer := tokenbudget.ForProvider("openai")
+	empty := CountMessageTokens(tokenizer, []Message{{Role: "user", Content: ""}})
+
+	// The tool result's text is counted
+	if tokens := CountMessageTokens(tokenizer, messages); tokens != empty+5 {
+		t.Errorf("Expected %d tokens, got %d", empty+5, tokens)
+	}
+
+	// Typed blocks in assistant responses are counted too
+	assistant := []Message{{
+		Role: "assistant",
+		Content: []interface{}{
+			TextContent{Type: "text", Text: "Let me check"},
+			ToolUse{Type: "tool_use", ID: "123", Name: "query_database", Input: map[string]interface{}{"query": "SELECT 1"}},
+		},
+	}}
+	if tokens := CountMessageTokens(tokenizer, assistant); tokens <= empty+3 {
+		t.Errorf("Expected the text and the tool call to be counted, got %d tokens", tokens)
 	}
 }
@@ -25,6 +25,7 @@ import (
 type SlashCommand struct {
 	Command string
 	Args    []string
+	Raw     string // Text after the command, unparsed
 }
 
 // ParseSlashCommand parses a slash command from user input
@@ -42,9 +43,11 @@ func ParseSlashCommand(input string) *SlashCommand {
 		return nil
 	}
 
+	_, raw, _ := strings.Cut(strings.TrimLeft(input, " \t"), parts[0])
 	return &SlashCommand{
 		Command: parts[0],
 		Args:    parts[1:],
+		Raw:     strings.TrimSpace(raw),
 	}
 }
 
@@ -192,9 +195,30 @@ func (c *Client) HandleSlashCommand(ctx context.Context, cmd *SlashCommand) bool
 	case "save":
 		return c.handleSaveConversation(ctx)
 
+	case "remember":
+		return c.handleRememberCommand(ctx, cmd.Args)
+
+	case "forget":
+		return c.handleForgetCommand(ctx, cmd.Args)
+
+	case "approve":
+		return c.handleApproveCommand(cmd.Args)
+
+	case "sql":
+		return c.handleSQLCommand(ctx, cmd.Raw)
+
+	case "export":
+		return c.handleExportCommand(ctx, cmd.Args)
+
+	case "replay":
+		return c.handleReplayCommand(ctx, cmd.Args)
+
+	case "edit":
+		return c.handleEditCommand(ctx, cmd.Raw)
+
 	default:
-		// Unknown slash command, let it be sent to LLM
-		return false
+		// Commands defined in the configuration, if any
+		return c.handleCustomCommand(ctx, cmd)
 	}
 }
 
@@ -207,6 +231,10 @@ Commands:
   /tools                               List available MCP tools
   /resources                           List available MCP resources
   /prompts                             List available MCP prompts
+  /sql <query>                         Run a query directly, without the LLM
+  /export [markdown|json] [file]       Save the conversation, including tool calls, to a file
+  /replay <file>                       Run the prompts of an export or prompt file again
+  /edit [text]                         Compose a prompt in $EDITOR
   /quit, /exit                         Exit the chat client
 
 Settings:
@@ -214,9 +242,10 @@ Settings:
   /set status-messages <on|off>        Enable or disable status messages
   /set markdown <on|off>               Enable or disable markdown rendering
   /set debug <on|off>                  Enable or disable debug messages
-  /set llm-provider <provider>         Set LLM provider (anthropic, openai, ollama)
+  /set llm-provider <provider>         Set LLM provider (anthropic, openai, azure, bedrock, ollama)
   /set llm-model <model>               Set LLM model to use
   /set database <name>                 Select a database connection
+  /approve [on|off]                    Ask before running tool calls that modify the database
   /show color                          Show current color setting
   /show status-messages                Show current status messages setting
   /show markdown                       Show current markdown rendering setting
@@ -224,6 +253,7 @@ Settings:
   /show llm-provider                   Show current LLM provider
   /show llm-model                      Show current LLM model
   /show database                       Show current database connection
+  /show context                        Show the conversation's context window usage
   /show settings                       Show all current settings
   /list models                         List available models from current LLM provider
   /list databases                      List available database connections
@@ -243,9 +273,17 @@ Conversation History (requires authentication):
   /history rename <id> "new title"     Rename a saved conversation
   /history delete <id>                 Delete a saved conversation
   /history delete-all                  Delete all saved conversations
+
+Memories (requires authentication):
+  /remember <fact>                     Remember a fact or preference across sessions
+  /remember                            List remembered facts
+  /forget <id>                         Forget a remembered fact
+  /forget all                          Forget all remembered facts
 `
 	}
 
+	help += c.customCommandsHelp()
+
 	help += `
 Examples:
   /set llm-provider openai
@@ -261,6 +299,32 @@ Anything else you type will be sent to the LLM.
 	fmt.Print(help)
 }
 
+// handleApproveCommand turns approval of database writes on or off, or
+// shows whether it is on. The setting lasts for the session; use
+// mcp.approve_writes to turn it on by default.
+func (c *Client) handleApproveCommand(args []string) bool {
+	if len(args) == 0 {
+		status := "off"
+		if c.config.MCP.ApproveWrites {
+			status = "on"
+		}
+		c.ui.PrintSystemMessage(fmt.Sprintf("Approval of database writes: %s", status))
+		return true
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on", "true", "1", "yes":
+		c.config.MCP.ApproveWrites = true
+		c.ui.PrintSystemMessage("Tool calls that modify the database will ask for approval")
+	case "off", "false", "0", "no":
+		c.config.MCP.ApproveWrites = false
+		c.ui.PrintSystemMessage("Tool calls that modify the database will run without approval")
+	default:
+		c.ui.PrintError(fmt.Sprintf("Invalid value for approve: %s (use on or off)", args[0]))
+	}
+	return true
+}
+
 // handleSetCommand handles /set commands
 func (c *Client) handleSetCommand(ctx context.Context, args []string) bool {
 	if len(args) < 2 {
@@ -324,7 +388,7 @@ func (c *Client) handleSetColor(value string) bool {
 	}
 
 	// Save preferences
-	if err := SavePreferences(c.preferences); err != nil {
+	if err := c.savePreferences(); err != nil {
 		c.ui.PrintError(fmt.Sprintf("Warning: Failed to save preferences: %v", err))
 	}
 
@@ -354,7 +418,7 @@ func (c *Client) handleSetStatusMessages(value string) bool {
 	}
 
 	// Save preferences
-	if err := SavePreferences(c.preferences); err != nil {
+	if err := c.savePreferences(); err != nil {
 		c.ui.PrintError(fmt.Sprintf("Warning: Failed to save preferences: %v", err))
 	}
 
@@ -384,7 +448,7 @@ func (c *Client) handleSetMarkdown(value string) bool {
 	}
 
 	// Save preferences
-	if err := SavePreferences(c.preferences); err != nil {
+	if err := c.savePreferences(); err != nil {
 		c.ui.PrintError(fmt.Sprintf("Warning: Failed to save preferences: %v", err))
 	}
 
@@ -418,7 +482,7 @@ func (c *Client) handleSetDebug(value string) bool {
 	}
 
 	// Save preferences
-	if err := SavePreferences(c.preferences); err != nil {
+	if err := c.savePreferences(); err != nil {
 		c.ui.PrintError(fmt.Sprintf("Warning: Failed to save preferences: %v", err))
 	}
 
@@ -433,18 +497,20 @@ func (c *Client) handleSetLLMProvider(provider string) bool {
 	validProviders := map[string]bool{
 		"anthropic": true,
 		"openai":    true,
+		"azure":     true,
+		"bedrock":   true,
 		"ollama":    true,
 	}
 
 	if !validProviders[provider] {
 		c.ui.PrintError(fmt.Sprintf("Invalid LLM provider: %s", provider))
-		c.ui.PrintSystemMessage("Valid providers: anthropic, openai, ollama")
+		c.ui.PrintSystemMessage("Valid providers: anthropic, openai, azure, bedrock, ollama")
 		return true
 	}
 
 	// Check if provider is configured
 	if !c.config.IsProviderConfigured(provider) {
-		c.ui.PrintError(fmt.Sprintf("Provider %s is not configured (missing API key or URL)", provider))
+		c.ui.PrintError(fmt.Sprintf("Provider %s is not configured (missing API key, URL or credentials)", provider))
 		return true
 	}
 
@@ -469,7 +535,7 @@ func (c *Client) handleSetLLMProvider(provider string) bool {
 	}
 
 	// Save preferences (model was already saved in initializeLLM)
-	if err := SavePreferences(c.preferences); err != nil {
+	if err := c.savePreferences(); err != nil {
 		c.ui.PrintError(fmt.Sprintf("Warning: Failed to save preferences: %v", err))
 	}
 
@@ -508,7 +574,7 @@ func (c *Client) handleSetLLMModel(model string) bool {
 	}
 
 	// Save preferences
-	if err := SavePreferences(c.preferences); err != nil {
+	if err := c.savePreferences(); err != nil {
 		c.ui.PrintError(fmt.Sprintf("Warning: Failed to save preferences: %v", err))
 	}
 
@@ -520,7 +586,7 @@ func (c *Client) handleSetLLMModel(model string) bool {
 func (c *Client) handleShowCommand(ctx context.Context, args []string) bool {
 	if len(args) < 1 {
 		c.ui.PrintError("Usage: /show <setting>")
-		c.ui.PrintSystemMessage("Available settings: color, status-messages, markdown, debug, llm-provider, llm-model, database, settings")
+		c.ui.PrintSystemMessage("Available settings: color, status-messages, markdown, debug, llm-provider, llm-model, database, context, settings")
 		return true
 	}
 
@@ -564,17 +630,29 @@ func (c *Client) handleShowCommand(ctx context.Context, args []string) bool {
 	case "database":
 		return c.handleShowDatabase(ctx)
 
+	case "context":
+		c.ui.PrintSystemMessage(c.contextUsage())
+
 	case "settings":
 		c.printAllSettings()
 
 	default:
 		c.ui.PrintError(fmt.Sprintf("Unknown setting: %s", setting))
-		c.ui.PrintSystemMessage("Available settings: color, status-messages, markdown, debug, llm-provider, llm-model, database, settings")
+		c.ui.PrintSystemMessage("Available settings: color, status-messages, markdown, debug, llm-provider, llm-model, database, context, settings")
 	}
 
 	return true
 }
 
+// contextUsage describes how much of the model's context window the
+// conversation uses, counted with the provider's tokenizer
+func (c *Client) contextUsage() string {
+	budget := c.budget()
+	usage := budget.Usage(CountMessageTokens(budget.Tokenizer, c.messages))
+	return fmt.Sprintf("Context: ~%d tokens in %d messages (%.1f%% of the %d token context window); history is compacted above %d tokens",
+		usage.Tokens, len(c.messages), usage.Percent, usage.ContextWindow, usage.CompactAt)
+}
+
 // printAllSettings prints all current settings
 func (c *Client) printAllSettings() {
 	fmt.Println("\nCurrent Settings:")
@@ -619,6 +697,11 @@ func (c *Client) printAllSettings() {
 	} else {
 		fmt.Printf("  Server Path:      %s\n", c.config.MCP.ServerPath)
 	}
+	approve := "off"
+	if c.config.MCP.ApproveWrites {
+		approve = "on"
+	}
+	fmt.Printf("  Approve Writes:   %s\n", approve)
 
 	fmt.Println("─────────────────────────────────────────────────")
 }
@@ -750,6 +833,11 @@ type DatabaseInfo struct {
 	Database string `json:"database"`
 	User     string `json:"user"`
 	SSLMode  string `json:"sslmode"`
+
+	Environment string   `json:"environment,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+	ReadOnly    bool     `json:"read_only,omitempty"` // Writes are disabled
 }
 
 // ListDatabasesResponse is the response from GET /api/databases
@@ -829,6 +917,21 @@ func (c *Client) handleListDatabases(ctx context.Context) bool {
 		}
 		fmt.Printf("  %s%s - %s@%s:%d/%s\n",
 			db.Name, currentMarker, db.User, db.Host, db.Port, db.Database)
+
+		var labels []string
+		if db.Environment != "" {
+			labels = append(labels, db.Environment)
+		}
+		labels = append(labels, db.Tags...)
+		if db.ReadOnly {
+			labels = append(labels, "read-only")
+		}
+		if len(labels) > 0 {
+			fmt.Printf("      [%s]\n", strings.Join(labels, ", "))
+		}
+		if db.Description != "" {
+			fmt.Printf("      %s\n", db.Description)
+		}
 	}
 
 	return true
@@ -863,7 +966,7 @@ func (c *Client) handleSetDatabase(ctx context.Context, dbName string) bool {
 	// Save the preference for this server
 	serverKey := c.getServerKey()
 	c.preferences.SetDatabaseForServer(serverKey, dbName)
-	if err := SavePreferences(c.preferences); err != nil {
+	if err := c.savePreferences(); err != nil {
 		c.ui.PrintError(fmt.Sprintf("Warning: Failed to save preference: %v", err))
 	}
 
@@ -11,6 +11,7 @@
 package chat
 
 import (
+	"context"
 	"testing"
 )
 
@@ -242,6 +243,36 @@ func TestHandleSetColorInvalidValue(t *testing.T) {
 	}
 }
 
+func TestHandleApproveCommand(t *testing.T) {
+	cfg := &Config{
+		LLM: LLMConfig{
+			Provider:  "ollama",
+			OllamaURL: "http://localhost:11434",
+		},
+	}
+	client, err := NewClient(cfg, &ConfigOverrides{ProviderSet: true})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"on"}, true},
+		{nil, true}, // Shows the setting without changing it
+		{[]string{"invalid"}, true},
+		{[]string{"OFF"}, false},
+	} {
+		if !client.HandleSlashCommand(context.Background(), &SlashCommand{Command: "approve", Args: tt.args}) {
+			t.Fatalf("expected /approve %v to be handled", tt.args)
+		}
+		if client.config.MCP.ApproveWrites != tt.want {
+			t.Errorf("after /approve %v, ApproveWrites = %v, want %v", tt.args, client.config.MCP.ApproveWrites, tt.want)
+		}
+	}
+}
+
 func TestHandleSetLLMProvider(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -579,3 +610,13 @@ func TestParsePromptCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSlashCommand_Raw(t *testing.T) {
+	cmd := ParseSlashCommand(`/sql  SELECT 'a  b' AS "Label"  `)
+	if cmd == nil || cmd.Command != "sql" {
+		t.Fatalf("expected a sql command, got %+v", cmd)
+	}
+	if cmd.Raw != `SELECT 'a  b' AS "Label"` {
+		t.Errorf("Raw = %q", cmd.Raw)
+	}
+}
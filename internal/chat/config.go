@@ -17,14 +17,19 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"pgedge-postgres-mcp/internal/netproxy"
+	"pgedge-postgres-mcp/internal/sigv4"
 )
 
 // Config holds all configuration for the chat client
 type Config struct {
-	MCP         MCPConfig `yaml:"mcp"`
-	LLM         LLMConfig `yaml:"llm"`
-	UI          UIConfig  `yaml:"ui"`
-	HistoryFile string    `yaml:"history_file"` // Path to chat history file
+	MCP         MCPConfig         `yaml:"mcp"`
+	LLM         LLMConfig         `yaml:"llm"`
+	UI          UIConfig          `yaml:"ui"`
+	Proxy       netproxy.Settings `yaml:"proxy"`        // Outbound proxy for LLM provider calls
+	HistoryFile string            `yaml:"history_file"` // Path to chat history file
+	Commands    []CustomCommand   `yaml:"commands"`     // Slash commands defined by the user
 }
 
 // ConfigOverrides tracks which config values were explicitly set via command-line flags
@@ -44,11 +49,20 @@ type MCPConfig struct {
 	Username         string `yaml:"username"`           // Username (for user mode)
 	Password         string `yaml:"password"`           // Password (for user mode)
 	TLS              bool   `yaml:"tls"`                // Use TLS/HTTPS
+	MaxParallelTools int    `yaml:"max_parallel_tools"` // Tool calls from one LLM turn to run at once
+
+	// ToolTimeoutSeconds bounds each tool call; a call that runs longer is
+	// reported to the LLM as failed (default: 0, no limit)
+	ToolTimeoutSeconds int `yaml:"tool_timeout_seconds"`
+
+	// ApproveWrites asks the user to confirm each tool call that can modify
+	// the database, showing its SQL, before it runs
+	ApproveWrites bool `yaml:"approve_writes"`
 }
 
 // LLMConfig holds LLM provider configuration
 type LLMConfig struct {
-	Provider            string  `yaml:"provider"`               // anthropic, openai, or ollama
+	Provider            string  `yaml:"provider"`               // anthropic, openai, azure, bedrock, or ollama
 	Model               string  `yaml:"model"`                  // Model to use
 	AnthropicAPIKey     string  `yaml:"anthropic_api_key"`      // API key for Anthropic (direct - discouraged, use api_key_file or env var)
 	AnthropicAPIKeyFile string  `yaml:"anthropic_api_key_file"` // Path to file containing Anthropic API key
@@ -57,6 +71,19 @@ type LLMConfig struct {
 	OllamaURL           string  `yaml:"ollama_url"`             // Ollama server URL
 	MaxTokens           int     `yaml:"max_tokens"`             // Max tokens for response
 	Temperature         float64 `yaml:"temperature"`            // Temperature for sampling
+	ContextWindow       int     `yaml:"context_window"`         // Model context window in tokens (default: known for the model)
+
+	// Azure OpenAI: requests go to a deployment of a resource
+	AzureEndpoint   string `yaml:"azure_endpoint"`     // Resource endpoint, e.g. https://example.openai.azure.com
+	AzureAPIKey     string `yaml:"azure_api_key"`      // API key for Azure OpenAI (direct - discouraged, use api_key_file or env var)
+	AzureAPIKeyFile string `yaml:"azure_api_key_file"` // Path to file containing Azure OpenAI API key
+	AzureAPIVersion string `yaml:"azure_api_version"`  // API version (default: 2024-10-21)
+	AzureDeployment string `yaml:"azure_deployment"`   // Deployment to send requests to
+
+	// Amazon Bedrock: credentials are read from the AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables
+	BedrockRegion   string `yaml:"bedrock_region"`   // AWS region (default: AWS_REGION env var)
+	BedrockEndpoint string `yaml:"bedrock_endpoint"` // Runtime endpoint override, e.g. a VPC endpoint
 }
 
 // UIConfig holds UI configuration
@@ -80,6 +107,7 @@ func LoadConfig(configPath string) (*Config, error) {
 			Username:         os.Getenv("PGEDGE_MCP_USERNAME"),
 			Password:         os.Getenv("PGEDGE_MCP_PASSWORD"),
 			TLS:              false,
+			MaxParallelTools: 4,
 		},
 		LLM: LLMConfig{
 			Provider:        getEnvOrDefault("PGEDGE_LLM_PROVIDER", "anthropic"),
@@ -89,12 +117,22 @@ func LoadConfig(configPath string) (*Config, error) {
 			OllamaURL:       getEnvOrDefault("PGEDGE_OLLAMA_URL", "http://localhost:11434"),
 			MaxTokens:       4096,
 			Temperature:     0.7,
+			AzureEndpoint:   getEnvWithFallback("PGEDGE_AZURE_OPENAI_ENDPOINT", "AZURE_OPENAI_ENDPOINT"),
+			AzureAPIKey:     getEnvWithFallback("PGEDGE_AZURE_OPENAI_API_KEY", "AZURE_OPENAI_API_KEY"),
+			AzureAPIVersion: getEnvOrDefault("PGEDGE_AZURE_OPENAI_API_VERSION", DefaultAzureAPIVersion),
+			AzureDeployment: os.Getenv("PGEDGE_AZURE_OPENAI_DEPLOYMENT"),
+			BedrockRegion:   getEnvOrDefault("PGEDGE_BEDROCK_REGION", sigv4.RegionFromEnv()),
+			BedrockEndpoint: os.Getenv("PGEDGE_BEDROCK_ENDPOINT"),
 		},
 		UI: UIConfig{
 			NoColor:               os.Getenv("NO_COLOR") != "",
 			DisplayStatusMessages: true, // Default to showing status messages
 			RenderMarkdown:        true, // Default to rendering markdown
 		},
+		Proxy: netproxy.Settings{
+			URL:     os.Getenv("PGEDGE_PROXY_URL"),
+			NoProxy: os.Getenv("PGEDGE_NO_PROXY"),
+		},
 		HistoryFile: filepath.Join(os.Getenv("HOME"), ".pgedge-nla-cli-history"),
 	}
 
@@ -134,6 +172,12 @@ func LoadConfig(configPath string) (*Config, error) {
 		}
 		// Note: errors are silently ignored - file may not exist and that's ok
 	}
+	if cfg.LLM.AzureAPIKey == "" && cfg.LLM.AzureAPIKeyFile != "" {
+		if key, err := readAPIKeyFromFile(cfg.LLM.AzureAPIKeyFile); err == nil && key != "" {
+			cfg.LLM.AzureAPIKey = key
+		}
+		// Note: errors are silently ignored - file may not exist and that's ok
+	}
 	// 2. Direct config value (if set) is already in cfg.LLM.AnthropicAPIKey/OpenAIAPIKey from loadConfigFile
 
 	// Load authentication token with priority
@@ -193,9 +237,32 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("mcp-server-path is required for stdio mode")
 	}
 
+	if c.MCP.MaxParallelTools < 0 {
+		return fmt.Errorf("invalid max_parallel_tools: %d (must be 0 or more)", c.MCP.MaxParallelTools)
+	}
+	if c.MCP.ToolTimeoutSeconds < 0 {
+		return fmt.Errorf("invalid tool_timeout_seconds: %d (must be 0 or more)", c.MCP.ToolTimeoutSeconds)
+	}
+
+	if c.LLM.ContextWindow < 0 {
+		return fmt.Errorf("invalid context_window: %d (must be 0 or more)", c.LLM.ContextWindow)
+	}
+
 	// Validate LLM provider
-	if c.LLM.Provider != "anthropic" && c.LLM.Provider != "openai" && c.LLM.Provider != "ollama" {
-		return fmt.Errorf("invalid llm-provider: %s (must be anthropic, openai, or ollama)", c.LLM.Provider)
+	switch c.LLM.Provider {
+	case "anthropic", "openai", "azure", "bedrock", "ollama":
+	default:
+		return fmt.Errorf("invalid llm-provider: %s (must be anthropic, openai, azure, bedrock, or ollama)", c.LLM.Provider)
+	}
+
+	// Validate outbound proxy settings
+	if err := netproxy.Validate(c.Proxy); err != nil {
+		return err
+	}
+
+	// Validate custom slash commands
+	if err := validateCustomCommands(c.Commands); err != nil {
+		return err
 	}
 
 	// Validate LLM configuration based on provider
@@ -213,6 +280,26 @@ func (c *Config) Validate() error {
 		if c.LLM.Model == "" {
 			c.LLM.Model = "gpt-4o"
 		}
+	} else if c.LLM.Provider == "azure" {
+		if c.LLM.AzureEndpoint == "" || c.LLM.AzureAPIKey == "" {
+			return fmt.Errorf("PGEDGE_AZURE_OPENAI_ENDPOINT and PGEDGE_AZURE_OPENAI_API_KEY environment variables or azure_endpoint and azure_api_key config are required for Azure OpenAI")
+		}
+		if c.LLM.AzureDeployment == "" {
+			return fmt.Errorf("PGEDGE_AZURE_OPENAI_DEPLOYMENT environment variable or azure_deployment config is required for Azure OpenAI")
+		}
+		if c.LLM.Model == "" {
+			c.LLM.Model = c.LLM.AzureDeployment
+		}
+	} else if c.LLM.Provider == "bedrock" {
+		if c.LLM.BedrockRegion == "" {
+			return fmt.Errorf("AWS_REGION environment variable or bedrock_region config is required for Amazon Bedrock")
+		}
+		if !sigv4.CredentialsFromEnv().IsSet() {
+			return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are required for Amazon Bedrock")
+		}
+		if c.LLM.Model == "" {
+			c.LLM.Model = DefaultBedrockModel
+		}
 	} else {
 		if c.LLM.OllamaURL == "" {
 			c.LLM.OllamaURL = "http://localhost:11434"
@@ -232,6 +319,10 @@ func (c *Config) IsProviderConfigured(provider string) bool {
 		return c.LLM.AnthropicAPIKey != ""
 	case "openai":
 		return c.LLM.OpenAIAPIKey != ""
+	case "azure":
+		return c.LLM.AzureEndpoint != "" && c.LLM.AzureAPIKey != "" && c.LLM.AzureDeployment != ""
+	case "bedrock":
+		return c.LLM.BedrockRegion != "" && sigv4.CredentialsFromEnv().IsSet()
 	case "ollama":
 		// Ollama is configured if URL is set (defaults to localhost)
 		return c.LLM.OllamaURL != ""
@@ -241,7 +332,7 @@ func (c *Config) IsProviderConfigured(provider string) bool {
 }
 
 // GetConfiguredProviders returns a list of providers that are configured
-// in priority order: anthropic, openai, ollama
+// in priority order: anthropic, openai, azure, bedrock, ollama
 func (c *Config) GetConfiguredProviders() []string {
 	providers := []string{}
 	if c.IsProviderConfigured("anthropic") {
@@ -250,6 +341,12 @@ func (c *Config) GetConfiguredProviders() []string {
 	if c.IsProviderConfigured("openai") {
 		providers = append(providers, "openai")
 	}
+	if c.IsProviderConfigured("azure") {
+		providers = append(providers, "azure")
+	}
+	if c.IsProviderConfigured("bedrock") {
+		providers = append(providers, "bedrock")
+	}
 	if c.IsProviderConfigured("ollama") {
 		providers = append(providers, "ollama")
 	}
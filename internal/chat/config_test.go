@@ -13,6 +13,7 @@ package chat
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -44,6 +45,10 @@ func TestLoadConfig_Defaults(t *testing.T) {
 	if cfg.LLM.Temperature != 0.7 {
 		t.Errorf("Expected Temperature 0.7, got %f", cfg.LLM.Temperature)
 	}
+
+	if cfg.MCP.MaxParallelTools != 4 {
+		t.Errorf("Expected MaxParallelTools 4, got %d", cfg.MCP.MaxParallelTools)
+	}
 }
 
 func TestLoadConfig_Environment(t *testing.T) {
@@ -197,6 +202,60 @@ func TestValidate_InvalidMode(t *testing.T) {
 	}
 }
 
+func TestValidate_NegativeMaxParallelTools(t *testing.T) {
+	cfg := &Config{
+		MCP: MCPConfig{
+			Mode:             "stdio",
+			ServerPath:       "/usr/local/bin/pgedge-postgres-mcp",
+			MaxParallelTools: -1,
+		},
+		LLM: LLMConfig{
+			Provider:        "anthropic",
+			AnthropicAPIKey: "test-key",
+		},
+	}
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected validation error for negative max_parallel_tools")
+	}
+}
+
+func TestValidate_NegativeToolTimeout(t *testing.T) {
+	cfg := &Config{
+		MCP: MCPConfig{
+			Mode:               "stdio",
+			ServerPath:         "/usr/local/bin/pgedge-postgres-mcp",
+			ToolTimeoutSeconds: -1,
+		},
+		LLM: LLMConfig{
+			Provider:        "anthropic",
+			AnthropicAPIKey: "test-key",
+		},
+	}
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected validation error for negative tool_timeout_seconds")
+	}
+}
+
+func TestValidate_NegativeContextWindow(t *testing.T) {
+	cfg := &Config{
+		MCP: MCPConfig{
+			Mode:       "stdio",
+			ServerPath: "/usr/local/bin/pgedge-postgres-mcp",
+		},
+		LLM: LLMConfig{
+			Provider:        "ollama",
+			ContextWindow:   -1,
+			AnthropicAPIKey: "test-key",
+		},
+	}
+
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "context_window") {
+		t.Errorf("Expected validation error for negative context_window, got %v", err)
+	}
+}
+
 func TestValidate_MissingURL(t *testing.T) {
 	cfg := &Config{
 		MCP: MCPConfig{
@@ -263,3 +322,66 @@ func TestValidate_MissingAPIKey(t *testing.T) {
 		t.Error("Expected validation error for missing API key for Anthropic")
 	}
 }
+
+func TestValidate_Azure(t *testing.T) {
+	cfg := &Config{
+		MCP: MCPConfig{
+			Mode:       "stdio",
+			ServerPath: "/path/to/server",
+		},
+		LLM: LLMConfig{
+			Provider:      "azure",
+			AzureEndpoint: "https://example.openai.azure.com",
+			AzureAPIKey:   "test-key",
+		},
+	}
+
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "azure_deployment") {
+		t.Errorf("Expected validation error for missing deployment, got %v", err)
+	}
+
+	cfg.LLM.AzureDeployment = "example-deployment"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if cfg.LLM.Model != "example-deployment" {
+		t.Errorf("Expected the deployment as the model, got %q", cfg.LLM.Model)
+	}
+	if !cfg.IsProviderConfigured("azure") {
+		t.Error("Expected azure to be configured")
+	}
+}
+
+func TestValidate_Bedrock(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	cfg := &Config{
+		MCP: MCPConfig{
+			Mode:       "stdio",
+			ServerPath: "/path/to/server",
+		},
+		LLM: LLMConfig{
+			Provider:      "bedrock",
+			BedrockRegion: "us-east-1",
+		},
+	}
+
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "AWS_ACCESS_KEY_ID") {
+		t.Errorf("Expected validation error for missing credentials, got %v", err)
+	}
+	if cfg.IsProviderConfigured("bedrock") {
+		t.Error("Expected bedrock not to be configured without credentials")
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if cfg.LLM.Model != DefaultBedrockModel {
+		t.Errorf("Expected the default Bedrock model, got %q", cfg.LLM.Model)
+	}
+	if providers := cfg.GetConfiguredProviders(); len(providers) == 0 || providers[0] != "bedrock" {
+		t.Errorf("Expected bedrock to be configured, got %v", providers)
+	}
+}
@@ -0,0 +1,284 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CustomCommand defines a slash command in the configuration: a prompt
+// template sent to the LLM, or a direct call of an MCP tool
+type CustomCommand struct {
+	Name        string            `yaml:"name"`        // Command name, without the slash
+	Description string            `yaml:"description"` // Shown by /help
+	Prompt      string            `yaml:"prompt"`      // Prompt template sent to the LLM
+	Tool        string            `yaml:"tool"`        // Tool to call directly instead of sending a prompt
+	Arguments   map[string]string `yaml:"arguments"`   // Tool arguments; values are templates
+}
+
+// builtinCommands are the slash commands handled by the client itself,
+// which custom commands can't replace
+var builtinCommands = map[string]bool{
+	"help": true, "clear": true, "tools": true, "resources": true, "prompts": true,
+	"quit": true, "exit": true, "set": true, "show": true, "list": true,
+	"prompt": true, "history": true, "new": true, "save": true,
+	"remember": true, "forget": true,
+}
+
+var (
+	// customCommandName matches valid custom command names
+	customCommandName = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+	// templatePlaceholder matches {{name}} and {{name:default}} in command
+	// templates
+	templatePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*(?::([^}]*))?\}\}`)
+
+	// namedArgument matches key=value command arguments
+	namedArgument = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+)
+
+// validateCustomCommands checks the custom commands in the configuration
+func validateCustomCommands(commands []CustomCommand) error {
+	seen := make(map[string]bool, len(commands))
+	for i, cmd := range commands {
+		if !customCommandName.MatchString(cmd.Name) {
+			return fmt.Errorf("command %d: invalid name %q (use up to 32 lowercase letters, digits, '-' or '_', starting with a letter)", i, cmd.Name)
+		}
+		if builtinCommands[cmd.Name] {
+			return fmt.Errorf("command %q: the name is used by a built-in command", cmd.Name)
+		}
+		if seen[cmd.Name] {
+			return fmt.Errorf("command %q is defined more than once", cmd.Name)
+		}
+		seen[cmd.Name] = true
+
+		switch {
+		case cmd.Prompt != "" && cmd.Tool != "":
+			return fmt.Errorf("command %q: set either prompt or tool, not both", cmd.Name)
+		case cmd.Prompt == "" && cmd.Tool == "":
+			return fmt.Errorf("command %q: a prompt or tool is required", cmd.Name)
+		case cmd.Prompt != "" && len(cmd.Arguments) > 0:
+			return fmt.Errorf("command %q: arguments are only used with tool", cmd.Name)
+		}
+	}
+	return nil
+}
+
+// findCustomCommand returns the configured command with the given name
+func (c *Client) findCustomCommand(name string) *CustomCommand {
+	for i := range c.config.Commands {
+		if c.config.Commands[i].Name == name {
+			return &c.config.Commands[i]
+		}
+	}
+	return nil
+}
+
+// splitCommandArgs separates key=value arguments from positional ones
+func splitCommandArgs(args []string) ([]string, map[string]string) {
+	var positional []string
+	named := make(map[string]string)
+	for _, arg := range args {
+		if m := namedArgument.FindStringSubmatch(arg); m != nil {
+			named[m[1]] = m[2]
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	return positional, named
+}
+
+// expandCommandTemplate replaces the placeholders in a custom command
+// template with the command's arguments:
+//
+//	{{args}}          all positional arguments, separated by spaces
+//	{{1}}, {{2}}, ... one positional argument
+//	{{name}}          the value of a name=value argument
+//	{{name:default}}  the same, or default when it is not given
+//
+// An error lists the placeholders that have neither an argument nor a
+// default.
+func expandCommandTemplate(template string, positional []string, named map[string]string) (string, error) {
+	var missing []string
+	expanded := templatePlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		m := templatePlaceholder.FindStringSubmatch(match)
+		name, def := m[1], m[2]
+		hasDefault := strings.Contains(match, ":")
+
+		if name == "args" {
+			return strings.Join(positional, " ")
+		}
+		if n, err := strconv.Atoi(name); err == nil {
+			if n >= 1 && n <= len(positional) {
+				return positional[n-1]
+			}
+		} else if value, ok := named[name]; ok {
+			return value
+		}
+		if hasDefault {
+			return strings.TrimSpace(def)
+		}
+		missing = append(missing, name)
+		return match
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing arguments: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// toolArgumentValue converts an expanded argument to the type the tool's
+// input schema declares for it
+func toolArgumentValue(schemaType, value string) (interface{}, error) {
+	switch schemaType {
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "array", "object":
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, fmt.Errorf("expected JSON: %w", err)
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// toolPropertyTypes returns the declared type of each argument of a tool
+// offered by the server, and whether the tool was found
+func (c *Client) toolPropertyTypes(name string) (map[string]string, bool) {
+	for _, tool := range c.tools {
+		if tool.Name != name {
+			continue
+		}
+		types := make(map[string]string, len(tool.InputSchema.Properties))
+		for prop, schema := range tool.InputSchema.Properties {
+			if s, ok := schema.(map[string]interface{}); ok {
+				if t, ok := s["type"].(string); ok {
+					types[prop] = t
+				}
+			}
+		}
+		return types, true
+	}
+	return nil, false
+}
+
+// buildToolArguments expands a tool command's argument templates; arguments
+// that expand to nothing are left out so the tool uses its defaults
+func (c *Client) buildToolArguments(cmd *CustomCommand, positional []string, named map[string]string) (map[string]interface{}, error) {
+	types, ok := c.toolPropertyTypes(cmd.Tool)
+	if !ok {
+		return nil, fmt.Errorf("tool %s is not available on this server", cmd.Tool)
+	}
+
+	names := make([]string, 0, len(cmd.Arguments))
+	for name := range cmd.Arguments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make(map[string]interface{}, len(names))
+	var missing []string
+	for _, name := range names {
+		value, err := expandCommandTemplate(cmd.Arguments[name], positional, named)
+		if err != nil {
+			missing = append(missing, strings.TrimPrefix(err.Error(), "missing arguments: "))
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		converted, err := toolArgumentValue(types[name], value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %w", value, name, err)
+		}
+		args[name] = converted
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing arguments: %s", strings.Join(missing, ", "))
+	}
+	return args, nil
+}
+
+// handleCustomCommand runs a custom command from the configuration,
+// returning false if there is none with the command's name
+func (c *Client) handleCustomCommand(ctx context.Context, cmd *SlashCommand) bool {
+	custom := c.findCustomCommand(cmd.Command)
+	if custom == nil {
+		return false
+	}
+	positional, named := splitCommandArgs(cmd.Args)
+
+	if custom.Prompt != "" {
+		prompt, err := expandCommandTemplate(custom.Prompt, positional, named)
+		if err != nil {
+			c.ui.PrintError(fmt.Sprintf("/%s: %v", custom.Name, err))
+			return true
+		}
+		if err := c.processQuery(ctx, prompt); err != nil {
+			c.ui.PrintError(err.Error())
+		}
+		return true
+	}
+
+	args, err := c.buildToolArguments(custom, positional, named)
+	if err != nil {
+		c.ui.PrintError(fmt.Sprintf("/%s: %v", custom.Name, err))
+		return true
+	}
+	c.ui.PrintToolExecution(custom.Tool, args)
+	result, err := c.mcp.CallTool(ctx, custom.Tool, args)
+	if err != nil {
+		c.ui.PrintError(fmt.Sprintf("/%s: %v", custom.Name, err))
+		return true
+	}
+
+	var text strings.Builder
+	for _, item := range result.Content {
+		if item.Type == "text" {
+			text.WriteString(item.Text)
+		}
+	}
+	if result.IsError {
+		c.ui.PrintError(text.String())
+		return true
+	}
+	c.ui.PrintAssistantResponse(text.String())
+	return true
+}
+
+// customCommandsHelp lists the custom commands for /help
+func (c *Client) customCommandsHelp() string {
+	if len(c.config.Commands) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\nCustom Commands:\n")
+	for _, cmd := range c.config.Commands {
+		description := cmd.Description
+		if description == "" && cmd.Tool != "" {
+			description = "Run the " + cmd.Tool + " tool"
+		}
+		sb.WriteString(fmt.Sprintf("  %-36s %s\n", "/"+cmd.Name, description))
+	}
+	return sb.String()
+}
@@ -0,0 +1,138 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+func TestExpandCommandTemplate(t *testing.T) {
+	positional := []string{"orders", "public"}
+	named := map[string]string{"hours": "24"}
+
+	tests := []struct {
+		template string
+		want     string
+		missing  string
+	}{
+		{"Report on {{args}}", "Report on orders public", ""},
+		{"Table {{2}}.{{1}}", "Table public.orders", ""},
+		{"Last {{hours}} hours", "Last 24 hours", ""},
+		{"Limit {{limit:10}}", "Limit 10", ""},
+		{"Hours {{ hours : 1 }}", "Hours 24", ""},
+		{"Schema {{3:public}}", "Schema public", ""},
+		{"{{3}} and {{node}}", "", "3, node"},
+	}
+	for _, tt := range tests {
+		got, err := expandCommandTemplate(tt.template, positional, named)
+		if tt.missing != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.missing) {
+				t.Errorf("expandCommandTemplate(%q) error = %v, want missing %s", tt.template, err, tt.missing)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("expandCommandTemplate(%q) = %q, %v; want %q", tt.template, got, err, tt.want)
+		}
+	}
+}
+
+func TestSplitCommandArgs(t *testing.T) {
+	positional, named := splitCommandArgs([]string{"orders", "limit=5", "where=a=b", "=x"})
+	if !reflect.DeepEqual(positional, []string{"orders", "=x"}) {
+		t.Errorf("positional = %v", positional)
+	}
+	if !reflect.DeepEqual(named, map[string]string{"limit": "5", "where": "a=b"}) {
+		t.Errorf("named = %v", named)
+	}
+}
+
+func TestValidateCustomCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []CustomCommand
+		errorMsg string
+	}{
+		{"valid", []CustomCommand{
+			{Name: "oncall-report", Prompt: "Summarize the last {{hours:24}} hours"},
+			{Name: "replication-status", Tool: "query_database", Arguments: map[string]string{"query": "SELECT 1"}},
+		}, ""},
+		{"invalid name", []CustomCommand{{Name: "Oncall", Prompt: "x"}}, "invalid name"},
+		{"built-in name", []CustomCommand{{Name: "history", Prompt: "x"}}, "built-in"},
+		{"duplicate", []CustomCommand{{Name: "a", Prompt: "x"}, {Name: "a", Prompt: "y"}}, "more than once"},
+		{"prompt and tool", []CustomCommand{{Name: "a", Prompt: "x", Tool: "t"}}, "not both"},
+		{"neither", []CustomCommand{{Name: "a"}}, "is required"},
+		{"prompt arguments", []CustomCommand{{Name: "a", Prompt: "x", Arguments: map[string]string{"k": "v"}}}, "only used with tool"},
+	}
+	for _, tt := range tests {
+		err := validateCustomCommands(tt.commands)
+		if tt.errorMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.errorMsg)
+		}
+	}
+}
+
+func TestBuildToolArguments(t *testing.T) {
+	client := &Client{
+		config: &Config{},
+		tools: []mcp.Tool{{
+			Name: "get_table_stats",
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"table":   map[string]interface{}{"type": "string"},
+					"limit":   map[string]interface{}{"type": "integer"},
+					"refresh": map[string]interface{}{"type": "boolean"},
+					"schema":  map[string]interface{}{"type": "string"},
+				},
+			},
+		}},
+	}
+	cmd := &CustomCommand{
+		Name: "stats",
+		Tool: "get_table_stats",
+		Arguments: map[string]string{
+			"table":   "{{1}}",
+			"limit":   "{{limit:10}}",
+			"refresh": "{{refresh:false}}",
+			"schema":  "{{schema:}}",
+		},
+	}
+
+	args, err := client.buildToolArguments(cmd, []string{"orders"}, map[string]string{"refresh": "true"})
+	if err != nil {
+		t.Fatalf("buildToolArguments() error = %v", err)
+	}
+	want := map[string]interface{}{"table": "orders", "limit": int64(10), "refresh": true}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("buildToolArguments() = %#v, want %#v", args, want)
+	}
+
+	if _, err := client.buildToolArguments(cmd, nil, nil); err == nil || !strings.Contains(err.Error(), "missing arguments: 1") {
+		t.Errorf("expected a missing argument error, got %v", err)
+	}
+	if _, err := client.buildToolArguments(cmd, []string{"orders"}, map[string]string{"limit": "ten"}); err == nil {
+		t.Error("expected an error for a non-integer limit")
+	}
+
+	cmd.Tool = "missing_tool"
+	if _, err := client.buildToolArguments(cmd, []string{"orders"}, nil); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("expected an unavailable tool error, got %v", err)
+	}
+}
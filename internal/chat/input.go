@@ -0,0 +1,175 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// altEnterRune stands for Alt+Enter in the input read by readline, which
+// would otherwise see only the Enter key. It is in the Unicode private use
+// area, so it cannot be typed.
+const altEnterRune = '\ue000'
+
+// altEnterReader replaces the Alt+Enter sequence (Escape followed by a
+// carriage return or line feed) with altEnterRune followed by the Enter
+// key, so that the line is ended and the filter can tell it continues
+type altEnterReader struct {
+	r io.Reader
+}
+
+func (a *altEnterReader) Read(p []byte) (int, error) {
+	// Leave room for the replacement, which is longer than the sequence
+	size := len(p) / 2
+	if size == 0 {
+		size = 1
+	}
+	buf := make([]byte, size)
+	n, err := a.r.Read(buf)
+	data := buf[:n]
+	if bytes.IndexByte(data, KeyEscape) < 0 {
+		return copy(p, data), err
+	}
+
+	data = bytes.ReplaceAll(data, []byte{KeyEscape, '\r'}, []byte(string(altEnterRune)+"\r"))
+	data = bytes.ReplaceAll(data, []byte{KeyEscape, '\n'}, []byte(string(altEnterRune)+"\r"))
+	return copy(p, data), err
+}
+
+// multilineInput collects the lines of a prompt that spans several lines:
+// lines ended with Alt+Enter, and the lines of a ``` code block, which
+// continues until its closing fence
+type multilineInput struct {
+	lines  []string
+	fenced bool // Inside a code block
+}
+
+// add adds a line of input. continued is true if the line was ended with
+// Alt+Enter. It returns the whole input once it is complete.
+func (m *multilineInput) add(line string, continued bool) (string, bool) {
+	m.lines = append(m.lines, line)
+
+	// An odd number of fences opens or closes a code block
+	if strings.Count(line, "```")%2 == 1 {
+		m.fenced = !m.fenced
+	}
+	if continued || m.fenced {
+		return "", false
+	}
+
+	input := strings.Join(m.lines, "\n")
+	m.lines = nil
+	return input, true
+}
+
+// active reports whether earlier lines are waiting for the input to end
+func (m *multilineInput) active() bool {
+	return len(m.lines) > 0
+}
+
+// reset discards the lines collected so far
+func (m *multilineInput) reset() {
+	m.lines = nil
+	m.fenced = false
+}
+
+// stripCodeFence returns the contents of text if it is a single ``` code
+// block, or text unchanged otherwise
+func stripCodeFence(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || strings.Count(trimmed, "```") != 2 {
+		return text
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(trimmed, "```"), "```")
+
+	// Drop the language of the fence, if any
+	if first, rest, ok := strings.Cut(body, "\n"); ok && !strings.ContainsAny(strings.TrimSpace(first), " \t") {
+		body = rest
+	}
+	return strings.TrimSpace(body)
+}
+
+// editorCommand returns the editor to compose prompts with: $VISUAL,
+// $EDITOR, or the platform's default editor
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editText opens initial in the editor and returns the saved text
+func editText(ctx context.Context, initial string) (string, error) {
+	file, err := os.CreateTemp("", "pgedge-prompt-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+
+	_, err = file.WriteString(initial)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	editor := editorCommand()
+	cmd := exec.CommandContext(ctx, editor[0], append(editor[1:], path)...) //nolint:gosec // The editor is chosen by the user
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read temporary file: %w", err)
+	}
+	return string(data), nil
+}
+
+// handleEditCommand composes a prompt in the editor, starting from text if
+// given, and sends it to the LLM
+func (c *Client) handleEditCommand(ctx context.Context, text string) bool {
+	prompt, err := editText(ctx, text)
+	if err != nil {
+		c.ui.PrintError(err.Error())
+		return true
+	}
+
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		c.ui.PrintSystemMessage("Nothing to send")
+		return true
+	}
+
+	fmt.Println(c.ui.GetPrompt() + prompt)
+	if err := c.processQuery(ctx, prompt); err != nil {
+		c.ui.PrintError(err.Error())
+	}
+	c.ui.PrintSeparator()
+	return true
+}
@@ -0,0 +1,155 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAltEnterReader(t *testing.T) {
+	r := &altEnterReader{r: strings.NewReader("SELECT 1\x1b\rFROM t\x1b\nWHERE x\r\x1b[A")}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT 1" + string(altEnterRune) + "\rFROM t" + string(altEnterRune) + "\rWHERE x\r\x1b[A"
+	if string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestMultilineInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		alt   []bool // Lines ended with Alt+Enter
+		want  string
+	}{
+		{
+			name:  "single line",
+			lines: []string{"How many users are there?"},
+			want:  "How many users are there?",
+		},
+		{
+			name:  "alt enter",
+			lines: []string{"Explain this query:", "SELECT 1"},
+			alt:   []bool{true, false},
+			want:  "Explain this query:\nSELECT 1",
+		},
+		{
+			name:  "code block",
+			lines: []string{"```sql", "SELECT *", "FROM users", "```"},
+			want:  "```sql\nSELECT *\nFROM users\n```",
+		},
+		{
+			name:  "code block after text",
+			lines: []string{"Why is this slow? ```", "SELECT * FROM orders", "```"},
+			want:  "Why is this slow? ```\nSELECT * FROM orders\n```",
+		},
+		{
+			name:  "inline fences",
+			lines: []string{"What does ```now()``` return?"},
+			want:  "What does ```now()``` return?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input multilineInput
+			for i, line := range tt.lines {
+				continued := i < len(tt.alt) && tt.alt[i]
+				got, complete := input.add(line, continued)
+				last := i == len(tt.lines)-1
+				if complete != last {
+					t.Fatalf("line %d: complete = %v, want %v", i, complete, last)
+				}
+				if last && got != tt.want {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			}
+			if input.active() {
+				t.Error("expected no pending lines")
+			}
+		})
+	}
+
+	var input multilineInput
+	input.add("```", false)
+	if !input.active() {
+		t.Fatal("expected an open code block")
+	}
+	input.reset()
+	if got, complete := input.add("SELECT 1", false); !complete || got != "SELECT 1" {
+		t.Errorf("expected input after reset to stand alone, got %q, %v", got, complete)
+	}
+}
+
+func TestStripCodeFence(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"```\nSELECT 1\n```", "SELECT 1"},
+		{"```sql\nSELECT *\nFROM users\n```", "SELECT *\nFROM users"},
+		{"```SELECT 1```", "SELECT 1"},
+		{"SELECT '```'", "SELECT '```'"},
+	}
+	for _, tt := range tests {
+		if got := stripCodeFence(tt.text); got != tt.want {
+			t.Errorf("stripCodeFence(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "code --wait")
+	if got := strings.Join(editorCommand(), " "); got != "code --wait" {
+		t.Errorf("editorCommand() = %q", got)
+	}
+
+	t.Setenv("VISUAL", "nano")
+	if got := strings.Join(editorCommand(), " "); got != "nano" {
+		t.Errorf("expected VISUAL to take precedence, got %q", got)
+	}
+}
+
+func TestEditText(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the editor")
+	}
+
+	// The editor appends a line to the file it is given
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\necho 'FROM users' >> \"$1\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", editor)
+
+	got, err := editText(context.Background(), "SELECT *\n")
+	if err != nil {
+		t.Fatalf("editText() error = %v", err)
+	}
+	if got != "SELECT *\nFROM users\n" {
+		t.Errorf("editText() = %q", got)
+	}
+
+	t.Setenv("VISUAL", filepath.Join(t.TempDir(), "missing-editor"))
+	if _, err := editText(context.Background(), ""); err == nil {
+		t.Error("expected an error for a missing editor")
+	}
+}
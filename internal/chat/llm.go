@@ -13,16 +13,23 @@ package chat
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"pgedge-postgres-mcp/internal/embedding"
 	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/netproxy"
+	"pgedge-postgres-mcp/internal/toolschema"
 )
 
 // Message represents a chat message
@@ -32,6 +39,33 @@ type Message struct {
 	CacheControl map[string]interface{} `json:"cache_control,omitempty"`
 }
 
+// systemPrompt is the system prompt of providers with native tool calling
+const systemPrompt = `You are a helpful PostgreSQL database assistant with expert knowledge on PostgreSQL and products from pgEdge with access to MCP tools.
+
+When executing tools:
+- Be concise and direct
+- Show results without explaining your methodology unless specifically asked
+- Base responses ONLY on actual tool results - never make up or guess data
+- Format results clearly for the user
+- Only use tools when necessary to answer the question`
+
+// systemContextKey is the context key for additional system prompt content
+type systemContextKey struct{}
+
+// WithSystemContext returns a context carrying additional system prompt
+// content, such as a database schema summary. The content should be stable
+// across requests: Anthropic clients send it as a separate cached system
+// block, other providers append it to their system message.
+func WithSystemContext(ctx context.Context, text string) context.Context {
+	return context.WithValue(ctx, systemContextKey{}, text)
+}
+
+// systemContextFrom returns the additional system prompt content, if any
+func systemContextFrom(ctx context.Context) string {
+	text, _ := ctx.Value(systemContextKey{}).(string) //nolint:errcheck // Unset means no extra context
+	return text
+}
+
 // ToolUse represents a tool invocation in a message
 type ToolUse struct {
 	Type  string                 `json:"type"`
@@ -72,6 +106,16 @@ type TokenUsage struct {
 	CacheSavingsPercentage float64 `json:"cache_savings_percentage,omitempty"`
 }
 
+// APIError is an error response from an LLM provider's API
+type APIError struct {
+	StatusCode int    // HTTP status of the response
+	Message    string // User-friendly message extracted from the response
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
 // LLMClient provides a unified interface for different LLM providers
 type LLMClient interface {
 	// Chat sends messages and available tools to the LLM and returns the response
@@ -99,7 +143,7 @@ func NewAnthropicClient(apiKey, model string, maxTokens int, temperature float64
 		maxTokens:   maxTokens,
 		temperature: temperature,
 		debug:       debug,
-		client:      &http.Client{},
+		client:      netproxy.NewClient(netproxy.ProviderAnthropic, 0),
 	}
 }
 
@@ -110,6 +154,7 @@ type anthropicRequest struct {
 	Tools       []map[string]interface{} `json:"tools,omitempty"`
 	Temperature float64                  `json:"temperature,omitempty"`
 	System      []map[string]interface{} `json:"system,omitempty"` // Support for system messages with caching
+	Stream      bool                     `json:"stream,omitempty"`
 }
 
 type anthropicUsage struct {
@@ -147,6 +192,29 @@ func extractAnthropicErrorMessage(statusCode int, body []byte) string {
 	return fmt.Sprintf("API error (%d): %s", statusCode, string(body))
 }
 
+// splitSystemMessages separates system messages with text content from the
+// rest of a conversation
+func splitSystemMessages(messages []Message) ([]Message, []string) {
+	var texts []string
+	for _, msg := range messages {
+		if text, ok := msg.Content.(string); ok && msg.Role == "system" {
+			texts = append(texts, text)
+		}
+	}
+	if texts == nil {
+		return messages, nil
+	}
+
+	rest := make([]Message, 0, len(messages)-len(texts))
+	for _, msg := range messages {
+		if _, ok := msg.Content.(string); ok && msg.Role == "system" {
+			continue
+		}
+		rest = append(rest, msg)
+	}
+	return rest, texts
+}
+
 func (c *anthropicClient) Chat(ctx context.Context, messages []Message, tools interface{}) (LLMResponse, error) {
 	startTime := time.Now()
 	operation := "chat"
@@ -154,47 +222,25 @@ func (c *anthropicClient) Chat(ctx context.Context, messages []Message, tools in
 
 	embedding.LogLLMCallDetails("anthropic", c.model, operation, url, len(messages))
 
-	// Convert interface{} tools to []mcp.Tool via JSON
-	var mcpTools []mcp.Tool
-	if tools != nil {
-		toolsJSON, err := json.Marshal(tools)
-		if err != nil {
-			return LLMResponse{}, fmt.Errorf("failed to marshal tools: %w", err)
-		}
-		if err := json.Unmarshal(toolsJSON, &mcpTools); err != nil {
-			return LLMResponse{}, fmt.Errorf("failed to unmarshal tools: %w", err)
-		}
+	// Convert interface{} tools to tool definitions via JSON
+	mcpTools, err := toolschema.FromAny(tools)
+	if err != nil {
+		return LLMResponse{}, err
 	}
 
 	// Convert MCP tools to Anthropic format with caching
-	anthropicTools := make([]map[string]interface{}, 0, len(mcpTools))
-	for i, tool := range mcpTools {
-		toolDef := map[string]interface{}{
-			"name":         tool.Name,
-			"description":  tool.Description,
-			"input_schema": tool.InputSchema,
-		}
+	anthropicTools := toolschema.Anthropic(mcpTools)
 
-		// Add cache_control to the last tool definition to cache all tools
-		// This caches the entire tools array (must be on the last item)
-		if i == len(mcpTools)-1 {
-			toolDef["cache_control"] = map[string]interface{}{
-				"type": "ephemeral",
-			}
+	// Add cache_control to the last tool definition to cache all tools
+	// This caches the entire tools array (must be on the last item)
+	if len(anthropicTools) > 0 {
+		anthropicTools[len(anthropicTools)-1]["cache_control"] = map[string]interface{}{
+			"type": "ephemeral",
 		}
-
-		anthropicTools = append(anthropicTools, toolDef)
 	}
 
 	// Create system message for better UX
-	systemContent := `You are a helpful PostgreSQL database assistant with expert knowledge on PostgreSQL and products from pgEdge with access to MCP tools.
-
-When executing tools:
-- Be concise and direct
-- Show results without explaining your methodology unless specifically asked
-- Base responses ONLY on actual tool results - never make up or guess data
-- Format results clearly for the user
-- Only use tools when necessary to answer the question`
+	systemContent := systemPrompt
 
 	systemMessage := []map[string]interface{}{
 		{
@@ -203,6 +249,29 @@ When executing tools:
 		},
 	}
 
+	// Additional context (e.g. schema summary) goes in its own block with a
+	// cache breakpoint so the tools and system prompt are read from cache
+	if extra := systemContextFrom(ctx); extra != "" {
+		systemMessage = append(systemMessage, map[string]interface{}{
+			"type": "text",
+			"text": extra,
+			"cache_control": map[string]interface{}{
+				"type": "ephemeral",
+			},
+		})
+	}
+
+	// Anthropic only accepts user and assistant messages, so system
+	// messages in the conversation, such as compaction summaries, are added
+	// to the system prompt after the cached blocks
+	messages, systemTexts := splitSystemMessages(messages)
+	for _, text := range systemTexts {
+		systemMessage = append(systemMessage, map[string]interface{}{
+			"type": "text",
+			"text": text,
+		})
+	}
+
 	req := anthropicRequest{
 		Model:       c.model,
 		MaxTokens:   c.maxTokens,
@@ -212,6 +281,11 @@ When executing tools:
 		System:      systemMessage,
 	}
 
+	onText := textStreamFrom(ctx)
+	if onText != nil {
+		req.Stream = true
+	}
+
 	reqData, err := json.Marshal(req)
 	if err != nil {
 		return LLMResponse{}, fmt.Errorf("failed to marshal request: %w", err)
@@ -256,13 +330,20 @@ When executing tools:
 		userFriendlyMsg := extractAnthropicErrorMessage(resp.StatusCode, body)
 
 		duration := time.Since(startTime)
-		apiErr := fmt.Errorf("%s", userFriendlyMsg)
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: userFriendlyMsg}
 		embedding.LogLLMCall("anthropic", c.model, operation, 0, 0, duration, apiErr)
 		return LLMResponse{}, apiErr
 	}
 
 	var anthropicResp anthropicResponse
-	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
+	if onText != nil {
+		anthropicResp, err = readAnthropicStream(resp.Body, onText)
+		if err != nil {
+			duration := time.Since(startTime)
+			embedding.LogLLMCall("anthropic", c.model, operation, 0, 0, duration, err)
+			return LLMResponse{}, fmt.Errorf("failed to read response stream: %w", err)
+		}
+	} else if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
 		duration := time.Since(startTime)
 		embedding.LogLLMCall("anthropic", c.model, operation, 0, 0, duration, err)
 		return LLMResponse{}, fmt.Errorf("failed to decode response: %w", err)
@@ -404,12 +485,16 @@ func (c *anthropicClient) ListModels(ctx context.Context) ([]string, error) {
 	return models, nil
 }
 
-// ollamaClient implements LLMClient for Ollama
+// ollamaClient implements LLMClient for Ollama. Tools are passed with
+// Ollama's native tool calling API; models that do not support it are told
+// about the tools in the system prompt and asked to reply with a JSON tool
+// call instead.
 type ollamaClient struct {
-	baseURL string
-	model   string
-	debug   bool
-	client  *http.Client
+	baseURL     string
+	model       string
+	debug       bool
+	client      *http.Client
+	promptTools atomic.Bool // Set once the model has rejected native tools
 }
 
 // NewOllamaClient creates a new Ollama client
@@ -418,19 +503,35 @@ func NewOllamaClient(baseURL, model string, debug bool) LLMClient {
 		baseURL: baseURL,
 		model:   model,
 		debug:   debug,
-		client:  &http.Client{},
+		client:  netproxy.NewClient(netproxy.ProviderOllama, 0),
 	}
 }
 
 type ollamaMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // Tool whose result a tool message holds
+}
+
+// ollamaToolCall is a tool call of Ollama's native tool calling API. Older
+// Ollama versions do not return an ID, so one is generated when missing.
+type ollamaToolCall struct {
+	ID       string             `json:"id,omitempty"`
+	Function ollamaToolFunction `json:"function"`
+}
+
+type ollamaToolFunction struct {
+	Index     int                    `json:"index,omitempty"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
 }
 
 type ollamaRequest struct {
-	Model    string          `json:"model"`
-	Messages []ollamaMessage `json:"messages"`
-	Stream   bool            `json:"stream"`
+	Model    string                   `json:"model"`
+	Messages []ollamaMessage          `json:"messages"`
+	Tools    []map[string]interface{} `json:"tools,omitempty"`
+	Stream   bool                     `json:"stream"`
 }
 
 type ollamaResponse struct {
@@ -464,6 +565,24 @@ func extractOllamaErrorMessage(statusCode int, body []byte) string {
 	return fmt.Sprintf("Ollama error (%d): %s", statusCode, bodyStr)
 }
 
+// isToolsUnsupported reports whether Ollama rejected a request because the
+// model does not support native tool calling
+func isToolsUnsupported(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+		strings.Contains(apiErr.Message, "does not support tools")
+}
+
+// newToolCallID returns an ID for a tool call that the provider did not
+// identify
+func newToolCallID() string {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return fmt.Sprintf("call_%d", time.Now().UnixNano())
+	}
+	return "call_" + hex.EncodeToString(random)
+}
+
 // extractJSONFromText attempts to extract a JSON object from text that may contain
 // additional explanation or commentary around the JSON
 func extractJSONFromText(text string) string {
@@ -495,27 +614,159 @@ func extractJSONFromText(text string) string {
 	return text[firstBrace : lastBrace+1]
 }
 
+// Chat sends messages to the model with the tools passed natively. If the
+// model rejects them, the request is repeated with the tools described in
+// the system prompt, and the client keeps doing so for later requests.
 func (c *ollamaClient) Chat(ctx context.Context, messages []Message, tools interface{}) (LLMResponse, error) {
+	// Convert interface{} tools to tool definitions via JSON
+	mcpTools, err := toolschema.FromAny(tools)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+
+	if !c.promptTools.Load() {
+		response, err := c.chatNative(ctx, messages, mcpTools)
+		if !isToolsUnsupported(err) {
+			return response, err
+		}
+		c.promptTools.Store(true)
+		if c.debug {
+			fmt.Fprintf(os.Stderr, "\r\n[LLM] [DEBUG] Ollama - %s does not support native tool calling, describing tools in the system prompt\n", c.model)
+		}
+	}
+	return c.chatPrompt(ctx, messages, mcpTools)
+}
+
+// chatNative sends a request with Ollama's native tool calling. Every tool
+// call in the response becomes a tool use, so a model can call several
+// tools at once.
+func (c *ollamaClient) chatNative(ctx context.Context, messages []Message, tools []toolschema.Tool) (LLMResponse, error) {
 	startTime := time.Now()
-	operation := "chat"
-	url := c.baseURL + "/api/chat"
 
-	embedding.LogLLMCallDetails("ollama", c.model, operation, url, len(messages))
+	systemContent := systemPrompt
+	if extra := systemContextFrom(ctx); extra != "" {
+		systemContent += "\n\n" + extra
+	}
 
-	// Convert interface{} tools to []mcp.Tool via JSON
-	var mcpTools []mcp.Tool
-	if tools != nil {
-		toolsJSON, err := json.Marshal(tools)
-		if err != nil {
-			return LLMResponse{}, fmt.Errorf("failed to marshal tools: %w", err)
+	// Ollama accepts tools in the OpenAI function format
+	req := ollamaRequest{
+		Model:    c.model,
+		Messages: append([]ollamaMessage{{Role: "system", Content: systemContent}}, ollamaNativeMessages(messages)...),
+		Tools:    toolschema.OpenAI(tools),
+	}
+	ollamaResp, err := c.send(ctx, startTime, req, false)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+
+	message := ollamaResp.Message
+	if len(message.ToolCalls) == 0 {
+		return c.response(startTime, []interface{}{TextContent{Type: "text", Text: message.Content}}, "end_turn"), nil
+	}
+
+	var content []interface{}
+	if strings.TrimSpace(message.Content) != "" {
+		content = append(content, TextContent{Type: "text", Text: message.Content})
+	}
+	for _, call := range message.ToolCalls {
+		id := call.ID
+		if id == "" {
+			id = newToolCallID()
+		}
+		input := call.Function.Arguments
+		if input == nil {
+			input = map[string]interface{}{}
+		}
+		content = append(content, ToolUse{
+			Type:  "tool_use",
+			ID:    id,
+			Name:  call.Function.Name,
+			Input: input,
+		})
+	}
+	return c.response(startTime, content, "tool_use"), nil
+}
+
+// ollamaNativeMessages converts conversation messages for native tool
+// calling: tool uses become tool calls of the assistant message, and each
+// tool result becomes a tool message naming its tool
+func ollamaNativeMessages(messages []Message) []ollamaMessage {
+	toolNames := make(map[string]string) // Tool use ID to tool name
+	result := make([]ollamaMessage, 0, len(messages))
+	for _, msg := range messages {
+		converted := ollamaMessage{Role: msg.Role}
+		var toolMessages []ollamaMessage
+		addToolUse := func(id, name string, input map[string]interface{}) {
+			toolNames[id] = name
+			converted.ToolCalls = append(converted.ToolCalls, ollamaToolCall{
+				ID:       id,
+				Function: ollamaToolFunction{Name: name, Arguments: input},
+			})
+		}
+		addToolResult := func(id string, content interface{}) {
+			text := extractTextFromContent(content)
+			if text == "" {
+				text = "{}"
+			}
+			toolMessages = append(toolMessages, ollamaMessage{Role: "tool", Content: text, ToolName: toolNames[id]})
+		}
+
+		switch content := msg.Content.(type) {
+		case string:
+			converted.Content = content
+		case []ToolResult:
+			for _, v := range content {
+				addToolResult(v.ToolUseID, v.Content)
+			}
+		case []interface{}:
+			for _, item := range content {
+				switch v := item.(type) {
+				case TextContent:
+					converted.Content = v.Text
+				case ToolUse:
+					addToolUse(v.ID, v.Name, v.Input)
+				case ToolResult:
+					addToolResult(v.ToolUseID, v.Content)
+				case map[string]interface{}:
+					// Items unmarshaled from JSON
+					switch v["type"] {
+					case "text":
+						if text, ok := v["text"].(string); ok {
+							converted.Content = text
+						}
+					case "tool_use":
+						id, ok1 := v["id"].(string)
+						name, ok2 := v["name"].(string)
+						if !ok1 || !ok2 {
+							continue
+						}
+						input, _ := v["input"].(map[string]interface{}) //nolint:errcheck // Missing input is sent as empty
+						addToolUse(id, name, input)
+					case "tool_result":
+						if id, ok := v["tool_use_id"].(string); ok {
+							addToolResult(id, v["content"])
+						}
+					}
+				}
+			}
 		}
-		if err := json.Unmarshal(toolsJSON, &mcpTools); err != nil {
-			return LLMResponse{}, fmt.Errorf("failed to unmarshal tools: %w", err)
+
+		if converted.Content != "" || len(converted.ToolCalls) > 0 {
+			result = append(result, converted)
 		}
+		result = append(result, toolMessages...)
 	}
+	return result
+}
+
+// chatPrompt sends a request for a model without native tool calling. The
+// tools are described in the system prompt, and a reply that is a JSON
+// object naming a tool is taken as a call of that tool.
+func (c *ollamaClient) chatPrompt(ctx context.Context, messages []Message, tools []toolschema.Tool) (LLMResponse, error) {
+	startTime := time.Now()
 
 	// Format tools for Ollama
-	toolsContext := c.formatToolsForOllama(mcpTools)
+	toolsContext := toolschema.Describe(tools)
 
 	// Create system message with tool information
 	systemMessage := fmt.Sprintf(`You are a helpful PostgreSQL database assistant with expert knowledge on PostgreSQL and products from pgEdge. You have access to the following tools:
@@ -538,70 +789,122 @@ IMPORTANT INSTRUCTIONS:
 5. Only use tools when necessary to answer the user's question.
 6. Be concise and direct - show results without explaining your methodology unless specifically asked.`, toolsContext)
 
-	// Convert messages to Ollama format
-	ollamaMessages := []ollamaMessage{
-		{
-			Role:    "system",
-			Content: systemMessage,
-		},
+	if extra := systemContextFrom(ctx); extra != "" {
+		systemMessage += "\n\n" + extra
+	}
+
+	req := ollamaRequest{
+		Model:    c.model,
+		Messages: append([]ollamaMessage{{Role: "system", Content: systemMessage}}, ollamaPromptMessages(messages)...),
+	}
+	ollamaResp, err := c.send(ctx, startTime, req, true)
+	if err != nil {
+		return LLMResponse{}, err
 	}
 
+	content := ollamaResp.Message.Content
+
+	// Try to parse as tool call. First try direct parsing (if the model
+	// behaved correctly), then try to extract JSON from surrounding text,
+	// which handles cases where the model adds explanation around the JSON.
+	var toolCall toolCallRequest
+	parsed := json.Unmarshal([]byte(strings.TrimSpace(content)), &toolCall) == nil && toolCall.Tool != ""
+	if !parsed {
+		if extractedJSON := extractJSONFromText(content); extractedJSON != "" {
+			toolCall = toolCallRequest{}
+			parsed = json.Unmarshal([]byte(extractedJSON), &toolCall) == nil && toolCall.Tool != ""
+		}
+	}
+	if parsed {
+		return c.response(startTime, []interface{}{
+			ToolUse{
+				Type:  "tool_use",
+				ID:    newToolCallID(),
+				Name:  toolCall.Tool,
+				Input: toolCall.Arguments,
+			},
+		}, "tool_use"), nil
+	}
+
+	// It's a text response
+	return c.response(startTime, []interface{}{TextContent{Type: "text", Text: content}}, "end_turn"), nil
+}
+
+// ollamaPromptMessages converts conversation messages for a model without
+// native tool calling, passing tool results as text
+func ollamaPromptMessages(messages []Message) []ollamaMessage {
+	result := make([]ollamaMessage, 0, len(messages))
 	for _, msg := range messages {
+		var parts []string
 		switch content := msg.Content.(type) {
 		case string:
-			ollamaMessages = append(ollamaMessages, ollamaMessage{
+			result = append(result, ollamaMessage{
 				Role:    msg.Role,
 				Content: content,
 			})
+			continue
+		case []ToolResult:
+			for _, tr := range content {
+				parts = append(parts, fmt.Sprintf("Tool result:\n%s", ollamaToolResultText(tr.Content)))
+			}
 		case []interface{}:
 			// Handle tool results
-			var parts []string
 			for _, item := range content {
 				if tr, ok := item.(ToolResult); ok {
-					contentStr := ""
-					switch c := tr.Content.(type) {
-					case string:
-						contentStr = c
-					case []mcp.ContentItem:
-						var texts []string
-						for _, ci := range c {
-							texts = append(texts, ci.Text)
-						}
-						contentStr = strings.Join(texts, "\n")
-					default:
-						data, err := json.Marshal(c)
-						if err != nil {
-							contentStr = fmt.Sprintf("%v", c)
-						} else {
-							contentStr = string(data)
-						}
-					}
-					parts = append(parts, fmt.Sprintf("Tool result:\n%s", contentStr))
+					parts = append(parts, fmt.Sprintf("Tool result:\n%s", ollamaToolResultText(tr.Content)))
 				}
 			}
-			if len(parts) > 0 {
-				ollamaMessages = append(ollamaMessages, ollamaMessage{
-					Role:    msg.Role,
-					Content: strings.Join(parts, "\n\n"),
-				})
-			}
+		}
+		if len(parts) > 0 {
+			result = append(result, ollamaMessage{
+				Role:    msg.Role,
+				Content: strings.Join(parts, "\n\n"),
+			})
 		}
 	}
+	return result
+}
 
-	req := ollamaRequest{
-		Model:    c.model,
-		Messages: ollamaMessages,
-		Stream:   false,
+// ollamaToolResultText returns the text of a tool result's content
+func ollamaToolResultText(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []mcp.ContentItem:
+		var texts []string
+		for _, ci := range c {
+			texts = append(texts, ci.Text)
+		}
+		return strings.Join(texts, "\n")
+	default:
+		data, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Sprintf("%v", c)
+		}
+		return string(data)
 	}
+}
+
+// send posts a chat request and returns Ollama's response, streaming text
+// to the context's text stream if there is one. With holdJSON, text that
+// may be a JSON tool call is held back from the stream.
+func (c *ollamaClient) send(ctx context.Context, startTime time.Time, req ollamaRequest, holdJSON bool) (ollamaResponse, error) {
+	operation := "chat"
+	url := c.baseURL + "/api/chat"
+
+	embedding.LogLLMCallDetails("ollama", c.model, operation, url, len(req.Messages))
+
+	onText := textStreamFrom(ctx)
+	req.Stream = onText != nil
 
 	reqData, err := json.Marshal(req)
 	if err != nil {
-		return LLMResponse{}, fmt.Errorf("failed to marshal request: %w", err)
+		return ollamaResponse{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/chat", bytes.NewBuffer(reqData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqData))
 	if err != nil {
-		return LLMResponse{}, fmt.Errorf("failed to create request: %w", err)
+		return ollamaResponse{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -611,7 +914,7 @@ IMPORTANT INSTRUCTIONS:
 		embedding.LogConnectionError("ollama", url, err)
 		duration := time.Since(startTime)
 		embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, err)
-		return LLMResponse{}, fmt.Errorf("failed to send request: %w", err)
+		return ollamaResponse{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -621,100 +924,39 @@ IMPORTANT INSTRUCTIONS:
 			duration := time.Since(startTime)
 			readErr := fmt.Errorf("API error %d (failed to read body: %w)", resp.StatusCode, err)
 			embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, readErr)
-			return LLMResponse{}, readErr
+			return ollamaResponse{}, readErr
 		}
 
 		// Extract user-friendly error message from Ollama's error response
 		userFriendlyMsg := extractOllamaErrorMessage(resp.StatusCode, body)
 
 		duration := time.Since(startTime)
-		apiErr := fmt.Errorf("%s", userFriendlyMsg)
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: userFriendlyMsg}
 		embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, apiErr)
-		return LLMResponse{}, apiErr
+		return ollamaResponse{}, apiErr
 	}
 
 	var ollamaResp ollamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		duration := time.Since(startTime)
-		embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, err)
-		return LLMResponse{}, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	content := ollamaResp.Message.Content
-
-	// Try to parse as tool call
-	// First try direct parsing (if the model behaved correctly)
-	var toolCall toolCallRequest
-	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &toolCall); err == nil && toolCall.Tool != "" {
-		// It's a tool call
-		duration := time.Since(startTime)
-		embedding.LogLLMResponseTrace("ollama", c.model, operation, resp.StatusCode, "tool_use")
-		embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, nil) // Ollama doesn't provide token counts
-
-		// Build token usage for debug (Ollama doesn't provide counts)
-		var tokenUsage *TokenUsage
-		if c.debug {
-			tokenUsage = &TokenUsage{
-				Provider: "ollama",
-			}
-
-			// Log to stderr for CLI
-			fmt.Fprintf(os.Stderr, "\r\n[LLM] [DEBUG] Ollama - Response: tool_use (Ollama does not provide token counts)\n")
-		}
-
-		return LLMResponse{
-			Content: []interface{}{
-				ToolUse{
-					Type:  "tool_use",
-					ID:    "ollama-tool-1", // Ollama doesn't provide IDs, so we generate one
-					Name:  toolCall.Tool,
-					Input: toolCall.Arguments,
-				},
-			},
-			StopReason: "tool_use",
-			TokenUsage: tokenUsage,
-		}, nil
-	}
-
-	// If direct parsing failed, try to extract JSON from surrounding text
-	// This handles cases where the model adds explanation around the JSON
-	if extractedJSON := extractJSONFromText(content); extractedJSON != "" {
-		if err := json.Unmarshal([]byte(extractedJSON), &toolCall); err == nil && toolCall.Tool != "" {
-			// Successfully extracted and parsed tool call
+	if onText != nil {
+		ollamaResp, err = readOllamaStream(resp.Body, onText, holdJSON)
+		if err != nil {
 			duration := time.Since(startTime)
-			embedding.LogLLMResponseTrace("ollama", c.model, operation, resp.StatusCode, "tool_use")
-			embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, nil)
-
-			// Build token usage for debug
-			var tokenUsage *TokenUsage
-			if c.debug {
-				tokenUsage = &TokenUsage{
-					Provider: "ollama",
-				}
-
-				// Log to stderr for CLI
-				fmt.Fprintf(os.Stderr, "\r\n[LLM] [DEBUG] Ollama - Response: tool_use (Ollama does not provide token counts)\n")
-			}
-
-			return LLMResponse{
-				Content: []interface{}{
-					ToolUse{
-						Type:  "tool_use",
-						ID:    "ollama-tool-1",
-						Name:  toolCall.Tool,
-						Input: toolCall.Arguments,
-					},
-				},
-				StopReason: "tool_use",
-				TokenUsage: tokenUsage,
-			}, nil
+			embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, err)
+			return ollamaResponse{}, fmt.Errorf("failed to read response stream: %w", err)
 		}
+	} else if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		duration := time.Since(startTime)
+		embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, err)
+		return ollamaResponse{}, fmt.Errorf("failed to decode response: %w", err)
 	}
+	return ollamaResp, nil
+}
 
-	// It's a text response
+// response logs a successful call and returns its content
+func (c *ollamaClient) response(startTime time.Time, content []interface{}, stopReason string) LLMResponse {
 	duration := time.Since(startTime)
-	embedding.LogLLMResponseTrace("ollama", c.model, operation, resp.StatusCode, "end_turn")
-	embedding.LogLLMCall("ollama", c.model, operation, 0, 0, duration, nil) // Ollama doesn't provide token counts
+	embedding.LogLLMResponseTrace("ollama", c.model, "chat", http.StatusOK, stopReason)
+	embedding.LogLLMCall("ollama", c.model, "chat", 0, 0, duration, nil) // Ollama doesn't provide token counts
 
 	// Build token usage for debug (Ollama doesn't provide counts)
 	var tokenUsage *TokenUsage
@@ -724,50 +966,14 @@ IMPORTANT INSTRUCTIONS:
 		}
 
 		// Log to stderr for CLI
-		fmt.Fprintf(os.Stderr, "\r\n[LLM] [DEBUG] Ollama - Response: end_turn (Ollama does not provide token counts)\n")
+		fmt.Fprintf(os.Stderr, "\r\n[LLM] [DEBUG] Ollama - Response: %s (Ollama does not provide token counts)\n", stopReason)
 	}
 
 	return LLMResponse{
-		Content: []interface{}{
-			TextContent{
-				Type: "text",
-				Text: content,
-			},
-		},
-		StopReason: "end_turn",
+		Content:    content,
+		StopReason: stopReason,
 		TokenUsage: tokenUsage,
-	}, nil
-}
-
-func (c *ollamaClient) formatToolsForOllama(tools []mcp.Tool) string {
-	var toolDescriptions []string
-	for _, tool := range tools {
-		toolDesc := fmt.Sprintf("- %s: %s", tool.Name, tool.Description)
-
-		// Add parameter info if available
-		if len(tool.InputSchema.Properties) > 0 {
-			var params []string
-			for paramName, paramInfo := range tool.InputSchema.Properties {
-				paramMap, ok := paramInfo.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				paramType, _ := paramMap["type"].(string)        //nolint:errcheck // Optional field, default to empty
-				paramDesc, _ := paramMap["description"].(string) //nolint:errcheck // Optional field, default to empty
-				if paramType == "" {
-					paramType = "any"
-				}
-				params = append(params, fmt.Sprintf("%s (%s): %s", paramName, paramType, paramDesc))
-			}
-			if len(params) > 0 {
-				toolDesc += "\n  Parameters:\n    " + strings.Join(params, "\n    ")
-			}
-		}
-
-		toolDescriptions = append(toolDescriptions, toolDesc)
 	}
-
-	return strings.Join(toolDescriptions, "\n")
 }
 
 // ListModels returns available models from the Ollama server
@@ -809,16 +1015,28 @@ func (c *ollamaClient) ListModels(ctx context.Context) ([]string, error) {
 	return models, nil
 }
 
-// openaiClient implements LLMClient for OpenAI GPT models
+// openaiClient implements LLMClient for OpenAI GPT models, and for
+// Azure OpenAI deployments, which serve the same API
 type openaiClient struct {
 	apiKey      string
-	model       string
+	model       string // Model, or the deployment for Azure OpenAI
 	maxTokens   int
 	temperature float64
 	debug       bool
 	client      *http.Client
+	azure       *azureOpenAI // Set for Azure OpenAI
+}
+
+// azureOpenAI addresses the deployments of an Azure OpenAI resource
+type azureOpenAI struct {
+	endpoint   string // Resource endpoint, e.g. https://example.openai.azure.com
+	apiVersion string
 }
 
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when none is
+// configured
+const DefaultAzureAPIVersion = "2024-10-21"
+
 // NewOpenAIClient creates a new OpenAI client
 func NewOpenAIClient(apiKey, model string, maxTokens int, temperature float64, debug bool) LLMClient {
 	return &openaiClient{
@@ -827,10 +1045,49 @@ func NewOpenAIClient(apiKey, model string, maxTokens int, temperature float64, d
 		maxTokens:   maxTokens,
 		temperature: temperature,
 		debug:       debug,
-		client:      &http.Client{},
+		client:      netproxy.NewClient(netproxy.ProviderOpenAI, 0),
 	}
 }
 
+// NewAzureOpenAIClient creates a client for a deployment of an Azure OpenAI
+// resource. Requests go to the deployment, so it takes the place of the
+// model.
+func NewAzureOpenAIClient(endpoint, apiVersion, apiKey, deployment string, maxTokens int, temperature float64, debug bool) LLMClient {
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+	return &openaiClient{
+		apiKey:      apiKey,
+		model:       deployment,
+		maxTokens:   maxTokens,
+		temperature: temperature,
+		debug:       debug,
+		client:      netproxy.NewClient(netproxy.ProviderAzure, 0),
+		azure: &azureOpenAI{
+			endpoint:   strings.TrimSuffix(endpoint, "/"),
+			apiVersion: apiVersion,
+		},
+	}
+}
+
+// chatURL returns the Chat Completions endpoint
+func (c *openaiClient) chatURL() string {
+	if c.azure != nil {
+		return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+			c.azure.endpoint, neturl.PathEscape(c.model), neturl.QueryEscape(c.azure.apiVersion))
+	}
+	return "https://api.openai.com/v1/chat/completions"
+}
+
+// setAuth adds the API key to a request
+func (c *openaiClient) setAuth(req *http.Request) {
+	if c.azure != nil {
+		req.Header.Set("api-key", c.apiKey)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+}
+
 type openaiMessage struct {
 	Role       string      `json:"role"`
 	Content    interface{} `json:"content,omitempty"`
@@ -839,12 +1096,14 @@ type openaiMessage struct {
 }
 
 type openaiRequest struct {
-	Model               string          `json:"model"`
-	Messages            []openaiMessage `json:"messages"`
-	Tools               interface{}     `json:"tools,omitempty"`
-	MaxTokens           int             `json:"max_tokens,omitempty"`
-	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"`
-	Temperature         float64         `json:"temperature,omitempty"`
+	Model               string               `json:"model"`
+	Messages            []openaiMessage      `json:"messages"`
+	Tools               interface{}          `json:"tools,omitempty"`
+	MaxTokens           int                  `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                  `json:"max_completion_tokens,omitempty"`
+	Temperature         float64              `json:"temperature,omitempty"`
+	Stream              bool                 `json:"stream,omitempty"`
+	StreamOptions       *openaiStreamOptions `json:"stream_options,omitempty"`
 }
 
 type openaiUsage struct {
@@ -921,47 +1180,26 @@ func extractTextFromContent(content interface{}) string {
 func (c *openaiClient) Chat(ctx context.Context, messages []Message, tools interface{}) (LLMResponse, error) {
 	startTime := time.Now()
 	operation := "chat"
-	url := "https://api.openai.com/v1/chat/completions"
+	url := c.chatURL()
 
 	embedding.LogLLMCallDetails("openai", c.model, operation, url, len(messages))
 
-	// Convert interface{} tools to []mcp.Tool via JSON
-	var mcpTools []mcp.Tool
-	if tools != nil {
-		toolsJSON, err := json.Marshal(tools)
-		if err != nil {
-			return LLMResponse{}, fmt.Errorf("failed to marshal tools: %w", err)
-		}
-		if err := json.Unmarshal(toolsJSON, &mcpTools); err != nil {
-			return LLMResponse{}, fmt.Errorf("failed to unmarshal tools: %w", err)
-		}
+	// Convert interface{} tools to tool definitions via JSON
+	mcpTools, err := toolschema.FromAny(tools)
+	if err != nil {
+		return LLMResponse{}, err
 	}
 
 	// Convert MCP tools to OpenAI format
-	var openaiTools []map[string]interface{}
-	if len(mcpTools) > 0 {
-		for _, tool := range mcpTools {
-			openaiTools = append(openaiTools, map[string]interface{}{
-				"type": "function",
-				"function": map[string]interface{}{
-					"name":        tool.Name,
-					"description": tool.Description,
-					"parameters":  tool.InputSchema,
-				},
-			})
-		}
-	}
+	openaiTools := toolschema.OpenAI(mcpTools)
 
 	// Convert messages to OpenAI format
 	// Start with system message
-	systemContent := `You are a helpful PostgreSQL database assistant with expert knowledge on PostgreSQL and products from pgEdge with access to MCP tools.
+	systemContent := systemPrompt
 
-When executing tools:
-- Be concise and direct
-- Show results without explaining your methodology unless specifically asked
-- Base responses ONLY on actual tool results - never make up or guess data
-- Format results clearly for the user
-- Only use tools when necessary to answer the question`
+	if extra := systemContextFrom(ctx); extra != "" {
+		systemContent += "\n\n" + extra
+	}
 
 	openaiMessages := make([]openaiMessage, 0, len(messages)+1)
 	openaiMessages = append(openaiMessages, openaiMessage{
@@ -1124,6 +1362,12 @@ When executing tools:
 		reqData.Tools = openaiTools
 	}
 
+	onText := textStreamFrom(ctx)
+	if onText != nil {
+		reqData.Stream = true
+		reqData.StreamOptions = &openaiStreamOptions{IncludeUsage: true}
+	}
+
 	reqJSON, err := json.Marshal(reqData)
 	if err != nil {
 		duration := time.Since(startTime)
@@ -1142,7 +1386,7 @@ When executing tools:
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	c.setAuth(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -1153,13 +1397,17 @@ When executing tools:
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		duration := time.Since(startTime)
-		readErr := fmt.Errorf("failed to read response body: %w", err)
-		embedding.LogLLMCall("openai", c.model, operation, 0, 0, duration, readErr)
-		return LLMResponse{}, readErr
+	// Read response body; a successful streamed response is read as it
+	// arrives
+	var body []byte
+	if onText == nil || resp.StatusCode != http.StatusOK {
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			duration := time.Since(startTime)
+			readErr := fmt.Errorf("failed to read response body: %w", err)
+			embedding.LogLLMCall("openai", c.model, operation, 0, 0, duration, readErr)
+			return LLMResponse{}, readErr
+		}
 	}
 
 	// Check for errors
@@ -1173,13 +1421,20 @@ When executing tools:
 		userFriendlyMsg := extractOpenAIErrorMessage(resp.StatusCode, body)
 
 		duration := time.Since(startTime)
-		apiErr := fmt.Errorf("%s", userFriendlyMsg)
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: userFriendlyMsg}
 		embedding.LogLLMCall("openai", c.model, operation, 0, 0, duration, apiErr)
 		return LLMResponse{}, apiErr
 	}
 
 	var openaiResp openaiResponse
-	if err := json.Unmarshal(body, &openaiResp); err != nil {
+	if onText != nil {
+		openaiResp, err = readOpenAIStream(resp.Body, onText)
+		if err != nil {
+			duration := time.Since(startTime)
+			embedding.LogLLMCall("openai", c.model, operation, 0, 0, duration, err)
+			return LLMResponse{}, fmt.Errorf("failed to read response stream: %w", err)
+		}
+	} else if err := json.Unmarshal(body, &openaiResp); err != nil {
 		duration := time.Since(startTime)
 		embedding.LogLLMCall("openai", c.model, operation, 0, 0, duration, err)
 		return LLMResponse{}, fmt.Errorf("failed to parse response: %w", err)
@@ -1312,6 +1567,9 @@ When executing tools:
 // ListModels returns available models from OpenAI
 // Filters out embedding, audio, and image models
 func (c *openaiClient) ListModels(ctx context.Context) ([]string, error) {
+	if c.azure != nil {
+		return c.listAzureDeployments(ctx)
+	}
 	url := "https://api.openai.com/v1/models"
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -1319,7 +1577,7 @@ func (c *openaiClient) ListModels(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	c.setAuth(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -1374,3 +1632,33 @@ func (c *openaiClient) ListModels(ctx context.Context) ([]string, error) {
 
 	return models, nil
 }
+
+// listAzureDeployments returns the client's deployment. The data plane API
+// cannot list deployments, so the resource's models are listed to check
+// that the endpoint and key work.
+func (c *openaiClient) listAzureDeployments(ctx context.Context) ([]string, error) {
+	url := fmt.Sprintf("%s/openai/models?api-version=%s", c.azure.endpoint, neturl.QueryEscape(c.azure.apiVersion))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setAuth(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck // Error response body read is best effort
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	if c.model == "" {
+		return []string{}, nil
+	}
+	return []string{c.model}, nil
+}
@@ -13,6 +13,7 @@ package chat
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -75,8 +76,70 @@ func TestAnthropicClient_TextResponse(t *testing.T) {
 	_, _ = server, client // Suppress unused warnings
 }
 
+func TestSplitSystemMessages(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "How many orders?"},
+		{Role: "system", Content: "[Summary of earlier conversation]\n- Orders were discussed"},
+		{Role: "assistant", Content: "42"},
+	}
+
+	rest, system := splitSystemMessages(messages)
+	if len(rest) != 2 || rest[0].Role != "user" || rest[1].Role != "assistant" {
+		t.Errorf("Expected the user and assistant messages, got %+v", rest)
+	}
+	if len(system) != 1 || !strings.Contains(system[0], "Orders were discussed") {
+		t.Errorf("Expected the system message text, got %v", system)
+	}
+
+	rest, system = splitSystemMessages(messages[:1])
+	if len(rest) != 1 || system != nil {
+		t.Errorf("Expected no system messages, got %v", system)
+	}
+}
+
+func TestOllamaClient_SystemContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+
+		if len(req.Messages) == 0 || req.Messages[0].Role != "system" {
+			t.Fatalf("Expected first message to be the system message")
+		}
+		if !strings.HasSuffix(req.Messages[0].Content, "\n\nSchema: public.users") {
+			t.Errorf("System message should end with the extra context, got %q", req.Messages[0].Content)
+		}
+
+		resp := ollamaResponse{
+			Model:   "test-model",
+			Message: ollamaMessage{Role: "assistant", Content: "ok"},
+			Done:    true,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(server.URL, "test-model", false)
+	ctx := WithSystemContext(context.Background(), "Schema: public.users")
+	if _, err := client.Chat(ctx, []Message{{Role: "user", Content: "hi"}}, []mcp.Tool{}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+}
+
+// rejectNativeTools fails a request with tools as Ollama does for models
+// without native tool calling, and reports whether it did
+func rejectNativeTools(w http.ResponseWriter, req ollamaRequest) bool {
+	if len(req.Tools) == 0 {
+		return false
+	}
+	http.Error(w, `{"error":"registry.ollama.ai/library/test-model:latest does not support tools"}`, http.StatusBadRequest)
+	return true
+}
+
 func TestOllamaClient_ToolCall(t *testing.T) {
-	// Create test server
+	// Create test server for a model without native tool calling
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Verify request
 		var req ollamaRequest
@@ -87,6 +150,9 @@ func TestOllamaClient_ToolCall(t *testing.T) {
 		if req.Model != "test-model" {
 			t.Errorf("Expected model 'test-model', got '%s'", req.Model)
 		}
+		if rejectNativeTools(w, req) {
+			return
+		}
 
 		// Send tool call response
 		resp := ollamaResponse{
@@ -207,8 +273,30 @@ func TestOllamaClient_TextResponse(t *testing.T) {
 	}
 }
 
-func TestFormatToolsForOllama(t *testing.T) {
-	client := &ollamaClient{}
+func TestOllamaClient_ToolsInSystemPrompt(t *testing.T) {
+	var systemPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if rejectNativeTools(w, req) {
+			return
+		}
+		if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+			systemPrompt = req.Messages[0].Content
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ollamaResponse{
+			Model:   "test-model",
+			Message: ollamaMessage{Role: "assistant", Content: "Done"},
+			Done:    true,
+		})
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(server.URL, "test-model", false)
 
 	tools := []mcp.Tool{
 		{
@@ -220,30 +308,137 @@ func TestFormatToolsForOllama(t *testing.T) {
 					"param1": map[string]interface{}{
 						"type":        "string",
 						"description": "First parameter",
+						"enum":        []string{"a", "b"},
 					},
 					"param2": map[string]interface{}{
 						"type":        "number",
 						"description": "Second parameter",
 					},
 				},
+				Required: []string{"param1"},
 			},
 		},
 	}
 
-	result := client.formatToolsForOllama(tools)
+	if _, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, tools); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	// Check for tool name, description and parameter constraints
+	for _, want := range []string{
+		"- test_tool: A test tool",
+		"param1 (string, required, one of: a | b): First parameter",
+		"param2 (number): Second parameter",
+	} {
+		if !containsString(systemPrompt, want) {
+			t.Errorf("System prompt should contain %q:\n%s", want, systemPrompt)
+		}
+	}
+}
+
+func TestOllamaClient_NativeToolCalls(t *testing.T) {
+	var requests []ollamaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, req)
+
+		// Two tool calls at once, without IDs as older Ollama versions
+		// return them
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"model":"test-model","message":{"role":"assistant","content":"",
+			"tool_calls":[
+				{"function":{"name":"query_database","arguments":{"query":"SELECT 1"}}},
+				{"function":{"name":"list_tables","arguments":{}}}]},"done":true}`)
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(server.URL, "test-model", false)
+	tools := []mcp.Tool{{Name: "query_database", Description: "Run a query", InputSchema: mcp.InputSchema{Type: "object"}}}
+	messages := []Message{
+		{Role: "user", Content: "How many orders?"},
+		{Role: "assistant", Content: []interface{}{
+			TextContent{Type: "text", Text: "Let me check."},
+			ToolUse{Type: "tool_use", ID: "call_a", Name: "query_database", Input: map[string]interface{}{"query": "SELECT count(*) FROM orders"}},
+		}},
+		{Role: "user", Content: []ToolResult{{Type: "tool_result", ToolUseID: "call_a", Content: "42"}}},
+	}
+
+	response, err := client.Chat(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
 
-	// Check that the result contains expected strings
-	if result == "" {
-		t.Error("Expected non-empty result")
+	// The tools are passed natively, and the history uses tool calls and
+	// tool messages
+	req := requests[0]
+	if len(req.Tools) != 1 || strings.Contains(req.Messages[0].Content, "query_database") {
+		t.Errorf("Expected native tools, got tools %v and system prompt %q", req.Tools, req.Messages[0].Content)
+	}
+	if len(req.Messages) != 4 {
+		t.Fatalf("Expected system, user, assistant and tool messages, got %+v", req.Messages)
+	}
+	assistant, tool := req.Messages[2], req.Messages[3]
+	if assistant.Content != "Let me check." || len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].Function.Arguments["query"] != "SELECT count(*) FROM orders" {
+		t.Errorf("Unexpected assistant message: %+v", assistant)
+	}
+	if tool.Role != "tool" || tool.Content != "42" || tool.ToolName != "query_database" {
+		t.Errorf("Unexpected tool message: %+v", tool)
 	}
 
-	// Check for tool name and description
-	if !containsString(result, "test_tool") {
-		t.Error("Result should contain tool name")
+	// Each tool call becomes a tool use with its own ID
+	if response.StopReason != "tool_use" || len(response.Content) != 2 {
+		t.Fatalf("Expected two tool uses, got %+v", response)
+	}
+	first, ok1 := response.Content[0].(ToolUse)
+	second, ok2 := response.Content[1].(ToolUse)
+	if !ok1 || !ok2 || first.Name != "query_database" || second.Name != "list_tables" {
+		t.Fatalf("Unexpected tool uses: %+v", response.Content)
+	}
+	if first.ID == "" || first.ID == second.ID || second.Input == nil {
+		t.Errorf("Expected distinct IDs and inputs, got %+v and %+v", first, second)
+	}
+}
+
+func TestOllamaClient_PromptToolsFallback(t *testing.T) {
+	var requests []ollamaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, req)
+		if rejectNativeTools(w, req) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ollamaResponse{
+			Model:   "test-model",
+			Message: ollamaMessage{Role: "assistant", Content: "Done"},
+			Done:    true,
+		})
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(server.URL, "test-model", false)
+	tools := []mcp.Tool{{Name: "query_database", Description: "Run a query", InputSchema: mcp.InputSchema{Type: "object"}}}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, tools); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
 	}
 
-	if !containsString(result, "A test tool") {
-		t.Error("Result should contain tool description")
+	// The first request is repeated with the tools in the system prompt,
+	// which the client keeps using
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requests))
+	}
+	for _, req := range requests[1:] {
+		if len(req.Tools) != 0 || !strings.Contains(req.Messages[0].Content, "- query_database: Run a query") {
+			t.Errorf("Expected the tools in the system prompt, got %+v", req)
+		}
 	}
 }
 
@@ -634,3 +829,60 @@ func TestOpenAIClient_GPT5UsesMaxCompletionTokens(t *testing.T) {
 		})
 	}
 }
+
+func TestAzureOpenAIClient_Chat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/example-deployment/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("api-version"); got != DefaultAzureAPIVersion {
+			t.Errorf("Expected api-version %s, got %s", DefaultAzureAPIVersion, got)
+		}
+		if r.Header.Get("api-key") != "test-key" || r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected only the api-key header, got %v", r.Header)
+		}
+
+		resp := openaiResponse{
+			Choices: []openaiChoice{{
+				Message:      openaiMessage{Role: "assistant", Content: "Hello from Azure"},
+				FinishReason: "stop",
+			}},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewAzureOpenAIClient(server.URL+"/", "", "test-key", "example-deployment", 1024, 0.7, false)
+	response, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "Hello"}}, []mcp.Tool{})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	text, ok := response.Content[0].(TextContent)
+	if !ok || text.Text != "Hello from Azure" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestAzureOpenAIClient_ListModels(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/models" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewAzureOpenAIClient(server.URL, "", "test-key", "example-deployment", 0, 0, false)
+	models, err := client.ListModels(context.Background())
+	if err != nil || len(models) != 1 || models[0] != "example-deployment" {
+		t.Errorf("ListModels() = %v, %v; want the deployment", models, err)
+	}
+
+	status = http.StatusUnauthorized
+	if _, err := client.ListModels(context.Background()); err == nil {
+		t.Error("Expected an error for a rejected key")
+	}
+}
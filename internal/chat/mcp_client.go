@@ -73,6 +73,7 @@ type stdioClient struct {
 	scanner    *bufio.Scanner
 	requestID  int
 	mu         sync.Mutex
+	pipeMu     sync.Mutex // Serializes request/response exchanges on the pipes
 	serverInfo mcp.Implementation
 }
 
@@ -268,6 +269,11 @@ func (c *stdioClient) sendRequest(ctx context.Context, method string, params int
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	// Responses are matched to requests by order, so only one request
+	// may be in flight at a time
+	c.pipeMu.Lock()
+	defer c.pipeMu.Unlock()
+
 	// Send request
 	if _, err := c.stdin.Write(append(reqData, '\n')); err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
@@ -0,0 +1,232 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Memory is a fact or preference stored on the server with /remember
+type Memory struct {
+	ID        int64     `json:"id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MemoriesClient manages remembered facts via the REST API
+type MemoriesClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewMemoriesClient creates a new memories client
+func NewMemoriesClient(baseURL, token string) *MemoriesClient {
+	return &MemoriesClient{
+		baseURL: strings.TrimSuffix(baseURL, "/mcp/v1") + "/api/memories",
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request and decodes a JSON response into result (if non-nil)
+func (c *MemoriesClient) do(ctx context.Context, method, url string, body interface{}, wantStatus int, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("memory not found")
+	}
+	if resp.StatusCode != wantStatus {
+		respBody, _ := io.ReadAll(resp.Body) //nolint:errcheck // Best effort to read error body
+		return fmt.Errorf("request failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// List returns the current user's memories
+func (c *MemoriesClient) List(ctx context.Context) ([]Memory, error) {
+	var result struct {
+		Memories []Memory `json:"memories"`
+	}
+	if err := c.do(ctx, "GET", c.baseURL, nil, http.StatusOK, &result); err != nil {
+		return nil, err
+	}
+	return result.Memories, nil
+}
+
+// Add stores a new memory
+func (c *MemoriesClient) Add(ctx context.Context, content string) (*Memory, error) {
+	var memory Memory
+	body := map[string]string{"content": content}
+	if err := c.do(ctx, "POST", c.baseURL, body, http.StatusCreated, &memory); err != nil {
+		return nil, err
+	}
+	return &memory, nil
+}
+
+// Delete removes a memory by ID
+func (c *MemoriesClient) Delete(ctx context.Context, id int64) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("%s/%d", c.baseURL, id), nil, http.StatusOK, nil)
+}
+
+// DeleteAll removes all of the current user's memories
+func (c *MemoriesClient) DeleteAll(ctx context.Context) (int64, error) {
+	var result struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := c.do(ctx, "DELETE", c.baseURL+"?all=true", nil, http.StatusOK, &result); err != nil {
+		return 0, err
+	}
+	return result.Deleted, nil
+}
+
+// formatMemories renders memories as a system prompt section
+// Matches the format used by the server's LLM proxy for the web client
+func formatMemories(memories []Memory) string {
+	if len(memories) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The user asked you to remember the following about their environment and preferences:\n")
+	for _, memory := range memories {
+		sb.WriteString("- ")
+		sb.WriteString(memory.Content)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// loadMemories refreshes the cached memory context from the server
+func (c *Client) loadMemories(ctx context.Context) error {
+	if c.memories == nil {
+		return nil
+	}
+	memories, err := c.memories.List(ctx)
+	if err != nil {
+		return err
+	}
+	c.memoryContext = formatMemories(memories)
+	return nil
+}
+
+// handleRememberCommand handles /remember [text]
+// Without arguments it lists the stored memories
+func (c *Client) handleRememberCommand(ctx context.Context, args []string) bool {
+	if c.memories == nil {
+		c.ui.PrintError("Memories are only available when running with authentication (HTTP mode)")
+		return true
+	}
+
+	if len(args) == 0 {
+		memories, err := c.memories.List(ctx)
+		if err != nil {
+			c.ui.PrintError(fmt.Sprintf("Failed to list memories: %v", err))
+			return true
+		}
+		if len(memories) == 0 {
+			c.ui.PrintSystemMessage("No memories saved. Use /remember <fact> to add one.")
+			return true
+		}
+		c.ui.PrintSystemMessage(fmt.Sprintf("Memories (%d):", len(memories)))
+		for _, memory := range memories {
+			fmt.Printf("  [%d] %s\n", memory.ID, memory.Content)
+		}
+		return true
+	}
+
+	memory, err := c.memories.Add(ctx, strings.Join(args, " "))
+	if err != nil {
+		c.ui.PrintError(fmt.Sprintf("Failed to save memory: %v", err))
+		return true
+	}
+	if err := c.loadMemories(ctx); err != nil {
+		c.ui.PrintError(fmt.Sprintf("Warning: Failed to reload memories: %v", err))
+	}
+
+	c.ui.PrintSystemMessage(fmt.Sprintf("Remembered [%d]: %s", memory.ID, memory.Content))
+	return true
+}
+
+// handleForgetCommand handles /forget <id|all>
+func (c *Client) handleForgetCommand(ctx context.Context, args []string) bool {
+	if c.memories == nil {
+		c.ui.PrintError("Memories are only available when running with authentication (HTTP mode)")
+		return true
+	}
+
+	if len(args) != 1 {
+		c.ui.PrintError("Usage: /forget <id|all>")
+		return true
+	}
+
+	if args[0] == "all" {
+		count, err := c.memories.DeleteAll(ctx)
+		if err != nil {
+			c.ui.PrintError(fmt.Sprintf("Failed to delete memories: %v", err))
+			return true
+		}
+		c.memoryContext = ""
+		c.ui.PrintSystemMessage(fmt.Sprintf("Forgot %d memory(s)", count))
+		return true
+	}
+
+	var id int64
+	if _, err := fmt.Sscanf(args[0], "%d", &id); err != nil {
+		c.ui.PrintError(fmt.Sprintf("Invalid memory ID: %s", args[0]))
+		return true
+	}
+	if err := c.memories.Delete(ctx, id); err != nil {
+		c.ui.PrintError(fmt.Sprintf("Failed to delete memory: %v", err))
+		return true
+	}
+	if err := c.loadMemories(ctx); err != nil {
+		c.ui.PrintError(fmt.Sprintf("Warning: Failed to reload memories: %v", err))
+	}
+
+	c.ui.PrintSystemMessage(fmt.Sprintf("Forgot memory %d", id))
+	return true
+}
@@ -0,0 +1,83 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMemoriesClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("Unexpected Authorization header: %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/memories":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"memories": []Memory{{ID: 1, Content: "Prefer the sales schema"}},
+			})
+		case r.Method == "POST" && r.URL.Path == "/api/memories":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(Memory{ID: 2, Content: body["content"]})
+		case r.Method == "DELETE" && r.URL.Path == "/api/memories/2":
+			json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewMemoriesClient(server.URL+"/mcp/v1", "test-token")
+	ctx := context.Background()
+
+	memories, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(memories) != 1 || memories[0].Content != "Prefer the sales schema" {
+		t.Errorf("Unexpected memories: %+v", memories)
+	}
+
+	memory, err := client.Add(ctx, "Use UTC")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if memory.ID != 2 || memory.Content != "Use UTC" {
+		t.Errorf("Unexpected memory: %+v", memory)
+	}
+
+	if err := client.Delete(ctx, 2); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if err := client.Delete(ctx, 3); err == nil {
+		t.Error("Expected error deleting a missing memory")
+	}
+}
+
+func TestFormatMemories(t *testing.T) {
+	if got := formatMemories(nil); got != "" {
+		t.Errorf("Expected empty string, got %q", got)
+	}
+
+	got := formatMemories([]Memory{{Content: "a"}, {Content: "b"}})
+	want := "The user asked you to remember the following about their environment and preferences:\n- a\n- b\n"
+	if got != want {
+		t.Errorf("formatMemories = %q, want %q", got, want)
+	}
+}
@@ -24,6 +24,14 @@ type Preferences struct {
 	ProviderModels  map[string]string `yaml:"provider_models"`
 	LastProvider    string            `yaml:"last_provider"`
 	ServerDatabases map[string]string `yaml:"server_databases,omitempty"` // server key -> database name
+	Sync            SyncPreferences   `yaml:"sync,omitempty"`
+}
+
+// SyncPreferences controls which preferences are shared with the server,
+// and so with the web client, when connected over HTTP with a user session
+type SyncPreferences struct {
+	Disabled  bool     `yaml:"disabled,omitempty"`
+	LocalOnly []string `yaml:"local_only,omitempty"` // provider, models, database or ui
 }
 
 // UIPreferences holds UI-related preferences
@@ -104,6 +112,7 @@ func getDefaultPreferences() *Preferences {
 		ProviderModels: map[string]string{
 			"anthropic": "claude-sonnet-4-5-20250929",
 			"openai":    "gpt-4o",
+			"bedrock":   DefaultBedrockModel,
 			"ollama":    "qwen3-coder:latest",
 		},
 		LastProvider: "anthropic",
@@ -122,6 +131,8 @@ func sanitizePreferences(prefs *Preferences) *Preferences {
 	validProviders := map[string]bool{
 		"anthropic": true,
 		"openai":    true,
+		"azure":     true,
+		"bedrock":   true,
 		"ollama":    true,
 	}
 	if !validProviders[prefs.LastProvider] {
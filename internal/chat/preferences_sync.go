@@ -0,0 +1,278 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Preference groups that can be kept local with sync.local_only
+const (
+	SyncProvider = "provider"
+	SyncModels   = "models"
+	SyncDatabase = "database"
+	SyncUI       = "ui"
+)
+
+// UI settings shared with the web client. show_activity is the web
+// client's name for status messages.
+var syncedUISettings = []string{"show_activity", "render_markdown", "debug", "color"}
+
+// ServerPreferences are the preferences stored on the server for the
+// authenticated user, shared with the web client
+type ServerPreferences struct {
+	Provider       string                 `json:"provider,omitempty"`
+	ProviderModels map[string]string      `json:"provider_models,omitempty"`
+	Database       string                 `json:"database,omitempty"`
+	UI             map[string]interface{} `json:"ui,omitempty"`
+}
+
+// PreferencesClient reads and updates server-side preferences via the REST API
+type PreferencesClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewPreferencesClient creates a new preferences client
+func NewPreferencesClient(baseURL, token string) *PreferencesClient {
+	return &PreferencesClient{
+		baseURL: strings.TrimSuffix(baseURL, "/mcp/v1") + "/api/preferences",
+		token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// do sends a request and decodes the preferences in the response
+func (c *PreferencesClient) do(ctx context.Context, method string, body interface{}) (*ServerPreferences, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body) //nolint:errcheck // Best effort to read error body
+		return nil, fmt.Errorf("request failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var prefs ServerPreferences
+	if err := json.NewDecoder(resp.Body).Decode(&prefs); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &prefs, nil
+}
+
+// Get returns the current user's preferences
+func (c *PreferencesClient) Get(ctx context.Context) (*ServerPreferences, error) {
+	return c.do(ctx, "GET", nil)
+}
+
+// Update merges a partial update into the current user's preferences
+func (c *PreferencesClient) Update(ctx context.Context, patch map[string]interface{}) (*ServerPreferences, error) {
+	return c.do(ctx, "PATCH", patch)
+}
+
+// syncs reports whether a preference group is shared with the server
+func (p *Preferences) syncs(group string) bool {
+	if p.Sync.Disabled {
+		return false
+	}
+	for _, local := range p.Sync.LocalOnly {
+		if strings.EqualFold(strings.TrimSpace(local), group) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeServerPreferences copies the server's preferences into prefs, except
+// for groups kept local. Values the server doesn't have are left alone.
+// Returns true if anything changed.
+func mergeServerPreferences(prefs *Preferences, server *ServerPreferences, serverKey string) bool {
+	changed := false
+
+	if prefs.syncs(SyncProvider) && server.Provider != "" && server.Provider != prefs.LastProvider {
+		prefs.LastProvider = server.Provider
+		changed = true
+	}
+
+	if prefs.syncs(SyncModels) {
+		for provider, model := range server.ProviderModels {
+			if model != "" && prefs.ProviderModels[provider] != model {
+				prefs.SetModelForProvider(provider, model)
+				changed = true
+			}
+		}
+	}
+
+	if prefs.syncs(SyncDatabase) && server.Database != "" && server.Database != prefs.GetDatabaseForServer(serverKey) {
+		prefs.SetDatabaseForServer(serverKey, server.Database)
+		changed = true
+	}
+
+	if prefs.syncs(SyncUI) {
+		for _, name := range syncedUISettings {
+			value, ok := server.UI[name].(bool)
+			if !ok {
+				continue
+			}
+			setting := prefs.uiSetting(name)
+			if *setting != value {
+				*setting = value
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+// preferencesPatch returns the partial update that publishes the synced
+// groups of prefs to the server
+func preferencesPatch(prefs *Preferences, serverKey string) map[string]interface{} {
+	patch := make(map[string]interface{})
+	if prefs.syncs(SyncProvider) && prefs.LastProvider != "" {
+		patch["provider"] = prefs.LastProvider
+	}
+	if prefs.syncs(SyncModels) && len(prefs.ProviderModels) > 0 {
+		patch["provider_models"] = prefs.ProviderModels
+	}
+	if database := prefs.GetDatabaseForServer(serverKey); prefs.syncs(SyncDatabase) && database != "" {
+		patch["database"] = database
+	}
+	if prefs.syncs(SyncUI) {
+		ui := make(map[string]interface{}, len(syncedUISettings))
+		for _, name := range syncedUISettings {
+			ui[name] = *prefs.uiSetting(name)
+		}
+		patch["ui"] = ui
+	}
+	return patch
+}
+
+// uiSetting returns the field holding a synced UI setting
+func (p *Preferences) uiSetting(name string) *bool {
+	switch name {
+	case "show_activity":
+		return &p.UI.DisplayStatusMessages
+	case "render_markdown":
+		return &p.UI.RenderMarkdown
+	case "debug":
+		return &p.UI.Debug
+	default:
+		return &p.UI.Color
+	}
+}
+
+// pullPreferences merges the preferences the user saved from other clients
+// into the local preferences. Server-side preferences need a user session;
+// when they are unavailable, syncing is turned off for this session.
+func (c *Client) pullPreferences(ctx context.Context) {
+	if c.preferencesSync == nil || c.preferences.Sync.Disabled {
+		return
+	}
+
+	server, err := c.preferencesSync.Get(ctx)
+	if err != nil {
+		if c.config.UI.Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Preferences are not synced with the server: %v\n", err)
+		}
+		c.preferencesSync = nil
+		return
+	}
+
+	if !mergeServerPreferences(c.preferences, server, c.getServerKey()) {
+		return
+	}
+	c.applyPreferences()
+	if err := SavePreferences(c.preferences); err != nil && c.config.UI.Debug {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to save preferences: %v\n", err)
+	}
+}
+
+// applyPreferences updates the session from merged preferences. The
+// provider is only changed when neither it nor the model was set by a flag.
+func (c *Client) applyPreferences() {
+	c.config.UI.DisplayStatusMessages = c.preferences.UI.DisplayStatusMessages
+	c.config.UI.RenderMarkdown = c.preferences.UI.RenderMarkdown
+	c.config.UI.Debug = c.preferences.UI.Debug
+	if os.Getenv("NO_COLOR") == "" {
+		c.config.UI.NoColor = !c.preferences.UI.Color
+	}
+	c.ui.DisplayStatusMessages = c.config.UI.DisplayStatusMessages
+	c.ui.RenderMarkdown = c.config.UI.RenderMarkdown
+	c.ui.SetNoColor(c.config.UI.NoColor)
+
+	provider := c.preferences.LastProvider
+	if !c.overrides.ProviderSet && !c.overrides.ModelSet &&
+		provider != c.config.LLM.Provider && c.config.IsProviderConfigured(provider) {
+		c.config.LLM.Provider = provider
+		c.config.LLM.Model = ""
+	}
+}
+
+// savePreferences saves preferences locally and publishes the synced ones
+// to the server
+func (c *Client) savePreferences() error {
+	if err := SavePreferences(c.preferences); err != nil {
+		return err
+	}
+	c.pushPreferences()
+	return nil
+}
+
+// pushPreferences publishes the synced preferences to the server
+func (c *Client) pushPreferences() {
+	if c.preferencesSync == nil {
+		return
+	}
+	patch := preferencesPatch(c.preferences, c.getServerKey())
+	if c.overrides.ProviderSet {
+		// A provider chosen with a flag is only used for this session
+		delete(patch, "provider")
+	}
+	if len(patch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.preferencesSync.Update(ctx, patch); err != nil && c.config.UI.Debug {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to sync preferences: %v\n", err)
+	}
+}
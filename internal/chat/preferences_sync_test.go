@@ -0,0 +1,149 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPreferencesClient(t *testing.T) {
+	var patched map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/preferences" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case "GET":
+			json.NewEncoder(w).Encode(ServerPreferences{Provider: "openai", Database: "sales"})
+		case "PATCH":
+			json.NewDecoder(r.Body).Decode(&patched)
+			json.NewEncoder(w).Encode(ServerPreferences{Provider: "ollama"})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewPreferencesClient(server.URL+"/mcp/v1", "test-token")
+
+	prefs, err := client.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if prefs.Provider != "openai" || prefs.Database != "sales" {
+		t.Errorf("Unexpected preferences: %+v", prefs)
+	}
+
+	if _, err := client.Update(ctx, map[string]interface{}{"provider": "ollama"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if patched["provider"] != "ollama" {
+		t.Errorf("Unexpected patch: %v", patched)
+	}
+
+	// Token authentication has no user, so the server rejects the request
+	if _, err := NewPreferencesClient(server.URL, "other-token").Get(ctx); err == nil {
+		t.Error("Expected an error for a rejected request")
+	}
+}
+
+func TestMergeServerPreferences(t *testing.T) {
+	server := &ServerPreferences{
+		Provider:       "openai",
+		ProviderModels: map[string]string{"openai": "example-model", "ollama": ""},
+		Database:       "sales",
+		UI:             map[string]interface{}{"render_markdown": false, "theme": "dark", "debug": "yes"},
+	}
+
+	prefs := getDefaultPreferences()
+	if !mergeServerPreferences(prefs, server, "server-1") {
+		t.Fatal("Expected the merge to report changes")
+	}
+	if prefs.LastProvider != "openai" || prefs.ProviderModels["openai"] != "example-model" {
+		t.Errorf("Provider and model not merged: %+v", prefs)
+	}
+	if prefs.ProviderModels["ollama"] == "" {
+		t.Error("Empty server values must not clear local ones")
+	}
+	if prefs.GetDatabaseForServer("server-1") != "sales" {
+		t.Errorf("Database not merged: %v", prefs.ServerDatabases)
+	}
+	if prefs.UI.RenderMarkdown || prefs.UI.Debug {
+		t.Errorf("Unexpected UI preferences: %+v", prefs.UI)
+	}
+	if mergeServerPreferences(prefs, server, "server-1") {
+		t.Error("Merging the same preferences again should not report changes")
+	}
+
+	// Local-only groups keep their local values
+	prefs = getDefaultPreferences()
+	prefs.Sync.LocalOnly = []string{"provider", "UI"}
+	mergeServerPreferences(prefs, server, "server-1")
+	if prefs.LastProvider != "anthropic" || !prefs.UI.RenderMarkdown {
+		t.Errorf("Local-only preferences were overwritten: %+v", prefs)
+	}
+	if prefs.ProviderModels["openai"] != "example-model" {
+		t.Error("Synced groups should still be merged")
+	}
+
+	prefs = getDefaultPreferences()
+	prefs.Sync.Disabled = true
+	if mergeServerPreferences(prefs, server, "server-1") {
+		t.Error("Nothing should be merged when sync is disabled")
+	}
+}
+
+func TestPreferencesPatch(t *testing.T) {
+	prefs := getDefaultPreferences()
+	prefs.UI.Debug = true
+
+	patch := preferencesPatch(prefs, "server-1")
+	if patch["provider"] != "anthropic" {
+		t.Errorf("Unexpected provider: %v", patch["provider"])
+	}
+	if _, ok := patch["database"]; ok {
+		t.Error("An unset database should not be published")
+	}
+	ui, ok := patch["ui"].(map[string]interface{})
+	if !ok || ui["show_activity"] != true || ui["debug"] != true {
+		t.Errorf("Unexpected UI settings: %v", patch["ui"])
+	}
+
+	prefs.SetDatabaseForServer("server-1", "sales")
+	prefs.Sync.LocalOnly = []string{"models", "ui"}
+	patch = preferencesPatch(prefs, "server-1")
+	if patch["database"] != "sales" {
+		t.Errorf("Unexpected database: %v", patch["database"])
+	}
+	if _, ok := patch["provider_models"]; ok {
+		t.Error("Local-only models should not be published")
+	}
+	if _, ok := patch["ui"]; ok {
+		t.Error("Local-only UI settings should not be published")
+	}
+
+	prefs.Sync.Disabled = true
+	if patch := preferencesPatch(prefs, "server-1"); len(patch) != 0 {
+		t.Errorf("Expected an empty patch when sync is disabled, got %v", patch)
+	}
+}
@@ -0,0 +1,233 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package chat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// Exit codes used when running prompts non-interactively
+const (
+	ExitUsage        = 1   // Invalid configuration, flags or script
+	ExitStartup      = 2   // Could not connect to the MCP server or LLM
+	ExitPromptFailed = 3   // A prompt failed (LLM error, too many tool calls)
+	ExitInterrupted  = 130 // Interrupted by a signal
+)
+
+// ExitError is returned by RunScript and carries the process exit code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the process exit code for an error returned by RunScript
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitUsage
+}
+
+// ScriptOptions controls a non-interactive run
+type ScriptOptions struct {
+	JSON     bool      // Print one JSON object per prompt, including the tool trace
+	Database string    // Database to select before running the prompts
+	Output   io.Writer // Where answers are written (default: stdout)
+}
+
+// ScriptResult is the JSON output for a single prompt
+type ScriptResult struct {
+	Prompt    string           `json:"prompt"`
+	Answer    string           `json:"answer"`
+	ToolCalls []ScriptToolCall `json:"tool_calls"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// ScriptToolCall records a tool executed while answering a prompt
+type ScriptToolCall struct {
+	Name    string                 `json:"name"`
+	Input   map[string]interface{} `json:"input"`
+	Result  string                 `json:"result"`
+	IsError bool                   `json:"is_error"`
+}
+
+// ReadScript reads prompts from r, one per line. Blank lines and lines
+// starting with # are skipped, and a line ending in a backslash is
+// continued on the next line.
+func ReadScript(r io.Reader) ([]string, error) {
+	var prompts []string
+	var pending []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if len(pending) == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			line = trimmed
+		}
+
+		if strings.HasSuffix(line, "\\") {
+			pending = append(pending, strings.TrimRight(strings.TrimSuffix(line, "\\"), " \t"))
+			continue
+		}
+
+		pending = append(pending, line)
+		prompts = append(prompts, strings.TrimSpace(strings.Join(pending, "\n")))
+		pending = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// A continuation on the last line still ends the prompt
+	if len(pending) > 0 {
+		prompts = append(prompts, strings.TrimSpace(strings.Join(pending, "\n")))
+	}
+
+	return prompts, nil
+}
+
+// RunScript runs each prompt in turn, in a single conversation, and prints
+// the answers. It never prompts on the terminal, so credentials must come
+// from the configuration. Running stops at the first prompt that fails.
+func (c *Client) RunScript(ctx context.Context, prompts []string, opts ScriptOptions) error {
+	if len(prompts) == 0 {
+		return &ExitError{Code: ExitUsage, Err: fmt.Errorf("no prompts to run")}
+	}
+	for _, prompt := range prompts {
+		if strings.HasPrefix(prompt, "/") {
+			return &ExitError{Code: ExitUsage, Err: fmt.Errorf("slash commands are not supported in non-interactive mode: %s", prompt)}
+		}
+	}
+
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	c.nonInteractive = true
+	err := c.start(ctx)
+	if c.mcp != nil {
+		defer c.mcp.Close()
+	}
+	if err != nil {
+		return c.scriptError(ctx, ExitStartup, err)
+	}
+
+	if opts.Database != "" {
+		if err := c.mcp.SelectDatabase(ctx, opts.Database); err != nil {
+			return c.scriptError(ctx, ExitStartup, fmt.Errorf("failed to select database %q: %w", opts.Database, err))
+		}
+	}
+
+	for i, prompt := range prompts {
+		result, err := c.runScriptPrompt(ctx, prompt)
+
+		if opts.JSON {
+			if err != nil {
+				result.Error = err.Error()
+			}
+			if encErr := json.NewEncoder(out).Encode(result); encErr != nil {
+				return &ExitError{Code: ExitPromptFailed, Err: fmt.Errorf("failed to write result: %w", encErr)}
+			}
+		} else if err == nil {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			fmt.Fprintln(out, result.Answer)
+		}
+
+		if err != nil {
+			return c.scriptError(ctx, ExitPromptFailed, fmt.Errorf("prompt %d failed: %w", i+1, err))
+		}
+	}
+
+	return nil
+}
+
+// runScriptPrompt answers a single prompt, recording the tools used
+func (c *Client) runScriptPrompt(ctx context.Context, prompt string) (ScriptResult, error) {
+	result := ScriptResult{
+		Prompt:    prompt,
+		ToolCalls: []ScriptToolCall{},
+	}
+
+	c.messages = append(c.messages, Message{
+		Role:    "user",
+		Content: prompt,
+	})
+
+	reqCtx := ctx
+	if c.memoryContext != "" {
+		reqCtx = WithSystemContext(reqCtx, c.memoryContext)
+	}
+
+	answer, err := c.runAgenticLoop(reqCtx, nil, func(toolUse ToolUse, toolResult ToolResult) {
+		result.ToolCalls = append(result.ToolCalls, ScriptToolCall{
+			Name:    toolUse.Name,
+			Input:   toolUse.Input,
+			Result:  toolResultText(toolResult.Content),
+			IsError: toolResult.IsError,
+		})
+	}, nil)
+	result.Answer = answer
+
+	return result, err
+}
+
+// scriptError wraps err with code, or with ExitInterrupted if ctx was canceled
+func (c *Client) scriptError(ctx context.Context, code int, err error) error {
+	if ctx.Err() != nil {
+		code = ExitInterrupted
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// toolResultText flattens the content of a tool result to text
+func toolResultText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []mcp.ContentItem:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, item.Text)
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
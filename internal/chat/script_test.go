@@ -0,0 +1,188 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadScript(t *testing.T) {
+	script := `# Nightly checks
+How many orders were placed yesterday?
+
+  List tables without a primary key
+Summarize the slowest queries \
+and suggest indexes
+# trailing comment
+Final prompt \`
+
+	prompts, err := ReadScript(strings.NewReader(script))
+	if err != nil {
+		t.Fatalf("ReadScript() error = %v", err)
+	}
+
+	want := []string{
+		"How many orders were placed yesterday?",
+		"List tables without a primary key",
+		"Summarize the slowest queries\nand suggest indexes",
+		"Final prompt",
+	}
+	if len(prompts) != len(want) {
+		t.Fatalf("expected %d prompts, got %d: %q", len(want), len(prompts), prompts)
+	}
+	for i := range want {
+		if prompts[i] != want[i] {
+			t.Errorf("prompt %d = %q, want %q", i, prompts[i], want[i])
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("plain error"), ExitUsage},
+		{&ExitError{Code: ExitPromptFailed, Err: errors.New("failed")}, ExitPromptFailed},
+		{fmt.Errorf("wrapped: %w", &ExitError{Code: ExitInterrupted, Err: context.Canceled}), ExitInterrupted},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// newScriptTestClient returns a client connected to the mock MCP server
+// with llm as its LLM
+func newScriptTestClient(t *testing.T, serverURL string, llm LLMClient) *Client {
+	t.Helper()
+	cfg := &Config{
+		MCP: MCPConfig{
+			Mode:  "http",
+			URL:   serverURL,
+			Token: "test-token",
+		},
+		LLM: LLMConfig{
+			Provider:        "anthropic",
+			AnthropicAPIKey: "test-key",
+			Model:           "claude-test",
+		},
+		UI: UIConfig{
+			NoColor: true,
+		},
+	}
+
+	client, err := NewClient(cfg, &ConfigOverrides{})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := client.connectToMCP(ctx); err != nil {
+		t.Fatalf("connectToMCP failed: %v", err)
+	}
+	if err := client.mcp.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	client.tools, err = client.mcp.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	client.llm = llm
+	return client
+}
+
+func TestClient_RunScriptPrompt_RecordsToolTrace(t *testing.T) {
+	server := mockMCPServer(t)
+	defer server.Close()
+
+	client := newScriptTestClient(t, server.URL, &mockLLMClient{
+		responses: []LLMResponse{
+			{
+				Content: []interface{}{
+					ToolUse{
+						Type:  "tool_use",
+						ID:    "tool_1",
+						Name:  "test_tool",
+						Input: map[string]interface{}{"query": "orders"},
+					},
+				},
+				StopReason: "tool_use",
+			},
+		},
+	})
+	defer client.mcp.Close()
+
+	result, err := client.runScriptPrompt(context.Background(), "How many orders?")
+	if err != nil {
+		t.Fatalf("runScriptPrompt() error = %v", err)
+	}
+	if result.Answer != "Final response" {
+		t.Errorf("Answer = %q", result.Answer)
+	}
+	if len(result.ToolCalls) != 1 {
+		t.Fatalf("expected 1 tool call, got %d", len(result.ToolCalls))
+	}
+	call := result.ToolCalls[0]
+	if call.Name != "test_tool" || call.Input["query"] != "orders" || call.IsError {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+	if call.Result != "Tool test_tool executed successfully" {
+		t.Errorf("Result = %q", call.Result)
+	}
+
+	// The prompt, tool exchange and answer are all kept in the conversation
+	if len(client.messages) != 4 {
+		t.Errorf("expected 4 messages in history, got %d", len(client.messages))
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(result); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	for _, want := range []string{`"prompt":"How many orders?"`, `"answer":"Final response"`, `"name":"test_tool"`, `"is_error":false`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected JSON to contain %s, got %s", want, buf.String())
+		}
+	}
+}
+
+func TestClient_RunScript_RejectsBadScripts(t *testing.T) {
+	client := &Client{config: &Config{}}
+
+	for _, prompts := range [][]string{nil, {"Show tables", "/set database prod"}} {
+		err := client.RunScript(context.Background(), prompts, ScriptOptions{})
+		if ExitCode(err) != ExitUsage {
+			t.Errorf("RunScript(%q) exit code = %d, want %d (%v)", prompts, ExitCode(err), ExitUsage, err)
+		}
+	}
+}
+
+func TestClient_ConnectToMCP_NonInteractiveRequiresToken(t *testing.T) {
+	client := &Client{
+		config: &Config{
+			MCP: MCPConfig{Mode: "http", URL: "http://localhost:1"},
+		},
+		nonInteractive: true,
+	}
+	err := client.connectToMCP(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "token is required") {
+		t.Errorf("expected a missing token error, got %v", err)
+	}
+}
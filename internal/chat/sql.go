@@ -0,0 +1,129 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// sqlPageSize is the number of rows /sql fetches and shows at a time
+const sqlPageSize = 50
+
+// queryPage is a page of rows parsed from a query_database result
+type queryPage struct {
+	columns []string
+	rows    [][]string
+	more    bool // More rows are available after this page
+}
+
+// handleSQLCommand runs a query with query_database without involving the
+// LLM and shows the rows as a table, a page at a time. The query and its
+// results are not added to the conversation.
+func (c *Client) handleSQLCommand(ctx context.Context, query string) bool {
+	query = strings.TrimSpace(stripCodeFence(query))
+	if query == "" {
+		c.ui.PrintError("Usage: /sql <query>")
+		return true
+	}
+	if c.mcp == nil {
+		c.ui.PrintError("Not connected to an MCP server")
+		return true
+	}
+
+	for offset := 0; ; offset += sqlPageSize {
+		result, err := c.mcp.CallTool(ctx, "query_database", map[string]interface{}{
+			"query":  query,
+			"limit":  sqlPageSize,
+			"offset": offset,
+		})
+		if err != nil {
+			c.ui.PrintError(fmt.Sprintf("Failed to run query: %v", err))
+			return true
+		}
+		text := toolResultText(result.Content)
+		if result.IsError {
+			c.ui.PrintError(text)
+			return true
+		}
+
+		page, ok := parseQueryResult(text)
+		if !ok {
+			// Show output that is not a result set as it is
+			fmt.Println(text)
+			return true
+		}
+		if len(page.rows) > 0 {
+			fmt.Println(renderResultTable(page, c.ui.getTerminalWidth(), !c.config.UI.NoColor))
+		}
+
+		switch {
+		case !page.more && offset == 0:
+			c.ui.PrintSystemMessage(fmt.Sprintf("(%d rows)", len(page.rows)))
+			return true
+		case !page.more:
+			c.ui.PrintSystemMessage(fmt.Sprintf("(rows %d-%d)", offset+1, offset+len(page.rows)))
+			return true
+		}
+		c.ui.PrintSystemMessage(fmt.Sprintf("(rows %d-%d, more available)", offset+1, offset+len(page.rows)))
+		if c.nonInteractive || !c.ui.PromptForMore(ctx) {
+			return true
+		}
+	}
+}
+
+// parseQueryResult extracts the rows from query_database's text result:
+// a "Results (...)" line followed by tab-separated values, with the column
+// names on the first line. It returns false if the text has no results.
+func parseQueryResult(text string) (queryPage, bool) {
+	start := strings.Index(text, "Results (")
+	if start < 0 || (start > 0 && text[start-1] != '\n') {
+		return queryPage{}, false
+	}
+	header, body, _ := strings.Cut(text[start:], "\n")
+
+	// Timing, when requested, follows the values after a blank line
+	body, _, _ = strings.Cut(body, "\n\n")
+
+	var page queryPage
+	page.more = strings.Contains(header, "more available")
+	for i, line := range strings.Split(body, "\n") {
+		if i == 0 {
+			page.columns = strings.Split(line, "\t")
+			continue
+		}
+		page.rows = append(page.rows, strings.Split(line, "\t"))
+	}
+	return page, true
+}
+
+// renderResultTable draws a page of rows as a table no wider than width
+func renderResultTable(page queryPage, width int, color bool) string {
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers(page.columns...).
+		Rows(page.rows...).
+		StyleFunc(func(row, _ int) lipgloss.Style {
+			style := lipgloss.NewStyle().Padding(0, 1)
+			if row == table.HeaderRow && color {
+				style = style.Bold(true)
+			}
+			return style
+		})
+	if rendered := t.String(); lipgloss.Width(rendered) <= width {
+		return rendered
+	}
+	return t.Width(width).String()
+}
@@ -0,0 +1,106 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestParseQueryResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		ok      bool
+		columns []string
+		rows    int
+		more    bool
+	}{
+		{
+			name:    "all rows",
+			text:    "Database: postgres://localhost/app\n\nSQL Query:\nSELECT id, name FROM users\n\nResults (2 rows):\nid\tname\n1\talice\n2\tbob",
+			ok:      true,
+			columns: []string{"id", "name"},
+			rows:    2,
+		},
+		{
+			name:    "more available",
+			text:    "SQL Query:\nSELECT id FROM users\n\nResults (1 rows shown, more available - use offset=1 for next page or count_rows for total):\nid\n1",
+			ok:      true,
+			columns: []string{"id"},
+			rows:    1,
+			more:    true,
+		},
+		{
+			name:    "later page with timing",
+			text:    "SQL Query:\nSELECT id FROM users\n\nResults (rows 51-52):\nid\n51\n52\n\nTiming:\n  Round trip: 1.200 ms",
+			ok:      true,
+			columns: []string{"id"},
+			rows:    2,
+		},
+		{
+			name:    "no rows",
+			text:    "SQL Query:\nSELECT id FROM users WHERE false\n\nResults (0 rows):\nid",
+			ok:      true,
+			columns: []string{"id"},
+		},
+		{
+			name: "not a result set",
+			text: "Query returned no results",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, ok := parseQueryResult(tt.text)
+			if ok != tt.ok {
+				t.Fatalf("parseQueryResult() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if strings.Join(page.columns, ",") != strings.Join(tt.columns, ",") {
+				t.Errorf("columns = %v, want %v", page.columns, tt.columns)
+			}
+			if len(page.rows) != tt.rows {
+				t.Errorf("got %d rows, want %d", len(page.rows), tt.rows)
+			}
+			if page.more != tt.more {
+				t.Errorf("more = %v, want %v", page.more, tt.more)
+			}
+		})
+	}
+}
+
+func TestRenderResultTable(t *testing.T) {
+	page := queryPage{
+		columns: []string{"id", "description"},
+		rows: [][]string{
+			{"1", "short"},
+			{"2", strings.Repeat("long text ", 20)},
+		},
+	}
+
+	rendered := renderResultTable(page, 200, false)
+	for _, want := range []string{"id", "description", "short", "long text"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("expected %q in the table:\n%s", want, rendered)
+		}
+	}
+
+	// The table is narrowed to fit the terminal
+	for _, line := range strings.Split(renderResultTable(page, 60, false), "\n") {
+		if width := lipgloss.Width(line); width > 60 {
+			t.Errorf("line is %d wide, want at most 60: %q", width, line)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// TextStreamFunc receives the text of a response as it is generated
+type TextStreamFunc func(text string)
+
+// textStreamKey is the context key for the text stream callback
+type textStreamKey struct{}
+
+// WithTextStream returns a context that makes LLM clients use their
+// provider's streaming API, passing each piece of generated text to fn as
+// it arrives. Chat still returns the complete response, which is
+// authoritative: streamed text is provisional, for example Ollama tool
+// calls are only recognized once the response is complete.
+func WithTextStream(ctx context.Context, fn TextStreamFunc) context.Context {
+	return context.WithValue(ctx, textStreamKey{}, fn)
+}
+
+// textStreamFrom returns the text stream callback, or nil if the response
+// should not be streamed
+func textStreamFrom(ctx context.Context) TextStreamFunc {
+	fn, _ := ctx.Value(textStreamKey{}).(TextStreamFunc) //nolint:errcheck // Unset means no streaming
+	return fn
+}
+
+// readSSE reads a server-sent event stream, calling fn with the event name
+// and data of each event
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	reader := bufio.NewReader(r)
+	var event string
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			// A blank line dispatches the event
+			if len(data) > 0 {
+				if fnErr := fn(event, strings.Join(data, "\n")); fnErr != nil {
+					return fnErr
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment, used for keepalives
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		if errors.Is(err, io.EOF) {
+			if len(data) > 0 {
+				return fn(event, strings.Join(data, "\n"))
+			}
+			return nil
+		}
+	}
+}
+
+// anthropicStreamEvent is an event of Anthropic's streaming Messages API
+type anthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	Message      *anthropicResponse     `json:"message,omitempty"`
+	ContentBlock map[string]interface{} `json:"content_block,omitempty"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// readAnthropicStream assembles a response from Anthropic's event stream,
+// passing text deltas to onText
+func readAnthropicStream(r io.Reader, onText TextStreamFunc) (anthropicResponse, error) {
+	var resp anthropicResponse
+	toolInput := make(map[int]*strings.Builder)
+	blocks := make(map[int]map[string]interface{})
+	var order []int
+
+	err := readSSE(r, func(_, data string) error {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				resp.ID = ev.Message.ID
+				resp.Role = ev.Message.Role
+				resp.Usage = ev.Message.Usage
+			}
+		case "content_block_start":
+			if ev.ContentBlock == nil {
+				return nil
+			}
+			blocks[ev.Index] = ev.ContentBlock
+			order = append(order, ev.Index)
+			if ev.ContentBlock["type"] == "tool_use" {
+				toolInput[ev.Index] = &strings.Builder{}
+			}
+		case "content_block_delta":
+			block, ok := blocks[ev.Index]
+			if !ok {
+				return nil
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				text, _ := block["text"].(string) //nolint:errcheck // Starts empty
+				block["text"] = text + ev.Delta.Text
+				if onText != nil && ev.Delta.Text != "" {
+					onText(ev.Delta.Text)
+				}
+			case "input_json_delta":
+				if sb, ok := toolInput[ev.Index]; ok {
+					sb.WriteString(ev.Delta.PartialJSON)
+				}
+			}
+		case "content_block_stop":
+			if sb, ok := toolInput[ev.Index]; ok && sb.Len() > 0 {
+				var input map[string]interface{}
+				if err := json.Unmarshal([]byte(sb.String()), &input); err != nil {
+					return fmt.Errorf("failed to decode tool input: %w", err)
+				}
+				blocks[ev.Index]["input"] = input
+			}
+		case "message_delta":
+			if ev.Delta.StopReason != "" {
+				resp.StopReason = ev.Delta.StopReason
+			}
+			if ev.Usage != nil {
+				resp.Usage.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "error":
+			if ev.Error != nil {
+				return &APIError{
+					StatusCode: anthropicErrorStatus(ev.Error.Type),
+					Message:    fmt.Sprintf("API error: %s", ev.Error.Message),
+				}
+			}
+			return fmt.Errorf("API error: %s", data)
+		}
+		return nil
+	})
+	if err != nil {
+		return anthropicResponse{}, err
+	}
+
+	for _, index := range order {
+		resp.Content = append(resp.Content, blocks[index])
+	}
+	return resp, nil
+}
+
+// anthropicErrorStatus returns the HTTP status Anthropic uses for an error
+// type, for errors reported in a stream after the response has started
+func anthropicErrorStatus(errorType string) int {
+	switch errorType {
+	case "invalid_request_error":
+		return http.StatusBadRequest
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "overloaded_error":
+		return 529
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// openaiStreamOptions requests the token usage at the end of a stream
+type openaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openaiStreamChunk is a chunk of OpenAI's streaming Chat Completions API
+type openaiStreamChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openaiUsage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// openaiStreamToolCall accumulates a streamed tool call
+type openaiStreamToolCall struct {
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+// readOpenAIStream assembles a response from OpenAI's chunk stream,
+// passing content deltas to onText. Tool calls are returned in the same
+// form as in a complete response.
+func readOpenAIStream(r io.Reader, onText TextStreamFunc) (openaiResponse, error) {
+	var resp openaiResponse
+	var content strings.Builder
+	var finishReason string
+	calls := make(map[int]*openaiStreamToolCall)
+	var order []int
+
+	err := readSSE(r, func(_, data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk openaiStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+		if chunk.ID != "" {
+			resp.ID = chunk.ID
+			resp.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			resp.Usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 {
+				continue
+			}
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				if onText != nil {
+					onText(choice.Delta.Content)
+				}
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				call, ok := calls[tc.Index]
+				if !ok {
+					call = &openaiStreamToolCall{}
+					calls[tc.Index] = call
+					order = append(order, tc.Index)
+				}
+				if tc.ID != "" {
+					call.id = tc.ID
+				}
+				if tc.Function.Name != "" {
+					call.name = tc.Function.Name
+				}
+				call.arguments.WriteString(tc.Function.Arguments)
+			}
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return openaiResponse{}, err
+	}
+
+	message := openaiMessage{Role: "assistant"}
+	if content.Len() > 0 {
+		message.Content = content.String()
+	}
+	if len(order) > 0 {
+		toolCalls := make([]interface{}, 0, len(order))
+		for _, index := range order {
+			call := calls[index]
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"id":   call.id,
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":      call.name,
+					"arguments": call.arguments.String(),
+				},
+			})
+		}
+		message.ToolCalls = toolCalls
+	}
+	resp.Choices = []openaiChoice{{Message: message, FinishReason: finishReason}}
+	return resp, nil
+}
+
+// ollamaStreamChunk is a line of Ollama's streaming chat API
+type ollamaStreamChunk struct {
+	ollamaResponse
+	Error string `json:"error"`
+}
+
+// readOllamaStream assembles a response from Ollama's newline-delimited
+// JSON stream, passing content to onText and collecting tool calls. With
+// holdJSON, tool calls are requested as JSON text, so a response that
+// starts with "{" is not streamed.
+func readOllamaStream(r io.Reader, onText TextStreamFunc, holdJSON bool) (ollamaResponse, error) {
+	var resp ollamaResponse
+	var content strings.Builder
+	streaming := false
+
+	decoder := json.NewDecoder(r)
+	for {
+		var chunk ollamaStreamChunk
+		if err := decoder.Decode(&chunk); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return ollamaResponse{}, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return ollamaResponse{}, fmt.Errorf("Ollama error: %s", chunk.Error)
+		}
+		resp.Model = chunk.Model
+		resp.Message.Role = chunk.Message.Role
+		content.WriteString(chunk.Message.Content)
+		resp.Message.ToolCalls = append(resp.Message.ToolCalls, chunk.Message.ToolCalls...)
+
+		if onText != nil {
+			if streaming {
+				if chunk.Message.Content != "" {
+					onText(chunk.Message.Content)
+				}
+			} else if text := strings.TrimSpace(content.String()); text != "" && (!holdJSON || !strings.HasPrefix(text, "{")) {
+				// Send the text held back while the kind of response was unknown
+				streaming = true
+				onText(content.String())
+			}
+		}
+		if chunk.Done {
+			resp.Done = true
+			break
+		}
+	}
+
+	resp.Message.Content = content.String()
+	return resp, nil
+}
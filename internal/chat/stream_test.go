@@ -0,0 +1,263 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+func TestReadSSE(t *testing.T) {
+	stream := ": keepalive\n\nevent: first\ndata: one\ndata: two\n\r\ndata: last"
+
+	var got []string
+	err := readSSE(strings.NewReader(stream), func(event, data string) error {
+		got = append(got, event+"="+data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readSSE() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "first=one\ntwo" || got[1] != "=last" {
+		t.Errorf("readSSE() events = %q", got)
+	}
+}
+
+func TestReadAnthropicStream(t *testing.T) {
+	stream := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","role":"assistant","content":[],"usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"query_database","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"query\": \"SELECT"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" 1\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":40}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+	var streamed strings.Builder
+	resp, err := readAnthropicStream(strings.NewReader(stream), func(text string) {
+		streamed.WriteString(text)
+	})
+	if err != nil {
+		t.Fatalf("readAnthropicStream() error = %v", err)
+	}
+
+	if streamed.String() != "Let me check." {
+		t.Errorf("streamed %q, want %q", streamed.String(), "Let me check.")
+	}
+	if resp.StopReason != "tool_use" || resp.Usage.InputTokens != 25 || resp.Usage.OutputTokens != 40 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(resp.Content) != 2 {
+		t.Fatalf("expected 2 content blocks, got %d", len(resp.Content))
+	}
+	if resp.Content[0]["text"] != "Let me check." {
+		t.Errorf("text block = %v", resp.Content[0])
+	}
+	input, ok := resp.Content[1]["input"].(map[string]interface{})
+	if !ok || input["query"] != "SELECT 1" {
+		t.Errorf("tool_use block = %v", resp.Content[1])
+	}
+}
+
+func TestReadAnthropicStream_Error(t *testing.T) {
+	stream := "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+	_, err := readAnthropicStream(strings.NewReader(stream), nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 529 || !strings.Contains(err.Error(), "Overloaded") {
+		t.Errorf("expected an overloaded API error, got %v", err)
+	}
+}
+
+func TestReadOpenAIStream(t *testing.T) {
+	stream := `data: {"id":"chatcmpl-1","model":"example-model","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}
+
+data: {"id":"chatcmpl-1","model":"example-model","choices":[{"index":0,"delta":{"content":" there"}}]}
+
+data: {"id":"chatcmpl-1","model":"example-model","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"query_database","arguments":""}}]}}]}
+
+data: {"id":"chatcmpl-1","model":"example-model","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"query\":"}}]}}]}
+
+data: {"id":"chatcmpl-1","model":"example-model","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"SELECT 1\"}"}}]},"finish_reason":"tool_calls"}]}
+
+data: {"id":"chatcmpl-1","model":"example-model","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":7,"total_tokens":19}}
+
+data: [DONE]
+
+`
+	var streamed strings.Builder
+	resp, err := readOpenAIStream(strings.NewReader(stream), func(text string) {
+		streamed.WriteString(text)
+	})
+	if err != nil {
+		t.Fatalf("readOpenAIStream() error = %v", err)
+	}
+
+	if streamed.String() != "Hello there" {
+		t.Errorf("streamed %q, want %q", streamed.String(), "Hello there")
+	}
+	if resp.Usage.TotalTokens != 19 || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || choice.Message.Content != "Hello there" {
+		t.Errorf("unexpected choice: %+v", choice)
+	}
+	toolCalls, ok := choice.Message.ToolCalls.([]interface{})
+	if !ok || len(toolCalls) != 1 {
+		t.Fatalf("expected 1 tool call, got %v", choice.Message.ToolCalls)
+	}
+	call := toolCalls[0].(map[string]interface{})
+	function := call["function"].(map[string]interface{})
+	if call["id"] != "call_1" || function["name"] != "query_database" || function["arguments"] != `{"query":"SELECT 1"}` {
+		t.Errorf("unexpected tool call: %v", call)
+	}
+}
+
+func TestReadOllamaStream(t *testing.T) {
+	tests := []struct {
+		name         string
+		chunks       []string
+		holdJSON     bool
+		wantContent  string
+		wantStreamed string
+	}{
+		{
+			name:         "text",
+			chunks:       []string{" ", "Hello", " there", ""},
+			holdJSON:     true,
+			wantContent:  " Hello there",
+			wantStreamed: " Hello there",
+		},
+		{
+			name:         "tool call",
+			chunks:       []string{`{"tool": `, `"query_database", "arguments": {}}`},
+			holdJSON:     true,
+			wantContent:  `{"tool": "query_database", "arguments": {}}`,
+			wantStreamed: "",
+		},
+		{
+			name:         "native json text",
+			chunks:       []string{`{"a": 1}`},
+			wantContent:  `{"a": 1}`,
+			wantStreamed: `{"a": 1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			for i, chunk := range tt.chunks {
+				line, err := json.Marshal(ollamaResponse{
+					Message: ollamaMessage{Role: "assistant", Content: chunk},
+					Done:    i == len(tt.chunks)-1,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				sb.Write(line)
+				sb.WriteByte('\n')
+			}
+
+			var streamed strings.Builder
+			resp, err := readOllamaStream(strings.NewReader(sb.String()), func(text string) {
+				streamed.WriteString(text)
+			}, tt.holdJSON)
+			if err != nil {
+				t.Fatalf("readOllamaStream() error = %v", err)
+			}
+			if resp.Message.Content != tt.wantContent || !resp.Done {
+				t.Errorf("content = %q, done = %v; want %q", resp.Message.Content, resp.Done, tt.wantContent)
+			}
+			if streamed.String() != tt.wantStreamed {
+				t.Errorf("streamed %q, want %q", streamed.String(), tt.wantStreamed)
+			}
+		})
+	}
+
+	// Native tool calls arrive in their own chunks
+	stream := `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"query_database","arguments":{"query":"SELECT 1"}}}]}}
+{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"list_tables","arguments":{}}}]},"done":true}
+`
+	resp, err := readOllamaStream(strings.NewReader(stream), func(string) {}, false)
+	if err != nil || len(resp.Message.ToolCalls) != 2 || resp.Message.ToolCalls[1].Function.Name != "list_tables" {
+		t.Errorf("expected both tool calls, got %+v (%v)", resp.Message.ToolCalls, err)
+	}
+
+	if _, err := readOllamaStream(strings.NewReader(`{"error":"model not found"}`), nil, false); err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("expected the stream's error, got %v", err)
+	}
+}
+
+func TestOllamaClient_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Stream {
+			t.Errorf("expected a streaming request, got %+v (%v)", req, err)
+		}
+		for i, chunk := range []string{"Hello", " there"} {
+			line, _ := json.Marshal(ollamaResponse{Message: ollamaMessage{Role: "assistant", Content: chunk}, Done: i == 1}) //nolint:errcheck // Test data
+			w.Write(append(line, '\n'))
+		}
+	}))
+	defer server.Close()
+
+	var deltas []string
+	ctx := WithTextStream(context.Background(), func(text string) {
+		deltas = append(deltas, text)
+	})
+	client := NewOllamaClient(server.URL, "test-model", false)
+	response, err := client.Chat(ctx, []Message{{Role: "user", Content: "Hello"}}, []mcp.Tool{})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if len(deltas) != 2 || deltas[0] != "Hello" || deltas[1] != " there" {
+		t.Errorf("deltas = %q", deltas)
+	}
+	text, ok := response.Content[0].(TextContent)
+	if !ok || text.Text != "Hello there" || response.StopReason != "end_turn" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
@@ -0,0 +1,304 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Transcript is a conversation exported with /export. Each turn is a
+// prompt with the tools used to answer it, in the same form as the JSON
+// output of non-interactive runs.
+type Transcript struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Provider   string         `json:"provider"`
+	Model      string         `json:"model"`
+	Database   string         `json:"database,omitempty"`
+	Turns      []ScriptResult `json:"turns"`
+}
+
+// transcriptItem is a content block of a message in any of the forms the
+// history holds: typed values from the LLM clients, or maps decoded from a
+// saved conversation
+type transcriptItem struct {
+	Type      string                 `json:"type"`
+	Text      string                 `json:"text"`
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Input     map[string]interface{} `json:"input"`
+	ToolUseID string                 `json:"tool_use_id"`
+	Content   json.RawMessage        `json:"content"`
+	IsError   bool                   `json:"is_error"`
+}
+
+// transcriptTurns groups a conversation into turns, each starting with a
+// user prompt and ending with the final answer
+func transcriptTurns(messages []Message) []ScriptResult {
+	var turns []ScriptResult
+	calls := make(map[string]int) // Index of each tool call in the current turn
+
+	current := func() *ScriptResult {
+		if len(turns) == 0 {
+			turns = append(turns, ScriptResult{ToolCalls: []ScriptToolCall{}})
+		}
+		return &turns[len(turns)-1]
+	}
+
+	for _, msg := range messages {
+		raw, err := json.Marshal(msg.Content)
+		if err != nil {
+			continue
+		}
+
+		var text string
+		if json.Unmarshal(raw, &text) == nil {
+			if msg.Role == "user" {
+				turns = append(turns, ScriptResult{Prompt: text, ToolCalls: []ScriptToolCall{}})
+				calls = make(map[string]int)
+			} else {
+				current().Answer = text
+			}
+			continue
+		}
+
+		var items []transcriptItem
+		if json.Unmarshal(raw, &items) != nil {
+			continue
+		}
+		turn := current()
+		for _, item := range items {
+			switch item.Type {
+			case "tool_use":
+				calls[item.ID] = len(turn.ToolCalls)
+				turn.ToolCalls = append(turn.ToolCalls, ScriptToolCall{Name: item.Name, Input: item.Input})
+			case "tool_result":
+				if i, ok := calls[item.ToolUseID]; ok {
+					turn.ToolCalls[i].Result = transcriptResultText(item.Content)
+					turn.ToolCalls[i].IsError = item.IsError
+				}
+			}
+		}
+	}
+	return turns
+}
+
+// transcriptResultText returns the text of a tool result's content, which
+// is either a string or a list of text blocks
+func transcriptResultText(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text
+	}
+	var blocks []struct {
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(content, &blocks) != nil {
+		return string(content)
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		parts = append(parts, block.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// renderTranscriptMarkdown formats a transcript as a markdown document
+func renderTranscriptMarkdown(t Transcript) string {
+	var sb strings.Builder
+	sb.WriteString("# Conversation Export\n\n")
+	fmt.Fprintf(&sb, "- Exported: %s\n", t.ExportedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- LLM: %s (%s)\n", t.Provider, t.Model)
+	if t.Database != "" {
+		fmt.Fprintf(&sb, "- Database: %s\n", t.Database)
+	}
+
+	for i, turn := range t.Turns {
+		fmt.Fprintf(&sb, "\n## Prompt %d\n\n%s\n", i+1, turn.Prompt)
+		for _, call := range turn.ToolCalls {
+			fmt.Fprintf(&sb, "\n### Tool: %s\n\n", call.Name)
+			input, err := json.MarshalIndent(call.Input, "", "  ")
+			if err == nil {
+				sb.WriteString(fenced("json", string(input)))
+			}
+			if call.IsError {
+				sb.WriteString("\nError:\n\n")
+			} else {
+				sb.WriteString("\nResult:\n\n")
+			}
+			sb.WriteString(fenced("", call.Result))
+		}
+		if turn.Answer != "" {
+			fmt.Fprintf(&sb, "\n### Answer\n\n%s\n", turn.Answer)
+		}
+	}
+	return sb.String()
+}
+
+// fenced wraps text in a code block whose fence is longer than any run of
+// backticks in the text
+func fenced(lang, text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + fence + "\n"
+}
+
+// handleExportCommand writes the conversation to a markdown or JSON file
+func (c *Client) handleExportCommand(ctx context.Context, args []string) bool {
+	format := ""
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "markdown", "md":
+			format, args = "markdown", args[1:]
+		case "json":
+			format, args = "json", args[1:]
+		}
+	}
+	if len(args) > 1 {
+		c.ui.PrintError("Usage: /export [markdown|json] [file]")
+		return true
+	}
+
+	path := ""
+	if len(args) == 1 {
+		path = args[0]
+		if format == "" && strings.EqualFold(filepath.Ext(path), ".json") {
+			format = "json"
+		}
+	}
+	if format == "" {
+		format = "markdown"
+	}
+	if path == "" {
+		ext := ".md"
+		if format == "json" {
+			ext = ".json"
+		}
+		path = "conversation-" + time.Now().Format("20060102-150405") + ext
+	}
+
+	turns := transcriptTurns(c.messages)
+	if len(turns) == 0 {
+		c.ui.PrintError("No messages to export")
+		return true
+	}
+
+	transcript := Transcript{
+		ExportedAt: time.Now().UTC(),
+		Provider:   c.config.LLM.Provider,
+		Model:      c.config.LLM.Model,
+		Turns:      turns,
+	}
+	if c.mcp != nil {
+		if _, current, err := c.mcp.ListDatabases(ctx); err == nil {
+			transcript.Database = current
+		}
+	}
+
+	var data []byte
+	if format == "json" {
+		var err error
+		data, err = json.MarshalIndent(transcript, "", "  ")
+		if err != nil {
+			c.ui.PrintError(fmt.Sprintf("Failed to export conversation: %v", err))
+			return true
+		}
+		data = append(data, '\n')
+	} else {
+		data = []byte(renderTranscriptMarkdown(transcript))
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		c.ui.PrintError(fmt.Sprintf("Failed to export conversation: %v", err))
+		return true
+	}
+	c.ui.PrintSystemMessage(fmt.Sprintf("Exported %d prompt(s) to %s", len(turns), path))
+	return true
+}
+
+// readReplayFile returns the prompts to replay from a JSON export, or from
+// a file of prompts in the format read by ReadScript
+func readReplayFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var transcript Transcript
+		if err := json.Unmarshal(trimmed, &transcript); err != nil {
+			return nil, fmt.Errorf("invalid export: %w", err)
+		}
+		prompts := make([]string, 0, len(transcript.Turns))
+		for _, turn := range transcript.Turns {
+			if strings.TrimSpace(turn.Prompt) != "" {
+				prompts = append(prompts, turn.Prompt)
+			}
+		}
+		return prompts, nil
+	}
+
+	return ReadScript(bytes.NewReader(data))
+}
+
+// handleReplayCommand runs the prompts of an export or prompt file in turn,
+// in the current conversation and against the current database. Lines
+// starting with / are run as slash commands. Replaying stops at the first
+// prompt that fails.
+func (c *Client) handleReplayCommand(ctx context.Context, args []string) bool {
+	if len(args) != 1 {
+		c.ui.PrintError("Usage: /replay <file>")
+		return true
+	}
+
+	prompts, err := readReplayFile(args[0])
+	if err != nil {
+		c.ui.PrintError(fmt.Sprintf("Failed to read %s: %v", args[0], err))
+		return true
+	}
+	if len(prompts) == 0 {
+		c.ui.PrintError(fmt.Sprintf("No prompts to replay in %s", args[0]))
+		return true
+	}
+
+	c.ui.PrintSystemMessage(fmt.Sprintf("Replaying %d prompt(s) from %s", len(prompts), args[0]))
+	for i, prompt := range prompts {
+		if ctx.Err() != nil {
+			return true
+		}
+		fmt.Println(c.ui.GetPrompt() + prompt)
+
+		if cmd := ParseSlashCommand(prompt); cmd != nil {
+			switch {
+			case cmd.Command == "replay":
+				c.ui.PrintError("/replay cannot be replayed")
+			case !c.HandleSlashCommand(ctx, cmd):
+				c.ui.PrintError(fmt.Sprintf("Unknown command: /%s", cmd.Command))
+			}
+			continue
+		}
+
+		if err := c.processQuery(ctx, prompt); err != nil {
+			c.ui.PrintError(fmt.Sprintf("Replay stopped at prompt %d: %v", i+1, err))
+			return true
+		}
+		c.ui.PrintSeparator()
+	}
+	return true
+}
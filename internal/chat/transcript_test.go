@@ -0,0 +1,184 @@
+/*-------------------------------------------------------------------------
+*
+ * pgEdge Natural Language Agent
+*
+* Portions copyright (c) 2025, pgEdge, Inc.
+* This software is released under The PostgreSQL License
+*
+*-------------------------------------------------------------------------
+*/
+
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// transcriptMessages is a conversation with a tool call, as held in the
+// history after a query
+var transcriptMessages = []Message{
+	{Role: "user", Content: "How many users are there?"},
+	{Role: "assistant", Content: []interface{}{
+		TextContent{Type: "text", Text: "Let me count them."},
+		ToolUse{Type: "tool_use", ID: "tool_1", Name: "count_rows", Input: map[string]interface{}{"table": "users"}},
+	}},
+	{Role: "user", Content: []ToolResult{
+		{Type: "tool_result", ToolUseID: "tool_1", Content: []mcp.ContentItem{{Type: "text", Text: "42"}}},
+	}},
+	{Role: "assistant", Content: "There are 42 users."},
+	{Role: "user", Content: "Thanks"},
+	{Role: "assistant", Content: "You're welcome."},
+}
+
+func TestTranscriptTurns(t *testing.T) {
+	turns := transcriptTurns(transcriptMessages)
+	if len(turns) != 2 {
+		t.Fatalf("expected 2 turns, got %d", len(turns))
+	}
+	if turns[0].Prompt != "How many users are there?" || turns[0].Answer != "There are 42 users." {
+		t.Errorf("unexpected first turn: %+v", turns[0])
+	}
+	if len(turns[0].ToolCalls) != 1 {
+		t.Fatalf("expected one tool call, got %+v", turns[0].ToolCalls)
+	}
+	call := turns[0].ToolCalls[0]
+	if call.Name != "count_rows" || call.Input["table"] != "users" || call.Result != "42" || call.IsError {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+	if len(turns[1].ToolCalls) != 0 || turns[1].Answer != "You're welcome." {
+		t.Errorf("unexpected second turn: %+v", turns[1])
+	}
+}
+
+func TestTranscriptTurns_SavedConversation(t *testing.T) {
+	// A conversation loaded from the server holds decoded maps
+	data, err := json.Marshal(transcriptMessages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		t.Fatal(err)
+	}
+
+	turns := transcriptTurns(messages)
+	if len(turns) != 2 || len(turns[0].ToolCalls) != 1 || turns[0].ToolCalls[0].Result != "42" {
+		t.Errorf("unexpected turns: %+v", turns)
+	}
+}
+
+func TestRenderTranscriptMarkdown(t *testing.T) {
+	transcript := Transcript{
+		Provider: "ollama",
+		Model:    "example-model",
+		Database: "staging",
+		Turns: []ScriptResult{{
+			Prompt: "Show the schema",
+			Answer: "Here it is.",
+			ToolCalls: []ScriptToolCall{{
+				Name:   "read_resource",
+				Input:  map[string]interface{}{"uri": "pg://schema"},
+				Result: "```sql\nCREATE TABLE users ();\n```",
+			}},
+		}},
+	}
+
+	markdown := renderTranscriptMarkdown(transcript)
+	for _, want := range []string{
+		"- LLM: ollama (example-model)",
+		"- Database: staging",
+		"## Prompt 1\n\nShow the schema",
+		"### Tool: read_resource",
+		`"uri": "pg://schema"`,
+		// The fence is longer than the backticks in the result
+		"````\n```sql\nCREATE TABLE users ();\n```\n````",
+		"### Answer\n\nHere it is.",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected %q in:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestReadReplayFile(t *testing.T) {
+	dir := t.TempDir()
+
+	export := filepath.Join(dir, "export.json")
+	data, err := json.Marshal(Transcript{Turns: transcriptTurns(transcriptMessages)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(export, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	prompts, err := readReplayFile(export)
+	if err != nil {
+		t.Fatalf("readReplayFile() error = %v", err)
+	}
+	if strings.Join(prompts, "|") != "How many users are there?|Thanks" {
+		t.Errorf("unexpected prompts from the export: %q", prompts)
+	}
+
+	script := filepath.Join(dir, "prompts.txt")
+	if err := os.WriteFile(script, []byte("# Checks\n/set database staging\nList the tables\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	prompts, err = readReplayFile(script)
+	if err != nil {
+		t.Fatalf("readReplayFile() error = %v", err)
+	}
+	if strings.Join(prompts, "|") != "/set database staging|List the tables" {
+		t.Errorf("unexpected prompts from the prompt file: %q", prompts)
+	}
+
+	if _, err := readReplayFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestHandleExportCommand(t *testing.T) {
+	cfg := &Config{
+		LLM: LLMConfig{
+			Provider:  "ollama",
+			OllamaURL: "http://localhost:11434",
+		},
+	}
+	client, err := NewClient(cfg, &ConfigOverrides{ProviderSet: true})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	client.messages = transcriptMessages
+	dir := t.TempDir()
+
+	// The format follows the file's extension
+	path := filepath.Join(dir, "session.json")
+	client.HandleSlashCommand(context.Background(), &SlashCommand{Command: "export", Args: []string{path}})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected the export to be written: %v", err)
+	}
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		t.Fatalf("expected a JSON export: %v", err)
+	}
+	if transcript.Provider != "ollama" || len(transcript.Turns) != 2 {
+		t.Errorf("unexpected export: %+v", transcript)
+	}
+
+	path = filepath.Join(dir, "session.txt")
+	client.HandleSlashCommand(context.Background(), &SlashCommand{Command: "export", Args: []string{"markdown", path}})
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected the export to be written: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Conversation Export") {
+		t.Errorf("expected a markdown export, got:\n%s", data)
+	}
+}
@@ -12,7 +12,9 @@ package chat
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"strings"
@@ -90,6 +92,12 @@ func (ui *UI) GetPrompt() string {
 	return ui.colorize(ColorGreen+ColorBold, "You: ")
 }
 
+// GetContinuationPrompt returns the prompt for the following lines of a
+// multi-line prompt
+func (ui *UI) GetContinuationPrompt() string {
+	return ui.colorize(ColorGreen+ColorBold, "...  ")
+}
+
 // PrintUserInput prints the user's input prompt (deprecated, kept for compatibility)
 func (ui *UI) PrintUserInput() {
 	fmt.Print(ui.GetPrompt())
@@ -369,6 +377,56 @@ func (ui *UI) PromptForPassword(ctx context.Context) (string, error) {
 	}
 }
 
+// PromptForApproval shows what a tool call would run against the database
+// and asks the user whether to run it. Only y or yes approves; an
+// interrupted prompt declines.
+func (ui *UI) PromptForApproval(ctx context.Context, toolName, statement string) bool {
+	fmt.Print("\r")
+	fmt.Println(ui.colorize(ColorYellow, fmt.Sprintf(" → %s wants to modify the database:", toolName)))
+	for _, line := range strings.Split(statement, "\n") {
+		fmt.Println("     " + line)
+	}
+	fmt.Print(ui.colorize(ColorYellow, "   Run it? [y/N]: "))
+
+	answer, ok := readAnswer(ctx)
+	return ok && (answer == "y" || answer == "yes")
+}
+
+// PromptForMore asks whether to show the next page of rows; pressing Enter
+// shows it
+func (ui *UI) PromptForMore(ctx context.Context) bool {
+	fmt.Print(ui.colorize(ColorYellow, "Show more rows? [Y/n]: "))
+
+	answer, ok := readAnswer(ctx)
+	return ok && (answer == "" || answer == "y" || answer == "yes")
+}
+
+// readAnswer reads a one-word answer from stdin in lower case. It returns
+// false if ctx is cancelled or stdin is closed before the answer is read.
+func readAnswer(ctx context.Context) (string, bool) {
+	type result struct {
+		answer string
+		ok     bool
+	}
+
+	// Use a channel to get the result from the blocking read
+	answerChan := make(chan result, 1)
+	go func() {
+		var answer string
+		// An empty line is an empty answer rather than an error
+		_, err := fmt.Scanln(&answer)
+		answerChan <- result{strings.ToLower(strings.TrimSpace(answer)), !errors.Is(err, io.EOF)}
+	}()
+
+	select {
+	case <-ctx.Done():
+		fmt.Println()
+		return "", false
+	case r := <-answerChan:
+		return r.answer, r.ok
+	}
+}
+
 // PrintHelp prints the help message
 func (ui *UI) PrintHelp() {
 	help := `
@@ -382,9 +440,16 @@ Commands (all commands start with /):
   /set <setting> <value>       - Change settings (status-messages, llm-provider, llm-model)
   /show <setting>              - Show current settings
   /list models                 - List available models from current LLM provider
+  /approve [on|off]            - Ask before running tool calls that modify the database
+  /sql <query>                 - Run a query directly, without the LLM
+  /export [format] [file]      - Save the conversation, including tool calls, to a file
+  /replay <file>               - Run the prompts of an export or prompt file again
+  /edit [text]                 - Compose a prompt in $EDITOR
 
 Keyboard shortcuts:
   Escape    - Cancel current LLM request and return to prompt
+  Alt+Enter - Continue the prompt on a new line
+  ` + "```" + `       - Start or end a multi-line code block
   Up/Down   - Navigate through command history
   Ctrl+R    - Reverse search history (type to filter, Ctrl+R for next match)
 
@@ -235,18 +235,25 @@ func (c *Classifier) classifyToolMessage(msg Message, result *ClassificationResu
 	// Classify based on tool type
 	for _, toolName := range toolNames {
 		switch toolName {
-		case "get_schema_info", "pg_dump_schema":
+		case "get_schema_info", "pg_dump_schema", "list_databases", "refresh_schema_cache", "find_relevant_tables":
 			result.Class = ClassAnchor
 			result.Importance = 1.0
 			result.Reasons = append(result.Reasons, "schema tool")
 			return
 
-		case "execute_explain", "analyze_query":
+		case "execute_explain", "explain_sql", "validate_sql", "compare_plan", "plan_schema_change", "get_table_stats", "index_advisor", "database_health_check", "lock_analysis", "check_collations", "generate_migration", "export_query_results", "analyze_query":
 			result.Class = ClassImportant
 			result.Importance = 0.85
 			result.Reasons = append(result.Reasons, "query analysis tool")
 			return
 
+		case "execute_script", "apply_migration", "create_vector_index", "install_extension", "setup_semantic_search", "setup_foreign_server", "add_database_connection", "snapshot_schema", "restore_schema_snapshot",
+			"begin_transaction", "commit_transaction", "rollback_transaction":
+			result.Class = ClassImportant
+			result.Importance = 0.85
+			result.Reasons = append(result.Reasons, "database change")
+			return
+
 		case "query_database":
 			// Check if results contain significant data
 			if len(text) > 500 {
@@ -260,7 +267,7 @@ func (c *Classifier) classifyToolMessage(msg Message, result *ClassificationResu
 			}
 			return
 
-		case "similarity_search", "search_documentation":
+		case "similarity_search", "hybrid_search", "search_documentation":
 			result.Class = ClassContextual
 			result.Importance = 0.65
 			result.Reasons = append(result.Reasons, "search tool")
@@ -24,6 +24,7 @@ type Compactor struct {
 	tokenEstimator    *TokenEstimator
 	providerEstimator *ProviderTokenEstimator
 	llmSummarizer     *LLMSummarizer
+	summarizer        Summarizer // Writes rolling summaries with an LLM (nil = basic summaries)
 	cache             *CompactionCache
 	analytics         *Analytics
 	maxTokens         int
@@ -89,8 +90,19 @@ func NewCompactor(req CompactRequest) *Compactor {
 	}
 }
 
+// SetSummarizer sets the summarizer that replaces dropped messages with a
+// rolling summary; without one, or when it fails, the basic summary is used
+func (c *Compactor) SetSummarizer(s Summarizer) {
+	c.summarizer = s
+}
+
 // Compact performs smart compaction on the message history.
 func (c *Compactor) Compact(messages []Message) CompactResponse {
+	return c.CompactContext(context.Background(), messages)
+}
+
+// CompactContext is Compact with a context for the summarizer.
+func (c *Compactor) CompactContext(ctx context.Context, messages []Message) CompactResponse {
 	startTime := time.Now()
 	originalCount := len(messages)
 
@@ -176,21 +188,30 @@ func (c *Compactor) Compact(messages []Message) CompactResponse {
 	if compactedTokens > c.maxTokens || c.options.EnableSummarization {
 		summary = c.createSummary(middle, important)
 
-		// Enhance summary with LLM if enabled
-		if c.llmSummarizer != nil && c.options.EnableLLMSummarization {
-			ctx := context.Background()
-			enhanced, err := c.llmSummarizer.GenerateSummary(ctx, middle, summary)
-			if err == nil {
-				summary = enhanced
+		if text, ok := c.summarizeDropped(ctx, middle, important); ok {
+			// The rolling summary replaces any earlier one kept from the
+			// middle and goes after the first anchor
+			summary.Description = text
+			rebuilt := make([]Message, 0, len(compacted)+1)
+			rebuilt = append(rebuilt, compacted[0], RollingSummaryMessage(text))
+			rebuilt = append(rebuilt, withoutRollingSummaries(important)...)
+			compacted = append(rebuilt, recent...)
+		} else {
+			// Enhance summary with LLM if enabled
+			if c.llmSummarizer != nil && c.options.EnableLLMSummarization {
+				enhanced, err := c.llmSummarizer.GenerateSummary(ctx, middle, summary)
+				if err == nil {
+					summary = enhanced
+				}
 			}
-		}
 
-		// Insert summary message after first anchor
-		summaryMsg := Message{
-			Role:    "assistant",
-			Content: c.formatSummary(summary),
+			// Insert summary message after first anchor
+			summaryMsg := Message{
+				Role:    "assistant",
+				Content: c.formatSummary(summary),
+			}
+			compacted = append([]Message{compacted[0], summaryMsg}, compacted[1:]...)
 		}
-		compacted = append([]Message{compacted[0], summaryMsg}, compacted[1:]...)
 		compactedTokens = c.tokenEstimator.EstimateTokensForMessages(compacted)
 	}
 
@@ -18,6 +18,20 @@ import (
 
 // HandleCompact is the HTTP handler for the /api/chat/compact endpoint.
 func HandleCompact(w http.ResponseWriter, r *http.Request) {
+	handleCompact(w, r, nil)
+}
+
+// NewHandler returns a handler for the /api/chat/compact endpoint that
+// replaces dropped messages with a rolling summary written by summarizer.
+func NewHandler(summarizer Summarizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleCompact(w, r, summarizer)
+	}
+}
+
+// handleCompact compacts the messages of a request, using summarizer, if
+// not nil, for the summary of dropped messages.
+func handleCompact(w http.ResponseWriter, r *http.Request, summarizer Summarizer) {
 	// Only accept POST requests
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -48,7 +62,10 @@ func HandleCompact(w http.ResponseWriter, r *http.Request) {
 
 	// Create compactor and perform compaction
 	compactor := NewCompactor(req)
-	response := compactor.Compact(req.Messages)
+	if summarizer != nil {
+		compactor.SetSummarizer(summarizer)
+	}
+	response := compactor.CompactContext(r.Context(), req.Messages)
 
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
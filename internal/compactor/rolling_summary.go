@@ -0,0 +1,194 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/logging"
+)
+
+// RollingSummaryPrefix starts the system message holding the summary of
+// messages dropped by compaction; the next compaction adds to it
+const RollingSummaryPrefix = "[Summary of earlier conversation]"
+
+const (
+	// summaryTimeout bounds the LLM call that writes a rolling summary
+	summaryTimeout = 60 * time.Second
+
+	// maxTranscriptMessageChars and maxTranscriptChars limit the dropped
+	// messages sent to the LLM to summarize
+	maxTranscriptMessageChars = 2000
+	maxTranscriptChars        = 60000
+)
+
+// Summarizer writes the rolling summary of a conversation: it returns the
+// previous summary (empty for the first) updated with the transcript of
+// messages that are being dropped
+type Summarizer interface {
+	Summarize(ctx context.Context, previous, transcript string) (string, error)
+}
+
+// RollingSummaryMessage returns the system message holding a rolling summary
+func RollingSummaryMessage(summary string) Message {
+	return Message{Role: "system", Content: RollingSummaryPrefix + "\n" + summary}
+}
+
+// rollingSummaryText returns the summary held by a rolling summary message
+func rollingSummaryText(msg Message) (string, bool) {
+	text, ok := msg.Content.(string)
+	if msg.Role != "system" || !ok || !strings.HasPrefix(text, RollingSummaryPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(text, RollingSummaryPrefix)), true
+}
+
+// withoutRollingSummaries removes rolling summary messages
+func withoutRollingSummaries(messages []Message) []Message {
+	kept := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if _, ok := rollingSummaryText(msg); !ok {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
+
+// summarizeDropped asks the summarizer to add the middle messages that are
+// not kept to the rolling summary found among them. It returns false when
+// there is no summarizer or it fails, so the basic summary is used.
+func (c *Compactor) summarizeDropped(ctx context.Context, middle, kept []Message) (string, bool) {
+	if c.summarizer == nil {
+		return "", false
+	}
+
+	var previous string
+	var dropped []Message
+	for _, msg := range middle {
+		if text, ok := rollingSummaryText(msg); ok {
+			previous = text
+			continue
+		}
+		if !c.containsMessage(kept, msg) {
+			dropped = append(dropped, msg)
+		}
+	}
+	if len(dropped) == 0 {
+		return previous, previous != ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+	summary, err := c.summarizer.Summarize(ctx, previous, formatTranscript(dropped))
+	if err != nil {
+		logging.Warn("compaction_summary_failed", "error", err)
+		return "", false
+	}
+	summary = strings.TrimSpace(summary)
+	return summary, summary != ""
+}
+
+// containsMessage reports whether a message is in a list
+func (c *Compactor) containsMessage(messages []Message, msg Message) bool {
+	for _, m := range messages {
+		if c.messagesEqual(m, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatTranscript renders messages as text for the summarizer, shortening
+// long messages and leaving out the oldest ones beyond the size limit
+func formatTranscript(messages []Message) string {
+	entries := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		text := transcriptText(msg)
+		if text == "" {
+			continue
+		}
+		if runes := []rune(text); len(runes) > maxTranscriptMessageChars {
+			text = string(runes[:maxTranscriptMessageChars]) + " [...]"
+		}
+		entries = append(entries, msg.Role+": "+text)
+	}
+
+	// Keep the most recent messages within the limit
+	size := 0
+	start := len(entries)
+	for start > 0 && size+len(entries[start-1]) <= maxTranscriptChars {
+		start--
+		size += len(entries[start]) + 2
+	}
+	transcript := strings.Join(entries[start:], "\n\n")
+	if start > 0 {
+		transcript = fmt.Sprintf("[%d earlier messages omitted]\n\n", start) + transcript
+	}
+	return transcript
+}
+
+// transcriptText returns the text of a message, including tool calls and
+// their results
+func transcriptText(msg Message) string {
+	switch content := msg.Content.(type) {
+	case string:
+		return content
+	case []interface{}:
+		var parts []string
+		for _, block := range content {
+			b, ok := block.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch b["type"] {
+			case "text":
+				if text, ok := b["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			case "tool_use":
+				input, err := json.Marshal(b["input"])
+				if err != nil {
+					input = []byte("{}")
+				}
+				parts = append(parts, fmt.Sprintf("[called %v with %s]", b["name"], input))
+			case "tool_result":
+				parts = append(parts, "[tool result: "+toolResultText(b["content"])+"]")
+			}
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return ""
+	}
+}
+
+// toolResultText returns the text of a tool result's content
+func toolResultText(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []interface{}:
+		var texts []string
+		for _, item := range c {
+			if m, ok := item.(map[string]interface{}); ok {
+				if text, ok := m["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,167 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package compactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeSummarizer records the requests it gets and returns a fixed summary
+type fakeSummarizer struct {
+	summary     string
+	err         error
+	calls       int
+	previous    string
+	transcripts []string
+}
+
+func (f *fakeSummarizer) Summarize(ctx context.Context, previous, transcript string) (string, error) {
+	f.calls++
+	f.previous = previous
+	f.transcripts = append(f.transcripts, transcript)
+	return f.summary, f.err
+}
+
+// longConversation returns a conversation that needs compaction
+func longConversation(count int) []Message {
+	messages := []Message{createMessage("user", "Initial question")}
+	for i := 1; i < count; i++ {
+		role := "assistant"
+		if i%2 == 1 {
+			role = "user"
+		}
+		messages = append(messages, createMessage(role, fmt.Sprintf("ok %d", i)))
+	}
+	return messages
+}
+
+func newTestCompactor(messages []Message) *Compactor {
+	return NewCompactor(CompactRequest{
+		Messages:     messages,
+		MaxTokens:    10,
+		RecentWindow: 4,
+		KeepAnchors:  true,
+	})
+}
+
+func TestCompactor_RollingSummary(t *testing.T) {
+	messages := longConversation(20)
+	summarizer := &fakeSummarizer{summary: "- The user asked about orders"}
+	c := newTestCompactor(messages)
+	c.SetSummarizer(summarizer)
+
+	result := c.CompactContext(context.Background(), messages)
+
+	if summarizer.calls != 1 {
+		t.Fatalf("Expected 1 summarizer call, got %d", summarizer.calls)
+	}
+	if summarizer.previous != "" {
+		t.Errorf("Expected no previous summary, got %q", summarizer.previous)
+	}
+	if !strings.Contains(summarizer.transcripts[0], "user: ok 1") {
+		t.Errorf("Expected dropped messages in the transcript, got %q", summarizer.transcripts[0])
+	}
+	if len(result.Messages) < 2 {
+		t.Fatalf("Expected at least 2 messages, got %d", len(result.Messages))
+	}
+	text, ok := rollingSummaryText(result.Messages[1])
+	if !ok || text != summarizer.summary {
+		t.Errorf("Expected the rolling summary after the first message, got %#v", result.Messages[1])
+	}
+	if result.Summary == nil || result.Summary.Description != summarizer.summary {
+		t.Errorf("Expected the summary description to be the rolling summary, got %#v", result.Summary)
+	}
+}
+
+func TestCompactor_RollingSummaryUpdatesPrevious(t *testing.T) {
+	messages := longConversation(20)
+	messages = append(messages[:1], append([]Message{RollingSummaryMessage("- Earlier summary")}, messages[1:]...)...)
+	summarizer := &fakeSummarizer{summary: "- Updated summary"}
+	c := newTestCompactor(messages)
+	c.SetSummarizer(summarizer)
+
+	result := c.CompactContext(context.Background(), messages)
+
+	if summarizer.previous != "- Earlier summary" {
+		t.Errorf("Expected the earlier summary as previous, got %q", summarizer.previous)
+	}
+	if strings.Contains(summarizer.transcripts[0], RollingSummaryPrefix) {
+		t.Error("The earlier summary should not be part of the transcript")
+	}
+	count := 0
+	for _, msg := range result.Messages {
+		if _, ok := rollingSummaryText(msg); ok {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected exactly 1 rolling summary message, got %d", count)
+	}
+}
+
+func TestCompactor_RollingSummaryFallback(t *testing.T) {
+	messages := longConversation(20)
+	c := newTestCompactor(messages)
+	c.SetSummarizer(&fakeSummarizer{err: errors.New("disabled")})
+
+	result := c.CompactContext(context.Background(), messages)
+
+	if len(result.Messages) < 2 {
+		t.Fatalf("Expected at least 2 messages, got %d", len(result.Messages))
+	}
+	if result.Messages[1].Role != "assistant" {
+		t.Errorf("Expected the basic assistant summary, got role %q", result.Messages[1].Role)
+	}
+	if _, ok := rollingSummaryText(result.Messages[1]); ok {
+		t.Error("Expected no rolling summary when the summarizer fails")
+	}
+}
+
+func TestFormatTranscript(t *testing.T) {
+	long := strings.Repeat("x", maxTranscriptMessageChars+10)
+	transcript := formatTranscript([]Message{
+		createMessage("user", "How many orders?"),
+		createToolMessage("assistant", "query_database", "SELECT count(*) FROM orders"),
+		{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "tool_result", "content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "42"},
+			}},
+		}},
+		createMessage("assistant", long),
+	})
+
+	for _, want := range []string{
+		"user: How many orders?",
+		`[called query_database with {"query":"SELECT count(*) FROM orders"}]`,
+		"[tool result: 42]",
+		" [...]",
+	} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("Expected %q in the transcript:\n%s", want, transcript)
+		}
+	}
+
+	var many []Message
+	for i := 0; i < 2*maxTranscriptChars/maxTranscriptMessageChars; i++ {
+		many = append(many, createMessage("assistant", long))
+	}
+	transcript = formatTranscript(many)
+	if len(transcript) > maxTranscriptChars+100 {
+		t.Errorf("Expected the transcript to be limited, got %d characters", len(transcript))
+	}
+	if !strings.Contains(transcript, "earlier messages omitted]") {
+		t.Error("Expected a note about omitted messages")
+	}
+}
@@ -11,12 +11,27 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
+
+	"pgedge-postgres-mcp/internal/autoanalyze"
+	"pgedge-postgres-mcp/internal/netproxy"
+	"pgedge-postgres-mcp/internal/notify"
+	"pgedge-postgres-mcp/internal/scheduler"
+	"pgedge-postgres-mcp/internal/secrets"
+	"pgedge-postgres-mcp/internal/sigv4"
+	"pgedge-postgres-mcp/internal/tracing"
 )
 
 // Config represents the complete server configuration
@@ -39,6 +54,35 @@ type Config struct {
 	// Built-in tools, resources, and prompts configuration
 	Builtins BuiltinsConfig `yaml:"builtins"`
 
+	// Data masking rules applied to query results
+	Masking MaskingConfig `yaml:"masking"`
+
+	// PostgreSQL server log lines attached to failed tool calls
+	PostgresLogs PostgresLogConfig `yaml:"postgres_logs"`
+
+	// Outbound proxy for LLM and embedding provider calls
+	Proxy netproxy.Settings `yaml:"proxy"`
+
+	// Offline (air-gapped) mode: disables cloud LLM and embedding providers
+	// and the tools that depend on them (default: false)
+	Offline bool `yaml:"offline"`
+
+	// Seconds to wait for in-flight requests to finish on SIGTERM/SIGINT
+	// before they are cancelled (default: 30)
+	ShutdownTimeoutSeconds int `yaml:"shutdown_timeout_seconds"`
+
+	// Seconds between checks of subscribed resources for changes; clients
+	// are sent notifications/resources/updated when content changes (default: 30)
+	ResourcePollIntervalSeconds int `yaml:"resource_poll_interval_seconds"`
+
+	// Seconds a token's database connections may go unused before they are
+	// closed; they reconnect on the token's next request (default: 1800, 0 = never)
+	ClientIdleTimeoutSeconds int `yaml:"client_idle_timeout_seconds"`
+
+	// Seconds after which cached table and column metadata is reloaded, so
+	// tables created or dropped outside the server show up (default: 300, 0 = never)
+	MetadataRefreshIntervalSeconds int `yaml:"metadata_refresh_interval_seconds"`
+
 	// Secret file path (for encryption key)
 	SecretFile string `yaml:"secret_file"`
 
@@ -47,6 +91,310 @@ type Config struct {
 
 	// Data directory path (for conversation history, etc.)
 	DataDir string `yaml:"data_dir"`
+
+	// Stored conversation history (encryption and retention)
+	Conversations ConversationsConfig `yaml:"conversations"`
+
+	// Files written by the export_query_results tool
+	Exports ExportsConfig `yaml:"exports"`
+
+	// Files uploaded through /api/upload
+	Uploads UploadsConfig `yaml:"uploads"`
+
+	// Schema snapshots written by the snapshot_schema tool
+	Snapshots SnapshotsConfig `yaml:"snapshots"`
+
+	// Extensions the install_extension tool may install
+	Extensions ExtensionsConfig `yaml:"extensions"`
+
+	// Cache of generated reports
+	Artifacts ArtifactsConfig `yaml:"artifacts"`
+
+	// Per-session history of queries and cache of their results
+	QueryHistory QueryHistoryConfig `yaml:"query_history"`
+
+	// Limits on the queries run with query_database
+	QueryGuard QueryGuardConfig `yaml:"query_guard"`
+
+	// Operational metrics
+	Metrics MetricsConfig `yaml:"metrics"`
+
+	// Background ANALYZE of tables with badly estimated plans
+	AutoAnalyze AutoAnalyzeConfig `yaml:"auto_analyze"`
+
+	// Custom health probes reported by the health check
+	HealthProbes []HealthProbeConfig `yaml:"health_probes"`
+
+	// Queries and tool pipelines run on a schedule
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+
+	// Alerts pushed to Slack or webhooks
+	Notifications NotificationsConfig `yaml:"notifications"`
+
+	// OpenTelemetry traces exported to an OTLP collector
+	Tracing TracingConfig `yaml:"tracing"`
+}
+
+// HealthProbeConfig defines a custom health probe: a query run periodically
+// in a configured database that returns one row of a status (ok, warn or
+// crit) and a message
+type HealthProbeConfig struct {
+	Name            string `yaml:"name"`             // Unique name, reported in the health check
+	Database        string `yaml:"database"`         // Name of the database to run the query in (default: the first database)
+	SQL             string `yaml:"sql"`              // Query returning the status and message
+	IntervalSeconds int    `yaml:"interval_seconds"` // Seconds between runs (default: 60)
+	TimeoutSeconds  int    `yaml:"timeout_seconds"`  // Statement timeout of the query (default: 10)
+}
+
+// SchedulerConfig controls scheduled jobs: saved queries or pipelines of
+// tool calls run on a cron schedule. Jobs can also be added through the
+// /api/jobs endpoint; those are kept in the jobs directory under the data
+// directory, with the history of every job's runs.
+type SchedulerConfig struct {
+	Enabled     bool        `yaml:"enabled"`      // Run scheduled jobs (default: false)
+	HistorySize int         `yaml:"history_size"` // Runs kept per job (default: 100)
+	Jobs        []JobConfig `yaml:"jobs"`         // Jobs defined in the configuration
+}
+
+// JobConfig defines a scheduled job: a query, or tool calls run in order
+type JobConfig struct {
+	Name           string          `yaml:"name"`            // Unique name
+	Schedule       string          `yaml:"schedule"`        // Cron expression (minute hour day month weekday), @daily, @hourly, @weekly, @monthly or @every <duration>
+	Database       string          `yaml:"database"`        // Name of the database the job runs in (default: the first database)
+	SQL            string          `yaml:"sql"`             // Query run with query_database
+	Steps          []JobStepConfig `yaml:"steps"`           // Tool calls run in order, instead of sql
+	Webhook        string          `yaml:"webhook"`         // URL each run's result is posted to as JSON
+	TimeoutSeconds int             `yaml:"timeout_seconds"` // Bound on a run (default: 300)
+	Disabled       bool            `yaml:"disabled"`        // Only run the job through the API (default: false)
+}
+
+// JobStepConfig is a tool call of a job's pipeline
+type JobStepConfig struct {
+	Tool string                 `yaml:"tool"` // Tool name
+	Args map[string]interface{} `yaml:"args"` // Tool arguments
+}
+
+// SchedulerJobs returns the configured jobs for the scheduler
+func (c SchedulerConfig) SchedulerJobs() []scheduler.Job {
+	jobs := make([]scheduler.Job, 0, len(c.Jobs))
+	for _, jc := range c.Jobs {
+		job := scheduler.Job{
+			Name:           jc.Name,
+			Schedule:       jc.Schedule,
+			Database:       jc.Database,
+			SQL:            jc.SQL,
+			Webhook:        jc.Webhook,
+			TimeoutSeconds: jc.TimeoutSeconds,
+			Disabled:       jc.Disabled,
+			Source:         scheduler.SourceConfig,
+		}
+		for _, step := range jc.Steps {
+			job.Steps = append(job.Steps, scheduler.Step{Tool: step.Tool, Args: step.Args})
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// NotificationsConfig controls alerts pushed out of band to Slack or
+// webhooks: health probe changes, failed scheduled jobs, account lockouts
+// and API tokens about to expire
+type NotificationsConfig struct {
+	CooldownMinutes        int                          `yaml:"cooldown_minutes"`          // Minutes before a repeated alert is sent again (default: 10)
+	TokenExpiryWarningDays int                          `yaml:"token_expiry_warning_days"` // Days before an API token expires to warn of it, 0 = no warnings (default: 7)
+	Endpoints              []NotificationEndpointConfig `yaml:"endpoints"`                 // Where alerts are sent (default: none)
+}
+
+// NotificationEndpointConfig defines a destination of alerts
+type NotificationEndpointConfig struct {
+	Name        string   `yaml:"name"`         // Unique name, used in logs
+	Type        string   `yaml:"type"`         // slack or webhook
+	URL         string   `yaml:"url"`          // Incoming webhook URL; may be a secret reference
+	Events      []string `yaml:"events"`       // Categories sent: health, jobs, auth (default: all)
+	MinSeverity string   `yaml:"min_severity"` // Least severe alert sent: info, warning or critical (default: info)
+}
+
+// NotifyEndpoints returns the configured endpoints for the notifier
+func (c NotificationsConfig) NotifyEndpoints() []notify.Endpoint {
+	endpoints := make([]notify.Endpoint, 0, len(c.Endpoints))
+	for _, ec := range c.Endpoints {
+		endpoints = append(endpoints, notify.Endpoint{
+			Name:        ec.Name,
+			Type:        ec.Type,
+			URL:         ec.URL,
+			Events:      ec.Events,
+			MinSeverity: ec.MinSeverity,
+		})
+	}
+	return endpoints
+}
+
+// TracingConfig controls OpenTelemetry tracing of requests: spans of MCP
+// requests, tool calls, SQL queries and LLM calls exported to an OTLP/HTTP
+// collector such as Jaeger or Tempo
+type TracingConfig struct {
+	Enabled        bool              `yaml:"enabled"`         // Record and export spans (default: false)
+	Endpoint       string            `yaml:"endpoint"`        // OTLP/HTTP collector URL (default: http://localhost:4318)
+	Headers        map[string]string `yaml:"headers"`         // Headers sent with each export, such as an API key (default: none)
+	ServiceName    string            `yaml:"service_name"`    // service.name of the spans (default: pgedge-postgres-mcp)
+	SampleRatio    float64           `yaml:"sample_ratio"`    // Fraction of new traces recorded, above 0 and up to 1 (default: 1)
+	OmitStatements bool              `yaml:"omit_statements"` // Leave the SQL text out of query spans (default: false)
+}
+
+// TracerConfig returns the settings of the tracer
+func (c TracingConfig) TracerConfig(serviceVersion string) tracing.Config {
+	return tracing.Config{
+		Endpoint:       c.Endpoint,
+		Headers:        c.Headers,
+		ServiceName:    c.ServiceName,
+		ServiceVersion: serviceVersion,
+		SampleRatio:    c.SampleRatio,
+		OmitStatements: c.OmitStatements,
+	}
+}
+
+// MetricsConfig holds settings for the server's operational metrics
+type MetricsConfig struct {
+	Export MetricsExportConfig `yaml:"export"`
+}
+
+// MetricsExportConfig controls periodic snapshots of the server's metrics
+// (tool latencies and errors, connection pool statistics) written to the
+// pgedge_mcp_metrics schema of a configured database
+type MetricsExportConfig struct {
+	Enabled         bool   `yaml:"enabled"`          // Write metrics snapshots (default: false)
+	Database        string `yaml:"database"`         // Name of the database to write to (default: the first database)
+	IntervalSeconds int    `yaml:"interval_seconds"` // Seconds between snapshots (default: 60)
+	RetentionDays   int    `yaml:"retention_days"`   // Delete snapshots older than this (default: 7)
+}
+
+// ExportsConfig holds limits for query result exports, which are written to
+// the exports directory under the data directory
+type ExportsConfig struct {
+	MaxRows        int `yaml:"max_rows"`        // Rows per export (default: 1000000)
+	MaxSizeMB      int `yaml:"max_size_mb"`     // Size of each export file (default: 100)
+	RetentionHours int `yaml:"retention_hours"` // Delete exports older than this (default: 24)
+}
+
+// UploadsConfig controls the /api/upload endpoint, which stores files in
+// the uploads directory under the data directory for data imports and
+// knowledgebase ingestion
+type UploadsConfig struct {
+	Enabled        bool     `yaml:"enabled"`         // Register the endpoint in HTTP mode (default: false)
+	MaxSizeMB      int      `yaml:"max_size_mb"`     // Size of each uploaded file (default: 50)
+	AllowedTypes   []string `yaml:"allowed_types"`   // File extensions accepted, without the dot (default: see DefaultUploadTypes)
+	RetentionHours int      `yaml:"retention_hours"` // Delete uploads older than this (default: 24)
+}
+
+// DefaultUploadTypes are the file extensions accepted for upload by
+// default: data files and the document formats the knowledgebase builder
+// converts
+var DefaultUploadTypes = []string{"csv", "tsv", "json", "jsonl", "txt", "md", "html", "htm", "rst", "pdf"}
+
+// ExtensionsConfig holds the extensions the install_extension tool may
+// install with CREATE EXTENSION
+type ExtensionsConfig struct {
+	Allowed []string `yaml:"allowed"` // Extension names (default: see DefaultAllowedExtensions)
+}
+
+// DefaultAllowedExtensions are the extensions install_extension may install
+// by default: pgvector and widely used contrib extensions for search,
+// indexing, data types and monitoring
+var DefaultAllowedExtensions = []string{
+	"vector", "pg_trgm", "fuzzystrmatch", "unaccent", "btree_gin", "btree_gist",
+	"citext", "hstore", "pgcrypto", "uuid-ossp", "pg_stat_statements",
+}
+
+// IsAllowed reports whether install_extension may install an extension
+func (c *ExtensionsConfig) IsAllowed(name string) bool {
+	for _, allowed := range c.Allowed {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SnapshotsConfig holds limits for schema snapshots, which are written to the
+// snapshots directory under the data directory
+type SnapshotsConfig struct {
+	MaxSizeMB int `yaml:"max_size_mb"` // Size of the data of each snapshot (default: 100)
+}
+
+// AutoAnalyzeConfig controls background ANALYZE of tables whose row
+// estimates in execute_explain plans were far from the actual rows
+type AutoAnalyzeConfig struct {
+	Enabled            bool   `yaml:"enabled"`              // Queue ANALYZE for misestimated tables (default: false)
+	EstimateRatio      int    `yaml:"estimate_ratio"`       // Factor between estimated and actual rows that triggers ANALYZE (default: 10)
+	MinRows            int    `yaml:"min_rows"`             // Ignore scans where both counts are below this (default: 1000)
+	TableIntervalHours int    `yaml:"table_interval_hours"` // Minimum hours between ANALYZEs of one table (default: 24)
+	MaxPerHour         int    `yaml:"max_per_hour"`         // ANALYZEs started in any hour (default: 10)
+	Window             string `yaml:"window"`               // Local time of day to run in, e.g. "01:00-05:00" (default: any time)
+}
+
+// ArtifactsConfig controls the cache of generated artifacts, such as health
+// reports, which is kept in the artifacts directory under the data directory
+type ArtifactsConfig struct {
+	Cache         *bool `yaml:"cache"`           // Reuse artifacts for identical requests (default: true)
+	MaxAgeMinutes int   `yaml:"max_age_minutes"` // Reuse artifacts for this long, then delete them (default: 10)
+	MaxSizeMB     int   `yaml:"max_size_mb"`     // Total size of cached artifacts (default: 50)
+}
+
+// CacheEnabled reports whether generated artifacts are cached
+func (c ArtifactsConfig) CacheEnabled() bool {
+	return c.Cache == nil || *c.Cache
+}
+
+// QueryHistoryConfig controls the history of the queries run with
+// query_database in each session, and the reuse of the results of queries
+// repeated within the cache TTL
+type QueryHistoryConfig struct {
+	Size            int   `yaml:"size"`              // Queries kept per session (default: 50)
+	Cache           *bool `yaml:"cache"`             // Reuse results of identical queries (default: true)
+	CacheTTLSeconds int   `yaml:"cache_ttl_seconds"` // Reuse results for this long (default: 60)
+}
+
+// CacheEnabled reports whether query results are reused
+func (c QueryHistoryConfig) CacheEnabled() bool {
+	return c.Cache == nil || *c.Cache
+}
+
+// QueryGuardConfig limits what a query_database query can pull into the
+// conversation, and optionally refuses queries the planner estimates to be
+// too expensive
+type QueryGuardConfig struct {
+	MaxRows          int  `yaml:"max_rows"`           // Rows a query returns at most; larger LIMITs are lowered (default: 1000)
+	Explain          bool `yaml:"explain"`            // Plan each SELECT with EXPLAIN and refuse those over the limits below (default: false)
+	MaxCost          int  `yaml:"max_cost"`           // Highest estimated plan cost allowed, 0 = no limit (default: 1000000)
+	MaxEstimatedRows int  `yaml:"max_estimated_rows"` // Most rows any step of the plan may be estimated to process, 0 = no limit (default: 10000000)
+}
+
+// ConversationsConfig holds settings for the server-side conversation store
+type ConversationsConfig struct {
+	Backend              string `yaml:"backend"`                // Where conversations are stored: sqlite (in the data directory) or postgres (default: sqlite)
+	Database             string `yaml:"database"`               // Name of the database the postgres backend uses (default: the first database)
+	Encrypt              *bool  `yaml:"encrypt"`                // Encrypt stored conversations with a key derived from the server secret (default: true)
+	PerUserKeys          bool   `yaml:"per_user_keys"`          // Derive a separate key for each user (default: false)
+	MaxAgeDays           int    `yaml:"max_age_days"`           // Purge conversations not updated for this many days (default: 0, keep forever)
+	MaxPerUser           int    `yaml:"max_per_user"`           // Keep only each user's most recent conversations (default: 0, unlimited)
+	DeletedRetentionDays int    `yaml:"deleted_retention_days"` // Days the postgres backend keeps deleted conversations before purging them (default: 30)
+	PurgeIntervalMinutes int    `yaml:"purge_interval_minutes"` // Minutes between purge runs (default: 60)
+}
+
+// Conversation store backends
+const (
+	ConversationBackendSQLite   = "sqlite"
+	ConversationBackendPostgres = "postgres"
+)
+
+// UsesPostgres reports whether conversations are stored in PostgreSQL
+func (c ConversationsConfig) UsesPostgres() bool {
+	return c.Backend == ConversationBackendPostgres
+}
+
+// EncryptionEnabled reports whether stored conversations are encrypted
+func (c ConversationsConfig) EncryptionEnabled() bool {
+	return c.Encrypt == nil || *c.Encrypt
 }
 
 // BuiltinsConfig holds configuration for enabling/disabling built-in tools, resources, and prompts
@@ -60,19 +408,51 @@ type BuiltinsConfig struct {
 // All tools are enabled by default
 // Note: read_resource tool is always enabled as it's used to list resources
 type ToolsConfig struct {
-	QueryDatabase       *bool `yaml:"query_database"`       // Execute SQL queries (default: true)
-	GetSchemaInfo       *bool `yaml:"get_schema_info"`      // Get detailed schema information (default: true)
-	SimilaritySearch    *bool `yaml:"similarity_search"`    // Vector similarity search (default: true)
-	ExecuteExplain      *bool `yaml:"execute_explain"`      // Execute EXPLAIN queries (default: true)
-	GenerateEmbedding   *bool `yaml:"generate_embedding"`   // Generate text embeddings (default: true)
-	SearchKnowledgebase *bool `yaml:"search_knowledgebase"` // Search knowledgebase (default: true)
-	CountRows           *bool `yaml:"count_rows"`           // Count table rows (default: true)
+	QueryDatabase         *bool `yaml:"query_database"`          // Execute SQL queries (default: true)
+	GetSchemaInfo         *bool `yaml:"get_schema_info"`         // Get detailed schema information (default: true)
+	SimilaritySearch      *bool `yaml:"similarity_search"`       // Vector similarity search (default: true)
+	ExecuteExplain        *bool `yaml:"execute_explain"`         // Execute EXPLAIN queries (default: true)
+	GenerateEmbedding     *bool `yaml:"generate_embedding"`      // Generate text embeddings (default: true)
+	SearchKnowledgebase   *bool `yaml:"search_knowledgebase"`    // Search knowledgebase (default: true)
+	ListKBProjects        *bool `yaml:"list_kb_projects"`        // Projects and versions in the knowledgebase (default: true)
+	CountRows             *bool `yaml:"count_rows"`              // Count table rows (default: true)
+	ExplainSQL            *bool `yaml:"explain_sql"`             // Explain SQL against the schema without executing it (default: true)
+	ValidateSQL           *bool `yaml:"validate_sql"`            // Check SQL before it is run, planning it without executing it (default: true)
+	ComparePlan           *bool `yaml:"compare_plan"`            // Compare query plans with stored baselines (default: true)
+	PlanSchemaChange      *bool `yaml:"plan_schema_change"`      // Preview the effect of DDL without applying it (default: true)
+	GetTableStats         *bool `yaml:"get_table_stats"`         // Table statistics, bloat and index usage (default: true)
+	IndexAdvisor          *bool `yaml:"index_advisor"`           // Recommend indexes for expensive queries (default: true)
+	DatabaseHealthCheck   *bool `yaml:"database_health_check"`   // Vacuum, bloat and wraparound health report (default: true)
+	LockAnalysis          *bool `yaml:"lock_analysis"`           // Blocking trees of sessions waiting for locks (default: true)
+	CheckCollations       *bool `yaml:"check_collations"`        // Collation version mismatches and REINDEX guidance (default: true)
+	GenerateMigration     *bool `yaml:"generate_migration"`      // Generate forward and backward SQL for a schema change (default: true)
+	ExportQueryResults    *bool `yaml:"export_query_results"`    // Export query results to CSV, JSONL or Parquet files (default: true)
+	HybridSearch          *bool `yaml:"hybrid_search"`           // Full-text and vector search merged with reciprocal rank fusion (default: true)
+	GetContextUsage       *bool `yaml:"get_context_usage"`       // Size of the tool output returned in the conversation (default: true)
+	SnapshotSchema        *bool `yaml:"snapshot_schema"`         // Save a schema's DDL and data as a named snapshot (default: true)
+	ListSchemaSnapshots   *bool `yaml:"list_schema_snapshots"`   // List stored schema snapshots (default: true)
+	ListDatabases         *bool `yaml:"list_databases"`          // Configured databases with their labels (default: true)
+	RefreshSchemaCache    *bool `yaml:"refresh_schema_cache"`    // Reload the cached table and column metadata (default: true)
+	FindRelevantTables    *bool `yaml:"find_relevant_tables"`    // Match a natural-language request to the relevant tables (default: true)
+	GetQueryHistory       *bool `yaml:"get_query_history"`       // Queries run with query_database in the session (default: true)
+	ListJobs              *bool `yaml:"list_jobs"`               // Scheduled jobs and their runs, when the scheduler is enabled (default: true)
+	ExecuteScript         *bool `yaml:"execute_script"`          // Apply SQL scripts that modify the database (default: false)
+	ApplyMigration        *bool `yaml:"apply_migration"`         // Apply or roll back recorded schema migrations (default: false)
+	CreateVectorIndex     *bool `yaml:"create_vector_index"`     // Build HNSW/IVFFlat indexes on vector columns (default: false)
+	RestoreSchemaSnapshot *bool `yaml:"restore_schema_snapshot"` // Recreate a snapshot in a scratch schema (default: false)
+	InstallExtension      *bool `yaml:"install_extension"`       // Install allowed extensions with CREATE EXTENSION (default: false)
+	SetupSemanticSearch   *bool `yaml:"setup_semantic_search"`   // Add, fill and index an embedding column for a text column (default: false)
+	SetupForeignServer    *bool `yaml:"setup_foreign_server"`    // Wire other configured databases in with postgres_fdw (default: false)
+	AddDatabaseConnection *bool `yaml:"add_database_connection"` // Add configured databases at runtime; admin tokens only (default: false)
+	Transactions          *bool `yaml:"transactions"`            // begin_transaction, commit_transaction and rollback_transaction (default: false)
 }
 
 // ResourcesConfig holds configuration for enabling/disabling built-in resources
 // All resources are enabled by default
 type ResourcesConfig struct {
-	SystemInfo *bool `yaml:"system_info"` // pg://system_info (default: true)
+	SystemInfo  *bool `yaml:"system_info"` // pg://system_info (default: true)
+	Replication *bool `yaml:"replication"` // pg://replication (default: true)
+	Extensions  *bool `yaml:"extensions"`  // pg://extensions (default: true)
 }
 
 // PromptsConfig holds configuration for enabling/disabling built-in prompts
@@ -85,6 +465,11 @@ type PromptsConfig struct {
 }
 
 // IsToolEnabled returns true if the specified tool is enabled (defaults to true if not set)
+// execute_script, apply_migration, create_vector_index,
+// restore_schema_snapshot, install_extension, setup_semantic_search,
+// setup_foreign_server and the transaction tools can write to the database,
+// and add_database_connection changes the server's configuration, so they
+// must be enabled explicitly
 func (c *ToolsConfig) IsToolEnabled(toolName string) bool {
 	switch toolName {
 	case "query_database":
@@ -99,8 +484,68 @@ func (c *ToolsConfig) IsToolEnabled(toolName string) bool {
 		return c.GenerateEmbedding == nil || *c.GenerateEmbedding
 	case "search_knowledgebase":
 		return c.SearchKnowledgebase == nil || *c.SearchKnowledgebase
+	case "list_kb_projects":
+		return c.ListKBProjects == nil || *c.ListKBProjects
 	case "count_rows":
 		return c.CountRows == nil || *c.CountRows
+	case "explain_sql":
+		return c.ExplainSQL == nil || *c.ExplainSQL
+	case "validate_sql":
+		return c.ValidateSQL == nil || *c.ValidateSQL
+	case "compare_plan":
+		return c.ComparePlan == nil || *c.ComparePlan
+	case "plan_schema_change":
+		return c.PlanSchemaChange == nil || *c.PlanSchemaChange
+	case "get_table_stats":
+		return c.GetTableStats == nil || *c.GetTableStats
+	case "index_advisor":
+		return c.IndexAdvisor == nil || *c.IndexAdvisor
+	case "database_health_check":
+		return c.DatabaseHealthCheck == nil || *c.DatabaseHealthCheck
+	case "lock_analysis":
+		return c.LockAnalysis == nil || *c.LockAnalysis
+	case "check_collations":
+		return c.CheckCollations == nil || *c.CheckCollations
+	case "generate_migration":
+		return c.GenerateMigration == nil || *c.GenerateMigration
+	case "export_query_results":
+		return c.ExportQueryResults == nil || *c.ExportQueryResults
+	case "hybrid_search":
+		return c.HybridSearch == nil || *c.HybridSearch
+	case "get_context_usage":
+		return c.GetContextUsage == nil || *c.GetContextUsage
+	case "snapshot_schema":
+		return c.SnapshotSchema == nil || *c.SnapshotSchema
+	case "list_schema_snapshots":
+		return c.ListSchemaSnapshots == nil || *c.ListSchemaSnapshots
+	case "list_databases":
+		return c.ListDatabases == nil || *c.ListDatabases
+	case "refresh_schema_cache":
+		return c.RefreshSchemaCache == nil || *c.RefreshSchemaCache
+	case "find_relevant_tables":
+		return c.FindRelevantTables == nil || *c.FindRelevantTables
+	case "get_query_history":
+		return c.GetQueryHistory == nil || *c.GetQueryHistory
+	case "list_jobs":
+		return c.ListJobs == nil || *c.ListJobs
+	case "execute_script":
+		return c.ExecuteScript != nil && *c.ExecuteScript
+	case "apply_migration":
+		return c.ApplyMigration != nil && *c.ApplyMigration
+	case "create_vector_index":
+		return c.CreateVectorIndex != nil && *c.CreateVectorIndex
+	case "install_extension":
+		return c.InstallExtension != nil && *c.InstallExtension
+	case "setup_semantic_search":
+		return c.SetupSemanticSearch != nil && *c.SetupSemanticSearch
+	case "setup_foreign_server":
+		return c.SetupForeignServer != nil && *c.SetupForeignServer
+	case "add_database_connection":
+		return c.AddDatabaseConnection != nil && *c.AddDatabaseConnection
+	case "restore_schema_snapshot":
+		return c.RestoreSchemaSnapshot != nil && *c.RestoreSchemaSnapshot
+	case "begin_transaction", "commit_transaction", "rollback_transaction":
+		return c.Transactions != nil && *c.Transactions
 	default:
 		return true // Unknown tools are enabled by default
 	}
@@ -111,6 +556,10 @@ func (c *ResourcesConfig) IsResourceEnabled(resourceURI string) bool {
 	switch resourceURI {
 	case "pg://system_info":
 		return c.SystemInfo == nil || *c.SystemInfo
+	case "pg://replication":
+		return c.Replication == nil || *c.Replication
+	case "pg://extensions":
+		return c.Extensions == nil || *c.Extensions
 	default:
 		return true // Unknown resources are enabled by default
 	}
@@ -132,30 +581,113 @@ func (c *PromptsConfig) IsPromptEnabled(promptName string) bool {
 	}
 }
 
+// MaskingConfig holds data masking settings
+// Masking is applied to query results before they are returned to the client
+type MaskingConfig struct {
+	Enabled bool          `yaml:"enabled"`  // Whether masking rules are applied (default: false)
+	HashKey string        `yaml:"hash_key"` // Key for keyed (HMAC) hashing of masked values; required by hash rules
+	Rules   []MaskingRule `yaml:"rules"`    // Ordered list of masking rules (first matching rule wins)
+}
+
+// MaskingRule describes a single masking rule
+// Table, column and value patterns are regular expressions; empty patterns match anything,
+// but each rule must set at least a column or a value pattern
+type MaskingRule struct {
+	Table       string `yaml:"table"`       // Pattern matched against "schema.table" of the source column
+	Column      string `yaml:"column"`      // Pattern matched against the source column name (the result column name for computed columns)
+	Value       string `yaml:"value"`       // Pattern matched against the value; only matching text is masked
+	Action      string `yaml:"action"`      // "redact" or "hash" (default: redact)
+	Replacement string `yaml:"replacement"` // Replacement text for redact (default: "****")
+}
+
+// PostgresLogConfig controls attaching recent PostgreSQL server log lines
+// (errors and warnings logged during the call) to failed tool calls
+type PostgresLogConfig struct {
+	Enabled      bool   `yaml:"enabled"`        // Attach server log lines to failed tool calls (default: false)
+	Source       string `yaml:"source"`         // "auto", "file", "pg_read_file" or "log_fdw" (default: auto)
+	Path         string `yaml:"path"`           // Local log file or log directory, for servers on the same host
+	LogFDWServer string `yaml:"log_fdw_server"` // Foreign server for log_fdw (default: log_server)
+	MaxLines     int    `yaml:"max_lines"`      // Maximum number of lines attached (default: 20)
+}
+
 // HTTPConfig holds HTTP/HTTPS server settings
 type HTTPConfig struct {
-	Enabled bool       `yaml:"enabled"`
-	Address string     `yaml:"address"`
-	TLS     TLSConfig  `yaml:"tls"`
-	Auth    AuthConfig `yaml:"auth"`
+	Enabled         bool                  `yaml:"enabled"`
+	Address         string                `yaml:"address"`
+	TLS             TLSConfig             `yaml:"tls"`
+	Auth            AuthConfig            `yaml:"auth"`
+	SessionAffinity SessionAffinityConfig `yaml:"session_affinity"`
+	CORS            CORSConfig            `yaml:"cors"`
+	Limits          HTTPLimitsConfig      `yaml:"limits"`
+}
+
+// HTTPLimitsConfig bounds the size and duration of HTTP requests, so
+// clients cannot hold connections open or send unbounded payloads
+type HTTPLimitsConfig struct {
+	MaxHeaderBytes      int `yaml:"max_header_bytes"`      // Size of request headers (default: 1048576)
+	MaxBodyMB           int `yaml:"max_body_mb"`           // Size of each request body; uploads use uploads.max_size_mb (default: 10)
+	ReadTimeoutSeconds  int `yaml:"read_timeout_seconds"`  // Time to read a request, including its body (default: 120)
+	WriteTimeoutSeconds int `yaml:"write_timeout_seconds"` // Time to write a response; streamed responses are cut off too (default: 0, no timeout)
+	IdleTimeoutSeconds  int `yaml:"idle_timeout_seconds"`  // Time idle keep-alive connections are kept open (default: 120)
+}
+
+// CORSConfig allows browsers to call the MCP endpoint and the /api
+// endpoints from other origins, so a web client can be hosted separately
+// from the server. CORS is off while no origins are listed.
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins"`   // Origins such as https://gui.example.com; "*" allows any
+	AllowedHeaders   []string `yaml:"allowed_headers"`   // Request headers allowed (default: Authorization, Content-Type, Mcp-Session-Id, Last-Event-ID)
+	AllowCredentials bool     `yaml:"allow_credentials"` // Allow requests with cookies (default: false)
+	MaxAgeSeconds    int      `yaml:"max_age_seconds"`   // Seconds browsers may cache preflight results (default: 600)
+}
+
+// SessionAffinityConfig identifies this server to a load balancer that runs
+// several replicas, so each MCP session can be kept on the replica that
+// created it. The replicas must share the server secret, which signs the
+// affinity cookie.
+type SessionAffinityConfig struct {
+	Enabled    bool   `yaml:"enabled"`     // Add the replica to session IDs and set the affinity cookie (default: false)
+	ReplicaID  string `yaml:"replica_id"`  // Unique ID of this replica (default: host name)
+	CookieName string `yaml:"cookie_name"` // Affinity cookie name (default: pgedge_mcp_replica)
 }
 
 // AuthConfig holds authentication settings
 type AuthConfig struct {
-	Enabled                        bool   `yaml:"enabled"`                            // Whether authentication is required
-	TokenFile                      string `yaml:"token_file"`                         // Path to token configuration file
-	UserFile                       string `yaml:"user_file"`                          // Path to user configuration file
-	MaxFailedAttemptsBeforeLockout int    `yaml:"max_failed_attempts_before_lockout"` // Number of failed login attempts before account lockout (0 = disabled)
-	RateLimitWindowMinutes         int    `yaml:"rate_limit_window_minutes"`          // Time window in minutes for rate limiting (default: 15)
-	RateLimitMaxAttempts           int    `yaml:"rate_limit_max_attempts"`            // Maximum failed attempts per IP in the time window (default: 10)
+	Enabled                        bool        `yaml:"enabled"`                            // Whether authentication is required
+	TokenFile                      string      `yaml:"token_file"`                         // Path to token configuration file
+	UserFile                       string      `yaml:"user_file"`                          // Path to user configuration file
+	MaxFailedAttemptsBeforeLockout int         `yaml:"max_failed_attempts_before_lockout"` // Number of failed login attempts before account lockout (0 = disabled)
+	RateLimitWindowMinutes         int         `yaml:"rate_limit_window_minutes"`          // Time window in minutes for rate limiting (default: 15)
+	RateLimitMaxAttempts           int         `yaml:"rate_limit_max_attempts"`            // Maximum failed attempts per IP in the time window (default: 10)
+	Quotas                         QuotaConfig `yaml:"quotas"`                             // Per-token tool usage limits
+}
+
+// QuotaConfig holds per-token tool usage limits (0 = unlimited)
+type QuotaConfig struct {
+	ToolCallsPerMinute   int `yaml:"tool_calls_per_minute"`  // Maximum tool calls per token per minute (default: 0)
+	RowsPerHour          int `yaml:"rows_per_hour"`          // Maximum query rows returned per token per hour (default: 0)
+	MaxConcurrentQueries int `yaml:"max_concurrent_queries"` // Maximum in-flight tool calls per token (default: 0)
 }
 
 // TLSConfig holds TLS/HTTPS settings
 type TLSConfig struct {
-	Enabled   bool   `yaml:"enabled"`
-	CertFile  string `yaml:"cert_file"`
-	KeyFile   string `yaml:"key_file"`
-	ChainFile string `yaml:"chain_file"`
+	Enabled   bool       `yaml:"enabled"`
+	CertFile  string     `yaml:"cert_file"`
+	KeyFile   string     `yaml:"key_file"`
+	ChainFile string     `yaml:"chain_file"`
+	ACME      ACMEConfig `yaml:"acme"`
+}
+
+// ACMEConfig obtains and renews the HTTPS certificate automatically from
+// an ACME certificate authority such as Let's Encrypt; when enabled, the
+// certificate, key and chain files are not used
+type ACMEConfig struct {
+	Enabled              bool     `yaml:"enabled"`                // Obtain certificates automatically (default: false)
+	Domains              []string `yaml:"domains"`                // Host names to obtain certificates for
+	CacheDir             string   `yaml:"cache_dir"`              // Keeps the account key and certificates (default: acme under data_dir)
+	DirectoryURL         string   `yaml:"directory_url"`          // ACME directory (default: Let's Encrypt production)
+	Email                string   `yaml:"email"`                  // Contact address for certificate problems (optional)
+	HTTPChallengeAddress string   `yaml:"http_challenge_address"` // Address such as ":80" for HTTP-01 challenges (default: none, TLS-ALPN-01 only)
 }
 
 // NamedDatabaseConfig holds named database connection settings with access control
@@ -169,15 +701,147 @@ type NamedDatabaseConfig struct {
 	SSLMode          string   `yaml:"sslmode"`                      // SSL mode: disable, require, verify-ca, verify-full (default: prefer)
 	AvailableToUsers []string `yaml:"available_to_users,omitempty"` // List of usernames allowed to access this database (empty = all users)
 
+	// Labels shown by list_databases and /api/databases
+	Environment string   `yaml:"environment"`    // prod, staging, dev or test (optional); prod disables writes by default
+	Tags        []string `yaml:"tags,omitempty"` // Free-form labels; a prod tag disables writes by default
+	Description string   `yaml:"description"`    // What the database holds
+
+	// Whether tools may modify the database, whatever their arguments
+	// (default: writes are allowed unless the database is labeled prod)
+	AllowWrites *bool `yaml:"allow_writes,omitempty"`
+
 	// Connection pool settings
 	PoolMaxConns        int    `yaml:"pool_max_conns"`          // Maximum number of connections (default: 4)
 	PoolMinConns        int    `yaml:"pool_min_conns"`          // Minimum number of connections (default: 0)
 	PoolMaxConnIdleTime string `yaml:"pool_max_conn_idle_time"` // Max time a connection can be idle before being closed (default: 30m)
+
+	// Schema metadata loading
+	Metadata MetadataConfig `yaml:"metadata"`
+}
+
+// MetadataConfig controls which schemas the server loads table and column
+// metadata for, and when. Schema patterns use shell glob syntax (*, ?, [...]).
+type MetadataConfig struct {
+	Schemas        []string `yaml:"schemas"`         // Schemas to load (empty = all)
+	ExcludeSchemas []string `yaml:"exclude_schemas"` // Schemas to skip, applied after schemas
+	Lazy           bool     `yaml:"lazy"`            // Load on first use, one schema at a time, instead of at connect
+
+	// Also load tables and columns the connection's role cannot SELECT
+	// (default: false, so tools only offer objects the role can query)
+	IncludeInaccessible bool `yaml:"include_inaccessible"`
+}
+
+// IncludesSchema reports whether metadata should be loaded for schema
+func (m MetadataConfig) IncludesSchema(schema string) bool {
+	if len(m.Schemas) > 0 && !matchesAnySchema(m.Schemas, schema) {
+		return false
+	}
+	return !matchesAnySchema(m.ExcludeSchemas, schema)
+}
+
+// IsFiltered reports whether any schema patterns are configured
+func (m MetadataConfig) IsFiltered() bool {
+	return len(m.Schemas) > 0 || len(m.ExcludeSchemas) > 0
+}
+
+// matchesAnySchema reports whether schema matches one of the glob patterns
+func matchesAnySchema(patterns []string, schema string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, schema); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// productionLabels are the environments and tags that mark a production
+// database
+var productionLabels = map[string]bool{"prod": true, "production": true}
+
+// validEnvironments are the accepted database environments
+var validEnvironments = map[string]bool{
+	"prod": true, "production": true, "staging": true,
+	"dev": true, "development": true, "test": true,
+}
+
+// IsProduction reports whether the database is a production database: its
+// environment is prod, or it is tagged prod
+func (cfg *NamedDatabaseConfig) IsProduction() bool {
+	if productionLabels[strings.ToLower(cfg.Environment)] {
+		return true
+	}
+	for _, tag := range cfg.Tags {
+		if productionLabels[strings.ToLower(tag)] {
+			return true
+		}
+	}
+	return false
+}
+
+// WritesAllowed reports whether tools may modify the database: as set by
+// allow_writes, or else unless it is a production database
+func (cfg *NamedDatabaseConfig) WritesAllowed() bool {
+	if cfg.AllowWrites != nil {
+		return *cfg.AllowWrites
+	}
+	return !cfg.IsProduction()
+}
+
+// Validate checks the settings of a database other than the uniqueness of
+// its name
+func (cfg *NamedDatabaseConfig) Validate() error {
+	// Require user field
+	if cfg.User == "" {
+		return fmt.Errorf("database '%s': user is required (set via -db-user, PGEDGE_DB_USER, PGUSER env var, or config file)", cfg.Name)
+	}
+
+	// Schema patterns must be valid globs
+	for _, pattern := range append(append([]string{}, cfg.Metadata.Schemas...), cfg.Metadata.ExcludeSchemas...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("database '%s': invalid metadata schema pattern %q: %w", cfg.Name, pattern, err)
+		}
+	}
+
+	// A misspelled environment would silently leave writes enabled
+	if cfg.Environment != "" && !validEnvironments[strings.ToLower(cfg.Environment)] {
+		return fmt.Errorf("database '%s': invalid environment %q (must be prod, staging, dev or test)", cfg.Name, cfg.Environment)
+	}
+	for _, tag := range cfg.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("database '%s': tags must not be empty", cfg.Name)
+		}
+	}
+	return nil
+}
+
+// IsSocketHost reports whether the database is reached through a Unix
+// domain socket: as with libpq, a host starting with / is the directory
+// holding the socket
+func (cfg *NamedDatabaseConfig) IsSocketHost() bool {
+	return strings.HasPrefix(cfg.Host, "/")
 }
 
 // BuildConnectionString creates a PostgreSQL connection string from NamedDatabaseConfig
 // If password is not set, pgx will automatically look it up from .pgpass file
 func (cfg *NamedDatabaseConfig) BuildConnectionString() string {
+	// A socket directory isn't a valid URL host, so socket connections use
+	// the keyword/value format
+	if cfg.IsSocketHost() {
+		params := []string{
+			"host=" + quoteConnValue(cfg.Host),
+			"port=" + strconv.Itoa(cfg.Port),
+			"dbname=" + quoteConnValue(cfg.Database),
+			"user=" + quoteConnValue(cfg.User),
+		}
+		if cfg.Password != "" {
+			params = append(params, "password="+quoteConnValue(cfg.Password))
+		}
+		if cfg.SSLMode != "" {
+			params = append(params, "sslmode="+quoteConnValue(cfg.SSLMode))
+		}
+		return strings.Join(params, " ")
+	}
+
 	// Build connection string components
 	connStr := fmt.Sprintf("postgres://%s", cfg.User)
 
@@ -197,6 +861,17 @@ func (cfg *NamedDatabaseConfig) BuildConnectionString() string {
 	return connStr
 }
 
+// quoteConnValue quotes a value of a keyword/value connection string when
+// it is empty or holds spaces, quotes or backslashes
+func quoteConnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
 // EmbeddingConfig holds embedding generation settings
 type EmbeddingConfig struct {
 	Enabled          bool   `yaml:"enabled"`             // Whether embedding generation is enabled (default: false)
@@ -207,13 +882,29 @@ type EmbeddingConfig struct {
 	OpenAIAPIKey     string `yaml:"openai_api_key"`      // API key for OpenAI (direct - discouraged, use api_key_file or env var)
 	OpenAIAPIKeyFile string `yaml:"openai_api_key_file"` // Path to file containing OpenAI API key
 	OllamaURL        string `yaml:"ollama_url"`          // URL for Ollama service (default: http://localhost:11434)
+
+	Cache EmbeddingCacheConfig `yaml:"cache"`
+}
+
+// EmbeddingCacheConfig controls caching of generated embeddings, so repeated
+// search queries don't call the embedding API again
+type EmbeddingCacheConfig struct {
+	Enabled    *bool  `yaml:"enabled"`     // Cache embeddings (default: true)
+	MaxEntries int    `yaml:"max_entries"` // Embeddings to keep (default: 10000)
+	Persist    bool   `yaml:"persist"`     // Also store embeddings in SQLite so they survive restarts (default: false)
+	Path       string `yaml:"path"`        // SQLite file (default: embedding_cache.db in the data directory)
+}
+
+// IsEnabled reports whether embeddings are cached
+func (c EmbeddingCacheConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
 }
 
 // LLMConfig holds LLM configuration for web client chat proxy
 type LLMConfig struct {
 	Enabled             bool    `yaml:"enabled"`                // Whether LLM proxy is enabled (default: false)
-	Provider            string  `yaml:"provider"`               // "anthropic", "openai", or "ollama"
-	Model               string  `yaml:"model"`                  // Provider-specific model name
+	Provider            string  `yaml:"provider"`               // "anthropic", "openai", "azure", "bedrock", or "ollama"
+	Model               string  `yaml:"model"`                  // Provider-specific model name (the deployment for Azure OpenAI)
 	AnthropicAPIKey     string  `yaml:"anthropic_api_key"`      // API key for Anthropic (direct - discouraged, use api_key_file or env var instead)
 	AnthropicAPIKeyFile string  `yaml:"anthropic_api_key_file"` // Path to file containing Anthropic API key
 	OpenAIAPIKey        string  `yaml:"openai_api_key"`         // API key for OpenAI (direct - discouraged, use api_key_file or env var instead)
@@ -221,21 +912,99 @@ type LLMConfig struct {
 	OllamaURL           string  `yaml:"ollama_url"`             // URL for Ollama service (default: http://localhost:11434)
 	MaxTokens           int     `yaml:"max_tokens"`             // Maximum tokens for LLM response (default: 4096)
 	Temperature         float64 `yaml:"temperature"`            // Temperature for LLM sampling (default: 0.7)
+	CompactionSummaries bool    `yaml:"compaction_summaries"`   // Summarize messages dropped by chat history compaction with the LLM (default: false)
+	ContextWindow       int     `yaml:"context_window"`         // Model context window in tokens for usage reports (default: known for the model)
+
+	// Azure OpenAI: requests go to the deployment named by the model
+	AzureEndpoint   string `yaml:"azure_endpoint"`     // Resource endpoint, e.g. https://example.openai.azure.com
+	AzureAPIKey     string `yaml:"azure_api_key"`      // API key for Azure OpenAI (direct - discouraged, use api_key_file or env var instead)
+	AzureAPIKeyFile string `yaml:"azure_api_key_file"` // Path to file containing Azure OpenAI API key
+	AzureAPIVersion string `yaml:"azure_api_version"`  // API version (default: 2024-10-21)
+
+	// Amazon Bedrock: credentials are read from the AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables
+	BedrockRegion   string `yaml:"bedrock_region"`   // AWS region (default: AWS_REGION env var)
+	BedrockEndpoint string `yaml:"bedrock_endpoint"` // Runtime endpoint override, e.g. a VPC endpoint
+
+	// Failover: when the provider of a request is rate limited, fails with
+	// a server error or cannot be reached, the fallbacks are tried in order
+	Fallback                []LLMRouteConfig `yaml:"fallback"`                  // Providers and models to fail over to
+	FailoverCooldownSeconds int              `yaml:"failover_cooldown_seconds"` // How long an unavailable provider is skipped before it is health checked (default: 60)
+}
+
+// LLMRouteConfig is a provider and model the LLM proxy can fail over to
+type LLMRouteConfig struct {
+	Provider string `yaml:"provider"` // "anthropic", "openai", "azure", "bedrock", or "ollama"
+	Model    string `yaml:"model"`    // Provider-specific model name
 }
 
 // KnowledgebaseConfig holds knowledgebase configuration
 type KnowledgebaseConfig struct {
 	Enabled      bool   `yaml:"enabled"`       // Whether knowledgebase search is enabled (default: false)
+	Backend      string `yaml:"backend"`       // "sqlite" or "postgres" (default: sqlite)
 	DatabasePath string `yaml:"database_path"` // Path to SQLite knowledgebase database
 
+	// PostgreSQL knowledgebase built by kb-builder with backend: postgres
+	ConnectionString string `yaml:"connection_string"` // Database with the pgvector extension
+	Schema           string `yaml:"schema"`            // Schema holding the knowledgebase (default: pgedge_kb)
+
+	// How often an unavailable knowledgebase (missing, locked or corrupt) is
+	// checked again; search_knowledgebase is disabled until it can be opened
+	RetryIntervalSeconds int `yaml:"retry_interval_seconds"` // Default: 30
+
 	// Embedding provider configuration for KB similarity search (independent of generate_embeddings tool)
-	EmbeddingProvider         string `yaml:"embedding_provider"`            // "voyage", "openai", or "ollama"
-	EmbeddingModel            string `yaml:"embedding_model"`               // Provider-specific model name
+	// With "auto", the provider is chosen from those the knowledgebase has
+	// embeddings for, and the model is the one kb-builder recorded
+	EmbeddingProvider         string `yaml:"embedding_provider"`            // "auto", "voyage", "openai", or "ollama" (default: auto)
+	EmbeddingModel            string `yaml:"embedding_model"`               // Provider-specific model name (default: as recorded in the knowledgebase)
 	EmbeddingVoyageAPIKey     string `yaml:"embedding_voyage_api_key"`      // API key for Voyage AI
 	EmbeddingVoyageAPIKeyFile string `yaml:"embedding_voyage_api_key_file"` // Path to file containing Voyage API key
 	EmbeddingOpenAIAPIKey     string `yaml:"embedding_openai_api_key"`      // API key for OpenAI
 	EmbeddingOpenAIAPIKeyFile string `yaml:"embedding_openai_api_key_file"` // Path to file containing OpenAI API key
 	EmbeddingOllamaURL        string `yaml:"embedding_ollama_url"`          // URL for Ollama service (default: http://localhost:11434)
+
+	// Reranking of search results (optional)
+	Rerank KnowledgebaseRerankConfig `yaml:"rerank"`
+}
+
+// KnowledgebaseRerankConfig configures reranking of knowledgebase search
+// results: the nearest chunks by vector similarity are reordered by a
+// reranking model, which is slower but judges relevance more accurately
+type KnowledgebaseRerankConfig struct {
+	Enabled        bool   `yaml:"enabled"`         // Whether results are reranked (default: false)
+	Provider       string `yaml:"provider"`        // "voyage", "cohere", or "ollama"
+	Model          string `yaml:"model"`           // Default: rerank-2 (voyage), rerank-v3.5 (cohere); required for ollama
+	Candidates     int    `yaml:"candidates"`      // Vector search results passed to the reranker (default: 20)
+	TimeoutSeconds int    `yaml:"timeout_seconds"` // Rerank request timeout; results keep their vector order on failure (default: 10)
+	APIKey         string `yaml:"api_key"`         // Voyage AI or Cohere API key (voyage default: knowledgebase's Voyage key)
+	APIKeyFile     string `yaml:"api_key_file"`    // Path to file containing the API key
+	OllamaURL      string `yaml:"ollama_url"`      // URL for Ollama service (default: embedding_ollama_url)
+}
+
+// Knowledgebase storage backends
+const (
+	KnowledgebaseBackendSQLite   = "sqlite"
+	KnowledgebaseBackendPostgres = "postgres"
+)
+
+// maxRerankCandidates bounds the search results sent to the reranker
+const maxRerankCandidates = 100
+
+// KnowledgebaseProviderAuto selects the knowledgebase embedding provider
+// from the embeddings the knowledgebase contains
+const KnowledgebaseProviderAuto = "auto"
+
+// UsesPostgres reports whether the knowledgebase is stored in PostgreSQL
+func (c *KnowledgebaseConfig) UsesPostgres() bool {
+	return c.Backend == KnowledgebaseBackendPostgres
+}
+
+// IsConfigured reports whether the knowledgebase's location is set
+func (c *KnowledgebaseConfig) IsConfigured() bool {
+	if c.UsesPostgres() {
+		return c.ConnectionString != ""
+	}
+	return c.DatabasePath != ""
 }
 
 // LoadConfig loads configuration with proper priority:
@@ -263,11 +1032,19 @@ func LoadConfig(configPath string, cliFlags CLIFlags) (*Config, error) {
 	}
 
 	// Override with environment variables
+	if err := applyJSONEnvironmentVariables(cfg); err != nil {
+		return nil, err
+	}
 	applyEnvironmentVariables(cfg)
 
 	// Override with command line flags (highest priority)
 	applyCLIFlags(cfg, cliFlags)
 
+	// Read secrets kept in the environment, files or secret managers
+	if err := resolveSecrets(context.Background(), cfg, secrets.NewResolverFromEnv()); err != nil {
+		return nil, err
+	}
+
 	// Validate final configuration
 	if err := validateConfig(cfg); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -322,6 +1099,10 @@ type CLIFlags struct {
 	// Secret file flags
 	SecretFile    string
 	SecretFileSet bool
+
+	// Offline mode flag
+	Offline    bool
+	OfflineSet bool
 }
 
 // defaultConfig returns configuration with hard-coded defaults
@@ -336,6 +1117,15 @@ func defaultConfig() *Config {
 				KeyFile:   "./server.key",
 				ChainFile: "",
 			},
+			CORS: CORSConfig{
+				MaxAgeSeconds: 600,
+			},
+			Limits: HTTPLimitsConfig{
+				MaxHeaderBytes:     1 << 20,
+				MaxBodyMB:          10,
+				ReadTimeoutSeconds: 120,
+				IdleTimeoutSeconds: 120,
+			},
 			Auth: AuthConfig{
 				Enabled:                        true, // Authentication enabled by default
 				TokenFile:                      "",   // Will be set to default path if not specified
@@ -363,21 +1153,128 @@ func defaultConfig() *Config {
 			Temperature:     0.7,                      // Default temperature
 		},
 		Knowledgebase: KnowledgebaseConfig{
-			Enabled:               false,                    // Disabled by default (opt-in)
-			DatabasePath:          "",                       // Must be provided if enabled
-			EmbeddingProvider:     "ollama",                 // Default provider for KB embeddings
-			EmbeddingModel:        "nomic-embed-text",       // Default Ollama model
-			EmbeddingOllamaURL:    "http://localhost:11434", // Default Ollama URL
-			EmbeddingVoyageAPIKey: "",                       // Must be provided if using Voyage
-			EmbeddingOpenAIAPIKey: "",                       // Must be provided if using OpenAI
+			Enabled:               false, // Disabled by default (opt-in)
+			Backend:               KnowledgebaseBackendSQLite,
+			DatabasePath:          "", // Must be provided if enabled
+			Schema:                "pgedge_kb",
+			RetryIntervalSeconds:  30,
+			EmbeddingProvider:     KnowledgebaseProviderAuto, // Chosen from the knowledgebase's embeddings
+			EmbeddingModel:        "",                        // As recorded in the knowledgebase
+			EmbeddingOllamaURL:    "http://localhost:11434",  // Default Ollama URL
+			EmbeddingVoyageAPIKey: "",                        // Must be provided if using Voyage
+			EmbeddingOpenAIAPIKey: "",                        // Must be provided if using OpenAI
+			Rerank: KnowledgebaseRerankConfig{
+				Enabled:        false, // Opt-in
+				Candidates:     20,
+				TimeoutSeconds: 10,
+			},
+		},
+		PostgresLogs: PostgresLogConfig{
+			Enabled:      false,        // Disabled by default (opt-in)
+			Source:       "auto",       // Use the path if set, then log_fdw, then pg_read_file
+			LogFDWServer: "log_server", // Server name used in the log_fdw documentation
+			MaxLines:     20,           // Keep error payloads small
+		},
+		SecretFile:                     "",   // Will be set to default path if not specified
+		ShutdownTimeoutSeconds:         30,   // Drain in-flight requests for up to 30 seconds
+		ResourcePollIntervalSeconds:    30,   // Check subscribed resources every 30 seconds
+		ClientIdleTimeoutSeconds:       1800, // Close a token's connections after 30 idle minutes
+		MetadataRefreshIntervalSeconds: 300,  // Reload schema metadata every 5 minutes
+		Conversations: ConversationsConfig{
+			Backend:              ConversationBackendSQLite,
+			DeletedRetentionDays: 30, // Deleted conversations can be recovered for a month
+			PurgeIntervalMinutes: 60, // Apply the retention policy hourly
+		},
+		Exports: ExportsConfig{
+			MaxRows:        1000000,
+			MaxSizeMB:      100,
+			RetentionHours: 24, // Downloads are meant to be fetched promptly
+		},
+		Uploads: UploadsConfig{
+			MaxSizeMB:      50,
+			AllowedTypes:   DefaultUploadTypes,
+			RetentionHours: 24, // Uploads are meant to be used promptly
+		},
+		Snapshots: SnapshotsConfig{
+			MaxSizeMB: 100,
+		},
+		Extensions: ExtensionsConfig{
+			Allowed: DefaultAllowedExtensions,
+		},
+		Artifacts: ArtifactsConfig{
+			MaxAgeMinutes: 10, // Reports describe the database's current state
+			MaxSizeMB:     50,
+		},
+		QueryHistory: QueryHistoryConfig{
+			Size:            50,
+			CacheTTLSeconds: 60, // Long enough for agentic loops, short for changing data
+		},
+		QueryGuard: QueryGuardConfig{
+			MaxRows:          1000,
+			MaxCost:          1000000,
+			MaxEstimatedRows: 10000000,
+		},
+		Metrics: MetricsConfig{
+			Export: MetricsExportConfig{
+				IntervalSeconds: 60,
+				RetentionDays:   7,
+			},
+		},
+		AutoAnalyze: AutoAnalyzeConfig{
+			EstimateRatio:      10,
+			MinRows:            1000,
+			TableIntervalHours: 24,
+			MaxPerHour:         10,
+		},
+		Scheduler: SchedulerConfig{
+			HistorySize: scheduler.DefaultHistorySize,
+		},
+		Notifications: NotificationsConfig{
+			CooldownMinutes:        10,
+			TokenExpiryWarningDays: 7,
+		},
+		Tracing: TracingConfig{
+			Endpoint:    "http://localhost:4318",
+			ServiceName: tracing.DefaultServiceName,
+			SampleRatio: 1,
 		},
-		SecretFile: "", // Will be set to default path if not specified
 	}
 }
 
-// loadConfigFile loads configuration from a YAML file
+// StdinConfigPath is the configuration path that reads the configuration
+// from standard input, such as a Kubernetes ConfigMap piped to the server
+const StdinConfigPath = "-"
+
+// stdinSource reads the configuration from standard input once; reloads
+// parse the same YAML again, with the current environment and secrets
+type stdinSource struct {
+	r    io.Reader
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// read returns the configuration read from standard input
+func (s *stdinSource) read() ([]byte, error) {
+	s.once.Do(func() {
+		s.data, s.err = io.ReadAll(s.r)
+	})
+	return s.data, s.err
+}
+
+// configStdin is where StdinConfigPath is read from
+var configStdin = &stdinSource{r: os.Stdin}
+
+// loadConfigFile loads configuration from a YAML file, or from standard
+// input when path is StdinConfigPath
 func loadConfigFile(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	var data []byte
+	var err error
+	if path == StdinConfigPath {
+		data, err = configStdin.read()
+	} else {
+		data, err = os.ReadFile(path)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -413,9 +1310,64 @@ func mergeConfig(dest, src *Config) {
 	if src.HTTP.TLS.ChainFile != "" {
 		dest.HTTP.TLS.ChainFile = src.HTTP.TLS.ChainFile
 	}
+	if src.HTTP.TLS.ACME.Enabled {
+		dest.HTTP.TLS.ACME.Enabled = true
+	}
+	if len(src.HTTP.TLS.ACME.Domains) > 0 {
+		dest.HTTP.TLS.ACME.Domains = src.HTTP.TLS.ACME.Domains
+	}
+	if src.HTTP.TLS.ACME.CacheDir != "" {
+		dest.HTTP.TLS.ACME.CacheDir = src.HTTP.TLS.ACME.CacheDir
+	}
+	if src.HTTP.TLS.ACME.DirectoryURL != "" {
+		dest.HTTP.TLS.ACME.DirectoryURL = src.HTTP.TLS.ACME.DirectoryURL
+	}
+	if src.HTTP.TLS.ACME.Email != "" {
+		dest.HTTP.TLS.ACME.Email = src.HTTP.TLS.ACME.Email
+	}
+	if src.HTTP.TLS.ACME.HTTPChallengeAddress != "" {
+		dest.HTTP.TLS.ACME.HTTPChallengeAddress = src.HTTP.TLS.ACME.HTTPChallengeAddress
+	}
 
 	// Auth - note: we need to preserve false values, so check if src differs from default
 	// Use a simple heuristic: if token file is set, assume auth config is intentional
+	if src.HTTP.SessionAffinity.Enabled {
+		dest.HTTP.SessionAffinity.Enabled = true
+	}
+	if src.HTTP.SessionAffinity.ReplicaID != "" {
+		dest.HTTP.SessionAffinity.ReplicaID = src.HTTP.SessionAffinity.ReplicaID
+	}
+	if src.HTTP.SessionAffinity.CookieName != "" {
+		dest.HTTP.SessionAffinity.CookieName = src.HTTP.SessionAffinity.CookieName
+	}
+	if len(src.HTTP.CORS.AllowedOrigins) > 0 {
+		dest.HTTP.CORS.AllowedOrigins = src.HTTP.CORS.AllowedOrigins
+	}
+	if len(src.HTTP.CORS.AllowedHeaders) > 0 {
+		dest.HTTP.CORS.AllowedHeaders = src.HTTP.CORS.AllowedHeaders
+	}
+	if src.HTTP.CORS.AllowCredentials {
+		dest.HTTP.CORS.AllowCredentials = true
+	}
+	if src.HTTP.CORS.MaxAgeSeconds > 0 {
+		dest.HTTP.CORS.MaxAgeSeconds = src.HTTP.CORS.MaxAgeSeconds
+	}
+	if src.HTTP.Limits.MaxHeaderBytes > 0 {
+		dest.HTTP.Limits.MaxHeaderBytes = src.HTTP.Limits.MaxHeaderBytes
+	}
+	if src.HTTP.Limits.MaxBodyMB > 0 {
+		dest.HTTP.Limits.MaxBodyMB = src.HTTP.Limits.MaxBodyMB
+	}
+	if src.HTTP.Limits.ReadTimeoutSeconds > 0 {
+		dest.HTTP.Limits.ReadTimeoutSeconds = src.HTTP.Limits.ReadTimeoutSeconds
+	}
+	if src.HTTP.Limits.WriteTimeoutSeconds > 0 {
+		dest.HTTP.Limits.WriteTimeoutSeconds = src.HTTP.Limits.WriteTimeoutSeconds
+	}
+	if src.HTTP.Limits.IdleTimeoutSeconds > 0 {
+		dest.HTTP.Limits.IdleTimeoutSeconds = src.HTTP.Limits.IdleTimeoutSeconds
+	}
+
 	if src.HTTP.Auth.TokenFile != "" || !src.HTTP.Auth.Enabled {
 		dest.HTTP.Auth.Enabled = src.HTTP.Auth.Enabled
 		dest.HTTP.Auth.TokenFile = src.HTTP.Auth.TokenFile
@@ -432,6 +1384,15 @@ func mergeConfig(dest, src *Config) {
 	if src.HTTP.Auth.RateLimitMaxAttempts > 0 {
 		dest.HTTP.Auth.RateLimitMaxAttempts = src.HTTP.Auth.RateLimitMaxAttempts
 	}
+	if src.HTTP.Auth.Quotas.ToolCallsPerMinute > 0 {
+		dest.HTTP.Auth.Quotas.ToolCallsPerMinute = src.HTTP.Auth.Quotas.ToolCallsPerMinute
+	}
+	if src.HTTP.Auth.Quotas.RowsPerHour > 0 {
+		dest.HTTP.Auth.Quotas.RowsPerHour = src.HTTP.Auth.Quotas.RowsPerHour
+	}
+	if src.HTTP.Auth.Quotas.MaxConcurrentQueries > 0 {
+		dest.HTTP.Auth.Quotas.MaxConcurrentQueries = src.HTTP.Auth.Quotas.MaxConcurrentQueries
+	}
 
 	// Databases - if source has databases defined, use them (replace, don't merge)
 	if len(src.Databases) > 0 {
@@ -463,6 +1424,18 @@ func mergeConfig(dest, src *Config) {
 			dest.Embedding.OllamaURL = src.Embedding.OllamaURL
 		}
 	}
+	if src.Embedding.Cache.Enabled != nil {
+		dest.Embedding.Cache.Enabled = src.Embedding.Cache.Enabled
+	}
+	if src.Embedding.Cache.MaxEntries > 0 {
+		dest.Embedding.Cache.MaxEntries = src.Embedding.Cache.MaxEntries
+	}
+	if src.Embedding.Cache.Persist {
+		dest.Embedding.Cache.Persist = true
+	}
+	if src.Embedding.Cache.Path != "" {
+		dest.Embedding.Cache.Path = src.Embedding.Cache.Path
+	}
 
 	// LLM - merge if any LLM fields are set
 	if src.LLM.Provider != "" || src.LLM.Enabled {
@@ -494,14 +1467,56 @@ func mergeConfig(dest, src *Config) {
 		if src.LLM.Temperature != 0 {
 			dest.LLM.Temperature = src.LLM.Temperature
 		}
+		if src.LLM.CompactionSummaries {
+			dest.LLM.CompactionSummaries = true
+		}
+		if src.LLM.ContextWindow > 0 {
+			dest.LLM.ContextWindow = src.LLM.ContextWindow
+		}
+		if src.LLM.AzureEndpoint != "" {
+			dest.LLM.AzureEndpoint = src.LLM.AzureEndpoint
+		}
+		if src.LLM.AzureAPIKey != "" {
+			dest.LLM.AzureAPIKey = src.LLM.AzureAPIKey
+		}
+		if src.LLM.AzureAPIKeyFile != "" {
+			dest.LLM.AzureAPIKeyFile = src.LLM.AzureAPIKeyFile
+		}
+		if src.LLM.AzureAPIVersion != "" {
+			dest.LLM.AzureAPIVersion = src.LLM.AzureAPIVersion
+		}
+		if src.LLM.BedrockRegion != "" {
+			dest.LLM.BedrockRegion = src.LLM.BedrockRegion
+		}
+		if src.LLM.BedrockEndpoint != "" {
+			dest.LLM.BedrockEndpoint = src.LLM.BedrockEndpoint
+		}
+		if len(src.LLM.Fallback) > 0 {
+			dest.LLM.Fallback = src.LLM.Fallback
+		}
+		if src.LLM.FailoverCooldownSeconds != 0 {
+			dest.LLM.FailoverCooldownSeconds = src.LLM.FailoverCooldownSeconds
+		}
 	}
 
 	// Knowledgebase - merge if any KB fields are set
-	if src.Knowledgebase.DatabasePath != "" || src.Knowledgebase.Enabled {
+	if src.Knowledgebase.DatabasePath != "" || src.Knowledgebase.ConnectionString != "" || src.Knowledgebase.Enabled {
 		dest.Knowledgebase.Enabled = src.Knowledgebase.Enabled
+		if src.Knowledgebase.Backend != "" {
+			dest.Knowledgebase.Backend = src.Knowledgebase.Backend
+		}
 		if src.Knowledgebase.DatabasePath != "" {
 			dest.Knowledgebase.DatabasePath = src.Knowledgebase.DatabasePath
 		}
+		if src.Knowledgebase.ConnectionString != "" {
+			dest.Knowledgebase.ConnectionString = src.Knowledgebase.ConnectionString
+		}
+		if src.Knowledgebase.Schema != "" {
+			dest.Knowledgebase.Schema = src.Knowledgebase.Schema
+		}
+		if src.Knowledgebase.RetryIntervalSeconds > 0 {
+			dest.Knowledgebase.RetryIntervalSeconds = src.Knowledgebase.RetryIntervalSeconds
+		}
 		if src.Knowledgebase.EmbeddingProvider != "" {
 			dest.Knowledgebase.EmbeddingProvider = src.Knowledgebase.EmbeddingProvider
 		}
@@ -523,6 +1538,28 @@ func mergeConfig(dest, src *Config) {
 		if src.Knowledgebase.EmbeddingOllamaURL != "" {
 			dest.Knowledgebase.EmbeddingOllamaURL = src.Knowledgebase.EmbeddingOllamaURL
 		}
+		dest.Knowledgebase.Rerank.Enabled = src.Knowledgebase.Rerank.Enabled
+		if src.Knowledgebase.Rerank.Provider != "" {
+			dest.Knowledgebase.Rerank.Provider = src.Knowledgebase.Rerank.Provider
+		}
+		if src.Knowledgebase.Rerank.Model != "" {
+			dest.Knowledgebase.Rerank.Model = src.Knowledgebase.Rerank.Model
+		}
+		if src.Knowledgebase.Rerank.Candidates > 0 {
+			dest.Knowledgebase.Rerank.Candidates = src.Knowledgebase.Rerank.Candidates
+		}
+		if src.Knowledgebase.Rerank.TimeoutSeconds > 0 {
+			dest.Knowledgebase.Rerank.TimeoutSeconds = src.Knowledgebase.Rerank.TimeoutSeconds
+		}
+		if src.Knowledgebase.Rerank.APIKey != "" {
+			dest.Knowledgebase.Rerank.APIKey = src.Knowledgebase.Rerank.APIKey
+		}
+		if src.Knowledgebase.Rerank.APIKeyFile != "" {
+			dest.Knowledgebase.Rerank.APIKeyFile = src.Knowledgebase.Rerank.APIKeyFile
+		}
+		if src.Knowledgebase.Rerank.OllamaURL != "" {
+			dest.Knowledgebase.Rerank.OllamaURL = src.Knowledgebase.Rerank.OllamaURL
+		}
 	}
 
 	// Secret file
@@ -530,19 +1567,282 @@ func mergeConfig(dest, src *Config) {
 		dest.SecretFile = src.SecretFile
 	}
 
-	// Custom definitions path
-	if src.CustomDefinitionsPath != "" {
-		dest.CustomDefinitionsPath = src.CustomDefinitionsPath
+	// Offline mode
+	if src.Offline {
+		dest.Offline = true
 	}
 
-	// Data directory
-	if src.DataDir != "" {
-		dest.DataDir = src.DataDir
+	// Shutdown drain timeout
+	if src.ShutdownTimeoutSeconds > 0 {
+		dest.ShutdownTimeoutSeconds = src.ShutdownTimeoutSeconds
 	}
 
-	// Builtins - merge individual settings (pointer fields preserve explicit false values)
-	// Tools
-	if src.Builtins.Tools.QueryDatabase != nil {
+	// Resource subscription polling
+	if src.ResourcePollIntervalSeconds > 0 {
+		dest.ResourcePollIntervalSeconds = src.ResourcePollIntervalSeconds
+	}
+
+	// Idle connection reaper
+	if src.ClientIdleTimeoutSeconds > 0 {
+		dest.ClientIdleTimeoutSeconds = src.ClientIdleTimeoutSeconds
+	}
+
+	// Metadata refresher
+	if src.MetadataRefreshIntervalSeconds > 0 {
+		dest.MetadataRefreshIntervalSeconds = src.MetadataRefreshIntervalSeconds
+	}
+
+	// Custom definitions path
+	if src.CustomDefinitionsPath != "" {
+		dest.CustomDefinitionsPath = src.CustomDefinitionsPath
+	}
+
+	// Data directory
+	if src.DataDir != "" {
+		dest.DataDir = src.DataDir
+	}
+
+	// Conversation store
+	if src.Conversations.Backend != "" {
+		dest.Conversations.Backend = src.Conversations.Backend
+	}
+	if src.Conversations.Database != "" {
+		dest.Conversations.Database = src.Conversations.Database
+	}
+	if src.Conversations.Encrypt != nil {
+		dest.Conversations.Encrypt = src.Conversations.Encrypt
+	}
+	if src.Conversations.PerUserKeys {
+		dest.Conversations.PerUserKeys = true
+	}
+	if src.Conversations.MaxAgeDays > 0 {
+		dest.Conversations.MaxAgeDays = src.Conversations.MaxAgeDays
+	}
+	if src.Conversations.MaxPerUser > 0 {
+		dest.Conversations.MaxPerUser = src.Conversations.MaxPerUser
+	}
+	if src.Conversations.DeletedRetentionDays > 0 {
+		dest.Conversations.DeletedRetentionDays = src.Conversations.DeletedRetentionDays
+	}
+	if src.Conversations.PurgeIntervalMinutes > 0 {
+		dest.Conversations.PurgeIntervalMinutes = src.Conversations.PurgeIntervalMinutes
+	}
+
+	// Exports
+	if src.Exports.MaxRows > 0 {
+		dest.Exports.MaxRows = src.Exports.MaxRows
+	}
+	if src.Exports.MaxSizeMB > 0 {
+		dest.Exports.MaxSizeMB = src.Exports.MaxSizeMB
+	}
+	if src.Exports.RetentionHours > 0 {
+		dest.Exports.RetentionHours = src.Exports.RetentionHours
+	}
+
+	// Uploads
+	if src.Uploads.Enabled {
+		dest.Uploads.Enabled = true
+	}
+	if src.Uploads.MaxSizeMB > 0 {
+		dest.Uploads.MaxSizeMB = src.Uploads.MaxSizeMB
+	}
+	if len(src.Uploads.AllowedTypes) > 0 {
+		dest.Uploads.AllowedTypes = src.Uploads.AllowedTypes
+	}
+	if src.Uploads.RetentionHours > 0 {
+		dest.Uploads.RetentionHours = src.Uploads.RetentionHours
+	}
+
+	// Snapshots
+	if src.Snapshots.MaxSizeMB > 0 {
+		dest.Snapshots.MaxSizeMB = src.Snapshots.MaxSizeMB
+	}
+
+	// Extensions
+	if len(src.Extensions.Allowed) > 0 {
+		dest.Extensions.Allowed = src.Extensions.Allowed
+	}
+
+	// Artifact cache
+	if src.Artifacts.Cache != nil {
+		dest.Artifacts.Cache = src.Artifacts.Cache
+	}
+	if src.Artifacts.MaxAgeMinutes > 0 {
+		dest.Artifacts.MaxAgeMinutes = src.Artifacts.MaxAgeMinutes
+	}
+	if src.Artifacts.MaxSizeMB > 0 {
+		dest.Artifacts.MaxSizeMB = src.Artifacts.MaxSizeMB
+	}
+
+	// Query history
+	if src.QueryHistory.Size > 0 {
+		dest.QueryHistory.Size = src.QueryHistory.Size
+	}
+	if src.QueryHistory.Cache != nil {
+		dest.QueryHistory.Cache = src.QueryHistory.Cache
+	}
+	if src.QueryHistory.CacheTTLSeconds > 0 {
+		dest.QueryHistory.CacheTTLSeconds = src.QueryHistory.CacheTTLSeconds
+	}
+
+	// Query guard
+	if src.QueryGuard.MaxRows > 0 {
+		dest.QueryGuard.MaxRows = src.QueryGuard.MaxRows
+	}
+	if src.QueryGuard.Explain {
+		dest.QueryGuard.Explain = true
+	}
+	if src.QueryGuard.MaxCost > 0 {
+		dest.QueryGuard.MaxCost = src.QueryGuard.MaxCost
+	}
+	if src.QueryGuard.MaxEstimatedRows > 0 {
+		dest.QueryGuard.MaxEstimatedRows = src.QueryGuard.MaxEstimatedRows
+	}
+
+	// Metrics export
+	if src.Metrics.Export.Enabled {
+		dest.Metrics.Export.Enabled = true
+	}
+	if src.Metrics.Export.Database != "" {
+		dest.Metrics.Export.Database = src.Metrics.Export.Database
+	}
+	if src.Metrics.Export.IntervalSeconds > 0 {
+		dest.Metrics.Export.IntervalSeconds = src.Metrics.Export.IntervalSeconds
+	}
+	if src.Metrics.Export.RetentionDays > 0 {
+		dest.Metrics.Export.RetentionDays = src.Metrics.Export.RetentionDays
+	}
+
+	// Background ANALYZE
+	if src.AutoAnalyze.Enabled {
+		dest.AutoAnalyze.Enabled = true
+	}
+	if src.AutoAnalyze.EstimateRatio > 0 {
+		dest.AutoAnalyze.EstimateRatio = src.AutoAnalyze.EstimateRatio
+	}
+	if src.AutoAnalyze.MinRows > 0 {
+		dest.AutoAnalyze.MinRows = src.AutoAnalyze.MinRows
+	}
+	if src.AutoAnalyze.TableIntervalHours > 0 {
+		dest.AutoAnalyze.TableIntervalHours = src.AutoAnalyze.TableIntervalHours
+	}
+	if src.AutoAnalyze.MaxPerHour > 0 {
+		dest.AutoAnalyze.MaxPerHour = src.AutoAnalyze.MaxPerHour
+	}
+	if src.AutoAnalyze.Window != "" {
+		dest.AutoAnalyze.Window = src.AutoAnalyze.Window
+	}
+
+	// Health probes are replaced as a whole, like databases
+	if len(src.HealthProbes) > 0 {
+		dest.HealthProbes = src.HealthProbes
+	}
+
+	// Scheduler - jobs are replaced as a whole, like databases
+	if src.Scheduler.Enabled {
+		dest.Scheduler.Enabled = true
+	}
+	if src.Scheduler.HistorySize > 0 {
+		dest.Scheduler.HistorySize = src.Scheduler.HistorySize
+	}
+	if len(src.Scheduler.Jobs) > 0 {
+		dest.Scheduler.Jobs = src.Scheduler.Jobs
+	}
+
+	// Notifications - endpoints are replaced as a whole, like databases
+	if src.Notifications.CooldownMinutes > 0 {
+		dest.Notifications.CooldownMinutes = src.Notifications.CooldownMinutes
+	}
+	if src.Notifications.TokenExpiryWarningDays > 0 {
+		dest.Notifications.TokenExpiryWarningDays = src.Notifications.TokenExpiryWarningDays
+	}
+	if len(src.Notifications.Endpoints) > 0 {
+		dest.Notifications.Endpoints = src.Notifications.Endpoints
+	}
+
+	// Tracing - headers are replaced as a whole
+	if src.Tracing.Enabled {
+		dest.Tracing.Enabled = true
+	}
+	if src.Tracing.Endpoint != "" {
+		dest.Tracing.Endpoint = src.Tracing.Endpoint
+	}
+	if len(src.Tracing.Headers) > 0 {
+		dest.Tracing.Headers = src.Tracing.Headers
+	}
+	if src.Tracing.ServiceName != "" {
+		dest.Tracing.ServiceName = src.Tracing.ServiceName
+	}
+	if src.Tracing.SampleRatio != 0 {
+		dest.Tracing.SampleRatio = src.Tracing.SampleRatio
+	}
+	if src.Tracing.OmitStatements {
+		dest.Tracing.OmitStatements = true
+	}
+
+	// Masking - rules are replaced as a whole, like databases
+	if src.Masking.Enabled || len(src.Masking.Rules) > 0 {
+		dest.Masking.Enabled = src.Masking.Enabled
+		if src.Masking.HashKey != "" {
+			dest.Masking.HashKey = src.Masking.HashKey
+		}
+		if len(src.Masking.Rules) > 0 {
+			dest.Masking.Rules = src.Masking.Rules
+		}
+	}
+
+	// PostgreSQL server logs
+	if src.PostgresLogs.Enabled {
+		dest.PostgresLogs.Enabled = true
+	}
+	if src.PostgresLogs.Source != "" {
+		dest.PostgresLogs.Source = src.PostgresLogs.Source
+	}
+	if src.PostgresLogs.Path != "" {
+		dest.PostgresLogs.Path = src.PostgresLogs.Path
+	}
+	if src.PostgresLogs.LogFDWServer != "" {
+		dest.PostgresLogs.LogFDWServer = src.PostgresLogs.LogFDWServer
+	}
+	if src.PostgresLogs.MaxLines > 0 {
+		dest.PostgresLogs.MaxLines = src.PostgresLogs.MaxLines
+	}
+
+	// Proxy
+	if src.Proxy.URL != "" {
+		dest.Proxy.URL = src.Proxy.URL
+	}
+	if src.Proxy.NoProxy != "" {
+		dest.Proxy.NoProxy = src.Proxy.NoProxy
+	}
+	if src.Proxy.Anthropic != "" {
+		dest.Proxy.Anthropic = src.Proxy.Anthropic
+	}
+	if src.Proxy.OpenAI != "" {
+		dest.Proxy.OpenAI = src.Proxy.OpenAI
+	}
+	if src.Proxy.Azure != "" {
+		dest.Proxy.Azure = src.Proxy.Azure
+	}
+	if src.Proxy.Bedrock != "" {
+		dest.Proxy.Bedrock = src.Proxy.Bedrock
+	}
+	if src.Proxy.Voyage != "" {
+		dest.Proxy.Voyage = src.Proxy.Voyage
+	}
+	if src.Proxy.Ollama != "" {
+		dest.Proxy.Ollama = src.Proxy.Ollama
+	}
+	if src.Proxy.Cohere != "" {
+		dest.Proxy.Cohere = src.Proxy.Cohere
+	}
+	if src.Proxy.Git != "" {
+		dest.Proxy.Git = src.Proxy.Git
+	}
+
+	// Builtins - merge individual settings (pointer fields preserve explicit false values)
+	// Tools
+	if src.Builtins.Tools.QueryDatabase != nil {
 		dest.Builtins.Tools.QueryDatabase = src.Builtins.Tools.QueryDatabase
 	}
 	if src.Builtins.Tools.GetSchemaInfo != nil {
@@ -560,10 +1860,109 @@ func mergeConfig(dest, src *Config) {
 	if src.Builtins.Tools.SearchKnowledgebase != nil {
 		dest.Builtins.Tools.SearchKnowledgebase = src.Builtins.Tools.SearchKnowledgebase
 	}
+	if src.Builtins.Tools.ListKBProjects != nil {
+		dest.Builtins.Tools.ListKBProjects = src.Builtins.Tools.ListKBProjects
+	}
+	if src.Builtins.Tools.CountRows != nil {
+		dest.Builtins.Tools.CountRows = src.Builtins.Tools.CountRows
+	}
+	if src.Builtins.Tools.ExplainSQL != nil {
+		dest.Builtins.Tools.ExplainSQL = src.Builtins.Tools.ExplainSQL
+	}
+	if src.Builtins.Tools.ValidateSQL != nil {
+		dest.Builtins.Tools.ValidateSQL = src.Builtins.Tools.ValidateSQL
+	}
+	if src.Builtins.Tools.ComparePlan != nil {
+		dest.Builtins.Tools.ComparePlan = src.Builtins.Tools.ComparePlan
+	}
+	if src.Builtins.Tools.PlanSchemaChange != nil {
+		dest.Builtins.Tools.PlanSchemaChange = src.Builtins.Tools.PlanSchemaChange
+	}
+	if src.Builtins.Tools.GetTableStats != nil {
+		dest.Builtins.Tools.GetTableStats = src.Builtins.Tools.GetTableStats
+	}
+	if src.Builtins.Tools.IndexAdvisor != nil {
+		dest.Builtins.Tools.IndexAdvisor = src.Builtins.Tools.IndexAdvisor
+	}
+	if src.Builtins.Tools.DatabaseHealthCheck != nil {
+		dest.Builtins.Tools.DatabaseHealthCheck = src.Builtins.Tools.DatabaseHealthCheck
+	}
+	if src.Builtins.Tools.LockAnalysis != nil {
+		dest.Builtins.Tools.LockAnalysis = src.Builtins.Tools.LockAnalysis
+	}
+	if src.Builtins.Tools.CheckCollations != nil {
+		dest.Builtins.Tools.CheckCollations = src.Builtins.Tools.CheckCollations
+	}
+	if src.Builtins.Tools.GenerateMigration != nil {
+		dest.Builtins.Tools.GenerateMigration = src.Builtins.Tools.GenerateMigration
+	}
+	if src.Builtins.Tools.ExportQueryResults != nil {
+		dest.Builtins.Tools.ExportQueryResults = src.Builtins.Tools.ExportQueryResults
+	}
+	if src.Builtins.Tools.HybridSearch != nil {
+		dest.Builtins.Tools.HybridSearch = src.Builtins.Tools.HybridSearch
+	}
+	if src.Builtins.Tools.GetContextUsage != nil {
+		dest.Builtins.Tools.GetContextUsage = src.Builtins.Tools.GetContextUsage
+	}
+	if src.Builtins.Tools.SnapshotSchema != nil {
+		dest.Builtins.Tools.SnapshotSchema = src.Builtins.Tools.SnapshotSchema
+	}
+	if src.Builtins.Tools.ListSchemaSnapshots != nil {
+		dest.Builtins.Tools.ListSchemaSnapshots = src.Builtins.Tools.ListSchemaSnapshots
+	}
+	if src.Builtins.Tools.ListDatabases != nil {
+		dest.Builtins.Tools.ListDatabases = src.Builtins.Tools.ListDatabases
+	}
+	if src.Builtins.Tools.RefreshSchemaCache != nil {
+		dest.Builtins.Tools.RefreshSchemaCache = src.Builtins.Tools.RefreshSchemaCache
+	}
+	if src.Builtins.Tools.FindRelevantTables != nil {
+		dest.Builtins.Tools.FindRelevantTables = src.Builtins.Tools.FindRelevantTables
+	}
+	if src.Builtins.Tools.GetQueryHistory != nil {
+		dest.Builtins.Tools.GetQueryHistory = src.Builtins.Tools.GetQueryHistory
+	}
+	if src.Builtins.Tools.ListJobs != nil {
+		dest.Builtins.Tools.ListJobs = src.Builtins.Tools.ListJobs
+	}
+	if src.Builtins.Tools.ExecuteScript != nil {
+		dest.Builtins.Tools.ExecuteScript = src.Builtins.Tools.ExecuteScript
+	}
+	if src.Builtins.Tools.ApplyMigration != nil {
+		dest.Builtins.Tools.ApplyMigration = src.Builtins.Tools.ApplyMigration
+	}
+	if src.Builtins.Tools.CreateVectorIndex != nil {
+		dest.Builtins.Tools.CreateVectorIndex = src.Builtins.Tools.CreateVectorIndex
+	}
+	if src.Builtins.Tools.InstallExtension != nil {
+		dest.Builtins.Tools.InstallExtension = src.Builtins.Tools.InstallExtension
+	}
+	if src.Builtins.Tools.SetupSemanticSearch != nil {
+		dest.Builtins.Tools.SetupSemanticSearch = src.Builtins.Tools.SetupSemanticSearch
+	}
+	if src.Builtins.Tools.SetupForeignServer != nil {
+		dest.Builtins.Tools.SetupForeignServer = src.Builtins.Tools.SetupForeignServer
+	}
+	if src.Builtins.Tools.AddDatabaseConnection != nil {
+		dest.Builtins.Tools.AddDatabaseConnection = src.Builtins.Tools.AddDatabaseConnection
+	}
+	if src.Builtins.Tools.RestoreSchemaSnapshot != nil {
+		dest.Builtins.Tools.RestoreSchemaSnapshot = src.Builtins.Tools.RestoreSchemaSnapshot
+	}
+	if src.Builtins.Tools.Transactions != nil {
+		dest.Builtins.Tools.Transactions = src.Builtins.Tools.Transactions
+	}
 	// Resources
 	if src.Builtins.Resources.SystemInfo != nil {
 		dest.Builtins.Resources.SystemInfo = src.Builtins.Resources.SystemInfo
 	}
+	if src.Builtins.Resources.Replication != nil {
+		dest.Builtins.Resources.Replication = src.Builtins.Resources.Replication
+	}
+	if src.Builtins.Resources.Extensions != nil {
+		dest.Builtins.Resources.Extensions = src.Builtins.Resources.Extensions
+	}
 	// Prompts
 	if src.Builtins.Prompts.ExploreDatabase != nil {
 		dest.Builtins.Prompts.ExploreDatabase = src.Builtins.Prompts.ExploreDatabase
@@ -605,6 +2004,32 @@ func setBoolFromEnv(dest *bool, key string) {
 	}
 }
 
+// setBoolPtrFromEnv sets an optional boolean config value from an
+// environment variable if it exists
+func setBoolPtrFromEnv(dest **bool, key string) {
+	if os.Getenv(key) != "" {
+		var val bool
+		setBoolFromEnv(&val, key)
+		*dest = &val
+	}
+}
+
+// setStringSliceFromEnv sets a list config value from a comma-separated
+// environment variable if it exists
+func setStringSliceFromEnv(dest *[]string, key string) {
+	val := os.Getenv(key)
+	if val == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dest = items
+}
+
 // setIntFromEnv sets an integer config value from an environment variable if it exists
 func setIntFromEnv(dest *int, key string) {
 	if val := os.Getenv(key); val != "" {
@@ -616,18 +2041,97 @@ func setIntFromEnv(dest *int, key string) {
 	}
 }
 
+// parseLLMRoutes parses a comma-separated list of provider:model routes
+func parseLLMRoutes(val string) []LLMRouteConfig {
+	var routes []LLMRouteConfig
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		provider, model, _ := strings.Cut(item, ":")
+		routes = append(routes, LLMRouteConfig{Provider: strings.TrimSpace(provider), Model: strings.TrimSpace(model)})
+	}
+	return routes
+}
+
+// parseHeaderList parses a comma-separated list of name=value headers, as
+// in OTEL_EXPORTER_OTLP_HEADERS; values may be URL-encoded
+func parseHeaderList(val string) map[string]string {
+	headers := make(map[string]string)
+	for _, item := range strings.Split(val, ",") {
+		name, value, found := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(strings.TrimSpace(value)); err == nil {
+			value = unescaped
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// applyJSONEnvironmentVariables applies the environment variables that set
+// lists and sections of the configuration as JSON, with the configuration
+// file's keys. The lists replace those of the file; PGEDGE_BUILTINS only
+// overrides the toggles it sets.
+func applyJSONEnvironmentVariables(cfg *Config) error {
+	vars := []struct {
+		key  string
+		dest interface{}
+	}{
+		{"PGEDGE_DATABASES", &cfg.Databases},
+		{"PGEDGE_BUILTINS", &cfg.Builtins},
+		{"PGEDGE_MASKING_RULES", &cfg.Masking.Rules},
+		{"PGEDGE_HEALTH_PROBES", &cfg.HealthProbes},
+		{"PGEDGE_SCHEDULER_JOBS", &cfg.Scheduler.Jobs},
+		{"PGEDGE_NOTIFICATIONS_ENDPOINTS", &cfg.Notifications.Endpoints},
+	}
+	for _, v := range vars {
+		val := os.Getenv(v.key)
+		if strings.TrimSpace(val) == "" {
+			continue
+		}
+		// JSON is also YAML, so the yaml tags name the keys
+		if err := yaml.Unmarshal([]byte(val), v.dest); err != nil {
+			return fmt.Errorf("invalid %s: %w", v.key, err)
+		}
+	}
+	return nil
+}
+
 // applyEnvironmentVariables overrides config with environment variables if they exist
 // All environment variables use the PGEDGE_ prefix to avoid collisions
 func applyEnvironmentVariables(cfg *Config) {
 	// HTTP
 	setBoolFromEnv(&cfg.HTTP.Enabled, "PGEDGE_HTTP_ENABLED")
 	setStringFromEnv(&cfg.HTTP.Address, "PGEDGE_HTTP_ADDRESS")
+	setBoolFromEnv(&cfg.HTTP.SessionAffinity.Enabled, "PGEDGE_HTTP_SESSION_AFFINITY_ENABLED")
+	setStringFromEnv(&cfg.HTTP.SessionAffinity.ReplicaID, "PGEDGE_HTTP_REPLICA_ID")
+	setStringFromEnv(&cfg.HTTP.SessionAffinity.CookieName, "PGEDGE_HTTP_AFFINITY_COOKIE_NAME")
+	setStringSliceFromEnv(&cfg.HTTP.CORS.AllowedOrigins, "PGEDGE_HTTP_CORS_ALLOWED_ORIGINS")
+	setStringSliceFromEnv(&cfg.HTTP.CORS.AllowedHeaders, "PGEDGE_HTTP_CORS_ALLOWED_HEADERS")
+	setBoolFromEnv(&cfg.HTTP.CORS.AllowCredentials, "PGEDGE_HTTP_CORS_ALLOW_CREDENTIALS")
+	setIntFromEnv(&cfg.HTTP.CORS.MaxAgeSeconds, "PGEDGE_HTTP_CORS_MAX_AGE_SECONDS")
+	setIntFromEnv(&cfg.HTTP.Limits.MaxHeaderBytes, "PGEDGE_HTTP_MAX_HEADER_BYTES")
+	setIntFromEnv(&cfg.HTTP.Limits.MaxBodyMB, "PGEDGE_HTTP_MAX_BODY_MB")
+	setIntFromEnv(&cfg.HTTP.Limits.ReadTimeoutSeconds, "PGEDGE_HTTP_READ_TIMEOUT_SECONDS")
+	setIntFromEnv(&cfg.HTTP.Limits.WriteTimeoutSeconds, "PGEDGE_HTTP_WRITE_TIMEOUT_SECONDS")
+	setIntFromEnv(&cfg.HTTP.Limits.IdleTimeoutSeconds, "PGEDGE_HTTP_IDLE_TIMEOUT_SECONDS")
 
 	// TLS
 	setBoolFromEnv(&cfg.HTTP.TLS.Enabled, "PGEDGE_TLS_ENABLED")
 	setStringFromEnv(&cfg.HTTP.TLS.CertFile, "PGEDGE_TLS_CERT_FILE")
 	setStringFromEnv(&cfg.HTTP.TLS.KeyFile, "PGEDGE_TLS_KEY_FILE")
 	setStringFromEnv(&cfg.HTTP.TLS.ChainFile, "PGEDGE_TLS_CHAIN_FILE")
+	setBoolFromEnv(&cfg.HTTP.TLS.ACME.Enabled, "PGEDGE_TLS_ACME_ENABLED")
+	setStringSliceFromEnv(&cfg.HTTP.TLS.ACME.Domains, "PGEDGE_TLS_ACME_DOMAINS")
+	setStringFromEnv(&cfg.HTTP.TLS.ACME.CacheDir, "PGEDGE_TLS_ACME_CACHE_DIR")
+	setStringFromEnv(&cfg.HTTP.TLS.ACME.DirectoryURL, "PGEDGE_TLS_ACME_DIRECTORY_URL")
+	setStringFromEnv(&cfg.HTTP.TLS.ACME.Email, "PGEDGE_TLS_ACME_EMAIL")
+	setStringFromEnv(&cfg.HTTP.TLS.ACME.HTTPChallengeAddress, "PGEDGE_TLS_ACME_HTTP_CHALLENGE_ADDRESS")
 
 	// Auth
 	setBoolFromEnv(&cfg.HTTP.Auth.Enabled, "PGEDGE_AUTH_ENABLED")
@@ -636,6 +2140,9 @@ func applyEnvironmentVariables(cfg *Config) {
 	setIntFromEnv(&cfg.HTTP.Auth.MaxFailedAttemptsBeforeLockout, "PGEDGE_AUTH_MAX_FAILED_ATTEMPTS_BEFORE_LOCKOUT")
 	setIntFromEnv(&cfg.HTTP.Auth.RateLimitWindowMinutes, "PGEDGE_AUTH_RATE_LIMIT_WINDOW_MINUTES")
 	setIntFromEnv(&cfg.HTTP.Auth.RateLimitMaxAttempts, "PGEDGE_AUTH_RATE_LIMIT_MAX_ATTEMPTS")
+	setIntFromEnv(&cfg.HTTP.Auth.Quotas.ToolCallsPerMinute, "PGEDGE_AUTH_QUOTA_TOOL_CALLS_PER_MINUTE")
+	setIntFromEnv(&cfg.HTTP.Auth.Quotas.RowsPerHour, "PGEDGE_AUTH_QUOTA_ROWS_PER_HOUR")
+	setIntFromEnv(&cfg.HTTP.Auth.Quotas.MaxConcurrentQueries, "PGEDGE_AUTH_QUOTA_MAX_CONCURRENT_QUERIES")
 
 	// Database environment variables apply to the first database in the list
 	// If no databases configured yet, create a default one from env vars
@@ -663,6 +2170,8 @@ func applyEnvironmentVariables(cfg *Config) {
 		setStringFromEnv(&cfg.Databases[0].User, "PGEDGE_DB_USER")
 		setStringFromEnv(&cfg.Databases[0].Password, "PGEDGE_DB_PASSWORD")
 		setStringFromEnv(&cfg.Databases[0].SSLMode, "PGEDGE_DB_SSLMODE")
+		setStringFromEnv(&cfg.Databases[0].Environment, "PGEDGE_DB_ENVIRONMENT")
+		setBoolPtrFromEnv(&cfg.Databases[0].AllowWrites, "PGEDGE_DB_ALLOW_WRITES")
 
 		// Also support standard PostgreSQL environment variables for convenience
 		if cfg.Databases[0].Host == "localhost" {
@@ -694,6 +2203,8 @@ func applyEnvironmentVariables(cfg *Config) {
 	setStringFromEnvWithFallback(&cfg.Embedding.VoyageAPIKey, "PGEDGE_VOYAGE_API_KEY", "VOYAGE_API_KEY")
 	setStringFromEnvWithFallback(&cfg.Embedding.OpenAIAPIKey, "PGEDGE_OPENAI_API_KEY", "OPENAI_API_KEY")
 	// 2. If env vars not set and api_key_file is specified, load from file
+	setStringFromEnv(&cfg.Embedding.VoyageAPIKeyFile, "PGEDGE_VOYAGE_API_KEY_FILE")
+	setStringFromEnv(&cfg.Embedding.OpenAIAPIKeyFile, "PGEDGE_OPENAI_API_KEY_FILE")
 	if cfg.Embedding.VoyageAPIKey == "" && cfg.Embedding.VoyageAPIKeyFile != "" {
 		if key, err := readAPIKeyFromFile(cfg.Embedding.VoyageAPIKeyFile); err == nil && key != "" {
 			cfg.Embedding.VoyageAPIKey = key
@@ -708,6 +2219,14 @@ func applyEnvironmentVariables(cfg *Config) {
 	}
 	// 3. Direct config value (if set) is already in cfg.Embedding.VoyageAPIKey/OpenAIAPIKey from mergeConfig
 	setStringFromEnv(&cfg.Embedding.OllamaURL, "PGEDGE_OLLAMA_URL")
+	if _, ok := os.LookupEnv("PGEDGE_EMBEDDING_CACHE_ENABLED"); ok {
+		enabled := cfg.Embedding.Cache.IsEnabled()
+		setBoolFromEnv(&enabled, "PGEDGE_EMBEDDING_CACHE_ENABLED")
+		cfg.Embedding.Cache.Enabled = &enabled
+	}
+	setIntFromEnv(&cfg.Embedding.Cache.MaxEntries, "PGEDGE_EMBEDDING_CACHE_MAX_ENTRIES")
+	setBoolFromEnv(&cfg.Embedding.Cache.Persist, "PGEDGE_EMBEDDING_CACHE_PERSIST")
+	setStringFromEnv(&cfg.Embedding.Cache.Path, "PGEDGE_EMBEDDING_CACHE_PATH")
 
 	// LLM
 	setBoolFromEnv(&cfg.LLM.Enabled, "PGEDGE_LLM_ENABLED")
@@ -717,7 +2236,11 @@ func applyEnvironmentVariables(cfg *Config) {
 	// 1. Try environment variables first (PGEDGE_ prefixed, then standard)
 	setStringFromEnvWithFallback(&cfg.LLM.AnthropicAPIKey, "PGEDGE_ANTHROPIC_API_KEY", "ANTHROPIC_API_KEY")
 	setStringFromEnvWithFallback(&cfg.LLM.OpenAIAPIKey, "PGEDGE_OPENAI_API_KEY", "OPENAI_API_KEY")
+	setStringFromEnvWithFallback(&cfg.LLM.AzureAPIKey, "PGEDGE_AZURE_OPENAI_API_KEY", "AZURE_OPENAI_API_KEY")
 	// 2. If env vars not set and api_key_file is specified, load from file
+	setStringFromEnv(&cfg.LLM.AnthropicAPIKeyFile, "PGEDGE_ANTHROPIC_API_KEY_FILE")
+	setStringFromEnv(&cfg.LLM.OpenAIAPIKeyFile, "PGEDGE_OPENAI_API_KEY_FILE")
+	setStringFromEnv(&cfg.LLM.AzureAPIKeyFile, "PGEDGE_AZURE_OPENAI_API_KEY_FILE")
 	if cfg.LLM.AnthropicAPIKey == "" && cfg.LLM.AnthropicAPIKeyFile != "" {
 		if key, err := readAPIKeyFromFile(cfg.LLM.AnthropicAPIKeyFile); err == nil && key != "" {
 			cfg.LLM.AnthropicAPIKey = key
@@ -730,9 +2253,29 @@ func applyEnvironmentVariables(cfg *Config) {
 		}
 		// Note: errors are silently ignored - file may not exist and that's ok
 	}
+	if cfg.LLM.AzureAPIKey == "" && cfg.LLM.AzureAPIKeyFile != "" {
+		if key, err := readAPIKeyFromFile(cfg.LLM.AzureAPIKeyFile); err == nil && key != "" {
+			cfg.LLM.AzureAPIKey = key
+		}
+		// Note: errors are silently ignored - file may not exist and that's ok
+	}
 	// 3. Direct config value (if set) is already in cfg.LLM.AnthropicAPIKey/OpenAIAPIKey from mergeConfig
 	setStringFromEnv(&cfg.LLM.OllamaURL, "PGEDGE_OLLAMA_URL")
+	setStringFromEnvWithFallback(&cfg.LLM.AzureEndpoint, "PGEDGE_AZURE_OPENAI_ENDPOINT", "AZURE_OPENAI_ENDPOINT")
+	setStringFromEnv(&cfg.LLM.AzureAPIVersion, "PGEDGE_AZURE_OPENAI_API_VERSION")
+	setStringFromEnv(&cfg.LLM.BedrockRegion, "PGEDGE_BEDROCK_REGION")
+	if cfg.LLM.BedrockRegion == "" {
+		cfg.LLM.BedrockRegion = sigv4.RegionFromEnv()
+	}
+	setStringFromEnv(&cfg.LLM.BedrockEndpoint, "PGEDGE_BEDROCK_ENDPOINT")
 	setIntFromEnv(&cfg.LLM.MaxTokens, "PGEDGE_LLM_MAX_TOKENS")
+	setBoolFromEnv(&cfg.LLM.CompactionSummaries, "PGEDGE_LLM_COMPACTION_SUMMARIES")
+	setIntFromEnv(&cfg.LLM.ContextWindow, "PGEDGE_LLM_CONTEXT_WINDOW")
+	// Fallbacks are a comma-separated list of provider:model
+	if val := os.Getenv("PGEDGE_LLM_FALLBACK"); val != "" {
+		cfg.LLM.Fallback = parseLLMRoutes(val)
+	}
+	setIntFromEnv(&cfg.LLM.FailoverCooldownSeconds, "PGEDGE_LLM_FAILOVER_COOLDOWN_SECONDS")
 	// Temperature is a float, but we'll handle it specially
 	if val := os.Getenv("PGEDGE_LLM_TEMPERATURE"); val != "" {
 		var floatVal float64
@@ -744,7 +2287,11 @@ func applyEnvironmentVariables(cfg *Config) {
 
 	// Knowledgebase
 	setBoolFromEnv(&cfg.Knowledgebase.Enabled, "PGEDGE_KB_ENABLED")
+	setStringFromEnv(&cfg.Knowledgebase.Backend, "PGEDGE_KB_BACKEND")
 	setStringFromEnv(&cfg.Knowledgebase.DatabasePath, "PGEDGE_KB_DATABASE_PATH")
+	setStringFromEnv(&cfg.Knowledgebase.ConnectionString, "PGEDGE_KB_CONNECTION_STRING")
+	setStringFromEnv(&cfg.Knowledgebase.Schema, "PGEDGE_KB_SCHEMA")
+	setIntFromEnv(&cfg.Knowledgebase.RetryIntervalSeconds, "PGEDGE_KB_RETRY_INTERVAL_SECONDS")
 	setStringFromEnv(&cfg.Knowledgebase.EmbeddingProvider, "PGEDGE_KB_EMBEDDING_PROVIDER")
 	setStringFromEnv(&cfg.Knowledgebase.EmbeddingModel, "PGEDGE_KB_EMBEDDING_MODEL")
 	// API key loading priority: env vars > api_key_file > direct config value
@@ -752,6 +2299,8 @@ func applyEnvironmentVariables(cfg *Config) {
 	setStringFromEnvWithFallback(&cfg.Knowledgebase.EmbeddingVoyageAPIKey, "PGEDGE_KB_VOYAGE_API_KEY", "VOYAGE_API_KEY")
 	setStringFromEnvWithFallback(&cfg.Knowledgebase.EmbeddingOpenAIAPIKey, "PGEDGE_KB_OPENAI_API_KEY", "OPENAI_API_KEY")
 	// 2. If env vars not set and api_key_file is specified, load from file
+	setStringFromEnv(&cfg.Knowledgebase.EmbeddingVoyageAPIKeyFile, "PGEDGE_KB_VOYAGE_API_KEY_FILE")
+	setStringFromEnv(&cfg.Knowledgebase.EmbeddingOpenAIAPIKeyFile, "PGEDGE_KB_OPENAI_API_KEY_FILE")
 	if cfg.Knowledgebase.EmbeddingVoyageAPIKey == "" && cfg.Knowledgebase.EmbeddingVoyageAPIKeyFile != "" {
 		if key, err := readAPIKeyFromFile(cfg.Knowledgebase.EmbeddingVoyageAPIKeyFile); err == nil && key != "" {
 			cfg.Knowledgebase.EmbeddingVoyageAPIKey = key
@@ -767,17 +2316,158 @@ func applyEnvironmentVariables(cfg *Config) {
 	// 3. Direct config value (if set) is already in cfg.Knowledgebase.EmbeddingVoyageAPIKey/EmbeddingOpenAIAPIKey from mergeConfig
 	setStringFromEnv(&cfg.Knowledgebase.EmbeddingOllamaURL, "PGEDGE_KB_OLLAMA_URL")
 
+	// Knowledgebase reranking; the API key falls back to the provider's
+	// standard environment variable, or for Voyage AI the knowledgebase's key
+	rerank := &cfg.Knowledgebase.Rerank
+	setBoolFromEnv(&rerank.Enabled, "PGEDGE_KB_RERANK_ENABLED")
+	setStringFromEnv(&rerank.Provider, "PGEDGE_KB_RERANK_PROVIDER")
+	setStringFromEnv(&rerank.Model, "PGEDGE_KB_RERANK_MODEL")
+	setIntFromEnv(&rerank.Candidates, "PGEDGE_KB_RERANK_CANDIDATES")
+	setIntFromEnv(&rerank.TimeoutSeconds, "PGEDGE_KB_RERANK_TIMEOUT_SECONDS")
+	setStringFromEnv(&rerank.OllamaURL, "PGEDGE_KB_RERANK_OLLAMA_URL")
+	setStringFromEnv(&rerank.APIKey, "PGEDGE_KB_RERANK_API_KEY")
+	setStringFromEnv(&rerank.APIKeyFile, "PGEDGE_KB_RERANK_API_KEY_FILE")
+	if rerank.APIKey == "" && rerank.APIKeyFile != "" {
+		if key, err := readAPIKeyFromFile(rerank.APIKeyFile); err == nil && key != "" {
+			rerank.APIKey = key
+		}
+	}
+	if rerank.APIKey == "" {
+		switch rerank.Provider {
+		case "cohere":
+			setStringFromEnv(&rerank.APIKey, "COHERE_API_KEY")
+		case "voyage":
+			rerank.APIKey = cfg.Knowledgebase.EmbeddingVoyageAPIKey
+		}
+	}
+	if rerank.OllamaURL == "" {
+		rerank.OllamaURL = cfg.Knowledgebase.EmbeddingOllamaURL
+	}
+
+	// Masking
+	setBoolFromEnv(&cfg.Masking.Enabled, "PGEDGE_MASKING_ENABLED")
+	setStringFromEnv(&cfg.Masking.HashKey, "PGEDGE_MASKING_HASH_KEY")
+
+	// PostgreSQL server logs
+	setBoolFromEnv(&cfg.PostgresLogs.Enabled, "PGEDGE_POSTGRES_LOGS_ENABLED")
+	setStringFromEnv(&cfg.PostgresLogs.Source, "PGEDGE_POSTGRES_LOGS_SOURCE")
+	setStringFromEnv(&cfg.PostgresLogs.Path, "PGEDGE_POSTGRES_LOGS_PATH")
+	setStringFromEnv(&cfg.PostgresLogs.LogFDWServer, "PGEDGE_POSTGRES_LOGS_LOG_FDW_SERVER")
+	setIntFromEnv(&cfg.PostgresLogs.MaxLines, "PGEDGE_POSTGRES_LOGS_MAX_LINES")
+
+	// Proxy (standard HTTPS_PROXY/NO_PROXY are honored at request time when unset)
+	setStringFromEnv(&cfg.Proxy.URL, "PGEDGE_PROXY_URL")
+	setStringFromEnv(&cfg.Proxy.NoProxy, "PGEDGE_NO_PROXY")
+	setStringFromEnv(&cfg.Proxy.Anthropic, "PGEDGE_PROXY_ANTHROPIC")
+	setStringFromEnv(&cfg.Proxy.OpenAI, "PGEDGE_PROXY_OPENAI")
+	setStringFromEnv(&cfg.Proxy.Azure, "PGEDGE_PROXY_AZURE")
+	setStringFromEnv(&cfg.Proxy.Bedrock, "PGEDGE_PROXY_BEDROCK")
+	setStringFromEnv(&cfg.Proxy.Voyage, "PGEDGE_PROXY_VOYAGE")
+	setStringFromEnv(&cfg.Proxy.Ollama, "PGEDGE_PROXY_OLLAMA")
+	setStringFromEnv(&cfg.Proxy.Cohere, "PGEDGE_PROXY_COHERE")
+	setStringFromEnv(&cfg.Proxy.Git, "PGEDGE_PROXY_GIT")
+
 	// Secret file
 	setStringFromEnv(&cfg.SecretFile, "PGEDGE_SECRET_FILE")
 
 	// Custom definitions path
 	setStringFromEnv(&cfg.CustomDefinitionsPath, "PGEDGE_CUSTOM_DEFINITIONS_PATH")
 
+	// Offline mode
+	setBoolFromEnv(&cfg.Offline, "PGEDGE_OFFLINE")
+
+	// Shutdown drain timeout
+	setIntFromEnv(&cfg.ShutdownTimeoutSeconds, "PGEDGE_SHUTDOWN_TIMEOUT_SECONDS")
+	setIntFromEnv(&cfg.ResourcePollIntervalSeconds, "PGEDGE_RESOURCE_POLL_INTERVAL_SECONDS")
+	setIntFromEnv(&cfg.ClientIdleTimeoutSeconds, "PGEDGE_CLIENT_IDLE_TIMEOUT_SECONDS")
+	setIntFromEnv(&cfg.MetadataRefreshIntervalSeconds, "PGEDGE_METADATA_REFRESH_INTERVAL_SECONDS")
+
 	// Data directory
 	setStringFromEnv(&cfg.DataDir, "PGEDGE_DATA_DIR")
 
-	// Note: Builtins (tools, resources, prompts) are only configurable via
-	// config file, not environment variables
+	// Conversation store
+	if _, ok := os.LookupEnv("PGEDGE_CONVERSATIONS_ENCRYPT"); ok {
+		encrypt := cfg.Conversations.EncryptionEnabled()
+		setBoolFromEnv(&encrypt, "PGEDGE_CONVERSATIONS_ENCRYPT")
+		cfg.Conversations.Encrypt = &encrypt
+	}
+	setStringFromEnv(&cfg.Conversations.Backend, "PGEDGE_CONVERSATIONS_BACKEND")
+	setStringFromEnv(&cfg.Conversations.Database, "PGEDGE_CONVERSATIONS_DATABASE")
+	setBoolFromEnv(&cfg.Conversations.PerUserKeys, "PGEDGE_CONVERSATIONS_PER_USER_KEYS")
+	setIntFromEnv(&cfg.Conversations.MaxAgeDays, "PGEDGE_CONVERSATIONS_MAX_AGE_DAYS")
+	setIntFromEnv(&cfg.Conversations.MaxPerUser, "PGEDGE_CONVERSATIONS_MAX_PER_USER")
+	setIntFromEnv(&cfg.Conversations.DeletedRetentionDays, "PGEDGE_CONVERSATIONS_DELETED_RETENTION_DAYS")
+	setIntFromEnv(&cfg.Conversations.PurgeIntervalMinutes, "PGEDGE_CONVERSATIONS_PURGE_INTERVAL_MINUTES")
+	setIntFromEnv(&cfg.Exports.MaxRows, "PGEDGE_EXPORTS_MAX_ROWS")
+	setIntFromEnv(&cfg.Exports.MaxSizeMB, "PGEDGE_EXPORTS_MAX_SIZE_MB")
+	setIntFromEnv(&cfg.Exports.RetentionHours, "PGEDGE_EXPORTS_RETENTION_HOURS")
+	setBoolFromEnv(&cfg.Uploads.Enabled, "PGEDGE_UPLOADS_ENABLED")
+	setIntFromEnv(&cfg.Uploads.MaxSizeMB, "PGEDGE_UPLOADS_MAX_SIZE_MB")
+	setStringSliceFromEnv(&cfg.Uploads.AllowedTypes, "PGEDGE_UPLOADS_ALLOWED_TYPES")
+	setIntFromEnv(&cfg.Uploads.RetentionHours, "PGEDGE_UPLOADS_RETENTION_HOURS")
+	setIntFromEnv(&cfg.Snapshots.MaxSizeMB, "PGEDGE_SNAPSHOTS_MAX_SIZE_MB")
+	setStringSliceFromEnv(&cfg.Extensions.Allowed, "PGEDGE_EXTENSIONS_ALLOWED")
+	if _, ok := os.LookupEnv("PGEDGE_ARTIFACTS_CACHE"); ok {
+		cache := cfg.Artifacts.CacheEnabled()
+		setBoolFromEnv(&cache, "PGEDGE_ARTIFACTS_CACHE")
+		cfg.Artifacts.Cache = &cache
+	}
+	setIntFromEnv(&cfg.Artifacts.MaxAgeMinutes, "PGEDGE_ARTIFACTS_MAX_AGE_MINUTES")
+	setIntFromEnv(&cfg.Artifacts.MaxSizeMB, "PGEDGE_ARTIFACTS_MAX_SIZE_MB")
+	setIntFromEnv(&cfg.QueryHistory.Size, "PGEDGE_QUERY_HISTORY_SIZE")
+	if _, ok := os.LookupEnv("PGEDGE_QUERY_HISTORY_CACHE"); ok {
+		cache := cfg.QueryHistory.CacheEnabled()
+		setBoolFromEnv(&cache, "PGEDGE_QUERY_HISTORY_CACHE")
+		cfg.QueryHistory.Cache = &cache
+	}
+	setIntFromEnv(&cfg.QueryHistory.CacheTTLSeconds, "PGEDGE_QUERY_HISTORY_CACHE_TTL_SECONDS")
+	setIntFromEnv(&cfg.QueryGuard.MaxRows, "PGEDGE_QUERY_GUARD_MAX_ROWS")
+	setBoolFromEnv(&cfg.QueryGuard.Explain, "PGEDGE_QUERY_GUARD_EXPLAIN")
+	setIntFromEnv(&cfg.QueryGuard.MaxCost, "PGEDGE_QUERY_GUARD_MAX_COST")
+	setIntFromEnv(&cfg.QueryGuard.MaxEstimatedRows, "PGEDGE_QUERY_GUARD_MAX_ESTIMATED_ROWS")
+
+	// Metrics export
+	setBoolFromEnv(&cfg.Metrics.Export.Enabled, "PGEDGE_METRICS_EXPORT_ENABLED")
+	setStringFromEnv(&cfg.Metrics.Export.Database, "PGEDGE_METRICS_EXPORT_DATABASE")
+	setIntFromEnv(&cfg.Metrics.Export.IntervalSeconds, "PGEDGE_METRICS_EXPORT_INTERVAL_SECONDS")
+	setIntFromEnv(&cfg.Metrics.Export.RetentionDays, "PGEDGE_METRICS_EXPORT_RETENTION_DAYS")
+
+	// Background ANALYZE
+	setBoolFromEnv(&cfg.AutoAnalyze.Enabled, "PGEDGE_AUTO_ANALYZE_ENABLED")
+	setIntFromEnv(&cfg.AutoAnalyze.EstimateRatio, "PGEDGE_AUTO_ANALYZE_ESTIMATE_RATIO")
+	setIntFromEnv(&cfg.AutoAnalyze.MinRows, "PGEDGE_AUTO_ANALYZE_MIN_ROWS")
+	setIntFromEnv(&cfg.AutoAnalyze.TableIntervalHours, "PGEDGE_AUTO_ANALYZE_TABLE_INTERVAL_HOURS")
+	setIntFromEnv(&cfg.AutoAnalyze.MaxPerHour, "PGEDGE_AUTO_ANALYZE_MAX_PER_HOUR")
+	setStringFromEnv(&cfg.AutoAnalyze.Window, "PGEDGE_AUTO_ANALYZE_WINDOW")
+
+	// Scheduler
+	setBoolFromEnv(&cfg.Scheduler.Enabled, "PGEDGE_SCHEDULER_ENABLED")
+	setIntFromEnv(&cfg.Scheduler.HistorySize, "PGEDGE_SCHEDULER_HISTORY_SIZE")
+
+	// Notifications
+	setIntFromEnv(&cfg.Notifications.CooldownMinutes, "PGEDGE_NOTIFICATIONS_COOLDOWN_MINUTES")
+	setIntFromEnv(&cfg.Notifications.TokenExpiryWarningDays, "PGEDGE_NOTIFICATIONS_TOKEN_EXPIRY_WARNING_DAYS")
+
+	// Tracing - the standard OpenTelemetry variables are used when the
+	// PGEDGE_ ones are not set
+	setBoolFromEnv(&cfg.Tracing.Enabled, "PGEDGE_TRACING_ENABLED")
+	setStringFromEnvWithFallback(&cfg.Tracing.Endpoint, "PGEDGE_TRACING_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	for _, key := range []string{"PGEDGE_TRACING_HEADERS", "OTEL_EXPORTER_OTLP_HEADERS"} {
+		if val := os.Getenv(key); val != "" {
+			cfg.Tracing.Headers = parseHeaderList(val)
+			break
+		}
+	}
+	setStringFromEnvWithFallback(&cfg.Tracing.ServiceName, "PGEDGE_TRACING_SERVICE_NAME", "OTEL_SERVICE_NAME")
+	if val := os.Getenv("PGEDGE_TRACING_SAMPLE_RATIO"); val != "" {
+		if ratio, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.Tracing.SampleRatio = ratio
+		}
+	}
+	setBoolFromEnv(&cfg.Tracing.OmitStatements, "PGEDGE_TRACING_OMIT_STATEMENTS")
+
+	// Note: Lists and sections such as databases and builtins are set from
+	// JSON by applyJSONEnvironmentVariables
 }
 
 // applyCLIFlags overrides config with CLI flags if they were explicitly set
@@ -855,8 +2545,20 @@ func applyCLIFlags(cfg *Config, flags CLIFlags) {
 	if flags.SecretFileSet {
 		cfg.SecretFile = flags.SecretFile
 	}
+
+	// Offline mode
+	if flags.OfflineSet {
+		cfg.Offline = flags.Offline
+	}
 }
 
+// replicaIDPattern matches valid session affinity replica IDs, also used
+// for health probe names
+var replicaIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validExtensionName matches the extension names of extensions.allowed
+var validExtensionName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,63}$`)
+
 // validateConfig checks if the configuration is valid
 func validateConfig(cfg *Config) error {
 	// TLS requires HTTP to be enabled
@@ -864,8 +2566,52 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("TLS requires HTTP mode to be enabled")
 	}
 
+	// Replica IDs are used in session IDs and cookies
+	if id := cfg.HTTP.SessionAffinity.ReplicaID; id != "" && !replicaIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid http.session_affinity.replica_id %q: use 1 to 64 letters, digits, '-' or '_'", id)
+	}
+
+	// Any origin may not be combined with credentials, since the server
+	// echoes the request's origin
+	for _, origin := range cfg.HTTP.CORS.AllowedOrigins {
+		if origin == "*" && cfg.HTTP.CORS.AllowCredentials {
+			return fmt.Errorf("http.cors.allowed_origins cannot include \"*\" when allow_credentials is true")
+		}
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return fmt.Errorf("invalid http.cors origin %q: use a scheme and host such as https://gui.example.com", origin)
+		}
+	}
+	if cfg.HTTP.CORS.MaxAgeSeconds < 0 {
+		return fmt.Errorf("http.cors.max_age_seconds must be zero or positive")
+	}
+
+	limits := cfg.HTTP.Limits
+	if limits.MaxHeaderBytes < 0 || limits.MaxBodyMB < 0 || limits.ReadTimeoutSeconds < 0 ||
+		limits.WriteTimeoutSeconds < 0 || limits.IdleTimeoutSeconds < 0 {
+		return fmt.Errorf("http.limits values must be zero or positive")
+	}
+
+	// ACME replaces the certificate files, and needs names to request
+	// certificates for
+	if acme := cfg.HTTP.TLS.ACME; acme.Enabled {
+		if !cfg.HTTP.TLS.Enabled {
+			return fmt.Errorf("http.tls.acme requires http.tls.enabled")
+		}
+		if len(acme.Domains) == 0 {
+			return fmt.Errorf("http.tls.acme.domains is required when ACME is enabled")
+		}
+		for _, domain := range acme.Domains {
+			if domain == "" || strings.ContainsAny(domain, "/:*") {
+				return fmt.Errorf("invalid http.tls.acme domain %q: use a host name such as mcp.example.com", domain)
+			}
+		}
+		if acme.DirectoryURL != "" && !strings.HasPrefix(acme.DirectoryURL, "https://") {
+			return fmt.Errorf("http.tls.acme.directory_url must be an https:// URL")
+		}
+	}
+
 	// If HTTPS is enabled, cert and key are required
-	if cfg.HTTP.TLS.Enabled {
+	if cfg.HTTP.TLS.Enabled && !cfg.HTTP.TLS.ACME.Enabled {
 		if cfg.HTTP.TLS.CertFile == "" {
 			return fmt.Errorf("TLS certificate file is required when HTTPS is enabled")
 		}
@@ -881,6 +2627,129 @@ func validateConfig(cfg *Config) error {
 		}
 	}
 
+	// Quota limits cannot be negative
+	quotas := cfg.HTTP.Auth.Quotas
+	if quotas.ToolCallsPerMinute < 0 || quotas.RowsPerHour < 0 || quotas.MaxConcurrentQueries < 0 {
+		return fmt.Errorf("auth quotas must be zero (unlimited) or positive")
+	}
+
+	if cfg.ShutdownTimeoutSeconds < 0 {
+		return fmt.Errorf("shutdown_timeout_seconds must be zero or positive")
+	}
+
+	if cfg.ResourcePollIntervalSeconds < 0 {
+		return fmt.Errorf("resource_poll_interval_seconds must be zero or positive")
+	}
+
+	if cfg.ClientIdleTimeoutSeconds < 0 {
+		return fmt.Errorf("client_idle_timeout_seconds must be zero or positive")
+	}
+
+	if cfg.MetadataRefreshIntervalSeconds < 0 {
+		return fmt.Errorf("metadata_refresh_interval_seconds must be zero or positive")
+	}
+
+	conv := cfg.Conversations
+	if conv.MaxAgeDays < 0 || conv.MaxPerUser < 0 || conv.DeletedRetentionDays < 0 || conv.PurgeIntervalMinutes < 0 {
+		return fmt.Errorf("conversations max_age_days, max_per_user, deleted_retention_days and purge_interval_minutes must be zero or positive")
+	}
+	switch conv.Backend {
+	case "", ConversationBackendSQLite, ConversationBackendPostgres:
+	default:
+		return fmt.Errorf("conversations.backend must be %q or %q", ConversationBackendSQLite, ConversationBackendPostgres)
+	}
+	if conv.UsesPostgres() && conv.Database != "" && len(cfg.Databases) > 0 && cfg.GetDatabaseByName(conv.Database) == nil {
+		return fmt.Errorf("conversations database %q is not a configured database", conv.Database)
+	}
+
+	if cfg.Exports.MaxRows < 0 || cfg.Exports.MaxSizeMB < 0 || cfg.Exports.RetentionHours < 0 {
+		return fmt.Errorf("exports max_rows, max_size_mb and retention_hours must be zero or positive")
+	}
+	if cfg.Uploads.MaxSizeMB < 0 || cfg.Uploads.RetentionHours < 0 {
+		return fmt.Errorf("uploads max_size_mb and retention_hours must be zero or positive")
+	}
+	for _, ext := range cfg.Uploads.AllowedTypes {
+		if ext == "" || strings.ContainsAny(ext, "./\\ ") {
+			return fmt.Errorf("uploads.allowed_types must be file extensions without a dot, got %q", ext)
+		}
+	}
+	if cfg.Snapshots.MaxSizeMB < 0 {
+		return fmt.Errorf("snapshots.max_size_mb must be zero or positive")
+	}
+	for _, name := range cfg.Extensions.Allowed {
+		if !validExtensionName.MatchString(name) {
+			return fmt.Errorf("extensions.allowed must be extension names of letters, digits, '_' and '-', got %q", name)
+		}
+	}
+	if cfg.Artifacts.MaxAgeMinutes < 0 || cfg.Artifacts.MaxSizeMB < 0 {
+		return fmt.Errorf("artifacts max_age_minutes and max_size_mb must be zero or positive")
+	}
+	if cfg.QueryHistory.Size < 0 || cfg.QueryHistory.CacheTTLSeconds < 0 {
+		return fmt.Errorf("query_history size and cache_ttl_seconds must be zero or positive")
+	}
+	if cfg.QueryGuard.MaxRows < 0 || cfg.QueryGuard.MaxCost < 0 || cfg.QueryGuard.MaxEstimatedRows < 0 {
+		return fmt.Errorf("query_guard max_rows, max_cost and max_estimated_rows must be zero or positive")
+	}
+
+	export := cfg.Metrics.Export
+	if export.IntervalSeconds < 0 || export.RetentionDays < 0 {
+		return fmt.Errorf("metrics export interval_seconds and retention_days must be zero or positive")
+	}
+	if export.Enabled && export.Database != "" && len(cfg.Databases) > 0 && cfg.GetDatabaseByName(export.Database) == nil {
+		return fmt.Errorf("metrics export database %q is not a configured database", export.Database)
+	}
+
+	analyze := cfg.AutoAnalyze
+	if analyze.EstimateRatio < 0 || analyze.MinRows < 0 || analyze.TableIntervalHours < 0 || analyze.MaxPerHour < 0 {
+		return fmt.Errorf("auto_analyze estimate_ratio, min_rows, table_interval_hours and max_per_hour must be zero or positive")
+	}
+	if analyze.Enabled && analyze.EstimateRatio < 2 {
+		return fmt.Errorf("auto_analyze.estimate_ratio must be at least 2")
+	}
+	if _, err := autoanalyze.ParseWindow(analyze.Window); err != nil {
+		return fmt.Errorf("auto_analyze.window: %w", err)
+	}
+
+	switch cfg.Knowledgebase.Backend {
+	case "", KnowledgebaseBackendSQLite:
+	case KnowledgebaseBackendPostgres:
+		if cfg.Knowledgebase.Enabled && cfg.Knowledgebase.ConnectionString == "" {
+			return fmt.Errorf("knowledgebase.connection_string is required for the postgres backend")
+		}
+	default:
+		return fmt.Errorf("invalid knowledgebase.backend %q (must be sqlite or postgres)", cfg.Knowledgebase.Backend)
+	}
+	if cfg.Knowledgebase.RetryIntervalSeconds < 0 {
+		return fmt.Errorf("knowledgebase.retry_interval_seconds must be zero or positive")
+	}
+	switch cfg.Knowledgebase.EmbeddingProvider {
+	case "", KnowledgebaseProviderAuto, "voyage", "openai", "ollama":
+	default:
+		return fmt.Errorf("invalid knowledgebase.embedding_provider %q (must be auto, voyage, openai or ollama)",
+			cfg.Knowledgebase.EmbeddingProvider)
+	}
+	if rerank := cfg.Knowledgebase.Rerank; rerank.Enabled {
+		switch rerank.Provider {
+		case "voyage", "cohere":
+		case "ollama":
+			if rerank.Model == "" {
+				return fmt.Errorf("knowledgebase.rerank.model is required for the ollama provider")
+			}
+		default:
+			return fmt.Errorf("invalid knowledgebase.rerank.provider %q (must be voyage, cohere or ollama)", rerank.Provider)
+		}
+	}
+	if cfg.Knowledgebase.Rerank.Candidates < 0 || cfg.Knowledgebase.Rerank.Candidates > maxRerankCandidates {
+		return fmt.Errorf("knowledgebase.rerank.candidates must be between 0 and %d", maxRerankCandidates)
+	}
+	if cfg.Knowledgebase.Rerank.TimeoutSeconds < 0 {
+		return fmt.Errorf("knowledgebase.rerank.timeout_seconds must be zero or positive")
+	}
+
+	if cfg.Embedding.Cache.MaxEntries < 0 {
+		return fmt.Errorf("embedding cache max_entries must be zero or positive")
+	}
+
 	// Database configuration validation
 	// Validate each database in the list
 	seenNames := make(map[string]bool)
@@ -897,9 +2766,130 @@ func validateConfig(cfg *Config) error {
 		}
 		seenNames[db.Name] = true
 
-		// Require user field
-		if db.User == "" {
-			return fmt.Errorf("database '%s': user is required (set via -db-user, PGEDGE_DB_USER, PGUSER env var, or config file)", db.Name)
+		if err := db.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Proxy URLs must be well formed
+	if err := netproxy.Validate(cfg.Proxy); err != nil {
+		return err
+	}
+
+	// Server log collection needs a known source; the file source needs a path
+	switch cfg.PostgresLogs.Source {
+	case "", "auto", "pg_read_file", "log_fdw":
+	case "file":
+		if cfg.PostgresLogs.Enabled && cfg.PostgresLogs.Path == "" {
+			return fmt.Errorf("postgres_logs.path is required when postgres_logs.source is file")
+		}
+	default:
+		return fmt.Errorf("invalid postgres_logs.source %q (must be auto, file, pg_read_file or log_fdw)", cfg.PostgresLogs.Source)
+	}
+	if cfg.PostgresLogs.MaxLines < 0 {
+		return fmt.Errorf("postgres_logs.max_lines must be zero or positive")
+	}
+
+	// LLM fallbacks must name a supported provider and a model
+	for i, route := range cfg.LLM.Fallback {
+		switch route.Provider {
+		case "anthropic", "openai", "azure", "bedrock", "ollama":
+		default:
+			return fmt.Errorf("llm.fallback %d: unknown provider %q (must be anthropic, openai, azure, bedrock or ollama)", i, route.Provider)
+		}
+		if route.Model == "" {
+			return fmt.Errorf("llm.fallback %d: model is required", i)
+		}
+	}
+	if cfg.LLM.FailoverCooldownSeconds < 0 {
+		return fmt.Errorf("llm.failover_cooldown_seconds must be zero or positive")
+	}
+
+	// Health probe names identify the probes in the health check
+	probeNames := make(map[string]bool, len(cfg.HealthProbes))
+	for i, probe := range cfg.HealthProbes {
+		if !replicaIDPattern.MatchString(probe.Name) {
+			return fmt.Errorf("health probe %d: invalid name %q: use 1 to 64 letters, digits, '-' or '_'", i, probe.Name)
+		}
+		if probeNames[probe.Name] {
+			return fmt.Errorf("health probe %q is defined more than once", probe.Name)
+		}
+		probeNames[probe.Name] = true
+		if strings.TrimSpace(probe.SQL) == "" {
+			return fmt.Errorf("health probe %q: sql is required", probe.Name)
+		}
+		if probe.IntervalSeconds < 0 || probe.TimeoutSeconds < 0 {
+			return fmt.Errorf("health probe %q: interval_seconds and timeout_seconds must be zero or positive", probe.Name)
+		}
+		if probe.Database != "" && len(cfg.Databases) > 0 && cfg.GetDatabaseByName(probe.Database) == nil {
+			return fmt.Errorf("health probe %q: database %q is not a configured database", probe.Name, probe.Database)
+		}
+	}
+
+	// Scheduled jobs must be valid and uniquely named, since names identify
+	// jobs in the API and their history files
+	if cfg.Scheduler.HistorySize < 0 {
+		return fmt.Errorf("scheduler.history_size must be zero or positive")
+	}
+	jobNames := make(map[string]bool, len(cfg.Scheduler.Jobs))
+	for _, job := range cfg.Scheduler.SchedulerJobs() {
+		if err := job.Validate(); err != nil {
+			return fmt.Errorf("scheduler: %w", err)
+		}
+		if jobNames[job.Name] {
+			return fmt.Errorf("scheduler: job %q is defined more than once", job.Name)
+		}
+		jobNames[job.Name] = true
+		if job.Database != "" && len(cfg.Databases) > 0 && cfg.GetDatabaseByName(job.Database) == nil {
+			return fmt.Errorf("scheduler: job %q: database %q is not a configured database", job.Name, job.Database)
+		}
+	}
+
+	// Notification endpoints must be valid and uniquely named
+	if cfg.Notifications.CooldownMinutes < 0 || cfg.Notifications.TokenExpiryWarningDays < 0 {
+		return fmt.Errorf("notifications cooldown_minutes and token_expiry_warning_days must be zero or positive")
+	}
+	endpointNames := make(map[string]bool, len(cfg.Notifications.Endpoints))
+	for _, endpoint := range cfg.Notifications.NotifyEndpoints() {
+		if err := endpoint.Validate(); err != nil {
+			return fmt.Errorf("notifications: %w", err)
+		}
+		if endpointNames[endpoint.Name] {
+			return fmt.Errorf("notifications: endpoint %q is defined more than once", endpoint.Name)
+		}
+		endpointNames[endpoint.Name] = true
+	}
+
+	// Traces need a collector to go to
+	if cfg.Tracing.Enabled {
+		if err := tracing.ValidateEndpoint(cfg.Tracing.Endpoint); err != nil {
+			return fmt.Errorf("tracing: %w", err)
+		}
+		if cfg.Tracing.SampleRatio <= 0 || cfg.Tracing.SampleRatio > 1 {
+			return fmt.Errorf("tracing sample_ratio must be above 0 and at most 1")
+		}
+	}
+
+	// Masking rules must have valid patterns and a known action
+	for i, rule := range cfg.Masking.Rules {
+		if rule.Column == "" && rule.Value == "" {
+			return fmt.Errorf("masking rule %d: a column or value pattern is required", i)
+		}
+		for _, pattern := range []string{rule.Table, rule.Column, rule.Value} {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("masking rule %d: invalid pattern %q: %w", i, pattern, err)
+			}
+		}
+		switch rule.Action {
+		case "", "redact":
+		case "hash":
+			// An unkeyed hash of a low-entropy value such as an SSN is
+			// reversed by hashing every candidate
+			if cfg.Masking.Enabled && cfg.Masking.HashKey == "" {
+				return fmt.Errorf("masking rule %d: the hash action requires masking.hash_key", i)
+			}
+		default:
+			return fmt.Errorf("masking rule %d: unknown action %q (must be redact or hash)", i, rule.Action)
 		}
 	}
 
@@ -939,9 +2929,9 @@ func readAPIKeyFromFile(filePath string) (string, error) {
 }
 
 // GetDefaultConfigPath returns the default config file path
-// Searches /etc/pgedge/ first, then binary directory
+// Searches the system configuration directory first, then binary directory
 func GetDefaultConfigPath(binaryPath string) string {
-	systemPath := "/etc/pgedge/pgedge-postgres-mcp.yaml"
+	systemPath := filepath.Join(SystemConfigDir(), "pgedge-postgres-mcp.yaml")
 	if _, err := os.Stat(systemPath); err == nil {
 		return systemPath
 	}
@@ -951,9 +2941,9 @@ func GetDefaultConfigPath(binaryPath string) string {
 }
 
 // GetDefaultSecretPath returns the default secret file path
-// Searches /etc/pgedge/ first, then binary directory
+// Searches the system configuration directory first, then binary directory
 func GetDefaultSecretPath(binaryPath string) string {
-	systemPath := "/etc/pgedge/pgedge-postgres-mcp.secret"
+	systemPath := filepath.Join(SystemConfigDir(), "pgedge-postgres-mcp.secret")
 	if _, err := os.Stat(systemPath); err == nil {
 		return systemPath
 	}
@@ -962,6 +2952,12 @@ func GetDefaultSecretPath(binaryPath string) string {
 	return filepath.Join(dir, "pgedge-postgres-mcp.secret")
 }
 
+// GetDefaultDataDir returns the default data directory: next to the
+// binary, or under %ProgramData% on Windows
+func GetDefaultDataDir(binaryPath string) string {
+	return defaultDataDir(binaryPath)
+}
+
 // GetDatabaseByName returns the named database config or nil if not found
 func (cfg *Config) GetDatabaseByName(name string) *NamedDatabaseConfig {
 	for i := range cfg.Databases {
@@ -1004,8 +3000,12 @@ func (cfg *Config) GetDatabasesForUser(username string) []NamedDatabaseConfig {
 	return result
 }
 
-// ConfigFileExists checks if a config file exists at the given path
+// ConfigFileExists checks if a config file exists at the given path;
+// standard input (StdinConfigPath) always exists
 func ConfigFileExists(path string) bool {
+	if path == StdinConfigPath {
+		return true
+	}
 	_, err := os.Stat(path)
 	return err == nil
 }
@@ -13,7 +13,11 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+
+	"pgedge-postgres-mcp/internal/netproxy"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -59,6 +63,12 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Knowledgebase.Enabled {
 		t.Error("Expected knowledgebase to be disabled by default")
 	}
+	if cfg.Knowledgebase.Backend != KnowledgebaseBackendSQLite || cfg.Knowledgebase.Schema != "pgedge_kb" {
+		t.Errorf("Unexpected knowledgebase backend defaults: %q, %q", cfg.Knowledgebase.Backend, cfg.Knowledgebase.Schema)
+	}
+	if cfg.Knowledgebase.RetryIntervalSeconds != 30 {
+		t.Errorf("Expected knowledgebase retry interval 30s, got %d", cfg.Knowledgebase.RetryIntervalSeconds)
+	}
 
 	// Test rate limiting defaults
 	if cfg.HTTP.Auth.RateLimitWindowMinutes != 15 {
@@ -67,6 +77,113 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.HTTP.Auth.RateLimitMaxAttempts != 10 {
 		t.Errorf("Expected rate limit max attempts 10, got %d", cfg.HTTP.Auth.RateLimitMaxAttempts)
 	}
+
+	// Test shutdown defaults
+	if cfg.ShutdownTimeoutSeconds != 30 {
+		t.Errorf("Expected shutdown timeout 30 seconds, got %d", cfg.ShutdownTimeoutSeconds)
+	}
+	if cfg.ResourcePollIntervalSeconds != 30 {
+		t.Errorf("Expected resource poll interval 30 seconds, got %d", cfg.ResourcePollIntervalSeconds)
+	}
+	if cfg.ClientIdleTimeoutSeconds != 1800 {
+		t.Errorf("Expected client idle timeout 1800 seconds, got %d", cfg.ClientIdleTimeoutSeconds)
+	}
+	if cfg.MetadataRefreshIntervalSeconds != 300 {
+		t.Errorf("Expected metadata refresh interval 300 seconds, got %d", cfg.MetadataRefreshIntervalSeconds)
+	}
+
+	// Test conversation store defaults
+	if !cfg.Conversations.EncryptionEnabled() {
+		t.Error("Expected conversation encryption to be enabled by default")
+	}
+	if cfg.Conversations.PerUserKeys {
+		t.Error("Expected per-user conversation keys to be disabled by default")
+	}
+	if cfg.Conversations.MaxAgeDays != 0 || cfg.Conversations.MaxPerUser != 0 {
+		t.Error("Expected conversations to be kept forever by default")
+	}
+	if cfg.Conversations.PurgeIntervalMinutes != 60 {
+		t.Errorf("Expected purge interval 60 minutes, got %d", cfg.Conversations.PurgeIntervalMinutes)
+	}
+	if cfg.Conversations.UsesPostgres() || cfg.Conversations.DeletedRetentionDays != 30 {
+		t.Errorf("Expected SQLite conversations keeping deleted ones 30 days, got %+v", cfg.Conversations)
+	}
+
+	// Test export defaults
+	if cfg.Exports.MaxRows != 1000000 || cfg.Exports.MaxSizeMB != 100 || cfg.Exports.RetentionHours != 24 {
+		t.Errorf("Unexpected export defaults: %+v", cfg.Exports)
+	}
+
+	// Test upload defaults: disabled, with data and document types allowed
+	if cfg.Uploads.Enabled || cfg.Uploads.MaxSizeMB != 50 || cfg.Uploads.RetentionHours != 24 || len(cfg.Uploads.AllowedTypes) == 0 {
+		t.Errorf("Unexpected upload defaults: %+v", cfg.Uploads)
+	}
+
+	// Test extension defaults: pgvector can be installed
+	if !cfg.Extensions.IsAllowed("vector") || cfg.Extensions.IsAllowed("plpython3u") {
+		t.Errorf("Unexpected extension defaults: %+v", cfg.Extensions)
+	}
+
+	// Test embedding cache defaults: in memory only
+	if !cfg.Embedding.Cache.IsEnabled() || cfg.Embedding.Cache.Persist {
+		t.Errorf("Unexpected embedding cache defaults: %+v", cfg.Embedding.Cache)
+	}
+
+	// Test metrics export defaults
+	export := cfg.Metrics.Export
+	if export.Enabled || export.Database != "" || export.IntervalSeconds != 60 || export.RetentionDays != 7 {
+		t.Errorf("Unexpected metrics export defaults: %+v", export)
+	}
+
+	// Test artifact cache defaults
+	if !cfg.Artifacts.CacheEnabled() || cfg.Artifacts.MaxAgeMinutes != 10 || cfg.Artifacts.MaxSizeMB != 50 {
+		t.Errorf("Unexpected artifact cache defaults: %+v", cfg.Artifacts)
+	}
+
+	// Test query history defaults
+	if cfg.QueryHistory.Size != 50 || !cfg.QueryHistory.CacheEnabled() || cfg.QueryHistory.CacheTTLSeconds != 60 {
+		t.Errorf("Unexpected query history defaults: %+v", cfg.QueryHistory)
+	}
+
+	// Test query guard defaults
+	if cfg.QueryGuard.MaxRows != 1000 || cfg.QueryGuard.Explain ||
+		cfg.QueryGuard.MaxCost != 1000000 || cfg.QueryGuard.MaxEstimatedRows != 10000000 {
+		t.Errorf("Unexpected query guard defaults: %+v", cfg.QueryGuard)
+	}
+
+	// Test scheduler defaults
+	if cfg.Scheduler.Enabled || cfg.Scheduler.HistorySize != 100 || len(cfg.Scheduler.Jobs) != 0 {
+		t.Errorf("Unexpected scheduler defaults: %+v", cfg.Scheduler)
+	}
+
+	// Test notification defaults
+	if cfg.Notifications.CooldownMinutes != 10 || cfg.Notifications.TokenExpiryWarningDays != 7 || len(cfg.Notifications.Endpoints) != 0 {
+		t.Errorf("Unexpected notification defaults: %+v", cfg.Notifications)
+	}
+
+	// Test tracing defaults
+	if cfg.Tracing.Enabled || cfg.Tracing.Endpoint != "http://localhost:4318" || cfg.Tracing.ServiceName != "pgedge-postgres-mcp" ||
+		cfg.Tracing.SampleRatio != 1 || cfg.Tracing.OmitStatements {
+		t.Errorf("Unexpected tracing defaults: %+v", cfg.Tracing)
+	}
+
+	// Test background ANALYZE defaults
+	analyze := cfg.AutoAnalyze
+	if analyze.Enabled || analyze.EstimateRatio != 10 || analyze.MinRows != 1000 ||
+		analyze.TableIntervalHours != 24 || analyze.MaxPerHour != 10 || analyze.Window != "" {
+		t.Errorf("Unexpected auto_analyze defaults: %+v", analyze)
+	}
+
+	// Test server log defaults
+	if cfg.PostgresLogs.Enabled {
+		t.Error("Expected server log collection to be disabled by default")
+	}
+	if cfg.PostgresLogs.Source != "auto" {
+		t.Errorf("Expected server log source auto, got %q", cfg.PostgresLogs.Source)
+	}
+	if cfg.PostgresLogs.MaxLines != 20 {
+		t.Errorf("Expected server log max lines 20, got %d", cfg.PostgresLogs.MaxLines)
+	}
 }
 
 func TestBuildConnectionString(t *testing.T) {
@@ -119,6 +236,28 @@ func TestBuildConnectionString(t *testing.T) {
 			},
 			expected: "postgres://admin:p@ssw0rd@db.example.com:5433/production?sslmode=verify-full",
 		},
+		{
+			name: "unix socket",
+			config: NamedDatabaseConfig{
+				User:     "postgres",
+				Host:     "/var/run/postgresql",
+				Port:     5432,
+				Database: "testdb",
+				SSLMode:  "prefer",
+			},
+			expected: "host=/var/run/postgresql port=5432 dbname=testdb user=postgres sslmode=prefer",
+		},
+		{
+			name: "unix socket with quoted values",
+			config: NamedDatabaseConfig{
+				User:     "postgres",
+				Password: `it's a \secret`,
+				Host:     "/tmp/pg sockets",
+				Port:     5433,
+				Database: "testdb",
+			},
+			expected: `host='/tmp/pg sockets' port=5433 dbname=testdb user=postgres password='it\'s a \\secret'`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -147,10 +286,67 @@ func TestToolsConfig_IsToolEnabled(t *testing.T) {
 		{"unknown tool returns true", ToolsConfig{}, "unknown_tool", true},
 		{"get_schema_info nil", ToolsConfig{}, "get_schema_info", true},
 		{"similarity_search nil", ToolsConfig{}, "similarity_search", true},
+		{"hybrid_search nil", ToolsConfig{}, "hybrid_search", true},
+		{"hybrid_search disabled", ToolsConfig{HybridSearch: &falseVal}, "hybrid_search", false},
+		{"get_context_usage nil", ToolsConfig{}, "get_context_usage", true},
+		{"get_context_usage disabled", ToolsConfig{GetContextUsage: &falseVal}, "get_context_usage", false},
 		{"execute_explain nil", ToolsConfig{}, "execute_explain", true},
 		{"generate_embedding nil", ToolsConfig{}, "generate_embedding", true},
 		{"search_knowledgebase nil", ToolsConfig{}, "search_knowledgebase", true},
 		{"count_rows nil", ToolsConfig{}, "count_rows", true},
+		{"explain_sql nil", ToolsConfig{}, "explain_sql", true},
+		{"explain_sql disabled", ToolsConfig{ExplainSQL: &falseVal}, "explain_sql", false},
+		{"plan_schema_change nil", ToolsConfig{}, "plan_schema_change", true},
+		{"plan_schema_change disabled", ToolsConfig{PlanSchemaChange: &falseVal}, "plan_schema_change", false},
+		{"get_table_stats nil", ToolsConfig{}, "get_table_stats", true},
+		{"get_table_stats disabled", ToolsConfig{GetTableStats: &falseVal}, "get_table_stats", false},
+		{"index_advisor nil", ToolsConfig{}, "index_advisor", true},
+		{"index_advisor disabled", ToolsConfig{IndexAdvisor: &falseVal}, "index_advisor", false},
+		{"database_health_check nil", ToolsConfig{}, "database_health_check", true},
+		{"database_health_check disabled", ToolsConfig{DatabaseHealthCheck: &falseVal}, "database_health_check", false},
+		{"lock_analysis nil", ToolsConfig{}, "lock_analysis", true},
+		{"lock_analysis disabled", ToolsConfig{LockAnalysis: &falseVal}, "lock_analysis", false},
+		{"check_collations nil", ToolsConfig{}, "check_collations", true},
+		{"check_collations disabled", ToolsConfig{CheckCollations: &falseVal}, "check_collations", false},
+		{"execute_script nil", ToolsConfig{}, "execute_script", false},
+		{"execute_script enabled", ToolsConfig{ExecuteScript: &trueVal}, "execute_script", true},
+		{"generate_migration nil", ToolsConfig{}, "generate_migration", true},
+		{"generate_migration disabled", ToolsConfig{GenerateMigration: &falseVal}, "generate_migration", false},
+		{"apply_migration nil", ToolsConfig{}, "apply_migration", false},
+		{"apply_migration enabled", ToolsConfig{ApplyMigration: &trueVal}, "apply_migration", true},
+		{"create_vector_index nil", ToolsConfig{}, "create_vector_index", false},
+		{"create_vector_index enabled", ToolsConfig{CreateVectorIndex: &trueVal}, "create_vector_index", true},
+		{"install_extension nil", ToolsConfig{}, "install_extension", false},
+		{"install_extension enabled", ToolsConfig{InstallExtension: &trueVal}, "install_extension", true},
+		{"setup_semantic_search nil", ToolsConfig{}, "setup_semantic_search", false},
+		{"setup_semantic_search enabled", ToolsConfig{SetupSemanticSearch: &trueVal}, "setup_semantic_search", true},
+		{"setup_foreign_server nil", ToolsConfig{}, "setup_foreign_server", false},
+		{"setup_foreign_server enabled", ToolsConfig{SetupForeignServer: &trueVal}, "setup_foreign_server", true},
+		{"add_database_connection nil", ToolsConfig{}, "add_database_connection", false},
+		{"add_database_connection enabled", ToolsConfig{AddDatabaseConnection: &trueVal}, "add_database_connection", true},
+		{"export_query_results nil", ToolsConfig{}, "export_query_results", true},
+		{"export_query_results disabled", ToolsConfig{ExportQueryResults: &falseVal}, "export_query_results", false},
+		{"snapshot_schema nil", ToolsConfig{}, "snapshot_schema", true},
+		{"snapshot_schema disabled", ToolsConfig{SnapshotSchema: &falseVal}, "snapshot_schema", false},
+		{"list_schema_snapshots nil", ToolsConfig{}, "list_schema_snapshots", true},
+		{"list_databases nil", ToolsConfig{}, "list_databases", true},
+		{"list_databases disabled", ToolsConfig{ListDatabases: &falseVal}, "list_databases", false},
+		{"refresh_schema_cache nil", ToolsConfig{}, "refresh_schema_cache", true},
+		{"refresh_schema_cache disabled", ToolsConfig{RefreshSchemaCache: &falseVal}, "refresh_schema_cache", false},
+		{"find_relevant_tables nil", ToolsConfig{}, "find_relevant_tables", true},
+		{"find_relevant_tables disabled", ToolsConfig{FindRelevantTables: &falseVal}, "find_relevant_tables", false},
+		{"get_query_history nil", ToolsConfig{}, "get_query_history", true},
+		{"get_query_history disabled", ToolsConfig{GetQueryHistory: &falseVal}, "get_query_history", false},
+		{"list_jobs nil", ToolsConfig{}, "list_jobs", true},
+		{"list_jobs disabled", ToolsConfig{ListJobs: &falseVal}, "list_jobs", false},
+		{"restore_schema_snapshot nil", ToolsConfig{}, "restore_schema_snapshot", false},
+		{"restore_schema_snapshot enabled", ToolsConfig{RestoreSchemaSnapshot: &trueVal}, "restore_schema_snapshot", true},
+		{"validate_sql nil", ToolsConfig{}, "validate_sql", true},
+		{"validate_sql disabled", ToolsConfig{ValidateSQL: &falseVal}, "validate_sql", false},
+		{"compare_plan nil", ToolsConfig{}, "compare_plan", true},
+		{"compare_plan disabled", ToolsConfig{ComparePlan: &falseVal}, "compare_plan", false},
+		{"transactions default disabled", ToolsConfig{}, "begin_transaction", false},
+		{"transactions enabled", ToolsConfig{Transactions: &trueVal}, "commit_transaction", true},
 	}
 
 	for _, tt := range tests {
@@ -176,6 +372,11 @@ func TestResourcesConfig_IsResourceEnabled(t *testing.T) {
 		{"nil value returns true", ResourcesConfig{}, "pg://system_info", true},
 		{"explicit true", ResourcesConfig{SystemInfo: &trueVal}, "pg://system_info", true},
 		{"explicit false", ResourcesConfig{SystemInfo: &falseVal}, "pg://system_info", false},
+		{"replication nil", ResourcesConfig{}, "pg://replication", true},
+		{"replication disabled", ResourcesConfig{Replication: &falseVal}, "pg://replication", false},
+		{"replication disabled leaves system_info", ResourcesConfig{Replication: &falseVal}, "pg://system_info", true},
+		{"extensions nil", ResourcesConfig{}, "pg://extensions", true},
+		{"extensions disabled", ResourcesConfig{Extensions: &falseVal}, "pg://extensions", false},
 		{"unknown resource returns true", ResourcesConfig{}, "pg://unknown", true},
 	}
 
@@ -226,89 +427,567 @@ func TestValidateConfig(t *testing.T) {
 		errorMsg    string
 	}{
 		{
-			name: "valid config",
+			name: "valid config",
+			config: &Config{
+				HTTP: HTTPConfig{Enabled: false},
+			},
+			expectError: false,
+		},
+		{
+			name: "TLS without HTTP",
+			config: &Config{
+				HTTP: HTTPConfig{
+					Enabled: false,
+					TLS:     TLSConfig{Enabled: true},
+				},
+			},
+			expectError: true,
+			errorMsg:    "TLS requires HTTP mode",
+		},
+		{
+			name: "TLS without cert file",
+			config: &Config{
+				HTTP: HTTPConfig{
+					Enabled: true,
+					TLS:     TLSConfig{Enabled: true, KeyFile: "key.pem"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "certificate file is required",
+		},
+		{
+			name: "TLS without key file",
+			config: &Config{
+				HTTP: HTTPConfig{
+					Enabled: true,
+					TLS:     TLSConfig{Enabled: true, CertFile: "cert.pem"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "key file is required",
+		},
+		{
+			name: "HTTP auth without token file",
+			config: &Config{
+				HTTP: HTTPConfig{
+					Enabled: true,
+					Auth:    AuthConfig{Enabled: true, TokenFile: ""},
+				},
+			},
+			expectError: true,
+			errorMsg:    "authentication token file is required",
+		},
+		{
+			name: "duplicate database names",
+			config: &Config{
+				HTTP: HTTPConfig{Enabled: false},
+				Databases: []NamedDatabaseConfig{
+					{Name: "db1", User: "user1"},
+					{Name: "db1", User: "user2"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "duplicate database name",
+		},
+		{
+			name: "database without name",
+			config: &Config{
+				HTTP: HTTPConfig{Enabled: false},
+				Databases: []NamedDatabaseConfig{
+					{Name: "", User: "user1"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "name is required",
+		},
+		{
+			name: "database without user",
+			config: &Config{
+				HTTP: HTTPConfig{Enabled: false},
+				Databases: []NamedDatabaseConfig{
+					{Name: "db1", User: ""},
+				},
+			},
+			expectError: true,
+			errorMsg:    "user is required",
+		},
+		{
+			name: "negative quota",
+			config: &Config{
+				HTTP: HTTPConfig{
+					Auth: AuthConfig{Quotas: QuotaConfig{RowsPerHour: -1}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "auth quotas",
+		},
+		{
+			name: "negative conversation retention",
+			config: &Config{
+				Conversations: ConversationsConfig{MaxAgeDays: -1},
+			},
+			expectError: true,
+			errorMsg:    "max_age_days",
+		},
+		{
+			name: "unknown conversation backend",
+			config: &Config{
+				Conversations: ConversationsConfig{Backend: "redis"},
+			},
+			expectError: true,
+			errorMsg:    "conversations.backend",
+		},
+		{
+			name: "unknown conversation database",
+			config: &Config{
+				Databases:     []NamedDatabaseConfig{{Name: "main", User: "postgres"}},
+				Conversations: ConversationsConfig{Backend: "postgres", Database: "history"},
+			},
+			expectError: true,
+			errorMsg:    "conversations database",
+		},
+		{
+			name: "unknown LLM fallback provider",
+			config: &Config{
+				LLM: LLMConfig{Fallback: []LLMRouteConfig{{Provider: "example", Model: "example-model"}}},
+			},
+			expectError: true,
+			errorMsg:    "unknown provider",
+		},
+		{
+			name: "LLM fallback without model",
+			config: &Config{
+				LLM: LLMConfig{Fallback: []LLMRouteConfig{{Provider: "ollama"}}},
+			},
+			expectError: true,
+			errorMsg:    "model is required",
+		},
+		{
+			name: "valid health probe",
+			config: &Config{
+				Databases:    []NamedDatabaseConfig{{Name: "main", User: "postgres"}},
+				HealthProbes: []HealthProbeConfig{{Name: "replication_lag", Database: "main", SQL: "SELECT 'ok', ''"}},
+			},
+			expectError: false,
+		},
+		{
+			name: "duplicate health probe",
+			config: &Config{
+				HealthProbes: []HealthProbeConfig{
+					{Name: "lag", SQL: "SELECT 'ok', ''"},
+					{Name: "lag", SQL: "SELECT 'warn', ''"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "more than once",
+		},
+		{
+			name: "health probe without sql",
+			config: &Config{
+				HealthProbes: []HealthProbeConfig{{Name: "lag"}},
+			},
+			expectError: true,
+			errorMsg:    "sql is required",
+		},
+		{
+			name: "health probe with invalid name",
+			config: &Config{
+				HealthProbes: []HealthProbeConfig{{Name: "replication lag", SQL: "SELECT 'ok', ''"}},
+			},
+			expectError: true,
+			errorMsg:    "invalid name",
+		},
+		{
+			name: "unknown health probe database",
+			config: &Config{
+				Databases:    []NamedDatabaseConfig{{Name: "main", User: "postgres"}},
+				HealthProbes: []HealthProbeConfig{{Name: "lag", Database: "replica", SQL: "SELECT 'ok', ''"}},
+			},
+			expectError: true,
+			errorMsg:    "not a configured database",
+		},
+		{
+			name: "valid scheduled job",
+			config: &Config{
+				Databases: []NamedDatabaseConfig{{Name: "main", User: "postgres"}},
+				Scheduler: SchedulerConfig{Jobs: []JobConfig{{
+					Name: "nightly_bloat", Schedule: "0 2 * * *", Database: "main",
+					Steps: []JobStepConfig{{Tool: "query_database", Args: map[string]interface{}{"query": "SELECT 1"}}},
+				}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "duplicate scheduled job",
+			config: &Config{
+				Scheduler: SchedulerConfig{Jobs: []JobConfig{
+					{Name: "nightly", Schedule: "@daily", SQL: "SELECT 1"},
+					{Name: "nightly", Schedule: "@hourly", SQL: "SELECT 2"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "more than once",
+		},
+		{
+			name: "scheduled job with invalid schedule",
+			config: &Config{
+				Scheduler: SchedulerConfig{Jobs: []JobConfig{{Name: "nightly", Schedule: "0 25 * * *", SQL: "SELECT 1"}}},
+			},
+			expectError: true,
+			errorMsg:    "invalid schedule",
+		},
+		{
+			name: "unknown scheduled job database",
+			config: &Config{
+				Databases: []NamedDatabaseConfig{{Name: "main", User: "postgres"}},
+				Scheduler: SchedulerConfig{Jobs: []JobConfig{{Name: "nightly", Schedule: "@daily", Database: "replica", SQL: "SELECT 1"}}},
+			},
+			expectError: true,
+			errorMsg:    "not a configured database",
+		},
+		{
+			name: "negative scheduler history size",
+			config: &Config{
+				Scheduler: SchedulerConfig{HistorySize: -1},
+			},
+			expectError: true,
+			errorMsg:    "scheduler.history_size",
+		},
+		{
+			name: "valid notification endpoint",
+			config: &Config{
+				Notifications: NotificationsConfig{Endpoints: []NotificationEndpointConfig{
+					{Name: "ops", Type: "slack", URL: "https://hooks.example.com/services/x", Events: []string{"health", "jobs"}, MinSeverity: "warning"},
+				}},
+			},
+			expectError: false,
+		},
+		{
+			name: "duplicate notification endpoint",
+			config: &Config{
+				Notifications: NotificationsConfig{Endpoints: []NotificationEndpointConfig{
+					{Name: "ops", Type: "slack", URL: "https://hooks.example.com/a"},
+					{Name: "ops", Type: "webhook", URL: "https://hooks.example.com/b"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "more than once",
+		},
+		{
+			name: "notification endpoint with unknown event",
+			config: &Config{
+				Notifications: NotificationsConfig{Endpoints: []NotificationEndpointConfig{
+					{Name: "ops", Type: "webhook", URL: "https://hooks.example.com/a", Events: []string{"billing"}},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "unknown event",
+		},
+		{
+			name: "negative notification cooldown",
+			config: &Config{
+				Notifications: NotificationsConfig{CooldownMinutes: -1},
+			},
+			expectError: true,
+			errorMsg:    "cooldown_minutes",
+		},
+		{
+			name: "valid tracing",
+			config: &Config{
+				Tracing: TracingConfig{Enabled: true, Endpoint: "http://tempo:4318", SampleRatio: 0.1},
+			},
+			expectError: false,
+		},
+		{
+			name: "tracing without an http endpoint",
+			config: &Config{
+				Tracing: TracingConfig{Enabled: true, Endpoint: "tempo:4317", SampleRatio: 1},
+			},
+			expectError: true,
+			errorMsg:    "tracing: endpoint",
+		},
+		{
+			name: "tracing sample ratio above 1",
+			config: &Config{
+				Tracing: TracingConfig{Enabled: true, Endpoint: "http://tempo:4318", SampleRatio: 10},
+			},
+			expectError: true,
+			errorMsg:    "sample_ratio",
+		},
+		{
+			name: "negative export limit",
+			config: &Config{
+				Exports: ExportsConfig{MaxSizeMB: -1},
+			},
+			expectError: true,
+			errorMsg:    "exports max_rows",
+		},
+		{
+			name: "any CORS origin with credentials",
+			config: &Config{
+				HTTP: HTTPConfig{CORS: CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+			},
+			expectError: true,
+			errorMsg:    "allow_credentials",
+		},
+		{
+			name: "CORS origin without a scheme",
+			config: &Config{
+				HTTP: HTTPConfig{CORS: CORSConfig{AllowedOrigins: []string{"gui.example.com"}}},
+			},
+			expectError: true,
+			errorMsg:    "invalid http.cors origin",
+		},
+		{
+			name: "ACME without certificate files",
+			config: &Config{
+				HTTP: HTTPConfig{Enabled: true, TLS: TLSConfig{Enabled: true, ACME: ACMEConfig{Enabled: true, Domains: []string{"mcp.example.com"}}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "ACME without domains",
+			config: &Config{
+				HTTP: HTTPConfig{Enabled: true, TLS: TLSConfig{Enabled: true, ACME: ACMEConfig{Enabled: true}}},
+			},
+			expectError: true,
+			errorMsg:    "http.tls.acme.domains",
+		},
+		{
+			name: "ACME without TLS",
+			config: &Config{
+				HTTP: HTTPConfig{Enabled: true, TLS: TLSConfig{ACME: ACMEConfig{Enabled: true, Domains: []string{"mcp.example.com"}}}},
+			},
+			expectError: true,
+			errorMsg:    "requires http.tls.enabled",
+		},
+		{
+			name: "negative HTTP timeout",
+			config: &Config{
+				HTTP: HTTPConfig{Limits: HTTPLimitsConfig{ReadTimeoutSeconds: -1}},
+			},
+			expectError: true,
+			errorMsg:    "http.limits",
+		},
+		{
+			name: "upload type with a dot",
+			config: &Config{
+				Uploads: UploadsConfig{AllowedTypes: []string{".csv"}},
+			},
+			expectError: true,
+			errorMsg:    "uploads.allowed_types",
+		},
+		{
+			name: "invalid extension name",
+			config: &Config{
+				Extensions: ExtensionsConfig{Allowed: []string{"vector; DROP TABLE t"}},
+			},
+			expectError: true,
+			errorMsg:    "extensions.allowed",
+		},
+		{
+			name: "invalid replica ID",
+			config: &Config{
+				HTTP: HTTPConfig{SessionAffinity: SessionAffinityConfig{Enabled: true, ReplicaID: "mcp.example.com"}},
+			},
+			expectError: true,
+			errorMsg:    "replica_id",
+		},
+		{
+			name: "negative embedding cache size",
+			config: &Config{
+				Embedding: EmbeddingConfig{Cache: EmbeddingCacheConfig{MaxEntries: -1}},
+			},
+			expectError: true,
+			errorMsg:    "embedding cache max_entries",
+		},
+		{
+			name: "invalid proxy URL",
+			config: &Config{
+				Proxy: netproxy.Settings{Anthropic: "ftp://proxy:21"},
+			},
+			expectError: true,
+			errorMsg:    "unsupported scheme",
+		},
+		{
+			name: "masking rule without pattern",
+			config: &Config{
+				Masking: MaskingConfig{
+					Enabled: true,
+					Rules:   []MaskingRule{{Table: "users"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "column or value pattern is required",
+		},
+		{
+			name: "masking rule with invalid pattern",
+			config: &Config{
+				Masking: MaskingConfig{
+					Enabled: true,
+					Rules:   []MaskingRule{{Column: "(email"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid pattern",
+		},
+		{
+			name: "masking rule with unknown action",
+			config: &Config{
+				Masking: MaskingConfig{
+					Enabled: true,
+					Rules:   []MaskingRule{{Column: "email", Action: "encrypt"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "unknown action",
+		},
+		{
+			name: "invalid metadata schema pattern",
+			config: &Config{
+				Databases: []NamedDatabaseConfig{
+					{Name: "db1", User: "user1", Metadata: MetadataConfig{Schemas: []string{"sales_[a-"}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid metadata schema pattern",
+		},
+		{
+			name: "invalid database environment",
+			config: &Config{
+				Databases: []NamedDatabaseConfig{{Name: "db1", User: "user1", Environment: "prd"}},
+			},
+			expectError: true,
+			errorMsg:    "invalid environment",
+		},
+		{
+			name: "empty database tag",
 			config: &Config{
-				HTTP: HTTPConfig{Enabled: false},
+				Databases: []NamedDatabaseConfig{{Name: "db1", User: "user1", Tags: []string{"sales", " "}}},
 			},
-			expectError: false,
+			expectError: true,
+			errorMsg:    "tags must not be empty",
 		},
 		{
-			name: "TLS without HTTP",
+			name: "unknown server log source",
 			config: &Config{
-				HTTP: HTTPConfig{
-					Enabled: false,
-					TLS:     TLSConfig{Enabled: true},
-				},
+				PostgresLogs: PostgresLogConfig{Enabled: true, Source: "syslog"},
 			},
 			expectError: true,
-			errorMsg:    "TLS requires HTTP mode",
+			errorMsg:    "invalid postgres_logs.source",
 		},
 		{
-			name: "TLS without cert file",
+			name: "server log file source without path",
 			config: &Config{
-				HTTP: HTTPConfig{
-					Enabled: true,
-					TLS:     TLSConfig{Enabled: true, KeyFile: "key.pem"},
-				},
+				PostgresLogs: PostgresLogConfig{Enabled: true, Source: "file"},
 			},
 			expectError: true,
-			errorMsg:    "certificate file is required",
+			errorMsg:    "postgres_logs.path is required",
 		},
 		{
-			name: "TLS without key file",
+			name: "postgres knowledgebase without connection string",
 			config: &Config{
-				HTTP: HTTPConfig{
-					Enabled: true,
-					TLS:     TLSConfig{Enabled: true, CertFile: "cert.pem"},
-				},
+				Knowledgebase: KnowledgebaseConfig{Enabled: true, Backend: KnowledgebaseBackendPostgres},
 			},
 			expectError: true,
-			errorMsg:    "key file is required",
+			errorMsg:    "knowledgebase.connection_string is required",
 		},
 		{
-			name: "HTTP auth without token file",
+			name: "unknown knowledgebase backend",
 			config: &Config{
-				HTTP: HTTPConfig{
-					Enabled: true,
-					Auth:    AuthConfig{Enabled: true, TokenFile: ""},
-				},
+				Knowledgebase: KnowledgebaseConfig{Backend: "duckdb"},
 			},
 			expectError: true,
-			errorMsg:    "authentication token file is required",
+			errorMsg:    "invalid knowledgebase.backend",
 		},
 		{
-			name: "duplicate database names",
+			name: "negative knowledgebase retry interval",
 			config: &Config{
-				HTTP: HTTPConfig{Enabled: false},
-				Databases: []NamedDatabaseConfig{
-					{Name: "db1", User: "user1"},
-					{Name: "db1", User: "user2"},
-				},
+				Knowledgebase: KnowledgebaseConfig{RetryIntervalSeconds: -1},
 			},
 			expectError: true,
-			errorMsg:    "duplicate database name",
+			errorMsg:    "retry_interval_seconds must be zero or positive",
 		},
 		{
-			name: "database without name",
+			name: "invalid knowledgebase rerank provider",
 			config: &Config{
-				HTTP: HTTPConfig{Enabled: false},
-				Databases: []NamedDatabaseConfig{
-					{Name: "", User: "user1"},
-				},
+				Knowledgebase: KnowledgebaseConfig{Rerank: KnowledgebaseRerankConfig{Enabled: true, Provider: "openai"}},
 			},
 			expectError: true,
-			errorMsg:    "name is required",
+			errorMsg:    "invalid knowledgebase.rerank.provider",
 		},
 		{
-			name: "database without user",
+			name: "ollama reranking without model",
 			config: &Config{
-				HTTP: HTTPConfig{Enabled: false},
-				Databases: []NamedDatabaseConfig{
-					{Name: "db1", User: ""},
+				Knowledgebase: KnowledgebaseConfig{Rerank: KnowledgebaseRerankConfig{Enabled: true, Provider: "ollama"}},
+			},
+			expectError: true,
+			errorMsg:    "knowledgebase.rerank.model is required",
+		},
+		{
+			name: "too many knowledgebase rerank candidates",
+			config: &Config{
+				Knowledgebase: KnowledgebaseConfig{Rerank: KnowledgebaseRerankConfig{Candidates: 500}},
+			},
+			expectError: true,
+			errorMsg:    "knowledgebase.rerank.candidates must be between 0 and 100",
+		},
+		{
+			name: "metrics export to unknown database",
+			config: &Config{
+				Databases: []NamedDatabaseConfig{{Name: "db1", User: "user1"}},
+				Metrics:   MetricsConfig{Export: MetricsExportConfig{Enabled: true, Database: "monitoring"}},
+			},
+			expectError: true,
+			errorMsg:    "not a configured database",
+		},
+		{
+			name: "invalid auto_analyze window",
+			config: &Config{
+				AutoAnalyze: AutoAnalyzeConfig{Enabled: true, EstimateRatio: 10, Window: "1am-5am"},
+			},
+			expectError: true,
+			errorMsg:    "auto_analyze.window",
+		},
+		{
+			name: "auto_analyze ratio too small",
+			config: &Config{
+				AutoAnalyze: AutoAnalyzeConfig{Enabled: true, EstimateRatio: 1},
+			},
+			expectError: true,
+			errorMsg:    "estimate_ratio must be at least 2",
+		},
+		{
+			name: "valid auto_analyze window",
+			config: &Config{
+				AutoAnalyze: AutoAnalyzeConfig{Enabled: true, EstimateRatio: 10, Window: "22:00-04:00"},
+			},
+			expectError: false,
+		},
+		{
+			name: "hash masking rule without hash_key",
+			config: &Config{
+				Masking: MaskingConfig{
+					Enabled: true,
+					Rules:   []MaskingRule{{Column: "ssn", Action: "hash"}},
 				},
 			},
 			expectError: true,
-			errorMsg:    "user is required",
+			errorMsg:    "requires masking.hash_key",
+		},
+		{
+			name: "valid masking rules",
+			config: &Config{
+				Masking: MaskingConfig{
+					Enabled: true,
+					HashKey: "masking-key",
+					Rules: []MaskingRule{
+						{Table: `^public\.users$`, Column: "ssn"},
+						{Value: `\d{3}-\d{2}-\d{4}`, Action: "hash"},
+					},
+				},
+			},
+			expectError: false,
 		},
 	}
 
@@ -367,6 +1046,51 @@ func TestGetDatabaseByName(t *testing.T) {
 	}
 }
 
+func TestNamedDatabaseConfig_IsProduction(t *testing.T) {
+	tests := []struct {
+		name string
+		db   NamedDatabaseConfig
+		want bool
+	}{
+		{"unlabelled", NamedDatabaseConfig{}, false},
+		{"staging", NamedDatabaseConfig{Environment: "staging", Tags: []string{"sales"}}, false},
+		{"prod environment", NamedDatabaseConfig{Environment: "prod"}, true},
+		{"production environment", NamedDatabaseConfig{Environment: "Production"}, true},
+		{"prod tag", NamedDatabaseConfig{Environment: "dev", Tags: []string{"sales", "PROD"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.db.IsProduction(); got != tt.want {
+				t.Errorf("IsProduction() = %v, want %v", got, tt.want)
+			}
+			if got := tt.db.WritesAllowed(); got == tt.want {
+				t.Errorf("WritesAllowed() = %v, want %v", got, !tt.want)
+			}
+		})
+	}
+}
+
+func TestNamedDatabaseConfig_AllowWrites(t *testing.T) {
+	allow, deny := true, false
+	tests := []struct {
+		name string
+		db   NamedDatabaseConfig
+		want bool
+	}{
+		{"dev default", NamedDatabaseConfig{Environment: "dev"}, true},
+		{"dev denied", NamedDatabaseConfig{Environment: "dev", AllowWrites: &deny}, false},
+		{"prod default", NamedDatabaseConfig{Environment: "prod"}, false},
+		{"prod allowed", NamedDatabaseConfig{Environment: "prod", AllowWrites: &allow}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.db.WritesAllowed(); got != tt.want {
+				t.Errorf("WritesAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetDefaultDatabaseName(t *testing.T) {
 	// Test with databases
 	cfg := &Config{
@@ -470,6 +1194,10 @@ func TestConfigFileExists(t *testing.T) {
 	if ConfigFileExists(filepath.Join(tmpDir, "nonexistent.yaml")) {
 		t.Error("expected ConfigFileExists to return false for non-existent file")
 	}
+
+	if !ConfigFileExists(StdinConfigPath) {
+		t.Error("expected ConfigFileExists to return true for standard input")
+	}
 }
 
 func TestSaveConfig(t *testing.T) {
@@ -550,6 +1278,209 @@ databases:
 	}
 }
 
+func TestLoadConfigEmbeddingCache(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+embedding:
+    cache:
+        max_entries: 500
+        persist: true
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("PGEDGE_EMBEDDING_CACHE_PATH", "/tmp/embeddings.db")
+
+	cfg, err := LoadConfig(configPath, CLIFlags{ConfigFileSet: true, ConfigFile: configPath})
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cache := cfg.Embedding.Cache
+	if !cache.IsEnabled() || cache.MaxEntries != 500 || !cache.Persist || cache.Path != "/tmp/embeddings.db" {
+		t.Errorf("unexpected embedding cache config: %+v", cache)
+	}
+
+	t.Setenv("PGEDGE_EMBEDDING_CACHE_ENABLED", "false")
+	cfg, err = LoadConfig(configPath, CLIFlags{ConfigFileSet: true, ConfigFile: configPath})
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Embedding.Cache.IsEnabled() {
+		t.Error("expected the environment to disable the cache")
+	}
+}
+
+func TestLoadConfigKnowledgebaseRerank(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+knowledgebase:
+    enabled: true
+    database_path: /tmp/kb.db
+    embedding_ollama_url: http://ollama:11434
+    rerank:
+        enabled: true
+        provider: cohere
+        candidates: 40
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("COHERE_API_KEY", "cohere-key")
+	t.Setenv("PGEDGE_KB_RERANK_TIMEOUT_SECONDS", "3")
+
+	cfg, err := LoadConfig(configPath, CLIFlags{ConfigFileSet: true, ConfigFile: configPath})
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	rerank := cfg.Knowledgebase.Rerank
+	if !rerank.Enabled || rerank.Provider != "cohere" || rerank.Candidates != 40 || rerank.TimeoutSeconds != 3 {
+		t.Errorf("unexpected rerank config: %+v", rerank)
+	}
+	if rerank.APIKey != "cohere-key" {
+		t.Errorf("expected the Cohere API key from the environment, got %q", rerank.APIKey)
+	}
+	if rerank.OllamaURL != "http://ollama:11434" {
+		t.Errorf("expected the embedding Ollama URL as fallback, got %q", rerank.OllamaURL)
+	}
+}
+
+func TestLoadConfigFromStdin(t *testing.T) {
+	stdin := configStdin
+	t.Cleanup(func() { configStdin = stdin })
+	configStdin = &stdinSource{r: strings.NewReader(`
+http:
+    enabled: true
+databases:
+    - name: main
+      host: db.example.com
+      port: 5432
+      database: app
+      user: mcp
+`)}
+	t.Setenv("PGEDGE_HTTP_ADDRESS", ":9090")
+
+	// Standard input is read once; a reload parses it again
+	for i := 0; i < 2; i++ {
+		cfg, err := LoadConfig(StdinConfigPath, CLIFlags{ConfigFileSet: true, ConfigFile: StdinConfigPath})
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		if !cfg.HTTP.Enabled || cfg.HTTP.Address != ":9090" || len(cfg.Databases) != 1 || cfg.Databases[0].Host != "db.example.com" {
+			t.Errorf("unexpected config from standard input: %+v", cfg)
+		}
+	}
+}
+
+func TestLoadConfigJSONEnvironment(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+databases:
+    - name: file
+      user: file_user
+builtins:
+    tools:
+        query_database: false
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("PGEDGE_DATABASES", `[
+		{"name": "main", "host": "db1", "port": 5432, "database": "app", "user": "mcp", "environment": "prod"},
+		{"name": "reporting", "host": "db2", "port": 5433, "database": "reports", "user": "reader", "tags": ["analytics"]}
+	]`)
+	t.Setenv("PGEDGE_DB_PASSWORD", "env-password")
+	t.Setenv("PGEDGE_BUILTINS", `{"tools": {"execute_script": true}}`)
+	t.Setenv("PGEDGE_HEALTH_PROBES", `[{"name": "lag", "sql": "SELECT 'ok', ''"}]`)
+	t.Setenv("PGEDGE_PROXY_GIT", "http://proxy:3128")
+
+	cfg, err := LoadConfig(configPath, CLIFlags{ConfigFileSet: true, ConfigFile: configPath})
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if len(cfg.Databases) != 2 || cfg.Databases[0].Name != "main" || cfg.Databases[1].Port != 5433 ||
+		len(cfg.Databases[1].Tags) != 1 || !cfg.Databases[0].IsProduction() {
+		t.Errorf("expected the databases from PGEDGE_DATABASES, got %+v", cfg.Databases)
+	}
+	// The PGEDGE_DB_ variables apply to the first database
+	if cfg.Databases[0].Password != "env-password" || cfg.Databases[1].Password != "" {
+		t.Errorf("expected PGEDGE_DB_PASSWORD to apply to the first database, got %+v", cfg.Databases)
+	}
+	// PGEDGE_BUILTINS only overrides the toggles it sets
+	if cfg.Builtins.Tools.IsToolEnabled("query_database") || !cfg.Builtins.Tools.IsToolEnabled("execute_script") {
+		t.Errorf("unexpected builtins: %+v", cfg.Builtins.Tools)
+	}
+	if len(cfg.HealthProbes) != 1 || cfg.HealthProbes[0].Name != "lag" {
+		t.Errorf("expected the health probes from PGEDGE_HEALTH_PROBES, got %+v", cfg.HealthProbes)
+	}
+	if cfg.Proxy.Git != "http://proxy:3128" {
+		t.Errorf("expected the Git proxy from PGEDGE_PROXY_GIT, got %q", cfg.Proxy.Git)
+	}
+
+	t.Setenv("PGEDGE_DATABASES", `[{"name": "main"`)
+	if _, err := LoadConfig(configPath, CLIFlags{ConfigFileSet: true, ConfigFile: configPath}); err == nil || !strings.Contains(err.Error(), "PGEDGE_DATABASES") {
+		t.Errorf("expected an error naming PGEDGE_DATABASES, got %v", err)
+	}
+}
+
+func TestLoadConfigSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "anthropic-key")
+	if err := os.WriteFile(keyPath, []byte("file-api-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	configContent := `
+databases:
+    - name: main
+      host: localhost
+      user: postgres
+      password: env:PGEDGE_TEST_DB_PASSWORD
+llm:
+    provider: anthropic
+    anthropic_api_key: file:` + keyPath + `
+masking:
+    enabled: true
+    hash_key: plain-hash-key
+tracing:
+    headers:
+        authorization: env:PGEDGE_TEST_TRACING_AUTHORIZATION
+        x-scope-orgid: ops
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("PGEDGE_TEST_DB_PASSWORD", "env-password")
+	t.Setenv("PGEDGE_TEST_TRACING_AUTHORIZATION", "Bearer tracing-token")
+	t.Setenv("PGEDGE_ANTHROPIC_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	cfg, err := LoadConfig(configPath, CLIFlags{ConfigFileSet: true, ConfigFile: configPath})
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Databases[0].Password != "env-password" {
+		t.Errorf("expected the password from the environment, got %q", cfg.Databases[0].Password)
+	}
+	if cfg.LLM.AnthropicAPIKey != "file-api-key" {
+		t.Errorf("expected the API key from the file, got %q", cfg.LLM.AnthropicAPIKey)
+	}
+	if cfg.Masking.HashKey != "plain-hash-key" {
+		t.Errorf("expected the plain value to be kept, got %q", cfg.Masking.HashKey)
+	}
+	if cfg.Tracing.Headers["authorization"] != "Bearer tracing-token" || cfg.Tracing.Headers["x-scope-orgid"] != "ops" {
+		t.Errorf("expected the tracing header from the environment, got %v", cfg.Tracing.Headers)
+	}
+
+	// A reference that cannot be resolved fails loading and names the setting
+	if err := os.Unsetenv("PGEDGE_TEST_DB_PASSWORD"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(configPath, CLIFlags{ConfigFileSet: true, ConfigFile: configPath})
+	if err == nil || !contains(err.Error(), "databases[main].password") {
+		t.Errorf("expected an error naming the password setting, got %v", err)
+	}
+}
+
 func TestLoadConfigNonExistentFile(t *testing.T) {
 	// Test with ConfigFileSet=true (should error)
 	flags := CLIFlags{ConfigFileSet: true, ConfigFile: "/nonexistent/config.yaml"}
@@ -590,9 +1521,62 @@ func TestGetDefaultSecretPath(t *testing.T) {
 	}
 }
 
+func TestGetDefaultDataDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Setenv("ProgramData", `D:\ProgramData`)
+		if got, want := SystemConfigDir(), `D:\ProgramData\pgEdge`; got != want {
+			t.Errorf("expected system config dir %q, got %q", want, got)
+		}
+		got := GetDefaultDataDir(`C:\Program Files\pgEdge\pgedge-postgres-mcp.exe`)
+		if want := `D:\ProgramData\pgEdge\postgres-mcp\data`; got != want {
+			t.Errorf("expected data dir %q, got %q", want, got)
+		}
+		return
+	}
+
+	if got := SystemConfigDir(); got != "/etc/pgedge" {
+		t.Errorf("expected system config dir /etc/pgedge, got %q", got)
+	}
+	if got := GetDefaultDataDir("/usr/local/bin/pgedge-postgres-mcp"); got != "/usr/local/bin/data" {
+		t.Errorf("expected data dir /usr/local/bin/data, got %q", got)
+	}
+}
+
 func TestMergeConfig(t *testing.T) {
+	falseVal := false
+	trueVal := true
 	dest := defaultConfig()
 	src := &Config{
+		Builtins: BuiltinsConfig{Tools: ToolsConfig{
+			CountRows:           &falseVal,
+			ExplainSQL:          &falseVal,
+			ValidateSQL:         &falseVal,
+			ComparePlan:         &falseVal,
+			PlanSchemaChange:    &falseVal,
+			GetTableStats:       &falseVal,
+			IndexAdvisor:        &falseVal,
+			DatabaseHealthCheck: &falseVal,
+			LockAnalysis:        &falseVal,
+			CheckCollations:     &falseVal,
+			GenerateMigration:   &falseVal,
+			ExportQueryResults:  &falseVal,
+			HybridSearch:        &falseVal,
+			GetContextUsage:     &falseVal,
+			ListDatabases:       &falseVal,
+			RefreshSchemaCache:  &falseVal,
+			FindRelevantTables:  &falseVal,
+			GetQueryHistory:     &falseVal,
+			ListJobs:            &falseVal,
+			ExecuteScript:       &trueVal,
+			ApplyMigration:      &trueVal,
+			CreateVectorIndex:   &trueVal,
+			InstallExtension:    &trueVal,
+			SetupSemanticSearch: &trueVal,
+			SetupForeignServer:  &trueVal,
+
+			AddDatabaseConnection: &trueVal,
+		}},
+		Extensions: ExtensionsConfig{Allowed: []string{"postgis"}},
 		HTTP: HTTPConfig{
 			Enabled: true,
 			Address: ":9090",
@@ -601,6 +1585,28 @@ func TestMergeConfig(t *testing.T) {
 			{Name: "newdb", Host: "newhost"},
 		},
 		SecretFile: "/new/secret",
+		Conversations: ConversationsConfig{
+			Backend:     "postgres",
+			Database:    "newdb",
+			Encrypt:     &falseVal,
+			PerUserKeys: true,
+			MaxAgeDays:  90,
+		},
+		LLM: LLMConfig{
+			Enabled:             true,
+			Provider:            "ollama",
+			CompactionSummaries: true,
+			ContextWindow:       32768,
+			Fallback:            []LLMRouteConfig{{Provider: "ollama", Model: "llama3.1"}},
+			AzureEndpoint:       "https://example.openai.azure.com",
+			BedrockRegion:       "eu-west-1",
+		},
+		Proxy: netproxy.Settings{
+			URL:     "http://proxy:3128",
+			Azure:   "http://azure-proxy:3128",
+			Bedrock: "socks5://bedrock-proxy:1080",
+			Git:     "http://git-proxy:3128",
+		},
 	}
 
 	mergeConfig(dest, src)
@@ -617,6 +1623,41 @@ func TestMergeConfig(t *testing.T) {
 	if dest.SecretFile != "/new/secret" {
 		t.Errorf("expected SecretFile '/new/secret', got %q", dest.SecretFile)
 	}
+	for _, tool := range []string{"count_rows", "explain_sql", "validate_sql", "compare_plan", "plan_schema_change", "get_table_stats", "index_advisor", "database_health_check", "lock_analysis", "check_collations", "generate_migration", "export_query_results", "hybrid_search", "get_context_usage", "list_databases", "refresh_schema_cache", "find_relevant_tables", "get_query_history", "list_jobs"} {
+		if dest.Builtins.Tools.IsToolEnabled(tool) {
+			t.Errorf("expected %s to be disabled by the merged config", tool)
+		}
+	}
+	for _, tool := range []string{"execute_script", "apply_migration", "create_vector_index", "install_extension", "setup_semantic_search", "setup_foreign_server", "add_database_connection"} {
+		if !dest.Builtins.Tools.IsToolEnabled(tool) {
+			t.Errorf("expected %s to be enabled by the merged config", tool)
+		}
+	}
+	if !dest.Extensions.IsAllowed("postgis") || dest.Extensions.IsAllowed("vector") {
+		t.Errorf("expected the allowed extensions to be replaced, got %v", dest.Extensions.Allowed)
+	}
+	if dest.Conversations.EncryptionEnabled() || !dest.Conversations.PerUserKeys {
+		t.Error("expected conversation encryption settings to be merged")
+	}
+	if dest.Conversations.MaxAgeDays != 90 || dest.Conversations.PurgeIntervalMinutes != 60 {
+		t.Errorf("expected max age 90 and the default purge interval, got %+v", dest.Conversations)
+	}
+	if !dest.Conversations.UsesPostgres() || dest.Conversations.Database != "newdb" || dest.Conversations.DeletedRetentionDays != 30 {
+		t.Errorf("expected the postgres backend with the default deleted retention, got %+v", dest.Conversations)
+	}
+	if !dest.LLM.CompactionSummaries || dest.LLM.ContextWindow != 32768 {
+		t.Errorf("expected LLM compaction settings to be merged, got %+v", dest.LLM)
+	}
+	if len(dest.LLM.Fallback) != 1 || dest.LLM.Fallback[0].Model != "llama3.1" {
+		t.Errorf("expected LLM fallbacks to be merged, got %+v", dest.LLM.Fallback)
+	}
+	if dest.LLM.AzureEndpoint != "https://example.openai.azure.com" || dest.LLM.BedrockRegion != "eu-west-1" {
+		t.Errorf("expected Azure OpenAI and Bedrock settings to be merged, got %+v", dest.LLM)
+	}
+	if dest.Proxy.URL != "http://proxy:3128" || dest.Proxy.Azure != "http://azure-proxy:3128" ||
+		dest.Proxy.Bedrock != "socks5://bedrock-proxy:1080" || dest.Proxy.Git != "http://git-proxy:3128" {
+		t.Errorf("expected proxy settings to be merged, got %+v", dest.Proxy)
+	}
 }
 
 func TestApplyCLIFlags(t *testing.T) {
@@ -690,6 +1731,20 @@ func TestSetBoolFromEnv(t *testing.T) {
 	os.Unsetenv("TEST_BOOL_VAR")
 }
 
+func TestSetBoolPtrFromEnv(t *testing.T) {
+	var dest *bool
+	setBoolPtrFromEnv(&dest, "TEST_BOOL_PTR_VAR")
+	if dest != nil {
+		t.Errorf("expected nil without the variable, got %v", *dest)
+	}
+
+	t.Setenv("TEST_BOOL_PTR_VAR", "false")
+	setBoolPtrFromEnv(&dest, "TEST_BOOL_PTR_VAR")
+	if dest == nil || *dest {
+		t.Errorf("expected false, got %v", dest)
+	}
+}
+
 func TestSetIntFromEnv(t *testing.T) {
 	os.Setenv("TEST_INT_VAR", "42")
 	defer os.Unsetenv("TEST_INT_VAR")
@@ -709,3 +1764,94 @@ func TestSetIntFromEnv(t *testing.T) {
 		t.Errorf("expected 0 for invalid int, got %d", dest)
 	}
 }
+
+func TestParseLLMRoutes(t *testing.T) {
+	routes := parseLLMRoutes("openai:gpt-4o, ollama:llama3.1:70b,")
+	want := []LLMRouteConfig{{Provider: "openai", Model: "gpt-4o"}, {Provider: "ollama", Model: "llama3.1:70b"}}
+	if len(routes) != len(want) {
+		t.Fatalf("parseLLMRoutes() = %+v, want %+v", routes, want)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("route %d = %+v, want %+v", i, routes[i], want[i])
+		}
+	}
+}
+
+func TestParseHeaderList(t *testing.T) {
+	headers := parseHeaderList("x-honeycomb-team=abc123, Authorization=Basic%20dXNlcjpwYXNz,invalid,=x")
+	if len(headers) != 2 || headers["x-honeycomb-team"] != "abc123" || headers["Authorization"] != "Basic dXNlcjpwYXNz" {
+		t.Errorf("parseHeaderList() = %v", headers)
+	}
+}
+
+func TestOfflineDisabledTools(t *testing.T) {
+	enabled := true
+	cfg := &Config{
+		Builtins:      BuiltinsConfig{Tools: ToolsConfig{SetupSemanticSearch: &enabled}},
+		Embedding:     EmbeddingConfig{Enabled: true, Provider: "voyage"},
+		Knowledgebase: KnowledgebaseConfig{Enabled: true, EmbeddingProvider: "ollama"},
+		LLM:           LLMConfig{Enabled: true, Provider: "anthropic"},
+	}
+
+	// Online: nothing is disabled
+	if tools := cfg.OfflineDisabledTools(); tools != nil {
+		t.Errorf("expected no disabled tools when online, got %v", tools)
+	}
+	if !cfg.IsToolAvailable("setup_semantic_search") {
+		t.Error("expected setup_semantic_search to be available when online")
+	}
+	if cfg.OfflineDisablesLLM() {
+		t.Error("expected LLM proxy to be available when online")
+	}
+
+	cfg.Offline = true
+	if cfg.IsToolAvailable("generate_embedding") || cfg.IsToolAvailable("similarity_search") || cfg.IsToolAvailable("hybrid_search") {
+		t.Error("expected cloud embedding tools to be unavailable offline")
+	}
+	if cfg.IsToolAvailable("setup_semantic_search") {
+		t.Error("expected setup_semantic_search with a cloud embedding provider to be unavailable offline")
+	}
+	if !cfg.IsToolAvailable("search_knowledgebase") {
+		t.Error("expected knowledgebase search with Ollama to remain available offline")
+	}
+	if !cfg.IsToolAvailable("query_database") {
+		t.Error("expected database tools to remain available offline")
+	}
+	if !cfg.OfflineDisablesLLM() {
+		t.Error("expected Anthropic LLM proxy to be disabled offline")
+	}
+
+	cfg.LLM.Provider = "ollama"
+	if cfg.OfflineDisablesLLM() {
+		t.Error("expected Ollama LLM proxy to remain available offline")
+	}
+}
+
+func TestMetadataConfig_IncludesSchema(t *testing.T) {
+	all := MetadataConfig{}
+	if all.IsFiltered() || !all.IncludesSchema("anything") {
+		t.Error("expected an empty metadata config to include every schema")
+	}
+
+	scope := MetadataConfig{
+		Schemas:        []string{"public", "sales_*"},
+		ExcludeSchemas: []string{"sales_archive*"},
+	}
+	tests := map[string]bool{
+		"public":            true,
+		"sales_eu":          true,
+		"sales_archive2019": false,
+		"audit":             false,
+	}
+	for schema, want := range tests {
+		if got := scope.IncludesSchema(schema); got != want {
+			t.Errorf("IncludesSchema(%q) = %v, want %v", schema, got, want)
+		}
+	}
+
+	excludeOnly := MetadataConfig{ExcludeSchemas: []string{"tenant_*"}}
+	if !excludeOnly.IsFiltered() || excludeOnly.IncludesSchema("tenant_42") || !excludeOnly.IncludesSchema("public") {
+		t.Error("expected exclude_schemas alone to skip only matching schemas")
+	}
+}
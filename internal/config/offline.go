@@ -0,0 +1,59 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package config
+
+// IsCloudProvider reports whether an LLM or embedding provider is a hosted
+// service reached over the internet. Ollama is treated as local because
+// air-gapped deployments run it on their own network.
+func IsCloudProvider(provider string) bool {
+	switch provider {
+	case "anthropic", "openai", "azure", "bedrock", "voyage", "cohere":
+		return true
+	default:
+		return false
+	}
+}
+
+// OfflineDisabledTools returns the built-in tools that offline mode disables
+// because they depend on a cloud provider. Returns nil when not offline.
+func (c *Config) OfflineDisabledTools() []string {
+	if !c.Offline {
+		return nil
+	}
+
+	var disabled []string
+	if c.Embedding.Enabled && IsCloudProvider(c.Embedding.Provider) {
+		disabled = append(disabled, "generate_embedding", "similarity_search", "hybrid_search", "setup_semantic_search")
+	}
+	if c.Knowledgebase.Enabled && IsCloudProvider(c.Knowledgebase.EmbeddingProvider) {
+		disabled = append(disabled, "search_knowledgebase")
+	}
+	return disabled
+}
+
+// OfflineDisablesLLM reports whether offline mode disables the LLM proxy
+func (c *Config) OfflineDisablesLLM() bool {
+	return c.Offline && c.LLM.Enabled && IsCloudProvider(c.LLM.Provider)
+}
+
+// IsToolAvailable reports whether a built-in tool is enabled and usable,
+// taking offline mode into account
+func (c *Config) IsToolAvailable(toolName string) bool {
+	if !c.Builtins.Tools.IsToolEnabled(toolName) {
+		return false
+	}
+	for _, name := range c.OfflineDisabledTools() {
+		if name == toolName {
+			return false
+		}
+	}
+	return true
+}
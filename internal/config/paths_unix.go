@@ -0,0 +1,26 @@
+//go:build !windows
+
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package config
+
+import "path/filepath"
+
+// SystemConfigDir returns the system-wide configuration directory, which
+// is searched before the binary directory for the default files
+func SystemConfigDir() string {
+	return "/etc/pgedge"
+}
+
+// defaultDataDir returns the default data directory, next to the binary
+func defaultDataDir(binaryPath string) string {
+	return filepath.Join(filepath.Dir(binaryPath), "data")
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SystemConfigDir returns the system-wide configuration directory,
+// %ProgramData%\pgEdge, which is searched before the binary directory for
+// the default files
+func SystemConfigDir() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "pgEdge")
+}
+
+// defaultDataDir returns the default data directory. Services can't write
+// next to a binary installed under Program Files, so data is kept in
+// %ProgramData%\pgEdge\postgres-mcp\data.
+func defaultDataDir(string) string {
+	return filepath.Join(SystemConfigDir(), "postgres-mcp", "data")
+}
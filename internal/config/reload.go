@@ -13,6 +13,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"sync"
 )
 
@@ -76,7 +77,11 @@ func (rc *ReloadableConfig) Reload() error {
 	}
 
 	// Log successful reload
-	fmt.Fprintf(os.Stderr, "Configuration reloaded successfully from %s\n", rc.path)
+	source := rc.path
+	if source == StdinConfigPath {
+		source = "standard input"
+	}
+	fmt.Fprintf(os.Stderr, "Configuration reloaded successfully from %s\n", source)
 	fmt.Fprintf(os.Stderr, "  Databases: %d configured\n", len(newConfig.Databases))
 
 	// Log if databases changed
@@ -100,18 +105,54 @@ func (rc *ReloadableConfig) logRestartRequiredSettings(newConfig *Config) {
 		fmt.Fprintf(os.Stderr, "  WARNING: http.address changed - requires restart\n")
 	}
 
-	// TLS changes require restart
+	// Enabling or disabling TLS requires restart; certificates are reloaded in place
 	if old.HTTP.TLS.Enabled != newConfig.HTTP.TLS.Enabled {
 		fmt.Fprintf(os.Stderr, "  WARNING: http.tls.enabled changed - requires restart\n")
 	}
-	if old.HTTP.TLS.CertFile != newConfig.HTTP.TLS.CertFile {
-		fmt.Fprintf(os.Stderr, "  WARNING: http.tls.cert_file changed - requires restart\n")
+	if !reflect.DeepEqual(old.HTTP.TLS.ACME, newConfig.HTTP.TLS.ACME) {
+		fmt.Fprintf(os.Stderr, "  WARNING: http.tls.acme changed - requires restart\n")
+	}
+	if old.HTTP.TLS.CertFile != newConfig.HTTP.TLS.CertFile || old.HTTP.TLS.KeyFile != newConfig.HTTP.TLS.KeyFile {
+		fmt.Fprintf(os.Stderr, "  NOTE: TLS certificate files changed\n")
+	}
+
+	// Authentication mode changes require restart
+	if old.HTTP.Auth.Enabled != newConfig.HTTP.Auth.Enabled {
+		fmt.Fprintf(os.Stderr, "  WARNING: http.auth.enabled changed - requires restart\n")
+	}
+	if old.HTTP.SessionAffinity != newConfig.HTTP.SessionAffinity {
+		fmt.Fprintf(os.Stderr, "  WARNING: http.session_affinity changed - requires restart\n")
+	}
+	if !reflect.DeepEqual(old.HTTP.CORS, newConfig.HTTP.CORS) {
+		fmt.Fprintf(os.Stderr, "  WARNING: http.cors changed - requires restart\n")
+	}
+	if old.HTTP.Limits != newConfig.HTTP.Limits {
+		fmt.Fprintf(os.Stderr, "  WARNING: http.limits changed - requires restart\n")
 	}
-	if old.HTTP.TLS.KeyFile != newConfig.HTTP.TLS.KeyFile {
-		fmt.Fprintf(os.Stderr, "  WARNING: http.tls.key_file changed - requires restart\n")
+	if old.Metrics != newConfig.Metrics {
+		fmt.Fprintf(os.Stderr, "  WARNING: metrics.export changed - requires restart\n")
 	}
 
-	// LLM/embedding provider changes are logged (may work but connections need reset)
+	// LLM, knowledgebase and embedding changes apply to new requests
+	if old.Offline != newConfig.Offline {
+		fmt.Fprintf(os.Stderr, "  NOTE: offline mode changed to %t\n", newConfig.Offline)
+	}
+	if old.LLM.Enabled != newConfig.LLM.Enabled {
+		fmt.Fprintf(os.Stderr, "  NOTE: llm.enabled changed to %t\n", newConfig.LLM.Enabled)
+	}
+	if old.Knowledgebase.DatabasePath != newConfig.Knowledgebase.DatabasePath {
+		fmt.Fprintf(os.Stderr, "  NOTE: knowledgebase.database_path changed to %s\n", newConfig.Knowledgebase.DatabasePath)
+	}
+	if old.Knowledgebase.Backend != newConfig.Knowledgebase.Backend {
+		fmt.Fprintf(os.Stderr, "  NOTE: knowledgebase.backend changed to %s\n", newConfig.Knowledgebase.Backend)
+	}
+	if old.Knowledgebase.ConnectionString != newConfig.Knowledgebase.ConnectionString ||
+		old.Knowledgebase.Schema != newConfig.Knowledgebase.Schema {
+		fmt.Fprintf(os.Stderr, "  NOTE: knowledgebase connection settings changed\n")
+	}
+	if old.Knowledgebase.RetryIntervalSeconds != newConfig.Knowledgebase.RetryIntervalSeconds {
+		fmt.Fprintf(os.Stderr, "  NOTE: knowledgebase.retry_interval_seconds changed to %d\n", newConfig.Knowledgebase.RetryIntervalSeconds)
+	}
 	if old.LLM.Provider != newConfig.LLM.Provider {
 		fmt.Fprintf(os.Stderr, "  NOTE: llm.provider changed to %s\n", newConfig.LLM.Provider)
 	}
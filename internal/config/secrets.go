@@ -0,0 +1,82 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"pgedge-postgres-mcp/internal/secrets"
+)
+
+// secretField is a setting that may hold a secret reference
+type secretField struct {
+	name   string
+	value  *string
+	assign func(string) // Stores the resolved value, for map entries (optional)
+}
+
+// secretFields returns the password and API key settings that may hold a
+// secret reference such as "vault:secret/data/pgedge#password"
+func (c *Config) secretFields() []secretField {
+	fields := make([]secretField, 0, len(c.Databases)+len(c.Tracing.Headers)+10)
+	for i := range c.Databases {
+		fields = append(fields, secretField{fmt.Sprintf("databases[%s].password", c.Databases[i].Name), &c.Databases[i].Password, nil})
+	}
+	for i := range c.Notifications.Endpoints {
+		endpoint := &c.Notifications.Endpoints[i]
+		fields = append(fields, secretField{fmt.Sprintf("notifications.endpoints[%s].url", endpoint.Name), &endpoint.URL, nil})
+	}
+	names := make([]string, 0, len(c.Tracing.Headers))
+	for name := range c.Tracing.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := c.Tracing.Headers[name]
+		fields = append(fields, secretField{fmt.Sprintf("tracing.headers[%s]", name), &value, func(resolved string) {
+			c.Tracing.Headers[name] = resolved
+		}})
+	}
+	return append(fields,
+		secretField{"masking.hash_key", &c.Masking.HashKey, nil},
+		secretField{"embedding.voyage_api_key", &c.Embedding.VoyageAPIKey, nil},
+		secretField{"embedding.openai_api_key", &c.Embedding.OpenAIAPIKey, nil},
+		secretField{"llm.anthropic_api_key", &c.LLM.AnthropicAPIKey, nil},
+		secretField{"llm.openai_api_key", &c.LLM.OpenAIAPIKey, nil},
+		secretField{"llm.azure_api_key", &c.LLM.AzureAPIKey, nil},
+		secretField{"knowledgebase.connection_string", &c.Knowledgebase.ConnectionString, nil},
+		secretField{"knowledgebase.embedding_voyage_api_key", &c.Knowledgebase.EmbeddingVoyageAPIKey, nil},
+		secretField{"knowledgebase.embedding_openai_api_key", &c.Knowledgebase.EmbeddingOpenAIAPIKey, nil},
+		secretField{"knowledgebase.rerank.api_key", &c.Knowledgebase.Rerank.APIKey, nil},
+	)
+}
+
+// resolveSecrets replaces secret references in the password and API key
+// settings with the secrets they refer to. Settings that hold plain values
+// are left as they are.
+func resolveSecrets(ctx context.Context, cfg *Config, resolver *secrets.Resolver) error {
+	for _, field := range cfg.secretFields() {
+		if !secrets.IsReference(*field.value) {
+			continue
+		}
+		value, err := resolver.Resolve(ctx, *field.value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", field.name, err)
+		}
+		*field.value = value
+		if field.assign != nil {
+			field.assign(value)
+		}
+	}
+	return nil
+}
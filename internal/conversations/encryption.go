@@ -0,0 +1,283 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"pgedge-postgres-mcp/internal/crypto"
+)
+
+// EncryptionMode selects how conversation titles and messages are stored
+type EncryptionMode int
+
+const (
+	// EncryptionNone stores conversations as plaintext
+	EncryptionNone EncryptionMode = iota
+	// EncryptionServerKey encrypts with one key derived from the server secret
+	EncryptionServerKey
+	// EncryptionPerUserKey encrypts with a key derived for each user
+	EncryptionPerUserKey
+)
+
+// Stored values are prefixed with the format version and the kind of key
+// used, so plaintext from before encryption was enabled stays readable and
+// rows can be migrated when the mode changes
+const (
+	encryptedPrefix  = "enc:v1:"
+	serverKeyPrefix  = encryptedPrefix + "s:"
+	perUserKeyPrefix = encryptedPrefix + "u:"
+)
+
+// HKDF purposes for the keys derived from the server secret
+const (
+	serverKeyPurpose  = "pgedge-postgres-mcp conversations"
+	perUserKeyPurpose = "pgedge-postgres-mcp conversations user:"
+)
+
+// conversationCipher seals and opens stored conversation fields
+type conversationCipher struct {
+	mode      EncryptionMode
+	secret    *crypto.EncryptionKey
+	serverKey *crypto.EncryptionKey
+
+	mu       sync.Mutex
+	userKeys map[string]*crypto.EncryptionKey
+}
+
+// newConversationCipher creates a cipher for mode. secret may be nil only
+// when mode is EncryptionNone, in which case encrypted values cannot be read.
+func newConversationCipher(secret *crypto.EncryptionKey, mode EncryptionMode) (*conversationCipher, error) {
+	c := &conversationCipher{
+		mode:     mode,
+		secret:   secret,
+		userKeys: make(map[string]*crypto.EncryptionKey),
+	}
+	if secret == nil {
+		if mode != EncryptionNone {
+			return nil, fmt.Errorf("an encryption secret is required to encrypt conversations")
+		}
+		return c, nil
+	}
+
+	serverKey, err := secret.DeriveKey(serverKeyPurpose)
+	if err != nil {
+		return nil, err
+	}
+	c.serverKey = serverKey
+	return c, nil
+}
+
+// userKey returns the key derived for username, caching it
+func (c *conversationCipher) userKey(username string) (*crypto.EncryptionKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.userKeys[username]; ok {
+		return key, nil
+	}
+	key, err := c.secret.DeriveKey(perUserKeyPurpose + username)
+	if err != nil {
+		return nil, err
+	}
+	c.userKeys[username] = key
+	return key, nil
+}
+
+// seal encodes plaintext for storage according to the cipher's mode
+func (c *conversationCipher) seal(username, plaintext string) (string, error) {
+	switch c.mode {
+	case EncryptionServerKey:
+		ciphertext, err := c.serverKey.Encrypt(plaintext)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt conversation: %w", err)
+		}
+		return serverKeyPrefix + ciphertext, nil
+
+	case EncryptionPerUserKey:
+		key, err := c.userKey(username)
+		if err != nil {
+			return "", err
+		}
+		ciphertext, err := key.Encrypt(plaintext)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt conversation: %w", err)
+		}
+		return perUserKeyPrefix + ciphertext, nil
+	}
+	return plaintext, nil
+}
+
+// open decodes a stored value, whichever mode it was sealed with
+func (c *conversationCipher) open(username, stored string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		return stored, nil
+	}
+	if c.secret == nil {
+		return "", fmt.Errorf("conversation is encrypted but no encryption secret is configured")
+	}
+
+	var key *crypto.EncryptionKey
+	var ciphertext string
+	switch {
+	case strings.HasPrefix(stored, serverKeyPrefix):
+		key, ciphertext = c.serverKey, stored[len(serverKeyPrefix):]
+	case strings.HasPrefix(stored, perUserKeyPrefix):
+		var err error
+		if key, err = c.userKey(username); err != nil {
+			return "", err
+		}
+		ciphertext = stored[len(perUserKeyPrefix):]
+	default:
+		return "", fmt.Errorf("unsupported conversation encryption format")
+	}
+
+	plaintext, err := key.Decrypt(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt conversation (was the secret file changed?): %w", err)
+	}
+	return plaintext, nil
+}
+
+// sealedInMode reports whether a stored value is already in the cipher's mode
+func (c *conversationCipher) sealedInMode(stored string) bool {
+	switch c.mode {
+	case EncryptionServerKey:
+		return strings.HasPrefix(stored, serverKeyPrefix)
+	case EncryptionPerUserKey:
+		return strings.HasPrefix(stored, perUserKeyPrefix)
+	}
+	return !strings.HasPrefix(stored, encryptedPrefix)
+}
+
+// sealConversation encodes a conversation's title and messages for storage
+func (c *conversationCipher) sealConversation(username, title string, messages []Message) (storedTitle, storedMessages string, err error) {
+	messagesJSON, err := json.Marshal(messages)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal messages: %w", err)
+	}
+	if storedTitle, err = c.seal(username, title); err != nil {
+		return "", "", err
+	}
+	if storedMessages, err = c.seal(username, string(messagesJSON)); err != nil {
+		return "", "", err
+	}
+	return storedTitle, storedMessages, nil
+}
+
+// openConversation decodes a conversation's stored title and messages
+func (c *conversationCipher) openConversation(username, storedTitle, storedMessages string) (string, []Message, error) {
+	title, err := c.open(username, storedTitle)
+	if err != nil {
+		return "", nil, err
+	}
+	messagesJSON, err := c.open(username, storedMessages)
+	if err != nil {
+		return "", nil, err
+	}
+	var messages []Message
+	if err := json.Unmarshal([]byte(messagesJSON), &messages); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal messages: %w", err)
+	}
+	return title, messages, nil
+}
+
+// reseal re-encodes a stored title and messages that were sealed in a
+// different mode; rewrite is false when both are already in the cipher's
+// mode
+func (c *conversationCipher) reseal(username, storedTitle, storedMessages string) (title, messages string, rewrite bool, err error) {
+	if c.sealedInMode(storedTitle) && c.sealedInMode(storedMessages) {
+		return storedTitle, storedMessages, false, nil
+	}
+	resealed := [2]string{}
+	for i, stored := range []string{storedTitle, storedMessages} {
+		plaintext, err := c.open(username, stored)
+		if err != nil {
+			return "", "", false, err
+		}
+		if resealed[i], err = c.seal(username, plaintext); err != nil {
+			return "", "", false, err
+		}
+	}
+	return resealed[0], resealed[1], true, nil
+}
+
+// SetEncryption sets how conversations are stored from now on and rewrites
+// existing rows that were stored in a different mode, returning the number
+// of rows rewritten. secret is needed to read encrypted rows, so it should
+// be passed whenever one is available, even with EncryptionNone.
+func (s *Store) SetEncryption(secret *crypto.EncryptionKey, mode EncryptionMode) (int, error) {
+	c, err := newConversationCipher(secret, mode)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query("SELECT id, username, title, messages FROM conversations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query conversations: %w", err)
+	}
+
+	type rewrite struct {
+		id, title, messages string
+	}
+	var rewrites []rewrite
+	for rows.Next() {
+		var id, username, title, messages string
+		if err := rows.Scan(&id, &username, &title, &messages); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		r := rewrite{id: id}
+		var changed bool
+		if r.title, r.messages, changed, err = c.reseal(username, title, messages); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("conversation %s: %w", id, err)
+		}
+		if changed {
+			rewrites = append(rewrites, r)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if len(rewrites) > 0 {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return 0, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		for _, r := range rewrites {
+			if _, err := tx.Exec("UPDATE conversations SET title = ?, messages = ? WHERE id = ?",
+				r.title, r.messages, r.id); err != nil {
+				_ = tx.Rollback()
+				return 0, fmt.Errorf("failed to rewrite conversation %s: %w", r.id, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("failed to commit rewritten conversations: %w", err)
+		}
+
+		// Don't leave the previous copies in the write-ahead log
+		if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			return 0, fmt.Errorf("failed to checkpoint database: %w", err)
+		}
+	}
+
+	s.cipher = c
+	return len(rewrites), nil
+}
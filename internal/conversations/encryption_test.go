@@ -0,0 +1,166 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/crypto"
+)
+
+// rawConversation returns the stored title and messages of a conversation
+func rawConversation(t *testing.T, store *Store, id string) (title, messages string) {
+	t.Helper()
+	if err := store.db.QueryRow("SELECT title, messages FROM conversations WHERE id = ?", id).
+		Scan(&title, &messages); err != nil {
+		t.Fatalf("Failed to read raw conversation: %v", err)
+	}
+	return title, messages
+}
+
+func newTestKey(t *testing.T) *crypto.EncryptionKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+func TestEncryptedConversations(t *testing.T) {
+	for _, mode := range []EncryptionMode{EncryptionServerKey, EncryptionPerUserKey} {
+		store, err := NewStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		defer store.Close()
+
+		if _, err := store.SetEncryption(newTestKey(t), mode); err != nil {
+			t.Fatalf("SetEncryption failed: %v", err)
+		}
+
+		messages := []Message{{Role: "user", Content: "Show me the salary table"}}
+		conv, err := store.Create("alice", "", "", "", messages)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+
+		title, stored := rawConversation(t, store, conv.ID)
+		if strings.Contains(title, "salary") || strings.Contains(stored, "salary") {
+			t.Errorf("mode %d: expected ciphertext at rest, got title %q messages %q", mode, title, stored)
+		}
+		if !strings.HasPrefix(stored, encryptedPrefix) {
+			t.Errorf("mode %d: expected encrypted prefix, got %q", mode, stored)
+		}
+
+		got, err := store.Get(conv.ID, "alice")
+		if err != nil {
+			t.Fatalf("Failed to get conversation: %v", err)
+		}
+		if got.Title != "Show me the salary table" || got.Messages[0].Content != "Show me the salary table" {
+			t.Errorf("mode %d: unexpected decrypted conversation %+v", mode, got)
+		}
+
+		if err := store.Rename(conv.ID, "alice", "Payroll"); err != nil {
+			t.Fatalf("Failed to rename conversation: %v", err)
+		}
+		summaries, err := store.List("alice", 10, 0)
+		if err != nil {
+			t.Fatalf("Failed to list conversations: %v", err)
+		}
+		if len(summaries) != 1 || summaries[0].Title != "Payroll" || summaries[0].Preview != "Show me the salary table" {
+			t.Errorf("mode %d: unexpected summaries %+v", mode, summaries)
+		}
+	}
+}
+
+func TestPerUserKeysDiffer(t *testing.T) {
+	secret := newTestKey(t)
+	c, err := newConversationCipher(secret, EncryptionPerUserKey)
+	if err != nil {
+		t.Fatalf("newConversationCipher failed: %v", err)
+	}
+
+	sealed, err := c.seal("alice", "secret data")
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	if _, err := c.open("bob", sealed); err == nil {
+		t.Error("Expected another user's key to fail to decrypt")
+	}
+	if got, err := c.open("alice", sealed); err != nil || got != "secret data" {
+		t.Errorf("open() = %q, %v", got, err)
+	}
+}
+
+func TestSetEncryptionMigratesRows(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// A conversation stored before encryption was enabled
+	conv, err := store.Create("alice", "", "", "", []Message{{Role: "user", Content: "plaintext question"}})
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	secret := newTestKey(t)
+	for _, step := range []struct {
+		mode     EncryptionMode
+		migrated int
+		prefix   string
+	}{
+		{EncryptionServerKey, 1, serverKeyPrefix},
+		{EncryptionServerKey, 0, serverKeyPrefix},
+		{EncryptionPerUserKey, 1, perUserKeyPrefix},
+		{EncryptionNone, 1, "["},
+	} {
+		migrated, err := store.SetEncryption(secret, step.mode)
+		if err != nil {
+			t.Fatalf("SetEncryption(%d) failed: %v", step.mode, err)
+		}
+		if migrated != step.migrated {
+			t.Errorf("SetEncryption(%d) migrated %d rows, want %d", step.mode, migrated, step.migrated)
+		}
+		if _, stored := rawConversation(t, store, conv.ID); !strings.HasPrefix(stored, step.prefix) {
+			t.Errorf("mode %d: expected stored messages to start with %q, got %q", step.mode, step.prefix, stored)
+		}
+		if got, err := store.Get(conv.ID, "alice"); err != nil || got.Title != "plaintext question" {
+			t.Errorf("mode %d: Get() = %+v, %v", step.mode, got, err)
+		}
+	}
+}
+
+func TestSetEncryptionWrongSecret(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.SetEncryption(newTestKey(t), EncryptionServerKey); err != nil {
+		t.Fatalf("SetEncryption failed: %v", err)
+	}
+	if _, err := store.Create("alice", "", "", "", []Message{{Role: "user", Content: "hi"}}); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	// A different secret can't read the rows, so nothing is rewritten
+	if _, err := store.SetEncryption(newTestKey(t), EncryptionPerUserKey); err == nil {
+		t.Error("Expected an error when the secret has changed")
+	}
+	if _, err := store.SetEncryption(nil, EncryptionServerKey); err == nil {
+		t.Error("Expected an error when encrypting without a secret")
+	}
+}
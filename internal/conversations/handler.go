@@ -12,6 +12,7 @@ package conversations
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -22,18 +23,26 @@ import (
 
 // Handler handles conversation API requests
 type Handler struct {
-	store     *Store
-	userStore *auth.UserStore
+	store         *Store            // Memories and preferences
+	conversations ConversationStore // Conversations; store unless replaced
+	userStore     *auth.UserStore
 }
 
 // NewHandler creates a new conversation handler
 func NewHandler(store *Store, userStore *auth.UserStore) *Handler {
 	return &Handler{
-		store:     store,
-		userStore: userStore,
+		store:         store,
+		conversations: store,
+		userStore:     userStore,
 	}
 }
 
+// SetConversationStore serves conversations from cs instead of the store
+// passed to NewHandler, which keeps serving memories and preferences
+func (h *Handler) SetConversationStore(cs ConversationStore) {
+	h.conversations = cs
+}
+
 // extractUsername extracts the username from the session token
 func (h *Handler) extractUsername(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
@@ -95,7 +104,7 @@ func (h *Handler) HandleList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	conversations, err := h.store.List(username, limit, offset)
+	conversations, err := h.conversations.List(username, limit, offset)
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, "Failed to list conversations")
 		return
@@ -131,7 +140,7 @@ func (h *Handler) HandleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conv, err := h.store.Get(id, username)
+	conv, err := h.conversations.Get(id, username)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			sendError(w, http.StatusNotFound, "Conversation not found")
@@ -176,7 +185,7 @@ func (h *Handler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conv, err := h.store.Create(username, req.Provider, req.Model, req.Connection, req.Messages)
+	conv, err := h.conversations.Create(username, req.Provider, req.Model, req.Connection, req.Messages)
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, "Failed to create conversation")
 		return
@@ -219,7 +228,7 @@ func (h *Handler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conv, err := h.store.Update(id, username, req.Provider, req.Model, req.Connection, req.Messages)
+	conv, err := h.conversations.Update(id, username, req.Provider, req.Model, req.Connection, req.Messages)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			sendError(w, http.StatusNotFound, "Conversation not found")
@@ -270,7 +279,7 @@ func (h *Handler) HandleRename(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.store.Rename(id, username, req.Title)
+	err = h.conversations.Rename(id, username, req.Title)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
 			sendError(w, http.StatusNotFound, "Conversation not found")
@@ -303,7 +312,7 @@ func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.store.Delete(id, username)
+	err = h.conversations.Delete(id, username)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
 			sendError(w, http.StatusNotFound, "Conversation not found")
@@ -329,7 +338,7 @@ func (h *Handler) HandleDeleteAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	count, err := h.store.DeleteAll(username)
+	count, err := h.conversations.DeleteAll(username)
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, "Failed to delete conversations")
 		return
@@ -341,6 +350,181 @@ func (h *Handler) HandleDeleteAll(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// MemoryRequest represents a request to remember a fact or preference
+type MemoryRequest struct {
+	Content string `json:"content"`
+}
+
+// HandleListMemories handles GET /api/memories
+func (h *Handler) HandleListMemories(w http.ResponseWriter, r *http.Request) {
+	username, err := h.extractUsername(r)
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	memories, err := h.store.ListMemories(username)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list memories")
+		return
+	}
+
+	// Return empty array instead of null
+	if memories == nil {
+		memories = []Memory{}
+	}
+
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"memories": memories,
+	})
+}
+
+// HandleCreateMemory handles POST /api/memories
+func (h *Handler) HandleCreateMemory(w http.ResponseWriter, r *http.Request) {
+	username, err := h.extractUsername(r)
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req MemoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	memory, err := h.store.AddMemory(username, req.Content)
+	if err != nil {
+		if strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "exceeds") ||
+			strings.Contains(err.Error(), "limit reached") {
+			sendError(w, http.StatusBadRequest, err.Error())
+		} else {
+			sendError(w, http.StatusInternalServerError, "Failed to save memory")
+		}
+		return
+	}
+
+	sendJSON(w, http.StatusCreated, memory)
+}
+
+// HandleDeleteMemory handles DELETE /api/memories/{id}
+func (h *Handler) HandleDeleteMemory(w http.ResponseWriter, r *http.Request) {
+	username, err := h.extractUsername(r)
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// Extract ID from path
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/memories/"), 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Valid memory ID required")
+		return
+	}
+
+	if err := h.store.DeleteMemory(id, username); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			sendError(w, http.StatusNotFound, "Memory not found")
+		} else {
+			sendError(w, http.StatusInternalServerError, "Failed to delete memory")
+		}
+		return
+	}
+
+	sendJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// HandleDeleteAllMemories handles DELETE /api/memories?all=true
+func (h *Handler) HandleDeleteAllMemories(w http.ResponseWriter, r *http.Request) {
+	username, err := h.extractUsername(r)
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	count, err := h.store.DeleteAllMemories(username)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to delete memories")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"deleted": count,
+	})
+}
+
+// HandleGetPreferences handles GET /api/preferences
+func (h *Handler) HandleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	username, err := h.extractUsername(r)
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	prefs, err := h.store.GetPreferences(username)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to load preferences")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, prefs)
+}
+
+// HandleReplacePreferences handles PUT /api/preferences
+func (h *Handler) HandleReplacePreferences(w http.ResponseWriter, r *http.Request) {
+	username, err := h.extractUsername(r)
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var prefs Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.store.SavePreferences(username, &prefs); err != nil {
+		sendPreferencesError(w, err)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, prefs)
+}
+
+// HandleUpdatePreferences handles PATCH /api/preferences
+func (h *Handler) HandleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	username, err := h.extractUsername(r)
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var patch PreferencesPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	prefs, err := h.store.UpdatePreferences(username, &patch)
+	if err != nil {
+		sendPreferencesError(w, err)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, prefs)
+}
+
+// sendPreferencesError reports a failed preferences update
+func sendPreferencesError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrInvalidPreferences) {
+		sendError(w, http.StatusBadRequest, err.Error())
+	} else {
+		sendError(w, http.StatusInternalServerError, "Failed to save preferences")
+	}
+}
+
 // RegisterRoutes registers conversation routes with the given mux
 func (h *Handler) RegisterRoutes(mux *http.ServeMux, authWrapper func(http.HandlerFunc) http.HandlerFunc) {
 	// List conversations
@@ -377,4 +561,44 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux, authWrapper func(http.Handl
 			sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		}
 	}))
+
+	// Remembered facts and preferences
+	mux.HandleFunc("/api/memories", authWrapper(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.HandleListMemories(w, r)
+		case http.MethodPost:
+			h.HandleCreateMemory(w, r)
+		case http.MethodDelete:
+			if r.URL.Query().Get("all") == "true" {
+				h.HandleDeleteAllMemories(w, r)
+			} else {
+				sendError(w, http.StatusBadRequest, "Use ?all=true to delete all memories")
+			}
+		default:
+			sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}))
+
+	mux.HandleFunc("/api/memories/", authWrapper(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.HandleDeleteMemory(w, r)
+	}))
+
+	// Preferences shared by the CLI and web clients
+	mux.HandleFunc("/api/preferences", authWrapper(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.HandleGetPreferences(w, r)
+		case http.MethodPut:
+			h.HandleReplacePreferences(w, r)
+		case http.MethodPatch:
+			h.HandleUpdatePreferences(w, r)
+		default:
+			sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}))
 }
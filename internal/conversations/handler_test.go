@@ -611,3 +611,42 @@ func TestSendError(t *testing.T) {
 		t.Errorf("Expected error 'test error', got %q", response["error"])
 	}
 }
+
+func TestSetConversationStore(t *testing.T) {
+	handler, cleanup, token := setupTestHandler(t)
+	defer cleanup()
+
+	history, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer history.Close()
+	handler.SetConversationStore(history)
+
+	body, _ := json.Marshal(CreateRequest{Messages: []Message{{Role: "user", Content: "Hello"}}})
+	req := httptest.NewRequest("POST", "/api/conversations", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.HandleCreate(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	// Conversations go to the replacement store, memories stay in the
+	// original one
+	if list, _ := history.List("testuser", 10, 0); len(list) != 1 {
+		t.Errorf("Expected the conversation in the replacement store, got %+v", list)
+	}
+	if list, _ := handler.store.List("testuser", 10, 0); len(list) != 0 {
+		t.Errorf("Expected no conversation in the original store, got %+v", list)
+	}
+
+	body, _ = json.Marshal(MemoryRequest{Content: "Prefers metric units"})
+	req = httptest.NewRequest("POST", "/api/memories", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	handler.HandleCreateMemory(rr, req)
+	if memories, _ := handler.store.ListMemories("testuser"); len(memories) != 1 {
+		t.Errorf("Expected the memory in the original store, got %+v (status %d)", memories, rr.Code)
+	}
+}
@@ -0,0 +1,183 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/auth"
+)
+
+const (
+	// MaxMemoriesPerUser limits how many memories a user can store
+	MaxMemoriesPerUser = 100
+	// MaxMemoryLength limits the length of a single memory in characters
+	MaxMemoryLength = 1000
+)
+
+// Memory is a fact or preference a user has asked the assistant to remember
+// (e.g. naming conventions or preferred schemas)
+type Memory struct {
+	ID        int64     `json:"id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddMemory stores a new memory for a user
+func (s *Store) AddMemory(username, content string) (*Memory, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("memory content is required")
+	}
+	if len([]rune(content)) > MaxMemoryLength {
+		return nil, fmt.Errorf("memory exceeds %d characters", MaxMemoryLength)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int
+	if err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM memories WHERE username = ?", username,
+	).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count memories: %w", err)
+	}
+	if count >= MaxMemoriesPerUser {
+		return nil, fmt.Errorf("memory limit reached (%d); forget something first", MaxMemoriesPerUser)
+	}
+
+	memory := &Memory{
+		Content:   content,
+		CreatedAt: time.Now().UTC(),
+	}
+	result, err := s.db.Exec(
+		"INSERT INTO memories (username, content, created_at) VALUES (?, ?, ?)",
+		username, memory.Content, memory.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert memory: %w", err)
+	}
+	memory.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory ID: %w", err)
+	}
+
+	return memory, nil
+}
+
+// ListMemories returns a user's memories, oldest first
+func (s *Store) ListMemories(username string) ([]Memory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(
+		`SELECT id, content, created_at
+         FROM memories
+         WHERE username = ?
+         ORDER BY id`,
+		username,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memories: %w", err)
+	}
+	defer rows.Close()
+
+	var memories []Memory
+	for rows.Next() {
+		var memory Memory
+		if err := rows.Scan(&memory.ID, &memory.Content, &memory.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		memories = append(memories, memory)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return memories, nil
+}
+
+// DeleteMemory removes one of a user's memories
+func (s *Store) DeleteMemory(id int64, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(
+		"DELETE FROM memories WHERE id = ? AND username = ?",
+		id, username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete memory: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("memory not found or access denied")
+	}
+
+	return nil
+}
+
+// DeleteAllMemories removes all of a user's memories
+func (s *Store) DeleteAllMemories(username string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec("DELETE FROM memories WHERE username = ?", username)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete memories: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// MemoryContext returns the memories of the session user in the request
+// context formatted for a system prompt, or "" if there are none
+func (s *Store) MemoryContext(ctx context.Context) string {
+	username := auth.GetUsernameFromContext(ctx)
+	if username == "" {
+		return ""
+	}
+
+	memories, err := s.ListMemories(username)
+	if err != nil {
+		return ""
+	}
+
+	contents := make([]string, len(memories))
+	for i, memory := range memories {
+		contents[i] = memory.Content
+	}
+	return FormatMemories(contents)
+}
+
+// FormatMemories renders remembered facts as a system prompt section
+func FormatMemories(contents []string) string {
+	if len(contents) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The user asked you to remember the following about their environment and preferences:\n")
+	for _, content := range contents {
+		sb.WriteString("- ")
+		sb.WriteString(content)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,175 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/auth"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	tempDir, err := os.MkdirTemp("", "conversations_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	store, err := NewStore(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+		os.RemoveAll(tempDir)
+	})
+	return store
+}
+
+func TestMemories(t *testing.T) {
+	store := newTestStore(t)
+
+	first, err := store.AddMemory("alice", "  Tables use snake_case  ")
+	if err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	if first.Content != "Tables use snake_case" {
+		t.Errorf("Content should be trimmed, got %q", first.Content)
+	}
+	if _, err := store.AddMemory("alice", "Prefer the sales schema"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	if _, err := store.AddMemory("bob", "Bob's fact"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+
+	memories, err := store.ListMemories("alice")
+	if err != nil {
+		t.Fatalf("ListMemories failed: %v", err)
+	}
+	if len(memories) != 2 || memories[0].ID != first.ID {
+		t.Fatalf("Expected alice's 2 memories oldest first, got %+v", memories)
+	}
+
+	// Users cannot delete each other's memories
+	if err := store.DeleteMemory(first.ID, "bob"); err == nil {
+		t.Error("Expected error deleting another user's memory")
+	}
+	if err := store.DeleteMemory(first.ID, "alice"); err != nil {
+		t.Fatalf("DeleteMemory failed: %v", err)
+	}
+
+	count, err := store.DeleteAllMemories("alice")
+	if err != nil || count != 1 {
+		t.Errorf("DeleteAllMemories = %d, %v; want 1, nil", count, err)
+	}
+	if memories, _ := store.ListMemories("bob"); len(memories) != 1 {
+		t.Error("Other users' memories should be unaffected")
+	}
+}
+
+func TestAddMemory_Validation(t *testing.T) {
+	store := newTestStore(t)
+
+	if _, err := store.AddMemory("alice", "   "); err == nil {
+		t.Error("Expected error for empty memory")
+	}
+	if _, err := store.AddMemory("alice", strings.Repeat("x", MaxMemoryLength+1)); err == nil {
+		t.Error("Expected error for oversized memory")
+	}
+
+	for i := 0; i < MaxMemoriesPerUser; i++ {
+		if _, err := store.AddMemory("alice", fmt.Sprintf("fact %d", i)); err != nil {
+			t.Fatalf("AddMemory %d failed: %v", i, err)
+		}
+	}
+	if _, err := store.AddMemory("alice", "one too many"); err == nil {
+		t.Error("Expected error when the memory limit is reached")
+	}
+}
+
+func TestMemoryContext(t *testing.T) {
+	store := newTestStore(t)
+
+	if got := store.MemoryContext(context.Background()); got != "" {
+		t.Errorf("Expected no context without a user, got %q", got)
+	}
+
+	if _, err := store.AddMemory("alice", "Prefer the sales schema"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	ctx := context.WithValue(context.Background(), auth.UsernameContextKey, "alice")
+	if got := store.MemoryContext(ctx); !strings.Contains(got, "- Prefer the sales schema\n") {
+		t.Errorf("MemoryContext = %q", got)
+	}
+}
+
+func TestHandleMemories(t *testing.T) {
+	handler, cleanup, token := setupTestHandler(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, func(h http.HandlerFunc) http.HandlerFunc { return h })
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/memories", MemoryRequest{Content: "Use UTC timestamps"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Create status = %d: %s", rr.Code, rr.Body.String())
+	}
+	var memory Memory
+	if err := json.NewDecoder(rr.Body).Decode(&memory); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if rr := do("POST", "/api/memories", MemoryRequest{}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Empty memory status = %d, want 400", rr.Code)
+	}
+
+	rr = do("GET", "/api/memories", nil)
+	var list struct {
+		Memories []Memory `json:"memories"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list.Memories) != 1 || list.Memories[0].Content != "Use UTC timestamps" {
+		t.Errorf("Unexpected memories: %+v", list.Memories)
+	}
+
+	if rr := do("DELETE", fmt.Sprintf("/api/memories/%d", memory.ID), nil); rr.Code != http.StatusOK {
+		t.Errorf("Delete status = %d", rr.Code)
+	}
+	if rr := do("DELETE", fmt.Sprintf("/api/memories/%d", memory.ID), nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Second delete status = %d, want 404", rr.Code)
+	}
+	if rr := do("DELETE", "/api/memories/abc", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid ID status = %d, want 400", rr.Code)
+	}
+}
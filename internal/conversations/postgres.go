@@ -0,0 +1,423 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/crypto"
+)
+
+// PostgresSchema is the schema that holds conversations stored in PostgreSQL
+const PostgresSchema = "pgedge_mcp_conversations"
+
+// postgresTable is the conversations table, qualified with its schema
+const postgresTable = PostgresSchema + ".conversations"
+
+// postgresSchemaStatements create the conversations table. Deleted
+// conversations keep their row with deleted_at set until they are purged.
+var postgresSchemaStatements = []string{
+	`CREATE SCHEMA IF NOT EXISTS ` + PostgresSchema,
+	`CREATE TABLE IF NOT EXISTS ` + postgresTable + ` (
+		id text PRIMARY KEY,
+		username text NOT NULL,
+		title text NOT NULL,
+		provider text NOT NULL DEFAULT '',
+		model text NOT NULL DEFAULT '',
+		connection text NOT NULL DEFAULT '',
+		messages text NOT NULL,
+		created_at timestamptz NOT NULL,
+		updated_at timestamptz NOT NULL,
+		deleted_at timestamptz
+	)`,
+	`CREATE INDEX IF NOT EXISTS conversations_username_updated_at_idx
+		ON ` + postgresTable + ` (username, updated_at DESC) WHERE deleted_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS conversations_deleted_at_idx
+		ON ` + postgresTable + ` (deleted_at) WHERE deleted_at IS NOT NULL`,
+}
+
+// PostgresStore stores conversations in a PostgreSQL database, so that every
+// server sharing the database, and every device a user signs in from, sees
+// the same history. Deleting a conversation hides it; the row is removed
+// when the retention policy purges deleted conversations.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+
+	mu     sync.RWMutex // Protects cipher
+	cipher *conversationCipher
+}
+
+// NewPostgresStore creates the conversations schema if needed and returns a
+// store using pool, which must allow writes to the schema. The store takes
+// ownership of the pool and closes it in Close.
+func NewPostgresStore(ctx context.Context, pool *pgxpool.Pool) (*PostgresStore, error) {
+	for _, stmt := range postgresSchemaStatements {
+		if _, err := pool.Exec(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("failed to initialize schema: %w", err)
+		}
+	}
+
+	// Conversations are stored as plaintext until SetEncryption is called
+	cipher, err := newConversationCipher(nil, EncryptionNone)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresStore{pool: pool, cipher: cipher}, nil
+}
+
+// Close closes the connection pool
+func (s *PostgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+// currentCipher returns the cipher for the current encryption mode
+func (s *PostgresStore) currentCipher() *conversationCipher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cipher
+}
+
+// Create creates a new conversation
+func (s *PostgresStore) Create(username, provider, model, connection string, messages []Message) (*Conversation, error) {
+	now := time.Now().UTC()
+	conv := &Conversation{
+		ID:         generateID(),
+		Username:   username,
+		Title:      generateTitle(messages),
+		Provider:   provider,
+		Model:      model,
+		Connection: connection,
+		Messages:   messages,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	storedTitle, storedMessages, err := s.currentCipher().sealConversation(username, conv.Title, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.pool.Exec(context.Background(),
+		`INSERT INTO `+postgresTable+` (id, username, title, provider, model, connection, messages, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		conv.ID, conv.Username, storedTitle, conv.Provider, conv.Model, conv.Connection, storedMessages,
+		conv.CreatedAt, conv.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert conversation: %w", err)
+	}
+
+	return conv, nil
+}
+
+// Update updates an existing conversation
+func (s *PostgresStore) Update(id, username, provider, model, connection string, messages []Message) (*Conversation, error) {
+	storedTitle, storedMessages, err := s.currentCipher().sealConversation(username, generateTitle(messages), messages)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := s.pool.Exec(context.Background(),
+		`UPDATE `+postgresTable+`
+		SET title = $1, provider = $2, model = $3, connection = $4, messages = $5, updated_at = $6
+		WHERE id = $7 AND username = $8 AND deleted_at IS NULL`,
+		storedTitle, provider, model, connection, storedMessages, time.Now().UTC(), id, username,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update conversation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, s.missingError(id)
+	}
+
+	return s.Get(id, username)
+}
+
+// missingError distinguishes a conversation of another user from one that
+// does not exist, as Store does
+func (s *PostgresStore) missingError(id string) error {
+	var exists bool
+	err := s.pool.QueryRow(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM `+postgresTable+` WHERE id = $1 AND deleted_at IS NULL)`, id,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to query conversation: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("access denied")
+	}
+	return fmt.Errorf("conversation not found")
+}
+
+// Get retrieves a conversation by ID
+func (s *PostgresStore) Get(id, username string) (*Conversation, error) {
+	var conv Conversation
+	var storedTitle, storedMessages string
+
+	err := s.pool.QueryRow(context.Background(),
+		`SELECT id, username, title, provider, model, connection, messages, created_at, updated_at
+		FROM `+postgresTable+`
+		WHERE id = $1 AND username = $2 AND deleted_at IS NULL`,
+		id, username,
+	).Scan(&conv.ID, &conv.Username, &storedTitle, &conv.Provider, &conv.Model, &conv.Connection,
+		&storedMessages, &conv.CreatedAt, &conv.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("conversation not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversation: %w", err)
+	}
+
+	if conv.Title, conv.Messages, err = s.currentCipher().openConversation(username, storedTitle, storedMessages); err != nil {
+		return nil, err
+	}
+	conv.CreatedAt, conv.UpdatedAt = conv.CreatedAt.UTC(), conv.UpdatedAt.UTC()
+	return &conv, nil
+}
+
+// List lists a user's conversations, most recently updated first
+func (s *PostgresStore) List(username string, limit, offset int) ([]ConversationSummary, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	rows, err := s.pool.Query(context.Background(),
+		`SELECT id, title, connection, messages, created_at, updated_at
+		FROM `+postgresTable+`
+		WHERE username = $1 AND deleted_at IS NULL
+		ORDER BY updated_at DESC, id DESC
+		LIMIT $2 OFFSET $3`,
+		username, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %w", err)
+	}
+	defer rows.Close()
+
+	cipher := s.currentCipher()
+	var summaries []ConversationSummary
+	for rows.Next() {
+		var summary ConversationSummary
+		var storedTitle, storedMessages string
+		if err := rows.Scan(&summary.ID, &storedTitle, &summary.Connection, &storedMessages,
+			&summary.CreatedAt, &summary.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		title, messages, err := cipher.openConversation(username, storedTitle, storedMessages)
+		if err != nil {
+			return nil, err
+		}
+		summary.Title = title
+		summary.Preview = previewOf(messages)
+		summary.CreatedAt, summary.UpdatedAt = summary.CreatedAt.UTC(), summary.UpdatedAt.UTC()
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// Rename renames a conversation
+func (s *PostgresStore) Rename(id, username, title string) error {
+	storedTitle, err := s.currentCipher().seal(username, title)
+	if err != nil {
+		return err
+	}
+
+	tag, err := s.pool.Exec(context.Background(),
+		`UPDATE `+postgresTable+` SET title = $1, updated_at = $2
+		WHERE id = $3 AND username = $4 AND deleted_at IS NULL`,
+		storedTitle, time.Now().UTC(), id, username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to rename conversation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("conversation not found or access denied")
+	}
+	return nil
+}
+
+// Delete marks a conversation as deleted
+func (s *PostgresStore) Delete(id, username string) error {
+	tag, err := s.pool.Exec(context.Background(),
+		`UPDATE `+postgresTable+` SET deleted_at = $1
+		WHERE id = $2 AND username = $3 AND deleted_at IS NULL`,
+		time.Now().UTC(), id, username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("conversation not found or access denied")
+	}
+	return nil
+}
+
+// DeleteAll marks all of a user's conversations as deleted
+func (s *PostgresStore) DeleteAll(username string) (int64, error) {
+	tag, err := s.pool.Exec(context.Background(),
+		`UPDATE `+postgresTable+` SET deleted_at = $1 WHERE username = $2 AND deleted_at IS NULL`,
+		time.Now().UTC(), username,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete conversations: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeUser permanently deletes all of a user's conversations, including
+// those already marked as deleted
+func (s *PostgresStore) PurgeUser(username string) (int64, error) {
+	tag, err := s.pool.Exec(context.Background(),
+		`DELETE FROM `+postgresTable+` WHERE username = $1`, username)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete conversations: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Purge permanently deletes the conversations that fall outside the policy,
+// and those deleted longer ago than its DeletedMaxAge, returning the number
+// deleted
+func (s *PostgresStore) Purge(policy RetentionPolicy, now time.Time) (int64, error) {
+	if !policy.IsEnabled() {
+		return 0, nil
+	}
+
+	ctx := context.Background()
+	var purged int64
+	if policy.MaxAge > 0 {
+		tag, err := s.pool.Exec(ctx,
+			`DELETE FROM `+postgresTable+` WHERE updated_at < $1`, now.UTC().Add(-policy.MaxAge))
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge old conversations: %w", err)
+		}
+		purged += tag.RowsAffected()
+	}
+
+	if policy.MaxPerUser > 0 {
+		tag, err := s.pool.Exec(ctx,
+			`DELETE FROM `+postgresTable+` WHERE id IN (
+				SELECT id FROM (
+					SELECT id, row_number() OVER (
+						PARTITION BY username ORDER BY updated_at DESC, id DESC
+					) AS position
+					FROM `+postgresTable+`
+					WHERE deleted_at IS NULL
+				) ranked WHERE position > $1
+			)`,
+			policy.MaxPerUser,
+		)
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge excess conversations: %w", err)
+		}
+		purged += tag.RowsAffected()
+	}
+
+	if policy.DeletedMaxAge > 0 {
+		tag, err := s.pool.Exec(ctx,
+			`DELETE FROM `+postgresTable+` WHERE deleted_at < $1`, now.UTC().Add(-policy.DeletedMaxAge))
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge deleted conversations: %w", err)
+		}
+		purged += tag.RowsAffected()
+	}
+
+	return purged, nil
+}
+
+// RunPurger applies the policy immediately and then every interval until
+// ctx is cancelled, calling report after each run that deleted
+// conversations or failed
+func (s *PostgresStore) RunPurger(ctx context.Context, policy RetentionPolicy, interval time.Duration, report func(purged int64, err error)) {
+	runPurger(ctx, s, policy, interval, report)
+}
+
+// SetEncryption sets how conversations are stored from now on and rewrites
+// existing rows that were stored in a different mode, returning the number
+// of rows rewritten. Rows are rewritten in one transaction, so servers
+// sharing the database never see a partial migration.
+func (s *PostgresStore) SetEncryption(secret *crypto.EncryptionKey, mode EncryptionMode) (int, error) {
+	c, err := newConversationCipher(secret, mode)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // No-op after commit
+
+	// Lock the rows so another server starting at the same time waits
+	// for this migration instead of rewriting them concurrently
+	rows, err := tx.Query(ctx, `SELECT id, username, title, messages FROM `+postgresTable+` FOR UPDATE`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query conversations: %w", err)
+	}
+
+	type rewrite struct {
+		id, title, messages string
+	}
+	var rewrites []rewrite
+	for rows.Next() {
+		var id, username, title, messages string
+		if err := rows.Scan(&id, &username, &title, &messages); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		r := rewrite{id: id}
+		var changed bool
+		if r.title, r.messages, changed, err = c.reseal(username, title, messages); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("conversation %s: %w", id, err)
+		}
+		if changed {
+			rewrites = append(rewrites, r)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for _, r := range rewrites {
+		if _, err := tx.Exec(ctx, `UPDATE `+postgresTable+` SET title = $1, messages = $2 WHERE id = $3`,
+			r.title, r.messages, r.id); err != nil {
+			return 0, fmt.Errorf("failed to rewrite conversation %s: %w", r.id, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit rewritten conversations: %w", err)
+	}
+
+	s.cipher = c
+	return len(rewrites), nil
+}
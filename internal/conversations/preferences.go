@@ -0,0 +1,229 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// MaxPreferenceKeys limits the number of UI settings and provider models
+	MaxPreferenceKeys = 50
+	// MaxPreferenceValueLength limits the length of a single string value
+	MaxPreferenceValueLength = 200
+)
+
+// ErrInvalidPreferences is returned for preferences that fail validation
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+// preferenceKey matches provider names and UI setting names
+var preferenceKey = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// Preferences are a user's settings shared by the CLI and web clients, so
+// that a provider, model or database chosen in one is used by the other.
+// UI holds scalar settings; clients ignore the ones they don't support.
+type Preferences struct {
+	Provider       string                 `json:"provider,omitempty"`
+	ProviderModels map[string]string      `json:"provider_models,omitempty"`
+	Database       string                 `json:"database,omitempty"`
+	UI             map[string]interface{} `json:"ui,omitempty"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// storedPreferences is the JSON document kept in the preferences table; the
+// update time has its own column
+type storedPreferences struct {
+	Provider       string                 `json:"provider,omitempty"`
+	ProviderModels map[string]string      `json:"provider_models,omitempty"`
+	Database       string                 `json:"database,omitempty"`
+	UI             map[string]interface{} `json:"ui,omitempty"`
+}
+
+// PreferencesPatch is a partial update of Preferences. Omitted fields are
+// left unchanged; provider_models and ui entries are merged, and an entry
+// set to null (or an empty model) is removed.
+type PreferencesPatch struct {
+	Provider       *string                `json:"provider"`
+	ProviderModels map[string]*string     `json:"provider_models"`
+	Database       *string                `json:"database"`
+	UI             map[string]interface{} `json:"ui"`
+}
+
+// Apply merges the patch into p
+func (patch *PreferencesPatch) Apply(p *Preferences) {
+	if patch.Provider != nil {
+		p.Provider = *patch.Provider
+	}
+	if patch.Database != nil {
+		p.Database = *patch.Database
+	}
+	for provider, model := range patch.ProviderModels {
+		if model == nil || *model == "" {
+			delete(p.ProviderModels, provider)
+			continue
+		}
+		if p.ProviderModels == nil {
+			p.ProviderModels = make(map[string]string)
+		}
+		p.ProviderModels[provider] = *model
+	}
+	for key, value := range patch.UI {
+		if value == nil {
+			delete(p.UI, key)
+			continue
+		}
+		if p.UI == nil {
+			p.UI = make(map[string]interface{})
+		}
+		p.UI[key] = value
+	}
+}
+
+// Validate checks the sizes and types of the preferences
+func (p *Preferences) Validate() error {
+	if err := p.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPreferences, err)
+	}
+	return nil
+}
+
+func (p *Preferences) validate() error {
+	if p.Provider != "" && !preferenceKey.MatchString(p.Provider) {
+		return fmt.Errorf("invalid provider name %q", p.Provider)
+	}
+	if len(p.Database) > MaxPreferenceValueLength {
+		return fmt.Errorf("database name exceeds %d characters", MaxPreferenceValueLength)
+	}
+
+	if len(p.ProviderModels) > MaxPreferenceKeys {
+		return fmt.Errorf("too many provider models (maximum %d)", MaxPreferenceKeys)
+	}
+	for provider, model := range p.ProviderModels {
+		if !preferenceKey.MatchString(provider) {
+			return fmt.Errorf("invalid provider name %q", provider)
+		}
+		if len(model) > MaxPreferenceValueLength {
+			return fmt.Errorf("model for %s exceeds %d characters", provider, MaxPreferenceValueLength)
+		}
+	}
+
+	if len(p.UI) > MaxPreferenceKeys {
+		return fmt.Errorf("too many UI settings (maximum %d)", MaxPreferenceKeys)
+	}
+	for key, value := range p.UI {
+		if !preferenceKey.MatchString(key) {
+			return fmt.Errorf("invalid UI setting name %q", key)
+		}
+		switch v := value.(type) {
+		case bool, float64:
+		case string:
+			if len(v) > MaxPreferenceValueLength {
+				return fmt.Errorf("UI setting %s exceeds %d characters", key, MaxPreferenceValueLength)
+			}
+		default:
+			return fmt.Errorf("UI setting %s must be a boolean, number or string", key)
+		}
+	}
+	return nil
+}
+
+// GetPreferences returns a user's preferences, which are empty if none have
+// been saved
+func (s *Store) GetPreferences(username string) (*Preferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.getPreferences(username)
+}
+
+func (s *Store) getPreferences(username string) (*Preferences, error) {
+	var data string
+	var updatedAt time.Time
+	err := s.db.QueryRow(
+		"SELECT data, updated_at FROM preferences WHERE username = ?", username,
+	).Scan(&data, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Preferences{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query preferences: %w", err)
+	}
+
+	var stored storedPreferences
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse preferences: %w", err)
+	}
+	return &Preferences{
+		Provider:       stored.Provider,
+		ProviderModels: stored.ProviderModels,
+		Database:       stored.Database,
+		UI:             stored.UI,
+		UpdatedAt:      updatedAt,
+	}, nil
+}
+
+// SavePreferences replaces a user's preferences
+func (s *Store) SavePreferences(username string, prefs *Preferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.savePreferences(username, prefs)
+}
+
+func (s *Store) savePreferences(username string, prefs *Preferences) error {
+	prefs.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(storedPreferences{
+		Provider:       prefs.Provider,
+		ProviderModels: prefs.ProviderModels,
+		Database:       prefs.Database,
+		UI:             prefs.UI,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO preferences (username, data, updated_at) VALUES (?, ?, ?)
+         ON CONFLICT(username) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		username, string(data), prefs.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
+
+// UpdatePreferences applies a partial update to a user's preferences and
+// returns the result
+func (s *Store) UpdatePreferences(username string, patch *PreferencesPatch) (*Preferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefs, err := s.getPreferences(username)
+	if err != nil {
+		return nil, err
+	}
+	patch.Apply(prefs)
+	if err := prefs.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.savePreferences(username, prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
@@ -0,0 +1,153 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPreferences(t *testing.T) {
+	store := newTestStore(t)
+
+	prefs, err := store.GetPreferences("alice")
+	if err != nil {
+		t.Fatalf("GetPreferences failed: %v", err)
+	}
+	if prefs.Provider != "" || len(prefs.UI) != 0 || !prefs.UpdatedAt.IsZero() {
+		t.Errorf("Expected empty preferences, got %+v", prefs)
+	}
+
+	err = store.SavePreferences("alice", &Preferences{
+		Provider:       "openai",
+		ProviderModels: map[string]string{"openai": "example-model"},
+		Database:       "sales",
+		UI:             map[string]interface{}{"render_markdown": false, "theme": "dark"},
+	})
+	if err != nil {
+		t.Fatalf("SavePreferences failed: %v", err)
+	}
+
+	model := "other-model"
+	prefs, err = store.UpdatePreferences("alice", &PreferencesPatch{
+		ProviderModels: map[string]*string{"ollama": &model, "openai": nil},
+		UI:             map[string]interface{}{"theme": nil, "debug": true},
+	})
+	if err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+
+	if prefs.Provider != "openai" || prefs.Database != "sales" {
+		t.Errorf("Fields missing from the patch should be unchanged: %+v", prefs)
+	}
+	if len(prefs.ProviderModels) != 1 || prefs.ProviderModels["ollama"] != "other-model" {
+		t.Errorf("Unexpected provider models: %v", prefs.ProviderModels)
+	}
+	if _, ok := prefs.UI["theme"]; ok || prefs.UI["debug"] != true || prefs.UI["render_markdown"] != false {
+		t.Errorf("Unexpected UI settings: %v", prefs.UI)
+	}
+
+	// Preferences are per user and survive a reload
+	stored, err := store.GetPreferences("alice")
+	if err != nil {
+		t.Fatalf("GetPreferences failed: %v", err)
+	}
+	if stored.ProviderModels["ollama"] != "other-model" || stored.UpdatedAt.IsZero() {
+		t.Errorf("Unexpected stored preferences: %+v", stored)
+	}
+	if other, _ := store.GetPreferences("bob"); other.Provider != "" {
+		t.Error("Preferences must not be shared between users")
+	}
+}
+
+func TestPreferences_Validation(t *testing.T) {
+	store := newTestStore(t)
+
+	tests := []struct {
+		name  string
+		prefs Preferences
+	}{
+		{"provider name", Preferences{Provider: "Open AI"}},
+		{"long model", Preferences{ProviderModels: map[string]string{"openai": strings.Repeat("m", MaxPreferenceValueLength+1)}}},
+		{"UI key", Preferences{UI: map[string]interface{}{"Theme Mode": "dark"}}},
+		{"UI value type", Preferences{UI: map[string]interface{}{"theme": []interface{}{"dark"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.SavePreferences("alice", &tt.prefs); !errors.Is(err, ErrInvalidPreferences) {
+				t.Errorf("SavePreferences error = %v, want ErrInvalidPreferences", err)
+			}
+		})
+	}
+}
+
+func TestHandlePreferences(t *testing.T) {
+	handler, cleanup, token := setupTestHandler(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, func(h http.HandlerFunc) http.HandlerFunc { return h })
+
+	do := func(method string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/preferences", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder) Preferences {
+		t.Helper()
+		var prefs Preferences
+		if err := json.NewDecoder(rr.Body).Decode(&prefs); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return prefs
+	}
+
+	rr := do("PUT", `{"provider": "anthropic", "database": "sales", "ui": {"theme": "dark"}}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("PATCH", `{"provider_models": {"anthropic": "example-model"}, "ui": {"render_markdown": false}}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d: %s", rr.Code, rr.Body.String())
+	}
+
+	prefs := decode(do("GET", ""))
+	if prefs.Provider != "anthropic" || prefs.Database != "sales" ||
+		prefs.ProviderModels["anthropic"] != "example-model" ||
+		prefs.UI["theme"] != "dark" || prefs.UI["render_markdown"] != false {
+		t.Errorf("Unexpected preferences: %+v", prefs)
+	}
+
+	if rr := do("PATCH", `{"provider": "Not Valid"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid provider status = %d, want 400", rr.Code)
+	}
+	if rr := do("PATCH", `not json`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid body status = %d, want 400", rr.Code)
+	}
+	if rr := do("DELETE", ""); rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE status = %d, want 405", rr.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/preferences", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Unauthenticated status = %d, want 401", rr.Code)
+	}
+}
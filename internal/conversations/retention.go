@@ -0,0 +1,125 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy controls which stored conversations are purged
+type RetentionPolicy struct {
+	// MaxAge purges conversations not updated within this duration (0 = keep)
+	MaxAge time.Duration
+	// MaxPerUser keeps only each user's most recently updated
+	// conversations (0 = unlimited)
+	MaxPerUser int
+	// DeletedMaxAge permanently deletes conversations that were deleted
+	// longer ago than this duration (0 = keep). Only PostgresStore keeps
+	// deleted conversations.
+	DeletedMaxAge time.Duration
+}
+
+// IsEnabled reports whether the policy purges anything
+func (p RetentionPolicy) IsEnabled() bool {
+	return p.MaxAge > 0 || p.MaxPerUser > 0 || p.DeletedMaxAge > 0
+}
+
+// Purge deletes the conversations that fall outside the policy and returns
+// the number deleted
+func (s *Store) Purge(policy RetentionPolicy, now time.Time) (int64, error) {
+	if !policy.IsEnabled() {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	if policy.MaxAge > 0 {
+		result, err := s.db.Exec(
+			"DELETE FROM conversations WHERE updated_at < ?",
+			now.UTC().Add(-policy.MaxAge),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge old conversations: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		purged += rows
+	}
+
+	if policy.MaxPerUser > 0 {
+		result, err := s.db.Exec(
+			`DELETE FROM conversations WHERE id IN (
+                SELECT id FROM (
+                    SELECT id, ROW_NUMBER() OVER (
+                        PARTITION BY username ORDER BY updated_at DESC, id DESC
+                    ) AS position
+                    FROM conversations
+                ) WHERE position > ?
+            )`,
+			policy.MaxPerUser,
+		)
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge excess conversations: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return purged, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		purged += rows
+	}
+
+	if purged > 0 {
+		// Don't leave the deleted conversations in the write-ahead log
+		if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			return purged, fmt.Errorf("failed to checkpoint database: %w", err)
+		}
+	}
+
+	return purged, nil
+}
+
+// RunPurger applies the policy immediately and then every interval until
+// ctx is cancelled, calling report after each run that deleted
+// conversations or failed
+func (s *Store) RunPurger(ctx context.Context, policy RetentionPolicy, interval time.Duration, report func(purged int64, err error)) {
+	runPurger(ctx, s, policy, interval, report)
+}
+
+// runPurger implements RunPurger for either store
+func runPurger(ctx context.Context, s ConversationStore, policy RetentionPolicy, interval time.Duration, report func(purged int64, err error)) {
+	if !policy.IsEnabled() || interval <= 0 {
+		return
+	}
+
+	purge := func() {
+		if purged, err := s.Purge(policy, time.Now()); purged > 0 || err != nil {
+			report(purged, err)
+		}
+	}
+
+	purge()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purge()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package conversations
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPurge(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC()
+	// alice has conversations updated 1..4 days ago, bob one 10 days ago
+	for i, user := range []string{"alice", "alice", "alice", "alice", "bob"} {
+		age := time.Duration(i+1) * 24 * time.Hour
+		if user == "bob" {
+			age = 10 * 24 * time.Hour
+		}
+		conv, err := store.Create(user, "", "", "", []Message{{Role: "user", Content: fmt.Sprintf("q%d", i)}})
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		if _, err := store.db.Exec("UPDATE conversations SET updated_at = ? WHERE id = ?", now.Add(-age), conv.ID); err != nil {
+			t.Fatalf("Failed to age conversation: %v", err)
+		}
+	}
+
+	if !(RetentionPolicy{DeletedMaxAge: time.Hour}).IsEnabled() {
+		t.Error("Expected a policy purging deleted conversations to be enabled")
+	}
+	if purged, err := store.Purge(RetentionPolicy{}, now); err != nil || purged != 0 {
+		t.Errorf("Expected an empty policy to purge nothing, got %d, %v", purged, err)
+	}
+
+	// bob's conversation is too old
+	purged, err := store.Purge(RetentionPolicy{MaxAge: 7 * 24 * time.Hour}, now)
+	if err != nil || purged != 1 {
+		t.Fatalf("Expected 1 conversation purged by age, got %d, %v", purged, err)
+	}
+	if list, _ := store.List("bob", 10, 0); len(list) != 0 {
+		t.Errorf("Expected bob's conversation to be purged, got %+v", list)
+	}
+
+	// alice keeps only her two most recent conversations
+	purged, err = store.Purge(RetentionPolicy{MaxPerUser: 2}, now)
+	if err != nil || purged != 2 {
+		t.Fatalf("Expected 2 conversations purged by count, got %d, %v", purged, err)
+	}
+	list, err := store.List("alice", 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list conversations: %v", err)
+	}
+	if len(list) != 2 || list[0].Title != "q0" || list[1].Title != "q1" {
+		t.Errorf("Expected alice's two newest conversations to remain, got %+v", list)
+	}
+}
@@ -11,6 +11,7 @@
 package conversations
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -20,6 +21,8 @@ import (
 	"time"
 
 	_ "modernc.org/sqlite" // Pure Go SQLite driver
+
+	"pgedge-postgres-mcp/internal/crypto"
 )
 
 // Message represents a single message in a conversation
@@ -56,11 +59,37 @@ type ConversationSummary struct {
 	Preview    string    `json:"preview"`
 }
 
-// Store manages conversation persistence using SQLite
+// ConversationStore persists conversations. Store keeps them in a SQLite
+// database in the data directory, and PostgresStore in a PostgreSQL database
+// that several servers can share.
+type ConversationStore interface {
+	Create(username, provider, model, connection string, messages []Message) (*Conversation, error)
+	Update(id, username, provider, model, connection string, messages []Message) (*Conversation, error)
+	Get(id, username string) (*Conversation, error)
+	List(username string, limit, offset int) ([]ConversationSummary, error)
+	Rename(id, username, title string) error
+	Delete(id, username string) error
+	DeleteAll(username string) (int64, error)
+	// PurgeUser permanently deletes all of a user's conversations
+	PurgeUser(username string) (int64, error)
+	Purge(policy RetentionPolicy, now time.Time) (int64, error)
+	RunPurger(ctx context.Context, policy RetentionPolicy, interval time.Duration, report func(purged int64, err error))
+	SetEncryption(secret *crypto.EncryptionKey, mode EncryptionMode) (int, error)
+	Close() error
+}
+
+var (
+	_ ConversationStore = (*Store)(nil)
+	_ ConversationStore = (*PostgresStore)(nil)
+)
+
+// Store manages conversation persistence using SQLite. Titles and messages
+// are encrypted at rest once SetEncryption has been called.
 type Store struct {
-	db   *sql.DB
-	mu   sync.RWMutex
-	path string
+	db     *sql.DB
+	mu     sync.RWMutex
+	path   string
+	cipher *conversationCipher
 }
 
 // NewStore creates a new conversation store
@@ -89,9 +118,24 @@ func NewStore(dataDir string) (*Store, error) {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
+	// Overwrite deleted content so purged conversations can't be recovered
+	// from free pages
+	if _, err := db.Exec("PRAGMA secure_delete=ON"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to enable secure delete: %w", err)
+	}
+
+	// Conversations are stored as plaintext until SetEncryption is called
+	cipher, err := newConversationCipher(nil, EncryptionNone)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	store := &Store{
-		db:   db,
-		path: dbPath,
+		db:     db,
+		path:   dbPath,
+		cipher: cipher,
 	}
 
 	// Initialize schema
@@ -123,6 +167,22 @@ func (s *Store) initSchema() error {
 
     CREATE INDEX IF NOT EXISTS idx_conversations_updated_at
         ON conversations(updated_at DESC);
+
+    CREATE TABLE IF NOT EXISTS memories (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        username TEXT NOT NULL,
+        content TEXT NOT NULL,
+        created_at DATETIME NOT NULL
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_memories_username
+        ON memories(username);
+
+    CREATE TABLE IF NOT EXISTS preferences (
+        username TEXT PRIMARY KEY,
+        data TEXT NOT NULL,
+        updated_at DATETIME NOT NULL
+    );
     `
 
 	_, err := s.db.Exec(schema)
@@ -207,6 +267,21 @@ func generateTitle(messages []Message) string {
 	return "New conversation"
 }
 
+// previewOf returns the start of the first user message, for listing
+func previewOf(messages []Message) string {
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			if content, ok := msg.Content.(string); ok {
+				if len(content) > 100 {
+					return content[:97] + "..."
+				}
+				return content
+			}
+		}
+	}
+	return ""
+}
+
 // Create creates a new conversation
 func (s *Store) Create(username, provider, model, connection string, messages []Message) (*Conversation, error) {
 	s.mu.Lock()
@@ -224,15 +299,15 @@ func (s *Store) Create(username, provider, model, connection string, messages []
 		UpdatedAt:  time.Now().UTC(),
 	}
 
-	messagesJSON, err := json.Marshal(messages)
+	storedTitle, storedMessages, err := s.cipher.sealConversation(username, conv.Title, messages)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal messages: %w", err)
+		return nil, err
 	}
 
 	_, err = s.db.Exec(
 		`INSERT INTO conversations (id, username, title, provider, model, connection, messages, created_at, updated_at)
          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		conv.ID, conv.Username, conv.Title, conv.Provider, conv.Model, conv.Connection, string(messagesJSON),
+		conv.ID, conv.Username, storedTitle, conv.Provider, conv.Model, conv.Connection, storedMessages,
 		conv.CreatedAt, conv.UpdatedAt,
 	)
 	if err != nil {
@@ -262,19 +337,17 @@ func (s *Store) Update(id, username, provider, model, connection string, message
 		return nil, fmt.Errorf("access denied")
 	}
 
-	messagesJSON, err := json.Marshal(messages)
+	storedTitle, storedMessages, err := s.cipher.sealConversation(username, generateTitle(messages), messages)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal messages: %w", err)
+		return nil, err
 	}
-
-	title := generateTitle(messages)
 	updatedAt := time.Now().UTC()
 
 	_, err = s.db.Exec(
 		`UPDATE conversations
          SET title = ?, provider = ?, model = ?, connection = ?, messages = ?, updated_at = ?
          WHERE id = ? AND username = ?`,
-		title, provider, model, connection, string(messagesJSON), updatedAt, id, username,
+		storedTitle, provider, model, connection, storedMessages, updatedAt, id, username,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update conversation: %w", err)
@@ -312,8 +385,8 @@ func (s *Store) getUnlocked(id, username string) (*Conversation, error) {
 		return nil, fmt.Errorf("failed to query conversation: %w", err)
 	}
 
-	if err := json.Unmarshal([]byte(messagesJSON), &conv.Messages); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal messages: %w", err)
+	if conv.Title, conv.Messages, err = s.cipher.openConversation(username, conv.Title, messagesJSON); err != nil {
+		return nil, err
 	}
 
 	return &conv, nil
@@ -354,21 +427,18 @@ func (s *Store) List(username string, limit, offset int) ([]ConversationSummary,
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
+		var err error
+		if summary.Title, err = s.cipher.open(username, summary.Title); err != nil {
+			return nil, err
+		}
+		if messagesJSON, err = s.cipher.open(username, messagesJSON); err != nil {
+			return nil, err
+		}
+
 		// Extract preview from first user message
 		var messages []Message
 		if err := json.Unmarshal([]byte(messagesJSON), &messages); err == nil {
-			for _, msg := range messages {
-				if msg.Role == "user" {
-					if content, ok := msg.Content.(string); ok {
-						if len(content) > 100 {
-							summary.Preview = content[:97] + "..."
-						} else {
-							summary.Preview = content
-						}
-						break
-					}
-				}
-			}
+			summary.Preview = previewOf(messages)
 		}
 
 		summaries = append(summaries, summary)
@@ -386,10 +456,15 @@ func (s *Store) Rename(id, username, title string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	storedTitle, err := s.cipher.seal(username, title)
+	if err != nil {
+		return err
+	}
+
 	result, err := s.db.Exec(
 		`UPDATE conversations SET title = ?, updated_at = ?
          WHERE id = ? AND username = ?`,
-		title, time.Now().UTC(), id, username,
+		storedTitle, time.Now().UTC(), id, username,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to rename conversation: %w", err)
@@ -445,3 +520,9 @@ func (s *Store) DeleteAll(username string) (int64, error) {
 
 	return result.RowsAffected()
 }
+
+// PurgeUser permanently deletes all conversations for a user; conversations
+// deleted from this store are never kept, so this is DeleteAll
+func (s *Store) PurgeUser(username string) (int64, error) {
+	return s.DeleteAll(username)
+}
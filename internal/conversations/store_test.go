@@ -13,6 +13,7 @@ package conversations
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -569,3 +570,21 @@ func TestSchemaMigration(t *testing.T) {
 		t.Errorf("Expected model 'claude-3-sonnet', got '%s'", conv.Model)
 	}
 }
+
+func TestPreviewOf(t *testing.T) {
+	long := strings.Repeat("x", 120)
+	tests := []struct {
+		messages []Message
+		want     string
+	}{
+		{nil, ""},
+		{[]Message{{Role: "assistant", Content: "Hi"}, {Role: "user", Content: "Show tables"}}, "Show tables"},
+		{[]Message{{Role: "user", Content: long}}, long[:97] + "..."},
+		{[]Message{{Role: "user", Content: []interface{}{"block"}}}, ""},
+	}
+	for _, tt := range tests {
+		if got := previewOf(tt.messages); got != tt.want {
+			t.Errorf("previewOf(%v) = %q, want %q", tt.messages, got, tt.want)
+		}
+	}
+}
@@ -13,11 +13,17 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"runtime"
 )
 
 const (
@@ -47,9 +53,10 @@ func LoadKeyFromFile(path string) (*EncryptionKey, error) {
 		return nil, fmt.Errorf("failed to stat key file: %w", err)
 	}
 
-	// Verify file has 0600 permissions (owner read/write only)
+	// Verify file has 0600 permissions (owner read/write only). Windows
+	// controls access with ACLs and reports 0666 or 0444 for every file.
 	mode := fileInfo.Mode().Perm()
-	if mode != 0600 {
+	if mode != 0600 && runtime.GOOS != "windows" {
 		return nil, fmt.Errorf("insecure permissions on key file %s: %04o (expected 0600). Please run: chmod 600 %s", path, mode, path)
 	}
 
@@ -71,6 +78,39 @@ func LoadKeyFromFile(path string) (*EncryptionKey, error) {
 	return &EncryptionKey{key: key}, nil
 }
 
+// LoadOrGenerateKey loads the encryption key from path, generating and
+// saving a new key if the file does not exist. generated reports whether a
+// new key was created.
+func LoadOrGenerateKey(path string) (key *EncryptionKey, generated bool, err error) {
+	key, err = LoadKeyFromFile(path)
+	if err == nil {
+		return key, false, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+
+	key, err = GenerateKey()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := key.SaveToFile(path); err != nil {
+		return nil, false, err
+	}
+	return key, true, nil
+}
+
+// DeriveKey derives an independent key for the given purpose using
+// HKDF-SHA256, so one secret can protect several kinds of data without the
+// same key being used for all of them
+func (k *EncryptionKey) DeriveKey(purpose string) (*EncryptionKey, error) {
+	derived, err := hkdf.Key(sha256.New, k.key, nil, purpose, KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return &EncryptionKey{key: derived}, nil
+}
+
 // SaveToFile saves the encryption key to a file with restricted permissions
 func (k *EncryptionKey) SaveToFile(path string) error {
 	// Encode key as base64
@@ -149,3 +189,22 @@ func (k *EncryptionKey) Decrypt(ciphertext string) (string, error) {
 
 	return string(plaintext), nil
 }
+
+// Sign returns an HMAC-SHA256 signature of message, encoded as unpadded
+// URL-safe base64 so it can be used in cookies and headers
+func (k *EncryptionKey) Sign(message string) string {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is Sign's signature of message
+func (k *EncryptionKey) Verify(message, signature string) bool {
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write([]byte(message))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
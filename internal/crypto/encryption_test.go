@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -257,6 +258,10 @@ func TestLoadKeyFromInvalidFile(t *testing.T) {
 }
 
 func TestLoadKeyWithInsecurePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows controls access to the key file with ACLs")
+	}
+
 	tmpDir, err := os.MkdirTemp("", "pgedge-crypto-test-*")
 	if err != nil {
 		t.Fatalf("Failed to create temp dir: %v", err)
@@ -330,3 +335,93 @@ func TestDecryptInvalidCiphertext(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadOrGenerateKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "test.secret")
+
+	// First call generates and saves a key
+	key, generated, err := LoadOrGenerateKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadOrGenerateKey failed: %v", err)
+	}
+	if !generated {
+		t.Error("Expected a new key to be generated")
+	}
+
+	// Second call loads the same key
+	loaded, generated, err := LoadOrGenerateKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadOrGenerateKey failed: %v", err)
+	}
+	if generated {
+		t.Error("Expected the existing key to be loaded")
+	}
+	if string(key.key) != string(loaded.key) {
+		t.Error("Loaded key does not match generated key")
+	}
+
+	// An unreadable key is an error rather than being replaced
+	if err := os.WriteFile(keyPath, []byte("not a key"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, _, err := LoadOrGenerateKey(keyPath); err == nil {
+		t.Error("Expected error for an invalid key file")
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	a1, err := key.DeriveKey("purpose-a")
+	if err != nil {
+		t.Fatalf("DeriveKey failed: %v", err)
+	}
+	a2, _ := key.DeriveKey("purpose-a")
+	b, _ := key.DeriveKey("purpose-b")
+
+	if len(a1.key) != KeySize {
+		t.Errorf("Expected derived key size %d, got %d", KeySize, len(a1.key))
+	}
+	if string(a1.key) != string(a2.key) {
+		t.Error("Expected the same purpose to derive the same key")
+	}
+	if string(a1.key) == string(b.key) || string(a1.key) == string(key.key) {
+		t.Error("Expected derived keys to differ from each other and the secret")
+	}
+
+	ciphertext, err := a1.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if _, err := b.Decrypt(ciphertext); err == nil {
+		t.Error("Expected decrypting with a key for another purpose to fail")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	other, _ := GenerateKey()
+
+	signature := key.Sign("replica-1")
+	if signature != key.Sign("replica-1") {
+		t.Error("Expected signatures to be deterministic")
+	}
+	if !key.Verify("replica-1", signature) {
+		t.Error("Expected the signature to verify")
+	}
+	if key.Verify("replica-2", signature) {
+		t.Error("Expected a signature of another message to be rejected")
+	}
+	if other.Verify("replica-1", signature) {
+		t.Error("Expected a signature made with another key to be rejected")
+	}
+	if key.Verify("replica-1", "not base64!") {
+		t.Error("Expected a malformed signature to be rejected")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"pgedge-postgres-mcp/internal/config"
 )
@@ -26,6 +27,36 @@ type ClientManager struct {
 	dbConfigs     map[string]*config.NamedDatabaseConfig // dbName -> config
 	currentDB     map[string]string                      // tokenHash -> current dbName
 	defaultDBName string                                 // name of default database (first configured)
+	idleTimeout   time.Duration                          // how long unused clients are kept open (0 = forever)
+
+	metadataRefreshInterval time.Duration      // how old metadata may get before it is reloaded (0 = never)
+	metadataLoaded          MetadataLoadedFunc // set on the clients the manager creates
+
+	databasesChanged DatabasesChangedFunc // called after the configured databases change
+}
+
+// DatabasesChangedFunc is called with the configured databases after they
+// change
+type DatabasesChangedFunc func(databases []config.NamedDatabaseConfig)
+
+// SetDatabasesChangedFunc sets the function called after the configured
+// databases change: on a configuration reload, and when a database is added
+// or removed at runtime
+func (cm *ClientManager) SetDatabasesChangedFunc(fn DatabasesChangedFunc) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.databasesChanged = fn
+}
+
+// notifyDatabasesChanged calls the databases changed function; the caller
+// must not hold cm.mu
+func (cm *ClientManager) notifyDatabasesChanged() {
+	cm.mu.RLock()
+	fn := cm.databasesChanged
+	cm.mu.RUnlock()
+	if fn != nil {
+		fn(cm.GetDatabaseConfigs())
+	}
 }
 
 // NewClientManager creates a new client manager with database configurations
@@ -98,6 +129,7 @@ func (cm *ClientManager) GetClientForDatabase(tokenHash, dbName string) (*Client
 	cm.mu.RLock()
 	if tokenClients, exists := cm.clients[tokenHash]; exists {
 		if client, exists := tokenClients[dbName]; exists {
+			client.markUsed(time.Now())
 			cm.mu.RUnlock()
 			return client, nil
 		}
@@ -116,20 +148,23 @@ func (cm *ClientManager) GetClientForDatabase(tokenHash, dbName string) (*Client
 	// Double-check after acquiring write lock
 	if tokenClients, exists := cm.clients[tokenHash]; exists {
 		if client, exists := tokenClients[dbName]; exists {
+			client.markUsed(time.Now())
 			return client, nil
 		}
 	}
 
 	// Create and initialize new client with database configuration
 	client := NewClient(dbConfig)
+	client.SetMetadataLoadedFunc(cm.metadataLoaded)
 	if err := client.Connect(); err != nil {
 		return nil, fmt.Errorf("failed to connect to database '%s': %w", dbName, err)
 	}
 
-	if err := client.LoadMetadata(); err != nil {
+	if err := client.LoadInitialMetadata(); err != nil {
 		client.Close()
 		return nil, fmt.Errorf("failed to load metadata for database '%s': %w", dbName, err)
 	}
+	client.markCreated(time.Now())
 
 	// Ensure token's client map exists
 	if cm.clients[tokenHash] == nil {
@@ -197,6 +232,27 @@ func (cm *ClientManager) SetCurrentDatabaseAndCloseOthers(tokenHash, dbName stri
 	return nil
 }
 
+// LookupClient returns the existing client for a token's current database
+// Unlike GetClient it never creates or connects a client
+func (cm *ClientManager) LookupClient(tokenHash string) (*Client, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	dbName, exists := cm.currentDB[tokenHash]
+	if !exists {
+		dbName = cm.defaultDBName
+	}
+	if dbName == "" {
+		dbName = "default"
+	}
+
+	client, exists := cm.clients[tokenHash][dbName]
+	if exists {
+		client.markUsed(time.Now())
+	}
+	return client, exists
+}
+
 // GetCurrentDatabase returns the current database name for a token
 // Returns the default database if no specific database is set
 func (cm *ClientManager) GetCurrentDatabase(tokenHash string) string {
@@ -251,6 +307,7 @@ func (cm *ClientManager) GetDatabaseConfigs() []config.NamedDatabaseConfig {
 // Used for SIGHUP config reload
 // Note: Existing connections are NOT closed - they will be reused if config matches
 func (cm *ClientManager) UpdateDatabaseConfigs(databases []config.NamedDatabaseConfig) {
+	defer cm.notifyDatabasesChanged() // Runs after the lock is released
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
@@ -289,6 +346,53 @@ func (cm *ClientManager) UpdateDatabaseConfigs(databases []config.NamedDatabaseC
 	fmt.Fprintf(os.Stderr, "Updated database configurations: %d database(s)\n", len(databases))
 }
 
+// AddDatabaseConfig adds a database at runtime. The configured databases
+// are kept, so existing clients and the default database are unaffected.
+func (cm *ClientManager) AddDatabaseConfig(db config.NamedDatabaseConfig) error {
+	cm.mu.Lock()
+	if _, exists := cm.dbConfigs[db.Name]; exists {
+		cm.mu.Unlock()
+		return fmt.Errorf("database '%s' is already configured", db.Name)
+	}
+	cm.dbConfigs[db.Name] = &db
+	if cm.defaultDBName == "" {
+		cm.defaultDBName = db.Name
+	}
+	cm.mu.Unlock()
+
+	cm.notifyDatabasesChanged()
+	return nil
+}
+
+// RemoveDatabaseConfig removes a database added with AddDatabaseConfig and
+// closes the connections to it; it undoes an addition that could not be
+// completed
+func (cm *ClientManager) RemoveDatabaseConfig(name string) {
+	cm.mu.Lock()
+	if _, exists := cm.dbConfigs[name]; !exists {
+		cm.mu.Unlock()
+		return
+	}
+	defer cm.notifyDatabasesChanged() // Runs after the lock is released
+	defer cm.mu.Unlock()
+
+	delete(cm.dbConfigs, name)
+	if cm.defaultDBName == name {
+		cm.defaultDBName = ""
+	}
+	for _, tokenClients := range cm.clients {
+		if client, exists := tokenClients[name]; exists {
+			client.Close()
+			delete(tokenClients, name)
+		}
+	}
+	for tokenHash, dbName := range cm.currentDB {
+		if dbName == name {
+			cm.currentDB[tokenHash] = cm.defaultDBName
+		}
+	}
+}
+
 // RemoveClient removes and closes all database clients for a given token hash
 // This should be called when a token is removed or expires
 func (cm *ClientManager) RemoveClient(tokenHash string) error {
@@ -419,6 +523,7 @@ func (cm *ClientManager) GetOrCreateClient(key string, autoConnect bool) (*Clien
 	cm.mu.RLock()
 	if tokenClients, exists := cm.clients[key]; exists {
 		if client, exists := tokenClients[dbName]; exists {
+			client.markUsed(time.Now())
 			cm.mu.RUnlock()
 			return client, nil
 		}
@@ -441,20 +546,23 @@ func (cm *ClientManager) GetOrCreateClient(key string, autoConnect bool) (*Clien
 	// Double-check after acquiring write lock
 	if tokenClients, exists := cm.clients[key]; exists {
 		if client, exists := tokenClients[dbName]; exists {
+			client.markUsed(time.Now())
 			return client, nil
 		}
 	}
 
 	// Create and initialize new client with database configuration
 	client := NewClient(dbConfig)
+	client.SetMetadataLoadedFunc(cm.metadataLoaded)
 	if err := client.Connect(); err != nil {
 		return nil, fmt.Errorf("failed to connect to database '%s': %w", dbName, err)
 	}
 
-	if err := client.LoadMetadata(); err != nil {
+	if err := client.LoadInitialMetadata(); err != nil {
 		client.Close()
 		return nil, fmt.Errorf("failed to load metadata for database '%s': %w", dbName, err)
 	}
+	client.markCreated(time.Now())
 
 	if cm.clients[key] == nil {
 		cm.clients[key] = make(map[string]*Client)
@@ -11,7 +11,10 @@
 package database
 
 import (
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	"pgedge-postgres-mcp/internal/config"
 )
@@ -335,6 +338,60 @@ func TestClientManager_UpdateDatabaseConfigs(t *testing.T) {
 	}
 }
 
+func TestClientManager_AddRemoveDatabaseConfig(t *testing.T) {
+	cm := NewClientManager([]config.NamedDatabaseConfig{
+		{Name: "db1", Host: "host1", Port: 5432, Database: "test1"},
+	})
+
+	if err := cm.AddDatabaseConfig(config.NamedDatabaseConfig{Name: "db1", Host: "other"}); err == nil {
+		t.Error("expected an error when adding a configured name")
+	}
+	if err := cm.AddDatabaseConfig(config.NamedDatabaseConfig{Name: "db2", Host: "host2", Port: 5432, Database: "test2"}); err != nil {
+		t.Fatalf("AddDatabaseConfig() error = %v", err)
+	}
+	_ = cm.SetCurrentDatabase("token1", "db2")
+
+	cm.RemoveDatabaseConfig("db2")
+	if cfg := cm.GetDatabaseConfig("db2"); cfg != nil {
+		t.Error("expected db2 to be removed")
+	}
+	if current := cm.GetCurrentDatabase("token1"); current != "db1" {
+		t.Errorf("expected token1 to fall back to 'db1', got %q", current)
+	}
+
+	// Removing the only database clears the default
+	cm.RemoveDatabaseConfig("db1")
+	if name := cm.GetDefaultDatabaseName(); name != "" {
+		t.Errorf("expected no default database, got %q", name)
+	}
+}
+
+func TestClientManager_DatabasesChangedFunc(t *testing.T) {
+	cm := NewClientManager([]config.NamedDatabaseConfig{
+		{Name: "db1", Host: "host1", Port: 5432, Database: "test1"},
+	})
+	var calls [][]string
+	cm.SetDatabasesChangedFunc(func(databases []config.NamedDatabaseConfig) {
+		var names []string
+		for i := range databases {
+			names = append(names, databases[i].Name)
+		}
+		sort.Strings(names)
+		calls = append(calls, names)
+	})
+
+	_ = cm.AddDatabaseConfig(config.NamedDatabaseConfig{Name: "db1"}) // Already configured
+	_ = cm.AddDatabaseConfig(config.NamedDatabaseConfig{Name: "db2", Host: "host2"})
+	cm.RemoveDatabaseConfig("missing")
+	cm.RemoveDatabaseConfig("db1")
+	cm.UpdateDatabaseConfigs([]config.NamedDatabaseConfig{{Name: "db3", Host: "host3"}})
+
+	want := [][]string{{"db1", "db2"}, {"db2"}, {"db3"}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
 func TestClientManager_SetClient_Validation(t *testing.T) {
 	cm := NewClientManager([]config.NamedDatabaseConfig{
 		{Name: "db1", Host: "localhost", Port: 5432, Database: "test1"},
@@ -441,3 +498,97 @@ func TestClientManager_GetClientCount_Empty(t *testing.T) {
 		t.Errorf("expected 0 clients for empty manager, got %d", count)
 	}
 }
+
+func TestPoolStatsWithoutClients(t *testing.T) {
+	cm := NewClientManager([]config.NamedDatabaseConfig{{Name: "db1"}})
+	if stats := cm.PoolStats(); len(stats) != 0 {
+		t.Errorf("expected no pool statistics, got %+v", stats)
+	}
+}
+
+func TestClientManager_ReapIdleClients(t *testing.T) {
+	cm := NewClientManager([]config.NamedDatabaseConfig{{Name: "db1"}})
+	now := time.Now()
+
+	idle := NewClient(nil)
+	idle.markCreated(now.Add(-time.Hour))
+	recent := NewClient(nil)
+	recent.markCreated(now.Add(-time.Minute))
+	cm.clients["idle-token"] = map[string]*Client{"db1": idle}
+	cm.clients["recent-token"] = map[string]*Client{"db1": recent}
+	cm.currentDB["idle-token"] = "db1"
+	if err := cm.SetClient("default", NewClient(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is closed without an idle timeout
+	if closed := cm.ReapIdleClients(now); closed != 0 {
+		t.Errorf("expected no clients closed without a timeout, got %d", closed)
+	}
+
+	cm.SetIdleTimeout(30 * time.Minute)
+	if closed := cm.ReapIdleClients(now); closed != 1 {
+		t.Errorf("expected 1 client closed, got %d", closed)
+	}
+	if _, exists := cm.clients["idle-token"]; exists {
+		t.Error("expected the idle client to be removed")
+	}
+	if cm.GetCurrentDatabase("idle-token") != "db1" {
+		t.Error("expected the token's current database to be kept")
+	}
+	// Clients set with SetClient cannot be reconnected, so are kept
+	if cm.GetClientCount() != 2 {
+		t.Errorf("expected 2 clients left, got %d", cm.GetClientCount())
+	}
+
+	// Using a client keeps it open
+	if _, ok := cm.LookupClient("recent-token"); !ok {
+		t.Fatal("expected the recent client")
+	}
+	if closed := cm.ReapIdleClients(time.Now().Add(29 * time.Minute)); closed != 0 {
+		t.Errorf("expected the used client to be kept, got %d closed", closed)
+	}
+}
+
+func TestClientManager_InvalidateMetadata(t *testing.T) {
+	cm := NewClientManager([]config.NamedDatabaseConfig{{Name: "db1"}, {Name: "db2"}})
+	now := time.Now()
+
+	newClient := func(token, dbName string, loadedAt time.Time) *Client {
+		client := NewTestClient("postgres://"+token+"/"+dbName, map[string]TableInfo{})
+		client.connections[client.defaultConnStr].metadataLoadedAt = loadedAt
+		if cm.clients[token] == nil {
+			cm.clients[token] = make(map[string]*Client)
+		}
+		cm.clients[token][dbName] = client
+		return client
+	}
+	caller := newClient("token-a", "db1", now.Add(-time.Hour))
+	other := newClient("token-b", "db1", now.Add(-time.Hour))
+	elsewhere := newClient("token-b", "db2", now.Add(-time.Hour))
+
+	stale := func(client *Client) bool {
+		return client.connections[client.defaultConnStr].metadataStale
+	}
+
+	// The caller's metadata was reloaded after the change started
+	if marked := caller.InvalidateMetadata(now.Add(-2 * time.Hour)); marked != 0 {
+		t.Errorf("expected metadata reloaded since to be kept, got %d marked", marked)
+	}
+	if marked := cm.InvalidateMetadata("db1", now, caller); marked != 1 {
+		t.Errorf("expected 1 connection marked, got %d", marked)
+	}
+	if stale(caller) || !stale(other) || stale(elsewhere) {
+		t.Errorf("stale = caller %t, other %t, elsewhere %t; want only other",
+			stale(caller), stale(other), stale(elsewhere))
+	}
+
+	// Nothing is reloaded without an interval, or before metadata is due
+	if refreshed := cm.RefreshMetadata(now); refreshed != 0 {
+		t.Errorf("expected no refresh without an interval, got %d", refreshed)
+	}
+	cm.SetMetadataRefreshInterval(2 * time.Hour)
+	if refreshed := cm.RefreshMetadata(now); refreshed != 0 {
+		t.Errorf("expected no refresh of recent metadata, got %d", refreshed)
+	}
+}
@@ -0,0 +1,128 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// maxStatsValues is how many of a column's most common values are kept
+	maxStatsValues = 10
+	// maxHistogramBounds is how many histogram bounds are kept, including
+	// the lowest and highest
+	maxHistogramBounds = 11
+	// maxStatsValueLength is the length, in characters, values are cut to
+	maxStatsValueLength = 64
+)
+
+// columnStatsQuery reads the statistics of the columns the role may read.
+// Inherited statistics of partitioned tables are only used when a table
+// has no others. The value arrays are anyarray, read through their text
+// form.
+const columnStatsQuery = `
+	SELECT DISTINCT ON (s.schemaname, s.tablename, s.attname)
+		s.schemaname::text,
+		s.tablename::text,
+		s.attname::text,
+		COALESCE(s.null_frac, 0)::float8,
+		COALESCE(s.n_distinct, 0)::float8,
+		COALESCE(s.most_common_vals::text::text[], '{}'),
+		COALESCE(s.most_common_freqs::float8[], '{}'),
+		COALESCE(s.histogram_bounds::text::text[], '{}')
+	FROM pg_stats s
+	WHERE s.schemaname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+		AND ($1::text[] IS NULL OR s.schemaname = ANY($1::text[]))
+	ORDER BY s.schemaname, s.tablename, s.attname, s.inherited
+`
+
+// queryColumnStats reads the column statistics of the given schemas (all
+// schemas if nil), keyed by "schema.table" and column name
+func queryColumnStats(ctx context.Context, pool *pgxpool.Pool, schemas []string) (map[string]map[string]*ColumnStats, error) {
+	rows, err := pool.Query(ctx, columnStatsQuery, schemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column statistics: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]map[string]*ColumnStats)
+	for rows.Next() {
+		var schemaName, tableName, columnName string
+		var values, bounds []string
+		var column ColumnStats
+		if err := rows.Scan(&schemaName, &tableName, &columnName, &column.NullFrac, &column.NDistinct,
+			&values, &column.MostCommonFreqs, &bounds); err != nil {
+			return nil, fmt.Errorf("failed to scan column statistics: %w", err)
+		}
+
+		if len(values) > maxStatsValues {
+			values = values[:maxStatsValues]
+		}
+		if len(column.MostCommonFreqs) > len(values) {
+			column.MostCommonFreqs = column.MostCommonFreqs[:len(values)]
+		}
+		column.MostCommonValues = shortenStatsValues(values)
+		column.HistogramBounds = shortenStatsValues(sampleHistogramBounds(bounds, maxHistogramBounds))
+
+		key := schemaName + "." + tableName
+		if stats[key] == nil {
+			stats[key] = make(map[string]*ColumnStats)
+		}
+		stats[key][columnName] = &column
+	}
+	return stats, rows.Err()
+}
+
+// sampleHistogramBounds keeps up to limit bounds spread evenly over the
+// histogram, always including the lowest and highest
+func sampleHistogramBounds(bounds []string, limit int) []string {
+	if len(bounds) <= limit {
+		return bounds
+	}
+	sampled := make([]string, limit)
+	for i := range sampled {
+		sampled[i] = bounds[i*(len(bounds)-1)/(limit-1)]
+	}
+	return sampled
+}
+
+// shortenStatsValues cuts long values to maxStatsValueLength characters
+func shortenStatsValues(values []string) []string {
+	for i, value := range values {
+		if runes := []rune(value); len(runes) > maxStatsValueLength {
+			values[i] = string(runes[:maxStatsValueLength]) + "..."
+		}
+	}
+	return values
+}
+
+// attachColumnStats adds the column statistics to the metadata. Failing
+// to read them leaves the metadata without statistics rather than failing
+// the load, since they only help the LLM write queries.
+func attachColumnStats(ctx context.Context, pool *pgxpool.Pool, schemas []string, metadata map[string]TableInfo) {
+	stats, err := queryColumnStats(ctx, pool, schemas)
+	if err != nil {
+		globalLogger.Info("Column statistics not loaded: error=%v", err)
+		return
+	}
+	for key, table := range metadata {
+		tableStats := stats[key]
+		if tableStats == nil {
+			continue
+		}
+		for i := range table.Columns {
+			table.Columns[i].Stats = tableStats[table.Columns[i].ColumnName]
+		}
+	}
+}
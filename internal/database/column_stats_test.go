@@ -0,0 +1,44 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSampleHistogramBounds(t *testing.T) {
+	var bounds []string
+	for i := 0; i <= 100; i++ {
+		bounds = append(bounds, strconv.Itoa(i))
+	}
+
+	got := sampleHistogramBounds(bounds, 5)
+	if want := []string{"0", "25", "50", "75", "100"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sampleHistogramBounds() = %v, want %v", got, want)
+	}
+	if got := sampleHistogramBounds(bounds[:3], 5); len(got) != 3 {
+		t.Errorf("expected short histograms to be kept, got %v", got)
+	}
+}
+
+func TestShortenStatsValues(t *testing.T) {
+	long := strings.Repeat("é", maxStatsValueLength+10)
+	got := shortenStatsValues([]string{"short", long})
+	if got[0] != "short" {
+		t.Errorf("expected short values to be kept, got %q", got[0])
+	}
+	if want := strings.Repeat("é", maxStatsValueLength) + "..."; got[1] != want {
+		t.Errorf("shortenStatsValues() = %q, want %q", got[1], want)
+	}
+}
@@ -17,23 +17,45 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/tracing"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // ConnectionInfo holds a connection pool and its metadata
 type ConnectionInfo struct {
-	ConnString     string
-	Pool           *pgxpool.Pool
-	Metadata       map[string]TableInfo
-	MetadataLoaded bool
+	ConnString      string
+	Pool            *pgxpool.Pool
+	Metadata        map[string]TableInfo
+	MetadataLoaded  bool
+	MetadataVersion uint64 // Changes every time metadata is (re)loaded; unique across connections
+
+	// metadataLoadedAt is when Metadata was last (re)loaded; metadataStale
+	// is set when a schema change outdated it, so it is reloaded on next use
+	metadataLoadedAt time.Time
+	metadataStale    bool
+
+	// With lazy metadata loading, schemas holds the schemas in scope and
+	// loadedSchemas the ones loaded so far (nil once all are loaded)
+	schemas       []string
+	loadedSchemas map[string]bool
+
+	// settings are the SET statements applied to every connection of Pool
+	settings *sessionSettings
 }
 
+// metadataVersion is the source of metadata versions across all clients
+var metadataVersion atomic.Uint64
+
 // Client manages multiple PostgreSQL connections and metadata
 type Client struct {
 	connections    map[string]*ConnectionInfo  // keyed by connection string
@@ -41,8 +63,17 @@ type Client struct {
 	initialConnStr string                      // original connection string from env
 	dbConfig       *config.NamedDatabaseConfig // database configuration for pool settings
 	mu             sync.RWMutex
+	metadataMu     sync.Mutex   // serializes metadata loads
+	lastUsed       atomic.Int64 // Unix nanoseconds of the last use; 0 if not created by a ClientManager
+
+	// metadataLoaded is called after metadata is fully (re)loaded
+	metadataLoaded MetadataLoadedFunc
 }
 
+// MetadataLoadedFunc is called after a client fully (re)loads the metadata
+// of a connection, to update data derived from it
+type MetadataLoadedFunc func(client *Client, connStr string)
+
 // NewClient creates a new database client with optional database configuration
 func NewClient(dbConfig *config.NamedDatabaseConfig) *Client {
 	return &Client{
@@ -97,6 +128,21 @@ func (c *Client) Connect() error {
 	return c.ConnectTo(connStr)
 }
 
+// CheckConnection connects to a database once to check that its settings
+// work, without keeping the connection
+func CheckConnection(ctx context.Context, db *config.NamedDatabaseConfig) error {
+	connConfig, err := pgx.ParseConfig(db.BuildConnectionString())
+	if err != nil {
+		return fmt.Errorf("invalid connection settings: %w", err)
+	}
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx) //nolint:errcheck // the check is already done
+	return conn.Ping(ctx)
+}
+
 // ConnectTo establishes a connection to a specific PostgreSQL database
 func (c *Client) ConnectTo(connStr string) error {
 	startTime := time.Now()
@@ -158,6 +204,14 @@ func (c *Client) ConnectTo(connStr string) error {
 	}
 	poolConfig.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
 
+	// Apply settings made with SET to each connection as it is acquired, so
+	// they hold whichever connection a query runs on
+	settings := &sessionSettings{}
+	poolConfig.PrepareConn = settings.prepareConn
+
+	// Queries run within a trace are recorded as spans
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+
 	// Create pool with configured settings
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
@@ -178,6 +232,7 @@ func (c *Client) ConnectTo(connStr string) error {
 		Pool:           pool,
 		Metadata:       make(map[string]TableInfo),
 		MetadataLoaded: false,
+		settings:       settings,
 	}
 
 	duration := time.Since(startTime)
@@ -186,8 +241,21 @@ func (c *Client) ConnectTo(connStr string) error {
 	return nil
 }
 
+// applicationNameParam matches the application_name keyword of a
+// keyword/value connection string
+var applicationNameParam = regexp.MustCompile(`(^|\s)application_name\s*=`)
+
 // addApplicationName adds application_name parameter to a PostgreSQL connection string
 func addApplicationName(connStr, appName string) (string, error) {
+	// Keyword/value format, as used for Unix domain sockets
+	if !strings.Contains(connStr, "://") {
+		if applicationNameParam.MatchString(connStr) {
+			return connStr, nil
+		}
+		quoted := strings.ReplaceAll(strings.ReplaceAll(appName, `\`, `\\`), `'`, `\'`)
+		return strings.TrimSpace(connStr + " application_name='" + quoted + "'"), nil
+	}
+
 	// Parse the connection string
 	u, err := url.Parse(connStr)
 	if err != nil {
@@ -225,6 +293,15 @@ func (c *Client) SetDefaultConnection(connStr string) error {
 	return nil
 }
 
+// DatabaseName returns the name of the configured database the client
+// connects to, or "" for a client without a configuration
+func (c *Client) DatabaseName() string {
+	if c.dbConfig == nil {
+		return ""
+	}
+	return c.dbConfig.Name
+}
+
 // GetDefaultConnection returns the current default connection string
 func (c *Client) GetDefaultConnection() string {
 	c.mu.RLock()
@@ -254,8 +331,14 @@ func (c *Client) LoadMetadata() error {
 	return c.LoadMetadataFor(connStr)
 }
 
-// LoadMetadataFor loads table and column metadata for a specific connection
+// LoadMetadataFor loads table and column metadata for a specific connection,
+// limited to the schemas selected by the database's metadata configuration.
+// With lazy loading, it discards loaded tables so they are reloaded on next use.
 func (c *Client) LoadMetadataFor(connStr string) error {
+	if c.metadataConfig().Lazy {
+		return c.prepareLazyMetadataFor(connStr, true)
+	}
+
 	startTime := time.Now()
 
 	c.mu.RLock()
@@ -266,8 +349,253 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 		return fmt.Errorf("connection not found: %s", connStr)
 	}
 
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+
 	ctx := context.Background()
 
+	// Without schema patterns every schema is loaded in one query
+	var schemas []string
+	scope := c.metadataConfig()
+	if scope.IsFiltered() {
+		var err error
+		schemas, err = listMetadataSchemas(ctx, conn.Pool, scope)
+		if err != nil {
+			LogMetadataLoad(connStr, 0, time.Since(startTime), err)
+			return err
+		}
+	}
+
+	newMetadata, schemaCount, columnCount, err := queryMetadata(ctx, conn.Pool, schemas, scope.IncludeInaccessible)
+	if err != nil {
+		LogMetadataLoad(connStr, 0, time.Since(startTime), err)
+		return err
+	}
+
+	// Update metadata atomically
+	c.mu.Lock()
+	conn.Metadata = newMetadata
+	conn.MetadataLoaded = true
+	conn.MetadataVersion = metadataVersion.Add(1)
+	conn.metadataLoadedAt = startTime
+	conn.metadataStale = false
+	conn.schemas = schemas
+	conn.loadedSchemas = nil
+	c.mu.Unlock()
+
+	duration := time.Since(startTime)
+	LogMetadataLoad(connStr, len(newMetadata), duration, nil)
+
+	// Log detailed metadata info if debug logging is enabled
+	if GetLogLevel() >= LogLevelDebug {
+		LogMetadataDetails(connStr, schemaCount, len(newMetadata), columnCount)
+	}
+
+	c.mu.RLock()
+	loaded := c.metadataLoaded
+	c.mu.RUnlock()
+	if loaded != nil {
+		loaded(c, connStr)
+	}
+
+	return nil
+}
+
+// SetMetadataLoadedFunc sets the function called after metadata is fully
+// (re)loaded. Lazily loaded metadata, which is loaded a schema at a time,
+// does not call it.
+func (c *Client) SetMetadataLoadedFunc(fn MetadataLoadedFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metadataLoaded = fn
+}
+
+// LoadInitialMetadata loads metadata for a newly connected default
+// connection. With lazy loading configured it does nothing; metadata is then
+// loaded on first use instead.
+func (c *Client) LoadInitialMetadata() error {
+	if c.metadataConfig().Lazy {
+		return nil
+	}
+	return c.LoadMetadata()
+}
+
+// IsMetadataLazy reports whether the client loads metadata one schema at a
+// time as it is used
+func (c *Client) IsMetadataLazy() bool {
+	return c.metadataConfig().Lazy
+}
+
+// metadataConfig returns the metadata loading settings for this client
+func (c *Client) metadataConfig() config.MetadataConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.dbConfig == nil {
+		return config.MetadataConfig{}
+	}
+	return c.dbConfig.Metadata
+}
+
+// prepareLazyMetadataFor marks a lazily loaded connection as ready by
+// finding the schemas in scope, without loading any tables yet. Unless
+// reload is set, it does nothing if the connection is already prepared.
+func (c *Client) prepareLazyMetadataFor(connStr string, reload bool) error {
+	startTime := time.Now()
+
+	c.mu.RLock()
+	conn, exists := c.connections[connStr]
+	c.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("connection not found: %s", connStr)
+	}
+
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+
+	c.mu.RLock()
+	loaded := conn.MetadataLoaded
+	c.mu.RUnlock()
+	if loaded && !reload {
+		return nil
+	}
+
+	schemas, err := listMetadataSchemas(context.Background(), conn.Pool, c.metadataConfig())
+	if err != nil {
+		LogMetadataLoad(connStr, 0, time.Since(startTime), err)
+		return err
+	}
+
+	c.mu.Lock()
+	conn.Metadata = make(map[string]TableInfo)
+	conn.MetadataLoaded = true
+	conn.MetadataVersion = metadataVersion.Add(1)
+	conn.metadataLoadedAt = startTime
+	conn.metadataStale = false
+	conn.schemas = schemas
+	conn.loadedSchemas = make(map[string]bool)
+	c.mu.Unlock()
+
+	LogMetadataLoad(connStr, 0, time.Since(startTime), nil)
+	return nil
+}
+
+// loadSchemasFor loads the metadata of any of the given schemas that a
+// lazily loaded connection has not loaded yet
+func (c *Client) loadSchemasFor(connStr string, schemas []string) error {
+	startTime := time.Now()
+
+	// Connections that are not lazily loaded have nothing to do, and should
+	// not wait for a reload in progress
+	c.mu.RLock()
+	conn, exists := c.connections[connStr]
+	partial := exists && conn.loadedSchemas != nil
+	c.mu.RUnlock()
+	if !partial {
+		return nil
+	}
+
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+
+	c.mu.RLock()
+	var pending []string
+	if conn.loadedSchemas != nil {
+		inScope := make(map[string]bool, len(conn.schemas))
+		for _, schema := range conn.schemas {
+			inScope[schema] = true
+		}
+		for _, schema := range schemas {
+			if inScope[schema] && !conn.loadedSchemas[schema] {
+				pending = append(pending, schema)
+			}
+		}
+	}
+	c.mu.RUnlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	loaded, _, _, err := queryMetadata(context.Background(), conn.Pool, pending, c.metadataConfig().IncludeInaccessible)
+	if err != nil {
+		LogMetadataLoad(connStr, 0, time.Since(startTime), err)
+		return err
+	}
+
+	c.mu.Lock()
+	merged := make(map[string]TableInfo, len(conn.Metadata)+len(loaded))
+	for k, v := range conn.Metadata {
+		merged[k] = v
+	}
+	for k, v := range loaded {
+		merged[k] = v
+	}
+	conn.Metadata = merged
+	conn.MetadataVersion = metadataVersion.Add(1)
+	for _, schema := range pending {
+		conn.loadedSchemas[schema] = true
+	}
+	if len(conn.loadedSchemas) == len(conn.schemas) {
+		conn.loadedSchemas = nil
+	}
+	c.mu.Unlock()
+
+	LogMetadataLoad(connStr, len(loaded), time.Since(startTime), nil)
+	return nil
+}
+
+// loadRemainingSchemasFor loads every schema a lazily loaded connection has
+// not loaded yet. Errors are logged, leaving the metadata partly loaded.
+func (c *Client) loadRemainingSchemasFor(connStr string) {
+	c.mu.RLock()
+	var schemas []string
+	if conn, exists := c.connections[connStr]; exists && conn.loadedSchemas != nil {
+		schemas = conn.schemas
+	}
+	c.mu.RUnlock()
+
+	if len(schemas) > 0 {
+		_ = c.loadSchemasFor(connStr, schemas) //nolint:errcheck // logged by loadSchemasFor
+	}
+}
+
+// listMetadataSchemas returns the schemas selected by scope, leaving out
+// those the role cannot use unless scope includes inaccessible objects
+func listMetadataSchemas(ctx context.Context, pool *pgxpool.Pool, scope config.MetadataConfig) ([]string, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT nspname
+		FROM pg_namespace
+		WHERE nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+			AND ($1::boolean OR has_schema_privilege(oid, 'USAGE'))
+		ORDER BY nspname
+	`, scope.IncludeInaccessible)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+	defer rows.Close()
+
+	schemas := []string{}
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+		if scope.IncludesSchema(schema) {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas, rows.Err()
+}
+
+// queryMetadata loads table and column metadata for the given schemas, or
+// for every user schema if schemas is nil. Unless includeInaccessible is
+// set, tables and columns the connection's role cannot SELECT are left out,
+// so tools don't offer objects that queries would fail on. It returns the
+// metadata keyed by schema.table, with the number of schemas and columns
+// found.
+func queryMetadata(ctx context.Context, pool *pgxpool.Pool, schemas []string, includeInaccessible bool) (map[string]TableInfo, int, int, error) {
 	query := `
 		WITH table_comments AS (
 			SELECT
@@ -278,11 +606,24 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 					WHEN 'v' THEN 'VIEW'
 					WHEN 'm' THEN 'MATERIALIZED VIEW'
 				END AS table_type,
-				obj_description(c.oid) AS table_description
+				obj_description(c.oid) AS table_description,
+				-- Policies apply unless the role owns the table (without
+				-- FORCE ROW LEVEL SECURITY) or bypasses row security
+				(c.relrowsecurity
+					AND (c.relforcerowsecurity OR NOT pg_has_role(c.relowner, 'USAGE'))
+					AND NOT EXISTS (
+						SELECT 1 FROM pg_roles r
+						WHERE r.rolname = current_user AND (r.rolsuper OR r.rolbypassrls)
+					)) AS row_security
 			FROM pg_class c
 			JOIN pg_namespace n ON n.oid = c.relnamespace
 			WHERE c.relkind IN ('r', 'v', 'm')
 				AND n.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+				AND ($1::text[] IS NULL OR n.nspname = ANY($1::text[]))
+				AND ($2::boolean OR (
+					has_schema_privilege(n.oid, 'USAGE')
+					AND (has_table_privilege(c.oid, 'SELECT') OR has_any_column_privilege(c.oid, 'SELECT'))
+				))
 			ORDER BY n.nspname, c.relname
 		),
 		column_info AS (
@@ -304,8 +645,10 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 			JOIN pg_type t ON t.oid = a.atttypid
 			WHERE c.relkind IN ('r', 'v', 'm')
 				AND n.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+				AND ($1::text[] IS NULL OR n.nspname = ANY($1::text[]))
 				AND a.attnum > 0
 				AND NOT a.attisdropped
+				AND ($2::boolean OR has_column_privilege(c.oid, a.attnum, 'SELECT'))
 			ORDER BY n.nspname, c.relname, a.attnum
 		),
 		pk_columns AS (
@@ -318,6 +661,7 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 			JOIN pg_namespace n ON n.oid = c.relnamespace
 			JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = ANY(con.conkey)
 			WHERE con.contype = 'p'
+				AND ($1::text[] IS NULL OR n.nspname = ANY($1::text[]))
 		),
 		unique_columns AS (
 			SELECT DISTINCT
@@ -329,6 +673,7 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 			JOIN pg_namespace n ON n.oid = c.relnamespace
 			JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = ANY(con.conkey)
 			WHERE con.contype = 'u'
+				AND ($1::text[] IS NULL OR n.nspname = ANY($1::text[]))
 		),
 		fk_columns AS (
 			SELECT
@@ -345,6 +690,7 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 			JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = cols.col_num
 			JOIN pg_attribute fa ON fa.attrelid = fc.oid AND fa.attnum = cols.ref_num
 			WHERE con.contype = 'f'
+				AND ($1::text[] IS NULL OR n.nspname = ANY($1::text[]))
 		),
 		indexed_columns AS (
 			SELECT DISTINCT
@@ -356,6 +702,7 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 			JOIN pg_namespace n ON n.oid = c.relnamespace
 			JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = ANY(i.indkey)
 			WHERE n.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+				AND ($1::text[] IS NULL OR n.nspname = ANY($1::text[]))
 		),
 		column_defaults AS (
 			SELECT
@@ -369,12 +716,14 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 			JOIN pg_attribute a ON a.attrelid = d.adrelid AND a.attnum = d.adnum
 			WHERE n.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
 				AND NOT a.attisdropped
+				AND ($1::text[] IS NULL OR n.nspname = ANY($1::text[]))
 		)
 		SELECT
 			tc.schema_name,
 			tc.table_name,
 			tc.table_type,
 			COALESCE(tc.table_description, '') AS table_description,
+			tc.row_security,
 			ci.column_name,
 			ci.data_type,
 			ci.is_nullable,
@@ -397,11 +746,9 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 		ORDER BY tc.schema_name, tc.table_name, ci.column_name
 	`
 
-	rows, err := conn.Pool.Query(ctx, query)
+	rows, err := pool.Query(ctx, query, schemas, includeInaccessible)
 	if err != nil {
-		duration := time.Since(startTime)
-		LogMetadataLoad(connStr, 0, duration, err)
-		return fmt.Errorf("failed to query metadata: %w", err)
+		return nil, 0, 0, fmt.Errorf("failed to query metadata: %w", err)
 	}
 	defer rows.Close()
 
@@ -413,14 +760,12 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 		var schemaName, tableName, tableType, tableDesc, columnName, dataType, isNullable, columnDesc string
 		var typeName sql.NullString
 		var typeModifier sql.NullInt32
-		var isPrimaryKey, isUnique, isIndexed bool
+		var rowSecurity, isPrimaryKey, isUnique, isIndexed bool
 		var fkReference, identityType, defaultValue string
 
-		err := rows.Scan(&schemaName, &tableName, &tableType, &tableDesc, &columnName, &dataType, &isNullable, &columnDesc, &typeName, &typeModifier, &isPrimaryKey, &isUnique, &fkReference, &isIndexed, &identityType, &defaultValue)
+		err := rows.Scan(&schemaName, &tableName, &tableType, &tableDesc, &rowSecurity, &columnName, &dataType, &isNullable, &columnDesc, &typeName, &typeModifier, &isPrimaryKey, &isUnique, &fkReference, &isIndexed, &identityType, &defaultValue)
 		if err != nil {
-			duration := time.Since(startTime)
-			LogMetadataLoad(connStr, 0, duration, err)
-			return fmt.Errorf("failed to scan row: %w", err)
+			return nil, 0, 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		key := schemaName + "." + tableName
@@ -433,6 +778,7 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 				TableName:   tableName,
 				TableType:   tableType,
 				Description: tableDesc,
+				RowSecurity: rowSecurity,
 				Columns:     []ColumnInfo{},
 			}
 		}
@@ -473,26 +819,12 @@ func (c *Client) LoadMetadataFor(connStr string) error {
 	}
 
 	if err := rows.Err(); err != nil {
-		duration := time.Since(startTime)
-		LogMetadataLoad(connStr, 0, duration, err)
-		return err
+		return nil, 0, 0, err
 	}
 
-	// Update metadata atomically
-	c.mu.Lock()
-	conn.Metadata = newMetadata
-	conn.MetadataLoaded = true
-	c.mu.Unlock()
-
-	duration := time.Since(startTime)
-	LogMetadataLoad(connStr, len(newMetadata), duration, nil)
+	attachColumnStats(ctx, pool, schemas, newMetadata)
 
-	// Log detailed metadata info if debug logging is enabled
-	if GetLogLevel() >= LogLevelDebug {
-		LogMetadataDetails(connStr, len(schemaSet), len(newMetadata), columnCount)
-	}
-
-	return nil
+	return newMetadata, len(schemaSet), columnCount, nil
 }
 
 // GetMetadata returns a copy of the metadata map for the default connection
@@ -504,8 +836,11 @@ func (c *Client) GetMetadata() map[string]TableInfo {
 	return c.GetMetadataFor(connStr)
 }
 
-// GetMetadataFor returns a copy of the metadata map for a specific connection
+// GetMetadataFor returns a copy of the metadata map for a specific connection.
+// With lazy loading, any schemas not loaded yet are loaded first.
 func (c *Client) GetMetadataFor(connStr string) map[string]TableInfo {
+	c.loadRemainingSchemasFor(connStr)
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -521,6 +856,76 @@ func (c *Client) GetMetadataFor(connStr string) map[string]TableInfo {
 	return result
 }
 
+// GetSchemaMetadata returns the tables of one schema for the default connection
+func (c *Client) GetSchemaMetadata(schema string) map[string]TableInfo {
+	c.mu.RLock()
+	connStr := c.defaultConnStr
+	c.mu.RUnlock()
+
+	return c.GetSchemaMetadataFor(connStr, schema)
+}
+
+// GetSchemaMetadataFor returns the tables of one schema for a specific
+// connection. With lazy loading, only that schema is loaded if needed.
+func (c *Client) GetSchemaMetadataFor(connStr, schema string) map[string]TableInfo {
+	_ = c.loadSchemasFor(connStr, []string{schema}) //nolint:errcheck // logged by loadSchemasFor
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make(map[string]TableInfo)
+	conn, exists := c.connections[connStr]
+	if !exists {
+		return result
+	}
+	for k, v := range conn.Metadata {
+		if v.SchemaName == schema {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// GetSchemaNames returns the sorted names of the schemas in the metadata of
+// the default connection, without loading any lazily loaded schemas
+func (c *Client) GetSchemaNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	conn, exists := c.connections[c.defaultConnStr]
+	if !exists {
+		return nil
+	}
+	if conn.schemas != nil {
+		return append([]string(nil), conn.schemas...)
+	}
+
+	seen := make(map[string]bool)
+	var schemas []string
+	for _, table := range conn.Metadata {
+		if !seen[table.SchemaName] {
+			seen[table.SchemaName] = true
+			schemas = append(schemas, table.SchemaName)
+		}
+	}
+	sort.Strings(schemas)
+	return schemas
+}
+
+// GetMetadataVersion returns the metadata version for the default connection
+// The version changes whenever metadata is reloaded (0 = not loaded), so it can
+// be used to invalidate data derived from the metadata
+func (c *Client) GetMetadataVersion() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	conn, exists := c.connections[c.defaultConnStr]
+	if !exists || !conn.MetadataLoaded {
+		return 0
+	}
+	return conn.MetadataVersion
+}
+
 // IsMetadataLoaded returns whether metadata has been loaded for the default connection
 func (c *Client) IsMetadataLoaded() bool {
 	c.mu.RLock()
@@ -530,16 +935,27 @@ func (c *Client) IsMetadataLoaded() bool {
 	return c.IsMetadataLoadedFor(connStr)
 }
 
-// IsMetadataLoadedFor returns whether metadata has been loaded for a specific connection
+// IsMetadataLoadedFor returns whether metadata has been loaded for a specific
+// connection. With lazy loading configured, the first call finds the schemas
+// in scope and reports the connection ready; tables are loaded as needed.
+// Metadata outdated by a schema change is reloaded first.
 func (c *Client) IsMetadataLoadedFor(connStr string) bool {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
-
 	conn, exists := c.connections[connStr]
-	if !exists {
-		return false
+	loaded := exists && conn.MetadataLoaded
+	stale := loaded && conn.metadataStale
+	c.mu.RUnlock()
+
+	if stale {
+		// If the reload fails, the previous metadata is still usable and
+		// the reload is tried again on next use
+		_ = c.LoadMetadataFor(connStr) //nolint:errcheck // logged by LoadMetadataFor
+		return true
+	}
+	if !exists || loaded || !c.metadataConfig().Lazy {
+		return loaded
 	}
-	return conn.MetadataLoaded
+	return c.prepareLazyMetadataFor(connStr, false) == nil
 }
 
 // GetPool returns the connection pool for the default connection
@@ -12,6 +12,10 @@ package database
 
 import (
 	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/config"
 )
 
 func TestNewClient(t *testing.T) {
@@ -225,6 +229,70 @@ func TestGetMetadataFor(t *testing.T) {
 	}
 }
 
+func TestGetSchemaMetadataFor(t *testing.T) {
+	client := NewClient(nil)
+	client.connections["postgres://localhost/test"] = &ConnectionInfo{
+		ConnString: "postgres://localhost/test",
+		Metadata: map[string]TableInfo{
+			"public.users":  {SchemaName: "public", TableName: "users"},
+			"sales.orders":  {SchemaName: "sales", TableName: "orders"},
+			"sales.refunds": {SchemaName: "sales", TableName: "refunds"},
+		},
+		MetadataLoaded: true,
+	}
+
+	metadata := client.GetSchemaMetadataFor("postgres://localhost/test", "sales")
+	if len(metadata) != 2 {
+		t.Errorf("GetSchemaMetadataFor() returned %d entries, want 2", len(metadata))
+	}
+	if _, ok := metadata["public.users"]; ok {
+		t.Error("GetSchemaMetadataFor() returned a table from another schema")
+	}
+}
+
+func TestGetSchemaNames(t *testing.T) {
+	client := NewClient(nil)
+	client.defaultConnStr = "postgres://localhost/test"
+	conn := &ConnectionInfo{
+		ConnString: "postgres://localhost/test",
+		Metadata: map[string]TableInfo{
+			"sales.orders":  {SchemaName: "sales", TableName: "orders"},
+			"public.users":  {SchemaName: "public", TableName: "users"},
+			"sales.refunds": {SchemaName: "sales", TableName: "refunds"},
+		},
+		MetadataLoaded: true,
+	}
+	client.connections[conn.ConnString] = conn
+
+	if got := client.GetSchemaNames(); len(got) != 2 || got[0] != "public" || got[1] != "sales" {
+		t.Errorf("GetSchemaNames() = %v, want [public sales]", got)
+	}
+
+	// With lazy loading the schemas in scope are known before their tables
+	conn.schemas = []string{"archive", "public", "sales"}
+	if got := client.GetSchemaNames(); len(got) != 3 {
+		t.Errorf("GetSchemaNames() = %v, want the schemas in scope", got)
+	}
+}
+
+func TestLoadInitialMetadata_Lazy(t *testing.T) {
+	client := NewClient(&config.NamedDatabaseConfig{
+		Name:     "test",
+		Metadata: config.MetadataConfig{Lazy: true},
+	})
+
+	// With lazy loading nothing is loaded at connect, so no connection is needed
+	if err := client.LoadInitialMetadata(); err != nil {
+		t.Errorf("LoadInitialMetadata() error = %v, want nil with lazy loading", err)
+	}
+
+	// Without lazy loading the missing connection is reported
+	client = NewClient(&config.NamedDatabaseConfig{Name: "test"})
+	if err := client.LoadInitialMetadata(); err == nil {
+		t.Error("LoadInitialMetadata() expected an error without a connection")
+	}
+}
+
 func TestGetPoolFor(t *testing.T) {
 	client := NewClient(nil)
 
@@ -266,3 +334,56 @@ func TestClose(t *testing.T) {
 		t.Errorf("After Close(), connections map has %d entries, want 0", len(client.connections))
 	}
 }
+
+func TestAddApplicationName(t *testing.T) {
+	tests := []struct {
+		name     string
+		connStr  string
+		wantHost string
+		wantApp  string
+	}{
+		{
+			name:     "URL",
+			connStr:  "postgres://postgres@localhost:5432/mydb",
+			wantHost: "localhost",
+			wantApp:  "test app",
+		},
+		{
+			name:     "URL with application_name",
+			connStr:  "postgres://postgres@localhost:5432/mydb?application_name=custom",
+			wantHost: "localhost",
+			wantApp:  "custom",
+		},
+		{
+			name:     "Unix socket",
+			connStr:  (&config.NamedDatabaseConfig{Host: "/var/run/postgresql", Port: 5432, Database: "mydb", User: "postgres"}).BuildConnectionString(),
+			wantHost: "/var/run/postgresql",
+			wantApp:  "test app",
+		},
+		{
+			name:     "keyword/value with application_name",
+			connStr:  "host=/tmp dbname=mydb application_name=custom",
+			wantHost: "/tmp",
+			wantApp:  "custom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connStr, err := addApplicationName(tt.connStr, "test app")
+			if err != nil {
+				t.Fatalf("addApplicationName() error = %v", err)
+			}
+			connConfig, err := pgx.ParseConfig(connStr)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", connStr, err)
+			}
+			if connConfig.Host != tt.wantHost {
+				t.Errorf("host = %q, want %q", connConfig.Host, tt.wantHost)
+			}
+			if got := connConfig.RuntimeParams["application_name"]; got != tt.wantApp {
+				t.Errorf("application_name = %q, want %q", got, tt.wantApp)
+			}
+		})
+	}
+}
@@ -19,6 +19,23 @@ import (
 	"pgedge-postgres-mcp/internal/mcp"
 )
 
+// databaseInfo returns the listing of a configured database
+func databaseInfo(cfg *config.NamedDatabaseConfig) mcp.DatabaseInfo {
+	return mcp.DatabaseInfo{
+		Name:     cfg.Name,
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Database: cfg.Database,
+		User:     cfg.User,
+		SSLMode:  cfg.SSLMode,
+
+		Environment: cfg.Environment,
+		Tags:        cfg.Tags,
+		Description: cfg.Description,
+		ReadOnly:    !cfg.WritesAllowed(),
+	}
+}
+
 // StdioDatabaseProvider implements mcp.DatabaseProvider for STDIO mode
 // In STDIO mode there's no authentication, so we use a fixed key for all operations
 type StdioDatabaseProvider struct {
@@ -41,15 +58,7 @@ func (p *StdioDatabaseProvider) ListDatabases(ctx context.Context) ([]mcp.Databa
 
 	databases := make([]mcp.DatabaseInfo, 0, len(configs))
 	for i := range configs {
-		cfg := &configs[i]
-		databases = append(databases, mcp.DatabaseInfo{
-			Name:     cfg.Name,
-			Host:     cfg.Host,
-			Port:     cfg.Port,
-			Database: cfg.Database,
-			User:     cfg.User,
-			SSLMode:  cfg.SSLMode,
-		})
+		databases = append(databases, databaseInfo(&configs[i]))
 	}
 
 	return databases, current, nil
@@ -129,15 +138,7 @@ func (p *HTTPDatabaseProvider) ListDatabases(ctx context.Context) ([]mcp.Databas
 
 	databases := make([]mcp.DatabaseInfo, 0, len(accessibleConfigs))
 	for i := range accessibleConfigs {
-		cfg := &accessibleConfigs[i]
-		databases = append(databases, mcp.DatabaseInfo{
-			Name:     cfg.Name,
-			Host:     cfg.Host,
-			Port:     cfg.Port,
-			Database: cfg.Database,
-			User:     cfg.User,
-			SSLMode:  cfg.SSLMode,
-		})
+		databases = append(databases, databaseInfo(&accessibleConfigs[i]))
 	}
 
 	return databases, current, nil
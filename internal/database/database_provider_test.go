@@ -39,7 +39,8 @@ func TestNewStdioDatabaseProvider(t *testing.T) {
 func TestStdioDatabaseProvider_ListDatabases(t *testing.T) {
 	cm := NewClientManager([]config.NamedDatabaseConfig{
 		{Name: "db1", Host: "host1", Port: 5432, Database: "test1", User: "user1", SSLMode: "disable"},
-		{Name: "db2", Host: "host2", Port: 5433, Database: "test2", User: "user2", SSLMode: "require"},
+		{Name: "db2", Host: "host2", Port: 5433, Database: "test2", User: "user2", SSLMode: "require",
+			Environment: "staging", Tags: []string{"prod"}, Description: "Reporting replica"},
 	})
 
 	provider := NewStdioDatabaseProvider(cm)
@@ -69,6 +70,18 @@ func TestStdioDatabaseProvider_ListDatabases(t *testing.T) {
 			if db.Port != 5432 {
 				t.Errorf("expected port 5432 for db1, got %d", db.Port)
 			}
+			if db.ReadOnly {
+				t.Error("expected writes to be allowed for db1")
+			}
+		}
+		if db.Name == "db2" {
+			if db.Environment != "staging" || db.Description != "Reporting replica" {
+				t.Errorf("expected the labels of db2, got %+v", db)
+			}
+			// A prod tag makes the database read-only whatever its environment
+			if !db.ReadOnly {
+				t.Error("expected writes to be disabled for db2")
+			}
 		}
 	}
 
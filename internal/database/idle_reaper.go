@@ -0,0 +1,111 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// idleCheckInterval is how often the reaper looks for idle clients
+const idleCheckInterval = 30 * time.Second
+
+// markCreated records that the manager created the client, so it can be
+// closed when idle and reconnected on its next use
+func (c *Client) markCreated(now time.Time) {
+	c.lastUsed.Store(now.UnixNano())
+}
+
+// markUsed records a use of a client the manager created; clients set with
+// SetClient are not tracked, since they cannot be reconnected
+func (c *Client) markUsed(now time.Time) {
+	if c.lastUsed.Load() != 0 {
+		c.lastUsed.Store(now.UnixNano())
+	}
+}
+
+// idleSince returns when the client was last used, and false if it is not
+// tracked
+func (c *Client) idleSince() (time.Time, bool) {
+	nanos := c.lastUsed.Load()
+	if nanos == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// inUse reports whether any of the client's pools has a connection acquired
+func (c *Client) inUse() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, conn := range c.connections {
+		if conn.Pool != nil && conn.Pool.Stat().AcquiredConns() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SetIdleTimeout sets how long a token's client may go unused before the
+// reaper closes it (0 = never). Closed clients are reconnected on next use.
+func (cm *ClientManager) SetIdleTimeout(timeout time.Duration) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.idleTimeout = timeout
+}
+
+// ReapIdleClients closes the clients that have not been used within the
+// idle timeout and have no query running, and returns how many it closed.
+// The token's current database is kept, so its next request reconnects to
+// the same database.
+func (cm *ClientManager) ReapIdleClients(now time.Time) int {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.idleTimeout <= 0 {
+		return 0
+	}
+
+	closed := 0
+	for tokenHash, tokenClients := range cm.clients {
+		for dbName, client := range tokenClients {
+			lastUsed, tracked := client.idleSince()
+			if !tracked || now.Sub(lastUsed) < cm.idleTimeout || client.inUse() {
+				continue
+			}
+			client.Close()
+			delete(tokenClients, dbName)
+			closed++
+		}
+		if len(tokenClients) == 0 {
+			delete(cm.clients, tokenHash)
+		}
+	}
+	return closed
+}
+
+// RunIdleReaper closes idle clients periodically until ctx is cancelled,
+// calling report after each run that closed clients
+func (cm *ClientManager) RunIdleReaper(ctx context.Context, report func(closed int)) {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if closed := cm.ReapIdleClients(now); closed > 0 && report != nil {
+				report(closed)
+			}
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -172,6 +173,10 @@ func LogPoolStats(connStr string, acquiredConns, idleConns, maxConns int32) {
 		sanitized, acquiredConns, idleConns, maxConns)
 }
 
+// passwordParam matches the password of a keyword/value connection string,
+// quoted or not
+var passwordParam = regexp.MustCompile(`((?:^|\s)password\s*=\s*)('(?:[^'\\]|\\.)*'|\S*)`)
+
 // SanitizeConnStr sanitizes a PostgreSQL connection string by replacing the
 // password with "***". This should be used when displaying connection strings
 // to users or in error messages.
@@ -180,8 +185,8 @@ func SanitizeConnStr(connStr string) string {
 	// Find the scheme (postgres://)
 	schemeIdx := strings.Index(connStr, "://")
 	if schemeIdx == -1 {
-		// No scheme found, return as is
-		return connStr
+		// No scheme found: keyword/value format (host=... password=...)
+		return passwordParam.ReplaceAllString(connStr, "${1}***")
 	}
 
 	scheme := connStr[:schemeIdx+3] // Include "://"
@@ -0,0 +1,141 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// metadataCheckInterval is how often the refresher looks for outdated
+// metadata
+const metadataCheckInterval = 30 * time.Second
+
+// InvalidateMetadata marks the metadata of the client's connections loaded
+// before the given time as outdated, so it is reloaded on next use, and
+// returns how many connections it marked
+func (c *Client) InvalidateMetadata(before time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	marked := 0
+	for _, conn := range c.connections {
+		if conn.MetadataLoaded && conn.metadataLoadedAt.Before(before) {
+			conn.metadataStale = true
+			marked++
+		}
+	}
+	return marked
+}
+
+// refreshMetadataBefore reloads the metadata of the client's connections
+// loaded before cutoff, and returns how many it reloaded
+func (c *Client) refreshMetadataBefore(cutoff time.Time) int {
+	c.mu.RLock()
+	var due []string
+	for connStr, conn := range c.connections {
+		if conn.MetadataLoaded && conn.metadataLoadedAt.Before(cutoff) {
+			due = append(due, connStr)
+		}
+	}
+	c.mu.RUnlock()
+
+	refreshed := 0
+	for _, connStr := range due {
+		if c.LoadMetadataFor(connStr) == nil {
+			refreshed++
+		}
+	}
+	return refreshed
+}
+
+// SetMetadataRefreshInterval sets how old metadata may get before the
+// refresher reloads it (0 = never)
+func (cm *ClientManager) SetMetadataRefreshInterval(interval time.Duration) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.metadataRefreshInterval = interval
+}
+
+// InvalidateMetadata marks the metadata that the clients of a database
+// loaded before the given time as outdated, except for the skipped client,
+// and returns how many connections it marked. It is called after a schema
+// change made through the server, so other tokens see the change.
+func (cm *ClientManager) InvalidateMetadata(dbName string, before time.Time, skip *Client) int {
+	marked := 0
+	for _, client := range cm.databaseClients(dbName) {
+		if client != skip {
+			marked += client.InvalidateMetadata(before)
+		}
+	}
+	return marked
+}
+
+// RefreshMetadata reloads the metadata loaded longer than the refresh
+// interval ago, and returns how many connections it reloaded. The clients
+// are reloaded without holding the manager's lock, since reloads of large
+// schemas take a while.
+func (cm *ClientManager) RefreshMetadata(now time.Time) int {
+	cm.mu.RLock()
+	interval := cm.metadataRefreshInterval
+	cm.mu.RUnlock()
+	if interval <= 0 {
+		return 0
+	}
+
+	refreshed := 0
+	for _, client := range cm.databaseClients("") {
+		refreshed += client.refreshMetadataBefore(now.Add(-interval))
+	}
+	return refreshed
+}
+
+// RunMetadataRefresher reloads outdated metadata periodically until ctx is
+// cancelled, calling report after each run that reloaded metadata
+func (cm *ClientManager) RunMetadataRefresher(ctx context.Context, report func(refreshed int)) {
+	ticker := time.NewTicker(metadataCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if refreshed := cm.RefreshMetadata(now); refreshed > 0 && report != nil {
+				report(refreshed)
+			}
+		}
+	}
+}
+
+// databaseClients returns the clients of a database, or of every database
+// if dbName is empty
+func (cm *ClientManager) databaseClients(dbName string) []*Client {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	var clients []*Client
+	for _, tokenClients := range cm.clients {
+		for name, client := range tokenClients {
+			if dbName == "" || name == dbName {
+				clients = append(clients, client)
+			}
+		}
+	}
+	return clients
+}
+
+// SetMetadataLoadedFunc sets the function the clients created from now on
+// call after they fully (re)load metadata
+func (cm *ClientManager) SetMetadataLoadedFunc(fn MetadataLoadedFunc) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.metadataLoaded = fn
+}
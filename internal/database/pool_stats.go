@@ -0,0 +1,80 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"sort"
+	"time"
+)
+
+// PoolStats summarizes the connection pools of one configured database
+// across all clients (one per token in HTTP mode with authentication)
+type PoolStats struct {
+	Database          string
+	Clients           int   // Clients with a pool for the database
+	TotalConns        int32 // Open connections
+	AcquiredConns     int32 // Connections in use
+	IdleConns         int32 // Connections waiting to be used
+	MaxConns          int32 // Sum of the pools' size limits
+	AcquireCount      int64 // Connections acquired since the pools were created
+	EmptyAcquireCount int64 // Acquires that had to wait for or open a connection
+	AcquireDuration   time.Duration
+}
+
+// addPools adds the client's pools to the statistics
+func (s *PoolStats) addPools(c *Client) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	counted := false
+	for _, conn := range c.connections {
+		if conn.Pool == nil {
+			continue
+		}
+		stat := conn.Pool.Stat()
+		s.TotalConns += stat.TotalConns()
+		s.AcquiredConns += stat.AcquiredConns()
+		s.IdleConns += stat.IdleConns()
+		s.MaxConns += stat.MaxConns()
+		s.AcquireCount += stat.AcquireCount()
+		s.EmptyAcquireCount += stat.EmptyAcquireCount()
+		s.AcquireDuration += stat.AcquireDuration()
+		counted = true
+	}
+	if counted {
+		s.Clients++
+	}
+}
+
+// PoolStats returns connection pool statistics for each database that has
+// clients, sorted by database name
+func (cm *ClientManager) PoolStats() []PoolStats {
+	cm.mu.RLock()
+	byDatabase := make(map[string]*PoolStats)
+	for _, tokenClients := range cm.clients {
+		for dbName, client := range tokenClients {
+			stats, ok := byDatabase[dbName]
+			if !ok {
+				stats = &PoolStats{Database: dbName}
+				byDatabase[dbName] = stats
+			}
+			stats.addPools(client)
+		}
+	}
+	cm.mu.RUnlock()
+
+	result := make([]PoolStats, 0, len(byDatabase))
+	for _, stats := range byDatabase {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Database < result[j].Database })
+	return result
+}
@@ -0,0 +1,303 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// Server log sources
+const (
+	ServerLogSourceAuto       = "auto"
+	ServerLogSourceFile       = "file"
+	ServerLogSourcePgReadFile = "pg_read_file"
+	ServerLogSourceLogFDW     = "log_fdw"
+)
+
+const (
+	// serverLogTailBytes is how much of the end of a log file is read
+	serverLogTailBytes = 256 * 1024
+	// logFDWMaxBytes bounds the size of log files read through log_fdw,
+	// which cannot read only the end of a file
+	logFDWMaxBytes = 16 * 1024 * 1024
+	// serverLogClockSlack widens the time window to allow for clock and
+	// timestamp rounding differences
+	serverLogClockSlack = 2 * time.Second
+	// logFDWTable is the foreign table created (and rolled back) to read a
+	// log file through log_fdw
+	logFDWTable = "pgedge_mcp_log_tail"
+)
+
+// ServerLog holds PostgreSQL log lines related to a failed operation
+type ServerLog struct {
+	Source string   // Where the lines were read from
+	Lines  []string // Matching lines, oldest first
+}
+
+// logTimestamp matches the timestamp at the start of a log line written with
+// %t or %m in log_line_prefix
+var logTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
+
+// logSeverity matches the severity label of a stderr log line
+var logSeverity = regexp.MustCompile(`\b(DEBUG[1-5]?|INFO|NOTICE|WARNING|ERROR|LOG|FATAL|PANIC|DETAIL|HINT|QUERY|CONTEXT|LOCATION|STATEMENT):  `)
+
+// RecentServerLog returns errors and warnings that PostgreSQL logged during
+// the last `within` on the default connection, read from the configured
+// source. It returns nil if nothing relevant was logged.
+func (c *Client) RecentServerLog(ctx context.Context, cfg config.PostgresLogConfig, within time.Duration) (*ServerLog, error) {
+	pool := c.GetPoolFor(c.GetDefaultConnection())
+	if pool == nil {
+		return nil, fmt.Errorf("no connection pool")
+	}
+
+	// Log timestamps are written in log_timezone, so the cutoff is computed
+	// by the server; without it every recent error is reported
+	var cutoff string
+	err := pool.QueryRow(ctx, `SELECT to_char((now() - make_interval(secs => $1)) AT TIME ZONE current_setting('log_timezone'), 'YYYY-MM-DD HH24:MI:SS')`,
+		(within + serverLogClockSlack).Seconds()).Scan(&cutoff)
+	if err != nil {
+		cutoff = ""
+	}
+
+	source := cfg.Source
+	if source == "" || source == ServerLogSourceAuto {
+		switch {
+		case cfg.Path != "":
+			source = ServerLogSourceFile
+		case logFDWAvailable(ctx, pool):
+			source = ServerLogSourceLogFDW
+		default:
+			source = ServerLogSourcePgReadFile
+		}
+	}
+
+	var data []byte
+	switch source {
+	case ServerLogSourceFile:
+		data, err = readLocalLogTail(cfg.Path)
+	case ServerLogSourcePgReadFile:
+		data, err = readLogWithPgReadFile(ctx, pool)
+	case ServerLogSourceLogFDW:
+		server := cfg.LogFDWServer
+		if server == "" {
+			server = "log_server"
+		}
+		data, err = readLogWithLogFDW(ctx, pool, server)
+	default:
+		return nil, fmt.Errorf("unknown log source %q", source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the server log (%s): %w", source, err)
+	}
+
+	maxLines := cfg.MaxLines
+	if maxLines <= 0 {
+		maxLines = 20
+	}
+	lines := filterServerLog(string(data), cutoff, maxLines)
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return &ServerLog{Source: source, Lines: lines}, nil
+}
+
+// filterServerLog returns the last maxLines lines of errors and warnings,
+// with their DETAIL, HINT, CONTEXT and STATEMENT lines, logged at or after
+// cutoff ("YYYY-MM-DD HH:MM:SS"; empty for no time limit)
+func filterServerLog(data, cutoff string, maxLines int) []string {
+	var lines []string
+	include := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		if match := logSeverity.FindStringSubmatch(line); match != nil {
+			switch match[1] {
+			case "ERROR", "FATAL", "PANIC", "WARNING":
+				include = true
+			case "DETAIL", "HINT", "QUERY", "CONTEXT", "LOCATION", "STATEMENT":
+				// Part of the previous message
+			default:
+				include = false
+			}
+			if ts := logTimestamp.FindString(line); ts != "" && cutoff != "" && ts < cutoff {
+				include = false
+			}
+		}
+		// Lines without a severity continue the previous message
+		if include {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return lines
+}
+
+// readLocalLogTail reads the end of a log file; a directory is searched for
+// its most recently modified file
+func readLocalLogTail(path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no log path configured")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		path, err = newestLogFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err = f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - serverLogTailBytes
+	if offset < 0 {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(f)
+}
+
+// newestLogFile returns the most recently modified regular file in a directory
+func newestLogFile(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var newest string
+	var newestTime time.Time
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if newest == "" || info.ModTime().After(newestTime) {
+			newest = filepath.Join(dir, entry.Name())
+			newestTime = info.ModTime()
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("no log files in %s", dir)
+	}
+	return newest, nil
+}
+
+// readLogWithPgReadFile reads the end of the current log file through the
+// server; this needs superuser or pg_read_server_files and the logging
+// collector
+func readLogWithPgReadFile(ctx context.Context, pool *pgxpool.Pool) ([]byte, error) {
+	var path *string
+	if err := pool.QueryRow(ctx, "SELECT pg_current_logfile()").Scan(&path); err != nil {
+		return nil, err
+	}
+	if path == nil {
+		return nil, errors.New("the logging collector is not writing a log file")
+	}
+
+	// Binary reads avoid encoding errors when the offset splits a character
+	var data []byte
+	err := pool.QueryRow(ctx, `
+		SELECT pg_read_binary_file($1, greatest(s.size - $2, 0), $2)
+		FROM pg_stat_file($1) s`, *path, int64(serverLogTailBytes)).Scan(&data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// logFDWAvailable reports whether the log_fdw functions (Amazon RDS and
+// Aurora) are installed
+func logFDWAvailable(ctx context.Context, pool *pgxpool.Pool) bool {
+	var exists bool
+	err := pool.QueryRow(ctx, "SELECT to_regproc('list_postgres_log_files') IS NOT NULL").Scan(&exists)
+	return err == nil && exists
+}
+
+// readLogWithLogFDW reads the newest log file through log_fdw. The foreign
+// table is created in a transaction that is rolled back, so nothing is left
+// behind.
+func readLogWithLogFDW(ctx context.Context, pool *pgxpool.Pool, server string) ([]byte, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // the foreign table is never kept
+	}()
+
+	var file string
+	var size int64
+	err = tx.QueryRow(ctx, `
+		SELECT file_name, file_size_bytes FROM list_postgres_log_files()
+		WHERE file_name NOT LIKE '%.csv' AND file_name NOT LIKE '%.json'
+		ORDER BY file_name DESC LIMIT 1`).Scan(&file, &size)
+	if err != nil {
+		return nil, err
+	}
+	if size > logFDWMaxBytes {
+		return nil, fmt.Errorf("log file %s is too large to read through log_fdw (%d bytes)", file, size)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT create_foreign_table_for_log_file($1, $2, $3)", logFDWTable, server, file); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, "SELECT log_entry FROM "+logFDWTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sb strings.Builder
+	for rows.Next() {
+		var entry string
+		if err := rows.Scan(&entry); err != nil {
+			return nil, err
+		}
+		sb.WriteString(entry)
+		sb.WriteByte('\n')
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
@@ -0,0 +1,121 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleServerLog = `2025-06-01 10:00:00.123 UTC [101] LOG:  checkpoint starting: time
+2025-06-01 10:00:01.000 UTC [102] ERROR:  relation "old" does not exist at character 15
+2025-06-01 10:00:01.000 UTC [102] STATEMENT:  SELECT * FROM old
+2025-06-01 10:05:00.000 UTC [103] LOG:  connection received: host=[local]
+2025-06-01 10:05:02.500 UTC [104] ERROR:  duplicate key value violates unique constraint "users_pkey"
+2025-06-01 10:05:02.500 UTC [104] DETAIL:  Key (id)=(1) already exists.
+2025-06-01 10:05:02.500 UTC [104] STATEMENT:  INSERT INTO users VALUES (1,
+	'alice')
+2025-06-01 10:05:03.000 UTC [105] LOG:  disconnection: session time: 0:00:01.000
+2025-06-01 10:05:04.000 UTC [106] WARNING:  there is no transaction in progress
+`
+
+func TestFilterServerLog(t *testing.T) {
+	t.Run("keeps errors and their details after the cutoff", func(t *testing.T) {
+		lines := filterServerLog(sampleServerLog, "2025-06-01 10:05:00", 20)
+		expected := []string{
+			`2025-06-01 10:05:02.500 UTC [104] ERROR:  duplicate key value violates unique constraint "users_pkey"`,
+			`2025-06-01 10:05:02.500 UTC [104] DETAIL:  Key (id)=(1) already exists.`,
+			`2025-06-01 10:05:02.500 UTC [104] STATEMENT:  INSERT INTO users VALUES (1,`,
+			`	'alice')`,
+			`2025-06-01 10:05:04.000 UTC [106] WARNING:  there is no transaction in progress`,
+		}
+		if !reflect.DeepEqual(lines, expected) {
+			t.Errorf("unexpected lines:\n%s", strings.Join(lines, "\n"))
+		}
+	})
+
+	t.Run("no cutoff keeps every error", func(t *testing.T) {
+		lines := filterServerLog(sampleServerLog, "", 20)
+		if len(lines) != 7 {
+			t.Errorf("expected 7 lines, got %d:\n%s", len(lines), strings.Join(lines, "\n"))
+		}
+	})
+
+	t.Run("limits to the most recent lines", func(t *testing.T) {
+		lines := filterServerLog(sampleServerLog, "", 2)
+		if len(lines) != 2 || !strings.Contains(lines[1], "WARNING") {
+			t.Errorf("expected the last 2 lines, got %v", lines)
+		}
+	})
+
+	t.Run("nothing after the cutoff", func(t *testing.T) {
+		if lines := filterServerLog(sampleServerLog, "2025-06-01 11:00:00", 20); len(lines) != 0 {
+			t.Errorf("expected no lines, got %v", lines)
+		}
+	})
+}
+
+func TestReadLocalLogTail(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "postgresql-Sat.log")
+	newer := filepath.Join(dir, "postgresql-Sun.log")
+	if err := os.WriteFile(older, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newer, []byte("new\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(older, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := readLocalLogTail(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "new\n" {
+		t.Errorf("expected the newest file, got %q", data)
+	}
+
+	data, err = readLocalLogTail(older)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "old\n" {
+		t.Errorf("expected the named file, got %q", data)
+	}
+
+	// Only the end of a large file is read
+	large := filepath.Join(dir, "large.log")
+	content := strings.Repeat("x", serverLogTailBytes) + "tail\n"
+	if err := os.WriteFile(large, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	data, err = readLocalLogTail(large)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(data) != serverLogTailBytes || !strings.HasSuffix(string(data), "tail\n") {
+		t.Errorf("expected the last %d bytes, got %d", serverLogTailBytes, len(data))
+	}
+
+	if _, err := readLocalLogTail(t.TempDir()); err == nil {
+		t.Error("expected an error for an empty directory")
+	}
+	if _, err := readLocalLogTail(""); err == nil {
+		t.Error("expected an error without a path")
+	}
+}
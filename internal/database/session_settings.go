@@ -0,0 +1,201 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SessionSetting is a run-time parameter set with SET through
+// query_database. It is applied to every connection of the pool as the
+// connection is acquired, so later queries see it whichever connection
+// they run on.
+type SessionSetting struct {
+	Name  string // Lowercase parameter name
+	Value string // Value as written in the SET statement
+}
+
+// SetStatement is a parsed SET or RESET statement
+type SetStatement struct {
+	Name  string // Lowercase parameter name; empty for RESET ALL
+	Value string // Value as written; empty for RESET
+	Local bool   // SET LOCAL, which is kept for the session like SET
+}
+
+// IsReset reports whether the statement removes settings
+func (s *SetStatement) IsReset() bool {
+	return s.Value == ""
+}
+
+// SQL returns the statement that applies the setting to a session
+func (s *SetStatement) SQL() string {
+	switch {
+	case s.Name == "":
+		return "RESET ALL"
+	case s.IsReset():
+		return "RESET " + s.Name
+	default:
+		return fmt.Sprintf("SET %s TO %s", s.Name, s.Value)
+	}
+}
+
+var (
+	setStatementPattern = regexp.MustCompile(
+		`(?is)^SET\s+(?:(SESSION|LOCAL)\s+)?(?:(TIME\s+ZONE)\s+|(SCHEMA)\s+|([a-z_][a-z0-9_$]*(?:\.[a-z_][a-z0-9_$]*)?)(?:\s+TO\s+|\s*=\s*))(.+?)\s*;?$`)
+	resetStatementPattern = regexp.MustCompile(
+		`(?is)^RESET\s+([a-z_][a-z0-9_$]*(?:\.[a-z_][a-z0-9_$]*)?)\s*;?$`)
+)
+
+// protectedSettings cannot be changed through query_database, since they
+// would escape the read-only protection or change the user queries run as
+var protectedSettings = map[string]bool{
+	"default_transaction_read_only": true,
+	"transaction_read_only":         true,
+	"role":                          true,
+	"session_authorization":         true,
+}
+
+// ParseSetStatement parses a SET or RESET of a run-time parameter. It
+// returns nil for other statements, including SET ROLE and SET
+// TRANSACTION, and an error for settings that cannot be changed.
+func ParseSetStatement(query string) (*SetStatement, error) {
+	query = strings.TrimSpace(query)
+
+	var stmt SetStatement
+	if m := resetStatementPattern.FindStringSubmatch(query); m != nil {
+		if name := strings.ToLower(m[1]); name != "all" {
+			stmt.Name = name
+		}
+	} else if m := setStatementPattern.FindStringSubmatch(query); m != nil {
+		stmt.Local = strings.EqualFold(m[1], "LOCAL")
+		switch {
+		case m[2] != "":
+			stmt.Name = "timezone"
+		case m[3] != "":
+			stmt.Name = "search_path"
+		default:
+			stmt.Name = strings.ToLower(m[4])
+		}
+		stmt.Value = m[5]
+		if strings.Contains(stmt.Value, ";") {
+			return nil, fmt.Errorf("SET accepts a single statement")
+		}
+		// SET x TO DEFAULT and SET TIME ZONE LOCAL restore the default
+		if strings.EqualFold(stmt.Value, "DEFAULT") || (m[2] != "" && strings.EqualFold(stmt.Value, "LOCAL")) {
+			stmt.Value = ""
+		}
+	} else {
+		return nil, nil
+	}
+
+	if protectedSettings[stmt.Name] {
+		return nil, fmt.Errorf("%s cannot be changed with query_database", stmt.Name)
+	}
+	return &stmt, nil
+}
+
+// settingsVersionKey stores, in a connection's custom data, the version of
+// the session settings applied to it
+const settingsVersionKey = "pgedge.session_settings_version"
+
+// sessionSettings holds the settings of one connection pool
+type sessionSettings struct {
+	mu       sync.RWMutex
+	settings []SessionSetting // In the order they were set
+	version  uint64           // Incremented on every change
+}
+
+// list returns a copy of the settings and their version
+func (s *sessionSettings) list() ([]SessionSetting, uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]SessionSetting(nil), s.settings...), s.version
+}
+
+// apply records a SET or RESET statement
+func (s *sessionSettings) apply(stmt *SetStatement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stmt.Name == "" {
+		s.settings = nil
+		s.version++
+		return
+	}
+	kept := s.settings[:0]
+	for _, setting := range s.settings {
+		if setting.Name != stmt.Name {
+			kept = append(kept, setting)
+		}
+	}
+	if !stmt.IsReset() {
+		kept = append(kept, SessionSetting{Name: stmt.Name, Value: stmt.Value})
+	}
+	s.settings = kept
+	s.version++
+}
+
+// prepareConn brings a connection's settings up to date as it is acquired
+// from the pool. Connections whose settings are out of date are reset and
+// the current settings applied again.
+func (s *sessionSettings) prepareConn(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	settings, version := s.list()
+	data := conn.PgConn().CustomData()
+	if applied, _ := data[settingsVersionKey].(uint64); applied == version {
+		return true, nil
+	}
+
+	if _, err := conn.Exec(ctx, "RESET ALL"); err != nil {
+		return false, fmt.Errorf("failed to reset session settings: %w", err)
+	}
+	for _, setting := range settings {
+		stmt := SetStatement{Name: setting.Name, Value: setting.Value}
+		if _, err := conn.Exec(ctx, stmt.SQL()); err != nil {
+			return false, fmt.Errorf("failed to apply session setting %s: %w", setting.Name, err)
+		}
+	}
+	data[settingsVersionKey] = version
+	return true, nil
+}
+
+// SessionSettings returns the settings applied to the connections of
+// connStr, in the order they were set
+func (c *Client) SessionSettings(connStr string) []SessionSetting {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if conn, ok := c.connections[connStr]; ok && conn.settings != nil {
+		settings, _ := conn.settings.list()
+		return settings
+	}
+	return nil
+}
+
+// ApplySessionSetting records a SET or RESET statement for the connections
+// of connStr; connections are updated the next time they are acquired. The
+// statement should already have been run successfully.
+func (c *Client) ApplySessionSetting(connStr string, stmt *SetStatement) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	conn, ok := c.connections[connStr]
+	if !ok || conn.settings == nil {
+		return fmt.Errorf("connection not found: %s", SanitizeConnStr(connStr))
+	}
+	conn.settings.apply(stmt)
+	return nil
+}
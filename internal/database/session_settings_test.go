@@ -0,0 +1,110 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package database
+
+import (
+	"testing"
+)
+
+func TestParseSetStatement(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantSQL string // Empty when the query is not a SET or RESET
+		local   bool
+		wantErr bool
+	}{
+		{query: "SET search_path TO app, public", wantSQL: "SET search_path TO app, public"},
+		{query: "set Search_Path = 'app';", wantSQL: "SET search_path TO 'app'"},
+		{query: "SET LOCAL work_mem = '64MB'", wantSQL: "SET work_mem TO '64MB'", local: true},
+		{query: "SET SESSION statement_timeout TO 5000", wantSQL: "SET statement_timeout TO 5000"},
+		{query: "SET TIME ZONE 'UTC'", wantSQL: "SET timezone TO 'UTC'"},
+		{query: "SET TIME ZONE LOCAL", wantSQL: "RESET timezone"},
+		{query: "SET SCHEMA 'app'", wantSQL: "SET search_path TO 'app'"},
+		{query: "SET myapp.tenant = '42'", wantSQL: "SET myapp.tenant TO '42'"},
+		{query: "SET search_path TO DEFAULT", wantSQL: "RESET search_path"},
+		{query: "RESET search_path", wantSQL: "RESET search_path"},
+		{query: "reset all;", wantSQL: "RESET ALL"},
+		{query: "SELECT 1"},
+		{query: "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE"},
+		{query: "SET ROLE admin"},
+		{query: "SET role = admin", wantErr: true},
+		{query: "SET default_transaction_read_only = off", wantErr: true},
+		{query: "RESET session_authorization", wantErr: true},
+		{query: "SET search_path TO app; DROP TABLE users", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			stmt, err := ParseSetStatement(tt.query)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", stmt)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantSQL == "" {
+				if stmt != nil {
+					t.Errorf("expected no statement, got %+v", stmt)
+				}
+				return
+			}
+			if stmt == nil {
+				t.Fatal("expected a statement")
+			}
+			if stmt.SQL() != tt.wantSQL {
+				t.Errorf("SQL() = %q, want %q", stmt.SQL(), tt.wantSQL)
+			}
+			if stmt.Local != tt.local {
+				t.Errorf("Local = %v, want %v", stmt.Local, tt.local)
+			}
+		})
+	}
+}
+
+func TestClientSessionSettings(t *testing.T) {
+	connStr := "postgres://localhost/test"
+	client := NewTestClient(connStr, nil)
+
+	apply := func(query string) {
+		t.Helper()
+		stmt, err := ParseSetStatement(query)
+		if err != nil || stmt == nil {
+			t.Fatalf("ParseSetStatement(%q) = %v, %v", query, stmt, err)
+		}
+		if err := client.ApplySessionSetting(connStr, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	apply("SET search_path TO app")
+	apply("SET work_mem = '64MB'")
+	apply("SET search_path TO other, public")
+	settings := client.SessionSettings(connStr)
+	if len(settings) != 2 || settings[0].Name != "work_mem" || settings[1].Value != "other, public" {
+		t.Errorf("unexpected settings: %+v", settings)
+	}
+
+	apply("RESET work_mem")
+	if settings := client.SessionSettings(connStr); len(settings) != 1 || settings[0].Name != "search_path" {
+		t.Errorf("expected only search_path, got %+v", settings)
+	}
+
+	apply("RESET ALL")
+	if settings := client.SessionSettings(connStr); len(settings) != 0 {
+		t.Errorf("expected no settings, got %+v", settings)
+	}
+
+	if err := client.ApplySessionSetting("postgres://localhost/other", &SetStatement{}); err == nil {
+		t.Error("expected an error for an unknown connection")
+	}
+}
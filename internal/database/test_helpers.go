@@ -17,10 +17,12 @@ func NewTestClient(connStr string, metadata map[string]TableInfo) *Client {
 
 	// Add mock connection info
 	client.connections[connStr] = &ConnectionInfo{
-		ConnString:     connStr,
-		Pool:           nil, // No actual connection pool needed for tests
-		Metadata:       metadata,
-		MetadataLoaded: true,
+		ConnString:      connStr,
+		Pool:            nil, // No actual connection pool needed for tests
+		Metadata:        metadata,
+		MetadataLoaded:  true,
+		MetadataVersion: metadataVersion.Add(1),
+		settings:        &sessionSettings{},
 	}
 
 	// Set as default connection
@@ -16,6 +16,7 @@ type TableInfo struct {
 	TableName   string
 	TableType   string // 'TABLE', 'VIEW', or 'MATERIALIZED VIEW'
 	Description string
+	RowSecurity bool // True if row-level security policies limit the rows the role can see
 	Columns     []ColumnInfo
 }
 
@@ -33,4 +34,18 @@ type ColumnInfo struct {
 	DefaultValue     string // Default value expression if any, empty otherwise
 	IsVectorColumn   bool   // True if this is a pgvector column
 	VectorDimensions int    // Number of dimensions for vector columns (0 if not a vector)
+
+	// Planner statistics from pg_stats; nil if the column has not been
+	// analyzed or the role cannot read them
+	Stats *ColumnStats
+}
+
+// ColumnStats summarizes the planner statistics of a column. Values are
+// the text form PostgreSQL gives them, shortened to keep the cache small.
+type ColumnStats struct {
+	NullFrac         float64   // Fraction of the rows that are NULL
+	NDistinct        float64   // Distinct values; negative values are minus the fraction of the rows
+	MostCommonValues []string  // Most common values, most frequent first
+	MostCommonFreqs  []float64 // Fraction of the rows holding each of MostCommonValues
+	HistogramBounds  []string  // Bounds dividing the other values into groups of equal size
 }
@@ -0,0 +1,212 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package dbstore stores the database connections added while the server
+// runs, so they are configured again after a restart or configuration
+// reload. Passwords are encrypted with a key derived from the server
+// secret.
+package dbstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/crypto"
+)
+
+// FileName is the name of the store in the data directory
+const FileName = "databases.yaml"
+
+// keyPurpose derives the password key from the server secret
+const keyPurpose = "database-passwords"
+
+// storedDatabase is a database in the file, with its password encrypted
+type storedDatabase struct {
+	config.NamedDatabaseConfig `yaml:",inline"`
+	EncryptedPassword          string `yaml:"encrypted_password,omitempty"`
+}
+
+// storeFile is the layout of the file
+type storeFile struct {
+	Databases []storedDatabase `yaml:"databases"`
+}
+
+// Store manages the file of added databases
+type Store struct {
+	Path       string // The store file
+	SecretPath string // The server secret, created when the first password is stored
+
+	mu sync.Mutex
+}
+
+// NewStoreFromConfig returns the store in the configured data directory,
+// encrypting with the configured server secret
+func NewStoreFromConfig(cfg *config.Config) *Store {
+	execPath, err := os.Executable()
+	if err != nil {
+		execPath = "."
+	}
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		dataDir = config.GetDefaultDataDir(execPath)
+	}
+	secretPath := cfg.SecretFile
+	if secretPath == "" {
+		secretPath = config.GetDefaultSecretPath(execPath)
+	}
+	return &Store{Path: filepath.Join(dataDir, FileName), SecretPath: secretPath}
+}
+
+// Load returns the stored databases with their passwords decrypted
+func (s *Store) Load() ([]config.NamedDatabaseConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	var key *crypto.EncryptionKey
+	databases := make([]config.NamedDatabaseConfig, 0, len(stored))
+	for _, entry := range stored {
+		db := entry.NamedDatabaseConfig
+		if entry.EncryptedPassword != "" {
+			if key == nil {
+				if key, err = s.key(false); err != nil {
+					return nil, err
+				}
+			}
+			if db.Password, err = key.Decrypt(entry.EncryptedPassword); err != nil {
+				return nil, fmt.Errorf("failed to decrypt the password of database '%s': %w", db.Name, err)
+			}
+		}
+		databases = append(databases, db)
+	}
+	return databases, nil
+}
+
+// Add stores a database. A database with the same name must not be stored
+// yet.
+func (s *Store) Add(db config.NamedDatabaseConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.read()
+	if err != nil {
+		return err
+	}
+	for _, entry := range stored {
+		if entry.Name == db.Name {
+			return fmt.Errorf("database '%s' is already stored in %s", db.Name, s.Path)
+		}
+	}
+
+	entry := storedDatabase{NamedDatabaseConfig: db}
+	entry.Password = ""
+	if db.Password != "" {
+		key, err := s.key(true)
+		if err != nil {
+			return err
+		}
+		if entry.EncryptedPassword, err = key.Encrypt(db.Password); err != nil {
+			return fmt.Errorf("failed to encrypt the password: %w", err)
+		}
+	}
+	return s.write(append(stored, entry))
+}
+
+// Merge returns the configured databases followed by the stored ones.
+// Stored databases whose name is configured are left out: the
+// configuration file wins.
+func (s *Store) Merge(configured []config.NamedDatabaseConfig) ([]config.NamedDatabaseConfig, error) {
+	stored, err := s.Load()
+	if err != nil {
+		return configured, err
+	}
+	names := make(map[string]bool, len(configured))
+	for i := range configured {
+		names[configured[i].Name] = true
+	}
+	merged := append([]config.NamedDatabaseConfig{}, configured...)
+	for _, db := range stored {
+		if !names[db.Name] {
+			merged = append(merged, db)
+		}
+	}
+	return merged, nil
+}
+
+// key returns the password key, creating the server secret if create is set
+// and it does not exist
+func (s *Store) key(create bool) (*crypto.EncryptionKey, error) {
+	var secret *crypto.EncryptionKey
+	var err error
+	if create {
+		secret, _, err = crypto.LoadOrGenerateKey(s.SecretPath)
+	} else {
+		secret, err = crypto.LoadKeyFromFile(s.SecretPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the server secret: %w", err)
+	}
+	return secret.DeriveKey(keyPurpose)
+}
+
+// read returns the databases in the file, or none if it does not exist
+func (s *Store) read() ([]storedDatabase, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.Path, err)
+	}
+	var file storeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s is corrupt: %w", s.Path, err)
+	}
+	return file.Databases, nil
+}
+
+// write replaces the file. It is written to a temporary name first, so a
+// failed write never leaves a partial file.
+func (s *Store) write(databases []storedDatabase) error {
+	dir := filepath.Dir(s.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	data, err := yaml.Marshal(storeFile{Databases: databases})
+	if err != nil {
+		return fmt.Errorf("failed to encode the databases: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-"+FileName+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.Path, err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() //nolint:errcheck // the write error is reported
+		return fmt.Errorf("failed to write %s: %w", s.Path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.Path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package dbstore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+func newTestStore(t *testing.T) *Store {
+	dir := t.TempDir()
+	return &Store{Path: filepath.Join(dir, "data", FileName), SecretPath: filepath.Join(dir, "server.secret")}
+}
+
+func TestStore_AddLoad(t *testing.T) {
+	store := newTestStore(t)
+
+	databases, err := store.Load()
+	if err != nil || len(databases) != 0 {
+		t.Fatalf("Load() of a missing file = %v, %v", databases, err)
+	}
+
+	db := config.NamedDatabaseConfig{
+		Name: "analytics", Host: "db2", Port: 5432, Database: "warehouse",
+		User: "reporter", Password: "s3cret!", SSLMode: "require", Environment: "staging",
+	}
+	if err := store.Add(db); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(db); err == nil || !strings.Contains(err.Error(), "already stored") {
+		t.Errorf("expected a duplicate error, got %v", err)
+	}
+
+	data, err := os.ReadFile(store.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret!") {
+		t.Errorf("the password is stored in plain text:\n%s", data)
+	}
+	if _, err := os.Stat(store.SecretPath); err != nil {
+		t.Errorf("expected the server secret to be created: %v", err)
+	}
+
+	databases, err = store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(databases) != 1 || databases[0].Password != "s3cret!" || databases[0].Environment != "staging" {
+		t.Errorf("Load() = %+v", databases)
+	}
+
+	// Another secret cannot read the password
+	if err := os.Remove(store.SecretPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err == nil {
+		t.Error("expected an error without the server secret")
+	}
+}
+
+func TestStore_Merge(t *testing.T) {
+	store := newTestStore(t)
+	for _, db := range []config.NamedDatabaseConfig{
+		{Name: "main", Host: "stored", User: "app"},
+		{Name: "analytics", Host: "db2", User: "reporter"},
+	} {
+		if err := store.Add(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	merged, err := store.Merge([]config.NamedDatabaseConfig{{Name: "main", Host: "configured", User: "app"}})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(merged) != 2 || merged[0].Host != "configured" || merged[1].Name != "analytics" {
+		t.Errorf("Merge() = %+v", merged)
+	}
+}
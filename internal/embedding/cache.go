@@ -0,0 +1,315 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package embedding
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver
+)
+
+// DefaultCacheEntries is the default number of embeddings kept in memory
+const DefaultCacheEntries = 10000
+
+// cachePruneInterval is the number of persisted writes between trims of the
+// SQLite cache to its size limit
+const cachePruneInterval = 100
+
+// Cache stores embeddings keyed by provider, model and a hash of the text,
+// so repeated queries don't call the embedding API again. Recently used
+// embeddings are kept in memory; when a path is given they are also written
+// to SQLite so they survive restarts.
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // Front is the most recently used
+	entries    map[string]*list.Element
+
+	db     *sql.DB
+	writes int
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// cacheEntry is an embedding held in memory
+type cacheEntry struct {
+	key    string
+	vector []float64
+}
+
+// CacheStats holds the cache's counters
+type CacheStats struct {
+	Hits      uint64 // Lookups answered from the cache
+	Misses    uint64 // Lookups that called the provider
+	Evictions uint64 // Embeddings dropped from memory to stay within the limit
+	Entries   int    // Embeddings currently in memory
+	Persisted bool   // Whether embeddings are also stored in SQLite
+}
+
+// NewCache creates a cache holding up to maxEntries embeddings in memory
+// (DefaultCacheEntries if not positive). If path is not empty, embeddings
+// are also stored in the SQLite database at path, which is limited to the
+// same number of entries.
+func NewCache(maxEntries int, path string) (*Cache, error) {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheEntries
+	}
+	c := &Cache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedding cache: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS embedding_cache (
+			key TEXT PRIMARY KEY,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			embedding BLOB NOT NULL,
+			last_used_at INTEGER NOT NULL
+		)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create embedding cache table: %w", err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_embedding_cache_last_used
+		ON embedding_cache(last_used_at)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create embedding cache index: %w", err)
+	}
+	c.db = db
+	return c, nil
+}
+
+// CacheKey returns the cache key for text embedded by a provider's model
+func CacheKey(provider, model, text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return provider + "/" + model + "/" + hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached embedding for key, checking SQLite when the
+// embedding is not in memory, and counts the lookup as a hit or miss
+func (c *Cache) Get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		vector := elem.Value.(*cacheEntry).vector
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return vector, true
+	}
+	c.mu.Unlock()
+
+	if vector, ok := c.load(key); ok {
+		c.add(key, vector)
+		c.hits.Add(1)
+		return vector, true
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// Put stores an embedding in memory and, if enabled, in SQLite
+func (c *Cache) Put(provider, model, key string, vector []float64) {
+	c.add(key, vector)
+	c.store(provider, model, key, vector)
+}
+
+// Stats returns the cache's counters
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+		Persisted: c.db != nil,
+	}
+}
+
+// Close closes the SQLite database, if any
+func (c *Cache) Close() error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// add puts an embedding at the front of the in-memory list, evicting the
+// least recently used embeddings beyond the limit
+func (c *Cache) add(key string, vector []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).vector = vector
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, vector: vector})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.evictions.Add(1)
+	}
+}
+
+// load reads an embedding from SQLite and marks it as used
+func (c *Cache) load(key string) ([]float64, bool) {
+	if c.db == nil {
+		return nil, false
+	}
+	var blob []byte
+	if err := c.db.QueryRow("SELECT embedding FROM embedding_cache WHERE key = ?", key).Scan(&blob); err != nil {
+		if err != sql.ErrNoRows {
+			globalLogger.Info("Embedding cache read failed: %v", err)
+		}
+		return nil, false
+	}
+	vector, ok := decodeVector(blob)
+	if !ok {
+		return nil, false
+	}
+	if _, err := c.db.Exec("UPDATE embedding_cache SET last_used_at = ? WHERE key = ?",
+		time.Now().Unix(), key); err != nil {
+		globalLogger.Info("Embedding cache update failed: %v", err)
+	}
+	return vector, true
+}
+
+// store writes an embedding to SQLite, periodically removing the least
+// recently used embeddings beyond the limit. Failures only cost a future
+// cache miss, so they are logged rather than returned.
+func (c *Cache) store(provider, model, key string, vector []float64) {
+	if c.db == nil {
+		return
+	}
+	if _, err := c.db.Exec(`
+		INSERT INTO embedding_cache (key, provider, model, embedding, last_used_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET embedding = excluded.embedding, last_used_at = excluded.last_used_at`,
+		key, provider, model, encodeVector(vector), time.Now().Unix()); err != nil {
+		globalLogger.Info("Embedding cache write failed: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	c.writes++
+	prune := c.writes%cachePruneInterval == 0
+	c.mu.Unlock()
+	if prune {
+		if _, err := c.db.Exec(`
+			DELETE FROM embedding_cache WHERE key NOT IN (
+				SELECT key FROM embedding_cache ORDER BY last_used_at DESC LIMIT ?
+			)`, c.maxEntries); err != nil {
+			globalLogger.Info("Embedding cache prune failed: %v", err)
+		}
+	}
+}
+
+// encodeVector stores an embedding as little-endian float64 values
+func encodeVector(vector []float64) []byte {
+	buf := make([]byte, 8*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint64(buf[8*i:], math.Float64bits(v))
+	}
+	return buf
+}
+
+// decodeVector reverses encodeVector
+func decodeVector(buf []byte) ([]float64, bool) {
+	if len(buf) == 0 || len(buf)%8 != 0 {
+		return nil, false
+	}
+	vector := make([]float64, len(buf)/8)
+	for i := range vector {
+		vector[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*i:]))
+	}
+	return vector, true
+}
+
+// cachedProvider answers Embed from a cache, calling the wrapped provider
+// only on a miss
+type cachedProvider struct {
+	Provider
+	cache *Cache
+}
+
+// WithCache wraps a provider so its embeddings are cached; a nil cache
+// returns the provider unchanged
+func WithCache(p Provider, cache *Cache) Provider {
+	if cache == nil {
+		return p
+	}
+	return &cachedProvider{Provider: p, cache: cache}
+}
+
+// Embed returns the cached embedding for text, or generates and caches it
+func (p *cachedProvider) Embed(ctx context.Context, text string) ([]float64, error) {
+	key := CacheKey(p.ProviderName(), p.ModelName(), text)
+	if vector, ok := p.cache.Get(key); ok {
+		globalLogger.Debug("Embedding cache hit: provider=%s, model=%s", p.ProviderName(), p.ModelName())
+		return vector, nil
+	}
+
+	vector, err := p.Provider.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	if len(vector) > 0 {
+		p.cache.Put(p.ProviderName(), p.ModelName(), key, vector)
+	}
+	return vector, nil
+}
+
+// sharedCache is the cache applied by NewProvider, set with SetCache
+var sharedCache atomic.Pointer[Cache]
+
+// SetCache sets the cache used by providers created with NewProvider; nil
+// disables caching
+func SetCache(cache *Cache) {
+	sharedCache.Store(cache)
+}
+
+// SharedCache returns the cache set with SetCache, or nil
+func SharedCache() *Cache {
+	return sharedCache.Load()
+}
@@ -0,0 +1,123 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package embedding
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// countingProvider returns a fixed embedding and counts its calls
+type countingProvider struct {
+	model string
+	calls int
+}
+
+func (p *countingProvider) Embed(ctx context.Context, text string) ([]float64, error) {
+	p.calls++
+	return []float64{float64(len(text)), 0.5, -1}, nil
+}
+
+func (p *countingProvider) Dimensions() int      { return 3 }
+func (p *countingProvider) ModelName() string    { return p.model }
+func (p *countingProvider) ProviderName() string { return "test" }
+
+func TestCachedProvider(t *testing.T) {
+	cache, err := NewCache(10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &countingProvider{model: "model-a"}
+	provider := WithCache(inner, cache)
+
+	for i := 0; i < 3; i++ {
+		vector, err := provider.Embed(context.Background(), "hello")
+		if err != nil || len(vector) != 3 || vector[0] != 5 {
+			t.Fatalf("unexpected embedding %v, %v", vector, err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected one provider call, got %d", inner.calls)
+	}
+
+	// The same text from another model is a different entry
+	other := WithCache(&countingProvider{model: "model-b"}, cache)
+	if _, err := other.Embed(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if WithCache(inner, nil) != Provider(inner) {
+		t.Error("a nil cache should leave the provider unchanged")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	cache, err := NewCache(2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put("p", "m", "a", []float64{1})
+	cache.Put("p", "m", "b", []float64{2})
+	cache.Get("a") // b is now the least recently used
+	cache.Put("p", "m", "c", []float64{3})
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("expected a to be kept")
+	}
+	if stats := cache.Stats(); stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "embedding_cache.db")
+	key := CacheKey("test", "model-a", "hello")
+
+	cache, err := NewCache(10, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put("test", "model-a", key, []float64{0.25, -3.5})
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new cache reads the embedding from disk
+	cache, err = NewCache(10, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	vector, ok := cache.Get(key)
+	if !ok || len(vector) != 2 || vector[0] != 0.25 || vector[1] != -3.5 {
+		t.Fatalf("expected the persisted embedding, got %v, %v", vector, ok)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Entries != 1 || !stats.Persisted {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	if CacheKey("voyage", "m", "text") == CacheKey("openai", "m", "text") {
+		t.Error("keys should include the provider")
+	}
+	if CacheKey("voyage", "m", "text") != CacheKey("voyage", "m", "text") {
+		t.Error("keys should be stable")
+	}
+}
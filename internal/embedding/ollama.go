@@ -19,6 +19,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"pgedge-postgres-mcp/internal/netproxy"
 )
 
 const (
@@ -78,9 +80,7 @@ func NewOllamaProvider(baseURL, model string) (*OllamaProvider, error) {
 	return &OllamaProvider{
 		baseURL: baseURL,
 		model:   model,
-		client: &http.Client{
-			Timeout: OllamaHTTPTimeout,
-		},
+		client:  netproxy.NewClient(netproxy.ProviderOllama, OllamaHTTPTimeout),
 	}, nil
 }
 
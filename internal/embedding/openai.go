@@ -18,6 +18,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"pgedge-postgres-mcp/internal/netproxy"
 )
 
 const (
@@ -92,9 +94,7 @@ func NewOpenAIProvider(apiKey, model string) (*OpenAIProvider, error) {
 		apiKey:  apiKey,
 		model:   model,
 		baseURL: "https://api.openai.com/v1",
-		client: &http.Client{
-			Timeout: OpenAIHTTPTimeout,
-		},
+		client:  netproxy.NewClient(netproxy.ProviderOpenAI, OpenAIHTTPTimeout),
 	}, nil
 }
 
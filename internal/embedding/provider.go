@@ -46,7 +46,17 @@ type Config struct {
 }
 
 // NewProvider creates a new embedding provider based on configuration
+// Its embeddings are cached when a cache has been set with SetCache
 func NewProvider(cfg Config) (Provider, error) {
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return WithCache(provider, SharedCache()), nil
+}
+
+// newProvider creates the provider named in the configuration
+func newProvider(cfg Config) (Provider, error) {
 	switch cfg.Provider {
 	case "voyage":
 		if cfg.VoyageAPIKey == "" {
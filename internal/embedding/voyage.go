@@ -18,6 +18,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"pgedge-postgres-mcp/internal/netproxy"
 )
 
 const (
@@ -90,9 +92,7 @@ func NewVoyageProvider(apiKey, model string) (*VoyageProvider, error) {
 		apiKey:  apiKey,
 		model:   model,
 		baseURL: "https://api.voyageai.com/v1/embeddings",
-		client: &http.Client{
-			Timeout: VoyageHTTPTimeout,
-		},
+		client:  netproxy.NewClient(netproxy.ProviderVoyage, VoyageHTTPTimeout),
 	}, nil
 }
 
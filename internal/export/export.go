@@ -0,0 +1,257 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package export writes query results to CSV, JSON Lines and Parquet files
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat validates a format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(name))); f {
+	case FormatCSV, FormatJSONL, FormatParquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q (use csv, jsonl or parquet)", name)
+}
+
+// ColumnType is how a column's values are represented in typed formats
+// (JSON Lines and Parquet); CSV always writes PostgreSQL's text output
+type ColumnType int
+
+const (
+	TypeText ColumnType = iota
+	TypeBool
+	TypeInt
+	TypeFloat
+	TypeJSON
+)
+
+// PostgreSQL type OIDs with a typed representation
+const (
+	oidBool   = 16
+	oidInt8   = 20
+	oidInt2   = 21
+	oidInt4   = 23
+	oidOID    = 26
+	oidJSON   = 114
+	oidFloat4 = 700
+	oidFloat8 = 701
+	oidJSONB  = 3802
+)
+
+// TypeForOID returns the column type for a PostgreSQL type. numeric is
+// exported as text so no precision is lost.
+func TypeForOID(oid uint32) ColumnType {
+	switch oid {
+	case oidBool:
+		return TypeBool
+	case oidInt2, oidInt4, oidInt8, oidOID:
+		return TypeInt
+	case oidFloat4, oidFloat8:
+		return TypeFloat
+	case oidJSON, oidJSONB:
+		return TypeJSON
+	}
+	return TypeText
+}
+
+// Column describes an exported column
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Writer writes rows to an export file. Values are PostgreSQL text output
+// as strings, or nil for NULL.
+type Writer interface {
+	WriteRow(values []interface{}) error
+	// Close writes any buffered rows and the file trailer; it does not
+	// close the underlying writer
+	Close() error
+}
+
+// NewWriter creates a writer for format that writes to w
+func NewWriter(format Format, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatJSONL:
+		return newJSONLWriter(w, columns), nil
+	case FormatParquet:
+		return newParquetWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// UniqueNames makes column names unique by suffixing repeated names with
+// _2, _3 and so on, since JSON keys and Parquet fields must be unique
+func UniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, len(names))
+	for i, name := range names {
+		candidate := name
+		for n := 2; seen[candidate]; n++ {
+			candidate = fmt.Sprintf("%s_%d", name, n)
+		}
+		seen[candidate] = true
+		unique[i] = candidate
+	}
+	return unique
+}
+
+// csvWriter writes a header row followed by one record per row; NULL is an
+// empty field, as in COPY ... CSV
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(columns))}
+	for i, col := range columns {
+		cw.record[i] = col.Name
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) WriteRow(values []interface{}) error {
+	for i, v := range values {
+		cw.record[i] = textValue(v)
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonlWriter writes one JSON object per line, keeping the column order
+type jsonlWriter struct {
+	w       *bufio.Writer
+	columns []Column
+	keys    []string // Encoded keys including the colon
+	line    []byte
+}
+
+func newJSONLWriter(w io.Writer, columns []Column) *jsonlWriter {
+	jw := &jsonlWriter{w: bufio.NewWriter(w), columns: columns, keys: make([]string, len(columns))}
+	for i, col := range columns {
+		key, _ := json.Marshal(col.Name) //nolint:errcheck // strings always marshal
+		jw.keys[i] = string(key) + ":"
+	}
+	return jw
+}
+
+func (jw *jsonlWriter) WriteRow(values []interface{}) error {
+	line := append(jw.line[:0], '{')
+	for i, v := range values {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		line = append(line, jw.keys[i]...)
+		encoded, err := jsonValue(jw.columns[i], v)
+		if err != nil {
+			return err
+		}
+		line = append(line, encoded...)
+	}
+	line = append(line, '}', '\n')
+	jw.line = line
+	_, err := jw.w.Write(line)
+	return err
+}
+
+func (jw *jsonlWriter) Close() error {
+	return jw.w.Flush()
+}
+
+// jsonValue encodes a value as JSON according to its column type
+func jsonValue(col Column, v interface{}) ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+	text := textValue(v)
+
+	switch col.Type {
+	case TypeBool:
+		b, err := parseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		return strconv.AppendBool(nil, b), nil
+	case TypeInt:
+		if _, err := strconv.ParseInt(text, 10, 64); err != nil {
+			return nil, fmt.Errorf("column %s: invalid integer %q", col.Name, text)
+		}
+		return []byte(text), nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: invalid number %q", col.Name, text)
+		}
+		// JSON has no NaN or Infinity; keep PostgreSQL's spelling as a string
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			break
+		}
+		return []byte(text), nil
+	case TypeJSON:
+		if json.Valid([]byte(text)) {
+			return []byte(text), nil
+		}
+	}
+	return json.Marshal(text)
+}
+
+// textValue returns the text of a non-NULL value
+func textValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	}
+	return fmt.Sprint(v)
+}
+
+// parseBool parses PostgreSQL's boolean output
+func parseBool(text string) (bool, error) {
+	switch text {
+	case "t", "true":
+		return true, nil
+	case "f", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", text)
+}
@@ -0,0 +1,129 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"csv", "JSONL", " parquet "} {
+		if _, err := ParseFormat(name); err != nil {
+			t.Errorf("ParseFormat(%q) error = %v", name, err)
+		}
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	got := UniqueNames([]string{"id", "name", "id", "id_2", "id"})
+	want := []string{"id", "name", "id_2", "id_2_2", "id_3"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("UniqueNames()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// writeAll writes rows with a new writer and returns the output
+func writeAll(t *testing.T, format Format, columns []Column, rows [][]interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, columns)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	columns := []Column{{Name: "id", Type: TypeInt}, {Name: "note", Type: TypeText}}
+	out := writeAll(t, FormatCSV, columns, [][]interface{}{
+		{"1", "plain"},
+		{"2", "has, comma and \"quotes\""},
+		{"3", nil},
+	})
+
+	want := "id,note\n1,plain\n2,\"has, comma and \"\"quotes\"\"\"\n3,\n"
+	if string(out) != want {
+		t.Errorf("CSV output = %q, want %q", out, want)
+	}
+}
+
+func TestJSONLWriter(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: TypeInt},
+		{Name: "active", Type: TypeBool},
+		{Name: "score", Type: TypeFloat},
+		{Name: "doc", Type: TypeJSON},
+		{Name: "amount", Type: TypeText},
+	}
+	out := writeAll(t, FormatJSONL, columns, [][]interface{}{
+		{"1", "t", "1.5", `{"a": [1, 2]}`, "12.50"},
+		{"2", "f", "NaN", nil, nil},
+	})
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	want := []string{
+		`{"id":1,"active":true,"score":1.5,"doc":{"a": [1, 2]},"amount":"12.50"}`,
+		`{"id":2,"active":false,"score":"NaN","doc":null,"amount":null}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Got %d lines, want %d: %q", len(lines), len(want), out)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("Line %d = %s, want %s", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestJSONLWriterRejectsInvalidInteger(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatJSONL, &buf, []Column{{Name: "id", Type: TypeInt}})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.WriteRow([]interface{}{"****"}); err == nil {
+		t.Error("Expected an error for a non-integer value in an integer column")
+	}
+}
+
+func TestTypeForOID(t *testing.T) {
+	tests := []struct {
+		oid  uint32
+		want ColumnType
+	}{
+		{oidBool, TypeBool},
+		{oidInt4, TypeInt},
+		{oidFloat8, TypeFloat},
+		{oidJSONB, TypeJSON},
+		{1700, TypeText}, // numeric keeps its precision as text
+		{25, TypeText},
+	}
+	for _, tt := range tests {
+		if got := TypeForOID(tt.oid); got != tt.want {
+			t.Errorf("TypeForOID(%d) = %v, want %v", tt.oid, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// The Parquet writer produces files with one data page per column chunk,
+// PLAIN encoding and no compression. Every column is OPTIONAL so NULLs are
+// preserved. This is the simplest layout all Parquet readers accept, and
+// avoids a dependency for an occasional export.
+
+const (
+	parquetMagic = "PAR1"
+
+	// Rows buffered before a row group is written
+	parquetRowGroupRows = 10000
+	// Buffered value bytes that also end a row group
+	parquetRowGroupBytes = 16 << 20
+
+	parquetCreatedBy = "pgedge-postgres-mcp"
+)
+
+// Parquet physical types, encodings and other enums from parquet.thrift
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional = 1
+
+	parquetConvertedUTF8 = 0
+	parquetConvertedJSON = 19
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+// parquetColumn buffers the values of one column for the current row group
+type parquetColumn struct {
+	Column
+	physical int32
+	defined  []bool       // Definition level of each row: false for NULL
+	bools    []bool       // Non-NULL values of a boolean column
+	plain    bytes.Buffer // PLAIN encoded non-NULL values of other columns
+}
+
+// parquetChunk is a written column chunk, for the footer
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+	size   int64
+}
+
+type parquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64 // Rows in the current row group
+	totalRows int64
+	groups    []parquetRowGroup
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	pw := &parquetWriter{w: w}
+	for _, col := range columns {
+		pc := &parquetColumn{Column: col}
+		switch col.Type {
+		case TypeBool:
+			pc.physical = parquetBoolean
+		case TypeInt:
+			pc.physical = parquetInt64
+		case TypeFloat:
+			pc.physical = parquetDouble
+		default:
+			pc.physical = parquetByteArray
+		}
+		pw.columns = append(pw.columns, pc)
+	}
+	if err := pw.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *parquetWriter) write(p []byte) error {
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	return err
+}
+
+func (pw *parquetWriter) WriteRow(values []interface{}) error {
+	buffered := 0
+	for i, v := range values {
+		col := pw.columns[i]
+		col.defined = append(col.defined, v != nil)
+		if v == nil {
+			continue
+		}
+		text := textValue(v)
+
+		switch col.physical {
+		case parquetBoolean:
+			b, err := parseBool(text)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", col.Name, err)
+			}
+			col.bools = append(col.bools, b)
+		case parquetInt64:
+			n, err := strconv.ParseInt(text, 10, 64)
+			if err != nil {
+				return fmt.Errorf("column %s: invalid integer %q", col.Name, text)
+			}
+			var buf [8]byte
+			binary.LittleEndian.PutUint64(buf[:], uint64(n))
+			col.plain.Write(buf[:])
+		case parquetDouble:
+			f, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return fmt.Errorf("column %s: invalid number %q", col.Name, text)
+			}
+			var buf [8]byte
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+			col.plain.Write(buf[:])
+		default:
+			var buf [4]byte
+			binary.LittleEndian.PutUint32(buf[:], uint32(len(text)))
+			col.plain.Write(buf[:])
+			col.plain.WriteString(text)
+		}
+		buffered += col.plain.Len()
+	}
+
+	pw.rows++
+	if pw.rows >= parquetRowGroupRows || buffered >= parquetRowGroupBytes {
+		return pw.flushRowGroup()
+	}
+	return nil
+}
+
+// flushRowGroup writes the buffered rows as a row group
+func (pw *parquetWriter) flushRowGroup() error {
+	if pw.rows == 0 {
+		return nil
+	}
+
+	group := parquetRowGroup{rows: pw.rows}
+	for _, col := range pw.columns {
+		data := col.pageData()
+
+		var header thriftWriter
+		header.beginStruct()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.structField(5)
+		header.i32(1, int32(pw.rows))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunk := parquetChunk{
+			offset: pw.offset,
+			size:   int64(header.buf.Len() + len(data)),
+			values: pw.rows,
+		}
+		if err := pw.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := pw.write(data); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+
+		col.defined = col.defined[:0]
+		col.bools = col.bools[:0]
+		col.plain.Reset()
+	}
+
+	pw.groups = append(pw.groups, group)
+	pw.totalRows += pw.rows
+	pw.rows = 0
+	return nil
+}
+
+// pageData encodes the definition levels and values of a data page. The
+// levels use the RLE/bit-packed hybrid encoding with a bit width of 1,
+// written as a single bit-packed run.
+func (col *parquetColumn) pageData() []byte {
+	levels := packBits(col.defined)
+	var data bytes.Buffer
+	var run bytes.Buffer
+	writeUvarint(&run, uint64(len(levels))<<1|1)
+	run.Write(levels)
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(run.Len()))
+	data.Write(length[:])
+	data.Write(run.Bytes())
+
+	if col.physical == parquetBoolean {
+		data.Write(packBits(col.bools))
+	} else {
+		data.Write(col.plain.Bytes())
+	}
+	return data.Bytes()
+}
+
+// packBits packs booleans eight to a byte, least significant bit first
+func packBits(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+func (pw *parquetWriter) Close() error {
+	if err := pw.flushRowGroup(); err != nil {
+		return err
+	}
+
+	var meta thriftWriter
+	meta.beginStruct()
+	meta.i32(1, 1) // version
+
+	meta.listField(2, thriftStruct, len(pw.columns)+1)
+	meta.beginStruct()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(pw.columns)))
+	meta.endStruct()
+	for _, col := range pw.columns {
+		meta.beginStruct()
+		meta.i32(1, col.physical)
+		meta.i32(3, parquetOptional)
+		meta.binary(4, col.Name)
+		switch {
+		case col.Type == TypeJSON:
+			meta.i32(6, parquetConvertedJSON)
+		case col.physical == parquetByteArray:
+			meta.i32(6, parquetConvertedUTF8)
+		}
+		meta.endStruct()
+	}
+
+	meta.i64(3, pw.totalRows)
+
+	meta.listField(4, thriftStruct, len(pw.groups))
+	for _, group := range pw.groups {
+		meta.beginStruct()
+		meta.listField(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			col := pw.columns[i]
+			meta.beginStruct()
+			meta.i64(2, chunk.offset)
+			meta.structField(3)
+			meta.i32(1, col.physical)
+			meta.listField(2, thriftI32, 2)
+			meta.listI32(parquetEncodingPlain)
+			meta.listI32(parquetEncodingRLE)
+			meta.listField(3, thriftBinary, 1)
+			meta.listBinary(col.Name)
+			meta.i32(4, parquetUncompressed)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, group.size)
+		meta.i64(3, group.rows)
+		meta.endStruct()
+	}
+
+	meta.binary(6, parquetCreatedBy)
+	meta.endStruct()
+
+	if err := pw.write(meta.buf.Bytes()); err != nil {
+		return err
+	}
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(meta.buf.Len()))
+	if err := pw.write(length[:]); err != nil {
+		return err
+	}
+	return pw.write([]byte(parquetMagic))
+}
+
+// Thrift compact protocol types used by the Parquet metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol. Field ids
+// are delta encoded against the previous field of the enclosing struct.
+type thriftWriter struct {
+	buf    bytes.Buffer
+	lastID []int16
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.lastID = append(t.lastID, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0) // stop
+	t.lastID = t.lastID[:len(t.lastID)-1]
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.lastID[len(t.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		writeUvarint(&t.buf, zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	writeUvarint(&t.buf, zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	writeUvarint(&t.buf, zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.listBinary(s)
+}
+
+// structField starts a nested struct field; close it with endStruct
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginStruct()
+}
+
+// listField starts a list field; the elements follow without headers, and
+// struct elements are written with beginStruct and endStruct
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		writeUvarint(&t.buf, uint64(size))
+	}
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	writeUvarint(&t.buf, zigzag(int64(v)))
+}
+
+func (t *thriftWriter) listBinary(s string) {
+	writeUvarint(&t.buf, uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+}
@@ -0,0 +1,192 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// thriftReader decodes the subset of the Thrift compact protocol written by
+// thriftWriter. Structs decode to maps keyed by field id.
+type thriftReader struct {
+	buf *bytes.Reader
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, err := binary.ReadUvarint(r.buf)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		v := r.uvarint()
+		return int64(v>>1) ^ -int64(v&1)
+	case thriftBinary:
+		b := make([]byte, r.uvarint())
+		if _, err := r.buf.Read(b); err != nil && len(b) > 0 {
+			panic(err)
+		}
+		return string(b)
+	case thriftList:
+		header, _ := r.buf.ReadByte() //nolint:errcheck // checked by the decoded values
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.structValue()
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d", typ))
+}
+
+func (r *thriftReader) structValue() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		header, err := r.buf.ReadByte()
+		if err != nil {
+			panic(err)
+		}
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			v := r.uvarint()
+			id = int16(int64(v>>1) ^ -int64(v&1))
+		}
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: TypeInt},
+		{Name: "active", Type: TypeBool},
+		{Name: "score", Type: TypeFloat},
+		{Name: "name", Type: TypeText},
+		{Name: "doc", Type: TypeJSON},
+	}
+	out := writeAll(t, FormatParquet, columns, [][]interface{}{
+		{"1", "t", "2.5", "alice", `{"a":1}`},
+		{"2", nil, nil, nil, nil},
+		{"3", "f", "-1", "bob", `[]`},
+	})
+
+	if string(out[:4]) != parquetMagic || string(out[len(out)-4:]) != parquetMagic {
+		t.Fatal("File must start and end with PAR1")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(out[len(out)-8:]))
+	footer := out[len(out)-8-footerLen : len(out)-8]
+	meta := (&thriftReader{buf: bytes.NewReader(footer)}).structValue()
+
+	if meta[3] != int64(3) {
+		t.Errorf("num_rows = %v, want 3", meta[3])
+	}
+
+	schema := meta[2].([]interface{})
+	if len(schema) != len(columns)+1 {
+		t.Fatalf("Got %d schema elements, want %d", len(schema), len(columns)+1)
+	}
+	wantTypes := []int64{parquetInt64, parquetBoolean, parquetDouble, parquetByteArray, parquetByteArray}
+	for i, col := range columns {
+		element := schema[i+1].(map[int16]interface{})
+		if element[4] != col.Name {
+			t.Errorf("Schema element %d name = %v, want %s", i, element[4], col.Name)
+		}
+		if element[1] != wantTypes[i] {
+			t.Errorf("Column %s type = %v, want %d", col.Name, element[1], wantTypes[i])
+		}
+	}
+	if schema[5].(map[int16]interface{})[6] != int64(parquetConvertedJSON) {
+		t.Error("JSON column should have the JSON converted type")
+	}
+
+	groups := meta[4].([]interface{})
+	if len(groups) != 1 {
+		t.Fatalf("Got %d row groups, want 1", len(groups))
+	}
+	chunks := groups[0].(map[int16]interface{})[1].([]interface{})
+
+	// Decode the first column's page: definition levels then INT64 values
+	chunkMeta := chunks[0].(map[int16]interface{})[3].(map[int16]interface{})
+	offset := chunkMeta[9].(int64)
+	page := bytes.NewReader(out[offset:])
+	header := (&thriftReader{buf: page}).structValue()
+	data := make([]byte, header[3].(int64))
+	if _, err := page.Read(data); err != nil {
+		t.Fatalf("Failed to read page: %v", err)
+	}
+
+	levelsLen := binary.LittleEndian.Uint32(data)
+	levels := data[4 : 4+levelsLen]
+	if levels[0] != 1<<1|1 || levels[1] != 0b111 {
+		t.Errorf("Definition levels = %v, want one bit-packed group with all rows defined", levels)
+	}
+	values := data[4+levelsLen:]
+	for i, want := range []int64{1, 2, 3} {
+		if got := int64(binary.LittleEndian.Uint64(values[i*8:])); got != want {
+			t.Errorf("Value %d = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestParquetColumnPageData(t *testing.T) {
+	col := &parquetColumn{Column: Column{Name: "score", Type: TypeFloat}, physical: parquetDouble}
+	col.defined = []bool{true, false, true}
+	for _, f := range []float64{1.5, -2} {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+		col.plain.Write(buf[:])
+	}
+
+	data := col.pageData()
+	if got := binary.LittleEndian.Uint32(data); got != 2 {
+		t.Fatalf("Levels length = %d, want 2", got)
+	}
+	if data[5] != 0b101 {
+		t.Errorf("Definition bits = %03b, want 101", data[5])
+	}
+	if len(data) != 4+2+16 {
+		t.Errorf("Page size = %d, want %d", len(data), 4+2+16)
+	}
+}
+
+func TestParquetWriterRowGroups(t *testing.T) {
+	rows := make([][]interface{}, parquetRowGroupRows+1)
+	for i := range rows {
+		rows[i] = []interface{}{fmt.Sprint(i)}
+	}
+	out := writeAll(t, FormatParquet, []Column{{Name: "n", Type: TypeInt}}, rows)
+
+	footerLen := int(binary.LittleEndian.Uint32(out[len(out)-8:]))
+	meta := (&thriftReader{buf: bytes.NewReader(out[len(out)-8-footerLen : len(out)-8])}).structValue()
+	if groups := meta[4].([]interface{}); len(groups) != 2 {
+		t.Errorf("Got %d row groups, want 2", len(groups))
+	}
+	if meta[3] != int64(len(rows)) {
+		t.Errorf("num_rows = %v, want %d", meta[3], len(rows))
+	}
+}
@@ -0,0 +1,197 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package export
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// DownloadPath is the HTTP path exports are served under
+const DownloadPath = "/api/exports/"
+
+// ErrTooLarge is returned when an export exceeds the size limit
+var ErrTooLarge = errors.New("export exceeds the size limit")
+
+// Store manages the export directory
+type Store struct {
+	Dir       string
+	MaxBytes  int64         // Size limit for each file (0 = unlimited)
+	Retention time.Duration // Age after which files are deleted (0 = keep)
+}
+
+// NewStoreFromConfig returns the store in the exports directory under the
+// configured data directory
+func NewStoreFromConfig(cfg *config.Config) *Store {
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		execPath, err := os.Executable()
+		if err != nil {
+			execPath = "."
+		}
+		dataDir = config.GetDefaultDataDir(execPath)
+	}
+	return &Store{
+		Dir:       filepath.Join(dataDir, "exports"),
+		MaxBytes:  int64(cfg.Exports.MaxSizeMB) << 20,
+		Retention: time.Duration(cfg.Exports.RetentionHours) * time.Hour,
+	}
+}
+
+// File is an export being written
+type File struct {
+	Name string // File name, also used in the download URL
+	Path string
+
+	f     *os.File
+	size  int64
+	limit int64
+}
+
+// Write writes to the file, failing with ErrTooLarge once the limit is
+// exceeded
+func (f *File) Write(p []byte) (int, error) {
+	if f.limit > 0 && f.size+int64(len(p)) > f.limit {
+		return 0, ErrTooLarge
+	}
+	n, err := f.f.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Size returns the number of bytes written
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	return f.f.Close()
+}
+
+// Remove closes and deletes an incomplete export
+func (f *File) Remove() {
+	_ = f.f.Close()       //nolint:errcheck // the file is being discarded
+	_ = os.Remove(f.Path) //nolint:errcheck // best effort cleanup
+}
+
+// unsafeNameChars are replaced in file name prefixes
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// exportName matches the names of files created by the store
+var exportName = regexp.MustCompile(`^[A-Za-z0-9_-]+-[0-9]{8}-[0-9]{6}-[0-9a-f]{32}\.(csv|jsonl|parquet)$`)
+
+// Create creates a new export file, deleting expired exports first. prefix
+// is a hint for the file name; a timestamp and a random suffix make the name
+// unique and hard to guess.
+func (s *Store) Create(format Format, prefix string, now time.Time) (*File, error) {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	s.Cleanup(now)
+
+	prefix = strings.Trim(unsafeNameChars.ReplaceAllString(prefix, "_"), "_-")
+	if len(prefix) > 50 {
+		prefix = prefix[:50]
+	}
+	if prefix == "" {
+		prefix = "export"
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate export name: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s-%s.%s", prefix, now.UTC().Format("20060102-150405"), hex.EncodeToString(random), format)
+	path := filepath.Join(s.Dir, name)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+	return &File{Name: name, Path: path, f: f, limit: s.MaxBytes}, nil
+}
+
+// Cleanup deletes exports older than the retention period and returns the
+// number deleted
+func (s *Store) Cleanup(now time.Time) int {
+	if s.Retention <= 0 {
+		return 0
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return 0
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		if !exportName.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) <= s.Retention {
+			continue
+		}
+		if os.Remove(filepath.Join(s.Dir, entry.Name())) == nil {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// HandleDownload serves GET /api/exports/{name}
+func (s *Store) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, DownloadPath)
+	if !exportName.MatchString(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.Dir, name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || (s.Retention > 0 && time.Since(info.ModTime()) > s.Retention) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch filepath.Ext(name) {
+	case ".csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case ".jsonl":
+		w.Header().Set("Content-Type", "application/jsonl")
+	default:
+		w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
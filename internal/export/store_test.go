@@ -0,0 +1,167 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package export
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreCreate(t *testing.T) {
+	store := &Store{Dir: filepath.Join(t.TempDir(), "exports"), MaxBytes: 10}
+
+	f, err := store.Create(FormatCSV, "../orders 2025!", time.Now())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer f.Close()
+
+	if !strings.HasPrefix(f.Name, "orders_2025-") || !exportName.MatchString(f.Name) {
+		t.Errorf("Unexpected export name %q", f.Name)
+	}
+	if filepath.Dir(f.Path) != store.Dir {
+		t.Errorf("Export written outside the store: %s", f.Path)
+	}
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Export mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if _, err := f.Write([]byte("12345")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := f.Write([]byte("123456")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Write() error = %v, want ErrTooLarge", err)
+	}
+	if f.Size() != 5 {
+		t.Errorf("Size() = %d, want 5", f.Size())
+	}
+}
+
+func TestStoreCreateDefaultPrefix(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+	f, err := store.Create(FormatParquet, "", time.Now())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	f.Remove()
+
+	if !strings.HasPrefix(f.Name, "export-") || !strings.HasSuffix(f.Name, ".parquet") {
+		t.Errorf("Unexpected export name %q", f.Name)
+	}
+	if _, err := os.Stat(f.Path); !os.IsNotExist(err) {
+		t.Error("Remove() should delete the file")
+	}
+}
+
+func TestStoreCleanup(t *testing.T) {
+	store := &Store{Dir: t.TempDir(), Retention: time.Hour}
+	now := time.Now()
+
+	old, err := store.Create(FormatCSV, "old", now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	old.Close()
+	fresh, err := store.Create(FormatCSV, "fresh", now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	fresh.Close()
+
+	other := filepath.Join(store.Dir, "notes.txt")
+	if err := os.WriteFile(other, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	past := now.Add(-2 * time.Hour)
+	for _, path := range []string{old.Path, other} {
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if deleted := store.Cleanup(now); deleted != 1 {
+		t.Errorf("Cleanup() deleted %d files, want 1", deleted)
+	}
+	if _, err := os.Stat(old.Path); !os.IsNotExist(err) {
+		t.Error("Expired export should be deleted")
+	}
+	for _, path := range []string{fresh.Path, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s should be kept: %v", path, err)
+		}
+	}
+}
+
+func TestHandleDownload(t *testing.T) {
+	store := &Store{Dir: t.TempDir(), Retention: time.Hour}
+	f, err := store.Create(FormatCSV, "orders", time.Now())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := f.Write([]byte("id\n1\n")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"download", http.MethodGet, DownloadPath + f.Name, http.StatusOK},
+		{"head", http.MethodHead, DownloadPath + f.Name, http.StatusOK},
+		{"post", http.MethodPost, DownloadPath + f.Name, http.StatusMethodNotAllowed},
+		{"unknown", http.MethodGet, DownloadPath + "orders-20250101-000000-00000000000000000000000000000000.csv", http.StatusNotFound},
+		{"traversal", http.MethodGet, DownloadPath + "../secret.csv", http.StatusNotFound},
+		{"other file", http.MethodGet, DownloadPath + "notes.txt", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			store.HandleDownload(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("Status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && tt.method == http.MethodGet {
+				if rec.Body.String() != "id\n1\n" {
+					t.Errorf("Body = %q", rec.Body.String())
+				}
+				if !strings.Contains(rec.Header().Get("Content-Disposition"), f.Name) {
+					t.Errorf("Content-Disposition = %q", rec.Header().Get("Content-Disposition"))
+				}
+			}
+		})
+	}
+
+	// Expired exports are no longer served
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(f.Path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	store.HandleDownload(rec, httptest.NewRequest(http.MethodGet, DownloadPath+f.Name, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expired export status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,259 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package healthprobe runs operator-defined health probes: SQL queries run
+// periodically against a database that return a status of ok, warn or crit
+// and a message. The latest result of each probe is kept for the health
+// check, and state changes are reported as they happen.
+package healthprobe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/logging"
+)
+
+// Status is the state reported by a probe
+type Status string
+
+// Probe states
+const (
+	StatusOK    Status = "ok"
+	StatusWarn  Status = "warn"
+	StatusCrit  Status = "crit"
+	StatusError Status = "error" // The probe failed to run or returned an unknown status
+)
+
+const (
+	// DefaultInterval is the time between runs of a probe without an interval
+	DefaultInterval = time.Minute
+
+	// DefaultTimeout bounds a probe's query when no timeout is set
+	DefaultTimeout = 10 * time.Second
+)
+
+// ParseStatus converts the status returned by a probe's query; "warning"
+// and "critical" are accepted for warn and crit
+func ParseStatus(s string) (Status, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "ok":
+		return StatusOK, true
+	case "warn", "warning":
+		return StatusWarn, true
+	case "crit", "critical":
+		return StatusCrit, true
+	}
+	return "", false
+}
+
+// Probe is a query run periodically to check some condition
+type Probe struct {
+	Name     string        // Unique name of the probe
+	Database string        // Name of the database the query runs in
+	SQL      string        // Query returning one row: status and message
+	Interval time.Duration // Time between runs (0 = DefaultInterval)
+	Timeout  time.Duration // Statement timeout (0 = DefaultTimeout)
+	Pool     *pgxpool.Pool // Pool of the database
+}
+
+// Result is the latest outcome of a probe
+type Result struct {
+	Probe     string
+	Database  string
+	Status    Status
+	Message   string
+	CheckedAt time.Time // When the probe last ran
+	Since     time.Time // When the probe entered its current status
+}
+
+// Config configures a Runner
+type Config struct {
+	Probes []Probe
+
+	// OnChange is called when a probe reports a different status than the
+	// last time it ran; previous is nil for the first result
+	OnChange func(previous *Result, current Result)
+
+	// Check runs a probe's query (nil = run it in the probe's pool);
+	// replaceable for tests
+	Check func(ctx context.Context, p Probe) (Status, string, error)
+
+	// Now returns the current time (nil = time.Now); replaceable for tests
+	Now func() time.Time
+}
+
+// Runner runs probes on their intervals and keeps their latest results
+type Runner struct {
+	cfg Config
+
+	mu      sync.RWMutex
+	results map[string]Result
+}
+
+// New creates a runner; call Run to start the probes
+func New(cfg Config) *Runner {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Check == nil {
+		cfg.Check = check
+	}
+	return &Runner{cfg: cfg, results: make(map[string]Result)}
+}
+
+// shared is the runner whose results the tools report, set with SetShared
+var shared atomic.Pointer[Runner]
+
+// SetShared sets the runner whose results are included in health reports;
+// nil removes them
+func SetShared(r *Runner) {
+	shared.Store(r)
+}
+
+// Shared returns the runner set with SetShared, or nil
+func Shared() *Runner {
+	return shared.Load()
+}
+
+// Run runs each probe immediately and then on its interval until ctx is
+// cancelled
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, p := range r.cfg.Probes {
+		wg.Add(1)
+		go func(p Probe) {
+			defer wg.Done()
+			interval := p.Interval
+			if interval <= 0 {
+				interval = DefaultInterval
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				r.RunProbe(ctx, p)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+// RunProbe runs one probe and records its result
+func (r *Runner) RunProbe(ctx context.Context, p Probe) Result {
+	status, message, err := r.cfg.Check(ctx, p)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Shutting down; keep the last real result
+			return r.result(p.Name)
+		}
+		status, message = StatusError, err.Error()
+	}
+	logging.Debug("health_probe_checked", "probe", p.Name, "database", p.Database, "status", status)
+	return r.record(p, status, message)
+}
+
+// record stores a probe's result and reports a change of status
+func (r *Runner) record(p Probe, status Status, message string) Result {
+	now := r.cfg.Now()
+	current := Result{
+		Probe:     p.Name,
+		Database:  p.Database,
+		Status:    status,
+		Message:   message,
+		CheckedAt: now,
+		Since:     now,
+	}
+
+	r.mu.Lock()
+	previous, seen := r.results[p.Name]
+	if seen && previous.Status == status {
+		current.Since = previous.Since
+	}
+	r.results[p.Name] = current
+	r.mu.Unlock()
+
+	if r.cfg.OnChange != nil && (!seen || previous.Status != status) {
+		if seen {
+			r.cfg.OnChange(&previous, current)
+		} else {
+			r.cfg.OnChange(nil, current)
+		}
+	}
+	return current
+}
+
+// result returns the latest result of a probe
+func (r *Runner) result(name string) Result {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.results[name]
+}
+
+// Results returns the latest result of each probe that has run, sorted by
+// probe name
+func (r *Runner) Results() []Result {
+	r.mu.RLock()
+	results := make([]Result, 0, len(r.results))
+	for _, result := range r.results {
+		results = append(results, result)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Probe < results[j].Probe })
+	return results
+}
+
+// check runs a probe's query in a read-only transaction with the probe's
+// statement timeout
+func check(ctx context.Context, p Probe) (Status, string, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout+5*time.Second)
+	defer cancel()
+
+	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // Read-only, nothing to commit
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+		return "", "", fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+
+	var status string
+	var message *string
+	if err := tx.QueryRow(ctx, p.SQL).Scan(&status, &message); err != nil {
+		return "", "", fmt.Errorf("probe query failed: %w", err)
+	}
+	parsed, ok := ParseStatus(status)
+	if !ok {
+		return "", "", fmt.Errorf("probe returned unknown status %q (must be ok, warn or crit)", status)
+	}
+	if message == nil {
+		return parsed, "", nil
+	}
+	return parsed, *message, nil
+}
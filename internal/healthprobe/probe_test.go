@@ -0,0 +1,127 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package healthprobe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Status
+		ok   bool
+	}{
+		{"ok", StatusOK, true},
+		{" OK ", StatusOK, true},
+		{"warn", StatusWarn, true},
+		{"Warning", StatusWarn, true},
+		{"crit", StatusCrit, true},
+		{"CRITICAL", StatusCrit, true},
+		{"error", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseStatus(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseStatus(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	type outcome struct {
+		status  Status
+		message string
+		err     error
+	}
+	var next outcome
+	type change struct {
+		from, to Status
+	}
+	var changes []change
+
+	r := New(Config{
+		Check: func(ctx context.Context, p Probe) (Status, string, error) {
+			return next.status, next.message, next.err
+		},
+		OnChange: func(previous *Result, current Result) {
+			c := change{to: current.Status}
+			if previous != nil {
+				c.from = previous.Status
+			}
+			changes = append(changes, c)
+		},
+		Now: func() time.Time { return now },
+	})
+	probe := Probe{Name: "replication_lag", Database: "main"}
+
+	next = outcome{status: StatusOK, message: "lag 2s"}
+	r.RunProbe(context.Background(), probe)
+	start := now
+
+	now = now.Add(time.Minute)
+	next = outcome{status: StatusOK, message: "lag 3s"}
+	result := r.RunProbe(context.Background(), probe)
+	if result.Message != "lag 3s" || !result.Since.Equal(start) || !result.CheckedAt.Equal(now) {
+		t.Errorf("unexpected result for an unchanged status: %+v", result)
+	}
+
+	now = now.Add(time.Minute)
+	next = outcome{status: StatusCrit, message: "lag 600s"}
+	result = r.RunProbe(context.Background(), probe)
+	if !result.Since.Equal(now) {
+		t.Errorf("Since = %v, want %v after a status change", result.Since, now)
+	}
+
+	next = outcome{err: errors.New("connection refused")}
+	result = r.RunProbe(context.Background(), probe)
+	if result.Status != StatusError || result.Message != "connection refused" {
+		t.Errorf("unexpected result for a failed probe: %+v", result)
+	}
+
+	want := []change{{"", StatusOK}, {StatusOK, StatusCrit}, {StatusCrit, StatusError}}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %v, want %v", i, changes[i], want[i])
+		}
+	}
+
+	// A probe interrupted by shutdown keeps its last result
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	next = outcome{err: context.Canceled}
+	if result := r.RunProbe(ctx, probe); result.Status != StatusError || len(changes) != 3 {
+		t.Errorf("unexpected result after cancellation: %+v", result)
+	}
+}
+
+func TestResults(t *testing.T) {
+	r := New(Config{
+		Check: func(ctx context.Context, p Probe) (Status, string, error) {
+			return StatusWarn, p.Name, nil
+		},
+	})
+	for _, name := range []string{"b", "c", "a"} {
+		r.RunProbe(context.Background(), Probe{Name: name})
+	}
+	results := r.Results()
+	if len(results) != 3 || results[0].Probe != "a" || results[1].Probe != "b" || results[2].Probe != "c" {
+		t.Errorf("Results() = %+v, want probes a, b, c", results)
+	}
+}
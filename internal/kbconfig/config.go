@@ -14,24 +14,50 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"pgedge-postgres-mcp/internal/netproxy"
+)
+
+// Knowledgebase storage backends
+const (
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
 )
 
+// DefaultBatchSize is the default number of chunks per embedding request
+const DefaultBatchSize = 100
+
 // Config represents the kb-builder configuration
 type Config struct {
-	// Output database path
+	// Storage backend: "sqlite" (default) or "postgres"
+	Backend string `yaml:"backend"`
+
+	// Output database path (sqlite backend)
 	DatabasePath string `yaml:"database_path"`
 
+	// Connection string of a database with the pgvector extension, and the
+	// schema holding the knowledgebase (postgres backend)
+	ConnectionString string `yaml:"connection_string"`
+	Schema           string `yaml:"schema"`
+
 	// Directory for storing downloaded/processed documentation
 	DocSourcePath string `yaml:"doc_source_path"`
 
+	// Number of files converted and chunked at once (default: number of CPUs)
+	Concurrency int `yaml:"concurrency"`
+
 	// Documentation sources
 	Sources []DocumentSource `yaml:"sources"`
 
 	// Embedding provider configurations
 	Embeddings EmbeddingConfig `yaml:"embeddings"`
+
+	// Outbound proxy for embedding API calls and Git fetches
+	Proxy netproxy.Settings `yaml:"proxy"`
 }
 
 // DocumentSource represents a source of documentation
@@ -52,6 +78,9 @@ type DocumentSource struct {
 
 // EmbeddingConfig contains configuration for all embedding providers
 type EmbeddingConfig struct {
+	// Chunks sent to a provider in each embedding request (default: 100)
+	BatchSize int `yaml:"batch_size"`
+
 	OpenAI OpenAIConfig `yaml:"openai"`
 	Voyage VoyageConfig `yaml:"voyage"`
 	Ollama OllamaConfig `yaml:"ollama"`
@@ -116,6 +145,14 @@ func Load(configPath string) (*Config, error) {
 func applyDefaults(config *Config, configPath string) error {
 	configDir := filepath.Dir(configPath)
 
+	// Default backend
+	if config.Backend == "" {
+		config.Backend = BackendSQLite
+	}
+	if config.Backend == BackendPostgres && config.Schema == "" {
+		config.Schema = "pgedge_kb"
+	}
+
 	// Default database path
 	if config.DatabasePath == "" {
 		config.DatabasePath = filepath.Join(configDir, "pgedge-nla-kb.db")
@@ -126,6 +163,14 @@ func applyDefaults(config *Config, configPath string) error {
 		config.DocSourcePath = filepath.Join(configDir, "doc-source")
 	}
 
+	// Default pipeline settings
+	if config.Concurrency == 0 {
+		config.Concurrency = runtime.NumCPU()
+	}
+	if config.Embeddings.BatchSize == 0 {
+		config.Embeddings.BatchSize = DefaultBatchSize
+	}
+
 	// Default OpenAI settings
 	if config.Embeddings.OpenAI.Enabled {
 		if config.Embeddings.OpenAI.APIKeyFile == "" {
@@ -193,6 +238,27 @@ func validate(config *Config) error {
 		return fmt.Errorf("no documentation sources configured")
 	}
 
+	if err := netproxy.Validate(config.Proxy); err != nil {
+		return err
+	}
+
+	if config.Concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative")
+	}
+	if config.Embeddings.BatchSize < 0 {
+		return fmt.Errorf("embeddings.batch_size must not be negative")
+	}
+
+	switch config.Backend {
+	case "", BackendSQLite:
+	case BackendPostgres:
+		if config.ConnectionString == "" {
+			return fmt.Errorf("connection_string is required for the postgres backend")
+		}
+	default:
+		return fmt.Errorf("invalid backend %q (must be sqlite or postgres)", config.Backend)
+	}
+
 	for i, source := range config.Sources {
 		// Check that either Git or local path is specified
 		hasGit := source.GitURL != ""
@@ -159,6 +159,46 @@ func TestValidateConfig(t *testing.T) {
 			},
 			shouldError: true,
 		},
+		{
+			name: "postgres backend without connection string",
+			config: &Config{
+				Backend: BackendPostgres,
+				Sources: []DocumentSource{
+					{LocalPath: "/tmp/test", ProjectName: "Test"},
+				},
+				Embeddings: EmbeddingConfig{
+					OpenAI: OpenAIConfig{Enabled: true},
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "unknown backend",
+			config: &Config{
+				Backend: "duckdb",
+				Sources: []DocumentSource{
+					{LocalPath: "/tmp/test", ProjectName: "Test"},
+				},
+				Embeddings: EmbeddingConfig{
+					OpenAI: OpenAIConfig{Enabled: true},
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "postgres backend",
+			config: &Config{
+				Backend:          BackendPostgres,
+				ConnectionString: "postgres://kb@localhost/docs",
+				Sources: []DocumentSource{
+					{LocalPath: "/tmp/test", ProjectName: "Test"},
+				},
+				Embeddings: EmbeddingConfig{
+					OpenAI: OpenAIConfig{Enabled: true},
+				},
+			},
+			shouldError: false,
+		},
 		{
 			name: "missing project version is allowed",
 			config: &Config{
@@ -175,6 +215,32 @@ func TestValidateConfig(t *testing.T) {
 			},
 			shouldError: false,
 		},
+		{
+			name: "negative concurrency",
+			config: &Config{
+				Concurrency: -1,
+				Sources: []DocumentSource{
+					{LocalPath: "/tmp/test", ProjectName: "Test"},
+				},
+				Embeddings: EmbeddingConfig{
+					OpenAI: OpenAIConfig{Enabled: true},
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "negative batch size",
+			config: &Config{
+				Sources: []DocumentSource{
+					{LocalPath: "/tmp/test", ProjectName: "Test"},
+				},
+				Embeddings: EmbeddingConfig{
+					BatchSize: -1,
+					OpenAI:    OpenAIConfig{Enabled: true},
+				},
+			},
+			shouldError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -262,6 +328,18 @@ func TestApplyDefaults(t *testing.T) {
 		t.Error("DocSourcePath should have default")
 	}
 
+	if cfg.Backend != BackendSQLite {
+		t.Errorf("Backend should default to sqlite, got %q", cfg.Backend)
+	}
+
+	if cfg.Concurrency < 1 {
+		t.Errorf("Concurrency should default to the number of CPUs, got %d", cfg.Concurrency)
+	}
+
+	if cfg.Embeddings.BatchSize != DefaultBatchSize {
+		t.Errorf("BatchSize should default to %d, got %d", DefaultBatchSize, cfg.Embeddings.BatchSize)
+	}
+
 	if cfg.Embeddings.OpenAI.Model == "" {
 		t.Error("OpenAI model should have default")
 	}
@@ -52,6 +52,10 @@ func DetectDocumentType(filename string) kbtypes.DocumentType {
 		return kbtypes.TypeReStructuredText
 	case ".sgml", ".sgm", ".xml":
 		return kbtypes.TypeSGML
+	case ".pdf":
+		return kbtypes.TypePDF
+	case ".docx":
+		return kbtypes.TypeDOCX
 	default:
 		return kbtypes.TypeUnknown
 	}
@@ -69,6 +73,10 @@ func Convert(content []byte, docType kbtypes.DocumentType) (markdown string, tit
 		markdown, title, err = convertRST(content)
 	case kbtypes.TypeSGML:
 		markdown, title, err = convertSGML(content)
+	case kbtypes.TypePDF:
+		markdown, title, err = convertPDF(content)
+	case kbtypes.TypeDOCX:
+		markdown, title, err = convertDOCX(content)
 	default:
 		return "", "", ErrUnsupportedFormat
 	}
@@ -526,7 +534,7 @@ func IsSupported(filename string) bool {
 
 // GetSupportedExtensions returns a list of supported file extensions
 func GetSupportedExtensions() []string {
-	return []string{".html", ".htm", ".md", ".rst", ".sgml", ".sgm", ".xml"}
+	return []string{".html", ".htm", ".md", ".rst", ".sgml", ".sgm", ".xml", ".pdf", ".docx"}
 }
 
 // ReadAll reads all content from a reader
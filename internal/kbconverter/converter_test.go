@@ -29,6 +29,9 @@ func TestDetectDocumentType(t *testing.T) {
 		{"test.sgml", kbtypes.TypeSGML},
 		{"test.sgm", kbtypes.TypeSGML},
 		{"test.xml", kbtypes.TypeSGML},
+		{"test.pdf", kbtypes.TypePDF},
+		{"test.DOCX", kbtypes.TypeDOCX},
+		{"test.doc", kbtypes.TypeUnknown},
 		{"test.txt", kbtypes.TypeUnknown},
 		{"test", kbtypes.TypeUnknown},
 	}
@@ -256,8 +259,10 @@ func TestIsSupported(t *testing.T) {
 		{"test.md", true},
 		{"test.rst", true},
 		{"test.sgml", true},
+		{"test.pdf", true},
+		{"test.docx", true},
 		{"test.txt", false},
-		{"test.pdf", false},
+		{"test.doc", false},
 	}
 
 	for _, tt := range tests {
@@ -280,7 +285,7 @@ func TestConvertUnsupportedFormat(t *testing.T) {
 func TestGetSupportedExtensions(t *testing.T) {
 	extensions := GetSupportedExtensions()
 
-	expectedExtensions := []string{".html", ".htm", ".md", ".rst", ".sgml", ".sgm", ".xml"}
+	expectedExtensions := []string{".html", ".htm", ".md", ".rst", ".sgml", ".sgm", ".xml", ".pdf", ".docx"}
 
 	if len(extensions) != len(expectedExtensions) {
 		t.Errorf("Expected %d extensions, got %d", len(expectedExtensions), len(extensions))
@@ -0,0 +1,379 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package kbconverter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDOCXPartSize limits the size of an XML part read from a DOCX archive
+const maxDOCXPartSize = 64 << 20
+
+// headingStyleRe matches the names of Word's built-in heading styles
+var headingStyleRe = regexp.MustCompile(`(?i)^heading\s*([1-9])$`)
+
+// docxStyle is what the converter needs from a paragraph style
+type docxStyle struct {
+	title   bool
+	heading int // Heading level, or 0
+	code    bool
+}
+
+// convertDOCX converts a Word document to Markdown. Paragraphs with heading
+// styles become headings, list paragraphs become list items and tables
+// become Markdown tables; other formatting is dropped.
+func convertDOCX(content []byte) (string, string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open DOCX archive: %w", err)
+	}
+
+	document, err := readDOCXPart(archive, "word/document.xml")
+	if err != nil {
+		return "", "", err
+	}
+	if document == nil {
+		return "", "", fmt.Errorf("DOCX archive has no word/document.xml")
+	}
+
+	styles := map[string]docxStyle{}
+	if stylesXML, err := readDOCXPart(archive, "word/styles.xml"); err != nil {
+		return "", "", err
+	} else if stylesXML != nil {
+		styles = parseDOCXStyles(stylesXML)
+	}
+
+	title := ""
+	if coreXML, err := readDOCXPart(archive, "docProps/core.xml"); err != nil {
+		return "", "", err
+	} else if coreXML != nil {
+		title = parseDOCXTitle(coreXML)
+	}
+
+	body, bodyTitle, err := convertDOCXBody(document, styles)
+	if err != nil {
+		return "", "", err
+	}
+	if title == "" {
+		title = bodyTitle
+	}
+
+	markdown := body
+	if title != "" {
+		markdown = "# " + title + "\n\n" + body
+	}
+	return markdown, title, nil
+}
+
+// readDOCXPart reads a part of a DOCX archive, returning nil if it is missing
+func readDOCXPart(archive *zip.Reader, name string) ([]byte, error) {
+	for _, f := range archive.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxDOCXPartSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if len(data) > maxDOCXPartSize {
+			return nil, fmt.Errorf("%s is larger than %d MB", name, maxDOCXPartSize>>20)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// attr returns the value of an attribute by its local name
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// parseDOCXStyles maps paragraph style IDs to headings, titles and code.
+// Style IDs are localized, so headings are recognized by the style's name
+// or outline level.
+func parseDOCXStyles(data []byte) map[string]docxStyle {
+	styles := make(map[string]docxStyle)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var id string
+	var style docxStyle
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return styles
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "style":
+				id, style = "", docxStyle{}
+				if attr(el, "type") == "paragraph" {
+					id = attr(el, "styleId")
+				}
+			case "name":
+				name := attr(el, "val")
+				if m := headingStyleRe.FindStringSubmatch(name); m != nil {
+					style.heading, _ = strconv.Atoi(m[1])
+				}
+				lower := strings.ToLower(name)
+				style.title = lower == "title"
+				style.code = strings.Contains(lower, "code") || strings.Contains(lower, "source")
+			case "outlineLvl":
+				if style.heading == 0 {
+					if level, err := strconv.Atoi(attr(el, "val")); err == nil && level < 9 {
+						style.heading = level + 1
+					}
+				}
+			}
+		case xml.EndElement:
+			if el.Name.Local == "style" && id != "" {
+				styles[id] = style
+				id = ""
+			}
+		}
+	}
+}
+
+// parseDOCXTitle returns the title from the document properties
+func parseDOCXTitle(data []byte) string {
+	var core struct {
+		Title string `xml:"title"`
+	}
+	if err := xml.Unmarshal(data, &core); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(core.Title)
+}
+
+// docxParagraph is a paragraph being read
+type docxParagraph struct {
+	style string
+	list  bool
+	level int // List nesting level
+	text  strings.Builder
+}
+
+// docxConverter builds Markdown from the body of a document
+type docxConverter struct {
+	styles map[string]docxStyle
+	out    strings.Builder
+	title  string
+
+	// Tables may be nested; only the outermost is rendered as a table and
+	// nested tables become cell text
+	tableDepth int
+	inList     bool
+	rows       [][]string
+	row        []string
+	cell       []string
+}
+
+// convertDOCXBody converts word/document.xml, returning the Markdown and the
+// text of the first Title paragraph
+func convertDOCXBody(data []byte, styles map[string]docxStyle) (string, string, error) {
+	c := &docxConverter{styles: styles}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	// Text boxes put paragraphs inside paragraphs
+	var paras []*docxParagraph
+	inText := false
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse DOCX document: %w", err)
+		}
+
+		var para *docxParagraph
+		if len(paras) > 0 {
+			para = paras[len(paras)-1]
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "Fallback":
+				// Alternate content repeats the text of its preferred choice
+				if err := dec.Skip(); err != nil {
+					return "", "", fmt.Errorf("failed to parse DOCX document: %w", err)
+				}
+			case "p":
+				paras = append(paras, &docxParagraph{})
+			case "pStyle":
+				if para != nil {
+					para.style = attr(el, "val")
+				}
+			case "numPr":
+				if para != nil {
+					para.list = true
+				}
+			case "ilvl":
+				if para != nil {
+					para.level, _ = strconv.Atoi(attr(el, "val"))
+				}
+			case "t":
+				inText = true
+			case "tab":
+				if para != nil {
+					para.text.WriteString("\t")
+				}
+			case "br", "cr":
+				if para != nil {
+					para.text.WriteString("\n")
+				}
+			case "tbl":
+				c.tableDepth++
+				if c.tableDepth == 1 {
+					c.rows = nil
+				}
+			case "tr":
+				if c.tableDepth == 1 {
+					c.row = nil
+				}
+			case "tc":
+				if c.tableDepth == 1 {
+					c.cell = nil
+				}
+			}
+		case xml.CharData:
+			if inText && para != nil {
+				para.text.Write(el)
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if para != nil {
+					paras = paras[:len(paras)-1]
+					c.endParagraph(para)
+				}
+			case "tc":
+				if c.tableDepth == 1 {
+					c.row = append(c.row, strings.Join(c.cell, " "))
+				}
+			case "tr":
+				if c.tableDepth == 1 {
+					c.rows = append(c.rows, c.row)
+				}
+			case "tbl":
+				c.tableDepth--
+				if c.tableDepth == 0 {
+					c.writeTable()
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(c.out.String()), c.title, nil
+}
+
+// endParagraph writes a finished paragraph, or adds it to the current cell
+func (c *docxConverter) endParagraph(p *docxParagraph) {
+	text := strings.TrimSpace(p.text.String())
+	if c.tableDepth > 0 {
+		if text != "" {
+			c.cell = append(c.cell, strings.Join(strings.Fields(text), " "))
+		}
+		return
+	}
+	if text == "" {
+		return
+	}
+	if c.inList && !p.list {
+		ensureBlankLine(&c.out)
+	}
+	c.inList = p.list
+
+	style := c.styles[p.style]
+	if style.heading == 0 && !style.title {
+		// Documents without styles.xml still use the built-in style IDs
+		if m := headingStyleRe.FindStringSubmatch(p.style); m != nil {
+			style.heading, _ = strconv.Atoi(m[1])
+		}
+		style.title = strings.EqualFold(p.style, "Title")
+	}
+
+	switch {
+	case style.title:
+		if c.title == "" {
+			c.title = strings.Join(strings.Fields(text), " ")
+			return
+		}
+		c.out.WriteString("## " + text + "\n\n")
+	case style.heading > 0:
+		// The title is the only H1
+		c.out.WriteString(strings.Repeat("#", min(style.heading+1, 6)) + " " + strings.Join(strings.Fields(text), " ") + "\n\n")
+	case style.code:
+		c.out.WriteString("```\n" + text + "\n```\n\n")
+	case p.list:
+		c.inList = true
+		c.out.WriteString(strings.Repeat("  ", p.level) + "- " + strings.ReplaceAll(text, "\n", " ") + "\n")
+	default:
+		c.out.WriteString(text + "\n\n")
+	}
+}
+
+// writeTable writes the rows of a finished table; the first row is the header
+func (c *docxConverter) writeTable() {
+	columns := 0
+	for _, row := range c.rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return
+	}
+	c.inList = false
+	ensureBlankLine(&c.out)
+	for i, row := range c.rows {
+		cells := make([]string, columns)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.ReplaceAll(row[j], "|", `\|`)
+			}
+		}
+		c.out.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			c.out.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	c.out.WriteString("\n")
+	c.rows = nil
+}
+
+// ensureBlankLine ends the output with a blank line, unless it is empty
+func ensureBlankLine(sb *strings.Builder) {
+	s := sb.String()
+	switch {
+	case s == "", strings.HasSuffix(s, "\n\n"):
+	case strings.HasSuffix(s, "\n"):
+		sb.WriteString("\n")
+	default:
+		sb.WriteString("\n\n")
+	}
+}
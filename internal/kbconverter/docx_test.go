@@ -0,0 +1,127 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package kbconverter
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// testDOCX builds a DOCX archive from its parts
+func testDOCX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+const testDOCXStyles = `<?xml version="1.0" encoding="UTF-8"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:style w:type="paragraph" w:styleId="Titel"><w:name w:val="Title"/></w:style>
+  <w:style w:type="paragraph" w:styleId="berschrift1"><w:name w:val="heading 1"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Gliederung"><w:name w:val="Gliederung"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style>
+  <w:style w:type="paragraph" w:styleId="SourceCode"><w:name w:val="Source Code"/></w:style>
+</w:styles>`
+
+const testDOCXDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+    xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006">
+  <w:body>
+    <w:p><w:pPr><w:pStyle w:val="Titel"/></w:pPr><w:r><w:t>Design Notes</w:t></w:r></w:p>
+    <w:p><w:pPr><w:pStyle w:val="berschrift1"/></w:pPr><w:r><w:t>Overview</w:t></w:r></w:p>
+    <w:p><w:r><w:t xml:space="preserve">The service keeps </w:t></w:r><w:r><w:t>replicas in sync.</w:t></w:r></w:p>
+    <w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>first item</w:t></w:r></w:p>
+    <w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>nested item</w:t></w:r></w:p>
+    <w:p><w:r>
+      <mc:AlternateContent>
+        <mc:Choice Requires="wps"><w:txbxContent><w:p><w:r><w:t>Boxed note.</w:t></w:r></w:p></w:txbxContent></mc:Choice>
+        <mc:Fallback><w:txbxContent><w:p><w:r><w:t>Boxed note.</w:t></w:r></w:p></w:txbxContent></mc:Fallback>
+      </mc:AlternateContent>
+    </w:r></w:p>
+    <w:p><w:pPr><w:pStyle w:val="Gliederung"/></w:pPr><w:r><w:t>Settings</w:t></w:r></w:p>
+    <w:p><w:pPr><w:pStyle w:val="SourceCode"/></w:pPr><w:r><w:t>SHOW wal_level;</w:t></w:r></w:p>
+    <w:tbl>
+      <w:tr><w:tc><w:p><w:r><w:t>Setting</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Value</w:t></w:r></w:p></w:tc></w:tr>
+      <w:tr><w:tc><w:p><w:r><w:t>wal_level</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>logical</w:t></w:r></w:p></w:tc></w:tr>
+    </w:tbl>
+  </w:body>
+</w:document>`
+
+func TestConvertDOCX(t *testing.T) {
+	docx := testDOCX(t, map[string]string{
+		"word/document.xml": testDOCXDocument,
+		"word/styles.xml":   testDOCXStyles,
+	})
+
+	markdown, title, err := convertDOCX(docx)
+	if err != nil {
+		t.Fatalf("convertDOCX() error = %v", err)
+	}
+	if title != "Design Notes" {
+		t.Errorf("title = %q, want Design Notes", title)
+	}
+	want := "# Design Notes\n\n" +
+		"## Overview\n\n" +
+		"The service keeps replicas in sync.\n\n" +
+		"- first item\n" +
+		"  - nested item\n\n" +
+		"Boxed note.\n\n" +
+		"### Settings\n\n" +
+		"```\nSHOW wal_level;\n```\n\n" +
+		"| Setting | Value |\n" +
+		"| --- | --- |\n" +
+		"| wal_level | logical |"
+	if markdown != want {
+		t.Errorf("markdown =\n%s\nwant\n%s", markdown, want)
+	}
+}
+
+func TestConvertDOCX_CoreTitle(t *testing.T) {
+	docx := testDOCX(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Backups</w:t></w:r></w:p>
+<w:p><w:r><w:t>Take a base backup nightly.</w:t></w:r></w:p>
+</w:body></w:document>`,
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+  xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Operations Guide</dc:title></cp:coreProperties>`,
+	})
+
+	markdown, title, err := convertDOCX(docx)
+	if err != nil {
+		t.Fatalf("convertDOCX() error = %v", err)
+	}
+	if title != "Operations Guide" {
+		t.Errorf("title = %q, want Operations Guide", title)
+	}
+	if want := "# Operations Guide\n\n## Backups\n\nTake a base backup nightly."; markdown != want {
+		t.Errorf("markdown = %q, want %q", markdown, want)
+	}
+
+	if _, _, err := convertDOCX([]byte("not a zip")); err == nil {
+		t.Error("expected an error for a file that is not a DOCX archive")
+	}
+	if _, _, err := convertDOCX(testDOCX(t, map[string]string{"other.xml": "<x/>"})); err == nil {
+		t.Error("expected an error for an archive without word/document.xml")
+	}
+}
@@ -0,0 +1,1504 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package kbconverter
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// This file contains a small PDF text extractor. It is not a full PDF
+// reader: it finds the objects by scanning the file instead of reading the
+// cross-reference table, which also copes with damaged files, follows the
+// page tree, and interprets the text operators of each page's content
+// streams. Font sizes are used to recover headings. Encrypted PDFs and text
+// drawn as images (scanned documents) are not supported.
+
+const (
+	// maxPDFStreamSize limits the decompressed size of a single stream
+	maxPDFStreamSize = 256 << 20
+
+	// maxPDFFormDepth limits nesting of form XObjects drawn by a page
+	maxPDFFormDepth = 8
+
+	// maxPDFCMapRange limits the codes expanded from one ToUnicode range
+	maxPDFCMapRange = 1 << 16
+)
+
+var (
+	// errPDFEncrypted is returned for encrypted PDFs
+	errPDFEncrypted = errors.New("encrypted PDFs are not supported")
+
+	// errPDFNoText is returned when no page has extractable text
+	errPDFNoText = errors.New("PDF has no extractable text; it may be scanned or use fonts without a Unicode mapping")
+
+	// pdfObjRe matches the start of an indirect object
+	pdfObjRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+)
+
+// PDF object types; numbers are float64, booleans bool and null nil
+type (
+	pdfName    string
+	pdfString  string // Raw bytes of a literal or hexadecimal string
+	pdfKeyword string // Operators and other bare words
+	pdfDict    map[pdfName]interface{}
+	pdfArray   []interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// convertPDF extracts the text of a PDF as Markdown. The title comes from
+// the document information, or the first page's largest line.
+func convertPDF(content []byte) (string, string, error) {
+	head := content[:min(len(content), 1024)]
+	if !bytes.Contains(head, []byte("%PDF-")) {
+		return "", "", fmt.Errorf("not a PDF file")
+	}
+
+	doc := newPDFDocument(content)
+	if doc.encrypted() {
+		return "", "", errPDFEncrypted
+	}
+
+	var pages [][]*pdfLine
+	for _, page := range doc.pages() {
+		pages = append(pages, doc.pageText(page))
+	}
+
+	markdown, bodyTitle := pdfMarkdown(pages)
+	if strings.TrimSpace(markdown) == "" && bodyTitle == "" {
+		return "", "", errPDFNoText
+	}
+
+	title := doc.title()
+	if title == "" {
+		title = bodyTitle
+	} else if bodyTitle != "" {
+		markdown = "## " + bodyTitle + "\n\n" + markdown
+	}
+	if title != "" {
+		markdown = "# " + title + "\n\n" + markdown
+	}
+	return markdown, title, nil
+}
+
+// pdfDocument is a PDF file with its objects located
+type pdfDocument struct {
+	data      []byte
+	offsets   map[int]int // Object number to the offset of its definition
+	objects   map[int]interface{}
+	resolving map[int]bool
+	trailers  []pdfDict
+	fonts     map[pdfRef]*pdfFont
+}
+
+func newPDFDocument(data []byte) *pdfDocument {
+	d := &pdfDocument{
+		data:      data,
+		offsets:   make(map[int]int),
+		objects:   make(map[int]interface{}),
+		resolving: make(map[int]bool),
+		fonts:     make(map[pdfRef]*pdfFont),
+	}
+	d.scan()
+	return d
+}
+
+// scan locates the objects, trailers and object streams of the file. Later
+// definitions of an object replace earlier ones, as incremental updates do.
+func (d *pdfDocument) scan() {
+	pos := 0
+	for pos < len(d.data) {
+		loc := pdfObjRe.FindSubmatchIndex(d.data[pos:])
+		if loc == nil {
+			break
+		}
+		num, err := strconv.Atoi(string(d.data[pos+loc[2] : pos+loc[3]]))
+		start := pos + loc[0]
+		pos += loc[1]
+		if err == nil {
+			d.offsets[num] = start
+		}
+
+		// Skip stream data, which may contain anything
+		rest := d.data[pos:]
+		stream := bytes.Index(rest, []byte("stream"))
+		endobj := bytes.Index(rest, []byte("endobj"))
+		if stream >= 0 && (endobj < 0 || stream < endobj) {
+			if end := bytes.Index(rest[stream:], []byte("endstream")); end >= 0 {
+				pos += stream + end + len("endstream")
+			}
+		}
+	}
+
+	for pos := 0; ; {
+		i := bytes.Index(d.data[pos:], []byte("trailer"))
+		if i < 0 {
+			break
+		}
+		pos += i + len("trailer")
+		p := &pdfParser{data: d.data, pos: pos}
+		if obj, err := p.parseObject(); err == nil {
+			if dict, ok := obj.(pdfDict); ok {
+				d.trailers = append(d.trailers, dict)
+			}
+		}
+	}
+
+	nums := make([]int, 0, len(d.offsets))
+	for num := range d.offsets {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		stream, ok := d.object(num).(*pdfStream)
+		if !ok {
+			continue
+		}
+		switch stream.dict["Type"] {
+		case pdfName("XRef"):
+			d.trailers = append(d.trailers, stream.dict)
+		case pdfName("ObjStm"):
+			d.loadObjectStream(stream)
+		}
+	}
+}
+
+// loadObjectStream adds the objects compressed in an object stream, unless
+// they are also defined directly
+func (d *pdfDocument) loadObjectStream(stream *pdfStream) {
+	data, err := d.streamData(stream)
+	if err != nil {
+		return
+	}
+	n, _ := stream.dict["N"].(float64)
+	first, _ := stream.dict["First"].(float64)
+	p := &pdfParser{data: data}
+	type entry struct{ num, offset int }
+	var entries []entry
+	for i := 0; i < int(n); i++ {
+		num, err1 := p.parseObject()
+		offset, err2 := p.parseObject()
+		numF, ok1 := num.(float64)
+		offsetF, ok2 := offset.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			break
+		}
+		entries = append(entries, entry{int(numF), int(offsetF)})
+	}
+	for _, e := range entries {
+		if _, defined := d.offsets[e.num]; defined {
+			continue
+		}
+		if _, loaded := d.objects[e.num]; loaded {
+			continue
+		}
+		start := int(first) + e.offset
+		if start < 0 || start >= len(data) {
+			continue
+		}
+		obj, err := (&pdfParser{data: data, pos: start}).parseObject()
+		if err == nil {
+			d.objects[e.num] = obj
+		}
+	}
+}
+
+// object returns an indirect object, or nil if it is missing or damaged
+func (d *pdfDocument) object(num int) interface{} {
+	if obj, ok := d.objects[num]; ok {
+		return obj
+	}
+	offset, ok := d.offsets[num]
+	if !ok || d.resolving[num] {
+		return nil
+	}
+	d.resolving[num] = true
+	defer delete(d.resolving, num)
+
+	obj, err := d.parseIndirect(offset)
+	if err != nil {
+		obj = nil
+	}
+	d.objects[num] = obj
+	return obj
+}
+
+// parseIndirect parses the object defined at an offset, including its
+// stream data
+func (d *pdfDocument) parseIndirect(offset int) (interface{}, error) {
+	p := &pdfParser{data: d.data, pos: offset}
+	for i := 0; i < 3; i++ { // Object number, generation and "obj"
+		if _, err := p.parseObject(); err != nil {
+			return nil, err
+		}
+	}
+	obj, err := p.parseObject()
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return obj, nil
+	}
+	p.skipSpace()
+	if !bytes.HasPrefix(d.data[p.pos:], []byte("stream")) {
+		return dict, nil
+	}
+	start := p.pos + len("stream")
+	if bytes.HasPrefix(d.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(d.data) && (d.data[start] == '\n' || d.data[start] == '\r') {
+		start++
+	}
+
+	// Use the declared length if endstream follows it
+	if length, ok := d.resolve(dict["Length"]).(float64); ok && length >= 0 {
+		end := start + int(length)
+		if end <= len(d.data) {
+			after := bytes.TrimLeft(d.data[end:min(end+16, len(d.data))], "\r\n \t")
+			if bytes.HasPrefix(after, []byte("endstream")) {
+				return &pdfStream{dict: dict, raw: d.data[start:end]}, nil
+			}
+		}
+	}
+	end := bytes.Index(d.data[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, fmt.Errorf("stream without endstream")
+	}
+	raw := bytes.TrimRight(d.data[start:start+end], "\r\n")
+	return &pdfStream{dict: dict, raw: raw}, nil
+}
+
+// resolve follows indirect references
+func (d *pdfDocument) resolve(obj interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.object(ref.num)
+	}
+	return nil
+}
+
+// dict resolves an object that should be a dictionary; a stream's
+// dictionary is returned for a stream
+func (d *pdfDocument) dict(obj interface{}) pdfDict {
+	switch v := d.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// trailerValue returns a key of the newest trailer that has it
+func (d *pdfDocument) trailerValue(key pdfName) interface{} {
+	for i := len(d.trailers) - 1; i >= 0; i-- {
+		if v, ok := d.trailers[i][key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func (d *pdfDocument) encrypted() bool {
+	return d.trailerValue("Encrypt") != nil
+}
+
+// title returns the title in the document information
+func (d *pdfDocument) title() string {
+	info := d.dict(d.trailerValue("Info"))
+	if s, ok := d.resolve(info["Title"]).(pdfString); ok {
+		return strings.Join(strings.Fields(decodePDFTextString(s)), " ")
+	}
+	return ""
+}
+
+// pages returns the page dictionaries in order, with inherited resources
+// copied into each page
+func (d *pdfDocument) pages() []pdfDict {
+	root := d.dict(d.trailerValue("Root"))
+	if root == nil {
+		// Damaged files may lack a trailer; look for the catalog
+		for num := range d.offsets {
+			if dict := d.dict(pdfRef{num: num}); dict["Type"] == pdfName("Catalog") {
+				root = dict
+				break
+			}
+		}
+	}
+	var pages []pdfDict
+	visited := make(map[pdfRef]bool)
+	var walk func(node interface{}, resources interface{}, depth int)
+	walk = func(node interface{}, resources interface{}, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > 64 {
+			return
+		}
+		if r, ok := dict["Resources"]; ok {
+			resources = r
+		}
+		kids, isTree := d.resolve(dict["Kids"]).(pdfArray)
+		if !isTree {
+			page := make(pdfDict, len(dict)+1)
+			for k, v := range dict {
+				page[k] = v
+			}
+			page["Resources"] = resources
+			pages = append(pages, page)
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, resources, depth+1)
+		}
+	}
+	if root != nil {
+		walk(root["Pages"], nil, 0)
+	}
+	return pages
+}
+
+// streamData decodes a stream's data
+func (d *pdfDocument) streamData(s *pdfStream) ([]byte, error) {
+	var filters []pdfName
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []pdfName{f}
+	case pdfArray:
+		for _, v := range f {
+			if name, ok := d.resolve(v).(pdfName); ok {
+				filters = append(filters, name)
+			}
+		}
+	}
+
+	data := s.raw
+	for _, filter := range filters {
+		var err error
+		switch filter {
+		case "FlateDecode", "Fl":
+			data, err = inflatePDF(data)
+		case "ASCIIHexDecode", "AHx":
+			data = decodePDFHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodePDFASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %s", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflatePDF decompresses Flate data. Truncated streams are common, so the
+// data read before an unexpected end is kept.
+func inflatePDF(data []byte) ([]byte, error) {
+	var r io.Reader
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		r = zr
+	} else {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxPDFStreamSize+1))
+	if len(out) > maxPDFStreamSize {
+		return nil, fmt.Errorf("PDF stream is larger than %d MB", maxPDFStreamSize>>20)
+	}
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("failed to decompress PDF stream: %w", err)
+	}
+	return out, nil
+}
+
+// decodePDFHex decodes ASCIIHexDecode data
+func decodePDFHex(data []byte) []byte {
+	var out []byte
+	var b byte
+	odd := false
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		v, ok := hexValue(c)
+		if !ok {
+			continue
+		}
+		if odd {
+			out = append(out, b<<4|v)
+		} else {
+			b = v
+		}
+		odd = !odd
+	}
+	if odd {
+		out = append(out, b<<4)
+	}
+	return out
+}
+
+// decodePDFASCII85 decodes ASCII85Decode data
+func decodePDFASCII85(data []byte) ([]byte, error) {
+	var out []byte
+	var group [5]byte
+	n := 0
+	flush := func(count int) {
+		var v uint32
+		for i := 0; i < 5; i++ {
+			v = v*85 + uint32(group[i])
+		}
+		buf := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, buf[:count]...)
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '~':
+			i = len(data)
+		case c == 'z' && n == 0:
+			out = append(out, 0, 0, 0, 0)
+		case c >= '!' && c <= 'u':
+			group[n] = c - '!'
+			n++
+			if n == 5 {
+				flush(4)
+				n = 0
+			}
+		case isPDFSpace(c):
+		default:
+			return nil, fmt.Errorf("invalid ASCII85 data")
+		}
+	}
+	if n > 1 {
+		for i := n; i < 5; i++ {
+			group[i] = 84
+		}
+		flush(n - 1)
+	}
+	return out, nil
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// decodePDFTextString decodes a string outside a content stream, such as
+// the title: UTF-16BE or UTF-8 with a byte order mark, otherwise
+// PDFDocEncoding, which is close to Latin-1
+func decodePDFTextString(s pdfString) string {
+	b := []byte(s)
+	switch {
+	case bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		return decodeUTF16BE(b[2:])
+	case bytes.HasPrefix(b, []byte{0xEF, 0xBB, 0xBF}):
+		return string(b[3:])
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = winAnsiRune(c)
+	}
+	return string(runes)
+}
+
+func decodeUTF16BE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfParser reads PDF objects from a file or a content stream
+type pdfParser struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace skips whitespace and comments
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case isPDFSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// word reads a run of regular characters
+func (p *pdfParser) word() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isPDFSpace(p.data[p.pos]) && !isPDFDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// parseObject reads the next object. Closing delimiters are returned as
+// keywords, so callers can find the end of arrays and dictionaries.
+func (p *pdfParser) parseObject() (interface{}, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, io.EOF
+	}
+	c := p.data[p.pos]
+	switch c {
+	case '/':
+		return p.parseName(), nil
+	case '(':
+		return p.parseLiteralString(), nil
+	case '<':
+		if p.pos+1 < len(p.data) && p.data[p.pos+1] == '<' {
+			return p.parseDict()
+		}
+		return p.parseHexString(), nil
+	case '[':
+		return p.parseArray()
+	case '>':
+		if p.pos+1 < len(p.data) && p.data[p.pos+1] == '>' {
+			p.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		p.pos++
+		return pdfKeyword(">"), nil
+	case ']', ')', '{', '}':
+		p.pos++
+		return pdfKeyword(string(c)), nil
+	}
+
+	w := p.word()
+	if w == "" {
+		p.pos++
+		return pdfKeyword(string(c)), nil
+	}
+	switch w {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(w, 64)
+	if err != nil {
+		return pdfKeyword(w), nil
+	}
+
+	// An integer may start a reference: "12 0 R"
+	if num, err := strconv.Atoi(w); err == nil {
+		save := p.pos
+		p.skipSpace()
+		if gen, err := strconv.Atoi(p.word()); err == nil {
+			p.skipSpace()
+			if p.word() == "R" {
+				return pdfRef{num: num, gen: gen}, nil
+			}
+		}
+		p.pos = save
+	}
+	return n, nil
+}
+
+func (p *pdfParser) parseName() pdfName {
+	p.pos++
+	w := p.word()
+	if !strings.Contains(w, "#") {
+		return pdfName(w)
+	}
+	var b strings.Builder
+	for i := 0; i < len(w); i++ {
+		if w[i] == '#' && i+2 < len(w) {
+			hi, ok1 := hexValue(w[i+1])
+			lo, ok2 := hexValue(w[i+2])
+			if ok1 && ok2 {
+				b.WriteByte(hi<<4 | lo)
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(w[i])
+	}
+	return pdfName(b.String())
+}
+
+func (p *pdfParser) parseLiteralString() pdfString {
+	p.pos++
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(b)
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				return pdfString(b)
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+func (p *pdfParser) parseHexString() pdfString {
+	p.pos++
+	end := bytes.IndexByte(p.data[p.pos:], '>')
+	if end < 0 {
+		end = len(p.data) - p.pos
+	}
+	s := decodePDFHex(p.data[p.pos : p.pos+end])
+	p.pos += end + 1
+	return pdfString(s)
+}
+
+func (p *pdfParser) parseArray() (pdfArray, error) {
+	p.pos++
+	arr := pdfArray{}
+	for {
+		obj, err := p.parseObject()
+		if err != nil {
+			return arr, err
+		}
+		if obj == pdfKeyword("]") {
+			return arr, nil
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func (p *pdfParser) parseDict() (pdfDict, error) {
+	p.pos += 2
+	dict := pdfDict{}
+	for {
+		key, err := p.parseObject()
+		if err != nil {
+			return dict, err
+		}
+		if key == pdfKeyword(">>") {
+			return dict, nil
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			continue
+		}
+		value, err := p.parseObject()
+		if err != nil {
+			return dict, err
+		}
+		if value == pdfKeyword(">>") {
+			return dict, nil
+		}
+		dict[name] = value
+	}
+}
+
+// pdfFont maps the codes of a font's strings to text and widths
+type pdfFont struct {
+	toUnicode    map[string]string
+	codespace    [][2][]byte // Ranges of code bytes, from the ToUnicode CMap
+	composite    bool        // Type0 font with multi-byte codes
+	encoding     *[256]rune
+	widths       map[int]float64 // Glyph widths in thousandths of an em
+	defaultWidth float64
+}
+
+// font loads a font from a page's resources
+func (d *pdfDocument) font(resources pdfDict, name pdfName) *pdfFont {
+	fonts := d.dict(resources["Font"])
+	ref, isRef := fonts[name].(pdfRef)
+	if isRef {
+		if f, ok := d.fonts[ref]; ok {
+			return f
+		}
+	}
+	dict := d.dict(fonts[name])
+	f := d.loadFont(dict)
+	if isRef {
+		d.fonts[ref] = f
+	}
+	return f
+}
+
+func (d *pdfDocument) loadFont(dict pdfDict) *pdfFont {
+	f := &pdfFont{encoding: &winAnsiEncoding, widths: make(map[int]float64), defaultWidth: 500}
+	if dict == nil {
+		return f
+	}
+
+	if s, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.streamData(s); err == nil {
+			f.toUnicode, f.codespace = parseToUnicode(data)
+		}
+	}
+
+	if dict["Subtype"] == pdfName("Type0") {
+		f.composite = true
+		f.defaultWidth = 1000
+		if descendants, ok := d.resolve(dict["DescendantFonts"]).(pdfArray); ok && len(descendants) > 0 {
+			cid := d.dict(descendants[0])
+			if dw, ok := d.resolve(cid["DW"]).(float64); ok {
+				f.defaultWidth = dw
+			}
+			d.loadCIDWidths(f, cid)
+		}
+		return f
+	}
+
+	switch enc := d.resolve(dict["Encoding"]).(type) {
+	case pdfName:
+		f.encoding = namedPDFEncoding(enc)
+	case pdfDict:
+		base := namedPDFEncoding(pdfName(""))
+		if name, ok := d.resolve(enc["BaseEncoding"]).(pdfName); ok {
+			base = namedPDFEncoding(name)
+		}
+		custom := *base
+		if diffs, ok := d.resolve(enc["Differences"]).(pdfArray); ok {
+			code := 0
+			for _, v := range diffs {
+				switch v := d.resolve(v).(type) {
+				case float64:
+					code = int(v)
+				case pdfName:
+					if code >= 0 && code < 256 {
+						if r, ok := glyphRune(string(v)); ok {
+							custom[code] = r
+						}
+					}
+					code++
+				}
+			}
+		}
+		f.encoding = &custom
+	}
+
+	first, _ := d.resolve(dict["FirstChar"]).(float64)
+	if widths, ok := d.resolve(dict["Widths"]).(pdfArray); ok {
+		for i, w := range widths {
+			if w, ok := d.resolve(w).(float64); ok {
+				f.widths[int(first)+i] = w
+			}
+		}
+	}
+	if desc := d.dict(dict["FontDescriptor"]); desc != nil {
+		if mw, ok := d.resolve(desc["MissingWidth"]).(float64); ok && mw > 0 {
+			f.defaultWidth = mw
+		}
+	}
+	return f
+}
+
+// loadCIDWidths reads the W array of a CID font: "c [w1 w2 ...]" gives
+// widths from c on, "cfirst clast w" one width for a range
+func (d *pdfDocument) loadCIDWidths(f *pdfFont, cid pdfDict) {
+	w, ok := d.resolve(cid["W"]).(pdfArray)
+	if !ok {
+		return
+	}
+	for i := 0; i < len(w); {
+		first, ok := d.resolve(w[i]).(float64)
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		switch next := d.resolve(w[i+1]).(type) {
+		case pdfArray:
+			for j, v := range next {
+				if v, ok := d.resolve(v).(float64); ok {
+					f.widths[int(first)+j] = v
+				}
+			}
+			i += 2
+		case float64:
+			if i+2 >= len(w) {
+				return
+			}
+			width, _ := d.resolve(w[i+2]).(float64)
+			for c := int(first); c <= int(next) && c-int(first) < maxPDFCMapRange; c++ {
+				f.widths[c] = width
+			}
+			i += 3
+		default:
+			return
+		}
+	}
+}
+
+// codeLength returns the number of bytes of the code at the start of s
+func (f *pdfFont) codeLength(s []byte) int {
+	for _, r := range f.codespace {
+		n := len(r[0])
+		if n == 0 || n > len(s) || len(r[1]) != n {
+			continue
+		}
+		in := true
+		for i := 0; i < n; i++ {
+			if s[i] < r[0][i] || s[i] > r[1][i] {
+				in = false
+				break
+			}
+		}
+		if in {
+			return n
+		}
+	}
+	if f.composite && len(s) >= 2 {
+		return 2
+	}
+	return 1
+}
+
+// decode returns the text of a string and its width in thousandths of an em
+func (f *pdfFont) decode(s pdfString) (string, float64) {
+	var b strings.Builder
+	var width float64
+	raw := []byte(s)
+	for i := 0; i < len(raw); {
+		n := f.codeLength(raw[i:])
+		code := raw[i : i+n]
+		i += n
+
+		value := 0
+		for _, c := range code {
+			value = value<<8 | int(c)
+		}
+		if w, ok := f.widths[value]; ok {
+			width += w
+		} else {
+			width += f.defaultWidth
+		}
+
+		if text, ok := f.toUnicode[string(code)]; ok {
+			b.WriteString(text)
+			continue
+		}
+		// Codes of composite fonts are glyph IDs without a mapping
+		if !f.composite && n == 1 {
+			if r := f.encoding[code[0]]; r != 0 {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String(), width
+}
+
+// parseToUnicode reads the mappings of a ToUnicode CMap
+func parseToUnicode(data []byte) (map[string]string, [][2][]byte) {
+	mapping := make(map[string]string)
+	var codespace [][2][]byte
+	p := &pdfParser{data: data}
+	var operands []interface{}
+	for {
+		obj, err := p.parseObject()
+		if err != nil {
+			break
+		}
+		op, isOp := obj.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, obj)
+			continue
+		}
+		switch op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					codespace = append(codespace, [2][]byte{[]byte(lo), []byte(hi)})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					mapping[string(src)] = decodeUTF16BE([]byte(dst))
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+				addBFRange(mapping, []byte(lo), []byte(hi), operands[i+2])
+			}
+		}
+		operands = operands[:0]
+	}
+	return mapping, codespace
+}
+
+// addBFRange maps a range of codes, either to consecutive characters from a
+// starting one or to the strings of an array
+func addBFRange(mapping map[string]string, lo, hi []byte, dst interface{}) {
+	first, last := 0, 0
+	for i := range lo {
+		first = first<<8 | int(lo[i])
+		last = last<<8 | int(hi[i])
+	}
+	key := func(code int) string {
+		b := make([]byte, len(lo))
+		for i := len(b) - 1; i >= 0; i-- {
+			b[i] = byte(code)
+			code >>= 8
+		}
+		return string(b)
+	}
+	for offset := 0; first+offset <= last && offset < maxPDFCMapRange; offset++ {
+		switch dst := dst.(type) {
+		case pdfString:
+			b := []byte(dst)
+			if len(b) < 2 {
+				return
+			}
+			units := make([]uint16, len(b)/2)
+			for i := range units {
+				units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+			}
+			units[len(units)-1] += uint16(offset)
+			mapping[key(first+offset)] = string(utf16.Decode(units))
+		case pdfArray:
+			if offset >= len(dst) {
+				return
+			}
+			if s, ok := dst[offset].(pdfString); ok {
+				mapping[key(first+offset)] = decodeUTF16BE([]byte(s))
+			}
+		default:
+			return
+		}
+	}
+}
+
+// pdfLine is a line of text of a page
+type pdfLine struct {
+	text       strings.Builder
+	size       float64 // Largest font size in the line
+	paragraph  bool    // The line starts a paragraph
+	characters int
+}
+
+// pdfTextExtractor interprets the text operators of content streams
+type pdfTextExtractor struct {
+	doc   *pdfDocument
+	lines []*pdfLine
+	line  *pdfLine
+
+	font     *pdfFont
+	fontSize float64
+	leading  float64
+	tm       [6]float64 // Text matrix
+	tlm      [6]float64 // Text line matrix
+
+	lastY, endX float64 // Position of the last text shown
+	shown       bool
+}
+
+// pageText extracts the lines of a page
+func (d *pdfDocument) pageText(page pdfDict) []*pdfLine {
+	e := &pdfTextExtractor{doc: d, fontSize: 1}
+	e.newLine(true)
+	resources := d.dict(page["Resources"])
+	var contents []interface{}
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		contents = []interface{}{c}
+	case pdfArray:
+		contents = c
+	}
+	// The content streams of a page are one stream split in parts
+	var data []byte
+	for _, c := range contents {
+		if s, ok := d.resolve(c).(*pdfStream); ok {
+			if b, err := d.streamData(s); err == nil {
+				data = append(data, b...)
+				data = append(data, '\n')
+			}
+		}
+	}
+	e.run(data, resources, 0)
+	return e.lines
+}
+
+func (e *pdfTextExtractor) newLine(paragraph bool) {
+	if e.line != nil && e.line.characters == 0 {
+		e.line.paragraph = e.line.paragraph || paragraph
+		return
+	}
+	e.line = &pdfLine{paragraph: paragraph}
+	e.lines = append(e.lines, e.line)
+}
+
+// effectiveSize is the font size in user space
+func (e *pdfTextExtractor) effectiveSize() float64 {
+	scale := math.Hypot(e.tm[2], e.tm[3])
+	if scale == 0 {
+		scale = 1
+	}
+	return math.Abs(e.fontSize) * scale
+}
+
+// moved starts a new line or adds a space when text is positioned away
+// from where the previous text ended
+func (e *pdfTextExtractor) moved() {
+	if !e.shown {
+		return
+	}
+	size := e.effectiveSize()
+	dy := math.Abs(e.tm[5] - e.lastY)
+	dx := e.tm[4] - e.endX
+	switch {
+	case dy > size*0.5:
+		e.newLine(dy > size*1.8)
+	case dx > size*0.15 || dx < -size*2:
+		e.space()
+	}
+}
+
+func (e *pdfTextExtractor) space() {
+	s := e.line.text.String()
+	if s != "" && !strings.HasSuffix(s, " ") {
+		e.line.text.WriteString(" ")
+	}
+}
+
+// show adds the text of a string and advances the text position
+func (e *pdfTextExtractor) show(s pdfString) {
+	if e.font == nil {
+		e.font = e.doc.loadFont(nil)
+	}
+	text, width := e.font.decode(s)
+	if text != "" {
+		e.moved()
+		e.line.text.WriteString(text)
+		if strings.TrimSpace(text) != "" {
+			e.line.characters += utf8.RuneCountInString(text)
+			e.line.size = math.Max(e.line.size, e.effectiveSize())
+		}
+		e.shown = true
+	}
+	e.advance(width / 1000 * e.fontSize)
+	e.lastY = e.tm[5]
+	e.endX = e.tm[4]
+}
+
+// advance moves the text position along the line by tx text space units
+func (e *pdfTextExtractor) advance(tx float64) {
+	e.tm[4] += tx * e.tm[0]
+	e.tm[5] += tx * e.tm[1]
+}
+
+func (e *pdfTextExtractor) setLineMatrix(m [6]float64) {
+	e.tlm = m
+	e.tm = m
+	e.moved()
+}
+
+// run interprets a content stream
+func (e *pdfTextExtractor) run(data []byte, resources pdfDict, depth int) {
+	p := &pdfParser{data: data}
+	var operands []interface{}
+	number := func(i int) float64 {
+		if i < len(operands) {
+			if v, ok := operands[i].(float64); ok {
+				return v
+			}
+		}
+		return 0
+	}
+	identity := [6]float64{1, 0, 0, 1, 0, 0}
+
+	for {
+		obj, err := p.parseObject()
+		if err != nil {
+			return
+		}
+		op, isOp := obj.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			e.tm, e.tlm = identity, identity
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					e.font = e.doc.font(resources, name)
+				}
+				e.fontSize = number(1)
+			}
+		case "TL":
+			e.leading = number(0)
+		case "Td", "TD":
+			tx, ty := number(0), number(1)
+			if op == "TD" {
+				e.leading = -ty
+			}
+			m := e.tlm
+			m[4] += tx*m[0] + ty*m[2]
+			m[5] += tx*m[1] + ty*m[3]
+			e.setLineMatrix(m)
+		case "Tm":
+			e.setLineMatrix([6]float64{number(0), number(1), number(2), number(3), number(4), number(5)})
+		case "T*":
+			e.nextLine()
+		case "Tj":
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					e.show(s)
+				}
+			}
+		case "'", "\"":
+			e.nextLine()
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					e.show(s)
+				}
+			}
+		case "TJ":
+			if len(operands) == 0 {
+				break
+			}
+			arr, _ := operands[len(operands)-1].(pdfArray)
+			for _, item := range arr {
+				switch v := item.(type) {
+				case pdfString:
+					e.show(v)
+				case float64:
+					// Negative adjustments move right; a large one is a word gap
+					e.advance(-v / 1000 * e.fontSize)
+					e.endX = e.tm[4]
+					if v < -250 && e.shown {
+						e.space()
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 && depth < maxPDFFormDepth {
+				name, _ := operands[0].(pdfName)
+				xobjects := e.doc.dict(resources["XObject"])
+				if form, ok := e.doc.resolve(xobjects[name]).(*pdfStream); ok && form.dict["Subtype"] == pdfName("Form") {
+					formResources := resources
+					if r := e.doc.dict(form.dict["Resources"]); r != nil {
+						formResources = r
+					}
+					if b, err := e.doc.streamData(form); err == nil {
+						e.run(b, formResources, depth+1)
+					}
+				}
+			}
+		case "BI":
+			// Inline image data is binary; skip to the end of the image
+			if id := bytes.Index(data[p.pos:], []byte("ID")); id >= 0 {
+				p.pos += id + 2
+				if ei := bytes.Index(data[p.pos:], []byte("EI")); ei >= 0 {
+					p.pos += ei + 2
+				} else {
+					p.pos = len(data)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func (e *pdfTextExtractor) nextLine() {
+	m := e.tlm
+	m[4] += -e.leading * m[2]
+	m[5] += -e.leading * m[3]
+	e.tlm = m
+	e.tm = m
+	if e.shown {
+		e.newLine(false)
+	}
+}
+
+// pdfMarkdown assembles the lines of the pages. Lines noticeably larger than
+// the body text become headings; the largest line of the first page is
+// returned as the title instead if it stands out.
+func pdfMarkdown(pages [][]*pdfLine) (string, string) {
+	// The body size is the size used by most characters
+	counts := make(map[float64]int)
+	for _, lines := range pages {
+		for _, l := range lines {
+			counts[math.Round(l.size*2)/2] += l.characters
+		}
+	}
+	body, best := 0.0, -1
+	for size, n := range counts {
+		if n > best || (n == best && size < body) {
+			body, best = size, n
+		}
+	}
+
+	var titleLine *pdfLine
+	if len(pages) > 0 {
+		for _, l := range pages[0] {
+			if l.characters > 0 && l.characters <= 200 && l.size >= body*1.2 && (titleLine == nil || l.size > titleLine.size) {
+				titleLine = l
+			}
+		}
+	}
+
+	var sb strings.Builder
+	lastHeading := ""
+	for _, lines := range pages {
+		for _, l := range lines {
+			text := strings.TrimSpace(l.text.String())
+			if text == "" || l == titleLine {
+				continue
+			}
+			level := ""
+			if l.characters <= 120 && body > 0 {
+				switch {
+				case l.size >= body*1.6:
+					level = "##"
+				case l.size >= body*1.2:
+					level = "###"
+				}
+			}
+			switch {
+			case level != "" && level == lastHeading:
+				// A heading wrapped over several lines
+				out := strings.TrimRight(sb.String(), "\n")
+				sb.Reset()
+				sb.WriteString(out + " " + text + "\n\n")
+				continue
+			case level != "":
+				ensureBlankLine(&sb)
+				sb.WriteString(level + " " + text + "\n\n")
+			case l.paragraph:
+				ensureBlankLine(&sb)
+				sb.WriteString(text + "\n")
+			default:
+				sb.WriteString(text + "\n")
+			}
+			lastHeading = level
+		}
+		ensureBlankLine(&sb)
+		lastHeading = ""
+	}
+
+	title := ""
+	if titleLine != nil {
+		title = strings.Join(strings.Fields(titleLine.text.String()), " ")
+	}
+	return strings.TrimSpace(sb.String()), title
+}
+
+// namedPDFEncoding returns a simple font encoding by name. Fonts without an
+// encoding use their built-in one, usually close to WinAnsiEncoding.
+func namedPDFEncoding(name pdfName) *[256]rune {
+	switch name {
+	case "MacRomanEncoding":
+		return &macRomanEncoding
+	case "StandardEncoding":
+		return &standardEncoding
+	}
+	return &winAnsiEncoding
+}
+
+var (
+	winAnsiEncoding  = buildWinAnsiEncoding()
+	standardEncoding = buildStandardEncoding()
+	macRomanEncoding = buildMacRomanEncoding()
+)
+
+// winAnsiHigh are the characters of Windows-1252 from 0x80 to 0x9F
+var winAnsiHigh = []rune("€\u0000‚ƒ„…†‡ˆ‰Š‹Œ\u0000Ž\u0000\u0000‘’“”•–—˜™š›œ\u0000žŸ")
+
+func winAnsiRune(c byte) rune {
+	if c >= 0x80 && c <= 0x9F {
+		return winAnsiHigh[c-0x80]
+	}
+	return rune(c)
+}
+
+func buildWinAnsiEncoding() [256]rune {
+	var enc [256]rune
+	for c := 0x20; c < 256; c++ {
+		enc[c] = winAnsiRune(byte(c))
+	}
+	enc['\t'], enc['\n'], enc['\r'] = ' ', ' ', ' '
+	enc[0x7F] = 0
+	return enc
+}
+
+// buildStandardEncoding approximates StandardEncoding, which differs from
+// WinAnsiEncoding in the quotes and above 0x7F
+func buildStandardEncoding() [256]rune {
+	enc := buildWinAnsiEncoding()
+	enc['\''] = '’'
+	enc['`'] = '‘'
+	enc[0xAE], enc[0xAF] = 'ﬁ', 'ﬂ'
+	enc[0xB1], enc[0xD0] = '–', '—'
+	enc[0xB7], enc[0xBC] = '•', '…'
+	return enc
+}
+
+func buildMacRomanEncoding() [256]rune {
+	enc := buildWinAnsiEncoding()
+	high := []rune("ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø¿¡¬√ƒ≈∆«»…\u00a0ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔ\uf8ffÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+	for i, r := range high {
+		enc[0x80+i] = r
+	}
+	return enc
+}
+
+// latin1GlyphNames are the glyph names of the Latin-1 characters from 0xC0
+var latin1GlyphNames = strings.Fields(`Agrave Aacute Acircumflex Atilde Adieresis
+	Aring AE Ccedilla Egrave Eacute Ecircumflex Edieresis Igrave Iacute
+	Icircumflex Idieresis Eth Ntilde Ograve Oacute Ocircumflex Otilde Odieresis
+	multiply Oslash Ugrave Uacute Ucircumflex Udieresis Yacute Thorn germandbls
+	agrave aacute acircumflex atilde adieresis aring ae ccedilla egrave eacute
+	ecircumflex edieresis igrave iacute icircumflex idieresis eth ntilde ograve
+	oacute ocircumflex otilde odieresis divide oslash ugrave uacute ucircumflex
+	udieresis yacute thorn ydieresis`)
+
+// asciiGlyphNames are the glyph names of the printable ASCII characters
+// that are not letters or digits, from 0x20
+var asciiGlyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#',
+	"dollar": '$', "percent": '%', "ampersand": '&', "quotesingle": '\'',
+	"parenleft": '(', "parenright": ')', "asterisk": '*', "plus": '+',
+	"comma": ',', "hyphen": '-', "period": '.', "slash": '/', "colon": ':',
+	"semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\',
+	"bracketright": ']', "asciicircum": '^', "underscore": '_', "grave": '`',
+	"braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"quoteleft": '‘', "quoteright": '’', "quotedblleft": '“',
+	"quotedblright": '”', "quotesinglbase": '‚', "quotedblbase": '„',
+	"bullet": '•', "endash": '–', "emdash": '—', "ellipsis": '…',
+	"fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ', "ffi": 'ﬃ', "ffl": 'ﬄ',
+	"dagger": '†', "daggerdbl": '‡', "degree": '°', "copyright": '©',
+	"registered": '®', "trademark": '™', "section": '§', "paragraph": '¶',
+	"periodcentered": '·', "minus": '−', "Euro": '€', "nbspace": '\u00a0',
+	"guillemotleft": '«', "guillemotright": '»', "dotlessi": 'ı',
+	"exclamdown": '¡', "questiondown": '¿', "cent": '¢', "sterling": '£',
+	"yen": '¥', "plusminus": '±', "mu": 'µ', "OE": 'Œ', "oe": 'œ',
+	"Scaron": 'Š', "scaron": 'š', "Zcaron": 'Ž', "zcaron": 'ž',
+	"Ydieresis": 'Ÿ', "florin": 'ƒ', "perthousand": '‰',
+}
+
+// glyphRune maps a glyph name to its character
+func glyphRune(name string) (rune, bool) {
+	if r, ok := asciiGlyphNames[name]; ok {
+		return r, true
+	}
+	for i, n := range latin1GlyphNames {
+		if n == name {
+			return rune(0xC0 + i), true
+		}
+	}
+	if len(name) == 1 && (name[0] >= 'A' && name[0] <= 'Z' || name[0] >= 'a' && name[0] <= 'z') {
+		return rune(name[0]), true
+	}
+	// uniXXXX and uXXXX[XX] names carry the code point
+	if hex, ok := strings.CutPrefix(name, "uni"); ok && len(hex) >= 4 {
+		if v, err := strconv.ParseUint(hex[:4], 16, 32); err == nil {
+			return rune(v), true
+		}
+	}
+	if hex, ok := strings.CutPrefix(name, "u"); ok && len(hex) >= 4 && len(hex) <= 6 {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil && utf8.ValidRune(rune(v)) {
+			return rune(v), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,150 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package kbconverter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testPDF assembles a PDF; objects[i] is object i+1, and empty objects are
+// left out (they live in an object stream)
+func testPDF(trailer string, objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		if obj != "" {
+			fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+		}
+	}
+	fmt.Fprintf(&b, "trailer\n%s\n%%%%EOF\n", trailer)
+	return b.Bytes()
+}
+
+// testPDFStream returns a stream object, Flate-compressed if compress is set
+func testPDFStream(dict string, data string, compress bool) string {
+	if compress {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		_, _ = w.Write([]byte(data)) //nolint:errcheck // writes to a buffer
+		_ = w.Close()                //nolint:errcheck // writes to a buffer
+		data = buf.String()
+		dict += " /Filter /FlateDecode"
+	}
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func TestConvertPDF(t *testing.T) {
+	content := `BT /F1 24 Tf 72 720 Td (Database Runbook) Tj ET
+BT /F1 16 Tf 72 680 Td (Failover) Tj ET
+BT /F2 10 Tf 72 660 Td [(Promote the)-300(standby)] TJ
+0 -12 Td (with pg_ctl promote.) Tj
+0 -30 Td (Then check the ) Tj (\(new\) primary') Tj (s lag.) Tj ET`
+
+	for _, compress := range []bool{false, true} {
+		pdf := testPDF("<< /Root 1 0 R >>",
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+			testPDFStream("", content, compress),
+			"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+			"<< /Type /Font /Subtype /Type1 /BaseFont /Times-Roman /Encoding << /Differences [39 /quoteright] >> >>",
+		)
+
+		markdown, title, err := convertPDF(pdf)
+		if err != nil {
+			t.Fatalf("convertPDF() error = %v", err)
+		}
+		if title != "Database Runbook" {
+			t.Errorf("title = %q, want Database Runbook", title)
+		}
+		want := "# Database Runbook\n\n## Failover\n\nPromote the standby\nwith pg_ctl promote.\n\nThen check the (new) primary’s lag."
+		if markdown != want {
+			t.Errorf("compress=%v: markdown =\n%s\nwant\n%s", compress, markdown, want)
+		}
+	}
+}
+
+func TestConvertPDF_ToUnicodeAndObjectStreams(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0048>
+<0002> <0069>
+endbfchar
+1 beginbfrange
+<0003> <0004> <00E9>
+endbfrange
+endcmap
+end end`
+
+	// The fonts are objects 7 and 8, stored in the object stream 6
+	font := "<< /Type /Font /Subtype /Type0 /BaseFont /ABCDEF+Example /Encoding /Identity-H /DescendantFonts [8 0 R] /ToUnicode 9 0 R >>"
+	cid := "<< /Type /Font /Subtype /CIDFontType2 /DW 600 /W [1 [500 500] 3 4 250] >>"
+	header := fmt.Sprintf("7 0 8 %d ", len(font)+1)
+	objStm := testPDFStream(fmt.Sprintf("/Type /ObjStm /N 2 /First %d", len(header)), header+font+" "+cid, true)
+
+	pdf := testPDF("<< /Root 1 0 R /Info 10 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 7 0 R >> >> /Contents [4 0 R 5 0 R] >>",
+		testPDFStream("", "BT /F1 12 Tf 100 700 Td <00010002> Tj", false),
+		testPDFStream("", "< 0003 0004 > Tj ET", false),
+		objStm,
+		"",
+		"",
+		testPDFStream("", cmap, true),
+		"<< /Title <FEFF00520075006E0062006F006F006B> >>",
+	)
+
+	markdown, title, err := convertPDF(pdf)
+	if err != nil {
+		t.Fatalf("convertPDF() error = %v", err)
+	}
+	if title != "Runbook" {
+		t.Errorf("title = %q, want Runbook", title)
+	}
+	if want := "# Runbook\n\nHiéê"; markdown != want {
+		t.Errorf("markdown = %q, want %q", markdown, want)
+	}
+}
+
+func TestConvertPDF_Errors(t *testing.T) {
+	if _, _, err := convertPDF([]byte("hello")); err == nil {
+		t.Error("expected an error for a file that is not a PDF")
+	}
+
+	encrypted := testPDF("<< /Root 1 0 R /Encrypt 4 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /Filter /Standard /V 2 >>",
+	)
+	if _, _, err := convertPDF(encrypted); !errors.Is(err, errPDFEncrypted) {
+		t.Errorf("convertPDF(encrypted) error = %v, want %v", err, errPDFEncrypted)
+	}
+
+	empty := testPDF("<< /Root 1 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+	)
+	if _, _, err := convertPDF(empty); !errors.Is(err, errPDFNoText) {
+		t.Errorf("convertPDF(empty) error = %v, want %v", err, errPDFNoText)
+	}
+}
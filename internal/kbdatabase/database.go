@@ -84,6 +84,22 @@ func (d *Database) createSchema() error {
         PRIMARY KEY (checksum, project_name, project_version)
     );
 
+    -- Model that produced each provider's embeddings
+    CREATE TABLE IF NOT EXISTS embedding_models (
+        provider TEXT PRIMARY KEY,
+        model TEXT NOT NULL,
+        dimensions INTEGER NOT NULL,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    -- Chunks still waiting for each provider's embedding
+    CREATE TABLE IF NOT EXISTS pending_embeddings (
+        provider TEXT NOT NULL,
+        chunk_id INTEGER NOT NULL,
+
+        PRIMARY KEY (provider, chunk_id)
+    );
+
     -- Indexes for fast filtering
     CREATE INDEX IF NOT EXISTS idx_project ON chunks(project_name, project_version);
     CREATE INDEX IF NOT EXISTS idx_title ON chunks(title);
@@ -120,6 +136,7 @@ func (d *Database) InsertChunks(chunks []*kbtypes.Chunk) error {
 
 	// Track source files and their chunk counts
 	sourceFileChunks := make(map[string]int) // checksum+project+version -> count
+	ids := make([]int64, len(chunks))
 
 	for i, chunk := range chunks {
 		// Serialize embeddings to BLOB
@@ -135,7 +152,7 @@ func (d *Database) InsertChunks(chunks []*kbtypes.Chunk) error {
 			ollamaBlob = serializeEmbedding(chunk.OllamaEmbedding)
 		}
 
-		_, err := stmt.Exec(
+		result, err := stmt.Exec(
 			chunk.Text,
 			chunk.Title,
 			chunk.Section,
@@ -150,6 +167,9 @@ func (d *Database) InsertChunks(chunks []*kbtypes.Chunk) error {
 		if err != nil {
 			return fmt.Errorf("failed to insert chunk %d: %w", i, err)
 		}
+		if ids[i], err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get ID of chunk %d: %w", i, err)
+		}
 
 		// Track source file
 		if chunk.SourceFileChecksum != "" {
@@ -200,6 +220,9 @@ func (d *Database) InsertChunks(chunks []*kbtypes.Chunk) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	for i, chunk := range chunks {
+		chunk.ID = int(ids[i])
+	}
 	return nil
 }
 
@@ -313,11 +336,62 @@ func (d *Database) SearchChunks(query string, limit int) ([]*kbtypes.Chunk, erro
 
 // GetAllChunks retrieves all chunks from the database
 func (d *Database) GetAllChunks() ([]*kbtypes.Chunk, error) {
-	rows, err := d.db.Query(`
+	return d.queryChunks(`
         SELECT id, text, title, section, project_name, project_version, file_path,
                openai_embedding, voyage_embedding, ollama_embedding
         FROM chunks
     `)
+}
+
+// GetPendingEmbeddings retrieves the chunks still waiting for a provider's
+// embedding
+func (d *Database) GetPendingEmbeddings(provider string) ([]*kbtypes.Chunk, error) {
+	if _, err := embeddingColumn(provider); err != nil {
+		return nil, err
+	}
+	return d.queryChunks(`
+        SELECT c.id, c.text, c.title, c.section, c.project_name, c.project_version, c.file_path,
+               c.openai_embedding, c.voyage_embedding, c.ollama_embedding
+        FROM chunks c
+        JOIN pending_embeddings p ON p.chunk_id = c.id
+        WHERE p.provider = ?
+        ORDER BY c.id
+    `, strings.ToLower(provider))
+}
+
+// AddPendingEmbeddings records that stored chunks are waiting for a
+// provider's embedding
+func (d *Database) AddPendingEmbeddings(provider string, chunks []*kbtypes.Chunk) error {
+	if _, err := embeddingColumn(provider); err != nil {
+		return err
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Rollback is safe to ignore here as it will be a no-op if commit succeeds
+		_ = tx.Rollback() //nolint:errcheck // rollback error is not actionable
+	}()
+
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO pending_embeddings (provider, chunk_id) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, chunk := range chunks {
+		if _, err := stmt.Exec(strings.ToLower(provider), chunk.ID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queryChunks runs a query selecting chunks with the columns of GetAllChunks
+func (d *Database) queryChunks(query string, args ...interface{}) ([]*kbtypes.Chunk, error) {
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -393,60 +467,26 @@ func (d *Database) UpdateChunkEmbeddings(chunks []*kbtypes.Chunk) error {
 
 // UpdateOpenAIEmbeddings updates only OpenAI embeddings for existing chunks
 func (d *Database) UpdateOpenAIEmbeddings(chunks []*kbtypes.Chunk) error {
-	tx, err := d.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		// Rollback is safe to ignore here as it will be a no-op if commit succeeds
-		_ = tx.Rollback() //nolint:errcheck // rollback error is not actionable
-	}()
-
-	stmt, err := tx.Prepare(`UPDATE chunks SET openai_embedding = ? WHERE id = ?`)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	for _, chunk := range chunks {
-		blob := serializeEmbedding(chunk.OpenAIEmbedding)
-		if _, err := stmt.Exec(blob, chunk.ID); err != nil {
-			return err
-		}
-	}
-
-	return tx.Commit()
+	return d.updateEmbeddings("openai", chunks, func(c *kbtypes.Chunk) []float32 { return c.OpenAIEmbedding })
 }
 
 // UpdateVoyageEmbeddings updates only Voyage embeddings for existing chunks
 func (d *Database) UpdateVoyageEmbeddings(chunks []*kbtypes.Chunk) error {
-	tx, err := d.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		// Rollback is safe to ignore here as it will be a no-op if commit succeeds
-		_ = tx.Rollback() //nolint:errcheck // rollback error is not actionable
-	}()
-
-	stmt, err := tx.Prepare(`UPDATE chunks SET voyage_embedding = ? WHERE id = ?`)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	for _, chunk := range chunks {
-		blob := serializeEmbedding(chunk.VoyageEmbedding)
-		if _, err := stmt.Exec(blob, chunk.ID); err != nil {
-			return err
-		}
-	}
-
-	return tx.Commit()
+	return d.updateEmbeddings("voyage", chunks, func(c *kbtypes.Chunk) []float32 { return c.VoyageEmbedding })
 }
 
 // UpdateOllamaEmbeddings updates only Ollama embeddings for existing chunks
 func (d *Database) UpdateOllamaEmbeddings(chunks []*kbtypes.Chunk) error {
+	return d.updateEmbeddings("ollama", chunks, func(c *kbtypes.Chunk) []float32 { return c.OllamaEmbedding })
+}
+
+// updateEmbeddings sets one provider's embeddings of existing chunks and
+// clears their pending records, so the batch is checkpointed atomically
+func (d *Database) updateEmbeddings(provider string, chunks []*kbtypes.Chunk, embedding func(*kbtypes.Chunk) []float32) error {
+	column, err := embeddingColumn(provider)
+	if err != nil {
+		return err
+	}
 	tx, err := d.db.Begin()
 	if err != nil {
 		return err
@@ -456,15 +496,23 @@ func (d *Database) UpdateOllamaEmbeddings(chunks []*kbtypes.Chunk) error {
 		_ = tx.Rollback() //nolint:errcheck // rollback error is not actionable
 	}()
 
-	stmt, err := tx.Prepare(`UPDATE chunks SET ollama_embedding = ? WHERE id = ?`)
+	stmt, err := tx.Prepare("UPDATE chunks SET " + column + " = ? WHERE id = ?")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
+	done, err := tx.Prepare(`DELETE FROM pending_embeddings WHERE provider = ? AND chunk_id = ?`)
+	if err != nil {
+		return err
+	}
+	defer done.Close()
+
 	for _, chunk := range chunks {
-		blob := serializeEmbedding(chunk.OllamaEmbedding)
-		if _, err := stmt.Exec(blob, chunk.ID); err != nil {
+		if _, err := stmt.Exec(serializeEmbedding(embedding(chunk)), chunk.ID); err != nil {
+			return err
+		}
+		if _, err := done.Exec(provider, chunk.ID); err != nil {
 			return err
 		}
 	}
@@ -474,16 +522,9 @@ func (d *Database) UpdateOllamaEmbeddings(chunks []*kbtypes.Chunk) error {
 
 // ClearEmbeddings clears all embeddings for a specific provider
 func (d *Database) ClearEmbeddings(provider string) (int64, error) {
-	var column string
-	switch strings.ToLower(provider) {
-	case "openai":
-		column = "openai_embedding"
-	case "voyage":
-		column = "voyage_embedding"
-	case "ollama":
-		column = "ollama_embedding"
-	default:
-		return 0, fmt.Errorf("invalid provider: %s (must be openai, voyage, or ollama)", provider)
+	column, err := embeddingColumn(provider)
+	if err != nil {
+		return 0, err
 	}
 
 	query := fmt.Sprintf("UPDATE chunks SET %s = NULL", column)
@@ -491,10 +532,43 @@ func (d *Database) ClearEmbeddings(provider string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if _, err := d.db.Exec("DELETE FROM embedding_models WHERE provider = ?", strings.ToLower(provider)); err != nil {
+		return 0, err
+	}
 
 	return result.RowsAffected()
 }
 
+// SetEmbeddingModel records the model that produced a provider's embeddings
+func (d *Database) SetEmbeddingModel(model kbtypes.EmbeddingModel) error {
+	_, err := d.db.Exec(`
+        INSERT INTO embedding_models (provider, model, dimensions, updated_at)
+        VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT (provider) DO UPDATE
+        SET model = excluded.model, dimensions = excluded.dimensions, updated_at = excluded.updated_at
+    `, model.Provider, model.Model, model.Dimensions)
+	return err
+}
+
+// GetEmbeddingModels returns the recorded embedding models, by provider
+func (d *Database) GetEmbeddingModels() ([]kbtypes.EmbeddingModel, error) {
+	rows, err := d.db.Query("SELECT provider, model, dimensions FROM embedding_models ORDER BY provider")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var models []kbtypes.EmbeddingModel
+	for rows.Next() {
+		var m kbtypes.EmbeddingModel
+		if err := rows.Scan(&m.Provider, &m.Model, &m.Dimensions); err != nil {
+			return nil, err
+		}
+		models = append(models, m)
+	}
+	return models, rows.Err()
+}
+
 // FileNeedsProcessing checks if a file needs processing based on its checksum
 // Returns true if the file is new or changed, false if already processed
 func (d *Database) FileNeedsProcessing(checksum, projectName, projectVersion string) (bool, error) {
@@ -583,6 +657,10 @@ func (d *Database) DeleteChunksForFile(checksum, projectName, projectVersion str
 		return err
 	}
 
+	if err := deleteOrphanedPendingEmbeddings(tx); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -643,6 +721,10 @@ func (d *Database) CleanupStaleChunks(projectName, projectVersion string, validC
 		return err
 	}
 
+	if err := deleteOrphanedPendingEmbeddings(tx); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -654,6 +736,13 @@ func (d *Database) CleanupStaleChunks(projectName, projectVersion string, validC
 	return nil
 }
 
+// deleteOrphanedPendingEmbeddings deletes the pending embeddings of deleted
+// chunks
+func deleteOrphanedPendingEmbeddings(tx *sql.Tx) error {
+	_, err := tx.Exec(`DELETE FROM pending_embeddings WHERE chunk_id NOT IN (SELECT id FROM chunks)`)
+	return err
+}
+
 // joinStrings is a helper to join strings with a separator
 func joinStrings(strs []string, sep string) string {
 	if len(strs) == 0 {
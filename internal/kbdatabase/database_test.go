@@ -285,3 +285,155 @@ func TestTransactionRollback(t *testing.T) {
 	// In a real scenario with mock errors, this would test rollback
 	_, _ = initialCount, finalCount // Suppress unused variable warnings
 }
+
+func TestVectorLiteral(t *testing.T) {
+	if got := vectorLiteral(nil); got != nil {
+		t.Errorf("expected NULL for an empty embedding, got %v", got)
+	}
+
+	literal := vectorLiteral([]float32{0.1, -2, 3.5e-7})
+	if literal != "[0.1,-2,3.5e-07]" {
+		t.Fatalf("unexpected literal %v", literal)
+	}
+	s := literal.(string)
+	got := parseVector(&s)
+	want := []float32{0.1, -2, 3.5e-7}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("element %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+
+	for _, text := range []string{"[]", "[1,x]"} {
+		text := text
+		if got := parseVector(&text); got != nil {
+			t.Errorf("parseVector(%q) = %v, want nil", text, got)
+		}
+	}
+	if got := parseVector(nil); got != nil {
+		t.Errorf("expected nil for NULL, got %v", got)
+	}
+}
+
+func TestEmbeddingModels(t *testing.T) {
+	tmpFile := t.TempDir() + "/test.db"
+
+	db, err := Open(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetEmbeddingModel(kbtypes.EmbeddingModel{Provider: "openai", Model: "text-embedding-3-small", Dimensions: 1536}); err != nil {
+		t.Fatalf("Failed to set model: %v", err)
+	}
+	if err := db.SetEmbeddingModel(kbtypes.EmbeddingModel{Provider: "ollama", Model: "old-model", Dimensions: 384}); err != nil {
+		t.Fatalf("Failed to set model: %v", err)
+	}
+	if err := db.SetEmbeddingModel(kbtypes.EmbeddingModel{Provider: "ollama", Model: "nomic-embed-text", Dimensions: 768}); err != nil {
+		t.Fatalf("Failed to replace model: %v", err)
+	}
+
+	models, err := db.GetEmbeddingModels()
+	if err != nil {
+		t.Fatalf("Failed to get models: %v", err)
+	}
+	want := []kbtypes.EmbeddingModel{
+		{Provider: "ollama", Model: "nomic-embed-text", Dimensions: 768},
+		{Provider: "openai", Model: "text-embedding-3-small", Dimensions: 1536},
+	}
+	if len(models) != len(want) {
+		t.Fatalf("Expected %d models, got %+v", len(want), models)
+	}
+	for i := range want {
+		if models[i] != want[i] {
+			t.Errorf("models[%d] = %+v, want %+v", i, models[i], want[i])
+		}
+	}
+
+	// Clearing a provider's embeddings forgets its model
+	if _, err := db.ClearEmbeddings("ollama"); err != nil {
+		t.Fatalf("Failed to clear embeddings: %v", err)
+	}
+	models, err = db.GetEmbeddingModels()
+	if err != nil {
+		t.Fatalf("Failed to get models: %v", err)
+	}
+	if len(models) != 1 || models[0].Provider != "openai" {
+		t.Errorf("Expected only the openai model after clearing, got %+v", models)
+	}
+}
+
+func TestPendingEmbeddings(t *testing.T) {
+	tmpFile := t.TempDir() + "/test.db"
+
+	db, err := Open(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	chunks := []*kbtypes.Chunk{
+		{Text: "first", ProjectName: "PostgreSQL", ProjectVersion: "17", SourceFileChecksum: "a"},
+		{Text: "second", ProjectName: "PostgreSQL", ProjectVersion: "17", SourceFileChecksum: "a"},
+		{Text: "third", ProjectName: "PostgreSQL", ProjectVersion: "17", SourceFileChecksum: "b"},
+	}
+	if err := db.InsertChunks(chunks); err != nil {
+		t.Fatalf("Failed to insert chunks: %v", err)
+	}
+	for i, chunk := range chunks {
+		if chunk.ID == 0 {
+			t.Fatalf("Expected chunk %d to have an ID after insert", i)
+		}
+	}
+
+	if err := db.AddPendingEmbeddings("OpenAI", chunks); err != nil {
+		t.Fatalf("Failed to add pending embeddings: %v", err)
+	}
+	// Adding the same chunks again is harmless
+	if err := db.AddPendingEmbeddings("openai", chunks[:1]); err != nil {
+		t.Fatalf("Failed to add pending embeddings again: %v", err)
+	}
+	if err := db.AddPendingEmbeddings("unknown", chunks); err == nil {
+		t.Error("Expected an invalid provider to be rejected")
+	}
+
+	pending, err := db.GetPendingEmbeddings("openai")
+	if err != nil {
+		t.Fatalf("Failed to get pending embeddings: %v", err)
+	}
+	if len(pending) != 3 || pending[0].Text != "first" {
+		t.Fatalf("Expected 3 pending chunks in ID order, got %+v", pending)
+	}
+	if pending, err := db.GetPendingEmbeddings("voyage"); err != nil || len(pending) != 0 {
+		t.Errorf("Expected no pending voyage embeddings, got %d (%v)", len(pending), err)
+	}
+
+	// Saving a batch clears its pending records
+	chunks[0].OpenAIEmbedding = []float32{0.1, 0.2}
+	if err := db.UpdateOpenAIEmbeddings(chunks[:1]); err != nil {
+		t.Fatalf("Failed to update embeddings: %v", err)
+	}
+	pending, err = db.GetPendingEmbeddings("openai")
+	if err != nil {
+		t.Fatalf("Failed to get pending embeddings: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != chunks[1].ID {
+		t.Fatalf("Expected the saved chunk to be done, got %+v", pending)
+	}
+
+	// Removing stale chunks removes their pending records
+	if err := db.CleanupStaleChunks("PostgreSQL", "17", []string{"a"}); err != nil {
+		t.Fatalf("Failed to clean up stale chunks: %v", err)
+	}
+	var remaining int
+	if err := db.db.QueryRow("SELECT count(*) FROM pending_embeddings").Scan(&remaining); err != nil {
+		t.Fatalf("Failed to count pending embeddings: %v", err)
+	}
+	if remaining != 1 {
+		t.Errorf("Expected 1 pending record after cleanup, got %d", remaining)
+	}
+}
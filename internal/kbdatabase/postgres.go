@@ -0,0 +1,543 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package kbdatabase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/kbtypes"
+)
+
+// DefaultSchema is the schema holding a PostgreSQL knowledgebase
+const DefaultSchema = "pgedge_kb"
+
+// PostgresDatabase stores the knowledgebase in a PostgreSQL database, with
+// embeddings in pgvector columns. The tables mirror the SQLite layout.
+type PostgresDatabase struct {
+	pool   *pgxpool.Pool
+	schema string
+}
+
+// OpenPostgres connects to a PostgreSQL knowledgebase, creating the schema
+// and tables if they don't exist. The pgvector extension is created if it
+// isn't installed, which requires the privilege to do so.
+func OpenPostgres(connString, schema string) (*PostgresDatabase, error) {
+	if schema == "" {
+		schema = DefaultSchema
+	}
+
+	ctx := context.Background()
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection string: %w", err)
+	}
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = "pgedge-kb-builder"
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	d := &PostgresDatabase{pool: pool, schema: schema}
+	if err := d.createSchema(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	return d, nil
+}
+
+// Close closes the connection pool
+func (d *PostgresDatabase) Close() error {
+	d.pool.Close()
+	return nil
+}
+
+// table returns the quoted name of a knowledgebase table
+func (d *PostgresDatabase) table(name string) string {
+	return pgx.Identifier{d.schema, name}.Sanitize()
+}
+
+// createSchema creates the knowledgebase schema and tables
+func (d *PostgresDatabase) createSchema(ctx context.Context) error {
+	if _, err := d.pool.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS vector"); err != nil {
+		return fmt.Errorf("the pgvector extension is required: %w", err)
+	}
+
+	statements := []string{
+		"CREATE SCHEMA IF NOT EXISTS " + pgx.Identifier{d.schema}.Sanitize(),
+		`CREATE TABLE IF NOT EXISTS ` + d.table("chunks") + ` (
+            id bigserial PRIMARY KEY,
+            text text NOT NULL,
+            title text,
+            section text,
+            project_name text NOT NULL,
+            project_version text NOT NULL,
+            file_path text,
+            source_file_checksum text,
+
+            -- Embeddings from different providers
+            openai_embedding vector,
+            voyage_embedding vector,
+            ollama_embedding vector,
+
+            created_at timestamptz NOT NULL DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS ` + d.table("source_files") + ` (
+            checksum text NOT NULL,
+            file_path text NOT NULL,
+            project_name text NOT NULL,
+            project_version text NOT NULL,
+            doc_type text,
+            num_chunks integer NOT NULL DEFAULT 0,
+            processed_at timestamptz NOT NULL DEFAULT now(),
+
+            PRIMARY KEY (checksum, project_name, project_version)
+        )`,
+		`CREATE TABLE IF NOT EXISTS ` + d.table("embedding_models") + ` (
+            provider text PRIMARY KEY,
+            model text NOT NULL,
+            dimensions integer NOT NULL,
+            updated_at timestamptz NOT NULL DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS ` + d.table("pending_embeddings") + ` (
+            provider text NOT NULL,
+            chunk_id bigint NOT NULL REFERENCES ` + d.table("chunks") + ` (id) ON DELETE CASCADE,
+
+            PRIMARY KEY (provider, chunk_id)
+        )`,
+		"CREATE INDEX IF NOT EXISTS chunks_project_idx ON " + d.table("chunks") + " (project_name, project_version)",
+		"CREATE INDEX IF NOT EXISTS chunks_source_checksum_idx ON " + d.table("chunks") + " (source_file_checksum)",
+	}
+	for _, stmt := range statements {
+		if _, err := d.pool.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertChunks inserts chunks into the database and records source file metadata
+func (d *PostgresDatabase) InsertChunks(chunks []*kbtypes.Chunk) error {
+	ctx := context.Background()
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // No-op after commit
+
+	insert := `INSERT INTO ` + d.table("chunks") + ` (
+            text, title, section, project_name, project_version, file_path, source_file_checksum,
+            openai_embedding, voyage_embedding, ollama_embedding
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8::vector, $9::vector, $10::vector)
+        RETURNING id`
+
+	batch := &pgx.Batch{}
+	sourceFileChunks := make(map[string]int)
+	sourceFileInfo := make(map[string]*kbtypes.Chunk)
+	var sourceFileKeys []string
+	for _, chunk := range chunks {
+		batch.Queue(insert,
+			chunk.Text, chunk.Title, chunk.Section, chunk.ProjectName, chunk.ProjectVersion,
+			chunk.FilePath, chunk.SourceFileChecksum,
+			vectorLiteral(chunk.OpenAIEmbedding),
+			vectorLiteral(chunk.VoyageEmbedding),
+			vectorLiteral(chunk.OllamaEmbedding),
+		)
+
+		if chunk.SourceFileChecksum != "" {
+			key := chunk.SourceFileChecksum + "|" + chunk.ProjectName + "|" + chunk.ProjectVersion
+			if _, exists := sourceFileInfo[key]; !exists {
+				sourceFileInfo[key] = chunk
+				sourceFileKeys = append(sourceFileKeys, key)
+			}
+			sourceFileChunks[key]++
+		}
+	}
+
+	for _, key := range sourceFileKeys {
+		chunk := sourceFileInfo[key]
+		batch.Queue(`INSERT INTO `+d.table("source_files")+` (checksum, file_path, project_name, project_version, num_chunks)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT (checksum, project_name, project_version)
+            DO UPDATE SET num_chunks = source_files.num_chunks + EXCLUDED.num_chunks, processed_at = now()`,
+			chunk.SourceFileChecksum, chunk.FilePath, chunk.ProjectName, chunk.ProjectVersion, sourceFileChunks[key])
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	ids := make([]int64, len(chunks))
+	for i := range chunks {
+		if err := results.QueryRow().Scan(&ids[i]); err != nil {
+			results.Close()
+			return fmt.Errorf("failed to insert chunk %d: %w", i, err)
+		}
+	}
+	for i := len(chunks); i < batch.Len(); i++ {
+		if _, err := results.Exec(); err != nil {
+			results.Close()
+			return fmt.Errorf("failed to insert source file record: %w", err)
+		}
+	}
+	if err := results.Close(); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for i, chunk := range chunks {
+		chunk.ID = int(ids[i])
+	}
+	return nil
+}
+
+// GetStats returns statistics about the database
+func (d *PostgresDatabase) GetStats() (map[string]interface{}, error) {
+	ctx := context.Background()
+	stats := make(map[string]interface{})
+
+	var totalChunks int
+	if err := d.pool.QueryRow(ctx, "SELECT count(*) FROM "+d.table("chunks")).Scan(&totalChunks); err != nil {
+		return nil, err
+	}
+	stats["total_chunks"] = totalChunks
+
+	rows, err := d.pool.Query(ctx, `
+        SELECT project_name, project_version, count(*)
+        FROM `+d.table("chunks")+`
+        GROUP BY project_name, project_version
+        ORDER BY project_name, project_version
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var name, version string
+		var count int
+		if err := rows.Scan(&name, &version, &count); err != nil {
+			return nil, err
+		}
+		projects = append(projects, map[string]interface{}{
+			"name":    name,
+			"version": version,
+			"chunks":  count,
+		})
+	}
+	stats["projects"] = projects
+
+	return stats, rows.Err()
+}
+
+// GetAllChunks retrieves all chunks from the database
+func (d *PostgresDatabase) GetAllChunks() ([]*kbtypes.Chunk, error) {
+	return d.queryChunks(`
+        SELECT id, text, coalesce(title, ''), coalesce(section, ''), project_name, project_version,
+               coalesce(file_path, ''), coalesce(source_file_checksum, ''),
+               openai_embedding::text, voyage_embedding::text, ollama_embedding::text
+        FROM ` + d.table("chunks") + `
+        ORDER BY id
+    `)
+}
+
+// GetPendingEmbeddings retrieves the chunks still waiting for a provider's
+// embedding
+func (d *PostgresDatabase) GetPendingEmbeddings(provider string) ([]*kbtypes.Chunk, error) {
+	if _, err := embeddingColumn(provider); err != nil {
+		return nil, err
+	}
+	return d.queryChunks(`
+        SELECT c.id, c.text, coalesce(c.title, ''), coalesce(c.section, ''), c.project_name, c.project_version,
+               coalesce(c.file_path, ''), coalesce(c.source_file_checksum, ''),
+               c.openai_embedding::text, c.voyage_embedding::text, c.ollama_embedding::text
+        FROM `+d.table("chunks")+` c
+        JOIN `+d.table("pending_embeddings")+` p ON p.chunk_id = c.id
+        WHERE p.provider = $1
+        ORDER BY c.id
+    `, strings.ToLower(provider))
+}
+
+// AddPendingEmbeddings records that stored chunks are waiting for a
+// provider's embedding
+func (d *PostgresDatabase) AddPendingEmbeddings(provider string, chunks []*kbtypes.Chunk) error {
+	if _, err := embeddingColumn(provider); err != nil {
+		return err
+	}
+	ids := make([]int64, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = int64(chunk.ID)
+	}
+	_, err := d.pool.Exec(context.Background(), `
+        INSERT INTO `+d.table("pending_embeddings")+` (provider, chunk_id)
+        SELECT $1, unnest($2::bigint[])
+        ON CONFLICT DO NOTHING
+    `, strings.ToLower(provider), ids)
+	return err
+}
+
+// queryChunks runs a query selecting chunks with the columns of GetAllChunks
+func (d *PostgresDatabase) queryChunks(query string, args ...interface{}) ([]*kbtypes.Chunk, error) {
+	rows, err := d.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*kbtypes.Chunk
+	for rows.Next() {
+		var id int64
+		var openai, voyage, ollama *string
+		chunk := &kbtypes.Chunk{}
+		err := rows.Scan(&id, &chunk.Text, &chunk.Title, &chunk.Section, &chunk.ProjectName,
+			&chunk.ProjectVersion, &chunk.FilePath, &chunk.SourceFileChecksum, &openai, &voyage, &ollama)
+		if err != nil {
+			return nil, err
+		}
+		chunk.ID = int(id)
+		chunk.OpenAIEmbedding = parseVector(openai)
+		chunk.VoyageEmbedding = parseVector(voyage)
+		chunk.OllamaEmbedding = parseVector(ollama)
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, rows.Err()
+}
+
+// UpdateOpenAIEmbeddings updates only OpenAI embeddings for existing chunks
+func (d *PostgresDatabase) UpdateOpenAIEmbeddings(chunks []*kbtypes.Chunk) error {
+	return d.updateEmbeddings("openai", chunks, func(c *kbtypes.Chunk) []float32 { return c.OpenAIEmbedding })
+}
+
+// UpdateVoyageEmbeddings updates only Voyage embeddings for existing chunks
+func (d *PostgresDatabase) UpdateVoyageEmbeddings(chunks []*kbtypes.Chunk) error {
+	return d.updateEmbeddings("voyage", chunks, func(c *kbtypes.Chunk) []float32 { return c.VoyageEmbedding })
+}
+
+// UpdateOllamaEmbeddings updates only Ollama embeddings for existing chunks
+func (d *PostgresDatabase) UpdateOllamaEmbeddings(chunks []*kbtypes.Chunk) error {
+	return d.updateEmbeddings("ollama", chunks, func(c *kbtypes.Chunk) []float32 { return c.OllamaEmbedding })
+}
+
+// updateEmbeddings sets one provider's embeddings of existing chunks and
+// clears their pending records, so the batch is checkpointed atomically
+func (d *PostgresDatabase) updateEmbeddings(provider string, chunks []*kbtypes.Chunk, embedding func(*kbtypes.Chunk) []float32) error {
+	column, err := embeddingColumn(provider)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // No-op after commit
+
+	update := "UPDATE " + d.table("chunks") + " SET " + column + " = $1::vector WHERE id = $2"
+	done := "DELETE FROM " + d.table("pending_embeddings") + " WHERE provider = $1 AND chunk_id = $2"
+	batch := &pgx.Batch{}
+	for _, chunk := range chunks {
+		batch.Queue(update, vectorLiteral(embedding(chunk)), chunk.ID)
+		batch.Queue(done, provider, chunk.ID)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ClearEmbeddings clears all embeddings for a specific provider
+func (d *PostgresDatabase) ClearEmbeddings(provider string) (int64, error) {
+	column, err := embeddingColumn(provider)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := d.pool.Exec(context.Background(),
+		"UPDATE "+d.table("chunks")+" SET "+column+" = NULL WHERE "+column+" IS NOT NULL")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := d.pool.Exec(context.Background(),
+		"DELETE FROM "+d.table("embedding_models")+" WHERE provider = $1", strings.ToLower(provider)); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// SetEmbeddingModel records the model that produced a provider's embeddings
+func (d *PostgresDatabase) SetEmbeddingModel(model kbtypes.EmbeddingModel) error {
+	_, err := d.pool.Exec(context.Background(), `
+        INSERT INTO `+d.table("embedding_models")+` (provider, model, dimensions, updated_at)
+        VALUES ($1, $2, $3, now())
+        ON CONFLICT (provider) DO UPDATE
+        SET model = excluded.model, dimensions = excluded.dimensions, updated_at = excluded.updated_at
+    `, model.Provider, model.Model, model.Dimensions)
+	return err
+}
+
+// GetEmbeddingModels returns the recorded embedding models, by provider
+func (d *PostgresDatabase) GetEmbeddingModels() ([]kbtypes.EmbeddingModel, error) {
+	rows, err := d.pool.Query(context.Background(),
+		"SELECT provider, model, dimensions FROM "+d.table("embedding_models")+" ORDER BY provider")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (kbtypes.EmbeddingModel, error) {
+		var m kbtypes.EmbeddingModel
+		err := row.Scan(&m.Provider, &m.Model, &m.Dimensions)
+		return m, err
+	})
+}
+
+// FileNeedsProcessing checks if a file needs processing based on its checksum
+// Returns true if the file is new or changed, false if already processed
+func (d *PostgresDatabase) FileNeedsProcessing(checksum, projectName, projectVersion string) (bool, error) {
+	var exists bool
+	err := d.pool.QueryRow(context.Background(), `
+        SELECT EXISTS (
+            SELECT 1 FROM `+d.table("source_files")+`
+            WHERE checksum = $1 AND project_name = $2 AND project_version = $3
+        )
+    `, checksum, projectName, projectVersion).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
+
+// GetChunksForChecksum retrieves existing chunks for a given checksum from a different project/version
+// This enables deduplication across versions
+func (d *PostgresDatabase) GetChunksForChecksum(checksum string) ([]*kbtypes.Chunk, error) {
+	rows, err := d.pool.Query(context.Background(), `
+        SELECT text, coalesce(title, ''), coalesce(section, ''), coalesce(file_path, ''),
+               openai_embedding::text, voyage_embedding::text, ollama_embedding::text
+        FROM `+d.table("chunks")+`
+        WHERE source_file_checksum = $1
+        ORDER BY id
+        LIMIT 1000
+    `, checksum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*kbtypes.Chunk
+	for rows.Next() {
+		var openai, voyage, ollama *string
+		chunk := &kbtypes.Chunk{SourceFileChecksum: checksum}
+		err := rows.Scan(&chunk.Text, &chunk.Title, &chunk.Section, &chunk.FilePath, &openai, &voyage, &ollama)
+		if err != nil {
+			return nil, err
+		}
+		chunk.OpenAIEmbedding = parseVector(openai)
+		chunk.VoyageEmbedding = parseVector(voyage)
+		chunk.OllamaEmbedding = parseVector(ollama)
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, rows.Err()
+}
+
+// CleanupStaleChunks removes chunks for files that no longer exist in the current processing run
+func (d *PostgresDatabase) CleanupStaleChunks(projectName, projectVersion string, validChecksums []string) error {
+	if len(validChecksums) == 0 {
+		// If no checksums provided, don't delete anything (safety check)
+		return nil
+	}
+
+	ctx := context.Background()
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // No-op after commit
+
+	tag, err := tx.Exec(ctx, `
+        DELETE FROM `+d.table("chunks")+`
+        WHERE project_name = $1 AND project_version = $2
+        AND NOT (source_file_checksum = ANY($3))
+    `, projectName, projectVersion, validChecksums)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM `+d.table("source_files")+`
+        WHERE project_name = $1 AND project_version = $2
+        AND NOT (checksum = ANY($3))
+    `, projectName, projectVersion, validChecksums)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() > 0 {
+		fmt.Printf("  Cleaned up %d stale chunks from previous runs\n", tag.RowsAffected())
+	}
+
+	return nil
+}
+
+// vectorLiteral formats an embedding as a pgvector literal, or NULL if
+// there is no embedding
+func vectorLiteral(embedding []float32) interface{} {
+	if len(embedding) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, v := range embedding {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// parseVector parses a pgvector value in its text form
+func parseVector(text *string) []float32 {
+	if text == nil {
+		return nil
+	}
+	s := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(*text), "["), "]")
+	if s == "" {
+		return nil
+	}
+
+	parts := strings.Split(s, ",")
+	embedding := make([]float32, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil
+		}
+		embedding[i] = float32(v)
+	}
+	return embedding
+}
@@ -0,0 +1,60 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package kbdatabase
+
+import (
+	"fmt"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/kbtypes"
+)
+
+// Store is the knowledgebase storage used by kb-builder. Database stores
+// the knowledgebase in a SQLite file and PostgresDatabase in a PostgreSQL
+// database with the pgvector extension.
+//
+// Pending embeddings checkpoint embedding generation: they record, per
+// provider, the stored chunks still waiting for an embedding, and saving a
+// batch of a provider's embeddings clears the batch's pending records in the
+// same transaction. A run that is interrupted leaves exactly the unfinished
+// embeddings pending, for the next run to resume.
+type Store interface {
+	// InsertChunks stores chunks and sets their IDs
+	InsertChunks(chunks []*kbtypes.Chunk) error
+	GetStats() (map[string]interface{}, error)
+	GetAllChunks() ([]*kbtypes.Chunk, error)
+	UpdateOpenAIEmbeddings(chunks []*kbtypes.Chunk) error
+	UpdateVoyageEmbeddings(chunks []*kbtypes.Chunk) error
+	UpdateOllamaEmbeddings(chunks []*kbtypes.Chunk) error
+	ClearEmbeddings(provider string) (int64, error)
+	AddPendingEmbeddings(provider string, chunks []*kbtypes.Chunk) error
+	GetPendingEmbeddings(provider string) ([]*kbtypes.Chunk, error)
+	SetEmbeddingModel(model kbtypes.EmbeddingModel) error
+	GetEmbeddingModels() ([]kbtypes.EmbeddingModel, error)
+	FileNeedsProcessing(checksum, projectName, projectVersion string) (bool, error)
+	GetChunksForChecksum(checksum string) ([]*kbtypes.Chunk, error)
+	CleanupStaleChunks(projectName, projectVersion string, validChecksums []string) error
+	Close() error
+}
+
+var (
+	_ Store = (*Database)(nil)
+	_ Store = (*PostgresDatabase)(nil)
+)
+
+// embeddingColumn returns the chunks column holding a provider's embeddings
+func embeddingColumn(provider string) (string, error) {
+	switch strings.ToLower(provider) {
+	case "openai", "voyage", "ollama":
+		return strings.ToLower(provider) + "_embedding", nil
+	}
+	return "", fmt.Errorf("invalid provider: %s (must be openai, voyage, or ollama)", provider)
+}
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +24,7 @@ import (
 	"pgedge-postgres-mcp/internal/kbconfig"
 	"pgedge-postgres-mcp/internal/kbdatabase"
 	"pgedge-postgres-mcp/internal/kbtypes"
+	"pgedge-postgres-mcp/internal/netproxy"
 )
 
 const (
@@ -34,13 +36,14 @@ const (
 // EmbeddingGenerator generates embeddings using configured providers
 type EmbeddingGenerator struct {
 	config *kbconfig.Config
-	client *http.Client
-	db     *kbdatabase.Database
+	client *http.Client            // Default client (environment proxy settings)
+	byProv map[string]*http.Client // Per-provider clients honoring proxy configuration
+	db     kbdatabase.Store
 	dbMux  sync.Mutex // Protects database writes from concurrent providers
 }
 
 // NewEmbeddingGenerator creates a new embedding generator
-func NewEmbeddingGenerator(config *kbconfig.Config, db *kbdatabase.Database) *EmbeddingGenerator {
+func NewEmbeddingGenerator(config *kbconfig.Config, db kbdatabase.Store) *EmbeddingGenerator {
 	// Use longer timeout for Ollama (models may need initialization, slower processing)
 	// OpenAI/Voyage typically respond in seconds, but Ollama can take much longer
 	timeout := 5 * time.Minute
@@ -50,10 +53,23 @@ func NewEmbeddingGenerator(config *kbconfig.Config, db *kbdatabase.Database) *Em
 		client: &http.Client{
 			Timeout: timeout,
 		},
+		byProv: map[string]*http.Client{
+			netproxy.ProviderOpenAI: netproxy.NewClient(netproxy.ProviderOpenAI, timeout),
+			netproxy.ProviderVoyage: netproxy.NewClient(netproxy.ProviderVoyage, timeout),
+			netproxy.ProviderOllama: netproxy.NewClient(netproxy.ProviderOllama, timeout),
+		},
 		db: db,
 	}
 }
 
+// httpClient returns the HTTP client to use for the given provider
+func (eg *EmbeddingGenerator) httpClient(provider string) *http.Client {
+	if c, ok := eg.byProv[provider]; ok {
+		return c
+	}
+	return eg.client
+}
+
 // GenerateEmbeddings generates embeddings for all chunks using all enabled providers in parallel
 // Returns a map of provider names to errors (if any), but does not fail on individual provider errors
 func (eg *EmbeddingGenerator) GenerateEmbeddings(chunks []*kbtypes.Chunk) map[string]error {
@@ -67,6 +83,7 @@ func (eg *EmbeddingGenerator) GenerateEmbeddings(chunks []*kbtypes.Chunk) map[st
 	resultChan := make(chan providerResult, 3)
 
 	startTime := time.Now()
+	recorded := eg.recordedModels()
 
 	// Generate embeddings for each provider in parallel
 	if eg.config.Embeddings.OpenAI.Enabled {
@@ -75,11 +92,22 @@ func (eg *EmbeddingGenerator) GenerateEmbeddings(chunks []*kbtypes.Chunk) map[st
 			defer wg.Done()
 			fmt.Printf("Starting OpenAI embeddings...\n")
 			providerStart := time.Now()
+			model := eg.config.Embeddings.OpenAI.Model
+			if err := eg.checkModel(netproxy.ProviderOpenAI, model, recorded); err != nil {
+				fmt.Printf("⚠️  OpenAI embeddings skipped: %v\n", err)
+				resultChan <- providerResult{"OpenAI", err}
+				return
+			}
 			if err := eg.generateOpenAIEmbeddings(chunks); err != nil {
 				fmt.Printf("⚠️  OpenAI embeddings failed: %v\n", err)
 				resultChan <- providerResult{"OpenAI", err}
 				return
 			}
+			if err := eg.recordModel(netproxy.ProviderOpenAI, model, chunks, func(c *kbtypes.Chunk) []float32 { return c.OpenAIEmbedding }); err != nil {
+				fmt.Printf("⚠️  OpenAI embedding model could not be recorded: %v\n", err)
+				resultChan <- providerResult{"OpenAI", err}
+				return
+			}
 			fmt.Printf("✓ OpenAI embeddings completed in %.2fs\n", time.Since(providerStart).Seconds())
 			resultChan <- providerResult{"OpenAI", nil}
 		}()
@@ -91,11 +119,22 @@ func (eg *EmbeddingGenerator) GenerateEmbeddings(chunks []*kbtypes.Chunk) map[st
 			defer wg.Done()
 			fmt.Printf("Starting Voyage embeddings...\n")
 			providerStart := time.Now()
+			model := eg.config.Embeddings.Voyage.Model
+			if err := eg.checkModel(netproxy.ProviderVoyage, model, recorded); err != nil {
+				fmt.Printf("⚠️  Voyage embeddings skipped: %v\n", err)
+				resultChan <- providerResult{"Voyage", err}
+				return
+			}
 			if err := eg.generateVoyageEmbeddings(chunks); err != nil {
 				fmt.Printf("⚠️  Voyage embeddings failed: %v\n", err)
 				resultChan <- providerResult{"Voyage", err}
 				return
 			}
+			if err := eg.recordModel(netproxy.ProviderVoyage, model, chunks, func(c *kbtypes.Chunk) []float32 { return c.VoyageEmbedding }); err != nil {
+				fmt.Printf("⚠️  Voyage embedding model could not be recorded: %v\n", err)
+				resultChan <- providerResult{"Voyage", err}
+				return
+			}
 			fmt.Printf("✓ Voyage embeddings completed in %.2fs\n", time.Since(providerStart).Seconds())
 			resultChan <- providerResult{"Voyage", nil}
 		}()
@@ -107,11 +146,22 @@ func (eg *EmbeddingGenerator) GenerateEmbeddings(chunks []*kbtypes.Chunk) map[st
 			defer wg.Done()
 			fmt.Printf("Starting Ollama embeddings...\n")
 			providerStart := time.Now()
+			model := eg.config.Embeddings.Ollama.Model
+			if err := eg.checkModel(netproxy.ProviderOllama, model, recorded); err != nil {
+				fmt.Printf("⚠️  Ollama embeddings skipped: %v\n", err)
+				resultChan <- providerResult{"Ollama", err}
+				return
+			}
 			if err := eg.generateOllamaEmbeddings(chunks); err != nil {
 				fmt.Printf("⚠️  Ollama embeddings failed: %v\n", err)
 				resultChan <- providerResult{"Ollama", err}
 				return
 			}
+			if err := eg.recordModel(netproxy.ProviderOllama, model, chunks, func(c *kbtypes.Chunk) []float32 { return c.OllamaEmbedding }); err != nil {
+				fmt.Printf("⚠️  Ollama embedding model could not be recorded: %v\n", err)
+				resultChan <- providerResult{"Ollama", err}
+				return
+			}
 			fmt.Printf("✓ Ollama embeddings completed in %.2fs\n", time.Since(providerStart).Seconds())
 			resultChan <- providerResult{"Ollama", nil}
 		}()
@@ -134,6 +184,123 @@ func (eg *EmbeddingGenerator) GenerateEmbeddings(chunks []*kbtypes.Chunk) map[st
 	return errors
 }
 
+// providerEmbedding is an enabled provider, with the chunk field holding
+// its embeddings
+type providerEmbedding struct {
+	name      string
+	embedding func(*kbtypes.Chunk) []float32
+}
+
+// enabledProviders returns the enabled embedding providers
+func (eg *EmbeddingGenerator) enabledProviders() []providerEmbedding {
+	var providers []providerEmbedding
+	if eg.config.Embeddings.OpenAI.Enabled {
+		providers = append(providers, providerEmbedding{netproxy.ProviderOpenAI, func(c *kbtypes.Chunk) []float32 { return c.OpenAIEmbedding }})
+	}
+	if eg.config.Embeddings.Voyage.Enabled {
+		providers = append(providers, providerEmbedding{netproxy.ProviderVoyage, func(c *kbtypes.Chunk) []float32 { return c.VoyageEmbedding }})
+	}
+	if eg.config.Embeddings.Ollama.Enabled {
+		providers = append(providers, providerEmbedding{netproxy.ProviderOllama, func(c *kbtypes.Chunk) []float32 { return c.OllamaEmbedding }})
+	}
+	return providers
+}
+
+// MarkPending records in the database that stored chunks are waiting for
+// the embeddings of each enabled provider they lack. Chunks without text are
+// never embedded, so they are not marked.
+func (eg *EmbeddingGenerator) MarkPending(chunks []*kbtypes.Chunk) error {
+	for _, provider := range eg.enabledProviders() {
+		var pending []*kbtypes.Chunk
+		for _, chunk := range chunks {
+			if len(provider.embedding(chunk)) == 0 && strings.TrimSpace(chunk.Text) != "" {
+				pending = append(pending, chunk)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		if err := eg.db.AddPendingEmbeddings(provider.name, pending); err != nil {
+			return fmt.Errorf("failed to record pending %s embeddings: %w", provider.name, err)
+		}
+	}
+	return nil
+}
+
+// PendingChunks loads the stored chunks that are waiting for an embedding
+// of any enabled provider, in ID order
+func (eg *EmbeddingGenerator) PendingChunks() ([]*kbtypes.Chunk, error) {
+	byID := make(map[int]*kbtypes.Chunk)
+	for _, provider := range eg.enabledProviders() {
+		chunks, err := eg.db.GetPendingEmbeddings(provider.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pending %s embeddings: %w", provider.name, err)
+		}
+		for _, chunk := range chunks {
+			byID[chunk.ID] = chunk
+		}
+	}
+
+	chunks := make([]*kbtypes.Chunk, 0, len(byID))
+	for _, chunk := range byID {
+		chunks = append(chunks, chunk)
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ID < chunks[j].ID })
+	return chunks, nil
+}
+
+// recordedModels returns the embedding models recorded in the database, by
+// provider. Databases without recorded models (built by older versions)
+// return none.
+func (eg *EmbeddingGenerator) recordedModels() map[string]string {
+	models := make(map[string]string)
+	if eg.db == nil {
+		return models
+	}
+	recorded, err := eg.db.GetEmbeddingModels()
+	if err != nil {
+		return models
+	}
+	for _, m := range recorded {
+		models[m.Provider] = m.Model
+	}
+	return models
+}
+
+// checkModel refuses to add embeddings from a different model than the one
+// that produced the provider's existing embeddings, as they would not be
+// comparable at search time
+func (eg *EmbeddingGenerator) checkModel(provider, model string, recorded map[string]string) error {
+	existing, ok := recorded[provider]
+	if !ok || existing == model {
+		return nil
+	}
+	return fmt.Errorf("the database contains %s embeddings from model %q, but %q is configured; "+
+		"run with --clear-embeddings %s to re-embed with the new model", provider, existing, model, provider)
+}
+
+// recordModel records the model that produced a provider's embeddings, with
+// the dimensions of the generated vectors
+func (eg *EmbeddingGenerator) recordModel(provider, model string, chunks []*kbtypes.Chunk, embedding func(*kbtypes.Chunk) []float32) error {
+	if eg.db == nil {
+		return nil
+	}
+	dimensions := 0
+	for _, chunk := range chunks {
+		if vec := embedding(chunk); len(vec) > 0 {
+			dimensions = len(vec)
+			break
+		}
+	}
+	if dimensions == 0 {
+		return nil
+	}
+
+	eg.dbMux.Lock()
+	defer eg.dbMux.Unlock()
+	return eg.db.SetEmbeddingModel(kbtypes.EmbeddingModel{Provider: provider, Model: model, Dimensions: dimensions})
+}
+
 // retryWithBackoff executes a function with exponential backoff retry logic
 func retryWithBackoff(operation string, fn func() (*http.Response, error)) (*http.Response, error) {
 	var lastErr error
@@ -207,9 +374,17 @@ type openAIEmbeddingResponse struct {
 	} `json:"data"`
 }
 
+// batchSize returns the number of chunks to send in each embedding request
+func (eg *EmbeddingGenerator) batchSize() int {
+	if eg.config.Embeddings.BatchSize > 0 {
+		return eg.config.Embeddings.BatchSize
+	}
+	return kbconfig.DefaultBatchSize
+}
+
 // generateOpenAIEmbeddings generates embeddings using OpenAI
 func (eg *EmbeddingGenerator) generateOpenAIEmbeddings(chunks []*kbtypes.Chunk) error {
-	const batchSize = 100 // OpenAI allows up to 2048, but we'll be conservative
+	batchSize := eg.batchSize() // OpenAI allows up to 2048
 	config := eg.config.Embeddings.OpenAI
 
 	// Filter chunks that need OpenAI embeddings
@@ -279,7 +454,7 @@ func (eg *EmbeddingGenerator) generateOpenAIEmbeddings(chunks []*kbtypes.Chunk)
 			}
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", "Bearer "+config.APIKey)
-			return eg.client.Do(req)
+			return eg.httpClient(netproxy.ProviderOpenAI).Do(req)
 		})
 		if err != nil {
 			return fmt.Errorf("failed to make request: %w", err)
@@ -332,7 +507,7 @@ type voyageEmbeddingResponse struct {
 
 // generateVoyageEmbeddings generates embeddings using Voyage AI
 func (eg *EmbeddingGenerator) generateVoyageEmbeddings(chunks []*kbtypes.Chunk) error {
-	const batchSize = 100
+	batchSize := eg.batchSize()
 	config := eg.config.Embeddings.Voyage
 
 	// Filter chunks that need Voyage embeddings
@@ -401,7 +576,7 @@ func (eg *EmbeddingGenerator) generateVoyageEmbeddings(chunks []*kbtypes.Chunk)
 			}
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", "Bearer "+config.APIKey)
-			return eg.client.Do(req)
+			return eg.httpClient(netproxy.ProviderVoyage).Do(req)
 		})
 		if err != nil {
 			return fmt.Errorf("failed to make request: %w", err)
@@ -443,18 +618,19 @@ func (eg *EmbeddingGenerator) generateVoyageEmbeddings(chunks []*kbtypes.Chunk)
 // Ollama API structures
 type ollamaEmbeddingRequest struct {
 	Model   string                 `json:"model"`
-	Prompt  string                 `json:"prompt"`
+	Input   []string               `json:"input"`
 	Options map[string]interface{} `json:"options,omitempty"`
 }
 
 type ollamaEmbeddingResponse struct {
-	Embedding []float32 `json:"embedding"`
+	Embeddings [][]float32 `json:"embeddings"`
 }
 
 // generateOllamaEmbeddings generates embeddings using Ollama
 func (eg *EmbeddingGenerator) generateOllamaEmbeddings(chunks []*kbtypes.Chunk) error {
+	batchSize := eg.batchSize()
 	config := eg.config.Embeddings.Ollama
-	endpoint := config.Endpoint + "/api/embeddings"
+	endpoint := config.Endpoint + "/api/embed"
 
 	// Filter chunks that need Ollama embeddings
 	var chunksToProcess []*kbtypes.Chunk
@@ -476,15 +652,18 @@ func (eg *EmbeddingGenerator) generateOllamaEmbeddings(chunks []*kbtypes.Chunk)
 		fmt.Printf("  Ollama: Processing %d chunks\n", len(chunksToProcess))
 	}
 
-	// Ollama processes one at a time, save every 50 chunks
-	const saveInterval = 50
-	var pendingSave []*kbtypes.Chunk
+	for i := 0; i < len(chunksToProcess); i += batchSize {
+		end := min(i+batchSize, len(chunksToProcess))
+		batch := chunksToProcess[i:end]
 
-	for i, chunk := range chunksToProcess {
+		texts := make([]string, len(batch))
+		for j, chunk := range batch {
+			texts[j] = chunk.Text
+		}
 
 		reqBody := ollamaEmbeddingRequest{
-			Model:  config.Model,
-			Prompt: chunk.Text,
+			Model: config.Model,
+			Input: texts,
 			Options: map[string]interface{}{
 				"num_ctx": config.ContextLength,
 			},
@@ -496,22 +675,22 @@ func (eg *EmbeddingGenerator) generateOllamaEmbeddings(chunks []*kbtypes.Chunk)
 		}
 
 		// Make API request with retry logic
-		operation := fmt.Sprintf("Ollama chunk %d/%d", i+1, len(chunksToProcess))
+		operation := fmt.Sprintf("Ollama batch %d-%d", i+1, end)
 		resp, err := retryWithBackoff(operation, func() (*http.Response, error) {
 			req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
 			if err != nil {
 				return nil, err
 			}
 			req.Header.Set("Content-Type", "application/json")
-			return eg.client.Do(req)
+			return eg.httpClient(netproxy.ProviderOllama).Do(req)
 		})
 		if err != nil {
-			// Log details about the failing chunk
-			fmt.Printf("\n  ❌ Failed chunk details:\n")
-			fmt.Printf("     File: %s\n", chunk.FilePath)
-			fmt.Printf("     Section: %s\n", chunk.Section)
-			fmt.Printf("     Chars: %d, Words: %d\n", len(chunk.Text), len(strings.Fields(chunk.Text)))
-			fmt.Printf("     Preview: %.200s...\n", chunk.Text)
+			// Log details about the files in the failing batch
+			fmt.Printf("\n  ❌ Failed batch details:\n")
+			for _, chunk := range batch {
+				fmt.Printf("     File: %s, Section: %s, Chars: %d, Words: %d\n",
+					chunk.FilePath, chunk.Section, len(chunk.Text), len(strings.Fields(chunk.Text)))
+			}
 			return fmt.Errorf("failed to make request: %w", err)
 		}
 
@@ -522,25 +701,25 @@ func (eg *EmbeddingGenerator) generateOllamaEmbeddings(chunks []*kbtypes.Chunk)
 		}
 		resp.Body.Close()
 
-		chunk.OllamaEmbedding = embResp.Embedding
-		pendingSave = append(pendingSave, chunk)
-
-		// Save progress every saveInterval chunks or on last chunk (only for existing chunks with IDs)
-		if len(pendingSave) >= saveInterval || i == len(chunksToProcess)-1 {
-			if eg.db != nil && len(pendingSave) > 0 && pendingSave[0].ID != 0 {
-				eg.dbMux.Lock()
-				if err := eg.db.UpdateOllamaEmbeddings(pendingSave); err != nil {
-					eg.dbMux.Unlock()
-					return fmt.Errorf("failed to save chunks to database: %w", err)
-				}
+		if len(embResp.Embeddings) != len(batch) {
+			return fmt.Errorf("expected %d embeddings, got %d", len(batch), len(embResp.Embeddings))
+		}
+
+		for j, chunk := range batch {
+			chunk.OllamaEmbedding = embResp.Embeddings[j]
+		}
+
+		// Save progress to database after each batch (only for existing chunks with IDs)
+		if eg.db != nil && batch[0].ID != 0 {
+			eg.dbMux.Lock()
+			if err := eg.db.UpdateOllamaEmbeddings(batch); err != nil {
 				eg.dbMux.Unlock()
-				pendingSave = nil
+				return fmt.Errorf("failed to save batch to database: %w", err)
 			}
+			eg.dbMux.Unlock()
 		}
 
-		if (i+1)%10 == 0 || i == len(chunksToProcess)-1 {
-			fmt.Printf("  Ollama: Processed %d/%d chunks\n", i+1, len(chunksToProcess))
-		}
+		fmt.Printf("  Ollama: Processed %d/%d chunks\n", end, len(chunksToProcess))
 	}
 
 	return nil
@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	"pgedge-postgres-mcp/internal/kbconfig"
+	"pgedge-postgres-mcp/internal/kbdatabase"
 	"pgedge-postgres-mcp/internal/kbtypes"
 )
 
@@ -108,8 +109,8 @@ func TestVoyageRequestStructure(t *testing.T) {
 func TestOllamaRequestStructure(t *testing.T) {
 	// Test that we can marshal Ollama request correctly
 	req := ollamaEmbeddingRequest{
-		Model:  "nomic-embed-text",
-		Prompt: "test text",
+		Model: "nomic-embed-text",
+		Input: []string{"test text", "more text"},
 	}
 
 	data, err := json.Marshal(req)
@@ -127,8 +128,8 @@ func TestOllamaRequestStructure(t *testing.T) {
 		t.Errorf("Expected model 'nomic-embed-text', got %q", decoded.Model)
 	}
 
-	if decoded.Prompt != "test text" {
-		t.Errorf("Expected prompt 'test text', got %q", decoded.Prompt)
+	if len(decoded.Input) != 2 || decoded.Input[0] != "test text" {
+		t.Errorf("Expected input ['test text', 'more text'], got %q", decoded.Input)
 	}
 }
 
@@ -184,19 +185,85 @@ func TestVoyageResponseStructure(t *testing.T) {
 
 func TestOllamaResponseStructure(t *testing.T) {
 	// Test that we can unmarshal Ollama response correctly
-	responseJSON := `{"embedding": [0.1, 0.2, 0.3, 0.4, 0.5]}`
+	responseJSON := `{"embeddings": [[0.1, 0.2, 0.3, 0.4, 0.5], [0.6, 0.7, 0.8, 0.9, 1.0]]}`
 
 	var resp ollamaEmbeddingResponse
 	if err := json.Unmarshal([]byte(responseJSON), &resp); err != nil {
 		t.Fatalf("Failed to unmarshal Ollama response: %v", err)
 	}
 
-	if len(resp.Embedding) != 5 {
-		t.Errorf("Expected embedding with 5 dimensions, got %d", len(resp.Embedding))
+	if len(resp.Embeddings) != 2 {
+		t.Fatalf("Expected 2 embeddings, got %d", len(resp.Embeddings))
 	}
 
-	if resp.Embedding[0] != 0.1 {
-		t.Errorf("Expected first value 0.1, got %f", resp.Embedding[0])
+	if len(resp.Embeddings[0]) != 5 {
+		t.Errorf("Expected embedding with 5 dimensions, got %d", len(resp.Embeddings[0]))
+	}
+
+	if resp.Embeddings[0][0] != 0.1 {
+		t.Errorf("Expected first value 0.1, got %f", resp.Embeddings[0][0])
+	}
+}
+
+func TestGenerateOllamaEmbeddings_Batches(t *testing.T) {
+	var batchSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("Expected request to /api/embed, got %s", r.URL.Path)
+		}
+		var req ollamaEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		batchSizes = append(batchSizes, len(req.Input))
+
+		// Return each text's length as its embedding
+		var resp ollamaEmbeddingResponse
+		for _, text := range req.Input {
+			resp.Embeddings = append(resp.Embeddings, []float32{float32(len(text))})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	config := &kbconfig.Config{
+		Embeddings: kbconfig.EmbeddingConfig{
+			BatchSize: 2,
+			Ollama: kbconfig.OllamaConfig{
+				Enabled:  true,
+				Endpoint: server.URL,
+				Model:    "example-model",
+			},
+		},
+	}
+	eg := NewEmbeddingGenerator(config, nil)
+
+	chunks := []*kbtypes.Chunk{
+		{Text: "a"},
+		{Text: "bb"},
+		{Text: "   "}, // Empty chunks are not sent
+		{Text: "ccc"},
+		{Text: "dddd"},
+		{Text: "eeeee"},
+	}
+	if err := eg.generateOllamaEmbeddings(chunks); err != nil {
+		t.Fatalf("generateOllamaEmbeddings failed: %v", err)
+	}
+
+	if len(batchSizes) != 3 || batchSizes[0] != 2 || batchSizes[1] != 2 || batchSizes[2] != 1 {
+		t.Errorf("Expected batches of 2, 2 and 1 chunks, got %v", batchSizes)
+	}
+	for _, chunk := range chunks {
+		if chunk.Text == "   " {
+			if len(chunk.OllamaEmbedding) != 0 {
+				t.Error("Empty chunk should not have an embedding")
+			}
+			continue
+		}
+		if len(chunk.OllamaEmbedding) != 1 || chunk.OllamaEmbedding[0] != float32(len(chunk.Text)) {
+			t.Errorf("Chunk %q got embedding %v", chunk.Text, chunk.OllamaEmbedding)
+		}
 	}
 }
 
@@ -375,3 +442,84 @@ func TestEmbeddingAssignment(t *testing.T) {
 		t.Error("OpenAI embedding values incorrect")
 	}
 }
+
+func TestEmbeddingModelRecording(t *testing.T) {
+	db, err := kbdatabase.Open(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	eg := NewEmbeddingGenerator(&kbconfig.Config{}, db)
+	chunks := []*kbtypes.Chunk{
+		{Text: "no embedding"},
+		{Text: "embedded", OllamaEmbedding: []float32{0.1, 0.2, 0.3}},
+	}
+	if err := eg.recordModel("ollama", "nomic-embed-text", chunks, func(c *kbtypes.Chunk) []float32 { return c.OllamaEmbedding }); err != nil {
+		t.Fatalf("recordModel failed: %v", err)
+	}
+
+	recorded := eg.recordedModels()
+	if recorded["ollama"] != "nomic-embed-text" {
+		t.Fatalf("Expected the ollama model to be recorded, got %v", recorded)
+	}
+	models, err := db.GetEmbeddingModels()
+	if err != nil || len(models) != 1 || models[0].Dimensions != 3 {
+		t.Errorf("Expected 3 dimensions to be recorded, got %+v (%v)", models, err)
+	}
+
+	if err := eg.checkModel("ollama", "nomic-embed-text", recorded); err != nil {
+		t.Errorf("Expected the recorded model to be accepted, got %v", err)
+	}
+	if err := eg.checkModel("openai", "text-embedding-3-small", recorded); err != nil {
+		t.Errorf("Expected an unrecorded provider to be accepted, got %v", err)
+	}
+	if err := eg.checkModel("ollama", "mxbai-embed-large", recorded); err == nil {
+		t.Error("Expected a different model to be refused")
+	}
+}
+
+func TestPendingChunks(t *testing.T) {
+	db, err := kbdatabase.Open(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	config := &kbconfig.Config{}
+	config.Embeddings.OpenAI.Enabled = true
+	config.Embeddings.Ollama.Enabled = true
+	eg := NewEmbeddingGenerator(config, db)
+
+	chunks := []*kbtypes.Chunk{
+		{Text: "needs both"},
+		{Text: "needs ollama", OpenAIEmbedding: []float32{0.1}},
+		{Text: "complete", OpenAIEmbedding: []float32{0.1}, OllamaEmbedding: []float32{0.2}},
+		{Text: "   "},
+	}
+	if err := db.InsertChunks(chunks); err != nil {
+		t.Fatalf("Failed to insert chunks: %v", err)
+	}
+	if err := eg.MarkPending(chunks); err != nil {
+		t.Fatalf("MarkPending failed: %v", err)
+	}
+
+	pending, err := eg.PendingChunks()
+	if err != nil {
+		t.Fatalf("PendingChunks failed: %v", err)
+	}
+	if len(pending) != 2 || pending[0].Text != "needs both" || pending[1].Text != "needs ollama" {
+		t.Fatalf("Expected the two incomplete chunks, got %+v", pending)
+	}
+	openai, err := db.GetPendingEmbeddings("openai")
+	if err != nil || len(openai) != 1 {
+		t.Errorf("Expected 1 pending OpenAI embedding, got %d (%v)", len(openai), err)
+	}
+
+	// A provider that is no longer enabled is not resumed
+	config.Embeddings.OpenAI.Enabled = false
+	config.Embeddings.Ollama.Enabled = false
+	if pending, err := eg.PendingChunks(); err != nil || len(pending) != 0 {
+		t.Errorf("Expected no pending chunks without enabled providers, got %d (%v)", len(pending), err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"strings"
 
 	"pgedge-postgres-mcp/internal/kbconfig"
+	"pgedge-postgres-mcp/internal/netproxy"
 )
 
 // SourceInfo represents information about a processed documentation source
@@ -182,9 +183,15 @@ func fetchLocalSource(source kbconfig.DocumentSource, docSourcePath string) (Sou
 	}, nil
 }
 
+// gitCommand builds a git command, routing remote access through the
+// configured outbound proxy if there is one
+func gitCommand(args ...string) *exec.Cmd {
+	return exec.Command("git", append(netproxy.GitConfigArgs(), args...)...)
+}
+
 // gitClone clones a Git repository
 func gitClone(url, path string) error {
-	cmd := exec.Command("git", "clone", url, path)
+	cmd := gitCommand("clone", url, path)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -193,7 +200,7 @@ func gitClone(url, path string) error {
 // gitCloneWithReference clones a Git repository using an existing local repo as a reference
 // This saves bandwidth by reusing objects from the reference repo while still fetching all branches
 func gitCloneWithReference(url, path, referencePath string) error {
-	cmd := exec.Command("git", "clone", "--reference", referencePath, url, path)
+	cmd := gitCommand("clone", "--reference", referencePath, url, path)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -201,7 +208,7 @@ func gitCloneWithReference(url, path, referencePath string) error {
 
 // gitFetch fetches updates from a Git repository (works with both branches and tags)
 func gitFetch(path string) error {
-	cmd := exec.Command("git", "fetch", "--all", "--tags")
+	cmd := gitCommand("fetch", "--all", "--tags")
 	cmd.Dir = path
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -24,6 +24,10 @@ const (
 	TypeReStructuredText
 	// TypeSGML represents an SGML document
 	TypeSGML
+	// TypePDF represents a PDF document
+	TypePDF
+	// TypeDOCX represents a Word (Office Open XML) document
+	TypeDOCX
 )
 
 // String returns the string representation of a DocumentType
@@ -37,6 +41,10 @@ func (dt DocumentType) String() string {
 		return "reStructuredText"
 	case TypeSGML:
 		return "SGML"
+	case TypePDF:
+		return "PDF"
+	case TypeDOCX:
+		return "DOCX"
 	default:
 		return "Unknown"
 	}
@@ -53,6 +61,14 @@ type Document struct {
 	DocType        DocumentType
 }
 
+// EmbeddingModel records the model that produced a provider's embeddings
+// in a knowledgebase, so the server can search with the same model
+type EmbeddingModel struct {
+	Provider   string // "openai", "voyage" or "ollama"
+	Model      string
+	Dimensions int
+}
+
 // Chunk represents a chunk of a document with embeddings
 type Chunk struct {
 	ID                 int // Database ID (populated when retrieved from DB)
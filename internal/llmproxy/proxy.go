@@ -13,11 +13,19 @@ package llmproxy
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"pgedge-postgres-mcp/internal/chat"
+	"pgedge-postgres-mcp/internal/sigv4"
+	"pgedge-postgres-mcp/internal/tokenbudget"
+	"pgedge-postgres-mcp/internal/toolschema"
+	"pgedge-postgres-mcp/internal/tracing"
 )
 
 // Config holds LLM configuration from the server config
@@ -29,6 +37,83 @@ type Config struct {
 	OllamaURL       string
 	MaxTokens       int
 	Temperature     float64
+	Schema          SchemaSource // Optional source for the schema context block in system prompts
+	Memory          MemorySource // Optional source for the user's remembered facts and preferences
+
+	// Azure OpenAI resource; the model names the deployment
+	AzureEndpoint   string
+	AzureAPIKey     string
+	AzureAPIVersion string
+
+	// Amazon Bedrock region, optional runtime endpoint, and credentials
+	BedrockRegion      string
+	BedrockEndpoint    string
+	BedrockCredentials sigv4.Credentials
+
+	// Summarize messages dropped by chat history compaction with the LLM
+	CompactionSummaries bool
+	// Context window of the model in tokens, when it differs from the
+	// model's known window
+	ContextWindow int
+
+	// Providers tried in order when the provider of a request is
+	// unavailable, and how long an unavailable provider is skipped
+	Fallback         []Route
+	FailoverCooldown time.Duration
+	// Health of the providers, shared by configurations from the same
+	// ConfigStore so it survives reloads
+	Health *ProviderHealth
+}
+
+// MemorySource provides the facts and preferences a user has asked the
+// assistant to remember, formatted for the system prompt
+type MemorySource interface {
+	MemoryContext(ctx context.Context) string
+}
+
+// ConfigStore holds the active LLM proxy configuration so it can be
+// replaced on configuration reload. A nil configuration means the proxy is disabled.
+type ConfigStore struct {
+	mu     sync.RWMutex
+	config *Config
+	health *ProviderHealth
+}
+
+// NewConfigStore creates a configuration store (config may be nil)
+func NewConfigStore(config *Config) *ConfigStore {
+	s := &ConfigStore{health: NewProviderHealth()}
+	s.Set(config)
+	return s
+}
+
+// Get returns the current configuration, or nil if the proxy is disabled
+func (s *ConfigStore) Get() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// Set replaces the configuration (nil disables the proxy)
+func (s *ConfigStore) Set(config *Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if config != nil && config.Health == nil {
+		config.Health = s.health
+	}
+	s.config = config
+}
+
+// Handler adapts an LLM proxy handler to use the current configuration
+// Requests receive 404 Not Found while the proxy is disabled
+func (s *ConfigStore) Handler(handle func(http.ResponseWriter, *http.Request, *Config)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := s.Get()
+		if config == nil {
+			http.NotFound(w, r)
+			return
+		}
+		handle(w, r, config)
+	}
 }
 
 // Message represents a message in the chat conversation
@@ -39,18 +124,11 @@ type Message struct {
 }
 
 // Tool represents an MCP tool definition
-type Tool struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema InputSchema `json:"inputSchema"`
-}
+type Tool = toolschema.Tool
 
-// InputSchema defines the JSON schema for tool input
-type InputSchema struct {
-	Type       string                 `json:"type"`
-	Properties map[string]interface{} `json:"properties"`
-	Required   []string               `json:"required,omitempty"`
-}
+// InputSchema defines the JSON schema for tool input. Keywords beyond type,
+// properties and required are kept so they reach the LLM.
+type InputSchema = toolschema.Schema
 
 // ProvidersResponse represents the response for GET /api/llm/providers
 type ProvidersResponse struct {
@@ -83,6 +161,7 @@ type ChatRequest struct {
 	Provider string    `json:"provider,omitempty"` // Override default provider
 	Model    string    `json:"model,omitempty"`    // Override default model
 	Debug    bool      `json:"debug,omitempty"`    // Enable debug mode for token usage
+	Stream   bool      `json:"stream,omitempty"`   // Stream the response as server-sent events
 }
 
 // ChatResponse represents the response body for POST /api/llm/chat
@@ -90,6 +169,15 @@ type ChatResponse struct {
 	Content    []interface{}    `json:"content"`
 	StopReason string           `json:"stop_reason"`
 	TokenUsage *chat.TokenUsage `json:"token_usage,omitempty"` // Optional token usage (when debug enabled)
+
+	// ContextUsage reports the request's size against the model's context
+	// window, so clients know when to compact the conversation
+	ContextUsage *tokenbudget.Usage `json:"context_usage,omitempty"`
+
+	// Provider and Model that produced the response; they differ from the
+	// request's when the proxy failed over to a fallback provider
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
 }
 
 // HandleProviders handles GET /api/llm/providers
@@ -118,6 +206,22 @@ func HandleProviders(w http.ResponseWriter, r *http.Request, config *Config) {
 		})
 	}
 
+	if config.AzureEndpoint != "" && config.AzureAPIKey != "" {
+		providers = append(providers, ProviderInfo{
+			Name:      "azure",
+			Display:   "Azure OpenAI",
+			IsDefault: config.Provider == "azure",
+		})
+	}
+
+	if config.BedrockRegion != "" && config.BedrockCredentials.IsSet() {
+		providers = append(providers, ProviderInfo{
+			Name:      "bedrock",
+			Display:   "Amazon Bedrock",
+			IsDefault: config.Provider == "bedrock",
+		})
+	}
+
 	if config.OllamaURL != "" {
 		providers = append(providers, ProviderInfo{
 			Name:      "ollama",
@@ -151,28 +255,9 @@ func HandleModels(w http.ResponseWriter, r *http.Request, config *Config) {
 	}
 
 	// Create LLM client for the provider (debug mode always false for models listing)
-	var client chat.LLMClient
-	switch provider {
-	case "anthropic":
-		if config.AnthropicAPIKey == "" {
-			http.Error(w, "Anthropic API key not configured", http.StatusBadRequest)
-			return
-		}
-		client = chat.NewAnthropicClient(config.AnthropicAPIKey, config.Model, config.MaxTokens, config.Temperature, false)
-	case "openai":
-		if config.OpenAIAPIKey == "" {
-			http.Error(w, "OpenAI API key not configured", http.StatusBadRequest)
-			return
-		}
-		client = chat.NewOpenAIClient(config.OpenAIAPIKey, config.Model, config.MaxTokens, config.Temperature, false)
-	case "ollama":
-		if config.OllamaURL == "" {
-			http.Error(w, "Ollama URL not configured", http.StatusBadRequest)
-			return
-		}
-		client = chat.NewOllamaClient(config.OllamaURL, config.Model, false)
-	default:
-		http.Error(w, fmt.Sprintf("Unsupported provider: %s", provider), http.StatusBadRequest)
+	client, err := newLLMClient(config, provider, config.Model, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -183,6 +268,12 @@ func HandleModels(w http.ResponseWriter, r *http.Request, config *Config) {
 		return
 	}
 
+	// Azure OpenAI deployments cannot be listed, so the configured ones are
+	// offered once the resource has accepted the key
+	if provider == "azure" {
+		modelNames = config.deployments()
+	}
+
 	// Convert to model info
 	models := make([]ModelInfo, len(modelNames))
 	for i, name := range modelNames {
@@ -201,6 +292,52 @@ func HandleModels(w http.ResponseWriter, r *http.Request, config *Config) {
 	}
 }
 
+// newLLMClient creates a client for a provider and model with the
+// configured credentials
+func newLLMClient(config *Config, provider, model string, debug bool) (chat.LLMClient, error) {
+	switch provider {
+	case "anthropic":
+		if config.AnthropicAPIKey == "" {
+			return nil, fmt.Errorf("Anthropic API key not configured")
+		}
+		return chat.NewAnthropicClient(config.AnthropicAPIKey, model, config.MaxTokens, config.Temperature, debug), nil
+	case "openai":
+		if config.OpenAIAPIKey == "" {
+			return nil, fmt.Errorf("OpenAI API key not configured")
+		}
+		return chat.NewOpenAIClient(config.OpenAIAPIKey, model, config.MaxTokens, config.Temperature, debug), nil
+	case "azure":
+		if config.AzureEndpoint == "" || config.AzureAPIKey == "" {
+			return nil, fmt.Errorf("Azure OpenAI endpoint and API key not configured")
+		}
+		return chat.NewAzureOpenAIClient(config.AzureEndpoint, config.AzureAPIVersion, config.AzureAPIKey, model, config.MaxTokens, config.Temperature, debug), nil
+	case "bedrock":
+		if config.BedrockRegion == "" || !config.BedrockCredentials.IsSet() {
+			return nil, fmt.Errorf("Amazon Bedrock region and AWS credentials not configured")
+		}
+		return chat.NewBedrockClient(config.BedrockRegion, config.BedrockEndpoint, config.BedrockCredentials, model, config.MaxTokens, config.Temperature, debug), nil
+	case "ollama":
+		if config.OllamaURL == "" {
+			return nil, fmt.Errorf("Ollama URL not configured")
+		}
+		return chat.NewOllamaClient(config.OllamaURL, model, debug), nil
+	default:
+		return nil, fmt.Errorf("Unsupported provider: %s", provider)
+	}
+}
+
+// deployments returns the configured Azure OpenAI deployments: the model
+// when Azure OpenAI is the provider, and the models of Azure fallbacks
+func (c *Config) deployments() []string {
+	deployments := []string{}
+	for _, route := range c.routes(c.Provider, c.Model) {
+		if route.Provider == "azure" && route.Model != "" {
+			deployments = append(deployments, route.Model)
+		}
+	}
+	return deployments
+}
+
 // HandleChat handles POST /api/llm/chat
 func HandleChat(w http.ResponseWriter, r *http.Request, config *Config) {
 	if r.Method != http.MethodPost {
@@ -218,6 +355,11 @@ func HandleChat(w http.ResponseWriter, r *http.Request, config *Config) {
 	// Parse request body
 	var req ChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -233,31 +375,13 @@ func HandleChat(w http.ResponseWriter, r *http.Request, config *Config) {
 		model = config.Model
 	}
 
-	// Create LLM client with debug mode from request
-	var client chat.LLMClient
-	switch provider {
-	case "anthropic":
-		if config.AnthropicAPIKey == "" {
-			http.Error(w, "Anthropic API key not configured", http.StatusBadRequest)
-			return
-		}
-		client = chat.NewAnthropicClient(config.AnthropicAPIKey, model, config.MaxTokens, config.Temperature, req.Debug)
-	case "openai":
-		if config.OpenAIAPIKey == "" {
-			http.Error(w, "OpenAI API key not configured", http.StatusBadRequest)
-			return
-		}
-		client = chat.NewOpenAIClient(config.OpenAIAPIKey, model, config.MaxTokens, config.Temperature, req.Debug)
-	case "ollama":
-		if config.OllamaURL == "" {
-			http.Error(w, "Ollama URL not configured", http.StatusBadRequest)
-			return
-		}
-		client = chat.NewOllamaClient(config.OllamaURL, model, req.Debug)
-	default:
-		http.Error(w, fmt.Sprintf("Unsupported provider: %s", provider), http.StatusBadRequest)
+	// The requested provider must be configured; the fallback providers
+	// are only used when it is unavailable
+	if _, err := newLLMClient(config, provider, model, req.Debug); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	routes := config.routes(provider, model)
 
 	// Convert proxy messages to chat messages
 	chatMessages := make([]chat.Message, len(req.Messages))
@@ -271,22 +395,143 @@ func HandleChat(w http.ResponseWriter, r *http.Request, config *Config) {
 
 	// Call LLM - pass tools as []interface{} to avoid import cycle
 	// The chat client will access tool fields which are structurally identical to mcp.Tool
-	ctx := context.Background()
-	llmResponse, err := client.Chat(ctx, chatMessages, req.Tools)
+	// The request's context stops the generation if the client disconnects
+	ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), "POST /api/llm/chat", tracing.KindServer,
+		tracing.String("gen_ai.system", provider),
+		tracing.String("gen_ai.request.model", model),
+	)
+	defer span.End()
+
+	// Include a schema summary for the caller's database and the user's
+	// memories; the schema summary is cached per metadata version so
+	// providers can serve it from their prompt cache
+	var systemContext []string
+	if config.Schema != nil {
+		if summary := schemaContext(r.Context(), config.Schema); summary != "" {
+			systemContext = append(systemContext, summary)
+		}
+	}
+	if config.Memory != nil {
+		if memories := config.Memory.MemoryContext(r.Context()); memories != "" {
+			systemContext = append(systemContext, memories)
+		}
+	}
+	if len(systemContext) > 0 {
+		ctx = chat.WithSystemContext(ctx, strings.Join(systemContext, "\n"))
+	}
+
+	// Send the request, failing over to the fallback providers
+	send := func(ctx context.Context, streamed func() bool) (ChatResponse, error) {
+		llmResponse, route, err := chatWithFailover(ctx, config, routes, req.Debug, chatMessages, req.Tools, streamed)
+		if err != nil {
+			return ChatResponse{}, err
+		}
+		usage := requestUsage(tokenbudget.New(route.Provider, route.Model, config.ContextWindow), chatMessages, req.Tools, systemContext)
+		return ChatResponse{
+			Content:      llmResponse.Content,
+			StopReason:   llmResponse.StopReason,
+			TokenUsage:   llmResponse.TokenUsage,
+			ContextUsage: &usage,
+			Provider:     route.Provider,
+			Model:        route.Model,
+		}, nil
+	}
+
+	if req.Stream {
+		streamChat(ctx, w, send)
+		return
+	}
+
+	response, err := send(ctx, nil)
 	if err != nil {
+		span.SetError(err.Error())
 		http.Error(w, fmt.Sprintf("LLM error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Return response
-	response := ChatResponse{
-		Content:    llmResponse.Content,
-		StopReason: llmResponse.StopReason,
-		TokenUsage: llmResponse.TokenUsage,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: Failed to encode LLM chat response: %v\n", err)
 	}
 }
+
+// StreamDelta is the data of a "delta" event of a streamed chat response
+type StreamDelta struct {
+	Text string `json:"text"`
+}
+
+// StreamError is the data of an "error" event of a streamed chat response
+type StreamError struct {
+	Error string `json:"error"`
+}
+
+// streamChat sends a chat response as server-sent events: "delta" events
+// carry text as it is generated, followed by a "done" event with the
+// complete ChatResponse or an "error" event. Text deltas are provisional;
+// the "done" event's content replaces them. Cancelling ctx, which happens
+// when the client closes the connection, stops the generation.
+func streamChat(ctx context.Context, w http.ResponseWriter, send func(ctx context.Context, streamed func() bool) (ChatResponse, error)) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop reverse proxies such as nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	// Requests only fail over to another provider until text is sent
+	streamed := false
+	ctx = chat.WithTextStream(ctx, func(text string) {
+		streamed = true
+		// Write errors mean the client has gone, which cancels ctx
+		_ = writeStreamEvent(w, "delta", StreamDelta{Text: text}) //nolint:errcheck // See above
+	})
+	response, err := send(ctx, func() bool { return streamed })
+	if err != nil {
+		if ctx.Err() != nil {
+			// The client cancelled the request
+			return
+		}
+		if err := writeStreamEvent(w, "error", StreamError{Error: fmt.Sprintf("LLM error: %v", err)}); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Failed to send LLM chat error: %v\n", err)
+		}
+		return
+	}
+
+	if err := writeStreamEvent(w, "done", response); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Failed to send LLM chat response: %v\n", err)
+	}
+}
+
+// writeStreamEvent writes a server-sent event with JSON data
+func writeStreamEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// requestUsage counts the tokens of a chat request, including the tool
+// definitions and the system context sent with it, against a budget
+func requestUsage(budget *tokenbudget.Budget, messages []chat.Message, tools []Tool, systemContext []string) tokenbudget.Usage {
+	tokens := chat.CountMessageTokens(budget.Tokenizer, messages)
+	if len(tools) > 0 {
+		if data, err := json.Marshal(tools); err == nil {
+			tokens += budget.Tokenizer.Count(string(data))
+		}
+	}
+	for _, text := range systemContext {
+		tokens += budget.Tokenizer.Count(text)
+	}
+	return budget.Usage(tokens)
+}
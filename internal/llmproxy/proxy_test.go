@@ -15,7 +15,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"pgedge-postgres-mcp/internal/chat"
+	"pgedge-postgres-mcp/internal/sigv4"
+	"pgedge-postgres-mcp/internal/tokenbudget"
 )
 
 func TestHandleProviders_Success(t *testing.T) {
@@ -557,3 +562,246 @@ func TestModelsResponseStruct(t *testing.T) {
 		t.Errorf("expected 2 models, got %d", len(decoded.Models))
 	}
 }
+
+func TestConfigStore_Handler(t *testing.T) {
+	store := NewConfigStore(nil)
+	handler := store.Handler(HandleProviders)
+
+	// Disabled proxy responds with 404
+	req := httptest.NewRequest(http.MethodGet, "/api/llm/providers", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 while disabled, got %d", w.Code)
+	}
+
+	// Enabling the proxy takes effect on the next request
+	store.Set(&Config{Provider: "anthropic", Model: "claude-test", AnthropicAPIKey: "key"})
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 after enabling, got %d", w.Code)
+	}
+
+	var resp ProvidersResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.DefaultModel != "claude-test" {
+		t.Errorf("expected default model claude-test, got %s", resp.DefaultModel)
+	}
+}
+
+func TestRequestUsage(t *testing.T) {
+	budget := tokenbudget.New("ollama", "example-model", 1000)
+	messages := []chat.Message{{Role: "user", Content: "How many orders are there?"}}
+
+	base := requestUsage(budget, messages, nil, nil)
+	if base.Tokens == 0 || base.ContextWindow != 1000 || base.ShouldCompact {
+		t.Errorf("requestUsage() = %+v", base)
+	}
+
+	// Tool definitions and the system context are part of the request
+	tools := []Tool{{Name: "query_database", Description: "Run a read-only SQL query"}}
+	full := requestUsage(budget, messages, tools, []string{"Schema: public.orders (id, total)"})
+	if full.Tokens <= base.Tokens {
+		t.Errorf("expected tools and system context to be counted, got %d <= %d", full.Tokens, base.Tokens)
+	}
+}
+
+// ollamaServer returns a test Ollama server that streams chunks of text, or
+// fails with an error if status is not 200
+func ollamaServer(t *testing.T, status int, chunks ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			http.Error(w, `{"error":"model not found"}`, status)
+			return
+		}
+		for i, chunk := range chunks {
+			line, _ := json.Marshal(map[string]interface{}{ //nolint:errcheck // Test data
+				"message": map[string]string{"role": "assistant", "content": chunk},
+				"done":    i == len(chunks)-1,
+			})
+			w.Write(append(line, '\n'))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// streamEvents returns the events of an SSE response as event name and data
+func streamEvents(t *testing.T, body string) [][2]string {
+	t.Helper()
+	var events [][2]string
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var event [2]string
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event[0] = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+				event[1] = data
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestHandleChat_Stream(t *testing.T) {
+	server := ollamaServer(t, http.StatusOK, "Hello", " there")
+	config := &Config{Provider: "ollama", Model: "example-model", OllamaURL: server.URL}
+
+	bodyBytes, _ := json.Marshal(ChatRequest{
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+		Stream:   true,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/llm/chat", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+	HandleChat(w, req, config)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	events := streamEvents(t, w.Body.String())
+	if len(events) != 3 {
+		t.Fatalf("expected 2 deltas and done, got %q", events)
+	}
+	for i, want := range []string{"Hello", " there"} {
+		var delta StreamDelta
+		if events[i][0] != "delta" || json.Unmarshal([]byte(events[i][1]), &delta) != nil || delta.Text != want {
+			t.Errorf("event %d = %q, want a delta of %q", i, events[i], want)
+		}
+	}
+
+	var response ChatResponse
+	if events[2][0] != "done" {
+		t.Fatalf("expected a done event, got %q", events[2])
+	}
+	if err := json.Unmarshal([]byte(events[2][1]), &response); err != nil {
+		t.Fatalf("invalid done event: %v", err)
+	}
+	if response.StopReason != "end_turn" || len(response.Content) != 1 || response.ContextUsage == nil {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
+
+func TestHandleChat_StreamError(t *testing.T) {
+	server := ollamaServer(t, http.StatusNotFound)
+	config := &Config{Provider: "ollama", Model: "example-model", OllamaURL: server.URL}
+
+	bodyBytes, _ := json.Marshal(ChatRequest{
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+		Stream:   true,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/llm/chat", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+	HandleChat(w, req, config)
+
+	events := streamEvents(t, w.Body.String())
+	var streamErr StreamError
+	if len(events) != 1 || events[0][0] != "error" || json.Unmarshal([]byte(events[0][1]), &streamErr) != nil {
+		t.Fatalf("expected an error event, got %q", events)
+	}
+	if !strings.Contains(streamErr.Error, "model not found") {
+		t.Errorf("error = %q", streamErr.Error)
+	}
+}
+
+func TestHandleProviders_CloudGateways(t *testing.T) {
+	config := &Config{
+		Provider:           "bedrock",
+		AzureEndpoint:      "https://example.openai.azure.com",
+		AzureAPIKey:        "azure-key",
+		BedrockRegion:      "us-east-1",
+		BedrockCredentials: sigv4.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/llm/providers", nil)
+	w := httptest.NewRecorder()
+	HandleProviders(w, req, config)
+
+	var response ProvidersResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Providers) != 2 || response.Providers[0].Name != "azure" || response.Providers[1].Name != "bedrock" {
+		t.Fatalf("expected azure and bedrock, got %+v", response.Providers)
+	}
+	if response.Providers[0].IsDefault || !response.Providers[1].IsDefault {
+		t.Errorf("expected bedrock to be the default, got %+v", response.Providers)
+	}
+
+	// Bedrock needs AWS credentials
+	config.BedrockCredentials = sigv4.Credentials{}
+	req = httptest.NewRequest(http.MethodGet, "/api/llm/models?provider=bedrock", nil)
+	w = httptest.NewRecorder()
+	HandleModels(w, req, config)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without credentials, got %d", w.Code)
+	}
+}
+
+func TestHandleModels_AzureDeployments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "azure-key" {
+			http.Error(w, `{"error":{"message":"invalid key"}}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Provider:      "azure",
+		Model:         "primary-deployment",
+		AzureEndpoint: server.URL,
+		AzureAPIKey:   "azure-key",
+		Fallback:      []Route{{Provider: "ollama", Model: "llama3.1"}, {Provider: "azure", Model: "fallback-deployment"}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/llm/models?provider=azure", nil)
+	w := httptest.NewRecorder()
+	HandleModels(w, req, config)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response ModelsResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Models) != 2 || response.Models[0].Name != "primary-deployment" || response.Models[1].Name != "fallback-deployment" {
+		t.Errorf("expected the configured deployments, got %+v", response.Models)
+	}
+}
+
+func TestHandleChat_Bedrock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			t.Errorf("expected a signed request, got %v", r.Header)
+		}
+		w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[{"text":"Hello from Bedrock"}]}},"stopReason":"end_turn","usage":{"inputTokens":5,"outputTokens":4,"totalTokens":9}}`))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Provider:           "bedrock",
+		Model:              "anthropic.example-model-v1:0",
+		BedrockRegion:      "us-east-1",
+		BedrockEndpoint:    server.URL,
+		BedrockCredentials: sigv4.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"},
+	}
+
+	bodyBytes, _ := json.Marshal(ChatRequest{Messages: []Message{{Role: "user", Content: "Hello"}}})
+	req := httptest.NewRequest(http.MethodPost, "/api/llm/chat", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+	HandleChat(w, req, config)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "Hello from Bedrock") {
+		t.Errorf("unexpected response: %s", w.Body.String())
+	}
+}
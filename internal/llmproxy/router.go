@@ -0,0 +1,230 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent - LLM Proxy
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package llmproxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"pgedge-postgres-mcp/internal/chat"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/tracing"
+)
+
+const (
+	// DefaultFailoverCooldown is how long an unavailable route is skipped
+	DefaultFailoverCooldown = time.Minute
+
+	// healthCheckTimeout bounds the check of a route whose cooldown ended
+	healthCheckTimeout = 5 * time.Second
+)
+
+// Route is a provider and model that chat requests can be sent to
+type Route struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+// routeState is the health of a route
+type routeState int
+
+const (
+	routeUp          routeState = iota // Available
+	routeCoolingDown                   // Failed recently; tried only as a last resort
+	routeNeedsCheck                    // Cooldown ended; checked before it is used
+)
+
+// ProviderHealth tracks which routes are unavailable. A route that is rate
+// limited, fails with a server error or cannot be reached is skipped for a
+// cooldown; afterwards its models are listed as a health check before
+// requests are sent to it again. It is kept across configuration reloads.
+type ProviderHealth struct {
+	mu   sync.Mutex
+	down map[Route]time.Time // When each unavailable route's cooldown ends
+	now  func() time.Time
+}
+
+// NewProviderHealth creates a health tracker with all routes available
+func NewProviderHealth() *ProviderHealth {
+	return &ProviderHealth{down: make(map[Route]time.Time), now: time.Now}
+}
+
+// state returns the health of a route
+func (h *ProviderHealth) state(route Route) routeState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	until, ok := h.down[route]
+	switch {
+	case !ok:
+		return routeUp
+	case h.now().Before(until):
+		return routeCoolingDown
+	default:
+		return routeNeedsCheck
+	}
+}
+
+// markDown records that a route is unavailable for a cooldown
+func (h *ProviderHealth) markDown(route Route, cooldown time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.down[route] = h.now().Add(cooldown)
+}
+
+// markUp records that a route is available
+func (h *ProviderHealth) markUp(route Route) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.down, route)
+}
+
+// routes returns the routes for a request in the order they are tried: the
+// requested provider and model, then the configured provider and model,
+// then the fallbacks
+func (c *Config) routes(provider, model string) []Route {
+	first := Route{Provider: provider, Model: model}
+	routes := []Route{first}
+	candidates := append([]Route{{Provider: c.Provider, Model: c.Model}}, c.Fallback...)
+	for _, route := range candidates {
+		seen := false
+		for _, existing := range routes {
+			if existing == route {
+				seen = true
+				break
+			}
+		}
+		if !seen && route.Provider != "" {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// chatWithFailover sends a chat request along routes, failing over to the
+// next route when a provider is unavailable. Routes in their cooldown are
+// tried last. Once streamed reports that text has been sent to the client,
+// errors are returned instead of failing over. It returns the response and
+// the route that produced it.
+func chatWithFailover(ctx context.Context, config *Config, routes []Route, debug bool, messages []chat.Message, tools []Tool, streamed func() bool) (chat.LLMResponse, Route, error) {
+	health := config.Health
+	if health == nil {
+		health = NewProviderHealth()
+	}
+	cooldown := config.FailoverCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultFailoverCooldown
+	}
+
+	var ordered, coolingDown []Route
+	for _, route := range routes {
+		if health.state(route) == routeCoolingDown {
+			coolingDown = append(coolingDown, route)
+		} else {
+			ordered = append(ordered, route)
+		}
+	}
+	ordered = append(ordered, coolingDown...)
+
+	var lastErr error
+	for i, route := range ordered {
+		last := i == len(ordered)-1
+		client, err := newLLMClient(config, route.Provider, route.Model, debug)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		// The last route is used even if its check fails, since there is
+		// nothing else to try
+		if health.state(route) == routeNeedsCheck {
+			if err := checkHealth(ctx, client); err != nil && !last {
+				health.markDown(route, cooldown)
+				logging.Warn("llm_health_check_failed", "provider", route.Provider, "model", route.Model, "error", err)
+				lastErr = err
+				continue
+			}
+		}
+
+		response, err := tracedChat(ctx, client, route, messages, tools)
+		if err == nil {
+			health.markUp(route)
+			return response, route, nil
+		}
+		if !shouldFailover(ctx, err) {
+			return chat.LLMResponse{}, route, err
+		}
+		health.markDown(route, cooldown)
+		lastErr = err
+		if streamed != nil && streamed() {
+			return chat.LLMResponse{}, route, err
+		}
+		if !last {
+			logging.Warn("llm_failover", "provider", route.Provider, "model", route.Model,
+				"next_provider", ordered[i+1].Provider, "next_model", ordered[i+1].Model, "error", err)
+		}
+	}
+	return chat.LLMResponse{}, Route{}, lastErr
+}
+
+// tracedChat sends messages to a route's LLM, recording the call as a span
+func tracedChat(ctx context.Context, client chat.LLMClient, route Route, messages []chat.Message, tools interface{}) (chat.LLMResponse, error) {
+	ctx, span := tracing.Start(ctx, "llm chat "+route.Model, tracing.KindClient,
+		tracing.String("gen_ai.operation.name", "chat"),
+		tracing.String("gen_ai.system", route.Provider),
+		tracing.String("gen_ai.request.model", route.Model),
+		tracing.Int("gen_ai.request.messages", len(messages)),
+	)
+	defer span.End()
+
+	response, err := client.Chat(ctx, messages, tools)
+	if err != nil {
+		span.SetError(err.Error())
+		return response, err
+	}
+	span.SetAttributes(tracing.String("gen_ai.response.finish_reason", response.StopReason))
+	if usage := response.TokenUsage; usage != nil {
+		span.SetAttributes(
+			tracing.Int("gen_ai.usage.input_tokens", usage.PromptTokens),
+			tracing.Int("gen_ai.usage.output_tokens", usage.CompletionTokens),
+		)
+	}
+	return response, nil
+}
+
+// checkHealth checks that a provider is available by listing its models
+func checkHealth(ctx context.Context, client chat.LLMClient) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	_, err := client.ListModels(ctx)
+	return err
+}
+
+// shouldFailover reports whether a chat error means the provider is
+// unavailable: it is rate limited, fails with a server error, or cannot be
+// reached. Other errors are caused by the request and would fail with any
+// provider.
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *chat.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,240 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent - LLM Proxy Tests
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package llmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/chat"
+)
+
+// modelServer is a test Ollama server whose models are either available or
+// fail with a status
+type modelServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   map[string]int // Status returned for each model, 200 if unset
+	requests []string       // Models requested, in order
+}
+
+func newModelServer(t *testing.T) *modelServer {
+	t.Helper()
+	s := &modelServer{status: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			fmt.Fprint(w, `{"models":[]}`)
+			return
+		}
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck // Test server
+
+		s.mu.Lock()
+		s.requests = append(s.requests, req.Model)
+		status := s.status[req.Model]
+		s.mu.Unlock()
+
+		if status != 0 && status != http.StatusOK {
+			http.Error(w, `{"error":"unavailable"}`, status)
+			return
+		}
+		fmt.Fprintf(w, `{"model":%q,"message":{"role":"assistant","content":"answer from %s"},"done":true}`, req.Model, req.Model)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// setStatus sets the status returned for a model
+func (s *modelServer) setStatus(model string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status[model] = status
+}
+
+// takeRequests returns and clears the models requested so far
+func (s *modelServer) takeRequests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func TestConfigRoutes(t *testing.T) {
+	config := &Config{
+		Provider: "anthropic",
+		Model:    "example-model",
+		Fallback: []Route{{Provider: "ollama", Model: "llama3.1"}, {Provider: "anthropic", Model: "example-model"}},
+	}
+
+	routes := config.routes("openai", "gpt-4o")
+	want := []Route{{"openai", "gpt-4o"}, {"anthropic", "example-model"}, {"ollama", "llama3.1"}}
+	if len(routes) != len(want) {
+		t.Fatalf("routes() = %v, want %v", routes, want)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("route %d = %v, want %v", i, routes[i], want[i])
+		}
+	}
+
+	if routes := config.routes("ollama", "llama3.1"); len(routes) != 2 || routes[1].Provider != "anthropic" {
+		t.Errorf("expected the requested fallback first, got %v", routes)
+	}
+}
+
+func TestProviderHealth(t *testing.T) {
+	now := time.Now()
+	health := NewProviderHealth()
+	health.now = func() time.Time { return now }
+	route := Route{Provider: "ollama", Model: "example-model"}
+
+	if health.state(route) != routeUp {
+		t.Fatal("expected a new route to be up")
+	}
+	health.markDown(route, time.Minute)
+	if health.state(route) != routeCoolingDown {
+		t.Error("expected the route to cool down")
+	}
+	now = now.Add(2 * time.Minute)
+	if health.state(route) != routeNeedsCheck {
+		t.Error("expected the route to be checked after its cooldown")
+	}
+	health.markUp(route)
+	if health.state(route) != routeUp {
+		t.Error("expected the route to be up")
+	}
+}
+
+func TestShouldFailover(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"rate limited", context.Background(), &chat.APIError{StatusCode: 429}, true},
+		{"server error", context.Background(), fmt.Errorf("failed: %w", &chat.APIError{StatusCode: 503}), true},
+		{"overloaded", context.Background(), &chat.APIError{StatusCode: 529}, true},
+		{"bad request", context.Background(), &chat.APIError{StatusCode: 400}, false},
+		{"unreachable", context.Background(), fmt.Errorf("failed to send request: %w", &url.Error{Op: "Post", URL: "http://localhost", Err: errors.New("connection refused")}), true},
+		{"cancelled", cancelled, &chat.APIError{StatusCode: 503}, false},
+		{"other", context.Background(), errors.New("failed to decode response"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldFailover(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("shouldFailover() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatWithFailover(t *testing.T) {
+	server := newModelServer(t)
+	now := time.Now()
+	health := NewProviderHealth()
+	health.now = func() time.Time { return now }
+	config := &Config{
+		Provider:  "ollama",
+		Model:     "primary-model",
+		OllamaURL: server.URL,
+		Fallback:  []Route{{Provider: "ollama", Model: "fallback-model"}},
+		Health:    health,
+	}
+	routes := config.routes(config.Provider, config.Model)
+	messages := []chat.Message{{Role: "user", Content: "Hello"}}
+
+	// The primary is unavailable, so the request fails over
+	server.setStatus("primary-model", http.StatusServiceUnavailable)
+	_, route, err := chatWithFailover(context.Background(), config, routes, false, messages, nil, nil)
+	if err != nil || route.Model != "fallback-model" {
+		t.Fatalf("chatWithFailover() = %v, %v; want the fallback", route, err)
+	}
+	if got := server.takeRequests(); len(got) != 2 {
+		t.Errorf("expected the primary and the fallback to be tried, got %v", got)
+	}
+
+	// During its cooldown the primary is skipped
+	if _, route, _ := chatWithFailover(context.Background(), config, routes, false, messages, nil, nil); route.Model != "fallback-model" {
+		t.Errorf("expected the fallback during the cooldown, got %v", route)
+	}
+	if got := server.takeRequests(); len(got) != 1 || got[0] != "fallback-model" {
+		t.Errorf("expected only the fallback to be tried, got %v", got)
+	}
+
+	// After the cooldown the primary is health checked and used again
+	server.setStatus("primary-model", http.StatusOK)
+	now = now.Add(DefaultFailoverCooldown + time.Second)
+	if _, route, err := chatWithFailover(context.Background(), config, routes, false, messages, nil, nil); err != nil || route.Model != "primary-model" {
+		t.Errorf("expected the primary after its cooldown, got %v, %v", route, err)
+	}
+
+	// Errors caused by the request are not retried with another provider
+	server.takeRequests()
+	server.setStatus("primary-model", http.StatusBadRequest)
+	if _, _, err := chatWithFailover(context.Background(), config, routes, false, messages, nil, nil); err == nil {
+		t.Error("expected the request's error")
+	}
+	if got := server.takeRequests(); len(got) != 1 {
+		t.Errorf("expected no failover for a bad request, got %v", got)
+	}
+
+	// A streamed response that has started is not retried
+	server.setStatus("primary-model", http.StatusServiceUnavailable)
+	if _, _, err := chatWithFailover(context.Background(), config, routes, false, messages, nil, func() bool { return true }); err == nil {
+		t.Error("expected the error of the started stream")
+	}
+	if got := server.takeRequests(); len(got) != 1 {
+		t.Errorf("expected no failover once streaming started, got %v", got)
+	}
+}
+
+func TestHandleChat_Failover(t *testing.T) {
+	server := newModelServer(t)
+	server.setStatus("primary-model", http.StatusTooManyRequests)
+	config := &Config{
+		Provider:  "ollama",
+		Model:     "primary-model",
+		OllamaURL: server.URL,
+		Fallback:  []Route{{Provider: "ollama", Model: "fallback-model"}},
+	}
+
+	bodyBytes, _ := json.Marshal(ChatRequest{Messages: []Message{{Role: "user", Content: "Hello"}}})
+	req := httptest.NewRequest(http.MethodPost, "/api/llm/chat", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+	HandleChat(w, req, config)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response ChatResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Provider != "ollama" || response.Model != "fallback-model" {
+		t.Errorf("expected the response to name the fallback, got %s/%s", response.Provider, response.Model)
+	}
+}
@@ -0,0 +1,162 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent - LLM Proxy
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package llmproxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/database"
+)
+
+const (
+	// maxSchemaTables bounds the number of tables in the schema summary
+	maxSchemaTables = 200
+	// maxCachedSchemas bounds the number of cached schema summaries
+	maxCachedSchemas = 64
+)
+
+// SchemaSource provides the database metadata used to build the schema
+// context block for chat requests
+type SchemaSource interface {
+	// Schema returns the metadata for the caller's current database and a
+	// version that changes whenever the metadata is reloaded
+	// ok is false if no metadata is available
+	Schema(ctx context.Context) (metadata map[string]database.TableInfo, version uint64, ok bool)
+}
+
+// clientManagerSchemaSource reads metadata from the caller's existing database client
+type clientManagerSchemaSource struct {
+	clientManager *database.ClientManager
+	authEnabled   bool
+}
+
+// NewClientManagerSchemaSource returns a SchemaSource backed by a client manager
+// It never opens connections; callers without a connected client get no schema
+func NewClientManagerSchemaSource(clientManager *database.ClientManager, authEnabled bool) SchemaSource {
+	return &clientManagerSchemaSource{
+		clientManager: clientManager,
+		authEnabled:   authEnabled,
+	}
+}
+
+func (s *clientManagerSchemaSource) Schema(ctx context.Context) (map[string]database.TableInfo, uint64, bool) {
+	key := "default"
+	if s.authEnabled {
+		key = auth.GetTokenHashFromContext(ctx)
+		if key == "" {
+			return nil, 0, false
+		}
+	}
+
+	client, ok := s.clientManager.LookupClient(key)
+	if !ok {
+		return nil, 0, false
+	}
+	version := client.GetMetadataVersion()
+	if version == 0 {
+		return nil, 0, false
+	}
+	return client.GetMetadata(), version, true
+}
+
+// schemaCache caches rendered schema summaries by metadata version so the
+// system prompt is byte-for-byte identical until the metadata changes,
+// which keeps it eligible for provider-side prompt caching
+type schemaCache struct {
+	mu        sync.Mutex
+	summaries map[uint64]string
+}
+
+var schemaSummaries = &schemaCache{summaries: make(map[uint64]string)}
+
+// get returns the cached summary for a metadata version, rendering it if needed
+func (c *schemaCache) get(metadata map[string]database.TableInfo, version uint64) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if summary, ok := c.summaries[version]; ok {
+		return summary
+	}
+
+	// Versions only increase, so dropping everything is a simple way to
+	// discard summaries for metadata that has since been reloaded
+	if len(c.summaries) >= maxCachedSchemas {
+		c.summaries = make(map[uint64]string)
+	}
+
+	summary := BuildSchemaSummary(metadata, maxSchemaTables)
+	c.summaries[version] = summary
+	return summary
+}
+
+// schemaContext returns the schema summary for the caller, or "" if unavailable
+func schemaContext(ctx context.Context, source SchemaSource) string {
+	metadata, version, ok := source.Schema(ctx)
+	if !ok || len(metadata) == 0 {
+		return ""
+	}
+	return schemaSummaries.get(metadata, version)
+}
+
+// BuildSchemaSummary renders a compact, deterministic description of the
+// tables and columns in metadata, listing at most maxTables tables
+func BuildSchemaSummary(metadata map[string]database.TableInfo, maxTables int) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("Database schema summary (use get_schema_info for full details):\n")
+
+	for i, key := range keys {
+		if i >= maxTables {
+			fmt.Fprintf(&sb, "- ... and %d more tables\n", len(keys)-maxTables)
+			break
+		}
+		table := metadata[key]
+		tableType := table.TableType
+		if table.RowSecurity {
+			tableType += ", row-level security"
+		}
+		fmt.Fprintf(&sb, "- %s.%s (%s): ", table.SchemaName, table.TableName, tableType)
+
+		columns := make([]string, 0, len(table.Columns))
+		for _, col := range table.Columns {
+			columns = append(columns, describeColumn(col))
+		}
+		sb.WriteString(strings.Join(columns, ", "))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// describeColumn returns a short description of a column with its key constraints
+func describeColumn(col database.ColumnInfo) string {
+	desc := col.ColumnName + " " + col.DataType
+	if col.IsPrimaryKey {
+		desc += " PK"
+	}
+	if col.IsUnique {
+		desc += " UNIQUE"
+	}
+	if col.ForeignKeyRef != "" {
+		desc += " FK->" + col.ForeignKeyRef
+	}
+	return desc
+}
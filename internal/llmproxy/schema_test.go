@@ -0,0 +1,120 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent - LLM Proxy Schema Context Tests
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package llmproxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+// staticSchemaSource returns fixed metadata for tests
+type staticSchemaSource struct {
+	metadata map[string]database.TableInfo
+	version  uint64
+}
+
+func (s *staticSchemaSource) Schema(ctx context.Context) (map[string]database.TableInfo, uint64, bool) {
+	return s.metadata, s.version, s.version != 0
+}
+
+func testMetadata() map[string]database.TableInfo {
+	return map[string]database.TableInfo{
+		"public.users": {
+			SchemaName: "public",
+			TableName:  "users",
+			TableType:  "TABLE",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+				{ColumnName: "email", DataType: "text", IsUnique: true},
+			},
+		},
+		"public.orders": {
+			SchemaName: "public",
+			TableName:  "orders",
+			TableType:  "TABLE",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+				{ColumnName: "user_id", DataType: "integer", ForeignKeyRef: "public.users.id"},
+			},
+		},
+	}
+}
+
+func TestBuildSchemaSummary(t *testing.T) {
+	summary := BuildSchemaSummary(testMetadata(), 10)
+
+	orders := strings.Index(summary, "- public.orders (TABLE): id integer PK, user_id integer FK->public.users.id")
+	users := strings.Index(summary, "- public.users (TABLE): id integer PK, email text UNIQUE")
+	if orders < 0 || users < 0 {
+		t.Fatalf("Summary missing expected tables:\n%s", summary)
+	}
+	if orders > users {
+		t.Error("Tables should be sorted by name")
+	}
+
+	// The summary must be stable so that it can be served from the prompt cache
+	for i := 0; i < 5; i++ {
+		if again := BuildSchemaSummary(testMetadata(), 10); again != summary {
+			t.Fatal("Summary should be deterministic")
+		}
+	}
+}
+
+func TestBuildSchemaSummary_Truncated(t *testing.T) {
+	summary := BuildSchemaSummary(testMetadata(), 1)
+	if strings.Contains(summary, "- public.users") {
+		t.Error("Summary should be limited to one table")
+	}
+	if !strings.Contains(summary, "... and 1 more tables") {
+		t.Errorf("Summary should note omitted tables:\n%s", summary)
+	}
+}
+
+func TestBuildSchemaSummary_RowSecurity(t *testing.T) {
+	metadata := testMetadata()
+	users := metadata["public.users"]
+	users.RowSecurity = true
+	metadata["public.users"] = users
+
+	summary := BuildSchemaSummary(metadata, 10)
+	if !strings.Contains(summary, "- public.users (TABLE, row-level security): ") {
+		t.Errorf("Summary should flag row-level security:\n%s", summary)
+	}
+}
+
+func TestSchemaContext_Invalidation(t *testing.T) {
+	source := &staticSchemaSource{metadata: testMetadata(), version: 1001}
+	first := schemaContext(context.Background(), source)
+	if first == "" {
+		t.Fatal("Expected a schema summary")
+	}
+
+	// Same version returns the cached summary even if the map changes
+	delete(source.metadata, "public.orders")
+	if got := schemaContext(context.Background(), source); got != first {
+		t.Error("Summary should be cached per metadata version")
+	}
+
+	// A new version (metadata reloaded) produces a fresh summary
+	source.version = 1002
+	if got := schemaContext(context.Background(), source); strings.Contains(got, "public.orders") {
+		t.Error("Summary should be rebuilt when the metadata version changes")
+	}
+
+	// No metadata means no schema context
+	source.version = 0
+	if got := schemaContext(context.Background(), source); got != "" {
+		t.Errorf("Expected empty context without metadata, got %q", got)
+	}
+}
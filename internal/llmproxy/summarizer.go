@@ -0,0 +1,92 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent - LLM Proxy
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package llmproxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/chat"
+)
+
+// ErrSummariesDisabled is returned by Summarizer when the LLM proxy or
+// compaction summaries are not enabled
+var ErrSummariesDisabled = errors.New("compaction summaries are not enabled")
+
+// summaryInstructions asks the LLM for a rolling summary of a conversation
+const summaryInstructions = `Summarize the earlier part of a conversation between a user and a PostgreSQL database assistant, so the assistant can continue the conversation without the original messages.
+
+Keep:
+- The user's goals and questions
+- The databases, schemas, tables, columns and queries discussed
+- Important results, numbers and findings
+- Conclusions, decisions, and changes made to the database
+- Errors and how they were resolved
+- Open questions and next steps
+
+Write concise bullet points, at most about 300 words. Reply with the summary only.`
+
+// Summarizer writes the rolling summaries of chat history compaction with
+// the configured LLM. It uses the current configuration of the store, so
+// summaries follow configuration reloads.
+type Summarizer struct {
+	store *ConfigStore
+}
+
+// NewSummarizer creates a summarizer using the store's configuration
+func NewSummarizer(store *ConfigStore) *Summarizer {
+	return &Summarizer{store: store}
+}
+
+// Summarize returns the previous summary updated with the transcript of
+// the messages being dropped
+func (s *Summarizer) Summarize(ctx context.Context, previous, transcript string) (string, error) {
+	config := s.store.Get()
+	if config == nil || !config.CompactionSummaries {
+		return "", ErrSummariesDisabled
+	}
+	client, err := newLLMClient(config, config.Provider, config.Model, false)
+	if err != nil {
+		return "", err
+	}
+
+	route := Route{Provider: config.Provider, Model: config.Model}
+	response, err := tracedChat(ctx, client, route, []chat.Message{{Role: "user", Content: summaryPrompt(previous, transcript)}}, nil)
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, item := range response.Content {
+		if t, ok := item.(chat.TextContent); ok {
+			text.WriteString(t.Text)
+		}
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		return "", errors.New("the LLM returned an empty summary")
+	}
+	return text.String(), nil
+}
+
+// summaryPrompt builds the request for a rolling summary
+func summaryPrompt(previous, transcript string) string {
+	var sb strings.Builder
+	sb.WriteString(summaryInstructions)
+	if previous != "" {
+		sb.WriteString("\n\nSummary of the conversation so far, to be updated:\n<summary>\n")
+		sb.WriteString(previous)
+		sb.WriteString("\n</summary>")
+	}
+	sb.WriteString("\n\nMessages to add to the summary:\n<messages>\n")
+	sb.WriteString(transcript)
+	sb.WriteString("\n</messages>")
+	return sb.String()
+}
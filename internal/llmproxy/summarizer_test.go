@@ -0,0 +1,47 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent - LLM Proxy Tests
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package llmproxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSummarizer_Disabled(t *testing.T) {
+	store := NewConfigStore(nil)
+	summarizer := NewSummarizer(store)
+
+	if _, err := summarizer.Summarize(context.Background(), "", "user: hello"); !errors.Is(err, ErrSummariesDisabled) {
+		t.Errorf("Expected ErrSummariesDisabled without a configuration, got %v", err)
+	}
+
+	store.Set(&Config{Provider: "ollama", Model: "example-model", OllamaURL: "http://localhost:11434"})
+	if _, err := summarizer.Summarize(context.Background(), "", "user: hello"); !errors.Is(err, ErrSummariesDisabled) {
+		t.Errorf("Expected ErrSummariesDisabled when summaries are off, got %v", err)
+	}
+}
+
+func TestSummaryPrompt(t *testing.T) {
+	prompt := summaryPrompt("", "user: How many orders?")
+	if !strings.Contains(prompt, "<messages>\nuser: How many orders?\n</messages>") {
+		t.Errorf("Expected the transcript in the prompt:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "<summary>") {
+		t.Error("Expected no previous summary in the prompt")
+	}
+
+	prompt = summaryPrompt("- Orders were counted", "user: And customers?")
+	if !strings.Contains(prompt, "<summary>\n- Orders were counted\n</summary>") {
+		t.Errorf("Expected the previous summary in the prompt:\n%s", prompt)
+	}
+}
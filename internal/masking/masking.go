@@ -0,0 +1,225 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package masking redacts or hashes sensitive values in query results
+// before they are returned to clients (and therefore to the LLM)
+package masking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+const (
+	// ActionRedact replaces the value with a fixed replacement string
+	ActionRedact = "redact"
+	// ActionHash replaces the value with a stable hash so equal values remain comparable
+	ActionHash = "hash"
+
+	// DefaultReplacement is used by redact rules without an explicit replacement
+	DefaultReplacement = "****"
+
+	// hashPrefixLength is the number of hex characters kept from the hash
+	hashPrefixLength = 16
+)
+
+// Column identifies a result column and the table it originates from
+type Column struct {
+	Table string // Qualified "schema.table" name, empty for computed columns
+	Name  string // Source column name; the result column name for computed columns
+}
+
+// rule is a compiled masking rule
+type rule struct {
+	table       *regexp.Regexp
+	column      *regexp.Regexp
+	value       *regexp.Regexp
+	action      string
+	replacement string
+}
+
+// Masker applies masking rules to query results
+// A nil Masker is valid and leaves results unchanged
+type Masker struct {
+	rules   []rule
+	hashKey []byte
+}
+
+// New compiles the masking configuration into a Masker
+// Returns nil (no masking) if masking is disabled or no rules are defined
+func New(cfg *config.MaskingConfig) (*Masker, error) {
+	if cfg == nil || !cfg.Enabled || len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+
+	m := &Masker{hashKey: []byte(cfg.HashKey)}
+	for i, r := range cfg.Rules {
+		if r.Column == "" && r.Value == "" {
+			return nil, fmt.Errorf("masking rule %d: a column or value pattern is required", i)
+		}
+
+		compiled := rule{
+			action:      r.Action,
+			replacement: r.Replacement,
+		}
+		if compiled.action == "" {
+			compiled.action = ActionRedact
+		}
+		if compiled.action != ActionRedact && compiled.action != ActionHash {
+			return nil, fmt.Errorf("masking rule %d: unknown action %q", i, r.Action)
+		}
+		if compiled.replacement == "" {
+			compiled.replacement = DefaultReplacement
+		}
+
+		var err error
+		if compiled.table, err = compileOptional(r.Table); err != nil {
+			return nil, fmt.Errorf("masking rule %d: invalid table pattern: %w", i, err)
+		}
+		if compiled.column, err = compileOptional(r.Column); err != nil {
+			return nil, fmt.Errorf("masking rule %d: invalid column pattern: %w", i, err)
+		}
+		if compiled.value, err = compileOptional(r.Value); err != nil {
+			return nil, fmt.Errorf("masking rule %d: invalid value pattern: %w", i, err)
+		}
+
+		m.rules = append(m.rules, compiled)
+	}
+
+	return m, nil
+}
+
+// compileOptional compiles a pattern, returning nil for an empty pattern
+func compileOptional(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// Enabled reports whether the masker has any rules to apply
+func (m *Masker) Enabled() bool {
+	return m != nil && len(m.rules) > 0
+}
+
+// columnRules returns, for each column, the rules that may apply to it
+// The source of a computed column (one without a source table), such as
+// "ssn::text", is unknown, so table-scoped rules match it by name alone
+func (m *Masker) columnRules(columns []Column) [][]*rule {
+	perColumn := make([][]*rule, len(columns))
+	for i, col := range columns {
+		for j := range m.rules {
+			r := &m.rules[j]
+			if r.table != nil && col.Table != "" && !r.table.MatchString(col.Table) {
+				continue
+			}
+			if r.column != nil && !r.column.MatchString(col.Name) {
+				continue
+			}
+			perColumn[i] = append(perColumn[i], r)
+		}
+	}
+	return perColumn
+}
+
+// MaskableColumns reports, for each column, whether any rule may change its
+// values, so callers that keep column types can treat those columns as text
+func (m *Masker) MaskableColumns(columns []Column) []bool {
+	maskable := make([]bool, len(columns))
+	if !m.Enabled() {
+		return maskable
+	}
+	for i, rules := range m.columnRules(columns) {
+		maskable[i] = len(rules) > 0
+	}
+	return maskable
+}
+
+// Apply masks values in place and returns the number of values that were masked
+// NULL values are never masked so that the presence of data remains visible
+func (m *Masker) Apply(columns []Column, rows [][]interface{}) int {
+	if !m.Enabled() {
+		return 0
+	}
+
+	perColumn := m.columnRules(columns)
+	masked := 0
+	for _, row := range rows {
+		for i := range row {
+			if i >= len(perColumn) || len(perColumn[i]) == 0 || row[i] == nil {
+				continue
+			}
+			if newVal, ok := m.maskValue(perColumn[i], row[i]); ok {
+				row[i] = newVal
+				masked++
+			}
+		}
+	}
+	return masked
+}
+
+// maskValue applies the first matching rule to a single value
+func (m *Masker) maskValue(rules []*rule, value interface{}) (interface{}, bool) {
+	text := stringify(value)
+	for _, r := range rules {
+		if r.value == nil {
+			return m.replace(r, text), true
+		}
+		if r.value.MatchString(text) {
+			return r.value.ReplaceAllStringFunc(text, func(match string) string {
+				return m.replace(r, match)
+			}), true
+		}
+	}
+	return nil, false
+}
+
+// replace produces the masked form of text according to the rule action
+func (m *Masker) replace(r *rule, text string) string {
+	if r.action == ActionHash {
+		return m.hash(text)
+	}
+	return r.replacement
+}
+
+// hash returns a short, stable digest of text
+// When a hash key is configured an HMAC is used so values cannot be
+// recovered by hashing a dictionary of candidates
+func (m *Masker) hash(text string) string {
+	var sum []byte
+	if len(m.hashKey) > 0 {
+		mac := hmac.New(sha256.New, m.hashKey)
+		mac.Write([]byte(text))
+		sum = mac.Sum(nil)
+	} else {
+		digest := sha256.Sum256([]byte(text))
+		sum = digest[:]
+	}
+	return "sha256:" + hex.EncodeToString(sum)[:hashPrefixLength]
+}
+
+// stringify converts a database value to the text that rules are matched against
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,207 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent - Masking Tests
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package masking
+
+import (
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+func TestNewDisabled(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.MaskingConfig
+	}{
+		{"nil config", nil},
+		{"disabled", &config.MaskingConfig{Enabled: false, Rules: []config.MaskingRule{{Column: "email"}}}},
+		{"no rules", &config.MaskingConfig{Enabled: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if m.Enabled() {
+				t.Error("Expected masker to be disabled")
+			}
+			rows := [][]interface{}{{"a@example.com"}}
+			if n := m.Apply([]Column{{Name: "email"}}, rows); n != 0 {
+				t.Errorf("Apply() masked %d values, want 0", n)
+			}
+		})
+	}
+}
+
+func TestNewInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.MaskingRule
+	}{
+		{"no patterns", config.MaskingRule{Table: "users"}},
+		{"bad column pattern", config.MaskingRule{Column: "("}},
+		{"bad table pattern", config.MaskingRule{Table: "[", Column: "email"}},
+		{"bad value pattern", config.MaskingRule{Value: "*"}},
+		{"unknown action", config.MaskingRule{Column: "email", Action: "encrypt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&config.MaskingConfig{Enabled: true, Rules: []config.MaskingRule{tt.rule}})
+			if err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestApplyColumnRules(t *testing.T) {
+	m, err := New(&config.MaskingConfig{
+		Enabled: true,
+		Rules: []config.MaskingRule{
+			{Table: `^public\.users$`, Column: `^ssn$`},
+			{Column: `(?i)email`, Action: "hash"},
+			{Column: `^phone$`, Replacement: "[hidden]"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	columns := []Column{
+		{Table: "public.users", Name: "id"},
+		{Table: "public.users", Name: "ssn"},
+		{Table: "public.users", Name: "Email"},
+		{Table: "public.users", Name: "phone"},
+		{Table: "", Name: "ssn"},
+	}
+	rows := [][]interface{}{
+		{1, "123-45-6789", "alice@example.com", "555-0100", "987-65-4321"},
+		{2, nil, "alice@example.com", nil, nil},
+	}
+
+	masked := m.Apply(columns, rows)
+	if masked != 5 {
+		t.Errorf("Apply() masked %d values, want 5", masked)
+	}
+
+	if rows[0][0] != 1 {
+		t.Errorf("id column changed: %v", rows[0][0])
+	}
+	if rows[0][1] != DefaultReplacement {
+		t.Errorf("ssn = %v, want %s", rows[0][1], DefaultReplacement)
+	}
+	hashed, ok := rows[0][2].(string)
+	if !ok || !strings.HasPrefix(hashed, "sha256:") {
+		t.Errorf("email = %v, want sha256 hash", rows[0][2])
+	}
+	if rows[1][2] != hashed {
+		t.Errorf("Equal values should hash identically: %v vs %v", rows[1][2], hashed)
+	}
+	if rows[0][3] != "[hidden]" {
+		t.Errorf("phone = %v, want [hidden]", rows[0][3])
+	}
+	// A computed column's source is unknown, so table-scoped rules match it by name
+	if rows[0][4] != DefaultReplacement {
+		t.Errorf("computed ssn = %v, want %s", rows[0][4], DefaultReplacement)
+	}
+	// NULLs are left alone
+	if rows[1][1] != nil || rows[1][3] != nil {
+		t.Error("NULL values should not be masked")
+	}
+}
+
+func TestMaskableColumns(t *testing.T) {
+	var disabled *Masker
+	if got := disabled.MaskableColumns([]Column{{Name: "ssn"}}); got[0] {
+		t.Error("A nil masker should not mask any column")
+	}
+
+	m, err := New(&config.MaskingConfig{
+		Enabled: true,
+		Rules:   []config.MaskingRule{{Table: `^public\.users$`, Column: `^ssn$`}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	got := m.MaskableColumns([]Column{
+		{Table: "public.users", Name: "id"},
+		{Table: "public.users", Name: "ssn"},
+		{Table: "public.orders", Name: "ssn"},
+		{Table: "", Name: "ssn"},
+	})
+	want := []bool{false, true, false, true}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("MaskableColumns()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestApplyValueRules(t *testing.T) {
+	m, err := New(&config.MaskingConfig{
+		Enabled: true,
+		Rules: []config.MaskingRule{
+			{Value: `\b\d{3}-\d{2}-\d{4}\b`, Replacement: "XXX-XX-XXXX"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	columns := []Column{{Name: "notes"}, {Name: "count"}}
+	rows := [][]interface{}{
+		{"customer ssn 123-45-6789 on file", 42},
+		{"nothing sensitive", 7},
+	}
+
+	masked := m.Apply(columns, rows)
+	if masked != 1 {
+		t.Errorf("Apply() masked %d values, want 1", masked)
+	}
+	if rows[0][0] != "customer ssn XXX-XX-XXXX on file" {
+		t.Errorf("notes = %q", rows[0][0])
+	}
+	if rows[1][0] != "nothing sensitive" {
+		t.Errorf("unmatched value changed: %q", rows[1][0])
+	}
+	if rows[0][1] != 42 {
+		t.Errorf("non-matching numeric value changed: %v", rows[0][1])
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	rules := []config.MaskingRule{{Column: "email", Action: "hash"}}
+	plain, err := New(&config.MaskingConfig{Enabled: true, Rules: rules})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	keyed, err := New(&config.MaskingConfig{Enabled: true, HashKey: "secret", Rules: rules})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	a := [][]interface{}{{"alice@example.com"}}
+	b := [][]interface{}{{"alice@example.com"}}
+	plain.Apply([]Column{{Name: "email"}}, a)
+	keyed.Apply([]Column{{Name: "email"}}, b)
+
+	if a[0][0] == b[0][0] {
+		t.Error("Keyed hash should differ from unkeyed hash")
+	}
+	if got := len(strings.TrimPrefix(b[0][0].(string), "sha256:")); got != hashPrefixLength {
+		t.Errorf("hash length = %d, want %d", got, hashPrefixLength)
+	}
+}
@@ -0,0 +1,81 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACMEConfig obtains and renews the HTTPS certificate automatically from
+// an ACME certificate authority such as Let's Encrypt, instead of loading
+// it from files
+type ACMEConfig struct {
+	Domains           []string // Host names to obtain certificates for; TLS handshakes for other names fail
+	CacheDir          string   // Directory that keeps the account key and certificates across restarts
+	DirectoryURL      string   // ACME directory URL (default: Let's Encrypt production)
+	Email             string   // Contact address for problems with certificates (optional)
+	HTTPChallengeAddr string   // Optional address, such as ":80", that answers HTTP-01 challenges and redirects other requests to HTTPS
+}
+
+// newACMEManager returns a certificate manager for config. Certificates are
+// obtained on the first TLS handshake for each domain and renewed before
+// they expire.
+func newACMEManager(config *ACMEConfig) (*autocert.Manager, error) {
+	if len(config.Domains) == 0 {
+		return nil, errors.New("ACME requires at least one domain")
+	}
+	if config.CacheDir == "" {
+		return nil, errors.New("ACME requires a cache directory")
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(config.CacheDir),
+		HostPolicy: autocert.HostWhitelist(config.Domains...),
+		Email:      config.Email,
+	}
+	if config.DirectoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: config.DirectoryURL}
+	}
+	return manager, nil
+}
+
+// acmeTLSConfig returns a TLS configuration that serves certificates from
+// manager and answers TLS-ALPN-01 challenges
+func acmeTLSConfig(manager *autocert.Manager) *tls.Config {
+	tlsConfig := manager.TLSConfig()
+	tlsConfig.MinVersion = tls.VersionTLS12
+	return tlsConfig
+}
+
+// serveACMEChallenges answers HTTP-01 challenges on addr until shutdown
+// begins. Other requests are redirected to HTTPS.
+func (s *Server) serveACMEChallenges(manager *autocert.Manager, addr string) {
+	challengeServer := &http.Server{
+		Addr:              addr,
+		Handler:           manager.HTTPHandler(nil),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-s.ShuttingDown()
+		_ = challengeServer.Close() //nolint:errcheck // Best effort; the server is stopping
+	}()
+	if err := challengeServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "WARNING: ACME HTTP challenge listener on %s stopped: %v\n", addr, err)
+	}
+}
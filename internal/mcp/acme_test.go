@@ -0,0 +1,55 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"golang.org/x/crypto/acme"
+)
+
+func TestNewACMEManager(t *testing.T) {
+	if _, err := newACMEManager(&ACMEConfig{CacheDir: t.TempDir()}); err == nil {
+		t.Error("expected an error without domains")
+	}
+	if _, err := newACMEManager(&ACMEConfig{Domains: []string{"mcp.example.com"}}); err == nil {
+		t.Error("expected an error without a cache directory")
+	}
+
+	manager, err := newACMEManager(&ACMEConfig{
+		Domains:      []string{"mcp.example.com"},
+		CacheDir:     t.TempDir(),
+		DirectoryURL: "https://acme-staging-v02.api.letsencrypt.org/directory",
+		Email:        "admin@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manager.Client == nil || manager.Client.DirectoryURL != "https://acme-staging-v02.api.letsencrypt.org/directory" {
+		t.Error("expected the configured directory URL")
+	}
+	if err := manager.HostPolicy(context.Background(), "mcp.example.com"); err != nil {
+		t.Errorf("expected the configured domain to be allowed: %v", err)
+	}
+	if err := manager.HostPolicy(context.Background(), "other.example.com"); err == nil {
+		t.Error("expected other domains to be refused")
+	}
+
+	tlsConfig := acmeTLSConfig(manager)
+	if !slices.Contains(tlsConfig.NextProtos, acme.ALPNProto) {
+		t.Errorf("expected TLS-ALPN-01 challenges to be answered, got %v", tlsConfig.NextProtos)
+	}
+	if tlsConfig.GetCertificate == nil {
+		t.Error("expected certificates to be served by the manager")
+	}
+}
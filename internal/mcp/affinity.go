@@ -0,0 +1,163 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/crypto"
+)
+
+const (
+	// ReplicaHeader names the replica that handled a request when session
+	// affinity is enabled
+	ReplicaHeader = "X-MCP-Replica"
+
+	// DefaultAffinityCookie is the default name of the session affinity cookie
+	DefaultAffinityCookie = "pgedge_mcp_replica"
+
+	// affinityKeyPurpose derives the cookie signing key from the server secret
+	affinityKeyPurpose = "session-affinity"
+)
+
+// replicaIDPattern limits replica IDs to characters that are safe in
+// session IDs, cookies and headers
+var replicaIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidateReplicaID checks that a replica ID can be used for session affinity
+func ValidateReplicaID(id string) error {
+	if !replicaIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid replica ID %q: use 1 to 64 letters, digits, '-' or '_'", id)
+	}
+	return nil
+}
+
+// AffinityConfig configures session affinity for servers running as several
+// replicas behind a load balancer. Sessions only exist on the replica that
+// created them, so the load balancer must send each session's requests to
+// that replica. Session IDs start with the replica ID, and a signed cookie
+// naming the replica is set when a session is created, so the load balancer
+// can route on either.
+type AffinityConfig struct {
+	ReplicaID  string                // Identifies this replica (required)
+	CookieName string                // Affinity cookie name (default: DefaultAffinityCookie)
+	Secret     *crypto.EncryptionKey // Server secret shared by all replicas; signs the cookie
+}
+
+// affinity implements session affinity for a Server
+type affinity struct {
+	replicaID  string
+	cookieName string
+	key        *crypto.EncryptionKey
+}
+
+// newAffinity validates the configuration and derives the signing key
+func newAffinity(config *AffinityConfig) (*affinity, error) {
+	if err := ValidateReplicaID(config.ReplicaID); err != nil {
+		return nil, err
+	}
+	if config.Secret == nil {
+		return nil, fmt.Errorf("session affinity requires a server secret")
+	}
+	key, err := config.Secret.DeriveKey(affinityKeyPurpose)
+	if err != nil {
+		return nil, err
+	}
+	cookieName := config.CookieName
+	if cookieName == "" {
+		cookieName = DefaultAffinityCookie
+	}
+	return &affinity{replicaID: config.ReplicaID, cookieName: cookieName, key: key}, nil
+}
+
+// setAffinity enables session affinity; sessions created afterwards carry
+// the replica ID
+func (s *Server) setAffinity(config *AffinityConfig) error {
+	a, err := newAffinity(config)
+	if err != nil {
+		return err
+	}
+	s.affinity = a
+	s.sessions.mu.Lock()
+	s.sessions.prefix = a.sessionPrefix()
+	s.sessions.mu.Unlock()
+	return nil
+}
+
+// middleware names this replica in every response, so misrouted requests
+// can be traced to the replica that received them
+func (a *affinity) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ReplicaHeader, a.replicaID)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionPrefix is prepended to the IDs of sessions created by this replica
+func (a *affinity) sessionPrefix() string {
+	return a.replicaID + "."
+}
+
+// cookie returns the affinity cookie for this replica
+func (a *affinity) cookie(secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     a.cookieName,
+		Value:    a.replicaID + "." + a.key.Sign(a.replicaID),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// cookieReplica returns the replica named by a correctly signed affinity
+// cookie, or "" if the request has none
+func (a *affinity) cookieReplica(r *http.Request) string {
+	cookie, err := r.Cookie(a.cookieName)
+	if err != nil {
+		return ""
+	}
+	replica, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !a.key.Verify(replica, signature) {
+		return ""
+	}
+	return replica
+}
+
+// sessionOwner returns the replica that created a session, from the
+// session ID or, for IDs without a replica, the affinity cookie
+func (a *affinity) sessionOwner(r *http.Request, sessionID string) string {
+	if replica, _, ok := strings.Cut(sessionID, "."); ok && replicaIDPattern.MatchString(replica) {
+		return replica
+	}
+	return a.cookieReplica(r)
+}
+
+// misrouted reports a request for a session created on another replica,
+// which means the load balancer is not keeping the session on one replica.
+// Returns false if the session belongs to this replica or its owner is
+// unknown.
+func (a *affinity) misrouted(w http.ResponseWriter, r *http.Request, sessionID string) bool {
+	owner := a.sessionOwner(r, sessionID)
+	if owner == "" || owner == a.replicaID {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "WARNING: Session affinity broken: session created on replica %q reached replica %q\n",
+		owner, a.replicaID)
+	http.Error(w, fmt.Sprintf("Session not found: the session was created on replica %q but this request "+
+		"reached replica %q. Configure the load balancer to route requests by the %s header or the %s cookie, "+
+		"then start a new session.", owner, a.replicaID, SessionIDHeader, a.cookieName), http.StatusNotFound)
+	return true
+}
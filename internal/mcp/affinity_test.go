@@ -0,0 +1,126 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/crypto"
+)
+
+// newReplica creates a server with session affinity for a replica
+func newReplica(t *testing.T, replicaID string, secret *crypto.EncryptionKey) *Server {
+	t.Helper()
+	server := NewServer(&mockToolProvider{})
+	if err := server.setAffinity(&AffinityConfig{ReplicaID: replicaID, Secret: secret}); err != nil {
+		t.Fatalf("failed to enable session affinity: %v", err)
+	}
+	return server
+}
+
+func TestSessionAffinity_SessionAndCookie(t *testing.T) {
+	secret, _ := crypto.GenerateKey()
+	server := newReplica(t, "replica-a", secret)
+
+	w := httptest.NewRecorder()
+	server.affinity.middleware(http.HandlerFunc(server.handleHTTPRequest)).ServeHTTP(w,
+		streamableRequest(t, http.MethodPost, "", JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "initialize"}))
+
+	sessionID := w.Header().Get(SessionIDHeader)
+	if !strings.HasPrefix(sessionID, "replica-a.") {
+		t.Errorf("expected the session ID to start with the replica, got %q", sessionID)
+	}
+	if got := w.Header().Get(ReplicaHeader); got != "replica-a" {
+		t.Errorf("expected the replica header, got %q", got)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != DefaultAffinityCookie || !cookies[0].HttpOnly {
+		t.Fatalf("expected the affinity cookie, got %v", cookies)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/mcp/v1", nil)
+	req.AddCookie(cookies[0])
+	if replica := server.affinity.cookieReplica(req); replica != "replica-a" {
+		t.Errorf("expected the cookie to name replica-a, got %q", replica)
+	}
+
+	// The session keeps working on its own replica
+	w = httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "tools/list"}))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 on the owning replica, got %d", w.Code)
+	}
+}
+
+func TestSessionAffinity_Misrouted(t *testing.T) {
+	secret, _ := crypto.GenerateKey()
+	replicaA := newReplica(t, "replica-a", secret)
+	replicaB := newReplica(t, "replica-b", secret)
+	sessionID := initializeSession(t, replicaA)
+
+	// A request for replica A's session reaches replica B
+	w := httptest.NewRecorder()
+	replicaB.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "tools/list"}))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{`created on replica "replica-a"`, `reached replica "replica-b"`, SessionIDHeader, DefaultAffinityCookie} {
+		if !strings.Contains(body, want) {
+			t.Errorf("error missing %q: %s", want, body)
+		}
+	}
+
+	// An expired session of this replica is an ordinary unknown session
+	w = httptest.NewRecorder()
+	replicaB.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, "replica-b.0123", JSONRPCRequest{JSONRPC: "2.0", ID: 3, Method: "tools/list"}))
+	if w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "created on replica") {
+		t.Errorf("expected a plain session not found error, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSessionAffinity_CookieOwner(t *testing.T) {
+	secret, _ := crypto.GenerateKey()
+	replicaA := newReplica(t, "replica-a", secret)
+	replicaB := newReplica(t, "replica-b", secret)
+
+	// Session IDs without a replica fall back to a correctly signed cookie
+	req := httptest.NewRequest(http.MethodPost, "/mcp/v1", nil)
+	req.AddCookie(replicaA.affinity.cookie(false))
+	if owner := replicaB.affinity.sessionOwner(req, "0123abcd"); owner != "replica-a" {
+		t.Errorf("expected the cookie's replica, got %q", owner)
+	}
+
+	other, _ := crypto.GenerateKey()
+	forged := newReplica(t, "replica-c", other)
+	req = httptest.NewRequest(http.MethodPost, "/mcp/v1", nil)
+	req.AddCookie(forged.affinity.cookie(false))
+	if owner := replicaB.affinity.sessionOwner(req, "0123abcd"); owner != "" {
+		t.Errorf("expected a cookie signed with another secret to be ignored, got %q", owner)
+	}
+}
+
+func TestSessionAffinity_Config(t *testing.T) {
+	secret, _ := crypto.GenerateKey()
+	if _, err := newAffinity(&AffinityConfig{ReplicaID: "bad.id", Secret: secret}); err == nil {
+		t.Error("expected an error for an invalid replica ID")
+	}
+	if _, err := newAffinity(&AffinityConfig{ReplicaID: "replica-a"}); err == nil {
+		t.Error("expected an error without a secret")
+	}
+	a, err := newAffinity(&AffinityConfig{ReplicaID: "replica-a", CookieName: "route", Secret: secret})
+	if err != nil || a.cookie(true).Name != "route" || !a.cookie(true).Secure {
+		t.Errorf("expected a secure cookie named route, got %+v, %v", a, err)
+	}
+}
@@ -0,0 +1,156 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// MaxCompletionValues is the most values a completion/complete response may
+// contain
+const MaxCompletionValues = 100
+
+// Completion reference types. ref/tool is an extension to the MCP
+// specification, which only defines completions for prompts and resources.
+const (
+	CompletionRefPrompt   = "ref/prompt"
+	CompletionRefResource = "ref/resource"
+	CompletionRefTool     = "ref/tool"
+)
+
+// CompletionProvider suggests values for prompt, resource and tool arguments
+type CompletionProvider interface {
+	Complete(ctx context.Context, params CompleteParams) (Completion, error)
+}
+
+// CompleteParams represents parameters for completion/complete
+type CompleteParams struct {
+	Ref      CompletionRef      `json:"ref"`
+	Argument CompletionArgument `json:"argument"`
+	Context  *CompletionContext `json:"context,omitempty"`
+}
+
+// CompletionRef identifies the prompt, resource or tool being completed
+type CompletionRef struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"` // ref/prompt and ref/tool
+	URI  string `json:"uri,omitempty"`  // ref/resource
+}
+
+// CompletionArgument is the argument being completed and its partial value
+type CompletionArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CompletionContext holds the values of arguments the user has already filled
+// in, so that a table can be completed within the chosen schema
+type CompletionContext struct {
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// ContextArgument returns an argument already filled in, or "" if there is none
+func (p CompleteParams) ContextArgument(name string) string {
+	if p.Context == nil {
+		return ""
+	}
+	return p.Context.Arguments[name]
+}
+
+// Completion holds the suggested values for an argument
+type Completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+// CompleteResult represents the result of completion/complete
+type CompleteResult struct {
+	Completion Completion `json:"completion"`
+}
+
+// SetCompletionProvider sets the completion provider for the server
+func (s *Server) SetCompletionProvider(completions CompletionProvider) {
+	s.completions = completions
+}
+
+// complete handles a completion/complete request for either transport
+func (s *Server) complete(ctx context.Context, params interface{}) (*CompleteResult, *RPCError) {
+	if s.completions == nil {
+		return nil, &RPCError{Code: -32601, Message: "Completions not supported"}
+	}
+
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		return nil, &RPCError{Code: -32602, Message: "Invalid params", Data: err.Error()}
+	}
+	var p CompleteParams
+	if err := json.Unmarshal(paramsBytes, &p); err != nil {
+		return nil, &RPCError{Code: -32602, Message: "Invalid params", Data: err.Error()}
+	}
+	switch p.Ref.Type {
+	case CompletionRefPrompt, CompletionRefTool:
+		if p.Ref.Name == "" {
+			return nil, &RPCError{Code: -32602, Message: "Invalid params", Data: "ref.name is required"}
+		}
+	case CompletionRefResource:
+		if p.Ref.URI == "" {
+			return nil, &RPCError{Code: -32602, Message: "Invalid params", Data: "ref.uri is required"}
+		}
+	default:
+		return nil, &RPCError{Code: -32602, Message: "Invalid params", Data: "unknown ref type: " + p.Ref.Type}
+	}
+	if p.Argument.Name == "" {
+		return nil, &RPCError{Code: -32602, Message: "Invalid params", Data: "argument.name is required"}
+	}
+
+	completion, err := s.completions.Complete(ctx, p)
+	if err != nil {
+		return nil, &RPCError{Code: -32603, Message: "Completion error", Data: err.Error()}
+	}
+
+	// Clients expect an array, and at most MaxCompletionValues entries
+	if completion.Values == nil {
+		completion.Values = []string{}
+	}
+	if len(completion.Values) > MaxCompletionValues {
+		if completion.Total < len(completion.Values) {
+			completion.Total = len(completion.Values)
+		}
+		completion.Values = completion.Values[:MaxCompletionValues]
+		completion.HasMore = true
+	}
+	return &CompleteResult{Completion: completion}, nil
+}
+
+func (s *Server) handleComplete(ctx context.Context, req JSONRPCRequest) {
+	result, rpcErr := s.complete(ctx, req.Params)
+	if rpcErr != nil {
+		sendError(req.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+		return
+	}
+
+	sendResponse(req.ID, result)
+}
+
+func (s *Server) handleCompleteHTTP(ctx context.Context, req JSONRPCRequest) JSONRPCResponse {
+	result, rpcErr := s.complete(ctx, req.Params)
+	if rpcErr != nil {
+		return createErrorResponse(req.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+	}
+
+	return JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
@@ -0,0 +1,136 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mockCompletionProvider records the last request and returns values
+type mockCompletionProvider struct {
+	values []string
+	last   CompleteParams
+}
+
+func (m *mockCompletionProvider) Complete(ctx context.Context, params CompleteParams) (Completion, error) {
+	m.last = params
+	return Completion{Values: m.values}, nil
+}
+
+// sendCompleteHTTP sends a completion/complete request and decodes the response
+func sendCompleteHTTP(t *testing.T, server *Server, params interface{}) JSONRPCResponse {
+	t.Helper()
+	body, _ := json.Marshal(JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "completion/complete",
+		Params:  params,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/mcp/v1", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	server.handleHTTPRequest(w, req)
+
+	var response JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestHandleCompleteHTTP_NoProvider(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+
+	response := sendCompleteHTTP(t, server, map[string]interface{}{
+		"ref":      map[string]interface{}{"type": "ref/prompt", "name": "explore-database"},
+		"argument": map[string]interface{}{"name": "table", "value": ""},
+	})
+	if response.Error == nil || response.Error.Code != -32601 {
+		t.Fatalf("expected method not supported error, got %+v", response.Error)
+	}
+}
+
+func TestHandleCompleteHTTP_Success(t *testing.T) {
+	completions := &mockCompletionProvider{values: []string{"orders", "order_items"}}
+	server := NewServer(&mockToolProvider{})
+	server.SetCompletionProvider(completions)
+
+	if _, ok := server.capabilities()["completions"]; !ok {
+		t.Error("expected completions capability")
+	}
+
+	response := sendCompleteHTTP(t, server, map[string]interface{}{
+		"ref":      map[string]interface{}{"type": "ref/tool", "name": "count_rows"},
+		"argument": map[string]interface{}{"name": "table", "value": "ord"},
+		"context":  map[string]interface{}{"arguments": map[string]string{"schema": "sales"}},
+	})
+	if response.Error != nil {
+		t.Fatalf("unexpected error: %+v", response.Error)
+	}
+
+	if completions.last.Ref.Name != "count_rows" || completions.last.Argument.Value != "ord" {
+		t.Errorf("unexpected params passed to provider: %+v", completions.last)
+	}
+	if got := completions.last.ContextArgument("schema"); got != "sales" {
+		t.Errorf("ContextArgument(schema) = %q, want sales", got)
+	}
+
+	result := response.Result.(map[string]interface{})
+	values := result["completion"].(map[string]interface{})["values"].([]interface{})
+	if len(values) != 2 || values[0] != "orders" {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestHandleCompleteHTTP_InvalidParams(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+	server.SetCompletionProvider(&mockCompletionProvider{})
+
+	tests := []map[string]interface{}{
+		{"ref": map[string]interface{}{"type": "ref/unknown", "name": "x"}, "argument": map[string]interface{}{"name": "table"}},
+		{"ref": map[string]interface{}{"type": "ref/prompt"}, "argument": map[string]interface{}{"name": "table"}},
+		{"ref": map[string]interface{}{"type": "ref/resource"}, "argument": map[string]interface{}{"name": "table"}},
+		{"ref": map[string]interface{}{"type": "ref/tool", "name": "count_rows"}, "argument": map[string]interface{}{}},
+	}
+	for _, params := range tests {
+		response := sendCompleteHTTP(t, server, params)
+		if response.Error == nil || response.Error.Code != -32602 {
+			t.Errorf("expected invalid params for %v, got %+v", params, response.Error)
+		}
+	}
+}
+
+func TestComplete_LimitsValues(t *testing.T) {
+	values := make([]string, 150)
+	for i := range values {
+		values[i] = fmt.Sprintf("table_%d", i)
+	}
+	server := NewServer(&mockToolProvider{})
+	server.SetCompletionProvider(&mockCompletionProvider{values: values})
+
+	result, rpcErr := server.complete(context.Background(), CompleteParams{
+		Ref:      CompletionRef{Type: CompletionRefPrompt, Name: "explore-database"},
+		Argument: CompletionArgument{Name: "table"},
+	})
+	if rpcErr != nil {
+		t.Fatalf("unexpected error: %+v", rpcErr)
+	}
+	if len(result.Completion.Values) != MaxCompletionValues || !result.Completion.HasMore || result.Completion.Total != 150 {
+		t.Errorf("expected %d values of 150 with more, got %d (total %d, hasMore %v)",
+			MaxCompletionValues, len(result.Completion.Values), result.Completion.Total, result.Completion.HasMore)
+	}
+}
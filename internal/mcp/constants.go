@@ -20,3 +20,10 @@ const (
 	// This prevents unbounded memory growth from malicious or malformed messages
 	ScannerMaxBufferSize = 1024 * 1024
 )
+
+// JSON-RPC error codes
+const (
+	// RateLimitExceededCode is returned when a caller exceeds a per-token quota
+	// It is in the implementation-defined server error range and maps to HTTP 429
+	RateLimitExceededCode = -32029
+)
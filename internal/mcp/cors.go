@@ -0,0 +1,83 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCORSHeaders are the request headers allowed from other origins by
+// default: those the MCP endpoint and the /api endpoints read
+var DefaultCORSHeaders = []string{"Authorization", "Content-Type", SessionIDHeader, "Last-Event-ID"}
+
+// CORSConfig allows browsers to call the server from other origins, such
+// as a web client hosted separately from the MCP server
+type CORSConfig struct {
+	AllowedOrigins   []string // Origins allowed to call the server; "*" allows any
+	AllowedHeaders   []string // Request headers allowed (default: DefaultCORSHeaders)
+	AllowCredentials bool     // Allow requests with cookies
+	MaxAgeSeconds    int      // How long browsers may cache preflight results (0 = browser default)
+}
+
+// allowsOrigin reports whether requests from origin are allowed
+func (c *CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware adds CORS headers to responses for allowed origins and
+// answers preflight requests, which carry no credentials, before they
+// reach authentication. Requests from other origins are passed on without
+// CORS headers, so browsers block their responses.
+func corsMiddleware(config *CORSConfig, next http.Handler) http.Handler {
+	headers := config.AllowedHeaders
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+	allowHeaders := strings.Join(headers, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !config.allowsOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		// The origin is echoed rather than "*", which browsers reject for
+		// requests with credentials
+		h.Set("Access-Control-Allow-Origin", origin)
+		if config.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+			if config.MaxAgeSeconds > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAgeSeconds))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Let scripts read the session ID of the MCP endpoint
+		h.Set("Access-Control-Expose-Headers", SessionIDHeader)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,98 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := corsMiddleware(&CORSConfig{
+		AllowedOrigins:   []string{"https://gui.example.com"},
+		AllowCredentials: true,
+		MaxAgeSeconds:    600,
+	}, next)
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		wantOrigin  string
+		wantCalled  bool
+		wantStatus  int
+		wantHeaders string
+	}{
+		{name: "same origin", method: http.MethodPost, wantCalled: true, wantStatus: http.StatusOK},
+		{name: "allowed origin", method: http.MethodPost, origin: "https://gui.example.com", wantOrigin: "https://gui.example.com", wantCalled: true, wantStatus: http.StatusOK},
+		{name: "other origin", method: http.MethodPost, origin: "https://evil.example.com", wantCalled: true, wantStatus: http.StatusOK},
+		{name: "preflight", method: http.MethodOptions, origin: "https://gui.example.com", preflight: true, wantOrigin: "https://gui.example.com", wantStatus: http.StatusNoContent, wantHeaders: "Authorization, Content-Type, Mcp-Session-Id, Last-Event-ID"},
+		{name: "preflight from other origin", method: http.MethodOptions, origin: "https://evil.example.com", preflight: true, wantCalled: true, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = false
+			req := httptest.NewRequest(tt.method, "/mcp/v1", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); got != tt.wantHeaders {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, tt.wantHeaders)
+			}
+			if tt.wantOrigin != "" && w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("expected credentials to be allowed")
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_AnyOrigin(t *testing.T) {
+	handler := corsMiddleware(&CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"Authorization"}},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/conversations", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:5173" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Authorization" {
+		t.Errorf("Access-Control-Allow-Headers = %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" || w.Header().Get("Access-Control-Max-Age") != "" {
+		t.Error("expected no credentials or max age")
+	}
+}
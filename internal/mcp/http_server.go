@@ -14,12 +14,17 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/tracing"
 )
 
 // HTTPConfig holds configuration for HTTP/HTTPS server mode
@@ -34,6 +39,17 @@ type HTTPConfig struct {
 	UserStore     *auth.UserStore                // User store for session token authentication
 	SetupHandlers func(mux *http.ServeMux) error // Optional callback to add custom handlers before auth middleware
 	Debug         bool                           // Enable debug logging
+	Affinity      *AffinityConfig                // Optional session affinity for replicas behind a load balancer
+	CORS          *CORSConfig                    // Optional cross-origin access for browser clients
+	ACME          *ACMEConfig                    // Optional automatic certificates, used instead of CertFile, KeyFile and ChainFile
+
+	// Limits guard against clients that send oversized or slow requests
+	MaxHeaderBytes int              // Size limit of request headers (0 = net/http default of 1 MB)
+	MaxBodyBytes   int64            // Size limit of request bodies (0 = unlimited)
+	BodyLimits     map[string]int64 // Body size limits for path prefixes, replacing MaxBodyBytes (0 = unlimited)
+	ReadTimeout    time.Duration    // Time to read a request, including its body (0 = no timeout)
+	WriteTimeout   time.Duration    // Time to write a response; streamed responses are cut off too (0 = no timeout)
+	IdleTimeout    time.Duration    // Time keep-alive connections wait for the next request (0 = ReadTimeout)
 }
 
 // RunHTTP starts the MCP server in HTTP/HTTPS mode
@@ -45,10 +61,18 @@ func (s *Server) RunHTTP(config *HTTPConfig) error {
 	// Store debug flag for use in handlers
 	s.debug = config.Debug
 
+	if config.Affinity != nil {
+		if err := s.setAffinity(config.Affinity); err != nil {
+			return fmt.Errorf("failed to configure session affinity: %w", err)
+		}
+	}
+
 	// Create HTTP handler
 	mux := http.NewServeMux()
 	mux.HandleFunc("/mcp/v1", s.handleHTTPRequest)
-	mux.HandleFunc("/health", s.handleHealthCheck)
+	mux.HandleFunc(auth.HealthCheckPath, s.handleHealthCheck)
+	mux.HandleFunc(auth.LivenessPath, s.handleLiveness)
+	mux.HandleFunc(auth.ReadinessPath, s.handleReadiness)
 
 	// Call custom handler setup if provided (allows main.go to add LLM proxy endpoints)
 	if config.SetupHandlers != nil {
@@ -58,18 +82,45 @@ func (s *Server) RunHTTP(config *HTTPConfig) error {
 	}
 
 	// Wrap with auth middleware if enabled
-	var handler http.Handler = mux
+	var handler http.Handler = bodyLimitMiddleware(config, mux)
 	if config.AuthEnabled {
 		handler = auth.AuthMiddleware(config.TokenStore, config.UserStore, true)(handler)
 	}
+	if s.affinity != nil {
+		handler = s.affinity.middleware(handler)
+	}
+	if config.CORS != nil && len(config.CORS.AllowedOrigins) > 0 {
+		handler = corsMiddleware(config.CORS, handler)
+	}
 
 	// Configure server
+	// Request contexts derive from the drain context so that Shutdown can
+	// cancel requests that outlive the drain timeout
 	httpServer := &http.Server{
-		Addr:    config.Addr,
-		Handler: handler,
+		Addr:           config.Addr,
+		Handler:        s.drainMiddleware(handler),
+		BaseContext:    func(net.Listener) context.Context { return s.drain.ctx },
+		MaxHeaderBytes: config.MaxHeaderBytes,
+		ReadTimeout:    config.ReadTimeout,
+		WriteTimeout:   config.WriteTimeout,
+		IdleTimeout:    config.IdleTimeout,
+	}
+	if !s.setHTTPServer(httpServer) {
+		return nil
 	}
 
 	// Start server with or without TLS
+	if config.TLSEnable && config.ACME != nil {
+		manager, err := newACMEManager(config.ACME)
+		if err != nil {
+			return fmt.Errorf("failed to configure ACME: %w", err)
+		}
+		httpServer.TLSConfig = acmeTLSConfig(manager)
+		if config.ACME.HTTPChallengeAddr != "" {
+			go s.serveACMEChallenges(manager, config.ACME.HTTPChallengeAddr)
+		}
+		return ignoreServerClosed(httpServer.ListenAndServeTLS("", ""))
+	}
 	if config.TLSEnable {
 		// Load TLS configuration
 		tlsConfig, err := s.loadTLSConfig(config)
@@ -78,44 +129,103 @@ func (s *Server) RunHTTP(config *HTTPConfig) error {
 		}
 		httpServer.TLSConfig = tlsConfig
 
-		return httpServer.ListenAndServeTLS(config.CertFile, config.KeyFile)
+		// Certificates are served from the TLS config so they can be reloaded
+		return ignoreServerClosed(httpServer.ListenAndServeTLS("", ""))
 	}
 
-	return httpServer.ListenAndServe()
+	return ignoreServerClosed(httpServer.ListenAndServe())
+}
+
+// ignoreServerClosed treats the error returned after Shutdown as a clean exit
+func ignoreServerClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
 // loadTLSConfig loads TLS certificates and creates a TLS configuration
+// The certificate is served through GetCertificate so that ReloadTLSCertificate
+// can replace it without restarting the listener
 func (s *Server) loadTLSConfig(config *HTTPConfig) (*tls.Config, error) {
-	// Load certificate and key
-	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	cert, err := loadCertificate(config.CertFile, config.KeyFile, config.ChainFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load certificate and key: %w", err)
+		return nil, err
 	}
 
+	s.tlsMu.Lock()
+	s.tlsCert = cert
+	s.tlsMu.Unlock()
+
 	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			s.tlsMu.RLock()
+			defer s.tlsMu.RUnlock()
+			return s.tlsCert, nil
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return tlsConfig, nil
+}
+
+// ReloadTLSCertificate replaces the certificate served by a running HTTPS server
+// New TLS handshakes use the new certificate; established connections are unaffected
+// The current certificate is kept if the new one cannot be loaded
+func (s *Server) ReloadTLSCertificate(certFile, keyFile, chainFile string) error {
+	s.tlsMu.RLock()
+	running := s.tlsCert != nil
+	s.tlsMu.RUnlock()
+	if !running {
+		return fmt.Errorf("HTTPS is not enabled")
+	}
+
+	cert, err := loadCertificate(certFile, keyFile, chainFile)
+	if err != nil {
+		return err
+	}
+
+	s.tlsMu.Lock()
+	s.tlsCert = cert
+	s.tlsMu.Unlock()
+	return nil
+}
+
+// loadCertificate loads a certificate, key and optional chain from disk
+func loadCertificate(certFile, keyFile, chainFile string) (*tls.Certificate, error) {
+	// Load certificate and key
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate and key: %w", err)
 	}
 
 	// Load certificate chain if provided
-	if config.ChainFile != "" {
-		chainData, err := os.ReadFile(config.ChainFile)
+	if chainFile != "" {
+		chainData, err := os.ReadFile(chainFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read certificate chain: %w", err)
 		}
 
 		// Append chain to certificate
 		cert.Certificate = append(cert.Certificate, chainData)
-		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
 
-	return tlsConfig, nil
+	return &cert, nil
 }
 
 // handleHTTPRequest handles HTTP requests and translates them to JSON-RPC
+// POST carries JSON-RPC messages; GET and DELETE are part of the Streamable
+// HTTP transport and open a server message stream or end a session
 func (s *Server) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
-	// Only accept POST requests
-	if r.Method != http.MethodPost {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodGet:
+		s.handleSSEStream(w, r)
+		return
+	case http.MethodDelete:
+		s.handleSessionDelete(w, r)
+		return
+	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -124,8 +234,15 @@ func (s *Server) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
 	ipAddress := auth.ExtractIPAddress(r)
 	ctx := context.WithValue(r.Context(), auth.IPAddressContextKey, ipAddress)
 
+	// Continue the caller's trace, if it sent a traceparent header
+	ctx = tracing.Extract(ctx, r.Header)
+
 	// Read request body
 	body, err := io.ReadAll(r.Body)
+	if isBodyTooLarge(err) {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
@@ -153,9 +270,63 @@ func (s *Server) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Streamable HTTP clients identify their session with a header
+	sess, ok := s.lookupSession(w, r)
+	if !ok {
+		return
+	}
+	if sess != nil {
+		ctx = withSession(ctx, sess)
+	}
+	streaming := acceptsEventStream(r)
+
+	// Responses to server requests such as sampling/createMessage are
+	// delivered to the tool call waiting for them
+	if response, ok := parseClientResponse(body); ok {
+		if sess == nil || !s.pending.deliver(sess.id, response) {
+			http.Error(w, "Unknown request ID", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// The session's roots are listed again when they are next needed
+	if req.Method == RootsListChangedMethod && sess != nil {
+		sess.roots.invalidate()
+	}
+
+	// Notifications have no response in the Streamable HTTP transport
+	if streaming && req.ID == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Potentially long-running calls are streamed so that results are not
+	// lost to proxy timeouts and can be resumed after a reconnect
+	if streaming && streamsResponse(req.Method) {
+		s.streamResponse(w, r, ctx, sess, req)
+		return
+	}
+
 	// Handle the request and capture the response (pass context with IP address)
 	response := s.handleRequestHTTP(ctx, req)
 
+	// Streamable HTTP clients get a session when they initialize
+	if streaming && req.Method == "initialize" && response.Error == nil && sess == nil {
+		newSession, err := s.sessions.create(auth.GetTokenHashFromContext(r.Context()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %v\n", err)
+		} else {
+			newSession.sampling.Store(clientSupportsSampling(req.Params))
+			newSession.roots.reset(clientSupportsRoots(req.Params))
+			w.Header().Set(SessionIDHeader, newSession.id)
+			if s.affinity != nil {
+				http.SetCookie(w, s.affinity.cookie(r.TLS != nil))
+			}
+		}
+	}
+
 	// Debug logging: log outgoing response
 	if s.debug {
 		if responseJSON, err := json.Marshal(response); err == nil {
@@ -164,8 +335,19 @@ func (s *Server) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send response
+	// Rate limit errors are also reported at the HTTP level so clients and
+	// proxies can back off using the standard status code and header
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	if response.Error != nil && response.Error.Code == RateLimitExceededCode {
+		if data, ok := response.Error.Data.(map[string]interface{}); ok {
+			if retryAfter, ok := data["retry_after_seconds"].(int); ok && retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			}
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: Failed to encode response: %v\n", err)
@@ -173,11 +355,14 @@ func (s *Server) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleRequestHTTP handles a JSON-RPC request and returns the response
-func (s *Server) handleRequestHTTP(ctx context.Context, req JSONRPCRequest) JSONRPCResponse {
+func (s *Server) handleRequestHTTP(ctx context.Context, req JSONRPCRequest) (response JSONRPCResponse) {
+	ctx, span := startRequestSpan(ctx, req)
+	defer func() { endRequestSpan(span, response.Error) }()
+
 	switch req.Method {
 	case "initialize":
 		return s.handleInitializeHTTP(req)
-	case "notifications/initialized":
+	case "notifications/initialized", RootsListChangedMethod:
 		// Client notification - return empty response
 		return JSONRPCResponse{
 			JSONRPC: "2.0",
@@ -185,17 +370,21 @@ func (s *Server) handleRequestHTTP(ctx context.Context, req JSONRPCRequest) JSON
 			Result:  json.RawMessage(`{}`),
 		}
 	case "tools/list":
-		return s.handleToolsListHTTP(req)
+		return s.handleToolsListHTTP(ctx, req)
 	case "tools/call":
 		return s.handleToolCallHTTP(ctx, req)
 	case "resources/list":
 		return s.handleResourcesListHTTP(req)
 	case "resources/read":
 		return s.handleResourceReadHTTP(ctx, req)
+	case "resources/subscribe", "resources/unsubscribe":
+		return s.handleResourceSubscribeHTTP(ctx, req)
 	case "prompts/list":
 		return s.handlePromptsListHTTP(req)
 	case "prompts/get":
 		return s.handlePromptGetHTTP(req)
+	case "completion/complete":
+		return s.handleCompleteHTTP(ctx, req)
 	case "pgedge/listDatabases":
 		return s.handleListDatabasesHTTP(ctx, req)
 	case "pgedge/selectDatabase":
@@ -208,23 +397,18 @@ func (s *Server) handleRequestHTTP(ctx context.Context, req JSONRPCRequest) JSON
 // HTTP-specific handlers that return responses instead of sending them
 
 func (s *Server) handleInitializeHTTP(req JSONRPCRequest) JSONRPCResponse {
-	capabilities := map[string]interface{}{
-		"tools": map[string]interface{}{},
-	}
-
-	// Add resources capability if resource provider is set
-	if s.resources != nil {
-		capabilities["resources"] = map[string]interface{}{}
-	}
-
-	// Add prompts capability if prompt provider is set
-	if s.prompts != nil {
-		capabilities["prompts"] = map[string]interface{}{}
+	// Use the client's protocol version if it is one we support
+	protocolVersion := ProtocolVersion
+	if paramsJSON, err := json.Marshal(req.Params); err == nil {
+		var params InitializeParams
+		if err := json.Unmarshal(paramsJSON, &params); err == nil && isSupportedProtocolVersion(params.ProtocolVersion) {
+			protocolVersion = params.ProtocolVersion
+		}
 	}
 
 	result := InitializeResult{
-		ProtocolVersion: ProtocolVersion,
-		Capabilities:    capabilities,
+		ProtocolVersion: protocolVersion,
+		Capabilities:    s.capabilities(),
 		ServerInfo: Implementation{
 			Name:    ServerName,
 			Version: ServerVersion,
@@ -238,8 +422,8 @@ func (s *Server) handleInitializeHTTP(req JSONRPCRequest) JSONRPCResponse {
 	}
 }
 
-func (s *Server) handleToolsListHTTP(req JSONRPCRequest) JSONRPCResponse {
-	tools := s.tools.List()
+func (s *Server) handleToolsListHTTP(ctx context.Context, req JSONRPCRequest) JSONRPCResponse {
+	tools := s.listTools(ctx)
 	result := ToolsListResult{Tools: tools}
 
 	return JSONRPCResponse{
@@ -265,6 +449,13 @@ func (s *Server) handleToolCallHTTP(ctx context.Context, req JSONRPCRequest) JSO
 	// Pass context for per-token connection isolation
 	response, err := s.tools.Execute(ctx, params.Name, params.Arguments)
 	if err != nil {
+		var quotaErr *auth.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			return createErrorResponse(req.ID, RateLimitExceededCode, "Rate limit exceeded", map[string]interface{}{
+				"reason":              quotaErr.Error(),
+				"retry_after_seconds": quotaErr.RetryAfterSeconds(),
+			})
+		}
 		return createErrorResponse(req.ID, -32603, "Internal error", err.Error())
 	}
 
@@ -319,6 +510,31 @@ func (s *Server) handleResourceReadHTTP(ctx context.Context, req JSONRPCRequest)
 	}
 }
 
+func (s *Server) handleResourceSubscribeHTTP(ctx context.Context, req JSONRPCRequest) JSONRPCResponse {
+	// Notifications are delivered on the session's GET stream, so plain
+	// JSON clients without a session cannot subscribe
+	sess := sessionFromContext(ctx)
+	if sess == nil {
+		return createErrorResponse(req.ID, -32600, "Invalid Request",
+			"resource subscriptions require a Streamable HTTP session ("+SessionIDHeader+" header)")
+	}
+
+	client := &subscriber{
+		send: func(data []byte) { sess.record(data) },
+		ctx:  context.WithoutCancel(ctx),
+		done: sess.ctx.Done(),
+	}
+	if rpcErr := s.subscribeResource(sess.id, client, req.Params, req.Method == "resources/subscribe"); rpcErr != nil {
+		return createErrorResponse(req.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+	}
+
+	return JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  map[string]interface{}{},
+	}
+}
+
 func (s *Server) handlePromptsListHTTP(req JSONRPCRequest) JSONRPCResponse {
 	if s.prompts == nil {
 		return createErrorResponse(req.ID, -32601, "Prompts not supported", nil)
@@ -431,11 +647,51 @@ func (s *Server) handleSelectDatabaseHTTP(ctx context.Context, req JSONRPCReques
 	}
 }
 
+// Health check states
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusDegraded    = "degraded"    // The server runs with a component unavailable
+	HealthStatusUnavailable = "unavailable" // State of a component that can't be used
+	HealthStatusWarning     = "warning"     // State of a custom health probe that reported warn
+	HealthStatusCritical    = "critical"    // State of a custom health probe that reported crit
+)
+
+// HealthComponent is the state of an optional server component, such as
+// the knowledgebase, reported by the health check
+type HealthComponent struct {
+	Status string `json:"status"`           // HealthStatusOK, HealthStatusUnavailable, or for probes HealthStatusWarning or HealthStatusCritical
+	Reason string `json:"reason,omitempty"` // Why the component is unavailable
+}
+
+// healthResponse is the body of the health check endpoint
+type healthResponse struct {
+	Status     string                     `json:"status"`
+	Server     string                     `json:"server"`
+	Version    string                     `json:"version"`
+	Components map[string]HealthComponent `json:"components,omitempty"`
+}
+
 // handleHealthCheck provides a simple health check endpoint
+// The server is reported as degraded, with status 200 since it still serves
+// requests, while an optional component is unavailable
 func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
+	response := healthResponse{Status: HealthStatusOK, Server: ServerName, Version: ServerVersion}
+
+	s.capMu.RLock()
+	if len(s.health) > 0 {
+		response.Components = make(map[string]HealthComponent, len(s.health))
+		for name, component := range s.health {
+			response.Components[name] = component
+			if component.Status != HealthStatusOK {
+				response.Status = HealthStatusDegraded
+			}
+		}
+	}
+	s.capMu.RUnlock()
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if _, err := fmt.Fprintf(w, `{"status":"ok","server":"%s","version":"%s"}`, ServerName, ServerVersion); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		fmt.Fprintf(os.Stderr, "WARNING: Failed to write health check response: %v\n", err)
 	}
 }
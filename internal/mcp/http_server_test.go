@@ -18,6 +18,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/auth"
 )
 
 func TestHandleHealthCheck(t *testing.T) {
@@ -60,6 +63,42 @@ func TestHandleHealthCheck(t *testing.T) {
 	}
 }
 
+func TestHandleHealthCheck_Components(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+	server.SetHealthComponent("knowledgebase", &HealthComponent{Status: HealthStatusOK})
+
+	check := func() healthResponse {
+		w := httptest.NewRecorder()
+		server.handleHealthCheck(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+		var response healthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return response
+	}
+
+	if response := check(); response.Status != HealthStatusOK || response.Components["knowledgebase"].Status != HealthStatusOK {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	server.SetHealthComponent("knowledgebase", &HealthComponent{Status: HealthStatusUnavailable, Reason: "file missing"})
+	response := check()
+	if response.Status != HealthStatusDegraded {
+		t.Errorf("expected status %q, got %q", HealthStatusDegraded, response.Status)
+	}
+	if response.Components["knowledgebase"].Reason != "file missing" {
+		t.Errorf("expected the component's reason, got %+v", response.Components)
+	}
+
+	server.SetHealthComponent("knowledgebase", nil)
+	if response := check(); response.Status != HealthStatusOK || response.Components != nil {
+		t.Errorf("expected the component to be removed, got %+v", response)
+	}
+}
+
 func TestHandleHTTPRequest_MethodNotAllowed(t *testing.T) {
 	tools := &mockToolProvider{}
 	server := NewServer(tools)
@@ -194,6 +233,26 @@ func TestHandleInitializeHTTP_WithProviders(t *testing.T) {
 	}
 }
 
+func TestHandleInitializeHTTP_ExperimentalCapabilities(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+	server.SetExperimentalCapability("pgedge/offline", map[string]interface{}{"enabled": true})
+
+	caps := server.capabilities()
+	experimental, ok := caps["experimental"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected experimental capabilities")
+	}
+	if _, ok := experimental["pgedge/offline"]; !ok {
+		t.Error("expected pgedge/offline capability")
+	}
+
+	// Setting nil removes the capability
+	server.SetExperimentalCapability("pgedge/offline", nil)
+	if _, ok := server.capabilities()["experimental"]; ok {
+		t.Error("expected experimental capabilities to be removed")
+	}
+}
+
 func TestHandleToolsListHTTP(t *testing.T) {
 	tools := &mockToolProvider{
 		tools: []Tool{
@@ -309,6 +368,56 @@ func TestHandleToolCallHTTP_ExecutionError(t *testing.T) {
 	}
 }
 
+func TestHandleToolCallHTTP_QuotaExceeded(t *testing.T) {
+	tools := &mockToolProvider{
+		executeFunc: func(ctx context.Context, name string, args map[string]interface{}) (ToolResponse, error) {
+			return ToolResponse{}, &auth.QuotaExceededError{Limit: "10 tool calls per minute", RetryAfter: 30 * time.Second}
+		},
+	}
+	server := NewServer(tools)
+
+	rpcReq := JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name": "query_database",
+		},
+	}
+
+	body, _ := json.Marshal(rpcReq)
+	req := httptest.NewRequest(http.MethodPost, "/mcp/v1", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	server.handleHTTPRequest(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, got %q", got)
+	}
+
+	var response JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Error == nil {
+		t.Fatal("expected error response")
+	}
+	if response.Error.Code != RateLimitExceededCode {
+		t.Errorf("expected rate limit code %d, got %d", RateLimitExceededCode, response.Error.Code)
+	}
+	data, ok := response.Error.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected error data object, got %T", response.Error.Data)
+	}
+	if data["retry_after_seconds"] != float64(30) {
+		t.Errorf("expected retry_after_seconds 30, got %v", data["retry_after_seconds"])
+	}
+}
+
 func TestHandleResourcesListHTTP_NoProvider(t *testing.T) {
 	tools := &mockToolProvider{}
 	server := NewServer(tools)
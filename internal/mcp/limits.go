@@ -0,0 +1,53 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// bodyLimit returns the size limit of the body of a request to path: the
+// limit of the longest matching prefix in BodyLimits, otherwise
+// MaxBodyBytes. 0 means unlimited.
+func (c *HTTPConfig) bodyLimit(path string) int64 {
+	limit, matched := c.MaxBodyBytes, ""
+	for prefix, l := range c.BodyLimits {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			limit, matched = l, prefix
+		}
+	}
+	return limit
+}
+
+// bodyLimitMiddleware caps the size of request bodies. Requests that
+// declare a larger body are rejected at once; others fail with
+// http.MaxBytesError once handlers read past the limit.
+func bodyLimitMiddleware(config *HTTPConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := config.bodyLimit(r.URL.Path)
+		if limit > 0 {
+			if r.ContentLength > limit {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isBodyTooLarge reports whether err is a read past the body size limit
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
@@ -0,0 +1,89 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPConfigBodyLimit(t *testing.T) {
+	config := &HTTPConfig{
+		MaxBodyBytes: 100,
+		BodyLimits:   map[string]int64{"/api/": 200, "/api/upload": 0},
+	}
+	tests := []struct {
+		path string
+		want int64
+	}{
+		{"/mcp/v1", 100},
+		{"/api/llm/chat", 200},
+		{"/api/upload", 0},
+	}
+	for _, tt := range tests {
+		if got := config.bodyLimit(tt.path); got != tt.want {
+			t.Errorf("bodyLimit(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			if isBodyTooLarge(err) {
+				http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := bodyLimitMiddleware(&HTTPConfig{MaxBodyBytes: 10}, next)
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		want          int
+	}{
+		{name: "within the limit", body: "0123456789", contentLength: 10, want: http.StatusOK},
+		{name: "declared too large", body: "01234567890", contentLength: 11, want: http.StatusRequestEntityTooLarge},
+		{name: "streamed too large", body: "01234567890", contentLength: -1, want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp/v1", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleHTTPRequest_BodyTooLarge(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+	handler := bodyLimitMiddleware(&HTTPConfig{MaxBodyBytes: 16}, http.HandlerFunc(server.handleHTTPRequest))
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp/v1", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", w.Code)
+	}
+}
@@ -0,0 +1,123 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Readiness states
+const (
+	ReadinessStatusReady    = "ready"
+	ReadinessStatusNotReady = "not_ready"
+)
+
+// readinessTimeout bounds the checks of a readiness probe
+const readinessTimeout = 5 * time.Second
+
+// ReadinessCheck checks a dependency of the server for the readiness probe.
+// It returns nil when the dependency is ready, or why it is not.
+type ReadinessCheck func(ctx context.Context) error
+
+// readinessResponse is the body of the readiness probe endpoint
+type readinessResponse struct {
+	Status  string                     `json:"status"`
+	Server  string                     `json:"server"`
+	Version string                     `json:"version"`
+	Checks  map[string]HealthComponent `json:"checks,omitempty"`
+}
+
+// SetReadinessCheck adds a check to the HTTP readiness probe (nil removes
+// it)
+func (s *Server) SetReadinessCheck(name string, check ReadinessCheck) {
+	s.capMu.Lock()
+	defer s.capMu.Unlock()
+	if check == nil {
+		delete(s.readiness, name)
+		return
+	}
+	if s.readiness == nil {
+		s.readiness = make(map[string]ReadinessCheck)
+	}
+	s.readiness[name] = check
+}
+
+// handleLiveness reports that the process is up and serving HTTP requests.
+// It doesn't check any dependency, so that an orchestrator doesn't restart
+// the server while, for example, a database is down.
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeProbeResponse(w, http.StatusOK, healthResponse{
+		Status:  HealthStatusOK,
+		Server:  ServerName,
+		Version: ServerVersion,
+	})
+}
+
+// handleReadiness runs the readiness checks concurrently and reports
+// whether the server can serve requests: status 200 when every check
+// passes, and 503 otherwise
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	s.capMu.RLock()
+	checks := make(map[string]ReadinessCheck, len(s.readiness))
+	for name, check := range s.readiness {
+		checks[name] = check
+	}
+	s.capMu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	response := readinessResponse{Status: ReadinessStatusReady, Server: ServerName, Version: ServerVersion}
+	if len(checks) > 0 {
+		response.Checks = make(map[string]HealthComponent, len(checks))
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check ReadinessCheck) {
+			defer wg.Done()
+			component := HealthComponent{Status: HealthStatusOK}
+			if err := check(ctx); err != nil {
+				component = HealthComponent{Status: HealthStatusUnavailable, Reason: err.Error()}
+			}
+			mu.Lock()
+			response.Checks[name] = component
+			if component.Status != HealthStatusOK {
+				response.Status = ReadinessStatusNotReady
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if response.Status != ReadinessStatusReady {
+		status = http.StatusServiceUnavailable
+	}
+	writeProbeResponse(w, status, response)
+}
+
+// writeProbeResponse writes the JSON body of a health or readiness probe
+func writeProbeResponse(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Failed to write probe response: %v\n", err)
+	}
+}
@@ -0,0 +1,94 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleLiveness(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+	// Liveness ignores the readiness checks
+	server.SetReadinessCheck("database:main", func(context.Context) error { return errors.New("down") })
+
+	w := httptest.NewRecorder()
+	server.handleLiveness(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	var response healthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if response.Status != HealthStatusOK || response.Server != ServerName || response.Version != ServerVersion {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
+
+func TestHandleReadiness(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+
+	check := func(wantCode int) readinessResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.handleReadiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if w.Code != wantCode {
+			t.Errorf("expected status %d, got %d", wantCode, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("expected Content-Type application/json, got %q", got)
+		}
+		var response readinessResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return response
+	}
+
+	// Without checks the server is ready
+	if response := check(http.StatusOK); response.Status != ReadinessStatusReady || response.Checks != nil {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	dbErr := errors.New("connection refused")
+	server.SetReadinessCheck("token_store", func(context.Context) error { return nil })
+	server.SetReadinessCheck("database:main", func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("checks should run with a deadline")
+		}
+		return dbErr
+	})
+	response := check(http.StatusServiceUnavailable)
+	if response.Status != ReadinessStatusNotReady {
+		t.Errorf("expected status %q, got %q", ReadinessStatusNotReady, response.Status)
+	}
+	if got := response.Checks["database:main"]; got.Status != HealthStatusUnavailable || got.Reason != "connection refused" {
+		t.Errorf("unexpected database check: %+v", got)
+	}
+	if got := response.Checks["token_store"]; got.Status != HealthStatusOK {
+		t.Errorf("unexpected token store check: %+v", got)
+	}
+
+	dbErr = nil
+	if response := check(http.StatusOK); response.Status != ReadinessStatusReady || len(response.Checks) != 2 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	server.SetReadinessCheck("database:main", nil)
+	if response := check(http.StatusOK); len(response.Checks) != 1 {
+		t.Errorf("expected the check to be removed, got %+v", response.Checks)
+	}
+}
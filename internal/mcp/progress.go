@@ -0,0 +1,146 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between progress notifications for a
+// request; the final update (progress == total) is always sent
+const progressInterval = 250 * time.Millisecond
+
+// ProgressReporter reports the progress of a long-running request to the client
+// Reporting is best effort: updates may be dropped if they arrive too quickly,
+// do not increase, or the client did not ask for progress
+type ProgressReporter interface {
+	// Report sends a progress update; total is 0 when unknown
+	Report(progress, total float64, message string)
+}
+
+// ProgressNotificationParams are the parameters of notifications/progress
+type ProgressNotificationParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+type progressReporterKey struct{}
+
+// noopProgress is used when the client did not request progress
+type noopProgress struct{}
+
+func (noopProgress) Report(float64, float64, string) {}
+
+// WithProgressReporter returns a context carrying a progress reporter
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressReporterKey{}, reporter)
+}
+
+// ProgressFromContext returns the progress reporter for a request
+// A no-op reporter is returned if the client did not request progress
+func ProgressFromContext(ctx context.Context) ProgressReporter {
+	if ctx != nil {
+		if reporter, ok := ctx.Value(progressReporterKey{}).(ProgressReporter); ok {
+			return reporter
+		}
+	}
+	return noopProgress{}
+}
+
+// progressToken extracts params._meta.progressToken from request parameters
+func progressToken(params interface{}) interface{} {
+	p, ok := params.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	meta, ok := p["_meta"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch token := meta["progressToken"].(type) {
+	case string, float64:
+		return token
+	}
+	return nil
+}
+
+// progressNotifier sends notifications/progress messages for one request
+type progressNotifier struct {
+	token interface{}
+	send  func(data []byte)
+
+	mu       sync.Mutex
+	last     float64
+	lastSent time.Time
+	started  bool
+}
+
+// newProgressNotifier returns a reporter for a request, or nil if the
+// request did not include a progress token
+func newProgressNotifier(params interface{}, send func(data []byte)) *progressNotifier {
+	token := progressToken(params)
+	if token == nil {
+		return nil
+	}
+	return &progressNotifier{token: token, send: send}
+}
+
+// Report implements ProgressReporter
+// Progress must increase with every notification, so updates that do not
+// increase it are ignored
+func (p *progressNotifier) Report(progress, total float64, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started && progress <= p.last {
+		return
+	}
+	final := total > 0 && progress >= total
+	if p.started && !final && time.Since(p.lastSent) < progressInterval {
+		return
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/progress",
+		"params": ProgressNotificationParams{
+			ProgressToken: p.token,
+			Progress:      progress,
+			Total:         total,
+			Message:       message,
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Failed to marshal progress notification: %v\n", err)
+		return
+	}
+
+	p.started = true
+	p.last = progress
+	p.lastSent = time.Now()
+	p.send(data)
+}
+
+// withRequestProgress attaches a progress reporter to ctx if the request
+// asked for progress
+func withRequestProgress(ctx context.Context, req JSONRPCRequest, send func(data []byte)) context.Context {
+	if notifier := newProgressNotifier(req.Params, send); notifier != nil {
+		return WithProgressReporter(ctx, notifier)
+	}
+	return ctx
+}
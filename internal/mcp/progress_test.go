@@ -0,0 +1,154 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// progressToolProvider is a tool provider whose tool reports progress
+type progressToolProvider struct{}
+
+func (p *progressToolProvider) List() []Tool {
+	return []Tool{{Name: "slow_tool"}}
+}
+
+func (p *progressToolProvider) Execute(ctx context.Context, name string, args map[string]interface{}) (ToolResponse, error) {
+	progress := ProgressFromContext(ctx)
+	progress.Report(1, 2, "halfway")
+	progress.Report(2, 2, "done")
+	return NewToolSuccess("finished")
+}
+
+func TestProgressToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		params interface{}
+		want   interface{}
+	}{
+		{"no params", nil, nil},
+		{"no meta", map[string]interface{}{"name": "x"}, nil},
+		{"string token", map[string]interface{}{"_meta": map[string]interface{}{"progressToken": "abc"}}, "abc"},
+		{"numeric token", map[string]interface{}{"_meta": map[string]interface{}{"progressToken": float64(7)}}, float64(7)},
+		{"invalid token", map[string]interface{}{"_meta": map[string]interface{}{"progressToken": true}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := progressToken(tt.params); got != tt.want {
+				t.Errorf("progressToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressNotifier(t *testing.T) {
+	var sent []ProgressNotificationParams
+	notifier := newProgressNotifier(
+		map[string]interface{}{"_meta": map[string]interface{}{"progressToken": "tok"}},
+		func(data []byte) {
+			var msg struct {
+				Method string                     `json:"method"`
+				Params ProgressNotificationParams `json:"params"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("invalid notification: %v", err)
+			}
+			if msg.Method != "notifications/progress" {
+				t.Errorf("method = %q", msg.Method)
+			}
+			sent = append(sent, msg.Params)
+		})
+	if notifier == nil {
+		t.Fatal("expected a notifier for a request with a progress token")
+	}
+
+	notifier.Report(1, 4, "first")
+	notifier.Report(1, 4, "not increasing")
+	notifier.Report(2, 4, "too soon")
+	notifier.Report(4, 4, "final")
+
+	if len(sent) != 2 {
+		t.Fatalf("expected 2 notifications, got %+v", sent)
+	}
+	if sent[0].ProgressToken != "tok" || sent[0].Progress != 1 || sent[0].Message != "first" {
+		t.Errorf("unexpected first notification: %+v", sent[0])
+	}
+	if sent[1].Progress != 4 || sent[1].Total != 4 {
+		t.Errorf("final update should always be sent: %+v", sent[1])
+	}
+
+	if newProgressNotifier(map[string]interface{}{}, func([]byte) {}) != nil {
+		t.Error("expected no notifier without a progress token")
+	}
+	// Reporting without a reporter in the context is a no-op
+	ProgressFromContext(context.Background()).Report(1, 1, "ignored")
+}
+
+func TestStreamableHTTP_Progress(t *testing.T) {
+	server := NewServer(&progressToolProvider{})
+	sessionID := initializeSession(t, server)
+
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":  "slow_tool",
+			"_meta": map[string]interface{}{"progressToken": "p1"},
+		},
+	}))
+
+	var events []string
+	for _, block := range strings.Split(strings.TrimSpace(w.Body.String()), "\n\n") {
+		for _, line := range strings.Split(block, "\n") {
+			if strings.HasPrefix(line, "data: ") {
+				events = append(events, strings.TrimPrefix(line, "data: "))
+			}
+		}
+	}
+
+	// halfway, done (final), then the result
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d: %q", len(events), w.Body.String())
+	}
+	for i, want := range []string{`"progress":1`, `"progress":2`} {
+		if !strings.Contains(events[i], `"method":"notifications/progress"`) || !strings.Contains(events[i], want) {
+			t.Errorf("event %d = %s, want progress notification with %s", i, events[i], want)
+		}
+	}
+	if !strings.Contains(events[2], `"id":2`) || !strings.Contains(events[2], "finished") {
+		t.Errorf("last event should be the result, got %s", events[2])
+	}
+
+	// Plain JSON clients get the result without notifications
+	w = httptest.NewRecorder()
+	req := streamableRequest(t, http.MethodPost, "", JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      3,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":  "slow_tool",
+			"_meta": map[string]interface{}{"progressToken": "p2"},
+		},
+	})
+	req.Header.Set("Accept", "application/json")
+	server.handleHTTPRequest(w, req)
+	if strings.Contains(w.Body.String(), "notifications/progress") {
+		t.Errorf("plain JSON response should not contain notifications: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,242 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rootsTimeout bounds how long a tool waits for the client's roots
+const rootsTimeout = 30 * time.Second
+
+// RootsListChangedMethod is the notification a client sends when its roots
+// change
+const RootsListChangedMethod = "notifications/roots/list_changed"
+
+// ErrRootsUnsupported is returned when the client did not declare the roots
+// capability or the transport cannot carry server requests. Tools must not
+// touch files for such clients.
+var ErrRootsUnsupported = errors.New("file access requires a client that supports MCP roots")
+
+// Root is a directory the client allows the server to work in
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// ListRootsResult is the client's response to roots/list
+type ListRootsResult struct {
+	Roots []Root `json:"roots"`
+}
+
+// RootsLister returns the roots of the connected client
+type RootsLister interface {
+	ListRoots(ctx context.Context) ([]Root, error)
+}
+
+type rootsListerKey struct{}
+
+// WithRootsLister returns a context carrying a roots lister
+func WithRootsLister(ctx context.Context, lister RootsLister) context.Context {
+	return context.WithValue(ctx, rootsListerKey{}, lister)
+}
+
+// RootsListerFromContext returns the roots lister for a request, and false
+// if the client does not support roots
+func RootsListerFromContext(ctx context.Context) (RootsLister, bool) {
+	if ctx != nil {
+		if lister, ok := ctx.Value(rootsListerKey{}).(RootsLister); ok {
+			return lister, true
+		}
+	}
+	return nil, false
+}
+
+// clientSupportsRoots reports whether initialize parameters declare the
+// roots client capability
+func clientSupportsRoots(params interface{}) bool {
+	return clientHasCapability(params, "roots")
+}
+
+// rootsCache holds a client's roots between requests; the roots are listed
+// again after the client announces a change
+type rootsCache struct {
+	supported atomic.Bool // Whether the client declared the roots capability
+
+	mu         sync.Mutex
+	roots      []Root
+	valid      bool
+	generation uint64 // Incremented on each change so stale lists are not cached
+}
+
+// reset records whether a newly initialized client supports roots
+func (c *rootsCache) reset(supported bool) {
+	c.supported.Store(supported)
+	c.invalidate()
+}
+
+// invalidate forgets the cached roots
+func (c *rootsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roots = nil
+	c.valid = false
+	c.generation++
+}
+
+// clientRoots sends roots/list requests to one client
+type clientRoots struct {
+	cache   *rootsCache
+	pending *pendingRequests
+	client  string
+	send    func(data []byte)
+}
+
+// ListRoots implements RootsLister
+func (c *clientRoots) ListRoots(ctx context.Context) ([]Root, error) {
+	c.cache.mu.Lock()
+	if c.cache.valid {
+		roots := append([]Root(nil), c.cache.roots...)
+		c.cache.mu.Unlock()
+		return roots, nil
+	}
+	generation := c.cache.generation
+	c.cache.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, rootsTimeout)
+	defer cancel()
+
+	response, err := c.pending.call(ctx, c.client, c.send, "roots/list", map[string]interface{}{})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("no roots response from the client: %w", err)
+		}
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("client rejected roots request: %s", response.Error.Message)
+	}
+	var result ListRootsResult
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid roots result: %w", err)
+	}
+
+	c.cache.mu.Lock()
+	if c.cache.generation == generation {
+		c.cache.roots = result.Roots
+		c.cache.valid = true
+	}
+	c.cache.mu.Unlock()
+	return append([]Root(nil), result.Roots...), nil
+}
+
+// rootPath returns the local directory of a file:// root URI
+func rootPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid root URI %q: %w", uri, err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported root URI %q: only file:// roots are supported", uri)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("unsupported root URI %q: remote hosts are not supported", uri)
+	}
+	path := u.Path
+	// file:///C:/data is the Windows path C:/data
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	path = filepath.Clean(filepath.FromSlash(path))
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("invalid root URI %q: the path is not absolute", uri)
+	}
+	return path, nil
+}
+
+// resolvePath resolves symbolic links in a path so links cannot lead out of
+// a root. The file itself need not exist yet, so only its directory is
+// resolved then.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	dir, file := filepath.Split(path)
+	if dir == "" || filepath.Clean(dir) == path {
+		return path
+	}
+	return filepath.Join(resolvePath(filepath.Clean(dir)), file)
+}
+
+// pathWithin reports whether path is dir or inside it
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)))
+}
+
+// AllowedPath checks that a file path given to a tool is inside one of the
+// client's roots and returns its absolute path. Relative paths are taken
+// from the first root. Tools that take file paths from the caller are to use
+// it, so they only touch the directories the client has approved; no
+// built-in tool does yet.
+func AllowedPath(ctx context.Context, path string) (string, error) {
+	lister, ok := RootsListerFromContext(ctx)
+	if !ok {
+		return "", ErrRootsUnsupported
+	}
+	roots, err := lister.ListRoots(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var dirs []string
+	for _, root := range roots {
+		dir, err := rootPath(root.URI)
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) == 0 {
+		return "", errors.New("the client has not shared any file:// roots")
+	}
+
+	if strings.HasPrefix(path, "file:") {
+		if path, err = rootPath(path); err != nil {
+			return "", err
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dirs[0], path)
+	}
+	path = resolvePath(filepath.Clean(path))
+
+	for _, dir := range dirs {
+		if pathWithin(path, resolvePath(dir)) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s is outside the client's roots", path)
+}
@@ -0,0 +1,196 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// staticRoots is a roots lister with fixed roots
+type staticRoots []Root
+
+func (r staticRoots) ListRoots(ctx context.Context) ([]Root, error) {
+	return r, nil
+}
+
+// fileURI returns the file:// URI of a local directory
+func fileURI(path string) string {
+	return "file://" + filepath.ToSlash(path)
+}
+
+func TestClientSupportsRoots(t *testing.T) {
+	if clientSupportsRoots(map[string]interface{}{"capabilities": map[string]interface{}{"sampling": map[string]interface{}{}}}) {
+		t.Error("expected no roots without the capability")
+	}
+	if !clientSupportsRoots(map[string]interface{}{"capabilities": map[string]interface{}{"roots": map[string]interface{}{"listChanged": true}}}) {
+		t.Error("expected roots with the capability")
+	}
+}
+
+func TestRootPath(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    string
+		wantErr string
+	}{
+		{"file:///data/imports", "/data/imports", ""},
+		{"file://localhost/data/", "/data", ""},
+		{"https://example.com/data", "", "only file://"},
+		{"file://server/share", "", "remote hosts"},
+	}
+	for _, tt := range tests {
+		got, err := rootPath(tt.uri)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("rootPath(%q) error = %v, want %q", tt.uri, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != filepath.FromSlash(tt.want) {
+			t.Errorf("rootPath(%q) = %q, %v; want %q", tt.uri, got, err, tt.want)
+		}
+	}
+}
+
+func TestAllowedPath(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{root, outside} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithRootsLister(context.Background(), staticRoots{
+		{URI: "https://example.com/ignored"},
+		{URI: fileURI(root), Name: "imports"},
+	})
+
+	tests := []struct {
+		path    string
+		want    string
+		allowed bool
+	}{
+		{filepath.Join(root, "orders.csv"), filepath.Join(resolvedRoot, "orders.csv"), true},
+		{"orders.csv", filepath.Join(resolvedRoot, "orders.csv"), true},
+		{fileURI(filepath.Join(root, "new", "orders.csv")), filepath.Join(resolvedRoot, "new", "orders.csv"), true},
+		{root, resolvedRoot, true},
+		{filepath.Join(root, "..", "outside", "orders.csv"), "", false},
+		{filepath.Join(root, "escape", "orders.csv"), "", false},
+		{root + "-sibling", "", false},
+	}
+	for _, tt := range tests {
+		got, err := AllowedPath(ctx, tt.path)
+		if !tt.allowed {
+			if err == nil || !strings.Contains(err.Error(), "outside the client's roots") {
+				t.Errorf("AllowedPath(%q) = %q, %v; want an outside error", tt.path, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("AllowedPath(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	if _, err := AllowedPath(context.Background(), filepath.Join(root, "orders.csv")); !errors.Is(err, ErrRootsUnsupported) {
+		t.Errorf("expected ErrRootsUnsupported, got %v", err)
+	}
+	noRoots := WithRootsLister(context.Background(), staticRoots{})
+	if _, err := AllowedPath(noRoots, filepath.Join(root, "orders.csv")); err == nil || !strings.Contains(err.Error(), "not shared any") {
+		t.Errorf("expected an error without roots, got %v", err)
+	}
+}
+
+func TestClientRoots(t *testing.T) {
+	pending := newPendingRequests()
+	cache := &rootsCache{}
+	cache.reset(true)
+
+	requests := 0
+	lister := &clientRoots{cache: cache, pending: pending, client: "c1", send: func(data []byte) {
+		var req map[string]interface{}
+		if err := json.Unmarshal(data, &req); err != nil {
+			t.Errorf("invalid request: %v", err)
+			return
+		}
+		if req["method"] != "roots/list" {
+			t.Errorf("unexpected request: %v", req)
+		}
+		requests++
+		go pending.deliver("c1", clientResponse{ID: req["id"], Result: json.RawMessage(`{"roots":[{"uri":"file:///data","name":"data"}]}`)})
+	}}
+
+	for i := 0; i < 2; i++ {
+		roots, err := lister.ListRoots(context.Background())
+		if err != nil {
+			t.Fatalf("ListRoots() error = %v", err)
+		}
+		if len(roots) != 1 || roots[0].URI != "file:///data" || roots[0].Name != "data" {
+			t.Errorf("ListRoots() = %v", roots)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the roots to be cached, got %d requests", requests)
+	}
+
+	// The roots are listed again after the client announces a change
+	cache.invalidate()
+	if _, err := lister.ListRoots(context.Background()); err != nil {
+		t.Fatalf("ListRoots() error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected the roots to be listed again, got %d requests", requests)
+	}
+
+	rejecting := &clientRoots{cache: &rootsCache{}, pending: pending, client: "c1", send: func(data []byte) {
+		var req map[string]interface{}
+		_ = json.Unmarshal(data, &req) //nolint:errcheck // The request was just marshaled
+		go pending.deliver("c1", clientResponse{ID: req["id"], Error: &RPCError{Code: -32601, Message: "Method not found"}})
+	}}
+	if _, err := rejecting.ListRoots(context.Background()); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("expected a rejection error, got %v", err)
+	}
+}
+
+func TestRootsListChanged_Stdio(t *testing.T) {
+	server := NewServer(nil)
+	if _, ok := RootsListerFromContext(server.withStdioRoots(context.Background())); ok {
+		t.Fatal("expected no roots lister before the client declares roots")
+	}
+	server.stdioRoots.reset(true)
+	if _, ok := RootsListerFromContext(server.withStdioRoots(context.Background())); !ok {
+		t.Fatal("expected a roots lister for a client with the roots capability")
+	}
+
+	server.stdioRoots.mu.Lock()
+	server.stdioRoots.roots = []Root{{URI: "file:///data"}}
+	server.stdioRoots.valid = true
+	server.stdioRoots.mu.Unlock()
+
+	server.handleRequest(context.Background(), JSONRPCRequest{Method: RootsListChangedMethod})
+	if server.stdioRoots.valid {
+		t.Error("expected the roots to be invalidated by notifications/roots/list_changed")
+	}
+}
@@ -0,0 +1,269 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// samplingTimeout bounds how long a tool waits for the client's model;
+// clients may ask the user to approve each sampling request
+const samplingTimeout = 5 * time.Minute
+
+// ErrSamplingUnsupported is returned when the client did not declare the
+// sampling capability or the transport cannot carry server requests
+var ErrSamplingUnsupported = errors.New("the client does not support sampling")
+
+// SamplingMessage is a message sent to the client's model
+type SamplingMessage struct {
+	Role    string      `json:"role"`
+	Content ContentItem `json:"content"`
+}
+
+// ModelHint suggests a model by name; clients may map it to any model
+type ModelHint struct {
+	Name string `json:"name"`
+}
+
+// ModelPreferences express priorities for the client's choice of model
+// Priorities range from 0 to 1
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         float64     `json:"costPriority,omitempty"`
+	SpeedPriority        float64     `json:"speedPriority,omitempty"`
+	IntelligencePriority float64     `json:"intelligencePriority,omitempty"`
+}
+
+// CreateMessageParams are the parameters of sampling/createMessage
+type CreateMessageParams struct {
+	Messages         []SamplingMessage `json:"messages"`
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+	SystemPrompt     string            `json:"systemPrompt,omitempty"`
+	MaxTokens        int               `json:"maxTokens"`
+	Temperature      float64           `json:"temperature,omitempty"`
+	StopSequences    []string          `json:"stopSequences,omitempty"`
+}
+
+// CreateMessageResult is the client's response to sampling/createMessage
+type CreateMessageResult struct {
+	Role       string      `json:"role"`
+	Content    ContentItem `json:"content"`
+	Model      string      `json:"model"`
+	StopReason string      `json:"stopReason,omitempty"`
+}
+
+// Sampler asks the connected client's model to generate a message
+type Sampler interface {
+	CreateMessage(ctx context.Context, params CreateMessageParams) (*CreateMessageResult, error)
+}
+
+type samplerKey struct{}
+
+// WithSampler returns a context carrying a sampler
+func WithSampler(ctx context.Context, sampler Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, sampler)
+}
+
+// SamplerFromContext returns the sampler for a request, and false if the
+// client cannot sample (tools should then fall back or skip the step)
+func SamplerFromContext(ctx context.Context) (Sampler, bool) {
+	if ctx != nil {
+		if sampler, ok := ctx.Value(samplerKey{}).(Sampler); ok {
+			return sampler, true
+		}
+	}
+	return nil, false
+}
+
+// clientSupportsSampling reports whether initialize parameters declare the
+// sampling client capability
+func clientSupportsSampling(params interface{}) bool {
+	return clientHasCapability(params, "sampling")
+}
+
+// clientHasCapability reports whether initialize parameters declare a
+// client capability
+func clientHasCapability(params interface{}, name string) bool {
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		return false
+	}
+	var p InitializeParams
+	if err := json.Unmarshal(paramsBytes, &p); err != nil {
+		return false
+	}
+	_, ok := p.Capabilities[name]
+	return ok
+}
+
+// clientResponse is a response from the client to a server request
+type clientResponse struct {
+	ID     interface{}     `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// parseClientResponse reports whether a message from the client is a
+// response rather than a request or notification
+func parseClientResponse(data []byte) (clientResponse, bool) {
+	var msg struct {
+		clientResponse
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return clientResponse{}, false
+	}
+	if msg.Method != "" || msg.ID == nil || (msg.Result == nil && msg.Error == nil) {
+		return clientResponse{}, false
+	}
+	return msg.clientResponse, true
+}
+
+// pendingRequests tracks requests sent to clients that await a response
+// Requests are keyed by client so one client cannot answer another's
+type pendingRequests struct {
+	mu      sync.Mutex
+	nextID  uint64
+	waiting map[string]chan clientResponse
+}
+
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{waiting: make(map[string]chan clientResponse)}
+}
+
+// start allocates a request ID for a client and returns the channel its
+// response is delivered on; done must be called when no longer waiting
+func (p *pendingRequests) start(client string) (string, <-chan clientResponse, func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	id := fmt.Sprintf("pgedge-%d", p.nextID)
+	key := client + "/" + id
+	ch := make(chan clientResponse, 1)
+	p.waiting[key] = ch
+	return id, ch, func() {
+		p.mu.Lock()
+		delete(p.waiting, key)
+		p.mu.Unlock()
+	}
+}
+
+// deliver passes a response to the waiting request; it returns false if no
+// request from this client has the response's ID
+func (p *pendingRequests) deliver(client string, response clientResponse) bool {
+	id, ok := response.ID.(string)
+	if !ok {
+		return false
+	}
+	key := client + "/" + id
+
+	p.mu.Lock()
+	ch, ok := p.waiting[key]
+	delete(p.waiting, key)
+	p.mu.Unlock()
+
+	if ok {
+		ch <- response
+	}
+	return ok
+}
+
+// call sends a request to a client and waits for its response or the end
+// of the context
+func (p *pendingRequests) call(ctx context.Context, client string, send func(data []byte), method string, params interface{}) (clientResponse, error) {
+	id, responses, done := p.start(client)
+	defer done()
+
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return clientResponse{}, fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	send(data)
+
+	select {
+	case response := <-responses:
+		return response, nil
+	case <-ctx.Done():
+		return clientResponse{}, ctx.Err()
+	}
+}
+
+// clientSampler sends sampling/createMessage requests to one client
+type clientSampler struct {
+	pending *pendingRequests
+	client  string
+	send    func(data []byte)
+}
+
+// CreateMessage implements Sampler
+func (c *clientSampler) CreateMessage(ctx context.Context, params CreateMessageParams) (*CreateMessageResult, error) {
+	if len(params.Messages) == 0 {
+		return nil, errors.New("sampling requires at least one message")
+	}
+	if params.MaxTokens <= 0 {
+		return nil, errors.New("sampling requires maxTokens")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, samplingTimeout)
+	defer cancel()
+
+	response, err := c.pending.call(ctx, c.client, c.send, "sampling/createMessage", params)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("no sampling response from the client: %w", err)
+		}
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("client rejected sampling request: %s", response.Error.Message)
+	}
+	var result CreateMessageResult
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid sampling result: %w", err)
+	}
+	return &result, nil
+}
+
+// SampleText asks the client's model for a text completion of a single
+// prompt. It returns ErrSamplingUnsupported if the client cannot sample.
+func SampleText(ctx context.Context, systemPrompt, prompt string, maxTokens int) (string, error) {
+	sampler, ok := SamplerFromContext(ctx)
+	if !ok {
+		return "", ErrSamplingUnsupported
+	}
+	result, err := sampler.CreateMessage(ctx, CreateMessageParams{
+		Messages: []SamplingMessage{
+			{Role: "user", Content: ContentItem{Type: "text", Text: prompt}},
+		},
+		SystemPrompt: systemPrompt,
+		MaxTokens:    maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Content.Type != "text" {
+		return "", fmt.Errorf("expected text from the client's model, got %q", result.Content.Type)
+	}
+	return strings.TrimSpace(result.Content.Text), nil
+}
@@ -0,0 +1,233 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// samplingToolProvider is a tool provider whose tool asks the client's model
+type samplingToolProvider struct{}
+
+func (p *samplingToolProvider) List() []Tool {
+	return []Tool{{Name: "sampling_tool"}}
+}
+
+func (p *samplingToolProvider) Execute(ctx context.Context, name string, args map[string]interface{}) (ToolResponse, error) {
+	text, err := SampleText(ctx, "Be brief", "Write a query", 100)
+	if err != nil {
+		return NewToolError(err.Error())
+	}
+	return NewToolSuccess("model said: " + text)
+}
+
+func TestParseClientResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"result", `{"jsonrpc":"2.0","id":"pgedge-1","result":{}}`, true},
+		{"error", `{"jsonrpc":"2.0","id":"pgedge-1","error":{"code":-1,"message":"no"}}`, true},
+		{"request", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, false},
+		{"notification", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, false},
+		{"no id", `{"jsonrpc":"2.0","result":{}}`, false},
+		{"invalid", `not json`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := parseClientResponse([]byte(tt.data)); got != tt.want {
+				t.Errorf("parseClientResponse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientSupportsSampling(t *testing.T) {
+	if clientSupportsSampling(map[string]interface{}{"capabilities": map[string]interface{}{}}) {
+		t.Error("expected no sampling without the capability")
+	}
+	if !clientSupportsSampling(map[string]interface{}{"capabilities": map[string]interface{}{"sampling": map[string]interface{}{}}}) {
+		t.Error("expected sampling with the capability")
+	}
+}
+
+func TestClientSampler(t *testing.T) {
+	pending := newPendingRequests()
+
+	// The client answers each request; the second is rejected
+	var requests []map[string]interface{}
+	sampler := &clientSampler{pending: pending, client: "c1", send: func(data []byte) {
+		var req map[string]interface{}
+		if err := json.Unmarshal(data, &req); err != nil {
+			t.Errorf("invalid request: %v", err)
+			return
+		}
+		requests = append(requests, req)
+		response := clientResponse{ID: req["id"], Result: json.RawMessage(`{"role":"assistant","content":{"type":"text","text":" SELECT 1 "},"model":"m"}`)}
+		if len(requests) == 2 {
+			response = clientResponse{ID: req["id"], Error: &RPCError{Code: -1, Message: "User rejected sampling request"}}
+		}
+		// Responses are only accepted from the client that was asked
+		if pending.deliver("c2", response) {
+			t.Error("response delivered to the wrong client")
+		}
+		go pending.deliver("c1", response)
+	}}
+	ctx := WithSampler(context.Background(), sampler)
+
+	text, err := SampleText(ctx, "system", "prompt", 50)
+	if err != nil {
+		t.Fatalf("SampleText() error = %v", err)
+	}
+	if text != "SELECT 1" {
+		t.Errorf("SampleText() = %q", text)
+	}
+	if requests[0]["method"] != "sampling/createMessage" {
+		t.Errorf("unexpected request: %v", requests[0])
+	}
+	params, _ := requests[0]["params"].(map[string]interface{})
+	if params["systemPrompt"] != "system" || params["maxTokens"] != float64(50) {
+		t.Errorf("unexpected params: %v", params)
+	}
+
+	if _, err := SampleText(ctx, "system", "prompt", 50); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("expected rejection error, got %v", err)
+	}
+
+	// A client that never answers is abandoned when the context ends
+	silent := &clientSampler{pending: pending, client: "c1", send: func([]byte) {}}
+	timeoutCtx, cancel := context.WithTimeout(WithSampler(context.Background(), silent), 10*time.Millisecond)
+	defer cancel()
+	if _, err := SampleText(timeoutCtx, "", "prompt", 50); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+	if len(pending.waiting) != 0 {
+		t.Errorf("expected no pending requests, got %d", len(pending.waiting))
+	}
+
+	if _, err := SampleText(context.Background(), "", "prompt", 50); !errors.Is(err, ErrSamplingUnsupported) {
+		t.Errorf("expected ErrSamplingUnsupported, got %v", err)
+	}
+}
+
+// initializeSamplingSession initializes a session for a client with the
+// sampling capability
+func initializeSamplingSession(t *testing.T, server *Server) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, "", JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"protocolVersion": "2025-03-26",
+			"capabilities":    map[string]interface{}{"sampling": map[string]interface{}{}},
+		},
+	}))
+	sessionID := w.Header().Get(SessionIDHeader)
+	if sessionID == "" {
+		t.Fatal("expected a session ID on initialize")
+	}
+	return sessionID
+}
+
+func TestSampling_StreamableHTTP(t *testing.T) {
+	server := NewServer(&samplingToolProvider{})
+	sessionID := initializeSamplingSession(t, server)
+	sess, _ := server.sessions.get(sessionID, "")
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+			JSONRPC: "2.0",
+			ID:      2,
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": "sampling_tool"},
+		}))
+	}()
+
+	// Wait for the sampling request on the session's stream
+	var request map[string]interface{}
+	deadline := time.Now().Add(5 * time.Second)
+	for request == nil && time.Now().Before(deadline) {
+		events, notify := sess.eventsAfter(0)
+		for _, event := range events {
+			if strings.Contains(string(event.data), "sampling/createMessage") {
+				if err := json.Unmarshal(event.data, &request); err != nil {
+					t.Fatalf("invalid sampling request: %v", err)
+				}
+			}
+		}
+		if request == nil {
+			select {
+			case <-notify:
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+	if request == nil {
+		t.Fatal("no sampling request was sent")
+	}
+
+	// Responses must come from the same session
+	reply := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      request["id"],
+		"result":  map[string]interface{}{"role": "assistant", "content": map[string]interface{}{"type": "text", "text": "SELECT 1"}, "model": "m"},
+	}
+	other := httptest.NewRecorder()
+	server.handleHTTPRequest(other, streamableRequest(t, http.MethodPost, "", reply))
+	if other.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a response without a session, got %d", other.Code)
+	}
+
+	accepted := httptest.NewRecorder()
+	server.handleHTTPRequest(accepted, streamableRequest(t, http.MethodPost, sessionID, reply))
+	if accepted.Code != http.StatusAccepted {
+		t.Errorf("expected 202 for the response, got %d", accepted.Code)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool call did not finish")
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "sampling/createMessage") || !strings.Contains(body, "model said: SELECT 1") {
+		t.Errorf("unexpected stream: %s", body)
+	}
+}
+
+func TestSampling_NotDeclared(t *testing.T) {
+	server := NewServer(&samplingToolProvider{})
+	sessionID := initializeSession(t, server)
+
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "sampling_tool"},
+	}))
+	if !strings.Contains(w.Body.String(), ErrSamplingUnsupported.Error()) {
+		t.Errorf("expected sampling to be unsupported: %s", w.Body.String())
+	}
+}
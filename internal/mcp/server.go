@@ -13,9 +13,12 @@ package mcp
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -24,12 +27,23 @@ const (
 	ServerVersion   = "1.0.0-beta1"
 )
 
+// stdioClientID identifies the single client of the stdio transport in
+// per-client state such as subscriptions and pending server requests
+const stdioClientID = "stdio"
+
 // ToolProvider is an interface for listing and executing tools
 type ToolProvider interface {
 	List() []Tool
 	Execute(ctx context.Context, name string, args map[string]interface{}) (ToolResponse, error)
 }
 
+// ContextToolLister is implemented by tool providers whose tools depend on
+// the caller, for example on the scopes of its API token. tools/list uses
+// it instead of List when it is available.
+type ContextToolLister interface {
+	ListForContext(ctx context.Context) []Tool
+}
+
 // ResourceProvider is an interface for listing and reading resources
 type ResourceProvider interface {
 	List() []Resource
@@ -50,6 +64,11 @@ type DatabaseInfo struct {
 	Database string `json:"database"`
 	User     string `json:"user"`
 	SSLMode  string `json:"sslmode"`
+
+	Environment string   `json:"environment,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+	ReadOnly    bool     `json:"read_only,omitempty"` // Writes are disabled
 }
 
 // DatabaseProvider is an interface for managing database connections
@@ -62,17 +81,53 @@ type DatabaseProvider interface {
 
 // Server handles MCP protocol communication
 type Server struct {
-	tools     ToolProvider
-	resources ResourceProvider
-	prompts   PromptProvider
-	databases DatabaseProvider
-	debug     bool // Enable debug logging for HTTP mode
+	tools       ToolProvider
+	resources   ResourceProvider
+	prompts     PromptProvider
+	databases   DatabaseProvider
+	completions CompletionProvider
+	debug       bool // Enable debug logging for HTTP mode
+
+	// Server-specific capabilities reported under "experimental" in initialize
+	// capMu also guards the components reported by the health check and
+	// the checks of the readiness probe
+	capMu        sync.RWMutex
+	experimental map[string]interface{}
+	health       map[string]HealthComponent
+	readiness    map[string]ReadinessCheck
+
+	// Active TLS certificate in HTTPS mode (replaced by ReloadTLSCertificate)
+	tlsMu   sync.RWMutex
+	tlsCert *tls.Certificate
+
+	// In-flight request tracking for graceful shutdown
+	drain *drainState
+
+	// Streamable HTTP transport sessions
+	sessions *sessionStore
+	// Session affinity for replicas behind a load balancer (nil if disabled)
+	affinity *affinity
+
+	// Resource subscriptions by client (stdio client or HTTP session)
+	subscriptions *subscriptionStore
+
+	// Requests sent to clients, such as sampling/createMessage, that await
+	// a response
+	pending *pendingRequests
+	// Whether the stdio client declared the sampling capability
+	stdioSampling atomic.Bool
+	// The stdio client's roots
+	stdioRoots rootsCache
 }
 
 // NewServer creates a new MCP server
 func NewServer(tools ToolProvider) *Server {
 	return &Server{
-		tools: tools,
+		tools:         tools,
+		drain:         newDrainState(),
+		sessions:      newSessionStore(),
+		subscriptions: newSubscriptionStore(),
+		pending:       newPendingRequests(),
 	}
 }
 
@@ -91,13 +146,111 @@ func (s *Server) SetDatabaseProvider(databases DatabaseProvider) {
 	s.databases = databases
 }
 
+// SetExperimentalCapability reports a server-specific capability to clients
+// in the "experimental" section of the initialize result (nil removes it)
+func (s *Server) SetExperimentalCapability(name string, value interface{}) {
+	s.capMu.Lock()
+	defer s.capMu.Unlock()
+	if value == nil {
+		delete(s.experimental, name)
+		return
+	}
+	if s.experimental == nil {
+		s.experimental = make(map[string]interface{})
+	}
+	s.experimental[name] = value
+}
+
+// SetHealthComponent reports the state of an optional server component in
+// the HTTP health check (nil removes it)
+func (s *Server) SetHealthComponent(name string, component *HealthComponent) {
+	s.capMu.Lock()
+	defer s.capMu.Unlock()
+	if component == nil {
+		delete(s.health, name)
+		return
+	}
+	if s.health == nil {
+		s.health = make(map[string]HealthComponent)
+	}
+	s.health[name] = *component
+}
+
+// capabilities builds the capabilities advertised in the initialize result
+func (s *Server) capabilities() map[string]interface{} {
+	capabilities := map[string]interface{}{
+		"tools": map[string]interface{}{},
+	}
+
+	// Add resources capability if resource provider is set
+	// Clients may subscribe to resources to be notified when they change
+	if s.resources != nil {
+		capabilities["resources"] = map[string]interface{}{
+			"subscribe": true,
+		}
+	}
+
+	// Add prompts capability if prompt provider is set
+	if s.prompts != nil {
+		capabilities["prompts"] = map[string]interface{}{}
+	}
+
+	// Add completions capability if completion provider is set
+	if s.completions != nil {
+		capabilities["completions"] = map[string]interface{}{}
+	}
+
+	s.capMu.RLock()
+	defer s.capMu.RUnlock()
+	if len(s.experimental) > 0 {
+		experimental := make(map[string]interface{}, len(s.experimental))
+		for name, value := range s.experimental {
+			experimental[name] = value
+		}
+		capabilities["experimental"] = experimental
+	}
+
+	return capabilities
+}
+
 // Run starts the stdio server loop
 func (s *Server) Run() error {
 	scanner := bufio.NewScanner(os.Stdin)
 	scanner.Buffer(make([]byte, 0, ScannerInitialBufferSize), ScannerMaxBufferSize)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	// Read stdin in the background so that Shutdown can stop the loop
+	// while it is blocked waiting for input. Responses to server requests
+	// are delivered here, since the loop is busy with the request that is
+	// waiting for them.
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for scanner.Scan() {
+			if response, ok := parseClientResponse(scanner.Bytes()); ok {
+				if !s.pending.deliver(stdioClientID, response) {
+					fmt.Fprintf(os.Stderr, "WARNING: Ignoring response to unknown request %v\n", response.ID)
+				}
+				continue
+			}
+			select {
+			case lines <- scanner.Text():
+			case <-s.drain.done:
+				return
+			}
+		}
+	}()
+
+	for {
+		var line string
+		var ok bool
+		select {
+		case line, ok = <-lines:
+		case <-s.drain.done:
+			return nil
+		}
+		if !ok {
+			break
+		}
 		if line == "" {
 			continue
 		}
@@ -108,7 +261,12 @@ func (s *Server) Run() error {
 			continue
 		}
 
-		s.handleRequest(req)
+		ctx, end, accepted := s.beginRequest()
+		if !accepted {
+			return nil
+		}
+		s.handleRequest(ctx, req)
+		end()
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -118,28 +276,37 @@ func (s *Server) Run() error {
 	return nil
 }
 
-func (s *Server) handleRequest(req JSONRPCRequest) {
+func (s *Server) handleRequest(ctx context.Context, req JSONRPCRequest) {
+	ctx, span := startRequestSpan(ctx, req)
+	defer span.End()
+
 	switch req.Method {
 	case "initialize":
 		s.handleInitialize(req)
 	case "notifications/initialized":
 		// Client notification - no response needed
+	case RootsListChangedMethod:
+		s.stdioRoots.invalidate()
 	case "tools/list":
-		s.handleToolsList(req)
+		s.handleToolsList(ctx, req)
 	case "tools/call":
-		s.handleToolCall(req)
+		s.handleToolCall(s.withStdioRoots(s.withStdioSampler(withRequestProgress(ctx, req, writeStdout))), req)
 	case "resources/list":
 		s.handleResourcesList(req)
 	case "resources/read":
-		s.handleResourceRead(req)
+		s.handleResourceRead(withRequestProgress(ctx, req, writeStdout), req)
+	case "resources/subscribe", "resources/unsubscribe":
+		s.handleResourceSubscribe(ctx, req)
 	case "prompts/list":
 		s.handlePromptsList(req)
 	case "prompts/get":
 		s.handlePromptsGet(req)
+	case "completion/complete":
+		s.handleComplete(ctx, req)
 	case "pgedge/listDatabases":
-		s.handleListDatabases(req)
+		s.handleListDatabases(ctx, req)
 	case "pgedge/selectDatabase":
-		s.handleSelectDatabase(req)
+		s.handleSelectDatabase(ctx, req)
 	default:
 		if req.ID != nil {
 			sendError(req.ID, -32601, "Method not found", nil)
@@ -159,29 +326,18 @@ func (s *Server) handleInitialize(req JSONRPCRequest) {
 		return
 	}
 
+	s.stdioSampling.Store(clientSupportsSampling(req.Params))
+	s.stdioRoots.reset(clientSupportsRoots(req.Params))
+
 	// Accept the client's protocol version for compatibility
 	protocolVersion := params.ProtocolVersion
 	if protocolVersion == "" {
 		protocolVersion = ProtocolVersion
 	}
 
-	capabilities := map[string]interface{}{
-		"tools": map[string]interface{}{},
-	}
-
-	// Add resources capability if resource provider is set
-	if s.resources != nil {
-		capabilities["resources"] = map[string]interface{}{}
-	}
-
-	// Add prompts capability if prompt provider is set
-	if s.prompts != nil {
-		capabilities["prompts"] = map[string]interface{}{}
-	}
-
 	result := InitializeResult{
 		ProtocolVersion: protocolVersion,
-		Capabilities:    capabilities,
+		Capabilities:    s.capabilities(),
 		ServerInfo: Implementation{
 			Name:    ServerName,
 			Version: ServerVersion,
@@ -191,8 +347,34 @@ func (s *Server) handleInitialize(req JSONRPCRequest) {
 	sendResponse(req.ID, result)
 }
 
-func (s *Server) handleToolsList(req JSONRPCRequest) {
-	tools := s.tools.List()
+// withStdioSampler lets tools call the stdio client's model if the client
+// supports sampling
+func (s *Server) withStdioSampler(ctx context.Context) context.Context {
+	if !s.stdioSampling.Load() {
+		return ctx
+	}
+	return WithSampler(ctx, &clientSampler{pending: s.pending, client: stdioClientID, send: writeStdout})
+}
+
+// withStdioRoots lets tools check file paths against the stdio client's
+// roots if the client supports roots
+func (s *Server) withStdioRoots(ctx context.Context) context.Context {
+	if !s.stdioRoots.supported.Load() {
+		return ctx
+	}
+	return WithRootsLister(ctx, &clientRoots{cache: &s.stdioRoots, pending: s.pending, client: stdioClientID, send: writeStdout})
+}
+
+// listTools returns the tools available to the caller
+func (s *Server) listTools(ctx context.Context) []Tool {
+	if lister, ok := s.tools.(ContextToolLister); ok {
+		return lister.ListForContext(ctx)
+	}
+	return s.tools.List()
+}
+
+func (s *Server) handleToolsList(ctx context.Context, req JSONRPCRequest) {
+	tools := s.listTools(ctx)
 
 	result := map[string]interface{}{
 		"tools": tools,
@@ -201,7 +383,7 @@ func (s *Server) handleToolsList(req JSONRPCRequest) {
 	sendResponse(req.ID, result)
 }
 
-func (s *Server) handleToolCall(req JSONRPCRequest) {
+func (s *Server) handleToolCall(ctx context.Context, req JSONRPCRequest) {
 	paramsBytes, err := json.Marshal(req.Params)
 	if err != nil {
 		sendError(req.ID, -32602, "Invalid params", err.Error())
@@ -213,8 +395,8 @@ func (s *Server) handleToolCall(req JSONRPCRequest) {
 		return
 	}
 
-	// For stdio mode, use background context (no authentication)
-	response, err := s.tools.Execute(context.Background(), params.Name, params.Arguments)
+	// For stdio mode there is no authentication; ctx is only cancelled on shutdown
+	response, err := s.tools.Execute(ctx, params.Name, params.Arguments)
 	if err != nil {
 		sendError(req.ID, -32603, "Tool execution error", err.Error())
 		return
@@ -238,7 +420,7 @@ func (s *Server) handleResourcesList(req JSONRPCRequest) {
 	sendResponse(req.ID, result)
 }
 
-func (s *Server) handleResourceRead(req JSONRPCRequest) {
+func (s *Server) handleResourceRead(ctx context.Context, req JSONRPCRequest) {
 	if s.resources == nil {
 		sendError(req.ID, -32601, "Resources not supported", nil)
 		return
@@ -256,7 +438,7 @@ func (s *Server) handleResourceRead(req JSONRPCRequest) {
 	}
 
 	// Use background context for stdio mode (no HTTP request context available)
-	content, err := s.resources.Read(context.Background(), params.URI)
+	content, err := s.resources.Read(ctx, params.URI)
 	if err != nil {
 		sendError(req.ID, -32603, "Resource read error", err.Error())
 		return
@@ -265,6 +447,18 @@ func (s *Server) handleResourceRead(req JSONRPCRequest) {
 	sendResponse(req.ID, content)
 }
 
+func (s *Server) handleResourceSubscribe(ctx context.Context, req JSONRPCRequest) {
+	// The stdio transport has a single client for the life of the process
+	client := &subscriber{send: writeStdout, ctx: context.WithoutCancel(ctx)}
+	rpcErr := s.subscribeResource(stdioClientID, client, req.Params, req.Method == "resources/subscribe")
+	if rpcErr != nil {
+		sendError(req.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+		return
+	}
+
+	sendResponse(req.ID, map[string]interface{}{})
+}
+
 func (s *Server) handlePromptsList(req JSONRPCRequest) {
 	if s.prompts == nil {
 		sendError(req.ID, -32601, "Prompts not supported", nil)
@@ -325,14 +519,14 @@ type SelectDatabaseResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
-func (s *Server) handleListDatabases(req JSONRPCRequest) {
+func (s *Server) handleListDatabases(ctx context.Context, req JSONRPCRequest) {
 	if s.databases == nil {
 		sendError(req.ID, -32601, "Database management not supported", nil)
 		return
 	}
 
 	// Use background context for stdio mode (no HTTP request context available)
-	databases, current, err := s.databases.ListDatabases(context.Background())
+	databases, current, err := s.databases.ListDatabases(ctx)
 	if err != nil {
 		sendError(req.ID, -32603, "Failed to list databases", err.Error())
 		return
@@ -346,7 +540,7 @@ func (s *Server) handleListDatabases(req JSONRPCRequest) {
 	sendResponse(req.ID, result)
 }
 
-func (s *Server) handleSelectDatabase(req JSONRPCRequest) {
+func (s *Server) handleSelectDatabase(ctx context.Context, req JSONRPCRequest) {
 	if s.databases == nil {
 		sendError(req.ID, -32601, "Database management not supported", nil)
 		return
@@ -369,7 +563,7 @@ func (s *Server) handleSelectDatabase(req JSONRPCRequest) {
 	}
 
 	// Use background context for stdio mode (no HTTP request context available)
-	if err := s.databases.SelectDatabase(context.Background(), params.Name); err != nil {
+	if err := s.databases.SelectDatabase(ctx, params.Name); err != nil {
 		result := SelectDatabaseResponse{
 			Success: false,
 			Error:   err.Error(),
@@ -399,8 +593,7 @@ func sendResponse(id, result interface{}) {
 		fmt.Fprintf(os.Stderr, "ERROR: Failed to marshal response: %v\n", err)
 		return
 	}
-	fmt.Println(string(data))
-	_ = os.Stdout.Sync()
+	writeStdout(data)
 }
 
 func sendError(id interface{}, code int, message string, data interface{}) {
@@ -419,6 +612,17 @@ func sendError(id interface{}, code int, message string, data interface{}) {
 		fmt.Fprintf(os.Stderr, "ERROR: Failed to marshal error response: %v\n", err)
 		return
 	}
-	fmt.Println(string(respData))
+	writeStdout(respData)
+}
+
+// stdoutMu serializes stdio messages; progress notifications may be sent
+// from tool goroutines while a response is being written
+var stdoutMu sync.Mutex
+
+// writeStdout writes one newline-delimited message to stdout
+func writeStdout(data []byte) {
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+	fmt.Println(string(data))
 	_ = os.Stdout.Sync()
 }
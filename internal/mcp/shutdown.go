@@ -0,0 +1,169 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cancelGracePeriod is how long Shutdown waits for handlers to return after
+// their contexts have been cancelled
+const cancelGracePeriod = 5 * time.Second
+
+// drainState tracks in-flight requests so that Shutdown can wait for them
+type drainState struct {
+	mu         sync.Mutex
+	draining   bool
+	active     int
+	idle       chan struct{} // Closed when active drops to zero while draining
+	done       chan struct{} // Closed when shutdown begins
+	ctx        context.Context
+	cancel     context.CancelFunc
+	httpServer *http.Server
+}
+
+func newDrainState() *drainState {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &drainState{
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// beginRequest registers an in-flight request and returns its parent context
+// Returns false if the server is shutting down and the request must be rejected
+func (s *Server) beginRequest() (context.Context, func(), bool) {
+	d := s.drain
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return nil, nil, false
+	}
+	d.active++
+
+	var once sync.Once
+	end := func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.active--
+			if d.active == 0 && d.idle != nil {
+				close(d.idle)
+				d.idle = nil
+			}
+		})
+	}
+	return d.ctx, end, true
+}
+
+// drainMiddleware rejects new requests once shutdown has begun and tracks
+// the ones in flight
+func (s *Server) drainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, end, ok := s.beginRequest()
+		if !ok {
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", strconv.Itoa(1))
+			http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer end()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ShuttingDown returns a channel that is closed when shutdown begins
+func (s *Server) ShuttingDown() <-chan struct{} {
+	return s.drain.done
+}
+
+// Shutdown stops accepting new requests and waits for in-flight requests to
+// finish. If ctx expires first, the contexts of the remaining requests are
+// cancelled, which aborts the queries they are running, and Shutdown waits
+// briefly for their handlers to return.
+// Run and RunHTTP return nil once shutdown has begun.
+func (s *Server) Shutdown(ctx context.Context) error {
+	d := s.drain
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		close(d.done)
+	}
+	httpServer := d.httpServer
+	var idle chan struct{}
+	if d.active > 0 {
+		if d.idle == nil {
+			d.idle = make(chan struct{})
+		}
+		idle = d.idle
+	}
+	d.mu.Unlock()
+
+	// Stop listening; requests already in flight are tracked by the drain state
+	if httpServer != nil {
+		go func() {
+			_ = httpServer.Shutdown(context.Background()) //nolint:errcheck // Connections are closed below if draining times out
+		}()
+	}
+
+	if idle == nil {
+		return nil
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Drain timeout expired: cancel the remaining requests
+	d.cancel()
+	remaining := s.inFlight()
+
+	timer := time.NewTimer(cancelGracePeriod)
+	defer timer.Stop()
+	select {
+	case <-idle:
+	case <-timer.C:
+	}
+
+	if httpServer != nil {
+		_ = httpServer.Close() //nolint:errcheck // Best effort; the process is exiting
+	}
+
+	return fmt.Errorf("drain timeout expired, cancelled %d in-flight request(s)", remaining)
+}
+
+// inFlight returns the number of requests currently being handled
+func (s *Server) inFlight() int {
+	s.drain.mu.Lock()
+	defer s.drain.mu.Unlock()
+	return s.drain.active
+}
+
+// setHTTPServer records the HTTP server so that Shutdown can stop it
+// Returns false if shutdown has already begun
+func (s *Server) setHTTPServer(httpServer *http.Server) bool {
+	s.drain.mu.Lock()
+	defer s.drain.mu.Unlock()
+	if s.drain.draining {
+		return false
+	}
+	s.drain.httpServer = httpServer
+	return true
+}
@@ -0,0 +1,96 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShutdown_WaitsForInFlightRequests(t *testing.T) {
+	server := NewServer(nil)
+
+	_, end, ok := server.beginRequest()
+	if !ok {
+		t.Fatal("Request should be accepted before shutdown")
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(finished)
+		end()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	select {
+	case <-finished:
+	default:
+		t.Error("Shutdown returned before the in-flight request finished")
+	}
+
+	if _, _, ok := server.beginRequest(); ok {
+		t.Error("New requests should be rejected after shutdown")
+	}
+}
+
+func TestShutdown_CancelsAfterTimeout(t *testing.T) {
+	server := NewServer(nil)
+
+	reqCtx, end, ok := server.beginRequest()
+	if !ok {
+		t.Fatal("Request should be accepted before shutdown")
+	}
+	go func() {
+		<-reqCtx.Done()
+		end()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); err == nil {
+		t.Error("Expected an error when the drain timeout expires")
+	}
+	if reqCtx.Err() == nil {
+		t.Error("In-flight request context should be cancelled")
+	}
+}
+
+func TestDrainMiddleware_RejectsDuringShutdown(t *testing.T) {
+	server := NewServer(nil)
+	handler := server.drainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp/v1", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Status before shutdown = %d, want 200", w.Code)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp/v1", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Status during shutdown = %d, want 503", w.Code)
+	}
+}
@@ -0,0 +1,518 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pgedge-postgres-mcp/internal/auth"
+)
+
+// Streamable HTTP transport constants
+const (
+	// SessionIDHeader carries the session ID assigned at initialization
+	SessionIDHeader = "Mcp-Session-Id"
+
+	// sessionIdleTimeout is how long an unused session is kept
+	sessionIdleTimeout = 30 * time.Minute
+	// maxSessions bounds the number of sessions; the least recently used is evicted
+	maxSessions = 10000
+	// maxBufferedEvents is the number of SSE events kept per session for resumption
+	maxBufferedEvents = 100
+	// sseKeepaliveInterval is how often a comment is sent on idle SSE streams
+	// so that proxies do not time out long-running tool calls
+	sseKeepaliveInterval = 15 * time.Second
+)
+
+// supportedProtocolVersions lists the protocol versions accepted over HTTP
+// 2025-03-26 introduced the Streamable HTTP transport
+var supportedProtocolVersions = []string{"2025-03-26", ProtocolVersion}
+
+// isSupportedProtocolVersion reports whether a protocol version is supported over HTTP
+func isSupportedProtocolVersion(version string) bool {
+	for _, supported := range supportedProtocolVersions {
+		if version == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// sseEvent is a message sent (or to be sent) on an SSE stream
+type sseEvent struct {
+	id   uint64
+	data []byte
+}
+
+// session holds state for a Streamable HTTP client session
+type session struct {
+	id        string
+	tokenHash string // Token that created the session; other tokens cannot use it
+	ctx       context.Context
+	cancel    context.CancelFunc
+	sampling  atomic.Bool // Whether the client declared the sampling capability
+	roots     rootsCache  // The client's roots, if it declared the capability
+
+	mu       sync.Mutex
+	lastSeen time.Time
+	nextID   uint64
+	events   []sseEvent    // Recent events for replay after reconnecting
+	notify   chan struct{} // Closed and replaced when an event is recorded
+}
+
+// record stores an event for replay and wakes up listening streams
+func (sess *session) record(data []byte) sseEvent {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.nextID++
+	event := sseEvent{id: sess.nextID, data: data}
+	sess.events = append(sess.events, event)
+	if len(sess.events) > maxBufferedEvents {
+		sess.events = sess.events[len(sess.events)-maxBufferedEvents:]
+	}
+	close(sess.notify)
+	sess.notify = make(chan struct{})
+	return event
+}
+
+// eventsAfter returns buffered events with IDs greater than lastID and a
+// channel that is closed when another event is recorded
+func (sess *session) eventsAfter(lastID uint64) ([]sseEvent, <-chan struct{}) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	var events []sseEvent
+	for _, event := range sess.events {
+		if event.id > lastID {
+			events = append(events, event)
+		}
+	}
+	return events, sess.notify
+}
+
+// touch records session activity
+func (sess *session) touch() {
+	sess.mu.Lock()
+	sess.lastSeen = time.Now()
+	sess.mu.Unlock()
+}
+
+// idleSince returns the time of the last session activity
+func (sess *session) idleSince() time.Time {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.lastSeen
+}
+
+// sessionStore tracks active Streamable HTTP sessions
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	prefix   string // Prepended to session IDs (the replica with session affinity)
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]*session)}
+}
+
+// create starts a new session for a token
+func (st *sessionStore) create(tokenHash string) (*session, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	sess := &session{
+		id:        st.prefix + hex.EncodeToString(buf),
+		tokenHash: tokenHash,
+		ctx:       ctx,
+		cancel:    cancel,
+		lastSeen:  time.Now(),
+		notify:    make(chan struct{}),
+	}
+
+	st.expireLocked()
+	if len(st.sessions) >= maxSessions {
+		var oldest *session
+		for _, candidate := range st.sessions {
+			if oldest == nil || candidate.idleSince().Before(oldest.idleSince()) {
+				oldest = candidate
+			}
+		}
+		st.removeLocked(oldest.id)
+	}
+	st.sessions[sess.id] = sess
+	return sess, nil
+}
+
+// get returns an active session if it belongs to the token
+func (st *sessionStore) get(id, tokenHash string) (*session, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	sess, ok := st.sessions[id]
+	if !ok || sess.tokenHash != tokenHash {
+		return nil, false
+	}
+	if time.Since(sess.idleSince()) > sessionIdleTimeout {
+		st.removeLocked(id)
+		return nil, false
+	}
+	sess.touch()
+	return sess, true
+}
+
+// remove terminates a session
+func (st *sessionStore) remove(id string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.removeLocked(id)
+}
+
+func (st *sessionStore) removeLocked(id string) {
+	if sess, ok := st.sessions[id]; ok {
+		sess.cancel()
+		delete(st.sessions, id)
+	}
+}
+
+// expireLocked removes sessions that have been idle too long
+func (st *sessionStore) expireLocked() {
+	cutoff := time.Now().Add(-sessionIdleTimeout)
+	for id, sess := range st.sessions {
+		if sess.idleSince().Before(cutoff) {
+			st.removeLocked(id)
+		}
+	}
+}
+
+// acceptsEventStream reports whether the client accepts SSE responses,
+// which identifies clients using the Streamable HTTP transport
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// streamsResponse reports whether a method's response is sent as an SSE
+// stream to clients that accept one; these are the potentially long-running calls
+func streamsResponse(method string) bool {
+	return method == "tools/call" || method == "resources/read"
+}
+
+type sessionKey struct{}
+
+// withSession returns a context carrying the request's session
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sess)
+}
+
+// sessionFromContext returns the request's session, or nil for clients
+// that do not use sessions
+func sessionFromContext(ctx context.Context) *session {
+	sess, _ := ctx.Value(sessionKey{}).(*session)
+	return sess
+}
+
+// SessionIDFromContext returns the ID of the request's session, or "" for
+// clients that do not use sessions
+func SessionIDFromContext(ctx context.Context) string {
+	if sess := sessionFromContext(ctx); sess != nil {
+		return sess.id
+	}
+	return ""
+}
+
+// SessionDoneFromContext returns a channel that is closed when the
+// request's session ends, or nil for clients that do not use sessions
+func SessionDoneFromContext(ctx context.Context) <-chan struct{} {
+	if sess := sessionFromContext(ctx); sess != nil {
+		return sess.ctx.Done()
+	}
+	return nil
+}
+
+// lookupSession resolves the Mcp-Session-Id header of a request
+// Requests without the header are handled without a session for compatibility
+// with clients that use plain JSON over HTTP. Writes an error and returns
+// false if the session is unknown or expired, so the client re-initializes;
+// with session affinity the error explains when the session was created on
+// another replica.
+func (s *Server) lookupSession(w http.ResponseWriter, r *http.Request) (*session, bool) {
+	id := r.Header.Get(SessionIDHeader)
+	if id == "" {
+		return nil, true
+	}
+	sess, ok := s.sessions.get(id, auth.GetTokenHashFromContext(r.Context()))
+	if !ok {
+		if s.affinity != nil && s.affinity.misrouted(w, r, id) {
+			return nil, false
+		}
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil, false
+	}
+	return sess, true
+}
+
+// detachContext returns a context carrying the values of ctx that is not
+// cancelled when the client disconnects, so that a client can reconnect and
+// pick up the result. It is still cancelled when the session ends or when
+// the server cancels requests during shutdown.
+func (s *Server) detachContext(ctx context.Context, sess *session) (context.Context, context.CancelFunc) {
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stopSession := context.AfterFunc(sess.ctx, cancel)
+	stopDrain := context.AfterFunc(s.drain.ctx, cancel)
+	return detached, func() {
+		stopSession()
+		stopDrain()
+		cancel()
+	}
+}
+
+// writeSSEHeaders starts an SSE response
+func writeSSEHeaders(w http.ResponseWriter, sess *session) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	if sess != nil {
+		w.Header().Set(SessionIDHeader, sess.id)
+	}
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeSSEEvent writes a single SSE message event
+// Events without a session have no ID because they cannot be replayed
+func writeSSEEvent(w http.ResponseWriter, event sseEvent) error {
+	var sb strings.Builder
+	if event.id > 0 {
+		fmt.Fprintf(&sb, "id: %d\n", event.id)
+	}
+	sb.WriteString("event: message\n")
+	fmt.Fprintf(&sb, "data: %s\n\n", event.data)
+	if _, err := w.Write([]byte(sb.String())); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// writeSSEComment writes a keepalive comment
+func writeSSEComment(w http.ResponseWriter) error {
+	if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// streamResponse handles a request and sends its response as an SSE stream,
+// with keepalive comments while the request is running. With a session the
+// response is buffered so it can be replayed if the client reconnects.
+func (s *Server) streamResponse(w http.ResponseWriter, r *http.Request, ctx context.Context, sess *session, req JSONRPCRequest) {
+	if sess != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = s.detachContext(ctx, sess)
+		defer cancel()
+	}
+
+	writeSSEHeaders(w, sess)
+
+	// Progress notifications are sent on the stream ahead of the result
+	messages := make(chan sseEvent, 32)
+	ctx = withRequestProgress(ctx, req, func(data []byte) {
+		event := sseEvent{data: data}
+		if sess != nil {
+			event = sess.record(data)
+		}
+		select {
+		case messages <- event:
+		default:
+			// The writer is behind; progress is advisory so the update is dropped
+		}
+	})
+
+	// Sampling and roots requests are sent on the same stream; the client
+	// answers with a POST, so a session is needed to match the response
+	if sess != nil {
+		requestCtx := ctx
+		send := func(data []byte) {
+			select {
+			case messages <- sess.record(data):
+			case <-requestCtx.Done():
+			}
+		}
+		if sess.sampling.Load() {
+			ctx = WithSampler(ctx, &clientSampler{pending: s.pending, client: sess.id, send: send})
+		}
+		if sess.roots.supported.Load() {
+			ctx = WithRootsLister(ctx, &clientRoots{cache: &sess.roots, pending: s.pending, client: sess.id, send: send})
+		}
+	}
+
+	result := make(chan sseEvent, 1)
+	go func() {
+		response := s.handleRequestHTTP(ctx, req)
+		data, err := json.Marshal(response)
+		if err != nil {
+			data, _ = json.Marshal(createErrorResponse(req.ID, -32603, "Internal error", err.Error())) //nolint:errcheck // Static structure always marshals
+		}
+		if s.debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Outgoing response (stream): %s\n", string(data))
+		}
+		event := sseEvent{data: data}
+		if sess != nil {
+			event = sess.record(data)
+		}
+		result <- event
+	}()
+
+	ticker := time.NewTicker(sseKeepaliveInterval)
+	defer ticker.Stop()
+
+	connected := true
+	disconnected := r.Context().Done()
+	for {
+		select {
+		case event := <-messages:
+			if connected {
+				if err := writeSSEEvent(w, event); err != nil {
+					connected = false
+				}
+			}
+		case event := <-result:
+			// Progress is reported before the handler returns, so anything
+			// still queued precedes the result
+			for drained := false; !drained && connected; {
+				select {
+				case p := <-messages:
+					if err := writeSSEEvent(w, p); err != nil {
+						connected = false
+					}
+				default:
+					drained = true
+				}
+			}
+			if connected {
+				if err := writeSSEEvent(w, event); err != nil {
+					fmt.Fprintf(os.Stderr, "WARNING: Failed to write SSE event: %v\n", err)
+				}
+			}
+			return
+		case <-ticker.C:
+			if connected {
+				if err := writeSSEComment(w); err != nil {
+					connected = false
+				}
+			}
+		case <-disconnected:
+			// The client disconnected; with a session the request keeps
+			// running so the client can resume the stream to get the result
+			if sess == nil {
+				<-result
+				return
+			}
+			connected = false
+			disconnected = nil
+		}
+	}
+}
+
+// handleSSEStream handles GET requests, which open a stream for messages
+// from the server. A Last-Event-ID header replays events the client missed.
+func (s *Server) handleSSEStream(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get(SessionIDHeader) == "" {
+		http.Error(w, "Missing "+SessionIDHeader+" header", http.StatusBadRequest)
+		return
+	}
+	sess, ok := s.lookupSession(w, r)
+	if !ok {
+		return
+	}
+
+	var lastID uint64
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID header", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	writeSSEHeaders(w, sess)
+
+	ticker := time.NewTicker(sseKeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		events, notify := sess.eventsAfter(lastID)
+		for _, event := range events {
+			if err := writeSSEEvent(w, event); err != nil {
+				return
+			}
+			lastID = event.id
+		}
+
+		select {
+		case <-notify:
+		case <-ticker.C:
+			sess.touch()
+			if err := writeSSEComment(w); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-sess.ctx.Done():
+			return
+		case <-s.drain.done:
+			return
+		}
+	}
+}
+
+// handleSessionDelete handles DELETE requests, which terminate a session
+func (s *Server) handleSessionDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(SessionIDHeader) == "" {
+		http.Error(w, "Missing "+SessionIDHeader+" header", http.StatusBadRequest)
+		return
+	}
+	sess, ok := s.lookupSession(w, r)
+	if !ok {
+		return
+	}
+	s.sessions.remove(sess.id)
+	w.WriteHeader(http.StatusNoContent)
+}
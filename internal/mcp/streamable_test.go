@@ -0,0 +1,211 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/auth"
+)
+
+// streamableRequest builds a request from a Streamable HTTP client
+func streamableRequest(t *testing.T, method, sessionID string, rpcReq interface{}) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	if rpcReq != nil {
+		if err := json.NewEncoder(&body).Encode(rpcReq); err != nil {
+			t.Fatalf("failed to encode request: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, "/mcp/v1", &body)
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set(SessionIDHeader, sessionID)
+	}
+	return req
+}
+
+// initializeSession initializes a Streamable HTTP session and returns its ID
+func initializeSession(t *testing.T, server *Server) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, "", JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params:  map[string]interface{}{"protocolVersion": "2025-03-26"},
+	}))
+
+	var response JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	result, ok := response.Result.(map[string]interface{})
+	if !ok || result["protocolVersion"] != "2025-03-26" {
+		t.Errorf("expected negotiated protocol version 2025-03-26, got %v", response.Result)
+	}
+
+	sessionID := w.Header().Get(SessionIDHeader)
+	if sessionID == "" {
+		t.Fatal("expected a session ID on initialize")
+	}
+	return sessionID
+}
+
+func TestStreamableHTTP_ToolCallStream(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+	sessionID := initializeSession(t, server)
+
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "test_tool"},
+	}))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "id: 1\nevent: message\ndata: ") {
+		t.Fatalf("unexpected SSE body: %q", body)
+	}
+
+	data := strings.TrimSpace(strings.TrimPrefix(body, "id: 1\nevent: message\ndata: "))
+	var response JSONRPCResponse
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatalf("failed to decode event data: %v", err)
+	}
+	if response.Error != nil || response.ID != float64(2) {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
+
+func TestStreamableHTTP_Notification(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+	sessionID := initializeSession(t, server)
+
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/initialized",
+	}))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body.String())
+	}
+}
+
+func TestStreamableHTTP_ResumeStream(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+	sessionID := initializeSession(t, server)
+
+	// A tool result is recorded in the session
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "test_tool"},
+	}))
+
+	// Reconnecting with Last-Event-ID 0 replays it
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req := streamableRequest(t, http.MethodGet, sessionID, nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "0")
+	w = httptest.NewRecorder()
+	server.handleHTTPRequest(w, req)
+
+	if !strings.Contains(w.Body.String(), "id: 1\nevent: message\n") {
+		t.Errorf("expected replayed event, got %q", w.Body.String())
+	}
+
+	// Events the client already received are not replayed
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	req = streamableRequest(t, http.MethodGet, sessionID, nil).WithContext(ctx2)
+	req.Header.Set("Last-Event-ID", "1")
+	w = httptest.NewRecorder()
+	server.handleHTTPRequest(w, req)
+
+	if strings.Contains(w.Body.String(), "event: message") {
+		t.Errorf("expected no replayed events, got %q", w.Body.String())
+	}
+}
+
+func TestStreamableHTTP_Sessions(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+
+	// Unknown sessions return 404 so the client re-initializes
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, "unknown", JSONRPCRequest{
+		JSONRPC: "2.0", ID: 1, Method: "tools/list",
+	}))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown session, got %d", w.Code)
+	}
+
+	sessionID := initializeSession(t, server)
+
+	// Sessions cannot be used with a different token
+	req := streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+		JSONRPC: "2.0", ID: 2, Method: "tools/list",
+	})
+	req = req.WithContext(context.WithValue(req.Context(), auth.TokenHashContextKey, "other-token"))
+	w = httptest.NewRecorder()
+	server.handleHTTPRequest(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for another token's session, got %d", w.Code)
+	}
+
+	// DELETE terminates the session
+	w = httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodDelete, sessionID, nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+		JSONRPC: "2.0", ID: 3, Method: "tools/list",
+	}))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 after session was deleted, got %d", w.Code)
+	}
+}
+
+func TestStreamableHTTP_LegacyClient(t *testing.T) {
+	server := NewServer(&mockToolProvider{})
+
+	// Plain JSON clients get no session and JSON responses
+	body, _ := json.Marshal(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "initialize"})
+	req := httptest.NewRequest(http.MethodPost, "/mcp/v1", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, req)
+
+	if w.Header().Get(SessionIDHeader) != "" {
+		t.Error("expected no session for a plain JSON client")
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+}
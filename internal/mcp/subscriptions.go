@@ -0,0 +1,263 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultResourcePollInterval is how often subscribed resources are re-read
+// to detect changes when no interval is configured
+const DefaultResourcePollInterval = 30 * time.Second
+
+// ResourceUpdatedParams are the parameters of notifications/resources/updated
+type ResourceUpdatedParams struct {
+	URI string `json:"uri"`
+}
+
+// subscriber is a client with resource subscriptions: the stdio client or
+// a Streamable HTTP session
+type subscriber struct {
+	send func(data []byte)
+	// ctx carries the values of the subscribing request (token, database
+	// selection) so resources are re-read as that client
+	ctx context.Context
+	// done is closed when the client's session ends (nil for stdio)
+	done <-chan struct{}
+
+	mu   sync.Mutex
+	uris map[string]string // Subscribed URI -> hash of the last content seen
+}
+
+// subscriptionStore tracks resource subscriptions by client
+type subscriptionStore struct {
+	mu          sync.Mutex
+	subscribers map[string]*subscriber
+}
+
+func newSubscriptionStore() *subscriptionStore {
+	return &subscriptionStore{subscribers: make(map[string]*subscriber)}
+}
+
+// subscribe adds a URI to a client's subscriptions with the hash of its
+// current content; client is used if the client has no subscriptions yet
+func (st *subscriptionStore) subscribe(id string, client *subscriber, uri, hash string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	sub, ok := st.subscribers[id]
+	if !ok || sub.ended() {
+		sub = client
+		sub.uris = make(map[string]string)
+		st.subscribers[id] = sub
+	}
+	sub.mu.Lock()
+	sub.uris[uri] = hash
+	sub.mu.Unlock()
+}
+
+// unsubscribe removes a URI from a client's subscriptions
+func (st *subscriptionStore) unsubscribe(id, uri string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	sub, ok := st.subscribers[id]
+	if !ok {
+		return
+	}
+	sub.mu.Lock()
+	delete(sub.uris, uri)
+	empty := len(sub.uris) == 0
+	sub.mu.Unlock()
+	if empty {
+		delete(st.subscribers, id)
+	}
+}
+
+// active returns the subscribers whose sessions are still open, dropping
+// the others
+func (st *subscriptionStore) active() []*subscriber {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	subs := make([]*subscriber, 0, len(st.subscribers))
+	for id, sub := range st.subscribers {
+		if sub.ended() {
+			delete(st.subscribers, id)
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// ended reports whether the client's session has ended
+func (sub *subscriber) ended() bool {
+	select {
+	case <-sub.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// subscribedURIs returns a copy of the subscriber's URIs and content hashes
+func (sub *subscriber) subscribedURIs() map[string]string {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	uris := make(map[string]string, len(sub.uris))
+	for uri, hash := range sub.uris {
+		uris[uri] = hash
+	}
+	return uris
+}
+
+// update records new content for a URI and reports whether it changed
+// Returns false if the client unsubscribed in the meantime
+func (sub *subscriber) update(uri, hash string) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	old, ok := sub.uris[uri]
+	if !ok || old == hash {
+		return false
+	}
+	sub.uris[uri] = hash
+	return true
+}
+
+// notify sends notifications/resources/updated for a URI
+func (sub *subscriber) notify(uri string) {
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/resources/updated",
+		"params":  ResourceUpdatedParams{URI: uri},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Failed to marshal resource notification: %v\n", err)
+		return
+	}
+	sub.send(data)
+}
+
+// contentHash fingerprints resource content so changes can be detected
+func contentHash(content ResourceContent) string {
+	data, err := json.Marshal(content.Contents)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// resourceExists reports whether a URI is listed by the resource provider
+func (s *Server) resourceExists(uri string) bool {
+	for _, resource := range s.resources.List() {
+		if resource.URI == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribeResource validates a subscription request from a client and
+// records it. The resource is read once so that later changes can be detected
+func (s *Server) subscribeResource(id string, client *subscriber, params interface{}, subscribe bool) *RPCError {
+	if s.resources == nil {
+		return &RPCError{Code: -32601, Message: "Resources not supported"}
+	}
+
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		return &RPCError{Code: -32602, Message: "Invalid params", Data: err.Error()}
+	}
+	var p ResourceReadParams
+	if err := json.Unmarshal(paramsBytes, &p); err != nil {
+		return &RPCError{Code: -32602, Message: "Invalid params", Data: err.Error()}
+	}
+	if p.URI == "" {
+		return &RPCError{Code: -32602, Message: "Invalid params", Data: "uri is required"}
+	}
+
+	if !subscribe {
+		s.subscriptions.unsubscribe(id, p.URI)
+		return nil
+	}
+
+	if !s.resourceExists(p.URI) {
+		return &RPCError{Code: -32602, Message: "Resource not found", Data: p.URI}
+	}
+	content, err := s.resources.Read(client.ctx, p.URI)
+	if err != nil {
+		return &RPCError{Code: -32603, Message: "Resource read error", Data: err.Error()}
+	}
+	s.subscriptions.subscribe(id, client, p.URI, contentHash(content))
+	return nil
+}
+
+// NotifyResourceUpdated sends notifications/resources/updated to every
+// client subscribed to the URI; use it when a change is known without polling
+func (s *Server) NotifyResourceUpdated(uri string) {
+	for _, sub := range s.subscriptions.active() {
+		if _, ok := sub.subscribedURIs()[uri]; ok {
+			sub.notify(uri)
+		}
+	}
+}
+
+// pollSubscriptions re-reads every subscribed resource and notifies clients
+// whose resources changed since they were last read
+func (s *Server) pollSubscriptions() {
+	if s.resources == nil {
+		return
+	}
+	for _, sub := range s.subscriptions.active() {
+		for uri := range sub.subscribedURIs() {
+			content, err := s.resources.Read(sub.ctx, uri)
+			if err != nil {
+				if s.debug {
+					fmt.Fprintf(os.Stderr, "[DEBUG] Failed to poll resource %s: %v\n", uri, err)
+				}
+				continue
+			}
+			if sub.update(uri, contentHash(content)) {
+				sub.notify(uri)
+			}
+		}
+	}
+}
+
+// StartResourceWatcher polls subscribed resources for changes until the
+// server shuts down; an interval of zero uses DefaultResourcePollInterval
+// Resources are only read while clients are subscribed to them
+func (s *Server) StartResourceWatcher(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultResourcePollInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.pollSubscriptions()
+			case <-s.drain.done:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,137 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// changingResources is a resource provider whose content can be changed
+type changingResources struct {
+	mu      sync.Mutex
+	content string
+}
+
+func (c *changingResources) List() []Resource {
+	return []Resource{{URI: "pg://system_info", Name: "System Info"}}
+}
+
+func (c *changingResources) Read(ctx context.Context, uri string) (ResourceContent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return NewResourceSuccess(uri, "text/plain", c.content)
+}
+
+func (c *changingResources) set(content string) {
+	c.mu.Lock()
+	c.content = content
+	c.mu.Unlock()
+}
+
+// subscribeRequest sends resources/subscribe or resources/unsubscribe
+func subscribeRequest(t *testing.T, server *Server, sessionID, method, uri string) JSONRPCResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	server.handleHTTPRequest(w, streamableRequest(t, http.MethodPost, sessionID, JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      5,
+		Method:  method,
+		Params:  map[string]interface{}{"uri": uri},
+	}))
+	var response JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestResourceSubscriptions_HTTP(t *testing.T) {
+	resources := &changingResources{content: "v1"}
+	server := NewServer(&mockToolProvider{})
+	server.SetResourceProvider(resources)
+
+	caps := server.capabilities()
+	if res, ok := caps["resources"].(map[string]interface{}); !ok || res["subscribe"] != true {
+		t.Errorf("expected resources.subscribe capability, got %v", caps["resources"])
+	}
+
+	sessionID := initializeSession(t, server)
+
+	if resp := subscribeRequest(t, server, sessionID, "resources/subscribe", "pg://missing"); resp.Error == nil {
+		t.Error("expected an error subscribing to an unknown resource")
+	}
+	if resp := subscribeRequest(t, server, "", "resources/subscribe", "pg://system_info"); resp.Error == nil {
+		t.Error("expected an error subscribing without a session")
+	}
+	if resp := subscribeRequest(t, server, sessionID, "resources/subscribe", "pg://system_info"); resp.Error != nil {
+		t.Fatalf("subscribe failed: %+v", resp.Error)
+	}
+
+	// Unchanged content does not notify
+	server.pollSubscriptions()
+	sess, _ := server.sessions.get(sessionID, "")
+	if events, _ := sess.eventsAfter(0); len(events) != 0 {
+		t.Fatalf("expected no notifications, got %d", len(events))
+	}
+
+	resources.set("v2")
+	server.pollSubscriptions()
+	server.pollSubscriptions()
+	events, _ := sess.eventsAfter(0)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(events))
+	}
+	data := string(events[0].data)
+	if !strings.Contains(data, `"method":"notifications/resources/updated"`) || !strings.Contains(data, `"uri":"pg://system_info"`) {
+		t.Errorf("unexpected notification: %s", data)
+	}
+
+	// Explicit notifications reach subscribers too
+	server.NotifyResourceUpdated("pg://system_info")
+	server.NotifyResourceUpdated("pg://other")
+	if events, _ := sess.eventsAfter(0); len(events) != 2 {
+		t.Errorf("expected 2 notifications, got %d", len(events))
+	}
+
+	if resp := subscribeRequest(t, server, sessionID, "resources/unsubscribe", "pg://system_info"); resp.Error != nil {
+		t.Fatalf("unsubscribe failed: %+v", resp.Error)
+	}
+	resources.set("v3")
+	server.pollSubscriptions()
+	if events, _ := sess.eventsAfter(0); len(events) != 2 {
+		t.Errorf("expected no notifications after unsubscribing, got %d", len(events))
+	}
+}
+
+func TestResourceSubscriptions_SessionEnd(t *testing.T) {
+	resources := &changingResources{content: "v1"}
+	server := NewServer(&mockToolProvider{})
+	server.SetResourceProvider(resources)
+
+	sessionID := initializeSession(t, server)
+	if resp := subscribeRequest(t, server, sessionID, "resources/subscribe", "pg://system_info"); resp.Error != nil {
+		t.Fatalf("subscribe failed: %+v", resp.Error)
+	}
+	if n := len(server.subscriptions.active()); n != 1 {
+		t.Fatalf("expected 1 subscriber, got %d", n)
+	}
+
+	server.sessions.remove(sessionID)
+	if n := len(server.subscriptions.active()); n != 0 {
+		t.Errorf("subscriptions should end with the session, got %d", n)
+	}
+}
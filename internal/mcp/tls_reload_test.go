@@ -0,0 +1,112 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate and key with the given common name
+func writeTestCert(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, commonName+".crt")
+	keyFile = filepath.Join(dir, commonName+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// servedCommonName returns the common name of the certificate the TLS config would serve
+func servedCommonName(t *testing.T, tlsConfig *tls.Config) string {
+	t.Helper()
+
+	cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("GetCertificate failed: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse served certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloadTLSCertificate(t *testing.T) {
+	dir := t.TempDir()
+	oldCert, oldKey := writeTestCert(t, dir, "old")
+	newCert, newKey := writeTestCert(t, dir, "new")
+
+	server := NewServer(&mockToolProvider{})
+
+	// Reload is rejected when HTTPS is not running
+	if err := server.ReloadTLSCertificate(newCert, newKey, ""); err == nil {
+		t.Error("expected error when HTTPS is not enabled")
+	}
+
+	tlsConfig, err := server.loadTLSConfig(&HTTPConfig{CertFile: oldCert, KeyFile: oldKey})
+	if err != nil {
+		t.Fatalf("loadTLSConfig failed: %v", err)
+	}
+	if got := servedCommonName(t, tlsConfig); got != "old" {
+		t.Fatalf("expected initial certificate 'old', got %q", got)
+	}
+
+	if err := server.ReloadTLSCertificate(newCert, newKey, ""); err != nil {
+		t.Fatalf("ReloadTLSCertificate failed: %v", err)
+	}
+	if got := servedCommonName(t, tlsConfig); got != "new" {
+		t.Errorf("expected reloaded certificate 'new', got %q", got)
+	}
+
+	// A broken certificate keeps the current one in service
+	if err := server.ReloadTLSCertificate(filepath.Join(dir, "missing.crt"), newKey, ""); err == nil {
+		t.Error("expected error for missing certificate file")
+	}
+	if got := servedCommonName(t, tlsConfig); got != "new" {
+		t.Errorf("expected certificate 'new' to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,49 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"pgedge-postgres-mcp/internal/tracing"
+)
+
+// startRequestSpan starts the span of a JSON-RPC request, named after its
+// method and, for tool calls, the tool
+func startRequestSpan(ctx context.Context, req JSONRPCRequest) (context.Context, *tracing.Span) {
+	name := "mcp " + req.Method
+	attrs := []tracing.Attr{
+		tracing.String("rpc.system", "jsonrpc"),
+		tracing.String("rpc.method", req.Method),
+	}
+	if req.ID != nil {
+		attrs = append(attrs, tracing.String("rpc.jsonrpc.request_id", fmt.Sprint(req.ID)))
+	}
+	if req.Method == "tools/call" {
+		if params, ok := req.Params.(map[string]interface{}); ok {
+			if tool, ok := params["name"].(string); ok {
+				name += " " + tool
+				attrs = append(attrs, tracing.String("mcp.tool.name", tool))
+			}
+		}
+	}
+	return tracing.Start(ctx, name, tracing.KindServer, attrs...)
+}
+
+// endRequestSpan ends the span of a JSON-RPC request with its outcome
+func endRequestSpan(span *tracing.Span, rpcErr *RPCError) {
+	if rpcErr != nil {
+		span.SetAttributes(tracing.Int("rpc.jsonrpc.error_code", rpcErr.Code))
+		span.SetError(rpcErr.Message)
+	}
+	span.End()
+}
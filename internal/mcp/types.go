@@ -81,8 +81,18 @@ type ToolCallParams struct {
 
 // ToolResponse represents the response from a tool execution
 type ToolResponse struct {
-	Content []ContentItem `json:"content"`
-	IsError bool          `json:"isError,omitempty"`
+	Content []ContentItem     `json:"content"`
+	IsError bool              `json:"isError,omitempty"`
+	Meta    *ToolResponseMeta `json:"_meta,omitempty"`
+}
+
+// ToolResponseMeta describes the size of a tool response, so clients can
+// decide how much of it to keep in their context
+type ToolResponseMeta struct {
+	Bytes           int  `json:"bytes"`           // Size of the text content
+	EstimatedTokens int  `json:"estimatedTokens"` // Approximate tokens of the text content
+	Rows            *int `json:"rows,omitempty"`  // Rows returned, for tools that return rows
+	Truncated       bool `json:"truncated"`       // Whether the tool left out results
 }
 
 // ContentItem represents a piece of content in a tool response
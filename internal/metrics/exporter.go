@@ -0,0 +1,242 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+// Schema is the schema that exported metrics are written to
+const Schema = "pgedge_mcp_metrics"
+
+// DefaultExportInterval is used when no interval is configured
+const DefaultExportInterval = time.Minute
+
+// finalExportTimeout bounds the export made when the exporter stops
+const finalExportTimeout = 5 * time.Second
+
+// schemaStatements create the export tables. Tool calls are per snapshot
+// window; pool statistics and samples are the values at capture time.
+var schemaStatements = []string{
+	`CREATE SCHEMA IF NOT EXISTS ` + Schema,
+	`CREATE TABLE IF NOT EXISTS ` + Schema + `.tool_calls (
+		captured_at timestamptz NOT NULL,
+		server text NOT NULL,
+		window_seconds double precision NOT NULL,
+		tool_name text NOT NULL,
+		calls bigint NOT NULL,
+		errors bigint NOT NULL,
+		avg_ms double precision NOT NULL,
+		p95_ms double precision NOT NULL,
+		max_ms double precision NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS tool_calls_captured_at_idx ON ` + Schema + `.tool_calls (captured_at)`,
+	`CREATE TABLE IF NOT EXISTS ` + Schema + `.pool_stats (
+		captured_at timestamptz NOT NULL,
+		server text NOT NULL,
+		database_name text NOT NULL,
+		clients integer NOT NULL,
+		total_conns integer NOT NULL,
+		acquired_conns integer NOT NULL,
+		idle_conns integer NOT NULL,
+		max_conns integer NOT NULL,
+		acquire_count bigint NOT NULL,
+		empty_acquire_count bigint NOT NULL,
+		avg_acquire_ms double precision NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS pool_stats_captured_at_idx ON ` + Schema + `.pool_stats (captured_at)`,
+	`CREATE TABLE IF NOT EXISTS ` + Schema + `.samples (
+		captured_at timestamptz NOT NULL,
+		server text NOT NULL,
+		metric text NOT NULL,
+		labels jsonb NOT NULL,
+		value double precision NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS samples_captured_at_idx ON ` + Schema + `.samples (captured_at)`,
+}
+
+// exportTables are the tables pruned by the retention period
+var exportTables = []string{"tool_calls", "pool_stats", "samples"}
+
+// ExporterConfig configures the database export
+type ExporterConfig struct {
+	Server    string                      // Identifies this server in the exported rows
+	Interval  time.Duration               // Time between snapshots (0 = DefaultExportInterval)
+	Retention time.Duration               // Delete older rows (0 = keep all)
+	Tools     *ToolMetrics                // Tool calls to export
+	Registry  *Registry                   // Samples to export (nil = none)
+	Pools     func() []database.PoolStats // Connection pool statistics (nil = none)
+}
+
+// Exporter periodically writes snapshots of the server's metrics to a
+// database, so they can be queried like any other data
+type Exporter struct {
+	pool        *pgxpool.Pool
+	cfg         ExporterConfig
+	schemaReady bool
+}
+
+// snapshot is the data written by one export
+type snapshot struct {
+	capturedAt time.Time
+	window     time.Duration
+	tools      []ToolWindowStats
+	pools      []database.PoolStats
+	samples    []Sample
+}
+
+// NewExporter creates an exporter writing through pool, which must allow
+// writes to the metrics schema
+func NewExporter(pool *pgxpool.Pool, cfg ExporterConfig) *Exporter {
+	if cfg.Tools == nil {
+		cfg.Tools = Tools
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultExportInterval
+	}
+	return &Exporter{pool: pool, cfg: cfg}
+}
+
+// Run exports a snapshot every interval until ctx is cancelled, then
+// exports the last window. Failed exports are reported to onError and
+// their data is lost; the next export is attempted as usual.
+func (e *Exporter) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Export(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		case <-ctx.Done():
+			finalCtx, cancel := context.WithTimeout(context.Background(), finalExportTimeout)
+			if err := e.Export(finalCtx); err != nil && onError != nil {
+				onError(err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// Export writes a snapshot of the metrics and applies the retention period
+func (e *Exporter) Export(ctx context.Context) error {
+	snap := e.takeSnapshot()
+
+	if !e.schemaReady {
+		for _, stmt := range schemaStatements {
+			if _, err := e.pool.Exec(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to create the %s schema: %w", Schema, err)
+			}
+		}
+		e.schemaReady = true
+	}
+
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin metrics export: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // No-op after commit
+
+	copies := []struct {
+		table   string
+		columns []string
+		rows    [][]interface{}
+	}{
+		{"tool_calls", []string{"captured_at", "server", "window_seconds", "tool_name", "calls", "errors", "avg_ms", "p95_ms", "max_ms"}, snap.toolRows(e.cfg.Server)},
+		{"pool_stats", []string{"captured_at", "server", "database_name", "clients", "total_conns", "acquired_conns", "idle_conns", "max_conns", "acquire_count", "empty_acquire_count", "avg_acquire_ms"}, snap.poolRows(e.cfg.Server)},
+		{"samples", []string{"captured_at", "server", "metric", "labels", "value"}, snap.sampleRows(e.cfg.Server)},
+	}
+	for _, c := range copies {
+		if len(c.rows) == 0 {
+			continue
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{Schema, c.table}, c.columns, pgx.CopyFromRows(c.rows)); err != nil {
+			return fmt.Errorf("failed to write %s.%s: %w", Schema, c.table, err)
+		}
+	}
+
+	if e.cfg.Retention > 0 {
+		cutoff := snap.capturedAt.Add(-e.cfg.Retention)
+		for _, table := range exportTables {
+			if _, err := tx.Exec(ctx, "DELETE FROM "+pgx.Identifier{Schema, table}.Sanitize()+" WHERE captured_at < $1", cutoff); err != nil {
+				return fmt.Errorf("failed to prune %s.%s: %w", Schema, table, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit metrics export: %w", err)
+	}
+	return nil
+}
+
+// takeSnapshot collects the current metrics, starting a new tool window
+func (e *Exporter) takeSnapshot() snapshot {
+	snap := snapshot{capturedAt: time.Now()}
+	snap.tools, snap.window = e.cfg.Tools.TakeWindow()
+	if e.cfg.Pools != nil {
+		snap.pools = e.cfg.Pools()
+	}
+	if e.cfg.Registry != nil {
+		snap.samples = e.cfg.Registry.Gather()
+	}
+	return snap
+}
+
+// toolRows returns the tool_calls rows of the snapshot
+func (s snapshot) toolRows(server string) [][]interface{} {
+	rows := make([][]interface{}, 0, len(s.tools))
+	for _, t := range s.tools {
+		rows = append(rows, []interface{}{
+			s.capturedAt, server, s.window.Seconds(), t.Tool, t.Calls, t.Errors, t.AvgMS, t.P95MS, t.MaxMS,
+		})
+	}
+	return rows
+}
+
+// poolRows returns the pool_stats rows of the snapshot
+func (s snapshot) poolRows(server string) [][]interface{} {
+	rows := make([][]interface{}, 0, len(s.pools))
+	for _, p := range s.pools {
+		avgAcquireMS := 0.0
+		if p.AcquireCount > 0 {
+			avgAcquireMS = milliseconds(p.AcquireDuration) / float64(p.AcquireCount)
+		}
+		rows = append(rows, []interface{}{
+			s.capturedAt, server, p.Database, int32(p.Clients), p.TotalConns, p.AcquiredConns, p.IdleConns,
+			p.MaxConns, p.AcquireCount, p.EmptyAcquireCount, avgAcquireMS,
+		})
+	}
+	return rows
+}
+
+// sampleRows returns the samples rows of the snapshot
+func (s snapshot) sampleRows(server string) [][]interface{} {
+	rows := make([][]interface{}, 0, len(s.samples))
+	for _, sample := range s.samples {
+		labels := sample.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		rows = append(rows, []interface{}{s.capturedAt, server, sample.Name, labels, sample.Value})
+	}
+	return rows
+}
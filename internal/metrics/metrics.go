@@ -0,0 +1,146 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package metrics serves the server's operational counters in the
+// Prometheus text exposition format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Path is the HTTP path of the metrics endpoint
+const Path = "/metrics"
+
+// Metric types
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Sample is one value of a metric
+type Sample struct {
+	Name   string            // Metric name, such as pgedge_mcp_embedding_cache_hits_total
+	Help   string            // Description shown in the HELP line
+	Type   string            // Counter or Gauge
+	Labels map[string]string // Optional labels
+	Value  float64
+}
+
+// Collector returns the current samples of a component
+type Collector func() []Sample
+
+// Registry holds the collectors whose samples are served
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// Default is the registry served by the server's metrics endpoint
+var Default = NewRegistry()
+
+// Register adds or replaces the collector with the given name
+func (r *Registry) Register(name string, collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors[name] = collector
+}
+
+// Unregister removes a collector
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.collectors, name)
+}
+
+// Gather returns the samples of all collectors, sorted by metric name
+func (r *Registry) Gather() []Sample {
+	r.mu.RLock()
+	var samples []Sample
+	for _, collect := range r.collectors {
+		samples = append(samples, collect()...)
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Name < samples[j].Name
+	})
+	return samples
+}
+
+// Write writes the samples in the Prometheus text format, with one HELP and
+// TYPE line per metric
+func (r *Registry) Write(w io.Writer) error {
+	previous := ""
+	for _, s := range r.Gather() {
+		if s.Name != previous {
+			if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", s.Name, s.Help, s.Name, s.Type); err != nil {
+				return err
+			}
+			previous = s.Name
+		}
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", s.Name, formatLabels(s.Labels),
+			strconv.FormatFloat(s.Value, 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registry's samples
+func (r *Registry) Handler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	//nolint:errcheck // Nothing to do if the client has gone away
+	r.Write(w)
+}
+
+// formatLabels formats labels as {name="value",...}, sorted by name
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,67 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Register("cache", func() []Sample {
+		return []Sample{
+			{Name: "test_hits_total", Help: "Hits.", Type: Counter, Value: 3},
+			{Name: "test_entries", Help: "Entries.", Type: Gauge, Value: 1.5},
+		}
+	})
+	r.Register("tools", func() []Sample {
+		return []Sample{
+			{Name: "test_calls_total", Help: "Calls.", Type: Counter, Labels: map[string]string{"tool": "query_database", "db": `a"b`}, Value: 2},
+			{Name: "test_calls_total", Help: "Calls.", Type: Counter, Labels: map[string]string{"tool": "hybrid_search"}, Value: 1},
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	r.Handler(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	body := rec.Body.String()
+
+	want := `# HELP test_calls_total Calls.
+# TYPE test_calls_total counter
+test_calls_total{db="a\"b",tool="query_database"} 2
+test_calls_total{tool="hybrid_search"} 1
+# HELP test_entries Entries.
+# TYPE test_entries gauge
+test_entries 1.5
+# HELP test_hits_total Hits.
+# TYPE test_hits_total counter
+test_hits_total 3
+`
+	if body != want {
+		t.Errorf("unexpected output:\n%s", body)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+
+	r.Unregister("tools")
+	if samples := r.Gather(); len(samples) != 2 {
+		t.Errorf("expected only the cache samples after unregistering, got %d", len(samples))
+	}
+
+	rec = httptest.NewRecorder()
+	r.Handler(rec, httptest.NewRequest(http.MethodPost, Path, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for POST, got %d", rec.Code)
+	}
+}
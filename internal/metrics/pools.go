@@ -0,0 +1,31 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package metrics
+
+import "pgedge-postgres-mcp/internal/database"
+
+// PoolCollector reports connection pool statistics for each database
+func PoolCollector(pools func() []database.PoolStats) Collector {
+	return func() []Sample {
+		var samples []Sample
+		for _, p := range pools() {
+			labels := map[string]string{"database": p.Database}
+			samples = append(samples,
+				Sample{Name: "pgedge_mcp_pool_connections", Help: "Open database connections.", Type: Gauge, Labels: labels, Value: float64(p.TotalConns)},
+				Sample{Name: "pgedge_mcp_pool_acquired_connections", Help: "Database connections in use.", Type: Gauge, Labels: labels, Value: float64(p.AcquiredConns)},
+				Sample{Name: "pgedge_mcp_pool_max_connections", Help: "Sum of the connection pools' size limits.", Type: Gauge, Labels: labels, Value: float64(p.MaxConns)},
+				Sample{Name: "pgedge_mcp_pool_acquires_total", Help: "Connections acquired from the pools.", Type: Counter, Labels: labels, Value: float64(p.AcquireCount)},
+				Sample{Name: "pgedge_mcp_pool_empty_acquires_total", Help: "Acquires that waited for or opened a connection.", Type: Counter, Labels: labels, Value: float64(p.EmptyAcquireCount)},
+			)
+		}
+		return samples
+	}
+}
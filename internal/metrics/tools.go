@@ -0,0 +1,201 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxTrackedTools bounds the number of tool names tracked; calls to
+	// further tools are counted under OtherTool
+	maxTrackedTools = 200
+
+	// maxWindowSamples bounds the latencies kept per tool for percentiles
+	// between snapshots; later calls still count towards calls, errors,
+	// average and maximum
+	maxWindowSamples = 2000
+
+	// OtherTool is the name under which untracked tools are counted
+	OtherTool = "other"
+)
+
+// ToolMetrics records tool call counts and latencies, both in total (for
+// the metrics endpoint) and per window between snapshots (for the
+// database export)
+type ToolMetrics struct {
+	mu          sync.Mutex
+	totals      map[string]*toolTotals
+	window      map[string]*toolWindow
+	windowStart time.Time
+}
+
+// toolTotals holds a tool's counters since the server started
+type toolTotals struct {
+	calls    uint64
+	errors   uint64
+	duration time.Duration
+}
+
+// toolWindow holds a tool's calls since the last snapshot
+type toolWindow struct {
+	calls   int64
+	errors  int64
+	total   time.Duration
+	max     time.Duration
+	samples []time.Duration
+}
+
+// ToolWindowStats summarizes a tool's calls in a snapshot window
+type ToolWindowStats struct {
+	Tool   string
+	Calls  int64
+	Errors int64
+	AvgMS  float64
+	P95MS  float64
+	MaxMS  float64
+}
+
+// NewToolMetrics creates an empty recorder
+func NewToolMetrics() *ToolMetrics {
+	return &ToolMetrics{
+		totals:      make(map[string]*toolTotals),
+		window:      make(map[string]*toolWindow),
+		windowStart: time.Now(),
+	}
+}
+
+// Tools records the server's tool calls
+var Tools = NewToolMetrics()
+
+// Record records a completed tool call
+func (m *ToolMetrics) Record(tool string, duration time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totals, ok := m.totals[tool]
+	if !ok {
+		if len(m.totals) >= maxTrackedTools {
+			tool = OtherTool
+			totals = m.totals[tool]
+		}
+		if totals == nil {
+			totals = &toolTotals{}
+			m.totals[tool] = totals
+		}
+	}
+	totals.calls++
+	totals.duration += duration
+
+	window, ok := m.window[tool]
+	if !ok {
+		window = &toolWindow{}
+		m.window[tool] = window
+	}
+	window.calls++
+	window.total += duration
+	if duration > window.max {
+		window.max = duration
+	}
+	if len(window.samples) < maxWindowSamples {
+		window.samples = append(window.samples, duration)
+	}
+
+	if failed {
+		totals.errors++
+		window.errors++
+	}
+}
+
+// TakeWindow returns the statistics of each tool called since the last call
+// to TakeWindow, sorted by tool name, and the window's length
+func (m *ToolMetrics) TakeWindow() ([]ToolWindowStats, time.Duration) {
+	m.mu.Lock()
+	window := m.window
+	length := time.Since(m.windowStart)
+	m.window = make(map[string]*toolWindow)
+	m.windowStart = time.Now()
+	m.mu.Unlock()
+
+	stats := make([]ToolWindowStats, 0, len(window))
+	for tool, w := range window {
+		stats = append(stats, ToolWindowStats{
+			Tool:   tool,
+			Calls:  w.calls,
+			Errors: w.errors,
+			AvgMS:  milliseconds(w.total) / float64(w.calls),
+			P95MS:  milliseconds(percentile(w.samples, 0.95)),
+			MaxMS:  milliseconds(w.max),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Tool < stats[j].Tool })
+	return stats, length
+}
+
+// Collect returns the total counters as samples for the metrics endpoint
+func (m *ToolMetrics) Collect() []Sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var samples []Sample
+	for tool, totals := range m.totals {
+		labels := map[string]string{"tool": tool}
+		samples = append(samples,
+			Sample{
+				Name:   "pgedge_mcp_tool_calls_total",
+				Help:   "Tool calls handled.",
+				Type:   Counter,
+				Labels: labels,
+				Value:  float64(totals.calls),
+			},
+			Sample{
+				Name:   "pgedge_mcp_tool_errors_total",
+				Help:   "Tool calls that returned an error.",
+				Type:   Counter,
+				Labels: labels,
+				Value:  float64(totals.errors),
+			},
+			Sample{
+				Name:   "pgedge_mcp_tool_duration_seconds_total",
+				Help:   "Time spent handling tool calls.",
+				Type:   Counter,
+				Labels: labels,
+				Value:  totals.duration.Seconds(),
+			},
+		)
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Labels["tool"] < samples[j].Labels["tool"]
+	})
+	return samples
+}
+
+// percentile returns the p-th percentile of durations using the
+// nearest-rank method; durations is sorted in place
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	rank := int(math.Ceil(p * float64(len(durations))))
+	if rank < 1 {
+		rank = 1
+	}
+	return durations[rank-1]
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,114 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+func TestToolMetricsWindow(t *testing.T) {
+	m := NewToolMetrics()
+	for i := 1; i <= 20; i++ {
+		m.Record("query_database", time.Duration(i)*time.Millisecond, i%10 == 0)
+	}
+	m.Record("get_schema_info", 5*time.Millisecond, false)
+
+	stats, window := m.TakeWindow()
+	if window <= 0 {
+		t.Errorf("expected a positive window, got %v", window)
+	}
+	if len(stats) != 2 || stats[0].Tool != "get_schema_info" || stats[1].Tool != "query_database" {
+		t.Fatalf("unexpected tools: %+v", stats)
+	}
+	q := stats[1]
+	if q.Calls != 20 || q.Errors != 2 || q.AvgMS != 10.5 || q.P95MS != 19 || q.MaxMS != 20 {
+		t.Errorf("unexpected query_database stats: %+v", q)
+	}
+
+	// The window restarts, the totals don't
+	if stats, _ := m.TakeWindow(); len(stats) != 0 {
+		t.Errorf("expected an empty window, got %+v", stats)
+	}
+	samples := m.Collect()
+	if len(samples) != 6 {
+		t.Fatalf("expected 6 samples, got %d", len(samples))
+	}
+	for _, s := range samples {
+		if s.Labels["tool"] == "query_database" && s.Name == "pgedge_mcp_tool_calls_total" && s.Value != 20 {
+			t.Errorf("expected 20 query_database calls, got %v", s.Value)
+		}
+	}
+}
+
+func TestToolMetricsCap(t *testing.T) {
+	m := NewToolMetrics()
+	for i := 0; i < maxTrackedTools+5; i++ {
+		m.Record(fmt.Sprintf("tool_%03d", i), time.Millisecond, false)
+	}
+
+	stats, _ := m.TakeWindow()
+	if len(stats) != maxTrackedTools+1 {
+		t.Fatalf("expected %d tools, got %d", maxTrackedTools+1, len(stats))
+	}
+	for _, s := range stats {
+		if s.Tool == OtherTool && s.Calls != 5 {
+			t.Errorf("expected 5 calls counted as %q, got %d", OtherTool, s.Calls)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		durations []time.Duration
+		p         float64
+		want      time.Duration
+	}{
+		{nil, 0.95, 0},
+		{[]time.Duration{7}, 0.95, 7},
+		{[]time.Duration{4, 1, 3, 2}, 0.5, 2},
+		{[]time.Duration{4, 1, 3, 2}, 0.95, 4},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.durations, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %v, want %v", tt.durations, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotRows(t *testing.T) {
+	snap := snapshot{
+		capturedAt: time.Now(),
+		window:     time.Minute,
+		tools:      []ToolWindowStats{{Tool: "query_database", Calls: 3, Errors: 1, AvgMS: 2, P95MS: 3, MaxMS: 3}},
+		pools: []database.PoolStats{{
+			Database: "prod", Clients: 2, TotalConns: 4, MaxConns: 8,
+			AcquireCount: 4, AcquireDuration: 8 * time.Millisecond,
+		}},
+		samples: []Sample{{Name: "test_entries", Value: 1}},
+	}
+
+	tools := snap.toolRows("replica-1")
+	if len(tools) != 1 || tools[0][1] != "replica-1" || tools[0][2] != 60.0 || tools[0][3] != "query_database" {
+		t.Errorf("unexpected tool rows: %v", tools)
+	}
+	pools := snap.poolRows("replica-1")
+	if len(pools) != 1 || pools[0][2] != "prod" || pools[0][3] != int32(2) || pools[0][10] != 2.0 {
+		t.Errorf("unexpected pool rows: %v", pools)
+	}
+	samples := snap.sampleRows("replica-1")
+	if labels, ok := samples[0][3].(map[string]string); len(samples) != 1 || !ok || labels == nil {
+		t.Errorf("expected empty labels rather than null, got %v", samples)
+	}
+}
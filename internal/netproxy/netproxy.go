@@ -0,0 +1,252 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package netproxy provides outbound proxy support for calls to external
+// services (LLM providers, embedding providers and Git remotes)
+package netproxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Provider names used to select a per-provider proxy
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderAzure     = "azure"
+	ProviderBedrock   = "bedrock"
+	ProviderVoyage    = "voyage"
+	ProviderOllama    = "ollama"
+	ProviderCohere    = "cohere"
+	ProviderGit       = "git"
+	ProviderWebhook   = "webhook" // Scheduled job webhooks and notifications; uses the general proxy
+)
+
+// Settings holds outbound proxy configuration
+// When no proxy is configured the standard HTTPS_PROXY, HTTP_PROXY and
+// NO_PROXY environment variables are honored
+type Settings struct {
+	URL       string `yaml:"url"`       // Proxy for all providers: http://, https://, socks5:// or socks5h://
+	NoProxy   string `yaml:"no_proxy"`  // Comma-separated hosts/domains/CIDRs to reach directly (default: NO_PROXY env var)
+	Anthropic string `yaml:"anthropic"` // Proxy override for Anthropic API calls
+	OpenAI    string `yaml:"openai"`    // Proxy override for OpenAI API calls
+	Azure     string `yaml:"azure"`     // Proxy override for Azure OpenAI API calls
+	Bedrock   string `yaml:"bedrock"`   // Proxy override for Amazon Bedrock API calls
+	Voyage    string `yaml:"voyage"`    // Proxy override for Voyage AI API calls
+	Ollama    string `yaml:"ollama"`    // Proxy override for Ollama calls
+	Cohere    string `yaml:"cohere"`    // Proxy override for Cohere API calls
+	Git       string `yaml:"git"`       // Proxy override for Git fetches (kb-builder)
+}
+
+var (
+	mu         sync.RWMutex
+	settings   Settings
+	offline    bool
+	transports = make(map[string]*http.Transport) // Shared by clients, by provider
+)
+
+// SetOffline enables or disables offline mode
+// In offline mode requests to hosted providers (Anthropic, OpenAI, Azure
+// OpenAI, Amazon Bedrock, Voyage AI, Cohere) fail before any connection is made; Ollama and Git are unaffected
+func SetOffline(enabled bool) {
+	mu.Lock()
+	offline = enabled
+	mu.Unlock()
+}
+
+// IsOffline reports whether offline mode is enabled
+func IsOffline() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return offline
+}
+
+// isHostedProvider reports whether a provider is a hosted internet service
+func isHostedProvider(provider string) bool {
+	return provider == ProviderAnthropic || provider == ProviderOpenAI || provider == ProviderAzure ||
+		provider == ProviderBedrock || provider == ProviderVoyage || provider == ProviderCohere
+}
+
+// Validate checks that all configured proxy URLs are well formed
+func Validate(s Settings) error {
+	for name, value := range map[string]string{
+		"url":       s.URL,
+		"anthropic": s.Anthropic,
+		"openai":    s.OpenAI,
+		"azure":     s.Azure,
+		"bedrock":   s.Bedrock,
+		"voyage":    s.Voyage,
+		"ollama":    s.Ollama,
+		"cohere":    s.Cohere,
+		"git":       s.Git,
+	} {
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil {
+			return fmt.Errorf("proxy %s: invalid URL: %w", name, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("proxy %s: unsupported scheme %q (supported: http, https, socks5, socks5h)", name, u.Scheme)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("proxy %s: URL %q has no host", name, value)
+		}
+	}
+	return nil
+}
+
+// Configure validates and installs the process-wide proxy settings
+// Clients created by this package pick up new settings on their next request;
+// the shared transports are rebuilt so that connections made through the
+// previous proxies are not reused
+func Configure(s Settings) error {
+	if err := Validate(s); err != nil {
+		return err
+	}
+	mu.Lock()
+	settings = s
+	previous := transports
+	transports = make(map[string]*http.Transport)
+	mu.Unlock()
+
+	for _, transport := range previous {
+		transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Current returns the active proxy settings
+func Current() Settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	return settings
+}
+
+// URLFor returns the explicitly configured proxy URL for a provider
+// The per-provider setting takes precedence over the general URL
+// Returns an empty string if no proxy is configured
+func URLFor(provider string) string {
+	s := Current()
+
+	var override string
+	switch provider {
+	case ProviderAnthropic:
+		override = s.Anthropic
+	case ProviderOpenAI:
+		override = s.OpenAI
+	case ProviderAzure:
+		override = s.Azure
+	case ProviderBedrock:
+		override = s.Bedrock
+	case ProviderVoyage:
+		override = s.Voyage
+	case ProviderOllama:
+		override = s.Ollama
+	case ProviderCohere:
+		override = s.Cohere
+	case ProviderGit:
+		override = s.Git
+	}
+	if override != "" {
+		return override
+	}
+	return s.URL
+}
+
+// noProxy returns the configured bypass list, falling back to the environment
+func noProxy() string {
+	if np := Current().NoProxy; np != "" {
+		return np
+	}
+	if np := os.Getenv("NO_PROXY"); np != "" {
+		return np
+	}
+	return os.Getenv("no_proxy")
+}
+
+// ProxyFunc returns a proxy selector for http.Transport.Proxy
+// The configuration is consulted on every request so reloads take effect
+// without recreating clients. It also enforces offline mode.
+func ProxyFunc(provider string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if isHostedProvider(provider) && IsOffline() {
+			return nil, fmt.Errorf("offline mode: outbound requests to %s are disabled", provider)
+		}
+		proxyURL := URLFor(provider)
+		if proxyURL == "" {
+			return http.ProxyFromEnvironment(req)
+		}
+		cfg := httpproxy.Config{
+			HTTPProxy:  proxyURL,
+			HTTPSProxy: proxyURL,
+			NoProxy:    noProxy(),
+		}
+		return cfg.ProxyFunc()(req.URL)
+	}
+}
+
+// Transport returns the shared transport for the provider, based on
+// http.DefaultTransport, that routes requests through the proxy configured
+// for the provider. Sharing it lets clients created per request reuse
+// connections. Callers must not modify it.
+func Transport(provider string) *http.Transport {
+	mu.RLock()
+	transport := transports[provider]
+	mu.RUnlock()
+	if transport != nil {
+		return transport
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if transport := transports[provider]; transport != nil {
+		return transport
+	}
+	if dt, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = dt.Clone()
+	} else {
+		transport = &http.Transport{}
+	}
+	transport.Proxy = ProxyFunc(provider)
+	transports[provider] = transport
+	return transport
+}
+
+// NewClient returns an HTTP client for the given provider that uses the
+// provider's shared transport
+// A zero timeout means no timeout
+func NewClient(provider string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: Transport(provider),
+		Timeout:   timeout,
+	}
+}
+
+// GitConfigArgs returns "git -c" arguments that route Git HTTP(S) traffic
+// through the configured proxy, or nil if none is configured
+// Without explicit configuration git honors the proxy environment variables itself
+func GitConfigArgs() []string {
+	proxyURL := URLFor(ProviderGit)
+	if proxyURL == "" {
+		return nil
+	}
+	return []string{"-c", "http.proxy=" + proxyURL}
+}
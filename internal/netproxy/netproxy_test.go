@@ -0,0 +1,196 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent - Outbound Proxy Tests
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package netproxy
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func resetSettings(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		_ = Configure(Settings{}) //nolint:errcheck // empty settings are always valid
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    Settings
+		expectError bool
+	}{
+		{"empty", Settings{}, false},
+		{"http proxy", Settings{URL: "http://proxy.example.com:3128"}, false},
+		{"socks5 override", Settings{Anthropic: "socks5://127.0.0.1:1080"}, false},
+		{"socks5h git", Settings{Git: "socks5h://proxy:1080"}, false},
+		{"unsupported scheme", Settings{OpenAI: "ftp://proxy:21"}, true},
+		{"missing host", Settings{URL: "http://"}, true},
+		{"no scheme", Settings{Voyage: "proxy.example.com:3128"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.settings)
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	resetSettings(t)
+
+	if err := Configure(Settings{
+		URL:       "http://default:3128",
+		Anthropic: "socks5://anthropic:1080",
+	}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	if got := URLFor(ProviderAnthropic); got != "socks5://anthropic:1080" {
+		t.Errorf("URLFor(anthropic) = %q", got)
+	}
+	if got := URLFor(ProviderOpenAI); got != "http://default:3128" {
+		t.Errorf("URLFor(openai) = %q", got)
+	}
+}
+
+func TestConfigureRejectsInvalid(t *testing.T) {
+	resetSettings(t)
+
+	if err := Configure(Settings{URL: "http://good:3128"}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if err := Configure(Settings{URL: "gopher://bad"}); err == nil {
+		t.Fatal("expected error for invalid settings")
+	}
+	// Previous settings must be kept when new ones are rejected
+	if got := Current().URL; got != "http://good:3128" {
+		t.Errorf("Current().URL = %q, want previous value", got)
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	resetSettings(t)
+
+	if err := Configure(Settings{
+		URL:     "http://proxy.internal:3128",
+		NoProxy: "internal.example.com",
+	}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	proxy := ProxyFunc(ProviderOpenAI)
+
+	tests := []struct {
+		name    string
+		target  string
+		wantURL string
+	}{
+		{"external host is proxied", "https://api.openai.com/v1/embeddings", "http://proxy.internal:3128"},
+		{"no_proxy host is direct", "https://internal.example.com/v1", ""},
+		{"localhost is direct", "http://localhost:11434/api/chat", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.target, nil)
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			got, err := proxy(req)
+			if err != nil {
+				t.Fatalf("proxy() error = %v", err)
+			}
+			gotURL := ""
+			if got != nil {
+				gotURL = got.String()
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("proxy(%s) = %q, want %q", tt.target, gotURL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	client := NewClient(ProviderVoyage, 0)
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Transport type = %T, want *http.Transport", client.Transport)
+	}
+	if transport.Proxy == nil {
+		t.Error("Transport.Proxy should be set")
+	}
+}
+
+func TestNewClient_SharesTransport(t *testing.T) {
+	resetSettings(t)
+
+	first := NewClient(ProviderWebhook, time.Second).Transport
+	if second := NewClient(ProviderWebhook, 0).Transport; second != first {
+		t.Error("Clients for the same provider should share a transport")
+	}
+	if other := NewClient(ProviderOllama, 0).Transport; other == first {
+		t.Error("Clients for different providers should not share a transport")
+	}
+
+	if err := Configure(Settings{URL: "http://proxy:3128"}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if reloaded := NewClient(ProviderWebhook, 0).Transport; reloaded == first {
+		t.Error("Configure should rebuild the shared transports")
+	}
+}
+
+func TestGitConfigArgs(t *testing.T) {
+	resetSettings(t)
+
+	if args := GitConfigArgs(); args != nil {
+		t.Errorf("GitConfigArgs() = %v, want nil without configuration", args)
+	}
+
+	if err := Configure(Settings{Git: "socks5://gitproxy:1080"}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	want := []string{"-c", "http.proxy=socks5://gitproxy:1080"}
+	if args := GitConfigArgs(); !reflect.DeepEqual(args, want) {
+		t.Errorf("GitConfigArgs() = %v, want %v", args, want)
+	}
+}
+
+func TestOfflineMode(t *testing.T) {
+	t.Cleanup(func() { SetOffline(false) })
+	SetOffline(true)
+
+	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	if _, err := ProxyFunc(ProviderAnthropic)(req); err == nil {
+		t.Error("expected hosted provider request to fail in offline mode")
+	}
+
+	req, err = http.NewRequest("POST", "http://localhost:11434/api/embed", nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	if _, err := ProxyFunc(ProviderOllama)(req); err != nil {
+		t.Errorf("Ollama requests should be allowed offline: %v", err)
+	}
+}
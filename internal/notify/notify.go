@@ -0,0 +1,262 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package notify pushes alerts out of band to Slack or to webhooks: health
+// probe changes, failed scheduled jobs, account lockouts and tokens about
+// to expire. Subsystems call Send, which does nothing until a Notifier is
+// set with SetShared.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pgedge-postgres-mcp/internal/logging"
+)
+
+// Event categories, which endpoints subscribe to
+const (
+	CategoryHealth = "health" // Health probe status changes
+	CategoryJobs   = "jobs"   // Failed scheduled jobs
+	CategoryAuth   = "auth"   // Lockouts and tokens about to expire
+)
+
+// Categories lists the event categories
+var Categories = []string{CategoryHealth, CategoryJobs, CategoryAuth}
+
+// Severities, in increasing order
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{SeverityInfo: 0, SeverityWarning: 1, SeverityCritical: 2}
+
+// Endpoint types
+const (
+	TypeSlack   = "slack"   // Slack incoming webhook; also Mattermost
+	TypeWebhook = "webhook" // The event as JSON
+)
+
+const (
+	// DefaultCooldown is the time during which an event with the same key
+	// is not sent again
+	DefaultCooldown = 10 * time.Minute
+
+	// sendTimeout bounds the delivery of an event to an endpoint
+	sendTimeout = 15 * time.Second
+)
+
+// validName matches endpoint names
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// Event is an alert
+type Event struct {
+	Category string
+	Severity string
+	Title    string            // One line, such as "Health probe replication_lag is critical"
+	Message  string            // Details; may span lines
+	Fields   map[string]string // Facts about the event, such as the database
+
+	// Key identifies repeats of the event, which are sent once per
+	// cooldown (empty = the title)
+	Key string
+
+	Time time.Time // Set by Send when zero
+}
+
+// Endpoint is a destination of alerts
+type Endpoint struct {
+	Name        string
+	Type        string // TypeSlack or TypeWebhook
+	URL         string
+	Events      []string // Categories sent (empty = all)
+	MinSeverity string   // Least severe event sent (empty = info)
+}
+
+// Validate checks an endpoint
+func (e *Endpoint) Validate() error {
+	if !validName.MatchString(e.Name) {
+		return fmt.Errorf("invalid notification endpoint name %q: use up to 64 letters, digits, '_' or '-', starting with a letter or digit", e.Name)
+	}
+	if e.Type != TypeSlack && e.Type != TypeWebhook {
+		return fmt.Errorf("notification endpoint %q: type must be %s or %s", e.Name, TypeSlack, TypeWebhook)
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("notification endpoint %q: url must be an http or https URL", e.Name)
+	}
+	for _, category := range e.Events {
+		if !isCategory(category) {
+			return fmt.Errorf("notification endpoint %q: unknown event %q (use %s)", e.Name, category, strings.Join(Categories, ", "))
+		}
+	}
+	if _, ok := severityRank[e.MinSeverity]; e.MinSeverity != "" && !ok {
+		return fmt.Errorf("notification endpoint %q: min_severity must be %s, %s or %s", e.Name, SeverityInfo, SeverityWarning, SeverityCritical)
+	}
+	return nil
+}
+
+// wants reports whether the endpoint takes an event
+func (e *Endpoint) wants(event Event) bool {
+	if severityRank[event.Severity] < severityRank[e.MinSeverity] {
+		return false
+	}
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, category := range e.Events {
+		if category == event.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// isCategory reports whether a name is an event category
+func isCategory(name string) bool {
+	for _, category := range Categories {
+		if category == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Config configures a Notifier
+type Config struct {
+	Endpoints []Endpoint
+	Cooldown  time.Duration // 0 = DefaultCooldown
+
+	// Post delivers an event to an endpoint (nil = POST it over HTTP);
+	// replaceable for tests
+	Post func(ctx context.Context, endpoint Endpoint, event Event) error
+
+	// Now returns the current time (nil = time.Now); replaceable for tests
+	Now func() time.Time
+}
+
+// Notifier sends events to the endpoints that take them
+type Notifier struct {
+	cfg Config
+
+	mu   sync.Mutex
+	sent map[string]time.Time // When each event key was last sent
+	wg   sync.WaitGroup
+}
+
+// New creates a notifier
+func New(cfg Config) *Notifier {
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultCooldown
+	}
+	if cfg.Post == nil {
+		cfg.Post = post
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Notifier{cfg: cfg, sent: make(map[string]time.Time)}
+}
+
+// shared is the notifier Send uses, set with SetShared
+var shared atomic.Pointer[Notifier]
+
+// SetShared sets the notifier that Send uses; nil stops notifications
+func SetShared(n *Notifier) {
+	shared.Store(n)
+}
+
+// Shared returns the notifier set with SetShared, or nil
+func Shared() *Notifier {
+	return shared.Load()
+}
+
+// Send sends an event with the shared notifier, if one is set
+func Send(event Event) {
+	if n := Shared(); n != nil {
+		n.Send(event)
+	}
+}
+
+// Send delivers an event to the endpoints that take it, in the background.
+// An event whose key was sent within the cooldown is dropped. Failed
+// deliveries are logged.
+func (n *Notifier) Send(event Event) {
+	now := n.cfg.Now()
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+	if event.Severity == "" {
+		event.Severity = SeverityInfo
+	}
+	key := event.Key
+	if key == "" {
+		key = event.Title
+	}
+	key = event.Category + "|" + key
+
+	n.mu.Lock()
+	if last, ok := n.sent[key]; ok && now.Sub(last) < n.cfg.Cooldown {
+		n.mu.Unlock()
+		return
+	}
+	n.sent[key] = now
+	// Forget keys past their cooldown, so the map stays small
+	for k, last := range n.sent {
+		if now.Sub(last) >= n.cfg.Cooldown {
+			delete(n.sent, k)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, endpoint := range n.cfg.Endpoints {
+		if !endpoint.wants(event) {
+			continue
+		}
+		n.wg.Add(1)
+		go func(endpoint Endpoint) {
+			defer n.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := n.cfg.Post(ctx, endpoint, event); err != nil {
+				logging.Warn("notification_failed",
+					"endpoint", endpoint.Name,
+					"category", event.Category,
+					"title", event.Title,
+					"error", err,
+				)
+			}
+		}(endpoint)
+	}
+}
+
+// Wait waits for the deliveries in progress
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// sortedFields returns an event's fields sorted by name
+func sortedFields(fields map[string]string) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
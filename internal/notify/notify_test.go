@@ -0,0 +1,171 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records the deliveries of a notifier
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recorder) post(ctx context.Context, endpoint Endpoint, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, endpoint.Name+": "+event.Title)
+	return nil
+}
+
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sent := r.sent
+	r.sent = nil
+	sort.Strings(sent)
+	return sent
+}
+
+func TestEndpointValidate(t *testing.T) {
+	valid := Endpoint{Name: "ops", Type: TypeSlack, URL: "https://hooks.example.com/services/x", Events: []string{CategoryHealth}, MinSeverity: SeverityWarning}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid endpoint rejected: %v", err)
+	}
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+	}{
+		{"bad name", Endpoint{Name: "ops team", Type: TypeSlack, URL: "https://hooks.example.com/x"}},
+		{"bad type", Endpoint{Name: "ops", Type: "email", URL: "https://hooks.example.com/x"}},
+		{"bad url", Endpoint{Name: "ops", Type: TypeWebhook, URL: "hooks.example.com/x"}},
+		{"bad event", Endpoint{Name: "ops", Type: TypeWebhook, URL: "https://hooks.example.com/x", Events: []string{"billing"}}},
+		{"bad severity", Endpoint{Name: "ops", Type: TypeWebhook, URL: "https://hooks.example.com/x", MinSeverity: "error"}},
+	}
+	for _, tt := range tests {
+		if err := tt.endpoint.Validate(); err == nil {
+			t.Errorf("%s: Validate succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestSendRoutesAndCoolsDown(t *testing.T) {
+	now := time.Date(2025, 6, 4, 2, 0, 0, 0, time.UTC)
+	rec := &recorder{}
+	n := New(Config{
+		Endpoints: []Endpoint{
+			{Name: "all"},
+			{Name: "health", Events: []string{CategoryHealth}},
+			{Name: "pager", MinSeverity: SeverityCritical},
+		},
+		Cooldown: 10 * time.Minute,
+		Post:     rec.post,
+		Now:      func() time.Time { return now },
+	})
+
+	n.Send(Event{Category: CategoryJobs, Severity: SeverityWarning, Title: "Scheduled job nightly failed"})
+	n.Wait()
+	if got := rec.take(); strings.Join(got, "|") != "all: Scheduled job nightly failed" {
+		t.Errorf("sent %v, want only the endpoint taking every event", got)
+	}
+
+	n.Send(Event{Category: CategoryHealth, Severity: SeverityCritical, Title: "Health probe lag is crit"})
+	n.Wait()
+	if got := rec.take(); len(got) != 3 {
+		t.Errorf("sent %v, want all three endpoints", got)
+	}
+
+	// Repeats are dropped until the cooldown passes
+	now = now.Add(5 * time.Minute)
+	n.Send(Event{Category: CategoryJobs, Severity: SeverityWarning, Title: "Scheduled job nightly failed"})
+	n.Wait()
+	if got := rec.take(); len(got) != 0 {
+		t.Errorf("repeat within the cooldown sent: %v", got)
+	}
+	now = now.Add(5 * time.Minute)
+	n.Send(Event{Category: CategoryJobs, Severity: SeverityWarning, Title: "Scheduled job nightly failed"})
+	n.Wait()
+	if got := rec.take(); len(got) != 1 {
+		t.Errorf("repeat after the cooldown: sent %v, want one", got)
+	}
+}
+
+func TestSendShared(t *testing.T) {
+	SetShared(nil)
+	Send(Event{Title: "dropped"}) // No notifier: nothing happens
+
+	rec := &recorder{}
+	n := New(Config{Endpoints: []Endpoint{{Name: "ops"}}, Post: rec.post})
+	SetShared(n)
+	defer SetShared(nil)
+	Send(Event{Category: CategoryAuth, Title: "User account alice locked"})
+	n.Wait()
+	if got := rec.take(); len(got) != 1 || got[0] != "ops: User account alice locked" {
+		t.Errorf("sent %v", got)
+	}
+}
+
+func TestPost(t *testing.T) {
+	var bodies []map[string]interface{}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	event := Event{
+		Category: CategoryHealth,
+		Severity: SeverityCritical,
+		Title:    "Health probe replication_lag is crit",
+		Message:  "replay lag 412 seconds",
+		Fields:   map[string]string{"probe": "replication_lag", "database": "main"},
+		Time:     time.Date(2025, 6, 4, 2, 0, 0, 0, time.UTC),
+	}
+
+	if err := post(context.Background(), Endpoint{Type: TypeSlack, URL: server.URL + "/slack"}, event); err != nil {
+		t.Fatalf("slack post failed: %v", err)
+	}
+	want := ":rotating_light: *Health probe replication_lag is crit*\nreplay lag 412 seconds\n• database: main\n• probe: replication_lag"
+	if bodies[0]["text"] != want || len(bodies[0]) != 1 {
+		t.Errorf("slack payload = %v, want text %q", bodies[0], want)
+	}
+
+	if err := post(context.Background(), Endpoint{Type: TypeWebhook, URL: server.URL + "/hook"}, event); err != nil {
+		t.Fatalf("webhook post failed: %v", err)
+	}
+	if bodies[1]["category"] != CategoryHealth || bodies[1]["severity"] != SeverityCritical ||
+		bodies[1]["time"] != "2025-06-04T02:00:00Z" || !strings.HasPrefix(bodies[1]["text"].(string), "[CRITICAL] Health probe") {
+		t.Errorf("unexpected webhook payload: %v", bodies[1])
+	}
+
+	err := post(context.Background(), Endpoint{Type: TypeWebhook, URL: server.URL + "/fail"}, event)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("failed delivery: err = %v, want status 403", err)
+	}
+}
@@ -0,0 +1,125 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/netproxy"
+)
+
+// slackPayload is the message posted to a Slack incoming webhook
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// webhookPayload is the JSON posted to a webhook endpoint. text summarizes
+// the event for services that show it as a message.
+type webhookPayload struct {
+	Text     string            `json:"text"`
+	Category string            `json:"category"`
+	Severity string            `json:"severity"`
+	Title    string            `json:"title"`
+	Message  string            `json:"message,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Time     time.Time         `json:"time"`
+}
+
+// severityIcons prefix Slack messages
+var severityIcons = map[string]string{
+	SeverityInfo:     ":information_source:",
+	SeverityWarning:  ":warning:",
+	SeverityCritical: ":rotating_light:",
+}
+
+// slackText formats an event as a Slack message
+func slackText(event Event) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s *%s*", severityIcons[event.Severity], event.Title))
+	if event.Message != "" {
+		sb.WriteString("\n")
+		sb.WriteString(event.Message)
+	}
+	for _, name := range sortedFields(event.Fields) {
+		sb.WriteString(fmt.Sprintf("\n• %s: %s", name, event.Fields[name]))
+	}
+	return sb.String()
+}
+
+// plainText formats an event as plain text
+func plainText(event Event) string {
+	text := fmt.Sprintf("[%s] %s", strings.ToUpper(event.Severity), event.Title)
+	if event.Message != "" {
+		text += "\n" + event.Message
+	}
+	return text
+}
+
+// payload encodes an event for an endpoint
+func payload(endpoint Endpoint, event Event) ([]byte, error) {
+	if endpoint.Type == TypeSlack {
+		return json.Marshal(slackPayload{Text: slackText(event)})
+	}
+	return json.Marshal(webhookPayload{
+		Text:     plainText(event),
+		Category: event.Category,
+		Severity: event.Severity,
+		Title:    event.Title,
+		Message:  event.Message,
+		Fields:   event.Fields,
+		Time:     event.Time.UTC(),
+	})
+}
+
+// post delivers an event to an endpoint as JSON; responses other than 2xx
+// are errors. Requests go through the general outbound proxy, if one is set.
+func post(ctx context.Context, endpoint Endpoint, event Event) error {
+	body, err := payload(endpoint, event)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := netproxy.NewClient(netproxy.ProviderWebhook, sendTimeout).Do(req)
+	if err != nil {
+		// The URL of a Slack webhook is its secret, so it is not reported
+		return fmt.Errorf("notification request failed: %w", stripURL(err))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) //nolint:errcheck // drained so the connection can be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// stripURL removes the request URL from an HTTP client error
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package plans
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Node is a node of a plan, with the fields used to compare plans
+type Node struct {
+	NodeType     string  `json:"Node Type"`
+	Operation    string  `json:"Operation"` // ModifyTable: Insert, Update, Delete or Merge
+	JoinType     string  `json:"Join Type"`
+	Strategy     string  `json:"Strategy"` // Aggregate: Plain, Sorted, Hashed or Mixed
+	RelationName string  `json:"Relation Name"`
+	IndexName    string  `json:"Index Name"`
+	StartupCost  float64 `json:"Startup Cost"`
+	TotalCost    float64 `json:"Total Cost"`
+	PlanRows     float64 `json:"Plan Rows"`
+	Plans        []*Node `json:"Plans"`
+}
+
+// Label describes a node the way EXPLAIN's text output does, without costs
+func (n *Node) Label() string {
+	label := n.NodeType
+	switch {
+	case n.Operation != "":
+		label = n.Operation
+	case n.Strategy != "" && n.NodeType == "Aggregate":
+		label = n.Strategy + " Aggregate"
+	case n.JoinType != "" && n.JoinType != "Inner":
+		label += " (" + n.JoinType + ")"
+	}
+	if n.IndexName != "" {
+		label += " using " + n.IndexName
+	}
+	if n.RelationName != "" {
+		label += " on " + n.RelationName
+	}
+	return label
+}
+
+// ParsePlan returns the root node of EXPLAIN (FORMAT JSON) output
+func ParsePlan(data []byte) (*Node, error) {
+	var output []struct {
+		Plan *Node `json:"Plan"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("invalid EXPLAIN output: %w", err)
+	}
+	if len(output) == 0 || output[0].Plan == nil {
+		return nil, fmt.Errorf("EXPLAIN output has no plan")
+	}
+	return output[0].Plan, nil
+}
+
+// ChangeKind is how a plan node differs between the baseline and the
+// current plan
+type ChangeKind string
+
+const (
+	Unchanged ChangeKind = "="
+	Replaced  ChangeKind = "~" // Another operation at the same place in the plan
+	Added     ChangeKind = "+"
+	Removed   ChangeKind = "-"
+)
+
+// Change is a node of the compared plans
+type Change struct {
+	Kind   ChangeKind
+	Depth  int
+	Before *Node // Nil for added nodes
+	After  *Node // Nil for removed nodes
+}
+
+// Diff compares two plan trees node by node, in plan order. Children are
+// matched by position; a node inserted above or removed from the middle of
+// the plan is recognized, so the nodes below it still match.
+func Diff(before, after *Node) []Change {
+	var changes []Change
+	diffNodes(before, after, 0, &changes)
+	return changes
+}
+
+func diffNodes(before, after *Node, depth int, changes *[]Change) {
+	switch {
+	case before == nil && after == nil:
+		return
+	case before == nil:
+		addSubtree(Added, after, depth, changes)
+		return
+	case after == nil:
+		addSubtree(Removed, before, depth, changes)
+		return
+	}
+
+	if before.Label() != after.Label() {
+		// A node inserted above the baseline's node, such as a Sort
+		if len(after.Plans) == 1 && after.Plans[0].Label() == before.Label() {
+			*changes = append(*changes, Change{Kind: Added, Depth: depth, After: after})
+			diffNodes(before, after.Plans[0], depth+1, changes)
+			return
+		}
+		// A baseline node that is no longer in the plan
+		if len(before.Plans) == 1 && before.Plans[0].Label() == after.Label() {
+			*changes = append(*changes, Change{Kind: Removed, Depth: depth, Before: before})
+			diffNodes(before.Plans[0], after, depth+1, changes)
+			return
+		}
+	}
+
+	kind := Unchanged
+	if before.Label() != after.Label() {
+		kind = Replaced
+	}
+	*changes = append(*changes, Change{Kind: kind, Depth: depth, Before: before, After: after})
+	for i := 0; i < len(before.Plans) || i < len(after.Plans); i++ {
+		var b, a *Node
+		if i < len(before.Plans) {
+			b = before.Plans[i]
+		}
+		if i < len(after.Plans) {
+			a = after.Plans[i]
+		}
+		diffNodes(b, a, depth+1, changes)
+	}
+}
+
+// addSubtree records a node and its children as added or removed
+func addSubtree(kind ChangeKind, node *Node, depth int, changes *[]Change) {
+	change := Change{Kind: kind, Depth: depth}
+	if kind == Added {
+		change.After = node
+	} else {
+		change.Before = node
+	}
+	*changes = append(*changes, change)
+	for _, child := range node.Plans {
+		addSubtree(kind, child, depth+1, changes)
+	}
+}
+
+// CostChange returns the relative change of a total cost, such as 0.25 for
+// 25% more; 0 when the baseline cost is 0
+func CostChange(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return (after - before) / before
+}
@@ -0,0 +1,144 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package plans
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePlan(t *testing.T) {
+	root, err := ParsePlan([]byte(`[{"Plan": {
+		"Node Type": "Hash Join", "Join Type": "Left", "Total Cost": 42.5, "Plan Rows": 10,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 20},
+			{"Node Type": "Hash", "Plans": [
+				{"Node Type": "Index Scan", "Index Name": "customers_pkey", "Relation Name": "customers"}
+			]}
+		]}, "Planning Time": 0.1}]`))
+	if err != nil {
+		t.Fatalf("ParsePlan() error = %v", err)
+	}
+	if root.Label() != "Hash Join (Left)" || root.TotalCost != 42.5 || len(root.Plans) != 2 {
+		t.Errorf("unexpected root %+v", root)
+	}
+	if got := root.Plans[1].Plans[0].Label(); got != "Index Scan using customers_pkey on customers" {
+		t.Errorf("Label() = %q", got)
+	}
+
+	for _, input := range []string{"", "{}", "[]", `[{"Planning Time": 1}]`} {
+		if _, err := ParsePlan([]byte(input)); err == nil {
+			t.Errorf("ParsePlan(%q) expected an error", input)
+		}
+	}
+}
+
+func TestNodeLabel(t *testing.T) {
+	tests := []struct {
+		node Node
+		want string
+	}{
+		{Node{NodeType: "ModifyTable", Operation: "Update", RelationName: "t"}, "Update on t"},
+		{Node{NodeType: "Aggregate", Strategy: "Hashed"}, "Hashed Aggregate"},
+		{Node{NodeType: "Nested Loop", JoinType: "Inner"}, "Nested Loop"},
+		{Node{NodeType: "Merge Join", JoinType: "Anti"}, "Merge Join (Anti)"},
+	}
+	for _, tt := range tests {
+		if got := tt.node.Label(); got != tt.want {
+			t.Errorf("Label() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// render writes changes as "<kind><indent><label>" lines
+func render(changes []Change) string {
+	var lines []string
+	for _, c := range changes {
+		node := c.After
+		if node == nil {
+			node = c.Before
+		}
+		label := node.Label()
+		if c.Kind == Replaced {
+			label = c.Before.Label() + " -> " + c.After.Label()
+		}
+		lines = append(lines, fmt.Sprintf("%s%s%s", c.Kind, strings.Repeat(" ", c.Depth), label))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDiff(t *testing.T) {
+	scan := func(table string) *Node { return &Node{NodeType: "Seq Scan", RelationName: table} }
+	index := func(table string) *Node {
+		return &Node{NodeType: "Index Scan", IndexName: table + "_pkey", RelationName: table}
+	}
+
+	tests := []struct {
+		name   string
+		before *Node
+		after  *Node
+		want   string
+	}{
+		{
+			name:   "unchanged",
+			before: &Node{NodeType: "Hash Join", Plans: []*Node{scan("a"), {NodeType: "Hash", Plans: []*Node{scan("b")}}}},
+			after:  &Node{NodeType: "Hash Join", Plans: []*Node{scan("a"), {NodeType: "Hash", Plans: []*Node{scan("b")}}}},
+			want:   "=Hash Join\n= Seq Scan on a\n= Hash\n=  Seq Scan on b",
+		},
+		{
+			name:   "scan replaced",
+			before: index("orders"),
+			after:  scan("orders"),
+			want:   "~Index Scan using orders_pkey on orders -> Seq Scan on orders",
+		},
+		{
+			name:   "node inserted above",
+			before: scan("orders"),
+			after:  &Node{NodeType: "Sort", Plans: []*Node{scan("orders")}},
+			want:   "+Sort\n= Seq Scan on orders",
+		},
+		{
+			name:   "node removed from the middle",
+			before: &Node{NodeType: "Limit", Plans: []*Node{{NodeType: "Sort", Plans: []*Node{scan("orders")}}}},
+			after:  &Node{NodeType: "Limit", Plans: []*Node{scan("orders")}},
+			want:   "=Limit\n- Sort\n=  Seq Scan on orders",
+		},
+		{
+			name:   "join replaced with a new subtree",
+			before: &Node{NodeType: "Nested Loop", Plans: []*Node{scan("a"), index("b")}},
+			after:  &Node{NodeType: "Hash Join", Plans: []*Node{scan("a"), {NodeType: "Hash", Plans: []*Node{scan("b")}}}},
+			want:   "~Nested Loop -> Hash Join\n= Seq Scan on a\n~ Index Scan using b_pkey on b -> Hash\n+  Seq Scan on b",
+		},
+		{
+			name:   "child removed",
+			before: &Node{NodeType: "Append", Plans: []*Node{scan("p1"), scan("p2")}},
+			after:  &Node{NodeType: "Append", Plans: []*Node{scan("p1")}},
+			want:   "=Append\n= Seq Scan on p1\n- Seq Scan on p2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(Diff(tt.before, tt.after)); got != tt.want {
+				t.Errorf("Diff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostChange(t *testing.T) {
+	if got := CostChange(100, 125); got != 0.25 {
+		t.Errorf("CostChange(100, 125) = %v", got)
+	}
+	if got := CostChange(0, 10); got != 0 {
+		t.Errorf("CostChange(0, 10) = %v", got)
+	}
+}
@@ -0,0 +1,157 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package plans stores query plan baselines: the JSON EXPLAIN output of a
+// named query, saved so later plans of the query can be compared with it to
+// find plan regressions
+package plans
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// ErrNotFound is returned for a baseline name that is not stored
+var ErrNotFound = errors.New("plan baseline not found")
+
+// validName matches baseline names, which are also file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// Baseline is the stored plan of a named query
+type Baseline struct {
+	Name      string          `json:"name"`
+	Database  string          `json:"database"` // Sanitized connection string the plan was taken on
+	Query     string          `json:"query"`
+	CreatedAt time.Time       `json:"created_at"`
+	Plan      json.RawMessage `json:"plan"` // EXPLAIN (FORMAT JSON) output
+}
+
+// Store manages the plan baseline directory
+type Store struct {
+	Dir string
+}
+
+// NewStoreFromConfig returns the store in the plans directory under the
+// configured data directory
+func NewStoreFromConfig(cfg *config.Config) *Store {
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		execPath, err := os.Executable()
+		if err != nil {
+			execPath = "."
+		}
+		dataDir = config.GetDefaultDataDir(execPath)
+	}
+	return &Store{Dir: filepath.Join(dataDir, "plans")}
+}
+
+// ValidateName checks that a baseline name can be stored
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid plan baseline name %q: use up to 64 letters, digits, '_' or '-', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// path returns the file of a baseline
+func (s *Store) path(name string) string {
+	return filepath.Join(s.Dir, name+".json")
+}
+
+// Save stores a baseline under its name, replacing one with the same name.
+// The file is written to a temporary name first, so a failed write never
+// leaves a partial baseline.
+func (s *Store) Save(baseline *Baseline) error {
+	if err := ValidateName(baseline.Name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create plan directory: %w", err)
+	}
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan baseline: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, ".tmp-"+baseline.Name+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write plan baseline: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() //nolint:errcheck // the write error is reported
+		return fmt.Errorf("failed to write plan baseline: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write plan baseline: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(baseline.Name)); err != nil {
+		return fmt.Errorf("failed to store plan baseline %q: %w", baseline.Name, err)
+	}
+	return nil
+}
+
+// Open returns the stored baseline with a name
+func (s *Store) Open(name string) (*Baseline, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan baseline %q: %w", name, err)
+	}
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("plan baseline %q is corrupt: %w", name, err)
+	}
+	baseline.Name = name
+	return &baseline, nil
+}
+
+// List returns the stored baselines sorted by name. Baselines that cannot
+// be read are skipped.
+func (s *Store) List() ([]*Baseline, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan directory: %w", err)
+	}
+
+	var baselines []*Baseline
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok || !validName.MatchString(name) {
+			continue
+		}
+		baseline, err := s.Open(name)
+		if err != nil {
+			continue
+		}
+		baselines = append(baselines, baseline)
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		return baselines[i].Name < baselines[j].Name
+	})
+	return baselines, nil
+}
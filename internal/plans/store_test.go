@@ -0,0 +1,89 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package plans
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"orders_by_customer", "v1-2", "2025"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../etc", ".hidden", "-x", "a b", string(make([]byte, 65))} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) expected an error", name)
+		}
+	}
+}
+
+func TestStoreSaveAndOpen(t *testing.T) {
+	store := &Store{Dir: filepath.Join(t.TempDir(), "plans")}
+
+	if _, err := store.Open("orders"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open() of a missing baseline error = %v, want ErrNotFound", err)
+	}
+	if baselines, err := store.List(); err != nil || len(baselines) != 0 {
+		t.Fatalf("List() of a missing directory = %v, %v", baselines, err)
+	}
+
+	baseline := &Baseline{
+		Name:      "orders",
+		Database:  "postgres://app@localhost/shop",
+		Query:     "SELECT * FROM orders WHERE id = 1",
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Plan:      json.RawMessage(`[{"Plan": {"Node Type": "Seq Scan", "Total Cost": 10}}]`),
+	}
+	if err := store.Save(baseline); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	baseline.Plan = json.RawMessage(`[{"Plan": {"Node Type": "Index Scan", "Total Cost": 8.3}}]`)
+	if err := store.Save(baseline); err != nil {
+		t.Fatalf("Save() replacing error = %v", err)
+	}
+	if err := store.Save(&Baseline{Name: "../x"}); err == nil {
+		t.Error("Save() with an invalid name expected an error")
+	}
+
+	got, err := store.Open("orders")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got.Query != baseline.Query || !got.CreatedAt.Equal(baseline.CreatedAt) {
+		t.Errorf("unexpected baseline %+v", got)
+	}
+	root, err := ParsePlan(got.Plan)
+	if err != nil || root.NodeType != "Index Scan" {
+		t.Errorf("stored plan = %v, %v; want the replacement", root, err)
+	}
+
+	// Temporary files and other files are not listed
+	if err := os.WriteFile(filepath.Join(store.Dir, ".tmp-orders-1"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(store.Dir, "broken.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	baselines, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(baselines) != 1 || baselines[0].Name != "orders" {
+		t.Errorf("List() = %v, want only orders", baselines)
+	}
+}
@@ -13,6 +13,7 @@ package prompts
 import (
 	"fmt"
 	"sort"
+	"sync"
 
 	"pgedge-postgres-mcp/internal/mcp"
 )
@@ -24,7 +25,9 @@ type Prompt struct {
 }
 
 // Registry manages available MCP prompts
+// It is safe for concurrent use so prompts can be changed on configuration reload
 type Registry struct {
+	mu      sync.RWMutex
 	prompts map[string]Prompt
 }
 
@@ -37,17 +40,30 @@ func NewRegistry() *Registry {
 
 // Register adds a prompt to the registry
 func (r *Registry) Register(name string, prompt Prompt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.prompts[name] = prompt
 }
 
+// Unregister removes a prompt from the registry
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.prompts, name)
+}
+
 // Get retrieves a prompt by name
 func (r *Registry) Get(name string) (Prompt, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	prompt, exists := r.prompts[name]
 	return prompt, exists
 }
 
 // List returns all registered prompt definitions
 func (r *Registry) List() []mcp.Prompt {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	prompts := make([]mcp.Prompt, 0, len(r.prompts))
 	for _, prompt := range r.prompts {
 		prompts = append(prompts, prompt.Definition)
@@ -60,10 +76,12 @@ func (r *Registry) Execute(name string, args map[string]string) (mcp.PromptResul
 	prompt, exists := r.Get(name)
 	if !exists {
 		// Build list of available prompt names (sorted alphabetically)
+		r.mu.RLock()
 		available := make([]string, 0, len(r.prompts))
 		for promptName := range r.prompts {
 			available = append(available, promptName)
 		}
+		r.mu.RUnlock()
 		sort.Strings(available)
 		return mcp.PromptResult{}, fmt.Errorf("prompt %q not found. Available prompts: %v", name, available)
 	}
@@ -48,6 +48,19 @@ func TestRegisterAndGet(t *testing.T) {
 	}
 }
 
+func TestUnregister(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("design-schema", DesignSchema())
+
+	registry.Unregister("design-schema")
+	if _, found := registry.Get("design-schema"); found {
+		t.Error("Expected prompt to be removed")
+	}
+
+	// Removing an unknown prompt is a no-op
+	registry.Unregister("non-existent")
+}
+
 func TestGetNonExistent(t *testing.T) {
 	registry := NewRegistry()
 
@@ -125,7 +125,9 @@ Optional:
 Scenario: No vector-enabled tables found
 - The database may not have semantic search capability set up
 - Try using query_database with SQL LIKE or full-text search instead
-- Suggest checking if embeddings need to be generated
+- If the setup_semantic_search tool is available, offer to embed a text
+  column of a relevant table with it (dry run first, then with the user's
+  confirmation)
 
 Scenario: Results have low relevance scores (< 0.5)
 - Query may be too specific or use different terminology
@@ -0,0 +1,86 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package rerank
+
+import (
+	"context"
+	"net/http"
+
+	"pgedge-postgres-mcp/internal/netproxy"
+)
+
+// CohereReranker uses Cohere's rerank API
+type CohereReranker struct {
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+// cohereRerankRequest is a request to Cohere's rerank API
+type cohereRerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+// cohereRerankResponse is a response from Cohere's rerank API
+type cohereRerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+func newCohereReranker(cfg Config) *CohereReranker {
+	model := cfg.Model
+	if model == "" {
+		model = "rerank-v3.5"
+	}
+	return &CohereReranker{
+		apiKey:  cfg.APIKey,
+		model:   model,
+		baseURL: "https://api.cohere.com/v2/rerank",
+		client:  netproxy.NewClient(netproxy.ProviderCohere, cfg.Timeout),
+	}
+}
+
+// Rerank scores the documents with Cohere
+func (r *CohereReranker) Rerank(ctx context.Context, query string, documents []string) ([]Result, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	var resp cohereRerankResponse
+	err := postJSON(ctx, r.client, r.baseURL, r.apiKey,
+		cohereRerankRequest{Model: r.model, Query: query, Documents: documents}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(resp.Results))
+	for _, d := range resp.Results {
+		results = append(results, Result{Index: d.Index, Score: d.RelevanceScore})
+	}
+	if err := checkIndexes(results, len(documents)); err != nil {
+		return nil, err
+	}
+	return sortResults(results), nil
+}
+
+// ProviderName returns "cohere"
+func (r *CohereReranker) ProviderName() string {
+	return "cohere"
+}
+
+// ModelName returns the model name
+func (r *CohereReranker) ModelName() string {
+	return r.model
+}
@@ -0,0 +1,144 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"pgedge-postgres-mcp/internal/netproxy"
+)
+
+// ollamaConcurrency bounds the documents scored at once by Ollama
+const ollamaConcurrency = 4
+
+// ollamaScorePrompt asks the model for a relevance score; a small local
+// model answering with a single number acts as a cross-encoder
+const ollamaScorePrompt = `Judge how relevant the document is to the search query.
+Answer with a single number from 0 (irrelevant) to 10 (directly answers the query) and nothing else.
+
+Query: %s
+
+Document:
+%s
+
+Relevance:`
+
+// ollamaScorePattern finds the score in the model's answer
+var ollamaScorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// OllamaReranker scores each document with a local model served by Ollama
+type OllamaReranker struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// ollamaGenerateRequest is a request to Ollama's generate API
+type ollamaGenerateRequest struct {
+	Model   string                 `json:"model"`
+	Prompt  string                 `json:"prompt"`
+	Stream  bool                   `json:"stream"`
+	Options map[string]interface{} `json:"options"`
+}
+
+// ollamaGenerateResponse is a response from Ollama's generate API
+type ollamaGenerateResponse struct {
+	Response string `json:"response"`
+}
+
+func newOllamaReranker(cfg Config) *OllamaReranker {
+	baseURL := cfg.URL
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	return &OllamaReranker{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   cfg.Model,
+		client:  netproxy.NewClient(netproxy.ProviderOllama, cfg.Timeout),
+	}
+}
+
+// Rerank scores the documents with the Ollama model, a few at a time
+func (r *OllamaReranker) Rerank(ctx context.Context, query string, documents []string) ([]Result, error) {
+	results := make([]Result, len(documents))
+	errs := make([]error, len(documents))
+	sem := make(chan struct{}, ollamaConcurrency)
+	var wg sync.WaitGroup
+
+	for i, doc := range documents {
+		wg.Add(1)
+		go func(i int, doc string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			score, err := r.score(ctx, query, doc)
+			results[i] = Result{Index: i, Score: score}
+			errs[i] = err
+		}(i, doc)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sortResults(results), nil
+}
+
+// score asks the model for a document's relevance, normalized to 0-1
+// An answer without a number scores 0
+func (r *OllamaReranker) score(ctx context.Context, query, document string) (float64, error) {
+	var resp ollamaGenerateResponse
+	err := postJSON(ctx, r.client, r.baseURL+"/api/generate", "", ollamaGenerateRequest{
+		Model:   r.model,
+		Prompt:  fmt.Sprintf(ollamaScorePrompt, query, document),
+		Stream:  false,
+		Options: map[string]interface{}{"temperature": 0, "num_predict": 8},
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+	return parseOllamaScore(resp.Response), nil
+}
+
+// parseOllamaScore extracts a 0-10 score from the model's answer
+func parseOllamaScore(answer string) float64 {
+	match := ollamaScorePattern.FindString(answer)
+	if match == "" {
+		return 0
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0
+	}
+	if score > 10 {
+		score = 10
+	}
+	return score / 10
+}
+
+// ProviderName returns "ollama"
+func (r *OllamaReranker) ProviderName() string {
+	return "ollama"
+}
+
+// ModelName returns the model name
+func (r *OllamaReranker) ModelName() string {
+	return r.model
+}
@@ -0,0 +1,139 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package rerank orders search results by their relevance to a query with
+// a reranking model, which scores each query and document pair together
+// and is more accurate than comparing their embeddings
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// DefaultTimeout bounds a rerank request when no timeout is configured
+const DefaultTimeout = 10 * time.Second
+
+// Result is the relevance of one of the reranked documents
+type Result struct {
+	Index int     // Position of the document in the request
+	Score float64 // Relevance to the query; higher is more relevant
+}
+
+// Reranker scores documents by their relevance to a query
+type Reranker interface {
+	// Rerank returns the documents' results, most relevant first
+	Rerank(ctx context.Context, query string, documents []string) ([]Result, error)
+
+	// ProviderName returns the name of the provider (e.g., "voyage")
+	ProviderName() string
+
+	// ModelName returns the name of the model being used
+	ModelName() string
+}
+
+// Config holds configuration for rerank providers
+type Config struct {
+	Provider string        // "voyage", "cohere", or "ollama"
+	Model    string        // Model name (provider-specific)
+	APIKey   string        // Voyage AI or Cohere API key
+	URL      string        // Ollama URL
+	Timeout  time.Duration // Per-request timeout (0 = DefaultTimeout)
+}
+
+// NewReranker creates the reranker named in the configuration
+func NewReranker(cfg Config) (Reranker, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	switch cfg.Provider {
+	case "voyage":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("Voyage AI API key is required when the rerank provider is 'voyage'")
+		}
+		return newVoyageReranker(cfg), nil
+	case "cohere":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("Cohere API key is required when the rerank provider is 'cohere'")
+		}
+		return newCohereReranker(cfg), nil
+	case "ollama":
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("a model is required when the rerank provider is 'ollama'")
+		}
+		return newOllamaReranker(cfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported rerank provider: %s (supported: voyage, cohere, ollama)", cfg.Provider)
+	}
+}
+
+// sortResults orders results by descending score, keeping the original
+// order for equal scores
+func sortResults(results []Result) []Result {
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results
+}
+
+// checkIndexes verifies that a provider returned one result for each
+// document, so a malformed response can't drop or duplicate results
+func checkIndexes(results []Result, documents int) error {
+	if len(results) != documents {
+		return fmt.Errorf("expected %d results, got %d", documents, len(results))
+	}
+	seen := make([]bool, documents)
+	for _, r := range results {
+		if r.Index < 0 || r.Index >= documents || seen[r.Index] {
+			return fmt.Errorf("invalid result index %d", r.Index)
+		}
+		seen[r.Index] = true
+	}
+	return nil
+}
+
+// postJSON sends a JSON request with a bearer token and decodes the JSON
+// response into out
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	reqBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make API request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, readErr := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if readErr != nil {
+			return fmt.Errorf("API request failed with status %d (error reading response body: %w)", resp.StatusCode, readErr)
+		}
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewReranker(t *testing.T) {
+	tests := []struct {
+		cfg       Config
+		wantModel string
+		wantErr   string
+	}{
+		{cfg: Config{Provider: "voyage", APIKey: "key"}, wantModel: "rerank-2"},
+		{cfg: Config{Provider: "cohere", APIKey: "key", Model: "rerank-english-v3.0"}, wantModel: "rerank-english-v3.0"},
+		{cfg: Config{Provider: "ollama", Model: "example-model"}, wantModel: "example-model"},
+		{cfg: Config{Provider: "voyage"}, wantErr: "API key is required"},
+		{cfg: Config{Provider: "cohere"}, wantErr: "API key is required"},
+		{cfg: Config{Provider: "ollama"}, wantErr: "model is required"},
+		{cfg: Config{Provider: "other"}, wantErr: "unsupported rerank provider"},
+	}
+	for _, tt := range tests {
+		r, err := NewReranker(tt.cfg)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewReranker(%+v): expected error containing %q, got %v", tt.cfg, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("NewReranker(%+v): %v", tt.cfg, err)
+		}
+		if r.ProviderName() != tt.cfg.Provider || r.ModelName() != tt.wantModel {
+			t.Errorf("got %s/%s, want %s/%s", r.ProviderName(), r.ModelName(), tt.cfg.Provider, tt.wantModel)
+		}
+	}
+}
+
+func TestVoyageReranker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		var req voyageRerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query != "q" || len(req.Documents) != 2 {
+			t.Errorf("unexpected request %+v (%v)", req, err)
+		}
+		w.Write([]byte(`{"data": [{"index": 1, "relevance_score": 0.9}, {"index": 0, "relevance_score": 0.2}]}`))
+	}))
+	defer server.Close()
+
+	r := newVoyageReranker(Config{APIKey: "key", Timeout: DefaultTimeout})
+	r.baseURL = server.URL
+	results, err := r.Rerank(context.Background(), "q", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Index != 1 || results[1].Index != 0 {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestCohereReranker(t *testing.T) {
+	response := `{"results": [{"index": 0, "relevance_score": 0.1}, {"index": 1, "relevance_score": 0.7}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if response == "" {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	r := newCohereReranker(Config{APIKey: "key", Timeout: DefaultTimeout})
+	r.baseURL = server.URL
+	results, err := r.Rerank(context.Background(), "q", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if results[0].Index != 1 || results[0].Score != 0.7 {
+		t.Errorf("expected the second document first, got %+v", results)
+	}
+
+	// Responses that don't cover every document once are rejected
+	response = `{"results": [{"index": 0, "relevance_score": 0.1}, {"index": 0, "relevance_score": 0.7}]}`
+	if _, err := r.Rerank(context.Background(), "q", []string{"a", "b"}); err == nil {
+		t.Error("expected an error for a duplicated index")
+	}
+
+	response = ""
+	if _, err := r.Rerank(context.Background(), "q", []string{"a", "b"}); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("expected the API error, got %v", err)
+	}
+}
+
+func TestOllamaReranker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req ollamaGenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		answer := "2"
+		if strings.Contains(req.Prompt, "relevant text") {
+			answer = " 9\n"
+		}
+		json.NewEncoder(w).Encode(ollamaGenerateResponse{Response: answer})
+	}))
+	defer server.Close()
+
+	r := newOllamaReranker(Config{URL: server.URL + "/", Model: "example-model", Timeout: DefaultTimeout})
+	results, err := r.Rerank(context.Background(), "q", []string{"other", "relevant text", "more"})
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if results[0].Index != 1 || results[0].Score != 0.9 || results[1].Index != 0 || results[2].Index != 2 {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestParseOllamaScore(t *testing.T) {
+	for answer, want := range map[string]float64{
+		"7":               0.7,
+		"Relevance: 8.5":  0.85,
+		"42":              1,
+		"not relevant":    0,
+		"":                0,
+		"10/10 excellent": 1,
+	} {
+		if got := parseOllamaScore(answer); got != want {
+			t.Errorf("parseOllamaScore(%q) = %v, want %v", answer, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package rerank
+
+import (
+	"context"
+	"net/http"
+
+	"pgedge-postgres-mcp/internal/netproxy"
+)
+
+// VoyageReranker uses Voyage AI's rerank API
+type VoyageReranker struct {
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+// voyageRerankRequest is a request to Voyage AI's rerank API
+type voyageRerankRequest struct {
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	Model     string   `json:"model"`
+}
+
+// voyageRerankResponse is a response from Voyage AI's rerank API
+type voyageRerankResponse struct {
+	Data []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"data"`
+}
+
+func newVoyageReranker(cfg Config) *VoyageReranker {
+	model := cfg.Model
+	if model == "" {
+		model = "rerank-2"
+	}
+	return &VoyageReranker{
+		apiKey:  cfg.APIKey,
+		model:   model,
+		baseURL: "https://api.voyageai.com/v1/rerank",
+		client:  netproxy.NewClient(netproxy.ProviderVoyage, cfg.Timeout),
+	}
+}
+
+// Rerank scores the documents with Voyage AI
+func (r *VoyageReranker) Rerank(ctx context.Context, query string, documents []string) ([]Result, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	var resp voyageRerankResponse
+	err := postJSON(ctx, r.client, r.baseURL, r.apiKey,
+		voyageRerankRequest{Query: query, Documents: documents, Model: r.model}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(resp.Data))
+	for _, d := range resp.Data {
+		results = append(results, Result{Index: d.Index, Score: d.RelevanceScore})
+	}
+	if err := checkIndexes(results, len(documents)); err != nil {
+		return nil, err
+	}
+	return sortResults(results), nil
+}
+
+// ProviderName returns "voyage"
+func (r *VoyageReranker) ProviderName() string {
+	return "voyage"
+}
+
+// ModelName returns the model name
+func (r *VoyageReranker) ModelName() string {
+	return r.model
+}
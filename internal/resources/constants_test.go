@@ -28,6 +28,8 @@ func TestURIConstants(t *testing.T) {
 		expected string
 	}{
 		{"URISystemInfo", URISystemInfo, "pg://system_info"},
+		{"URIExtensions", URIExtensions, "pg://extensions"},
+		{"URIReplication", URIReplication, "pg://replication"},
 	}
 
 	for _, tt := range tests {
@@ -41,7 +43,7 @@ func TestURIConstants(t *testing.T) {
 
 func TestURIFormat(t *testing.T) {
 	// All resource URIs should follow pg:// scheme
-	uris := []string{URISystemInfo}
+	uris := []string{URISystemInfo, URIExtensions, URIReplication}
 
 	for _, uri := range uris {
 		if !strings.HasPrefix(uri, "pg://") {
@@ -13,6 +13,7 @@ package resources
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"pgedge-postgres-mcp/internal/auth"
 	"pgedge-postgres-mcp/internal/config"
@@ -30,7 +31,9 @@ type ContextAwareRegistry struct {
 	authEnabled     bool
 	accessChecker   *auth.DatabaseAccessChecker
 	customResources map[string]customResource
-	cfg             *config.Config
+
+	cfgMu sync.RWMutex
+	cfg   *config.Config
 }
 
 // customResource represents a user-defined resource
@@ -50,12 +53,27 @@ func NewContextAwareRegistry(clientManager *database.ClientManager, authEnabled
 	}
 }
 
+// SetConfig replaces the configuration used for built-in resource toggles
+// Called on configuration reload
+func (r *ContextAwareRegistry) SetConfig(cfg *config.Config) {
+	r.cfgMu.Lock()
+	defer r.cfgMu.Unlock()
+	r.cfg = cfg
+}
+
+// config returns the current configuration
+func (r *ContextAwareRegistry) config() *config.Config {
+	r.cfgMu.RLock()
+	defer r.cfgMu.RUnlock()
+	return r.cfg
+}
+
 // List returns all available resource definitions
 func (r *ContextAwareRegistry) List() []mcp.Resource {
 	// Start with static built-in resources (only include enabled ones)
 	resources := []mcp.Resource{}
 
-	if r.cfg.Builtins.Resources.IsResourceEnabled(URISystemInfo) {
+	if r.config().Builtins.Resources.IsResourceEnabled(URISystemInfo) {
 		resources = append(resources, mcp.Resource{
 			URI:         URISystemInfo,
 			Name:        "PostgreSQL System Information",
@@ -64,6 +82,24 @@ func (r *ContextAwareRegistry) List() []mcp.Resource {
 		})
 	}
 
+	if r.config().Builtins.Resources.IsResourceEnabled(URIExtensions) {
+		resources = append(resources, mcp.Resource{
+			URI:         URIExtensions,
+			Name:        "PostgreSQL Extensions",
+			Description: "Returns the extensions installed in the database with their versions and schemas, and the extensions available on the server to install.",
+			MimeType:    "application/json",
+		})
+	}
+
+	if r.config().Builtins.Resources.IsResourceEnabled(URIReplication) {
+		resources = append(resources, mcp.Resource{
+			URI:         URIReplication,
+			Name:        "PostgreSQL Replication Status",
+			Description: "Returns connected standbys with their lag in bytes and seconds, replication slots with the WAL they retain, and findings such as lagging standbys or inactive slots.",
+			MimeType:    "application/json",
+		})
+	}
+
 	// Add custom resources
 	for _, customRes := range r.customResources {
 		resources = append(resources, customRes.definition)
@@ -92,28 +128,28 @@ func (r *ContextAwareRegistry) Read(ctx context.Context, uri string) (mcp.Resour
 		return customRes.handler(ctx, dbClient)
 	}
 
-	// Get the appropriate database client for built-in resources
-	dbClient, err := r.getClient(ctx)
-	if err != nil {
+	// Check if the built-in resource is enabled before connecting
+	if !r.config().Builtins.Resources.IsResourceEnabled(uri) {
 		return mcp.ResourceContent{
 			URI: uri,
 			Contents: []mcp.ContentItem{
 				{
 					Type: "text",
-					Text: fmt.Sprintf("Error: %v", err),
+					Text: fmt.Sprintf("Resource '%s' is not available", uri),
 				},
 			},
 		}, nil
 	}
 
-	// Check if the built-in resource is enabled
-	if uri == URISystemInfo && !r.cfg.Builtins.Resources.IsResourceEnabled(uri) {
+	// Get the appropriate database client for built-in resources
+	dbClient, err := r.getClient(ctx)
+	if err != nil {
 		return mcp.ResourceContent{
 			URI: uri,
 			Contents: []mcp.ContentItem{
 				{
 					Type: "text",
-					Text: fmt.Sprintf("Resource '%s' is not available", uri),
+					Text: fmt.Sprintf("Error: %v", err),
 				},
 			},
 		}, nil
@@ -124,6 +160,10 @@ func (r *ContextAwareRegistry) Read(ctx context.Context, uri string) (mcp.Resour
 	switch uri {
 	case URISystemInfo:
 		resource = PGSystemInfoResource(dbClient)
+	case URIExtensions:
+		resource = PGExtensionsResource(dbClient)
+	case URIReplication:
+		resource = PGReplicationResource(dbClient)
 	default:
 		return mcp.ResourceContent{
 			URI: uri,
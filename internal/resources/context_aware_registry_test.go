@@ -89,6 +89,34 @@ func TestContextAwareRegistry_List(t *testing.T) {
 		if !found[URISystemInfo] {
 			t.Error("expected URISystemInfo to be in list")
 		}
+		if !found[URIExtensions] {
+			t.Error("expected URIExtensions to be in list")
+		}
+		if !found[URIReplication] {
+			t.Error("expected URIReplication to be in list")
+		}
+	})
+
+	t.Run("with replication disabled", func(t *testing.T) {
+		cfg := &conf.Config{
+			Builtins: conf.BuiltinsConfig{
+				Resources: conf.ResourcesConfig{
+					Replication: boolPtr(false),
+				},
+			},
+		}
+
+		registry := NewContextAwareRegistry(cm, false, nil, cfg)
+		found := make(map[string]bool)
+		for _, r := range registry.List() {
+			found[r.URI] = true
+		}
+		if found[URIReplication] {
+			t.Error("expected URIReplication to be disabled")
+		}
+		if !found[URISystemInfo] {
+			t.Error("expected URISystemInfo to stay enabled")
+		}
 	})
 
 	t.Run("with system_info disabled", func(t *testing.T) {
@@ -142,6 +170,15 @@ func TestContextAwareRegistry_Read_DisabledResource(t *testing.T) {
 	if content.Contents[0].Text == "" {
 		t.Error("expected error message in content")
 	}
+
+	cfg.Builtins.Resources.Replication = boolPtr(false)
+	content, err = registry.Read(context.Background(), URIReplication)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(content.Contents) == 0 || content.Contents[0].Text != "Resource 'pg://replication' is not available" {
+		t.Errorf("unexpected content: %+v", content.Contents)
+	}
 }
 
 func TestContextAwareRegistry_Read_NotFound(t *testing.T) {
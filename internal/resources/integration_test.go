@@ -47,6 +47,16 @@ func TestAllResources_Integration(t *testing.T) {
 			resource:     PGSystemInfoResource(client),
 			requiresData: false,
 		},
+		{
+			name:         "pg://extensions",
+			resource:     PGExtensionsResource(client),
+			requiresData: false,
+		},
+		{
+			name:         "pg://replication",
+			resource:     PGReplicationResource(client),
+			requiresData: false,
+		},
 	}
 
 	for _, tt := range tests {
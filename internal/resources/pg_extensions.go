@@ -0,0 +1,114 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package resources
+
+import (
+	"fmt"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/mcp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PGExtensionsResource creates a resource listing the installed extensions
+// of the database and the extensions available on the server
+func PGExtensionsResource(dbClient *database.Client) Resource {
+	return Resource{
+		Definition: mcp.Resource{
+			URI:  URIExtensions,
+			Name: "PostgreSQL Extensions",
+			Description: `Extensions installed in the database, with their versions and schemas, and the extensions available on the server to install.
+
+<usecase>
+Use for:
+- Checking whether pgvector (vector) is installed before semantic search setup
+- Finding the version of an installed extension and whether an update is available
+- Finding which extensions could be installed without installing server packages
+</usecase>
+
+<provided_info>
+Returns JSON with:
+- installed: name, version, schema, default_version, update_available and
+  description of each extension installed in the database
+- available: name, default_version and description of each extension whose
+  files are on the server but which is not installed in the database
+</provided_info>
+
+<important>
+- An extension that is not listed at all needs its package installed on the
+  database server first
+- Extensions are installed with the install_extension tool, when enabled
+</important>`,
+			MimeType: "application/json",
+		},
+		Handler: func() (mcp.ResourceContent, error) {
+			query := `
+				SELECT
+					COALESCE(e.extname, a.name)::text,
+					e.extversion,
+					n.nspname::text,
+					a.default_version,
+					a.comment
+				FROM pg_catalog.pg_available_extensions a
+				FULL JOIN pg_catalog.pg_extension e ON e.extname = a.name
+				LEFT JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace
+				ORDER BY 1
+			`
+
+			processor := func(rows pgx.Rows) (interface{}, error) {
+				var extensions []Extension
+				for rows.Next() {
+					var ext Extension
+					if err := rows.Scan(&ext.Name, &ext.Version, &ext.Schema, &ext.DefaultVersion, &ext.Description); err != nil {
+						return nil, fmt.Errorf("failed to scan extension: %w", err)
+					}
+					extensions = append(extensions, ext)
+				}
+				return buildExtensionInventory(extensions), nil
+			}
+
+			return database.ExecuteResourceQuery(dbClient, URIExtensions, query, processor)
+		},
+	}
+}
+
+// Extension represents an extension installed in the database or available
+// on the server
+type Extension struct {
+	Name            string  `json:"name"`
+	Version         *string `json:"version,omitempty"` // Installed version
+	Schema          *string `json:"schema,omitempty"`  // Schema of an installed extension
+	DefaultVersion  *string `json:"default_version"`   // NULL when the extension's files were removed
+	UpdateAvailable bool    `json:"update_available,omitempty"`
+	Description     *string `json:"description"`
+}
+
+// ExtensionInventory represents the extensions of a database
+type ExtensionInventory struct {
+	Installed []Extension `json:"installed"`
+	Available []Extension `json:"available"`
+}
+
+// buildExtensionInventory splits extensions into installed and available
+// ones, flagging installed extensions older than the server's default version
+func buildExtensionInventory(extensions []Extension) ExtensionInventory {
+	inventory := ExtensionInventory{Installed: []Extension{}, Available: []Extension{}}
+	for _, ext := range extensions {
+		if ext.Version == nil {
+			inventory.Available = append(inventory.Available, ext)
+			continue
+		}
+		ext.UpdateAvailable = ext.DefaultVersion != nil && *ext.DefaultVersion != *ext.Version
+		inventory.Installed = append(inventory.Installed, ext)
+	}
+	return inventory
+}
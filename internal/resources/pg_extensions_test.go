@@ -0,0 +1,51 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package resources
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildExtensionInventory(t *testing.T) {
+	inventory := buildExtensionInventory([]Extension{
+		{Name: "pg_trgm", DefaultVersion: strPtr("1.6")},
+		{Name: "plpgsql", Version: strPtr("1.0"), Schema: strPtr("pg_catalog"), DefaultVersion: strPtr("1.0")},
+		{Name: "removed", Version: strPtr("2.0"), Schema: strPtr("public")},
+		{Name: "vector", Version: strPtr("0.7.0"), Schema: strPtr("public"), DefaultVersion: strPtr("0.8.0")},
+	})
+
+	if len(inventory.Available) != 1 || inventory.Available[0].Name != "pg_trgm" {
+		t.Errorf("unexpected available extensions %+v", inventory.Available)
+	}
+	updates := map[string]bool{}
+	for _, ext := range inventory.Installed {
+		updates[ext.Name] = ext.UpdateAvailable
+	}
+	want := map[string]bool{"plpgsql": false, "removed": false, "vector": true}
+	if len(updates) != len(want) {
+		t.Fatalf("unexpected installed extensions %+v", inventory.Installed)
+	}
+	for name, update := range want {
+		if updates[name] != update {
+			t.Errorf("%s update_available = %v, want %v", name, updates[name], update)
+		}
+	}
+
+	// Empty lists are arrays rather than null
+	data, err := json.Marshal(buildExtensionInventory(nil))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `{"installed":[],"available":[]}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
@@ -0,0 +1,396 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/mcp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Thresholds above which a standby is reported as lagging. 16 MB is the
+// default WAL segment size.
+const (
+	replicationLagBytesThreshold   = 16 << 20
+	replicationLagSecondsThreshold = 60
+)
+
+// PGReplicationResource creates a resource for the replication status of
+// the server: connected standbys, replication slots and, on a standby, the
+// WAL receiver
+func PGReplicationResource(dbClient *database.Client) Resource {
+	return Resource{
+		Definition: mcp.Resource{
+			URI:  URIReplication,
+			Name: "PostgreSQL Replication Status",
+			Description: `Replication status: connected standbys with their lag, replication slots with the WAL they retain, and on a standby the WAL receiver.
+
+<usecase>
+Use for:
+- "Is my standby lagging?" and "How far behind is the replica?"
+- Finding inactive replication slots that retain WAL and fill the disk
+- Checking synchronous replication (sync_state, synchronous_standby_names)
+- Checking whether a standby is connected to its primary
+</usecase>
+
+<provided_info>
+Returns JSON with:
+- role: primary or standby
+- wal_position: current WAL position (the replay position on a standby)
+- replicas: pg_stat_replication rows with sent, flush and replay lag in
+  bytes and seconds, state and sync_state
+- slots: pg_replication_slots rows with retained_wal_bytes, wal_status and,
+  for logical slots, the bytes not yet confirmed by the consumer
+- wal_receiver: on a standby, the connection to the upstream server and the
+  bytes received but not yet replayed
+- findings: lagging standbys, inactive slots, lost WAL and missing
+  privileges; empty when nothing needs attention
+</provided_info>
+
+<important>
+- Lag is measured from this server's current WAL position; run it on the
+  primary to see every standby
+- replay_delay_seconds on a standby grows while the primary is idle, since it
+  is the age of the last replayed transaction
+- Without the pg_monitor role, the details of standby connections are hidden
+</important>`,
+			MimeType: "application/json",
+		},
+		Handler: func() (mcp.ResourceContent, error) {
+			if !dbClient.IsMetadataLoaded() {
+				return mcp.NewResourceError(URIReplication, mcp.DatabaseNotReadyErrorShort)
+			}
+			pool := dbClient.GetPool()
+			if pool == nil {
+				return mcp.ResourceContent{}, fmt.Errorf("no connection pool available")
+			}
+
+			ctx := context.Background()
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+			if err != nil {
+				return mcp.ResourceContent{}, fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			defer tx.Rollback(ctx) //nolint:errcheck // The transaction only reads
+
+			status, err := readReplicationStatus(ctx, tx)
+			if err != nil {
+				return mcp.ResourceContent{}, err
+			}
+			status.Findings = replicationFindings(status)
+
+			jsonData, err := json.MarshalIndent(status, "", "  ")
+			if err != nil {
+				return mcp.ResourceContent{}, fmt.Errorf("failed to marshal JSON: %w", err)
+			}
+			return mcp.NewResourceSuccess(URIReplication, "application/json", string(jsonData))
+		},
+	}
+}
+
+// ReplicationStatus represents the replication status of a server
+type ReplicationStatus struct {
+	Role                    string            `json:"role"` // primary or standby
+	WALPosition             *string           `json:"wal_position"`
+	WALLevel                string            `json:"wal_level"`
+	MaxWALSenders           int               `json:"max_wal_senders"`
+	MaxReplicationSlots     int               `json:"max_replication_slots"`
+	SynchronousStandbyNames string            `json:"synchronous_standby_names"`
+	ReplayDelaySeconds      *float64          `json:"replay_delay_seconds,omitempty"` // Standby only
+	WALReceiver             *WALReceiver      `json:"wal_receiver,omitempty"`         // Standby only
+	Replicas                []Replica         `json:"replicas"`
+	Slots                   []ReplicationSlot `json:"slots"`
+	Findings                []string          `json:"findings"`
+}
+
+// Replica represents a standby or other WAL sender connected to the server
+type Replica struct {
+	PID              int        `json:"pid"`
+	User             *string    `json:"user"`
+	ApplicationName  *string    `json:"application_name"`
+	ClientAddr       *string    `json:"client_addr"`
+	State            *string    `json:"state"` // NULL without the privileges to see it
+	SyncState        *string    `json:"sync_state"`
+	SyncPriority     *int       `json:"sync_priority"`
+	SentLSN          *string    `json:"sent_lsn"`
+	FlushLSN         *string    `json:"flush_lsn"`
+	ReplayLSN        *string    `json:"replay_lsn"`
+	SentLagBytes     *int64     `json:"sent_lag_bytes"`
+	FlushLagBytes    *int64     `json:"flush_lag_bytes"`
+	ReplayLagBytes   *int64     `json:"replay_lag_bytes"`
+	WriteLagSeconds  *float64   `json:"write_lag_seconds"`
+	FlushLagSeconds  *float64   `json:"flush_lag_seconds"`
+	ReplayLagSeconds *float64   `json:"replay_lag_seconds"`
+	BackendStart     *time.Time `json:"backend_start"`
+}
+
+// ReplicationSlot represents a physical or logical replication slot
+type ReplicationSlot struct {
+	SlotName               string  `json:"slot_name"`
+	SlotType               string  `json:"slot_type"` // physical or logical
+	Plugin                 *string `json:"plugin"`
+	Database               *string `json:"database"`
+	Active                 bool    `json:"active"`
+	ActivePID              *int    `json:"active_pid"`
+	Temporary              bool    `json:"temporary"`
+	RestartLSN             *string `json:"restart_lsn"`
+	ConfirmedFlushLSN      *string `json:"confirmed_flush_lsn"`
+	RetainedWALBytes       *int64  `json:"retained_wal_bytes"`
+	ConfirmedFlushLagBytes *int64  `json:"confirmed_flush_lag_bytes"` // Logical slots only
+	WALStatus              *string `json:"wal_status"`                // PostgreSQL 13 and later
+}
+
+// WALReceiver represents the connection of a standby to its upstream server
+type WALReceiver struct {
+	Status             string     `json:"status"`
+	SenderHost         *string    `json:"sender_host"`
+	SenderPort         *int       `json:"sender_port"`
+	SlotName           *string    `json:"slot_name"`
+	FlushedLSN         *string    `json:"flushed_lsn"`
+	ReplayLSN          *string    `json:"replay_lsn"`
+	ReplayLagBytes     *int64     `json:"replay_lag_bytes"` // Received but not yet replayed
+	LastMsgReceiptTime *time.Time `json:"last_msg_receipt_time"`
+}
+
+// walPosition is the current WAL position of the server: the insert
+// position on a primary and the replay position on a standby
+const walPosition = `CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END`
+
+// readReplicationStatus reads the replication status. Columns that differ
+// between PostgreSQL versions are read through to_jsonb, so a missing
+// column reads as NULL.
+func readReplicationStatus(ctx context.Context, tx pgx.Tx) (*ReplicationStatus, error) {
+	status := &ReplicationStatus{Replicas: []Replica{}, Slots: []ReplicationSlot{}}
+
+	var inRecovery bool
+	err := tx.QueryRow(ctx, `
+		SELECT
+			pg_is_in_recovery(),
+			(`+walPosition+`)::text,
+			current_setting('wal_level'),
+			current_setting('max_wal_senders')::int,
+			current_setting('max_replication_slots')::int,
+			current_setting('synchronous_standby_names'),
+			CASE WHEN pg_is_in_recovery()
+				THEN EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8
+			END
+	`).Scan(&inRecovery, &status.WALPosition, &status.WALLevel, &status.MaxWALSenders,
+		&status.MaxReplicationSlots, &status.SynchronousStandbyNames, &status.ReplayDelaySeconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the server's replication settings: %w", err)
+	}
+	status.Role = "primary"
+	if inRecovery {
+		status.Role = "standby"
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		WITH pos AS (SELECT %s AS lsn)
+		SELECT
+			r.pid, r.usename::text, r.application_name, r.client_addr::text,
+			r.state, r.sync_state, r.sync_priority,
+			r.sent_lsn::text, r.flush_lsn::text, r.replay_lsn::text,
+			pg_wal_lsn_diff(pos.lsn, r.sent_lsn)::bigint,
+			pg_wal_lsn_diff(pos.lsn, r.flush_lsn)::bigint,
+			pg_wal_lsn_diff(pos.lsn, r.replay_lsn)::bigint,
+			EXTRACT(EPOCH FROM r.write_lag)::float8,
+			EXTRACT(EPOCH FROM r.flush_lag)::float8,
+			EXTRACT(EPOCH FROM r.replay_lag)::float8,
+			r.backend_start
+		FROM pg_stat_replication r, pos
+		ORDER BY r.application_name, r.pid
+		LIMIT %d
+	`, walPosition, DefaultQueryLimit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pg_stat_replication: %w", err)
+	}
+	for rows.Next() {
+		var r Replica
+		if err := rows.Scan(&r.PID, &r.User, &r.ApplicationName, &r.ClientAddr,
+			&r.State, &r.SyncState, &r.SyncPriority,
+			&r.SentLSN, &r.FlushLSN, &r.ReplayLSN,
+			&r.SentLagBytes, &r.FlushLagBytes, &r.ReplayLagBytes,
+			&r.WriteLagSeconds, &r.FlushLagSeconds, &r.ReplayLagSeconds,
+			&r.BackendStart); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan pg_stat_replication: %w", err)
+		}
+		status.Replicas = append(status.Replicas, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pg_stat_replication: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, fmt.Sprintf(`
+		WITH pos AS (SELECT %s AS lsn)
+		SELECT
+			s.slot_name::text, s.slot_type, s.plugin::text, s.database::text,
+			s.active, s.active_pid, s.temporary,
+			s.restart_lsn::text, s.confirmed_flush_lsn::text,
+			pg_wal_lsn_diff(pos.lsn, s.restart_lsn)::bigint,
+			pg_wal_lsn_diff(pos.lsn, s.confirmed_flush_lsn)::bigint,
+			to_jsonb(s)->>'wal_status'
+		FROM pg_replication_slots s, pos
+		ORDER BY s.slot_name
+		LIMIT %d
+	`, walPosition, DefaultQueryLimit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pg_replication_slots: %w", err)
+	}
+	for rows.Next() {
+		var s ReplicationSlot
+		if err := rows.Scan(&s.SlotName, &s.SlotType, &s.Plugin, &s.Database,
+			&s.Active, &s.ActivePID, &s.Temporary,
+			&s.RestartLSN, &s.ConfirmedFlushLSN,
+			&s.RetainedWALBytes, &s.ConfirmedFlushLagBytes, &s.WALStatus); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan pg_replication_slots: %w", err)
+		}
+		status.Slots = append(status.Slots, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pg_replication_slots: %w", err)
+	}
+
+	if !inRecovery {
+		return status, nil
+	}
+
+	// flushed_lsn was received_lsn before PostgreSQL 13
+	var w WALReceiver
+	err = tx.QueryRow(ctx, `
+		WITH w AS (
+			SELECT status, slot_name, last_msg_receipt_time, to_jsonb(r) AS j
+			FROM pg_stat_wal_receiver r
+		)
+		SELECT
+			status, j->>'sender_host', (j->>'sender_port')::int, slot_name,
+			COALESCE(j->>'flushed_lsn', j->>'received_lsn'),
+			pg_last_wal_replay_lsn()::text,
+			pg_wal_lsn_diff(COALESCE(j->>'flushed_lsn', j->>'received_lsn')::pg_lsn, pg_last_wal_replay_lsn())::bigint,
+			last_msg_receipt_time
+		FROM w
+	`).Scan(&w.Status, &w.SenderHost, &w.SenderPort, &w.SlotName,
+		&w.FlushedLSN, &w.ReplayLSN, &w.ReplayLagBytes, &w.LastMsgReceiptTime)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// No WAL receiver is running
+	case err != nil:
+		return nil, fmt.Errorf("failed to query pg_stat_wal_receiver: %w", err)
+	default:
+		status.WALReceiver = &w
+	}
+	return status, nil
+}
+
+// replicationFindings lists what needs attention in a replication status:
+// lagging or disconnected standbys, slots that retain WAL, and missing
+// privileges. Empty when nothing does.
+func replicationFindings(status *ReplicationStatus) []string {
+	findings := []string{}
+
+	hidden := 0
+	for _, r := range status.Replicas {
+		if r.State == nil {
+			hidden++
+			continue
+		}
+		name := replicaName(r)
+		if *r.State != "streaming" {
+			findings = append(findings, fmt.Sprintf("Standby %s is %s, not streaming", name, *r.State))
+		}
+		lagging := r.ReplayLagBytes != nil && *r.ReplayLagBytes > replicationLagBytesThreshold
+		lagging = lagging || (r.ReplayLagSeconds != nil && *r.ReplayLagSeconds > replicationLagSecondsThreshold)
+		if lagging {
+			findings = append(findings, fmt.Sprintf("Standby %s is lagging: replay is %s behind", name, formatLag(r.ReplayLagBytes, r.ReplayLagSeconds)))
+		}
+	}
+	if hidden > 0 {
+		findings = append(findings, fmt.Sprintf("The details of %d standby connection(s) are hidden; grant the pg_monitor role to the database user to see them", hidden))
+	}
+
+	for _, s := range status.Slots {
+		if s.WALStatus != nil {
+			switch *s.WALStatus {
+			case "lost":
+				findings = append(findings, fmt.Sprintf("Replication slot %s has lost WAL it needs; its consumer cannot resume and the slot should be dropped and recreated", s.SlotName))
+				continue
+			case "unreserved":
+				findings = append(findings, fmt.Sprintf("Replication slot %s is about to lose WAL it needs (max_slot_wal_keep_size)", s.SlotName))
+			}
+		}
+		if !s.Active && !s.Temporary {
+			findings = append(findings, fmt.Sprintf("Replication slot %s is inactive and retains %s of WAL; drop it with pg_drop_replication_slot if its consumer is gone",
+				s.SlotName, formatLagBytes(s.RetainedWALBytes)))
+		}
+	}
+
+	if status.Role == "standby" {
+		switch {
+		case status.WALReceiver == nil:
+			findings = append(findings, "This server is a standby but no WAL receiver is running; it is not connected to a primary (or it replays WAL from an archive)")
+		case status.WALReceiver.Status != "streaming":
+			findings = append(findings, fmt.Sprintf("The WAL receiver is %s, not streaming", status.WALReceiver.Status))
+		}
+		if w := status.WALReceiver; w != nil && w.ReplayLagBytes != nil && *w.ReplayLagBytes > replicationLagBytesThreshold {
+			findings = append(findings, fmt.Sprintf("%s of WAL has been received but not yet replayed", formatLagBytes(w.ReplayLagBytes)))
+		}
+	}
+	return findings
+}
+
+// replicaName identifies a standby by application name and address
+func replicaName(r Replica) string {
+	name := fmt.Sprintf("pid %d", r.PID)
+	if r.ApplicationName != nil && *r.ApplicationName != "" {
+		name = *r.ApplicationName
+	}
+	if r.ClientAddr != nil {
+		name += " (" + *r.ClientAddr + ")"
+	}
+	return name
+}
+
+// formatLagBytes formats a byte count using binary units like pg_size_pretty
+func formatLagBytes(bytes *int64) string {
+	if bytes == nil {
+		return "an unknown amount"
+	}
+	value := float64(*bytes)
+	for _, unit := range []string{"bytes", "kB", "MB", "GB"} {
+		if value < 1024 {
+			if unit == "bytes" {
+				return fmt.Sprintf("%d bytes", *bytes)
+			}
+			return fmt.Sprintf("%.1f %s", value, unit)
+		}
+		value /= 1024
+	}
+	return fmt.Sprintf("%.1f TB", value)
+}
+
+// formatLag describes the replay lag of a standby in bytes and, when the
+// server reports it, in time
+func formatLag(bytes *int64, seconds *float64) string {
+	lag := formatLagBytes(bytes)
+	if seconds != nil {
+		lag += fmt.Sprintf(" (%s)", time.Duration(*seconds*float64(time.Second)).Round(time.Second))
+	}
+	return lag
+}
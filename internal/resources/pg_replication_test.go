@@ -0,0 +1,132 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package resources
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func strPtr(s string) *string       { return &s }
+func int64Ptr(n int64) *int64       { return &n }
+func float64Ptr(f float64) *float64 { return &f }
+
+func TestReplicationFindings(t *testing.T) {
+	tests := []struct {
+		name   string
+		status ReplicationStatus
+		want   []string
+	}{
+		{
+			name: "healthy primary",
+			status: ReplicationStatus{
+				Role: "primary",
+				Replicas: []Replica{{
+					PID: 10, ApplicationName: strPtr("standby1"), State: strPtr("streaming"),
+					ReplayLagBytes: int64Ptr(4096), ReplayLagSeconds: float64Ptr(0.2),
+				}},
+				Slots: []ReplicationSlot{{SlotName: "standby1", SlotType: "physical", Active: true, WALStatus: strPtr("reserved")}},
+			},
+		},
+		{
+			name: "lagging standby",
+			status: ReplicationStatus{
+				Role: "primary",
+				Replicas: []Replica{{
+					PID: 10, ApplicationName: strPtr("standby1"), ClientAddr: strPtr("10.0.0.2"), State: strPtr("streaming"),
+					ReplayLagBytes: int64Ptr(3 << 30), ReplayLagSeconds: float64Ptr(125),
+				}},
+			},
+			want: []string{"Standby standby1 (10.0.0.2) is lagging: replay is 3.0 GB (2m5s) behind"},
+		},
+		{
+			name: "lag in time only",
+			status: ReplicationStatus{
+				Role: "primary",
+				Replicas: []Replica{{
+					PID: 11, ApplicationName: strPtr(""), State: strPtr("catchup"),
+					ReplayLagBytes: int64Ptr(100), ReplayLagSeconds: float64Ptr(90),
+				}},
+			},
+			want: []string{
+				"Standby pid 11 is catchup, not streaming",
+				"Standby pid 11 is lagging: replay is 100 bytes (1m30s) behind",
+			},
+		},
+		{
+			name: "hidden standbys",
+			status: ReplicationStatus{
+				Role:     "primary",
+				Replicas: []Replica{{PID: 10}, {PID: 11}},
+			},
+			want: []string{"The details of 2 standby connection(s) are hidden; grant the pg_monitor role to the database user to see them"},
+		},
+		{
+			name: "slots",
+			status: ReplicationStatus{
+				Role: "primary",
+				Slots: []ReplicationSlot{
+					{SlotName: "old_sub", SlotType: "logical", RetainedWALBytes: int64Ptr(5 << 20), WALStatus: strPtr("extended")},
+					{SlotName: "gone", SlotType: "physical", WALStatus: strPtr("lost")},
+					{SlotName: "tight", SlotType: "physical", Active: true, WALStatus: strPtr("unreserved")},
+					{SlotName: "tmp", SlotType: "logical", Temporary: true},
+				},
+			},
+			want: []string{
+				"Replication slot old_sub is inactive and retains 5.0 MB of WAL; drop it with pg_drop_replication_slot if its consumer is gone",
+				"Replication slot gone has lost WAL it needs; its consumer cannot resume and the slot should be dropped and recreated",
+				"Replication slot tight is about to lose WAL it needs (max_slot_wal_keep_size)",
+			},
+		},
+		{
+			name:   "standby without receiver",
+			status: ReplicationStatus{Role: "standby"},
+			want:   []string{"This server is a standby but no WAL receiver is running; it is not connected to a primary (or it replays WAL from an archive)"},
+		},
+		{
+			name: "standby behind in replay",
+			status: ReplicationStatus{
+				Role:        "standby",
+				WALReceiver: &WALReceiver{Status: "streaming", ReplayLagBytes: int64Ptr(64 << 20)},
+			},
+			want: []string{"64.0 MB of WAL has been received but not yet replayed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := replicationFindings(&tt.status)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("replicationFindings() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestReplicationStatusJSON(t *testing.T) {
+	status := &ReplicationStatus{Role: "primary", Replicas: []Replica{}, Slots: []ReplicationSlot{}}
+	status.Findings = replicationFindings(status)
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// Empty lists are arrays rather than null, and standby fields are omitted
+	for _, want := range []string{`"replicas":[]`, `"slots":[]`, `"findings":[]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON missing %s: %s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "wal_receiver") || strings.Contains(string(data), "replay_delay_seconds") {
+		t.Errorf("JSON has standby fields on a primary: %s", data)
+	}
+}
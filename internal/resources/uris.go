@@ -15,4 +15,8 @@ package resources
 const (
 	// System Information Resources
 	URISystemInfo = "pg://system_info"
+	URIExtensions = "pg://extensions"
+
+	// Monitoring Resources
+	URIReplication = "pg://replication"
 )
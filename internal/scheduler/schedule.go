@@ -0,0 +1,209 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minEvery is the shortest interval of an @every schedule
+const minEvery = time.Minute
+
+// maxSearchYears bounds the search for the next time of a cron schedule,
+// so a date that never occurs (such as February 30) ends the search
+const maxSearchYears = 5
+
+// Schedule is when a job runs: a cron expression or a fixed interval
+type Schedule struct {
+	minute, hour, day, month, weekday uint64 // Bit n set = value n matches
+	dayAny, weekdayAny                bool   // The field was *
+	every                             time.Duration
+}
+
+// cronField describes a field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// cronShorthands are the named schedules accepted in place of an expression
+var cronShorthands = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseSchedule parses a schedule: a five-field cron expression (minute,
+// hour, day of month, month and day of week, each *, a value, a range, a
+// list or a step such as */15), @hourly, @daily, @weekly, @monthly or
+// @yearly, or @every followed by a duration of at least a minute, such as
+// "@every 90m". Cron schedules use the server's local time.
+func ParseSchedule(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+		}
+		if every < minEvery {
+			return nil, fmt.Errorf("invalid schedule %q: the interval must be at least %s", expr, minEvery)
+		}
+		return &Schedule{every: every}, nil
+	}
+	if shorthand, ok := cronShorthands[strings.ToLower(expr)]; ok {
+		expr = shorthand
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day month weekday), @daily or @every <duration>", expr)
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	// Sunday may be written as 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Schedule{
+		minute:     bits[0],
+		hour:       bits[1],
+		day:        bits[2],
+		month:      bits[3],
+		weekday:    bits[4],
+		dayAny:     fields[2] == "*",
+		weekdayAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses one field of a cron expression into a bit set
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s: invalid step %q", f.name, stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = cronValue(from, f); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(to, f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: range %q is backwards", f.name, rangePart)
+			}
+		default:
+			n, err := cronValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			lo = n
+			if !hasStep {
+				hi = n
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// cronValue parses a value of a cron field
+func cronValue(s string, f cronField) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: %q is not a number from %d to %d", f.name, s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// Next returns the first time the schedule matches after t, or the zero
+// time if it never does
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// A daylight saving change can map the next hour back onto the
+		// current one
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the cron rule for the day fields: when both the day
+// of month and the day of week are restricted, either may match
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day := s.day&(1<<uint(t.Day())) != 0
+	weekday := s.weekday&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.dayAny && s.weekdayAny:
+		return true
+	case s.dayAny:
+		return weekday
+	case s.weekdayAny:
+		return day
+	}
+	return day || weekday
+}
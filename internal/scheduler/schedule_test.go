@@ -0,0 +1,101 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every 30s",
+		"@every soon",
+		"@fortnightly",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2025, 6, 4, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 6, 4, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 6, 4, 10, 30, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2025, 6, 4, 10, 25, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2025, 6, 5, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 6, 5, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 6, 4, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2025, 6, 5, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 6 1,15 * *", time.Date(2025, 6, 15, 6, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted: the 10th or a
+		// Friday
+		{"0 0 10 * 5", time.Date(2025, 6, 6, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2025, 6, 4, 11, 47, 30, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNextNever(t *testing.T) {
+	s, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+	if next := s.Next(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Errorf("Next of February 30 = %s, want the zero time", next)
+	}
+}
+
+func TestScheduleNextDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	s, err := ParseSchedule("30 2 * * *")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+	// 2:30 does not exist on 9 March 2025; the run moves to the next day
+	next := s.Next(time.Date(2025, 3, 9, 0, 0, 0, 0, loc))
+	if next.IsZero() || next.Day() != 10 || next.Hour() != 2 || next.Minute() != 30 {
+		t.Errorf("Next across the spring change = %s, want 2:30 on 10 March", next)
+	}
+}
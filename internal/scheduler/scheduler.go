@@ -0,0 +1,590 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package scheduler runs scheduled jobs: a saved query, or a pipeline of
+// tool calls, run on a cron schedule. Jobs come from the configuration or
+// are added through the API; the result of each run is kept in the job's
+// history in the data directory and can be posted to a webhook.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/notify"
+)
+
+const (
+	// DefaultHistorySize is the number of runs kept per job when the
+	// configuration does not set one
+	DefaultHistorySize = 100
+
+	// DefaultTimeout bounds a run of a job without a timeout
+	DefaultTimeout = 5 * time.Minute
+
+	// maxOutputBytes bounds the output kept for a run
+	maxOutputBytes = 64 * 1024
+
+	// maxSleep bounds the scheduler's wait, so a change of the system clock
+	// delays a job by at most this long
+	maxSleep = time.Minute
+)
+
+// Job sources
+const (
+	SourceConfig = "config" // Defined in the configuration file
+	SourceAPI    = "api"    // Added through the API, stored in the data directory
+)
+
+// Run statuses
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	// ErrNotFound is returned for a job name that is not defined
+	ErrNotFound = errors.New("job not found")
+
+	// ErrConfigJob is returned when the API changes a job defined in the
+	// configuration
+	ErrConfigJob = errors.New("job is defined in the configuration file")
+
+	// ErrRunning is returned when a job is run while its last run has not
+	// finished
+	ErrRunning = errors.New("job is already running")
+
+	// ErrNotStarted is returned when a job is run before the scheduler
+	// started
+	ErrNotStarted = errors.New("scheduler is not running")
+)
+
+// validName matches job names, which are also file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// Step is a tool call of a job's pipeline
+type Step struct {
+	Tool string                 `json:"tool"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// Job is a query or a pipeline of tool calls run on a schedule
+type Job struct {
+	Name           string `json:"name"`
+	Schedule       string `json:"schedule"`
+	Database       string `json:"database,omitempty"` // Empty = the first database
+	SQL            string `json:"sql,omitempty"`      // Run with query_database
+	Steps          []Step `json:"steps,omitempty"`    // Run in order instead of SQL
+	Webhook        string `json:"webhook,omitempty"`  // URL each run's result is posted to
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	Disabled       bool   `json:"disabled,omitempty"` // Run only when asked to
+	Source         string `json:"source,omitempty"`
+}
+
+// Pipeline returns the tool calls of a job; a query is run with
+// query_database
+func (j *Job) Pipeline() []Step {
+	if strings.TrimSpace(j.SQL) != "" {
+		return []Step{{Tool: "query_database", Args: map[string]interface{}{"query": j.SQL}}}
+	}
+	return j.Steps
+}
+
+// Validate checks that a job can be scheduled and run
+func (j *Job) Validate() error {
+	if !validName.MatchString(j.Name) {
+		return fmt.Errorf("invalid job name %q: use up to 64 letters, digits, '_' or '-', starting with a letter or digit", j.Name)
+	}
+	if _, err := ParseSchedule(j.Schedule); err != nil {
+		return fmt.Errorf("job %q: %w", j.Name, err)
+	}
+	hasSQL := strings.TrimSpace(j.SQL) != ""
+	if hasSQL == (len(j.Steps) > 0) {
+		return fmt.Errorf("job %q: set either sql or steps", j.Name)
+	}
+	for i, step := range j.Steps {
+		if strings.TrimSpace(step.Tool) == "" {
+			return fmt.Errorf("job %q: step %d has no tool", j.Name, i+1)
+		}
+	}
+	if j.Webhook != "" {
+		u, err := url.Parse(j.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("job %q: webhook must be an http or https URL", j.Name)
+		}
+	}
+	if j.TimeoutSeconds < 0 {
+		return fmt.Errorf("job %q: timeout_seconds must be zero or positive", j.Name)
+	}
+	return nil
+}
+
+// timeout returns the bound on a run of the job
+func (j *Job) timeout() time.Duration {
+	if j.TimeoutSeconds > 0 {
+		return time.Duration(j.TimeoutSeconds) * time.Second
+	}
+	return DefaultTimeout
+}
+
+// Run is the outcome of a run of a job
+type Run struct {
+	Job          string    `json:"job"`
+	Trigger      string    `json:"trigger"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Status       string    `json:"status"`
+	Output       string    `json:"output,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	WebhookError string    `json:"webhook_error,omitempty"`
+}
+
+// Duration returns how long the run took
+func (r *Run) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// JobStatus is a job with its next and last runs
+type JobStatus struct {
+	Job     Job       `json:"job"`
+	NextRun time.Time `json:"next_run,omitempty"` // Zero when disabled or not scheduled
+	Running bool      `json:"running"`
+	LastRun *Run      `json:"last_run,omitempty"`
+}
+
+// Executor runs a tool for a job in a database and returns the tool's text;
+// isError is set when the tool reported an error
+type Executor func(ctx context.Context, database, tool string, args map[string]interface{}) (text string, isError bool, err error)
+
+// Config configures a Scheduler
+type Config struct {
+	Jobs    []Job    // Jobs from the configuration file
+	Store   *Store   // Keeps API jobs and the history of runs
+	Execute Executor // Runs the tools of a job
+
+	// Post sends a run's result to a webhook (nil = POST it as JSON);
+	// replaceable for tests
+	Post func(ctx context.Context, url string, run *Run) error
+
+	// Now returns the current time (nil = time.Now); replaceable for tests
+	Now func() time.Time
+}
+
+// jobState is a job with its schedule and runs
+type jobState struct {
+	job      Job
+	schedule *Schedule
+	next     time.Time
+	running  bool
+	last     *Run
+}
+
+// Scheduler runs jobs on their schedules
+type Scheduler struct {
+	cfg  Config
+	wake chan struct{}
+
+	mu     sync.Mutex
+	jobs   map[string]*jobState
+	runCtx context.Context // Set while Run runs
+	wg     sync.WaitGroup
+}
+
+// New creates a scheduler with the configured jobs and those stored by the
+// API; call Run to start it. A stored job named like a configured job is
+// ignored.
+func New(cfg Config) (*Scheduler, error) {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Post == nil {
+		cfg.Post = postWebhook
+	}
+	s := &Scheduler{
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+		jobs: make(map[string]*jobState),
+	}
+
+	stored, err := cfg.Store.LoadJobs()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range stored {
+		if err := s.addLocked(job); err != nil {
+			logging.Warn("scheduled_job_skipped", "job", job.Name, "error", err)
+		}
+	}
+	s.SetConfigJobs(cfg.Jobs)
+	return s, nil
+}
+
+// shared is the scheduler the tools report on, set with SetShared
+var shared atomic.Pointer[Scheduler]
+
+// SetShared sets the scheduler that list_jobs reports on; nil reports that
+// scheduling is disabled
+func SetShared(s *Scheduler) {
+	shared.Store(s)
+}
+
+// Shared returns the scheduler set with SetShared, or nil
+func Shared() *Scheduler {
+	return shared.Load()
+}
+
+// addLocked adds or replaces a job, keeping the last run of a job that is
+// replaced. The caller must hold s.mu, or own s before New returns.
+func (s *Scheduler) addLocked(job Job) error {
+	if err := job.Validate(); err != nil {
+		return err
+	}
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return err
+	}
+	state := &jobState{job: job, schedule: schedule}
+	if previous, ok := s.jobs[job.Name]; ok {
+		state.running = previous.running
+		state.last = previous.last
+	} else if runs, err := s.cfg.Store.Runs(job.Name, 1); err == nil && len(runs) > 0 {
+		state.last = &runs[0]
+	}
+	if !job.Disabled {
+		state.next = schedule.Next(s.cfg.Now())
+	}
+	s.jobs[job.Name] = state
+	return nil
+}
+
+// SetConfigJobs replaces the jobs from the configuration file, after a
+// reload. Jobs are validated when the configuration is loaded; invalid ones
+// are logged and skipped.
+func (s *Scheduler) SetConfigJobs(jobs []Job) {
+	s.mu.Lock()
+	for name, state := range s.jobs {
+		if state.job.Source == SourceConfig {
+			delete(s.jobs, name)
+		}
+	}
+	for _, job := range jobs {
+		job.Source = SourceConfig
+		if err := s.addLocked(job); err != nil {
+			logging.Warn("scheduled_job_skipped", "job", job.Name, "error", err)
+		}
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+// SaveJob adds a job through the API, or replaces one added through the
+// API, and stores the API's jobs
+func (s *Scheduler) SaveJob(job Job) error {
+	job.Source = SourceAPI
+	if err := job.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.jobs[job.Name]; ok && existing.job.Source == SourceConfig {
+		return fmt.Errorf("%w: %s", ErrConfigJob, job.Name)
+	}
+	previous, existed := s.jobs[job.Name]
+	if err := s.addLocked(job); err != nil {
+		return err
+	}
+	if err := s.saveLocked(); err != nil {
+		if existed {
+			s.jobs[job.Name] = previous
+		} else {
+			delete(s.jobs, job.Name)
+		}
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// DeleteJob removes a job added through the API, and its history
+func (s *Scheduler) DeleteJob(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if state.job.Source == SourceConfig {
+		return fmt.Errorf("%w: %s", ErrConfigJob, name)
+	}
+	delete(s.jobs, name)
+	if err := s.saveLocked(); err != nil {
+		s.jobs[name] = state
+		return err
+	}
+	return s.cfg.Store.DeleteRuns(name)
+}
+
+// saveLocked stores the jobs added through the API
+// The caller must hold s.mu
+func (s *Scheduler) saveLocked() error {
+	var jobs []Job
+	for _, state := range s.jobs {
+		if state.job.Source == SourceAPI {
+			jobs = append(jobs, state.job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return s.cfg.Store.SaveJobs(jobs)
+}
+
+// Jobs returns every job with its next and last runs, sorted by name
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, state := range s.jobs {
+		statuses = append(statuses, state.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Job.Name < statuses[j].Job.Name })
+	return statuses
+}
+
+// Job returns a job with its next and last runs
+func (s *Scheduler) Job(name string) (JobStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.jobs[name]
+	if !ok {
+		return JobStatus{}, false
+	}
+	return state.status(), true
+}
+
+// status describes a job
+func (state *jobState) status() JobStatus {
+	status := JobStatus{Job: state.job, NextRun: state.next, Running: state.running}
+	if state.last != nil {
+		last := *state.last
+		status.LastRun = &last
+	}
+	return status
+}
+
+// History returns a job's newest runs, newest first
+func (s *Scheduler) History(name string, limit int) ([]Run, error) {
+	if _, ok := s.Job(name); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return s.cfg.Store.Runs(name, limit)
+}
+
+// RunNow starts a run of a job in the background, whether or not it is
+// disabled
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.jobs[name]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	case s.runCtx == nil:
+		return ErrNotStarted
+	case state.running:
+		return fmt.Errorf("%w: %s", ErrRunning, name)
+	}
+	s.startLocked(s.runCtx, state, TriggerManual)
+	return nil
+}
+
+// notify wakes the scheduler to recompute its wait
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the jobs on their schedules until ctx is cancelled, then waits
+// for running jobs, which are cancelled too
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.runCtx = ctx
+	s.mu.Unlock()
+
+	for {
+		wait := s.StartDue(ctx)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.mu.Lock()
+			s.runCtx = nil
+			s.mu.Unlock()
+			s.wg.Wait()
+			return
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// StartDue starts the jobs whose time has come and returns how long to wait
+// for the next one. A job still running when its time comes again skips
+// that run.
+func (s *Scheduler) StartDue(ctx context.Context) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.cfg.Now()
+	wait := maxSleep
+	for _, state := range s.jobs {
+		if state.next.IsZero() {
+			continue
+		}
+		if !state.next.After(now) {
+			if state.running {
+				logging.Warn("scheduled_job_skipped", "job", state.job.Name, "error", "the previous run has not finished")
+			} else {
+				s.startLocked(ctx, state, TriggerSchedule)
+			}
+			state.next = state.schedule.Next(now)
+			if state.next.IsZero() {
+				continue
+			}
+		}
+		if d := state.next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// startLocked runs a job in the background
+// The caller must hold s.mu
+func (s *Scheduler) startLocked(ctx context.Context, state *jobState, trigger string) {
+	state.running = true
+	job := state.job
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		run := s.execute(ctx, job, trigger)
+
+		s.mu.Lock()
+		state.running = false
+		state.last = run
+		s.mu.Unlock()
+	}()
+}
+
+// execute runs a job's pipeline, posts the result to its webhook, records
+// it in the history and sends a notification when it fails
+func (s *Scheduler) execute(ctx context.Context, job Job, trigger string) *Run {
+	run := &Run{Job: job.Name, Trigger: trigger, StartedAt: s.cfg.Now(), Status: StatusOK}
+	runCtx, cancel := context.WithTimeout(ctx, job.timeout())
+	defer cancel()
+
+	var output strings.Builder
+	steps := job.Pipeline()
+	for i, step := range steps {
+		if len(steps) > 1 {
+			output.WriteString(fmt.Sprintf("## %d. %s\n", i+1, step.Tool))
+		}
+		text, isError, err := s.cfg.Execute(runCtx, job.Database, step.Tool, step.Args)
+		output.WriteString(text)
+		output.WriteString("\n")
+		if err == nil && isError {
+			err = errors.New(lastLine(text))
+		}
+		if err != nil {
+			run.Status = StatusError
+			run.Error = fmt.Sprintf("%s: %v", step.Tool, err)
+			if runCtx.Err() == context.DeadlineExceeded {
+				run.Error = fmt.Sprintf("%s: timed out after %s", step.Tool, job.timeout())
+			}
+			break
+		}
+	}
+	run.Output = truncateOutput(strings.TrimSpace(output.String()))
+	run.FinishedAt = s.cfg.Now()
+
+	if job.Webhook != "" {
+		postCtx, cancel := context.WithTimeout(ctx, webhookTimeout)
+		if err := s.cfg.Post(postCtx, job.Webhook, run); err != nil {
+			run.WebhookError = err.Error()
+			logging.Warn("scheduled_job_webhook_failed", "job", job.Name, "error", err)
+		}
+		cancel()
+	}
+
+	if err := s.cfg.Store.AppendRun(run); err != nil {
+		logging.Warn("scheduled_job_history_failed", "job", job.Name, "error", err)
+	}
+	logging.Info("scheduled_job_completed",
+		"job", job.Name,
+		"trigger", trigger,
+		"status", run.Status,
+		"duration_ms", run.Duration().Milliseconds(),
+		"error", run.Error,
+	)
+	if run.Status == StatusError {
+		fields := map[string]string{"job": job.Name, "trigger": trigger}
+		if job.Database != "" {
+			fields["database"] = job.Database
+		}
+		notify.Send(notify.Event{
+			Category: notify.CategoryJobs,
+			Severity: notify.SeverityWarning,
+			Title:    fmt.Sprintf("Scheduled job %s failed", job.Name),
+			Message:  run.Error,
+			Fields:   fields,
+		})
+	}
+	return run
+}
+
+// lastLine returns the last non-empty line of a text
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// truncateOutput shortens a run's output to maxOutputBytes, on a line
+// boundary where possible
+func truncateOutput(s string) string {
+	if len(s) <= maxOutputBytes {
+		return s
+	}
+	cut := s[:maxOutputBytes]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	return fmt.Sprintf("%s\n... (output truncated; %d bytes in total)", cut, len(s))
+}
@@ -0,0 +1,310 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTools records the tool calls of jobs and answers them
+type fakeTools struct {
+	mu    sync.Mutex
+	calls []string
+	fail  map[string]bool
+}
+
+func (f *fakeTools) execute(ctx context.Context, database, tool string, args map[string]interface{}) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf("%s@%s", tool, database))
+	if f.fail[tool] {
+		return "relation \"missing\" does not exist", true, nil
+	}
+	return fmt.Sprintf("%s ran %v", tool, args), false, nil
+}
+
+func newTestScheduler(t *testing.T, jobs []Job, tools *fakeTools, now *time.Time) *Scheduler {
+	t.Helper()
+	s, err := New(Config{
+		Jobs:    jobs,
+		Store:   NewStore(t.TempDir(), 3),
+		Execute: tools.execute,
+		Post:    func(ctx context.Context, url string, run *Run) error { return nil },
+		Now:     func() time.Time { return *now },
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return s
+}
+
+func TestJobValidate(t *testing.T) {
+	valid := Job{Name: "nightly_bloat", Schedule: "0 2 * * *", SQL: "SELECT 1"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid job rejected: %v", err)
+	}
+	tests := []struct {
+		name string
+		job  Job
+	}{
+		{"bad name", Job{Name: "../etc", Schedule: "@daily", SQL: "SELECT 1"}},
+		{"bad schedule", Job{Name: "a", Schedule: "daily", SQL: "SELECT 1"}},
+		{"no work", Job{Name: "a", Schedule: "@daily"}},
+		{"sql and steps", Job{Name: "a", Schedule: "@daily", SQL: "SELECT 1", Steps: []Step{{Tool: "get_schema_info"}}}},
+		{"step without tool", Job{Name: "a", Schedule: "@daily", Steps: []Step{{}}}},
+		{"bad webhook", Job{Name: "a", Schedule: "@daily", SQL: "SELECT 1", Webhook: "ftp://example.com"}},
+		{"negative timeout", Job{Name: "a", Schedule: "@daily", SQL: "SELECT 1", TimeoutSeconds: -1}},
+	}
+	for _, tt := range tests {
+		if err := tt.job.Validate(); err == nil {
+			t.Errorf("%s: Validate succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestStartDue(t *testing.T) {
+	now := time.Date(2025, 6, 4, 1, 59, 0, 0, time.UTC)
+	tools := &fakeTools{}
+	s := newTestScheduler(t, []Job{
+		{Name: "nightly", Schedule: "0 2 * * *", Database: "main", SQL: "SELECT 1"},
+		{Name: "paused", Schedule: "* * * * *", SQL: "SELECT 2", Disabled: true},
+	}, tools, &now)
+
+	if wait := s.StartDue(context.Background()); wait != time.Minute {
+		t.Errorf("wait before the first run = %s, want 1m", wait)
+	}
+	if len(tools.calls) != 0 {
+		t.Fatalf("jobs ran before their time: %v", tools.calls)
+	}
+
+	now = now.Add(time.Minute)
+	s.StartDue(context.Background())
+	s.wg.Wait()
+	if len(tools.calls) != 1 || tools.calls[0] != "query_database@main" {
+		t.Fatalf("calls = %v, want one query_database@main", tools.calls)
+	}
+
+	status, ok := s.Job("nightly")
+	if !ok {
+		t.Fatal("job not found")
+	}
+	if want := time.Date(2025, 6, 5, 2, 0, 0, 0, time.UTC); !status.NextRun.Equal(want) {
+		t.Errorf("next run = %s, want %s", status.NextRun, want)
+	}
+	if status.LastRun == nil || status.LastRun.Status != StatusOK || status.LastRun.Trigger != TriggerSchedule {
+		t.Errorf("unexpected last run: %+v", status.LastRun)
+	}
+	if paused, _ := s.Job("paused"); !paused.NextRun.IsZero() {
+		t.Errorf("disabled job is scheduled for %s", paused.NextRun)
+	}
+
+	runs, err := s.History("nightly", 10)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("History = %v, %v; want one run", runs, err)
+	}
+	if !strings.Contains(runs[0].Output, "SELECT 1") {
+		t.Errorf("output not recorded: %q", runs[0].Output)
+	}
+}
+
+func TestPipelineStopsAtError(t *testing.T) {
+	now := time.Date(2025, 6, 4, 0, 0, 0, 0, time.UTC)
+	tools := &fakeTools{fail: map[string]bool{"query_database": true}}
+	var posted *Run
+	s, err := New(Config{
+		Jobs: []Job{{Name: "report", Schedule: "@daily", Webhook: "https://hooks.example.com/x", Steps: []Step{
+			{Tool: "get_schema_info"},
+			{Tool: "query_database", Args: map[string]interface{}{"query": "SELECT * FROM missing"}},
+			{Tool: "list_jobs"},
+		}}},
+		Store:   NewStore(t.TempDir(), 0),
+		Execute: tools.execute,
+		Post: func(ctx context.Context, url string, run *Run) error {
+			posted = run
+			return errors.New("webhook returned status 500")
+		},
+		Now: func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	run := s.execute(context.Background(), s.jobs["report"].job, TriggerManual)
+	if len(tools.calls) != 2 {
+		t.Errorf("calls = %v, want the pipeline to stop at the failing step", tools.calls)
+	}
+	if run.Status != StatusError || !strings.Contains(run.Error, "does not exist") {
+		t.Errorf("unexpected run: %+v", run)
+	}
+	if !strings.Contains(run.Output, "## 1. get_schema_info") || !strings.Contains(run.Output, "## 2. query_database") {
+		t.Errorf("steps not labelled in output: %q", run.Output)
+	}
+	if posted == nil || run.WebhookError != "webhook returned status 500" {
+		t.Errorf("webhook not reported: posted=%v error=%q", posted, run.WebhookError)
+	}
+}
+
+func TestAPIJobs(t *testing.T) {
+	now := time.Date(2025, 6, 4, 0, 0, 0, 0, time.UTC)
+	store := NewStore(t.TempDir(), 0)
+	config := []Job{{Name: "from_config", Schedule: "@daily", SQL: "SELECT 1"}}
+	s, err := New(Config{Jobs: config, Store: store, Execute: (&fakeTools{}).execute, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if err := s.SaveJob(Job{Name: "from_config", Schedule: "@hourly", SQL: "SELECT 2"}); !errors.Is(err, ErrConfigJob) {
+		t.Errorf("replacing a configured job: err = %v, want ErrConfigJob", err)
+	}
+	if err := s.DeleteJob("from_config"); !errors.Is(err, ErrConfigJob) {
+		t.Errorf("deleting a configured job: err = %v, want ErrConfigJob", err)
+	}
+	if err := s.SaveJob(Job{Name: "hourly", Schedule: "@hourly", SQL: "SELECT 2"}); err != nil {
+		t.Fatalf("SaveJob failed: %v", err)
+	}
+	if err := s.RunNow("hourly"); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("RunNow before Run: err = %v, want ErrNotStarted", err)
+	}
+
+	// A new scheduler on the same store loads the API's jobs
+	reloaded, err := New(Config{Jobs: config, Store: store, Execute: (&fakeTools{}).execute, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	jobs := reloaded.Jobs()
+	if len(jobs) != 2 || jobs[0].Job.Name != "from_config" || jobs[1].Job.Name != "hourly" || jobs[1].Job.Source != SourceAPI {
+		t.Fatalf("unexpected jobs after reload: %+v", jobs)
+	}
+
+	// A reload of the configuration keeps the API's jobs
+	reloaded.SetConfigJobs(nil)
+	if jobs := reloaded.Jobs(); len(jobs) != 1 || jobs[0].Job.Name != "hourly" {
+		t.Errorf("unexpected jobs after a configuration reload: %+v", jobs)
+	}
+
+	if err := reloaded.DeleteJob("hourly"); err != nil {
+		t.Fatalf("DeleteJob failed: %v", err)
+	}
+	if err := reloaded.DeleteJob("hourly"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a missing job: err = %v, want ErrNotFound", err)
+	}
+	if stored, _ := store.LoadJobs(); len(stored) != 0 {
+		t.Errorf("deleted job still stored: %+v", stored)
+	}
+}
+
+func TestRunNow(t *testing.T) {
+	now := time.Date(2025, 6, 4, 0, 0, 0, 0, time.UTC)
+	tools := &fakeTools{}
+	s := newTestScheduler(t, []Job{{Name: "manual", Schedule: "@yearly", SQL: "SELECT 1", Disabled: true}}, tools, &now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := s.RunNow("manual")
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrNotStarted) || time.Now().After(deadline) {
+			t.Fatalf("RunNow failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	runs, err := s.History("manual", 0)
+	if err != nil || len(runs) != 1 || runs[0].Trigger != TriggerManual {
+		t.Errorf("History = %+v, %v; want one manual run", runs, err)
+	}
+}
+
+func TestStoreKeepsNewestRuns(t *testing.T) {
+	store := NewStore(t.TempDir(), 3)
+	start := time.Date(2025, 6, 4, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		run := &Run{Job: "nightly", StartedAt: start.Add(time.Duration(i) * time.Hour), Status: StatusOK}
+		if err := store.AppendRun(run); err != nil {
+			t.Fatalf("AppendRun failed: %v", err)
+		}
+	}
+
+	runs, err := store.Runs("nightly", 0)
+	if err != nil {
+		t.Fatalf("Runs failed: %v", err)
+	}
+	if len(runs) != 3 || runs[0].StartedAt.Hour() != 9 || runs[2].StartedAt.Hour() != 7 {
+		t.Errorf("unexpected runs: %+v", runs)
+	}
+	if runs, _ := store.Runs("nightly", 1); len(runs) != 1 || runs[0].StartedAt.Hour() != 9 {
+		t.Errorf("limited runs: %+v", runs)
+	}
+	if runs, err := store.Runs("never_ran", 5); err != nil || len(runs) != 0 {
+		t.Errorf("runs of a job without history = %v, %v", runs, err)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	short := "a\nb"
+	if got := truncateOutput(short); got != short {
+		t.Errorf("short output changed: %q", got)
+	}
+	long := strings.Repeat("0123456789\n", maxOutputBytes/5)
+	got := truncateOutput(long)
+	if len(got) > maxOutputBytes+100 || !strings.HasSuffix(got, fmt.Sprintf("(output truncated; %d bytes in total)", len(long))) {
+		t.Errorf("long output not truncated: %d bytes, ending %q", len(got), got[len(got)-60:])
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	start := time.Date(2025, 6, 4, 2, 0, 0, 0, time.UTC)
+	run := &Run{Job: "nightly", Trigger: TriggerSchedule, StartedAt: start, FinishedAt: start.Add(1500 * time.Millisecond),
+		Status: StatusOK, Output: "relname\tbloat\norders\t42%"}
+	if err := postWebhook(context.Background(), server.URL+"/ok", run); err != nil {
+		t.Fatalf("postWebhook failed: %v", err)
+	}
+	if payload["job"] != "nightly" || payload["status"] != StatusOK || payload["duration_ms"] != float64(1500) {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+	if text, _ := payload["text"].(string); !strings.HasPrefix(text, "Job nightly: ok in 1.5s") || !strings.Contains(text, "orders\t42%") {
+		t.Errorf("unexpected text: %q", text)
+	}
+
+	if err := postWebhook(context.Background(), server.URL+"/fail", run); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("failed delivery: err = %v, want status 502", err)
+	}
+}
@@ -0,0 +1,221 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package scheduler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// jobsFile holds the jobs added through the API
+const jobsFile = "jobs.json"
+
+// historyDir holds a file of runs for each job
+const historyDir = "history"
+
+// Store keeps the jobs added through the API and the history of each job's
+// runs in a directory, normally jobs under the data directory. Each job's
+// runs are appended to history/<name>.jsonl, one JSON object per line, and
+// the file is cut back to the newest runs as it grows.
+type Store struct {
+	Dir         string
+	HistorySize int // Runs kept per job (0 = DefaultHistorySize)
+
+	mu sync.Mutex
+}
+
+// NewStore returns the store in a directory
+func NewStore(dir string, historySize int) *Store {
+	return &Store{Dir: dir, HistorySize: historySize}
+}
+
+// historySize returns the runs kept per job
+func (s *Store) historySize() int {
+	if s.HistorySize > 0 {
+		return s.HistorySize
+	}
+	return DefaultHistorySize
+}
+
+// LoadJobs returns the jobs added through the API
+func (s *Store) LoadJobs() ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(s.Dir, jobsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("jobs file is corrupt: %w", err)
+	}
+	for i := range jobs {
+		jobs[i].Source = SourceAPI
+	}
+	return jobs, nil
+}
+
+// SaveJobs replaces the jobs added through the API. The file is written to
+// a temporary name first, so a failed write never leaves partial jobs.
+func (s *Store) SaveJobs(jobs []Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if jobs == nil {
+		jobs = []Job{}
+	}
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode jobs: %w", err)
+	}
+	return writeFileAtomic(s.Dir, jobsFile, data)
+}
+
+// historyPath returns the history file of a job
+func (s *Store) historyPath(name string) string {
+	return filepath.Join(s.Dir, historyDir, name+".jsonl")
+}
+
+// AppendRun adds a run to its job's history, keeping the newest runs
+func (s *Store) AppendRun(run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(s.Dir, historyDir), 0700); err != nil {
+		return fmt.Errorf("failed to create job history directory: %w", err)
+	}
+	path := s.historyPath(run.Job)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open job history: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close() //nolint:errcheck // the write error is reported
+		return fmt.Errorf("failed to write job history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write job history: %w", err)
+	}
+
+	// Rewriting the file once it holds twice the runs kept keeps appends
+	// cheap
+	runs, err := readRuns(path)
+	if err != nil || len(runs) <= 2*s.historySize() {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, r := range runs[len(runs)-s.historySize():] {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to encode run: %w", err)
+		}
+		buf.Write(append(data, '\n'))
+	}
+	return writeFileAtomic(filepath.Join(s.Dir, historyDir), run.Job+".jsonl", buf.Bytes())
+}
+
+// Runs returns a job's newest runs, newest first; limit <= 0 returns all
+// that are kept
+func (s *Store) Runs(name string, limit int) ([]Run, error) {
+	s.mu.Lock()
+	runs, err := readRuns(s.historyPath(name))
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if max := s.historySize(); len(runs) > max {
+		runs = runs[len(runs)-max:]
+	}
+	if limit > 0 && len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	return runs, nil
+}
+
+// DeleteRuns removes a job's history
+func (s *Store) DeleteRuns(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.historyPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove job history: %w", err)
+	}
+	return nil
+}
+
+// readRuns reads a history file, oldest run first. Lines that cannot be
+// decoded, such as one cut short by a crash, are skipped.
+func readRuns(path string) ([]Run, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job history: %w", err)
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*maxOutputBytes)
+	for scanner.Scan() {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err == nil {
+			runs = append(runs, run)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read job history: %w", err)
+	}
+	return runs, nil
+}
+
+// writeFileAtomic replaces a file in a directory by writing a temporary
+// file and renaming it
+func writeFileAtomic(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-"+name+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() //nolint:errcheck // the write error is reported
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	return nil
+}
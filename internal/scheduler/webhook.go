@@ -0,0 +1,96 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"pgedge-postgres-mcp/internal/netproxy"
+)
+
+// webhookTimeout bounds the delivery of a run's result to a webhook
+const webhookTimeout = 15 * time.Second
+
+// maxWebhookText bounds the output included in the text of a webhook
+// message, which chat services show as the message
+const maxWebhookText = 3000
+
+// webhookPayload is the JSON posted to a job's webhook. text summarizes the
+// run for services such as Slack and Mattermost that show it as a message.
+type webhookPayload struct {
+	Text       string    `json:"text"`
+	Job        string    `json:"job"`
+	Trigger    string    `json:"trigger"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// newWebhookPayload describes a run for a webhook
+func newWebhookPayload(run *Run) webhookPayload {
+	text := fmt.Sprintf("Job %s: %s in %s", run.Job, run.Status, run.Duration().Round(time.Millisecond))
+	if run.Error != "" {
+		text += "\n" + run.Error
+	}
+	if run.Output != "" {
+		output := run.Output
+		if len(output) > maxWebhookText {
+			output = output[:maxWebhookText] + "\n..."
+		}
+		text += "\n```\n" + output + "\n```"
+	}
+	return webhookPayload{
+		Text:       text,
+		Job:        run.Job,
+		Trigger:    run.Trigger,
+		Status:     run.Status,
+		StartedAt:  run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		DurationMS: run.Duration().Milliseconds(),
+		Output:     run.Output,
+		Error:      run.Error,
+	}
+}
+
+// postWebhook posts a run's result as JSON; responses other than 2xx are
+// errors. Requests go through the general outbound proxy, if one is set.
+func postWebhook(ctx context.Context, url string, run *Run) error {
+	body, err := json.Marshal(newWebhookPayload(run))
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := netproxy.NewClient(netproxy.ProviderWebhook, webhookTimeout).Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) //nolint:errcheck // drained so the connection can be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package search
+
+import "sort"
+
+// DefaultRRFConstant is the rank constant k commonly used for reciprocal
+// rank fusion; larger values flatten the difference between top ranks
+const DefaultRRFConstant = 60
+
+// RankedList is one retriever's results, best first
+type RankedList struct {
+	Name   string   // Retriever name, such as "full_text" or "vector"
+	Weight float64  // Multiplier for the retriever's contribution (0 = 1.0)
+	IDs    []string // Result identifiers in rank order
+}
+
+// FusedResult is a result with its combined score
+type FusedResult struct {
+	ID    string
+	Score float64        // Sum of weight / (k + rank) over the lists
+	Ranks map[string]int // 1-based rank in each list that returned the result
+}
+
+// FuseRankings merges ranked lists with reciprocal rank fusion: a result
+// scores weight / (k + rank) in each list that returned it, so results
+// found by several retrievers rise above those found by one. Only ranks are
+// used, which makes scores from different retrievers (ts_rank, vector
+// distance) comparable without normalization. Results are ordered by score,
+// then by their best rank, then by ID.
+func FuseRankings(lists []RankedList, k int) []FusedResult {
+	if k <= 0 {
+		k = DefaultRRFConstant
+	}
+
+	byID := make(map[string]*FusedResult)
+	var order []string
+	for _, list := range lists {
+		weight := list.Weight
+		if weight == 0 {
+			weight = 1.0
+		}
+		for i, id := range list.IDs {
+			result, ok := byID[id]
+			if !ok {
+				result = &FusedResult{ID: id, Ranks: make(map[string]int)}
+				byID[id] = result
+				order = append(order, id)
+			}
+			if _, seen := result.Ranks[list.Name]; seen {
+				continue // A duplicate within a list keeps its first rank
+			}
+			rank := i + 1
+			result.Ranks[list.Name] = rank
+			result.Score += weight / float64(k+rank)
+		}
+	}
+
+	results := make([]FusedResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *byID[id])
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if bi, bj := bestRank(results[i]), bestRank(results[j]); bi != bj {
+			return bi < bj
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// bestRank returns the result's highest rank in any list
+func bestRank(result FusedResult) int {
+	best := 0
+	for _, rank := range result.Ranks {
+		if best == 0 || rank < best {
+			best = rank
+		}
+	}
+	return best
+}
@@ -0,0 +1,81 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFuseRankings(t *testing.T) {
+	lists := []RankedList{
+		{Name: "full_text", IDs: []string{"a", "b", "c"}},
+		{Name: "vector", IDs: []string{"c", "d", "a"}},
+	}
+
+	results := FuseRankings(lists, 60)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+
+	// a: 1/61 + 1/63, c: 1/63 + 1/61; both beat results found once
+	if results[0].ID != "a" || results[1].ID != "c" {
+		t.Errorf("expected a and c first, got %s and %s", results[0].ID, results[1].ID)
+	}
+	want := 1.0/61 + 1.0/63
+	if math.Abs(results[0].Score-want) > 1e-12 {
+		t.Errorf("expected score %f, got %f", want, results[0].Score)
+	}
+	if results[0].Ranks["full_text"] != 1 || results[0].Ranks["vector"] != 3 {
+		t.Errorf("unexpected ranks for a: %v", results[0].Ranks)
+	}
+
+	// b and d tie on score; both are rank 2 in one list, so the ID decides
+	if results[2].ID != "b" || results[3].ID != "d" {
+		t.Errorf("expected b then d, got %s then %s", results[2].ID, results[3].ID)
+	}
+	if _, ok := results[3].Ranks["full_text"]; ok {
+		t.Error("d should have no full-text rank")
+	}
+}
+
+func TestFuseRankings_Weights(t *testing.T) {
+	lists := []RankedList{
+		{Name: "full_text", Weight: 1, IDs: []string{"a"}},
+		{Name: "vector", Weight: 2, IDs: []string{"b"}},
+	}
+
+	results := FuseRankings(lists, 60)
+	if results[0].ID != "b" {
+		t.Errorf("expected the weighted list to win, got %s", results[0].ID)
+	}
+	if math.Abs(results[0].Score-2.0/61) > 1e-12 {
+		t.Errorf("expected score %f, got %f", 2.0/61, results[0].Score)
+	}
+}
+
+func TestFuseRankings_Defaults(t *testing.T) {
+	results := FuseRankings([]RankedList{{Name: "vector", IDs: []string{"a", "a", "b"}}}, 0)
+
+	if len(results) != 2 {
+		t.Fatalf("expected duplicates to be merged, got %d results", len(results))
+	}
+	if math.Abs(results[0].Score-1.0/float64(DefaultRRFConstant+1)) > 1e-12 {
+		t.Errorf("expected the default k and weight, got score %f", results[0].Score)
+	}
+	if results[1].Ranks["vector"] != 3 {
+		t.Errorf("expected b to keep its position, got rank %d", results[1].Ranks["vector"])
+	}
+
+	if len(FuseRankings(nil, 60)) != 0 {
+		t.Error("expected no results without lists")
+	}
+}
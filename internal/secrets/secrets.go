@@ -0,0 +1,287 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package secrets resolves references to secrets kept outside the
+// configuration file, such as "vault:secret/data/pgedge#password", so
+// passwords and API keys need not be stored in plain text
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/sigv4"
+)
+
+// Reference schemes
+const (
+	SchemeEnv   = "env:"    // env:NAME reads an environment variable
+	SchemeFile  = "file:"   // file:/path reads a file, without surrounding whitespace
+	SchemeVault = "vault:"  // vault:path#field reads a HashiCorp Vault KV secret
+	SchemeAWS   = "aws-sm:" // aws-sm:secret-id[#key] reads an AWS Secrets Manager secret
+)
+
+var schemes = []string{SchemeEnv, SchemeFile, SchemeVault, SchemeAWS}
+
+// requestTimeout bounds each request to a secret manager
+const requestTimeout = 10 * time.Second
+
+// IsReference reports whether value refers to a secret instead of holding
+// it
+func IsReference(value string) bool {
+	for _, scheme := range schemes {
+		if strings.HasPrefix(value, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver reads the secrets that references refer to. Each reference is
+// read once; later lookups are served from memory.
+type Resolver struct {
+	VaultAddr      string            // Vault server URL (VAULT_ADDR)
+	VaultToken     string            // Vault token (VAULT_TOKEN)
+	VaultNamespace string            // Vault Enterprise namespace (VAULT_NAMESPACE, optional)
+	AWSCredentials sigv4.Credentials // AWS credentials (AWS_ACCESS_KEY_ID and related variables)
+	AWSRegion      string            // Region of secrets given by name (AWS_REGION)
+	AWSEndpoint    string            // Secrets Manager endpoint override (AWS_ENDPOINT_URL_SECRETS_MANAGER, optional)
+	Client         *http.Client
+
+	cache map[string]string
+}
+
+// NewResolverFromEnv returns a resolver configured from the standard Vault
+// and AWS environment variables
+func NewResolverFromEnv() *Resolver {
+	return &Resolver{
+		VaultAddr:      os.Getenv("VAULT_ADDR"),
+		VaultToken:     os.Getenv("VAULT_TOKEN"),
+		VaultNamespace: os.Getenv("VAULT_NAMESPACE"),
+		AWSCredentials: sigv4.CredentialsFromEnv(),
+		AWSRegion:      sigv4.RegionFromEnv(),
+		AWSEndpoint:    os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER"),
+		Client:         &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Resolve returns the secret value refers to, or value itself if it is
+// not a reference
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	if secret, ok := r.cache[value]; ok {
+		return secret, nil
+	}
+
+	var secret string
+	var err error
+	switch {
+	case strings.HasPrefix(value, SchemeEnv):
+		secret, err = resolveEnv(strings.TrimPrefix(value, SchemeEnv))
+	case strings.HasPrefix(value, SchemeFile):
+		secret, err = resolveFile(strings.TrimPrefix(value, SchemeFile))
+	case strings.HasPrefix(value, SchemeVault):
+		secret, err = r.resolveVault(ctx, strings.TrimPrefix(value, SchemeVault))
+	case strings.HasPrefix(value, SchemeAWS):
+		secret, err = r.resolveAWS(ctx, strings.TrimPrefix(value, SchemeAWS))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if r.cache == nil {
+		r.cache = make(map[string]string)
+	}
+	r.cache[value] = secret
+	return secret, nil
+}
+
+// resolveEnv reads an environment variable, which must be set
+func resolveEnv(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("env: reference without a variable name")
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveFile reads a file, expanding a leading ~ to the home directory
+func resolveFile(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("file: reference without a path")
+	}
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// splitField splits "path#field" into its parts
+func splitField(ref string) (string, string) {
+	path, field, _ := strings.Cut(ref, "#")
+	return path, field
+}
+
+// resolveVault reads a field of a Vault secret. Both KV version 1 paths
+// ("secret/pgedge") and version 2 paths ("secret/data/pgedge") are
+// supported.
+func (r *Resolver) resolveVault(ctx context.Context, ref string) (string, error) {
+	path, field := splitField(ref)
+	if path == "" || field == "" {
+		return "", fmt.Errorf("invalid vault reference %q: use vault:path#field", ref)
+	}
+	if r.VaultAddr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is required for vault references")
+	}
+	if r.VaultToken == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is required for vault references")
+	}
+
+	url := strings.TrimSuffix(r.VaultAddr, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", r.VaultToken)
+	if r.VaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.VaultNamespace)
+	}
+
+	body, err := r.do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s: %w", path, err)
+	}
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse vault response: %w", err)
+	}
+	data := response.Data
+	// KV version 2 nests the secret's fields under data.data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nested
+		}
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no string field %q", path, field)
+	}
+	return value, nil
+}
+
+// resolveAWS reads an AWS Secrets Manager secret, or one key of a secret
+// that holds a JSON object. The region is taken from the secret's ARN, if
+// it is given as one.
+func (r *Resolver) resolveAWS(ctx context.Context, ref string) (string, error) {
+	secretID, key := splitField(ref)
+	if secretID == "" {
+		return "", fmt.Errorf("invalid aws-sm reference %q: use aws-sm:secret-id or aws-sm:secret-id#key", ref)
+	}
+	if !r.AWSCredentials.IsSet() {
+		return "", fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for aws-sm references")
+	}
+	region := r.AWSRegion
+	if parts := strings.Split(secretID, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return "", fmt.Errorf("AWS_REGION is required for aws-sm references by name")
+	}
+
+	endpoint := r.AWSEndpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+	}
+	reqBody, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create secrets manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, reqBody, r.AWSCredentials, region, "secretsmanager", time.Now())
+
+	body, err := r.do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read AWS secret %s: %w", secretID, err)
+	}
+
+	var response struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse secrets manager response: %w", err)
+	}
+	if response.SecretString == nil {
+		return "", fmt.Errorf("AWS secret %s has no string value", secretID)
+	}
+	if key == "" {
+		return *response.SecretString, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(*response.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("AWS secret %s is not a JSON object, so key %q cannot be read", secretID, key)
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("AWS secret %s has no string key %q", secretID, key)
+	}
+	return value, nil
+}
+
+// do sends a request and returns the body of a successful response
+func (r *Resolver) do(req *http.Request) ([]byte, error) {
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,165 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/sigv4"
+)
+
+func TestIsReference(t *testing.T) {
+	for value, want := range map[string]bool{
+		"env:DB_PASSWORD":             true,
+		"file:/run/secrets/db":        true,
+		"vault:secret/data/pg#pass":   true,
+		"aws-sm:prod/pgedge#password": true,
+		"plain-password":              false,
+		"":                            false,
+		"https://example.com":         false,
+	} {
+		if got := IsReference(value); got != want {
+			t.Errorf("IsReference(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestResolve_EnvAndFile(t *testing.T) {
+	t.Setenv("PGEDGE_TEST_SECRET", "from-env")
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Resolver{}
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "plain", want: "plain"},
+		{value: "env:PGEDGE_TEST_SECRET", want: "from-env"},
+		{value: "env:PGEDGE_TEST_UNSET_SECRET", wantErr: true},
+		{value: "file:" + path, want: "from-file"},
+		{value: "file:" + path + ".missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(context.Background(), tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Resolve(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolve_Vault(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/pgedge":
+			fmt.Fprint(w, `{"data":{"data":{"password":"kv2-secret"},"metadata":{"version":1}}}`)
+		case "/v1/kv/pgedge":
+			fmt.Fprint(w, `{"data":{"password":"kv1-secret"}}`)
+		default:
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r := &Resolver{VaultAddr: server.URL, VaultToken: "test-token", Client: server.Client()}
+	for value, want := range map[string]string{
+		"vault:secret/data/pgedge#password": "kv2-secret",
+		"vault:kv/pgedge#password":          "kv1-secret",
+	} {
+		got, err := r.Resolve(context.Background(), value)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+
+	// Resolved secrets are cached
+	before := requests
+	if _, err := r.Resolve(context.Background(), "vault:kv/pgedge#password"); err != nil || requests != before {
+		t.Errorf("expected a cached secret, got %v after %d requests", err, requests-before)
+	}
+
+	for _, value := range []string{"vault:secret/data/pgedge#missing", "vault:secret/data/other#password", "vault:secret/data/pgedge"} {
+		if _, err := r.Resolve(context.Background(), value); err == nil {
+			t.Errorf("Resolve(%q): expected an error", value)
+		}
+	}
+	if _, err := (&Resolver{}).Resolve(context.Background(), "vault:kv/pgedge#password"); err == nil || !strings.Contains(err.Error(), "VAULT_ADDR") {
+		t.Errorf("expected an error naming VAULT_ADDR, got %v", err)
+	}
+}
+
+func TestResolve_AWS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.Contains(r.Header.Get("Authorization"), "/secretsmanager/aws4_request") {
+			http.Error(w, `{"message":"bad request"}`, http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			SecretId string
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck // Test server
+		switch req.SecretId {
+		case "prod/plain":
+			fmt.Fprint(w, `{"SecretString":"plain-secret"}`)
+		case "prod/pgedge":
+			fmt.Fprint(w, `{"SecretString":"{\"password\":\"json-secret\"}"}`)
+		default:
+			http.Error(w, `{"message":"Secrets Manager can't find the specified secret."}`, http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	r := &Resolver{
+		AWSCredentials: sigv4.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"},
+		AWSRegion:      "us-east-1",
+		AWSEndpoint:    server.URL,
+		Client:         server.Client(),
+	}
+	for value, want := range map[string]string{
+		"aws-sm:prod/plain":           "plain-secret",
+		"aws-sm:prod/pgedge#password": "json-secret",
+	} {
+		got, err := r.Resolve(context.Background(), value)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"aws-sm:prod/missing", "aws-sm:prod/plain#password", "aws-sm:prod/pgedge#other"} {
+		if _, err := r.Resolve(context.Background(), value); err == nil {
+			t.Errorf("Resolve(%q): expected an error", value)
+		}
+	}
+	if _, err := (&Resolver{}).Resolve(context.Background(), "aws-sm:prod/plain"); err == nil {
+		t.Error("expected an error without AWS credentials")
+	}
+}
@@ -0,0 +1,91 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package sigv4
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exampleCredentials are the credentials of the AWS Signature Version 4
+// test suite
+var exampleCredentials = Credentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSign_TestSuite(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	Sign(req, nil, exampleCredentials, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %s", got)
+	}
+}
+
+func TestSign_SessionToken(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://bedrock-runtime.us-east-1.amazonaws.com/model/example-model/converse", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	creds := exampleCredentials
+	creds.SessionToken = "session-token"
+	Sign(req, []byte("{}"), creds, "us-east-1", "bedrock", time.Now())
+
+	if req.Header.Get("X-Amz-Security-Token") != "session-token" {
+		t.Error("expected the session token header")
+	}
+	if !strings.Contains(req.Header.Get("Authorization"), "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("unexpected signed headers: %s", req.Header.Get("Authorization"))
+	}
+}
+
+func TestCanonicalURI(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/foundation-models", "/foundation-models"},
+		// Escaped characters are encoded again
+		{"/model/anthropic.claude-v2%3A1/converse", "/model/anthropic.claude-v2%253A1/converse"},
+	}
+	for _, tt := range tests {
+		if got := canonicalURI(tt.path); got != tt.want {
+			t.Errorf("canonicalURI(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/?b=2&a=x y&a=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := canonicalQuery(req); got != "a=1&a=x%20y&b=2" {
+		t.Errorf("canonicalQuery() = %q", got)
+	}
+}
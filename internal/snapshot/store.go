@@ -0,0 +1,285 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package snapshot stores schema snapshots: the DDL of a schema, and
+// optionally its data, saved under a name so the schema can be recreated
+// later
+package snapshot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// metadataFile holds a snapshot's description and DDL
+const metadataFile = "snapshot.json"
+
+// ErrTooLarge is returned when a snapshot's data exceeds the size limit
+var ErrTooLarge = errors.New("snapshot data exceeds the size limit")
+
+// ErrNotFound is returned for a snapshot name that is not stored
+var ErrNotFound = errors.New("snapshot not found")
+
+// validName matches snapshot names, which are also directory names
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// Snapshot describes a stored snapshot
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Database  string    `json:"database"` // Sanitized connection string of the source
+	Schema    string    `json:"schema"`
+	CreatedAt time.Time `json:"created_at"`
+	PreData   []string  `json:"pre_data"`  // Types, sequences, functions and tables
+	PostData  []string  `json:"post_data"` // Constraints, views, indexes and triggers, created after the data is loaded
+	Tables    []Table   `json:"tables"`
+	Warnings  []string  `json:"warnings,omitempty"` // Objects that are not captured
+	DataBytes int64     `json:"data_bytes"`
+
+	// SequenceValues restore the sequences' values; they only run when the
+	// data is loaded
+	SequenceValues []string `json:"sequence_values,omitempty"`
+}
+
+// HasData reports whether the snapshot includes table data
+func (s *Snapshot) HasData() bool {
+	for _, t := range s.Tables {
+		if t.DataFile != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Rows returns the number of rows in the snapshot's data
+func (s *Snapshot) Rows() int64 {
+	var rows int64
+	for _, t := range s.Tables {
+		rows += t.Rows
+	}
+	return rows
+}
+
+// Table is a table of a snapshot
+type Table struct {
+	Name     string   `json:"name"`
+	Columns  []string `json:"columns,omitempty"`   // Columns in the data file
+	Rows     int64    `json:"rows"`                // Rows in the data file
+	DataFile string   `json:"data_file,omitempty"` // COPY text format; empty without data
+}
+
+// Store manages the snapshot directory
+type Store struct {
+	Dir      string
+	MaxBytes int64 // Size limit for the data of each snapshot (0 = unlimited)
+}
+
+// NewStoreFromConfig returns the store in the snapshots directory under the
+// configured data directory
+func NewStoreFromConfig(cfg *config.Config) *Store {
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		execPath, err := os.Executable()
+		if err != nil {
+			execPath = "."
+		}
+		dataDir = config.GetDefaultDataDir(execPath)
+	}
+	return &Store{
+		Dir:      filepath.Join(dataDir, "snapshots"),
+		MaxBytes: int64(cfg.Snapshots.MaxSizeMB) << 20,
+	}
+}
+
+// ValidateName checks that a snapshot name can be stored
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use up to 64 letters, digits, '_' or '-', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// Writer is a snapshot being written. It is written to a temporary
+// directory, so an incomplete snapshot never replaces a stored one.
+type Writer struct {
+	store   *Store
+	name    string
+	replace bool
+	dir     string
+	size    int64
+}
+
+// Create starts a new snapshot. Unless replace is set, an existing snapshot
+// with the name is an error.
+func (s *Store) Create(name string, replace bool) (*Writer, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	if !replace {
+		if _, err := os.Stat(filepath.Join(s.Dir, name)); err == nil {
+			return nil, fmt.Errorf("snapshot %q already exists; choose another name or replace it", name)
+		}
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	dir := filepath.Join(s.Dir, ".tmp-"+name+"-"+hex.EncodeToString(random))
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return &Writer{store: s, name: name, replace: replace, dir: dir}, nil
+}
+
+// DataFile creates the data file of the index'th table. Writes fail with
+// ErrTooLarge once the snapshot's data exceeds the size limit.
+func (w *Writer) DataFile(index int) (string, io.WriteCloser, error) {
+	name := fmt.Sprintf("%04d.copy", index)
+	f, err := os.OpenFile(filepath.Join(w.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create snapshot data file: %w", err)
+	}
+	return name, &dataFile{f: f, w: w}, nil
+}
+
+// Size returns the number of data bytes written
+func (w *Writer) Size() int64 {
+	return w.size
+}
+
+// Commit writes the snapshot's description and stores it under its name,
+// replacing a snapshot with the same name if the writer allows it
+func (w *Writer) Commit(snap *Snapshot) error {
+	snap.Name = w.name
+	snap.DataBytes = w.size
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(w.dir, metadataFile), data, 0600); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	final := filepath.Join(w.store.Dir, w.name)
+	if w.replace {
+		if err := os.RemoveAll(final); err != nil {
+			w.Abort()
+			return fmt.Errorf("failed to replace snapshot %q: %w", w.name, err)
+		}
+	}
+	if err := os.Rename(w.dir, final); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to store snapshot %q: %w", w.name, err)
+	}
+	return nil
+}
+
+// Abort discards the snapshot
+func (w *Writer) Abort() {
+	_ = os.RemoveAll(w.dir) //nolint:errcheck // best effort cleanup
+}
+
+// dataFile counts the bytes written towards the snapshot's size limit
+type dataFile struct {
+	f *os.File
+	w *Writer
+}
+
+func (d *dataFile) Write(p []byte) (int, error) {
+	if d.w.store.MaxBytes > 0 && d.w.size+int64(len(p)) > d.w.store.MaxBytes {
+		return 0, ErrTooLarge
+	}
+	n, err := d.f.Write(p)
+	d.w.size += int64(n)
+	return n, err
+}
+
+func (d *dataFile) Close() error {
+	return d.f.Close()
+}
+
+// Open returns the stored snapshot with a name
+func (s *Store) Open(name string) (*Snapshot, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(s.Dir, name, metadataFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %q: %w", name, err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("snapshot %q is corrupt: %w", name, err)
+	}
+	snap.Name = name
+	return &snap, nil
+}
+
+// OpenData opens the data file of a snapshot's table
+func (s *Store) OpenData(snap *Snapshot, table Table) (io.ReadCloser, error) {
+	if table.DataFile == "" || filepath.Base(table.DataFile) != table.DataFile {
+		return nil, fmt.Errorf("snapshot %q has no data for table %s", snap.Name, table.Name)
+	}
+	f, err := os.Open(filepath.Join(s.Dir, snap.Name, table.DataFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data of table %s: %w", table.Name, err)
+	}
+	return f, nil
+}
+
+// List returns the stored snapshots, newest first. Snapshots that cannot be
+// read are skipped.
+func (s *Store) List() ([]*Snapshot, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	var snapshots []*Snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() || !validName.MatchString(entry.Name()) {
+			continue
+		}
+		snap, err := s.Open(entry.Name())
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snap)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].CreatedAt.Equal(snapshots[j].CreatedAt) {
+			return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+		}
+		return snapshots[i].Name < snapshots[j].Name
+	})
+	return snapshots, nil
+}
@@ -0,0 +1,172 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package snapshot
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"before_migration", "v1-2", "2025"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../etc", ".hidden", "-x", "a b", string(make([]byte, 65))} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) expected an error", name)
+		}
+	}
+}
+
+func TestStoreCreateAndOpen(t *testing.T) {
+	store := &Store{Dir: filepath.Join(t.TempDir(), "snapshots")}
+
+	w, err := store.Create("before", false)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	file, data, err := w.DataFile(1)
+	if err != nil {
+		t.Fatalf("DataFile() error = %v", err)
+	}
+	if _, err := data.Write([]byte("1\tone\n2\ttwo\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := data.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	snap := &Snapshot{
+		Schema:    "public",
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		PreData:   []string{"CREATE TABLE t (id integer, name text)"},
+		Tables: []Table{
+			{Name: "parent"},
+			{Name: "t", Columns: []string{"id", "name"}, Rows: 2, DataFile: file},
+		},
+	}
+	if err := w.Commit(snap); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	got, err := store.Open("before")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got.Schema != "public" || !got.HasData() || got.Rows() != 2 || got.DataBytes != 12 {
+		t.Errorf("unexpected snapshot %+v", got)
+	}
+	r, err := store.OpenData(got, got.Tables[1])
+	if err != nil {
+		t.Fatalf("OpenData() error = %v", err)
+	}
+	content, _ := io.ReadAll(r)
+	r.Close()
+	if string(content) != "1\tone\n2\ttwo\n" {
+		t.Errorf("unexpected data %q", content)
+	}
+	if _, err := store.OpenData(got, got.Tables[0]); err == nil {
+		t.Error("expected an error for a table without data")
+	}
+
+	// Temporary directories are removed once the snapshot is stored
+	entries, _ := os.ReadDir(store.Dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the snapshot directory, got %d entries", len(entries))
+	}
+
+	if _, err := store.Create("before", false); err == nil {
+		t.Error("expected an error for an existing snapshot")
+	}
+	if _, err := store.Open("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreReplaceAndList(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+	for i, name := range []string{"a", "b"} {
+		w, err := store.Create(name, false)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := w.Commit(&Snapshot{Schema: "public", CreatedAt: time.Unix(int64(i), 0)}); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+	}
+
+	// An aborted replacement keeps the stored snapshot
+	w, err := store.Create("a", true)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	w.Abort()
+	if snap, err := store.Open("a"); err != nil || snap.Schema != "public" {
+		t.Fatalf("expected snapshot a to be kept, got %v", err)
+	}
+
+	w, err = store.Create("a", true)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := w.Commit(&Snapshot{Schema: "sales", CreatedAt: time.Unix(10, 0)}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	snapshots, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Name != "a" || snapshots[0].Schema != "sales" || snapshots[1].Name != "b" {
+		t.Errorf("unexpected snapshots %+v", snapshots)
+	}
+}
+
+func TestStoreSizeLimit(t *testing.T) {
+	store := &Store{Dir: t.TempDir(), MaxBytes: 10}
+	w, err := store.Create("big", false)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer w.Abort()
+
+	for i := 0; i < 2; i++ {
+		_, data, err := w.DataFile(i)
+		if err != nil {
+			t.Fatalf("DataFile() error = %v", err)
+		}
+		_, err = data.Write([]byte("123456"))
+		data.Close()
+		if i == 0 && err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if i == 1 && !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Write() error = %v, want ErrTooLarge", err)
+		}
+	}
+	if w.Size() != 6 {
+		t.Errorf("Size() = %d, want 6", w.Size())
+	}
+}
+
+func TestStoreListMissingDirectory(t *testing.T) {
+	store := &Store{Dir: filepath.Join(t.TempDir(), "missing")}
+	snapshots, err := store.List()
+	if err != nil || len(snapshots) != 0 {
+		t.Errorf("List() = %v, %v; want no snapshots", snapshots, err)
+	}
+}
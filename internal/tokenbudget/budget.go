@@ -0,0 +1,139 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tokenbudget
+
+import "strings"
+
+const (
+	// DefaultCompactAt is the conversation size at which history is
+	// compacted. It stays well below context windows so that requests also
+	// fit the providers' input token rate limits.
+	DefaultCompactAt = 15000
+
+	// DefaultTarget is the size compaction reduces a conversation to
+	DefaultTarget = 100000
+
+	// defaultOllamaContextWindow is assumed for Ollama models, which are
+	// often run with a small context
+	defaultOllamaContextWindow = 8192
+
+	// defaultContextWindow is assumed for cloud models that are not known
+	defaultContextWindow = 128000
+)
+
+// Budget decides when a conversation with a model needs compaction
+type Budget struct {
+	Tokenizer     *Tokenizer
+	ContextWindow int // Tokens the model accepts
+	CompactAt     int // Conversation size that triggers compaction
+	Target        int // Size compaction reduces the conversation to
+}
+
+// Usage reports how much of a model's context a request uses
+type Usage struct {
+	Tokens        int     `json:"tokens"`
+	ContextWindow int     `json:"context_window"`
+	Percent       float64 `json:"percent"`
+	CompactAt     int     `json:"compact_at"`
+	ShouldCompact bool    `json:"should_compact"`
+}
+
+// New returns the budget for a provider's model. contextWindow overrides
+// the model's known context window when it is greater than zero, for
+// example for an Ollama model run with a larger context.
+func New(provider, model string, contextWindow int) *Budget {
+	if contextWindow <= 0 {
+		contextWindow = ContextWindow(provider, model)
+	}
+	b := &Budget{
+		Tokenizer:     ForProvider(provider),
+		ContextWindow: contextWindow,
+		CompactAt:     DefaultCompactAt,
+		Target:        DefaultTarget,
+	}
+	// Small context windows are compacted sooner, leaving room for the
+	// tool definitions and the reply
+	if limit := contextWindow * 3 / 4; b.CompactAt > limit {
+		b.CompactAt = limit
+	}
+	if limit := contextWindow / 2; b.Target > limit {
+		b.Target = limit
+	}
+	return b
+}
+
+// Usage reports a request of the given size against the budget
+func (b *Budget) Usage(tokens int) Usage {
+	usage := Usage{
+		Tokens:        tokens,
+		ContextWindow: b.ContextWindow,
+		CompactAt:     b.CompactAt,
+		ShouldCompact: tokens > b.CompactAt,
+	}
+	if b.ContextWindow > 0 {
+		usage.Percent = float64(tokens) * 100 / float64(b.ContextWindow)
+	}
+	return usage
+}
+
+// ForProvider returns the tokenizer for a provider's models
+func ForProvider(provider string) *Tokenizer {
+	switch provider {
+	case "openai", "azure":
+		// The o200k and cl100k vocabularies hold most English words and
+		// three-digit numbers
+		return &Tokenizer{WordChars: 6, DigitsPerToken: 3, PunctuationChars: 2, MessageOverhead: 4, RequestOverhead: 3}
+	case "anthropic", "bedrock":
+		return &Tokenizer{WordChars: 5, DigitsPerToken: 3, PunctuationChars: 2, MessageOverhead: 5, RequestOverhead: 3}
+	default:
+		return &Tokenizer{WordChars: 5, DigitsPerToken: 3, PunctuationChars: 2, MessageOverhead: 4, RequestOverhead: 3}
+	}
+}
+
+// ContextWindow returns the context window of a provider's model
+func ContextWindow(provider, model string) int {
+	model = strings.ToLower(model)
+	switch provider {
+	case "anthropic":
+		return 200000
+	case "openai", "azure":
+		// Azure OpenAI deployments are usually named after their model
+		switch {
+		case strings.HasPrefix(model, "gpt-4.1"):
+			return 1047576
+		case strings.HasPrefix(model, "gpt-5"):
+			return 400000
+		case strings.HasPrefix(model, "gpt-3.5"):
+			return 16385
+		case model == "gpt-4" || strings.HasPrefix(model, "gpt-4-0"):
+			return 8192
+		default:
+			return defaultContextWindow
+		}
+	case "bedrock":
+		// Bedrock model IDs name the model's provider, optionally after a
+		// cross-region inference profile prefix such as "us."
+		switch {
+		case strings.Contains(model, "anthropic."):
+			return 200000
+		case strings.Contains(model, "amazon.titan-text-premier"):
+			return 32000
+		case strings.Contains(model, "amazon.titan-text"):
+			return 8192
+		default:
+			return defaultContextWindow
+		}
+	case "ollama":
+		return defaultOllamaContextWindow
+	default:
+		return defaultContextWindow
+	}
+}
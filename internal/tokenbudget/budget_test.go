@@ -0,0 +1,71 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tokenbudget
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name          string
+		provider      string
+		contextWindow int
+		wantWindow    int
+		wantCompactAt int
+		wantTarget    int
+	}{
+		{"cloud model", "anthropic", 0, 200000, DefaultCompactAt, DefaultTarget},
+		{"unknown model", "openai", 0, defaultContextWindow, DefaultCompactAt, defaultContextWindow / 2},
+		{"small context", "ollama", 0, 8192, 6144, 4096},
+		{"configured context", "ollama", 32768, 32768, DefaultCompactAt, 16384},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(tt.provider, "example-model", tt.contextWindow)
+			if b.ContextWindow != tt.wantWindow || b.CompactAt != tt.wantCompactAt || b.Target != tt.wantTarget {
+				t.Errorf("New() = window %d, compact at %d, target %d; want %d, %d, %d",
+					b.ContextWindow, b.CompactAt, b.Target, tt.wantWindow, tt.wantCompactAt, tt.wantTarget)
+			}
+			if b.Tokenizer == nil {
+				t.Error("expected a tokenizer")
+			}
+		})
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := []struct {
+		provider string
+		model    string
+		want     int
+	}{
+		{"azure", "gpt-4.1-mini", 1047576},
+		{"bedrock", "us.anthropic.example-model-v1:0", 200000},
+		{"bedrock", "amazon.titan-text-express-v1", 8192},
+		{"bedrock", "example-model", defaultContextWindow},
+	}
+	for _, tt := range tests {
+		if got := ContextWindow(tt.provider, tt.model); got != tt.want {
+			t.Errorf("ContextWindow(%q, %q) = %d, want %d", tt.provider, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestBudgetUsage(t *testing.T) {
+	b := New("ollama", "example-model", 10000)
+
+	usage := b.Usage(2500)
+	if usage.Tokens != 2500 || usage.ContextWindow != 10000 || usage.Percent != 25 || usage.ShouldCompact {
+		t.Errorf("Usage(2500) = %+v", usage)
+	}
+	if usage := b.Usage(b.CompactAt + 1); !usage.ShouldCompact {
+		t.Errorf("expected compaction above %d tokens, got %+v", b.CompactAt, usage)
+	}
+}
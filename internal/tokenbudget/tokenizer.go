@@ -0,0 +1,232 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package tokenbudget counts the tokens of LLM requests and decides when a
+// conversation needs to be compacted to fit the model's context window.
+package tokenbudget
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts tokens the way byte-pair encoding tokenizers do: text is
+// split into pieces (words, numbers, punctuation and whitespace) as the
+// tiktoken pre-tokenizer does, and each piece is charged by how such pieces
+// are merged by the provider's vocabulary. Counts are estimates, but they
+// follow the structure of the text, so code, SQL, JSON and non-English
+// text are counted much more accurately than by dividing the length.
+type Tokenizer struct {
+	// WordChars is the length of the longest word that is usually a single
+	// token; longer words are split into tokens of this size
+	WordChars int
+	// DigitsPerToken is the size of the groups numbers are split into
+	DigitsPerToken int
+	// PunctuationChars is how many punctuation characters usually merge
+	// into one token
+	PunctuationChars int
+	// MessageOverhead is the cost of a message's role and delimiters
+	MessageOverhead int
+	// RequestOverhead is the cost of the request's framing, such as the
+	// tokens that start the reply
+	RequestOverhead int
+}
+
+// pieceKind classifies the pieces text is split into
+type pieceKind int
+
+const (
+	pieceWord pieceKind = iota
+	pieceNumber
+	piecePunctuation
+	pieceSpace
+	pieceNewline
+)
+
+// Count returns the number of tokens in a text
+func (t *Tokenizer) Count(text string) int {
+	tokens := 0
+	for len(text) > 0 {
+		kind, size, letters, wide := nextPiece(text)
+		tokens += t.pieceTokens(kind, letters, wide)
+		text = text[size:]
+	}
+	return tokens
+}
+
+// CountMessage returns the tokens of a message with the given text,
+// including the message's overhead
+func (t *Tokenizer) CountMessage(text string) int {
+	return t.MessageOverhead + t.Count(text)
+}
+
+// pieceTokens returns the tokens of one piece. letters counts its
+// characters, leaving out a leading space and counting other non-ASCII
+// letters twice; wide counts the characters that are usually a token on
+// their own, such as ideographs, kana and emoji.
+func (t *Tokenizer) pieceTokens(kind pieceKind, letters, wide int) int {
+	switch kind {
+	case pieceWord:
+		return wide + ceilDiv(letters, t.WordChars)
+	case pieceNumber:
+		return ceilDiv(letters, t.DigitsPerToken)
+	case piecePunctuation:
+		return wide + ceilDiv(letters, t.PunctuationChars)
+	default:
+		// Runs of spaces, such as indentation, and of newlines merge well
+		return ceilDiv(letters, 16)
+	}
+}
+
+// nextPiece splits the next piece off the start of text, returning its
+// kind, its size in bytes, and its character counts for pieceTokens
+func nextPiece(text string) (kind pieceKind, size, letters, wide int) {
+	r, n := utf8.DecodeRuneInString(text)
+
+	// Contractions are pieces of their own
+	if r == '\'' {
+		if m := contractionLength(text[n:]); m > 0 {
+			return pieceWord, n + m, m + 1, 0
+		}
+	}
+
+	// A word may start with one space or punctuation character
+	start := 0
+	if !isWordRune(r) && r != '\n' && r != '\r' && !unicode.IsDigit(r) && len(text) > n {
+		if next, _ := utf8.DecodeRuneInString(text[n:]); isWordRune(next) {
+			start = n
+			if r != ' ' {
+				letters = 1
+			}
+		}
+	}
+
+	if start > 0 || isWordRune(r) {
+		size = start
+		for size < len(text) {
+			c, m := utf8.DecodeRuneInString(text[size:])
+			if !isWordRune(c) {
+				break
+			}
+			switch {
+			case isWideRune(c):
+				wide++
+			case c >= utf8.RuneSelf:
+				letters += 2
+			default:
+				letters++
+			}
+			size += m
+		}
+		return pieceWord, size, letters, wide
+	}
+
+	switch {
+	case unicode.IsDigit(r):
+		for size < len(text) {
+			c, m := utf8.DecodeRuneInString(text[size:])
+			if !unicode.IsDigit(c) {
+				break
+			}
+			letters++
+			size += m
+		}
+		return pieceNumber, size, letters, 0
+	case r == '\n' || r == '\r':
+		for size < len(text) && (text[size] == '\n' || text[size] == '\r') {
+			letters++
+			size++
+		}
+		return pieceNewline, size, letters, 0
+	case r == ' ' && len(text) > n && isPunctuation(text[n:]):
+		// Punctuation may start with a space
+		size, letters, wide = punctuationRun(text, n)
+		return piecePunctuation, size, letters, wide
+	case unicode.IsSpace(r):
+		for size < len(text) {
+			c, m := utf8.DecodeRuneInString(text[size:])
+			if !unicode.IsSpace(c) || c == '\n' || c == '\r' {
+				break
+			}
+			letters++
+			size += m
+		}
+		return pieceSpace, size, letters, 0
+	default:
+		size, letters, wide = punctuationRun(text, 0)
+		return piecePunctuation, size, letters, wide
+	}
+}
+
+// isPunctuation reports whether text starts with a punctuation character
+func isPunctuation(text string) bool {
+	r, _ := utf8.DecodeRuneInString(text)
+	return !isWordRune(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+// punctuationRun returns the end of the punctuation characters from start
+// and their counts; non-ASCII symbols such as emoji count as wide
+func punctuationRun(text string, start int) (size, letters, wide int) {
+	size = start
+	for size < len(text) && isPunctuation(text[size:]) {
+		c, m := utf8.DecodeRuneInString(text[size:])
+		if c >= utf8.RuneSelf {
+			wide++
+		} else {
+			letters++
+		}
+		size += m
+	}
+	return size, letters, wide
+}
+
+// contractionLength returns the length of the English contraction ('s, 't,
+// 're, 've, 'm, 'll, 'd) at the start of text after an apostrophe, or 0
+func contractionLength(text string) int {
+	for _, suffix := range []string{"ll", "re", "ve", "s", "t", "m", "d"} {
+		if len(text) >= len(suffix) && equalFoldASCII(text[:len(suffix)], suffix) {
+			if len(text) == len(suffix) || !isWordRune(rune(text[len(suffix)])) {
+				return len(suffix)
+			}
+		}
+	}
+	return 0
+}
+
+// equalFoldASCII compares ASCII strings ignoring case
+func equalFoldASCII(a, b string) bool {
+	for i := 0; i < len(a); i++ {
+		if a[i]|0x20 != b[i]|0x20 {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordRune reports whether a rune is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.Is(unicode.Mn, r)
+}
+
+// isWideRune reports whether a rune is usually at least one token on its own
+func isWideRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai)
+}
+
+// ceilDiv divides rounding up
+func ceilDiv(a, b int) int {
+	if a <= 0 {
+		return 0
+	}
+	if b <= 1 {
+		return a
+	}
+	return (a + b - 1) / b
+}
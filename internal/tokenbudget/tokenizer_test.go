@@ -0,0 +1,67 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tokenbudget
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenizerCount(t *testing.T) {
+	tokenizer := ForProvider("openai")
+
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"word", "hello", 1},
+		{"words", "hello world", 2},
+		{"sentence", "This is a longer string with more words.", 9},
+		{"contraction", "don't", 2},
+		{"long word", "internationalization", 4},
+		{"number", "1234567", 3},
+		{"sql", "SELECT count(*) FROM orders;", 7},
+		{"json", `{"query": "SELECT 1"}`, 8},
+		{"indentation", "\n        return", 3},
+		{"ideographs", "数据库", 3},
+		{"accents", "café", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizer.Count(tt.text); got != tt.want {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizerCountStructuredText(t *testing.T) {
+	// Punctuation-heavy text costs more tokens per character than prose,
+	// which a fixed characters-per-token ratio does not reflect
+	tokenizer := ForProvider("anthropic")
+	prose := strings.Repeat("the orders table holds one row per order ", 20)
+	data := strings.Repeat(`{"id":1,"v":[2,3]},`, 40)
+
+	proseRatio := float64(len(prose)) / float64(tokenizer.Count(prose))
+	dataRatio := float64(len(data)) / float64(tokenizer.Count(data))
+	if proseRatio <= dataRatio {
+		t.Errorf("expected more characters per token for prose (%.2f) than JSON (%.2f)", proseRatio, dataRatio)
+	}
+}
+
+func TestTokenizerCountMessage(t *testing.T) {
+	tokenizer := ForProvider("anthropic")
+	if got := tokenizer.CountMessage("hello"); got != tokenizer.MessageOverhead+1 {
+		t.Errorf("CountMessage() = %d, want %d", got, tokenizer.MessageOverhead+1)
+	}
+}
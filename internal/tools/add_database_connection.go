@@ -0,0 +1,230 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/dbstore"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// connectionCheckTimeout bounds the test connection to a new database
+const connectionCheckTimeout = 15 * time.Second
+
+// validDatabaseName matches the names of added databases
+var validDatabaseName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+// validSSLModes are the sslmode settings libpq accepts
+var validSSLModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true,
+	"require": true, "verify-ca": true, "verify-full": true,
+}
+
+// AddDatabaseConnectionTool creates the add_database_connection tool, which
+// configures another database while the server runs. The connection is
+// checked first, then stored with its password encrypted so it is kept
+// across restarts.
+// The tool changes the server's configuration, so it is disabled unless
+// enabled, and only API tokens that name it may use it
+func AddDatabaseConnectionTool(clientManager *database.ClientManager, store *dbstore.Store) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "add_database_connection",
+			Description: `Add a PostgreSQL database to the server's configured databases, without a
+restart.
+
+<usecase>
+Use when an administrator asks to register a new database, so it can be
+selected and queried like the databases in the configuration file.
+</usecase>
+
+<behavior>
+- Connects to the database once to check the settings; nothing is added if
+  the connection fails
+- Stores the database in the server's data directory with its password
+  encrypted, so it is configured again after restarts
+- Empty available_to_users makes the database available to every user
+</behavior>
+
+<important>
+- Confirm the settings with the user before calling: the password is sent
+  to the server and stored
+- A database with the same name as a configured one cannot be added
+- Label production databases with environment=prod: the write tools only
+  run there as dry runs
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Unique name to select the database by (letters, digits, '_', '.' or '-')",
+					},
+					"host": map[string]interface{}{
+						"type":        "string",
+						"description": "Database server host (default: localhost)",
+						"default":     "localhost",
+					},
+					"port": map[string]interface{}{
+						"type":        "integer",
+						"description": "Database server port (default: 5432)",
+						"default":     5432,
+					},
+					"database": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the PostgreSQL database",
+					},
+					"user": map[string]interface{}{
+						"type":        "string",
+						"description": "Database user",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of the user (empty: use the server's .pgpass file)",
+					},
+					"sslmode": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"},
+						"description": "SSL mode (default: prefer)",
+						"default":     "prefer",
+					},
+					"environment": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"prod", "staging", "dev", "test"},
+						"description": "Environment label; prod makes the database read-only for the tools",
+					},
+					"description": map[string]interface{}{
+						"type":        "string",
+						"description": "What the database holds, shown by list_databases",
+					},
+					"available_to_users": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Users who may access the database (default: all users)",
+					},
+				},
+				Required: []string{"name", "database", "user"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			name, errResp := ValidateStringParam(args, "name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			dbName, errResp := ValidateStringParam(args, "database")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			user, errResp := ValidateStringParam(args, "user")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			db := config.NamedDatabaseConfig{
+				Name:                name,
+				Host:                ValidateOptionalStringParam(args, "host", "localhost"),
+				Port:                int(ValidateOptionalNumberParam(args, "port", 5432)),
+				Database:            dbName,
+				User:                user,
+				Password:            ValidateOptionalStringParam(args, "password", ""),
+				SSLMode:             ValidateOptionalStringParam(args, "sslmode", "prefer"),
+				Environment:         ValidateOptionalStringParam(args, "environment", ""),
+				Description:         ValidateOptionalStringParam(args, "description", ""),
+				AvailableToUsers:    stringListParam(args, "available_to_users"),
+				PoolMaxConns:        4,
+				PoolMaxConnIdleTime: "30m",
+			}
+
+			if !validDatabaseName.MatchString(db.Name) {
+				return mcp.NewToolError(fmt.Sprintf("Invalid name %q: use up to 63 letters, digits, '_', '.' or '-', starting with a letter or digit", db.Name))
+			}
+			if db.Port < 1 || db.Port > 65535 {
+				return mcp.NewToolError(fmt.Sprintf("Invalid port %d", db.Port))
+			}
+			if !validSSLModes[db.SSLMode] {
+				return mcp.NewToolError(fmt.Sprintf("Invalid sslmode %q (must be disable, allow, prefer, require, verify-ca or verify-full)", db.SSLMode))
+			}
+			if err := db.Validate(); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			if clientManager.GetDatabaseConfig(db.Name) != nil {
+				return mcp.NewToolError(fmt.Sprintf("Database %s is already configured; choose another name", db.Name))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+			checkCtx, cancel := context.WithTimeout(ctx, connectionCheckTimeout)
+			defer cancel()
+			target := fmt.Sprintf("%s@%s:%d/%s", db.User, db.Host, db.Port, db.Database)
+			if err := database.CheckConnection(checkCtx, &db); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Could not connect to %s, so the database was not added: %v", target, err))
+			}
+
+			// Register the database first: registration rejects a name
+			// taken meanwhile, so only a database that was added is stored
+			if err := clientManager.AddDatabaseConfig(db); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			if err := store.Add(db); err != nil {
+				clientManager.RemoveDatabaseConfig(db.Name)
+				return mcp.NewToolError(fmt.Sprintf("Failed to store the database: %v", err))
+			}
+
+			logging.Info("add_database_connection_executed",
+				"name", db.Name,
+				"host", db.Host,
+				"port", db.Port,
+				"database", db.Database,
+				"user", db.User,
+				"environment", db.Environment,
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database %s added: %s\n", db.Name, target))
+			if db.Environment != "" {
+				sb.WriteString(fmt.Sprintf("Environment: %s\n", db.Environment))
+			}
+			if !db.WritesAllowed() {
+				sb.WriteString("Writes: disabled\n")
+			}
+			if len(db.AvailableToUsers) > 0 {
+				sb.WriteString(fmt.Sprintf("Available to: %s\n", strings.Join(db.AvailableToUsers, ", ")))
+			}
+			sb.WriteString(fmt.Sprintf("\nThe connection is stored in %s and kept across restarts. Users can now select the database; list_databases shows it.\n",
+				store.Path))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// stringListParam returns a list of strings argument, skipping empty items
+func stringListParam(args map[string]interface{}, name string) []string {
+	list, ok := args[name].([]interface{})
+	if !ok {
+		return nil
+	}
+	var values []string
+	for _, item := range list {
+		if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+			values = append(values, strings.TrimSpace(s))
+		}
+	}
+	return values
+}
@@ -0,0 +1,80 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/dbstore"
+)
+
+func TestAddDatabaseConnectionTool(t *testing.T) {
+	clientManager := database.NewClientManager([]config.NamedDatabaseConfig{
+		{Name: "main", Host: "db1", Port: 5432, Database: "app", User: "app"},
+	})
+	defer clientManager.CloseAll()
+
+	dir := t.TempDir()
+	store := &dbstore.Store{Path: filepath.Join(dir, dbstore.FileName), SecretPath: filepath.Join(dir, "server.secret")}
+	tool := AddDatabaseConnectionTool(clientManager, store)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{"name": "analytics", "database": "warehouse", "user": "reporter"}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(args map[string]interface{})
+		wantErr string
+	}{
+		{"missing database", func(args map[string]interface{}) { delete(args, "database") }, "database"},
+		{"invalid name", func(args map[string]interface{}) { args["name"] = "my db" }, "Invalid name"},
+		{"invalid port", func(args map[string]interface{}) { args["port"] = float64(70000) }, "Invalid port"},
+		{"invalid sslmode", func(args map[string]interface{}) { args["sslmode"] = "always" }, "Invalid sslmode"},
+		{"invalid environment", func(args map[string]interface{}) { args["environment"] = "qa" }, "environment"},
+		{"configured name", func(args map[string]interface{}) { args["name"] = "main" }, "already configured"},
+		{"unreachable database", func(args map[string]interface{}) {
+			args["host"] = "127.0.0.1"
+			args["port"] = float64(1)
+			args["sslmode"] = "disable"
+		}, "Could not connect"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := valid()
+			tt.change(args)
+			response, err := tool.Handler(args)
+			if err != nil {
+				t.Fatalf("Handler returned error: %v", err)
+			}
+			if !response.IsError {
+				t.Fatalf("expected an error, got %s", response.Content[0].Text)
+			}
+			if !strings.Contains(response.Content[0].Text, tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", response.Content[0].Text, tt.wantErr)
+			}
+		})
+	}
+
+	// Nothing is added when a check fails
+	if clientManager.GetDatabaseConfig("analytics") != nil {
+		t.Error("expected analytics not to be configured")
+	}
+	if _, err := os.Stat(store.Path); !os.IsNotExist(err) {
+		t.Errorf("expected no store file, got %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/masking"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// ApplyMigrationTool creates the apply_migration tool, which applies or
+// rolls back a named migration in a transaction and records it in the
+// migrations table
+// The tool writes to the database, so it is disabled unless enabled in the
+// configuration
+func ApplyMigrationTool(dbClient *database.Client, masker *masking.Masker) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "apply_migration",
+			Description: `Apply a named schema migration in a single transaction and record it in the
+` + migrationsTable + ` table, or roll back the last applied migration with
+that name. Unlike query_database, this tool CAN modify the schema.
+
+<usecase>
+Use when:
+- The user has reviewed a migration from generate_migration and wants it
+  tested (dry_run=true) or applied (dry_run=false)
+- Undoing a migration applied earlier (direction=down)
+</usecase>
+
+<behavior>
+- dry_run defaults to true: the forward SQL runs, then the backward SQL runs
+  to prove it undoes the change, and everything is rolled back. The result
+  shows the before and after values of a sample of the rows each UPDATE
+  changes
+- direction=up runs the forward SQL; the backward SQL is generated as with
+  generate_migration unless "down" is given. Any failing statement rolls the
+  whole migration back
+- direction=down runs the recorded backward SQL of the migration and marks it
+  rolled back; irreversible migrations are refused
+- A name that is already applied is rejected, so retries are safe
+</behavior>
+
+<important>
+- Confirm the migration with the user before running with dry_run=false
+- Do not include BEGIN, COMMIT or ROLLBACK; statements that cannot run in a
+  transaction (CREATE INDEX CONCURRENTLY, VACUUM) are rejected
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the migration",
+					},
+					"up": map[string]interface{}{
+						"type":        "string",
+						"description": "The forward SQL (required for direction=up)",
+					},
+					"down": map[string]interface{}{
+						"type":        "string",
+						"description": "The backward SQL; generated from the forward SQL if omitted",
+					},
+					"direction": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"up", "down"},
+						"description": "'up' applies the migration, 'down' rolls back the last applied migration with this name (default: up)",
+						"default":     "up",
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Run the migration and roll it back without applying or recording it (default: true)",
+						"default":     true,
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			name, errResp := ValidateStringParam(args, "name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			direction := ValidateOptionalStringParam(args, "direction", "up")
+			if direction != "up" && direction != "down" {
+				return mcp.NewToolError("direction must be 'up' or 'down'")
+			}
+			dryRun := ValidateBoolParam(args, "dry_run", true)
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+			ctx := context.Background()
+
+			// The backward SQL is generated from the schema before the
+			// migration runs
+			var m *migration
+			if direction == "up" {
+				up, errResp := ValidateStringParam(args, "up")
+				if errResp != nil {
+					return *errResp, nil
+				}
+				var err error
+				if m, err = prepareMigration(ctx, dbClient, connStr, name, up, ValidateOptionalStringParam(args, "down", "")); err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+			}
+
+			// Connections default to read-only transactions
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			committed := false
+			defer func() {
+				if !committed {
+					_ = tx.Rollback(ctx) //nolint:errcheck // rollback after a failed commit is expected to fail
+				}
+			}()
+
+			// Create the history table on first use, and serialize migrations so
+			// two sessions cannot apply the same one
+			if _, err := tx.Exec(ctx, createMigrationsTableSQL); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to create the %s table: %v", migrationsTable, err))
+			}
+			if _, err := tx.Exec(ctx, "LOCK TABLE "+migrationsTable+" IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to lock the %s table: %v", migrationsTable, err))
+			}
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			mode := ""
+			if dryRun {
+				mode = ", dry run"
+			}
+			sb.WriteString(fmt.Sprintf("Migration: %s (%s%s)\n\n", name, direction, mode))
+
+			// A dry run also shows the values its UPDATEs change
+			var exec scriptExecer = tx
+			if dryRun {
+				exec = newPreviewExecer(tx, masker)
+			}
+
+			var results []scriptStatement
+			var aborted, downFailed bool
+			var id int64
+			if direction == "up" {
+				if err := checkMigrationNotApplied(ctx, tx, m); err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+
+				results, aborted = runScript(ctx, exec, m.Up, false)
+				sb.WriteString(formatScriptResults(results))
+				sb.WriteString(formatUpdatePreviews(results))
+
+				switch {
+				case aborted:
+				case dryRun && len(m.Down) > 0:
+					var downResults []scriptStatement
+					downResults, downFailed = runScript(ctx, tx, m.Down, false)
+					sb.WriteString("\nBackward check:\n")
+					sb.WriteString(formatScriptResults(downResults))
+				case !dryRun:
+					err := tx.QueryRow(ctx, `
+						INSERT INTO `+migrationsTable+` (name, up_sql, down_sql, reversible, checksum)
+						VALUES ($1, $2, $3, $4, $5)
+						RETURNING id`, m.Name, m.upSQL(), m.downSQL(), m.Reversible, m.checksum()).Scan(&id)
+					if err != nil {
+						return mcp.NewToolError(fmt.Sprintf("%s\nFailed to record the migration, no changes were applied: %v",
+							formatScriptResults(results), err))
+					}
+				}
+				if len(m.Warnings) > 0 {
+					sb.WriteString("\nWarnings:\n")
+					for _, w := range m.Warnings {
+						sb.WriteString("- " + w + "\n")
+					}
+				}
+			} else {
+				var statements []string
+				if id, statements, err = loadMigrationDown(ctx, tx, name); err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+				results, aborted = runScript(ctx, exec, statements, false)
+				sb.WriteString(formatScriptResults(results))
+				sb.WriteString(formatUpdatePreviews(results))
+				if !aborted && !dryRun {
+					if _, err := tx.Exec(ctx, "UPDATE "+migrationsTable+" SET rolled_back_at = now() WHERE id = $1", id); err != nil {
+						return mcp.NewToolError(fmt.Sprintf("%s\nFailed to record the rollback, no changes were applied: %v",
+							formatScriptResults(results), err))
+					}
+				}
+			}
+
+			if !aborted && !dryRun {
+				if err := tx.Commit(ctx); err != nil {
+					return mcp.NewToolError(fmt.Sprintf("%s\nCommit failed, no changes were applied: %v",
+						formatScriptResults(results), err))
+				}
+				committed = true
+
+				// Schema changes invalidate the cached metadata used by other tools
+				if err := dbClient.LoadMetadataFor(connStr); err != nil {
+					logging.Warn("apply_migration_metadata_refresh_failed", "error", err)
+				}
+			}
+
+			logging.Info("apply_migration_executed",
+				"name", name,
+				"direction", direction,
+				"dry_run", dryRun,
+				"committed", committed,
+			)
+
+			sb.WriteString("\n")
+			sb.WriteString(formatMigrationOutcome(name, direction, dryRun, aborted, downFailed, id))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// prepareMigration splits the forward SQL and generates the backward SQL,
+// unless it is given
+func prepareMigration(ctx context.Context, dbClient *database.Client, connStr, name, up, down string) (*migration, error) {
+	if _, err := splitScript(up); err != nil {
+		return nil, err
+	}
+
+	pool := dbClient.GetPoolFor(connStr)
+	m, err := generateMigration(name, up, dbClient.GetMetadataFor(connStr), poolMigrationCatalog{ctx: ctx, pool: pool})
+	if err != nil {
+		return nil, fmt.Errorf("could not generate migration: %w", err)
+	}
+	if strings.TrimSpace(down) == "" {
+		return m, nil
+	}
+
+	statements, err := splitScript(down)
+	if err != nil {
+		return nil, fmt.Errorf("down script: %w", err)
+	}
+	m.Down = statements
+	m.Warnings = nil
+	m.Reversible = true
+	return m, nil
+}
+
+// checkMigrationNotApplied rejects a migration whose name is already applied
+func checkMigrationNotApplied(ctx context.Context, tx pgx.Tx, m *migration) error {
+	var checksum string
+	err := tx.QueryRow(ctx, `
+		SELECT checksum FROM `+migrationsTable+`
+		WHERE name = $1 AND rolled_back_at IS NULL
+		ORDER BY id DESC LIMIT 1`, m.Name).Scan(&checksum)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %v", migrationsTable, err)
+	case checksum == m.checksum():
+		return fmt.Errorf("migration %q is already applied", m.Name)
+	}
+	return fmt.Errorf("a different migration named %q is already applied; choose another name or roll it back first", m.Name)
+}
+
+// loadMigrationDown returns the id and backward statements of the last
+// applied migration with a name
+func loadMigrationDown(ctx context.Context, tx pgx.Tx, name string) (int64, []string, error) {
+	var id int64
+	var downSQL string
+	var reversible bool
+	err := tx.QueryRow(ctx, `
+		SELECT id, down_sql, reversible FROM `+migrationsTable+`
+		WHERE name = $1 AND rolled_back_at IS NULL
+		ORDER BY id DESC LIMIT 1`, name).Scan(&id, &downSQL, &reversible)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return 0, nil, fmt.Errorf("no applied migration named %q", name)
+	case err != nil:
+		return 0, nil, fmt.Errorf("failed to read %s: %v", migrationsTable, err)
+	case !reversible:
+		return 0, nil, fmt.Errorf("migration %q is irreversible: its backward SQL does not undo every statement. Write a new migration to revert it", name)
+	}
+
+	if strings.TrimSpace(downSQL) == "" {
+		return id, nil, nil
+	}
+	statements, err := splitScript(downSQL)
+	if err != nil {
+		return 0, nil, fmt.Errorf("recorded backward SQL: %w", err)
+	}
+	return id, statements, nil
+}
+
+// formatMigrationOutcome summarizes what happened to the migration
+func formatMigrationOutcome(name, direction string, dryRun, aborted, downFailed bool, id int64) string {
+	switch {
+	case aborted:
+		return "Transaction rolled back: no changes were applied. Fix the failing statement and run the migration again.\n"
+	case downFailed:
+		return "Dry run rolled back: the forward SQL succeeded but the backward SQL failed, so the migration could not be undone once applied. Fix the down script before applying.\n"
+	case dryRun:
+		return "Dry run succeeded and was rolled back: no changes were applied. Run again with dry_run=false to apply it.\n"
+	case direction == "down":
+		return fmt.Sprintf("Rolled back migration %s (id %d) and marked it rolled back in %s.\n", name, id, migrationsTable)
+	}
+	return fmt.Sprintf("Applied migration %s (id %d) and recorded it in %s.\n", name, id, migrationsTable)
+}
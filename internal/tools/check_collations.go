@@ -0,0 +1,499 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+const (
+	// collationIndexMaxLimit caps the affected indexes listed
+	collationIndexMaxLimit = 500
+
+	// defaultCollationOID is the OID of the "default" collation, which
+	// index columns use when they follow the database's collation
+	defaultCollationOID = 100
+)
+
+// CheckCollationsTool creates the check_collations tool, which compares the
+// collation versions recorded in the catalogs with those provided by the
+// operating system's libraries, and lists the indexes to rebuild when they
+// differ
+func CheckCollationsTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "check_collations",
+			Description: `Check for collation version mismatches: the locale, provider and
+collation version of each database and of the collations used in the current
+database, compared with the versions the operating system's C library or ICU
+provides now. After an OS upgrade changes sort order, indexes on text columns
+can silently return wrong results or admit duplicate keys until rebuilt.
+
+<usecase>
+Use when:
+- PostgreSQL logs "collation version mismatch" warnings
+- The operating system, glibc or ICU was upgraded, or the data directory was
+  moved to another host or restored from a physical backup
+- Queries using an index on text columns return missing or duplicate rows
+- The health report flags a collation version mismatch
+</usecase>
+
+<what_it_returns>
+- The collation provider, locale and recorded and current versions of each
+  database (PostgreSQL 15 and later) and of the collations in use
+- The indexes in the current database that use a mismatched collation
+- The REINDEX and REFRESH VERSION commands that resolve the mismatch, in order
+</what_it_returns>
+
+<important>
+- Nothing is rebuilt; present the commands to the user, who decides when to
+  run them (REINDEX CONCURRENTLY avoids blocking writes but takes longer)
+- Only indexes in the current database are listed; run the check connected
+  to each database with a mismatch
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Only list affected indexes in this schema (default: all schemas)",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum affected indexes listed (default: 50, max: %d)", collationIndexMaxLimit),
+						"default":     50,
+					},
+				},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			schema := ValidateOptionalStringParam(args, "schema", "")
+			limit := ValidateOptionalNumberParam(args, "limit", 50)
+			if limit < 1 || limit > collationIndexMaxLimit {
+				return mcp.NewToolError(fmt.Sprintf("limit must be between 1 and %d", collationIndexMaxLimit))
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			report, err := loadCollationReport(ctx, pool, schema, int(limit))
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to check collations: %v", err))
+			}
+
+			logging.Info("check_collations_executed",
+				"schema", schema,
+				"mismatches", len(report.mismatches()),
+				"affected_indexes", report.AffectedIndexCount,
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatCollationReport(report))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// collationVersion is a database's default collation or a collation object,
+// with the version recorded in the catalog and the library's current version
+type collationVersion struct {
+	Database bool   // A database's default collation rather than a collation object
+	Current  bool   // The database the server is connected to
+	OID      uint32 // The collation's OID (collations only)
+	Schema   string // The collation's schema (collations only)
+	Name     string // Database or collation name
+	Provider string // libc, icu or builtin
+	Locale   string
+	Recorded string // Version when the collation was created or last refreshed ("" if not tracked)
+	Actual   string // Version the library provides now ("" if unversioned)
+	Indexes  int64  // Indexes in the current database using the collation
+}
+
+// mismatched reports whether the library's version differs from the
+// recorded one
+func (c collationVersion) mismatched() bool {
+	return c.Recorded != "" && c.Actual != "" && c.Recorded != c.Actual
+}
+
+// collationIndex is an index using a mismatched collation
+type collationIndex struct {
+	Schema     string
+	Table      string
+	Index      string
+	Bytes      int64
+	Unique     bool
+	Collations []string
+}
+
+// collationReport holds the results of a collation check
+type collationReport struct {
+	ServerVersion      int
+	CurrentDatabase    string
+	Schema             string // Schema filter for the indexes, or empty
+	Databases          []collationVersion
+	Collations         []collationVersion
+	AffectedIndexes    []collationIndex
+	AffectedIndexCount int64 // Including those beyond the limit
+}
+
+// mismatches returns the databases and collations with a version mismatch
+func (r *collationReport) mismatches() []collationVersion {
+	var result []collationVersion
+	for _, list := range [][]collationVersion{r.Databases, r.Collations} {
+		for _, c := range list {
+			if c.mismatched() {
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+// collationProvider names a provider code from the catalogs
+func collationProvider(code string) string {
+	switch code {
+	case "c":
+		return "libc"
+	case "i":
+		return "icu"
+	case "b":
+		return "builtin"
+	case "d":
+		return "default"
+	}
+	return code
+}
+
+// loadCollationReport runs the collation queries in a read-only transaction
+func loadCollationReport(ctx context.Context, pool *pgxpool.Pool, schema string, limit int) (*collationReport, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // read-only transaction, nothing to keep
+	}()
+
+	if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+		return nil, fmt.Errorf("failed to set transaction to read-only: %w", err)
+	}
+
+	report := &collationReport{Schema: schema}
+	err = tx.QueryRow(ctx, "SELECT current_setting('server_version_num')::int, current_database()::text").
+		Scan(&report.ServerVersion, &report.CurrentDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+
+	report.Databases, report.Collations, err = loadCollationVersions(ctx, tx, report.ServerVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index columns following the database's collation record the default
+	// collation, so a mismatch of the current database affects them
+	var oids []uint32
+	for _, d := range report.Databases {
+		if d.Current && d.mismatched() {
+			oids = append(oids, defaultCollationOID)
+		}
+	}
+	for _, c := range report.Collations {
+		if c.mismatched() {
+			oids = append(oids, c.OID)
+		}
+	}
+	if len(oids) == 0 {
+		return report, nil
+	}
+
+	const affectedFilter = `
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = ic.relnamespace
+		WHERE i.indcollation::oid[] && $1::oid[]
+			AND ic.relkind = 'i'
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND n.nspname NOT LIKE 'pg_toast%'
+			AND ($2 = '' OR n.nspname = $2)`
+	report.AffectedIndexes, err = collectRows(ctx, tx, "affected indexes", func(row pgx.Rows) (collationIndex, error) {
+		var idx collationIndex
+		return idx, row.Scan(&idx.Schema, &idx.Table, &idx.Index, &idx.Bytes, &idx.Unique, &idx.Collations)
+	}, `
+		SELECT n.nspname::text, t.relname::text, ic.relname::text,
+			pg_catalog.pg_relation_size(i.indexrelid), i.indisunique,
+			ARRAY(SELECT DISTINCT coll.collname::text
+				FROM pg_catalog.pg_collation coll
+				WHERE coll.oid = ANY(i.indcollation::oid[]) AND coll.oid = ANY($1::oid[])
+				ORDER BY 1)`+affectedFilter+`
+		ORDER BY 1, 2, 3
+		LIMIT $3`, oids, schema, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, "SELECT count(*)"+affectedFilter, oids, schema).Scan(&report.AffectedIndexCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count affected indexes: %w", err)
+	}
+
+	return report, nil
+}
+
+// loadCollationVersions reads the collation versions of every database
+// that accepts connections, and of the collations used by columns or
+// indexes in the current database or with a version mismatch. Database
+// collation versions are tracked from PostgreSQL 15.
+func loadCollationVersions(ctx context.Context, tx pgx.Tx, serverVersion int) ([]collationVersion, []collationVersion, error) {
+	if serverVersion < 100000 {
+		return nil, nil, fmt.Errorf("collation versions are tracked from PostgreSQL 10")
+	}
+
+	databaseQuery := `
+		SELECT d.datname::text, d.datname = current_database(), 'c', coalesce(d.datcollate::text, ''), '', ''
+		FROM pg_catalog.pg_database d
+		WHERE d.datallowconn
+		ORDER BY 1`
+	if serverVersion >= 150000 {
+		locale := "d.daticulocale"
+		if serverVersion >= 170000 {
+			locale = "d.datlocale"
+		}
+		databaseQuery = `
+			SELECT d.datname::text, d.datname = current_database(), d.datlocprovider::text,
+				coalesce(` + locale + `, d.datcollate)::text,
+				coalesce(d.datcollversion, ''),
+				coalesce(pg_catalog.pg_database_collation_actual_version(d.oid), '')
+			FROM pg_catalog.pg_database d
+			WHERE d.datallowconn
+			ORDER BY 1`
+	}
+	databases, err := collectRows(ctx, tx, "database collations", func(row pgx.Rows) (collationVersion, error) {
+		c := collationVersion{Database: true}
+		err := row.Scan(&c.Name, &c.Current, &c.Provider, &c.Locale, &c.Recorded, &c.Actual)
+		c.Provider = collationProvider(c.Provider)
+		return c, err
+	}, databaseQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locale := "c.collcollate"
+	switch {
+	case serverVersion >= 170000:
+		locale = "coalesce(c.colllocale, c.collcollate)"
+	case serverVersion >= 150000:
+		locale = "coalesce(c.colliculocale, c.collcollate)"
+	}
+	collations, err := collectRows(ctx, tx, "collations", func(row pgx.Rows) (collationVersion, error) {
+		var c collationVersion
+		err := row.Scan(&c.OID, &c.Schema, &c.Name, &c.Provider, &c.Locale, &c.Recorded, &c.Actual, &c.Indexes)
+		c.Provider = collationProvider(c.Provider)
+		return c, err
+	}, `
+		SELECT c.oid, n.nspname::text, c.collname::text, c.collprovider::text,
+			coalesce(`+locale+`, '')::text,
+			coalesce(c.collversion, ''),
+			coalesce(pg_catalog.pg_collation_actual_version(c.oid), ''),
+			(SELECT count(*) FROM pg_catalog.pg_index i WHERE c.oid = ANY(i.indcollation::oid[]))
+		FROM pg_catalog.pg_collation c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.collnamespace
+		WHERE c.collprovider <> 'd'
+			AND c.collencoding IN (-1, pg_catalog.pg_char_to_encoding(pg_catalog.getdatabaseencoding()))
+			AND (EXISTS (SELECT 1 FROM pg_catalog.pg_index i WHERE c.oid = ANY(i.indcollation::oid[]))
+				OR EXISTS (SELECT 1 FROM pg_catalog.pg_attribute a
+					JOIN pg_catalog.pg_class r ON r.oid = a.attrelid
+					JOIN pg_catalog.pg_namespace rn ON rn.oid = r.relnamespace
+					WHERE a.attcollation = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+						AND rn.nspname NOT IN ('pg_catalog', 'information_schema'))
+				OR (c.collversion IS NOT NULL
+					AND c.collversion IS DISTINCT FROM pg_catalog.pg_collation_actual_version(c.oid)))
+		ORDER BY 2, 3`)
+	if err != nil {
+		return nil, nil, err
+	}
+	return databases, collations, nil
+}
+
+// formatCollationVersion formats one database or collation
+func formatCollationVersion(c collationVersion) string {
+	name := c.Name
+	if c.Schema != "" {
+		name = c.Schema + "." + c.Name
+	}
+	line := fmt.Sprintf("%s: %s", name, c.Provider)
+	if c.Locale != "" {
+		line += fmt.Sprintf(", locale %s", c.Locale)
+	}
+	switch {
+	case c.mismatched():
+		line += fmt.Sprintf(", recorded version %s, library now provides %s - MISMATCH", c.Recorded, c.Actual)
+	case c.Recorded != "":
+		line += fmt.Sprintf(", version %s", c.Recorded)
+	case c.Actual != "":
+		line += fmt.Sprintf(", version %s (not recorded)", c.Actual)
+	default:
+		line += ", unversioned"
+	}
+	if c.Current {
+		line += " (current database)"
+	}
+	if !c.Database && c.Indexes > 0 {
+		line += fmt.Sprintf(", used by %d indexes", c.Indexes)
+	}
+	return line
+}
+
+// formatCollationReport formats a collation report for the LLM
+func formatCollationReport(report *collationReport) string {
+	var sb strings.Builder
+	mismatches := report.mismatches()
+
+	status := "OK"
+	if len(mismatches) > 0 {
+		status = "WARNING"
+	}
+	sb.WriteString(fmt.Sprintf("Collation check status: %s\n", status))
+
+	sb.WriteString("\nDatabases:\n")
+	for _, d := range report.Databases {
+		sb.WriteString(fmt.Sprintf("  - %s\n", formatCollationVersion(d)))
+	}
+	if report.ServerVersion < 150000 {
+		sb.WriteString("  (database collation versions are tracked from PostgreSQL 15; check the collations below after OS upgrades)\n")
+	}
+
+	sb.WriteString("\nCollations in use in this database:\n")
+	if len(report.Collations) == 0 {
+		sb.WriteString("  (only the database default)\n")
+	}
+	for _, c := range report.Collations {
+		sb.WriteString(fmt.Sprintf("  - %s\n", formatCollationVersion(c)))
+	}
+
+	if len(mismatches) == 0 {
+		sb.WriteString("\nNo collation version mismatches found.\n")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("\nIndexes in %s using mismatched collations (%d):\n", report.CurrentDatabase, report.AffectedIndexCount))
+	if len(report.AffectedIndexes) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, idx := range report.AffectedIndexes {
+		unique := ""
+		if idx.Unique {
+			unique = "unique, "
+		}
+		sb.WriteString(fmt.Sprintf("  - %s.%s on %s.%s (%s%s, collations: %s)\n",
+			idx.Schema, idx.Index, idx.Schema, idx.Table, unique, formatSize(idx.Bytes), strings.Join(idx.Collations, ", ")))
+	}
+	if extra := report.AffectedIndexCount - int64(len(report.AffectedIndexes)); extra > 0 {
+		sb.WriteString(fmt.Sprintf("  ... and %d more\n", extra))
+	}
+
+	sb.WriteString("\nREINDEX guidance:\n")
+	for i, step := range collationGuidance(report) {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, step))
+	}
+	return sb.String()
+}
+
+// collationGuidance returns the steps that resolve the mismatches in a
+// report, with the SQL to run
+func collationGuidance(report *collationReport) []string {
+	var steps []string
+	concurrently := ""
+	if report.ServerVersion >= 120000 {
+		concurrently = " CONCURRENTLY"
+	}
+
+	currentMismatched := false
+	var others []string
+	for _, d := range report.Databases {
+		if !d.mismatched() {
+			continue
+		}
+		if d.Current {
+			currentMismatched = true
+		} else {
+			others = append(others, d.Name)
+		}
+	}
+
+	if report.AffectedIndexCount > 0 {
+		steps = append(steps, "Until the indexes are rebuilt, queries using them may miss rows and unique indexes may admit duplicates. "+
+			"To confirm the damage first, check an index with the amcheck extension: "+
+			"SELECT bt_index_check('schema.index'::regclass, true);")
+
+		var sb strings.Builder
+		switch {
+		case report.AffectedIndexCount <= int64(len(report.AffectedIndexes)):
+			sb.WriteString("Rebuild the affected indexes:")
+			for _, idx := range report.AffectedIndexes {
+				sb.WriteString(fmt.Sprintf("\n   REINDEX INDEX%s %s.%s;", concurrently,
+					quoteIdentIfNeeded(idx.Schema), quoteIdentIfNeeded(idx.Index)))
+			}
+		case report.Schema != "":
+			sb.WriteString(fmt.Sprintf("Rebuild the affected indexes; with this many, rebuilding every index in the schema is simplest:\n   REINDEX SCHEMA%s %s;",
+				concurrently, quoteIdentIfNeeded(report.Schema)))
+		default:
+			sb.WriteString(fmt.Sprintf("Rebuild the affected indexes; with this many, rebuilding every index in the database is simplest:\n   REINDEX DATABASE%s %s;",
+				concurrently, quoteIdentIfNeeded(report.CurrentDatabase)))
+		}
+		steps = append(steps, sb.String())
+		steps = append(steps, "If rebuilding a unique index fails with a duplicate key error, remove the duplicate rows the mismatch let in, then run the REINDEX again.")
+	}
+	if report.Schema != "" {
+		steps = append(steps, fmt.Sprintf("Only indexes in schema %s were checked; run check_collations without a schema to find the rest before recording the new versions.",
+			report.Schema))
+	}
+
+	var refresh strings.Builder
+	for _, c := range report.Collations {
+		if c.mismatched() {
+			refresh.WriteString(fmt.Sprintf("\n   ALTER COLLATION %s.%s REFRESH VERSION;", quoteIdentIfNeeded(c.Schema), quoteIdentIfNeeded(c.Name)))
+		}
+	}
+	if currentMismatched {
+		refresh.WriteString(fmt.Sprintf("\n   ALTER DATABASE %s REFRESH COLLATION VERSION;", quoteIdentIfNeeded(report.CurrentDatabase)))
+	}
+	if refresh.Len() > 0 {
+		steps = append(steps, "After the rebuild, record the new versions so the warnings stop:"+refresh.String())
+	}
+
+	if len(others) > 0 {
+		steps = append(steps, fmt.Sprintf("Databases %s also have a mismatch; connect to each, run check_collations, and repeat these steps there.",
+			strings.Join(others, ", ")))
+	}
+	return steps
+}
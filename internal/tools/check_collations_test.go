@@ -0,0 +1,120 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+// sampleCollationReport returns a report for a database whose collations
+// match the libraries
+func sampleCollationReport() *collationReport {
+	return &collationReport{
+		ServerVersion:   160000,
+		CurrentDatabase: "app",
+		Databases: []collationVersion{
+			{Database: true, Current: true, Name: "app", Provider: "libc", Locale: "en_US.UTF-8", Recorded: "2.36", Actual: "2.36"},
+			{Database: true, Name: "postgres", Provider: "libc", Locale: "C", Recorded: "", Actual: ""},
+		},
+		Collations: []collationVersion{
+			{OID: 12345, Schema: "pg_catalog", Name: "de-x-icu", Provider: "icu", Locale: "de", Recorded: "153.120", Actual: "153.120", Indexes: 1},
+		},
+	}
+}
+
+func TestCollationVersionMismatched(t *testing.T) {
+	tests := []struct {
+		recorded, actual string
+		want             bool
+	}{
+		{"2.36", "2.36", false},
+		{"2.31", "2.36", true},
+		{"", "2.36", false}, // Not tracked
+		{"2.31", "", false}, // Unversioned now
+	}
+	for _, tt := range tests {
+		c := collationVersion{Recorded: tt.recorded, Actual: tt.actual}
+		if got := c.mismatched(); got != tt.want {
+			t.Errorf("mismatched(%q, %q) = %v, want %v", tt.recorded, tt.actual, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCollationReport(t *testing.T) {
+	report := sampleCollationReport()
+	out := formatCollationReport(report)
+	for _, want := range []string{
+		"Collation check status: OK",
+		"app: libc, locale en_US.UTF-8, version 2.36 (current database)",
+		"postgres: libc, locale C, unversioned",
+		"pg_catalog.de-x-icu: icu, locale de, version 153.120, used by 1 indexes",
+		"No collation version mismatches found.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in report:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "REINDEX") {
+		t.Errorf("expected no REINDEX guidance without mismatches:\n%s", out)
+	}
+
+	report.Databases[0].Recorded = "2.31"
+	report.AffectedIndexes = []collationIndex{
+		{Schema: "public", Table: "users", Index: "users_email_key", Bytes: 8192, Unique: true, Collations: []string{"default"}},
+		{Schema: "Sales", Table: "orders", Index: "orders_customer_idx", Bytes: 16384, Collations: []string{"default"}},
+	}
+	report.AffectedIndexCount = 2
+	out = formatCollationReport(report)
+	for _, want := range []string{
+		"Collation check status: WARNING",
+		"recorded version 2.31, library now provides 2.36 - MISMATCH",
+		"Indexes in app using mismatched collations (2):",
+		"public.users_email_key on public.users (unique, 8.0 kB, collations: default)",
+		"REINDEX INDEX CONCURRENTLY public.users_email_key;",
+		`REINDEX INDEX CONCURRENTLY "Sales".orders_customer_idx;`,
+		"ALTER DATABASE app REFRESH COLLATION VERSION;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in report:\n%s", want, out)
+		}
+	}
+}
+
+func TestCollationGuidance(t *testing.T) {
+	report := sampleCollationReport()
+	report.ServerVersion = 110000
+	report.Collations[0].Actual = "153.121"
+	report.Databases[1].Recorded, report.Databases[1].Actual = "2.31", "2.36"
+	report.AffectedIndexes = []collationIndex{{Schema: "public", Table: "t", Index: "t_name_idx", Collations: []string{"de-x-icu"}}}
+	report.AffectedIndexCount = 30
+
+	steps := strings.Join(collationGuidance(report), "\n")
+	for _, want := range []string{
+		"bt_index_check",
+		"REINDEX DATABASE app;", // No CONCURRENTLY before PostgreSQL 12
+		"ALTER COLLATION pg_catalog.\"de-x-icu\" REFRESH VERSION;",
+		"Databases postgres also have a mismatch",
+	} {
+		if !strings.Contains(steps, want) {
+			t.Errorf("expected %q in guidance:\n%s", want, steps)
+		}
+	}
+	if strings.Contains(steps, "ALTER DATABASE") {
+		t.Errorf("expected no ALTER DATABASE when the current database matches:\n%s", steps)
+	}
+
+	report.Schema = "public"
+	steps = strings.Join(collationGuidance(report), "\n")
+	if !strings.Contains(steps, "REINDEX SCHEMA public;") || !strings.Contains(steps, "Only indexes in schema public were checked") {
+		t.Errorf("expected schema-level guidance:\n%s", steps)
+	}
+}
@@ -0,0 +1,290 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/plans"
+)
+
+// planCostThreshold is the relative change of a plan's total cost reported
+// as a regression or an improvement
+const planCostThreshold = 0.10
+
+// ComparePlanTool creates the compare_plan tool, which stores the plan of a
+// named query as a baseline in the data directory and compares later plans
+// of the query with it
+func ComparePlanTool(dbClient *database.Client, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "compare_plan",
+			Description: `Detect query plan regressions by comparing a query's current plan with a
+stored baseline plan.
+
+<usecase>
+Use to:
+- Save the plan of an important query before a schema change, index change,
+  upgrade or configuration change
+- Check afterwards whether the plan changed and how its cost moved
+- Compare a rewritten query with the plan of the original
+</usecase>
+
+<behavior>
+- The first call for a name saves the query's current plan as the baseline
+- Later calls plan the baseline's query again (or the given query) and list
+  every plan node as unchanged (=), replaced (~), added (+) or removed (-),
+  with its cost before and after
+- A total cost change of more than 10% is reported as a regression or an
+  improvement
+- update_baseline=true replaces the baseline with the current plan
+</behavior>
+
+<important>
+- Plans are made with EXPLAIN (FORMAT JSON), without ANALYZE, in a
+  read-only transaction: the query is never run
+- Costs are the planner's estimates; they change with table statistics as
+  well as with the plan
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the baseline: letters, digits, '_' and '-', e.g. 'orders_by_customer'",
+					},
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "The query to plan (default: the baseline's query; required for a new baseline)",
+					},
+					"update_baseline": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace the baseline with the current plan after comparing (default: false)",
+						"default":     false,
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			name, errResp := ValidateStringParam(args, "name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			if err := plans.ValidateName(name); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			query := strings.TrimSpace(ValidateOptionalStringParam(args, "query", ""))
+			updateBaseline := ValidateBoolParam(args, "update_baseline", false)
+
+			store := plans.NewStoreFromConfig(cfg)
+			baseline, err := store.Open(name)
+			if err != nil && !errors.Is(err, plans.ErrNotFound) {
+				return mcp.NewToolError(err.Error())
+			}
+			if baseline == nil && query == "" {
+				return mcp.NewToolError(fmt.Sprintf("No plan baseline named %q exists; pass the query to save its plan as the baseline", name))
+			}
+			if query == "" {
+				query = baseline.Query
+			}
+			if err := checkPlannableQuery(query); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+			data, err := explainJSON(ctx, pool, query)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to plan the query: %v", err))
+			}
+			current, err := plans.ParsePlan(data)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			var sb strings.Builder
+			sanitized := database.SanitizeConnStr(connStr)
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", sanitized))
+
+			newBaseline := &plans.Baseline{
+				Name:      name,
+				Database:  sanitized,
+				Query:     query,
+				CreatedAt: time.Now().UTC(),
+				Plan:      data,
+			}
+
+			if baseline == nil {
+				if err := store.Save(newBaseline); err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+				logging.Info("compare_plan_executed", "name", name, "baseline_created", true)
+				sb.WriteString(fmt.Sprintf("Saved plan baseline %q for: %s\n\n", name, statementPreview(query)))
+				sb.WriteString(fmt.Sprintf("Plan: %s, cost %.2f, %.0f rows estimated\n", current.Label(), current.TotalCost, current.PlanRows))
+				sb.WriteString("\nCall compare_plan with the same name later to compare the query's plan with this baseline.\n")
+				return mcp.NewToolSuccess(sb.String())
+			}
+
+			before, err := plans.ParsePlan(baseline.Plan)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Plan baseline %q is corrupt: %v", name, err))
+			}
+
+			sb.WriteString(fmt.Sprintf("Baseline: %s (saved %s)\n", name, baseline.CreatedAt.Format(time.RFC3339)))
+			if baseline.Database != sanitized {
+				sb.WriteString(fmt.Sprintf("Note: the baseline was saved on %s; plans of different databases may differ for that reason alone\n", baseline.Database))
+			}
+			if query != baseline.Query {
+				sb.WriteString(fmt.Sprintf("Baseline query: %s\n", statementPreview(baseline.Query)))
+			}
+			sb.WriteString(fmt.Sprintf("Query: %s\n\n", statementPreview(query)))
+
+			changes := plans.Diff(before, current)
+			sb.WriteString(formatPlanComparison(before, current, changes))
+
+			if updateBaseline {
+				if err := store.Save(newBaseline); err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+				sb.WriteString("\nThe baseline was replaced with the current plan.\n")
+			}
+
+			logging.Info("compare_plan_executed",
+				"name", name,
+				"nodes_changed", countPlanChanges(changes),
+				"cost_before", before.TotalCost,
+				"cost_after", current.TotalCost,
+				"baseline_updated", updateBaseline,
+			)
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// checkPlannableQuery accepts a single statement that EXPLAIN can plan
+func checkPlannableQuery(query string) error {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return fmt.Errorf("could not parse the query: %w", err)
+	}
+	statements := splitStatements(tokens)
+	if len(statements) != 1 {
+		return fmt.Errorf("compare_plan plans a single statement; the query has %d", len(statements))
+	}
+	first := statements[0][0]
+	if !explainableStatements[first.upper] && !first.isPunct("(") {
+		return fmt.Errorf("%s statements cannot be planned; use SELECT, INSERT, UPDATE, DELETE or MERGE", first.upper)
+	}
+	return nil
+}
+
+// explainJSON plans a query with EXPLAIN (FORMAT JSON) in a read-only
+// transaction that is rolled back
+func explainJSON(ctx context.Context, pool *pgxpool.Pool, query string) ([]byte, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // The transaction only plans
+
+	var data []byte
+	if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// countPlanChanges counts the nodes that are not the same in both plans
+func countPlanChanges(changes []plans.Change) int {
+	count := 0
+	for _, c := range changes {
+		if c.Kind != plans.Unchanged {
+			count++
+		}
+	}
+	return count
+}
+
+// formatCostChange describes a cost change, such as "8.30 -> 1450.00 (+17369.9%)"
+func formatCostChange(before, after float64) string {
+	if before == 0 {
+		return fmt.Sprintf("%.2f -> %.2f", before, after)
+	}
+	return fmt.Sprintf("%.2f -> %.2f (%+.1f%%)", before, after, plans.CostChange(before, after)*100)
+}
+
+// formatPlanComparison renders the verdict and the node-by-node changes of
+// a plan compared with its baseline
+func formatPlanComparison(before, after *plans.Node, changes []plans.Change) string {
+	var sb strings.Builder
+	changed := countPlanChanges(changes)
+	costChange := plans.CostChange(before.TotalCost, after.TotalCost)
+
+	switch {
+	case costChange > planCostThreshold:
+		sb.WriteString("Result: REGRESSION, the estimated cost increased")
+	case costChange < -planCostThreshold:
+		sb.WriteString("Result: IMPROVED, the estimated cost decreased")
+	case changed > 0:
+		sb.WriteString("Result: PLAN CHANGED, the estimated cost is similar")
+	default:
+		sb.WriteString("Result: UNCHANGED")
+	}
+	if changed > 0 {
+		sb.WriteString(fmt.Sprintf("; %d plan node(s) differ", changed))
+	} else {
+		sb.WriteString("; the plan has the same shape")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("Total cost: %s\n", formatCostChange(before.TotalCost, after.TotalCost)))
+	sb.WriteString(fmt.Sprintf("Rows estimated: %.0f -> %.0f\n", before.PlanRows, after.PlanRows))
+
+	sb.WriteString("\nPlan nodes (= unchanged, ~ replaced, + added, - removed):\n")
+	for _, c := range changes {
+		indent := strings.Repeat("  ", c.Depth)
+		switch c.Kind {
+		case plans.Added:
+			sb.WriteString(fmt.Sprintf("+ %s%s (cost %.2f)\n", indent, c.After.Label(), c.After.TotalCost))
+		case plans.Removed:
+			sb.WriteString(fmt.Sprintf("- %s%s (cost %.2f)\n", indent, c.Before.Label(), c.Before.TotalCost))
+		case plans.Replaced:
+			sb.WriteString(fmt.Sprintf("~ %s%s -> %s (cost %s)\n", indent, c.Before.Label(), c.After.Label(),
+				formatCostChange(c.Before.TotalCost, c.After.TotalCost)))
+		default:
+			sb.WriteString(fmt.Sprintf("= %s%s (cost %s)\n", indent, c.After.Label(),
+				formatCostChange(c.Before.TotalCost, c.After.TotalCost)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/plans"
+)
+
+func TestCheckPlannableQuery(t *testing.T) {
+	for _, query := range []string{
+		"SELECT * FROM orders WHERE id = 1",
+		"WITH x AS (SELECT 1) SELECT * FROM x",
+		"(SELECT 1) UNION (SELECT 2)",
+		"UPDATE orders SET status = 'x' WHERE id = 1;",
+	} {
+		if err := checkPlannableQuery(query); err != nil {
+			t.Errorf("checkPlannableQuery(%q) error = %v", query, err)
+		}
+	}
+	for _, query := range []string{
+		"",
+		"SELECT 1; SELECT 2",
+		"CREATE TABLE t (id int)",
+		"VACUUM orders",
+		"SELECT 'unterminated",
+	} {
+		if err := checkPlannableQuery(query); err == nil {
+			t.Errorf("checkPlannableQuery(%q) expected an error", query)
+		}
+	}
+}
+
+func TestFormatPlanComparison(t *testing.T) {
+	before := &plans.Node{NodeType: "Limit", TotalCost: 8.5, PlanRows: 1, Plans: []*plans.Node{
+		{NodeType: "Index Scan", IndexName: "orders_pkey", RelationName: "orders", TotalCost: 8.3, PlanRows: 1},
+	}}
+	after := &plans.Node{NodeType: "Limit", TotalCost: 1450, PlanRows: 1, Plans: []*plans.Node{
+		{NodeType: "Seq Scan", RelationName: "orders", TotalCost: 1449, PlanRows: 1},
+	}}
+
+	out := formatPlanComparison(before, after, plans.Diff(before, after))
+	for _, want := range []string{
+		"Result: REGRESSION, the estimated cost increased; 1 plan node(s) differ",
+		"Total cost: 8.50 -> 1450.00 (+16958.8%)",
+		"= Limit (cost 8.50 -> 1450.00",
+		"~   Index Scan using orders_pkey on orders -> Seq Scan on orders (cost 8.30 -> 1449.00",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	out = formatPlanComparison(after, before, plans.Diff(after, before))
+	if !strings.Contains(out, "Result: IMPROVED") {
+		t.Errorf("expected an improvement:\n%s", out)
+	}
+
+	same := formatPlanComparison(before, before, plans.Diff(before, before))
+	if !strings.Contains(same, "Result: UNCHANGED; the plan has the same shape") {
+		t.Errorf("expected an unchanged plan:\n%s", same)
+	}
+
+	// A new node with a similar cost
+	sorted := &plans.Node{NodeType: "Sort", TotalCost: 8.6, Plans: []*plans.Node{before}}
+	out = formatPlanComparison(before, sorted, plans.Diff(before, sorted))
+	if !strings.Contains(out, "Result: PLAN CHANGED") || !strings.Contains(out, "+ Sort (cost 8.60)") {
+		t.Errorf("expected a changed plan:\n%s", out)
+	}
+}
+
+func TestComparePlanTool_MissingBaseline(t *testing.T) {
+	cfg := &config.Config{DataDir: t.TempDir()}
+	tool := ComparePlanTool(nil, cfg)
+
+	resp, err := tool.Handler(map[string]interface{}{"name": "orders"})
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	if !resp.IsError || !strings.Contains(resp.Content[0].Text, "No plan baseline named") {
+		t.Errorf("expected a missing baseline error, got %+v", resp)
+	}
+
+	resp, _ = tool.Handler(map[string]interface{}{"name": "../orders", "query": "SELECT 1"})
+	if !resp.IsError || !strings.Contains(resp.Content[0].Text, "invalid plan baseline name") {
+		t.Errorf("expected an invalid name error, got %+v", resp)
+	}
+
+	resp, _ = tool.Handler(map[string]interface{}{"name": "orders", "query": "DROP TABLE orders"})
+	if !resp.IsError || !strings.Contains(resp.Content[0].Text, "cannot be planned") {
+		t.Errorf("expected a statement error, got %+v", resp)
+	}
+}
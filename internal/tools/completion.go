@@ -0,0 +1,187 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// Kinds of argument values that can be completed
+const (
+	completeDatabase = "database"
+	completeSchema   = "schema"
+	completeTable    = "table"
+	completeColumn   = "column"
+)
+
+// completionKinds maps tool and prompt argument names to what they name
+var completionKinds = map[string]string{
+	"database":      completeDatabase,
+	"database_name": completeDatabase,
+	"schema":        completeSchema,
+	"schema_name":   completeSchema,
+	"table":         completeTable,
+	"table_name":    completeTable,
+	"column":        completeColumn,
+	"column_name":   completeColumn,
+	"text_column":   completeColumn,
+	"vector_column": completeColumn,
+}
+
+// Complete suggests values for tool and prompt arguments that name a
+// database, schema, table or column. Suggestions come from the metadata
+// cache of the caller's current database, and only databases the caller may
+// access are offered.
+func (p *ContextAwareProvider) Complete(ctx context.Context, params mcp.CompleteParams) (mcp.Completion, error) {
+	// Tool arguments are only completed for enabled tools that take them
+	if params.Ref.Type == mcp.CompletionRefTool {
+		cfg, base := p.current()
+		tool, exists := base.Get(params.Ref.Name)
+		if !exists || !cfg.IsToolAvailable(params.Ref.Name) {
+			return mcp.Completion{}, nil
+		}
+		if _, ok := tool.Definition.InputSchema.Properties[params.Argument.Name]; !ok {
+			return mcp.Completion{}, nil
+		}
+	}
+
+	kind := completionKinds[params.Argument.Name]
+	if kind == "" {
+		return mcp.Completion{}, nil
+	}
+
+	if p.authEnabled && auth.GetTokenHashFromContext(ctx) == "" {
+		return mcp.Completion{}, fmt.Errorf("no authentication token found in request context")
+	}
+
+	value := params.Argument.Value
+	if kind == completeDatabase {
+		var names []string
+		configs := p.clientManager.GetDatabaseConfigs()
+		if p.accessChecker != nil {
+			configs = p.accessChecker.GetAccessibleDatabases(ctx, configs)
+		}
+		for i := range configs {
+			names = append(names, configs[i].Name)
+		}
+		return matchCompletions(names, value), nil
+	}
+
+	client, err := p.getClient(ctx)
+	if err != nil {
+		return mcp.Completion{}, err
+	}
+	if !client.IsMetadataLoadedFor(client.GetDefaultConnection()) {
+		return mcp.Completion{}, nil
+	}
+
+	schema := params.ContextArgument("schema")
+	if schema == "" {
+		schema = params.ContextArgument("schema_name")
+	}
+
+	switch kind {
+	case completeSchema:
+		return matchCompletions(client.GetSchemaNames(), value), nil
+
+	case completeTable:
+		// A qualified value completes the table within its schema
+		if schema == "" {
+			if dot := strings.Index(value, "."); dot >= 0 {
+				prefix := value[:dot+1]
+				tables := tableNames(client.GetSchemaMetadata(value[:dot]))
+				for i := range tables {
+					tables[i] = prefix + tables[i]
+				}
+				return matchCompletions(tables, value), nil
+			}
+			return matchCompletions(tableNames(client.GetMetadata()), value), nil
+		}
+		return matchCompletions(tableNames(client.GetSchemaMetadata(schema)), value), nil
+
+	case completeColumn:
+		table := params.ContextArgument("table")
+		if table == "" {
+			table = params.ContextArgument("table_name")
+		}
+		if table == "" {
+			return mcp.Completion{}, nil
+		}
+		if schema == "" {
+			if dot := strings.Index(table, "."); dot >= 0 {
+				schema, table = table[:dot], table[dot+1:]
+			}
+		}
+		var metadata map[string]database.TableInfo
+		if schema != "" {
+			metadata = client.GetSchemaMetadata(schema)
+		} else {
+			metadata = client.GetMetadata()
+		}
+		return matchCompletions(columnNames(metadata, table), value), nil
+	}
+
+	return mcp.Completion{}, nil
+}
+
+// tableNames returns the names of the tables in metadata
+func tableNames(metadata map[string]database.TableInfo) []string {
+	names := make([]string, 0, len(metadata))
+	for _, table := range metadata {
+		names = append(names, table.TableName)
+	}
+	return names
+}
+
+// columnNames returns the column names of the tables called table
+// in metadata
+func columnNames(metadata map[string]database.TableInfo, table string) []string {
+	var names []string
+	for _, info := range metadata {
+		if info.TableName != table {
+			continue
+		}
+		for _, column := range info.Columns {
+			names = append(names, column.ColumnName)
+		}
+	}
+	return names
+}
+
+// matchCompletions returns the sorted, distinct candidates that start with
+// prefix, ignoring case
+func matchCompletions(candidates []string, prefix string) mcp.Completion {
+	prefix = strings.ToLower(prefix)
+	seen := make(map[string]bool, len(candidates))
+	values := []string{}
+	for _, candidate := range candidates {
+		if seen[candidate] || !strings.HasPrefix(strings.ToLower(candidate), prefix) {
+			continue
+		}
+		seen[candidate] = true
+		values = append(values, candidate)
+	}
+	sort.Strings(values)
+
+	completion := mcp.Completion{Values: values, Total: len(values)}
+	if len(values) > mcp.MaxCompletionValues {
+		completion.Values = values[:mcp.MaxCompletionValues]
+		completion.HasMore = true
+	}
+	return completion
+}
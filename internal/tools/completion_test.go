@@ -0,0 +1,126 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/resources"
+)
+
+func TestMatchCompletions(t *testing.T) {
+	candidates := []string{"orders", "Order_Items", "customers", "orders"}
+
+	got := matchCompletions(candidates, "ord")
+	if want := []string{"Order_Items", "orders"}; !reflect.DeepEqual(got.Values, want) {
+		t.Errorf("matchCompletions() = %v, want %v", got.Values, want)
+	}
+	if got.Total != 2 || got.HasMore {
+		t.Errorf("expected total 2 without more, got %+v", got)
+	}
+
+	if got := matchCompletions(nil, "x"); got.Values == nil || len(got.Values) != 0 {
+		t.Errorf("expected an empty list, got %#v", got.Values)
+	}
+
+	many := make([]string, 150)
+	for i := range many {
+		many[i] = fmt.Sprintf("t%03d", i)
+	}
+	got = matchCompletions(many, "")
+	if len(got.Values) != mcp.MaxCompletionValues || got.Total != 150 || !got.HasMore {
+		t.Errorf("expected %d of 150 values with more, got %d (total %d, hasMore %v)",
+			mcp.MaxCompletionValues, len(got.Values), got.Total, got.HasMore)
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	metadata := map[string]database.TableInfo{
+		"public.orders": {SchemaName: "public", TableName: "orders", Columns: []database.ColumnInfo{
+			{ColumnName: "id"}, {ColumnName: "total"},
+		}},
+		"public.customers": {SchemaName: "public", TableName: "customers", Columns: []database.ColumnInfo{
+			{ColumnName: "name"},
+		}},
+	}
+
+	got := matchCompletions(columnNames(metadata, "orders"), "")
+	if want := []string{"id", "total"}; !reflect.DeepEqual(got.Values, want) {
+		t.Errorf("columnNames() = %v, want %v", got.Values, want)
+	}
+	if got := columnNames(metadata, "missing"); len(got) != 0 {
+		t.Errorf("expected no columns for an unknown table, got %v", got)
+	}
+}
+
+func TestContextAwareProvider_Complete(t *testing.T) {
+	clientManager := database.NewClientManager([]config.NamedDatabaseConfig{
+		{Name: "production", Host: "localhost", Database: "prod"},
+		{Name: "staging", Host: "localhost", Database: "stage"},
+	})
+	defer clientManager.CloseAll()
+
+	cfg := &config.Config{}
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, database.NewClient(nil), cfg, nil, "", nil, 0, nil)
+
+	complete := func(refType, name, argument, value string) mcp.Completion {
+		t.Helper()
+		completion, err := provider.Complete(context.Background(), mcp.CompleteParams{
+			Ref:      mcp.CompletionRef{Type: refType, Name: name},
+			Argument: mcp.CompletionArgument{Name: argument, Value: value},
+		})
+		if err != nil {
+			t.Fatalf("Complete() error = %v", err)
+		}
+		return completion
+	}
+
+	// Database names come from the configuration
+	got := complete(mcp.CompletionRefPrompt, "custom-prompt", "database", "st")
+	if want := []string{"staging"}; !reflect.DeepEqual(got.Values, want) {
+		t.Errorf("database completion = %v, want %v", got.Values, want)
+	}
+
+	// Tool arguments are only completed if the tool takes them
+	if got := complete(mcp.CompletionRefTool, "count_rows", "database", ""); len(got.Values) != 0 {
+		t.Errorf("expected no values for an argument count_rows does not take, got %v", got.Values)
+	}
+	if got := complete(mcp.CompletionRefTool, "no_such_tool", "table", ""); len(got.Values) != 0 {
+		t.Errorf("expected no values for an unknown tool, got %v", got.Values)
+	}
+
+	// Arguments that do not name database objects are not completed
+	if got := complete(mcp.CompletionRefTool, "query_database", "query", "SEL"); len(got.Values) != 0 {
+		t.Errorf("expected no values for a free-text argument, got %v", got.Values)
+	}
+}
+
+func TestContextAwareProvider_Complete_RequiresToken(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	cfg := &config.Config{}
+	resourceReg := resources.NewContextAwareRegistry(clientManager, true, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, true, database.NewClient(nil), cfg, nil, "", nil, 0, nil)
+
+	_, err := provider.Complete(context.Background(), mcp.CompleteParams{
+		Ref:      mcp.CompletionRef{Type: mcp.CompletionRefTool, Name: "count_rows"},
+		Argument: mcp.CompletionArgument{Name: "table", Value: "ord"},
+	})
+	if err == nil {
+		t.Error("expected an error without an authentication token")
+	}
+}
@@ -14,13 +14,20 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"pgedge-postgres-mcp/internal/auth"
 	"pgedge-postgres-mcp/internal/config"
 	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/dbstore"
+	"pgedge-postgres-mcp/internal/masking"
 	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/metrics"
 	"pgedge-postgres-mcp/internal/resources"
+	"pgedge-postgres-mcp/internal/tracing"
 )
 
 // ContextAwareProvider wraps a tool registry and provides per-token database clients
@@ -37,11 +44,27 @@ type ContextAwareProvider struct {
 	rateLimiter       *auth.RateLimiter           // Rate limiter for authentication attempts
 	maxFailedAttempts int                         // Maximum failed attempts before account lockout
 	accessChecker     *auth.DatabaseAccessChecker // Database access control checker
+	masker            *masking.Masker             // Data masking rules for query results (nil = disabled)
+	quotaLimiter      *auth.QuotaLimiter          // Per-token tool usage limits (nil = unlimited)
+	contextUsage      *ContextUsageTracker        // Tool output returned per conversation
+	transactions      *TransactionManager         // Transactions opened with begin_transaction
+	schemaIndex       *SchemaIndex                // Table embeddings for find_relevant_tables
+	queryHistory      *QueryHistory               // Queries and cached results per session
 
 	// Cache of registries per client to avoid re-creating tools on every Execute()
+	// mu also guards cfg, masker, baseRegistry, kbCfg and kbErr, which are replaced
+	// on reload
 	mu               sync.RWMutex
 	clientRegistries map[*database.Client]*Registry
 
+	// Why the knowledgebase can't be searched (nil = available or not in use)
+	// search_knowledgebase and list_kb_projects are only registered while it
+	// is available
+	kbErr error
+	// Knowledgebase settings with the embedding provider and model resolved
+	// from the knowledgebase's contents
+	kbCfg config.KnowledgebaseConfig
+
 	// Hidden tools registry (not advertised to LLM but available for execution)
 	hiddenRegistry *Registry
 }
@@ -54,34 +77,146 @@ func (p *ContextAwareProvider) registerStatelessTools(registry *Registry) {
 	registry.Register("read_resource", ReadResourceTool(p.createResourceAdapter()))
 
 	// Embedding generation tool (stateless, only requires config)
-	if p.cfg.Builtins.Tools.IsToolEnabled("generate_embedding") {
+	if p.cfg.IsToolAvailable("generate_embedding") {
 		registry.Register("generate_embedding", GenerateEmbeddingTool(p.cfg))
 	}
 
 	// Knowledgebase search tool (if enabled in both knowledgebase config and builtins config)
-	if p.cfg.Knowledgebase.Enabled && p.cfg.Knowledgebase.DatabasePath != "" &&
-		p.cfg.Builtins.Tools.IsToolEnabled("search_knowledgebase") {
-		registry.Register("search_knowledgebase", SearchKnowledgebaseTool(p.cfg.Knowledgebase.DatabasePath, p.cfg))
+	if kbInUse(p.cfg) && p.kbErr == nil {
+		registry.Register("search_knowledgebase", SearchKnowledgebaseTool(p.kbCfg))
+		if p.cfg.IsToolAvailable("list_kb_projects") {
+			registry.Register("list_kb_projects", ListKBProjectsTool(p.kbCfg))
+		}
+	}
+
+	// Tool output accounting for the caller's conversation
+	if p.cfg.IsToolAvailable("get_context_usage") {
+		registry.Register("get_context_usage", GetContextUsageTool(p.contextUsage))
+	}
+
+	// Queries run with query_database in the caller's session
+	if p.cfg.IsToolAvailable("get_query_history") {
+		registry.Register("get_query_history", GetQueryHistoryTool(p.queryHistory))
+	}
+
+	// Scheduled jobs, when the scheduler runs
+	if p.cfg.Scheduler.Enabled && p.cfg.IsToolAvailable("list_jobs") {
+		registry.Register("list_jobs", ListJobsTool())
+	}
+
+	// Schema snapshots are stored in the data directory
+	if p.cfg.IsToolAvailable("list_schema_snapshots") {
+		registry.Register("list_schema_snapshots", ListSchemaSnapshotsTool(p.cfg))
+	}
+
+	// The configured databases the caller may access
+	if p.cfg.IsToolAvailable("list_databases") {
+		registry.Register("list_databases", ListDatabasesTool(
+			database.NewHTTPDatabaseProvider(p.clientManager, p.authEnabled, p.accessChecker)))
+	}
+
+	// Databases added at runtime are stored in the data directory
+	if p.cfg.IsToolAvailable("add_database_connection") {
+		registry.Register("add_database_connection", AddDatabaseConnectionTool(p.clientManager, dbstore.NewStoreFromConfig(p.cfg)))
 	}
 }
 
 // registerDatabaseTools registers all database-dependent tools
 func (p *ContextAwareProvider) registerDatabaseTools(registry *Registry, client *database.Client) {
-	if p.cfg.Builtins.Tools.IsToolEnabled("query_database") {
-		registry.Register("query_database", QueryDatabaseTool(client))
+	federation := p.federation(client)
+	if p.cfg.IsToolAvailable("query_database") {
+		registry.Register("query_database", QueryDatabaseTool(client, p.masker, p.transactions, federation, p.queryHistory, p.cfg.QueryGuard))
 	}
-	if p.cfg.Builtins.Tools.IsToolEnabled("get_schema_info") {
-		registry.Register("get_schema_info", GetSchemaInfoTool(client))
+	if p.cfg.IsToolAvailable("get_schema_info") {
+		registry.Register("get_schema_info", GetSchemaInfoTool(client, p.masker))
 	}
-	if p.cfg.Builtins.Tools.IsToolEnabled("similarity_search") {
+	if p.cfg.IsToolAvailable("similarity_search") {
 		registry.Register("similarity_search", SimilaritySearchTool(client, p.cfg))
 	}
-	if p.cfg.Builtins.Tools.IsToolEnabled("execute_explain") {
-		registry.Register("execute_explain", ExecuteExplainTool(client))
+	if p.cfg.IsToolAvailable("execute_explain") {
+		registry.Register("execute_explain", ExecuteExplainTool(client, func() bool {
+			dbCfg := p.databaseConfig(client)
+			return dbCfg == nil || dbCfg.WritesAllowed()
+		}))
 	}
-	if p.cfg.Builtins.Tools.IsToolEnabled("count_rows") {
+	if p.cfg.IsToolAvailable("count_rows") {
 		registry.Register("count_rows", CountRowsTool(client))
 	}
+	if p.cfg.IsToolAvailable("export_query_results") {
+		registry.Register("export_query_results", ExportQueryResultsTool(client, p.masker, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("hybrid_search") {
+		registry.Register("hybrid_search", HybridSearchTool(client, p.masker, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("explain_sql") {
+		registry.Register("explain_sql", ExplainSQLTool(client))
+	}
+	if p.cfg.IsToolAvailable("validate_sql") {
+		registry.Register("validate_sql", ValidateSQLTool(client))
+	}
+	if p.cfg.IsToolAvailable("compare_plan") {
+		registry.Register("compare_plan", ComparePlanTool(client, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("plan_schema_change") {
+		registry.Register("plan_schema_change", PlanSchemaChangeTool(client))
+	}
+	if p.cfg.IsToolAvailable("get_table_stats") {
+		registry.Register("get_table_stats", GetTableStatsTool(client))
+	}
+	if p.cfg.IsToolAvailable("index_advisor") {
+		registry.Register("index_advisor", IndexAdvisorTool(client))
+	}
+	if p.cfg.IsToolAvailable("database_health_check") {
+		registry.Register("database_health_check", DatabaseHealthCheckTool(client, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("lock_analysis") {
+		registry.Register("lock_analysis", LockAnalysisTool(client))
+	}
+	if p.cfg.IsToolAvailable("check_collations") {
+		registry.Register("check_collations", CheckCollationsTool(client))
+	}
+	if p.cfg.IsToolAvailable("generate_migration") {
+		registry.Register("generate_migration", GenerateMigrationTool(client))
+	}
+	if p.cfg.IsToolAvailable("find_relevant_tables") {
+		registry.Register("find_relevant_tables", FindRelevantTablesTool(client, p.schemaIndex, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("refresh_schema_cache") {
+		registry.Register("refresh_schema_cache", RefreshSchemaCacheTool(client))
+	}
+	if p.cfg.IsToolAvailable("execute_script") {
+		registry.Register("execute_script", ExecuteScriptTool(client, p.masker))
+	}
+	if p.cfg.IsToolAvailable("apply_migration") {
+		registry.Register("apply_migration", ApplyMigrationTool(client, p.masker))
+	}
+	if p.cfg.IsToolAvailable("create_vector_index") {
+		registry.Register("create_vector_index", CreateVectorIndexTool(client))
+	}
+	if p.cfg.IsToolAvailable("install_extension") {
+		registry.Register("install_extension", InstallExtensionTool(client, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("setup_foreign_server") {
+		registry.Register("setup_foreign_server", SetupForeignServerTool(client, federation))
+	}
+	if p.cfg.IsToolAvailable("setup_semantic_search") {
+		registry.Register("setup_semantic_search", SetupSemanticSearchTool(client, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("snapshot_schema") {
+		registry.Register("snapshot_schema", SnapshotSchemaTool(client, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("restore_schema_snapshot") {
+		registry.Register("restore_schema_snapshot", RestoreSchemaSnapshotTool(client, p.cfg))
+	}
+	if p.cfg.IsToolAvailable("begin_transaction") {
+		registry.Register("begin_transaction", BeginTransactionTool(client, p.transactions))
+	}
+	if p.cfg.IsToolAvailable("commit_transaction") {
+		registry.Register("commit_transaction", CommitTransactionTool(p.transactions))
+	}
+	if p.cfg.IsToolAvailable("rollback_transaction") {
+		registry.Register("rollback_transaction", RollbackTransactionTool(p.transactions))
+	}
 }
 
 // NewContextAwareProvider creates a new context-aware tool provider
@@ -100,8 +235,38 @@ func NewContextAwareProvider(clientManager *database.ClientManager, resourceReg
 		accessChecker:     accessChecker,
 		clientRegistries:  make(map[*database.Client]*Registry),
 		hiddenRegistry:    NewRegistry(),
+		contextUsage:      NewContextUsageTracker(),
+		transactions:      NewTransactionManager(),
+		schemaIndex:       NewSchemaIndex(),
+		queryHistory:      NewQueryHistory(cfg.QueryHistory),
 	}
 
+	// Index the tables for find_relevant_tables as metadata is loaded. The
+	// fallback client loaded its metadata before the provider existed.
+	if clientManager != nil {
+		clientManager.SetMetadataLoadedFunc(provider.prepareSchemaIndex)
+	}
+	if fallbackClient != nil {
+		fallbackClient.SetMetadataLoadedFunc(provider.prepareSchemaIndex)
+		if fallbackClient.GetMetadataVersion() > 0 {
+			provider.prepareSchemaIndex(fallbackClient, fallbackClient.GetDefaultConnection())
+		}
+	}
+
+	// Compile masking rules once; configuration is validated at load time so
+	// an error here means the rules were modified programmatically
+	masker, err := masking.New(&cfg.Masking)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Data masking disabled: %v\n", err)
+	}
+	provider.masker = masker
+
+	// A missing or corrupt knowledgebase disables search_knowledgebase
+	// rather than preventing startup
+	kbCfg, kbErr := checkKnowledgebase(cfg)
+	provider.kbCfg = kbCfg
+	provider.setKnowledgebaseErrorLocked(kbErr)
+
 	// Register ALL tools in base registry so they're always visible in tools/list
 	// Database-dependent tools will fail gracefully in Execute() if no connection exists
 	// This provides better UX - users can discover all tools even before connecting
@@ -116,6 +281,16 @@ func NewContextAwareProvider(clientManager *database.ClientManager, resourceReg
 	return provider
 }
 
+// prepareSchemaIndex starts indexing the tables of a connection whose
+// metadata was loaded, when find_relevant_tables is available
+func (p *ContextAwareProvider) prepareSchemaIndex(client *database.Client, connStr string) {
+	cfg, _ := p.current()
+	if !cfg.IsToolAvailable("find_relevant_tables") {
+		return
+	}
+	p.schemaIndex.Prepare(cfg, connStr, client.GetMetadataFor(connStr))
+}
+
 // resourceReaderAdapter adapts ContextAwareRegistry to the ResourceReader interface
 // This provides backward compatibility for the read_resource tool
 type resourceReaderAdapter struct {
@@ -132,6 +307,59 @@ func (a *resourceReaderAdapter) Read(ctx context.Context, uri string) (mcp.Resou
 	return a.registry.Read(ctx, uri)
 }
 
+// federation gives the tools the configured databases the caller may access,
+// to wire them into client's database with postgres_fdw
+func (p *ContextAwareProvider) federation(client *database.Client) *Federation {
+	cfg := p.cfg
+	return &Federation{
+		Lookup: func(ctx context.Context, name string) (*config.NamedDatabaseConfig, error) {
+			configs := p.clientManager.GetDatabaseConfigs()
+			if p.accessChecker != nil {
+				configs = p.accessChecker.GetAccessibleDatabases(ctx, configs)
+			}
+			var names []string
+			for i := range configs {
+				if configs[i].Name == name {
+					return &configs[i], nil
+				}
+				names = append(names, configs[i].Name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("database %q is not configured or not accessible (databases: %s)", name, strings.Join(names, ", "))
+		},
+		CanWire: func(ctx context.Context) bool {
+			if dbCfg := p.databaseConfig(client); dbCfg != nil && !dbCfg.WritesAllowed() {
+				return false
+			}
+			// Setting up a foreign server writes to the database, which the
+			// read scope of query_database does not allow
+			return cfg.IsToolAvailable("setup_foreign_server") &&
+				(p.accessChecker == nil || (p.accessChecker.CanUseTool(ctx, "setup_foreign_server") &&
+					!p.accessChecker.IsReadOnlyTool(ctx, "query_database")))
+		},
+		Extensions: &cfg.Extensions,
+	}
+}
+
+// writesDatabase reports whether a tool call can modify the database:
+// write tools outside dry runs, and queries in a read-write transaction
+func (p *ContextAwareProvider) writesDatabase(ctx context.Context, name string, args map[string]interface{}) bool {
+	if name == "query_database" {
+		txn := p.transactions.current(ctx)
+		return txn != nil && txn.readWrite
+	}
+	return isDatabaseWrite(name, args)
+}
+
+// databaseConfig returns the current configuration of client's database,
+// which reflects configuration reloads, or nil when it is not configured
+func (p *ContextAwareProvider) databaseConfig(client *database.Client) *config.NamedDatabaseConfig {
+	if client == nil || client.DatabaseName() == "" {
+		return nil
+	}
+	return p.clientManager.GetDatabaseConfig(client.DatabaseName())
+}
+
 // createResourceAdapter creates an adapter for the resource registry
 func (p *ContextAwareProvider) createResourceAdapter() ResourceReader {
 	return &resourceReaderAdapter{
@@ -139,9 +367,122 @@ func (p *ContextAwareProvider) createResourceAdapter() ResourceReader {
 	}
 }
 
+// SetQuotaLimiter enables per-token tool usage limits
+// Limits only apply when authentication is enabled
+func (p *ContextAwareProvider) SetQuotaLimiter(q *auth.QuotaLimiter) {
+	p.quotaLimiter = q
+}
+
+// RollbackTransactions rolls back the transactions opened with
+// begin_transaction, releasing their connections before shutdown
+func (p *ContextAwareProvider) RollbackTransactions() {
+	p.transactions.RollbackAll()
+}
+
 // GetBaseRegistry returns the base registry for adding additional tools
 func (p *ContextAwareProvider) GetBaseRegistry() *Registry {
-	return p.baseRegistry
+	_, base := p.current()
+	return base
+}
+
+// current returns the active configuration and base registry
+func (p *ContextAwareProvider) current() (*config.Config, *Registry) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cfg, p.baseRegistry
+}
+
+// Reload applies a new configuration (builtin tool toggles, knowledgebase
+// settings and masking rules) without restarting the server
+// Cached per-client registries are discarded and rebuilt on next use
+func (p *ContextAwareProvider) Reload(cfg *config.Config) {
+	masker, err := masking.New(&cfg.Masking)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Data masking disabled: %v\n", err)
+	}
+	kbCfg, kbErr := checkKnowledgebase(cfg)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cfg = cfg
+	p.masker = masker
+	p.queryHistory.Configure(cfg.QueryHistory)
+	p.kbCfg = kbCfg
+	p.setKnowledgebaseErrorLocked(kbErr)
+	p.rebuildRegistriesLocked()
+}
+
+// CheckKnowledgebase checks again whether the knowledgebase can be opened,
+// registering or removing search_knowledgebase when that changes, and
+// returns the reason it can't be searched (nil = available or not in use)
+func (p *ContextAwareProvider) CheckKnowledgebase() error {
+	cfg, _ := p.current()
+	kbCfg, kbErr := checkKnowledgebase(cfg)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The configuration was reloaded, and checked, meanwhile
+	if p.cfg != cfg {
+		return p.kbErr
+	}
+	kbCfgChanged := p.kbCfg != kbCfg
+	p.kbCfg = kbCfg
+	if p.setKnowledgebaseErrorLocked(kbErr) || kbCfgChanged {
+		p.rebuildRegistriesLocked()
+	}
+	return kbErr
+}
+
+// KnowledgebaseStatus reports whether search_knowledgebase is enabled and
+// configured, and if so why the knowledgebase can't be searched (nil =
+// available)
+func (p *ContextAwareProvider) KnowledgebaseStatus() (bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return kbInUse(p.cfg), p.kbErr
+}
+
+// KnowledgebaseEmbedding returns the embedding provider and model used to
+// search the knowledgebase, as resolved from its contents when available
+func (p *ContextAwareProvider) KnowledgebaseEmbedding() (provider, model string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.kbCfg.EmbeddingProvider, p.kbCfg.EmbeddingModel
+}
+
+// KnowledgebaseRetryInterval returns how often an unavailable knowledgebase
+// is checked again (0 = never)
+func (p *ContextAwareProvider) KnowledgebaseRetryInterval() time.Duration {
+	cfg, _ := p.current()
+	return time.Duration(cfg.Knowledgebase.RetryIntervalSeconds) * time.Second
+}
+
+// setKnowledgebaseErrorLocked records the knowledgebase's availability,
+// logging when it changes, and reports whether it changed
+// The caller must hold p.mu (or have exclusive access during construction)
+func (p *ContextAwareProvider) setKnowledgebaseErrorLocked(kbErr error) bool {
+	wasAvailable := p.kbErr == nil
+	p.kbErr = kbErr
+	switch {
+	case wasAvailable && kbErr != nil:
+		fmt.Fprintf(os.Stderr, "WARNING: Knowledgebase unavailable, search_knowledgebase disabled: %v\n", kbErr)
+	case !wasAvailable && kbErr == nil && kbInUse(p.cfg):
+		fmt.Fprintf(os.Stderr, "Knowledgebase available, search_knowledgebase enabled\n")
+	}
+	return wasAvailable != (kbErr == nil)
+}
+
+// rebuildRegistriesLocked re-registers the tools for the current settings
+// Cached per-client registries are discarded and rebuilt on next use
+// The caller must hold p.mu
+func (p *ContextAwareProvider) rebuildRegistriesLocked() {
+	baseRegistry := NewRegistry()
+	p.registerStatelessTools(baseRegistry)
+	p.registerDatabaseTools(baseRegistry, nil)
+	p.baseRegistry = baseRegistry
+	p.clientRegistries = make(map[*database.Client]*Registry)
 }
 
 // RegisterTools initializes tool registrations
@@ -158,7 +499,24 @@ func (p *ContextAwareProvider) RegisterTools(ctx context.Context) error {
 // List returns all registered tool definitions
 // Hidden tools (like authenticate_user) are not included as they're in a separate registry
 func (p *ContextAwareProvider) List() []mcp.Tool {
-	return p.baseRegistry.List()
+	_, base := p.current()
+	return base.List()
+}
+
+// ListForContext returns the tool definitions the caller may use; API
+// tokens with allowed_tools only see those tools
+func (p *ContextAwareProvider) ListForContext(ctx context.Context) []mcp.Tool {
+	tools := p.List()
+	if p.accessChecker == nil {
+		return tools
+	}
+	allowed := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if p.accessChecker.CanUseTool(ctx, tool.Name) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
 }
 
 // getOrCreateRegistryForClient returns a cached registry for the given client
@@ -166,7 +524,8 @@ func (p *ContextAwareProvider) List() []mcp.Tool {
 func (p *ContextAwareProvider) getOrCreateRegistryForClient(client *database.Client) *Registry {
 	if client == nil {
 		// No client available - return base registry only
-		return p.baseRegistry
+		_, base := p.current()
+		return base
 	}
 
 	// Fast path: check if registry already exists (read lock)
@@ -201,7 +560,12 @@ func (p *ContextAwareProvider) getOrCreateRegistryForClient(client *database.Cli
 
 // Execute runs a tool by name with the given arguments and context
 // Uses cached per-client registries to avoid re-creating tools on every request
+// The context carries the request's mcp.ProgressReporter through to the tool
+// handlers, which report progress for long-running calls
 func (p *ContextAwareProvider) Execute(ctx context.Context, name string, args map[string]interface{}) (mcp.ToolResponse, error) {
+	// Handlers report rows and truncation for the response's size metadata
+	ctx, usage := WithToolUsage(ctx)
+
 	// Check if this is a hidden tool (like authenticate_user)
 	// Hidden tools don't require authentication and are not advertised to LLM
 	if p.hiddenRegistry != nil {
@@ -215,13 +579,32 @@ func (p *ContextAwareProvider) Execute(ctx context.Context, name string, args ma
 					fmt.Fprintf(os.Stderr, "Warning: failed to save user store: %v\n", saveErr)
 				}
 			}
+			if err == nil {
+				response.Meta = responseMeta(response, usage)
+			}
 			return response, err
 		}
 	}
 
+	cfg, baseRegistry := p.current()
+
 	// Check if this tool is enabled in the builtins configuration
 	// read_resource is always enabled as it's used to list resources
-	if name != "read_resource" && !p.cfg.Builtins.Tools.IsToolEnabled(name) {
+	if name == "search_knowledgebase" || name == "list_kb_projects" {
+		if _, kbErr := p.KnowledgebaseStatus(); kbErr != nil {
+			return mcp.NewToolError(fmt.Sprintf("The knowledgebase is unavailable: %v", kbErr))
+		}
+	}
+	if p.accessChecker != nil && !p.accessChecker.CanUseTool(ctx, name) {
+		if auth.IsAdminTool(name) {
+			return mcp.NewToolError(fmt.Sprintf("Tool '%s' is an admin tool: only API tokens whose allowed_tools name it may use it", name))
+		}
+		return mcp.NewToolError(fmt.Sprintf("Tool '%s' is not allowed for this API token", name))
+	}
+	if p.accessChecker != nil && p.accessChecker.IsReadOnlyTool(ctx, name) && p.writesDatabase(ctx, name, args) {
+		return mcp.NewToolError(fmt.Sprintf("Tool '%s' is limited to reads for this API token (allowed_tools: %s(read)), and this call can modify the database", name, name))
+	}
+	if name != "read_resource" && !cfg.IsToolAvailable(name) {
 		return mcp.ToolResponse{
 			Content: []mcp.ContentItem{
 				{
@@ -233,23 +616,70 @@ func (p *ContextAwareProvider) Execute(ctx context.Context, name string, args ma
 		}, nil
 	}
 
+	// Record the call's latency and outcome for the server's metrics, and
+	// as a span of the request's trace
+	ctx, span := tracing.Start(ctx, "tool "+name, tracing.KindInternal, tracing.String("mcp.tool.name", name))
+	started := time.Now()
+	response, err := p.execute(ctx, cfg, baseRegistry, name, args)
+	metrics.Tools.Record(name, time.Since(started), err != nil || response.IsError)
+	switch {
+	case err != nil:
+		span.SetError(err.Error())
+	case response.IsError:
+		span.SetError("the tool returned an error")
+	}
+	span.End()
+
+	// Describe the response's size, and add it to the conversation's totals
+	if err == nil {
+		response.Meta = responseMeta(response, usage)
+		p.contextUsage.Record(conversationKey(ctx), name, response.Meta)
+	}
+	return response, err
+}
+
+// execute runs an available tool for the request's token and database
+func (p *ContextAwareProvider) execute(ctx context.Context, cfg *config.Config, baseRegistry *Registry, name string, args map[string]interface{}) (mcp.ToolResponse, error) {
+
 	// If authentication is enabled, validate token for ALL non-hidden tools
 	if p.authEnabled {
 		tokenHash := auth.GetTokenHashFromContext(ctx)
 		if tokenHash == "" {
 			return mcp.ToolResponse{}, fmt.Errorf("no authentication token found in request context")
 		}
+
+		// Enforce per-token quotas; the error is reported to the client as a rate limit
+		if p.quotaLimiter != nil {
+			release, err := p.quotaLimiter.Acquire(tokenHash)
+			if err != nil {
+				return mcp.ToolResponse{}, err
+			}
+			defer release()
+
+			if usage := usageFromContext(ctx); usage != nil {
+				defer func() {
+					p.quotaLimiter.RecordRows(tokenHash, usage.Rows())
+				}()
+			}
+		}
 	}
 
 	// Check if this is a stateless tool that doesn't require a database client
 	statelessTools := map[string]bool{
-		"read_resource":      true, // Resource access tool
-		"generate_embedding": true, // Embedding generation doesn't need database
+		"read_resource":           true, // Resource access tool
+		"generate_embedding":      true, // Embedding generation doesn't need database
+		"get_context_usage":       true, // Reports the conversation's tool output
+		"get_query_history":       true, // Reports the session's queries
+		"list_jobs":               true, // Reports the scheduler's jobs
+		"list_kb_projects":        true, // Reads the knowledgebase, not a database
+		"list_schema_snapshots":   true, // Reads the data directory
+		"list_databases":          true, // Reads the database configuration
+		"add_database_connection": true, // Connects to the database it adds
 	}
 
 	if statelessTools[name] {
 		// Execute from base registry (no database client needed)
-		return p.baseRegistry.Execute(ctx, name, args)
+		return baseRegistry.Execute(ctx, name, args)
 	}
 
 	// Get the appropriate database client for this request
@@ -268,17 +698,65 @@ func (p *ContextAwareProvider) Execute(ctx context.Context, name string, args ma
 		}, nil // Don't return error, just error response
 	}
 
+	// A database's write policy applies to every tool, whatever the arguments
+	if isDatabaseWrite(name, args) {
+		if dbCfg := p.databaseConfig(dbClient); dbCfg != nil && !dbCfg.WritesAllowed() {
+			return mcp.NewToolError(writePolicyError(dbCfg, name))
+		}
+	}
+
 	// Get the cached registry for this client (or create if first use)
 	// This avoids re-creating all tools on every request
 	registry := p.getOrCreateRegistryForClient(dbClient)
 
 	// Execute the tool using the client-specific registry
-	return registry.Execute(ctx, name, args)
+	// Failed calls can include what PostgreSQL logged while they ran
+	started := time.Now()
+	response, err := registry.Execute(ctx, name, args)
+	if err == nil && response.IsError && cfg.PostgresLogs.Enabled {
+		response = attachServerLog(ctx, dbClient, cfg.PostgresLogs, response, time.Since(started))
+	}
+
+	// Metadata cached before a schema change is reloaded on next use, by this
+	// client unless the tool already reloaded it, and by the database's
+	// other clients
+	// Cached query results may no longer match the data either
+	if err == nil && !response.IsError && changesSchema(name, args) {
+		dbClient.InvalidateMetadata(started)
+		p.clientManager.InvalidateMetadata(dbClient.DatabaseName(), time.Now(), dbClient)
+		p.queryHistory.InvalidateResults()
+	}
+	return response, err
+}
+
+// databaseOverrideKey is the context key of the database a tool call made
+// by the server itself runs on
+type databaseOverrideKey struct{}
+
+// WithDatabase makes tool calls with ctx run on the named database (empty =
+// the first database) rather than the caller's current one, using the
+// clients of the context's token, or of "default" without one. It is for
+// calls the server makes itself, such as scheduled jobs, and skips the
+// caller's database access checks.
+func WithDatabase(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, databaseOverrideKey{}, name)
 }
 
 // getClient returns the appropriate database client based on authentication state
 // and the currently selected database for the token
 func (p *ContextAwareProvider) getClient(ctx context.Context) (*database.Client, error) {
+	if name, ok := ctx.Value(databaseOverrideKey{}).(string); ok {
+		key := auth.GetTokenHashFromContext(ctx)
+		if key == "" {
+			key = "default"
+		}
+		client, err := p.clientManager.GetClientForDatabase(key, name)
+		if err != nil {
+			return nil, fmt.Errorf("no database connection configured: %w", err)
+		}
+		return client, nil
+	}
+
 	if !p.authEnabled {
 		// Authentication disabled - use "default" key in ClientManager
 		// Get the current database for this session
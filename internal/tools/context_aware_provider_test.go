@@ -12,12 +12,17 @@ package tools
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"pgedge-postgres-mcp/internal/auth"
 	"pgedge-postgres-mcp/internal/config"
 	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/kbdatabase"
+	"pgedge-postgres-mcp/internal/kbtypes"
 	"pgedge-postgres-mcp/internal/resources"
 )
 
@@ -68,15 +73,34 @@ func TestContextAwareProvider_List(t *testing.T) {
 		// List tools - should return all tools
 		tools := provider.List()
 
-		// Should have all 7 tools (no filtering)
+		// Should have all 26 tools (no filtering)
 		expectedTools := []string{
 			"read_resource",
 			"generate_embedding",
+			"get_context_usage",
+			"get_query_history",
 			"query_database",
 			"get_schema_info",
 			"similarity_search",
 			"execute_explain",
 			"count_rows",
+			"explain_sql",
+			"validate_sql",
+			"compare_plan",
+			"plan_schema_change",
+			"get_table_stats",
+			"index_advisor",
+			"database_health_check",
+			"lock_analysis",
+			"check_collations",
+			"generate_migration",
+			"export_query_results",
+			"hybrid_search",
+			"snapshot_schema",
+			"list_schema_snapshots",
+			"list_databases",
+			"refresh_schema_cache",
+			"find_relevant_tables",
 		}
 
 		if len(tools) != len(expectedTools) {
@@ -97,6 +121,65 @@ func TestContextAwareProvider_List(t *testing.T) {
 	})
 }
 
+// TestContextAwareProvider_ExecuteScriptOptIn tests that execute_script,
+// apply_migration, create_vector_index, install_extension,
+// setup_semantic_search, setup_foreign_server, restore_schema_snapshot and
+// add_database_connection are only listed when enabled, since they modify
+// the database or the server's configuration
+func TestContextAwareProvider_ExecuteScriptOptIn(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	enabled := true
+	cfg := &config.Config{}
+	cfg.Builtins.Tools.ExecuteScript = &enabled
+	cfg.Builtins.Tools.ApplyMigration = &enabled
+	cfg.Builtins.Tools.CreateVectorIndex = &enabled
+	cfg.Builtins.Tools.RestoreSchemaSnapshot = &enabled
+	cfg.Builtins.Tools.InstallExtension = &enabled
+	cfg.Builtins.Tools.SetupSemanticSearch = &enabled
+	cfg.Builtins.Tools.SetupForeignServer = &enabled
+	cfg.Builtins.Tools.Transactions = &enabled
+	cfg.Builtins.Tools.AddDatabaseConnection = &enabled
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, database.NewClient(nil), cfg, nil, "", nil, 0, nil)
+
+	found := make(map[string]bool)
+	for _, tool := range provider.List() {
+		found[tool.Name] = true
+	}
+	for _, name := range []string{"execute_script", "apply_migration", "create_vector_index", "install_extension", "setup_semantic_search", "setup_foreign_server", "restore_schema_snapshot", "begin_transaction", "commit_transaction", "rollback_transaction", "add_database_connection"} {
+		if !found[name] {
+			t.Errorf("expected %s to be listed when enabled", name)
+		}
+	}
+}
+
+// TestContextAwareProvider_ListJobs tests that list_jobs is only listed
+// when the scheduler is enabled
+func TestContextAwareProvider_ListJobs(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	listed := func(cfg *config.Config) bool {
+		resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+		provider := NewContextAwareProvider(clientManager, resourceReg, false, database.NewClient(nil), cfg, nil, "", nil, 0, nil)
+		for _, tool := range provider.List() {
+			if tool.Name == "list_jobs" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if listed(&config.Config{}) {
+		t.Error("list_jobs listed without the scheduler")
+	}
+	if !listed(&config.Config{Scheduler: config.SchedulerConfig{Enabled: true}}) {
+		t.Error("list_jobs not listed with the scheduler enabled")
+	}
+}
+
 // TestContextAwareProvider_Execute_NoAuth tests execution without authentication
 func TestContextAwareProvider_Execute_NoAuth(t *testing.T) {
 	// This test doesn't require database connection, testing read_resource tool
@@ -214,6 +297,352 @@ func TestContextAwareProvider_Execute_WithAuth(t *testing.T) {
 	})
 }
 
+// TestContextAwareProvider_Execute_Quota tests per-token quota enforcement
+func TestContextAwareProvider_Execute_Quota(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	fallbackClient := database.NewClient(nil)
+	cfg := &config.Config{}
+	resourceReg := resources.NewContextAwareRegistry(clientManager, true, nil, cfg)
+
+	provider := NewContextAwareProvider(clientManager, resourceReg, true, fallbackClient, cfg, nil, "", nil, 0, nil)
+	quotaLimiter := auth.NewQuotaLimiter(auth.QuotaLimits{ToolCallsPerMinute: 1})
+	defer quotaLimiter.Stop()
+	provider.SetQuotaLimiter(quotaLimiter)
+
+	ctx := context.WithValue(context.Background(), auth.TokenHashContextKey, "quota-token")
+	args := map[string]interface{}{"uri": "test://test"}
+
+	if _, err := provider.Execute(ctx, "read_resource", args); err != nil {
+		t.Fatalf("First call should be allowed: %v", err)
+	}
+
+	_, err := provider.Execute(ctx, "read_resource", args)
+	var quotaErr *auth.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("Expected QuotaExceededError, got %v", err)
+	}
+
+	// Quotas are tracked per token
+	otherCtx := context.WithValue(context.Background(), auth.TokenHashContextKey, "other-token")
+	if _, err := provider.Execute(otherCtx, "read_resource", args); err != nil {
+		t.Errorf("Other token should not be limited: %v", err)
+	}
+}
+
+// TestContextAwareProvider_AllowedTools tests restricting API tokens to
+// their allowed tools
+func TestContextAwareProvider_AllowedTools(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	fallbackClient := database.NewClient(nil)
+	cfg := &config.Config{}
+	resourceReg := resources.NewContextAwareRegistry(clientManager, true, nil, cfg)
+
+	store := auth.InitializeTokenStore()
+	if err := store.AddToken("scoped", auth.HashToken("scoped-token"), "", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetAllowedTools("scoped", []string{"get_schema_info"}); err != nil {
+		t.Fatal(err)
+	}
+	checker := auth.NewDatabaseAccessChecker(store, true, false)
+	provider := NewContextAwareProvider(clientManager, resourceReg, true, fallbackClient, cfg, nil, "", nil, 0, checker)
+	if err := provider.RegisterTools(context.TODO()); err != nil {
+		t.Fatalf("RegisterTools failed: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), auth.TokenHashContextKey, auth.HashToken("scoped-token"))
+	ctx = context.WithValue(ctx, auth.IsAPITokenContextKey, true)
+
+	tools := provider.ListForContext(ctx)
+	if len(tools) != 1 || tools[0].Name != "get_schema_info" {
+		t.Errorf("Expected only get_schema_info, got %v", tools)
+	}
+
+	response, err := provider.Execute(ctx, "read_resource", map[string]interface{}{"uri": "test://test"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !response.IsError || !strings.Contains(response.Content[0].Text, "not allowed") {
+		t.Errorf("Expected a not allowed error, got %+v", response)
+	}
+}
+
+// TestContextAwareProvider_ReadScope tests that the read scope of
+// query_database keeps it from setting up foreign servers
+func TestContextAwareProvider_ReadScope(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	enabled := true
+	cfg := &config.Config{}
+	cfg.Builtins.Tools.SetupForeignServer = &enabled
+	cfg.Builtins.Tools.ExecuteScript = &enabled
+	resourceReg := resources.NewContextAwareRegistry(clientManager, true, nil, cfg)
+
+	store := auth.InitializeTokenStore()
+	for id, tools := range map[string][]string{
+		"reader": {"query_database(read)", "setup_foreign_server", "execute_script(read)"},
+		"writer": {"query_database", "setup_foreign_server"},
+	} {
+		if err := store.AddToken(id, auth.HashToken(id+"-token"), "", nil, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetAllowedTools(id, tools); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checker := auth.NewDatabaseAccessChecker(store, true, false)
+	provider := NewContextAwareProvider(clientManager, resourceReg, true, database.NewClient(nil), cfg, nil, "", nil, 0, checker)
+
+	tokenContext := func(id string) context.Context {
+		ctx := context.WithValue(context.Background(), auth.TokenHashContextKey, auth.HashToken(id+"-token"))
+		return context.WithValue(ctx, auth.IsAPITokenContextKey, true)
+	}
+	federation := provider.federation(database.NewClient(nil))
+	if federation.CanWire(tokenContext("reader")) {
+		t.Error("expected query_database(read) not to set up foreign servers")
+	}
+	if !federation.CanWire(tokenContext("writer")) {
+		t.Error("expected query_database to set up foreign servers")
+	}
+
+	// Write tools with the read scope only run as dry runs
+	response, err := provider.Execute(tokenContext("reader"), "execute_script", map[string]interface{}{"script": "DROP TABLE t"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !response.IsError || !strings.Contains(response.Content[0].Text, "limited to reads") {
+		t.Errorf("expected a read scope error, got %+v", response)
+	}
+}
+
+// TestContextAwareProvider_ProductionWrites tests that write tools only run
+// as dry runs against databases labeled prod, unless allow_writes is set
+func TestContextAwareProvider_ProductionWrites(t *testing.T) {
+	prod := config.NamedDatabaseConfig{Name: "main", Host: "localhost", Database: "app", User: "app", Environment: "prod"}
+	clientManager := database.NewClientManager([]config.NamedDatabaseConfig{prod})
+	defer clientManager.CloseAll()
+	if err := clientManager.SetClient("default", database.NewClient(&prod)); err != nil {
+		t.Fatal(err)
+	}
+
+	enabled := true
+	cfg := &config.Config{}
+	cfg.Builtins.Tools.ExecuteScript = &enabled
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, nil, cfg, nil, "", nil, 0, nil)
+
+	response, err := provider.Execute(context.Background(), "execute_script", map[string]interface{}{"script": "DROP TABLE t"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !response.IsError || !strings.Contains(response.Content[0].Text, "production database") {
+		t.Errorf("expected a production database error, got %+v", response)
+	}
+
+	response, err = provider.Execute(context.Background(), "execute_script", map[string]interface{}{"script": "DROP TABLE t", "dry_run": true})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if response.IsError && strings.Contains(response.Content[0].Text, "production database") {
+		t.Errorf("expected a dry run to be allowed, got %+v", response)
+	}
+
+	// Once relabeled, the database accepts writes again
+	prod.Environment = "staging"
+	clientManager.UpdateDatabaseConfigs([]config.NamedDatabaseConfig{prod})
+	response, _ = provider.Execute(context.Background(), "execute_script", map[string]interface{}{"script": "DROP TABLE t"})
+	if response.IsError && strings.Contains(response.Content[0].Text, "production database") {
+		t.Errorf("expected writes to be allowed on staging, got %+v", response)
+	}
+
+	// allow_writes overrides the environment either way
+	deny, allow := false, true
+	prod.AllowWrites = &deny
+	clientManager.UpdateDatabaseConfigs([]config.NamedDatabaseConfig{prod})
+	response, _ = provider.Execute(context.Background(), "execute_script", map[string]interface{}{"script": "DROP TABLE t"})
+	if !response.IsError || !strings.Contains(response.Content[0].Text, "allow_writes: false") {
+		t.Errorf("expected an allow_writes error, got %+v", response)
+	}
+
+	prod.Environment = "prod"
+	prod.AllowWrites = &allow
+	clientManager.UpdateDatabaseConfigs([]config.NamedDatabaseConfig{prod})
+	response, _ = provider.Execute(context.Background(), "execute_script", map[string]interface{}{"script": "DROP TABLE t"})
+	if response.IsError && strings.Contains(response.Content[0].Text, "may not modify") {
+		t.Errorf("expected allow_writes: true to allow writes on prod, got %+v", response)
+	}
+}
+
+func TestIsDatabaseWrite(t *testing.T) {
+	tests := []struct {
+		tool string
+		args map[string]interface{}
+		want bool
+	}{
+		{"execute_script", map[string]interface{}{}, true},
+		{"execute_script", map[string]interface{}{"dry_run": true}, false},
+		{"apply_migration", map[string]interface{}{}, false},
+		{"apply_migration", map[string]interface{}{"dry_run": false}, true},
+		{"install_extension", map[string]interface{}{}, true},
+		{"begin_transaction", map[string]interface{}{}, false},
+		{"begin_transaction", map[string]interface{}{"read_write": true}, true},
+		{"query_database", map[string]interface{}{}, false},
+	}
+	for _, tt := range tests {
+		if got := isDatabaseWrite(tt.tool, tt.args); got != tt.want {
+			t.Errorf("isDatabaseWrite(%s, %v) = %v, want %v", tt.tool, tt.args, got, tt.want)
+		}
+	}
+}
+
+// TestContextAwareProvider_Reload tests applying a new configuration
+func TestContextAwareProvider_Reload(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	fallbackClient := database.NewClient(nil)
+	cfg := &config.Config{}
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, fallbackClient, cfg, nil, "", nil, 0, nil)
+
+	hasTool := func(name string) bool {
+		for _, tool := range provider.List() {
+			if tool.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !hasTool("count_rows") {
+		t.Fatal("Expected count_rows to be listed before reload")
+	}
+	if hasTool("search_knowledgebase") {
+		t.Fatal("Expected search_knowledgebase to be hidden without a knowledgebase")
+	}
+
+	disabled := false
+	newCfg := &config.Config{}
+	newCfg.Builtins.Tools.CountRows = &disabled
+	newCfg.Knowledgebase.Enabled = true
+	newCfg.Knowledgebase.DatabasePath = createTestKnowledgebase(t)
+	provider.Reload(newCfg)
+
+	if hasTool("count_rows") {
+		t.Error("Expected count_rows to be removed after reload")
+	}
+	if !hasTool("search_knowledgebase") {
+		t.Error("Expected search_knowledgebase to be listed after reload")
+	}
+
+	response, err := provider.Execute(context.Background(), "count_rows", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !response.IsError || !strings.Contains(response.Content[0].Text, "not available") {
+		t.Errorf("Expected disabled tool error, got: %+v", response)
+	}
+}
+
+// TestContextAwareProvider_UnavailableKnowledgebase tests that a missing
+// knowledgebase disables search_knowledgebase until it can be opened
+func TestContextAwareProvider_UnavailableKnowledgebase(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	kbPath := filepath.Join(t.TempDir(), "kb.db")
+	cfg := &config.Config{}
+	cfg.Knowledgebase.Enabled = true
+	cfg.Knowledgebase.DatabasePath = kbPath
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, database.NewClient(nil), cfg, nil, "", nil, 0, nil)
+
+	hasTool := func() bool {
+		for _, tool := range provider.List() {
+			if tool.Name == "search_knowledgebase" {
+				return true
+			}
+		}
+		return false
+	}
+
+	inUse, kbErr := provider.KnowledgebaseStatus()
+	if !inUse || kbErr == nil || !strings.Contains(kbErr.Error(), "does not exist") {
+		t.Fatalf("KnowledgebaseStatus() = %t, %v; want a missing file error", inUse, kbErr)
+	}
+	if _, err := os.Stat(kbPath); !os.IsNotExist(err) {
+		t.Error("Expected the check not to create the knowledgebase file")
+	}
+	if hasTool() {
+		t.Error("Expected search_knowledgebase to be hidden while unavailable")
+	}
+	response, err := provider.Execute(context.Background(), "search_knowledgebase", map[string]interface{}{"query": "x"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !response.IsError || !strings.Contains(response.Content[0].Text, "knowledgebase is unavailable") {
+		t.Errorf("Expected unavailable knowledgebase error, got: %+v", response)
+	}
+
+	// Once the knowledgebase is built, the next check enables the tool,
+	// using the embedding provider and model the knowledgebase was built with
+	if err := os.Rename(createTestKnowledgebase(t), kbPath); err != nil {
+		t.Fatalf("Failed to install knowledgebase: %v", err)
+	}
+	if err := provider.CheckKnowledgebase(); err != nil {
+		t.Fatalf("CheckKnowledgebase() = %v", err)
+	}
+	if !hasTool() {
+		t.Error("Expected search_knowledgebase to be listed once available")
+	}
+	if provider, model := provider.KnowledgebaseEmbedding(); provider != "ollama" || model != "nomic-embed-text" {
+		t.Errorf("KnowledgebaseEmbedding() = %s, %s; want ollama, nomic-embed-text", provider, model)
+	}
+}
+
+// createTestKnowledgebase creates a knowledgebase file with one chunk,
+// embedded with Ollama
+func createTestKnowledgebase(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kb.db")
+	db, err := kbdatabase.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to create knowledgebase: %v", err)
+	}
+	chunk := &kbtypes.Chunk{Text: "Test chunk", ProjectName: "Test", ProjectVersion: "1.0", OllamaEmbedding: []float32{0.1, 0.2, 0.3}}
+	if err := db.InsertChunks([]*kbtypes.Chunk{chunk}); err != nil {
+		t.Fatalf("Failed to insert chunk: %v", err)
+	}
+	if err := db.SetEmbeddingModel(kbtypes.EmbeddingModel{Provider: "ollama", Model: "nomic-embed-text", Dimensions: 3}); err != nil {
+		t.Fatalf("Failed to record embedding model: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close knowledgebase: %v", err)
+	}
+	return path
+}
+
+// TestRecordRowsReturned tests row accounting through the tool context
+func TestRecordRowsReturned(t *testing.T) {
+	ctx, usage := WithToolUsage(context.Background())
+
+	recordRowsReturned(map[string]interface{}{"__context": ctx}, 3)
+	recordRowsReturned(map[string]interface{}{"__context": ctx}, 4)
+	// Calls without a usage accumulator are ignored
+	recordRowsReturned(map[string]interface{}{"__context": context.Background()}, 10)
+	recordRowsReturned(map[string]interface{}{}, 10)
+
+	if usage.Rows() != 7 {
+		t.Errorf("Rows() = %d, want 7", usage.Rows())
+	}
+}
+
 // TestContextAwareProvider_Execute_InvalidTool tests execution of non-existent tool
 func TestContextAwareProvider_Execute_InvalidTool(t *testing.T) {
 	clientManager := database.NewClientManagerWithConfig(nil)
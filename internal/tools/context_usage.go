@@ -0,0 +1,263 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+const (
+	// maxTrackedConversations bounds the conversations whose tool output is
+	// tracked; the least recently active one is forgotten to make room
+	maxTrackedConversations = 1000
+
+	// bytesPerToken approximates the tokenization of tool output, which is
+	// mostly ASCII text and TSV
+	bytesPerToken = 4
+
+	// defaultConversation is used for requests without a session or token,
+	// such as stdio mode, where the server has a single client
+	defaultConversation = "default"
+)
+
+// ToolOutputTotals accumulates the size of the output of a tool's calls
+type ToolOutputTotals struct {
+	Calls           int
+	Bytes           int
+	EstimatedTokens int
+	Rows            int
+	Truncated       int // Calls that left out results
+}
+
+// add adds a call's response metadata to the totals
+func (t *ToolOutputTotals) add(meta *mcp.ToolResponseMeta) {
+	t.Calls++
+	t.Bytes += meta.Bytes
+	t.EstimatedTokens += meta.EstimatedTokens
+	if meta.Rows != nil {
+		t.Rows += *meta.Rows
+	}
+	if meta.Truncated {
+		t.Truncated++
+	}
+}
+
+// ConversationUsage is the tool output returned in a conversation
+type ConversationUsage struct {
+	Started time.Time
+	Total   ToolOutputTotals
+	Tools   map[string]ToolOutputTotals
+}
+
+// conversationUsage holds a tracked conversation's totals
+type conversationUsage struct {
+	started  time.Time
+	lastCall time.Time
+	tools    map[string]*ToolOutputTotals
+}
+
+// ContextUsageTracker accumulates tool output per conversation, so clients
+// can see how much of their context window tool results have used
+type ContextUsageTracker struct {
+	mu            sync.Mutex
+	conversations map[string]*conversationUsage
+	now           func() time.Time
+}
+
+// NewContextUsageTracker creates an empty tracker
+func NewContextUsageTracker() *ContextUsageTracker {
+	return &ContextUsageTracker{
+		conversations: make(map[string]*conversationUsage),
+		now:           time.Now,
+	}
+}
+
+// conversationKey identifies the conversation of a request: its MCP session,
+// else its token (one conversation per token for sessionless clients)
+func conversationKey(ctx context.Context) string {
+	if id := mcp.SessionIDFromContext(ctx); id != "" {
+		return "session:" + id
+	}
+	if tokenHash := auth.GetTokenHashFromContext(ctx); tokenHash != "" {
+		return "token:" + tokenHash
+	}
+	return defaultConversation
+}
+
+// Record adds a tool call's response metadata to its conversation
+func (t *ContextUsageTracker) Record(conversation, tool string, meta *mcp.ToolResponseMeta) {
+	if meta == nil {
+		return
+	}
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conv, ok := t.conversations[conversation]
+	if !ok {
+		if len(t.conversations) >= maxTrackedConversations {
+			t.evictLocked()
+		}
+		conv = &conversationUsage{started: now, tools: make(map[string]*ToolOutputTotals)}
+		t.conversations[conversation] = conv
+	}
+	conv.lastCall = now
+
+	totals, ok := conv.tools[tool]
+	if !ok {
+		totals = &ToolOutputTotals{}
+		conv.tools[tool] = totals
+	}
+	totals.add(meta)
+}
+
+// evictLocked forgets the least recently active conversation
+// The caller must hold t.mu
+func (t *ContextUsageTracker) evictLocked() {
+	var oldestKey string
+	var oldest time.Time
+	for key, conv := range t.conversations {
+		if oldestKey == "" || conv.lastCall.Before(oldest) {
+			oldestKey = key
+			oldest = conv.lastCall
+		}
+	}
+	delete(t.conversations, oldestKey)
+}
+
+// Usage returns the tool output of a conversation, and false if no tool
+// calls have been recorded for it
+func (t *ContextUsageTracker) Usage(conversation string) (ConversationUsage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conv, ok := t.conversations[conversation]
+	if !ok {
+		return ConversationUsage{}, false
+	}
+	usage := ConversationUsage{
+		Started: conv.started,
+		Tools:   make(map[string]ToolOutputTotals, len(conv.tools)),
+	}
+	for tool, totals := range conv.tools {
+		usage.Tools[tool] = *totals
+		usage.Total.Calls += totals.Calls
+		usage.Total.Bytes += totals.Bytes
+		usage.Total.EstimatedTokens += totals.EstimatedTokens
+		usage.Total.Rows += totals.Rows
+		usage.Total.Truncated += totals.Truncated
+	}
+	return usage, true
+}
+
+// responseMeta describes the size of a tool response
+// usage holds what the handler reported while running, and may be nil
+func responseMeta(response mcp.ToolResponse, usage *ToolUsage) *mcp.ToolResponseMeta {
+	meta := &mcp.ToolResponseMeta{}
+	for _, item := range response.Content {
+		meta.Bytes += len(item.Text)
+	}
+	meta.EstimatedTokens = (meta.Bytes + bytesPerToken - 1) / bytesPerToken
+	if usage != nil {
+		if rows, reported := usage.ReportedRows(); reported {
+			meta.Rows = &rows
+		}
+		meta.Truncated = usage.Truncated()
+	}
+	return meta
+}
+
+// formatContextUsage formats a conversation's tool output, with the tools
+// returning the most output first
+func formatContextUsage(usage ConversationUsage) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Tool output in this conversation since %s: %d calls, %d bytes (~%d tokens)",
+		usage.Started.UTC().Format(time.RFC3339), usage.Total.Calls, usage.Total.Bytes, usage.Total.EstimatedTokens))
+	if usage.Total.Rows > 0 {
+		sb.WriteString(fmt.Sprintf(", %d rows", usage.Total.Rows))
+	}
+	if usage.Total.Truncated > 0 {
+		sb.WriteString(fmt.Sprintf(", %d truncated", usage.Total.Truncated))
+	}
+	sb.WriteString("\n\n")
+
+	names := make([]string, 0, len(usage.Tools))
+	for name := range usage.Tools {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := usage.Tools[names[i]], usage.Tools[names[j]]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return names[i] < names[j]
+	})
+
+	sb.WriteString(BuildTSVRow("tool", "calls", "bytes", "estimated_tokens", "rows", "truncated"))
+	for _, name := range names {
+		totals := usage.Tools[name]
+		sb.WriteString("\n")
+		sb.WriteString(BuildTSVRow(name,
+			fmt.Sprint(totals.Calls), fmt.Sprint(totals.Bytes), fmt.Sprint(totals.EstimatedTokens),
+			fmt.Sprint(totals.Rows), fmt.Sprint(totals.Truncated)))
+	}
+	return sb.String()
+}
+
+// GetContextUsageTool creates the get_context_usage tool, which reports the
+// tool output returned so far in the caller's conversation
+func GetContextUsageTool(tracker *ContextUsageTracker) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "get_context_usage",
+			Description: `Report how much tool output has been returned in this conversation.
+
+<usecase>
+Use get_context_usage to:
+- Check how much of the context window tool results have used
+- Find which tools return the most output before making further calls
+- Decide whether to narrow queries (smaller limits, fewer columns)
+</usecase>
+
+<output>
+A summary line with the total calls, bytes, estimated tokens, rows and
+truncated results, followed by a TSV breakdown per tool, largest first.
+Token counts are estimates (about 4 bytes per token).
+</output>`,
+			InputSchema: mcp.InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+				Required:   []string{},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			ctx, ok := args["__context"].(context.Context)
+			if !ok || ctx == nil {
+				ctx = context.Background()
+			}
+
+			usage, ok := tracker.Usage(conversationKey(ctx))
+			if !ok {
+				return mcp.NewToolSuccess("No tool output has been returned in this conversation yet.")
+			}
+			return mcp.NewToolSuccess(formatContextUsage(usage))
+		},
+	}
+}
@@ -0,0 +1,192 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/auth"
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/resources"
+)
+
+// TestResponseMeta tests the size metadata of tool responses
+func TestResponseMeta(t *testing.T) {
+	response := mcp.ToolResponse{Content: []mcp.ContentItem{
+		{Type: "text", Text: "12345"},
+		{Type: "text", Text: "678"},
+	}}
+
+	meta := responseMeta(response, nil)
+	if meta.Bytes != 8 || meta.EstimatedTokens != 2 {
+		t.Errorf("bytes/tokens = %d/%d, want 8/2", meta.Bytes, meta.EstimatedTokens)
+	}
+	if meta.Rows != nil || meta.Truncated {
+		t.Errorf("expected no rows or truncation without usage, got %+v", meta)
+	}
+
+	ctx, usage := WithToolUsage(context.Background())
+	args := map[string]interface{}{"__context": ctx}
+	recordRowsReturned(args, 0)
+	recordTruncated(args)
+
+	meta = responseMeta(mcp.ToolResponse{}, usage)
+	if meta.Rows == nil || *meta.Rows != 0 {
+		t.Errorf("expected 0 reported rows, got %v", meta.Rows)
+	}
+	if !meta.Truncated {
+		t.Error("expected truncated response")
+	}
+	if meta.Bytes != 0 || meta.EstimatedTokens != 0 {
+		t.Errorf("expected empty response size, got %+v", meta)
+	}
+}
+
+// TestConversationKey tests how requests are assigned to conversations
+func TestConversationKey(t *testing.T) {
+	if key := conversationKey(context.Background()); key != defaultConversation {
+		t.Errorf("conversationKey() = %q, want %q", key, defaultConversation)
+	}
+	ctx := context.WithValue(context.Background(), auth.TokenHashContextKey, "abc")
+	if key := conversationKey(ctx); key != "token:abc" {
+		t.Errorf("conversationKey() = %q, want token:abc", key)
+	}
+}
+
+// TestContextUsageTracker tests per-conversation totals and eviction
+func TestContextUsageTracker(t *testing.T) {
+	tracker := NewContextUsageTracker()
+	rows := 10
+
+	tracker.Record("a", "query_database", &mcp.ToolResponseMeta{Bytes: 400, EstimatedTokens: 100, Rows: &rows, Truncated: true})
+	tracker.Record("a", "query_database", &mcp.ToolResponseMeta{Bytes: 40, EstimatedTokens: 10, Rows: &rows})
+	tracker.Record("a", "get_schema_info", &mcp.ToolResponseMeta{Bytes: 1000, EstimatedTokens: 250})
+	tracker.Record("b", "query_database", &mcp.ToolResponseMeta{Bytes: 4, EstimatedTokens: 1})
+	tracker.Record("b", "query_database", nil)
+
+	usage, ok := tracker.Usage("a")
+	if !ok {
+		t.Fatal("expected usage for conversation a")
+	}
+	want := ToolOutputTotals{Calls: 3, Bytes: 1440, EstimatedTokens: 360, Rows: 20, Truncated: 1}
+	if usage.Total != want {
+		t.Errorf("Total = %+v, want %+v", usage.Total, want)
+	}
+	if got := usage.Tools["query_database"]; got.Calls != 2 || got.Truncated != 1 {
+		t.Errorf("query_database totals = %+v", got)
+	}
+
+	usage, _ = tracker.Usage("b")
+	if usage.Total.Calls != 1 {
+		t.Errorf("expected nil metadata to be ignored, got %d calls", usage.Total.Calls)
+	}
+	if _, ok := tracker.Usage("c"); ok {
+		t.Error("expected no usage for an unknown conversation")
+	}
+
+	// The least recently active conversation is forgotten when full
+	now := time.Now()
+	tracker = NewContextUsageTracker()
+	tracker.now = func() time.Time { return now }
+	for i := 0; i < maxTrackedConversations; i++ {
+		now = now.Add(time.Second)
+		tracker.Record(fmt.Sprintf("conv-%d", i), "read_resource", &mcp.ToolResponseMeta{})
+	}
+	now = now.Add(time.Second)
+	tracker.Record("conv-0", "read_resource", &mcp.ToolResponseMeta{})
+	tracker.Record("new", "read_resource", &mcp.ToolResponseMeta{})
+
+	if _, ok := tracker.Usage("conv-1"); ok {
+		t.Error("expected the least recently active conversation to be evicted")
+	}
+	for _, key := range []string{"conv-0", "new"} {
+		if _, ok := tracker.Usage(key); !ok {
+			t.Errorf("expected conversation %s to be tracked", key)
+		}
+	}
+}
+
+// TestFormatContextUsage tests the get_context_usage output
+func TestFormatContextUsage(t *testing.T) {
+	usage := ConversationUsage{
+		Started: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Total:   ToolOutputTotals{Calls: 3, Bytes: 1440, EstimatedTokens: 360, Rows: 20, Truncated: 1},
+		Tools: map[string]ToolOutputTotals{
+			"query_database":  {Calls: 2, Bytes: 440, EstimatedTokens: 110, Rows: 20, Truncated: 1},
+			"get_schema_info": {Calls: 1, Bytes: 1000, EstimatedTokens: 250},
+		},
+	}
+
+	got := formatContextUsage(usage)
+	want := "Tool output in this conversation since 2025-01-02T03:04:05Z: 3 calls, 1440 bytes (~360 tokens), 20 rows, 1 truncated\n\n" +
+		"tool\tcalls\tbytes\testimated_tokens\trows\ttruncated\n" +
+		"get_schema_info\t1\t1000\t250\t0\t0\n" +
+		"query_database\t2\t440\t110\t20\t1"
+	if got != want {
+		t.Errorf("formatContextUsage() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestContextAwareProvider_ContextUsage tests the response metadata and
+// conversation totals of calls through the provider
+func TestContextAwareProvider_ContextUsage(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	cfg := &config.Config{}
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, database.NewClient(nil), cfg, nil, "", nil, 0, nil)
+
+	ctx := context.Background()
+	response, err := provider.Execute(ctx, "get_context_usage", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.Contains(response.Content[0].Text, "No tool output") {
+		t.Errorf("expected empty usage report, got %q", response.Content[0].Text)
+	}
+
+	response, err = provider.Execute(ctx, "read_resource", map[string]interface{}{"list": true})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if response.Meta == nil {
+		t.Fatal("expected response metadata")
+	}
+	if response.Meta.Bytes != len(response.Content[0].Text) {
+		t.Errorf("Meta.Bytes = %d, want %d", response.Meta.Bytes, len(response.Content[0].Text))
+	}
+
+	response, err = provider.Execute(ctx, "get_context_usage", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	text := response.Content[0].Text
+	if !strings.Contains(text, "2 calls") || !strings.Contains(text, "\nread_resource\t1\t") {
+		t.Errorf("unexpected usage report:\n%s", text)
+	}
+
+	disabled := false
+	cfg = &config.Config{}
+	cfg.Builtins.Tools.GetContextUsage = &disabled
+	provider.Reload(cfg)
+	for _, tool := range provider.List() {
+		if tool.Name == "get_context_usage" {
+			t.Error("expected get_context_usage to be hidden when disabled")
+		}
+	}
+}
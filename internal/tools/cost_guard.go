@@ -0,0 +1,109 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// planEstimate is the planner's estimate for a query
+type planEstimate struct {
+	cost float64 // Total cost of the plan
+	rows float64 // Largest row estimate of any step of the plan
+}
+
+// costGuardApplies reports whether the cost guard plans a query before it
+// runs: a SELECT, VALUES or TABLE, or a WITH query that only reads. EXPLAIN
+// is left alone, and queries that cannot be tokenized run as written so the
+// server reports the error.
+func costGuardApplies(query string) bool {
+	toks, err := tokenizeSQL(query)
+	if err != nil || len(toks) == 0 || toks[0].isKeyword("EXPLAIN") {
+		return false
+	}
+	return returnsRows(query)
+}
+
+// estimatePlan plans a query with EXPLAIN (FORMAT JSON), without running it
+func estimatePlan(ctx context.Context, tx pgx.Tx, query string) (*planEstimate, error) {
+	var data []byte
+	if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&data); err != nil {
+		return nil, err
+	}
+	root, err := parseExplainJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	estimate := &planEstimate{cost: root.TotalCost}
+	root.walk(nil, func(node, _ *explainNode) {
+		if node.PlanRows > estimate.rows {
+			estimate.rows = node.PlanRows
+		}
+	})
+	return estimate, nil
+}
+
+// costGuardViolation describes how a plan exceeds the guard's limits, or
+// returns "" if it does not
+func costGuardViolation(estimate *planEstimate, guard config.QueryGuardConfig) string {
+	var reasons []string
+	if guard.MaxCost > 0 && estimate.cost > float64(guard.MaxCost) {
+		reasons = append(reasons, fmt.Sprintf("estimated cost %.0f is above query_guard.max_cost (%d)",
+			estimate.cost, guard.MaxCost))
+	}
+	if guard.MaxEstimatedRows > 0 && estimate.rows > float64(guard.MaxEstimatedRows) {
+		reasons = append(reasons, fmt.Sprintf("a step of the plan is estimated to process %.0f rows, above query_guard.max_estimated_rows (%d)",
+			estimate.rows, guard.MaxEstimatedRows))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// textPlan returns the plan of a query in EXPLAIN's text format
+func textPlan(ctx context.Context, tx pgx.Tx, query string) (string, error) {
+	rows, err := tx.Query(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatCostGuardRefusal explains why a query was refused, with its plan,
+// so the model can rewrite it
+func formatCostGuardRefusal(query, violation, plan string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Query refused by the cost guard: %s.\n\n", violation))
+	sb.WriteString(fmt.Sprintf("SQL Query:\n%s\n\n", query))
+	if plan != "" {
+		sb.WriteString(fmt.Sprintf("Plan:\n%s\n\n", plan))
+	}
+	sb.WriteString("The query was not run. Rewrite it to read less: filter on indexed columns with WHERE, " +
+		"join on keys rather than producing every combination of rows, aggregate, or query a smaller range.")
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+func TestCostGuardApplies(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT * FROM orders", true},
+		{"WITH recent AS (SELECT * FROM orders) SELECT * FROM recent", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"EXPLAIN SELECT * FROM orders", false},
+		{"WITH moved AS (DELETE FROM orders RETURNING *) INSERT INTO archive SELECT * FROM moved", false},
+		{"INSERT INTO orders VALUES (1)", false},
+		{"SHOW search_path", false},
+		{"SELECT 'unterminated", false},
+	}
+	for _, tt := range tests {
+		if got := costGuardApplies(tt.query); got != tt.want {
+			t.Errorf("costGuardApplies(%q) = %t, want %t", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCostGuardViolation(t *testing.T) {
+	guard := config.QueryGuardConfig{Explain: true, MaxCost: 1000, MaxEstimatedRows: 50000}
+	tests := []struct {
+		name     string
+		estimate planEstimate
+		guard    config.QueryGuardConfig
+		want     []string
+	}{
+		{"within limits", planEstimate{cost: 1000, rows: 50000}, guard, nil},
+		{"costly", planEstimate{cost: 25000.5, rows: 10}, guard, []string{"estimated cost 25000 is above query_guard.max_cost (1000)"}},
+		{"many rows", planEstimate{cost: 10, rows: 2e6}, guard, []string{"2000000 rows, above query_guard.max_estimated_rows (50000)"}},
+		{"both", planEstimate{cost: 2000, rows: 60000}, guard, []string{"max_cost", "; ", "max_estimated_rows"}},
+		{"no limits", planEstimate{cost: 1e9, rows: 1e9}, config.QueryGuardConfig{Explain: true}, nil},
+	}
+	for _, tt := range tests {
+		got := costGuardViolation(&tt.estimate, tt.guard)
+		if (got == "") != (tt.want == nil) {
+			t.Errorf("%s: costGuardViolation = %q", tt.name, got)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: costGuardViolation = %q, want it to contain %q", tt.name, got, want)
+			}
+		}
+	}
+}
+
+func TestFormatCostGuardRefusal(t *testing.T) {
+	out := formatCostGuardRefusal("SELECT * FROM a, b", "estimated cost 5000 is above query_guard.max_cost (1000)",
+		"Nested Loop  (cost=0.00..5000.00 rows=1000000 width=16)")
+	for _, want := range []string{"Query refused by the cost guard: estimated cost 5000", "SQL Query:\nSELECT * FROM a, b",
+		"Plan:\nNested Loop", "was not run"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// CreateVectorIndexTool creates the create_vector_index tool, which builds an
+// HNSW or IVFFlat index on a pgvector column with parameters chosen from the
+// table's size
+// The tool writes to the database, so it is disabled unless enabled in the
+// configuration
+func CreateVectorIndexTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "create_vector_index",
+			Description: `Build an HNSW or IVFFlat index on a pgvector column so similarity_search
+and nearest-neighbour queries don't compare the query with every row.
+
+<usecase>
+Use when:
+- similarity_search reports that a vector column has no index, or only an
+  index for a different distance metric
+- The user asks to speed up vector search on a table
+</usecase>
+
+<behavior>
+- dry_run defaults to true: the statement and the chosen parameters are shown
+  without building the index
+- method=auto picks HNSW, or IVFFlat for tables over a million rows, which
+  builds much faster; parameters (m and ef_construction, or lists) are
+  chosen from the table's estimated row count
+- The index supports one distance metric; it must match the distance_metric
+  used in searches
+- A column that already has an index for the metric is left alone
+</behavior>
+
+<important>
+- Confirm with the user before running with dry_run=false: building the
+  index blocks writes to the table and can take minutes on large tables
+- IVFFlat indexes need the data to be loaded first
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Table with the vector column (can include schema: 'schema.table')",
+					},
+					"column_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Vector column to index; optional when the table has one vector column",
+					},
+					"distance_metric": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"cosine", "l2", "inner_product"},
+						"description": "Distance metric the index supports (default: cosine)",
+						"default":     "cosine",
+					},
+					"method": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"auto", "hnsw", "ivfflat"},
+						"description": "Index method (default: auto)",
+						"default":     "auto",
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Show the statement without building the index (default: true)",
+						"default":     true,
+					},
+				},
+				Required: []string{"table_name"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			tableName, errResp := ValidateStringParam(args, "table_name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			columnName := ValidateOptionalStringParam(args, "column_name", "")
+			metric := ValidateOptionalStringParam(args, "distance_metric", "cosine")
+			if metric != "cosine" && metric != "l2" && metric != "inner_product" {
+				return mcp.NewToolError("distance_metric must be 'cosine', 'l2' or 'inner_product'")
+			}
+			method := ValidateOptionalStringParam(args, "method", "auto")
+			dryRun := ValidateBoolParam(args, "dry_run", true)
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			tableInfo, err := findTableInMetadataMap(dbClient.GetMetadataFor(connStr), tableName)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("%v\nUse get_schema_info(vector_tables_only=true) to find tables with vector columns", err))
+			}
+			column, err := selectVectorColumn(tableInfo, columnName)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			ctx := context.Background()
+			indexes, err := lookupVectorIndexes(ctx, pool, tableInfo.SchemaName, tableInfo.TableName)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			for _, idx := range indexes {
+				if idx.Column == column.ColumnName && idx.Metric() == metric {
+					sb.WriteString(fmt.Sprintf("%s.%s already has %s index %s for %s distance:\n%s\n",
+						tableName, column.ColumnName, idx.Method, idx.Name, metric, idx.Definition))
+					return mcp.NewToolSuccess(sb.String())
+				}
+			}
+
+			rows, err := estimateRowCount(ctx, pool, tableInfo.SchemaName, tableInfo.TableName)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			plan, err := planVectorIndex(method, rows, column.VectorDimensions, metric)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			name := vectorIndexName(tableInfo.TableName, column.ColumnName, plan.Method, metric)
+			stmt := plan.statement(name, tableInfo.SchemaName, tableInfo.TableName, column.ColumnName)
+
+			if !dryRun {
+				tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+				if err != nil {
+					return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+				}
+				defer func() {
+					_ = tx.Rollback(ctx) //nolint:errcheck // no-op after a successful commit
+				}()
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return mcp.NewToolError(fmt.Sprintf("Failed to create the index: %v\nStatement: %s", err, stmt))
+				}
+				if err := tx.Commit(ctx); err != nil {
+					return mcp.NewToolError(fmt.Sprintf("Commit failed, the index was not created: %v", err))
+				}
+			}
+
+			logging.Info("create_vector_index_executed",
+				"table", tableName,
+				"column", column.ColumnName,
+				"method", plan.Method,
+				"metric", metric,
+				"estimated_rows", rows,
+				"dry_run", dryRun,
+			)
+
+			if dryRun {
+				sb.WriteString("Dry run, the index was not created.\n\n")
+			} else {
+				sb.WriteString(fmt.Sprintf("Created index %s.\n\n", name))
+			}
+			sb.WriteString(fmt.Sprintf("Table: %s (about %d rows)\n", tableName, rows))
+			sb.WriteString(fmt.Sprintf("Column: %s", column.ColumnName))
+			if column.VectorDimensions > 0 {
+				sb.WriteString(fmt.Sprintf(" (%d dimensions)", column.VectorDimensions))
+			}
+			sb.WriteString(fmt.Sprintf("\nMethod: %s, %s distance\n\n", plan.Method, metric))
+			sb.WriteString(stmt + ";\n")
+			if len(plan.Notes) > 0 {
+				sb.WriteString("\nNotes:\n")
+				for _, note := range plan.Notes {
+					sb.WriteString("- " + note + "\n")
+				}
+			}
+			if dryRun {
+				sb.WriteString("\nRun again with dry_run=false to build the index.\n")
+			}
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// selectVectorColumn returns the named vector column, or the table's only
+// vector column when no name is given
+func selectVectorColumn(tableInfo database.TableInfo, columnName string) (database.ColumnInfo, error) {
+	vectorCols := discoverVectorColumns(tableInfo)
+	if len(vectorCols) == 0 {
+		return database.ColumnInfo{}, fmt.Errorf("table %s.%s has no vector columns", tableInfo.SchemaName, tableInfo.TableName)
+	}
+
+	if columnName == "" {
+		if len(vectorCols) > 1 {
+			return database.ColumnInfo{}, fmt.Errorf("table %s.%s has several vector columns (%s); specify column_name",
+				tableInfo.SchemaName, tableInfo.TableName, strings.Join(vectorColumnNames(vectorCols), ", "))
+		}
+		return vectorCols[0], nil
+	}
+
+	for _, col := range vectorCols {
+		if col.ColumnName == columnName {
+			return col, nil
+		}
+	}
+	return database.ColumnInfo{}, fmt.Errorf("%s is not a vector column of %s.%s (vector columns: %s)",
+		columnName, tableInfo.SchemaName, tableInfo.TableName, strings.Join(vectorColumnNames(vectorCols), ", "))
+}
+
+// estimateRowCount returns the planner's row estimate for a table, counting
+// the rows when the table has never been analyzed
+func estimateRowCount(ctx context.Context, pool *pgxpool.Pool, schema, table string) (int64, error) {
+	qualified := quoteIdentifier(schema) + "." + quoteIdentifier(table)
+
+	var estimate float64
+	if err := pool.QueryRow(ctx, "SELECT reltuples FROM pg_catalog.pg_class WHERE oid = $1::regclass", qualified).Scan(&estimate); err != nil {
+		return 0, fmt.Errorf("failed to estimate the row count: %w", err)
+	}
+	if estimate >= 0 {
+		return int64(estimate), nil
+	}
+
+	var count int64
+	if err := pool.QueryRow(ctx, "SELECT count(*) FROM "+qualified).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return count, nil
+}
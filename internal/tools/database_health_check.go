@@ -0,0 +1,670 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/artifact"
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/healthprobe"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+const (
+	// healthCheckMaxLimit caps the rows listed in each report section
+	healthCheckMaxLimit = 50
+
+	// longTransactionAge is how long a transaction must be open before it
+	// is reported as holding back vacuum
+	longTransactionAge = 5 * time.Minute
+
+	// wraparoundCriticalAge is the transaction ID age at which wraparound
+	// is close enough to need immediate attention; PostgreSQL stops
+	// assigning transaction IDs at about 2.1 billion
+	wraparoundCriticalAge = 1500000000
+)
+
+// healthReportArtifact is the artifact kind of cached health reports
+const healthReportArtifact = "health_report"
+
+// DatabaseHealthCheckTool creates the database_health_check tool, which
+// reports vacuum health: dead rows, autovacuum backlog, transactions holding
+// back vacuum, wraparound risk and unused indexes, along with collation
+// version mismatches. Reports are cached, so a
+// repeated check returns the recent report unless refresh is set.
+func DatabaseHealthCheckTool(dbClient *database.Client, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "database_health_check",
+			Description: `Check the vacuum health of the current database: dead row ratios, tables
+past their autovacuum threshold, long-running transactions and other sessions
+that stop vacuum from cleaning up, transaction ID wraparound risk, unused
+indexes, and collation version mismatches that can corrupt indexes after OS
+upgrades.
+
+<usecase>
+Use when:
+- The user asks for a general health check or maintenance review
+- Tables keep growing or queries slow down over time (bloat)
+- Checking whether autovacuum is keeping up, or why it is not
+- Investigating transaction ID wraparound warnings
+</usecase>
+
+<examples>
+✓ database_health_check() → Report for all schemas
+✓ database_health_check(schema="sales", limit=20)
+</examples>
+
+<important>
+- Use get_table_stats for a detailed look at one table from this report, and
+  check_collations for the indexes affected by a collation mismatch
+- Dead row and index usage counters are cumulative since statistics were last reset
+- Sessions of other users are only visible with the pg_read_all_stats role
+- A report generated in the last few minutes is returned from the cache; use
+  refresh=true after maintenance to check again
+- Results of the custom health probes configured by the operator are listed
+  at the end, as of their last run
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Only report tables and indexes in this schema (default: all schemas)",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum rows listed in each section (default: 10, max: %d)", healthCheckMaxLimit),
+						"default":     10,
+					},
+					"refresh": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Run the checks even if a recent report is cached (default: false)",
+						"default":     false,
+					},
+				},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			schema := ValidateOptionalStringParam(args, "schema", "")
+			limit := ValidateOptionalNumberParam(args, "limit", 10)
+			if limit < 1 || limit > healthCheckMaxLimit {
+				return mcp.NewToolError(fmt.Sprintf("limit must be between 1 and %d", healthCheckMaxLimit))
+			}
+			refresh := ValidateBoolParam(args, "refresh", false)
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			header := fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr))
+			store := artifact.NewStoreFromConfig(cfg)
+			key := artifact.Key(healthReportArtifact, dbClient.GetMetadataVersion(), connStr, schema, strconv.Itoa(int(limit)))
+			now := time.Now()
+			if store != nil && !refresh {
+				if cached, ok := store.Get(healthReportArtifact, key, now); ok {
+					logging.Info("database_health_check_executed", "schema", schema, "cached", true)
+					return mcp.NewToolSuccess(header + cached.Content + formatProbeResults(healthprobe.Shared(), now) + fmt.Sprintf(
+						"\n(Cached report from %s ago; use refresh=true to run the checks again.)\n",
+						now.Sub(cached.CreatedAt).Round(time.Second)))
+				}
+			}
+
+			report, err := loadHealthReport(ctx, pool, schema, int(limit))
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to run health check: %v", err))
+			}
+
+			findings := healthFindings(report)
+			logging.Info("database_health_check_executed",
+				"schema", schema,
+				"status", healthStatus(findings),
+				"findings", len(findings),
+			)
+
+			content := formatHealthReport(report, now)
+			if store != nil {
+				if err := store.Put(healthReportArtifact, key, content, now); err != nil {
+					logging.Warn("database_health_check_cache_failed", "error", err)
+				}
+			}
+			// Probe results are current, so they are not cached with the report
+			return mcp.NewToolSuccess(header + content + formatProbeResults(healthprobe.Shared(), now))
+		},
+	}
+}
+
+// healthReport holds the results of a health check
+type healthReport struct {
+	Schema     string // Schema filter, or empty for all schemas
+	StatsReset *time.Time
+
+	// Wraparound
+	FreezeMaxAge      int64
+	MultiXactMaxAge   int64
+	Databases         []databaseXIDAge
+	OldestTables      []tableXIDAge
+	DeadTuples        []deadTupleStats
+	OverThreshold     []deadTupleStats
+	LongTransactions  []longTransaction
+	PreparedXacts     []preparedXact
+	ReplicationSlots  []replicationSlotHold
+	UnusedIndexes     []unusedIndex
+	UnusedIndexesSize int64
+
+	// Databases and collations whose library version no longer matches the
+	// version recorded in the catalogs
+	CollationMismatches []collationVersion
+}
+
+// databaseXIDAge is the age of a database's oldest unfrozen transaction IDs
+type databaseXIDAge struct {
+	Name        string
+	XIDAge      int64
+	MultiXIDAge int64
+}
+
+// tableXIDAge is the age of a table's oldest unfrozen transaction ID
+type tableXIDAge struct {
+	Schema string
+	Table  string
+	XIDAge int64
+	Bytes  int64
+}
+
+// deadTupleStats describes dead rows in a table against its autovacuum threshold
+type deadTupleStats struct {
+	Schema             string
+	Table              string
+	LiveTuples         int64
+	DeadTuples         int64
+	Threshold          int64 // Dead rows at which autovacuum processes the table
+	AutovacuumDisabled bool
+	LastVacuum         *time.Time // Latest of manual and automatic vacuum
+}
+
+// longTransaction is a session whose snapshot holds back vacuum
+type longTransaction struct {
+	PID      int32
+	User     string
+	Database string
+	State    string
+	XIDAge   int64
+	Duration time.Duration
+	Query    string
+}
+
+// preparedXact is a two-phase transaction waiting to be committed
+type preparedXact struct {
+	GID      string
+	Owner    string
+	Database string
+	Prepared time.Time
+	XIDAge   int64
+}
+
+// replicationSlotHold is a replication slot that holds back vacuum
+type replicationSlotHold struct {
+	Name   string
+	Type   string
+	Active bool
+	XIDAge int64
+}
+
+// unusedIndex is an index that has never been scanned
+type unusedIndex struct {
+	Schema string
+	Table  string
+	Index  string
+	Bytes  int64
+}
+
+// deadTupleQuery lists tables with their dead rows and autovacuum threshold,
+// honouring per-table autovacuum settings
+const deadTupleQuery = `
+	WITH t AS (
+		SELECT s.schemaname::text AS schema_name, s.relname::text AS table_name,
+			s.n_live_tup, s.n_dead_tup,
+			(coalesce(ro.threshold, current_setting('autovacuum_vacuum_threshold')::float8)
+				+ coalesce(ro.scale_factor, current_setting('autovacuum_vacuum_scale_factor')::float8)
+				* greatest(c.reltuples, 0))::bigint AS threshold,
+			NOT coalesce(ro.enabled, current_setting('autovacuum')::bool) AS autovacuum_disabled,
+			greatest(s.last_vacuum, s.last_autovacuum) AS last_vacuum
+		FROM pg_catalog.pg_stat_all_tables s
+		JOIN pg_catalog.pg_class c ON c.oid = s.relid
+		LEFT JOIN LATERAL (
+			SELECT
+				max(option_value) FILTER (WHERE option_name = 'autovacuum_vacuum_threshold')::float8 AS threshold,
+				max(option_value) FILTER (WHERE option_name = 'autovacuum_vacuum_scale_factor')::float8 AS scale_factor,
+				max(option_value) FILTER (WHERE option_name = 'autovacuum_enabled')::bool AS enabled
+			FROM pg_catalog.pg_options_to_table(c.reloptions)
+		) ro ON true
+		WHERE s.schemaname NOT IN ('pg_catalog', 'information_schema')
+			AND s.schemaname NOT LIKE 'pg_toast%'
+			AND ($1 = '' OR s.schemaname = $1)
+			AND s.n_dead_tup > 0
+	)
+	SELECT schema_name, table_name, n_live_tup, n_dead_tup, threshold, autovacuum_disabled, last_vacuum
+	FROM t`
+
+// loadHealthReport runs the health check queries in a read-only transaction
+func loadHealthReport(ctx context.Context, pool *pgxpool.Pool, schema string, limit int) (*healthReport, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // read-only transaction, nothing to keep
+	}()
+
+	if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+		return nil, fmt.Errorf("failed to set transaction to read-only: %w", err)
+	}
+
+	report := &healthReport{Schema: schema}
+
+	var serverVersion int
+	err = tx.QueryRow(ctx, `
+		SELECT current_setting('autovacuum_freeze_max_age')::bigint,
+			current_setting('autovacuum_multixact_freeze_max_age')::bigint,
+			(SELECT stats_reset FROM pg_catalog.pg_stat_database WHERE datname = current_database()),
+			current_setting('server_version_num')::int`,
+	).Scan(&report.FreezeMaxAge, &report.MultiXactMaxAge, &report.StatsReset, &serverVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	report.Databases, err = collectRows(ctx, tx, "database ages", func(row pgx.Rows) (databaseXIDAge, error) {
+		var d databaseXIDAge
+		return d, row.Scan(&d.Name, &d.XIDAge, &d.MultiXIDAge)
+	}, `
+		SELECT datname::text, age(datfrozenxid)::bigint, mxid_age(datminmxid)::bigint
+		FROM pg_catalog.pg_database
+		WHERE datallowconn
+		ORDER BY 2 DESC`)
+	if err != nil {
+		return nil, err
+	}
+
+	report.OldestTables, err = collectRows(ctx, tx, "table ages", func(row pgx.Rows) (tableXIDAge, error) {
+		var t tableXIDAge
+		return t, row.Scan(&t.Schema, &t.Table, &t.XIDAge, &t.Bytes)
+	}, `
+		SELECT n.nspname::text, c.relname::text, age(c.relfrozenxid)::bigint,
+			pg_catalog.pg_total_relation_size(c.oid)
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'm')
+			AND ($1 = '' OR n.nspname = $1)
+		ORDER BY 3 DESC
+		LIMIT $2`, schema, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	scanDead := func(row pgx.Rows) (deadTupleStats, error) {
+		var d deadTupleStats
+		return d, row.Scan(&d.Schema, &d.Table, &d.LiveTuples, &d.DeadTuples,
+			&d.Threshold, &d.AutovacuumDisabled, &d.LastVacuum)
+	}
+	report.DeadTuples, err = collectRows(ctx, tx, "dead rows", scanDead,
+		deadTupleQuery+" ORDER BY n_dead_tup DESC LIMIT $2", schema, limit)
+	if err != nil {
+		return nil, err
+	}
+	report.OverThreshold, err = collectRows(ctx, tx, "autovacuum thresholds", scanDead,
+		deadTupleQuery+" WHERE n_dead_tup > threshold ORDER BY n_dead_tup - threshold DESC LIMIT $2", schema, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report.LongTransactions, err = collectRows(ctx, tx, "long-running transactions", func(row pgx.Rows) (longTransaction, error) {
+		var l longTransaction
+		var seconds float64
+		err := row.Scan(&l.PID, &l.User, &l.Database, &l.State, &l.XIDAge, &seconds, &l.Query)
+		l.Duration = time.Duration(seconds * float64(time.Second))
+		return l, err
+	}, `
+		SELECT pid, coalesce(usename::text, ''), coalesce(datname::text, ''), coalesce(state, ''),
+			age(backend_xmin)::bigint,
+			extract(epoch FROM now() - coalesce(xact_start, backend_start))::float8,
+			left(coalesce(query, ''), 200)
+		FROM pg_catalog.pg_stat_activity
+		WHERE backend_xmin IS NOT NULL
+			AND pid <> pg_catalog.pg_backend_pid()
+			AND now() - coalesce(xact_start, backend_start) > make_interval(secs => $1)
+		ORDER BY age(backend_xmin) DESC
+		LIMIT $2`, longTransactionAge.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report.PreparedXacts, err = collectRows(ctx, tx, "prepared transactions", func(row pgx.Rows) (preparedXact, error) {
+		var p preparedXact
+		return p, row.Scan(&p.GID, &p.Owner, &p.Database, &p.Prepared, &p.XIDAge)
+	}, `
+		SELECT gid, owner::text, database::text, prepared, age(transaction)::bigint
+		FROM pg_catalog.pg_prepared_xacts
+		ORDER BY prepared
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report.ReplicationSlots, err = collectRows(ctx, tx, "replication slots", func(row pgx.Rows) (replicationSlotHold, error) {
+		var r replicationSlotHold
+		return r, row.Scan(&r.Name, &r.Type, &r.Active, &r.XIDAge)
+	}, `
+		SELECT slot_name::text, slot_type, active,
+			greatest(age(xmin), age(catalog_xmin))::bigint
+		FROM pg_catalog.pg_replication_slots
+		WHERE xmin IS NOT NULL OR catalog_xmin IS NOT NULL
+		ORDER BY 4 DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indexes backing constraints are needed even if never scanned
+	const unusedIndexFilter = `
+		FROM pg_catalog.pg_stat_all_indexes s
+		JOIN pg_catalog.pg_index i ON i.indexrelid = s.indexrelid
+		WHERE s.idx_scan = 0
+			AND NOT i.indisunique AND NOT i.indisprimary
+			AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint con WHERE con.conindid = s.indexrelid)
+			AND s.schemaname NOT IN ('pg_catalog', 'information_schema')
+			AND s.schemaname NOT LIKE 'pg_toast%'
+			AND ($1 = '' OR s.schemaname = $1)`
+	report.UnusedIndexes, err = collectRows(ctx, tx, "unused indexes", func(row pgx.Rows) (unusedIndex, error) {
+		var u unusedIndex
+		return u, row.Scan(&u.Schema, &u.Table, &u.Index, &u.Bytes)
+	}, `
+		SELECT s.schemaname::text, s.relname::text, s.indexrelname::text,
+			pg_catalog.pg_relation_size(s.indexrelid)`+unusedIndexFilter+`
+		ORDER BY 4 DESC
+		LIMIT $2`, schema, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `SELECT coalesce(sum(pg_catalog.pg_relation_size(s.indexrelid)), 0)::bigint`+unusedIndexFilter, schema).
+		Scan(&report.UnusedIndexesSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read unused indexes: %w", err)
+	}
+
+	if serverVersion >= 100000 {
+		databases, collations, err := loadCollationVersions(ctx, tx, serverVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range append(databases, collations...) {
+			if c.mismatched() {
+				report.CollationMismatches = append(report.CollationMismatches, c)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// collectRows runs a query and scans every row with scan
+func collectRows[T any](ctx context.Context, tx pgx.Tx, what string, scan func(pgx.Rows) (T, error), query string, args ...interface{}) ([]T, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", what, err)
+	}
+	defer rows.Close()
+
+	var result []T
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", what, err)
+		}
+		result = append(result, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", what, err)
+	}
+	return result, nil
+}
+
+// healthFinding is a problem found by the health check
+type healthFinding struct {
+	Severity string // "critical" or "warning"
+	Message  string
+}
+
+// healthFindings points out the parts of a report that call for action
+func healthFindings(report *healthReport) []healthFinding {
+	var findings []healthFinding
+	add := func(severity, format string, args ...interface{}) {
+		findings = append(findings, healthFinding{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, db := range report.Databases {
+		switch {
+		case db.XIDAge >= wraparoundCriticalAge:
+			add("critical", "Database %s is %d transactions from its oldest unfrozen ID; wraparound protection will stop writes at about 2.1 billion. Find what is blocking vacuum below and run VACUUM (FREEZE) on the oldest tables.",
+				db.Name, db.XIDAge)
+		case report.FreezeMaxAge > 0 && db.XIDAge > report.FreezeMaxAge:
+			add("warning", "Database %s has a transaction ID age of %d, above autovacuum_freeze_max_age (%d); anti-wraparound vacuums should be running.",
+				db.Name, db.XIDAge, report.FreezeMaxAge)
+		}
+		if report.MultiXactMaxAge > 0 && db.MultiXIDAge > report.MultiXactMaxAge {
+			add("warning", "Database %s has a multixact ID age of %d, above autovacuum_multixact_freeze_max_age (%d).",
+				db.Name, db.MultiXIDAge, report.MultiXactMaxAge)
+		}
+	}
+
+	for _, t := range report.OverThreshold {
+		if t.AutovacuumDisabled {
+			add("warning", "%s.%s has %d dead rows and autovacuum is disabled for it; run VACUUM or re-enable autovacuum.",
+				t.Schema, t.Table, t.DeadTuples)
+		} else if t.DeadTuples > 2*t.Threshold && t.DeadTuples > 10000 {
+			add("warning", "%s.%s has %d dead rows, more than twice its autovacuum threshold of %d; autovacuum is not keeping up or is being blocked.",
+				t.Schema, t.Table, t.DeadTuples, t.Threshold)
+		}
+	}
+
+	for _, l := range report.LongTransactions {
+		add("warning", "Session %d (%s, %s) has held a snapshot for %s, stopping vacuum from removing rows deleted since then.",
+			l.PID, l.User, l.State, formatHealthDuration(l.Duration))
+	}
+	for _, p := range report.PreparedXacts {
+		add("warning", "Prepared transaction %q in %s is holding back vacuum (transaction ID age %d); commit or roll it back with COMMIT PREPARED or ROLLBACK PREPARED.",
+			p.GID, p.Database, p.XIDAge)
+	}
+	for _, r := range report.ReplicationSlots {
+		if !r.Active {
+			add("warning", "Inactive replication slot %s is holding back vacuum (transaction ID age %d); drop it if it is no longer needed.",
+				r.Name, r.XIDAge)
+		}
+	}
+
+	for _, c := range report.CollationMismatches {
+		what := "Collation " + c.Schema + "." + c.Name
+		if c.Database {
+			what = "The default collation of database " + c.Name
+		}
+		add("warning", "%s was recorded with version %s, but the %s library now provides %s; indexes using it may return wrong results until rebuilt. Run check_collations for the affected indexes and REINDEX commands.",
+			what, c.Recorded, c.Provider, c.Actual)
+	}
+
+	if report.UnusedIndexesSize > 100*1024*1024 {
+		add("warning", "Indexes that have never been scanned take %s; consider dropping them if the statistics cover a representative period.",
+			formatSize(report.UnusedIndexesSize))
+	}
+	return findings
+}
+
+// healthStatus summarizes findings as OK, WARNING or CRITICAL
+func healthStatus(findings []healthFinding) string {
+	status := "OK"
+	for _, f := range findings {
+		if f.Severity == "critical" {
+			return "CRITICAL"
+		}
+		status = "WARNING"
+	}
+	return status
+}
+
+// formatHealthReport formats a health report for the LLM
+func formatHealthReport(report *healthReport, now time.Time) string {
+	var sb strings.Builder
+	findings := healthFindings(report)
+
+	sb.WriteString(fmt.Sprintf("Health check status: %s\n", healthStatus(findings)))
+	if report.Schema != "" {
+		sb.WriteString(fmt.Sprintf("Schema: %s\n", report.Schema))
+	}
+	sb.WriteString(fmt.Sprintf("Statistics collected since: %s\n", formatStatsTime(report.StatsReset, now, "server start or last reset")))
+
+	if len(findings) > 0 {
+		sb.WriteString("\nFindings:\n")
+		for _, f := range findings {
+			sb.WriteString(fmt.Sprintf("- [%s] %s\n", strings.ToUpper(f.Severity), f.Message))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\nTransaction ID wraparound (autovacuum_freeze_max_age: %d):\n", report.FreezeMaxAge))
+	for _, db := range report.Databases {
+		sb.WriteString(fmt.Sprintf("  - %s: transaction ID age %d (%s of 2^31), multixact age %d\n",
+			db.Name, db.XIDAge, formatRatio(db.XIDAge, 1<<31), db.MultiXIDAge))
+	}
+	if len(report.OldestTables) > 0 {
+		sb.WriteString("  Oldest tables in this database:\n")
+		for _, t := range report.OldestTables {
+			sb.WriteString(fmt.Sprintf("  - %s.%s: age %d (%s)\n", t.Schema, t.Table, t.XIDAge, formatSize(t.Bytes)))
+		}
+	}
+
+	sb.WriteString("\nDead rows:\n")
+	if len(report.DeadTuples) == 0 {
+		sb.WriteString("  (no tables with dead rows)\n")
+	}
+	for _, t := range report.DeadTuples {
+		sb.WriteString(fmt.Sprintf("  - %s\n", formatDeadTuples(t, now)))
+	}
+
+	sb.WriteString("\nTables past their autovacuum threshold:\n")
+	if len(report.OverThreshold) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, t := range report.OverThreshold {
+		sb.WriteString(fmt.Sprintf("  - %s\n", formatDeadTuples(t, now)))
+	}
+
+	sb.WriteString(fmt.Sprintf("\nSessions holding back vacuum (open longer than %s):\n", formatHealthDuration(longTransactionAge)))
+	if len(report.LongTransactions)+len(report.PreparedXacts)+len(report.ReplicationSlots) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, l := range report.LongTransactions {
+		sb.WriteString(fmt.Sprintf("  - pid %d, user %s, database %s, %s for %s, xmin age %d: %s\n",
+			l.PID, l.User, l.Database, l.State, formatHealthDuration(l.Duration), l.XIDAge, strings.Join(strings.Fields(l.Query), " ")))
+	}
+	for _, p := range report.PreparedXacts {
+		sb.WriteString(fmt.Sprintf("  - prepared transaction %q, owner %s, database %s, prepared %s, xid age %d\n",
+			p.GID, p.Owner, p.Database, formatStatsTime(&p.Prepared, now, ""), p.XIDAge))
+	}
+	for _, r := range report.ReplicationSlots {
+		state := "inactive"
+		if r.Active {
+			state = "active"
+		}
+		sb.WriteString(fmt.Sprintf("  - replication slot %s (%s, %s), xmin age %d\n", r.Name, r.Type, state, r.XIDAge))
+	}
+
+	sb.WriteString(fmt.Sprintf("\nUnused indexes (never scanned, %s in total):\n", formatSize(report.UnusedIndexesSize)))
+	if len(report.UnusedIndexes) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, u := range report.UnusedIndexes {
+		sb.WriteString(fmt.Sprintf("  - %s on %s.%s (%s)\n", u.Index, u.Schema, u.Table, formatSize(u.Bytes)))
+	}
+
+	sb.WriteString("\nCollation version mismatches:\n")
+	if len(report.CollationMismatches) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, c := range report.CollationMismatches {
+		sb.WriteString(fmt.Sprintf("  - %s\n", formatCollationVersion(c)))
+	}
+	return sb.String()
+}
+
+// formatProbeResults formats the latest results of the custom health
+// probes, or returns an empty string if none are configured
+func formatProbeResults(runner *healthprobe.Runner, now time.Time) string {
+	if runner == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\nCustom health probes:\n")
+	results := runner.Results()
+	if len(results) == 0 {
+		sb.WriteString("  (no results yet)\n")
+	}
+	for _, r := range results {
+		line := fmt.Sprintf("  - %s (database %s): %s since %s, checked %s ago",
+			r.Probe, r.Database, strings.ToUpper(string(r.Status)),
+			r.Since.UTC().Format(time.RFC3339), now.Sub(r.CheckedAt).Round(time.Second))
+		if r.Message != "" {
+			line += ": " + r.Message
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// formatDeadTuples formats one table's dead row statistics
+func formatDeadTuples(t deadTupleStats, now time.Time) string {
+	line := fmt.Sprintf("%s.%s: %d dead, %d live (%s dead), autovacuum threshold %d, last vacuum %s",
+		t.Schema, t.Table, t.DeadTuples, t.LiveTuples, formatRatio(t.DeadTuples, t.LiveTuples+t.DeadTuples),
+		t.Threshold, formatStatsTime(t.LastVacuum, now, "never"))
+	if t.AutovacuumDisabled {
+		line += ", autovacuum disabled"
+	}
+	return line
+}
+
+// formatHealthDuration formats a duration rounded to a readable unit
+func formatHealthDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%.1f hours", d.Hours())
+	default:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	}
+}
@@ -0,0 +1,166 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/healthprobe"
+)
+
+// sampleHealthReport returns a report for a database with nothing to fix
+func sampleHealthReport(now time.Time) *healthReport {
+	vacuumed := now.Add(-2 * time.Hour)
+	return &healthReport{
+		FreezeMaxAge:    200000000,
+		MultiXactMaxAge: 400000000,
+		Databases: []databaseXIDAge{
+			{Name: "app", XIDAge: 50000000, MultiXIDAge: 10},
+		},
+		OldestTables: []tableXIDAge{
+			{Schema: "public", Table: "orders", XIDAge: 50000000, Bytes: 8192},
+		},
+		DeadTuples: []deadTupleStats{
+			{Schema: "public", Table: "orders", LiveTuples: 9000, DeadTuples: 1000, Threshold: 1850, LastVacuum: &vacuumed},
+		},
+	}
+}
+
+func TestHealthFindings(t *testing.T) {
+	now := time.Now()
+
+	report := sampleHealthReport(now)
+	if findings := healthFindings(report); len(findings) != 0 {
+		t.Errorf("expected no findings for a healthy database, got %v", findings)
+	}
+	if status := healthStatus(healthFindings(report)); status != "OK" {
+		t.Errorf("expected status OK, got %s", status)
+	}
+
+	report.Databases[0].XIDAge = 250000000
+	report.OverThreshold = []deadTupleStats{
+		{Schema: "public", Table: "events", LiveTuples: 100000, DeadTuples: 60000, Threshold: 20050},
+		{Schema: "public", Table: "queue", DeadTuples: 500, Threshold: 50, AutovacuumDisabled: true},
+	}
+	report.LongTransactions = []longTransaction{
+		{PID: 42, User: "etl", State: "idle in transaction", Duration: 3 * time.Hour},
+	}
+	report.ReplicationSlots = []replicationSlotHold{
+		{Name: "active_slot", Type: "physical", Active: true, XIDAge: 100},
+		{Name: "old_slot", Type: "logical", XIDAge: 90000000},
+	}
+	findings := healthFindings(report)
+	if len(findings) != 5 {
+		t.Fatalf("expected 5 findings, got %d: %v", len(findings), findings)
+	}
+	if status := healthStatus(findings); status != "WARNING" {
+		t.Errorf("expected status WARNING, got %s", status)
+	}
+
+	report.Databases[0].XIDAge = 1600000000
+	findings = healthFindings(report)
+	if findings[0].Severity != "critical" || !strings.Contains(findings[0].Message, "wraparound") {
+		t.Errorf("expected a critical wraparound finding first, got %v", findings[0])
+	}
+	if status := healthStatus(findings); status != "CRITICAL" {
+		t.Errorf("expected status CRITICAL, got %s", status)
+	}
+}
+
+func TestHealthFindingsCollations(t *testing.T) {
+	report := sampleHealthReport(time.Now())
+	report.CollationMismatches = []collationVersion{
+		{Database: true, Current: true, Name: "app", Provider: "libc", Recorded: "2.31", Actual: "2.36"},
+		{Schema: "public", Name: "german", Provider: "icu", Recorded: "153.14", Actual: "153.120"},
+	}
+	findings := healthFindings(report)
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, "default collation of database app") ||
+		!strings.Contains(findings[1].Message, "Collation public.german") ||
+		!strings.Contains(findings[1].Message, "check_collations") {
+		t.Errorf("unexpected collation findings: %v", findings)
+	}
+
+	out := formatHealthReport(report, time.Now())
+	if !strings.Contains(out, "Collation version mismatches:\n  - app: libc") {
+		t.Errorf("expected the mismatches in the report:\n%s", out)
+	}
+}
+
+func TestFormatHealthReport(t *testing.T) {
+	now := time.Now()
+	report := sampleHealthReport(now)
+	report.PreparedXacts = []preparedXact{
+		{GID: "tx1", Owner: "app", Database: "app", Prepared: now.Add(-time.Hour), XIDAge: 5000},
+	}
+	report.UnusedIndexes = []unusedIndex{
+		{Schema: "public", Table: "orders", Index: "orders_note_idx", Bytes: 16384},
+	}
+	report.UnusedIndexesSize = 16384
+
+	output := formatHealthReport(report, now)
+	for _, want := range []string{
+		"Health check status: WARNING",
+		`Prepared transaction "tx1"`,
+		"app: transaction ID age 50000000",
+		"public.orders: 1000 dead, 9000 live (10.0% dead), autovacuum threshold 1850",
+		"Tables past their autovacuum threshold:\n  (none)",
+		"orders_note_idx on public.orders (16.0 kB)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestFormatHealthDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{7 * time.Minute, "7 minutes"},
+		{90 * time.Minute, "1.5 hours"},
+		{72 * time.Hour, "3 days"},
+	}
+	for _, tt := range tests {
+		if got := formatHealthDuration(tt.d); got != tt.want {
+			t.Errorf("formatHealthDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestFormatProbeResults(t *testing.T) {
+	if got := formatProbeResults(nil, time.Now()); got != "" {
+		t.Errorf("expected no section without probes, got %q", got)
+	}
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	runner := healthprobe.New(healthprobe.Config{
+		Check: func(ctx context.Context, p healthprobe.Probe) (healthprobe.Status, string, error) {
+			return healthprobe.StatusWarn, "replica is 90s behind", nil
+		},
+		Now: func() time.Time { return now.Add(-30 * time.Second) },
+	})
+	if got := formatProbeResults(runner, now); !strings.Contains(got, "(no results yet)") {
+		t.Errorf("expected a placeholder before probes run, got %q", got)
+	}
+
+	runner.RunProbe(context.Background(), healthprobe.Probe{Name: "replication_lag", Database: "main"})
+	want := "  - replication_lag (database main): WARN since 2025-06-01T11:59:30Z, checked 30s ago: replica is 90s behind\n"
+	if got := formatProbeResults(runner, now); !strings.Contains(got, want) {
+		t.Errorf("expected %q in:\n%s", want, got)
+	}
+}
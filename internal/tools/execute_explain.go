@@ -16,13 +16,20 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/autoanalyze"
 	"pgedge-postgres-mcp/internal/database"
 	"pgedge-postgres-mcp/internal/logging"
 	"pgedge-postgres-mcp/internal/mcp"
 )
 
-// ExecuteExplainTool creates the execute_explain tool for query performance analysis
-func ExecuteExplainTool(dbClient *database.Client) Tool {
+// ExecuteExplainTool creates the execute_explain tool for query performance
+// analysis. writesAllowed reports whether the database allows writes; the
+// background ANALYZE of misestimated tables is only queued when it does
+// (nil = never).
+func ExecuteExplainTool(dbClient *database.Client, writesAllowed func() bool) Tool {
 	return Tool{
 		Definition: mcp.Tool{
 			Name: "execute_explain",
@@ -170,7 +177,9 @@ READ ONLY transaction to prevent side effects. However, be cautious with:
 				return mcp.NewToolError(fmt.Sprintf("Failed to set transaction to read-only: %v", err))
 			}
 
-			// Execute EXPLAIN
+			// Execute EXPLAIN; with ANALYZE this runs the query, which may take a while
+			progress := progressFromArgs(args)
+			progress.Report(1, 2, "Running EXPLAIN")
 			rows, err := tx.Query(ctx, explainQuery)
 			if err != nil {
 				return mcp.NewToolError(fmt.Sprintf("Error executing EXPLAIN: %v\n\nQuery: %s", err, explainQuery))
@@ -191,11 +200,20 @@ READ ONLY transaction to prevent side effects. However, be cautious with:
 				return mcp.NewToolError(fmt.Sprintf("Error iterating EXPLAIN output: %v", err))
 			}
 
+			// Tables with badly estimated scans get fresh statistics in the
+			// background, if enabled. ANALYZE writes to the catalogs, so it
+			// only runs in databases that allow writes.
+			var analyzeQueued []string
+			if analyzer := autoanalyze.Shared(); analyzer != nil && analyze && writesAllowed != nil && writesAllowed() {
+				analyzeQueued = queueAutoAnalyze(ctx, tx, analyzer, pool, connStr, strings.Join(explainOutput, "\n"), format)
+			}
+
 			// Commit the read-only transaction
 			if err := tx.Commit(ctx); err != nil {
 				return mcp.NewToolError(fmt.Sprintf("Failed to commit transaction: %v", err))
 			}
 			committed = true
+			progress.Report(2, 2, "Analyzing plan")
 
 			// Format the output
 			var result strings.Builder
@@ -220,6 +238,10 @@ READ ONLY transaction to prevent side effects. However, be cautious with:
 					result.WriteString(analysis)
 				}
 			}
+			if len(analyzeQueued) > 0 {
+				result.WriteString(fmt.Sprintf("\nRow estimates were far from the actual rows for %s; ANALYZE has been queued to refresh their statistics in the background.\n",
+					strings.Join(analyzeQueued, ", ")))
+			}
 
 			// Log execution metrics
 			logging.Info("execute_explain_executed",
@@ -228,6 +250,7 @@ READ ONLY transaction to prevent side effects. However, be cautious with:
 				"buffers", buffers,
 				"format", format,
 				"output_lines", len(explainOutput),
+				"analyze_queued", len(analyzeQueued),
 			)
 
 			return mcp.NewToolSuccess(result.String())
@@ -235,6 +258,37 @@ READ ONLY transaction to prevent side effects. However, be cautious with:
 	}
 }
 
+// queueAutoAnalyze queues background ANALYZE of the tables whose scans in
+// an EXPLAIN ANALYZE plan were badly estimated, and returns the tables
+// queued. The plan's table names are resolved in the transaction that ran
+// it, so they follow the same search path; system catalogs are skipped.
+func queueAutoAnalyze(ctx context.Context, tx pgx.Tx, analyzer *autoanalyze.Analyzer, pool *pgxpool.Pool, connStr string, plan string, format string) []string {
+	names := analyzer.Misestimated(plan, format)
+	if len(names) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, `SELECT c.oid::regclass::text FROM pg_catalog.pg_class c
+		WHERE c.oid IN (SELECT to_regclass(n) FROM unnest($1::text[]) AS n)
+		AND c.relkind IN ('r', 'm') AND c.relnamespace <> 'pg_catalog'::regnamespace`, names)
+	if err != nil {
+		logging.Warn("auto_analyze_resolve_failed", "error", err)
+		return nil
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		logging.Warn("auto_analyze_resolve_failed", "error", err)
+		return nil
+	}
+
+	sanitizedConn := database.SanitizeConnStr(connStr)
+	requests := make([]autoanalyze.Table, len(tables))
+	for i, name := range tables {
+		requests[i] = autoanalyze.Table{Database: sanitizedConn, Name: name, Pool: pool}
+	}
+	return analyzer.Request(requests...)
+}
+
 // analyzeExplainOutput extracts key metrics and provides recommendations
 func analyzeExplainOutput(explainText string) string {
 	var analysis strings.Builder
@@ -11,12 +11,19 @@
 package tools
 
 import (
+	"context"
+	"os"
 	"strings"
 	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/autoanalyze"
+	"pgedge-postgres-mcp/internal/database"
 )
 
 func TestExecuteExplainToolDefinition(t *testing.T) {
-	tool := ExecuteExplainTool(nil)
+	tool := ExecuteExplainTool(nil, nil)
 
 	if tool.Definition.Name != "execute_explain" {
 		t.Errorf("Tool name = %v, want execute_explain", tool.Definition.Name)
@@ -63,7 +70,7 @@ func TestExecuteExplainToolDefinition(t *testing.T) {
 }
 
 func TestExecuteExplainValidation(t *testing.T) {
-	tool := ExecuteExplainTool(nil)
+	tool := ExecuteExplainTool(nil, nil)
 
 	tests := []struct {
 		name        string
@@ -210,7 +217,7 @@ func TestAnalyzeExplainOutput(t *testing.T) {
 
 func TestExecuteExplainToolResponseFormat(t *testing.T) {
 	// This test verifies the tool definition format
-	tool := ExecuteExplainTool(nil)
+	tool := ExecuteExplainTool(nil, nil)
 
 	// Verify tool definition structure
 	if tool.Definition.Name != "execute_explain" {
@@ -233,7 +240,7 @@ func TestExecuteExplainToolResponseFormat(t *testing.T) {
 }
 
 func TestExecuteExplainBooleanDefaults(t *testing.T) {
-	tool := ExecuteExplainTool(nil)
+	tool := ExecuteExplainTool(nil, nil)
 
 	// Test that boolean parameters have proper defaults
 	schema := tool.Definition.InputSchema
@@ -263,7 +270,7 @@ func TestExecuteExplainBooleanDefaults(t *testing.T) {
 func TestExecuteExplainToolRegistration(t *testing.T) {
 	// Verify that execute_explain tool can be registered
 	registry := NewRegistry()
-	tool := ExecuteExplainTool(nil)
+	tool := ExecuteExplainTool(nil, nil)
 
 	registry.Register("execute_explain", tool)
 
@@ -279,7 +286,7 @@ func TestExecuteExplainToolRegistration(t *testing.T) {
 
 func TestExecuteExplainReturnsToolResponse(t *testing.T) {
 	// Test that validation errors return proper tool responses without requiring DB
-	tool := ExecuteExplainTool(nil)
+	tool := ExecuteExplainTool(nil, nil)
 
 	// Test with missing query (validation error, no DB needed)
 	response, _ := tool.Handler(map[string]interface{}{})
@@ -308,7 +315,7 @@ func TestExecuteExplainReturnsToolResponse(t *testing.T) {
 func TestExecuteExplainToolResponse(t *testing.T) {
 	// Test that execute_explain properly uses mcp.NewToolError and mcp.NewToolSuccess
 	// This is tested implicitly through the validation tests above
-	tool := ExecuteExplainTool(nil)
+	tool := ExecuteExplainTool(nil, nil)
 
 	// Test validation error response
 	response, _ := tool.Handler(map[string]interface{}{})
@@ -322,3 +329,56 @@ func TestExecuteExplainToolResponse(t *testing.T) {
 		t.Error("Non-SELECT query should return error response")
 	}
 }
+
+// TestExecuteExplainAutoAnalyze_WritesAllowed checks that background ANALYZE
+// is only queued in databases that allow writes
+func TestExecuteExplainAutoAnalyze_WritesAllowed(t *testing.T) {
+	connStr := os.Getenv("TEST_PGEDGE_POSTGRES_CONNECTION_STRING")
+	if connStr == "" {
+		t.Skip("TEST_PGEDGE_POSTGRES_CONNECTION_STRING not set, skipping integration test")
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer conn.Close(ctx)
+	// Every row matches, so the default selectivity badly underestimates them
+	for _, sql := range []string{
+		"DROP TABLE IF EXISTS auto_analyze_orders",
+		"CREATE TABLE auto_analyze_orders (id int, status text)",
+		"INSERT INTO auto_analyze_orders SELECT g, 'pending' FROM generate_series(1, 20000) g",
+	} {
+		if _, err := conn.Exec(ctx, sql); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+	defer conn.Exec(ctx, "DROP TABLE auto_analyze_orders") //nolint:errcheck // best-effort cleanup
+
+	client := database.NewClientWithConnectionString(connStr, nil)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer client.Close()
+
+	autoanalyze.SetShared(autoanalyze.New(autoanalyze.Config{
+		MinRatio: 10,
+		MinRows:  1000,
+		Analyze:  func(context.Context, autoanalyze.Table) error { return nil },
+	}))
+	defer autoanalyze.SetShared(nil)
+
+	args := map[string]interface{}{"query": "SELECT * FROM auto_analyze_orders WHERE status = 'pending'"}
+	for _, writesAllowed := range []bool{false, true} {
+		tool := ExecuteExplainTool(client, func() bool { return writesAllowed })
+		response, err := tool.Handler(args)
+		if err != nil || response.IsError {
+			t.Fatalf("writes allowed %t: unexpected error: %v %+v", writesAllowed, err, response)
+		}
+		queued := strings.Contains(response.Content[0].Text, "ANALYZE has been queued")
+		if queued != writesAllowed {
+			t.Errorf("writes allowed %t: expected ANALYZE queued %t, got %t", writesAllowed, writesAllowed, queued)
+		}
+	}
+}
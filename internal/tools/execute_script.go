@@ -0,0 +1,374 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/masking"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// ExecuteScriptTool creates the execute_script tool, which applies a script
+// of SQL statements in a single transaction
+// The tool writes to the database, so it is disabled unless enabled in the
+// configuration
+func ExecuteScriptTool(dbClient *database.Client, masker *masking.Masker) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "execute_script",
+			Description: `Apply a script of SQL statements (a migration, data fix or DDL) in a single
+transaction. Unlike query_database, this tool CAN modify data and schema.
+
+<usecase>
+Use when:
+- The user asks you to apply a migration or schema change
+- Running several INSERT/UPDATE/DELETE statements that belong together
+</usecase>
+
+<behavior>
+- By default the first failing statement rolls back the whole script and the
+  remaining statements are skipped
+- With continue_on_error=true each statement runs inside a savepoint: a failing
+  statement is rolled back on its own and the script continues; the statements
+  that succeeded are committed
+- The result lists every statement as OK, ROLLED BACK or SKIPPED
+- With dry_run=true the script runs and is then rolled back; for each UPDATE
+  the result also shows the before and after values of a sample of the rows
+  it changes
+</behavior>
+
+<important>
+- Preview DDL with plan_schema_change and confirm with the user first
+- Preview data changes with dry_run=true and show the user the changed
+  values before applying them
+- Do not include BEGIN, COMMIT, ROLLBACK or SAVEPOINT; the tool manages the
+  transaction
+- Statements that cannot run in a transaction (CREATE INDEX CONCURRENTLY,
+  VACUUM, CREATE DATABASE) are rejected
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"script": map[string]interface{}{
+						"type":        "string",
+						"description": "SQL statements separated by semicolons",
+					},
+					"continue_on_error": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Roll back only the failing statement (using a savepoint) and continue with the rest (default: false)",
+						"default":     false,
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Run the script and roll it back, showing the values each UPDATE changes (default: false)",
+						"default":     false,
+					},
+				},
+				Required: []string{"script"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			script, errResp := ValidateStringParam(args, "script")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			continueOnError := ValidateBoolParam(args, "continue_on_error", false)
+			dryRun := ValidateBoolParam(args, "dry_run", false)
+
+			statements, err := splitScript(script)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			// Connections default to read-only transactions
+			ctx := context.Background()
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			committed := false
+			defer func() {
+				if !committed {
+					_ = tx.Rollback(ctx) //nolint:errcheck // rollback after a failed commit is expected to fail
+				}
+			}()
+
+			var exec scriptExecer = tx
+			if dryRun {
+				exec = newPreviewExecer(tx, masker)
+			}
+			results, aborted := runScript(ctx, exec, statements, continueOnError)
+			if !aborted && !dryRun {
+				if err := tx.Commit(ctx); err != nil {
+					return mcp.NewToolError(fmt.Sprintf("%s\nCommit failed, no changes were applied: %v",
+						formatScriptResults(results), err))
+				}
+				committed = true
+			}
+
+			// Schema changes invalidate the cached metadata used by other tools
+			if committed && scriptChangesSchema(results) {
+				if err := dbClient.LoadMetadataFor(connStr); err != nil {
+					logging.Warn("execute_script_metadata_refresh_failed", "error", err)
+				}
+			}
+
+			logging.Info("execute_script_executed",
+				"statements", len(statements),
+				"continue_on_error", continueOnError,
+				"dry_run", dryRun,
+				"committed", committed,
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatScriptResults(results))
+			if dryRun {
+				sb.WriteString(formatUpdatePreviews(results))
+				sb.WriteString("\n")
+				sb.WriteString(formatDryRunOutcome(results, aborted))
+				return mcp.NewToolSuccess(sb.String())
+			}
+			sb.WriteString("\n")
+			sb.WriteString(formatScriptOutcome(results, committed))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// scriptStatus is the outcome of one statement in a script
+type scriptStatus string
+
+const (
+	scriptOK         scriptStatus = "OK"
+	scriptRolledBack scriptStatus = "ROLLED BACK"
+	scriptSkipped    scriptStatus = "SKIPPED"
+)
+
+// scriptStatement is one statement of a script and its outcome
+type scriptStatement struct {
+	SQL      string
+	Status   scriptStatus
+	Tag      string // Command tag, such as "UPDATE 3"
+	Error    string
+	Duration time.Duration // Time the statement took to run
+
+	// Preview holds the changed values of an UPDATE run by a dry run
+	Preview *updatePreview
+
+	// Verifications hold the checks of a query_database script run with
+	// verify=true
+	Verifications []writeVerification
+}
+
+// scriptExecer runs statements; implemented by pgx.Tx
+type scriptExecer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// transactionControl statements would interfere with the script's transaction
+var transactionControl = map[string]bool{
+	"BEGIN": true, "START": true, "COMMIT": true, "END": true, "ROLLBACK": true,
+	"ABORT": true, "SAVEPOINT": true, "RELEASE": true,
+}
+
+// splitScript splits a script into statements, keeping the original text of
+// each, and rejects statements that cannot run in the script's transaction
+func splitScript(script string) ([]string, error) {
+	tokens, err := tokenizeSQL(script)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse script: %w", err)
+	}
+
+	var statements []string
+	for i, stmt := range splitStatements(tokens) {
+		text := script[stmt[0].start:stmt[len(stmt)-1].end]
+		if reason := scriptStatementError(stmt); reason != "" {
+			return nil, fmt.Errorf("statement %d cannot run in a transaction: %s\n%s", i+1, reason, text)
+		}
+		statements = append(statements, text)
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no statements found")
+	}
+	return statements, nil
+}
+
+// scriptStatementError explains why a statement cannot be part of a script,
+// or returns an empty string
+func scriptStatementError(stmt []sqlToken) string {
+	first := stmt[0].upper
+	switch {
+	case transactionControl[first], first == "PREPARE" && len(stmt) > 1 && stmt[1].isKeyword("TRANSACTION"):
+		return "transactions are managed by the tool; remove " + first
+	case first == "VACUUM":
+		return "VACUUM cannot run inside a transaction block"
+	case containsKeyword(stmt, "CONCURRENTLY"):
+		return "CONCURRENTLY cannot run inside a transaction block"
+	case (first == "CREATE" || first == "DROP") && len(stmt) > 1 && stmt[1].isKeyword("DATABASE", "TABLESPACE"):
+		return first + " " + stmt[1].upper + " cannot run inside a transaction block"
+	case first == "ALTER" && len(stmt) > 1 && stmt[1].isKeyword("SYSTEM"):
+		return "ALTER SYSTEM cannot run inside a transaction block"
+	}
+	return ""
+}
+
+// runScript executes statements in an open transaction. With
+// continueOnError each statement runs in a savepoint that is rolled back if
+// it fails; otherwise the first failure aborts the script. Returns true if
+// the transaction must be rolled back.
+func runScript(ctx context.Context, tx scriptExecer, statements []string, continueOnError bool) ([]scriptStatement, bool) {
+	results := make([]scriptStatement, len(statements))
+	for i, sql := range statements {
+		results[i] = scriptStatement{SQL: sql, Status: scriptSkipped}
+	}
+
+	for i := range results {
+		result := &results[i]
+		savepoint := fmt.Sprintf("execute_script_%d", i+1)
+
+		if continueOnError {
+			if _, err := tx.Exec(ctx, "SAVEPOINT "+savepoint); err != nil {
+				result.Status = scriptRolledBack
+				result.Error = fmt.Sprintf("failed to create savepoint: %v", err)
+				return results, true
+			}
+		}
+
+		started := time.Now()
+		tag, err := tx.Exec(ctx, result.SQL)
+		result.Duration = time.Since(started)
+		if err != nil {
+			result.Status = scriptRolledBack
+			result.Error = err.Error()
+			if !continueOnError {
+				return results, true
+			}
+			if _, err := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); err != nil {
+				result.Error += fmt.Sprintf("; failed to roll back to savepoint: %v", err)
+				return results, true
+			}
+			continue
+		}
+
+		result.Status = scriptOK
+		result.Tag = tag.String()
+		if previewer, ok := tx.(scriptPreviewer); ok {
+			result.Preview = previewer.takePreview()
+		}
+		if verifier, ok := tx.(scriptVerifier); ok {
+			result.Verifications = verifier.takeVerifications()
+		}
+		if continueOnError {
+			if _, err := tx.Exec(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+				result.Error = fmt.Sprintf("failed to release savepoint: %v", err)
+				return results, true
+			}
+		}
+	}
+	return results, false
+}
+
+// scriptChangesSchema reports whether a successful statement changed the schema
+func scriptChangesSchema(results []scriptStatement) bool {
+	for _, result := range results {
+		if result.Status != scriptOK {
+			continue
+		}
+		verb, _, _ := strings.Cut(result.Tag, " ")
+		switch verb {
+		case "CREATE", "ALTER", "DROP", "COMMENT":
+			return true
+		}
+	}
+	return false
+}
+
+// formatScriptResults lists the outcome of each statement
+func formatScriptResults(results []scriptStatement) string {
+	var sb strings.Builder
+	for i, result := range results {
+		status := string(result.Status)
+		if result.Tag != "" {
+			status += " (" + result.Tag + ")"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s: %s\n", i+1, status, statementPreview(result.SQL)))
+		if result.Error != "" {
+			sb.WriteString(fmt.Sprintf("   Error: %s\n", result.Error))
+		}
+	}
+	return sb.String()
+}
+
+// formatScriptOutcome summarizes what happened to the transaction
+func formatScriptOutcome(results []scriptStatement, committed bool) string {
+	counts := make(map[scriptStatus]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	if !committed {
+		msg := fmt.Sprintf("Transaction rolled back: no changes were applied (%d statements skipped).", counts[scriptSkipped])
+		if counts[scriptSkipped] > 0 {
+			msg += " Fix the failing statement and run the script again, or use continue_on_error to apply the statements that succeed."
+		}
+		return msg + "\n"
+	}
+
+	msg := fmt.Sprintf("Transaction committed: %d of %d statements applied", counts[scriptOK], len(results))
+	if counts[scriptRolledBack] > 0 {
+		msg += fmt.Sprintf(", %d rolled back to their savepoints and not applied", counts[scriptRolledBack])
+	}
+	return msg + ".\n"
+}
+
+// formatDryRunOutcome summarizes a dry run, which is always rolled back
+func formatDryRunOutcome(results []scriptStatement, aborted bool) string {
+	ok := 0
+	for _, result := range results {
+		if result.Status == scriptOK {
+			ok++
+		}
+	}
+	if aborted {
+		return fmt.Sprintf("Dry run failed and was rolled back: no changes were applied (%d of %d statements succeeded). Fix the failing statement before applying the script.\n",
+			ok, len(results))
+	}
+	return fmt.Sprintf("Dry run rolled back: no changes were applied (%d of %d statements succeeded). Run again with dry_run=false to apply the script.\n",
+		ok, len(results))
+}
+
+// statementPreview shortens a statement to a single line for display
+func statementPreview(sql string) string {
+	preview := []rune(strings.Join(strings.Fields(sql), " "))
+	if len(preview) > 100 {
+		return string(preview[:97]) + "..."
+	}
+	return string(preview)
+}
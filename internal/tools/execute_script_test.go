@@ -0,0 +1,141 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeScriptTx records statements and fails those containing "fail"
+type fakeScriptTx struct {
+	executed []string
+}
+
+func (f *fakeScriptTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	f.executed = append(f.executed, sql)
+	if strings.Contains(sql, "fail") {
+		return pgconn.CommandTag{}, errors.New(`ERROR: relation "fail" does not exist (SQLSTATE 42P01)`)
+	}
+	verb, _, _ := strings.Cut(sql, " ")
+	return pgconn.NewCommandTag(verb + " 1"), nil
+}
+
+func TestSplitScript(t *testing.T) {
+	statements, err := splitScript(`
+		-- add a column
+		ALTER TABLE "Orders" ADD COLUMN note text DEFAULT 'a;b';
+		CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;
+
+		UPDATE orders SET note = E'it\'s'`)
+	if err != nil {
+		t.Fatalf("splitScript() error = %v", err)
+	}
+	want := []string{
+		`ALTER TABLE "Orders" ADD COLUMN note text DEFAULT 'a;b'`,
+		`CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql`,
+		`UPDATE orders SET note = E'it\'s'`,
+	}
+	if strings.Join(statements, "\n") != strings.Join(want, "\n") {
+		t.Errorf("statements =\n%s\nwant\n%s", strings.Join(statements, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, script := range []string{
+		"BEGIN; UPDATE orders SET note = 'x'; COMMIT",
+		"UPDATE orders SET note = 'x'; SAVEPOINT s1",
+		"CREATE INDEX CONCURRENTLY ON orders (note)",
+		"VACUUM orders",
+		"CREATE DATABASE other",
+		"PREPARE TRANSACTION 'tx1'",
+		"  ;  ",
+	} {
+		if _, err := splitScript(script); err == nil {
+			t.Errorf("splitScript(%q) should fail", script)
+		}
+	}
+	if _, err := splitScript("PREPARE q AS SELECT 1"); err != nil {
+		t.Errorf("prepared statements should be allowed: %v", err)
+	}
+}
+
+func TestRunScript_StopsOnError(t *testing.T) {
+	tx := &fakeScriptTx{}
+	results, aborted := runScript(context.Background(), tx, []string{
+		"UPDATE orders SET note = 'x'",
+		"INSERT INTO fail VALUES (1)",
+		"DELETE FROM orders",
+	}, false)
+
+	if !aborted {
+		t.Error("expected the script to abort")
+	}
+	statuses := []scriptStatus{results[0].Status, results[1].Status, results[2].Status}
+	if statuses[0] != scriptOK || statuses[1] != scriptRolledBack || statuses[2] != scriptSkipped {
+		t.Errorf("unexpected statuses: %v", statuses)
+	}
+	if len(tx.executed) != 2 {
+		t.Errorf("expected 2 statements to run without savepoints, got %v", tx.executed)
+	}
+
+	output := formatScriptResults(results) + formatScriptOutcome(results, false)
+	for _, want := range []string{
+		"1. OK (UPDATE 1): UPDATE orders SET note = 'x'",
+		"2. ROLLED BACK: INSERT INTO fail VALUES (1)\n   Error: ERROR: relation \"fail\" does not exist",
+		"3. SKIPPED: DELETE FROM orders",
+		"Transaction rolled back: no changes were applied (1 statements skipped)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestRunScript_ContinueOnError(t *testing.T) {
+	tx := &fakeScriptTx{}
+	results, aborted := runScript(context.Background(), tx, []string{
+		"CREATE TABLE notes (id int)",
+		"INSERT INTO fail VALUES (1)",
+		"DELETE FROM orders",
+	}, true)
+
+	if aborted {
+		t.Error("expected the script to continue past the failure")
+	}
+	want := []string{
+		"SAVEPOINT execute_script_1",
+		"CREATE TABLE notes (id int)",
+		"RELEASE SAVEPOINT execute_script_1",
+		"SAVEPOINT execute_script_2",
+		"INSERT INTO fail VALUES (1)",
+		"ROLLBACK TO SAVEPOINT execute_script_2",
+		"SAVEPOINT execute_script_3",
+		"DELETE FROM orders",
+		"RELEASE SAVEPOINT execute_script_3",
+	}
+	if strings.Join(tx.executed, "\n") != strings.Join(want, "\n") {
+		t.Errorf("executed =\n%s\nwant\n%s", strings.Join(tx.executed, "\n"), strings.Join(want, "\n"))
+	}
+	if results[1].Status != scriptRolledBack || results[2].Status != scriptOK {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if !scriptChangesSchema(results) {
+		t.Error("CREATE TABLE should be reported as a schema change")
+	}
+
+	outcome := formatScriptOutcome(results, true)
+	if !strings.Contains(outcome, "Transaction committed: 2 of 3 statements applied, 1 rolled back to their savepoints") {
+		t.Errorf("unexpected outcome: %s", outcome)
+	}
+}
@@ -0,0 +1,246 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// ExplainSQLTool creates the explain_sql tool for explaining a statement
+// against the schema metadata without executing it
+func ExplainSQLTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "explain_sql",
+			Description: `Break down a SQL statement using the real schema, without executing it.
+
+<usecase>
+Use when:
+- The user asks "what does this query do?" or "explain this SQL"
+- Reviewing a query before running it
+- Checking that the tables and columns a query uses actually exist
+- Looking for common mistakes before using query_database
+</usecase>
+
+<what_it_returns>
+- Statement type and the clauses it uses (WITH, SELECT, JOIN, WHERE, ...)
+- Referenced tables and views with the actual types and constraints of the
+  columns the statement uses (primary keys, foreign keys, indexes)
+- Potential issues: unknown tables or columns, ambiguous columns, UPDATE or
+  DELETE without WHERE, SELECT *, leading-wildcard LIKE, NOT IN with
+  subqueries, comparisons with NULL, unindexed filter columns
+</what_it_returns>
+
+<when_not_to_use>
+- For execution plans and timing → use execute_explain
+- To run the query → use query_database
+</when_not_to_use>
+
+<important>
+The statement is never sent to the database; the analysis uses the cached
+schema metadata. Any statement type is accepted, including INSERT, UPDATE
+and DELETE. With suggest_fix, clients that support MCP sampling are asked to
+propose a corrected statement for the issues found.
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "The SQL statement to explain",
+					},
+					"suggest_fix": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Ask the client's model (via MCP sampling) for a corrected statement when issues are found",
+						"default":     false,
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			query, errResp := ValidateStringParam(args, "query")
+			if errResp != nil {
+				return *errResp, nil
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+
+			analysis, err := analyzeSQL(query, dbClient.GetMetadataFor(connStr))
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Could not parse SQL: %v", err))
+			}
+
+			logging.Info("explain_sql_executed",
+				"query_length", len(query),
+				"statement", analysis.StatementType,
+				"tables", len(analysis.Sources),
+				"issues", len(analysis.Issues),
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatSQLAnalysis(analysis))
+			if ValidateBoolParam(args, "suggest_fix", false) && len(analysis.Issues) > 0 {
+				sb.WriteString("\n")
+				sb.WriteString(suggestSQLFix(args, query, analysis))
+			}
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// sqlFixSystemPrompt instructs the client's model when suggesting a fix
+const sqlFixSystemPrompt = `You are a PostgreSQL expert. Rewrite the SQL statement to fix the
+listed issues, using only the tables and columns described. Reply with the
+corrected SQL only, without explanation or code fences.`
+
+// suggestSQLFix asks the client's model for a corrected statement
+// Sampling is optional, so failures are reported in the output
+func suggestSQLFix(args map[string]interface{}, query string, analysis *sqlAnalysis) string {
+	prompt := fmt.Sprintf("SQL statement:\n%s\n\n%s", query, formatSQLAnalysis(analysis))
+	fix, err := sampleTextFromArgs(args, sqlFixSystemPrompt, prompt, 1024)
+	switch {
+	case errors.Is(err, mcp.ErrSamplingUnsupported):
+		return "Suggested Fix: unavailable (the client does not support MCP sampling)\n"
+	case err != nil:
+		logging.Warn("explain_sql_sampling_failed", "error", err)
+		return fmt.Sprintf("Suggested Fix: unavailable (%v)\n", err)
+	}
+	fix = strings.TrimSuffix(strings.TrimPrefix(fix, "```sql"), "```")
+	return fmt.Sprintf("Suggested Fix (generated by the client's model, not verified):\n%s\n", strings.TrimSpace(fix))
+}
+
+// formatSQLAnalysis renders an analysis as text for the LLM
+func formatSQLAnalysis(analysis *sqlAnalysis) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Statement: %s\n", analysis.StatementType))
+	if len(analysis.Clauses) > 0 {
+		sb.WriteString(fmt.Sprintf("Clauses: %s\n", strings.Join(analysis.Clauses, ", ")))
+	}
+
+	sb.WriteString("\nReferenced Objects:\n")
+	if len(analysis.Sources) == 0 {
+		sb.WriteString("(none)\n")
+	}
+	seen := make(map[*sqlSource]bool)
+	for _, src := range analysis.Sources {
+		if seen[src] {
+			continue
+		}
+		seen[src] = true
+		sb.WriteString(formatSQLSource(src, analysis.Columns))
+	}
+
+	sb.WriteString("\nPotential Issues:\n")
+	if len(analysis.Issues) == 0 {
+		sb.WriteString("None found\n")
+	}
+	for _, issue := range analysis.Issues {
+		sb.WriteString(fmt.Sprintf("- %s\n", issue))
+	}
+
+	sb.WriteString("\nThe statement was not executed; it was checked against cached schema metadata.\n")
+	return sb.String()
+}
+
+// formatSQLSource describes one referenced object and the columns used from it
+func formatSQLSource(src *sqlSource, refs []*sqlColumnRef) string {
+	var sb strings.Builder
+
+	alias := ""
+	if src.Alias != "" {
+		alias = " AS " + src.Alias
+	}
+
+	switch src.Kind {
+	case sourceCTE:
+		sb.WriteString(fmt.Sprintf("- %s%s (CTE)\n", src.Name, alias))
+		return sb.String()
+	case sourceDerived:
+		sb.WriteString(fmt.Sprintf("- (subquery)%s\n", alias))
+		return sb.String()
+	case sourceFunction:
+		sb.WriteString(fmt.Sprintf("- %s()%s (function)\n", src.Name, alias))
+		return sb.String()
+	case sourceSystem:
+		sb.WriteString(fmt.Sprintf("- %s%s (system catalog)\n", src.displayName(), alias))
+		return sb.String()
+	case sourceUnknown:
+		sb.WriteString(fmt.Sprintf("- %s%s (not found)\n", src.displayName(), alias))
+		return sb.String()
+	}
+
+	table := src.Table
+	sb.WriteString(fmt.Sprintf("- %s%s (%s)", src.displayName(), alias, table.TableType))
+	if table.Description != "" {
+		sb.WriteString(": " + table.Description)
+	}
+	sb.WriteString("\n")
+
+	var columns []*database.ColumnInfo
+	if src.star {
+		for i := range table.Columns {
+			columns = append(columns, &table.Columns[i])
+		}
+	} else {
+		used := make(map[string]bool)
+		for _, ref := range refs {
+			if ref.Source == src && ref.Column != nil && !used[ref.Column.ColumnName] {
+				used[ref.Column.ColumnName] = true
+				columns = append(columns, ref.Column)
+			}
+		}
+	}
+
+	if len(columns) == 0 {
+		sb.WriteString("    (no columns referenced directly)\n")
+	}
+	for _, col := range columns {
+		sb.WriteString(fmt.Sprintf("    %s %s", col.ColumnName, col.DataType))
+		if flags := columnFlags(col); len(flags) > 0 {
+			sb.WriteString(" [" + strings.Join(flags, ", ") + "]")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// columnFlags lists the constraints and properties of a column
+func columnFlags(col *database.ColumnInfo) []string {
+	var flags []string
+	if col.IsPrimaryKey {
+		flags = append(flags, "PK")
+	}
+	if col.IsUnique {
+		flags = append(flags, "unique")
+	}
+	if col.ForeignKeyRef != "" {
+		flags = append(flags, "FK -> "+col.ForeignKeyRef)
+	}
+	if col.IsIndexed && !col.IsPrimaryKey {
+		flags = append(flags, "indexed")
+	}
+	if col.IsNullable == "YES" {
+		flags = append(flags, "nullable")
+	}
+	return flags
+}
@@ -0,0 +1,331 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// explainTestMetadata returns a small schema used by the explain_sql tests
+func explainTestMetadata() map[string]database.TableInfo {
+	return map[string]database.TableInfo{
+		"public.customers": {
+			SchemaName: "public",
+			TableName:  "customers",
+			TableType:  "TABLE",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "id", DataType: "integer", IsNullable: "NO", IsPrimaryKey: true, IsIndexed: true},
+				{ColumnName: "email", DataType: "text", IsNullable: "NO", IsUnique: true, IsIndexed: true},
+				{ColumnName: "name", DataType: "text", IsNullable: "YES"},
+			},
+		},
+		"public.orders": {
+			SchemaName:  "public",
+			TableName:   "orders",
+			TableType:   "TABLE",
+			Description: "Customer orders",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "id", DataType: "bigint", IsNullable: "NO", IsPrimaryKey: true, IsIndexed: true},
+				{ColumnName: "customer_id", DataType: "integer", IsNullable: "NO", ForeignKeyRef: "public.customers.id"},
+				{ColumnName: "status", DataType: "text", IsNullable: "NO"},
+				{ColumnName: "total", DataType: "numeric", IsNullable: "YES"},
+				{ColumnName: "created_at", DataType: "timestamp with time zone", IsNullable: "NO", IsIndexed: true},
+			},
+		},
+		"sales.orders": {
+			SchemaName: "sales",
+			TableName:  "orders",
+			TableType:  "TABLE",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "id", DataType: "bigint", IsNullable: "NO", IsPrimaryKey: true, IsIndexed: true},
+			},
+		},
+		"sales.regions": {
+			SchemaName: "sales",
+			TableName:  "regions",
+			TableType:  "VIEW",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "code", DataType: "text", IsNullable: "YES"},
+			},
+		},
+	}
+}
+
+func hasIssue(analysis *sqlAnalysis, substr string) bool {
+	for _, issue := range analysis.Issues {
+		if strings.Contains(issue, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTokenizeSQL(t *testing.T) {
+	tokens, err := tokenizeSQL(`SELECT "Mixed""Case", E'it\'s', $$a;b$$, x::text -- comment
+		/* block /* nested */ */ FROM t WHERE a <> $1`)
+	if err != nil {
+		t.Fatalf("tokenizeSQL() error = %v", err)
+	}
+
+	var values []string
+	for _, tok := range tokens {
+		values = append(values, tok.value)
+	}
+	got := strings.Join(values, " ")
+	want := `select Mixed"Case , it's , a;b , x :: text from t where a <> $1`
+	if got != want {
+		t.Errorf("tokens = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"SELECT 'open", `SELECT "open`, "SELECT /* open", "SELECT $x$ open"} {
+		if _, err := tokenizeSQL(bad); err == nil {
+			t.Errorf("tokenizeSQL(%q) expected error", bad)
+		}
+	}
+}
+
+func TestAnalyzeSQL_Select(t *testing.T) {
+	analysis, err := analyzeSQL(`
+		WITH recent AS (SELECT * FROM orders WHERE created_at > now() - interval '7 days')
+		SELECT c.name, count(*) AS order_count
+		FROM customers c
+		LEFT JOIN recent r ON r.customer_id = c.id
+		WHERE lower(c.email) LIKE '%@example.com'
+		GROUP BY c.name
+		ORDER BY order_count DESC
+		LIMIT 10`, explainTestMetadata())
+	if err != nil {
+		t.Fatalf("analyzeSQL() error = %v", err)
+	}
+
+	if analysis.StatementType != "SELECT" {
+		t.Errorf("StatementType = %q, want SELECT", analysis.StatementType)
+	}
+	wantClauses := "WITH, SELECT, FROM, LEFT JOIN, ON, WHERE, GROUP BY, ORDER BY, LIMIT"
+	if got := strings.Join(analysis.Clauses, ", "); got != wantClauses {
+		t.Errorf("Clauses = %q, want %q", got, wantClauses)
+	}
+
+	kinds := make(map[string]sqlSourceKind)
+	for _, src := range analysis.Sources {
+		kinds[src.displayName()] = src.Kind
+	}
+	if kinds["public.orders"] != sourceTable || kinds["public.customers"] != sourceTable {
+		t.Errorf("expected orders and customers to resolve, got %v", kinds)
+	}
+	if kind, ok := kinds["recent"]; !ok || kind != sourceCTE {
+		t.Errorf("expected recent to be a CTE, got %v", kinds)
+	}
+
+	if !hasIssue(analysis, "public.customers.email is wrapped in lower()") {
+		t.Errorf("expected expression index issue, got %v", analysis.Issues)
+	}
+	if !hasIssue(analysis, "starts with a wildcard") {
+		t.Errorf("expected leading wildcard issue, got %v", analysis.Issues)
+	}
+	if !hasIssue(analysis, "SELECT * returns every column of public.orders (5 columns)") {
+		t.Errorf("expected SELECT * issue, got %v", analysis.Issues)
+	}
+	// Output aliases, CTE columns and functions must not be reported
+	for _, issue := range analysis.Issues {
+		if strings.Contains(issue, "not found") || strings.Contains(issue, "ambiguous") {
+			t.Errorf("unexpected issue: %s", issue)
+		}
+	}
+}
+
+func TestAnalyzeSQL_UnknownObjects(t *testing.T) {
+	analysis, err := analyzeSQL(
+		"SELECT o.id, o.amount, x.id, missing_col FROM orders o JOIN customer c ON c.id = o.customer_id",
+		explainTestMetadata())
+	if err != nil {
+		t.Fatalf("analyzeSQL() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"Column amount does not exist in public.orders",
+		"x.id refers to x",
+		"Table customer was not found",
+	} {
+		if !hasIssue(analysis, want) {
+			t.Errorf("expected issue containing %q, got %v", want, analysis.Issues)
+		}
+	}
+	// An unknown table makes unqualified columns unresolvable, so they are not reported
+	if hasIssue(analysis, "missing_col") {
+		t.Errorf("unqualified column should not be reported when a source is unknown: %v", analysis.Issues)
+	}
+}
+
+func TestAnalyzeSQL_ColumnResolution(t *testing.T) {
+	analysis, err := analyzeSQL(
+		"SELECT id, name, nope FROM customers, orders WHERE status = 'open' AND total = NULL",
+		explainTestMetadata())
+	if err != nil {
+		t.Fatalf("analyzeSQL() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"Column reference id is ambiguous",
+		"Column nope was not found",
+		"Comparing with = NULL",
+		"Filter or join columns without an index: public.orders.status, public.orders.total",
+	} {
+		if !hasIssue(analysis, want) {
+			t.Errorf("expected issue containing %q, got %v", want, analysis.Issues)
+		}
+	}
+	if hasIssue(analysis, "Cartesian") {
+		t.Errorf("comma join with WHERE should not be reported as a Cartesian product: %v", analysis.Issues)
+	}
+}
+
+func TestAnalyzeSQL_Writes(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		statement string
+		issues    []string
+	}{
+		{
+			name:      "delete without where",
+			sql:       "DELETE FROM orders",
+			statement: "DELETE",
+			issues:    []string{"DELETE statements modify data", "affect every row in public.orders"},
+		},
+		{
+			name:      "update with where",
+			sql:       "UPDATE orders SET status = 'shipped' WHERE id = 1",
+			statement: "UPDATE",
+			issues:    []string{"UPDATE statements modify data"},
+		},
+		{
+			name:      "insert select",
+			sql:       "INSERT INTO customers (id, name) SELECT id, status FROM orders ON CONFLICT (id) DO UPDATE SET name = excluded.name",
+			statement: "INSERT",
+			issues:    []string{"INSERT statements modify data"},
+		},
+		{
+			name:      "truncate",
+			sql:       "TRUNCATE TABLE orders, nothing_here",
+			statement: "TRUNCATE",
+			issues:    []string{"TRUNCATE statements modify data", "Table nothing_here was not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := analyzeSQL(tt.sql, explainTestMetadata())
+			if err != nil {
+				t.Fatalf("analyzeSQL() error = %v", err)
+			}
+			if analysis.StatementType != tt.statement {
+				t.Errorf("StatementType = %q, want %q", analysis.StatementType, tt.statement)
+			}
+			if len(analysis.Issues) != len(tt.issues) {
+				t.Errorf("Issues = %v, want %d issue(s)", analysis.Issues, len(tt.issues))
+			}
+			for _, want := range tt.issues {
+				if !hasIssue(analysis, want) {
+					t.Errorf("expected issue containing %q, got %v", want, analysis.Issues)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeSQL_Schemas(t *testing.T) {
+	analysis, err := analyzeSQL("SELECT code FROM regions; SELECT 1", explainTestMetadata())
+	if err != nil {
+		t.Fatalf("analyzeSQL() error = %v", err)
+	}
+	if !hasIssue(analysis, "Table regions is in schema sales") {
+		t.Errorf("expected search_path issue, got %v", analysis.Issues)
+	}
+	if !hasIssue(analysis, "Input contains 2 statements") {
+		t.Errorf("expected multiple statements issue, got %v", analysis.Issues)
+	}
+
+	// A qualified name picks the table in that schema
+	analysis, err = analyzeSQL("SELECT o.id FROM sales.orders o WHERE o.id IN (SELECT id FROM public.orders)", explainTestMetadata())
+	if err != nil {
+		t.Fatalf("analyzeSQL() error = %v", err)
+	}
+	if len(analysis.Issues) != 0 {
+		t.Errorf("expected no issues, got %v", analysis.Issues)
+	}
+}
+
+func TestFormatSQLAnalysis(t *testing.T) {
+	analysis, err := analyzeSQL(
+		"EXPLAIN SELECT o.id, o.customer_id FROM orders o",
+		explainTestMetadata())
+	if err != nil {
+		t.Fatalf("analyzeSQL() error = %v", err)
+	}
+
+	output := formatSQLAnalysis(analysis)
+	for _, want := range []string{
+		"Statement: SELECT",
+		"Clauses: SELECT, FROM",
+		"- public.orders AS o (TABLE): Customer orders",
+		"    id bigint [PK]",
+		"    customer_id integer [FK -> public.customers.id]",
+		"The statement was not executed",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "status") {
+		t.Errorf("output should only list referenced columns:\n%s", output)
+	}
+}
+
+// fixSampler is a client model that returns a fixed reply
+type fixSampler struct {
+	prompt string
+}
+
+func (f *fixSampler) CreateMessage(ctx context.Context, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	f.prompt = params.Messages[0].Content.Text
+	return &mcp.CreateMessageResult{
+		Role:    "assistant",
+		Content: mcp.ContentItem{Type: "text", Text: "```sql\nSELECT id FROM orders WHERE status IS NULL\n```"},
+	}, nil
+}
+
+func TestSuggestSQLFix(t *testing.T) {
+	query := "SELECT * FROM orders WHERE status = NULL"
+	analysis, err := analyzeSQL(query, explainTestMetadata())
+	if err != nil {
+		t.Fatalf("analyzeSQL() error = %v", err)
+	}
+
+	if got := suggestSQLFix(map[string]interface{}{}, query, analysis); !strings.Contains(got, "does not support MCP sampling") {
+		t.Errorf("expected sampling to be unavailable, got %q", got)
+	}
+
+	sampler := &fixSampler{}
+	args := map[string]interface{}{"__context": mcp.WithSampler(context.Background(), sampler)}
+	got := suggestSQLFix(args, query, analysis)
+	if !strings.Contains(got, "Suggested Fix (generated by the client's model, not verified):\nSELECT id FROM orders WHERE status IS NULL\n") {
+		t.Errorf("unexpected suggestion: %q", got)
+	}
+	if !strings.Contains(sampler.prompt, query) || !strings.Contains(sampler.prompt, "Potential Issues:") {
+		t.Errorf("prompt should include the statement and its issues: %q", sampler.prompt)
+	}
+}
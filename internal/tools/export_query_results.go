@@ -0,0 +1,291 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/export"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/masking"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// exportResult summarises a completed export
+type exportResult struct {
+	file    *export.File
+	format  export.Format
+	rows    int
+	columns int
+	masked  int
+}
+
+// ExportQueryResultsTool creates the export_query_results tool
+// If masker is non-nil, its rules are applied to the exported rows
+func ExportQueryResultsTool(dbClient *database.Client, masker *masking.Masker, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "export_query_results",
+			Description: `Run a read-only query and write ALL of its rows to a CSV, JSON Lines or Parquet file.
+
+<usecase>
+Use export_query_results when the user wants the data itself rather than an answer:
+- "Export last month's orders to CSV"
+- Handing a result set to a spreadsheet, notebook or data pipeline
+- Result sets too large to return through query_database
+</usecase>
+
+<what_it_returns>
+The row count, file size and the path of the file on the server. When the
+server runs in HTTP mode, also a download URL (authenticated with the same
+token as MCP requests).
+</what_it_returns>
+
+<important>
+- The query runs in a READ-ONLY transaction; no LIMIT is added
+- Exports are capped by the server's row and size limits; narrow the query if it fails
+- Do NOT read the exported rows back with query_database; give the user the path or URL
+- Sensitive columns may be masked by server policy, as in query_database
+- Exported files are deleted after the server's retention period
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "SQL query whose results are exported. Runs in a read-only transaction.",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"csv", "jsonl", "parquet"},
+						"description": "File format: csv (PostgreSQL text output, NULL as an empty field), jsonl (one JSON object per row) or parquet",
+						"default":     "csv",
+					},
+					"filename": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional name prefix for the file, e.g. 'orders_2025_01'. A timestamp and random suffix are always added.",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			query, errResp := ValidateStringParam(args, "query")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			query = strings.TrimRight(strings.TrimSpace(query), ";")
+			format, err := export.ParseFormat(ValidateOptionalStringParam(args, "format", "csv"))
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			prefix := ValidateOptionalStringParam(args, "filename", "")
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			ctx := context.Background()
+			tx, err := pool.Begin(ctx)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			committed := false
+			defer func() {
+				if !committed {
+					_ = tx.Rollback(ctx) //nolint:errcheck // rollback in defer after commit is expected to fail
+				}
+			}()
+
+			if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to set transaction read-only: %v", err))
+			}
+
+			store := export.NewStoreFromConfig(cfg)
+			result, err := runExport(ctx, tx, query, format, prefix, store, masker, cfg.Exports.MaxRows)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("SQL Query:\n%s\n\nExport failed: %v", query, err))
+			}
+			recordRowsReturned(args, result.rows)
+
+			if err := tx.Commit(ctx); err != nil {
+				result.file.Remove()
+				return mcp.NewToolError(fmt.Sprintf("Failed to commit transaction: %v", err))
+			}
+			committed = true
+
+			logging.Info("export_query_results_executed",
+				"query_length", len(query),
+				"format", string(format),
+				"rows_exported", result.rows,
+				"bytes", result.file.Size(),
+				"masked_values", result.masked,
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatExportResult(result, cfg.HTTP.Enabled, store.Retention))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// runExport streams the query's rows into a new export file. Values are read
+// in PostgreSQL's text format so CSV output matches psql and COPY. The file
+// is removed if the export fails.
+func runExport(ctx context.Context, tx pgx.Tx, query string, format export.Format, prefix string, store *export.Store, masker *masking.Masker, maxRows int) (*exportResult, error) {
+	// Describe the query first: the source tables for masking cannot be
+	// looked up while the result rows are being streamed
+	desc, err := tx.Conn().PgConn().Prepare(ctx, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(desc.Fields) == 0 {
+		return nil, errors.New("the statement does not return rows")
+	}
+
+	var maskColumns []masking.Column
+	var maskable []bool
+	if masker.Enabled() {
+		maskColumns, err = resolveMaskingColumns(ctx, tx, desc.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve source tables for masking: %w", err)
+		}
+		maskable = masker.MaskableColumns(maskColumns)
+	}
+	columns := exportColumns(desc.Fields, maskable)
+
+	file, err := store.Create(format, prefix, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	result, err := writeExport(ctx, tx, query, file, format, columns, masker, maskColumns, maxRows)
+	if err != nil {
+		file.Remove()
+		if errors.Is(err, export.ErrTooLarge) {
+			return nil, fmt.Errorf("the export is larger than the %d MB limit; select fewer rows or columns", store.MaxBytes>>20)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// writeExport runs the query and writes every row to file
+func writeExport(ctx context.Context, tx pgx.Tx, query string, file *export.File, format export.Format, columns []export.Column, masker *masking.Masker, maskColumns []masking.Column, maxRows int) (*exportResult, error) {
+	writer, err := export.NewWriter(format, file, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, pgx.QueryResultFormatsByOID{})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &exportResult{file: file, format: format, columns: len(columns)}
+	row := make([][]interface{}, 1)
+	for rows.Next() {
+		if maxRows > 0 && result.rows >= maxRows {
+			return nil, fmt.Errorf("the query returns more than %d rows, the export limit; add a WHERE clause or LIMIT", maxRows)
+		}
+		values := make([]interface{}, len(columns))
+		for i, raw := range rows.RawValues() {
+			if raw != nil {
+				values[i] = string(raw)
+			}
+		}
+		if masker.Enabled() {
+			row[0] = values
+			result.masked += masker.Apply(maskColumns, row)
+		}
+		if err := writer.WriteRow(values); err != nil {
+			return nil, err
+		}
+		result.rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// exportColumns returns the export columns for a result. Columns that masking
+// rules may rewrite are exported as text, since a masked value no longer
+// parses as its original type.
+func exportColumns(fields []pgconn.FieldDescription, maskable []bool) []export.Column {
+	names := make([]string, len(fields))
+	for i, fd := range fields {
+		names[i] = fd.Name
+	}
+	names = export.UniqueNames(names)
+
+	columns := make([]export.Column, len(fields))
+	for i, fd := range fields {
+		columns[i] = export.Column{Name: names[i], Type: export.TypeForOID(fd.DataTypeOID)}
+		if i < len(maskable) && maskable[i] {
+			columns[i].Type = export.TypeText
+		}
+	}
+	return columns
+}
+
+// formatExportResult describes where the export was written
+func formatExportResult(r *exportResult, httpEnabled bool, retention time.Duration) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Exported %d rows (%d columns) as %s, %s\n\n",
+		r.rows, r.columns, strings.ToUpper(string(r.format)), formatSize(r.file.Size())))
+	sb.WriteString(fmt.Sprintf("File: %s\n", r.file.Path))
+	if httpEnabled {
+		sb.WriteString(fmt.Sprintf("Download: %s%s\n", export.DownloadPath, r.file.Name))
+		sb.WriteString("(GET on this server with the same Authorization header as MCP requests)\n")
+	}
+	if retention > 0 {
+		sb.WriteString(fmt.Sprintf("\nThe file is deleted after %s.\n", formatRetention(retention)))
+	}
+	if r.masked > 0 {
+		sb.WriteString(fmt.Sprintf("\n%d values were masked by server policy.\n", r.masked))
+	}
+	return sb.String()
+}
+
+// formatRetention renders a retention period in hours or days
+func formatRetention(d time.Duration) string {
+	hours := int(d / time.Hour)
+	switch {
+	case hours >= 48 && hours%24 == 0:
+		return fmt.Sprintf("%d days", hours/24)
+	case hours == 1:
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", hours)
+}
@@ -0,0 +1,82 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"pgedge-postgres-mcp/internal/export"
+)
+
+func TestExportColumns(t *testing.T) {
+	fields := []pgconn.FieldDescription{
+		{Name: "id", DataTypeOID: 23},
+		{Name: "ssn", DataTypeOID: 20},
+		{Name: "id", DataTypeOID: 701},
+	}
+
+	columns := exportColumns(fields, []bool{false, true, false})
+	want := []export.Column{
+		{Name: "id", Type: export.TypeInt},
+		{Name: "ssn", Type: export.TypeText}, // masked values are not numbers
+		{Name: "id_2", Type: export.TypeFloat},
+	}
+	for i := range want {
+		if columns[i] != want[i] {
+			t.Errorf("exportColumns()[%d] = %+v, want %+v", i, columns[i], want[i])
+		}
+	}
+
+	// Without masking the database types are kept
+	if columns := exportColumns(fields, nil); columns[1].Type != export.TypeInt {
+		t.Errorf("Unmasked bigint column type = %v, want TypeInt", columns[1].Type)
+	}
+}
+
+func TestFormatExportResult(t *testing.T) {
+	store := &export.Store{Dir: t.TempDir()}
+	file, err := store.Create(export.FormatCSV, "orders", time.Now())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer file.Remove()
+	if _, err := file.Write([]byte("id\n1\n2\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	result := &exportResult{file: file, format: export.FormatCSV, rows: 2, columns: 1}
+
+	out := formatExportResult(result, false, 24*time.Hour)
+	if !strings.Contains(out, "Exported 2 rows (1 columns) as CSV") {
+		t.Errorf("Missing summary line:\n%s", out)
+	}
+	if !strings.Contains(out, "File: "+file.Path) {
+		t.Errorf("Missing file path:\n%s", out)
+	}
+	if strings.Contains(out, "Download:") {
+		t.Errorf("Download URL should only be shown in HTTP mode:\n%s", out)
+	}
+	if !strings.Contains(out, "deleted after 24 hours") {
+		t.Errorf("Missing retention note:\n%s", out)
+	}
+
+	out = formatExportResult(result, true, 72*time.Hour)
+	if !strings.Contains(out, "Download: "+export.DownloadPath+file.Name) {
+		t.Errorf("Missing download URL:\n%s", out)
+	}
+	if !strings.Contains(out, "deleted after 3 days") {
+		t.Errorf("Missing retention note:\n%s", out)
+	}
+}
@@ -0,0 +1,296 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// schemaIndexWait bounds how long find_relevant_tables waits for an index
+// being built before matching by keywords
+const schemaIndexWait = 20 * time.Second
+
+// maxRelevantTables limits the tables find_relevant_tables returns
+const maxRelevantTables = 50
+
+// maxListedColumns limits the column names listed per table
+const maxListedColumns = 12
+
+// keywordStopWords are ignored when matching by keywords
+var keywordStopWords = map[string]bool{
+	"a": true, "about": true, "all": true, "an": true, "and": true, "are": true,
+	"by": true, "data": true, "for": true, "from": true, "in": true, "info": true,
+	"information": true, "is": true, "of": true, "on": true, "or": true,
+	"table": true, "tables": true, "the": true, "to": true, "where": true,
+	"which": true, "with": true,
+}
+
+// relevantTable is a table matched to a request, with its score
+type relevantTable struct {
+	table *database.TableInfo
+	score float64
+}
+
+// FindRelevantTablesTool creates the find_relevant_tables tool, which
+// matches a natural-language request to the tables most likely to hold the
+// data. Tables are matched by the similarity of their embeddings when
+// embedding generation is enabled, and by keywords otherwise.
+func FindRelevantTablesTool(dbClient *database.Client, index *SchemaIndex, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "find_relevant_tables",
+			Description: `Find the tables and views most relevant to a natural-language request.
+
+<usecase>
+Use before get_schema_info in databases with many tables, when the user
+describes the data ("customer churn data", "invoices paid late") rather
+than naming tables. Then call get_schema_info with the table_name of the
+best matches.
+</usecase>
+
+<behavior>
+- Matches the request against table and column names and comments
+- Uses semantic similarity of embeddings when embedding generation is
+  enabled, and keyword matching otherwise; the output says which
+- Returns TSV: schema, table, type, score, description, columns
+- Scores only rank the tables of one request; they are not comparable
+  between requests or matching methods
+</behavior>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Description of the data needed, e.g. 'customer churn data'",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum number of tables to return (default: 10, max: %d)", maxRelevantTables),
+						"default":     10,
+					},
+					"schema_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Only search the tables of this schema",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			query, errResp := ValidateStringParam(args, "query")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			limit := int(ValidateOptionalNumberParam(args, "limit", 10))
+			if limit < 1 || limit > maxRelevantTables {
+				return mcp.NewToolError(fmt.Sprintf("Invalid limit %d (must be between 1 and %d)", limit, maxRelevantTables))
+			}
+			schemaName := ValidateOptionalStringParam(args, "schema_name", "")
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			tables := dbClient.GetMetadataFor(connStr)
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			// Keyword scores are counts, similarities are shown with three
+			// decimals
+			var matches []relevantTable
+			var method string
+			scoreFormat := "%.3f"
+			reason := semanticSearchUnavailable(cfg)
+			if reason == "" {
+				var err error
+				matches, method, err = matchTablesSemantically(ctx, index, cfg, connStr, tables, query)
+				if err != nil {
+					reason = fmt.Sprintf("semantic matching failed: %v", err)
+				}
+			}
+			if reason != "" {
+				matches = matchTablesByKeywords(tables, query)
+				method = fmt.Sprintf("keywords (%s)", reason)
+				scoreFormat = "%.0f"
+			}
+
+			var results []relevantTable
+			for _, match := range matches {
+				if schemaName != "" && match.table.SchemaName != schemaName {
+					continue
+				}
+				results = append(results, match)
+				if len(results) == limit {
+					break
+				}
+			}
+
+			logging.Info("find_relevant_tables_executed",
+				"semantic", reason == "",
+				"tables", len(tables),
+				"matches", len(results),
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(fmt.Sprintf("Matched by: %s\n\n", method))
+			if len(results) == 0 {
+				sb.WriteString("No tables matched the request. Try other words, or get_schema_info to list the tables.\n")
+				return mcp.NewToolSuccess(sb.String())
+			}
+			sb.WriteString("schema\ttable\ttype\tscore\tdescription\tcolumns\n")
+			for _, result := range results {
+				sb.WriteString(BuildTSVRow(
+					result.table.SchemaName,
+					result.table.TableName,
+					result.table.TableType,
+					fmt.Sprintf(scoreFormat, result.score),
+					result.table.Description,
+					listColumnNames(result.table),
+				))
+			}
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// matchTablesSemantically ranks the tables by the similarity of their
+// embeddings to the request's, returning the matching method
+func matchTablesSemantically(ctx context.Context, index *SchemaIndex, cfg *config.Config, connStr string,
+	tables map[string]database.TableInfo, query string) ([]relevantTable, string, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, schemaIndexWait)
+	defer cancel()
+	build, err := index.Get(waitCtx, cfg, connStr, tables)
+	if err != nil {
+		return nil, "", err
+	}
+
+	provider, err := index.newProvider(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	vector, err := provider.Embed(ctx, query)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to embed the request: %w", err)
+	}
+
+	matches := make([]relevantTable, 0, len(build.entries))
+	for i := range build.entries {
+		matches = append(matches, relevantTable{
+			table: &build.entries[i].table,
+			score: vectorSimilarity(vector, build.entries[i].vector),
+		})
+	}
+	sortRelevantTables(matches)
+	return matches, fmt.Sprintf("semantic similarity (%s)", build.model), nil
+}
+
+// matchTablesByKeywords ranks the tables matching words of the request.
+// Each word scores once per table, by its best match: 3 for the table
+// name, 2 for a column name and 1 for a comment.
+func matchTablesByKeywords(tables map[string]database.TableInfo, query string) []relevantTable {
+	var keywords []string
+	for _, word := range splitWords(query) {
+		if !keywordStopWords[word] {
+			keywords = append(keywords, word)
+		}
+	}
+
+	var matches []relevantTable
+	for key := range tables {
+		table := tables[key]
+		nameWords := splitWords(table.TableName)
+		var columnWords, commentWords []string
+		commentWords = append(commentWords, splitWords(table.Description)...)
+		for i := range table.Columns {
+			columnWords = append(columnWords, splitWords(table.Columns[i].ColumnName)...)
+			commentWords = append(commentWords, splitWords(table.Columns[i].Description)...)
+		}
+
+		score := 0.0
+		for _, keyword := range keywords {
+			switch {
+			case matchesAnyWord(keyword, nameWords):
+				score += 3
+			case matchesAnyWord(keyword, columnWords):
+				score += 2
+			case matchesAnyWord(keyword, commentWords):
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, relevantTable{table: &table, score: score})
+		}
+	}
+	sortRelevantTables(matches)
+	return matches
+}
+
+// splitWords returns the lowercase words of a text or identifier
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchesAnyWord reports whether a keyword matches one of the words, either
+// exactly or, for words of 4 letters or more, as a prefix of the other
+// ("customer" matches "customers", "churned" matches "churn")
+func matchesAnyWord(keyword string, words []string) bool {
+	for _, word := range words {
+		if word == keyword {
+			return true
+		}
+		if len(word) >= 4 && len(keyword) >= 4 &&
+			(strings.HasPrefix(word, keyword) || strings.HasPrefix(keyword, word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRelevantTables orders matches by score, then by qualified name
+func sortRelevantTables(matches []relevantTable) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		a, b := matches[i].table, matches[j].table
+		return a.SchemaName+"."+a.TableName < b.SchemaName+"."+b.TableName
+	})
+}
+
+// listColumnNames lists the column names of a table, up to
+// maxListedColumns
+func listColumnNames(table *database.TableInfo) string {
+	names := make([]string, 0, maxListedColumns)
+	for i := range table.Columns {
+		if i == maxListedColumns {
+			names = append(names, fmt.Sprintf("... %d more", len(table.Columns)-maxListedColumns))
+			break
+		}
+		names = append(names, table.Columns[i].ColumnName)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,150 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/embedding"
+)
+
+// wordCountProvider embeds a text as the number of times it mentions each
+// word of a fixed vocabulary
+type wordCountProvider struct {
+	vocabulary []string
+	calls      atomic.Int32
+}
+
+func (p *wordCountProvider) Embed(_ context.Context, text string) ([]float64, error) {
+	p.calls.Add(1)
+	text = strings.ToLower(text)
+	vector := make([]float64, len(p.vocabulary))
+	for i, word := range p.vocabulary {
+		vector[i] = float64(strings.Count(text, word))
+	}
+	return vector, nil
+}
+
+func (p *wordCountProvider) Dimensions() int      { return len(p.vocabulary) }
+func (p *wordCountProvider) ModelName() string    { return "example-model" }
+func (p *wordCountProvider) ProviderName() string { return "ollama" }
+
+func relevantTestTables() map[string]database.TableInfo {
+	return map[string]database.TableInfo{
+		"public.customers": {
+			SchemaName: "public", TableName: "customers", TableType: "TABLE",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "id", DataType: "integer"},
+				{ColumnName: "churned_at", DataType: "timestamp", Description: "When the customer cancelled"},
+			},
+		},
+		"public.invoices": {
+			SchemaName: "public", TableName: "invoices", TableType: "TABLE",
+			Description: "Invoices sent to customers",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "customer_id", DataType: "integer"},
+				{ColumnName: "paid_at", DataType: "timestamp"},
+			},
+		},
+		"sales.products": {
+			SchemaName: "sales", TableName: "products", TableType: "TABLE",
+			Columns: []database.ColumnInfo{
+				{ColumnName: "name", DataType: "text", Description: "Product name"},
+			},
+		},
+	}
+}
+
+func TestMatchTablesByKeywords(t *testing.T) {
+	matches := matchTablesByKeywords(relevantTestTables(), "customer churn data")
+
+	var got []string
+	for _, match := range matches {
+		got = append(got, match.table.TableName)
+	}
+	// customers: "customer" in the name (3) and "churn" in a column (2);
+	// invoices: "customer" in a column (2)
+	if strings.Join(got, ",") != "customers,invoices" {
+		t.Fatalf("matches = %v, want customers then invoices", got)
+	}
+	if matches[0].score != 5 || matches[1].score != 2 {
+		t.Errorf("scores = %v and %v, want 5 and 2", matches[0].score, matches[1].score)
+	}
+
+	if matches := matchTablesByKeywords(relevantTestTables(), "the data"); len(matches) != 0 {
+		t.Errorf("expected stop words not to match, got %d matches", len(matches))
+	}
+}
+
+func TestSchemaIndex(t *testing.T) {
+	provider := &wordCountProvider{vocabulary: []string{"customer", "churn", "invoice", "product"}}
+	index := NewSchemaIndex()
+	index.newProvider = func(*config.Config) (embedding.Provider, error) { return provider, nil }
+	cfg := &config.Config{Embedding: config.EmbeddingConfig{Enabled: true, Provider: "ollama"}}
+	tables := relevantTestTables()
+
+	matches, method, err := matchTablesSemantically(context.Background(), index, cfg, "db", tables, "churned customers")
+	if err != nil {
+		t.Fatalf("matchTablesSemantically failed: %v", err)
+	}
+	if method != "semantic similarity (ollama/example-model)" {
+		t.Errorf("method = %q", method)
+	}
+	if len(matches) != 3 || matches[0].table.TableName != "customers" || matches[2].table.TableName != "products" {
+		t.Errorf("unexpected ranking: %v", matches)
+	}
+	// One embedding per table and one for the request
+	if calls := provider.calls.Load(); calls != 4 {
+		t.Errorf("expected 4 embeddings, got %d", calls)
+	}
+
+	// The index is reused while the tables are unchanged, and only changed
+	// tables are embedded again
+	provider.calls.Store(0)
+	if _, err := index.Get(context.Background(), cfg, "db", tables); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if calls := provider.calls.Load(); calls != 0 {
+		t.Errorf("expected the index to be reused, got %d embeddings", calls)
+	}
+	products := tables["sales.products"]
+	products.Description = "Products for sale"
+	tables["sales.products"] = products
+	if _, err := index.Get(context.Background(), cfg, "db", tables); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("expected only the changed table to be embedded, got %d embeddings", calls)
+	}
+}
+
+func TestSemanticSearchUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{"disabled", config.Config{}, "not enabled"},
+		{"offline cloud", config.Config{Offline: true, Embedding: config.EmbeddingConfig{Enabled: true, Provider: "voyage"}}, "offline"},
+		{"offline local", config.Config{Offline: true, Embedding: config.EmbeddingConfig{Enabled: true, Provider: "ollama"}}, ""},
+	}
+	for _, tt := range tests {
+		got := semanticSearchUnavailable(&tt.cfg)
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: semanticSearchUnavailable = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
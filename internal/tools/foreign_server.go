@@ -0,0 +1,291 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// Federation gives the tools access to the other configured databases,
+// which are wired into the current database with postgres_fdw
+type Federation struct {
+	// Lookup returns a configured database the caller may access
+	Lookup func(ctx context.Context, name string) (*config.NamedDatabaseConfig, error)
+
+	// CanWire reports whether query_database may set up the foreign server
+	// for a database that is not wired yet: setup_foreign_server must be
+	// enabled and usable by the caller
+	CanWire func(ctx context.Context) bool
+
+	// Extensions are the extensions that may be installed
+	Extensions *config.ExtensionsConfig
+}
+
+// nonIdentChars are the characters replaced in the names derived from a
+// database name
+var nonIdentChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// foreignSchemaName returns the default local schema for the tables of a
+// configured database: its name as a plain lower case identifier
+func foreignSchemaName(dbName string) string {
+	name := nonIdentChars.ReplaceAllString(strings.ToLower(dbName), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "db_" + name
+	}
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// foreignServerName returns the foreign server for a configured database
+func foreignServerName(dbName string) string {
+	name := "mcp_" + nonIdentChars.ReplaceAllString(strings.ToLower(dbName), "_")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// fdwTarget is a configured database wired into a local schema
+type fdwTarget struct {
+	DB           *config.NamedDatabaseConfig
+	Server       string
+	RemoteSchema string
+	LocalSchema  string
+}
+
+// newFDWTarget returns the wiring of a configured database, with the
+// default names for the schemas that are not given
+func newFDWTarget(db *config.NamedDatabaseConfig, remoteSchema, localSchema string) fdwTarget {
+	if remoteSchema == "" {
+		remoteSchema = "public"
+	}
+	if localSchema == "" {
+		localSchema = foreignSchemaName(db.Name)
+	}
+	return fdwTarget{DB: db, Server: foreignServerName(db.Name), RemoteSchema: remoteSchema, LocalSchema: localSchema}
+}
+
+// fdwState is what the current database already has of a wiring
+type fdwState struct {
+	ExtensionInstalled bool
+	ServerExists       bool
+	MappingExists      bool
+	SchemaExists       bool
+	ForeignTables      []string // Foreign tables of the server in the local schema
+	LocalRelations     []string // Every table, view and sequence in the local schema
+}
+
+// Wired reports whether the database's tables can be queried
+func (s *fdwState) Wired() bool {
+	return s.ServerExists && s.MappingExists && len(s.ForeignTables) > 0
+}
+
+// lookupFDWState reads what the current database has of a wiring
+func lookupFDWState(ctx context.Context, pool *pgxpool.Pool, target fdwTarget) (*fdwState, error) {
+	state := &fdwState{}
+	err := pool.QueryRow(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM pg_catalog.pg_extension WHERE extname = 'postgres_fdw'),
+			EXISTS (SELECT 1 FROM pg_catalog.pg_foreign_server WHERE srvname = $1),
+			EXISTS (SELECT 1 FROM pg_catalog.pg_user_mappings
+			        WHERE srvname = $1 AND usename IN (current_user, 'public')),
+			EXISTS (SELECT 1 FROM pg_catalog.pg_namespace WHERE nspname = $2)
+	`, target.Server, target.LocalSchema).Scan(&state.ExtensionInstalled, &state.ServerExists, &state.MappingExists, &state.SchemaExists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the foreign server: %w", err)
+	}
+	if !state.SchemaExists {
+		return state, nil
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT c.relname::text, s.srvname IS NOT DISTINCT FROM $2
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_catalog.pg_foreign_table ft ON ft.ftrelid = c.oid
+		LEFT JOIN pg_catalog.pg_foreign_server s ON s.oid = ft.ftserver
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+		ORDER BY 1
+	`, target.LocalSchema, target.Server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tables of schema %s: %w", target.LocalSchema, err)
+	}
+	for rows.Next() {
+		var name string
+		var fromServer bool
+		if err := rows.Scan(&name, &fromServer); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to list the tables of schema %s: %w", target.LocalSchema, err)
+		}
+		state.LocalRelations = append(state.LocalRelations, name)
+		if fromServer {
+			state.ForeignTables = append(state.ForeignTables, name)
+		}
+	}
+	return state, rows.Err()
+}
+
+// fdwStep is a statement that wires a database; Display hides the password
+type fdwStep struct {
+	SQL     string
+	Display string
+}
+
+// fdwSteps returns the statements that complete a wiring. The remote schema
+// is imported again to pick up new tables, except for the names the local
+// schema already uses.
+func fdwSteps(target fdwTarget, state *fdwState) []fdwStep {
+	var steps []fdwStep
+	add := func(sql string) {
+		steps = append(steps, fdwStep{SQL: sql, Display: sql})
+	}
+
+	if !state.ExtensionInstalled {
+		add(createExtensionStatement("postgres_fdw", ""))
+	}
+	if !state.ServerExists {
+		var options []string
+		if target.DB.Host != "" {
+			options = append(options, "host "+quoteLiteral(target.DB.Host))
+		}
+		if target.DB.Port != 0 {
+			options = append(options, "port "+quoteLiteral(strconv.Itoa(target.DB.Port)))
+		}
+		if target.DB.Database != "" {
+			options = append(options, "dbname "+quoteLiteral(target.DB.Database))
+		}
+		if target.DB.SSLMode != "" {
+			options = append(options, "sslmode "+quoteLiteral(target.DB.SSLMode))
+		}
+		stmt := "CREATE SERVER " + quoteIdentIfNeeded(target.Server) + " FOREIGN DATA WRAPPER postgres_fdw"
+		if len(options) > 0 {
+			stmt += " OPTIONS (" + strings.Join(options, ", ") + ")"
+		}
+		add(stmt)
+	}
+	if !state.MappingExists {
+		stmt := "CREATE USER MAPPING FOR CURRENT_USER SERVER " + quoteIdentIfNeeded(target.Server) +
+			" OPTIONS (user " + quoteLiteral(target.DB.User)
+		step := fdwStep{SQL: stmt, Display: stmt}
+		if target.DB.Password != "" {
+			step.SQL += ", password " + quoteLiteral(target.DB.Password)
+			step.Display += ", password '********'"
+		}
+		step.SQL += ")"
+		step.Display += ")"
+		steps = append(steps, step)
+	}
+	if !state.SchemaExists {
+		add("CREATE SCHEMA " + quoteIdentIfNeeded(target.LocalSchema))
+	}
+
+	stmt := "IMPORT FOREIGN SCHEMA " + quoteIdentIfNeeded(target.RemoteSchema)
+	if len(state.LocalRelations) > 0 {
+		except := make([]string, len(state.LocalRelations))
+		for i, name := range state.LocalRelations {
+			except[i] = quoteIdentIfNeeded(name)
+		}
+		stmt += " EXCEPT (" + strings.Join(except, ", ") + ")"
+	}
+	add(stmt + " FROM SERVER " + quoteIdentIfNeeded(target.Server) + " INTO " + quoteIdentIfNeeded(target.LocalSchema))
+	return steps
+}
+
+// applyFDWSteps runs the statements of a wiring in one transaction and
+// returns the number of foreign tables the local schema has afterwards
+func applyFDWSteps(ctx context.Context, pool *pgxpool.Pool, target fdwTarget, steps []fdwStep) (int, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // no-op after a successful commit
+	}()
+
+	for _, step := range steps {
+		if _, err := tx.Exec(ctx, step.SQL); err != nil {
+			return 0, fmt.Errorf("%w\nStatement: %s", err, step.Display)
+		}
+	}
+
+	var tables int
+	if err := tx.QueryRow(ctx, `
+		SELECT count(*)
+		FROM pg_catalog.pg_foreign_table ft
+		JOIN pg_catalog.pg_class c ON c.oid = ft.ftrelid
+		JOIN pg_catalog.pg_foreign_server s ON s.oid = ft.ftserver
+		WHERE c.relnamespace = (SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = $1)
+		  AND s.srvname = $2
+	`, target.LocalSchema, target.Server).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to count the imported tables: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit failed, nothing was set up: %w", err)
+	}
+	return tables, nil
+}
+
+// checkFDWExtension returns an error when postgres_fdw is missing and may
+// not be installed
+func checkFDWExtension(state *fdwState, extensions *config.ExtensionsConfig) error {
+	if state.ExtensionInstalled || (extensions != nil && extensions.IsAllowed("postgres_fdw")) {
+		return nil
+	}
+	allowed := ""
+	if extensions != nil {
+		allowed = strings.Join(extensions.Allowed, ", ")
+	}
+	return fmt.Errorf("the postgres_fdw extension is not installed and the server configuration does not allow installing it (extensions.allowed: %s)", allowed)
+}
+
+// wireForeignDatabase makes the tables of a configured database queryable
+// in the current database for query_database, setting up the foreign
+// server first when the caller may. It returns the local schema of the
+// database's tables, and whether the foreign server was set up by the call.
+func wireForeignDatabase(ctx context.Context, pool *pgxpool.Pool, federation *Federation, name string) (string, bool, error) {
+	if federation == nil || federation.Lookup == nil {
+		return "", false, fmt.Errorf("querying other databases is not available")
+	}
+	db, err := federation.Lookup(ctx, name)
+	if err != nil {
+		return "", false, err
+	}
+	target := newFDWTarget(db, "", "")
+	state, err := lookupFDWState(ctx, pool, target)
+	if err != nil {
+		return "", false, err
+	}
+	if state.Wired() {
+		return target.LocalSchema, false, nil
+	}
+
+	if federation.CanWire == nil || !federation.CanWire(ctx) {
+		return "", false, fmt.Errorf("database %s is not set up as a foreign server of this database; run setup_foreign_server(database=%q) first, or ask an administrator to enable it", name, name)
+	}
+	if err := checkFDWExtension(state, federation.Extensions); err != nil {
+		return "", false, err
+	}
+	if _, err := applyFDWSteps(ctx, pool, target, fdwSteps(target, state)); err != nil {
+		return "", false, fmt.Errorf("failed to set up database %s as a foreign server: %w", name, err)
+	}
+	return target.LocalSchema, true, nil
+}
@@ -0,0 +1,155 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/resources"
+)
+
+func TestForeignNames(t *testing.T) {
+	tests := []struct {
+		db, schema, server string
+	}{
+		{"analytics", "analytics", "mcp_analytics"},
+		{"Sales-EU", "sales_eu", "mcp_sales_eu"},
+		{"2024 archive", "db_2024_archive", "mcp_2024_archive"},
+	}
+	for _, tt := range tests {
+		if got := foreignSchemaName(tt.db); got != tt.schema {
+			t.Errorf("foreignSchemaName(%q) = %q, want %q", tt.db, got, tt.schema)
+		}
+		if got := foreignServerName(tt.db); got != tt.server {
+			t.Errorf("foreignServerName(%q) = %q, want %q", tt.db, got, tt.server)
+		}
+	}
+}
+
+func TestFDWSteps(t *testing.T) {
+	db := &config.NamedDatabaseConfig{
+		Name: "analytics", Host: "db2.internal", Port: 5432, Database: "warehouse",
+		User: "reporter", Password: "it's secret", SSLMode: "require",
+	}
+	target := newFDWTarget(db, "", "")
+
+	steps := fdwSteps(target, &fdwState{})
+	var sqls, displays []string
+	for _, step := range steps {
+		sqls = append(sqls, step.SQL)
+		displays = append(displays, step.Display)
+	}
+	wantDisplay := []string{
+		`CREATE EXTENSION IF NOT EXISTS "postgres_fdw" CASCADE`,
+		`CREATE SERVER mcp_analytics FOREIGN DATA WRAPPER postgres_fdw OPTIONS (host 'db2.internal', port '5432', dbname 'warehouse', sslmode 'require')`,
+		`CREATE USER MAPPING FOR CURRENT_USER SERVER mcp_analytics OPTIONS (user 'reporter', password '********')`,
+		`CREATE SCHEMA analytics`,
+		`IMPORT FOREIGN SCHEMA public FROM SERVER mcp_analytics INTO analytics`,
+	}
+	if strings.Join(displays, "\n") != strings.Join(wantDisplay, "\n") {
+		t.Errorf("displayed steps =\n%s\nwant\n%s", strings.Join(displays, "\n"), strings.Join(wantDisplay, "\n"))
+	}
+	if !strings.Contains(sqls[2], `password 'it''s secret'`) {
+		t.Errorf("user mapping does not carry the password: %s", sqls[2])
+	}
+
+	// A wired database only imports the tables added since
+	state := &fdwState{
+		ExtensionInstalled: true, ServerExists: true, MappingExists: true, SchemaExists: true,
+		ForeignTables: []string{"orders"}, LocalRelations: []string{"Notes", "orders"},
+	}
+	if !state.Wired() {
+		t.Error("expected the state to be wired")
+	}
+	steps = fdwSteps(newFDWTarget(db, "sales", "remote_sales"), state)
+	if len(steps) != 1 || steps[0].SQL != `IMPORT FOREIGN SCHEMA sales EXCEPT ("Notes", orders) FROM SERVER mcp_analytics INTO remote_sales` {
+		t.Errorf("steps = %+v", steps)
+	}
+}
+
+func TestCheckFDWExtension(t *testing.T) {
+	allowed := &config.ExtensionsConfig{Allowed: []string{"vector", "postgres_fdw"}}
+	notAllowed := &config.ExtensionsConfig{Allowed: []string{"vector"}}
+
+	if err := checkFDWExtension(&fdwState{ExtensionInstalled: true}, notAllowed); err != nil {
+		t.Errorf("installed extension: unexpected error %v", err)
+	}
+	if err := checkFDWExtension(&fdwState{}, allowed); err != nil {
+		t.Errorf("allowed extension: unexpected error %v", err)
+	}
+	if err := checkFDWExtension(&fdwState{}, notAllowed); err == nil || !strings.Contains(err.Error(), "extensions.allowed: vector") {
+		t.Errorf("expected a not allowed error, got %v", err)
+	}
+}
+
+func TestSetupForeignServerTool_Validation(t *testing.T) {
+	tool := SetupForeignServerTool(nil, nil)
+
+	resp, err := tool.Handler(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	if !resp.IsError {
+		t.Errorf("expected an error without a database, got %+v", resp)
+	}
+
+	resp, _ = tool.Handler(map[string]interface{}{"database": "analytics"})
+	if !resp.IsError || !strings.Contains(resp.Content[0].Text, "not available") {
+		t.Errorf("expected an unavailable error, got %+v", resp)
+	}
+}
+
+func TestContextAwareProvider_Federation(t *testing.T) {
+	databases := []config.NamedDatabaseConfig{
+		{Name: "main", Host: "localhost", Database: "app", User: "app"},
+		{Name: "analytics", Host: "db2", Database: "warehouse", User: "reporter"},
+	}
+	clientManager := database.NewClientManager(databases)
+	defer clientManager.CloseAll()
+	client := database.NewClient(&databases[0])
+
+	cfg := &config.Config{}
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, nil, cfg, nil, "", nil, 0, nil)
+	federation := provider.federation(client)
+
+	db, err := federation.Lookup(context.Background(), "analytics")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if db.Database != "warehouse" {
+		t.Errorf("Lookup() = %+v", db)
+	}
+	if _, err := federation.Lookup(context.Background(), "missing"); err == nil || !strings.Contains(err.Error(), "databases: analytics, main") {
+		t.Errorf("expected a not configured error, got %v", err)
+	}
+
+	// query_database only sets up foreign servers when setup_foreign_server is enabled
+	if federation.CanWire(context.Background()) {
+		t.Error("expected CanWire to be false by default")
+	}
+	enabled := true
+	cfg.Builtins.Tools.SetupForeignServer = &enabled
+	if !provider.federation(client).CanWire(context.Background()) {
+		t.Error("expected CanWire to be true with setup_foreign_server enabled")
+	}
+
+	// Nothing is set up in a production database
+	databases[0].Environment = "prod"
+	clientManager.UpdateDatabaseConfigs(databases)
+	if provider.federation(client).CanWire(context.Background()) {
+		t.Error("expected CanWire to be false for a production database")
+	}
+}
@@ -0,0 +1,141 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// GenerateMigrationTool creates the generate_migration tool, which turns a
+// requested schema change into forward and backward SQL without applying it
+func GenerateMigrationTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "generate_migration",
+			Description: `Turn a schema change into a named migration: the forward (up) SQL and the
+backward (down) SQL that undoes it, derived from the live schema.
+
+<usecase>
+Use when:
+- The user asks for a schema change that should be reviewable and reversible
+- Preparing a migration to apply with apply_migration
+</usecase>
+
+<what_it_returns>
+- The forward SQL as given, split into statements
+- The backward SQL: created objects are dropped, renames swapped, dropped
+  columns, indexes, views and constraints recreated from their current
+  definitions, and changed types, defaults, NOT NULL and comments restored
+- Warnings for changes that lose data or cannot be undone (DROP TABLE, data
+  changes, unnamed constraints); such migrations are marked irreversible
+</what_it_returns>
+
+<important>
+Nothing is executed. Write the forward SQL yourself, then show both scripts
+to the user. Check locking with plan_schema_change before applying.
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Short name for the migration, such as 'add_order_notes'",
+					},
+					"up": map[string]interface{}{
+						"type":        "string",
+						"description": "The forward DDL; several statements may be separated by semicolons",
+					},
+				},
+				Required: []string{"name", "up"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			name, errResp := ValidateStringParam(args, "name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			up, errResp := ValidateStringParam(args, "up")
+			if errResp != nil {
+				return *errResp, nil
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			if _, err := splitScript(up); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			catalog := poolMigrationCatalog{ctx: context.Background(), pool: pool}
+			m, err := generateMigration(name, up, dbClient.GetMetadataFor(connStr), catalog)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Could not generate migration: %v", err))
+			}
+
+			logging.Info("generate_migration_executed",
+				"name", name,
+				"statements", len(m.Up),
+				"reversible", m.Reversible,
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatMigration(m))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// formatMigration renders a generated migration for review
+func formatMigration(m *migration) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Migration: %s\n\n", m.Name))
+	sb.WriteString("-- Forward (up)\n")
+	sb.WriteString(m.upSQL())
+	sb.WriteString("\n-- Backward (down)\n")
+	if len(m.Down) == 0 {
+		sb.WriteString("-- (nothing to undo)\n")
+	} else {
+		sb.WriteString(m.downSQL())
+	}
+
+	if len(m.Warnings) > 0 {
+		sb.WriteString("\nWarnings:\n")
+		for _, w := range m.Warnings {
+			sb.WriteString("- " + w + "\n")
+		}
+	}
+
+	sb.WriteString("\n")
+	if m.Reversible {
+		sb.WriteString("The migration is reversible.\n")
+	} else {
+		sb.WriteString("The migration is NOT fully reversible: the backward SQL does not undo every statement. Write a down script by hand if a rollback must be possible.\n")
+	}
+
+	sb.WriteString("\n<next_steps>\n")
+	sb.WriteString("1. Show both scripts to the user and confirm the change\n")
+	sb.WriteString("2. Check locks and table rewrites with plan_schema_change(ddl=<up>)\n")
+	sb.WriteString(fmt.Sprintf("3. Test with apply_migration(name=%q, up=<up>, dry_run=true), then apply with dry_run=false\n", m.Name))
+	sb.WriteString("</next_steps>\n")
+	return sb.String()
+}
@@ -12,14 +12,18 @@ package tools
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/masking"
 	"pgedge-postgres-mcp/internal/mcp"
 )
 
 // GetSchemaInfoTool creates the get_schema_info tool
-func GetSchemaInfoTool(dbClient *database.Client) Tool {
+// If masker is non-nil, its rules are applied to the values shown in column
+// statistics
+func GetSchemaInfoTool(dbClient *database.Client, masker *masking.Masker) Tool {
 	return Tool{
 		Definition: mcp.Tool{
 			Name: "get_schema_info",
@@ -46,8 +50,9 @@ ALWAYS call this tool FIRST when:
 
 <key_features>
 Returns comprehensive information in TSV format (one row per column):
-- schema, table, type, table_desc, column, data_type, nullable, col_desc, is_pk, is_unique, fk_ref, is_indexed, identity, default, is_vector, vector_dims
-- All tables and views in the database
+- schema, table, type, table_desc, column, data_type, nullable, col_desc, is_pk, is_unique, fk_ref, is_indexed, identity, default, is_vector, vector_dims, row_security
+- All tables and views the connection's role can SELECT from (objects without
+  privileges are omitted, as are columns restricted by column-level GRANTs)
 - Column names, data types, nullable status
 - Primary key (is_pk) and unique constraint (is_unique) indicators
 - Foreign key references (fk_ref) in format "schema.table.column"
@@ -56,7 +61,13 @@ Returns comprehensive information in TSV format (one row per column):
 - Default values for columns (default)
 - Table and column descriptions from pg_description
 - Vector column detection (pgvector extension)
+- Row-level security (row_security): true if policies limit the rows the
+  role sees, so counts and results may not cover the whole table
 - Schema organization
+- With include_stats (the default for a single table): null_frac,
+  n_distinct, common_values and histogram from the planner statistics, so
+  you can filter on values that exist (e.g. status = 'shipped') and judge
+  selectivity. Empty if the table has not been analyzed
 </key_features>
 
 <filtering_options>
@@ -65,6 +76,7 @@ Returns comprehensive information in TSV format (one row per column):
 - table_name="users" (with schema_name): Get columns for specific table only
 - vector_tables_only=true: Show only tables with pgvector columns (reduces output 10x)
 - compact=true: Return table names only (no column details)
+- include_stats=true: Add column statistics (default: true with table_name)
 </filtering_options>
 
 <auto_summary_mode>
@@ -123,6 +135,10 @@ To avoid rate limits when calling this tool:
 						"description": "Optional: if true, return table names only (no column details). Use for quick overview.",
 						"default":     false,
 					},
+					"include_stats": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Optional: add each column's null fraction, distinct values, most common values and histogram bounds. Defaults to true when table_name is given.",
+					},
 				},
 			},
 		},
@@ -157,12 +173,22 @@ To avoid rate limits when calling this tool:
 				compactMode = false
 			}
 
+			// Statistics add several columns, so by default only a single
+			// table gets them
+			includeStats := ValidateBoolParam(args, "include_stats", tableName != "")
+
 			// Check if metadata is loaded
 			if !dbClient.IsMetadataLoaded() {
 				return mcp.NewToolError(mcp.DatabaseNotReadyError)
 			}
 
-			metadata := dbClient.GetMetadata()
+			// Only the requested schema is needed when filtering by schema
+			var metadata map[string]database.TableInfo
+			if schemaName != "" {
+				metadata = dbClient.GetSchemaMetadata(schemaName)
+			} else {
+				metadata = dbClient.GetMetadata()
+			}
 
 			// Threshold for auto-summary mode (when no filters applied)
 			const summaryThreshold = 10
@@ -268,7 +294,7 @@ To avoid rate limits when calling this tool:
 				// Standard output modes: TSV format
 				if compactMode {
 					// Compact mode: table names only (no column details)
-					sb.WriteString("schema\ttable\ttype\ttable_desc\n")
+					sb.WriteString("schema\ttable\ttype\ttable_desc\trow_security\n")
 
 					for _, table := range metadata {
 						// Filter by schema if requested
@@ -300,12 +326,17 @@ To avoid rate limits when calling this tool:
 							table.TableName,
 							table.TableType,
 							table.Description,
+							fmt.Sprintf("%t", table.RowSecurity),
 						))
 						sb.WriteString("\n")
 					}
 				} else {
 					// Full mode: one row per column with all details
-					sb.WriteString("schema\ttable\ttype\ttable_desc\tcolumn\tdata_type\tnullable\tcol_desc\tis_pk\tis_unique\tfk_ref\tis_indexed\tidentity\tdefault\tis_vector\tvector_dims\n")
+					sb.WriteString("schema\ttable\ttype\ttable_desc\tcolumn\tdata_type\tnullable\tcol_desc\tis_pk\tis_unique\tfk_ref\tis_indexed\tidentity\tdefault\tis_vector\tvector_dims\trow_security")
+					if includeStats {
+						sb.WriteString("\tnull_frac\tn_distinct\tcommon_values\thistogram")
+					}
+					sb.WriteString("\n")
 
 					for _, table := range metadata {
 						// Filter by schema if requested
@@ -335,7 +366,7 @@ To avoid rate limits when calling this tool:
 						// Output one row per column
 						for i := range table.Columns {
 							col := &table.Columns[i]
-							sb.WriteString(BuildTSVRow(
+							values := []string{
 								table.SchemaName,
 								table.TableName,
 								table.TableType,
@@ -352,7 +383,12 @@ To avoid rate limits when calling this tool:
 								col.DefaultValue,
 								fmt.Sprintf("%t", col.IsVectorColumn),
 								fmt.Sprintf("%d", col.VectorDimensions),
-							))
+								fmt.Sprintf("%t", table.RowSecurity),
+							}
+							if includeStats {
+								values = append(values, formatColumnStats(&table, col, masker)...)
+							}
+							sb.WriteString(BuildTSVRow(values...))
 							sb.WriteString("\n")
 						}
 					}
@@ -452,3 +488,64 @@ To avoid rate limits when calling this tool:
 		},
 	}
 }
+
+// formatColumnStats returns the null_frac, n_distinct, common_values and
+// histogram cells of a column, empty if it has no statistics. The masker's
+// rules apply to the sampled values as they do to query results.
+func formatColumnStats(table *database.TableInfo, col *database.ColumnInfo, masker *masking.Masker) []string {
+	stats := col.Stats
+	if stats == nil {
+		return []string{"", "", "", ""}
+	}
+
+	source := []masking.Column{{Table: table.SchemaName + "." + table.TableName, Name: col.ColumnName}}
+	mask := func(values []string) []string {
+		masked := make([]string, len(values))
+		copy(masked, values)
+		if !masker.Enabled() {
+			return masked
+		}
+		for i := range masked {
+			row := [][]interface{}{{masked[i]}}
+			masker.Apply(source, row)
+			masked[i] = fmt.Sprint(row[0][0])
+		}
+		return masked
+	}
+
+	common := mask(stats.MostCommonValues)
+	for i := range common {
+		if i < len(stats.MostCommonFreqs) {
+			common[i] = fmt.Sprintf("%s (%s)", common[i], formatStatsPercent(stats.MostCommonFreqs[i]))
+		}
+	}
+
+	return []string{
+		strconv.FormatFloat(stats.NullFrac, 'f', 2, 64),
+		formatNDistinct(stats.NDistinct),
+		strings.Join(common, " | "),
+		strings.Join(mask(stats.HistogramBounds), " | "),
+	}
+}
+
+// formatNDistinct describes pg_stats.n_distinct: a count of distinct
+// values, or minus the fraction of the rows when it grows with the table
+func formatNDistinct(n float64) string {
+	switch {
+	case n == -1:
+		return "all rows"
+	case n < 0:
+		return formatStatsPercent(-n) + " of rows"
+	case n == 0:
+		return ""
+	}
+	return strconv.FormatFloat(n, 'f', 0, 64)
+}
+
+// formatStatsPercent formats a fraction of the rows as a percentage
+func formatStatsPercent(fraction float64) string {
+	if fraction < 0.01 {
+		return strconv.FormatFloat(fraction*100, 'f', 2, 64) + "%"
+	}
+	return strconv.FormatFloat(fraction*100, 'f', 0, 64) + "%"
+}
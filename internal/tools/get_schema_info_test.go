@@ -14,7 +14,9 @@ import (
 	"strings"
 	"testing"
 
+	"pgedge-postgres-mcp/internal/config"
 	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/masking"
 )
 
 // Helper function to create a mock database client with test data
@@ -27,7 +29,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		client := database.NewClient(nil)
 		// Don't add any connections - database is not ready
 
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 		response, err := tool.Handler(map[string]interface{}{})
 
 		if err != nil {
@@ -48,7 +50,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 	t.Run("empty metadata", func(t *testing.T) {
 		client := createMockClient(map[string]database.TableInfo{})
 
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 		response, err := tool.Handler(map[string]interface{}{})
 
 		if err != nil {
@@ -99,7 +101,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 		response, err := tool.Handler(map[string]interface{}{})
 
 		if err != nil {
@@ -154,7 +156,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 
 		// Request only public schema
 		response, err := tool.Handler(map[string]interface{}{
@@ -220,7 +222,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 		response, err := tool.Handler(map[string]interface{}{})
 
 		if err != nil {
@@ -259,7 +261,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 		response, err := tool.Handler(map[string]interface{}{})
 
 		if err != nil {
@@ -293,7 +295,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 		response, err := tool.Handler(map[string]interface{}{})
 
 		if err != nil {
@@ -339,7 +341,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 		response, err := tool.Handler(map[string]interface{}{})
 
 		if err != nil {
@@ -377,7 +379,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 
 		// Pass invalid type for schema_name (should be ignored and default to "")
 		response, err := tool.Handler(map[string]interface{}{
@@ -436,7 +438,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 
 		// Request only users table in public schema
 		response, err := tool.Handler(map[string]interface{}{
@@ -487,7 +489,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 
 		// Request table_name without schema_name
 		response, err := tool.Handler(map[string]interface{}{
@@ -524,7 +526,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 
 		// Request non-existent table
 		response, err := tool.Handler(map[string]interface{}{
@@ -579,7 +581,7 @@ func TestGetSchemaInfoTool(t *testing.T) {
 		}
 
 		client := createMockClient(metadata)
-		tool := GetSchemaInfoTool(client)
+		tool := GetSchemaInfoTool(client, nil)
 
 		// Request with both table_name and compact=true
 		// compact should be ignored when table_name is provided
@@ -611,4 +613,108 @@ func TestGetSchemaInfoTool(t *testing.T) {
 			t.Error("Expected email column details in TSV")
 		}
 	})
+
+	t.Run("row-level security is reported", func(t *testing.T) {
+		metadata := map[string]database.TableInfo{
+			"public.accounts": {
+				SchemaName:  "public",
+				TableName:   "accounts",
+				TableType:   "TABLE",
+				RowSecurity: true,
+				Columns:     []database.ColumnInfo{{ColumnName: "id", DataType: "integer", IsNullable: "NO"}},
+			},
+		}
+
+		tool := GetSchemaInfoTool(createMockClient(metadata), nil)
+		for _, args := range []map[string]interface{}{{}, {"compact": true}} {
+			response, err := tool.Handler(args)
+			if err != nil || response.IsError {
+				t.Fatalf("Handler failed: %v", err)
+			}
+			content := response.Content[0].Text
+			if !strings.Contains(content, "\trow_security\n") {
+				t.Errorf("Expected row_security column in header for %v:\n%s", args, content)
+			}
+			if !strings.Contains(content, "\ttrue\n") {
+				t.Errorf("Expected row_security=true for %v:\n%s", args, content)
+			}
+		}
+	})
+
+	t.Run("column statistics", func(t *testing.T) {
+		metadata := map[string]database.TableInfo{
+			"public.orders": {
+				SchemaName: "public",
+				TableName:  "orders",
+				TableType:  "TABLE",
+				Columns: []database.ColumnInfo{
+					{ColumnName: "status", DataType: "text", IsNullable: "NO", Stats: &database.ColumnStats{
+						NDistinct:        4,
+						MostCommonValues: []string{"shipped", "pending"},
+						MostCommonFreqs:  []float64{0.62, 0.3},
+					}},
+					{ColumnName: "email", DataType: "text", IsNullable: "YES", Stats: &database.ColumnStats{
+						NullFrac:        0.05,
+						NDistinct:       -1,
+						HistogramBounds: []string{"ann@example.com", "zoe@example.com"},
+					}},
+					{ColumnName: "note", DataType: "text", IsNullable: "YES"},
+				},
+			},
+		}
+		masker, err := masking.New(&config.MaskingConfig{
+			Enabled: true,
+			Rules:   []config.MaskingRule{{Column: "^email$"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tool := GetSchemaInfoTool(createMockClient(metadata), masker)
+
+		response, err := tool.Handler(map[string]interface{}{"schema_name": "public", "table_name": "orders"})
+		if err != nil || response.IsError {
+			t.Fatalf("Handler failed: %v", err)
+		}
+		content := response.Content[0].Text
+		for _, want := range []string{
+			"\trow_security\tnull_frac\tn_distinct\tcommon_values\thistogram\n",
+			"\t0.00\t4\tshipped (62%) | pending (30%)\t\n",
+			"\t0.05\tall rows\t\t**** | ****\n",
+			"\tnote\t",
+		} {
+			if !strings.Contains(content, want) {
+				t.Errorf("expected %q in:\n%s", want, content)
+			}
+		}
+		if strings.Contains(content, "example.com") {
+			t.Errorf("expected masked values to be hidden:\n%s", content)
+		}
+
+		// Statistics are left out of schema-wide listings unless requested
+		response, err = tool.Handler(map[string]interface{}{"schema_name": "public"})
+		if err != nil || response.IsError {
+			t.Fatalf("Handler failed: %v", err)
+		}
+		if strings.Contains(response.Content[0].Text, "common_values") {
+			t.Errorf("expected no statistics without table_name:\n%s", response.Content[0].Text)
+		}
+	})
+}
+
+func TestFormatNDistinct(t *testing.T) {
+	tests := []struct {
+		n    float64
+		want string
+	}{
+		{0, ""},
+		{12, "12"},
+		{-1, "all rows"},
+		{-0.25, "25% of rows"},
+		{-0.004, "0.40% of rows"},
+	}
+	for _, tt := range tests {
+		if got := formatNDistinct(tt.n); got != tt.want {
+			t.Errorf("formatNDistinct(%v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
 }
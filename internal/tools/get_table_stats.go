@@ -0,0 +1,444 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// GetTableStatsTool creates the get_table_stats tool, which reports the
+// cumulative statistics, size, bloat and index usage of a table
+func GetTableStatsTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "get_table_stats",
+			Description: `Get performance statistics for a table: scans, row activity, dead rows,
+estimated bloat, cache hit ratios, index usage and vacuum/analyze history.
+
+<usecase>
+Use when:
+- The user asks why queries on a table are slow
+- Checking whether a table needs VACUUM or ANALYZE, or is bloated
+- Finding unused indexes or tables read mostly by sequential scans
+</usecase>
+
+<examples>
+✓ get_table_stats(table="orders") → Statistics for public.orders
+✓ get_table_stats(table="events", schema="analytics")
+</examples>
+
+<important>
+- Use this instead of writing queries against pg_stat_* and pg_statio_* views
+- Counters are cumulative since statistics were last reset; the output shows when
+- The bloat figure is an estimate from planner statistics, not a measurement
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"table": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the table",
+					},
+					"schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema name (default: public)",
+						"default":     "public",
+					},
+				},
+				Required: []string{"table"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			table, errResp := ValidateStringParam(args, "table")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			schema := "public"
+			if s, ok := args["schema"].(string); ok && s != "" {
+				schema = s
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			stats, err := loadTableStats(context.Background(), pool, schema, table)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to read statistics for %s.%s: %v", schema, table, err))
+			}
+			if stats == nil {
+				return mcp.NewToolError(fmt.Sprintf("Table %s.%s not found. Use get_schema_info to list the available tables.", schema, table))
+			}
+
+			logging.Info("get_table_stats_executed",
+				"schema", schema,
+				"table", table,
+				"indexes", len(stats.Indexes),
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatTableStatsReport(stats, time.Now()))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// tableStats holds the statistics reported for one table
+type tableStats struct {
+	Schema string
+	Table  string
+	Kind   string // relkind: r (table), p (partitioned table) or m (materialized view)
+
+	// Size
+	TableBytes int64
+	IndexBytes int64
+	TotalBytes int64
+	RelPages   int64
+	RelTuples  float64 // -1 (or 0 before PostgreSQL 14) if never analyzed
+	BlockSize  int64
+	FillFactor int64
+	// AvgRowWidth is the estimated width of a row's data from pg_stats
+	AvgRowWidth    float64
+	HasColumnStats bool
+
+	// pg_stat_all_tables
+	SeqScan          int64
+	SeqTupRead       int64
+	IdxScan          int64
+	IdxTupFetch      int64
+	Inserts          int64
+	Updates          int64
+	HotUpdates       int64
+	Deletes          int64
+	LiveTuples       int64
+	DeadTuples       int64
+	ModSinceAnalyze  int64
+	LastVacuum       *time.Time
+	LastAutovacuum   *time.Time
+	LastAnalyze      *time.Time
+	LastAutoanalyze  *time.Time
+	VacuumCount      int64
+	AutovacuumCount  int64
+	AnalyzeCount     int64
+	AutoanalyzeCount int64
+
+	// pg_statio_all_tables
+	HeapBlksRead  int64
+	HeapBlksHit   int64
+	IdxBlksRead   int64
+	IdxBlksHit    int64
+	ToastBlksRead int64
+	ToastBlksHit  int64
+
+	StatsReset *time.Time // When the database's statistics were last reset
+	Indexes    []indexStats
+}
+
+// indexStats holds the usage statistics of one index
+type indexStats struct {
+	Name     string
+	Scans    int64
+	TupRead  int64
+	TupFetch int64
+	Bytes    int64
+	Unique   bool
+	Primary  bool
+	BlksRead int64
+	BlksHit  int64
+}
+
+// loadTableStats reads a table's statistics in a read-only transaction
+// Returns nil if the table does not exist
+func loadTableStats(ctx context.Context, pool *pgxpool.Pool, schema, table string) (*tableStats, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // read-only transaction, nothing to keep
+	}()
+
+	if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+		return nil, fmt.Errorf("failed to set transaction to read-only: %w", err)
+	}
+
+	stats := &tableStats{Schema: schema, Table: table}
+	var oid uint32
+	err = tx.QueryRow(ctx, `
+		SELECT c.oid, c.relkind::text, c.relpages::bigint, c.reltuples::float8,
+			pg_catalog.pg_table_size(c.oid), pg_catalog.pg_indexes_size(c.oid),
+			pg_catalog.pg_total_relation_size(c.oid),
+			current_setting('block_size')::bigint,
+			coalesce((SELECT option_value::bigint FROM pg_catalog.pg_options_to_table(c.reloptions)
+				WHERE option_name = 'fillfactor'), 100),
+			coalesce(s.seq_scan, 0), coalesce(s.seq_tup_read, 0),
+			coalesce(s.idx_scan, 0), coalesce(s.idx_tup_fetch, 0),
+			coalesce(s.n_tup_ins, 0), coalesce(s.n_tup_upd, 0),
+			coalesce(s.n_tup_hot_upd, 0), coalesce(s.n_tup_del, 0),
+			coalesce(s.n_live_tup, 0), coalesce(s.n_dead_tup, 0),
+			coalesce(s.n_mod_since_analyze, 0),
+			s.last_vacuum, s.last_autovacuum, s.last_analyze, s.last_autoanalyze,
+			coalesce(s.vacuum_count, 0), coalesce(s.autovacuum_count, 0),
+			coalesce(s.analyze_count, 0), coalesce(s.autoanalyze_count, 0),
+			coalesce(io.heap_blks_read, 0), coalesce(io.heap_blks_hit, 0),
+			coalesce(io.idx_blks_read, 0), coalesce(io.idx_blks_hit, 0),
+			coalesce(io.toast_blks_read, 0), coalesce(io.toast_blks_hit, 0),
+			(SELECT stats_reset FROM pg_catalog.pg_stat_database WHERE datname = current_database())
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_catalog.pg_stat_all_tables s ON s.relid = c.oid
+		LEFT JOIN pg_catalog.pg_statio_all_tables io ON io.relid = c.oid
+		WHERE n.nspname = $1 AND c.relname = $2 AND c.relkind IN ('r', 'p', 'm')`,
+		schema, table).Scan(
+		&oid, &stats.Kind, &stats.RelPages, &stats.RelTuples,
+		&stats.TableBytes, &stats.IndexBytes, &stats.TotalBytes,
+		&stats.BlockSize, &stats.FillFactor,
+		&stats.SeqScan, &stats.SeqTupRead, &stats.IdxScan, &stats.IdxTupFetch,
+		&stats.Inserts, &stats.Updates, &stats.HotUpdates, &stats.Deletes,
+		&stats.LiveTuples, &stats.DeadTuples, &stats.ModSinceAnalyze,
+		&stats.LastVacuum, &stats.LastAutovacuum, &stats.LastAnalyze, &stats.LastAutoanalyze,
+		&stats.VacuumCount, &stats.AutovacuumCount, &stats.AnalyzeCount, &stats.AutoanalyzeCount,
+		&stats.HeapBlksRead, &stats.HeapBlksHit, &stats.IdxBlksRead, &stats.IdxBlksHit,
+		&stats.ToastBlksRead, &stats.ToastBlksHit,
+		&stats.StatsReset,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+
+	// pg_stats only lists columns the user can read, so the width is a
+	// lower bound when some columns are hidden
+	var columns int64
+	err = tx.QueryRow(ctx, `
+		SELECT coalesce(sum((1 - null_frac) * avg_width), 0)::float8, count(*)
+		FROM pg_catalog.pg_stats
+		WHERE schemaname = $1 AND tablename = $2 AND NOT inherited`,
+		schema, table).Scan(&stats.AvgRowWidth, &columns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column statistics: %w", err)
+	}
+	stats.HasColumnStats = columns > 0
+
+	rows, err := tx.Query(ctx, `
+		SELECT i.indexrelname::text, coalesce(i.idx_scan, 0), coalesce(i.idx_tup_read, 0),
+			coalesce(i.idx_tup_fetch, 0), pg_catalog.pg_relation_size(i.indexrelid),
+			x.indisunique, x.indisprimary,
+			coalesce(io.idx_blks_read, 0), coalesce(io.idx_blks_hit, 0)
+		FROM pg_catalog.pg_stat_all_indexes i
+		JOIN pg_catalog.pg_index x ON x.indexrelid = i.indexrelid
+		LEFT JOIN pg_catalog.pg_statio_all_indexes io ON io.indexrelid = i.indexrelid
+		WHERE i.relid = $1
+		ORDER BY i.indexrelname`, oid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var idx indexStats
+		if err := rows.Scan(&idx.Name, &idx.Scans, &idx.TupRead, &idx.TupFetch, &idx.Bytes,
+			&idx.Unique, &idx.Primary, &idx.BlksRead, &idx.BlksHit); err != nil {
+			return nil, fmt.Errorf("failed to read index statistics: %w", err)
+		}
+		stats.Indexes = append(stats.Indexes, idx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	return stats, nil
+}
+
+// estimateBloat estimates the bytes of free or dead space in a table from
+// its page count and the average row width in pg_stats, in the manner of the
+// common catalog-based bloat queries. It returns false when the table has
+// not been analyzed.
+func estimateBloat(stats *tableStats) (int64, bool) {
+	if !stats.HasColumnStats || stats.RelTuples < 0 || stats.RelPages == 0 || stats.BlockSize == 0 {
+		return 0, false
+	}
+
+	// Each row has a 23-byte header padded to 24, its data padded to 8
+	// bytes, and a 4-byte line pointer; pages have a 24-byte header
+	rowBytes := 24 + math.Ceil(stats.AvgRowWidth/8)*8 + 4
+	usable := float64(stats.BlockSize-24) * float64(stats.FillFactor) / 100
+	expectedPages := int64(math.Ceil(stats.RelTuples * rowBytes / usable))
+
+	bloatPages := stats.RelPages - expectedPages
+	if bloatPages < 0 {
+		bloatPages = 0
+	}
+	return bloatPages * stats.BlockSize, true
+}
+
+// tableStatsFindings points out statistics that usually call for action
+func tableStatsFindings(stats *tableStats) []string {
+	var findings []string
+
+	if stats.Kind != "p" && stats.LastAnalyze == nil && stats.LastAutoanalyze == nil {
+		findings = append(findings, "The table has never been analyzed, so the planner is guessing its size and data distribution. Run ANALYZE.")
+	}
+
+	if total := stats.LiveTuples + stats.DeadTuples; stats.DeadTuples > 1000 && float64(stats.DeadTuples) > 0.2*float64(total) {
+		findings = append(findings, fmt.Sprintf("%s of rows are dead; autovacuum may not be keeping up. Check autovacuum settings or run VACUUM.",
+			formatRatio(stats.DeadTuples, total)))
+	}
+
+	if stats.ModSinceAnalyze > 1000 && float64(stats.ModSinceAnalyze) > 0.2*float64(stats.LiveTuples) {
+		findings = append(findings, fmt.Sprintf("%d rows changed since the last analyze; planner statistics may be stale.", stats.ModSinceAnalyze))
+	}
+
+	if bloat, ok := estimateBloat(stats); ok && bloat > 10*1024*1024 && float64(bloat) > 0.3*float64(stats.RelPages*stats.BlockSize) {
+		findings = append(findings, fmt.Sprintf("About %s of the table is estimated to be free or dead space. VACUUM FULL or pg_repack can reclaim it, but VACUUM FULL locks the table.",
+			formatSize(bloat)))
+	}
+
+	if stats.SeqScan > stats.IdxScan && stats.LiveTuples > 10000 && stats.SeqTupRead/stats.SeqScan > 1000 {
+		findings = append(findings, fmt.Sprintf("Most scans are sequential, reading %d rows each on average. Check frequent queries with execute_explain for missing indexes.",
+			stats.SeqTupRead/stats.SeqScan))
+	}
+
+	if reads := stats.HeapBlksRead + stats.HeapBlksHit; reads > 1000 && float64(stats.HeapBlksHit) < 0.9*float64(reads) {
+		findings = append(findings, fmt.Sprintf("Only %s of table block reads were served from shared buffers.",
+			formatRatio(stats.HeapBlksHit, reads)))
+	}
+
+	for _, idx := range stats.Indexes {
+		if idx.Scans == 0 && !idx.Unique && !idx.Primary {
+			findings = append(findings, fmt.Sprintf("Index %s (%s) has not been used since statistics were reset; consider dropping it if that covers a representative period.",
+				idx.Name, formatSize(idx.Bytes)))
+		}
+	}
+	return findings
+}
+
+// formatTableStatsReport formats a table's statistics for the LLM
+func formatTableStatsReport(stats *tableStats, now time.Time) string {
+	var sb strings.Builder
+
+	kind := "table"
+	switch stats.Kind {
+	case "p":
+		kind = "partitioned table; activity and size are recorded on its partitions"
+	case "m":
+		kind = "materialized view"
+	}
+	sb.WriteString(fmt.Sprintf("Table: %s.%s (%s)\n", stats.Schema, stats.Table, kind))
+	sb.WriteString(fmt.Sprintf("Statistics collected since: %s\n", formatStatsTime(stats.StatsReset, now, "server start or last reset")))
+
+	sb.WriteString("\nSize:\n")
+	sb.WriteString(fmt.Sprintf("  Table: %s, Indexes: %s, Total: %s\n",
+		formatSize(stats.TableBytes), formatSize(stats.IndexBytes), formatSize(stats.TotalBytes)))
+	sb.WriteString(fmt.Sprintf("  Rows: %d live, %d dead (%s dead)\n",
+		stats.LiveTuples, stats.DeadTuples, formatRatio(stats.DeadTuples, stats.LiveTuples+stats.DeadTuples)))
+	if bloat, ok := estimateBloat(stats); ok {
+		sb.WriteString(fmt.Sprintf("  Estimated bloat: %s (%s of %d pages)\n",
+			formatSize(bloat), formatRatio(bloat, stats.RelPages*stats.BlockSize), stats.RelPages))
+	} else {
+		sb.WriteString("  Estimated bloat: unavailable until the table is analyzed\n")
+	}
+
+	sb.WriteString("\nActivity:\n")
+	sb.WriteString(fmt.Sprintf("  Sequential scans: %d (%d rows read)\n", stats.SeqScan, stats.SeqTupRead))
+	sb.WriteString(fmt.Sprintf("  Index scans: %d (%d rows fetched)\n", stats.IdxScan, stats.IdxTupFetch))
+	sb.WriteString(fmt.Sprintf("  Index usage: %s of scans\n", formatRatio(stats.IdxScan, stats.SeqScan+stats.IdxScan)))
+	sb.WriteString(fmt.Sprintf("  Inserts: %d, Updates: %d (%s HOT), Deletes: %d\n",
+		stats.Inserts, stats.Updates, formatRatio(stats.HotUpdates, stats.Updates), stats.Deletes))
+	sb.WriteString(fmt.Sprintf("  Rows modified since last analyze: %d\n", stats.ModSinceAnalyze))
+
+	sb.WriteString("\nMaintenance:\n")
+	sb.WriteString(fmt.Sprintf("  Last vacuum: %s\n", formatStatsTime(stats.LastVacuum, now, "never")))
+	sb.WriteString(fmt.Sprintf("  Last autovacuum: %s\n", formatStatsTime(stats.LastAutovacuum, now, "never")))
+	sb.WriteString(fmt.Sprintf("  Last analyze: %s\n", formatStatsTime(stats.LastAnalyze, now, "never")))
+	sb.WriteString(fmt.Sprintf("  Last autoanalyze: %s\n", formatStatsTime(stats.LastAutoanalyze, now, "never")))
+	sb.WriteString(fmt.Sprintf("  Vacuums: %d manual, %d automatic; Analyzes: %d manual, %d automatic\n",
+		stats.VacuumCount, stats.AutovacuumCount, stats.AnalyzeCount, stats.AutoanalyzeCount))
+
+	sb.WriteString("\nCache hit ratios (shared buffers):\n")
+	sb.WriteString(fmt.Sprintf("  Table: %s (%d hits, %d reads)\n",
+		formatRatio(stats.HeapBlksHit, stats.HeapBlksHit+stats.HeapBlksRead), stats.HeapBlksHit, stats.HeapBlksRead))
+	sb.WriteString(fmt.Sprintf("  Indexes: %s (%d hits, %d reads)\n",
+		formatRatio(stats.IdxBlksHit, stats.IdxBlksHit+stats.IdxBlksRead), stats.IdxBlksHit, stats.IdxBlksRead))
+	if stats.ToastBlksHit+stats.ToastBlksRead > 0 {
+		sb.WriteString(fmt.Sprintf("  TOAST: %s (%d hits, %d reads)\n",
+			formatRatio(stats.ToastBlksHit, stats.ToastBlksHit+stats.ToastBlksRead), stats.ToastBlksHit, stats.ToastBlksRead))
+	}
+
+	sb.WriteString("\nIndexes:\n")
+	if len(stats.Indexes) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, idx := range stats.Indexes {
+		name := idx.Name
+		switch {
+		case idx.Primary:
+			name += " (primary key)"
+		case idx.Unique:
+			name += " (unique)"
+		}
+		sb.WriteString(fmt.Sprintf("  - %s: %d scans, %d rows fetched, %s, cache hit %s\n",
+			name, idx.Scans, idx.TupFetch, formatSize(idx.Bytes), formatRatio(idx.BlksHit, idx.BlksHit+idx.BlksRead)))
+	}
+
+	if findings := tableStatsFindings(stats); len(findings) > 0 {
+		sb.WriteString("\nFindings:\n")
+		for _, finding := range findings {
+			sb.WriteString("- " + finding + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// formatRatio formats part/total as a percentage, or n/a when total is zero
+func formatRatio(part, total int64) string {
+	if total <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(part)/float64(total))
+}
+
+// formatStatsTime formats a statistics timestamp with its age
+func formatStatsTime(t *time.Time, now time.Time, missing string) string {
+	if t == nil {
+		return missing
+	}
+	age := now.Sub(*t)
+	var ago string
+	switch {
+	case age < time.Minute:
+		ago = "just now"
+	case age < time.Hour:
+		ago = fmt.Sprintf("%d minutes ago", int(age.Minutes()))
+	case age < 48*time.Hour:
+		ago = fmt.Sprintf("%d hours ago", int(age.Hours()))
+	default:
+		ago = fmt.Sprintf("%d days ago", int(age.Hours()/24))
+	}
+	return fmt.Sprintf("%s (%s)", t.UTC().Format("2006-01-02 15:04:05 UTC"), ago)
+}
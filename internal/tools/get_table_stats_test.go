@@ -0,0 +1,132 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleTableStats returns statistics for a busy, healthy table
+func sampleTableStats(now time.Time) *tableStats {
+	analyzed := now.Add(-3 * time.Hour)
+	return &tableStats{
+		Schema:         "public",
+		Table:          "orders",
+		Kind:           "r",
+		TableBytes:     100 * 8192,
+		IndexBytes:     20 * 8192,
+		TotalBytes:     120 * 8192,
+		RelPages:       100,
+		RelTuples:      5000,
+		BlockSize:      8192,
+		FillFactor:     100,
+		AvgRowWidth:    100,
+		HasColumnStats: true,
+		SeqScan:        2,
+		SeqTupRead:     10000,
+		IdxScan:        998,
+		IdxTupFetch:    1200,
+		Updates:        100,
+		HotUpdates:     90,
+		LiveTuples:     5000,
+		DeadTuples:     50,
+		LastAutovacuum: &analyzed,
+		LastAnalyze:    &analyzed,
+		HeapBlksRead:   10,
+		HeapBlksHit:    990,
+		Indexes: []indexStats{
+			{Name: "orders_pkey", Scans: 998, TupFetch: 1200, Bytes: 20 * 8192, Unique: true, Primary: true, BlksHit: 50},
+		},
+	}
+}
+
+func TestEstimateBloat(t *testing.T) {
+	stats := sampleTableStats(time.Now())
+
+	// 5000 rows of 24 + 104 + 4 bytes fit in 81 pages of 8168 usable bytes
+	bloat, ok := estimateBloat(stats)
+	if !ok {
+		t.Fatal("expected a bloat estimate")
+	}
+	if bloat != 19*8192 {
+		t.Errorf("expected 19 pages of bloat, got %d bytes", bloat)
+	}
+
+	// A lower fillfactor leaves free space on purpose
+	stats.FillFactor = 50
+	if bloat, _ := estimateBloat(stats); bloat != 0 {
+		t.Errorf("expected no bloat with fillfactor 50, got %d bytes", bloat)
+	}
+
+	stats.HasColumnStats = false
+	if _, ok := estimateBloat(stats); ok {
+		t.Error("expected no estimate without column statistics")
+	}
+}
+
+func TestTableStatsFindings(t *testing.T) {
+	now := time.Now()
+
+	if findings := tableStatsFindings(sampleTableStats(now)); len(findings) != 0 {
+		t.Errorf("expected no findings for a healthy table, got %v", findings)
+	}
+
+	stats := sampleTableStats(now)
+	stats.LastAnalyze = nil
+	stats.DeadTuples = 4000
+	stats.SeqScan = 1000
+	stats.SeqTupRead = 5000000
+	stats.LiveTuples = 50000
+	stats.HeapBlksRead = 500
+	stats.Indexes = append(stats.Indexes, indexStats{Name: "orders_status_idx", Bytes: 8192})
+
+	findings := strings.Join(tableStatsFindings(stats), "\n")
+	for _, want := range []string{
+		"never been analyzed",
+		"Most scans are sequential, reading 5000 rows each",
+		"Only 66.4% of table block reads",
+		"Index orders_status_idx (8.0 kB) has not been used",
+	} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("expected findings to contain %q, got:\n%s", want, findings)
+		}
+	}
+	if strings.Contains(findings, "orders_pkey") {
+		t.Error("primary keys should not be reported as unused")
+	}
+}
+
+func TestFormatTableStatsReport(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	report := formatTableStatsReport(sampleTableStats(now), now)
+
+	for _, want := range []string{
+		"Table: public.orders (table)",
+		"Statistics collected since: server start or last reset",
+		"Rows: 5000 live, 50 dead (1.0% dead)",
+		"Estimated bloat: 152.0 kB (19.0% of 100 pages)",
+		"Index usage: 99.8% of scans",
+		"Updates: 100 (90.0% HOT)",
+		"Last vacuum: never",
+		"Last analyze: 2025-06-01 09:00:00 UTC (3 hours ago)",
+		"Table: 99.0% (990 hits, 10 reads)",
+		"orders_pkey (primary key): 998 scans",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "Findings:") {
+		t.Error("expected no findings section for a healthy table")
+	}
+}
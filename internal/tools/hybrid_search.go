@@ -0,0 +1,595 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/masking"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/search"
+)
+
+// Names of the ranked lists fused by hybrid_search
+const (
+	hybridFullText = "full_text"
+	hybridVector   = "vector"
+)
+
+// HybridSearchTool creates the hybrid_search tool, which combines PostgreSQL
+// full-text search with pgvector similarity on one table and merges the two
+// rankings with reciprocal rank fusion
+// If masker is non-nil, its rules are applied to the returned rows
+func HybridSearchTool(dbClient *database.Client, masker *masking.Masker, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "hybrid_search",
+			Description: `Search a table by keywords AND meaning: runs PostgreSQL full-text search and
+pgvector similarity search on the same table and merges the two rankings with
+reciprocal rank fusion (RRF).
+
+<usecase>
+Use hybrid_search when:
+- The query mixes exact terms (product names, error codes, identifiers) with
+  a concept, e.g. "ERR_CONN_RESET after upgrading the driver"
+- Retrieving rows for retrieval-augmented generation, where rows that match
+  both the words and the meaning of the question should come first
+- similarity_search misses rows containing the exact terms of the query
+</usecase>
+
+<behavior>
+- Full-text search uses websearch_to_tsquery, so the query may contain
+  "quoted phrases", OR, and -excluded words
+- The full-text source is a tsvector column if the table has one, otherwise
+  the text column matching the vector column (or text_column)
+- Each retriever returns up to candidates rows; a row scores
+  weight / (rrf_k + rank) in each ranking that contains it
+- Returns whole rows (without vector columns) as TSV with the fused score
+  and the row's rank in each list
+</behavior>
+
+<important>
+- Requires a table with a pgvector column and embedding generation enabled
+- Long text values are shortened to max_text_chars characters
+- Call get_schema_info(vector_tables_only=true) first if you don't know the table
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Table to search (can include schema: 'schema.table')",
+					},
+					"query_text": map[string]interface{}{
+						"type":        "string",
+						"description": "Search query, used for both full-text and vector search",
+					},
+					"text_column": map[string]interface{}{
+						"type":        "string",
+						"description": "Text or tsvector column for full-text search (default: a tsvector column, or the text column of the vector column)",
+					},
+					"vector_column": map[string]interface{}{
+						"type":        "string",
+						"description": "Vector column for similarity search (default: the only vector column, or the content column's)",
+					},
+					"text_search_config": map[string]interface{}{
+						"type":        "string",
+						"description": "Text search configuration for the query and text columns (default: english)",
+						"default":     "english",
+					},
+					"distance_metric": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"cosine", "l2", "inner_product"},
+						"description": "Vector distance metric (default: cosine)",
+						"default":     "cosine",
+					},
+					"top_n": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of fused results to return (default: 10)",
+						"default":     10,
+						"minimum":     1,
+						"maximum":     100,
+					},
+					"candidates": map[string]interface{}{
+						"type":        "integer",
+						"description": "Rows retrieved by each search before fusion (default: 50)",
+						"default":     50,
+						"minimum":     1,
+						"maximum":     1000,
+					},
+					"rrf_k": map[string]interface{}{
+						"type":        "integer",
+						"description": "RRF rank constant; larger values give lower-ranked rows more weight (default: 60)",
+						"default":     search.DefaultRRFConstant,
+						"minimum":     1,
+					},
+					"text_weight": map[string]interface{}{
+						"type":        "number",
+						"description": "Weight of the full-text ranking (default: 1.0)",
+						"default":     1.0,
+						"minimum":     0,
+					},
+					"vector_weight": map[string]interface{}{
+						"type":        "number",
+						"description": "Weight of the vector ranking (default: 1.0)",
+						"default":     1.0,
+						"minimum":     0,
+					},
+					"max_text_chars": map[string]interface{}{
+						"type":        "integer",
+						"description": "Shorten text values to this many characters, 0 for no limit (default: 500)",
+						"default":     500,
+						"minimum":     0,
+					},
+				},
+				Required: []string{"table_name", "query_text"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			tableName, errResp := ValidateStringParam(args, "table_name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			queryText, errResp := ValidateStringParam(args, "query_text")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			queryText = strings.TrimSpace(queryText)
+			if queryText == "" {
+				return mcp.NewToolError("query_text must not be empty")
+			}
+
+			opts := hybridSearchOptions{
+				TextColumn:   ValidateOptionalStringParam(args, "text_column", ""),
+				VectorColumn: ValidateOptionalStringParam(args, "vector_column", ""),
+				TextConfig:   ValidateOptionalStringParam(args, "text_search_config", "english"),
+				Metric:       ValidateOptionalStringParam(args, "distance_metric", "cosine"),
+				TopN:         10,
+				Candidates:   50,
+				RRFConstant:  search.DefaultRRFConstant,
+				TextWeight:   1.0,
+				VectorWeight: 1.0,
+				MaxTextChars: 500,
+			}
+			if opts.Metric != "cosine" && opts.Metric != "l2" && opts.Metric != "inner_product" {
+				return mcp.NewToolError("distance_metric must be 'cosine', 'l2' or 'inner_product'")
+			}
+			if v, ok := args["top_n"].(float64); ok {
+				opts.TopN = int(v)
+			}
+			if v, ok := args["candidates"].(float64); ok {
+				opts.Candidates = int(v)
+			}
+			if v, ok := args["rrf_k"].(float64); ok {
+				opts.RRFConstant = int(v)
+			}
+			if v, ok := args["text_weight"].(float64); ok {
+				opts.TextWeight = v
+			}
+			if v, ok := args["vector_weight"].(float64); ok {
+				opts.VectorWeight = v
+			}
+			if v, ok := args["max_text_chars"].(float64); ok {
+				opts.MaxTextChars = int(v)
+			}
+			if err := opts.validate(); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			tableInfo, err := findTableInMetadataMap(dbClient.GetMetadataFor(connStr), tableName)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("%v\nUse get_schema_info(vector_tables_only=true) to find tables with vector columns", err))
+			}
+			vectorCol, err := selectHybridVectorColumn(tableInfo, opts.VectorColumn)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			textSource, err := selectHybridTextSource(tableInfo, vectorCol, opts.TextColumn)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			queryEmbedding, err := generateQueryEmbeddingWithConfig(cfg, queryText)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to generate query embedding: %v\n"+
+					"Full-text search alone is available through query_database with to_tsvector/websearch_to_tsquery", err))
+			}
+
+			// Both searches read one snapshot, so row ctids identify the same
+			// rows in each
+			ctx := context.Background()
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			defer func() {
+				_ = tx.Rollback(ctx) //nolint:errcheck // read-only transaction, nothing to keep
+			}()
+
+			columns := hybridOutputColumns(tableInfo)
+			q := newHybridQueries(tableInfo, columns, textSource, vectorCol.ColumnName, opts.Metric)
+
+			textHits, err := runHybridQuery(ctx, tx, masker, q.FullText, opts.TextConfig, queryText, opts.Candidates)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Full-text search failed: %v\nSQL:\n%s", err, q.FullText))
+			}
+			vectorHits, err := runHybridQuery(ctx, tx, masker, q.Vector, formatEmbeddingForPostgres(queryEmbedding), opts.Candidates)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Vector search failed: %v\nSQL:\n%s", err, q.Vector))
+			}
+
+			fused := fuseHybridHits(textHits, vectorHits, opts)
+			recordRowsReturned(args, len(fused))
+
+			logging.Info("hybrid_search_executed",
+				"table", tableName,
+				"text_source", textSource.Label,
+				"vector_column", vectorCol.ColumnName,
+				"full_text_hits", len(textHits),
+				"vector_hits", len(vectorHits),
+				"results", len(fused),
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\nTable: %s.%s\n\n",
+				database.SanitizeConnStr(connStr), tableInfo.SchemaName, tableInfo.TableName))
+			sb.WriteString(formatHybridResults(queryText, columns, textSource, vectorCol.ColumnName, textHits, vectorHits, fused, opts))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// hybridSearchOptions are the hybrid_search parameters
+type hybridSearchOptions struct {
+	TextColumn   string
+	VectorColumn string
+	TextConfig   string
+	Metric       string
+	TopN         int
+	Candidates   int
+	RRFConstant  int
+	TextWeight   float64
+	VectorWeight float64
+	MaxTextChars int
+}
+
+// validate checks the numeric options
+func (o *hybridSearchOptions) validate() error {
+	switch {
+	case o.TopN < 1 || o.TopN > 100:
+		return fmt.Errorf("top_n must be between 1 and 100")
+	case o.Candidates < 1 || o.Candidates > 1000:
+		return fmt.Errorf("candidates must be between 1 and 1000")
+	case o.RRFConstant < 1:
+		return fmt.Errorf("rrf_k must be at least 1")
+	case o.TextWeight < 0 || o.VectorWeight < 0:
+		return fmt.Errorf("text_weight and vector_weight must not be negative")
+	case o.TextWeight == 0 && o.VectorWeight == 0:
+		return fmt.Errorf("text_weight and vector_weight cannot both be 0")
+	case o.MaxTextChars < 0:
+		return fmt.Errorf("max_text_chars must not be negative")
+	}
+	if o.Candidates < o.TopN {
+		o.Candidates = o.TopN
+	}
+	return nil
+}
+
+// selectHybridVectorColumn returns the named vector column, the only vector
+// column, or the vector column of the table's main content column
+func selectHybridVectorColumn(tableInfo database.TableInfo, columnName string) (database.ColumnInfo, error) {
+	vectorCols := discoverVectorColumns(tableInfo)
+	if columnName != "" || len(vectorCols) <= 1 {
+		col, err := selectVectorColumn(tableInfo, columnName)
+		if err != nil && columnName == "" {
+			return col, fmt.Errorf("%v; hybrid_search needs a pgvector column, use get_schema_info(vector_tables_only=true) to find one", err)
+		}
+		return col, err
+	}
+
+	// Several vector columns: prefer the one whose text looks like content
+	best := -1.0
+	bestName := ""
+	for _, weight := range search.DetectColumnTypes(tableInfo, nil) {
+		if weight.Weight > best {
+			best = weight.Weight
+			bestName = weight.VectorName
+		}
+	}
+	if bestName == "" {
+		return database.ColumnInfo{}, fmt.Errorf("table %s.%s has several vector columns (%s); specify vector_column",
+			tableInfo.SchemaName, tableInfo.TableName, strings.Join(vectorColumnNames(vectorCols), ", "))
+	}
+	return selectVectorColumn(tableInfo, bestName)
+}
+
+// hybridTextSource is the document searched by full-text search
+type hybridTextSource struct {
+	Label string // Column names, for the output
+	// Expr is the tsvector expression; $1 is the text search configuration
+	Expr string
+}
+
+// selectHybridTextSource chooses the full-text document: the named column,
+// a tsvector column, or the text columns matching the vector column
+func selectHybridTextSource(tableInfo database.TableInfo, vectorCol database.ColumnInfo, columnName string) (hybridTextSource, error) {
+	if columnName != "" {
+		for i := range tableInfo.Columns {
+			col := &tableInfo.Columns[i]
+			if col.ColumnName != columnName {
+				continue
+			}
+			switch {
+			case isTSVectorType(col.DataType):
+				return hybridTextSource{Label: col.ColumnName, Expr: "t." + quoteIdentifier(col.ColumnName)}, nil
+			case isTextDataType(col.DataType):
+				return textColumnsSource([]string{col.ColumnName}), nil
+			}
+			return hybridTextSource{}, fmt.Errorf("text_column %s has type %s; use a text or tsvector column", columnName, col.DataType)
+		}
+		return hybridTextSource{}, fmt.Errorf("column %s not found in %s.%s", columnName, tableInfo.SchemaName, tableInfo.TableName)
+	}
+
+	for i := range tableInfo.Columns {
+		if isTSVectorType(tableInfo.Columns[i].DataType) {
+			name := tableInfo.Columns[i].ColumnName
+			return hybridTextSource{Label: name, Expr: "t." + quoteIdentifier(name)}, nil
+		}
+	}
+
+	textCols := discoverTextColumns(tableInfo, []database.ColumnInfo{vectorCol})
+	if len(textCols) == 0 {
+		return hybridTextSource{}, fmt.Errorf("table %s.%s has no text or tsvector columns for full-text search",
+			tableInfo.SchemaName, tableInfo.TableName)
+	}
+	return textColumnsSource(textCols), nil
+}
+
+// textColumnsSource builds a tsvector expression over text columns
+func textColumnsSource(columns []string) hybridTextSource {
+	parts := make([]string, len(columns))
+	for i, col := range columns {
+		parts[i] = fmt.Sprintf("coalesce(t.%s::text, '')", quoteIdentifier(col))
+	}
+	return hybridTextSource{
+		Label: strings.Join(columns, ", "),
+		Expr:  fmt.Sprintf("to_tsvector($1::regconfig, %s)", strings.Join(parts, " || ' ' || ")),
+	}
+}
+
+// isTSVectorType reports whether a column holds a tsvector
+func isTSVectorType(dataType string) bool {
+	return strings.EqualFold(dataType, "tsvector")
+}
+
+// hybridOutputColumns returns the columns returned for each row; vector and
+// tsvector columns are left out
+func hybridOutputColumns(tableInfo database.TableInfo) []string {
+	var columns []string
+	for i := range tableInfo.Columns {
+		col := &tableInfo.Columns[i]
+		if col.IsVectorColumn || isTSVectorType(col.DataType) {
+			continue
+		}
+		columns = append(columns, col.ColumnName)
+	}
+	return columns
+}
+
+// hybridQueries holds the two searches; each returns the row's ctid, its
+// score (ts_rank_cd or distance) and the output columns
+type hybridQueries struct {
+	FullText string // $1 text search configuration, $2 query text, $3 limit
+	Vector   string // $1 query embedding, $2 limit
+}
+
+// newHybridQueries builds the full-text and vector queries
+func newHybridQueries(tableInfo database.TableInfo, columns []string, text hybridTextSource, vectorColumn, metric string) hybridQueries {
+	table := quoteIdentifier(tableInfo.SchemaName) + "." + quoteIdentifier(tableInfo.TableName)
+	var colList strings.Builder
+	for _, col := range columns {
+		colList.WriteString(", t." + quoteIdentifier(col))
+	}
+	distance := fmt.Sprintf("t.%s %s $1::vector", quoteIdentifier(vectorColumn), getDistanceOperator(metric))
+
+	return hybridQueries{
+		FullText: fmt.Sprintf(`SELECT t.ctid::text, ts_rank_cd(%[1]s, query)::float8%[2]s
+FROM %[3]s t, websearch_to_tsquery($1::regconfig, $2) query
+WHERE %[1]s @@ query
+ORDER BY 2 DESC
+LIMIT $3`, text.Expr, colList.String(), table),
+		Vector: fmt.Sprintf(`SELECT t.ctid::text, (%[1]s)::float8%[2]s
+FROM %[3]s t
+WHERE t.%[4]s IS NOT NULL
+ORDER BY %[1]s
+LIMIT $2`, distance, colList.String(), table, quoteIdentifier(vectorColumn)),
+	}
+}
+
+// hybridHit is a row returned by one of the searches
+type hybridHit struct {
+	ID     string
+	Score  float64
+	Values []interface{}
+}
+
+// runHybridQuery runs a search and masks the returned columns
+func runHybridQuery(ctx context.Context, tx pgx.Tx, masker *masking.Masker, query string, args ...interface{}) ([]hybridHit, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	fields := rows.FieldDescriptions()
+
+	var hits []hybridHit
+	var values [][]interface{}
+	for rows.Next() {
+		row, err := rows.Values()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		hit := hybridHit{Values: row[2:]}
+		hit.ID, _ = row[0].(string)     //nolint:errcheck // ctid::text is always text
+		hit.Score, _ = row[1].(float64) //nolint:errcheck // cast to float8 in the query
+		hits = append(hits, hit)
+		values = append(values, hit.Values)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if masker.Enabled() && len(hits) > 0 {
+		maskColumns, err := resolveMaskingColumns(ctx, tx, fields[2:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve columns for masking: %w", err)
+		}
+		masker.Apply(maskColumns, values)
+	}
+	return hits, nil
+}
+
+// hybridResult is a fused row
+type hybridResult struct {
+	search.FusedResult
+	Values []interface{}
+}
+
+// fuseHybridHits merges the two result lists with reciprocal rank fusion
+// and returns the top rows
+func fuseHybridHits(textHits, vectorHits []hybridHit, opts hybridSearchOptions) []hybridResult {
+	rows := make(map[string][]interface{})
+	ids := func(hits []hybridHit) []string {
+		list := make([]string, len(hits))
+		for i, hit := range hits {
+			list[i] = hit.ID
+			if _, ok := rows[hit.ID]; !ok {
+				rows[hit.ID] = hit.Values
+			}
+		}
+		return list
+	}
+
+	var lists []search.RankedList
+	if opts.TextWeight > 0 {
+		lists = append(lists, search.RankedList{Name: hybridFullText, Weight: opts.TextWeight, IDs: ids(textHits)})
+	}
+	if opts.VectorWeight > 0 {
+		lists = append(lists, search.RankedList{Name: hybridVector, Weight: opts.VectorWeight, IDs: ids(vectorHits)})
+	}
+
+	fused := search.FuseRankings(lists, opts.RRFConstant)
+	if len(fused) > opts.TopN {
+		fused = fused[:opts.TopN]
+	}
+	results := make([]hybridResult, len(fused))
+	for i, f := range fused {
+		results[i] = hybridResult{FusedResult: f, Values: rows[f.ID]}
+	}
+	return results
+}
+
+// formatHybridResults describes the searches and lists the fused rows as TSV
+func formatHybridResults(queryText string, columns []string, text hybridTextSource, vectorColumn string,
+	textHits, vectorHits []hybridHit, results []hybridResult, opts hybridSearchOptions) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Hybrid Search: %q\n", queryText))
+	sb.WriteString(fmt.Sprintf("Full-text: %s (%s), %d matches", text.Label, opts.TextConfig, len(textHits)))
+	if opts.TextWeight == 0 {
+		sb.WriteString(", not used (text_weight=0)")
+	}
+	sb.WriteString(fmt.Sprintf("\nVector: %s (%s distance), %d nearest rows", vectorColumn, opts.Metric, len(vectorHits)))
+	if opts.VectorWeight == 0 {
+		sb.WriteString(", not used (vector_weight=0)")
+	}
+	sb.WriteString(fmt.Sprintf("\nFusion: reciprocal rank, k=%d, weights full-text %g / vector %g\n\n",
+		opts.RRFConstant, opts.TextWeight, opts.VectorWeight))
+
+	if len(results) == 0 {
+		sb.WriteString("No rows matched.\n")
+		return sb.String()
+	}
+	if len(textHits) == 0 && opts.TextWeight > 0 {
+		sb.WriteString("No full-text matches; the results are ranked by vector similarity only.\n\n")
+	}
+
+	textScores := hitScores(textHits)
+	vectorScores := hitScores(vectorHits)
+
+	header := append([]string{"rrf_score", "text_rank", "text_score", "vector_rank", "vector_distance"}, columns...)
+	rows := make([][]interface{}, len(results))
+	for i, result := range results {
+		row := make([]interface{}, 0, len(header))
+		row = append(row, fmt.Sprintf("%.5f", result.Score))
+		row = append(row, rankAndScore(result, hybridFullText, textScores)...)
+		row = append(row, rankAndScore(result, hybridVector, vectorScores)...)
+		for _, value := range result.Values {
+			row = append(row, truncateHybridValue(value, opts.MaxTextChars))
+		}
+		rows[i] = row
+	}
+
+	sb.WriteString(fmt.Sprintf("Results (%d):\n", len(results)))
+	sb.WriteString(FormatResultsAsTSV(header, rows))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// hitScores maps row IDs to their score in a search
+func hitScores(hits []hybridHit) map[string]float64 {
+	scores := make(map[string]float64, len(hits))
+	for _, hit := range hits {
+		if _, ok := scores[hit.ID]; !ok {
+			scores[hit.ID] = hit.Score
+		}
+	}
+	return scores
+}
+
+// rankAndScore returns a result's rank and score in one list, or NULLs if
+// the list did not return it
+func rankAndScore(result hybridResult, list string, scores map[string]float64) []interface{} {
+	rank, ok := result.Ranks[list]
+	if !ok {
+		return []interface{}{nil, nil}
+	}
+	return []interface{}{rank, fmt.Sprintf("%.4f", scores[result.ID])}
+}
+
+// truncateHybridValue shortens long text values
+func truncateHybridValue(value interface{}, maxChars int) interface{} {
+	s, ok := value.(string)
+	if !ok || maxChars <= 0 {
+		return value
+	}
+	runes := []rune(s)
+	if len(runes) <= maxChars {
+		return value
+	}
+	return string(runes[:maxChars]) + "..."
+}
@@ -0,0 +1,204 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+// hybridTestTable has a title and body, each with an embedding
+func hybridTestTable() database.TableInfo {
+	return database.TableInfo{
+		SchemaName: "public",
+		TableName:  "articles",
+		Columns: []database.ColumnInfo{
+			{ColumnName: "id", DataType: "integer", IsPrimaryKey: true},
+			{ColumnName: "title", DataType: "text"},
+			{ColumnName: "content", DataType: "text"},
+			{ColumnName: "title_embedding", DataType: "vector(384)", IsVectorColumn: true, VectorDimensions: 384},
+			{ColumnName: "content_embedding", DataType: "vector(384)", IsVectorColumn: true, VectorDimensions: 384},
+		},
+	}
+}
+
+func TestSelectHybridVectorColumn(t *testing.T) {
+	table := hybridTestTable()
+
+	col, err := selectHybridVectorColumn(table, "")
+	if err != nil || col.ColumnName != "content_embedding" {
+		t.Errorf("expected the content column's embedding, got %q, %v", col.ColumnName, err)
+	}
+	col, err = selectHybridVectorColumn(table, "title_embedding")
+	if err != nil || col.ColumnName != "title_embedding" {
+		t.Errorf("expected the named column, got %q, %v", col.ColumnName, err)
+	}
+	if _, err := selectHybridVectorColumn(table, "title"); err == nil {
+		t.Error("expected an error for a non-vector column")
+	}
+
+	table.Columns = table.Columns[:3]
+	if _, err := selectHybridVectorColumn(table, ""); err == nil || !strings.Contains(err.Error(), "needs a pgvector column") {
+		t.Errorf("expected an error without vector columns, got %v", err)
+	}
+}
+
+func TestSelectHybridTextSource(t *testing.T) {
+	table := hybridTestTable()
+	vectorCol := table.Columns[4]
+
+	source, err := selectHybridTextSource(table, vectorCol, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Label != "content" || source.Expr != `to_tsvector($1::regconfig, coalesce(t."content"::text, ''))` {
+		t.Errorf("unexpected source for the content embedding: %+v", source)
+	}
+
+	source, err = selectHybridTextSource(table, vectorCol, "title")
+	if err != nil || source.Label != "title" {
+		t.Errorf("expected the named column, got %+v, %v", source, err)
+	}
+	if _, err := selectHybridTextSource(table, vectorCol, "id"); err == nil || !strings.Contains(err.Error(), "has type integer") {
+		t.Errorf("expected an error for a non-text column, got %v", err)
+	}
+	if _, err := selectHybridTextSource(table, vectorCol, "missing"); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+
+	// A tsvector column is preferred, as it can use a GIN index
+	table.Columns = append(table.Columns, database.ColumnInfo{ColumnName: "search_vector", DataType: "tsvector"})
+	source, err = selectHybridTextSource(table, vectorCol, "")
+	if err != nil || source.Expr != `t."search_vector"` {
+		t.Errorf("expected the tsvector column, got %+v, %v", source, err)
+	}
+
+	// Without a matching text column, all text columns are searched
+	other := database.TableInfo{
+		SchemaName: "public",
+		TableName:  "notes",
+		Columns: []database.ColumnInfo{
+			{ColumnName: "subject", DataType: "character varying"},
+			{ColumnName: "body", DataType: "text"},
+			{ColumnName: "embedding", DataType: "vector(3)", IsVectorColumn: true},
+		},
+	}
+	source, err = selectHybridTextSource(other, other.Columns[2], "")
+	if err != nil || source.Label != "subject, body" || !strings.Contains(source.Expr, `|| ' ' ||`) {
+		t.Errorf("expected all text columns, got %+v, %v", source, err)
+	}
+}
+
+func TestNewHybridQueries(t *testing.T) {
+	table := hybridTestTable()
+	columns := hybridOutputColumns(table)
+	if strings.Join(columns, ",") != "id,title,content" {
+		t.Errorf("vector columns should not be returned: %v", columns)
+	}
+
+	source := textColumnsSource([]string{"content"})
+	q := newHybridQueries(table, columns, source, "content_embedding", "l2")
+
+	for _, want := range []string{
+		`FROM "public"."articles" t, websearch_to_tsquery($1::regconfig, $2) query`,
+		`WHERE to_tsvector($1::regconfig, coalesce(t."content"::text, '')) @@ query`,
+		`, t."id", t."title", t."content"`,
+		"LIMIT $3",
+	} {
+		if !strings.Contains(q.FullText, want) {
+			t.Errorf("full-text query missing %q:\n%s", want, q.FullText)
+		}
+	}
+	for _, want := range []string{
+		`ORDER BY t."content_embedding" <-> $1::vector`,
+		`WHERE t."content_embedding" IS NOT NULL`,
+		"LIMIT $2",
+	} {
+		if !strings.Contains(q.Vector, want) {
+			t.Errorf("vector query missing %q:\n%s", want, q.Vector)
+		}
+	}
+}
+
+func TestHybridSearchOptionsValidate(t *testing.T) {
+	valid := hybridSearchOptions{TopN: 20, Candidates: 5, RRFConstant: 60, TextWeight: 1, VectorWeight: 0, MaxTextChars: 0}
+	if err := valid.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if valid.Candidates != 20 {
+		t.Errorf("candidates should be raised to top_n, got %d", valid.Candidates)
+	}
+
+	for name, opts := range map[string]hybridSearchOptions{
+		"top_n":        {TopN: 0, Candidates: 50, RRFConstant: 60, TextWeight: 1, VectorWeight: 1},
+		"candidates":   {TopN: 10, Candidates: 5000, RRFConstant: 60, TextWeight: 1, VectorWeight: 1},
+		"rrf_k":        {TopN: 10, Candidates: 50, RRFConstant: 0, TextWeight: 1, VectorWeight: 1},
+		"weights":      {TopN: 10, Candidates: 50, RRFConstant: 60},
+		"negative":     {TopN: 10, Candidates: 50, RRFConstant: 60, TextWeight: -1, VectorWeight: 1},
+		"max_text_len": {TopN: 10, Candidates: 50, RRFConstant: 60, TextWeight: 1, VectorWeight: 1, MaxTextChars: -1},
+	} {
+		if err := opts.validate(); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
+
+func TestFuseAndFormatHybridResults(t *testing.T) {
+	opts := hybridSearchOptions{TextConfig: "english", Metric: "cosine", TopN: 2, RRFConstant: 60, TextWeight: 1, VectorWeight: 1, MaxTextChars: 10}
+	textHits := []hybridHit{
+		{ID: "(0,1)", Score: 0.5, Values: []interface{}{int32(1), "Connection resets", "The server resets connections"}},
+		{ID: "(0,2)", Score: 0.2, Values: []interface{}{int32(2), "Timeouts", "Idle timeouts"}},
+	}
+	vectorHits := []hybridHit{
+		{ID: "(0,3)", Score: 0.1, Values: []interface{}{int32(3), "Networking", "Dropped packets"}},
+		{ID: "(0,1)", Score: 0.15, Values: []interface{}{int32(1), "Connection resets", "The server resets connections"}},
+	}
+
+	results := fuseHybridHits(textHits, vectorHits, opts)
+	if len(results) != 2 {
+		t.Fatalf("expected top_n results, got %d", len(results))
+	}
+	if results[0].ID != "(0,1)" {
+		t.Errorf("the row found by both searches should rank first, got %s", results[0].ID)
+	}
+
+	out := formatHybridResults("connection reset", []string{"id", "title", "content"},
+		textColumnsSource([]string{"content"}), "content_embedding", textHits, vectorHits, results, opts)
+	for _, want := range []string{
+		"Full-text: content (english), 2 matches",
+		"Vector: content_embedding (cosine distance), 2 nearest rows",
+		"Fusion: reciprocal rank, k=60",
+		"rrf_score\ttext_rank\ttext_score\tvector_rank\tvector_distance\tid\ttitle\tcontent",
+		"0.03252\t1\t0.5000\t2\t0.1500\t1\tConnection...\tThe server...",
+		"0.01639\t\t\t1\t0.1000\t3\tNetworking\tDropped pa...",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	// With the full-text ranking switched off, only vector ranks count
+	opts.TextWeight = 0
+	results = fuseHybridHits(textHits, vectorHits, opts)
+	if results[0].ID != "(0,3)" {
+		t.Errorf("expected the vector ranking, got %s first", results[0].ID)
+	}
+	if _, ok := results[0].Ranks[hybridFullText]; ok {
+		t.Error("the full-text ranking should not be used")
+	}
+
+	out = formatHybridResults("q", nil, textColumnsSource([]string{"content"}), "content_embedding", nil, nil, nil, opts)
+	if !strings.Contains(out, "No rows matched.") {
+		t.Errorf("expected an empty result message:\n%s", out)
+	}
+}
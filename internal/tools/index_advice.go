@@ -0,0 +1,462 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// This file contains the planning side of the index advisor: it reads
+// EXPLAIN (FORMAT JSON, VERBOSE) plans, proposes B-tree indexes for the
+// sequential scans it finds, and ranks the proposals. Talking to the
+// database (pg_stat_statements, hypopg) is done in index_advisor.go.
+
+// maxIndexColumns bounds the number of columns in a proposed index
+const maxIndexColumns = 3
+
+// minIndexImprovement is the plan cost reduction a hypothetical index must
+// achieve to be recommended
+const minIndexImprovement = 0.1
+
+// advisorQuery is a query analyzed by the index advisor
+type advisorQuery struct {
+	SQL     string
+	Calls   int64   // From pg_stat_statements; 0 for a query given by the user
+	TotalMs float64 // Total execution time from pg_stat_statements
+	MeanMs  float64
+}
+
+// weight is how much the query counts when ranking recommendations
+func (q *advisorQuery) weight() float64 {
+	if q.TotalMs > 0 {
+		return q.TotalMs
+	}
+	return 1
+}
+
+// advisorQueryParams checks that a query can be planned by the advisor and
+// returns the number of positional parameters ($n) it uses
+func advisorQueryParams(sql string) (int, error) {
+	tokens, err := tokenizeSQL(sql)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse query: %w", err)
+	}
+	statements := splitStatements(tokens)
+	if len(statements) != 1 {
+		return 0, fmt.Errorf("expected a single statement, found %d", len(statements))
+	}
+	if !statements[0][0].isKeyword("SELECT", "WITH", "UPDATE", "DELETE") {
+		return 0, fmt.Errorf("only SELECT, UPDATE and DELETE statements are analyzed")
+	}
+
+	params := 0
+	for _, t := range statements[0] {
+		if t.kind != tokParam {
+			continue
+		}
+		if n, err := strconv.Atoi(t.value[1:]); err == nil && n > params {
+			params = n
+		}
+	}
+	return params, nil
+}
+
+// explainNode is a node of an EXPLAIN (FORMAT JSON, VERBOSE) plan
+type explainNode struct {
+	NodeType     string        `json:"Node Type"`
+	RelationName string        `json:"Relation Name"`
+	Schema       string        `json:"Schema"`
+	Alias        string        `json:"Alias"`
+	IndexName    string        `json:"Index Name"`
+	TotalCost    float64       `json:"Total Cost"`
+	PlanRows     float64       `json:"Plan Rows"`
+	Filter       string        `json:"Filter"`
+	HashCond     string        `json:"Hash Cond"`
+	MergeCond    string        `json:"Merge Cond"`
+	JoinFilter   string        `json:"Join Filter"`
+	SortKey      []string      `json:"Sort Key"`
+	Plans        []explainNode `json:"Plans"`
+}
+
+// parseExplainJSON returns the root node of EXPLAIN (FORMAT JSON) output
+func parseExplainJSON(data []byte) (*explainNode, error) {
+	var output []struct {
+		Plan explainNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("invalid EXPLAIN output: %w", err)
+	}
+	if len(output) == 0 {
+		return nil, fmt.Errorf("empty EXPLAIN output")
+	}
+	return &output[0].Plan, nil
+}
+
+// walk calls fn for the node and its descendants with each node's parent
+func (n *explainNode) walk(parent *explainNode, fn func(node, parent *explainNode)) {
+	fn(n, parent)
+	for i := range n.Plans {
+		n.Plans[i].walk(n, fn)
+	}
+}
+
+// indexCandidate is a proposed B-tree index
+type indexCandidate struct {
+	Schema  string
+	Table   string
+	Columns []string // Unquoted column names
+	Reason  string
+	// ScanShare is the fraction of the plan's cost spent in the sequential
+	// scan the index would replace
+	ScanShare float64
+}
+
+// key identifies the index regardless of why it was proposed
+func (c *indexCandidate) key() string {
+	return c.Schema + "." + c.Table + "(" + strings.Join(c.Columns, ",") + ")"
+}
+
+// tableName returns the qualified, quoted table name
+func (c *indexCandidate) tableName() string {
+	return quoteIdentIfNeeded(c.Schema) + "." + quoteIdentIfNeeded(c.Table)
+}
+
+// statement returns the CREATE INDEX statement for the candidate
+func (c *indexCandidate) statement(concurrently bool) string {
+	columns := make([]string, len(c.Columns))
+	for i, col := range c.Columns {
+		columns[i] = quoteIdentIfNeeded(col)
+	}
+	create := "CREATE INDEX ON "
+	if concurrently {
+		create = "CREATE INDEX CONCURRENTLY ON "
+	}
+	return create + c.tableName() + " (" + strings.Join(columns, ", ") + ")"
+}
+
+// plainIdent matches identifiers that never need quoting
+var plainIdent = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// quoteIdentIfNeeded quotes an identifier only when PostgreSQL requires it
+func quoteIdentIfNeeded(name string) string {
+	if plainIdent.MatchString(name) && !sqlKeywords[strings.ToUpper(name)] {
+		return name
+	}
+	return quoteIdentifier(name)
+}
+
+// unquoteIdent reverses identifier quoting in plan output
+func unquoteIdent(ident string) string {
+	if len(ident) >= 2 && ident[0] == '"' && ident[len(ident)-1] == '"' {
+		return strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`)
+	}
+	return ident
+}
+
+// aliasColumnPattern matches a column qualified by alias, quoted or not,
+// capturing the column name
+func aliasColumnPattern(alias string) string {
+	quoted := regexp.QuoteMeta(alias)
+	escaped := regexp.QuoteMeta(strings.ReplaceAll(alias, `"`, `""`))
+	return `(?:` + quoted + `|"` + escaped + `")\.("(?:[^"]|"")+"|[A-Za-z_][\w$]*)`
+}
+
+// columnRefPattern matches column references qualified by alias in plan
+// expressions, such as o.status or ((o.status)::text, followed by the
+// comparison operator if there is one. The first group is a name followed
+// by "(" when the column is a function argument.
+func columnRefPattern(alias string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|\w\(|[^\w."])` + aliasColumnPattern(alias) + `\)*(?:::[\w ]+(?:\[\])?)?\s*(<>|<=|>=|=|<|>)?`)
+}
+
+// filterColumns returns the columns of alias compared for equality and by
+// range in a plan filter expression
+func filterColumns(expr, alias string) (equality, ranged []string) {
+	for _, match := range columnRefPattern(alias).FindAllStringSubmatch(expr, -1) {
+		// A B-tree index on the column does not help lower(col) = ...
+		if len(match[1]) == 2 {
+			continue
+		}
+		col := unquoteIdent(match[2])
+		switch match[3] {
+		case "=":
+			equality = appendUnique(equality, col)
+		case "<", ">", "<=", ">=":
+			ranged = appendUnique(ranged, col)
+		}
+	}
+	return equality, ranged
+}
+
+// referencedColumns returns every column of alias referenced in an expression
+func referencedColumns(expr, alias string) []string {
+	var columns []string
+	for _, match := range columnRefPattern(alias).FindAllStringSubmatch(expr, -1) {
+		columns = appendUnique(columns, unquoteIdent(match[2]))
+	}
+	return columns
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// sortKeyColumn returns the column of a sort key such as "o.created_at DESC"
+// if it is a plain column of alias
+func sortKeyColumn(key, alias string) (string, bool) {
+	pattern := regexp.MustCompile(`^` + aliasColumnPattern(alias) + `(?: DESC)?(?: NULLS (?:FIRST|LAST))?$`)
+	match := pattern.FindStringSubmatch(key)
+	if match == nil {
+		return "", false
+	}
+	return unquoteIdent(match[1]), true
+}
+
+// indexCandidates proposes indexes for the sequential scans in a plan:
+// the columns each scan filters on (equality columns first, then one range
+// column), its join columns, and the sort key of a top-N sort
+func indexCandidates(plan *explainNode) []indexCandidate {
+	type scan struct {
+		node     *explainNode
+		equality []string
+		ranged   []string
+	}
+	scans := make(map[string]*scan)
+	var aliases []string
+	plan.walk(nil, func(node, parent *explainNode) {
+		if node.NodeType != "Seq Scan" || node.RelationName == "" || node.Alias == "" {
+			return
+		}
+		if node.Schema == "pg_catalog" || node.Schema == "information_schema" {
+			return
+		}
+		if _, ok := scans[node.Alias]; ok {
+			return
+		}
+		s := &scan{node: node}
+		s.equality, s.ranged = filterColumns(node.Filter, node.Alias)
+		scans[node.Alias] = s
+		aliases = append(aliases, node.Alias)
+	})
+
+	var candidates []indexCandidate
+	add := func(s *scan, columns []string, reason string) {
+		if len(columns) == 0 {
+			return
+		}
+		if len(columns) > maxIndexColumns {
+			columns = columns[:maxIndexColumns]
+		}
+		share := 0.0
+		if plan.TotalCost > 0 {
+			share = s.node.TotalCost / plan.TotalCost
+		}
+		candidate := indexCandidate{
+			Schema:    s.node.Schema,
+			Table:     s.node.RelationName,
+			Columns:   columns,
+			Reason:    reason,
+			ScanShare: share,
+		}
+		for _, existing := range candidates {
+			if existing.key() == candidate.key() {
+				return
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	for _, alias := range aliases {
+		s := scans[alias]
+		columns := append([]string{}, s.equality...)
+		if len(s.ranged) > 0 {
+			columns = append(columns, s.ranged[0])
+		}
+		filtered := append(append([]string{}, s.equality...), s.ranged...)
+		add(s, columns, "filter on "+strings.Join(filtered, ", "))
+	}
+
+	plan.walk(nil, func(node, parent *explainNode) {
+		for _, cond := range []string{node.HashCond, node.MergeCond, node.JoinFilter} {
+			if cond == "" {
+				continue
+			}
+			for _, alias := range aliases {
+				for _, col := range referencedColumns(cond, alias) {
+					add(scans[alias], []string{col}, "join on "+col)
+				}
+			}
+		}
+
+		// A top-N sort can be replaced by reading an index in order
+		if node.NodeType != "Sort" || parent == nil || parent.NodeType != "Limit" || len(node.SortKey) == 0 {
+			return
+		}
+		for _, alias := range aliases {
+			var sortColumns []string
+			for _, key := range node.SortKey {
+				col, ok := sortKeyColumn(key, alias)
+				if !ok {
+					sortColumns = nil
+					break
+				}
+				sortColumns = append(sortColumns, col)
+			}
+			if len(sortColumns) == 0 {
+				continue
+			}
+			columns := append([]string{}, scans[alias].equality...)
+			for _, col := range sortColumns {
+				columns = appendUnique(columns, col)
+			}
+			add(scans[alias], columns, "ORDER BY "+strings.Join(sortColumns, ", ")+" with LIMIT")
+		}
+	})
+	return candidates
+}
+
+// coveredByIndex reports whether an existing index (given as its column
+// names; expressions are empty strings) starts with the candidate's columns
+func coveredByIndex(candidate indexCandidate, existing [][]string) bool {
+	for _, columns := range existing {
+		if len(columns) < len(candidate.Columns) {
+			continue
+		}
+		covered := true
+		for i, col := range candidate.Columns {
+			if columns[i] != col {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// queryBenefit is the effect of a proposed index on one query
+type queryBenefit struct {
+	Query      *advisorQuery
+	CostBefore float64
+	CostAfter  float64 // Zero when the index was not evaluated
+}
+
+// improvement is the fraction by which the index lowers the plan cost
+func (b queryBenefit) improvement() float64 {
+	if b.CostBefore <= 0 || b.CostAfter <= 0 {
+		return 0
+	}
+	return 1 - b.CostAfter/b.CostBefore
+}
+
+// indexRecommendation is a proposed index with the queries it helps
+type indexRecommendation struct {
+	Candidate indexCandidate
+	Verified  bool // Evaluated as a hypothetical index
+	Benefits  []queryBenefit
+	Score     float64
+}
+
+// indexAdvice collects the advisor's results
+type indexAdvice struct {
+	Source          string // Where the queries came from
+	Analyzed        int
+	Hypothetical    bool // Whether hypopg was used
+	Skipped         []string
+	Recommendations []*indexRecommendation
+}
+
+// add records a candidate's benefit for a query, merging candidates that
+// propose the same index
+func (a *indexAdvice) add(candidate indexCandidate, benefit queryBenefit, verified bool) {
+	score := benefit.Query.weight() * candidate.ScanShare
+	if verified {
+		score = benefit.Query.weight() * benefit.improvement()
+	}
+	for _, rec := range a.Recommendations {
+		if rec.Candidate.key() == candidate.key() {
+			rec.Benefits = append(rec.Benefits, benefit)
+			rec.Score += score
+			return
+		}
+	}
+	a.Recommendations = append(a.Recommendations, &indexRecommendation{
+		Candidate: candidate,
+		Verified:  verified,
+		Benefits:  []queryBenefit{benefit},
+		Score:     score,
+	})
+}
+
+// rank orders the recommendations by their estimated benefit
+func (a *indexAdvice) rank() {
+	sort.SliceStable(a.Recommendations, func(i, j int) bool {
+		return a.Recommendations[i].Score > a.Recommendations[j].Score
+	})
+}
+
+// formatIndexAdvice formats the advisor's results for the LLM
+func formatIndexAdvice(advice *indexAdvice) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Queries analyzed: %d (%s)\n", advice.Analyzed, advice.Source))
+	if advice.Hypothetical {
+		sb.WriteString("Benefits were estimated by planning each query with hypothetical indexes (hypopg).\n")
+	} else {
+		sb.WriteString("hypopg is not installed, so the recommendations are not verified. Install it (CREATE EXTENSION hypopg) to estimate each index's benefit.\n")
+	}
+
+	if len(advice.Recommendations) == 0 {
+		sb.WriteString("\nNo index recommendations: the analyzed queries already use indexes, or no index lowered their estimated cost.\n")
+	} else {
+		sb.WriteString("\nRecommendations:\n")
+	}
+	for i, rec := range advice.Recommendations {
+		sb.WriteString(fmt.Sprintf("\n%d. %s;\n", i+1, rec.Candidate.statement(true)))
+		sb.WriteString(fmt.Sprintf("   Reason: %s (sequential scan on %s)\n", rec.Candidate.Reason, rec.Candidate.tableName()))
+		sb.WriteString("   Queries:\n")
+		for _, benefit := range rec.Benefits {
+			sb.WriteString("   - " + statementPreview(benefit.Query.SQL) + "\n")
+			if benefit.Query.Calls > 0 {
+				sb.WriteString(fmt.Sprintf("     %d calls, %.1f ms total, %.2f ms mean\n",
+					benefit.Query.Calls, benefit.Query.TotalMs, benefit.Query.MeanMs))
+			}
+			if rec.Verified {
+				sb.WriteString(fmt.Sprintf("     Estimated cost: %.1f -> %.1f (%.1f%% lower)\n",
+					benefit.CostBefore, benefit.CostAfter, 100*benefit.improvement()))
+			} else {
+				sb.WriteString(fmt.Sprintf("     The sequential scan is %.1f%% of the plan's estimated cost (%.1f)\n",
+					100*rec.Candidate.ScanShare, benefit.CostBefore))
+			}
+		}
+	}
+
+	if len(advice.Skipped) > 0 {
+		sb.WriteString("\nQueries not analyzed:\n")
+		for _, skipped := range advice.Skipped {
+			sb.WriteString("- " + skipped + "\n")
+		}
+	}
+
+	sb.WriteString("\nEstimates come from the planner. Each index slows down writes and uses disk space; confirm the benefit with execute_explain after creating it, and create indexes CONCURRENTLY on busy tables.\n")
+	return sb.String()
+}
@@ -0,0 +1,182 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+// samplePlan is EXPLAIN (FORMAT JSON, VERBOSE) output for
+// SELECT o.id FROM orders o JOIN "Customers" c ON c.id = o.customer_id
+// WHERE o.status = $1 AND o.created_at > $2 ORDER BY o.created_at DESC LIMIT 10
+const samplePlan = `[
+  {
+    "Plan": {
+      "Node Type": "Limit",
+      "Total Cost": 2150.5,
+      "Plan Rows": 10,
+      "Plans": [
+        {
+          "Node Type": "Sort",
+          "Total Cost": 2150.4,
+          "Sort Key": ["o.created_at DESC"],
+          "Plans": [
+            {
+              "Node Type": "Hash Join",
+              "Join Type": "Inner",
+              "Total Cost": 2100.0,
+              "Hash Cond": "(o.customer_id = c.id)",
+              "Plans": [
+                {
+                  "Node Type": "Seq Scan",
+                  "Relation Name": "orders",
+                  "Schema": "public",
+                  "Alias": "o",
+                  "Total Cost": 2000.0,
+                  "Plan Rows": 120,
+                  "Filter": "(((o.status)::text = $1) AND (o.created_at > $2))"
+                },
+                {
+                  "Node Type": "Hash",
+                  "Total Cost": 40.0,
+                  "Plans": [
+                    {
+                      "Node Type": "Seq Scan",
+                      "Relation Name": "Customers",
+                      "Schema": "public",
+                      "Alias": "c",
+                      "Total Cost": 35.0,
+                      "Plan Rows": 1000
+                    }
+                  ]
+                }
+              ]
+            }
+          ]
+        }
+      ]
+    }
+  }
+]`
+
+func TestAdvisorQueryParams(t *testing.T) {
+	params, err := advisorQueryParams("SELECT * FROM orders WHERE id = $1 AND status = $12 AND note = '$3'")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params != 12 {
+		t.Errorf("expected 12 parameters, got %d", params)
+	}
+
+	for _, sql := range []string{
+		"INSERT INTO orders VALUES (1)",
+		"SELECT 1; SELECT 2",
+		"VACUUM orders",
+	} {
+		if _, err := advisorQueryParams(sql); err == nil {
+			t.Errorf("expected %q to be rejected", sql)
+		}
+	}
+}
+
+func TestIndexCandidates(t *testing.T) {
+	plan, err := parseExplainJSON([]byte(samplePlan))
+	if err != nil {
+		t.Fatalf("parseExplainJSON() error = %v", err)
+	}
+
+	candidates := indexCandidates(plan)
+	var got []string
+	for _, c := range candidates {
+		got = append(got, c.statement(false)+" -- "+c.Reason)
+	}
+	want := []string{
+		"CREATE INDEX ON public.orders (status, created_at) -- filter on status, created_at",
+		"CREATE INDEX ON public.orders (customer_id) -- join on customer_id",
+		`CREATE INDEX ON public."Customers" (id) -- join on id`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("candidates =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The scan on orders is almost the whole cost of the plan
+	if share := candidates[0].ScanShare; share < 0.9 || share > 0.95 {
+		t.Errorf("expected a scan share of about 0.93, got %f", share)
+	}
+}
+
+func TestIndexCandidates_TopNSort(t *testing.T) {
+	plan, err := parseExplainJSON([]byte(`[{"Plan": {
+		"Node Type": "Limit", "Total Cost": 500,
+		"Plans": [{"Node Type": "Sort", "Total Cost": 499, "Sort Key": ["e.\"Created At\" DESC"],
+			"Plans": [{"Node Type": "Seq Scan", "Relation Name": "events", "Schema": "app", "Alias": "e", "Total Cost": 400}]}]
+	}}]`))
+	if err != nil {
+		t.Fatalf("parseExplainJSON() error = %v", err)
+	}
+	candidates := indexCandidates(plan)
+	if len(candidates) != 1 || candidates[0].statement(true) != `CREATE INDEX CONCURRENTLY ON app.events ("Created At")` {
+		t.Fatalf("unexpected candidates: %+v", candidates)
+	}
+}
+
+func TestFilterColumns(t *testing.T) {
+	equality, ranged := filterColumns(`((o."Order Type" = ANY ('{a,b}'::text[])) AND (o.total >= 100::numeric) AND (lower(o.email) = 'x'::text) AND (o.note <> ''::text))`, "o")
+	if strings.Join(equality, ",") != "Order Type" {
+		t.Errorf("equality = %v", equality)
+	}
+	if strings.Join(ranged, ",") != "total" {
+		t.Errorf("ranged = %v", ranged)
+	}
+}
+
+func TestCoveredByIndex(t *testing.T) {
+	candidate := indexCandidate{Schema: "public", Table: "orders", Columns: []string{"status", "created_at"}}
+	if !coveredByIndex(candidate, [][]string{{"id"}, {"status", "created_at", "id"}}) {
+		t.Error("expected a longer index with the same leading columns to cover the candidate")
+	}
+	if coveredByIndex(candidate, [][]string{{"created_at", "status"}, {"status"}, {"", "created_at"}}) {
+		t.Error("expected indexes with other leading columns not to cover the candidate")
+	}
+}
+
+func TestFormatIndexAdvice(t *testing.T) {
+	heavy := &advisorQuery{SQL: "SELECT * FROM orders WHERE status = $1", Calls: 500, TotalMs: 9000, MeanMs: 18}
+	light := &advisorQuery{SQL: "SELECT * FROM events WHERE kind = $1", Calls: 10, TotalMs: 50, MeanMs: 5}
+	statusIdx := indexCandidate{Schema: "public", Table: "orders", Columns: []string{"status"}, Reason: "filter on status"}
+	kindIdx := indexCandidate{Schema: "public", Table: "events", Columns: []string{"kind"}, Reason: "filter on kind"}
+
+	advice := &indexAdvice{Source: "pg_stat_statements", Analyzed: 2, Hypothetical: true}
+	advice.add(kindIdx, queryBenefit{Query: light, CostBefore: 100, CostAfter: 10}, true)
+	advice.add(statusIdx, queryBenefit{Query: heavy, CostBefore: 2000, CostAfter: 500}, true)
+	advice.add(statusIdx, queryBenefit{Query: light, CostBefore: 100, CostAfter: 80}, true)
+	advice.rank()
+
+	if len(advice.Recommendations) != 2 || advice.Recommendations[0].Candidate.Table != "orders" {
+		t.Fatalf("expected the index for the heavier query first, got %+v", advice.Recommendations)
+	}
+	if len(advice.Recommendations[0].Benefits) != 2 {
+		t.Errorf("expected the same index to be merged across queries")
+	}
+
+	output := formatIndexAdvice(advice)
+	for _, want := range []string{
+		"1. CREATE INDEX CONCURRENTLY ON public.orders (status);",
+		"500 calls, 9000.0 ms total, 18.00 ms mean",
+		"Estimated cost: 2000.0 -> 500.0 (75.0% lower)",
+		"2. CREATE INDEX CONCURRENTLY ON public.events (kind);",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
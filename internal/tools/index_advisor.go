@@ -0,0 +1,408 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// IndexAdvisorTool creates the index_advisor tool, which recommends indexes
+// for the most expensive queries in pg_stat_statements or a given query
+func IndexAdvisorTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "index_advisor",
+			Description: `Recommend indexes for the most time-consuming queries (from
+pg_stat_statements) or for a given query, with their estimated benefit.
+
+<usecase>
+Use when:
+- The user asks which indexes would speed up the database or a query
+- A query plan shows sequential scans on large tables
+</usecase>
+
+<what_it_returns>
+Ranked CREATE INDEX statements, each with:
+- Why it was proposed (filter, join or ORDER BY ... LIMIT columns)
+- The queries it helps, with their calls and execution time
+- The estimated plan cost before and after, when the hypopg extension is
+  installed (hypothetical indexes are never built)
+</what_it_returns>
+
+<important>
+- Nothing is created; show the recommendations to the user, who decides
+  whether to apply them
+- Queries are planned with EXPLAIN, never executed
+- Without pg_stat_statements, pass the query to analyze
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Query to analyze. If omitted, the most time-consuming queries in pg_stat_statements are analyzed",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of pg_stat_statements queries to analyze (default: 10, max: 50)",
+						"default":     10,
+						"minimum":     1,
+						"maximum":     50,
+					},
+				},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			query := strings.TrimSpace(ValidateOptionalStringParam(args, "query", ""))
+			limit := int(ValidateOptionalNumberParam(args, "limit", 10))
+			if limit < 1 {
+				limit = 1
+			}
+			if limit > 50 {
+				limit = 50
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			// Prepared statements, planner settings and hypothetical indexes
+			// belong to the session, so the connection is not returned to the
+			// pool
+			pooled, err := pool.Acquire(ctx)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to acquire a connection: %v", err))
+			}
+			conn := pooled.Hijack()
+			defer conn.Close(context.Background())
+
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			defer func() {
+				_ = tx.Rollback(ctx) //nolint:errcheck // read-only transaction, nothing to keep
+			}()
+			if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to set transaction to read-only: %v", err))
+			}
+
+			advisor, err := newIndexAdvisor(ctx, tx)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			var queries []*advisorQuery
+			advice := &indexAdvice{Hypothetical: advisor.hypopgSchema != ""}
+			if query != "" {
+				queries = []*advisorQuery{{SQL: query}}
+				advice.Source = "the given query"
+			} else {
+				queries, err = advisor.topQueries(ctx, limit)
+				if err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+				advice.Source = "the most time-consuming queries in pg_stat_statements"
+			}
+
+			progress := progressFromArgs(args)
+			for i, q := range queries {
+				progress.Report(float64(i), float64(len(queries)), fmt.Sprintf("Analyzing query %d of %d", i+1, len(queries)))
+				if err := advisor.analyze(ctx, q, advice); err != nil {
+					if query != "" {
+						return mcp.NewToolError(fmt.Sprintf("Could not analyze the query: %v", err))
+					}
+					advice.Skipped = append(advice.Skipped, fmt.Sprintf("%s (%v)", statementPreview(q.SQL), err))
+					continue
+				}
+				advice.Analyzed++
+			}
+			advice.rank()
+
+			logging.Info("index_advisor_executed",
+				"queries", len(queries),
+				"analyzed", advice.Analyzed,
+				"recommendations", len(advice.Recommendations),
+				"hypopg", advice.Hypothetical,
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatIndexAdvice(advice))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// indexAdvisor plans queries on one connection inside a read-only transaction
+type indexAdvisor struct {
+	tx            pgx.Tx
+	serverVersion int
+	hypopgSchema  string // Empty if hypopg is not installed
+	statsSchema   string // Schema of pg_stat_statements; empty if not installed
+	// existing caches the column lists of each table's indexes
+	existing map[string][][]string
+	nextName int
+}
+
+func newIndexAdvisor(ctx context.Context, tx pgx.Tx) (*indexAdvisor, error) {
+	a := &indexAdvisor{tx: tx, existing: make(map[string][][]string)}
+	if err := tx.QueryRow(ctx, "SELECT current_setting('server_version_num')::int").Scan(&a.serverVersion); err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT e.extname::text, n.nspname::text
+		FROM pg_catalog.pg_extension e
+		JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace
+		WHERE e.extname IN ('hypopg', 'pg_stat_statements')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list extensions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, schema string
+		if err := rows.Scan(&name, &schema); err != nil {
+			return nil, fmt.Errorf("failed to list extensions: %w", err)
+		}
+		if name == "hypopg" {
+			a.hypopgSchema = quoteIdentIfNeeded(schema)
+		} else {
+			a.statsSchema = quoteIdentIfNeeded(schema)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list extensions: %w", err)
+	}
+
+	// Queries from pg_stat_statements have parameters; a generic plan shows
+	// how they are planned for any value
+	if a.serverVersion >= 120000 {
+		if _, err := tx.Exec(ctx, "SET LOCAL plan_cache_mode = force_generic_plan"); err != nil {
+			return nil, fmt.Errorf("failed to configure the planner: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// topQueries returns the queries in the current database with the highest
+// total execution time
+func (a *indexAdvisor) topQueries(ctx context.Context, limit int) ([]*advisorQuery, error) {
+	if a.statsSchema == "" {
+		return nil, fmt.Errorf("pg_stat_statements is not installed in this database. Pass the query to analyze, or install the extension (CREATE EXTENSION pg_stat_statements, with pg_stat_statements in shared_preload_libraries)")
+	}
+
+	// The timing columns were renamed in PostgreSQL 13
+	total, mean := "total_exec_time", "mean_exec_time"
+	if a.serverVersion < 130000 {
+		total, mean = "total_time", "mean_time"
+	}
+	rows, err := a.tx.Query(ctx, fmt.Sprintf(`
+		SELECT query, calls, %[1]s, %[2]s
+		FROM %[3]s.pg_stat_statements
+		WHERE dbid = (SELECT oid FROM pg_catalog.pg_database WHERE datname = current_database())
+			AND query ~* '^\s*(select|with|update|delete)\M'
+		ORDER BY %[1]s DESC
+		LIMIT $1`, total, mean, a.statsSchema), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pg_stat_statements: %w", err)
+	}
+	defer rows.Close()
+
+	var queries []*advisorQuery
+	for rows.Next() {
+		q := &advisorQuery{}
+		if err := rows.Scan(&q.SQL, &q.Calls, &q.TotalMs, &q.MeanMs); err != nil {
+			return nil, fmt.Errorf("failed to read pg_stat_statements: %w", err)
+		}
+		queries = append(queries, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pg_stat_statements: %w", err)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("pg_stat_statements has no SELECT, UPDATE or DELETE queries for this database; the statistics may have been reset, or the user may lack pg_read_all_stats. Pass the query to analyze instead")
+	}
+	return queries, nil
+}
+
+// analyze proposes indexes for one query and records those that help
+// Failures roll back to a savepoint so the other queries can be analyzed
+func (a *indexAdvisor) analyze(ctx context.Context, q *advisorQuery, advice *indexAdvice) error {
+	params, err := advisorQueryParams(q.SQL)
+	if err != nil {
+		return err
+	}
+	if params > 0 && a.serverVersion < 120000 {
+		return fmt.Errorf("queries with parameters need PostgreSQL 12 or later")
+	}
+
+	return a.withSavepoint(ctx, "index_advisor_query", func() error {
+		if err := a.resetHypothetical(ctx); err != nil {
+			return err
+		}
+		plan, err := a.explain(ctx, q.SQL, params)
+		if err != nil {
+			return err
+		}
+
+		for _, candidate := range indexCandidates(plan) {
+			existing, err := a.existingIndexes(ctx, candidate.tableName())
+			if err != nil {
+				return err
+			}
+			if coveredByIndex(candidate, existing) {
+				continue
+			}
+
+			benefit := queryBenefit{Query: q, CostBefore: plan.TotalCost}
+			if a.hypopgSchema == "" {
+				if candidate.ScanShare >= minIndexImprovement {
+					advice.add(candidate, benefit, false)
+				}
+				continue
+			}
+
+			// A candidate the planner cannot use (for example a column type
+			// without a B-tree operator class) is skipped
+			_ = a.withSavepoint(ctx, "index_advisor_candidate", func() error { //nolint:errcheck // unusable candidates are skipped
+				benefit.CostAfter, err = a.hypotheticalCost(ctx, q.SQL, params, candidate)
+				return err
+			})
+			if benefit.improvement() >= minIndexImprovement {
+				advice.add(candidate, benefit, true)
+			}
+		}
+		return nil
+	})
+}
+
+// withSavepoint runs fn in a savepoint that is rolled back if fn fails
+func (a *indexAdvisor) withSavepoint(ctx context.Context, name string, fn func() error) error {
+	if _, err := a.tx.Exec(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := a.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w; failed to roll back to savepoint: %v", err, rbErr)
+		}
+		return err
+	}
+	_, err := a.tx.Exec(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// explain plans a query without executing it. Queries with parameters are
+// prepared and explained with NULL arguments; with plan_cache_mode set to
+// force_generic_plan the values do not affect the plan.
+func (a *indexAdvisor) explain(ctx context.Context, sql string, params int) (*explainNode, error) {
+	var data []byte
+	if params == 0 {
+		if err := a.tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON, VERBOSE) "+sql).Scan(&data); err != nil {
+			return nil, err
+		}
+		return parseExplainJSON(data)
+	}
+
+	a.nextName++
+	name := fmt.Sprintf("index_advisor_%d", a.nextName)
+	if _, err := a.tx.Exec(ctx, "PREPARE "+name+" AS "+sql); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_, _ = a.tx.Exec(ctx, "DEALLOCATE "+name) //nolint:errcheck // the connection is closed afterwards anyway
+	}()
+
+	nulls := strings.TrimSuffix(strings.Repeat("NULL, ", params), ", ")
+	if err := a.tx.QueryRow(ctx, fmt.Sprintf("EXPLAIN (FORMAT JSON, VERBOSE) EXECUTE %s(%s)", name, nulls)).Scan(&data); err != nil {
+		return nil, err
+	}
+	return parseExplainJSON(data)
+}
+
+// hypotheticalCost returns the query's plan cost with a hypothetical index
+func (a *indexAdvisor) hypotheticalCost(ctx context.Context, sql string, params int, candidate indexCandidate) (float64, error) {
+	if err := a.resetHypothetical(ctx); err != nil {
+		return 0, err
+	}
+	if _, err := a.tx.Exec(ctx, fmt.Sprintf("SELECT %s.hypopg_create_index($1)", a.hypopgSchema), candidate.statement(false)); err != nil {
+		return 0, err
+	}
+
+	plan, err := a.explain(ctx, sql, params)
+	if err != nil {
+		return 0, err
+	}
+	return plan.TotalCost, nil
+}
+
+// resetHypothetical removes the session's hypothetical indexes; they are
+// not transactional, so one left by a failed evaluation would otherwise
+// affect the next plan
+func (a *indexAdvisor) resetHypothetical(ctx context.Context) error {
+	if a.hypopgSchema == "" {
+		return nil
+	}
+	_, err := a.tx.Exec(ctx, fmt.Sprintf("SELECT %s.hypopg_reset()", a.hypopgSchema))
+	return err
+}
+
+// existingIndexes returns the column lists of a table's non-partial indexes
+// Expression columns are returned as empty strings
+func (a *indexAdvisor) existingIndexes(ctx context.Context, table string) ([][]string, error) {
+	if indexes, ok := a.existing[table]; ok {
+		return indexes, nil
+	}
+
+	rows, err := a.tx.Query(ctx, `
+		SELECT array_agg(coalesce(a.attname::text, '') ORDER BY k.ord)
+		FROM pg_catalog.pg_index x
+		CROSS JOIN LATERAL unnest(x.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = x.indrelid AND a.attnum = k.attnum
+		WHERE x.indrelid = to_regclass($1) AND x.indpred IS NULL
+		GROUP BY x.indexrelid`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the indexes of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var indexes [][]string
+	for rows.Next() {
+		var columns []string
+		if err := rows.Scan(&columns); err != nil {
+			return nil, fmt.Errorf("failed to read the indexes of %s: %w", table, err)
+		}
+		indexes = append(indexes, columns)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the indexes of %s: %w", table, err)
+	}
+	a.existing[table] = indexes
+	return indexes, nil
+}
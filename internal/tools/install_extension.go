@@ -0,0 +1,219 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// InstallExtensionTool creates the install_extension tool, which installs
+// an extension listed in extensions.allowed with CREATE EXTENSION
+// The tool writes to the database, so it is disabled unless enabled in the
+// configuration
+func InstallExtensionTool(dbClient *database.Client, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "install_extension",
+			Description: `Install a PostgreSQL extension in the database with CREATE EXTENSION.
+
+<usecase>
+Use when:
+- Semantic search needs pgvector: install the "vector" extension before
+  creating vector columns
+- A query or schema change needs an extension that pg://extensions lists as
+  available but not installed
+</usecase>
+
+<behavior>
+- Only extensions allowed by the server configuration can be installed; the
+  error lists them
+- Extensions the extension requires are installed too (CASCADE), when they
+  are allowed as well
+- An extension that is already installed is left alone; its version is
+  reported
+</behavior>
+
+<important>
+- Confirm with the user before installing: extensions add types, functions
+  and operators to the database
+- The extension's package must already be installed on the database server;
+  read pg://extensions to see what is available
+- Many extensions need a superuser, or a trusted extension and the CREATE
+  privilege on the database
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the extension, e.g. 'vector' for pgvector",
+					},
+					"schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema to install the extension's objects in (default: the first schema of the search path)",
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			name, errResp := ValidateStringParam(args, "name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			schema := ValidateOptionalStringParam(args, "schema", "")
+
+			if !cfg.Extensions.IsAllowed(name) {
+				return mcp.NewToolError(fmt.Sprintf("Extension %q is not allowed by the server configuration (extensions.allowed: %s)",
+					name, strings.Join(cfg.Extensions.Allowed, ", ")))
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+
+			var defaultVersion string
+			var installedVersion *string
+			err := pool.QueryRow(ctx,
+				"SELECT default_version, installed_version FROM pg_catalog.pg_available_extensions WHERE name = $1",
+				name).Scan(&defaultVersion, &installedVersion)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return mcp.NewToolError(fmt.Sprintf("Extension %q is not available on the database server; its package must be installed on the server first (read pg://extensions for the available extensions)", name))
+			}
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to look up the extension: %v", err))
+			}
+			if installedVersion != nil {
+				sb.WriteString(fmt.Sprintf("Extension %s is already installed (version %s).\n", name, *installedVersion))
+				if *installedVersion != defaultVersion {
+					sb.WriteString(fmt.Sprintf("Version %s is available; ALTER EXTENSION %s UPDATE installs it.\n", defaultVersion, quoteIdentifier(name)))
+				}
+				return mcp.NewToolSuccess(sb.String())
+			}
+
+			required, err := missingRequiredExtensions(ctx, pool, name)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			var disallowed []string
+			for _, ext := range required {
+				if !cfg.Extensions.IsAllowed(ext) {
+					disallowed = append(disallowed, ext)
+				}
+			}
+			if len(disallowed) > 0 {
+				return mcp.NewToolError(fmt.Sprintf("Extension %s requires %s, which the server configuration does not allow to be installed (extensions.allowed)",
+					name, strings.Join(disallowed, ", ")))
+			}
+
+			stmt := createExtensionStatement(name, schema)
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			defer func() {
+				_ = tx.Rollback(ctx) //nolint:errcheck // no-op after a successful commit
+			}()
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to install the extension: %v\nStatement: %s", err, stmt))
+			}
+			var version, installedSchema string
+			if err := tx.QueryRow(ctx,
+				"SELECT extversion, extnamespace::regnamespace::text FROM pg_catalog.pg_extension WHERE extname = $1",
+				name).Scan(&version, &installedSchema); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to read the installed extension: %v", err))
+			}
+			if err := tx.Commit(ctx); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Commit failed, the extension was not installed: %v", err))
+			}
+
+			logging.Info("install_extension_executed",
+				"extension", name,
+				"version", version,
+				"schema", installedSchema,
+				"required", len(required),
+			)
+
+			sb.WriteString(fmt.Sprintf("Installed extension %s version %s in schema %s.\n", name, version, installedSchema))
+			if len(required) > 0 {
+				sb.WriteString(fmt.Sprintf("Also installed the extensions it requires: %s\n", strings.Join(required, ", ")))
+			}
+			sb.WriteString("\n" + stmt + ";\n")
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// createExtensionStatement returns the statement that installs an extension
+// and the extensions it requires
+func createExtensionStatement(name, schema string) string {
+	stmt := "CREATE EXTENSION IF NOT EXISTS " + quoteIdentifier(name)
+	if schema != "" {
+		stmt += " WITH SCHEMA " + quoteIdentifier(schema)
+	}
+	return stmt + " CASCADE"
+}
+
+// missingRequiredExtensions returns the extensions CASCADE would install
+// with an extension: those its default version requires, directly or
+// through other extensions, that are not installed
+func missingRequiredExtensions(ctx context.Context, pool *pgxpool.Pool, name string) ([]string, error) {
+	rows, err := pool.Query(ctx, `
+		WITH RECURSIVE required(name) AS (
+			SELECT unnest(v.requires)::text
+			FROM pg_catalog.pg_available_extensions a
+			JOIN pg_catalog.pg_available_extension_versions v
+				ON v.name = a.name AND v.version = a.default_version
+			WHERE a.name = $1
+			UNION
+			SELECT unnest(v.requires)::text
+			FROM required r
+			JOIN pg_catalog.pg_available_extensions a ON a.name = r.name
+			JOIN pg_catalog.pg_available_extension_versions v
+				ON v.name = a.name AND v.version = a.default_version
+		)
+		SELECT r.name FROM required r
+		WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_extension e WHERE e.extname = r.name)
+		ORDER BY r.name
+	`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the extensions %s requires: %w", name, err)
+	}
+	required, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the extensions %s requires: %w", name, err)
+	}
+	return required, nil
+}
@@ -0,0 +1,50 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+func TestCreateExtensionStatement(t *testing.T) {
+	tests := []struct {
+		name, schema, want string
+	}{
+		{"vector", "", `CREATE EXTENSION IF NOT EXISTS "vector" CASCADE`},
+		{"uuid-ossp", "extensions", `CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA "extensions" CASCADE`},
+	}
+	for _, tt := range tests {
+		if got := createExtensionStatement(tt.name, tt.schema); got != tt.want {
+			t.Errorf("createExtensionStatement(%q, %q) = %q, want %q", tt.name, tt.schema, got, tt.want)
+		}
+	}
+}
+
+func TestInstallExtensionTool_NotAllowed(t *testing.T) {
+	cfg := &config.Config{Extensions: config.ExtensionsConfig{Allowed: []string{"vector", "pg_trgm"}}}
+	tool := InstallExtensionTool(nil, cfg)
+
+	resp, err := tool.Handler(map[string]interface{}{"name": "plpython3u"})
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	if !resp.IsError || !strings.Contains(resp.Content[0].Text, "extensions.allowed: vector, pg_trgm") {
+		t.Errorf("expected a not allowed error, got %+v", resp)
+	}
+
+	resp, _ = tool.Handler(map[string]interface{}{})
+	if !resp.IsError {
+		t.Errorf("expected an error without a name, got %+v", resp)
+	}
+}
@@ -0,0 +1,462 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/kbtypes"
+)
+
+// kbProduct is a project version in the knowledgebase
+type kbProduct struct {
+	Name    string
+	Version string
+	Chunks  int
+}
+
+// kbCheckTimeout bounds a knowledgebase availability check
+const kbCheckTimeout = 10 * time.Second
+
+// kbBackend reads a knowledgebase built by kb-builder
+type kbBackend interface {
+	// check reports why the knowledgebase can't be searched, or nil
+	check(ctx context.Context) error
+	// embeddingModels returns the providers with embeddings in the
+	// knowledgebase, with the model kb-builder recorded for each ("" for
+	// knowledgebases built before models were recorded)
+	embeddingModels(ctx context.Context) ([]kbtypes.EmbeddingModel, error)
+	products(ctx context.Context) ([]kbProduct, error)
+	search(ctx context.Context, queryEmbedding []float32, provider string, projectNames, projectVersions []string, topN int) ([]KBSearchResult, error)
+}
+
+// newKBBackend returns the backend for the configured knowledgebase
+func newKBBackend(ctx context.Context, cfg config.KnowledgebaseConfig) (kbBackend, error) {
+	if !cfg.UsesPostgres() {
+		return sqliteKB{path: cfg.DatabasePath}, nil
+	}
+	pool, err := kbPostgresPool(ctx, cfg.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	schema := cfg.Schema
+	if schema == "" {
+		schema = "pgedge_kb"
+	}
+	return postgresKB{pool: pool, schema: schema}, nil
+}
+
+// checkKnowledgebase reports why the configured knowledgebase can't be
+// searched, or nil if it can or search_knowledgebase isn't in use. It returns
+// the knowledgebase settings with the embedding provider and model resolved
+// from the embeddings the knowledgebase contains.
+func checkKnowledgebase(cfg *config.Config) (config.KnowledgebaseConfig, error) {
+	if !kbInUse(cfg) {
+		return cfg.Knowledgebase, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kbCheckTimeout)
+	defer cancel()
+
+	kb, err := newKBBackend(ctx, cfg.Knowledgebase)
+	if err != nil {
+		return cfg.Knowledgebase, err
+	}
+	if err := kb.check(ctx); err != nil {
+		return cfg.Knowledgebase, err
+	}
+	models, err := kb.embeddingModels(ctx)
+	if err != nil {
+		return cfg.Knowledgebase, fmt.Errorf("failed to read the knowledgebase's embedding models: %w", err)
+	}
+	return resolveKBEmbedding(cfg, models)
+}
+
+// kbProviderPreference is the order in which automatic selection considers
+// the providers a knowledgebase has embeddings for
+var kbProviderPreference = []string{"voyage", "openai", "ollama"}
+
+// resolveKBEmbedding chooses the embedding provider and model used for
+// queries from those the knowledgebase contains. A configured provider must
+// be one of them; with "auto" (or none) the first usable one is chosen,
+// skipping cloud providers in offline mode and those without an API key. A
+// configured model must match the one recorded by kb-builder.
+func resolveKBEmbedding(cfg *config.Config, models []kbtypes.EmbeddingModel) (config.KnowledgebaseConfig, error) {
+	kbCfg := cfg.Knowledgebase
+	byProvider := make(map[string]kbtypes.EmbeddingModel, len(models))
+	for _, m := range models {
+		byProvider[m.Provider] = m
+	}
+	if len(models) == 0 {
+		return kbCfg, fmt.Errorf("the knowledgebase contains no embeddings; build it with at least one embedding provider enabled")
+	}
+
+	if kbCfg.EmbeddingProvider != "" && kbCfg.EmbeddingProvider != config.KnowledgebaseProviderAuto {
+		m, ok := byProvider[kbCfg.EmbeddingProvider]
+		if !ok {
+			return kbCfg, fmt.Errorf("knowledgebase.embedding_provider is %s, but the knowledgebase only contains embeddings from %s; "+
+				"set it to one of these or to auto", kbCfg.EmbeddingProvider, describeKBModels(models))
+		}
+		if err := checkKBModel(kbCfg.EmbeddingModel, m); err != nil {
+			return kbCfg, err
+		}
+		if kbCfg.EmbeddingModel == "" {
+			kbCfg.EmbeddingModel = m.Model
+		}
+		return kbCfg, nil
+	}
+
+	var skipped []string
+	for _, provider := range kbProviderPreference {
+		m, ok := byProvider[provider]
+		if !ok {
+			continue
+		}
+		var reason string
+		switch {
+		case cfg.Offline && config.IsCloudProvider(provider):
+			reason = "offline mode"
+		case provider == "voyage" && kbCfg.EmbeddingVoyageAPIKey == "":
+			reason = "no Voyage API key"
+		case provider == "openai" && kbCfg.EmbeddingOpenAIAPIKey == "":
+			reason = "no OpenAI API key"
+		default:
+			if err := checkKBModel(kbCfg.EmbeddingModel, m); err != nil {
+				reason = fmt.Sprintf("model %s, not %s", m.Model, kbCfg.EmbeddingModel)
+			}
+		}
+		if reason != "" {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", provider, reason))
+			continue
+		}
+
+		kbCfg.EmbeddingProvider = m.Provider
+		if kbCfg.EmbeddingModel == "" {
+			kbCfg.EmbeddingModel = m.Model
+		}
+		return kbCfg, nil
+	}
+	return kbCfg, fmt.Errorf("no usable embedding provider for the knowledgebase, which contains embeddings from %s",
+		strings.Join(skipped, ", "))
+}
+
+// checkKBModel reports a configured model that differs from the one that
+// produced the knowledgebase's embeddings, whose vectors wouldn't be
+// comparable. Either being unknown ("") is accepted.
+func checkKBModel(configured string, m kbtypes.EmbeddingModel) error {
+	if configured == "" || m.Model == "" || configured == m.Model {
+		return nil
+	}
+	return fmt.Errorf("knowledgebase.embedding_model is %s, but the knowledgebase's %s embeddings were made with %s",
+		configured, m.Provider, m.Model)
+}
+
+// describeKBModels lists the providers and models of a knowledgebase
+func describeKBModels(models []kbtypes.EmbeddingModel) string {
+	descriptions := make([]string, 0, len(models))
+	for _, m := range models {
+		if m.Model != "" {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%s)", m.Provider, m.Model))
+		} else {
+			descriptions = append(descriptions, m.Provider)
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// kbProviderColumns maps each provider to its chunks embedding column
+var kbProviderColumns = []struct{ provider, column string }{
+	{"ollama", "ollama_embedding"},
+	{"openai", "openai_embedding"},
+	{"voyage", "voyage_embedding"},
+}
+
+// mergeKBModels combines the providers found to have embeddings with the
+// models recorded for them, in provider order
+func mergeKBModels(present map[string]bool, recorded []kbtypes.EmbeddingModel) []kbtypes.EmbeddingModel {
+	byProvider := make(map[string]kbtypes.EmbeddingModel, len(recorded))
+	for _, m := range recorded {
+		byProvider[m.Provider] = m
+	}
+	var models []kbtypes.EmbeddingModel
+	for _, pc := range kbProviderColumns {
+		if !present[pc.provider] {
+			continue
+		}
+		m, ok := byProvider[pc.provider]
+		if !ok {
+			m = kbtypes.EmbeddingModel{Provider: pc.provider}
+		}
+		models = append(models, m)
+	}
+	return models
+}
+
+// kbInUse reports whether search_knowledgebase is enabled and configured
+func kbInUse(cfg *config.Config) bool {
+	return cfg.Knowledgebase.Enabled && cfg.Knowledgebase.IsConfigured() &&
+		cfg.IsToolAvailable("search_knowledgebase")
+}
+
+// sqliteKB is a knowledgebase in a SQLite file, opened for each call so a
+// rebuilt file is picked up
+type sqliteKB struct {
+	path string
+}
+
+// check opens the file read-only, so a missing file isn't created, and
+// verifies its integrity. A file locked by kb-builder for longer than the
+// busy timeout is reported as unavailable.
+func (kb sqliteKB) check(ctx context.Context) error {
+	info, err := os.Stat(kb.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("knowledgebase file %s does not exist", kb.path)
+		}
+		return fmt.Errorf("cannot access knowledgebase file: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("knowledgebase path %s is a directory", kb.path)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+kb.path+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return fmt.Errorf("failed to open knowledgebase: %w", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("failed to check knowledgebase %s: %w", kb.path, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("knowledgebase %s is corrupt: %s", kb.path, result)
+	}
+
+	var tables int
+	if err := db.QueryRowContext(ctx,
+		"SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'chunks'").Scan(&tables); err != nil {
+		return fmt.Errorf("failed to read knowledgebase %s: %w", kb.path, err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("%s is not a knowledgebase built by kb-builder (no chunks table)", kb.path)
+	}
+	return nil
+}
+
+func (kb sqliteKB) embeddingModels(ctx context.Context) ([]kbtypes.EmbeddingModel, error) {
+	db, err := sql.Open("sqlite3", "file:"+kb.path+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	present := make(map[string]bool)
+	for _, pc := range kbProviderColumns {
+		var exists bool
+		if err := db.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM chunks WHERE "+pc.column+" IS NOT NULL)").Scan(&exists); err != nil {
+			return nil, err
+		}
+		present[pc.provider] = exists
+	}
+
+	var tables int
+	if err := db.QueryRowContext(ctx,
+		"SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'embedding_models'").Scan(&tables); err != nil {
+		return nil, err
+	}
+	var recorded []kbtypes.EmbeddingModel
+	if tables > 0 {
+		rows, err := db.QueryContext(ctx, "SELECT provider, model, dimensions FROM embedding_models")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var m kbtypes.EmbeddingModel
+			if err := rows.Scan(&m.Provider, &m.Model, &m.Dimensions); err != nil {
+				return nil, err
+			}
+			recorded = append(recorded, m)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return mergeKBModels(present, recorded), nil
+}
+
+func (kb sqliteKB) products(ctx context.Context) ([]kbProduct, error) {
+	return listKBProducts(kb.path)
+}
+
+func (kb sqliteKB) search(ctx context.Context, queryEmbedding []float32, provider string, projectNames, projectVersions []string, topN int) ([]KBSearchResult, error) {
+	return searchKB(kb.path, queryEmbedding, projectNames, projectVersions, topN, provider)
+}
+
+// postgresKB is a knowledgebase in a PostgreSQL schema, searched with
+// pgvector. Changes made by kb-builder are visible immediately.
+type postgresKB struct {
+	pool   *pgxpool.Pool
+	schema string
+}
+
+// kbPools holds a connection pool per knowledgebase connection string,
+// shared by all calls
+var kbPools = struct {
+	sync.Mutex
+	pools map[string]*pgxpool.Pool
+}{pools: make(map[string]*pgxpool.Pool)}
+
+// kbPostgresPool returns the read-only pool for a knowledgebase database
+func kbPostgresPool(ctx context.Context, connString string) (*pgxpool.Pool, error) {
+	kbPools.Lock()
+	defer kbPools.Unlock()
+
+	if pool, ok := kbPools.pools[connString]; ok {
+		return pool, nil
+	}
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid knowledgebase connection string: %w", err)
+	}
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = "pgedge-postgres-mcp knowledgebase"
+	poolConfig.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the knowledgebase: %w", err)
+	}
+	kbPools.pools[connString] = pool
+	return pool, nil
+}
+
+func (kb postgresKB) check(ctx context.Context) error {
+	var exists bool
+	table := pgx.Identifier{kb.schema, "chunks"}.Sanitize()
+	if err := kb.pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to query the knowledgebase: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("knowledgebase table %s does not exist", table)
+	}
+	return nil
+}
+
+func (kb postgresKB) embeddingModels(ctx context.Context) ([]kbtypes.EmbeddingModel, error) {
+	present := make(map[string]bool)
+	chunks := pgx.Identifier{kb.schema, "chunks"}.Sanitize()
+	for _, pc := range kbProviderColumns {
+		var exists bool
+		if err := kb.pool.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM "+chunks+" WHERE "+pc.column+" IS NOT NULL)").Scan(&exists); err != nil {
+			return nil, err
+		}
+		present[pc.provider] = exists
+	}
+
+	var tableExists bool
+	table := pgx.Identifier{kb.schema, "embedding_models"}.Sanitize()
+	if err := kb.pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&tableExists); err != nil {
+		return nil, err
+	}
+	var recorded []kbtypes.EmbeddingModel
+	if tableExists {
+		rows, err := kb.pool.Query(ctx, "SELECT provider, model, dimensions FROM "+table)
+		if err != nil {
+			return nil, err
+		}
+		recorded, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (kbtypes.EmbeddingModel, error) {
+			var m kbtypes.EmbeddingModel
+			err := row.Scan(&m.Provider, &m.Model, &m.Dimensions)
+			return m, err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mergeKBModels(present, recorded), nil
+}
+
+func (kb postgresKB) products(ctx context.Context) ([]kbProduct, error) {
+	rows, err := kb.pool.Query(ctx, `
+        SELECT project_name, project_version, count(*)
+        FROM `+pgx.Identifier{kb.schema, "chunks"}.Sanitize()+`
+        GROUP BY project_name, project_version
+        ORDER BY project_name, project_version
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (kbProduct, error) {
+		var p kbProduct
+		err := row.Scan(&p.Name, &p.Version, &p.Chunks)
+		return p, err
+	})
+}
+
+func (kb postgresKB) search(ctx context.Context, queryEmbedding []float32, provider string, projectNames, projectVersions []string, topN int) ([]KBSearchResult, error) {
+	// Unlike the SQLite search, chunks without an embedding from the query's
+	// provider are skipped: other providers' vectors aren't comparable
+	column := kbEmbeddingColumn(provider)
+	vector := make([]float64, len(queryEmbedding))
+	for i, v := range queryEmbedding {
+		vector[i] = float64(v)
+	}
+
+	query := `
+        SELECT text, coalesce(title, ''), coalesce(section, ''), project_name, project_version,
+               coalesce(file_path, ''), 1 - (` + column + ` <=> $1::vector)
+        FROM ` + pgx.Identifier{kb.schema, "chunks"}.Sanitize() + `
+        WHERE ` + column + ` IS NOT NULL AND vector_dims(` + column + `) = $2`
+	args := []interface{}{formatEmbeddingForPostgres(vector), len(vector)}
+	if len(projectNames) > 0 {
+		args = append(args, projectNames)
+		query += fmt.Sprintf(" AND project_name = ANY($%d)", len(args))
+	}
+	if len(projectVersions) > 0 {
+		args = append(args, projectVersions)
+		query += fmt.Sprintf(" AND project_version = ANY($%d)", len(args))
+	}
+	args = append(args, topN)
+	query += fmt.Sprintf(" ORDER BY %s <=> $1::vector LIMIT $%d", column, len(args))
+
+	rows, err := kb.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (KBSearchResult, error) {
+		var r KBSearchResult
+		err := row.Scan(&r.Text, &r.Title, &r.Section, &r.ProjectName, &r.ProjectVersion, &r.FilePath, &r.Similarity)
+		return r, err
+	})
+}
+
+// kbEmbeddingColumn returns the chunks column holding a provider's embeddings
+func kbEmbeddingColumn(provider string) string {
+	switch strings.ToLower(provider) {
+	case "voyage":
+		return "voyage_embedding"
+	case "ollama":
+		return "ollama_embedding"
+	default: // openai
+		return "openai_embedding"
+	}
+}
@@ -0,0 +1,95 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/netproxy"
+	"pgedge-postgres-mcp/internal/rerank"
+)
+
+// newReranker creates rerankers; replaced in tests
+var newReranker = rerank.NewReranker
+
+// kbRerankCandidates returns how many vector search results to fetch for
+// topN results: all of them are reranked, so more candidates than results
+// give the reranker room to promote chunks the vector search ranked lower
+func kbRerankCandidates(rr config.KnowledgebaseRerankConfig, topN int) int {
+	if !rr.Enabled {
+		return topN
+	}
+	candidates := rr.Candidates
+	if candidates <= 0 {
+		candidates = 20
+	}
+	if candidates < topN {
+		candidates = topN
+	}
+	return candidates
+}
+
+// rerankKBResults reorders search results by the reranker's relevance
+// scores and keeps the topN most relevant. It also returns a note on how the
+// results were ordered. When reranking isn't possible the results keep their
+// vector similarity order, as a slower or failed reranker shouldn't make
+// the search fail.
+func rerankKBResults(ctx context.Context, rr config.KnowledgebaseRerankConfig, query string, results []KBSearchResult, topN int) ([]KBSearchResult, string) {
+	if !rr.Enabled || len(results) == 0 {
+		return truncateKBResults(results, topN), ""
+	}
+	if netproxy.IsOffline() && config.IsCloudProvider(rr.Provider) {
+		return truncateKBResults(results, topN), "Not reranked (offline mode); ordered by vector similarity"
+	}
+
+	reranker, err := newReranker(rerank.Config{
+		Provider: rr.Provider,
+		Model:    rr.Model,
+		APIKey:   rr.APIKey,
+		URL:      rr.OllamaURL,
+		Timeout:  time.Duration(rr.TimeoutSeconds) * time.Second,
+	})
+	if err == nil {
+		documents := make([]string, len(results))
+		for i, r := range results {
+			documents[i] = r.Text
+		}
+
+		var ranked []rerank.Result
+		ranked, err = reranker.Rerank(ctx, query, documents)
+		if err == nil {
+			reordered := make([]KBSearchResult, 0, len(ranked))
+			for _, r := range ranked {
+				result := results[r.Index]
+				score := r.Score
+				result.RerankScore = &score
+				reordered = append(reordered, result)
+			}
+			return truncateKBResults(reordered, topN),
+				fmt.Sprintf("Reranked by %s (%s)", reranker.ProviderName(), reranker.ModelName())
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "WARNING: Knowledgebase reranking failed, using vector similarity order: %v\n", err)
+	return truncateKBResults(results, topN), "Not reranked (reranker unavailable); ordered by vector similarity"
+}
+
+// truncateKBResults returns at most n results
+func truncateKBResults(results []KBSearchResult, n int) []KBSearchResult {
+	if len(results) > n {
+		return results[:n]
+	}
+	return results
+}
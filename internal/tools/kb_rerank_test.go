@@ -0,0 +1,151 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/netproxy"
+	"pgedge-postgres-mcp/internal/rerank"
+)
+
+// fakeReranker scores documents by their length, or fails
+type fakeReranker struct {
+	err error
+}
+
+func (f *fakeReranker) Rerank(ctx context.Context, query string, documents []string) ([]rerank.Result, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	results := make([]rerank.Result, len(documents))
+	for i, doc := range documents {
+		results[i] = rerank.Result{Index: i, Score: float64(len(doc)) / 10}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results, nil
+}
+
+func (f *fakeReranker) ProviderName() string { return "fake" }
+func (f *fakeReranker) ModelName() string    { return "fake-model" }
+
+// useFakeReranker replaces the reranker factory for the test
+func useFakeReranker(t *testing.T, reranker rerank.Reranker) *rerank.Config {
+	t.Helper()
+	var got rerank.Config
+	original := newReranker
+	newReranker = func(cfg rerank.Config) (rerank.Reranker, error) {
+		got = cfg
+		return reranker, nil
+	}
+	t.Cleanup(func() { newReranker = original })
+	return &got
+}
+
+// TestKBRerankCandidates tests how many results are fetched for reranking
+func TestKBRerankCandidates(t *testing.T) {
+	tests := []struct {
+		rr   config.KnowledgebaseRerankConfig
+		topN int
+		want int
+	}{
+		{config.KnowledgebaseRerankConfig{}, 5, 5},
+		{config.KnowledgebaseRerankConfig{Enabled: true, Candidates: 30}, 5, 30},
+		{config.KnowledgebaseRerankConfig{Enabled: true}, 5, 20},
+		{config.KnowledgebaseRerankConfig{Enabled: true, Candidates: 10}, 25, 25},
+	}
+	for _, tt := range tests {
+		if got := kbRerankCandidates(tt.rr, tt.topN); got != tt.want {
+			t.Errorf("kbRerankCandidates(%+v, %d) = %d, want %d", tt.rr, tt.topN, got, tt.want)
+		}
+	}
+}
+
+// TestRerankKBResults tests reordering results and falling back to the
+// vector similarity order
+func TestRerankKBResults(t *testing.T) {
+	results := []KBSearchResult{
+		{Text: "a", Similarity: 0.9},
+		{Text: "abc", Similarity: 0.8},
+		{Text: "ab", Similarity: 0.7},
+	}
+	rr := config.KnowledgebaseRerankConfig{
+		Enabled:        true,
+		Provider:       "ollama",
+		Model:          "example-model",
+		OllamaURL:      "http://localhost:11434",
+		TimeoutSeconds: 5,
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		got, note := rerankKBResults(context.Background(), config.KnowledgebaseRerankConfig{}, "q", results, 2)
+		if len(got) != 2 || got[0].Text != "a" || note != "" {
+			t.Errorf("expected the first 2 results unchanged, got %+v %q", got, note)
+		}
+	})
+
+	t.Run("reranked", func(t *testing.T) {
+		cfg := useFakeReranker(t, &fakeReranker{})
+		got, note := rerankKBResults(context.Background(), rr, "q", results, 2)
+		if len(got) != 2 || got[0].Text != "abc" || got[1].Text != "ab" {
+			t.Fatalf("unexpected order: %+v", got)
+		}
+		if got[0].RerankScore == nil || *got[0].RerankScore != 0.3 {
+			t.Errorf("expected a relevance score of 0.3, got %v", got[0].RerankScore)
+		}
+		if got[0].Similarity != 0.8 {
+			t.Errorf("expected the similarity to be kept, got %v", got[0].Similarity)
+		}
+		if note != "Reranked by fake (fake-model)" {
+			t.Errorf("unexpected note %q", note)
+		}
+		if cfg.Provider != "ollama" || cfg.URL != "http://localhost:11434" || cfg.Timeout.Seconds() != 5 {
+			t.Errorf("unexpected reranker config %+v", *cfg)
+		}
+		if results[0].RerankScore != nil {
+			t.Error("expected the input results to be left unchanged")
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		useFakeReranker(t, &fakeReranker{err: fmt.Errorf("timeout")})
+		got, note := rerankKBResults(context.Background(), rr, "q", results, 2)
+		if len(got) != 2 || got[0].Text != "a" || got[0].RerankScore != nil {
+			t.Errorf("expected vector similarity order, got %+v", got)
+		}
+		if !strings.Contains(note, "Not reranked") {
+			t.Errorf("unexpected note %q", note)
+		}
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		useFakeReranker(t, &fakeReranker{})
+		netproxy.SetOffline(true)
+		defer netproxy.SetOffline(false)
+
+		cohere := rr
+		cohere.Provider = "cohere"
+		got, note := rerankKBResults(context.Background(), cohere, "q", results, 3)
+		if got[0].Text != "a" || !strings.Contains(note, "offline") {
+			t.Errorf("expected hosted reranking to be skipped offline, got %+v %q", got, note)
+		}
+
+		got, _ = rerankKBResults(context.Background(), rr, "q", results, 3)
+		if got[0].Text != "abc" {
+			t.Errorf("expected local reranking offline, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// ListDatabasesTool creates the list_databases tool, which lists the
+// configured databases the caller may access with their labels
+func ListDatabasesTool(databases mcp.DatabaseProvider) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "list_databases",
+			Description: `List the configured databases you can access, with their environment, tags
+and description.
+
+<usecase>
+Use to find which database holds the data the user asks about, or to check
+whether a database accepts changes before proposing any.
+</usecase>
+
+<output>
+TSV with one row per database, sorted by name: name, whether it is the current database,
+connection (user@host:port/database), environment, tags, whether writes are
+allowed, and description.
+</output>
+
+<important>
+- Databases whose writes column is disabled are read-only: write tools only
+  run there as dry runs. Production databases (environment or tag prod) are
+  read-only unless configured otherwise
+- Other databases can be queried with query_database(database=<name>) when
+  cross-database queries are enabled
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+				Required:   []string{},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			dbs, current, err := databases.ListDatabases(ctx)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			if len(dbs) == 0 {
+				return mcp.NewToolSuccess("No databases are configured for you.")
+			}
+			sort.Slice(dbs, func(i, j int) bool { return dbs[i].Name < dbs[j].Name })
+			recordRowsReturned(args, len(dbs))
+			return mcp.NewToolSuccess(formatDatabaseList(dbs, current))
+		},
+	}
+}
+
+// formatDatabaseList formats the database list as TSV
+func formatDatabaseList(dbs []mcp.DatabaseInfo, current string) string {
+	var sb strings.Builder
+	sb.WriteString(BuildTSVRow("name", "current", "connection", "environment", "tags", "writes", "description"))
+	for _, db := range dbs {
+		writes := "allowed"
+		if db.ReadOnly {
+			writes = "disabled"
+		}
+		sb.WriteString("\n")
+		sb.WriteString(BuildTSVRow(
+			db.Name,
+			strconv.FormatBool(db.Name == current),
+			db.User+"@"+db.Host+":"+strconv.Itoa(db.Port)+"/"+db.Database,
+			db.Environment,
+			strings.Join(db.Tags, ","),
+			writes,
+			db.Description,
+		))
+	}
+	return sb.String()
+}
@@ -0,0 +1,65 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/resources"
+)
+
+func TestListDatabasesTool(t *testing.T) {
+	clientManager := database.NewClientManager([]config.NamedDatabaseConfig{
+		{Name: "main", Host: "db1", Port: 5432, Database: "app", User: "app",
+			Environment: "prod", Tags: []string{"eu", "orders"}, Description: "Orders and customers"},
+		{Name: "scratch", Host: "db2", Port: 5433, Database: "scratch", User: "dev", Environment: "dev"},
+	})
+	defer clientManager.CloseAll()
+
+	cfg := &config.Config{}
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, nil, cfg, nil, "", nil, 0, nil)
+	if err := provider.RegisterTools(context.Background()); err != nil {
+		t.Fatalf("RegisterTools failed: %v", err)
+	}
+
+	// The tool does not need a connection to the current database
+	response, err := provider.Execute(context.Background(), "list_databases", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if response.IsError {
+		t.Fatalf("unexpected error: %s", response.Content[0].Text)
+	}
+
+	want := "name\tcurrent\tconnection\tenvironment\ttags\twrites\tdescription\n" +
+		"main\ttrue\tapp@db1:5432/app\tprod\teu,orders\tdisabled\tOrders and customers\n" +
+		"scratch\tfalse\tdev@db2:5433/scratch\tdev\t\tallowed\t"
+	if got := response.Content[0].Text; got != want {
+		t.Errorf("list_databases =\n%s\nwant\n%s", got, want)
+	}
+
+	disabled := false
+	cfg.Builtins.Tools.ListDatabases = &disabled
+	provider.Reload(cfg)
+	for _, tool := range provider.List() {
+		if tool.Name == "list_databases" {
+			t.Error("expected list_databases to be hidden when disabled")
+		}
+	}
+	if !strings.Contains(formatDatabaseList(nil, ""), "description") {
+		t.Error("expected a header without databases")
+	}
+}
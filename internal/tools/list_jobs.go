@@ -0,0 +1,182 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/scheduler"
+)
+
+// maxJobOutputBytes bounds the output of a job's last run shown by list_jobs
+const maxJobOutputBytes = 8000
+
+// ListJobsTool creates the list_jobs tool, which lists the scheduled jobs
+// or shows the recent runs of one
+func ListJobsTool() Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "list_jobs",
+			Description: `List the scheduled jobs, or show the recent runs of one with the output of its last run.
+
+<usecase>
+Use when the user asks about scheduled reports or automations: what runs
+when, whether last night's run succeeded, and what it reported.
+</usecase>
+
+<output>
+Without job_name: TSV with one row per job (name, schedule, database,
+source, enabled, next run, last status, last run, duration, error).
+With job_name: the job's definition, its recent runs as TSV, newest first,
+and the output of the newest run.
+</output>
+
+<important>
+Jobs are defined in the server configuration or through the /api/jobs
+endpoint by an administrator; this tool cannot change or start them.
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"job_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Show the runs of this job",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of runs to show (default: 10)",
+						"default":     10,
+						"minimum":     1,
+					},
+				},
+				Required: []string{},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			s := scheduler.Shared()
+			if s == nil {
+				return mcp.NewToolSuccess("Scheduled jobs are not enabled on this server (scheduler.enabled).")
+			}
+			limit := int(ValidateOptionalNumberParam(args, "limit", 10))
+			if errResp := ValidatePositiveNumber(float64(limit), "limit"); errResp != nil {
+				return *errResp, nil
+			}
+
+			if name := ValidateOptionalStringParam(args, "job_name", ""); name != "" {
+				status, ok := s.Job(name)
+				if !ok {
+					return mcp.NewToolError(fmt.Sprintf("No job named %q. Call list_jobs without job_name to list the jobs.", name))
+				}
+				runs, err := s.History(name, limit)
+				if err != nil && !errors.Is(err, scheduler.ErrNotFound) {
+					return mcp.NewToolError(err.Error())
+				}
+				recordRowsReturned(args, len(runs))
+				return mcp.NewToolSuccess(formatJobRuns(status, runs))
+			}
+
+			jobs := s.Jobs()
+			if len(jobs) == 0 {
+				return mcp.NewToolSuccess("No jobs are scheduled.")
+			}
+			recordRowsReturned(args, len(jobs))
+			return mcp.NewToolSuccess(formatJobs(jobs))
+		},
+	}
+}
+
+// formatJobTime formats a time for the job listings; zero times are empty
+func formatJobTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatJobs formats the job list as TSV
+func formatJobs(jobs []scheduler.JobStatus) string {
+	var sb strings.Builder
+	sb.WriteString(BuildTSVRow("name", "schedule", "database", "source", "enabled", "next_run",
+		"last_status", "last_run", "duration_ms", "error"))
+	for _, status := range jobs {
+		lastStatus, lastRun, duration, lastError := "", "", "", ""
+		if status.Running {
+			lastStatus = "running"
+		}
+		if run := status.LastRun; run != nil {
+			if !status.Running {
+				lastStatus = run.Status
+			}
+			lastRun = formatJobTime(run.StartedAt)
+			duration = fmt.Sprint(run.Duration().Milliseconds())
+			lastError = run.Error
+		}
+		sb.WriteString("\n")
+		sb.WriteString(BuildTSVRow(status.Job.Name, status.Job.Schedule, status.Job.Database, status.Job.Source,
+			fmt.Sprintf("%t", !status.Job.Disabled), formatJobTime(status.NextRun),
+			lastStatus, lastRun, duration, lastError))
+	}
+	return sb.String()
+}
+
+// formatJobRuns describes a job and formats its runs, newest first, with the
+// output of the newest run
+func formatJobRuns(status scheduler.JobStatus, runs []scheduler.Run) string {
+	job := status.Job
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Job: %s\nSchedule: %s\n", job.Name, job.Schedule))
+	if next := formatJobTime(status.NextRun); next != "" {
+		sb.WriteString(fmt.Sprintf("Next run: %s\n", next))
+	} else {
+		sb.WriteString("Next run: none (disabled)\n")
+	}
+	if job.Database != "" {
+		sb.WriteString(fmt.Sprintf("Database: %s\n", job.Database))
+	}
+	if job.SQL != "" {
+		sb.WriteString(fmt.Sprintf("SQL:\n%s\n", job.SQL))
+	} else {
+		tools := make([]string, len(job.Steps))
+		for i, step := range job.Steps {
+			tools[i] = step.Tool
+		}
+		sb.WriteString(fmt.Sprintf("Steps: %s\n", strings.Join(tools, " -> ")))
+	}
+	if status.Running {
+		sb.WriteString("Running now\n")
+	}
+
+	if len(runs) == 0 {
+		sb.WriteString("\nThe job has not run yet.\n")
+		return sb.String()
+	}
+	sb.WriteString("\nRuns, newest first:\n")
+	sb.WriteString(BuildTSVRow("started_at", "trigger", "status", "duration_ms", "error", "webhook_error"))
+	for _, run := range runs {
+		sb.WriteString("\n")
+		sb.WriteString(BuildTSVRow(formatJobTime(run.StartedAt), run.Trigger, run.Status,
+			fmt.Sprint(run.Duration().Milliseconds()), run.Error, run.WebhookError))
+	}
+
+	output := runs[0].Output
+	if output == "" {
+		return sb.String()
+	}
+	if len(output) > maxJobOutputBytes {
+		output = output[:maxJobOutputBytes] + "\n... (truncated)"
+	}
+	sb.WriteString(fmt.Sprintf("\n\nOutput of the run at %s:\n%s", formatJobTime(runs[0].StartedAt), output))
+	return sb.String()
+}
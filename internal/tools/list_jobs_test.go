@@ -0,0 +1,79 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/scheduler"
+)
+
+func TestListJobsTool_Disabled(t *testing.T) {
+	scheduler.SetShared(nil)
+	response, err := ListJobsTool().Handler(map[string]interface{}{})
+	if err != nil || response.IsError {
+		t.Fatalf("handler failed: %v %v", err, response)
+	}
+	if !strings.Contains(response.Content[0].Text, "not enabled") {
+		t.Errorf("unexpected response: %q", response.Content[0].Text)
+	}
+}
+
+func TestFormatJobs(t *testing.T) {
+	start := time.Date(2025, 6, 4, 2, 0, 0, 0, time.UTC)
+	out := formatJobs([]scheduler.JobStatus{
+		{
+			Job:     scheduler.Job{Name: "nightly_bloat", Schedule: "0 2 * * *", Database: "main", SQL: "SELECT 1", Source: scheduler.SourceConfig},
+			NextRun: start.Add(24 * time.Hour),
+			LastRun: &scheduler.Run{StartedAt: start, FinishedAt: start.Add(1200 * time.Millisecond), Status: scheduler.StatusError, Error: "query_database: timed out"},
+		},
+		{
+			Job:     scheduler.Job{Name: "adhoc", Schedule: "@daily", SQL: "SELECT 2", Disabled: true, Source: scheduler.SourceAPI},
+			Running: true,
+		},
+	})
+	lines := strings.Split(out, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 rows, got:\n%s", out)
+	}
+	if want := "nightly_bloat\t0 2 * * *\tmain\tconfig\ttrue\t2025-06-05T02:00:00Z\terror\t2025-06-04T02:00:00Z\t1200\tquery_database: timed out"; lines[1] != want {
+		t.Errorf("row = %q, want %q", lines[1], want)
+	}
+	if want := "adhoc\t@daily\t\tapi\tfalse\t\trunning\t\t\t"; lines[2] != want {
+		t.Errorf("row = %q, want %q", lines[2], want)
+	}
+}
+
+func TestFormatJobRuns(t *testing.T) {
+	start := time.Date(2025, 6, 4, 2, 0, 0, 0, time.UTC)
+	status := scheduler.JobStatus{Job: scheduler.Job{Name: "report", Schedule: "@daily", Steps: []scheduler.Step{
+		{Tool: "get_schema_info"}, {Tool: "query_database"},
+	}}}
+
+	if out := formatJobRuns(status, nil); !strings.Contains(out, "Steps: get_schema_info -> query_database") ||
+		!strings.Contains(out, "Next run: none") || !strings.Contains(out, "has not run yet") {
+		t.Errorf("unexpected description of a job without runs:\n%s", out)
+	}
+
+	runs := []scheduler.Run{
+		{StartedAt: start.Add(24 * time.Hour), FinishedAt: start.Add(24*time.Hour + time.Second), Trigger: scheduler.TriggerManual, Status: scheduler.StatusOK, Output: "newest output"},
+		{StartedAt: start, FinishedAt: start.Add(time.Second), Trigger: scheduler.TriggerSchedule, Status: scheduler.StatusOK, Output: "older output"},
+	}
+	out := formatJobRuns(status, runs)
+	if !strings.Contains(out, "2025-06-05T02:00:00Z\tmanual\tok\t1000") || !strings.HasSuffix(out, "newest output") {
+		t.Errorf("unexpected runs:\n%s", out)
+	}
+	if strings.Contains(out, "older output") {
+		t.Errorf("only the newest run's output should be shown:\n%s", out)
+	}
+}
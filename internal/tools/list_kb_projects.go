@@ -0,0 +1,79 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// ListKBProjectsTool creates the list_kb_projects tool, which lists the
+// projects and versions in the knowledgebase so searches can be filtered
+// with their exact names
+func ListKBProjectsTool(kbCfg config.KnowledgebaseConfig) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "list_kb_projects",
+			Description: `List the projects (products) and versions documented in the knowledgebase.
+
+<usecase>
+Use list_kb_projects before search_knowledgebase to:
+- Find the EXACT project names to filter by (names must match exactly)
+- Find the versions available, so a question about a specific version
+  (e.g. PostgreSQL 16) is answered only from that version's documentation
+</usecase>
+
+<output>
+TSV with one row per project version: project, version (empty for
+unversioned documentation) and the number of chunks.
+</output>`,
+			InputSchema: mcp.InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+				Required:   []string{},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			ctx, ok := args["__context"].(context.Context)
+			if !ok || ctx == nil {
+				ctx = context.Background()
+			}
+
+			kb, err := newKBBackend(ctx, kbCfg)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to open knowledgebase: %v", err))
+			}
+			products, err := kb.products(ctx)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to list projects: %v", err))
+			}
+			if len(products) == 0 {
+				return mcp.NewToolSuccess("The knowledgebase is empty.")
+			}
+			return mcp.NewToolSuccess(formatKBProjects(products))
+		},
+	}
+}
+
+// formatKBProjects formats the projects and versions as TSV
+func formatKBProjects(products []kbProduct) string {
+	var sb strings.Builder
+	sb.WriteString(BuildTSVRow("project", "version", "chunks"))
+	for _, p := range products {
+		sb.WriteString("\n")
+		sb.WriteString(BuildTSVRow(p.Name, p.Version, fmt.Sprint(p.Chunks)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,63 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/resources"
+)
+
+func TestFormatKBProjects(t *testing.T) {
+	got := formatKBProjects([]kbProduct{
+		{Name: "PostgreSQL", Version: "16", Chunks: 10},
+		{Name: "pgEdge RAG Server", Chunks: 3},
+	})
+	want := "project\tversion\tchunks\nPostgreSQL\t16\t10\npgEdge RAG Server\t\t3"
+	if got != want {
+		t.Errorf("formatKBProjects() = %q, want %q", got, want)
+	}
+}
+
+// TestContextAwareProvider_ListKBProjects tests that list_kb_projects is
+// available alongside search_knowledgebase and lists the knowledgebase
+func TestContextAwareProvider_ListKBProjects(t *testing.T) {
+	clientManager := database.NewClientManagerWithConfig(nil)
+	defer clientManager.CloseAll()
+
+	cfg := &config.Config{}
+	cfg.Knowledgebase.Enabled = true
+	cfg.Knowledgebase.DatabasePath = createTestKnowledgebase(t)
+	resourceReg := resources.NewContextAwareRegistry(clientManager, false, nil, cfg)
+	provider := NewContextAwareProvider(clientManager, resourceReg, false, database.NewClient(nil), cfg, nil, "", nil, 0, nil)
+
+	response, err := provider.Execute(context.Background(), "list_kb_projects", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if response.IsError || response.Content[0].Text != "project\tversion\tchunks\nTest\t1.0\t1" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	disabled := false
+	cfg = &config.Config{}
+	cfg.Knowledgebase = provider.cfg.Knowledgebase
+	cfg.Builtins.Tools.ListKBProjects = &disabled
+	provider.Reload(cfg)
+	for _, tool := range provider.List() {
+		if tool.Name == "list_kb_projects" {
+			t.Error("expected list_kb_projects to be hidden when disabled")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/snapshot"
+)
+
+// ListSchemaSnapshotsTool creates the list_schema_snapshots tool, which lists
+// the snapshots saved by snapshot_schema, or shows the DDL of one
+func ListSchemaSnapshotsTool(cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "list_schema_snapshots",
+			Description: `List the schema snapshots saved with snapshot_schema, or show the DDL of one.
+
+<usecase>
+Use to find the snapshot to restore with restore_schema_snapshot, or to
+review the DDL a snapshot recreates.
+</usecase>
+
+<output>
+Without a name: TSV with one row per snapshot, newest first (name, schema,
+source database, creation time, tables, rows and data bytes).
+With a name: the snapshot's summary and its DDL as a script.
+</output>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Show the DDL of this snapshot",
+					},
+				},
+				Required: []string{},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			store := snapshot.NewStoreFromConfig(cfg)
+
+			if name := ValidateOptionalStringParam(args, "name", ""); name != "" {
+				snap, err := store.Open(name)
+				if err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+				return mcp.NewToolSuccess(fmt.Sprintf("Database: %s\n\n%s\n%s",
+					snap.Database, formatSnapshotSummary(snap), formatSnapshotDDL(snap)))
+			}
+
+			snapshots, err := store.List()
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			if len(snapshots) == 0 {
+				return mcp.NewToolSuccess("No schema snapshots have been saved. Create one with snapshot_schema.")
+			}
+			recordRowsReturned(args, len(snapshots))
+			return mcp.NewToolSuccess(formatSchemaSnapshots(snapshots))
+		},
+	}
+}
+
+// formatSchemaSnapshots formats the snapshot list as TSV
+func formatSchemaSnapshots(snapshots []*snapshot.Snapshot) string {
+	var sb strings.Builder
+	sb.WriteString(BuildTSVRow("name", "schema", "database", "created_at", "tables", "rows", "data_bytes"))
+	for _, snap := range snapshots {
+		rows, dataBytes := "", ""
+		if snap.HasData() {
+			rows = fmt.Sprint(snap.Rows())
+			dataBytes = fmt.Sprint(snap.DataBytes)
+		}
+		sb.WriteString("\n")
+		sb.WriteString(BuildTSVRow(snap.Name, snap.Schema, snap.Database, snap.CreatedAt.UTC().Format(time.RFC3339),
+			fmt.Sprint(len(snap.Tables)), rows, dataBytes))
+	}
+	return sb.String()
+}
@@ -0,0 +1,422 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// LockAnalysisTool creates the lock_analysis tool, which reports which
+// sessions are blocked by which, as trees rooted at the sessions holding
+// the locks
+func LockAnalysisTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "lock_analysis",
+			Description: `Show which sessions are waiting for locks and who is blocking them, as
+blocking trees: each tree starts at a session that holds the locks and lists
+the sessions waiting on it, with how long they have been waiting and their
+query text.
+
+<usecase>
+Use when:
+- Queries hang or the application reports lock timeouts
+- The user asks what is blocking a query, or why a migration is stuck
+- Many sessions are waiting and the cause is unclear
+</usecase>
+
+<what_it_returns>
+- One tree per blocking session, with the user, state, transaction age and
+  query of every session in it, and the lock each waiter wants
+- Suggested actions, such as cancelling or terminating the root blocker
+</what_it_returns>
+
+<important>
+- Nothing is cancelled or terminated; present the suggestions to the user,
+  who decides whether to act on them
+- Sessions of other users are only visible with the pg_read_all_stats role
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"min_wait_seconds": map[string]interface{}{
+						"type":        "number",
+						"description": "Only report trees where some session has waited at least this long (default: 0)",
+						"default":     0,
+					},
+					"suggest_actions": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Suggest how to release each blocking session (default: true)",
+						"default":     true,
+					},
+				},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			minWait := ValidateOptionalNumberParam(args, "min_wait_seconds", 0)
+			if minWait < 0 {
+				return mcp.NewToolError("min_wait_seconds must not be negative")
+			}
+			suggest := ValidateBoolParam(args, "suggest_actions", true)
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			sessions, err := loadLockSessions(ctx, pool)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to read locks: %v", err))
+			}
+
+			trees := buildBlockingTrees(sessions)
+			trees = filterBlockingTrees(trees, time.Duration(minWait*float64(time.Second)))
+
+			logging.Info("lock_analysis_executed",
+				"sessions", len(sessions),
+				"trees", len(trees),
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatLockAnalysis(trees, suggest))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// lockSession is a session that is waiting for a lock or blocking one
+type lockSession struct {
+	PID              int32
+	User             string
+	Database         string
+	Application      string
+	State            string
+	QueryDuration    time.Duration // Since the current or last query started
+	XactDuration     time.Duration // Since the transaction started (0 if none)
+	Query            string
+	BlockedBy        []int32 // Sessions this one is waiting for
+	WaitLockType     string  // The lock being waited for, if any
+	WaitLockMode     string
+	WaitLockRelation string
+}
+
+// waiting reports whether the session is waiting for a lock
+func (s *lockSession) waiting() bool {
+	return len(s.BlockedBy) > 0
+}
+
+// blockingNode is a session in a blocking tree with the sessions waiting on it
+type blockingNode struct {
+	Session *lockSession
+	Waiters []*blockingNode
+}
+
+// count returns the number of sessions waiting on this one, directly or not
+func (n *blockingNode) count() int {
+	total := 0
+	for _, w := range n.Waiters {
+		total += 1 + w.count()
+	}
+	return total
+}
+
+// longestWait returns the longest wait among the sessions in the tree
+func (n *blockingNode) longestWait() time.Duration {
+	var longest time.Duration
+	if n.Session.waiting() {
+		longest = n.Session.QueryDuration
+	}
+	for _, w := range n.Waiters {
+		if d := w.longestWait(); d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// loadLockSessions reads the sessions that are waiting for locks and the
+// sessions blocking them
+func loadLockSessions(ctx context.Context, pool *pgxpool.Pool) ([]*lockSession, error) {
+	// pg_blocking_pids reports 0 for prepared transactions, which have no session
+	rows, err := pool.Query(ctx, `
+		WITH waiting AS (
+			SELECT pid, pg_catalog.pg_blocking_pids(pid) AS blocked_by
+			FROM pg_catalog.pg_stat_activity
+			WHERE cardinality(pg_catalog.pg_blocking_pids(pid)) > 0
+		)
+		SELECT a.pid, coalesce(a.usename::text, ''), coalesce(a.datname::text, ''),
+			coalesce(a.application_name, ''), coalesce(a.state, ''),
+			coalesce(extract(epoch FROM now() - a.query_start), 0)::float8,
+			coalesce(extract(epoch FROM now() - a.xact_start), 0)::float8,
+			left(coalesce(a.query, ''), 500),
+			coalesce(w.blocked_by, '{}'::int[]),
+			coalesce(l.locktype, ''), coalesce(l.mode, ''), coalesce(l.relation::regclass::text, '')
+		FROM pg_catalog.pg_stat_activity a
+		LEFT JOIN waiting w ON w.pid = a.pid
+		LEFT JOIN LATERAL (
+			SELECT locktype, mode, relation
+			FROM pg_catalog.pg_locks
+			WHERE pid = a.pid AND NOT granted
+			LIMIT 1
+		) l ON true
+		WHERE a.pid <> pg_catalog.pg_backend_pid()
+			AND (w.pid IS NOT NULL OR a.pid IN (SELECT unnest(blocked_by) FROM waiting))
+		ORDER BY a.pid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*lockSession
+	for rows.Next() {
+		var s lockSession
+		var querySeconds, xactSeconds float64
+		if err := rows.Scan(&s.PID, &s.User, &s.Database, &s.Application, &s.State,
+			&querySeconds, &xactSeconds, &s.Query, &s.BlockedBy,
+			&s.WaitLockType, &s.WaitLockMode, &s.WaitLockRelation); err != nil {
+			return nil, err
+		}
+		s.QueryDuration = time.Duration(querySeconds * float64(time.Second))
+		s.XactDuration = time.Duration(xactSeconds * float64(time.Second))
+		sessions = append(sessions, &s)
+	}
+	return sessions, rows.Err()
+}
+
+// buildBlockingTrees arranges sessions into trees, each rooted at a session
+// that blocks others without waiting itself. A session blocked by several
+// others appears under each of them. Sessions in a wait cycle (a deadlock
+// not yet detected) are rooted at the lowest PID in the cycle.
+func buildBlockingTrees(sessions []*lockSession) []*blockingNode {
+	byPID := make(map[int32]*lockSession, len(sessions))
+	waiters := make(map[int32][]*lockSession)
+	for _, s := range sessions {
+		byPID[s.PID] = s
+	}
+	for _, s := range sessions {
+		for _, blocker := range s.BlockedBy {
+			waiters[blocker] = append(waiters[blocker], s)
+			if _, ok := byPID[blocker]; !ok {
+				// A prepared transaction, or a session that has since ended
+				byPID[blocker] = &lockSession{PID: blocker}
+			}
+		}
+	}
+
+	var build func(s *lockSession, path map[int32]bool) *blockingNode
+	build = func(s *lockSession, path map[int32]bool) *blockingNode {
+		node := &blockingNode{Session: s}
+		path[s.PID] = true
+		for _, w := range waiters[s.PID] {
+			if !path[w.PID] {
+				node.Waiters = append(node.Waiters, build(w, path))
+			}
+		}
+		delete(path, s.PID)
+		return node
+	}
+
+	var roots []*lockSession
+	for pid, s := range byPID {
+		if len(waiters[pid]) > 0 && !s.waiting() {
+			roots = append(roots, s)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].PID < roots[j].PID })
+
+	// Any waiting session not reached from a root is part of a cycle
+	reached := make(map[int32]bool)
+	var mark func(n *blockingNode)
+	mark = func(n *blockingNode) {
+		reached[n.Session.PID] = true
+		for _, w := range n.Waiters {
+			mark(w)
+		}
+	}
+	var trees []*blockingNode
+	for _, root := range roots {
+		tree := build(root, make(map[int32]bool))
+		mark(tree)
+		trees = append(trees, tree)
+	}
+	pids := make([]int32, 0, len(byPID))
+	for pid := range byPID {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	for _, pid := range pids {
+		if s := byPID[pid]; s.waiting() && !reached[pid] {
+			tree := build(s, make(map[int32]bool))
+			mark(tree)
+			trees = append(trees, tree)
+		}
+	}
+
+	// The trees blocking the most sessions come first
+	sort.SliceStable(trees, func(i, j int) bool { return trees[i].count() > trees[j].count() })
+	return trees
+}
+
+// filterBlockingTrees drops trees where no session has waited minWait
+func filterBlockingTrees(trees []*blockingNode, minWait time.Duration) []*blockingNode {
+	if minWait <= 0 {
+		return trees
+	}
+	var kept []*blockingNode
+	for _, tree := range trees {
+		if tree.longestWait() >= minWait {
+			kept = append(kept, tree)
+		}
+	}
+	return kept
+}
+
+// formatLockAnalysis formats blocking trees for the LLM
+func formatLockAnalysis(trees []*blockingNode, suggest bool) string {
+	if len(trees) == 0 {
+		return "No sessions are waiting for locks.\n"
+	}
+
+	var sb strings.Builder
+	blocked := 0
+	for _, tree := range trees {
+		blocked += tree.count()
+	}
+	sb.WriteString(fmt.Sprintf("Blocking trees: %d (%d waiting sessions)\n", len(trees), blocked))
+
+	for i, tree := range trees {
+		sb.WriteString(fmt.Sprintf("\nTree %d: pid %d blocks %d session(s), longest wait %s\n",
+			i+1, tree.Session.PID, tree.count(), formatLockDuration(tree.longestWait())))
+		writeBlockingNode(&sb, tree, 0)
+	}
+
+	if suggest {
+		sb.WriteString("\nSuggested actions (not performed; confirm with the user first):\n")
+		for _, tree := range trees {
+			sb.WriteString(fmt.Sprintf("- %s\n", suggestLockAction(tree)))
+		}
+	}
+	return sb.String()
+}
+
+// writeBlockingNode writes a session and, indented, the sessions waiting on it
+func writeBlockingNode(sb *strings.Builder, node *blockingNode, depth int) {
+	indent := strings.Repeat("    ", depth)
+	s := node.Session
+	if s.State == "" && s.Query == "" {
+		sb.WriteString(fmt.Sprintf("%s- pid %d: %s\n", indent, s.PID, unknownBlocker(s.PID)))
+	} else {
+		line := fmt.Sprintf("%s- pid %d, user %s, %s", indent, s.PID, s.User, s.State)
+		if s.XactDuration > 0 {
+			line += fmt.Sprintf(", transaction open %s", formatLockDuration(s.XactDuration))
+		}
+		if s.waiting() {
+			line += fmt.Sprintf(", waiting %s", formatLockDuration(s.QueryDuration))
+			if s.WaitLockMode != "" {
+				line += fmt.Sprintf(" for %s on %s", s.WaitLockMode, lockTarget(s))
+			}
+		}
+		sb.WriteString(line + "\n")
+		if s.Query != "" {
+			sb.WriteString(fmt.Sprintf("%s  query: %s\n", indent, strings.Join(strings.Fields(s.Query), " ")))
+		}
+	}
+	for _, w := range node.Waiters {
+		writeBlockingNode(sb, w, depth+1)
+	}
+}
+
+// lockTarget describes what a waiting session's lock is on
+func lockTarget(s *lockSession) string {
+	if s.WaitLockRelation != "" {
+		return s.WaitLockRelation
+	}
+	return s.WaitLockType
+}
+
+// unknownBlocker describes a blocker that has no session
+func unknownBlocker(pid int32) string {
+	if pid == 0 {
+		return "a prepared transaction (see pg_prepared_xacts)"
+	}
+	return "session not visible (it may have ended, or needs pg_read_all_stats)"
+}
+
+// suggestLockAction suggests how to release the root blocker of a tree
+func suggestLockAction(tree *blockingNode) string {
+	s := tree.Session
+	switch {
+	case s.State == "" && s.Query == "":
+		if s.PID == 0 {
+			return "A prepared transaction is blocking; find it in pg_prepared_xacts and COMMIT PREPARED or ROLLBACK PREPARED it."
+		}
+		return fmt.Sprintf("Blocker pid %d is not visible; check it with a role that has pg_read_all_stats.", s.PID)
+	case s.waiting():
+		return fmt.Sprintf("Sessions %s are waiting on each other; PostgreSQL's deadlock detector should cancel one shortly, or cancel pid %d with SELECT pg_cancel_backend(%d).",
+			cyclePIDs(tree), s.PID, s.PID)
+	case strings.HasPrefix(s.State, "idle in transaction"):
+		// Cancelling does nothing when no query is running
+		return fmt.Sprintf("pid %d is idle in a transaction; end it with SELECT pg_terminate_backend(%d), and check why %s leaves transactions open.",
+			s.PID, s.PID, applicationName(s))
+	default:
+		return fmt.Sprintf("Cancel pid %d's query with SELECT pg_cancel_backend(%d), or wait for it to finish; use pg_terminate_backend(%d) if cancelling does not release the locks.",
+			s.PID, s.PID, s.PID)
+	}
+}
+
+// cyclePIDs lists the PIDs in the first chain of a tree
+func cyclePIDs(tree *blockingNode) string {
+	var pids []string
+	for n := tree; n != nil; {
+		pids = append(pids, fmt.Sprintf("%d", n.Session.PID))
+		if len(n.Waiters) == 0 {
+			break
+		}
+		n = n.Waiters[0]
+	}
+	return strings.Join(pids, ", ")
+}
+
+// applicationName names the application of a session for suggestions
+func applicationName(s *lockSession) string {
+	if s.Application != "" {
+		return s.Application
+	}
+	return "the client"
+}
+
+// formatLockDuration formats a lock wait or transaction age
+func formatLockDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.0fs", d.Seconds())
+	}
+	return formatHealthDuration(d)
+}
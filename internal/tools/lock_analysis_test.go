@@ -0,0 +1,120 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleLockSessions returns an idle transaction (100) blocking an ALTER
+// TABLE (101), which in turn blocks two queries (102, 103)
+func sampleLockSessions() []*lockSession {
+	return []*lockSession{
+		{PID: 100, User: "app", Application: "billing", State: "idle in transaction",
+			XactDuration: 20 * time.Minute, QueryDuration: 19 * time.Minute, Query: "UPDATE orders SET status = 'paid' WHERE id = 1"},
+		{PID: 101, User: "admin", State: "active", QueryDuration: 5 * time.Minute, Query: "ALTER TABLE orders ADD COLUMN note text",
+			BlockedBy: []int32{100}, WaitLockMode: "AccessExclusiveLock", WaitLockType: "relation", WaitLockRelation: "orders"},
+		{PID: 102, User: "app", State: "active", QueryDuration: 4 * time.Minute, Query: "SELECT * FROM orders",
+			BlockedBy: []int32{101}, WaitLockMode: "AccessShareLock", WaitLockType: "relation", WaitLockRelation: "orders"},
+		{PID: 103, User: "app", State: "active", QueryDuration: 30 * time.Second, Query: "SELECT count(*) FROM orders",
+			BlockedBy: []int32{101}, WaitLockMode: "AccessShareLock", WaitLockType: "relation", WaitLockRelation: "orders"},
+	}
+}
+
+func TestBuildBlockingTrees(t *testing.T) {
+	trees := buildBlockingTrees(sampleLockSessions())
+	if len(trees) != 1 {
+		t.Fatalf("expected 1 tree, got %d", len(trees))
+	}
+
+	root := trees[0]
+	if root.Session.PID != 100 {
+		t.Errorf("expected pid 100 at the root, got %d", root.Session.PID)
+	}
+	if root.count() != 3 {
+		t.Errorf("expected 3 waiting sessions, got %d", root.count())
+	}
+	if len(root.Waiters) != 1 || len(root.Waiters[0].Waiters) != 2 {
+		t.Errorf("expected 101 under 100 and two waiters under 101")
+	}
+	if root.longestWait() != 5*time.Minute {
+		t.Errorf("expected the longest wait to be 5 minutes, got %s", root.longestWait())
+	}
+}
+
+func TestBuildBlockingTrees_CycleAndPreparedTransaction(t *testing.T) {
+	sessions := []*lockSession{
+		{PID: 200, State: "active", Query: "UPDATE a", BlockedBy: []int32{201}},
+		{PID: 201, State: "active", Query: "UPDATE b", BlockedBy: []int32{200}},
+		{PID: 300, State: "active", Query: "UPDATE c", BlockedBy: []int32{0}},
+	}
+
+	trees := buildBlockingTrees(sessions)
+	if len(trees) != 2 {
+		t.Fatalf("expected 2 trees, got %d", len(trees))
+	}
+
+	roots := map[int32]int{}
+	for _, tree := range trees {
+		roots[tree.Session.PID] = tree.count()
+	}
+	if roots[0] != 1 {
+		t.Errorf("expected the prepared transaction (pid 0) to block 1 session, got %v", roots)
+	}
+	if roots[200] != 1 {
+		t.Errorf("expected the cycle to be rooted at pid 200, got %v", roots)
+	}
+
+	output := formatLockAnalysis(trees, true)
+	for _, want := range []string{"a prepared transaction", "pg_prepared_xacts", "deadlock detector"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestFilterBlockingTrees(t *testing.T) {
+	trees := buildBlockingTrees(sampleLockSessions())
+
+	if got := filterBlockingTrees(trees, 10*time.Minute); len(got) != 0 {
+		t.Errorf("expected no trees with waits of 10 minutes, got %d", len(got))
+	}
+	if got := filterBlockingTrees(trees, time.Minute); len(got) != 1 {
+		t.Errorf("expected 1 tree with waits of a minute, got %d", len(got))
+	}
+}
+
+func TestFormatLockAnalysis(t *testing.T) {
+	if got := formatLockAnalysis(nil, true); !strings.Contains(got, "No sessions are waiting") {
+		t.Errorf("unexpected output without trees: %q", got)
+	}
+
+	output := formatLockAnalysis(buildBlockingTrees(sampleLockSessions()), true)
+	for _, want := range []string{
+		"Blocking trees: 1 (3 waiting sessions)",
+		"- pid 100, user app, idle in transaction, transaction open 20 minutes",
+		"    - pid 101, user admin, active, waiting 5 minutes for AccessExclusiveLock on orders",
+		"        - pid 103, user app, active, waiting 30s for AccessShareLock on orders",
+		"query: ALTER TABLE orders ADD COLUMN note text",
+		"SELECT pg_terminate_backend(100)",
+		"billing",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	if output := formatLockAnalysis(buildBlockingTrees(sampleLockSessions()), false); strings.Contains(output, "Suggested actions") {
+		t.Error("expected no suggestions when suggest_actions is false")
+	}
+}
@@ -0,0 +1,595 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+// migrationsTable records the migrations applied by apply_migration. It is
+// created on first use in the first schema of the search path.
+const migrationsTable = "pgedge_mcp_migrations"
+
+// createMigrationsTableSQL creates the migrations table if it is missing
+const createMigrationsTableSQL = `CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name text NOT NULL,
+    up_sql text NOT NULL,
+    down_sql text NOT NULL,
+    reversible boolean NOT NULL,
+    checksum text NOT NULL,
+    applied_by text NOT NULL DEFAULT current_user,
+    applied_at timestamptz NOT NULL DEFAULT now(),
+    rolled_back_at timestamptz
+)`
+
+// migration is a forward script and the script that reverses it
+type migration struct {
+	Name       string
+	Up         []string
+	Down       []string // In the order they run, undoing the last change first
+	Warnings   []string
+	Reversible bool
+}
+
+// upSQL and downSQL render the scripts as stored and displayed
+func (m *migration) upSQL() string   { return joinStatements(m.Up) }
+func (m *migration) downSQL() string { return joinStatements(m.Down) }
+
+// checksum identifies the forward script, ignoring whitespace differences
+func (m *migration) checksum() string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(m.upSQL()), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// joinStatements renders statements as a script, one per line
+func joinStatements(statements []string) string {
+	var sb strings.Builder
+	for _, stmt := range statements {
+		sb.WriteString(strings.TrimRight(strings.TrimSpace(stmt), ";"))
+		sb.WriteString(";\n")
+	}
+	return sb.String()
+}
+
+// migrationCatalog looks up the definitions of objects a migration drops or
+// replaces, so the backward migration can recreate them
+type migrationCatalog interface {
+	IndexDef(name string) (string, bool)
+	ConstraintDef(table, name string) (string, bool)
+	ViewDef(name string) (def string, materialized bool, ok bool)
+}
+
+// poolMigrationCatalog reads definitions from the live database. Names are
+// resolved with the connection's search path, as the migration's are.
+type poolMigrationCatalog struct {
+	ctx  context.Context
+	pool *pgxpool.Pool
+}
+
+func (c poolMigrationCatalog) IndexDef(name string) (string, bool) {
+	var def *string
+	err := c.pool.QueryRow(c.ctx, "SELECT pg_catalog.pg_get_indexdef(to_regclass($1))", name).Scan(&def)
+	if err != nil || def == nil {
+		return "", false
+	}
+	return *def, true
+}
+
+func (c poolMigrationCatalog) ConstraintDef(table, name string) (string, bool) {
+	var def string
+	err := c.pool.QueryRow(c.ctx, `
+		SELECT pg_catalog.pg_get_constraintdef(oid)
+		FROM pg_catalog.pg_constraint
+		WHERE conrelid = to_regclass($1) AND conname = $2`, table, name).Scan(&def)
+	if err != nil {
+		return "", false
+	}
+	return def, true
+}
+
+func (c poolMigrationCatalog) ViewDef(name string) (string, bool, bool) {
+	var def string
+	var materialized bool
+	err := c.pool.QueryRow(c.ctx, `
+		SELECT pg_catalog.pg_get_viewdef(c.oid), c.relkind = 'm'
+		FROM pg_catalog.pg_class c
+		WHERE c.oid = to_regclass($1) AND c.relkind IN ('v', 'm')`, name).Scan(&def, &materialized)
+	if err != nil {
+		return "", false, false
+	}
+	return strings.TrimRight(strings.TrimSpace(def), ";"), materialized, true
+}
+
+// migrationGenerator derives the backward statements of a migration
+type migrationGenerator struct {
+	planner   *schemaPlanner // Resolves table names against the metadata
+	catalog   migrationCatalog
+	m         *migration
+	statement int
+}
+
+// generateMigration splits the forward script into statements and derives
+// the statements that undo them. Statements that cannot be undone (dropped
+// tables, data changes) are reported in the warnings and make the
+// migration irreversible.
+func generateMigration(name, up string, metadata map[string]database.TableInfo, catalog migrationCatalog) (*migration, error) {
+	tokens, err := tokenizeSQL(up)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse migration: %w", err)
+	}
+	statements := splitStatements(tokens)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no statements found")
+	}
+
+	g := &migrationGenerator{
+		planner: &schemaPlanner{
+			metadata: metadata,
+			plan:     &schemaPlan{},
+			tables:   make(map[string]bool),
+			columns:  make(map[string]bool),
+		},
+		catalog: catalog,
+		m:       &migration{Name: name, Reversible: true},
+	}
+
+	var downs [][]string
+	for i, stmt := range statements {
+		g.statement = i + 1
+		g.m.Up = append(g.m.Up, up[stmt[0].start:stmt[len(stmt)-1].end])
+		downs = append(downs, g.reverse(stmt))
+	}
+	for i := len(downs) - 1; i >= 0; i-- {
+		g.m.Down = append(g.m.Down, downs[i]...)
+	}
+	return g.m, nil
+}
+
+// irreversible records that a statement cannot be undone automatically
+func (g *migrationGenerator) irreversible(format string, args ...interface{}) {
+	g.m.Reversible = false
+	g.warn(format, args...)
+}
+
+// warn records a note about the backward migration of the current statement
+func (g *migrationGenerator) warn(format string, args ...interface{}) {
+	g.m.Warnings = append(g.m.Warnings, fmt.Sprintf("Statement %d: ", g.statement)+fmt.Sprintf(format, args...))
+}
+
+// quoteName renders an optionally schema-qualified name as SQL
+func quoteName(schema, name string) string {
+	if schema == "" {
+		return quoteIdentIfNeeded(name)
+	}
+	return quoteIdentIfNeeded(schema) + "." + quoteIdentIfNeeded(name)
+}
+
+// quoteLiteral renders a string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// reverse returns the statements that undo one forward statement
+func (g *migrationGenerator) reverse(toks []sqlToken) []string {
+	c := &ddlCursor{toks: toks}
+
+	switch {
+	case c.accept("CREATE"):
+		orReplace := c.accept("OR", "REPLACE")
+		_ = c.accept("TEMP") || c.accept("TEMPORARY") || c.accept("UNLOGGED")
+		switch {
+		case c.accept("TABLE"):
+			return g.reverseCreateTable(c)
+		case c.accept("UNIQUE", "INDEX"), c.accept("INDEX"):
+			return g.reverseCreateIndex(c)
+		case c.accept("VIEW"):
+			return g.reverseCreateView(c, orReplace, false)
+		case c.accept("MATERIALIZED", "VIEW"):
+			return g.reverseCreateView(c, orReplace, true)
+		case c.accept("SCHEMA"):
+			return g.reverseCreate("SCHEMA", c)
+		case c.accept("SEQUENCE"):
+			return g.reverseCreate("SEQUENCE", c)
+		case c.accept("EXTENSION"):
+			return g.reverseCreate("EXTENSION", c)
+		case c.accept("TYPE"):
+			return g.reverseCreate("TYPE", c)
+		}
+
+	case c.accept("ALTER", "TABLE"):
+		return g.reverseAlterTable(c)
+
+	case c.accept("DROP", "INDEX"):
+		return g.reverseDropIndexes(c)
+	case c.accept("DROP", "VIEW"):
+		return g.reverseDropViews(c)
+	case c.accept("DROP", "MATERIALIZED", "VIEW"):
+		return g.reverseDropViews(c)
+	case c.accept("DROP", "TABLE"):
+		g.irreversible("DROP TABLE deletes the table's data, which cannot be restored by a backward migration")
+		return nil
+
+	case c.accept("COMMENT", "ON"):
+		return g.reverseComment(c)
+	}
+
+	head := toks
+	if len(head) > 3 {
+		head = head[:3]
+	}
+	if toks[0].isKeyword("INSERT", "UPDATE", "DELETE", "MERGE", "TRUNCATE", "COPY") {
+		g.irreversible("%s changes data, which is not reversed automatically; supply a down script", toks[0].upper)
+	} else {
+		g.irreversible("%s... is not reversed automatically; supply a down script", tokensText(head))
+	}
+	return nil
+}
+
+// reverseCreate undoes CREATE SCHEMA, SEQUENCE, EXTENSION or TYPE
+func (g *migrationGenerator) reverseCreate(kind string, c *ddlCursor) []string {
+	c.accept("IF", "NOT", "EXISTS")
+	schema, name, ok := c.name()
+	if !ok {
+		g.irreversible("could not find the %s name", strings.ToLower(kind))
+		return nil
+	}
+	return []string{"DROP " + kind + " " + quoteName(schema, name)}
+}
+
+// reverseCreateTable undoes CREATE TABLE, unless the table already existed
+func (g *migrationGenerator) reverseCreateTable(c *ddlCursor) []string {
+	ifNotExists := c.accept("IF", "NOT", "EXISTS")
+	schema, name, ok := c.name()
+	if !ok {
+		g.irreversible("could not find the table name")
+		return nil
+	}
+	key, _, exists := g.planner.lookupTable(schema, name)
+	if exists && ifNotExists {
+		g.warn("table %s already exists, so there is nothing to undo", key)
+		return nil
+	}
+	g.planner.tables[key] = true
+	return []string{"DROP TABLE " + quoteName(schema, name)}
+}
+
+// reverseCreateIndex undoes CREATE INDEX; the index is created in its
+// table's schema
+func (g *migrationGenerator) reverseCreateIndex(c *ddlCursor) []string {
+	c.accept("CONCURRENTLY")
+	ifNotExists := c.accept("IF", "NOT", "EXISTS")
+	if c.peek().isKeyword("ON") {
+		g.irreversible("the index has no name, so it cannot be dropped by name; name the index")
+		return nil
+	}
+	schema, name, ok := c.name()
+	if !ok || !c.accept("ON") {
+		g.irreversible("could not find the index name")
+		return nil
+	}
+	c.accept("ONLY")
+	if tableSchema, _, ok := c.name(); ok && schema == "" {
+		schema = tableSchema
+	}
+	if ifNotExists {
+		if _, exists := g.catalog.IndexDef(quoteName(schema, name)); exists {
+			g.warn("index %s already exists, so there is nothing to undo", name)
+			return nil
+		}
+	}
+	return []string{"DROP INDEX " + quoteName(schema, name)}
+}
+
+// reverseCreateView undoes CREATE VIEW, restoring the previous definition
+// of a replaced view
+func (g *migrationGenerator) reverseCreateView(c *ddlCursor, orReplace, materialized bool) []string {
+	c.accept("IF", "NOT", "EXISTS")
+	schema, name, ok := c.name()
+	if !ok {
+		g.irreversible("could not find the view name")
+		return nil
+	}
+	view := quoteName(schema, name)
+	if orReplace {
+		if def, _, exists := g.catalog.ViewDef(view); exists {
+			return []string{"CREATE OR REPLACE VIEW " + view + " AS\n" + def}
+		}
+	}
+	if materialized {
+		return []string{"DROP MATERIALIZED VIEW " + view}
+	}
+	return []string{"DROP VIEW " + view}
+}
+
+// reverseDropIndexes recreates dropped indexes from their definitions
+func (g *migrationGenerator) reverseDropIndexes(c *ddlCursor) []string {
+	c.accept("CONCURRENTLY")
+	c.accept("IF", "EXISTS")
+	var downs []string
+	for _, name := range droppedNames(c.rest()) {
+		def, ok := g.catalog.IndexDef(name)
+		if !ok {
+			g.irreversible("index %s was not found, so it cannot be recreated", name)
+			continue
+		}
+		downs = append(downs, def)
+	}
+	return downs
+}
+
+// reverseDropViews recreates dropped views from their definitions
+func (g *migrationGenerator) reverseDropViews(c *ddlCursor) []string {
+	c.accept("IF", "EXISTS")
+	var downs []string
+	for _, name := range droppedNames(c.rest()) {
+		def, materialized, ok := g.catalog.ViewDef(name)
+		if !ok {
+			g.irreversible("view %s was not found, so it cannot be recreated", name)
+			continue
+		}
+		if materialized {
+			downs = append(downs, "CREATE MATERIALIZED VIEW "+name+" AS\n"+def)
+			g.warn("materialized view %s is recreated and refreshed, which runs its query", name)
+		} else {
+			downs = append(downs, "CREATE VIEW "+name+" AS\n"+def)
+		}
+	}
+	return downs
+}
+
+// droppedNames returns the names in a DROP statement's object list,
+// without CASCADE or RESTRICT
+func droppedNames(toks []sqlToken) []string {
+	var names []string
+	for _, part := range splitTopLevel(toks) {
+		for len(part) > 0 && part[len(part)-1].isKeyword("CASCADE", "RESTRICT") {
+			part = part[:len(part)-1]
+		}
+		if len(part) > 0 {
+			names = append(names, tokensText(part))
+		}
+	}
+	return names
+}
+
+// reverseAlterTable undoes ALTER TABLE. Renames are swapped; the actions of
+// a combined ALTER TABLE are undone in reverse order in one statement.
+func (g *migrationGenerator) reverseAlterTable(c *ddlCursor) []string {
+	c.accept("IF", "EXISTS")
+	c.accept("ONLY")
+	schema, name, ok := c.name()
+	if !ok {
+		g.irreversible("could not find the table name")
+		return nil
+	}
+	if c.peek().isPunct("*") {
+		c.pos++
+	}
+	table := quoteName(schema, name)
+	key, info, _ := g.planner.lookupTable(schema, name)
+
+	switch {
+	case c.accept("RENAME", "TO"):
+		_, newName, _ := c.name()
+		g.planner.tables[key] = false
+		g.planner.tables[schemaOf(key)+"."+newName] = true
+		return []string{"ALTER TABLE " + quoteName(schema, newName) + " RENAME TO " + quoteIdentIfNeeded(name)}
+	case c.accept("RENAME", "CONSTRAINT"):
+		_, oldName, _ := c.name()
+		c.accept("TO")
+		_, newName, _ := c.name()
+		return []string{fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s", table, quoteIdentIfNeeded(newName), quoteIdentIfNeeded(oldName))}
+	case c.accept("RENAME"):
+		c.accept("COLUMN")
+		_, oldName, _ := c.name()
+		c.accept("TO")
+		_, newName, _ := c.name()
+		return []string{fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, quoteIdentIfNeeded(newName), quoteIdentIfNeeded(oldName))}
+	case c.accept("SET", "SCHEMA"):
+		_, newSchema, _ := c.name()
+		oldSchema := schema
+		if oldSchema == "" {
+			oldSchema = schemaOf(key)
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s SET SCHEMA %s", quoteName(newSchema, name), quoteIdentIfNeeded(oldSchema))}
+	}
+
+	var undo []string
+	for _, action := range splitTopLevel(c.rest()) {
+		if len(action) == 0 {
+			continue
+		}
+		if u, ok := g.reverseAlterAction(key, info, table, action); ok && u != "" {
+			undo = append([]string{u}, undo...)
+		}
+	}
+	if len(undo) == 0 {
+		return nil
+	}
+	return []string{"ALTER TABLE " + table + " " + strings.Join(undo, ", ")}
+}
+
+// reverseAlterAction returns the ALTER TABLE action undoing one action, or
+// false if it cannot be undone. An empty action means there is nothing to
+// undo.
+func (g *migrationGenerator) reverseAlterAction(key string, info *database.TableInfo, table string, toks []sqlToken) (string, bool) {
+	c := &ddlCursor{toks: toks}
+
+	switch {
+	case c.accept("ADD", "CONSTRAINT"):
+		_, name, ok := c.name()
+		if !ok {
+			break
+		}
+		return "DROP CONSTRAINT " + quoteIdentIfNeeded(name), true
+
+	case c.accept("ADD", "PRIMARY"):
+		// PostgreSQL names an unnamed primary key after its table
+		tableName := key[len(schemaOf(key))+1:]
+		return "DROP CONSTRAINT " + quoteIdentIfNeeded(tableName+"_pkey"), true
+
+	case c.accept("ADD", "UNIQUE"), c.accept("ADD", "FOREIGN"), c.accept("ADD", "CHECK"), c.accept("ADD", "EXCLUDE"):
+		g.irreversible("the constraint has no name, so it cannot be dropped by name; use ADD CONSTRAINT <name>")
+		return "", false
+
+	case c.accept("ADD"):
+		c.accept("COLUMN")
+		c.accept("IF", "NOT", "EXISTS")
+		_, column, ok := c.name()
+		if !ok {
+			break
+		}
+		g.planner.columns[key+"."+column] = true
+		return "DROP COLUMN " + quoteIdentIfNeeded(column), true
+
+	case c.accept("DROP", "CONSTRAINT"):
+		c.accept("IF", "EXISTS")
+		_, name, ok := c.name()
+		if !ok {
+			break
+		}
+		def, found := g.catalog.ConstraintDef(table, name)
+		if !found {
+			g.irreversible("constraint %s was not found on %s, so it cannot be recreated", name, key)
+			return "", false
+		}
+		return "ADD CONSTRAINT " + quoteIdentIfNeeded(name) + " " + def, true
+
+	case c.accept("DROP"):
+		c.accept("COLUMN")
+		c.accept("IF", "EXISTS")
+		_, column, ok := c.name()
+		if !ok {
+			break
+		}
+		col, _ := g.planner.lookupColumn(key, info, column)
+		if col == nil {
+			g.irreversible("column %s of %s is not in the schema metadata, so it cannot be recreated", column, key)
+			return "", false
+		}
+		undo := "ADD COLUMN " + quoteIdentIfNeeded(column) + " " + col.DataType
+		if col.DefaultValue != "" {
+			undo += " DEFAULT " + col.DefaultValue
+		}
+		g.warn("dropping %s deletes its data; the backward migration recreates the column empty", column)
+		if col.IsNullable == "NO" {
+			g.warn("%s is recreated without NOT NULL, since existing rows have no value for it", column)
+		}
+		return undo, true
+
+	case c.accept("ALTER"):
+		c.accept("COLUMN")
+		_, column, ok := c.name()
+		if !ok {
+			break
+		}
+		col, _ := g.planner.lookupColumn(key, info, column)
+		return g.reverseAlterColumn(c, key, column, col)
+	}
+
+	g.irreversible("%s is not reversed automatically; supply a down script", tokensText(toks))
+	return "", false
+}
+
+// reverseAlterColumn returns the action undoing ALTER COLUMN, using the
+// column's current definition
+func (g *migrationGenerator) reverseAlterColumn(c *ddlCursor, key, column string, col *database.ColumnInfo) (string, bool) {
+	alter := "ALTER COLUMN " + quoteIdentIfNeeded(column)
+
+	switch {
+	case c.accept("TYPE"), c.accept("SET", "DATA", "TYPE"):
+		if col == nil {
+			g.irreversible("the current type of %s.%s is unknown, so it cannot be restored", key, column)
+			return "", false
+		}
+		g.warn("changing %s back to %s fails if the new values cannot be converted", column, col.DataType)
+		return alter + " TYPE " + col.DataType, true
+
+	case c.accept("SET", "NOT", "NULL"):
+		if col != nil && col.IsNullable == "NO" {
+			return "", true
+		}
+		return alter + " DROP NOT NULL", true
+
+	case c.accept("DROP", "NOT", "NULL"):
+		if col != nil && col.IsNullable == "NO" {
+			g.warn("restoring NOT NULL on %s fails if NULLs were stored after the migration", column)
+			return alter + " SET NOT NULL", true
+		}
+		return "", true
+
+	case c.accept("SET", "DEFAULT"), c.accept("DROP", "DEFAULT"):
+		if col != nil && col.DefaultValue != "" {
+			return alter + " SET DEFAULT " + col.DefaultValue, true
+		}
+		return alter + " DROP DEFAULT", true
+	}
+
+	g.irreversible("ALTER COLUMN %s %s is not reversed automatically; supply a down script", column, tokensText(c.rest()))
+	return "", false
+}
+
+// reverseComment restores the previous comment on a table or column
+func (g *migrationGenerator) reverseComment(c *ddlCursor) []string {
+	kind := c.peek().upper
+	if kind != "TABLE" && kind != "COLUMN" {
+		g.irreversible("COMMENT ON %s is not reversed automatically; supply a down script", kind)
+		return nil
+	}
+	c.pos++
+
+	var parts []string
+	for !c.done() && !c.peek().isKeyword("IS") {
+		if t := c.peek(); t.kind == tokWord || t.kind == tokQuotedIdent {
+			parts = append(parts, t.value)
+		}
+		c.pos++
+	}
+
+	var schema, table, column string
+	switch {
+	case kind == "TABLE" && len(parts) == 1:
+		table = parts[0]
+	case kind == "TABLE" && len(parts) == 2:
+		schema, table = parts[0], parts[1]
+	case kind == "COLUMN" && len(parts) == 2:
+		table, column = parts[0], parts[1]
+	case kind == "COLUMN" && len(parts) == 3:
+		schema, table, column = parts[0], parts[1], parts[2]
+	default:
+		g.irreversible("could not find the commented object")
+		return nil
+	}
+
+	key, info, _ := g.planner.lookupTable(schema, table)
+	previous := "NULL"
+	target := quoteName(schema, table)
+	if column == "" {
+		if info != nil && info.Description != "" {
+			previous = quoteLiteral(info.Description)
+		}
+	} else {
+		target += "." + quoteIdentIfNeeded(column)
+		if col, _ := g.planner.lookupColumn(key, info, column); col != nil && col.Description != "" {
+			previous = quoteLiteral(col.Description)
+		}
+	}
+	return []string{"COMMENT ON " + kind + " " + target + " IS " + previous}
+}
@@ -0,0 +1,306 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeMigrationCatalog serves object definitions from maps
+type fakeMigrationCatalog struct {
+	indexes     map[string]string
+	constraints map[string]string // "table.name"
+	views       map[string]string
+}
+
+func (c fakeMigrationCatalog) IndexDef(name string) (string, bool) {
+	def, ok := c.indexes[name]
+	return def, ok
+}
+
+func (c fakeMigrationCatalog) ConstraintDef(table, name string) (string, bool) {
+	def, ok := c.constraints[table+"."+name]
+	return def, ok
+}
+
+func (c fakeMigrationCatalog) ViewDef(name string) (string, bool, bool) {
+	def, ok := c.views[name]
+	return def, strings.HasPrefix(name, "mv_"), ok
+}
+
+func testMigrationCatalog() fakeMigrationCatalog {
+	return fakeMigrationCatalog{
+		indexes: map[string]string{
+			"orders_created_idx": "CREATE INDEX orders_created_idx ON public.orders USING btree (created_at)",
+		},
+		constraints: map[string]string{
+			"orders.orders_customer_fk": "FOREIGN KEY (customer_id) REFERENCES customers(id)",
+		},
+		views: map[string]string{
+			"open_orders":   "SELECT id FROM orders WHERE status = 'open'",
+			"mv_daily":      "SELECT created_at::date, count(*) FROM orders GROUP BY 1",
+			"sales.regions": "SELECT 'eu'::text AS code",
+		},
+	}
+}
+
+func TestGenerateMigration(t *testing.T) {
+	tests := []struct {
+		name         string
+		up           string
+		down         []string
+		irreversible bool
+		warning      string
+	}{
+		{
+			name: "create table",
+			up:   "CREATE TABLE notes (id bigint PRIMARY KEY, body text)",
+			down: []string{"DROP TABLE notes"},
+		},
+		{
+			name:    "create existing table if not exists",
+			up:      "CREATE TABLE IF NOT EXISTS orders (id bigint)",
+			warning: "already exists",
+		},
+		{
+			name: "create index takes the table's schema",
+			up:   "CREATE UNIQUE INDEX orders_ref_idx ON sales.orders (id)",
+			down: []string{"DROP INDEX sales.orders_ref_idx"},
+		},
+		{
+			name:         "unnamed index",
+			up:           "CREATE INDEX ON orders (status)",
+			irreversible: true,
+			warning:      "name the index",
+		},
+		{
+			name: "replace view restores the definition",
+			up:   "CREATE OR REPLACE VIEW open_orders AS SELECT id, total FROM orders WHERE status = 'open'",
+			down: []string{"CREATE OR REPLACE VIEW open_orders AS\nSELECT id FROM orders WHERE status = 'open'"},
+		},
+		{
+			name: "add columns in one statement",
+			up:   "ALTER TABLE orders ADD COLUMN notes text, ADD COLUMN priority int DEFAULT 0",
+			down: []string{"ALTER TABLE orders DROP COLUMN priority, DROP COLUMN notes"},
+		},
+		{
+			name:    "drop column recreates it",
+			up:      "ALTER TABLE orders DROP COLUMN status",
+			down:    []string{"ALTER TABLE orders ADD COLUMN status text"},
+			warning: "recreated without NOT NULL",
+		},
+		{
+			name:         "drop unknown column",
+			up:           "ALTER TABLE orders DROP COLUMN missing",
+			irreversible: true,
+		},
+		{
+			name: "rename table",
+			up:   "ALTER TABLE orders RENAME TO purchases",
+			down: []string{"ALTER TABLE purchases RENAME TO orders"},
+		},
+		{
+			name: "rename column",
+			up:   "ALTER TABLE public.orders RENAME COLUMN total TO amount",
+			down: []string{"ALTER TABLE public.orders RENAME COLUMN amount TO total"},
+		},
+		{
+			name: "set schema",
+			up:   "ALTER TABLE customers SET SCHEMA sales",
+			down: []string{"ALTER TABLE sales.customers SET SCHEMA public"},
+		},
+		{
+			name:    "type change restores the old type",
+			up:      "ALTER TABLE orders ALTER COLUMN customer_id TYPE bigint",
+			down:    []string{"ALTER TABLE orders ALTER COLUMN customer_id TYPE integer"},
+			warning: "cannot be converted",
+		},
+		{
+			name: "not null changes",
+			up:   "ALTER TABLE orders ALTER COLUMN total SET NOT NULL, ALTER COLUMN status DROP NOT NULL",
+			down: []string{"ALTER TABLE orders ALTER COLUMN status SET NOT NULL, ALTER COLUMN total DROP NOT NULL"},
+		},
+		{
+			name: "default without previous default",
+			up:   "ALTER TABLE orders ALTER COLUMN status SET DEFAULT 'open'",
+			down: []string{"ALTER TABLE orders ALTER COLUMN status DROP DEFAULT"},
+		},
+		{
+			name: "named constraint",
+			up:   "ALTER TABLE orders ADD CONSTRAINT total_positive CHECK (total > 0)",
+			down: []string{"ALTER TABLE orders DROP CONSTRAINT total_positive"},
+		},
+		{
+			name:         "unnamed constraint",
+			up:           "ALTER TABLE orders ADD UNIQUE (status)",
+			irreversible: true,
+			warning:      "ADD CONSTRAINT <name>",
+		},
+		{
+			name: "unnamed primary key",
+			up:   "ALTER TABLE customers ADD PRIMARY KEY (id)",
+			down: []string{"ALTER TABLE customers DROP CONSTRAINT customers_pkey"},
+		},
+		{
+			name: "drop constraint recreates it",
+			up:   "ALTER TABLE orders DROP CONSTRAINT orders_customer_fk",
+			down: []string{"ALTER TABLE orders ADD CONSTRAINT orders_customer_fk FOREIGN KEY (customer_id) REFERENCES customers(id)"},
+		},
+		{
+			name: "drop index recreates it",
+			up:   "DROP INDEX IF EXISTS orders_created_idx",
+			down: []string{"CREATE INDEX orders_created_idx ON public.orders USING btree (created_at)"},
+		},
+		{
+			name: "drop views recreates them",
+			up:   "DROP VIEW open_orders, sales.regions CASCADE",
+			down: []string{
+				"CREATE VIEW open_orders AS\nSELECT id FROM orders WHERE status = 'open'",
+				"CREATE VIEW sales.regions AS\nSELECT 'eu'::text AS code",
+			},
+		},
+		{
+			name:    "drop materialized view",
+			up:      "DROP MATERIALIZED VIEW mv_daily",
+			down:    []string{"CREATE MATERIALIZED VIEW mv_daily AS\nSELECT created_at::date, count(*) FROM orders GROUP BY 1"},
+			warning: "refreshed",
+		},
+		{
+			name:         "drop table",
+			up:           "DROP TABLE orders",
+			irreversible: true,
+			warning:      "deletes the table's data",
+		},
+		{
+			name:         "data change",
+			up:           "UPDATE orders SET status = 'closed'",
+			irreversible: true,
+			warning:      "UPDATE changes data",
+		},
+		{
+			name: "comment restores the previous comment",
+			up:   "COMMENT ON TABLE orders IS 'All orders'",
+			down: []string{"COMMENT ON TABLE orders IS 'Customer orders'"},
+		},
+		{
+			name: "column comment without previous comment",
+			up:   "COMMENT ON COLUMN public.orders.total IS 'Gross total'",
+			down: []string{"COMMENT ON COLUMN public.orders.total IS NULL"},
+		},
+		{
+			name: "quoted identifiers",
+			up:   `CREATE TABLE "Audit Log" (id int)`,
+			down: []string{`DROP TABLE "Audit Log"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := generateMigration("test", tt.up, explainTestMetadata(), testMigrationCatalog())
+			if err != nil {
+				t.Fatalf("generateMigration() error = %v", err)
+			}
+			if !reflect.DeepEqual(m.Down, tt.down) {
+				t.Errorf("Down = %q, want %q", m.Down, tt.down)
+			}
+			if m.Reversible == tt.irreversible {
+				t.Errorf("Reversible = %v, want %v (warnings: %v)", m.Reversible, !tt.irreversible, m.Warnings)
+			}
+			if tt.warning != "" && !hasText(m.Warnings, tt.warning) {
+				t.Errorf("expected warning containing %q, got %v", tt.warning, m.Warnings)
+			}
+		})
+	}
+}
+
+func TestGenerateMigration_Script(t *testing.T) {
+	up := `CREATE TABLE notes (id bigint PRIMARY KEY);
+ALTER TABLE notes ADD COLUMN body text;
+CREATE INDEX notes_body_idx ON notes (body);`
+
+	m, err := generateMigration("add_notes", up, explainTestMetadata(), testMigrationCatalog())
+	if err != nil {
+		t.Fatalf("generateMigration() error = %v", err)
+	}
+	if len(m.Up) != 3 {
+		t.Fatalf("expected 3 forward statements, got %d", len(m.Up))
+	}
+	want := []string{
+		"DROP INDEX notes_body_idx",
+		"ALTER TABLE notes DROP COLUMN body",
+		"DROP TABLE notes",
+	}
+	if !reflect.DeepEqual(m.Down, want) {
+		t.Errorf("Down = %q, want %q", m.Down, want)
+	}
+	if !m.Reversible {
+		t.Errorf("expected a reversible migration, warnings: %v", m.Warnings)
+	}
+	if got := m.downSQL(); !strings.HasPrefix(got, "DROP INDEX notes_body_idx;\n") {
+		t.Errorf("downSQL() = %q", got)
+	}
+}
+
+func TestMigrationChecksum(t *testing.T) {
+	a := &migration{Up: []string{"ALTER TABLE orders\n  ADD COLUMN notes text"}}
+	b := &migration{Up: []string{"ALTER TABLE orders ADD COLUMN notes text;"}}
+	c := &migration{Up: []string{"ALTER TABLE orders ADD COLUMN note text"}}
+
+	if a.checksum() != b.checksum() {
+		t.Error("checksums should ignore whitespace and trailing semicolons")
+	}
+	if a.checksum() == c.checksum() {
+		t.Error("different migrations should have different checksums")
+	}
+}
+
+func TestFormatMigration(t *testing.T) {
+	m, err := generateMigration("drop_orders", "DROP TABLE orders", explainTestMetadata(), testMigrationCatalog())
+	if err != nil {
+		t.Fatalf("generateMigration() error = %v", err)
+	}
+	out := formatMigration(m)
+	for _, want := range []string{
+		"Migration: drop_orders",
+		"-- Forward (up)\nDROP TABLE orders;",
+		"(nothing to undo)",
+		"NOT fully reversible",
+		`apply_migration(name="drop_orders"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatMigrationOutcome(t *testing.T) {
+	tests := []struct {
+		direction  string
+		dryRun     bool
+		aborted    bool
+		downFailed bool
+		want       string
+	}{
+		{"up", true, true, false, "no changes were applied"},
+		{"up", true, false, true, "backward SQL failed"},
+		{"up", true, false, false, "dry_run=false"},
+		{"up", false, false, false, "Applied migration m1 (id 7)"},
+		{"down", false, false, false, "Rolled back migration m1 (id 7)"},
+	}
+	for _, tt := range tests {
+		got := formatMigrationOutcome("m1", tt.direction, tt.dryRun, tt.aborted, tt.downFailed, 7)
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("formatMigrationOutcome(%s, dry_run=%v) = %q, want %q", tt.direction, tt.dryRun, got, tt.want)
+		}
+	}
+}
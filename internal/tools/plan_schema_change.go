@@ -0,0 +1,215 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// PlanSchemaChangeTool creates the plan_schema_change tool, which previews
+// the effect of DDL against the live schema without applying it
+func PlanSchemaChangeTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "plan_schema_change",
+			Description: `Preview proposed DDL against the live schema before it is applied, like
+"terraform plan" for PostgreSQL.
+
+<usecase>
+Use when:
+- The user proposes a migration or schema change and wants to know its impact
+- Checking whether ALTER TABLE will rewrite a table or block queries
+- Reviewing DDL you have written before the user applies it
+</usecase>
+
+<what_it_returns>
+A plan listing what will be created (+), changed (~) or dropped (-), with:
+- The lock each statement takes and what it blocks
+- Whether the table is rewritten or scanned, with live row and size estimates
+- Statements that will fail (missing objects, foreign keys without CASCADE,
+  CONCURRENTLY inside a transaction)
+- Dependent views and safer alternatives (CONCURRENTLY, NOT VALID)
+</what_it_returns>
+
+<important>
+The DDL is never executed. Show the plan to the user and let them approve it
+before the change is applied outside this server.
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"ddl": map[string]interface{}{
+						"type":        "string",
+						"description": "The DDL to plan; several statements may be separated by semicolons",
+					},
+				},
+				Required: []string{"ddl"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			ddl, errResp := ValidateStringParam(args, "ddl")
+			if errResp != nil {
+				return *errResp, nil
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+
+			plan, err := planSchemaChange(ddl, dbClient.GetMetadataFor(connStr))
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Could not parse DDL: %v", err))
+			}
+
+			// Row counts, sizes and dependencies come from the live catalog;
+			// the plan is still useful without them
+			var catalog *planCatalog
+			if pool := dbClient.GetPoolFor(connStr); pool != nil {
+				tables, indexes := plan.catalogTargets()
+				catalog, err = loadPlanCatalog(context.Background(), pool, tables, indexes)
+				if err != nil {
+					logging.Warn("plan_schema_change_catalog_failed", "error", err)
+					plan.Warnings = append(plan.Warnings,
+						fmt.Sprintf("Live catalog details are unavailable (%v); sizes, dependent views and index lookups are missing", err))
+				} else {
+					plan.applyCatalog(catalog)
+				}
+			}
+
+			logging.Info("plan_schema_change_executed",
+				"ddl_length", len(ddl),
+				"statements", plan.Statements,
+				"changes", len(plan.Changes),
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(formatSchemaPlan(plan, catalog))
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// loadPlanCatalog reads table sizes, dependent views and index owners for a
+// plan in a read-only transaction
+func loadPlanCatalog(ctx context.Context, pool *pgxpool.Pool, tables, indexes []string) (*planCatalog, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // read-only transaction, nothing to keep
+	}()
+
+	if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+		return nil, fmt.Errorf("failed to set transaction to read-only: %w", err)
+	}
+
+	catalog := &planCatalog{
+		Tables:      make(map[string]planTableStats),
+		Dependents:  make(map[string][]string),
+		IndexTables: make(map[string]string),
+	}
+
+	if err := tx.QueryRow(ctx, "SELECT current_setting('server_version_num')::int").Scan(&catalog.ServerVersion); err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+
+	if len(indexes) > 0 {
+		rows, err := tx.Query(ctx, `
+			SELECT schemaname || '.' || indexname, schemaname || '.' || tablename
+			FROM pg_catalog.pg_indexes
+			WHERE schemaname || '.' || indexname = ANY($1)`, indexes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up indexes: %w", err)
+		}
+		for rows.Next() {
+			var index, table string
+			if err := rows.Scan(&index, &table); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read index: %w", err)
+			}
+			catalog.IndexTables[index] = table
+			tables = append(tables, table)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to look up indexes: %w", err)
+		}
+	}
+
+	if len(tables) == 0 {
+		return catalog, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT n.nspname || '.' || c.relname, c.reltuples::bigint, pg_catalog.pg_total_relation_size(c.oid)
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'm')
+		  AND n.nspname || '.' || c.relname = ANY($1)`, tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table sizes: %w", err)
+	}
+	for rows.Next() {
+		var table string
+		var stats planTableStats
+		if err := rows.Scan(&table, &stats.Rows, &stats.Bytes); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read table size: %w", err)
+		}
+		catalog.Tables[table] = stats
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read table sizes: %w", err)
+	}
+
+	// Views depend on tables through their rewrite rules
+	rows, err = tx.Query(ctx, `
+		SELECT DISTINCT sn.nspname || '.' || sc.relname, vn.nspname || '.' || vc.relname
+		FROM pg_catalog.pg_depend d
+		JOIN pg_catalog.pg_rewrite r ON r.oid = d.objid
+		JOIN pg_catalog.pg_class vc ON vc.oid = r.ev_class
+		JOIN pg_catalog.pg_namespace vn ON vn.oid = vc.relnamespace
+		JOIN pg_catalog.pg_class sc ON sc.oid = d.refobjid
+		JOIN pg_catalog.pg_namespace sn ON sn.oid = sc.relnamespace
+		WHERE d.classid = 'pg_catalog.pg_rewrite'::regclass
+		  AND d.refclassid = 'pg_catalog.pg_class'::regclass
+		  AND vc.oid <> sc.oid
+		  AND sn.nspname || '.' || sc.relname = ANY($1)
+		ORDER BY 1, 2`, tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependent views: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, view string
+		if err := rows.Scan(&table, &view); err != nil {
+			return nil, fmt.Errorf("failed to read dependent view: %w", err)
+		}
+		catalog.Dependents[table] = append(catalog.Dependents[table], view)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dependent views: %w", err)
+	}
+
+	return catalog, nil
+}
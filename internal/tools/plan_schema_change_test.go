@@ -0,0 +1,275 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+// planChange plans a script and returns its only change
+func planChange(t *testing.T, ddl string) *plannedChange {
+	t.Helper()
+	plan, err := planSchemaChange(ddl, explainTestMetadata())
+	if err != nil {
+		t.Fatalf("planSchemaChange() error = %v", err)
+	}
+	if len(plan.Changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(plan.Changes))
+	}
+	return plan.Changes[0]
+}
+
+func hasText(items []string, substr string) bool {
+	for _, item := range items {
+		if strings.Contains(item, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPlanSchemaChange_AlterTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		ddl     string
+		lock    string
+		rewrite bool
+		scan    bool
+		note    string
+		err     string
+	}{
+		{
+			name: "add column with constant default",
+			ddl:  "ALTER TABLE orders ADD COLUMN priority integer DEFAULT 0 NOT NULL",
+			lock: lockAccessExclusive,
+		},
+		{
+			name:    "add column with volatile default",
+			ddl:     "ALTER TABLE orders ADD COLUMN token uuid DEFAULT gen_random_uuid()",
+			lock:    lockAccessExclusive,
+			rewrite: true,
+			note:    "default for token is volatile",
+		},
+		{
+			name:    "add serial column",
+			ddl:     "ALTER TABLE orders ADD seq bigserial",
+			lock:    lockAccessExclusive,
+			rewrite: true,
+		},
+		{
+			name: "add existing column",
+			ddl:  "ALTER TABLE orders ADD COLUMN status text",
+			lock: lockAccessExclusive,
+			err:  "Column status already exists",
+		},
+		{
+			name:    "type change rewrites",
+			ddl:     "ALTER TABLE orders ALTER COLUMN customer_id TYPE bigint",
+			lock:    lockAccessExclusive,
+			rewrite: true,
+			note:    "rewrites the table",
+		},
+		{
+			name: "binary compatible type change",
+			ddl:  "ALTER TABLE customers ALTER COLUMN name TYPE varchar",
+			lock: lockAccessExclusive,
+		},
+		{
+			name: "set not null scans",
+			ddl:  "ALTER TABLE orders ALTER COLUMN total SET NOT NULL",
+			lock: lockAccessExclusive,
+			scan: true,
+			note: "NOT VALID",
+		},
+		{
+			name: "foreign key not valid",
+			ddl:  "ALTER TABLE orders ADD CONSTRAINT fk FOREIGN KEY (customer_id) REFERENCES customers (id) NOT VALID",
+			lock: lockShareRowExclusive,
+			note: "VALIDATE CONSTRAINT",
+		},
+		{
+			name: "second primary key",
+			ddl:  "ALTER TABLE orders ADD PRIMARY KEY (status)",
+			lock: lockAccessExclusive,
+			scan: true,
+			err:  "already has a primary key",
+		},
+		{
+			name: "validate constraint",
+			ddl:  "ALTER TABLE orders VALIDATE CONSTRAINT fk",
+			lock: lockShareUpdateExclusive,
+			scan: true,
+		},
+		{
+			name: "drop referenced column",
+			ddl:  "ALTER TABLE customers DROP COLUMN id",
+			lock: lockAccessExclusive,
+			err:  "referenced by a foreign key from public.orders",
+		},
+		{
+			name: "missing table",
+			ddl:  "ALTER TABLE nothing ADD COLUMN x int",
+			err:  "Table public.nothing does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := planChange(t, tt.ddl)
+			if got := change.lock(); got != tt.lock {
+				t.Errorf("lock = %q, want %q", got, tt.lock)
+			}
+			if change.rewrites() != tt.rewrite {
+				t.Errorf("rewrites = %v, want %v", change.rewrites(), tt.rewrite)
+			}
+			if change.scans() != tt.scan {
+				t.Errorf("scans = %v, want %v", change.scans(), tt.scan)
+			}
+			if tt.note != "" && !hasText(change.Notes, tt.note) {
+				t.Errorf("expected note containing %q, got %v", tt.note, change.Notes)
+			}
+			if tt.err == "" && len(change.Errors) > 0 {
+				t.Errorf("unexpected errors: %v", change.Errors)
+			}
+			if tt.err != "" && !hasText(change.Errors, tt.err) {
+				t.Errorf("expected error containing %q, got %v", tt.err, change.Errors)
+			}
+		})
+	}
+}
+
+func TestPlanSchemaChange_Script(t *testing.T) {
+	plan, err := planSchemaChange(`
+		CREATE TABLE audit_log (id bigserial PRIMARY KEY, order_id bigint REFERENCES orders (id), note text);
+		CREATE INDEX ON audit_log (order_id);
+		ALTER TABLE audit_log ADD COLUMN created_at timestamptz DEFAULT clock_timestamp();
+		CREATE INDEX CONCURRENTLY idx_orders_status ON orders (status);
+		DROP TABLE IF EXISTS legacy;
+		DROP TABLE customers;
+		GRANT SELECT ON orders TO reporting`, explainTestMetadata())
+	if err != nil {
+		t.Fatalf("planSchemaChange() error = %v", err)
+	}
+
+	var titles []string
+	for _, change := range plan.Changes {
+		titles = append(titles, change.Action.symbol()+" "+change.Title)
+	}
+	want := []string{
+		"+ create table public.audit_log (3 columns)",
+		"+ create index on public.audit_log (order_id)",
+		"~ alter table public.audit_log",
+		"+ create index idx_orders_status on public.orders (status)",
+		"= drop table public.legacy",
+		"- drop table public.customers",
+		"? grant SELECT ON orders...",
+	}
+	if got := strings.Join(titles, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("changes =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// Changes to a table created by the script do not lock or rewrite anything
+	if newTable := plan.Changes[2]; !newTable.newTable || newTable.rewrites() {
+		t.Errorf("changes to a new table should not rewrite: %+v", newTable)
+	}
+	if lock := plan.Changes[3].lock(); lock != lockShareUpdateExclusive {
+		t.Errorf("CREATE INDEX CONCURRENTLY lock = %q", lock)
+	}
+	if !hasText(plan.Changes[5].Errors, "fails without CASCADE") {
+		t.Errorf("expected DROP TABLE to fail on the foreign key: %v", plan.Changes[5].Errors)
+	}
+	if !hasText(plan.Warnings, "lock_timeout") {
+		t.Errorf("expected lock_timeout warning: %v", plan.Warnings)
+	}
+}
+
+func TestPlanSchemaChange_Transaction(t *testing.T) {
+	plan, err := planSchemaChange(`
+		BEGIN;
+		SET LOCAL lock_timeout = '5s';
+		ALTER TABLE orders RENAME COLUMN status TO state;
+		ALTER TABLE orders DROP COLUMN status;
+		CREATE INDEX CONCURRENTLY ON orders (state);
+		COMMIT;`, explainTestMetadata())
+	if err != nil {
+		t.Fatalf("planSchemaChange() error = %v", err)
+	}
+	if len(plan.Changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(plan.Changes))
+	}
+	if !hasText(plan.Changes[1].Errors, "Column status does not exist") {
+		t.Errorf("renamed column should no longer exist: %v", plan.Changes[1].Errors)
+	}
+	if !hasText(plan.Changes[2].Errors, "cannot run inside a transaction block") {
+		t.Errorf("expected CONCURRENTLY error: %v", plan.Changes[2].Errors)
+	}
+	if len(plan.Warnings) != 0 {
+		t.Errorf("expected no warnings with lock_timeout and a transaction, got %v", plan.Warnings)
+	}
+}
+
+func TestTypeChangeIsBinaryCompatible(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"character varying(50)", "varchar(100)", true},
+		{"character varying(100)", "varchar(50)", false},
+		{"character varying(50)", "text", true},
+		{"text", "varchar", true},
+		{"text", "varchar(10)", false},
+		{"numeric(10,2)", "numeric(12, 2)", true},
+		{"numeric(10,2)", "numeric(12,3)", false},
+		{"numeric", "numeric(12,2)", false},
+		{"integer", "int4", true},
+		{"integer", "bigint", false},
+	}
+	for _, tt := range tests {
+		if got := typeChangeIsBinaryCompatible(tt.from, tt.to); got != tt.want {
+			t.Errorf("typeChangeIsBinaryCompatible(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSchemaPlan(t *testing.T) {
+	plan, err := planSchemaChange(`
+		ALTER TABLE orders ALTER COLUMN total TYPE numeric(12,2);
+		DROP INDEX orders_status_idx;
+		ALTER TABLE orders ADD COLUMN note text DEFAULT ''`, explainTestMetadata())
+	if err != nil {
+		t.Fatalf("planSchemaChange() error = %v", err)
+	}
+	catalog := &planCatalog{
+		ServerVersion: 100000,
+		Tables:        map[string]planTableStats{"public.orders": {Rows: 1500, Bytes: 3 * 1024 * 1024}},
+		Dependents:    map[string][]string{"public.orders": {"public.order_totals"}},
+		IndexTables:   map[string]string{"public.orders_status_idx": "public.orders"},
+	}
+	plan.applyCatalog(catalog)
+
+	output := formatSchemaPlan(plan, catalog)
+	for _, want := range []string{
+		"~ alter table public.orders\n    ~ alter column total type numeric -> numeric(12, 2)  [rewrites table]",
+		"lock: ACCESS EXCLUSIVE on public.orders (all reads and writes wait) for the duration of the operation",
+		"size: ~1500 rows, 3.0 MB",
+		"note: Dependent views: public.order_totals",
+		"- drop index public.orders_status_idx on public.orders",
+		"Before PostgreSQL 11, adding a column with a default rewrites the table",
+		"Plan: 0 to create, 2 to change, 1 to drop.",
+		"Table rewrites: public.orders\n",
+		"Nothing was executed",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
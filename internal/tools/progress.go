@@ -0,0 +1,25 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// progressFromArgs returns the progress reporter for a tool call
+// The request context is injected into args by Registry.Execute; a no-op
+// reporter is returned when the client did not ask for progress
+func progressFromArgs(args map[string]interface{}) mcp.ProgressReporter {
+	ctx, _ := args["__context"].(context.Context)
+	return mcp.ProgressFromContext(ctx)
+}
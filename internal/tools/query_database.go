@@ -14,14 +14,27 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"pgedge-postgres-mcp/internal/config"
 	"pgedge-postgres-mcp/internal/database"
 	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/masking"
 	"pgedge-postgres-mcp/internal/mcp"
 )
 
 // QueryDatabaseTool creates the query_database tool
-func QueryDatabaseTool(dbClient *database.Client) Tool {
+// If masker is non-nil, its rules are applied to the results before they are returned
+// Queries run in the session's transaction when one was opened with
+// begin_transaction (transactions may be nil). The tables of other configured
+// databases are reached through federation (nil = not available).
+// Queries are recorded in the session's history, which also reuses the
+// results of repeated read-only queries (history may be nil). guard caps the
+// rows a query returns.
+func QueryDatabaseTool(dbClient *database.Client, masker *masking.Masker, transactions *TransactionManager, federation *Federation, history *QueryHistory, guard config.QueryGuardConfig) Tool {
 	return Tool{
 		Definition: mcp.Tool{
 			Name: "query_database",
@@ -55,9 +68,31 @@ DO NOT use for:
 </examples>
 
 <important>
-- All queries run in READ-ONLY transactions (no data modifications possible)
-- Results are limited to prevent excessive token usage
+- All queries run in READ-ONLY transactions (no data modifications possible),
+  unless begin_transaction(read_write=true) started a transaction for the session
+- Results are limited to prevent excessive token usage: queries without a
+  LIMIT get one, and larger LIMITs are lowered to the server's maximum, which
+  the result reports
+- The server may plan SELECTs with EXPLAIN first and refuse those estimated
+  to be too expensive, returning the plan; rewrite the query to read less
+  rather than retrying it unchanged
 - Results are returned in TSV (tab-separated values) format for efficiency
+- Sensitive columns may be masked by server policy (shown as **** or sha256:...)
+- SET (or SET LOCAL) and RESET of settings such as search_path last for the rest of the session and apply to every later query
+- A query of several statements separated by ';' runs one statement at a time,
+  each in a savepoint, and reports each statement's status, rows affected,
+  error and duration instead of rows; a failing statement does not stop the rest
+- verify=true checks the effect of data and schema changes after they run:
+  to_regclass for created and dropped tables, indexes, views and sequences,
+  and the table's row count before and after INSERT and DELETE; report its
+  evidence rather than assuming a change succeeded
+- database=<name> makes the tables of another configured database available
+  as <schema named after the database>.<table>, through postgres_fdw, so the
+  query can JOIN them with local tables; the tool reports the schema
+- include_timing=true adds execution time, rows and buffer statistics, but runs the query twice; use it only when the user asks about performance
+- Repeating an identical read-only query within a minute returns the cached
+  result, marked as such; use_cache=false runs it again when fresh data is
+  needed. get_query_history lists the queries of the session
 </important>
 
 <rate_limit_awareness>
@@ -77,7 +112,7 @@ To avoid rate limits (30,000 input tokens/minute):
 					},
 					"limit": map[string]interface{}{
 						"type":        "integer",
-						"description": "Maximum number of rows to return (default: 100, max: 1000). Automatically appended to query if not already present. Use higher limits only when necessary to avoid excessive token usage.",
+						"description": "Maximum number of rows to return (default: 100, at most the server's query_guard.max_rows, 1000 by default). Automatically appended to query if not already present. Use higher limits only when necessary to avoid excessive token usage.",
 						"default":     100,
 						"minimum":     1,
 						"maximum":     1000,
@@ -88,11 +123,30 @@ To avoid rate limits (30,000 input tokens/minute):
 						"default":     0,
 						"minimum":     0,
 					},
+					"include_timing": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also report planning and execution time, rows and shared buffer hits/reads from EXPLAIN (ANALYZE, BUFFERS, TIMING). The query is executed a second time to collect them.",
+						"default":     false,
+					},
+					"database": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of another configured database whose tables the query uses, as <schema>.<table> where the schema is named after the database (e.g. analytics.orders). Sets up postgres_fdw when setup_foreign_server is enabled.",
+					},
+					"verify": map[string]interface{}{
+						"type":        "boolean",
+						"description": "After a data or schema change, check its effect (created or dropped objects with to_regclass, row count change for INSERT and DELETE) and include the evidence in the result. Row counts scan the table.",
+						"default":     false,
+					},
+					"use_cache": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Reuse the result of an identical query run recently in this session (default: true). Set to false when the data may have changed.",
+						"default":     true,
+					},
 				},
 				Required: []string{"query"},
 			},
 		},
-		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+		Handler: history.wrap(dbClient, transactions, func(args map[string]interface{}) (mcp.ToolResponse, error) {
 			query, ok := args["query"].(string)
 			if !ok {
 				return mcp.NewToolError("Missing or invalid 'query' parameter")
@@ -147,9 +201,72 @@ To avoid rate limits (30,000 input tokens/minute):
 				return mcp.NewToolError(mcp.DatabaseNotReadyError)
 			}
 
+			// The tables of another configured database are reached as
+			// foreign tables
+			if foreignDB := ValidateOptionalStringParam(args, "database", ""); foreignDB != "" {
+				ctx, ok := args["__context"].(context.Context)
+				if !ok {
+					ctx = context.Background()
+				}
+				pool := dbClient.GetPoolFor(connStr)
+				if pool == nil {
+					return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+				}
+				schema, wired, err := wireForeignDatabase(ctx, pool, federation, foreignDB)
+				if err != nil {
+					return mcp.NewToolError(fmt.Sprintf("%s%v", connectionMessage, err))
+				}
+				if wired {
+					if err := dbClient.LoadMetadataFor(connStr); err != nil {
+						logging.Warn("query_database_metadata_refresh_failed", "error", err)
+					}
+					connectionMessage += fmt.Sprintf("Set up database %s as a foreign server; its tables are in schema %s\n\n", foreignDB, quoteIdentIfNeeded(schema))
+				} else {
+					connectionMessage += fmt.Sprintf("Tables of database %s are in schema %s\n\n", foreignDB, quoteIdentIfNeeded(schema))
+				}
+			}
+
 			// Use the cleaned query as SQL
 			sqlQuery := strings.TrimSpace(queryCtx.CleanedQuery)
 
+			// Scripts of several statements run one statement at a time
+			statements, err := splitQueryScript(sqlQuery)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("%sSQL Query:\n%s\n\nError: %v", connectionMessage, sqlQuery, err))
+			}
+
+			// SET and RESET apply to the rest of the session, on every
+			// pooled connection
+			var setStmt *database.SetStatement
+			if statements == nil {
+				setStmt, err = database.ParseSetStatement(sqlQuery)
+				if err != nil {
+					return mcp.NewToolError(fmt.Sprintf("%sSQL Query:\n%s\n\nError: %v", connectionMessage, sqlQuery, err))
+				}
+			}
+
+			// Queries run in the session's transaction, if one is open
+			reqCtx, ok := args["__context"].(context.Context)
+			if !ok {
+				reqCtx = context.Background()
+			}
+			txn := transactions.current(reqCtx)
+			if txn != nil {
+				if txn.client != dbClient || txn.connStr != connStr {
+					return mcp.NewToolError(fmt.Sprintf("A transaction is in progress on %s; commit or roll it back before querying another database",
+						database.SanitizeConnStr(txn.connStr)))
+				}
+				txn.mu.Lock()
+				defer txn.mu.Unlock()
+			}
+
+			if setStmt != nil {
+				return applySessionSetting(reqCtx, dbClient, connStr, connectionMessage, setStmt, txn)
+			}
+			if statements != nil {
+				return runQueryScript(reqCtx, dbClient, connStr, connectionMessage, statements, txn, ValidateBoolParam(args, "verify", false))
+			}
+
 			// Determine the limit to use
 			limit := 100 // default
 			if limitVal, ok := args["limit"]; ok {
@@ -172,10 +289,36 @@ To avoid rate limits (30,000 input tokens/minute):
 				}
 			}
 
-			// Track if query already had LIMIT/OFFSET clauses
-			upperQuery := strings.ToUpper(sqlQuery)
-			hasExistingLimit := strings.Contains(upperQuery, "LIMIT")
-			hasExistingOffset := strings.Contains(upperQuery, "OFFSET")
+			// The row guard caps the limit, and the query's own LIMIT below
+			maxRows := guard.MaxRows
+			limitLowered := false
+			if maxRows > 0 && limit > maxRows {
+				limit = maxRows
+				limitLowered = true
+			}
+
+			includeTiming := ValidateBoolParam(args, "include_timing", false)
+
+			// Plan the checks of a data or schema change before it runs
+			verify := ValidateBoolParam(args, "verify", false)
+			var checks []*writeCheck
+			if verify {
+				checks = planWriteChecks(sqlQuery)
+			}
+
+			// Track if query already had top-level LIMIT/OFFSET clauses;
+			// statements that do not return rows cannot take them
+			rowsReturned := returnsRows(sqlQuery)
+			clauses := parseRowClauses(sqlQuery)
+			hasExistingLimit := clauses.limited || !rowsReturned
+			hasExistingOffset := clauses.offset || !rowsReturned
+
+			// A LIMIT of the query's own above the maximum is lowered by
+			// running the query as a subquery
+			guarded := false
+			if rowsReturned {
+				sqlQuery, guarded = guardRowLimit(clauses, maxRows)
+			}
 
 			// Only inject LIMIT/OFFSET if query doesn't already have them
 			// Fetch limit+1 to detect if more rows exist
@@ -186,17 +329,12 @@ To avoid rate limits (30,000 input tokens/minute):
 				sqlQuery = fmt.Sprintf("%s OFFSET %d", sqlQuery, offset)
 			}
 
-			// Execute the SQL query on the appropriate connection in a read-only transaction
-			ctx := context.Background()
-			pool := dbClient.GetPoolFor(connStr)
-			if pool == nil {
-				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
-			}
-
-			// Begin a transaction with read-only protection
-			tx, err := pool.Begin(ctx)
-			if err != nil {
-				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			// Execute the SQL query on the appropriate connection in a read-only
+			// transaction, or in a savepoint of the session's transaction
+			ctx := reqCtx
+			tx, errResp := beginQuery(ctx, dbClient, connStr, txn)
+			if errResp != nil {
+				return *errResp, nil
 			}
 
 			// Track whether transaction was committed
@@ -215,12 +353,40 @@ To avoid rate limits (30,000 input tokens/minute):
 				}
 			}()
 
-			// Set transaction to read-only to prevent any data modifications
-			_, err = tx.Exec(ctx, "SET TRANSACTION READ ONLY")
-			if err != nil {
-				return mcp.NewToolError(fmt.Sprintf("Failed to set transaction read-only: %v", err))
+			// Set transaction to read-only to prevent any data modifications;
+			// the session's transaction has the access mode it was begun with
+			if txn == nil {
+				_, err = tx.Exec(ctx, "SET TRANSACTION READ ONLY")
+				if err != nil {
+					return mcp.NewToolError(fmt.Sprintf("Failed to set transaction read-only: %v", err))
+				}
+			}
+
+			// The cost guard refuses queries the planner expects to be too
+			// expensive before they run
+			if guard.Explain && costGuardApplies(sqlQuery) {
+				estimate, err := estimatePlan(ctx, tx, sqlQuery)
+				if err != nil {
+					return mcp.NewToolError(fmt.Sprintf("%sSQL Query:\n%s\n\nError executing query: %v", connectionMessage, sqlQuery, err))
+				}
+				if violation := costGuardViolation(estimate, guard); violation != "" {
+					plan, err := textPlan(ctx, tx, sqlQuery)
+					if err != nil {
+						plan = fmt.Sprintf("(could not be shown: %v)", err)
+					}
+					logging.Info("query_database_refused",
+						"estimated_cost", estimate.cost,
+						"estimated_rows", estimate.rows,
+					)
+					return mcp.NewToolError(connectionMessage + formatCostGuardRefusal(sqlQuery, violation, plan))
+				}
+			}
+
+			for _, check := range checks {
+				check.prepare(ctx, tx)
 			}
 
+			started := time.Now()
 			rows, err := tx.Query(ctx, sqlQuery)
 			if err != nil {
 				return mcp.NewToolError(fmt.Sprintf("%sSQL Query:\n%s\n\nError executing query: %v", connectionMessage, sqlQuery, err))
@@ -248,15 +414,44 @@ To avoid rate limits (30,000 input tokens/minute):
 				return mcp.NewToolError(fmt.Sprintf("Error iterating rows: %v", err))
 			}
 
+			var verifications []writeVerification
+			if verify {
+				verifications = verifyWrites(ctx, tx, checks, rows.CommandTag())
+			}
+
+			var timing *queryTiming
+			if includeTiming {
+				timing = &queryTiming{RoundTrip: time.Since(started)}
+				analyzeTiming(ctx, tx, sqlQuery, timing)
+			}
+
 			// Check if results were truncated (we fetched limit+1 to detect this)
 			wasTruncated := false
 			if !hasExistingLimit && limit > 0 && len(results) > limit {
 				wasTruncated = true
 				results = results[:limit] // Truncate to requested limit
+				recordTruncated(args)
+			}
+			guardTruncated := false
+			if guarded && len(results) > maxRows {
+				guardTruncated = true
+				results = results[:maxRows]
+				recordTruncated(args)
+			}
+
+			// Apply masking rules before results leave the server
+			maskedValues := 0
+			if masker.Enabled() {
+				maskColumns, err := resolveMaskingColumns(ctx, tx, fieldDescriptions)
+				if err != nil {
+					return mcp.NewToolError(fmt.Sprintf("Failed to resolve source tables for masking: %v", err))
+				}
+				maskedValues = masker.Apply(maskColumns, results)
 			}
 
 			// Format results as TSV (tab-separated values)
 			resultsTSV := FormatResultsAsTSV(columnNames, results)
+			recordRowsReturned(args, len(results))
 
 			// Commit the read-only transaction
 			if err := tx.Commit(ctx); err != nil {
@@ -273,6 +468,9 @@ To avoid rate limits (30,000 input tokens/minute):
 			} else {
 				sb.WriteString(connectionMessage)
 			}
+			if txn != nil {
+				sb.WriteString(fmt.Sprintf("In transaction: %s (not yet committed)\n\n", txn.describe()))
+			}
 
 			sb.WriteString(fmt.Sprintf("SQL Query:\n%s\n\n", sqlQuery))
 
@@ -294,16 +492,353 @@ To avoid rate limits (30,000 input tokens/minute):
 				sb.WriteString(fmt.Sprintf("Results (%d rows):\n%s", len(results), resultsTSV))
 			}
 
+			switch {
+			case guardTruncated:
+				sb.WriteString(fmt.Sprintf("\n\nRow guard: the query's LIMIT allows more than the server's maximum of %d rows, so only the first %d rows are shown. Narrow the query with WHERE, aggregate it, or page through it with LIMIT and OFFSET.",
+					maxRows, maxRows))
+			case limitLowered && wasTruncated:
+				sb.WriteString(fmt.Sprintf("\n\nRow guard: limit was lowered to the server's maximum of %d rows. Narrow the query with WHERE, aggregate it, or page through it with offset.",
+					maxRows))
+			}
+
+			if timing != nil {
+				sb.WriteString("\n\n" + formatTiming(timing))
+			}
+			if verify {
+				sb.WriteString("\n\n" + formatVerifications(verifications, rows.CommandTag()))
+			}
+
 			// Log execution metrics
 			logging.Info("query_database_executed",
 				"query_length", len(sqlQuery),
 				"rows_returned", len(results),
 				"offset", offset,
-				"was_truncated", wasTruncated,
+				"was_truncated", wasTruncated || guardTruncated,
+				"row_guard", guarded || limitLowered,
+				"masked_values", maskedValues,
+				"include_timing", includeTiming,
+				"verify", verify,
 				"estimated_tokens", len(resultsTSV)/4,
 			)
 
 			return mcp.NewToolSuccess(sb.String())
-		},
+		}),
+	}
+}
+
+// applySessionSetting runs a SET or RESET statement and records it, so
+// that it is applied to every connection later queries run on
+// txn is the session's open transaction, if any, which the caller has locked
+func applySessionSetting(ctx context.Context, dbClient *database.Client, connStr, connectionMessage string, stmt *database.SetStatement, txn *openTransaction) (mcp.ToolResponse, error) {
+	// Run the statement first, so invalid names and values are reported
+	// before they are applied to other connections
+	tx, errResp := beginQuery(ctx, dbClient, connStr, txn)
+	if errResp != nil {
+		return *errResp, nil
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // rollback in defer after commit is expected to fail
+	}()
+	if _, err := tx.Exec(ctx, stmt.SQL()); err != nil {
+		return mcp.NewToolError(fmt.Sprintf("%sSQL Query:\n%s\n\nError executing query: %v", connectionMessage, stmt.SQL(), err))
+	}
+	var value string
+	if stmt.Name != "" {
+		if err := tx.QueryRow(ctx, "SELECT current_setting($1)", stmt.Name).Scan(&value); err != nil {
+			return mcp.NewToolError(fmt.Sprintf("Failed to read setting %s: %v", stmt.Name, err))
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return mcp.NewToolError(fmt.Sprintf("Failed to commit transaction: %v", err))
+	}
+	if err := dbClient.ApplySessionSetting(connStr, stmt); err != nil {
+		return mcp.NewToolError(fmt.Sprintf("Failed to apply session setting: %v", err))
+	}
+
+	logging.Info("query_database_session_setting", "name", stmt.Name, "reset", stmt.IsReset())
+
+	var sb strings.Builder
+	if connectionMessage == "" {
+		sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+	} else {
+		sb.WriteString(connectionMessage)
+	}
+	sb.WriteString(fmt.Sprintf("SQL Query:\n%s\n\n", stmt.SQL()))
+	switch {
+	case stmt.Name == "":
+		sb.WriteString("All session settings were reset to their defaults.")
+	case stmt.IsReset():
+		sb.WriteString(fmt.Sprintf("%s was reset to its default (%s) for the rest of the session.", stmt.Name, value))
+	default:
+		sb.WriteString(fmt.Sprintf("%s is now %s for the rest of the session; it applies to every later query until it is reset.", stmt.Name, value))
+		if stmt.Local {
+			sb.WriteString("\nSET LOCAL is kept for the session like SET, since each query runs in its own transaction.")
+		}
+	}
+	if settings := dbClient.SessionSettings(connStr); len(settings) > 0 {
+		sb.WriteString("\n\nSession settings:")
+		for _, setting := range settings {
+			sb.WriteString(fmt.Sprintf("\n  %s = %s", setting.Name, setting.Value))
+		}
+	}
+	return mcp.NewToolSuccess(sb.String())
+}
+
+// splitQueryScript splits a query of several statements into their text. It
+// returns nil for a single statement, which runs as a query.
+func splitQueryScript(query string) ([]string, error) {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return nil, nil // The statement runs as a query and reports the error
+	}
+	stmts := splitStatements(tokens)
+	if len(stmts) < 2 {
+		return nil, nil
+	}
+
+	statements := make([]string, 0, len(stmts))
+	for i, stmt := range stmts {
+		text := query[stmt[0].start:stmt[len(stmt)-1].end]
+		reason := scriptStatementError(stmt)
+		// SET would outlast the script on the pooled connection; SET LOCAL
+		// ends with it
+		if reason == "" && stmt[0].isKeyword("SET", "RESET") && (len(stmt) < 2 || !stmt[1].isKeyword("LOCAL")) {
+			reason = "run " + stmt[0].upper + " on its own so it lasts for the session, or use SET LOCAL"
+		}
+		if reason != "" {
+			return nil, fmt.Errorf("statement %d cannot run in a script: %s\n%s", i+1, reason, text)
+		}
+		statements = append(statements, text)
+	}
+	return statements, nil
+}
+
+// returnsRows reports whether a statement returns rows and so can take a
+// LIMIT: a SELECT, VALUES or TABLE, or a WITH query whose main statement is
+// a SELECT. EXPLAIN passes the LIMIT on to the statement it explains.
+func returnsRows(query string) bool {
+	toks, err := tokenizeSQL(query)
+	if err != nil || len(toks) == 0 {
+		return true // Run as written; the server reports the error
+	}
+	switch first := toks[0]; {
+	case first.isKeyword("SELECT", "VALUES", "TABLE", "EXPLAIN"), first.isPunct("("):
+		return true
+	case first.isKeyword("WITH"):
+		depth := 0
+		for i, t := range toks {
+			switch {
+			case t.isPunct("("):
+				depth++
+			case t.isPunct(")"):
+				depth--
+			case depth == 0 && t.isKeyword("INSERT", "UPDATE", "DELETE", "MERGE") && !toks[i-1].isKeyword("FOR", "NO", "KEY"):
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// runQueryScript runs the statements of a script one at a time, each in a
+// savepoint, so a failing statement is rolled back on its own and the rest
+// still run. The script runs in a read-only transaction, or in the
+// session's open transaction, whose lock the caller holds. With verify, the
+// effect of each statement is checked after it runs.
+func runQueryScript(ctx context.Context, dbClient *database.Client, connStr, connectionMessage string, statements []string, txn *openTransaction, verify bool) (mcp.ToolResponse, error) {
+	tx, errResp := beginQuery(ctx, dbClient, connStr, txn)
+	if errResp != nil {
+		return *errResp, nil
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // rollback in defer after commit is expected to fail
+	}()
+	if txn == nil {
+		if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+			return mcp.NewToolError(fmt.Sprintf("Failed to set transaction read-only: %v", err))
+		}
+	}
+
+	var exec scriptExecer = tx
+	if verify {
+		exec = &verifyingExecer{tx: tx}
+	}
+	results, aborted := runScript(ctx, exec, statements, true)
+	if !aborted {
+		if err := tx.Commit(ctx); err != nil {
+			return mcp.NewToolError(fmt.Sprintf("Failed to commit transaction: %v", err))
+		}
+	}
+
+	var sb strings.Builder
+	if connectionMessage == "" {
+		sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+	} else {
+		sb.WriteString(connectionMessage)
+	}
+	if txn != nil {
+		sb.WriteString(fmt.Sprintf("In transaction: %s (not yet committed)\n\n", txn.describe()))
+	}
+	sb.WriteString(fmt.Sprintf("Script (%d statements, each run in its own savepoint):\n", len(results)))
+	sb.WriteString(formatQueryScriptResults(results))
+	sb.WriteString("\n")
+	sb.WriteString(formatQueryScriptOutcome(results, aborted, txn != nil))
+
+	failed := 0
+	for _, result := range results {
+		if result.Status != scriptOK {
+			failed++
+		}
+	}
+	logging.Info("query_database_script_executed",
+		"statements", len(results),
+		"failed", failed,
+		"in_transaction", txn != nil,
+	)
+
+	return mcp.NewToolSuccess(sb.String())
+}
+
+// formatQueryScriptResults lists the outcome and duration of each statement
+// of a query_database script
+func formatQueryScriptResults(results []scriptStatement) string {
+	var sb strings.Builder
+	for i, result := range results {
+		var details []string
+		if result.Tag != "" {
+			details = append(details, result.Tag)
+		}
+		if result.Status != scriptSkipped {
+			details = append(details, fmt.Sprintf("%.3f ms", float64(result.Duration)/float64(time.Millisecond)))
+		}
+		status := string(result.Status)
+		if len(details) > 0 {
+			status += " (" + strings.Join(details, ", ") + ")"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s: %s\n", i+1, status, statementPreview(result.SQL)))
+		if result.Error != "" {
+			sb.WriteString(fmt.Sprintf("   Error: %s\n", result.Error))
+		}
+		for _, v := range result.Verifications {
+			verdict := "FAILED"
+			if v.Passed {
+				verdict = "PASSED"
+			}
+			sb.WriteString(fmt.Sprintf("   Verification %s: %s\n", verdict, v.Detail))
+		}
+	}
+	return sb.String()
+}
+
+// formatQueryScriptOutcome summarizes a query_database script
+func formatQueryScriptOutcome(results []scriptStatement, aborted, inTransaction bool) string {
+	counts := make(map[scriptStatus]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	msg := fmt.Sprintf("%d of %d statements succeeded", counts[scriptOK], len(results))
+	if counts[scriptRolledBack] > 0 {
+		msg += fmt.Sprintf(", %d failed and were rolled back to their savepoints", counts[scriptRolledBack])
+	}
+	if counts[scriptSkipped] > 0 {
+		msg += fmt.Sprintf(", %d were skipped", counts[scriptSkipped])
+	}
+	msg += "."
+	switch {
+	case aborted:
+		msg += " The script was stopped and rolled back."
+	case inTransaction:
+		msg += " The statements that succeeded are part of the open transaction."
+	}
+	return msg + " Rows are only returned for a single statement; run a SELECT on its own to see its rows.\n"
+}
+
+// beginQuery starts the transaction a query runs in: a savepoint of the
+// session's open transaction, whose lock the caller holds, else a new
+// transaction on a pooled connection
+func beginQuery(ctx context.Context, dbClient *database.Client, connStr string, txn *openTransaction) (pgx.Tx, *mcp.ToolResponse) {
+	var tx pgx.Tx
+	var message string
+	if txn != nil {
+		var err error
+		if tx, err = txn.savepoint(ctx); err != nil {
+			message = fmt.Sprintf("Failed to start statement in transaction: %v", err)
+		}
+	} else if pool := dbClient.GetPoolFor(connStr); pool == nil {
+		message = fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr))
+	} else {
+		var err error
+		if tx, err = pool.Begin(ctx); err != nil {
+			message = fmt.Sprintf("Failed to begin transaction: %v", err)
+		}
+	}
+	if message != "" {
+		resp, _ := mcp.NewToolError(message) //nolint:errcheck // NewToolError never fails
+		return nil, &resp
+	}
+	return tx, nil
+}
+
+// resolveMaskingColumns maps result columns to their source tables and
+// columns so that masking rules match the source column even when the query
+// renames it. Computed columns have no table and keep their result name.
+func resolveMaskingColumns(ctx context.Context, tx pgx.Tx, fields []pgconn.FieldDescription) ([]masking.Column, error) {
+	type source struct {
+		table  uint32
+		attnum int16
+	}
+	var attrels []uint32
+	var attnums []int16
+	for _, fd := range fields {
+		if fd.TableOID != 0 {
+			attrels = append(attrels, fd.TableOID)
+			attnums = append(attnums, int16(fd.TableAttributeNumber))
+		}
+	}
+
+	tableNames := make(map[uint32]string)
+	columnNames := make(map[source]string)
+	if len(attrels) > 0 {
+		rows, err := tx.Query(ctx, `
+			SELECT c.oid, n.nspname || '.' || c.relname, f.attnum, coalesce(a.attname, '')
+			FROM unnest($1::oid[], $2::int2[]) AS f(attrelid, attnum)
+			JOIN pg_catalog.pg_class c ON c.oid = f.attrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = f.attrelid AND a.attnum = f.attnum`, attrels, attnums)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var oid uint32
+			var table, column string
+			var attnum int16
+			if err := rows.Scan(&oid, &table, &attnum, &column); err != nil {
+				return nil, err
+			}
+			tableNames[oid] = table
+			columnNames[source{oid, attnum}] = column
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	columns := make([]masking.Column, len(fields))
+	for i, fd := range fields {
+		name := fd.Name
+		if fd.TableOID != 0 {
+			if column := columnNames[source{fd.TableOID, int16(fd.TableAttributeNumber)}]; column != "" {
+				name = column
+			}
+		}
+		columns[i] = masking.Column{
+			Table: tableNames[fd.TableOID],
+			Name:  name,
+		}
 	}
+	return columns, nil
 }
@@ -11,8 +11,19 @@
 package tools
 
 import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/masking"
+	"pgedge-postgres-mcp/internal/mcp"
 )
 
 func TestFormatTSVValue(t *testing.T) {
@@ -176,3 +187,218 @@ func TestFormatResultsAsTSV(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitQueryScript(t *testing.T) {
+	for _, query := range []string{
+		"SELECT 1",
+		"SELECT 'a;b';",
+		"SET search_path TO app",
+	} {
+		statements, err := splitQueryScript(query)
+		if err != nil || statements != nil {
+			t.Errorf("splitQueryScript(%q) = %v, %v; want a single statement", query, statements, err)
+		}
+	}
+
+	statements, err := splitQueryScript("SET LOCAL work_mem = '64MB'; SELECT count(*) FROM orders; UPDATE orders SET note = 'x';")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statements) != 3 || statements[1] != "SELECT count(*) FROM orders" {
+		t.Errorf("unexpected statements: %q", statements)
+	}
+
+	for _, query := range []string{
+		"SET search_path TO app; SELECT 1",
+		"SELECT 1; RESET ALL",
+		"BEGIN; SELECT 1; COMMIT",
+		"SELECT 1; VACUUM orders",
+	} {
+		if _, err := splitQueryScript(query); err == nil {
+			t.Errorf("splitQueryScript(%q) should fail", query)
+		}
+	}
+}
+
+func TestFormatQueryScript(t *testing.T) {
+	results, aborted := runScript(context.Background(), &fakeScriptTx{}, []string{
+		"INSERT INTO orders VALUES (1)",
+		"INSERT INTO fail VALUES (1)",
+		"DELETE FROM orders",
+	}, true)
+	results[0].Duration = 1500 * time.Microsecond
+
+	output := formatQueryScriptResults(results) + formatQueryScriptOutcome(results, aborted, true)
+	for _, want := range []string{
+		"1. OK (INSERT 1, 1.500 ms): INSERT INTO orders VALUES (1)",
+		"2. ROLLED BACK (",
+		"   Error: ERROR: relation \"fail\" does not exist",
+		"3. OK (DELETE 1, ",
+		"2 of 3 statements succeeded, 1 failed and were rolled back to their savepoints.",
+		"part of the open transaction",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestReturnsRows(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT * FROM orders":                                                true,
+		"(SELECT 1) UNION (SELECT 2)":                                         true,
+		"VALUES (1), (2)":                                                     true,
+		"WITH o AS (SELECT * FROM orders) SELECT * FROM o":                    true,
+		"WITH o AS (SELECT 1) SELECT * FROM orders FOR UPDATE":                true,
+		"WITH d AS (DELETE FROM a RETURNING *) INSERT INTO b SELECT * FROM d": false,
+		"INSERT INTO orders VALUES (1)":                                       false,
+		"UPDATE orders SET note = 'x'":                                        false,
+		"CREATE TABLE t (id int)":                                             false,
+		"SHOW work_mem":                                                       false,
+	}
+	for query, want := range tests {
+		if got := returnsRows(query); got != want {
+			t.Errorf("returnsRows(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestQueryDatabase_ShutdownCancelsQuery(t *testing.T) {
+	connStr := os.Getenv("TEST_PGEDGE_POSTGRES_CONNECTION_STRING")
+	if connStr == "" {
+		t.Skip("TEST_PGEDGE_POSTGRES_CONNECTION_STRING not set, skipping integration test")
+	}
+
+	client := database.NewClientWithConnectionString(connStr, nil)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer client.Close()
+	if err := client.LoadMetadata(); err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+
+	registry := NewRegistry()
+	registry.Register("query_database", QueryDatabaseTool(client, nil, nil, nil, nil, config.QueryGuardConfig{}))
+	server := mcp.NewServer(registry)
+
+	// Drive the stdio loop, whose requests run in the drain context
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, stdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdinReader, stdoutWriter
+	defer func() { os.Stdin, os.Stdout = stdin, stdout }()
+	defer stdinWriter.Close()
+
+	responses := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdoutReader)
+		for scanner.Scan() {
+			responses <- scanner.Text()
+		}
+	}()
+	go server.Run() //nolint:errcheck // Run returns nil once shutdown has begun
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"query_database","arguments":{"query":"SELECT pg_sleep(60)"}}}`
+	if _, err := stdinWriter.WriteString(request + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond) // Let the query start
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := server.Shutdown(ctx); err == nil {
+		t.Error("Expected an error when the drain timeout expires")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Shutdown took %s; the query was not cancelled", elapsed)
+	}
+
+	select {
+	case response := <-responses:
+		if !strings.Contains(response, `"isError":true`) {
+			t.Errorf("Expected the cancelled query to fail, got %s", response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("No response for the cancelled query")
+	}
+}
+
+func TestResolveMaskingColumns_Database(t *testing.T) {
+	connStr := os.Getenv("TEST_PGEDGE_POSTGRES_CONNECTION_STRING")
+	if connStr == "" {
+		t.Skip("TEST_PGEDGE_POSTGRES_CONNECTION_STRING not set, skipping integration test")
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer conn.Close(ctx)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // the test's changes are discarded
+
+	for _, sql := range []string{
+		"CREATE TEMP TABLE masked_customers (id int, ssn text)",
+		"INSERT INTO masked_customers VALUES (1, '123-45-6789')",
+	} {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+
+	masker, err := masking.New(&config.MaskingConfig{
+		Enabled: true,
+		Rules:   []config.MaskingRule{{Table: `\.masked_customers$`, Column: "^ssn$"}},
+	})
+	if err != nil {
+		t.Fatalf("masking.New() error = %v", err)
+	}
+
+	for _, query := range []string{
+		"SELECT id, ssn AS x FROM masked_customers",
+		"SELECT id, ssn::text FROM masked_customers",
+		"WITH c AS (SELECT id, ssn AS y FROM masked_customers) SELECT id, y FROM c",
+		"SELECT id, z FROM (SELECT id, ssn AS z FROM masked_customers) s",
+	} {
+		t.Run(query, func(t *testing.T) {
+			rows, err := tx.Query(ctx, query)
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			fields := rows.FieldDescriptions()
+			var values [][]interface{}
+			for rows.Next() {
+				row, err := rows.Values()
+				if err != nil {
+					t.Fatal(err)
+				}
+				values = append(values, row)
+			}
+			rows.Close()
+
+			columns, err := resolveMaskingColumns(ctx, tx, fields)
+			if err != nil {
+				t.Fatalf("resolveMaskingColumns() error = %v", err)
+			}
+			masker.Apply(columns, values)
+			if values[0][0] != int32(1) {
+				t.Errorf("id = %v, want 1", values[0][0])
+			}
+			if values[0][1] != masking.DefaultReplacement {
+				t.Errorf("ssn = %v, want it masked", values[0][1])
+			}
+		})
+	}
+}
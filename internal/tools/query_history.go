@@ -0,0 +1,487 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+const (
+	// defaultQueryHistorySize is the number of queries kept per session
+	// when the configuration does not set one
+	defaultQueryHistorySize = 50
+
+	// maxCachedResultBytes bounds the size of a cached query result; larger
+	// results are not cached
+	maxCachedResultBytes = 256 * 1024
+
+	// maxHistoryQueryBytes bounds the query text kept in the history
+	maxHistoryQueryBytes = 4000
+
+	// maxHistoryErrorBytes bounds the error message kept in the history
+	maxHistoryErrorBytes = 200
+)
+
+// QueryHistoryEntry is a query run with query_database
+type QueryHistoryEntry struct {
+	Time     time.Time
+	Database string // Sanitized connection string
+	Query    string
+	Rows     int
+	Duration time.Duration
+	Error    string // Empty if the query succeeded
+	Cached   bool   // The result was reused from an identical earlier query
+}
+
+// cachedQueryResult is the response of a query, reused for identical
+// queries of the session until it expires
+type cachedQueryResult struct {
+	response     mcp.ToolResponse
+	rows         int
+	rowsReported bool
+	truncated    bool
+	stored       time.Time
+}
+
+// queryHistorySession holds the queries and cached results of a session
+type queryHistorySession struct {
+	entries  []QueryHistoryEntry // Ring buffer, oldest at next once full
+	next     int
+	lastUsed time.Time
+	results  map[string]*cachedQueryResult
+}
+
+// QueryHistory keeps the recent queries of each session, the conversation
+// key used by get_context_usage, and caches the results of read-only
+// queries so an identical query repeated within the cache TTL is not run
+// again. Cached results are dropped when a tool changes the database.
+type QueryHistory struct {
+	mu       sync.Mutex
+	sessions map[string]*queryHistorySession
+	size     int
+	cacheTTL time.Duration // 0 = results are not cached
+	now      func() time.Time
+}
+
+// NewQueryHistory creates a query history configured by cfg
+func NewQueryHistory(cfg config.QueryHistoryConfig) *QueryHistory {
+	h := &QueryHistory{
+		sessions: make(map[string]*queryHistorySession),
+		now:      time.Now,
+	}
+	h.Configure(cfg)
+	return h
+}
+
+// Configure applies new settings; the kept queries of each session are
+// trimmed when the size shrinks, and cached results are dropped
+func (h *QueryHistory) Configure(cfg config.QueryHistoryConfig) {
+	size := cfg.Size
+	if size <= 0 {
+		size = defaultQueryHistorySize
+	}
+	var ttl time.Duration
+	if cfg.CacheEnabled() {
+		ttl = time.Duration(cfg.CacheTTLSeconds) * time.Second
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if size != h.size {
+		for _, session := range h.sessions {
+			entries := session.ordered()
+			if len(entries) > size {
+				entries = entries[len(entries)-size:]
+			}
+			session.entries = entries
+			session.next = len(entries) % size
+		}
+	}
+	h.size = size
+	h.cacheTTL = ttl
+	for _, session := range h.sessions {
+		session.results = nil
+	}
+}
+
+// ordered returns the session's queries, oldest first
+func (s *queryHistorySession) ordered() []QueryHistoryEntry {
+	entries := make([]QueryHistoryEntry, 0, len(s.entries))
+	entries = append(entries, s.entries[s.next:]...)
+	return append(entries, s.entries[:s.next]...)
+}
+
+// sessionLocked returns a session, creating it if needed and forgetting
+// the least recently active session to make room
+// The caller must hold h.mu
+func (h *QueryHistory) sessionLocked(key string) *queryHistorySession {
+	session, ok := h.sessions[key]
+	if !ok {
+		if len(h.sessions) >= maxTrackedConversations {
+			var oldestKey string
+			var oldest time.Time
+			for k, s := range h.sessions {
+				if oldestKey == "" || s.lastUsed.Before(oldest) {
+					oldestKey, oldest = k, s.lastUsed
+				}
+			}
+			delete(h.sessions, oldestKey)
+		}
+		session = &queryHistorySession{}
+		h.sessions[key] = session
+	}
+	session.lastUsed = h.now()
+	return session
+}
+
+// Record adds a query to a session's history, replacing the oldest once
+// the history is full
+func (h *QueryHistory) Record(sessionKey string, entry QueryHistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.sessionLocked(sessionKey)
+	if len(session.entries) < h.size {
+		session.entries = append(session.entries, entry)
+		session.next = len(session.entries) % h.size
+		return
+	}
+	session.entries[session.next] = entry
+	session.next = (session.next + 1) % h.size
+}
+
+// Entries returns a session's queries, newest first
+func (h *QueryHistory) Entries(sessionKey string) []QueryHistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, ok := h.sessions[sessionKey]
+	if !ok {
+		return nil
+	}
+	entries := session.ordered()
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// cachedResult returns a session's unexpired result for a query key
+func (h *QueryHistory) cachedResult(sessionKey, key string) (*cachedQueryResult, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, ok := h.sessions[sessionKey]
+	if !ok || h.cacheTTL <= 0 {
+		return nil, false
+	}
+	result, ok := session.results[key]
+	if !ok || h.now().Sub(result.stored) >= h.cacheTTL {
+		return nil, false
+	}
+	return result, true
+}
+
+// storeResult caches a query's result for the session, dropping expired
+// results. A session caches at most as many results as it keeps queries.
+func (h *QueryHistory) storeResult(sessionKey, key string, result *cachedQueryResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cacheTTL <= 0 {
+		return
+	}
+	session := h.sessionLocked(sessionKey)
+	if session.results == nil {
+		session.results = make(map[string]*cachedQueryResult)
+	}
+	var oldestKey string
+	for k, r := range session.results {
+		if h.now().Sub(r.stored) >= h.cacheTTL {
+			delete(session.results, k)
+		} else if oldestKey == "" || r.stored.Before(session.results[oldestKey].stored) {
+			oldestKey = k
+		}
+	}
+	if len(session.results) >= h.size && oldestKey != "" {
+		delete(session.results, oldestKey)
+	}
+	session.results[key] = result
+}
+
+// InvalidateResults drops the cached results of every session, after a
+// tool changed a database or the masking rules changed
+func (h *QueryHistory) InvalidateResults() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, session := range h.sessions {
+		session.results = nil
+	}
+}
+
+// wrap records the queries a query_database handler runs in the session's
+// history, and answers identical read-only queries from the cache while
+// their result is fresh. A nil history returns the handler unchanged.
+func (h *QueryHistory) wrap(dbClient *database.Client, transactions *TransactionManager,
+	handler func(args map[string]interface{}) (mcp.ToolResponse, error)) func(args map[string]interface{}) (mcp.ToolResponse, error) {
+	if h == nil {
+		return handler
+	}
+	return func(args map[string]interface{}) (mcp.ToolResponse, error) {
+		query, ok := args["query"].(string)
+		if !ok || strings.TrimSpace(query) == "" {
+			return handler(args)
+		}
+		ctx, ok := args["__context"].(context.Context)
+		if !ok || ctx == nil {
+			ctx = context.Background()
+		}
+		sessionKey := conversationKey(ctx)
+
+		connStr := dbClient.GetDefaultConnection()
+		queryCtx := database.ParseQueryForConnection(query)
+		if queryCtx.ConnectionString != "" {
+			connStr = queryCtx.ConnectionString
+		}
+		entry := QueryHistoryEntry{
+			Time:     h.now(),
+			Database: database.SanitizeConnStr(connStr),
+			Query:    truncateHistoryText(strings.TrimSpace(query), maxHistoryQueryBytes),
+		}
+
+		key := ""
+		if queryCtx.ConnectionString == "" && transactions.current(ctx) == nil {
+			key = queryResultKey(dbClient, connStr, args, queryCtx.CleanedQuery)
+		}
+		if key != "" && ValidateBoolParam(args, "use_cache", true) {
+			if result, ok := h.cachedResult(sessionKey, key); ok {
+				if result.rowsReported {
+					recordRowsReturned(args, result.rows)
+				}
+				if result.truncated {
+					recordTruncated(args)
+				}
+				entry.Rows = result.rows
+				entry.Cached = true
+				h.Record(sessionKey, entry)
+
+				age := h.now().Sub(result.stored).Round(time.Second)
+				logging.Info("query_database_cache_hit", "age_seconds", int(age.Seconds()), "rows", result.rows)
+				return cachedQueryResponse(result.response, age), nil
+			}
+		}
+
+		response, err := handler(args)
+		entry.Duration = time.Since(entry.Time)
+		usage := usageFromArgs(args)
+		if usage != nil {
+			entry.Rows = usage.Rows()
+		}
+		switch {
+		case err != nil:
+			entry.Error = truncateHistoryText(err.Error(), maxHistoryErrorBytes)
+		case response.IsError && len(response.Content) > 0:
+			entry.Error = truncateHistoryText(lastLine(response.Content[0].Text), maxHistoryErrorBytes)
+		}
+		h.Record(sessionKey, entry)
+
+		if key != "" && err == nil && !response.IsError && responseSize(response) <= maxCachedResultBytes {
+			result := &cachedQueryResult{response: response, stored: h.now()}
+			if usage != nil {
+				result.rows, result.rowsReported = usage.ReportedRows()
+				result.truncated = usage.Truncated()
+			}
+			h.storeResult(sessionKey, key, result)
+		}
+		return response, err
+	}
+}
+
+// queryResultKey identifies the result of a query for the cache: the
+// connection, the normalized query, the arguments that change its result and
+// the session settings. It returns "" for queries whose results are not
+// cached: scripts, SET and RESET, statements that return no rows, and calls
+// that measure or verify the query.
+func queryResultKey(dbClient *database.Client, connStr string, args map[string]interface{}, query string) string {
+	query = strings.TrimSpace(query)
+	if query == "" || !returnsRows(query) ||
+		ValidateBoolParam(args, "include_timing", false) || ValidateBoolParam(args, "verify", false) {
+		return ""
+	}
+	if statements, err := splitQueryScript(query); err != nil || statements != nil {
+		return ""
+	}
+	if stmt, err := database.ParseSetStatement(query); err != nil || stmt != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(connStr)
+	sb.WriteString(fmt.Sprintf("\x00%s\x00%v\x00%v\x00%s",
+		normalizeQuery(query),
+		ValidateOptionalNumberParam(args, "limit", 100),
+		ValidateOptionalNumberParam(args, "offset", 0),
+		ValidateOptionalStringParam(args, "database", "")))
+	for _, setting := range dbClient.SessionSettings(connStr) {
+		sb.WriteString("\x00" + setting.Name + "=" + setting.Value)
+	}
+	return sb.String()
+}
+
+// normalizeQuery makes queries that differ only in whitespace, comments,
+// keyword case and trailing semicolons identical
+func normalizeQuery(query string) string {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return strings.Join(strings.Fields(query), " ")
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].isPunct(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	parts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		if tok.kind == tokWord {
+			parts[i] = tok.upper
+		} else {
+			parts[i] = query[tok.start:tok.end]
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// cachedQueryResponse returns a copy of a cached response, noting its age
+func cachedQueryResponse(response mcp.ToolResponse, age time.Duration) mcp.ToolResponse {
+	cached := response
+	cached.Content = make([]mcp.ContentItem, len(response.Content))
+	copy(cached.Content, response.Content)
+	if len(cached.Content) > 0 {
+		cached.Content[0].Text = fmt.Sprintf("Cached result of an identical query run %s ago (use_cache=false runs it again)\n\n%s",
+			age, cached.Content[0].Text)
+	}
+	return cached
+}
+
+// responseSize returns the size of the text of a response
+func responseSize(response mcp.ToolResponse) int {
+	size := 0
+	for _, item := range response.Content {
+		size += len(item.Text)
+	}
+	return size
+}
+
+// lastLine returns the last non-empty line of a text
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// truncateHistoryText shortens a text to at most max bytes, on a rune
+// boundary
+func truncateHistoryText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "..."
+}
+
+// formatQueryHistory formats a session's queries, newest first
+func formatQueryHistory(entries []QueryHistoryEntry, kept int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Queries in this session, newest first (%d shown, up to %d kept):\n\n", len(entries), kept))
+	sb.WriteString(BuildTSVRow("time", "database", "rows", "duration_ms", "cached", "error", "query"))
+	for _, entry := range entries {
+		sb.WriteString("\n")
+		sb.WriteString(BuildTSVRow(
+			entry.Time.UTC().Format(time.RFC3339),
+			entry.Database,
+			fmt.Sprint(entry.Rows),
+			fmt.Sprint(entry.Duration.Milliseconds()),
+			fmt.Sprintf("%t", entry.Cached),
+			entry.Error,
+			entry.Query,
+		))
+	}
+	return sb.String()
+}
+
+// GetQueryHistoryTool creates the get_query_history tool, which lists the
+// queries run with query_database in the caller's session
+func GetQueryHistoryTool(history *QueryHistory) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "get_query_history",
+			Description: `List the queries run with query_database in this session, newest first.
+
+<usecase>
+Use get_query_history to:
+- Recall a query run earlier in the conversation instead of writing it again
+- Check which queries failed and why
+- See which results were reused from the cache rather than run again
+</usecase>
+
+<output>
+TSV with the time, database, rows returned, duration, whether the result
+was cached, the error if the query failed, and the query.
+</output>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of queries to list (default: 20)",
+						"default":     20,
+						"minimum":     1,
+					},
+				},
+				Required: []string{},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			limit := int(ValidateOptionalNumberParam(args, "limit", 20))
+			if errResp := ValidatePositiveNumber(float64(limit), "limit"); errResp != nil {
+				return *errResp, nil
+			}
+			ctx, ok := args["__context"].(context.Context)
+			if !ok || ctx == nil {
+				ctx = context.Background()
+			}
+
+			entries := history.Entries(conversationKey(ctx))
+			if len(entries) == 0 {
+				return mcp.NewToolSuccess("No queries have been run with query_database in this session yet.")
+			}
+			if len(entries) > limit {
+				entries = entries[:limit]
+			}
+
+			history.mu.Lock()
+			kept := history.size
+			history.mu.Unlock()
+			return mcp.NewToolSuccess(formatQueryHistory(entries, kept))
+		},
+	}
+}
@@ -0,0 +1,192 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+func TestQueryHistory_Record(t *testing.T) {
+	history := NewQueryHistory(config.QueryHistoryConfig{Size: 3})
+	for i := 1; i <= 5; i++ {
+		history.Record("session:a", QueryHistoryEntry{Query: fmt.Sprintf("SELECT %d", i)})
+	}
+	history.Record("session:b", QueryHistoryEntry{Query: "SELECT 'b'"})
+
+	queries := func(key string) string {
+		var names []string
+		for _, entry := range history.Entries(key) {
+			names = append(names, entry.Query)
+		}
+		return strings.Join(names, ", ")
+	}
+	if got := queries("session:a"); got != "SELECT 5, SELECT 4, SELECT 3" {
+		t.Errorf("session a = %q, want the newest 3 queries, newest first", got)
+	}
+	if got := queries("session:b"); got != "SELECT 'b'" {
+		t.Errorf("session b = %q", got)
+	}
+	if got := queries("session:c"); got != "" {
+		t.Errorf("unknown session = %q, want nothing", got)
+	}
+
+	// Shrinking the history keeps the newest queries
+	history.Configure(config.QueryHistoryConfig{Size: 2})
+	if got := queries("session:a"); got != "SELECT 5, SELECT 4" {
+		t.Errorf("after shrinking = %q", got)
+	}
+	history.Record("session:a", QueryHistoryEntry{Query: "SELECT 6"})
+	if got := queries("session:a"); got != "SELECT 6, SELECT 5" {
+		t.Errorf("after recording = %q", got)
+	}
+}
+
+func TestQueryHistory_Cache(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	history := NewQueryHistory(config.QueryHistoryConfig{Size: 10, CacheTTLSeconds: 60})
+	history.now = func() time.Time { return now }
+
+	calls := 0
+	handler := history.wrap(database.NewClient(nil), nil, func(args map[string]interface{}) (mcp.ToolResponse, error) {
+		calls++
+		return mcp.NewToolSuccess(fmt.Sprintf("Results (%d)", calls))
+	})
+	run := func(args map[string]interface{}) string {
+		t.Helper()
+		response, err := handler(args)
+		if err != nil || response.IsError {
+			t.Fatalf("handler failed: %v %v", err, response)
+		}
+		return response.Content[0].Text
+	}
+
+	run(map[string]interface{}{"query": "SELECT * FROM orders"})
+	out := run(map[string]interface{}{"query": "select *\n  from orders;"})
+	if calls != 1 || !strings.HasPrefix(out, "Cached result") || !strings.HasSuffix(out, "Results (1)") {
+		t.Errorf("expected the identical query to be answered from the cache, got %d calls and %q", calls, out)
+	}
+
+	// Other limits, use_cache=false and measured queries run again
+	run(map[string]interface{}{"query": "SELECT * FROM orders", "limit": float64(10)})
+	run(map[string]interface{}{"query": "SELECT * FROM orders", "use_cache": false})
+	run(map[string]interface{}{"query": "SELECT * FROM orders", "include_timing": true})
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+
+	// Results expire after the TTL, and are dropped when invalidated
+	now = now.Add(2 * time.Minute)
+	if out := run(map[string]interface{}{"query": "SELECT * FROM orders"}); strings.HasPrefix(out, "Cached") {
+		t.Errorf("expected an expired result to run again, got %q", out)
+	}
+	history.InvalidateResults()
+	if out := run(map[string]interface{}{"query": "SELECT * FROM orders"}); strings.HasPrefix(out, "Cached") {
+		t.Errorf("expected an invalidated result to run again, got %q", out)
+	}
+
+	entries := history.Entries(defaultConversation)
+	if len(entries) != 7 || !entries[5].Cached || entries[0].Cached {
+		t.Errorf("unexpected history: %+v", entries)
+	}
+}
+
+func TestQueryHistory_CacheDisabled(t *testing.T) {
+	disabled := false
+	history := NewQueryHistory(config.QueryHistoryConfig{Cache: &disabled, CacheTTLSeconds: 60})
+
+	calls := 0
+	handler := history.wrap(database.NewClient(nil), nil, func(args map[string]interface{}) (mcp.ToolResponse, error) {
+		calls++
+		return mcp.NewToolSuccess("Results")
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := handler(map[string]interface{}{"query": "SELECT 1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected both queries to run, got %d calls", calls)
+	}
+	if entries := history.Entries(defaultConversation); len(entries) != 2 {
+		t.Errorf("expected the queries to be recorded, got %d", len(entries))
+	}
+}
+
+func TestQueryResultKey(t *testing.T) {
+	client := database.NewClient(nil)
+	tests := []struct {
+		query     string
+		cacheable bool
+	}{
+		{"SELECT 1", true},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", true},
+		{"SELECT 1; SELECT 2", false},
+		{"SET search_path = sales", false},
+		{"CREATE TABLE t (id int)", false},
+	}
+	for _, tt := range tests {
+		key := queryResultKey(client, "", map[string]interface{}{}, tt.query)
+		if (key != "") != tt.cacheable {
+			t.Errorf("queryResultKey(%q) = %q, want cacheable %t", tt.query, key, tt.cacheable)
+		}
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"SELECT * FROM orders", "select  *\nfrom orders -- all\n;", true},
+		{"SELECT 'A'", "SELECT 'a'", false},
+		{`SELECT "Name" FROM t`, `SELECT "name" FROM t`, false},
+	}
+	for _, tt := range tests {
+		if got := normalizeQuery(tt.a) == normalizeQuery(tt.b); got != tt.equal {
+			t.Errorf("normalizeQuery(%q) == normalizeQuery(%q) is %t, want %t", tt.a, tt.b, got, tt.equal)
+		}
+	}
+}
+
+func TestGetQueryHistoryTool(t *testing.T) {
+	history := NewQueryHistory(config.QueryHistoryConfig{})
+	tool := GetQueryHistoryTool(history)
+
+	response, err := tool.Handler(map[string]interface{}{})
+	if err != nil || !strings.Contains(response.Content[0].Text, "No queries") {
+		t.Fatalf("unexpected response for an empty history: %v %v", err, response)
+	}
+
+	history.Record(defaultConversation, QueryHistoryEntry{
+		Time: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), Database: "postgres://app@localhost/shop",
+		Query: "SELECT count(*)\nFROM orders", Rows: 1, Duration: 12 * time.Millisecond,
+	})
+	history.Record(defaultConversation, QueryHistoryEntry{Query: "SELECT nope", Error: "column \"nope\" does not exist"})
+
+	response, err = tool.Handler(map[string]interface{}{"limit": float64(5)})
+	if err != nil || response.IsError {
+		t.Fatalf("handler failed: %v %v", err, response)
+	}
+	out := response.Content[0].Text
+	if !strings.Contains(out, "2 shown, up to 50 kept") || strings.Index(out, "SELECT nope") > strings.Index(out, "SELECT count") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if !strings.Contains(out, "2025-06-01T12:00:00Z\tpostgres://app@localhost/shop\t1\t12\tfalse") {
+		t.Errorf("expected the query's details in the output:\n%s", out)
+	}
+}
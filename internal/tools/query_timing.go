@@ -0,0 +1,145 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryTiming holds the execution statistics reported by include_timing
+type queryTiming struct {
+	RoundTrip time.Duration // Time to run the query and read its rows, measured by the server
+
+	// From EXPLAIN (ANALYZE, BUFFERS, TIMING); Analyzed is false when the
+	// statement could not be explained
+	Analyzed      bool
+	PlanningMS    float64
+	ExecutionMS   float64
+	Rows          float64
+	SharedHit     int64
+	SharedRead    int64
+	SharedDirtied int64
+	TempRead      int64
+	TempWritten   int64
+	AnalyzeError  string
+}
+
+// analyzeOutput is the part of EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON)
+// output used for timing
+type analyzeOutput struct {
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+	Plan          struct {
+		ActualRows    float64 `json:"Actual Rows"`
+		ActualLoops   float64 `json:"Actual Loops"`
+		SharedHit     int64   `json:"Shared Hit Blocks"`
+		SharedRead    int64   `json:"Shared Read Blocks"`
+		SharedDirtied int64   `json:"Shared Dirtied Blocks"`
+		TempRead      int64   `json:"Temp Read Blocks"`
+		TempWritten   int64   `json:"Temp Written Blocks"`
+	} `json:"Plan"`
+}
+
+// analyzeTiming runs EXPLAIN (ANALYZE, BUFFERS, TIMING) for query in a
+// savepoint, so a statement that cannot be explained does not abort the
+// caller's transaction. The statement is executed a second time; the
+// savepoint is rolled back so a data change is not applied twice.
+func analyzeTiming(ctx context.Context, tx pgx.Tx, query string, timing *queryTiming) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		timing.AnalyzeError = err.Error()
+		return
+	}
+
+	var data []byte
+	err = savepoint.QueryRow(ctx, "EXPLAIN (ANALYZE, BUFFERS, TIMING, FORMAT JSON) "+query).Scan(&data)
+	if err != nil {
+		_ = savepoint.Rollback(ctx) //nolint:errcheck // the outer transaction reports connection errors
+		timing.AnalyzeError = err.Error()
+		return
+	}
+	if err := savepoint.Rollback(ctx); err != nil {
+		timing.AnalyzeError = err.Error()
+		return
+	}
+
+	if err := parseAnalyzeTiming(data, timing); err != nil {
+		timing.AnalyzeError = err.Error()
+	}
+}
+
+// parseAnalyzeTiming fills timing from EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON)
+// output. Buffer counts of the top plan node include all of its children.
+func parseAnalyzeTiming(data []byte, timing *queryTiming) error {
+	var output []analyzeOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return fmt.Errorf("invalid EXPLAIN output: %w", err)
+	}
+	if len(output) == 0 {
+		return fmt.Errorf("empty EXPLAIN output")
+	}
+
+	out := output[0]
+	timing.Analyzed = true
+	timing.PlanningMS = out.PlanningTime
+	timing.ExecutionMS = out.ExecutionTime
+	timing.Rows = out.Plan.ActualRows
+	if out.Plan.ActualLoops > 1 {
+		timing.Rows *= out.Plan.ActualLoops
+	}
+	timing.SharedHit = out.Plan.SharedHit
+	timing.SharedRead = out.Plan.SharedRead
+	timing.SharedDirtied = out.Plan.SharedDirtied
+	timing.TempRead = out.Plan.TempRead
+	timing.TempWritten = out.Plan.TempWritten
+	return nil
+}
+
+// formatTiming renders the timing section of a query_database response
+func formatTiming(t *queryTiming) string {
+	var sb strings.Builder
+	sb.WriteString("Timing:\n")
+	sb.WriteString(fmt.Sprintf("  Round trip: %.3f ms (query and row transfer, measured by the server)\n",
+		float64(t.RoundTrip)/float64(time.Millisecond)))
+
+	if !t.Analyzed {
+		if t.AnalyzeError != "" {
+			sb.WriteString(fmt.Sprintf("  Execution statistics unavailable: %s\n", t.AnalyzeError))
+		}
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("  Planning: %.3f ms\n", t.PlanningMS))
+	sb.WriteString(fmt.Sprintf("  Execution: %.3f ms\n", t.ExecutionMS))
+	sb.WriteString(fmt.Sprintf("  Rows: %.0f\n", t.Rows))
+
+	sb.WriteString(fmt.Sprintf("  Buffers: shared hit=%d read=%d", t.SharedHit, t.SharedRead))
+	if t.SharedDirtied > 0 {
+		sb.WriteString(fmt.Sprintf(" dirtied=%d", t.SharedDirtied))
+	}
+	if total := t.SharedHit + t.SharedRead; total > 0 {
+		sb.WriteString(fmt.Sprintf(" (%.1f%% from cache)", float64(t.SharedHit)*100/float64(total)))
+	}
+	sb.WriteString("\n")
+	if t.TempRead > 0 || t.TempWritten > 0 {
+		sb.WriteString(fmt.Sprintf("  Temp buffers: read=%d written=%d (the query spilled to disk; consider raising work_mem)\n",
+			t.TempRead, t.TempWritten))
+	}
+
+	sb.WriteString("  (Planning, execution and buffer figures come from EXPLAIN ANALYZE, which ran the query a second time; caches may be warmer than for the first run)\n")
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleAnalyzeJSON = `[
+  {
+    "Plan": {
+      "Node Type": "Limit",
+      "Actual Rows": 101,
+      "Actual Loops": 1,
+      "Shared Hit Blocks": 120,
+      "Shared Read Blocks": 8,
+      "Shared Dirtied Blocks": 0,
+      "Temp Read Blocks": 0,
+      "Temp Written Blocks": 0,
+      "Plans": [{"Node Type": "Seq Scan", "Actual Rows": 101, "Actual Loops": 1}]
+    },
+    "Planning Time": 0.215,
+    "Triggers": [],
+    "Execution Time": 12.5
+  }
+]`
+
+func TestParseAnalyzeTiming(t *testing.T) {
+	var timing queryTiming
+	if err := parseAnalyzeTiming([]byte(sampleAnalyzeJSON), &timing); err != nil {
+		t.Fatalf("parseAnalyzeTiming() error = %v", err)
+	}
+
+	if !timing.Analyzed {
+		t.Error("Expected Analyzed to be set")
+	}
+	if timing.PlanningMS != 0.215 || timing.ExecutionMS != 12.5 {
+		t.Errorf("Planning/execution = %v/%v, want 0.215/12.5", timing.PlanningMS, timing.ExecutionMS)
+	}
+	if timing.Rows != 101 {
+		t.Errorf("Rows = %v, want 101", timing.Rows)
+	}
+	if timing.SharedHit != 120 || timing.SharedRead != 8 {
+		t.Errorf("Buffers = hit %d read %d, want hit 120 read 8", timing.SharedHit, timing.SharedRead)
+	}
+
+	if err := parseAnalyzeTiming([]byte(`[]`), &timing); err == nil {
+		t.Error("Expected an error for empty EXPLAIN output")
+	}
+}
+
+func TestFormatTiming(t *testing.T) {
+	timing := &queryTiming{RoundTrip: 15 * time.Millisecond}
+	if err := parseAnalyzeTiming([]byte(sampleAnalyzeJSON), timing); err != nil {
+		t.Fatal(err)
+	}
+
+	out := formatTiming(timing)
+	for _, want := range []string{
+		"Round trip: 15.000 ms",
+		"Planning: 0.215 ms",
+		"Execution: 12.500 ms",
+		"Rows: 101",
+		"shared hit=120 read=8 (93.8% from cache)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Temp buffers") {
+		t.Errorf("Temp buffers should only be shown when used:\n%s", out)
+	}
+
+	timing.TempWritten = 64
+	if out := formatTiming(timing); !strings.Contains(out, "Temp buffers: read=0 written=64") {
+		t.Errorf("Missing temp buffer line:\n%s", out)
+	}
+
+	// A statement that cannot be explained still reports the round trip
+	failed := &queryTiming{RoundTrip: time.Millisecond, AnalyzeError: "EXPLAIN not supported"}
+	out = formatTiming(failed)
+	if !strings.Contains(out, "Round trip: 1.000 ms") || !strings.Contains(out, "unavailable: EXPLAIN not supported") {
+		t.Errorf("Unexpected output for an unexplained statement:\n%s", out)
+	}
+}
@@ -46,6 +46,13 @@ Use read_resource when:
    - PostgreSQL version, OS, architecture
    - Connection details (host, port, user, database)
    - Platform information for compatibility checks
+2. pg://extensions
+   - Installed extensions with versions and schemas
+   - Extensions available on the server to install
+3. pg://replication
+   - Connected standbys with their lag in bytes and seconds
+   - Replication slots with the WAL they retain
+   - Findings such as lagging standbys or inactive slots
 </available_resources>
 
 <alternatives>
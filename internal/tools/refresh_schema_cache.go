@@ -0,0 +1,132 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// maxListedTableChanges limits the added and removed tables listed by
+// refresh_schema_cache
+const maxListedTableChanges = 20
+
+// RefreshSchemaCacheTool creates the refresh_schema_cache tool, which
+// reloads the table and column metadata cached for the current database
+func RefreshSchemaCacheTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "refresh_schema_cache",
+			Description: `Reload the cached list of tables, views and columns of the current database.
+
+<usecase>
+Use when get_schema_info does not show a table the user says exists, or
+still shows one that was dropped, for example after a schema change made
+outside this server.
+</usecase>
+
+<behavior>
+- Reports the tables and views added and removed since the previous load
+- The cache is also reloaded periodically and after schema changes made
+  with this server's tools, so this is rarely needed
+</behavior>`,
+			InputSchema: mcp.InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+				Required:   []string{},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+
+			// Lazily loaded metadata is only dropped: reading all of it to
+			// compare would load every schema
+			lazy := dbClient.IsMetadataLazy()
+			var before map[string]database.TableInfo
+			if !lazy {
+				before = dbClient.GetMetadataFor(connStr)
+			}
+
+			if err := dbClient.LoadMetadataFor(connStr); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to reload the schema metadata: %v", err))
+			}
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			if lazy {
+				logging.Info("refresh_schema_cache_executed", "lazy", true)
+				sb.WriteString("Schema metadata cleared; tables are loaded again as they are used.\n")
+				return mcp.NewToolSuccess(sb.String())
+			}
+
+			after := dbClient.GetMetadataFor(connStr)
+			added, removed := diffTableNames(before, after)
+
+			logging.Info("refresh_schema_cache_executed",
+				"tables", len(after),
+				"added", len(added),
+				"removed", len(removed),
+			)
+
+			sb.WriteString(fmt.Sprintf("Schema metadata reloaded: %d tables and views (%d before).\n", len(after), len(before)))
+			writeTableChanges(&sb, "Added", added)
+			writeTableChanges(&sb, "Removed", removed)
+			if len(added) == 0 && len(removed) == 0 {
+				sb.WriteString("No tables or views were added or removed.\n")
+			}
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// diffTableNames returns the sorted qualified names of the tables in after
+// but not before, and in before but not after
+func diffTableNames(before, after map[string]database.TableInfo) (added, removed []string) {
+	for key, table := range after {
+		if _, ok := before[key]; !ok {
+			added = append(added, table.SchemaName+"."+table.TableName)
+		}
+	}
+	for key, table := range before {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, table.SchemaName+"."+table.TableName)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// writeTableChanges lists changed tables, up to maxListedTableChanges
+func writeTableChanges(sb *strings.Builder, label string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	listed := names
+	if len(listed) > maxListedTableChanges {
+		listed = listed[:maxListedTableChanges]
+	}
+	sb.WriteString(fmt.Sprintf("\n%s (%d):\n", label, len(names)))
+	for _, name := range listed {
+		sb.WriteString("  " + name + "\n")
+	}
+	if len(names) > len(listed) {
+		sb.WriteString(fmt.Sprintf("  ... and %d more\n", len(names)-len(listed)))
+	}
+}
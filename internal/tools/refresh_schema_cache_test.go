@@ -0,0 +1,84 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+func TestDiffTableNames(t *testing.T) {
+	before := map[string]database.TableInfo{
+		"public.orders":    {SchemaName: "public", TableName: "orders"},
+		"public.customers": {SchemaName: "public", TableName: "customers"},
+	}
+	after := map[string]database.TableInfo{
+		"public.orders":     {SchemaName: "public", TableName: "orders"},
+		"sales.invoices":    {SchemaName: "sales", TableName: "invoices"},
+		"public.line_items": {SchemaName: "public", TableName: "line_items"},
+	}
+
+	added, removed := diffTableNames(before, after)
+	if want := []string{"public.line_items", "sales.invoices"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added = %v, want %v", added, want)
+	}
+	if want := []string{"public.customers"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+}
+
+func TestWriteTableChanges(t *testing.T) {
+	var names []string
+	for i := 0; i < maxListedTableChanges+3; i++ {
+		names = append(names, fmt.Sprintf("public.t%02d", i))
+	}
+
+	var sb strings.Builder
+	writeTableChanges(&sb, "Added", names)
+	out := sb.String()
+	if !strings.Contains(out, "Added (23):") || !strings.Contains(out, "... and 3 more") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if strings.Contains(out, "public.t20") {
+		t.Errorf("expected the list to stop at %d names:\n%s", maxListedTableChanges, out)
+	}
+
+	sb.Reset()
+	writeTableChanges(&sb, "Removed", nil)
+	if sb.Len() != 0 {
+		t.Errorf("expected nothing for no changes, got %q", sb.String())
+	}
+}
+
+func TestChangesSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want bool
+	}{
+		{"execute_script", map[string]interface{}{}, true},
+		{"execute_script", map[string]interface{}{"dry_run": true}, false},
+		{"apply_migration", map[string]interface{}{}, false},
+		{"install_extension", map[string]interface{}{}, true},
+		{"begin_transaction", map[string]interface{}{"read_write": true}, false},
+		{"commit_transaction", map[string]interface{}{}, true},
+		{"query_database", map[string]interface{}{}, false},
+	}
+	for _, tt := range tests {
+		if got := changesSchema(tt.name, tt.args); got != tt.want {
+			t.Errorf("changesSchema(%s, %v) = %t, want %t", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/snapshot"
+)
+
+// RestoreSchemaSnapshotTool creates the restore_schema_snapshot tool, which
+// recreates a snapshot saved by snapshot_schema in a schema of the current
+// database
+// The tool writes to the database, so it is disabled unless enabled in the
+// configuration
+func RestoreSchemaSnapshotTool(dbClient *database.Client, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "restore_schema_snapshot",
+			Description: `Recreate a schema snapshot saved with snapshot_schema in a new schema of
+the current database, in a single transaction. Unlike query_database, this
+tool CAN modify the schema.
+
+<usecase>
+Use when:
+- A migration tried after snapshot_schema needs to be compared with the
+  original schema (restore into a scratch schema such as 'public_before')
+- Setting up a copy of a schema to experiment on
+- Rolling a schema back to the snapshot (replace=true on the original schema)
+</usecase>
+
+<behavior>
+- The target schema is created; it must not exist unless replace=true, which
+  DROPS it first WITH CASCADE, including objects in other schemas that
+  depend on it
+- Tables are created, the data is loaded (if the snapshot has data and
+  include_data is true), then constraints, indexes, views and triggers are
+  created. Any failing statement rolls the whole restore back
+- References to other schemas are kept, so restored foreign keys and views
+  still point to objects outside the snapshot schema
+- To restore into another database, select that database first
+</behavior>
+
+<important>
+- Confirm with the user before using replace=true
+- Privileges and ownership are not restored; the restored objects are owned
+  by the server's database user
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the snapshot (see list_schema_snapshots)",
+					},
+					"target_schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema to create and restore into, e.g. 'public_before'",
+					},
+					"include_data": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Load the snapshot's data, if it has any (default: true)",
+						"default":     true,
+					},
+					"replace": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Drop the target schema with CASCADE first if it exists (default: false)",
+						"default":     false,
+					},
+				},
+				Required: []string{"name", "target_schema"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			name, errResp := ValidateStringParam(args, "name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			target, errResp := ValidateStringParam(args, "target_schema")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			if err := checkRestoreTarget(target); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			includeData := ValidateBoolParam(args, "include_data", true)
+			replace := ValidateBoolParam(args, "replace", false)
+
+			store := snapshot.NewStoreFromConfig(cfg)
+			snap, err := store.Open(name)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			includeData = includeData && snap.HasData()
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			// Connections default to read-only transactions
+			ctx := context.Background()
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			committed := false
+			defer func() {
+				if !committed {
+					_ = tx.Rollback(ctx) //nolint:errcheck // rollback after a failed commit is expected to fail
+				}
+			}()
+
+			rows, err := restoreSchemaSnapshot(ctx, tx, store, snap, target, includeData, replace)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Restore of snapshot %s into schema %s failed: %v\n\nTransaction rolled back: no changes were applied.",
+					name, target, err))
+			}
+			if err := tx.Commit(ctx); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Commit failed, no changes were applied: %v", err))
+			}
+			committed = true
+
+			// New objects invalidate the cached metadata used by other tools
+			if err := dbClient.LoadMetadataFor(connStr); err != nil {
+				logging.Warn("restore_schema_snapshot_metadata_refresh_failed", "error", err)
+			}
+
+			logging.Info("restore_schema_snapshot_executed",
+				"name", name,
+				"target_schema", target,
+				"replace", replace,
+				"rows", rows,
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			sb.WriteString(fmt.Sprintf("Restored snapshot %s (schema %s of %s, %s) into schema %s.\n",
+				snap.Name, snap.Schema, snap.Database, snap.CreatedAt.Format(time.RFC3339), target))
+			sb.WriteString(fmt.Sprintf("Tables: %d\n", len(snap.Tables)))
+			switch {
+			case includeData:
+				sb.WriteString(fmt.Sprintf("Data: %d rows loaded\n", rows))
+			case snap.HasData():
+				sb.WriteString("Data: not loaded\n")
+			default:
+				sb.WriteString("Data: none in the snapshot\n")
+			}
+			if len(snap.Warnings) > 0 {
+				sb.WriteString("\nNot restored:\n")
+				for _, w := range snap.Warnings {
+					sb.WriteString("- " + w + "\n")
+				}
+			}
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// checkRestoreTarget rejects schemas that must not be restored into
+func checkRestoreTarget(schema string) error {
+	lower := strings.ToLower(schema)
+	if strings.HasPrefix(lower, "pg_") || lower == "information_schema" {
+		return fmt.Errorf("cannot restore into the system schema %s", schema)
+	}
+	return nil
+}
+
+// restoreSchemaSnapshot recreates a snapshot in the target schema and
+// returns the number of rows loaded. The snapshot's DDL names the objects
+// of its schema unqualified, so with the target first in the search path
+// they are created there.
+func restoreSchemaSnapshot(ctx context.Context, tx pgx.Tx, store *snapshot.Store, snap *snapshot.Snapshot, target string, includeData, replace bool) (int64, error) {
+	schemaIdent := quoteIdentifier(target)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_namespace WHERE nspname = $1)", target).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists {
+		if !replace {
+			return 0, errors.New("the schema already exists; choose another target_schema or set replace=true")
+		}
+		if _, err := tx.Exec(ctx, "DROP SCHEMA "+schemaIdent+" CASCADE"); err != nil {
+			return 0, err
+		}
+	}
+
+	setup := []string{
+		"CREATE SCHEMA " + schemaIdent,
+		"SELECT pg_catalog.set_config('search_path', " + quoteLiteral(schemaIdent) + " || ', pg_catalog', true)",
+		// Function bodies may refer to tables created after them
+		"SET LOCAL check_function_bodies = off",
+	}
+	if err := execSnapshotStatements(ctx, tx, setup); err != nil {
+		return 0, err
+	}
+	if err := execSnapshotStatements(ctx, tx, snap.PreData); err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	if includeData {
+		for _, t := range snap.Tables {
+			if t.DataFile == "" {
+				continue
+			}
+			r, err := store.OpenData(snap, t)
+			if err != nil {
+				return 0, err
+			}
+			tag, err := tx.Conn().PgConn().CopyFrom(ctx, r,
+				fmt.Sprintf("COPY %s (%s) FROM STDIN", quoteIdentifier(t.Name), strings.Join(t.Columns, ", ")))
+			_ = r.Close() //nolint:errcheck // the file was only read
+			if err != nil {
+				return 0, fmt.Errorf("failed to load the data of table %s: %w", t.Name, err)
+			}
+			rows += tag.RowsAffected()
+		}
+		if err := execSnapshotStatements(ctx, tx, snap.SequenceValues); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := execSnapshotStatements(ctx, tx, snap.PostData); err != nil {
+		return 0, err
+	}
+	return rows, nil
+}
+
+// execSnapshotStatements runs statements, stopping at the first failure
+func execSnapshotStatements(ctx context.Context, tx pgx.Tx, statements []string) error {
+	for _, stmt := range statements {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("%v\nStatement: %s", err, statementPreview(stmt))
+		}
+	}
+	return nil
+}
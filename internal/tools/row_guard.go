@@ -0,0 +1,97 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// rowClauses describes the top-level clauses of a query that limit its rows
+type rowClauses struct {
+	body    string // The query without trailing semicolons and comments
+	limited bool   // Has a LIMIT or FETCH clause
+	limit   int64  // Rows the clause allows; -1 for LIMIT ALL or an expression
+	offset  bool   // Has an OFFSET clause
+	explain bool   // The query is an EXPLAIN
+}
+
+// parseRowClauses finds the LIMIT, FETCH and OFFSET clauses of the main
+// statement of a query, ignoring those of subqueries, CTEs and function
+// calls, and words such as a column named limit_date. Queries that cannot be
+// tokenized are reported as limited, so they run as written and the server
+// reports the error.
+func parseRowClauses(query string) rowClauses {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return rowClauses{body: query, limited: true, offset: true}
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].isPunct(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return rowClauses{body: query}
+	}
+
+	clauses := rowClauses{
+		body:    query[:tokens[len(tokens)-1].end],
+		explain: tokens[0].isKeyword("EXPLAIN"),
+	}
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth != 0:
+		case t.isKeyword("LIMIT"):
+			clauses.limited = true
+			clauses.limit = -1
+			if i+1 < len(tokens) && tokens[i+1].kind == tokNumber {
+				if n, err := strconv.ParseInt(tokens[i+1].value, 10, 64); err == nil {
+					clauses.limit = n
+				}
+			}
+		case t.isKeyword("FETCH"):
+			// FETCH { FIRST | NEXT } [ count ] { ROW | ROWS } ...
+			clauses.limited = true
+			clauses.limit = 1
+			if i+2 < len(tokens) {
+				switch next := tokens[i+2]; {
+				case next.kind == tokNumber:
+					if n, err := strconv.ParseInt(next.value, 10, 64); err == nil {
+						clauses.limit = n
+					} else {
+						clauses.limit = -1
+					}
+				case !next.isKeyword("ROW", "ROWS"):
+					clauses.limit = -1
+				}
+			}
+		case t.isKeyword("OFFSET"):
+			clauses.offset = true
+		}
+	}
+	return clauses
+}
+
+// guardRowLimit rewrites a query whose own LIMIT allows more than maxRows
+// rows to return at most maxRows+1, so truncation can be detected, by
+// running it as a subquery. It returns the query unchanged, and false, when
+// the query's limit is within maxRows or it is an EXPLAIN.
+func guardRowLimit(clauses rowClauses, maxRows int) (string, bool) {
+	if maxRows <= 0 || !clauses.limited || clauses.explain ||
+		(clauses.limit >= 0 && clauses.limit <= int64(maxRows)) {
+		return clauses.body, false
+	}
+	return fmt.Sprintf("SELECT * FROM (\n%s\n) AS row_guard LIMIT %d", clauses.body, maxRows+1), true
+}
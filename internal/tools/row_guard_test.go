@@ -0,0 +1,73 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRowClauses(t *testing.T) {
+	tests := []struct {
+		query   string
+		limited bool
+		limit   int64
+		offset  bool
+		body    string
+	}{
+		{"SELECT * FROM orders", false, 0, false, "SELECT * FROM orders"},
+		{"SELECT * FROM orders; -- all of them", false, 0, false, "SELECT * FROM orders"},
+		{"SELECT * FROM orders LIMIT 50", true, 50, false, ""},
+		{"SELECT * FROM orders LIMIT ALL", true, -1, false, ""},
+		{"SELECT * FROM orders LIMIT $1 OFFSET 10", true, -1, true, ""},
+		{"SELECT * FROM orders OFFSET 5 ROWS FETCH FIRST 20 ROWS ONLY", true, 20, true, ""},
+		{"SELECT * FROM orders FETCH NEXT ROW ONLY", true, 1, false, ""},
+		{"SELECT limit_date, 'LIMIT 5' FROM offsets", false, 0, false, ""},
+		{"SELECT * FROM (SELECT * FROM orders LIMIT 5) o", false, 0, false, ""},
+		{"WITH recent AS (SELECT * FROM orders LIMIT 5) SELECT * FROM recent", false, 0, false, ""},
+		{"SELECT 'unterminated", true, 0, true, "SELECT 'unterminated"},
+	}
+	for _, tt := range tests {
+		got := parseRowClauses(tt.query)
+		if got.limited != tt.limited || got.offset != tt.offset || (tt.limited && got.limit != tt.limit) {
+			t.Errorf("parseRowClauses(%q) = %+v, want limited %t (%d), offset %t",
+				tt.query, got, tt.limited, tt.limit, tt.offset)
+		}
+		if tt.body != "" && got.body != tt.body {
+			t.Errorf("parseRowClauses(%q).body = %q, want %q", tt.query, got.body, tt.body)
+		}
+	}
+}
+
+func TestGuardRowLimit(t *testing.T) {
+	tests := []struct {
+		query   string
+		maxRows int
+		guarded bool
+	}{
+		{"SELECT * FROM orders", 1000, false},
+		{"SELECT * FROM orders LIMIT 1000", 1000, false},
+		{"SELECT * FROM orders LIMIT 5000", 1000, true},
+		{"SELECT * FROM orders LIMIT ALL", 1000, true},
+		{"SELECT * FROM orders FETCH FIRST 2000 ROWS ONLY", 1000, true},
+		{"SELECT * FROM orders LIMIT 5000", 0, false},
+		{"EXPLAIN SELECT * FROM orders LIMIT 5000", 1000, false},
+	}
+	for _, tt := range tests {
+		query, guarded := guardRowLimit(parseRowClauses(tt.query), tt.maxRows)
+		if guarded != tt.guarded {
+			t.Errorf("guardRowLimit(%q, %d) guarded = %t, want %t", tt.query, tt.maxRows, guarded, tt.guarded)
+		}
+		if guarded && !strings.HasSuffix(query, ") AS row_guard LIMIT 1001") {
+			t.Errorf("guardRowLimit(%q) = %q, want it wrapped with LIMIT 1001", tt.query, query)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// sampleTextFromArgs asks the connected client's model to answer a prompt
+// using MCP sampling, so tools do not need a server-side LLM. It returns
+// mcp.ErrSamplingUnsupported when the client cannot sample.
+func sampleTextFromArgs(args map[string]interface{}, systemPrompt, prompt string, maxTokens int) (string, error) {
+	ctx, _ := args["__context"].(context.Context)
+	return mcp.SampleText(ctx, systemPrompt, prompt, maxTokens)
+}
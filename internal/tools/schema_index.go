@@ -0,0 +1,264 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/embedding"
+	"pgedge-postgres-mcp/internal/logging"
+)
+
+// schemaIndexBuildTimeout bounds embedding the tables of one database
+const schemaIndexBuildTimeout = 10 * time.Minute
+
+// maxTableTextRunes limits the text embedded for a table, so tables with
+// many columns stay within the models' input limits
+const maxTableTextRunes = 2000
+
+// SchemaIndex holds embeddings of the tables of each database, made from
+// their names, types, columns and comments, for find_relevant_tables.
+// Indexes are built in the background when metadata is loaded, so a search
+// of a database with hundreds of tables does not wait for the embeddings.
+// They are keyed by connection string and rebuilt when the tables change,
+// embedding only the tables whose text changed.
+type SchemaIndex struct {
+	mu      sync.Mutex
+	indexes map[string]*schemaIndexBuild
+
+	// newProvider creates the embedding provider; replaced in tests
+	newProvider func(cfg *config.Config) (embedding.Provider, error)
+}
+
+// schemaIndexBuild is an index of one database's tables, complete once
+// done is closed
+type schemaIndexBuild struct {
+	key     string // fingerprint of the model and the embedded texts
+	model   string
+	done    chan struct{}
+	entries []schemaIndexEntry
+	err     error
+}
+
+// schemaIndexEntry is a table with the embedding of its text
+type schemaIndexEntry struct {
+	table  database.TableInfo
+	text   string
+	vector []float64
+}
+
+// NewSchemaIndex creates an empty schema index
+func NewSchemaIndex() *SchemaIndex {
+	return &SchemaIndex{
+		indexes:     make(map[string]*schemaIndexBuild),
+		newProvider: newSchemaEmbeddingProvider,
+	}
+}
+
+// newSchemaEmbeddingProvider creates the configured embedding provider
+func newSchemaEmbeddingProvider(cfg *config.Config) (embedding.Provider, error) {
+	return embedding.NewProvider(embedding.Config{
+		Provider:     cfg.Embedding.Provider,
+		Model:        cfg.Embedding.Model,
+		VoyageAPIKey: cfg.Embedding.VoyageAPIKey,
+		OpenAIAPIKey: cfg.Embedding.OpenAIAPIKey,
+		OllamaURL:    cfg.Embedding.OllamaURL,
+	})
+}
+
+// semanticSearchUnavailable returns why tables can't be matched by
+// embeddings, or "" if they can
+func semanticSearchUnavailable(cfg *config.Config) string {
+	switch {
+	case !cfg.Embedding.Enabled:
+		return "embedding generation is not enabled"
+	case cfg.Offline && config.IsCloudProvider(cfg.Embedding.Provider):
+		return "offline mode disables the " + cfg.Embedding.Provider + " embedding provider"
+	}
+	return ""
+}
+
+// Prepare starts building the index of a database's tables in the
+// background, if embeddings are available and the index is outdated
+func (si *SchemaIndex) Prepare(cfg *config.Config, connStr string, tables map[string]database.TableInfo) {
+	if semanticSearchUnavailable(cfg) != "" || len(tables) == 0 {
+		return
+	}
+	if _, err := si.start(cfg, connStr, tables); err != nil {
+		logging.Warn("schema_index_unavailable", "error", err)
+	}
+}
+
+// Get returns the index of a database's tables, building it if outdated
+// and waiting until ctx is done for a build in progress
+func (si *SchemaIndex) Get(ctx context.Context, cfg *config.Config, connStr string, tables map[string]database.TableInfo) (*schemaIndexBuild, error) {
+	build, err := si.start(cfg, connStr, tables)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-build.done:
+		if build.err != nil {
+			return nil, build.err
+		}
+		return build, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("the index of the tables is still being built")
+	}
+}
+
+// start returns the build of the index for the given tables, starting one
+// if the current index is for other tables or failed
+func (si *SchemaIndex) start(cfg *config.Config, connStr string, tables map[string]database.TableInfo) (*schemaIndexBuild, error) {
+	provider, err := si.newProvider(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the embedding provider: %w", err)
+	}
+	model := provider.ProviderName() + "/" + provider.ModelName()
+
+	entries := make([]schemaIndexEntry, 0, len(tables))
+	for _, table := range tables {
+		entries = append(entries, schemaIndexEntry{table: table, text: tableSearchText(&table)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].text < entries[j].text })
+	hash := sha256.New()
+	hash.Write([]byte(model))
+	for i := range entries {
+		hash.Write([]byte{0})
+		hash.Write([]byte(entries[i].text))
+	}
+	key := hex.EncodeToString(hash.Sum(nil))
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	previous := si.indexes[connStr]
+	if previous != nil && previous.key == key && !previous.failed() {
+		return previous, nil
+	}
+	build := &schemaIndexBuild{key: key, model: model, done: make(chan struct{}), entries: entries}
+	si.indexes[connStr] = build
+	go build.run(provider, previous)
+	return build, nil
+}
+
+// failed reports whether the build finished with an error
+func (b *schemaIndexBuild) failed() bool {
+	select {
+	case <-b.done:
+		return b.err != nil
+	default:
+		return false
+	}
+}
+
+// run embeds the texts of the tables, reusing the embeddings of unchanged
+// tables from the previous build when it completed
+func (b *schemaIndexBuild) run(provider embedding.Provider, previous *schemaIndexBuild) {
+	defer close(b.done)
+
+	reuse := make(map[string][]float64)
+	if previous != nil && previous.model == b.model {
+		select {
+		case <-previous.done:
+			if previous.err == nil {
+				for _, entry := range previous.entries {
+					reuse[entry.text] = entry.vector
+				}
+			}
+		default:
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), schemaIndexBuildTimeout)
+	defer cancel()
+
+	startTime := time.Now()
+	embedded := 0
+	for i := range b.entries {
+		if vector, ok := reuse[b.entries[i].text]; ok {
+			b.entries[i].vector = vector
+			continue
+		}
+		vector, err := provider.Embed(ctx, b.entries[i].text)
+		if err != nil {
+			b.err = fmt.Errorf("failed to embed table %s.%s: %w",
+				b.entries[i].table.SchemaName, b.entries[i].table.TableName, err)
+			logging.Warn("schema_index_build_failed", "model", b.model, "error", b.err)
+			return
+		}
+		b.entries[i].vector = vector
+		embedded++
+	}
+
+	logging.Debug("schema_index_built",
+		"model", b.model,
+		"tables", len(b.entries),
+		"embedded", embedded,
+		"duration_ms", time.Since(startTime).Milliseconds(),
+	)
+}
+
+// tableSearchText describes a table for embedding: its qualified name and
+// type, comment, and columns with their types and comments
+func tableSearchText(table *database.TableInfo) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s.%s (%s)", table.SchemaName, table.TableName, strings.ToLower(table.TableType)))
+	if table.Description != "" {
+		sb.WriteString(": " + table.Description)
+	}
+	if len(table.Columns) > 0 {
+		sb.WriteString("\nColumns: ")
+		for i := range table.Columns {
+			col := &table.Columns[i]
+			if i > 0 {
+				sb.WriteString("; ")
+			}
+			sb.WriteString(fmt.Sprintf("%s (%s)", col.ColumnName, col.DataType))
+			if col.Description != "" {
+				sb.WriteString(": " + col.Description)
+			}
+		}
+	}
+
+	text := []rune(sb.String())
+	if len(text) > maxTableTextRunes {
+		text = text[:maxTableTextRunes]
+	}
+	return string(text)
+}
+
+// vectorSimilarity returns the cosine similarity of two embeddings
+func vectorSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
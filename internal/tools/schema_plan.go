@@ -0,0 +1,1633 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+// This file plans DDL statements against the cached schema metadata. Like
+// sql_analysis.go it works on tokens rather than a full parse tree: each
+// statement is matched against the forms of CREATE, ALTER, DROP and TRUNCATE
+// that commonly appear in migrations. Statements it does not recognize are
+// listed as not analyzed rather than guessed at.
+
+// Table lock modes taken by DDL, weakest first
+const (
+	lockShareUpdateExclusive = "SHARE UPDATE EXCLUSIVE"
+	lockShare                = "SHARE"
+	lockShareRowExclusive    = "SHARE ROW EXCLUSIVE"
+	lockAccessExclusive      = "ACCESS EXCLUSIVE"
+)
+
+var lockStrength = map[string]int{
+	lockShareUpdateExclusive: 1,
+	lockShare:                2,
+	lockShareRowExclusive:    3,
+	lockAccessExclusive:      4,
+}
+
+// lockEffects describes what other sessions experience while a lock is held
+var lockEffects = map[string]string{
+	lockShareUpdateExclusive: "reads and writes continue; VACUUM and other schema changes wait",
+	lockShare:                "reads continue; INSERT, UPDATE and DELETE wait",
+	lockShareRowExclusive:    "reads continue; INSERT, UPDATE and DELETE wait",
+	lockAccessExclusive:      "all reads and writes wait",
+}
+
+// volatileFunctions are common volatile functions; a column default calling
+// one of them must be evaluated for every existing row
+var volatileFunctions = map[string]bool{
+	"random": true, "clock_timestamp": true, "timeofday": true,
+	"gen_random_uuid": true, "uuid_generate_v1": true, "uuid_generate_v1mc": true,
+	"uuid_generate_v4": true, "nextval": true,
+}
+
+// displayKeywords are words rendered in upper case when DDL is echoed back
+var displayKeywords = map[string]bool{
+	"ALWAYS": true, "CASCADE": true, "CHECK": true, "CONSTRAINT": true,
+	"EXCLUDE": true, "FOREIGN": true, "GENERATED": true, "IDENTITY": true,
+	"KEY": true, "PRIMARY": true, "REFERENCES": true, "RESTRICT": true,
+	"STORED": true, "UNIQUE": true, "VALID": true,
+}
+
+type planAction int
+
+const (
+	planCreate  planAction = iota // Object is created
+	planAlter                     // Object is changed in place
+	planDrop                      // Object or data is removed
+	planNoop                      // Nothing changes (IF EXISTS / IF NOT EXISTS)
+	planUnknown                   // Statement was not analyzed
+)
+
+// symbol returns the plan marker for an action
+func (a planAction) symbol() string {
+	switch a {
+	case planCreate:
+		return "+"
+	case planAlter:
+		return "~"
+	case planDrop:
+		return "-"
+	case planNoop:
+		return "="
+	}
+	return "?"
+}
+
+// planStep is a single change made by a statement, such as one ALTER TABLE action
+type planStep struct {
+	Action  planAction
+	Text    string
+	Lock    string
+	Rewrite bool // The table and its indexes are rewritten
+	Scan    bool // Existing rows are read to build an index or validate a constraint
+
+	// rewriteBefore11 is set for ADD COLUMN with a non-volatile default,
+	// which rewrites the table before PostgreSQL 11
+	rewriteBefore11 bool
+}
+
+// plannedChange is the plan for one object affected by a statement
+type plannedChange struct {
+	Action planAction
+	Title  string // e.g. "alter table public.orders"
+	Table  string // schema.table key of the locked table, if any
+	Lock   string // Lock taken by the statement itself (steps may add stronger ones)
+	Steps  []*planStep
+	Notes  []string
+	Errors []string // Reasons the statement is expected to fail
+
+	newTable   bool   // Table was created earlier in the same script
+	dropsData  bool   // Existing rows are removed
+	dependents bool   // Dependent views are relevant (drops and type changes)
+	ifExists   bool   // IF EXISTS was given
+	indexName  string // DROP INDEX target, resolved to its table from the catalog
+}
+
+// lock returns the strongest lock taken by the change
+func (c *plannedChange) lock() string {
+	lock := c.Lock
+	for _, step := range c.Steps {
+		if lockStrength[step.Lock] > lockStrength[lock] {
+			lock = step.Lock
+		}
+	}
+	return lock
+}
+
+func (c *plannedChange) rewrites() bool {
+	for _, step := range c.Steps {
+		if step.Rewrite {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *plannedChange) scans() bool {
+	for _, step := range c.Steps {
+		if step.Scan {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *plannedChange) addNote(format string, args ...interface{}) {
+	c.Notes = append(c.Notes, fmt.Sprintf(format, args...))
+}
+
+func (c *plannedChange) addError(format string, args ...interface{}) {
+	c.Errors = append(c.Errors, fmt.Sprintf(format, args...))
+}
+
+// schemaPlan is the plan for a DDL script
+type schemaPlan struct {
+	Statements int
+	Changes    []*plannedChange
+	Warnings   []string
+}
+
+// schemaPlanner tracks the planned state of the schema while the statements
+// of a script are planned in order
+type schemaPlanner struct {
+	metadata map[string]database.TableInfo
+	plan     *schemaPlan
+
+	tables  map[string]bool // Tables created (true) or dropped (false) by the script
+	columns map[string]bool // schema.table.column added (true) or dropped (false)
+
+	inTransaction bool // Inside BEGIN ... COMMIT
+	transactional bool // The script manages its own transaction
+	lockTimeout   bool // SET lock_timeout was seen
+}
+
+// planSchemaChange computes the plan for a DDL script without executing it
+func planSchemaChange(ddl string, metadata map[string]database.TableInfo) (*schemaPlan, error) {
+	tokens, err := tokenizeSQL(ddl)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &schemaPlanner{
+		metadata: metadata,
+		plan:     &schemaPlan{},
+		tables:   make(map[string]bool),
+		columns:  make(map[string]bool),
+	}
+
+	statements := splitStatements(tokens)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no statements found")
+	}
+	for _, stmt := range statements {
+		p.plan.Changes = append(p.plan.Changes, p.planStatement(stmt)...)
+	}
+	p.plan.Statements = len(statements)
+	p.addWarnings()
+
+	return p.plan, nil
+}
+
+// ddlCursor walks the tokens of a statement
+type ddlCursor struct {
+	toks []sqlToken
+	pos  int
+}
+
+func (c *ddlCursor) done() bool {
+	return c.pos >= len(c.toks)
+}
+
+func (c *ddlCursor) peek() sqlToken {
+	if c.done() {
+		return sqlToken{kind: tokPunct}
+	}
+	return c.toks[c.pos]
+}
+
+// accept consumes a sequence of keywords if all of them are next
+func (c *ddlCursor) accept(words ...string) bool {
+	for i, w := range words {
+		if c.pos+i >= len(c.toks) || !c.toks[c.pos+i].isKeyword(w) {
+			return false
+		}
+	}
+	c.pos += len(words)
+	return true
+}
+
+// name consumes an optionally schema-qualified identifier
+func (c *ddlCursor) name() (schema, name string, ok bool) {
+	t := c.peek()
+	if t.kind != tokWord && t.kind != tokQuotedIdent {
+		return "", "", false
+	}
+	c.pos++
+	name = t.value
+	if c.peek().isPunct(".") && c.pos+1 < len(c.toks) {
+		next := c.toks[c.pos+1]
+		if next.kind == tokWord || next.kind == tokQuotedIdent {
+			c.pos += 2
+			return name, next.value, true
+		}
+	}
+	return "", name, true
+}
+
+// parens consumes a parenthesized list and returns the tokens inside it
+func (c *ddlCursor) parens() ([]sqlToken, bool) {
+	if !c.peek().isPunct("(") {
+		return nil, false
+	}
+	start := c.pos + 1
+	depth := 0
+	for ; c.pos < len(c.toks); c.pos++ {
+		switch {
+		case c.toks[c.pos].isPunct("("):
+			depth++
+		case c.toks[c.pos].isPunct(")"):
+			depth--
+			if depth == 0 {
+				c.pos++
+				return c.toks[start : c.pos-1], true
+			}
+		}
+	}
+	return c.toks[start:], true
+}
+
+func (c *ddlCursor) rest() []sqlToken {
+	if c.done() {
+		return nil
+	}
+	return c.toks[c.pos:]
+}
+
+// splitTopLevel splits tokens on commas outside parentheses
+func splitTopLevel(toks []sqlToken) [][]sqlToken {
+	var parts [][]sqlToken
+	depth := 0
+	start := 0
+	for i, t := range toks {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case t.isPunct(",") && depth == 0:
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(toks) {
+		parts = append(parts, toks[start:])
+	}
+	return parts
+}
+
+// containsKeyword reports whether any token outside parentheses is one of words
+func containsKeyword(toks []sqlToken, words ...string) bool {
+	depth := 0
+	for _, t := range toks {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.isKeyword(words...):
+			return true
+		}
+	}
+	return false
+}
+
+// tokensText renders tokens back into SQL for display
+func tokensText(toks []sqlToken) string {
+	var sb strings.Builder
+	for i, t := range toks {
+		text := t.value
+		switch t.kind {
+		case tokString:
+			text = "'" + strings.ReplaceAll(t.value, "'", "''") + "'"
+		case tokQuotedIdent:
+			text = `"` + strings.ReplaceAll(t.value, `"`, `""`) + `"`
+		case tokWord:
+			if sqlKeywords[t.upper] || displayKeywords[t.upper] {
+				text = t.upper
+			}
+		}
+		if i > 0 && needsSpace(toks[i-1], t) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
+
+// needsSpace decides whether a space separates two tokens when rendering SQL
+func needsSpace(prev, t sqlToken) bool {
+	if prev.isPunct("(") || prev.isPunct(".") || prev.isPunct("::") {
+		return false
+	}
+	if t.isPunct(",") || t.isPunct(")") || t.isPunct(".") || t.isPunct("::") {
+		return false
+	}
+	if t.isPunct("(") {
+		// Function calls and type modifiers hug the name; keywords do not
+		return !prev.isIdent()
+	}
+	return true
+}
+
+// qualify joins a schema and name for display
+func qualify(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
+
+// tableKey resolves a table name the way the default search path would:
+// public first, then the only schema containing a table with that name
+func (p *schemaPlanner) tableKey(schema, name string) string {
+	if schema != "" {
+		return schema + "." + name
+	}
+	key := "public." + name
+	if _, ok := p.tables[key]; ok {
+		return key
+	}
+	if _, ok := p.metadata[key]; ok {
+		return key
+	}
+	match := ""
+	for k, table := range p.metadata {
+		if table.TableName == name {
+			if match != "" {
+				return key
+			}
+			match = k
+		}
+	}
+	if match != "" {
+		return match
+	}
+	return key
+}
+
+// lookupTable returns the key of a table, its metadata, and whether it
+// exists at this point of the script
+func (p *schemaPlanner) lookupTable(schema, name string) (string, *database.TableInfo, bool) {
+	key := p.tableKey(schema, name)
+	if exists, ok := p.tables[key]; ok {
+		if !exists {
+			return key, nil, false
+		}
+		if table, ok := p.metadata[key]; ok {
+			return key, &table, true
+		}
+		return key, nil, true
+	}
+	if table, ok := p.metadata[key]; ok {
+		return key, &table, true
+	}
+	return key, nil, false
+}
+
+// isNewTable reports whether a table was created earlier in the script
+func (p *schemaPlanner) isNewTable(key string) bool {
+	_, inMetadata := p.metadata[key]
+	return p.tables[key] && !inMetadata
+}
+
+// lookupColumn returns a column's metadata and whether it exists at this
+// point of the script; columns created by the script have no metadata
+func (p *schemaPlanner) lookupColumn(key string, table *database.TableInfo, name string) (*database.ColumnInfo, bool) {
+	if exists, ok := p.columns[key+"."+name]; ok {
+		if !exists {
+			return nil, false
+		}
+		if table != nil {
+			return findColumn(table, name), true
+		}
+		return nil, true
+	}
+	if table == nil {
+		return nil, false
+	}
+	col := findColumn(table, name)
+	return col, col != nil
+}
+
+// referencingTables lists the tables with foreign keys referencing key
+func (p *schemaPlanner) referencingTables(key string) []string {
+	seen := make(map[string]bool)
+	for k, table := range p.metadata {
+		if exists, ok := p.tables[k]; k == key || (ok && !exists) {
+			continue
+		}
+		for _, col := range table.Columns {
+			if strings.HasPrefix(col.ForeignKeyRef, key+".") {
+				seen[k] = true
+			}
+		}
+	}
+	var tables []string
+	for k := range seen {
+		tables = append(tables, k)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// planStatement plans one statement; statements naming several objects
+// (DROP TABLE a, b) produce one change per object
+func (p *schemaPlanner) planStatement(toks []sqlToken) []*plannedChange {
+	c := &ddlCursor{toks: toks}
+
+	switch {
+	case c.accept("BEGIN"), c.accept("START", "TRANSACTION"):
+		p.inTransaction = true
+		p.transactional = true
+		return nil
+	case c.accept("COMMIT"), c.accept("END"), c.accept("ROLLBACK"):
+		p.inTransaction = false
+		return nil
+	case c.accept("SET"):
+		c.accept("LOCAL")
+		if c.peek().isKeyword("LOCK_TIMEOUT") {
+			p.lockTimeout = true
+		}
+		return nil
+
+	case c.accept("CREATE"):
+		orReplace := c.accept("OR", "REPLACE")
+		switch {
+		case c.accept("TEMP"), c.accept("TEMPORARY"), c.accept("UNLOGGED"):
+			if c.accept("TABLE") {
+				return []*plannedChange{p.planCreateTable(c)}
+			}
+		case c.accept("TABLE"):
+			return []*plannedChange{p.planCreateTable(c)}
+		case c.accept("UNIQUE", "INDEX"):
+			return []*plannedChange{p.planCreateIndex(c, true)}
+		case c.accept("INDEX"):
+			return []*plannedChange{p.planCreateIndex(c, false)}
+		case c.accept("VIEW"):
+			return []*plannedChange{p.planCreateView(c, orReplace, false)}
+		case c.accept("MATERIALIZED", "VIEW"):
+			return []*plannedChange{p.planCreateView(c, orReplace, true)}
+		}
+
+	case c.accept("ALTER", "TABLE"):
+		return []*plannedChange{p.planAlterTable(c)}
+
+	case c.accept("DROP", "TABLE"):
+		return p.planDropTable(c)
+	case c.accept("DROP", "INDEX"):
+		return p.planDropIndex(c)
+	case c.accept("DROP", "VIEW"), c.accept("DROP", "MATERIALIZED", "VIEW"):
+		return p.planDropView(c)
+
+	case c.accept("TRUNCATE"):
+		return p.planTruncate(c)
+	}
+
+	change := &plannedChange{Action: planUnknown}
+	head := toks
+	if len(head) > 4 {
+		head = head[:4]
+	}
+	change.Title = tokensText(head) + "..."
+	if toks[0].isKeyword("SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "MERGE", "COPY") {
+		change.addNote("Not a schema change; it was not analyzed")
+	} else {
+		change.addNote("This statement type is not analyzed; review its locking and effects manually")
+	}
+	return []*plannedChange{change}
+}
+
+// planCreateTable plans CREATE TABLE
+func (p *schemaPlanner) planCreateTable(c *ddlCursor) *plannedChange {
+	ifNotExists := c.accept("IF", "NOT", "EXISTS")
+	schema, name, ok := c.name()
+	if !ok {
+		return &plannedChange{Action: planUnknown, Title: "create table", Errors: []string{"Could not find the table name"}}
+	}
+	if schema == "" {
+		schema = "public"
+	}
+	key := schema + "." + name
+	change := &plannedChange{Action: planCreate, Title: "create table " + key}
+
+	if _, _, exists := p.lookupTable(schema, name); exists {
+		if ifNotExists {
+			change.Action = planNoop
+			change.addNote("Table already exists; IF NOT EXISTS makes this a no-op")
+		} else {
+			change.addError("Table %s already exists", key)
+		}
+		return change
+	}
+
+	p.tables[key] = true
+	switch {
+	case c.accept("PARTITION", "OF"):
+		ps, pn, _ := c.name()
+		parentKey, _, exists := p.lookupTable(ps, pn)
+		change.Title += " (partition of " + parentKey + ")"
+		if !exists {
+			change.addError("Parent table %s does not exist", parentKey)
+		} else if !p.isNewTable(parentKey) {
+			change.Table = parentKey
+			change.Lock = lockAccessExclusive
+		}
+		return change
+	case c.accept("AS"):
+		change.addNote("Rows are copied from the query; this can take a while for large results")
+		return change
+	}
+
+	elements, ok := c.parens()
+	if !ok {
+		return change
+	}
+	columns := 0
+	for _, elem := range splitTopLevel(elements) {
+		if len(elem) == 0 {
+			continue
+		}
+		if elem[0].isKeyword("CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE", "LIKE") {
+			p.checkReferences(change, elem)
+			continue
+		}
+		columns++
+		p.columns[key+"."+elem[0].value] = true
+		p.checkReferences(change, elem)
+	}
+	change.Title += fmt.Sprintf(" (%d columns)", columns)
+	return change
+}
+
+// checkReferences notes the lock a foreign key takes on the referenced
+// table and reports referenced tables that do not exist
+func (p *schemaPlanner) checkReferences(change *plannedChange, toks []sqlToken) {
+	for i, t := range toks {
+		if !t.isKeyword("REFERENCES") {
+			continue
+		}
+		c := &ddlCursor{toks: toks, pos: i + 1}
+		schema, name, ok := c.name()
+		if !ok {
+			continue
+		}
+		key, _, exists := p.lookupTable(schema, name)
+		switch {
+		case !exists:
+			change.addError("Referenced table %s does not exist", key)
+		case !p.isNewTable(key):
+			change.addNote("The foreign key takes a %s lock on %s (%s)",
+				lockShareRowExclusive, key, lockEffects[lockShareRowExclusive])
+		}
+	}
+}
+
+// planCreateIndex plans CREATE [UNIQUE] INDEX
+func (p *schemaPlanner) planCreateIndex(c *ddlCursor, unique bool) *plannedChange {
+	concurrently := c.accept("CONCURRENTLY")
+	ifNotExists := c.accept("IF", "NOT", "EXISTS")
+	indexName := ""
+	if !c.peek().isKeyword("ON") {
+		s, n, _ := c.name()
+		indexName = qualify(s, n)
+	}
+	c.accept("ON")
+	c.accept("ONLY")
+	schema, name, ok := c.name()
+	if !ok {
+		return &plannedChange{Action: planUnknown, Title: "create index", Errors: []string{"Could not find the indexed table"}}
+	}
+	key, table, exists := p.lookupTable(schema, name)
+
+	kind := "index"
+	if unique {
+		kind = "unique index"
+	}
+	title := "create " + kind
+	if indexName != "" {
+		title += " " + indexName
+	}
+	change := &plannedChange{Action: planCreate, Title: title + " on " + key, Table: key}
+	if !exists {
+		change.addError("Table %s does not exist", key)
+		return change
+	}
+
+	if c.accept("USING") {
+		if method := c.peek(); method.kind == tokWord {
+			c.pos++
+			change.Title += " using " + method.value
+		}
+	}
+	elements, _ := c.parens()
+	var columns []string
+	for _, elem := range splitTopLevel(elements) {
+		if len(elem) == 0 {
+			continue
+		}
+		if elem[0].isPunct("(") || (len(elem) > 1 && elem[1].isPunct("(")) {
+			columns = append(columns, tokensText(elem))
+			continue
+		}
+		col, found := p.lookupColumn(key, table, elem[0].value)
+		if !found {
+			change.addError("Column %s does not exist in %s", elem[0].value, key)
+		}
+		if len(splitTopLevel(elements)) == 1 && col != nil && col.IsIndexed && !c.peek().isKeyword("WHERE") {
+			change.addNote("Column %s is already indexed; check that this index is not redundant", col.ColumnName)
+		}
+		columns = append(columns, elem[0].value)
+	}
+	change.Title += " (" + strings.Join(columns, ", ") + ")"
+	if c.accept("INCLUDE") {
+		c.parens()
+	}
+	if c.peek().isKeyword("WHERE") {
+		change.Title += " (partial)"
+	}
+
+	if p.isNewTable(key) {
+		return change
+	}
+
+	lock := lockShare
+	if concurrently {
+		lock = lockShareUpdateExclusive
+	}
+	change.Steps = append(change.Steps, &planStep{
+		Action: planCreate,
+		Text:   "build the index by reading the whole table",
+		Lock:   lock,
+		Scan:   true,
+	})
+	if ifNotExists && indexName != "" {
+		change.addNote("IF NOT EXISTS makes this a no-op if an index named %s already exists", indexName)
+	}
+	if unique {
+		change.addNote("Fails if existing rows contain duplicate values")
+	}
+	switch {
+	case concurrently && p.inTransaction:
+		change.addError("CREATE INDEX CONCURRENTLY cannot run inside a transaction block")
+	case concurrently:
+		change.addNote("CONCURRENTLY scans the table twice and leaves an INVALID index behind if it fails")
+	default:
+		change.addNote("Use CREATE INDEX CONCURRENTLY to keep writes flowing (it cannot run inside a transaction block)")
+	}
+	return change
+}
+
+// planCreateView plans CREATE [OR REPLACE] [MATERIALIZED] VIEW
+func (p *schemaPlanner) planCreateView(c *ddlCursor, orReplace, materialized bool) *plannedChange {
+	kind := "view"
+	if materialized {
+		kind = "materialized view"
+	}
+	c.accept("IF", "NOT", "EXISTS")
+	schema, name, ok := c.name()
+	if !ok {
+		return &plannedChange{Action: planUnknown, Title: "create " + kind, Errors: []string{"Could not find the view name"}}
+	}
+	if schema == "" {
+		schema = "public"
+	}
+	key := schema + "." + name
+	change := &plannedChange{Action: planCreate, Title: "create " + kind + " " + key}
+
+	if _, _, exists := p.lookupTable(schema, name); exists {
+		if !orReplace {
+			change.addError("%s already exists", key)
+			return change
+		}
+		change.Action = planAlter
+		change.Title = "replace " + kind + " " + key
+		change.Table = key
+		change.Lock = lockAccessExclusive
+		change.addNote("CREATE OR REPLACE VIEW can only add columns at the end; renaming, removing or retyping columns fails")
+	}
+	p.tables[key] = true
+	if materialized {
+		change.addNote("The query runs once to populate the view")
+	}
+	return change
+}
+
+// planAlterTable plans ALTER TABLE and each of its actions
+func (p *schemaPlanner) planAlterTable(c *ddlCursor) *plannedChange {
+	ifExists := c.accept("IF", "EXISTS")
+	c.accept("ONLY")
+	schema, name, ok := c.name()
+	if !ok {
+		return &plannedChange{Action: planUnknown, Title: "alter table", Errors: []string{"Could not find the table name"}}
+	}
+	if c.peek().isPunct("*") {
+		c.pos++
+	}
+	key, table, exists := p.lookupTable(schema, name)
+	change := &plannedChange{Action: planAlter, Title: "alter table " + key, Table: key, ifExists: ifExists}
+
+	if !exists {
+		if ifExists {
+			change.Action = planNoop
+			change.addNote("Table does not exist; IF EXISTS makes this a no-op")
+		} else {
+			change.addError("Table %s does not exist", key)
+		}
+		return change
+	}
+	change.newTable = p.isNewTable(key)
+
+	switch {
+	case c.accept("RENAME", "TO"):
+		_, newName, _ := c.name()
+		change.Steps = append(change.Steps, &planStep{Action: planAlter, Text: "rename table to " + newName, Lock: lockAccessExclusive})
+		change.addNote("Queries, views and functions that refer to %s by name must be updated", key)
+		p.tables[key] = false
+		p.tables[schemaOf(key)+"."+newName] = true
+		return change
+	case c.accept("RENAME", "CONSTRAINT"):
+		_, oldName, _ := c.name()
+		c.accept("TO")
+		_, newName, _ := c.name()
+		change.Steps = append(change.Steps, &planStep{Action: planAlter, Text: fmt.Sprintf("rename constraint %s to %s", oldName, newName), Lock: lockAccessExclusive})
+		return change
+	case c.accept("RENAME"):
+		c.accept("COLUMN")
+		_, oldName, _ := c.name()
+		c.accept("TO")
+		_, newName, _ := c.name()
+		if _, found := p.lookupColumn(key, table, oldName); !found {
+			change.addError("Column %s does not exist in %s", oldName, key)
+		}
+		p.columns[key+"."+oldName] = false
+		p.columns[key+"."+newName] = true
+		change.Steps = append(change.Steps, &planStep{Action: planAlter, Text: fmt.Sprintf("rename column %s to %s", oldName, newName), Lock: lockAccessExclusive})
+		change.addNote("Queries and functions that refer to column %s must be updated", oldName)
+		return change
+	case c.accept("SET", "SCHEMA"):
+		_, newSchema, _ := c.name()
+		change.Steps = append(change.Steps, &planStep{Action: planAlter, Text: "move to schema " + newSchema, Lock: lockAccessExclusive})
+		p.tables[key] = false
+		p.tables[newSchema+"."+name] = true
+		return change
+	}
+
+	for _, action := range splitTopLevel(c.rest()) {
+		if len(action) > 0 {
+			p.planAlterAction(change, key, table, action)
+		}
+	}
+	return change
+}
+
+// schemaOf returns the schema part of a schema.table key
+func schemaOf(key string) string {
+	if i := strings.Index(key, "."); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// planAlterAction plans one action of ALTER TABLE
+func (p *schemaPlanner) planAlterAction(change *plannedChange, key string, table *database.TableInfo, toks []sqlToken) {
+	c := &ddlCursor{toks: toks}
+	step := &planStep{Action: planAlter, Lock: lockAccessExclusive, Text: tokensText(toks)}
+
+	switch {
+	case c.accept("ADD", "CONSTRAINT"), c.accept("ADD", "PRIMARY"), c.accept("ADD", "UNIQUE"),
+		c.accept("ADD", "FOREIGN"), c.accept("ADD", "CHECK"), c.accept("ADD", "EXCLUDE"):
+		step.Action = planCreate
+		p.planAddConstraint(change, step, key, table, toks[1:])
+
+	case c.accept("ADD"):
+		c.accept("COLUMN")
+		step.Action = planCreate
+		p.planAddColumn(change, step, key, table, c)
+
+	case c.accept("DROP", "CONSTRAINT"):
+		step.Action = planDrop
+
+	case c.accept("DROP"):
+		c.accept("COLUMN")
+		step.Action = planDrop
+		ifExists := c.accept("IF", "EXISTS")
+		_, colName, _ := c.name()
+		cascade := c.accept("CASCADE")
+		col, found := p.lookupColumn(key, table, colName)
+		step.Text = "drop column " + colName
+		switch {
+		case !found && ifExists:
+			step.Action = planNoop
+			step.Text += " (does not exist; skipped)"
+		case !found:
+			change.addError("Column %s does not exist in %s", colName, key)
+		default:
+			p.columns[key+"."+colName] = false
+			if !change.newTable {
+				change.addNote("Dropping %s removes its data; the space is reclaimed only as rows are rewritten (no immediate table rewrite)", colName)
+				change.dependents = true
+			}
+			if col != nil && col.IsPrimaryKey {
+				change.addNote("%s is part of the primary key; the primary key constraint is dropped too", colName)
+			}
+			if col != nil && !cascade {
+				for _, ref := range p.referencingTables(key) {
+					if p.referencesColumn(ref, key+"."+colName) {
+						change.addError("Column %s is referenced by a foreign key from %s; the statement fails without CASCADE", colName, ref)
+					}
+				}
+			}
+		}
+
+	case c.accept("ALTER"):
+		c.accept("COLUMN")
+		_, colName, _ := c.name()
+		col, found := p.lookupColumn(key, table, colName)
+		if !found {
+			change.addError("Column %s does not exist in %s", colName, key)
+		}
+		p.planAlterColumn(change, step, col, colName, c)
+
+	case c.accept("VALIDATE", "CONSTRAINT"):
+		step.Lock = lockShareUpdateExclusive
+		step.Scan = true
+
+	case c.accept("SET", "TABLESPACE"), c.accept("SET", "LOGGED"), c.accept("SET", "UNLOGGED"),
+		c.accept("SET", "ACCESS", "METHOD"):
+		step.Rewrite = true
+
+	case c.accept("SET", "WITHOUT", "CLUSTER"), c.accept("CLUSTER", "ON"),
+		c.accept("SET"), c.accept("RESET"):
+		// Storage parameters such as fillfactor and autovacuum settings
+		step.Lock = lockShareUpdateExclusive
+
+	case c.accept("ENABLE", "TRIGGER"), c.accept("DISABLE", "TRIGGER"),
+		c.accept("ENABLE", "ALWAYS", "TRIGGER"), c.accept("ENABLE", "REPLICA", "TRIGGER"):
+		step.Lock = lockShareRowExclusive
+
+	case c.accept("ATTACH", "PARTITION"):
+		step.Lock = lockShareUpdateExclusive
+		step.Scan = true
+		change.addNote("The partition is scanned to check its rows match the bound unless a matching CHECK constraint exists")
+
+	case c.accept("DETACH", "PARTITION"):
+		if containsKeyword(toks, "CONCURRENTLY") {
+			step.Lock = lockShareUpdateExclusive
+		}
+
+	case c.accept("OWNER", "TO"), c.accept("ENABLE", "ROW"), c.accept("DISABLE", "ROW"),
+		c.accept("FORCE", "ROW"), c.accept("NO", "FORCE", "ROW"), c.accept("INHERIT"), c.accept("NO", "INHERIT"),
+		c.accept("REPLICA", "IDENTITY"):
+		// Catalog-only changes under an ACCESS EXCLUSIVE lock
+
+	default:
+		step.Action = planUnknown
+		change.addNote("Action %q was not recognized; ACCESS EXCLUSIVE is assumed", tokensText(toks))
+	}
+
+	change.Steps = append(change.Steps, step)
+}
+
+// referencesColumn reports whether a table has a foreign key to target (schema.table.column)
+func (p *schemaPlanner) referencesColumn(key, target string) bool {
+	table, ok := p.metadata[key]
+	if !ok {
+		return false
+	}
+	for _, col := range table.Columns {
+		if col.ForeignKeyRef == target {
+			return true
+		}
+	}
+	return false
+}
+
+// planAddColumn plans ALTER TABLE ... ADD COLUMN
+func (p *schemaPlanner) planAddColumn(change *plannedChange, step *planStep, key string, table *database.TableInfo, c *ddlCursor) {
+	ifNotExists := c.accept("IF", "NOT", "EXISTS")
+	def := c.rest()
+	if len(def) == 0 {
+		return
+	}
+	colName := def[0].value
+	step.Text = "add column " + tokensText(def)
+
+	if _, found := p.lookupColumn(key, table, colName); found {
+		if ifNotExists {
+			step.Action = planNoop
+			step.Text = "add column " + colName + " (already exists; skipped)"
+		} else {
+			change.addError("Column %s already exists in %s", colName, key)
+		}
+		return
+	}
+	p.columns[key+"."+colName] = true
+	p.checkReferences(change, def)
+	if change.newTable {
+		return
+	}
+
+	typeName := ""
+	if len(def) > 1 && def[1].kind == tokWord {
+		typeName = def[1].value
+	}
+	hasDefault := containsKeyword(def, "DEFAULT")
+	serial := typeName == "serial" || typeName == "bigserial" || typeName == "smallserial" ||
+		typeName == "serial4" || typeName == "serial8" || typeName == "serial2"
+
+	switch {
+	case containsKeyword(def, "IDENTITY"):
+		step.Rewrite = true
+		change.addNote("Identity column %s is filled in for every existing row, which rewrites the table", colName)
+	case containsKeyword(def, "GENERATED") && containsKeyword(def, "STORED"):
+		step.Rewrite = true
+		change.addNote("Stored generated column %s is computed for every existing row, which rewrites the table", colName)
+	case serial:
+		step.Rewrite = true
+		change.addNote("%s columns get a nextval() default that is evaluated for every existing row, which rewrites the table", typeName)
+	case hasDefault && defaultIsVolatile(def):
+		step.Rewrite = true
+		change.addNote("The default for %s is volatile, so it is evaluated for every existing row and the table is rewritten", colName)
+	case hasDefault:
+		step.rewriteBefore11 = true
+	}
+
+	if containsKeyword(def, "NOT") && containsKeyword(def, "NULL") && !hasDefault && !serial &&
+		!containsKeyword(def, "GENERATED") {
+		change.addNote("NOT NULL without a default fails unless the table is empty")
+	}
+	if containsKeyword(def, "PRIMARY", "UNIQUE") {
+		step.Scan = true
+		change.addNote("The %s constraint on %s builds an index by reading the whole table", constraintKind(def), colName)
+	}
+	if containsKeyword(def, "CHECK") && !step.Rewrite {
+		step.Scan = true
+	}
+}
+
+// constraintKind names the index-backed constraint in a column definition
+func constraintKind(toks []sqlToken) string {
+	if containsKeyword(toks, "PRIMARY") {
+		return "PRIMARY KEY"
+	}
+	return "UNIQUE"
+}
+
+// defaultIsVolatile reports whether the DEFAULT expression of a column
+// definition calls a volatile function
+func defaultIsVolatile(def []sqlToken) bool {
+	inDefault := false
+	for i, t := range def {
+		if t.isKeyword("DEFAULT") {
+			inDefault = true
+			continue
+		}
+		if !inDefault {
+			continue
+		}
+		if t.isKeyword("NOT", "NULL", "CONSTRAINT", "PRIMARY", "UNIQUE", "REFERENCES", "CHECK", "COLLATE") {
+			return false
+		}
+		if t.kind == tokWord && volatileFunctions[t.value] && i+1 < len(def) && def[i+1].isPunct("(") {
+			return true
+		}
+	}
+	return false
+}
+
+// planAlterColumn plans ALTER TABLE ... ALTER COLUMN
+func (p *schemaPlanner) planAlterColumn(change *plannedChange, step *planStep, col *database.ColumnInfo, colName string, c *ddlCursor) {
+	switch {
+	case c.accept("TYPE"), c.accept("SET", "DATA", "TYPE"):
+		var typeToks []sqlToken
+		for !c.done() && !c.peek().isKeyword("USING", "COLLATE") {
+			typeToks = append(typeToks, c.peek())
+			c.pos++
+		}
+		newType := tokensText(typeToks)
+		step.Text = fmt.Sprintf("alter column %s type %s", colName, newType)
+		if col == nil || change.newTable {
+			return
+		}
+		step.Text = fmt.Sprintf("alter column %s type %s -> %s", colName, col.DataType, newType)
+		change.dependents = true
+		switch {
+		case c.accept("USING"):
+			step.Rewrite = true
+		case typeChangeIsBinaryCompatible(col.DataType, newType):
+			if col.IsIndexed && !sameType(col.DataType, newType) {
+				change.addNote("Indexes on %s may be rebuilt, but the table is not rewritten", colName)
+			}
+			return
+		default:
+			step.Rewrite = true
+		}
+		change.addNote("Changing %s from %s to %s rewrites the table and rebuilds its indexes", colName, col.DataType, newType)
+		change.addNote("The statement fails if a view or rule uses %s", colName)
+
+	case c.accept("SET", "NOT", "NULL"):
+		step.Text = fmt.Sprintf("alter column %s set not null", colName)
+		if col != nil && col.IsNullable == "NO" {
+			step.Action = planNoop
+			step.Text += " (already NOT NULL)"
+			return
+		}
+		if change.newTable {
+			return
+		}
+		step.Scan = true
+		change.addNote("SET NOT NULL scans the whole table under ACCESS EXCLUSIVE and fails if any %s is NULL; "+
+			"a CHECK (%s IS NOT NULL) NOT VALID constraint validated beforehand lets PostgreSQL 12+ skip the scan", colName, colName)
+
+	case c.accept("DROP", "NOT", "NULL"):
+		step.Text = fmt.Sprintf("alter column %s drop not null", colName)
+		if col != nil && col.IsPrimaryKey {
+			change.addError("Column %s is part of the primary key and cannot be made nullable", colName)
+		}
+
+	case c.accept("SET", "DEFAULT"):
+		step.Text = fmt.Sprintf("alter column %s set default %s", colName, tokensText(c.rest()))
+		change.addNote("A new default only applies to rows inserted afterwards; existing rows are not changed")
+
+	case c.accept("DROP", "DEFAULT"):
+		step.Text = fmt.Sprintf("alter column %s drop default", colName)
+
+	case c.accept("SET", "STATISTICS"), c.peek().isKeyword("SET", "RESET") && len(c.rest()) > 1 && c.rest()[1].isPunct("("):
+		step.Lock = lockShareUpdateExclusive
+
+	case c.accept("ADD", "GENERATED"):
+		step.Text = fmt.Sprintf("alter column %s add identity", colName)
+	}
+}
+
+// typeAliases maps type names to the names format_type() reports
+var typeAliases = map[string]string{
+	"bool": "boolean", "bpchar": "character", "char": "character",
+	"decimal": "numeric", "float4": "real", "float8": "double precision",
+	"int": "integer", "int2": "smallint", "int4": "integer", "int8": "bigint",
+	"time": "time without time zone", "timestamp": "timestamp without time zone",
+	"timestamptz": "timestamp with time zone", "timetz": "time with time zone",
+	"varbit": "bit varying", "varchar": "character varying",
+}
+
+// splitType separates a type name from its modifier, normalizing aliases:
+// "varchar(20)" becomes ("character varying", "20")
+func splitType(t string) (string, string) {
+	t = strings.ToLower(t)
+	mod := ""
+	if i := strings.Index(t, "("); i >= 0 {
+		if j := strings.Index(t[i:], ")"); j >= 0 {
+			mod = strings.ReplaceAll(t[i+1:i+j], " ", "")
+			t = t[:i] + " " + t[i+j+1:]
+		}
+	}
+	t = strings.Join(strings.Fields(t), " ")
+	if alias, ok := typeAliases[t]; ok {
+		t = alias
+	}
+	return t, mod
+}
+
+// sameType reports whether two type names denote the same type
+func sameType(a, b string) bool {
+	ab, am := splitType(a)
+	bb, bm := splitType(b)
+	return ab == bb && am == bm
+}
+
+// typeChangeIsBinaryCompatible reports whether a column type change can be
+// made without rewriting the table, such as increasing a varchar limit
+func typeChangeIsBinaryCompatible(from, to string) bool {
+	fb, fm := splitType(from)
+	tb, tm := splitType(to)
+
+	switch {
+	case fb == tb && fm == tm:
+		return true
+	case fb == "character varying" && tb == "text":
+		return true
+	case fb == "text" && tb == "character varying":
+		return tm == ""
+	case fb == "character varying" && tb == "character varying":
+		return tm == "" || (fm != "" && atoiOrZero(tm) >= atoiOrZero(fm))
+	case fb == "numeric" && tb == "numeric":
+		if tm == "" {
+			return true
+		}
+		if fm == "" {
+			return false
+		}
+		fp, fs, _ := strings.Cut(fm, ",")
+		tp, ts, _ := strings.Cut(tm, ",")
+		return atoiOrZero(fs) == atoiOrZero(ts) && atoiOrZero(tp) >= atoiOrZero(fp)
+	}
+	return false
+}
+
+func atoiOrZero(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// planAddConstraint plans ALTER TABLE ... ADD [CONSTRAINT name] ...
+func (p *schemaPlanner) planAddConstraint(change *plannedChange, step *planStep, key string, table *database.TableInfo, toks []sqlToken) {
+	c := &ddlCursor{toks: toks}
+	name := ""
+	if c.accept("CONSTRAINT") {
+		_, name, _ = c.name()
+	}
+	notValid := containsKeyword(toks, "NOT") && len(toks) > 1 &&
+		toks[len(toks)-2].isKeyword("NOT") && toks[len(toks)-1].isKeyword("VALID")
+	label := ""
+	if name != "" {
+		label = " " + name
+	}
+	step.Text = "add constraint" + label + ": " + tokensText(c.rest())
+
+	checkColumns := func(cols []sqlToken) []string {
+		var names []string
+		for _, part := range splitTopLevel(cols) {
+			if len(part) == 0 {
+				continue
+			}
+			names = append(names, part[0].value)
+			if _, found := p.lookupColumn(key, table, part[0].value); !found {
+				change.addError("Column %s does not exist in %s", part[0].value, key)
+			}
+		}
+		return names
+	}
+
+	switch {
+	case c.accept("FOREIGN", "KEY"):
+		cols, _ := c.parens()
+		checkColumns(cols)
+		p.checkReferences(change, toks)
+		step.Lock = lockShareRowExclusive
+		if change.newTable {
+			return
+		}
+		if notValid {
+			change.addNote("NOT VALID skips checking existing rows; run VALIDATE CONSTRAINT later (SHARE UPDATE EXCLUSIVE lock)")
+		} else {
+			step.Scan = true
+			change.addNote("Existing rows are checked against the referenced table; adding it NOT VALID and validating later avoids blocking writes during the check")
+		}
+
+	case c.accept("CHECK"):
+		if change.newTable {
+			return
+		}
+		if notValid {
+			change.addNote("NOT VALID skips checking existing rows; run VALIDATE CONSTRAINT later (SHARE UPDATE EXCLUSIVE lock)")
+		} else {
+			step.Scan = true
+			change.addNote("Existing rows are checked under ACCESS EXCLUSIVE; adding it NOT VALID and validating later keeps the lock short")
+		}
+
+	case c.accept("PRIMARY", "KEY"), c.accept("UNIQUE"):
+		isPK := toks[c.pos-1].isKeyword("KEY")
+		cols, _ := c.parens()
+		names := checkColumns(cols)
+		if isPK && table != nil {
+			for _, col := range table.Columns {
+				if col.IsPrimaryKey {
+					change.addError("Table %s already has a primary key", key)
+					break
+				}
+			}
+		}
+		if change.newTable {
+			return
+		}
+		if c.accept("USING", "INDEX") {
+			change.addNote("The constraint uses an existing index, so no index is built")
+			return
+		}
+		step.Scan = true
+		change.addNote("The index for the constraint is built under ACCESS EXCLUSIVE; building it with CREATE UNIQUE INDEX CONCURRENTLY and adding the constraint USING INDEX avoids the long lock")
+		if isPK && table != nil {
+			for _, n := range names {
+				if col := findColumn(table, n); col != nil && col.IsNullable == "YES" {
+					change.addNote("Column %s is nullable; PRIMARY KEY also sets it NOT NULL, which fails if any row is NULL", n)
+				}
+			}
+		}
+
+	case c.accept("EXCLUDE"):
+		if !change.newTable {
+			step.Scan = true
+		}
+	}
+}
+
+// planDropTable plans DROP TABLE
+func (p *schemaPlanner) planDropTable(c *ddlCursor) []*plannedChange {
+	ifExists := c.accept("IF", "EXISTS")
+	cascade := containsKeyword(c.rest(), "CASCADE")
+
+	var names [][2]string
+	for !c.done() {
+		schema, name, ok := c.name()
+		if !ok {
+			break
+		}
+		names = append(names, [2]string{schema, name})
+		if !c.peek().isPunct(",") {
+			break
+		}
+		c.pos++
+	}
+
+	// Tables dropped together may reference each other
+	dropping := make(map[string]bool)
+	for _, n := range names {
+		dropping[p.tableKey(n[0], n[1])] = true
+	}
+
+	var changes []*plannedChange
+	for _, n := range names {
+		key, _, exists := p.lookupTable(n[0], n[1])
+		change := &plannedChange{Action: planDrop, Title: "drop table " + key, Table: key, ifExists: ifExists}
+		changes = append(changes, change)
+		if !exists {
+			if ifExists {
+				change.Action = planNoop
+				change.addNote("Table does not exist; IF EXISTS makes this a no-op")
+			} else {
+				change.addError("Table %s does not exist", key)
+			}
+			continue
+		}
+
+		change.newTable = p.isNewTable(key)
+		change.Lock = lockAccessExclusive
+		if !change.newTable {
+			change.dropsData = true
+			change.dependents = true
+			change.Notes = append(change.Notes, "All rows, indexes, triggers and constraints of the table are removed")
+		}
+		for _, ref := range p.referencingTables(key) {
+			if dropping[ref] {
+				continue
+			}
+			if cascade {
+				change.addNote("CASCADE drops the foreign key constraints on %s that reference it", ref)
+			} else {
+				change.addError("Table %s is referenced by a foreign key from %s; the statement fails without CASCADE", key, ref)
+			}
+		}
+		p.tables[key] = false
+	}
+	return changes
+}
+
+// planDropIndex plans DROP INDEX; the table is found from the live catalog
+func (p *schemaPlanner) planDropIndex(c *ddlCursor) []*plannedChange {
+	concurrently := c.accept("CONCURRENTLY")
+	ifExists := c.accept("IF", "EXISTS")
+
+	var changes []*plannedChange
+	for !c.done() {
+		schema, name, ok := c.name()
+		if !ok {
+			break
+		}
+		if schema == "" {
+			schema = "public"
+		}
+		change := &plannedChange{
+			Action:    planDrop,
+			Title:     "drop index " + schema + "." + name,
+			Lock:      lockAccessExclusive,
+			ifExists:  ifExists,
+			indexName: schema + "." + name,
+		}
+		if concurrently {
+			change.Lock = lockShareUpdateExclusive
+			if p.inTransaction {
+				change.addError("DROP INDEX CONCURRENTLY cannot run inside a transaction block")
+			}
+		} else {
+			change.addNote("DROP INDEX CONCURRENTLY avoids blocking queries on the table")
+		}
+		changes = append(changes, change)
+		if !c.peek().isPunct(",") {
+			break
+		}
+		c.pos++
+	}
+	return changes
+}
+
+// planDropView plans DROP [MATERIALIZED] VIEW
+func (p *schemaPlanner) planDropView(c *ddlCursor) []*plannedChange {
+	ifExists := c.accept("IF", "EXISTS")
+
+	var changes []*plannedChange
+	for !c.done() {
+		schema, name, ok := c.name()
+		if !ok {
+			break
+		}
+		key, _, exists := p.lookupTable(schema, name)
+		change := &plannedChange{Action: planDrop, Title: "drop view " + key, Table: key, ifExists: ifExists}
+		switch {
+		case exists:
+			change.Lock = lockAccessExclusive
+			change.dependents = true
+			p.tables[key] = false
+		case ifExists:
+			change.Action = planNoop
+			change.addNote("View does not exist; IF EXISTS makes this a no-op")
+		default:
+			change.addError("View %s does not exist", key)
+		}
+		changes = append(changes, change)
+		if !c.peek().isPunct(",") {
+			break
+		}
+		c.pos++
+	}
+	return changes
+}
+
+// planTruncate plans TRUNCATE
+func (p *schemaPlanner) planTruncate(c *ddlCursor) []*plannedChange {
+	c.accept("TABLE")
+	c.accept("ONLY")
+	cascade := containsKeyword(c.rest(), "CASCADE")
+
+	var keys []string
+	for !c.done() {
+		schema, name, ok := c.name()
+		if !ok {
+			break
+		}
+		keys = append(keys, p.tableKey(schema, name))
+		if c.peek().isPunct("*") {
+			c.pos++
+		}
+		if !c.peek().isPunct(",") {
+			break
+		}
+		c.pos++
+	}
+	truncating := make(map[string]bool)
+	for _, key := range keys {
+		truncating[key] = true
+	}
+
+	var changes []*plannedChange
+	for _, key := range keys {
+		change := &plannedChange{Action: planDrop, Title: "truncate table " + key, Table: key, Lock: lockAccessExclusive}
+		changes = append(changes, change)
+		if exists, ok := p.tables[key]; (ok && !exists) || (!ok && !hasTable(p.metadata, key)) {
+			change.addError("Table %s does not exist", key)
+			continue
+		}
+		change.newTable = p.isNewTable(key)
+		change.dropsData = !change.newTable
+		for _, ref := range p.referencingTables(key) {
+			if truncating[ref] {
+				continue
+			}
+			if cascade {
+				change.addNote("CASCADE also truncates %s", ref)
+			} else {
+				change.addError("Table %s is referenced by a foreign key from %s; truncate both or use CASCADE", key, ref)
+			}
+		}
+	}
+	return changes
+}
+
+func hasTable(metadata map[string]database.TableInfo, key string) bool {
+	_, ok := metadata[key]
+	return ok
+}
+
+// addWarnings adds warnings that concern the script as a whole
+func (p *schemaPlanner) addWarnings() {
+	plan := p.plan
+
+	var exclusive []string
+	concurrently := false
+	for _, change := range plan.Changes {
+		if change.lock() == lockAccessExclusive && !change.newTable && change.Table != "" {
+			exclusive = append(exclusive, change.Table)
+		}
+		if strings.Contains(change.Title, "index") && change.lock() == lockShareUpdateExclusive {
+			concurrently = true
+		}
+	}
+
+	if len(exclusive) > 0 && !p.lockTimeout {
+		plan.Warnings = append(plan.Warnings,
+			"ACCESS EXCLUSIVE locks are taken on existing tables. Set lock_timeout (for example SET lock_timeout = '5s') "+
+				"so the change fails fast instead of queueing behind long-running transactions and blocking every query on the table")
+	}
+	if plan.Statements > 1 && !p.transactional && !concurrently {
+		plan.Warnings = append(plan.Warnings,
+			"The script has several statements; run it in a single transaction so that a failure leaves the schema unchanged")
+	}
+}
+
+// planTableStats are live statistics for a table
+type planTableStats struct {
+	Rows  int64 // Estimated rows (reltuples); -1 if the table was never analyzed
+	Bytes int64 // Total size including indexes and TOAST
+}
+
+// planCatalog is live catalog information used to complete a plan
+type planCatalog struct {
+	ServerVersion int                       // server_version_num
+	Tables        map[string]planTableStats // Keyed by schema.table
+	Dependents    map[string][]string       // Views depending on each table
+	IndexTables   map[string]string         // schema.index -> schema.table
+}
+
+// catalogTargets lists the tables and indexes the plan needs catalog details for
+func (plan *schemaPlan) catalogTargets() (tables, indexes []string) {
+	seen := make(map[string]bool)
+	for _, change := range plan.Changes {
+		if change.Table != "" && !seen[change.Table] {
+			seen[change.Table] = true
+			tables = append(tables, change.Table)
+		}
+		if change.indexName != "" {
+			indexes = append(indexes, change.indexName)
+		}
+	}
+	return tables, indexes
+}
+
+// applyCatalog completes a plan with live catalog details
+func (plan *schemaPlan) applyCatalog(catalog *planCatalog) {
+	for _, change := range plan.Changes {
+		if change.indexName != "" && change.Table == "" {
+			if table, ok := catalog.IndexTables[change.indexName]; ok {
+				change.Table = table
+				change.Title += " on " + table
+			} else if change.ifExists {
+				change.Action = planNoop
+				change.Notes = []string{"Index does not exist; IF EXISTS makes this a no-op"}
+				change.Lock = ""
+			} else {
+				change.addError("Index %s does not exist", change.indexName)
+			}
+		}
+
+		for _, step := range change.Steps {
+			if step.rewriteBefore11 && catalog.ServerVersion > 0 && catalog.ServerVersion < 110000 {
+				step.Rewrite = true
+				change.addNote("Before PostgreSQL 11, adding a column with a default rewrites the table")
+			}
+		}
+
+		if change.dependents {
+			if views := catalog.Dependents[change.Table]; len(views) > 0 {
+				switch change.Action {
+				case planDrop:
+					change.addNote("Dependent views: %s (the statement fails unless CASCADE drops them too)", strings.Join(views, ", "))
+				default:
+					change.addNote("Dependent views: %s", strings.Join(views, ", "))
+				}
+			}
+		}
+	}
+}
+
+// formatSchemaPlan renders a plan as text for the LLM
+func formatSchemaPlan(plan *schemaPlan, catalog *planCatalog) string {
+	var sb strings.Builder
+
+	counts := make(map[planAction]int)
+	var rewrites, failures []string
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+		if change.rewrites() && !change.newTable && !slices.Contains(rewrites, change.Table) {
+			rewrites = append(rewrites, change.Table)
+		}
+		if len(change.Errors) > 0 {
+			failures = append(failures, change.Title)
+		}
+	}
+
+	for _, change := range plan.Changes {
+		sb.WriteString(fmt.Sprintf("%s %s\n", change.Action.symbol(), change.Title))
+		for _, step := range change.Steps {
+			sb.WriteString(fmt.Sprintf("    %s %s", step.Action.symbol(), step.Text))
+			switch {
+			case step.Rewrite && !change.newTable:
+				sb.WriteString("  [rewrites table]")
+			case step.Scan && !change.newTable:
+				sb.WriteString("  [scans table]")
+			}
+			sb.WriteString("\n")
+		}
+		if lock := change.lock(); lock != "" && change.Table != "" {
+			if change.newTable {
+				sb.WriteString("    lock: none on existing tables (created earlier in this script)\n")
+			} else {
+				sb.WriteString(fmt.Sprintf("    lock: %s on %s (%s)", lock, change.Table, lockEffects[lock]))
+				if change.rewrites() || change.scans() {
+					sb.WriteString(" for the duration of the operation")
+				}
+				sb.WriteString("\n")
+			}
+		}
+		if catalog != nil && change.Table != "" && !change.newTable {
+			if stats, ok := catalog.Tables[change.Table]; ok {
+				sb.WriteString(fmt.Sprintf("    size: %s\n", formatTableStats(stats)))
+			}
+		}
+		for _, err := range change.Errors {
+			sb.WriteString(fmt.Sprintf("    ! will fail: %s\n", err))
+		}
+		for _, note := range change.Notes {
+			sb.WriteString(fmt.Sprintf("    note: %s\n", note))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("Plan: %d to create, %d to change, %d to drop",
+		counts[planCreate], counts[planAlter], counts[planDrop]))
+	if counts[planNoop] > 0 {
+		sb.WriteString(fmt.Sprintf(", %d unchanged", counts[planNoop]))
+	}
+	if counts[planUnknown] > 0 {
+		sb.WriteString(fmt.Sprintf(", %d not analyzed", counts[planUnknown]))
+	}
+	sb.WriteString(".\n")
+	if len(rewrites) > 0 {
+		sb.WriteString(fmt.Sprintf("Table rewrites: %s\n", strings.Join(rewrites, ", ")))
+	}
+	if len(failures) > 0 {
+		sb.WriteString(fmt.Sprintf("Expected failures: %d statement(s) would fail as written\n", len(failures)))
+	}
+
+	if len(plan.Warnings) > 0 {
+		sb.WriteString("\nWarnings:\n")
+		for _, warning := range plan.Warnings {
+			sb.WriteString(fmt.Sprintf("- %s\n", warning))
+		}
+	}
+
+	sb.WriteString("\nNothing was executed. Review the plan with the user before applying the DDL.\n")
+	return sb.String()
+}
+
+// formatTableStats describes the size of a table
+func formatTableStats(stats planTableStats) string {
+	rows := "row estimate unavailable (never analyzed)"
+	if stats.Rows >= 0 {
+		rows = "~" + strconv.FormatInt(stats.Rows, 10) + " rows"
+	}
+	return rows + ", " + formatSize(stats.Bytes)
+}
+
+// formatSize formats a byte count using binary units like pg_size_pretty
+func formatSize(bytes int64) string {
+	units := []string{"bytes", "kB", "MB", "GB", "TB"}
+	size := float64(bytes)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d bytes", bytes)
+	}
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
@@ -0,0 +1,698 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/snapshot"
+)
+
+// snapshotMinServerVersion is the oldest server whose catalogs the snapshot
+// queries support (generated columns appeared in PostgreSQL 12)
+const snapshotMinServerVersion = 120000
+
+// snapshotColumn is a column of a snapshot table
+type snapshotColumn struct {
+	Ident     string // Quoted as needed
+	Name      string
+	Type      string
+	Collation string // Qualified collation, when it differs from the type's
+	Default   string
+	Identity  string // "a" (always), "d" (by default) or ""
+	Generated string // "s" (stored), "v" (virtual) or ""
+	NotNull   bool
+}
+
+// snapshotTableDef is a table of the snapshot schema
+type snapshotTableDef struct {
+	OID            uint32
+	Ident          string
+	Name           string
+	Partitioned    bool // A partitioned table, which has no data of its own
+	Unlogged       bool
+	PartitionKey   string // PARTITION BY clause of a partitioned table
+	Parent         string // Parent of a partition, qualified when outside the schema
+	PartitionBound string // FOR VALUES clause of a partition
+	Columns        []snapshotColumn
+}
+
+// renderCreateTable renders the CREATE TABLE statement of a table.
+// Partitions take their columns from the parent.
+func renderCreateTable(t snapshotTableDef) string {
+	var sb strings.Builder
+	sb.WriteString("CREATE ")
+	if t.Unlogged {
+		sb.WriteString("UNLOGGED ")
+	}
+	sb.WriteString("TABLE " + t.Ident)
+	if t.Parent != "" {
+		sb.WriteString(" PARTITION OF " + t.Parent + " " + t.PartitionBound)
+	} else {
+		sb.WriteString(" (")
+		for i, col := range t.Columns {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n    " + col.Ident + " " + col.Type)
+			if col.Collation != "" {
+				sb.WriteString(" COLLATE " + col.Collation)
+			}
+			switch {
+			case col.Identity == "a":
+				sb.WriteString(" GENERATED ALWAYS AS IDENTITY")
+			case col.Identity == "d":
+				sb.WriteString(" GENERATED BY DEFAULT AS IDENTITY")
+			case col.Generated == "s":
+				sb.WriteString(" GENERATED ALWAYS AS (" + col.Default + ") STORED")
+			case col.Generated == "v":
+				sb.WriteString(" GENERATED ALWAYS AS (" + col.Default + ") VIRTUAL")
+			case col.Default != "":
+				sb.WriteString(" DEFAULT " + col.Default)
+			}
+			if col.NotNull {
+				sb.WriteString(" NOT NULL")
+			}
+		}
+		sb.WriteString("\n)")
+	}
+	if t.PartitionKey != "" {
+		sb.WriteString(" PARTITION BY " + t.PartitionKey)
+	}
+	return sb.String()
+}
+
+// dataColumns returns the columns whose values are copied; generated
+// columns are computed again when the data is loaded
+func (t snapshotTableDef) dataColumns() []string {
+	var columns []string
+	for _, col := range t.Columns {
+		if col.Generated == "" {
+			columns = append(columns, col.Ident)
+		}
+	}
+	return columns
+}
+
+// snapshotSequence is a sequence of the snapshot schema
+type snapshotSequence struct {
+	Ident       string
+	Type        string
+	Increment   int64
+	Min         int64
+	Max         int64
+	Start       int64
+	Cache       int64
+	Cycle       bool
+	LastValue   *int64 // nil if the sequence was never used
+	OwnerTable  string // Table owning the sequence, if in the schema
+	OwnerColumn string
+	Identity    bool // The sequence of an identity column, created with it
+}
+
+// renderCreateSequence renders the CREATE SEQUENCE statement of a sequence
+func renderCreateSequence(s snapshotSequence) string {
+	stmt := fmt.Sprintf("CREATE SEQUENCE %s AS %s INCREMENT BY %d MINVALUE %d MAXVALUE %d START WITH %d CACHE %d",
+		s.Ident, s.Type, s.Increment, s.Min, s.Max, s.Start, s.Cache)
+	if s.Cycle {
+		stmt += " CYCLE"
+	}
+	return stmt
+}
+
+// sequenceValue renders the statement that restores the sequence's value,
+// or "" if it was never used
+func sequenceValue(s snapshotSequence) string {
+	if s.LastValue == nil {
+		return ""
+	}
+	seq := quoteLiteral(s.Ident)
+	if s.Identity {
+		seq = fmt.Sprintf("pg_catalog.pg_get_serial_sequence(%s, %s)", quoteLiteral(s.OwnerTable), quoteLiteral(s.OwnerColumn))
+	}
+	return fmt.Sprintf("SELECT pg_catalog.setval(%s, %d, true)", seq, *s.LastValue)
+}
+
+// snapshotView is a view or materialized view of the snapshot schema
+type snapshotView struct {
+	OID          uint32
+	Ident        string
+	Materialized bool
+	Definition   string
+	DependsOn    []uint32 // Relations the view reads
+}
+
+// renderCreateView renders the CREATE VIEW statement of a view
+func renderCreateView(v snapshotView) string {
+	kind := "VIEW"
+	if v.Materialized {
+		kind = "MATERIALIZED VIEW"
+	}
+	return fmt.Sprintf("CREATE %s %s AS\n%s", kind, v.Ident, strings.TrimRight(strings.TrimSpace(v.Definition), ";"))
+}
+
+// orderViews sorts views so each comes after the views it reads. Views are
+// otherwise kept in creation order.
+func orderViews(views []snapshotView) []snapshotView {
+	byOID := make(map[uint32]snapshotView, len(views))
+	for _, v := range views {
+		byOID[v.OID] = v
+	}
+
+	ordered := make([]snapshotView, 0, len(views))
+	visited := make(map[uint32]bool, len(views))
+	var visit func(v snapshotView)
+	visit = func(v snapshotView) {
+		if visited[v.OID] {
+			return
+		}
+		visited[v.OID] = true
+		for _, dep := range v.DependsOn {
+			if d, ok := byOID[dep]; ok {
+				visit(d)
+			}
+		}
+		ordered = append(ordered, v)
+	}
+	for _, v := range views {
+		visit(v)
+	}
+	return ordered
+}
+
+// unqualifyFunctionDef removes the schema from the name in a function
+// definition, which pg_get_functiondef always qualifies
+func unqualifyFunctionDef(def, schemaIdent string) string {
+	for _, kind := range []string{"FUNCTION ", "PROCEDURE "} {
+		prefix := "CREATE OR REPLACE " + kind + schemaIdent + "."
+		if strings.HasPrefix(def, prefix) {
+			return "CREATE OR REPLACE " + kind + def[len(prefix):]
+		}
+	}
+	return def
+}
+
+// notExtensionMember is a condition excluding the objects created by
+// extensions, which are recreated by CREATE EXTENSION
+func notExtensionMember(catalog, oid string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM pg_catalog.pg_depend d
+		WHERE d.classid = '%s'::regclass AND d.objid = %s AND d.deptype = 'e')`, catalog, oid)
+}
+
+// schemaSnapshotReader reads the definitions of a schema's objects. The
+// transaction's search path is the schema followed by pg_catalog, so the
+// catalog functions render the schema's objects unqualified, and those of
+// other schemas qualified: the DDL recreates the objects in whichever
+// schema is first in the search path when it runs.
+type schemaSnapshotReader struct {
+	ctx         context.Context
+	tx          pgx.Tx
+	schema      uint32
+	schemaIdent string
+	version     int
+	snap        *snapshot.Snapshot
+	tables      []snapshotTableDef
+	sequences   []snapshotSequence
+}
+
+// readSchemaSnapshot reads the DDL of a schema in a transaction, which
+// should be REPEATABLE READ so the DDL and data are consistent. It returns
+// the snapshot and its tables.
+func readSchemaSnapshot(ctx context.Context, tx pgx.Tx, schema string) (*snapshot.Snapshot, []snapshotTableDef, error) {
+	r := &schemaSnapshotReader{ctx: ctx, tx: tx, snap: &snapshot.Snapshot{Schema: schema}}
+
+	if err := tx.QueryRow(ctx, "SELECT current_setting('server_version_num')::int").Scan(&r.version); err != nil {
+		return nil, nil, fmt.Errorf("failed to read the server version: %w", err)
+	}
+	if r.version < snapshotMinServerVersion {
+		return nil, nil, fmt.Errorf("schema snapshots require PostgreSQL 12 or later")
+	}
+	err := tx.QueryRow(ctx, "SELECT oid, quote_ident(nspname) FROM pg_catalog.pg_namespace WHERE nspname = $1", schema).
+		Scan(&r.schema, &r.schemaIdent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, fmt.Errorf("schema %q does not exist", schema)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up schema %q: %w", schema, err)
+	}
+	if _, err := tx.Exec(ctx, "SELECT pg_catalog.set_config('search_path', quote_ident($1) || ', pg_catalog', true)", schema); err != nil {
+		return nil, nil, fmt.Errorf("failed to set the search path: %w", err)
+	}
+
+	steps := []struct {
+		what string
+		read func() error
+	}{
+		{"types", r.readTypes},
+		{"sequences", r.readSequences},
+		{"functions", r.readFunctions},
+		{"tables", r.readTables},
+		{"constraints", r.readConstraints},
+		{"views", r.readViews},
+		{"indexes", r.readIndexes},
+		{"triggers", r.readTriggers},
+		{"comments", r.readComments},
+		{"unsupported objects", r.readWarnings},
+	}
+	for _, step := range steps {
+		if err := step.read(); err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", step.what, err)
+		}
+	}
+	return r.snap, r.tables, nil
+}
+
+// query runs a catalog query with the schema OID as $1, calling scan for
+// each row
+func (r *schemaSnapshotReader) query(sql string, scan func(rows pgx.Rows) error) error {
+	rows, err := r.tx.Query(r.ctx, sql, r.schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *schemaSnapshotReader) readTypes() error {
+	err := r.query(`
+		SELECT quote_ident(t.typname),
+		       array_agg(quote_literal(e.enumlabel) ORDER BY e.enumsortorder)
+		FROM pg_catalog.pg_type t
+		JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid
+		WHERE t.typnamespace = $1 AND `+notExtensionMember("pg_type", "t.oid")+`
+		GROUP BY t.oid, t.typname
+		ORDER BY t.oid`, func(rows pgx.Rows) error {
+		var ident string
+		var labels []string
+		if err := rows.Scan(&ident, &labels); err != nil {
+			return err
+		}
+		r.snap.PreData = append(r.snap.PreData, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", ident, strings.Join(labels, ", ")))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.query(`
+		SELECT quote_ident(t.typname), pg_catalog.format_type(t.typbasetype, t.typtypmod),
+		       t.typnotnull, t.typdefault,
+		       coalesce((SELECT array_agg('CONSTRAINT ' || quote_ident(c.conname) || ' ' ||
+		                                  pg_catalog.pg_get_constraintdef(c.oid, true) ORDER BY c.conname)
+		                 FROM pg_catalog.pg_constraint c
+		                 WHERE c.contypid = t.oid AND c.contype = 'c'), '{}')
+		FROM pg_catalog.pg_type t
+		WHERE t.typnamespace = $1 AND t.typtype = 'd' AND `+notExtensionMember("pg_type", "t.oid")+`
+		ORDER BY t.oid`, func(rows pgx.Rows) error {
+		var ident, base string
+		var notNull bool
+		var def *string
+		var checks []string
+		if err := rows.Scan(&ident, &base, &notNull, &def, &checks); err != nil {
+			return err
+		}
+		stmt := "CREATE DOMAIN " + ident + " AS " + base
+		if def != nil {
+			stmt += " DEFAULT " + *def
+		}
+		if notNull {
+			stmt += " NOT NULL"
+		}
+		for _, check := range checks {
+			stmt += " " + check
+		}
+		r.snap.PreData = append(r.snap.PreData, stmt)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.query(`
+		SELECT quote_ident(t.typname),
+		       array_agg(quote_ident(a.attname) || ' ' || pg_catalog.format_type(a.atttypid, a.atttypmod) ORDER BY a.attnum)
+		FROM pg_catalog.pg_type t
+		JOIN pg_catalog.pg_class c ON c.oid = t.typrelid AND c.relkind = 'c'
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		WHERE t.typnamespace = $1 AND `+notExtensionMember("pg_type", "t.oid")+`
+		GROUP BY t.oid, t.typname
+		ORDER BY t.oid`, func(rows pgx.Rows) error {
+		var ident string
+		var attributes []string
+		if err := rows.Scan(&ident, &attributes); err != nil {
+			return err
+		}
+		r.snap.PreData = append(r.snap.PreData, fmt.Sprintf("CREATE TYPE %s AS (%s)", ident, strings.Join(attributes, ", ")))
+		return nil
+	})
+}
+
+func (r *schemaSnapshotReader) readSequences() error {
+	err := r.query(`
+		SELECT quote_ident(c.relname), pg_catalog.format_type(s.seqtypid, NULL),
+		       s.seqincrement, s.seqmin, s.seqmax, s.seqstart, s.seqcache, s.seqcycle,
+		       ps.last_value, owner.tbl, owner.col, coalesce(owner.deptype = 'i', false)
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_sequence s ON s.seqrelid = c.oid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_catalog.pg_sequences ps ON ps.schemaname = n.nspname AND ps.sequencename = c.relname
+		LEFT JOIN LATERAL (
+			SELECT quote_ident(t.relname) AS tbl, a.attname::text AS col, d.deptype::text AS deptype
+			FROM pg_catalog.pg_depend d
+			JOIN pg_catalog.pg_class t ON t.oid = d.refobjid AND t.relnamespace = c.relnamespace
+			JOIN pg_catalog.pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+			WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid
+			  AND d.refclassid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')
+			LIMIT 1
+		) owner ON true
+		WHERE c.relnamespace = $1 AND c.relkind = 'S' AND `+notExtensionMember("pg_class", "c.oid")+`
+		ORDER BY c.oid`, func(rows pgx.Rows) error {
+		var s snapshotSequence
+		var ownerTable, ownerColumn *string
+		if err := rows.Scan(&s.Ident, &s.Type, &s.Increment, &s.Min, &s.Max, &s.Start, &s.Cache, &s.Cycle,
+			&s.LastValue, &ownerTable, &ownerColumn, &s.Identity); err != nil {
+			return err
+		}
+		if ownerTable != nil && ownerColumn != nil {
+			s.OwnerTable, s.OwnerColumn = *ownerTable, *ownerColumn
+		}
+		r.sequences = append(r.sequences, s)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range r.sequences {
+		if !s.Identity {
+			r.snap.PreData = append(r.snap.PreData, renderCreateSequence(s))
+		}
+	}
+	return nil
+}
+
+func (r *schemaSnapshotReader) readFunctions() error {
+	return r.query(`
+		SELECT pg_catalog.pg_get_functiondef(p.oid)
+		FROM pg_catalog.pg_proc p
+		WHERE p.pronamespace = $1 AND p.prokind IN ('f', 'p') AND `+notExtensionMember("pg_proc", "p.oid")+`
+		ORDER BY p.oid`, func(rows pgx.Rows) error {
+		var def string
+		if err := rows.Scan(&def); err != nil {
+			return err
+		}
+		def = unqualifyFunctionDef(strings.TrimSpace(def), r.schemaIdent)
+		r.snap.PreData = append(r.snap.PreData, def)
+		return nil
+	})
+}
+
+func (r *schemaSnapshotReader) readTables() error {
+	err := r.query(`
+		SELECT c.oid, quote_ident(c.relname), c.relname::text, c.relkind = 'p', c.relpersistence = 'u',
+		       CASE WHEN c.relkind = 'p' THEN pg_catalog.pg_get_partkeydef(c.oid) END,
+		       CASE WHEN c.relispartition THEN
+		           (SELECT i.inhparent::regclass::text FROM pg_catalog.pg_inherits i WHERE i.inhrelid = c.oid)
+		       END,
+		       CASE WHEN c.relispartition THEN pg_catalog.pg_get_expr(c.relpartbound, c.oid, true) END,
+		       NOT c.relispartition AND EXISTS (SELECT 1 FROM pg_catalog.pg_inherits i WHERE i.inhrelid = c.oid)
+		FROM pg_catalog.pg_class c
+		WHERE c.relnamespace = $1 AND c.relkind IN ('r', 'p') AND `+notExtensionMember("pg_class", "c.oid")+`
+		ORDER BY c.oid`, func(rows pgx.Rows) error {
+		var t snapshotTableDef
+		var partitionKey, parent, bound *string
+		var inherits bool
+		if err := rows.Scan(&t.OID, &t.Ident, &t.Name, &t.Partitioned, &t.Unlogged, &partitionKey, &parent, &bound, &inherits); err != nil {
+			return err
+		}
+		if partitionKey != nil {
+			t.PartitionKey = *partitionKey
+		}
+		if parent != nil && bound != nil {
+			t.Parent, t.PartitionBound = *parent, *bound
+		}
+		if inherits {
+			r.snap.Warnings = append(r.snap.Warnings,
+				fmt.Sprintf("table %s inherits from another table; it is recreated with all its columns but without INHERITS", t.Name))
+		}
+		r.tables = append(r.tables, t)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	index := make(map[uint32]int, len(r.tables))
+	for i, t := range r.tables {
+		index[t.OID] = i
+	}
+	err = r.query(`
+		SELECT a.attrelid, quote_ident(a.attname), a.attname::text,
+		       pg_catalog.format_type(a.atttypid, a.atttypmod),
+		       CASE WHEN a.attcollation <> 0 AND a.attcollation <> t.typcollation
+		            THEN quote_ident(cn.nspname) || '.' || quote_ident(co.collname) END,
+		       pg_catalog.pg_get_expr(d.adbin, d.adrelid, true),
+		       a.attidentity::text, a.attgenerated::text, a.attnotnull
+		FROM pg_catalog.pg_attribute a
+		JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+		JOIN pg_catalog.pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		LEFT JOIN pg_catalog.pg_collation co ON co.oid = a.attcollation
+		LEFT JOIN pg_catalog.pg_namespace cn ON cn.oid = co.collnamespace
+		WHERE c.relnamespace = $1 AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attrelid, a.attnum`, func(rows pgx.Rows) error {
+		var rel uint32
+		var col snapshotColumn
+		var collation, def *string
+		if err := rows.Scan(&rel, &col.Ident, &col.Name, &col.Type, &collation, &def, &col.Identity, &col.Generated, &col.NotNull); err != nil {
+			return err
+		}
+		if collation != nil {
+			col.Collation = *collation
+		}
+		if def != nil {
+			col.Default = *def
+		}
+		if i, ok := index[rel]; ok {
+			r.tables[i].Columns = append(r.tables[i].Columns, col)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range r.tables {
+		r.snap.PreData = append(r.snap.PreData, renderCreateTable(t))
+		r.snap.Tables = append(r.snap.Tables, snapshot.Table{Name: t.Name, Columns: t.dataColumns()})
+	}
+	for _, s := range r.sequences {
+		if stmt := sequenceValue(s); stmt != "" {
+			r.snap.SequenceValues = append(r.snap.SequenceValues, stmt)
+		}
+		if !s.Identity && s.OwnerTable != "" {
+			r.snap.PostData = append(r.snap.PostData,
+				fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s", s.Ident, s.OwnerTable, quoteIdentifier(s.OwnerColumn)))
+		}
+	}
+	return nil
+}
+
+func (r *schemaSnapshotReader) readConstraints() error {
+	// Constraints cloned to partitions are created by their parent's
+	return r.query(`
+		SELECT quote_ident(c.relname), quote_ident(con.conname), pg_catalog.pg_get_constraintdef(con.oid, true)
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+		WHERE c.relnamespace = $1 AND c.relkind IN ('r', 'p')
+		  AND con.contype IN ('p', 'u', 'x', 'c', 'f') AND con.conislocal AND con.conparentid = 0
+		  AND `+notExtensionMember("pg_class", "c.oid")+`
+		ORDER BY con.contype = 'f', c.oid, con.conname`, func(rows pgx.Rows) error {
+		var table, name, def string
+		if err := rows.Scan(&table, &name, &def); err != nil {
+			return err
+		}
+		r.snap.PostData = append(r.snap.PostData, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", table, name, def))
+		return nil
+	})
+}
+
+func (r *schemaSnapshotReader) readViews() error {
+	var views []snapshotView
+	err := r.query(`
+		SELECT c.oid, quote_ident(c.relname), c.relkind = 'm', pg_catalog.pg_get_viewdef(c.oid, true),
+		       coalesce((SELECT array_agg(DISTINCT d.refobjid)
+		                 FROM pg_catalog.pg_rewrite rw
+		                 JOIN pg_catalog.pg_depend d ON d.classid = 'pg_rewrite'::regclass AND d.objid = rw.oid
+		                 WHERE rw.ev_class = c.oid AND d.refclassid = 'pg_class'::regclass AND d.refobjid <> c.oid),
+		                '{}')
+		FROM pg_catalog.pg_class c
+		WHERE c.relnamespace = $1 AND c.relkind IN ('v', 'm') AND `+notExtensionMember("pg_class", "c.oid")+`
+		ORDER BY c.oid`, func(rows pgx.Rows) error {
+		var v snapshotView
+		if err := rows.Scan(&v.OID, &v.Ident, &v.Materialized, &v.Definition, &v.DependsOn); err != nil {
+			return err
+		}
+		views = append(views, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, v := range orderViews(views) {
+		r.snap.PostData = append(r.snap.PostData, renderCreateView(v))
+	}
+	return nil
+}
+
+func (r *schemaSnapshotReader) readIndexes() error {
+	// Indexes backing constraints are created with them, and those of
+	// partitions with the parent's index
+	return r.query(`
+		SELECT pg_catalog.pg_get_indexdef(i.indexrelid, 0, true)
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_class c ON c.oid = i.indrelid
+		WHERE c.relnamespace = $1 AND c.relkind IN ('r', 'p', 'm')
+		  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint con
+		                  WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x'))
+		  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_inherits inh WHERE inh.inhrelid = i.indexrelid)
+		  AND `+notExtensionMember("pg_class", "c.oid")+`
+		ORDER BY i.indexrelid`, func(rows pgx.Rows) error {
+		var def string
+		if err := rows.Scan(&def); err != nil {
+			return err
+		}
+		r.snap.PostData = append(r.snap.PostData, def)
+		return nil
+	})
+}
+
+func (r *schemaSnapshotReader) readTriggers() error {
+	// Triggers cloned to partitions are created by their parent's
+	cloned := ""
+	if r.version >= 130000 {
+		cloned = "AND t.tgparentid = 0"
+	}
+	return r.query(`
+		SELECT pg_catalog.pg_get_triggerdef(t.oid, true)
+		FROM pg_catalog.pg_trigger t
+		JOIN pg_catalog.pg_class c ON c.oid = t.tgrelid
+		WHERE c.relnamespace = $1 AND NOT t.tgisinternal `+cloned+`
+		  AND `+notExtensionMember("pg_class", "c.oid")+`
+		ORDER BY t.oid`, func(rows pgx.Rows) error {
+		var def string
+		if err := rows.Scan(&def); err != nil {
+			return err
+		}
+		r.snap.PostData = append(r.snap.PostData, def)
+		return nil
+	})
+}
+
+func (r *schemaSnapshotReader) readComments() error {
+	return r.query(`
+		SELECT CASE c.relkind WHEN 'v' THEN 'VIEW' WHEN 'm' THEN 'MATERIALIZED VIEW' ELSE 'TABLE' END,
+		       quote_ident(c.relname), quote_ident(a.attname), d.description
+		FROM pg_catalog.pg_description d
+		JOIN pg_catalog.pg_class c ON c.oid = d.objoid AND d.classoid = 'pg_class'::regclass
+		LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum = d.objsubid AND d.objsubid > 0
+		WHERE c.relnamespace = $1 AND c.relkind IN ('r', 'p', 'v', 'm')
+		  AND (d.objsubid = 0 OR a.attname IS NOT NULL)
+		  AND `+notExtensionMember("pg_class", "c.oid")+`
+		ORDER BY c.oid, d.objsubid`, func(rows pgx.Rows) error {
+		var kind, relation, description string
+		var column *string
+		if err := rows.Scan(&kind, &relation, &column, &description); err != nil {
+			return err
+		}
+		if column != nil {
+			r.snap.PostData = append(r.snap.PostData,
+				fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s", relation, *column, quoteLiteral(description)))
+		} else {
+			r.snap.PostData = append(r.snap.PostData,
+				fmt.Sprintf("COMMENT ON %s %s IS %s", kind, relation, quoteLiteral(description)))
+		}
+		return nil
+	})
+}
+
+// readWarnings reports the objects of the schema that are not captured
+func (r *schemaSnapshotReader) readWarnings() error {
+	return r.query(`
+		SELECT kind, count FROM (
+			SELECT 'foreign tables' AS kind, count(*) AS count FROM pg_catalog.pg_class c
+			WHERE c.relnamespace = $1 AND c.relkind = 'f' AND `+notExtensionMember("pg_class", "c.oid")+`
+			UNION ALL
+			SELECT 'aggregates and window functions', count(*) FROM pg_catalog.pg_proc p
+			WHERE p.pronamespace = $1 AND p.prokind IN ('a', 'w') AND `+notExtensionMember("pg_proc", "p.oid")+`
+			UNION ALL
+			SELECT 'row level security policies', count(*) FROM pg_catalog.pg_policy pol
+			JOIN pg_catalog.pg_class c ON c.oid = pol.polrelid WHERE c.relnamespace = $1
+			UNION ALL
+			SELECT 'objects of extensions', count(*) FROM pg_catalog.pg_depend d
+			JOIN pg_catalog.pg_class c ON d.classid = 'pg_class'::regclass AND c.oid = d.objid
+			WHERE c.relnamespace = $1 AND d.deptype = 'e'
+		) unsupported
+		WHERE count > 0
+		ORDER BY kind`, func(rows pgx.Rows) error {
+		var kind string
+		var count int64
+		if err := rows.Scan(&kind, &count); err != nil {
+			return err
+		}
+		r.snap.Warnings = append(r.snap.Warnings, fmt.Sprintf("%d %s not captured", count, kind))
+		return nil
+	})
+}
+
+// formatSnapshotDDL renders a snapshot's DDL as a script, with a comment
+// where the data is loaded
+func formatSnapshotDDL(snap *snapshot.Snapshot) string {
+	var sb strings.Builder
+	for _, stmt := range snap.PreData {
+		sb.WriteString(stmt + ";\n\n")
+	}
+	if snap.HasData() {
+		sb.WriteString(fmt.Sprintf("-- Data: %d rows in %d tables\n\n", snap.Rows(), countDataTables(snap)))
+		for _, stmt := range snap.SequenceValues {
+			sb.WriteString(stmt + ";\n")
+		}
+		if len(snap.SequenceValues) > 0 {
+			sb.WriteString("\n")
+		}
+	}
+	for _, stmt := range snap.PostData {
+		sb.WriteString(stmt + ";\n\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// countDataTables returns the number of tables with data in a snapshot
+func countDataTables(snap *snapshot.Snapshot) int {
+	n := 0
+	for _, t := range snap.Tables {
+		if t.DataFile != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,194 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/snapshot"
+)
+
+func TestRenderCreateTable(t *testing.T) {
+	table := snapshotTableDef{
+		Ident:        "orders",
+		PartitionKey: "RANGE (created_at)",
+		Columns: []snapshotColumn{
+			{Ident: "id", Type: "bigint", Identity: "a", NotNull: true},
+			{Ident: `"Customer"`, Type: "text", Collation: `pg_catalog."C"`},
+			{Ident: "status", Type: "order_status", Default: "'new'::order_status", NotNull: true},
+			{Ident: "total", Type: "numeric(10,2)", Default: "quantity * price", Generated: "s"},
+			{Ident: "created_at", Type: "timestamp with time zone", Default: "now()"},
+		},
+	}
+	want := `CREATE TABLE orders (
+    id bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+    "Customer" text COLLATE pg_catalog."C",
+    status order_status DEFAULT 'new'::order_status NOT NULL,
+    total numeric(10,2) GENERATED ALWAYS AS (quantity * price) STORED,
+    created_at timestamp with time zone DEFAULT now()
+) PARTITION BY RANGE (created_at)`
+	if got := renderCreateTable(table); got != want {
+		t.Errorf("renderCreateTable() =\n%s\nwant\n%s", got, want)
+	}
+	if got := table.dataColumns(); !reflect.DeepEqual(got, []string{"id", `"Customer"`, "status", "created_at"}) {
+		t.Errorf("dataColumns() = %v", got)
+	}
+
+	partition := snapshotTableDef{
+		Ident:          "orders_2025",
+		Unlogged:       true,
+		Parent:         "orders",
+		PartitionBound: "FOR VALUES FROM ('2025-01-01') TO ('2026-01-01')",
+		Columns:        table.Columns,
+	}
+	want = "CREATE UNLOGGED TABLE orders_2025 PARTITION OF orders FOR VALUES FROM ('2025-01-01') TO ('2026-01-01')"
+	if got := renderCreateTable(partition); got != want {
+		t.Errorf("renderCreateTable() = %s, want %s", got, want)
+	}
+}
+
+func TestSnapshotSequences(t *testing.T) {
+	last := int64(42)
+	seq := snapshotSequence{Ident: "invoice_no", Type: "integer", Increment: 1, Min: 1, Max: 2147483647, Start: 1000, Cache: 1, Cycle: true, LastValue: &last}
+	want := "CREATE SEQUENCE invoice_no AS integer INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 START WITH 1000 CACHE 1 CYCLE"
+	if got := renderCreateSequence(seq); got != want {
+		t.Errorf("renderCreateSequence() = %s, want %s", got, want)
+	}
+	if got := sequenceValue(seq); got != "SELECT pg_catalog.setval('invoice_no', 42, true)" {
+		t.Errorf("sequenceValue() = %s", got)
+	}
+
+	identity := snapshotSequence{Ident: "orders_id_seq", LastValue: &last, Identity: true, OwnerTable: `"Orders"`, OwnerColumn: "id"}
+	want = `SELECT pg_catalog.setval(pg_catalog.pg_get_serial_sequence('"Orders"', 'id'), 42, true)`
+	if got := sequenceValue(identity); got != want {
+		t.Errorf("sequenceValue() = %s, want %s", got, want)
+	}
+
+	unused := snapshotSequence{Ident: "unused"}
+	if got := sequenceValue(unused); got != "" {
+		t.Errorf("expected no value for an unused sequence, got %s", got)
+	}
+}
+
+func TestOrderViews(t *testing.T) {
+	views := []snapshotView{
+		{OID: 1, Ident: "summary", DependsOn: []uint32{3, 100}},
+		{OID: 2, Ident: "standalone"},
+		{OID: 3, Ident: "base", DependsOn: []uint32{100}},
+	}
+	var names []string
+	for _, v := range orderViews(views) {
+		names = append(names, v.Ident)
+	}
+	if want := []string{"base", "summary", "standalone"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("orderViews() = %v, want %v", names, want)
+	}
+
+	mv := snapshotView{Ident: "totals", Materialized: true, Definition: " SELECT 1;"}
+	if got := renderCreateView(mv); got != "CREATE MATERIALIZED VIEW totals AS\nSELECT 1" {
+		t.Errorf("renderCreateView() = %q", got)
+	}
+}
+
+func TestUnqualifyFunctionDef(t *testing.T) {
+	def := "CREATE OR REPLACE FUNCTION public.add(a integer, b integer)\n RETURNS integer\n"
+	if got := unqualifyFunctionDef(def, "public"); !strings.HasPrefix(got, "CREATE OR REPLACE FUNCTION add(a integer") {
+		t.Errorf("unqualifyFunctionDef() = %q", got)
+	}
+	def = `CREATE OR REPLACE PROCEDURE "My Schema".archive()`
+	if got := unqualifyFunctionDef(def, `"My Schema"`); got != "CREATE OR REPLACE PROCEDURE archive()" {
+		t.Errorf("unqualifyFunctionDef() = %q", got)
+	}
+}
+
+func TestFormatSnapshotDDL(t *testing.T) {
+	snap := &snapshot.Snapshot{
+		PreData:        []string{"CREATE TABLE t (id integer)"},
+		PostData:       []string{"ALTER TABLE t ADD CONSTRAINT t_pkey PRIMARY KEY (id)"},
+		SequenceValues: []string{"SELECT pg_catalog.setval('s', 5, true)"},
+		Tables:         []snapshot.Table{{Name: "t", Rows: 3, DataFile: "0000.copy"}},
+	}
+	want := "CREATE TABLE t (id integer);\n\n" +
+		"-- Data: 3 rows in 1 tables\n\n" +
+		"SELECT pg_catalog.setval('s', 5, true);\n\n" +
+		"ALTER TABLE t ADD CONSTRAINT t_pkey PRIMARY KEY (id);"
+	if got := formatSnapshotDDL(snap); got != want {
+		t.Errorf("formatSnapshotDDL() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Sequence values only apply with the data
+	snap.Tables[0].DataFile = ""
+	if got := formatSnapshotDDL(snap); strings.Contains(got, "setval") {
+		t.Errorf("expected no sequence values without data, got\n%s", got)
+	}
+}
+
+func TestCheckRestoreTarget(t *testing.T) {
+	for _, schema := range []string{"pg_catalog", "PG_TOAST", "information_schema"} {
+		if err := checkRestoreTarget(schema); err == nil {
+			t.Errorf("expected restoring into %s to be rejected", schema)
+		}
+	}
+	if err := checkRestoreTarget("public_before"); err != nil {
+		t.Errorf("checkRestoreTarget() error = %v", err)
+	}
+}
+
+func TestListSchemaSnapshotsTool(t *testing.T) {
+	cfg := &config.Config{DataDir: t.TempDir()}
+	tool := ListSchemaSnapshotsTool(cfg)
+
+	response, err := tool.Handler(map[string]interface{}{})
+	if err != nil || response.IsError || !strings.Contains(response.Content[0].Text, "No schema snapshots") {
+		t.Fatalf("unexpected response %+v, %v", response, err)
+	}
+
+	store := snapshot.NewStoreFromConfig(cfg)
+	w, err := store.Create("before", false)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	err = w.Commit(&snapshot.Snapshot{
+		Database:  "postgres:///app",
+		Schema:    "public",
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		PreData:   []string{"CREATE TABLE t (id integer)"},
+		Tables:    []snapshot.Table{{Name: "t"}},
+		Warnings:  []string{"1 foreign tables not captured"},
+	})
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	response, _ = tool.Handler(map[string]interface{}{})
+	want := "name\tschema\tdatabase\tcreated_at\ttables\trows\tdata_bytes\n" +
+		"before\tpublic\tpostgres:///app\t2025-01-02T03:04:05Z\t1\t\t"
+	if response.Content[0].Text != want {
+		t.Errorf("list =\n%q\nwant\n%q", response.Content[0].Text, want)
+	}
+
+	response, _ = tool.Handler(map[string]interface{}{"name": "before"})
+	text := response.Content[0].Text
+	for _, s := range []string{"Snapshot: before (schema public", "Data: not included", "- 1 foreign tables not captured", "CREATE TABLE t (id integer);"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("expected %q in:\n%s", s, text)
+		}
+	}
+
+	response, _ = tool.Handler(map[string]interface{}{"name": "missing"})
+	if !response.IsError {
+		t.Error("expected an error for a missing snapshot")
+	}
+}
@@ -25,8 +25,11 @@ import (
 	"pgedge-postgres-mcp/internal/mcp"
 )
 
-// SearchKnowledgebaseTool creates the search_knowledgebase tool for searching documentation
-func SearchKnowledgebaseTool(kbPath string, cfg *config.Config) Tool {
+// SearchKnowledgebaseTool creates the search_knowledgebase tool for searching
+// documentation in the configured SQLite or PostgreSQL knowledgebase
+// kbCfg's embedding provider and model must be resolved (see
+// checkKnowledgebase)
+func SearchKnowledgebaseTool(kbCfg config.KnowledgebaseConfig) Tool {
 	return Tool{
 		Definition: mcp.Tool{
 			Name: "search_knowledgebase",
@@ -37,8 +40,8 @@ IMPORTANT: Product names require EXACT matches. "pgEdge" will NOT match
 "pgEdge RAG Server", "pgEdge Cloud", or "pgEdge Platform" - these are
 separate products.
 
-ALWAYS call with list_products=true FIRST to discover exact product names
-before filtering by project_names.
+ALWAYS call list_kb_projects (or this tool with list_products=true) FIRST to
+discover exact product names and versions before filtering.
 </critical>
 
 Use this tool when you need information about:
@@ -53,24 +56,27 @@ Note: In this tool, "project" and "product" are used interchangeably - they
 both refer to the software product/project being documented.
 
 <workflow>
-1. First call: {"list_products": true} to see available products
-2. Note the EXACT product names from the output
-3. Search with exact names: {"query": "...", "project_names": ["Exact Name"]}
+1. First call: list_kb_projects to see available products and versions
+2. Note the EXACT product names and versions from the output
+3. Search with exact names: {"query": "...", "project": "Exact Name"}
+4. When the user asks about a specific version, ALWAYS filter by it, so
+   documentation for other versions isn't mixed in
 </workflow>
 
 <troubleshooting>
 If you get zero results:
-- You likely have the wrong product name - call list_products=true
-- Try searching without project_names filter to see what's available
+- You likely have the wrong product name or version - the response lists
+  what is available
+- Try searching without filters to see what's available
 - Check for typos or partial names (e.g., "pgEdge" vs "pgEdge RAG Server")
 </troubleshooting>
 
 <examples>
-✓ {"list_products": true} - ALWAYS do this first!
 ✓ {"query": "PostgreSQL window functions"}
-✓ {"query": "RAG overview", "project_names": ["pgEdge RAG Server"]}
-✓ {"query": "replication", "project_names": ["pgEdge Platform"]}
-✓ {"query": "JSON functions", "project_names": ["PostgreSQL"], "project_versions": ["17"]}
+✓ {"query": "RAG overview", "project": "pgEdge RAG Server"}
+✓ {"query": "JSON functions", "project": "PostgreSQL", "version": "16"}
+✓ {"query": "replication", "project_names": ["pgEdge Platform", "Spock"]}
+✓ {"query": "vacuum", "project_names": ["PostgreSQL"], "project_versions": ["16", "17"]}
 </examples>`,
 			InputSchema: mcp.InputSchema{
 				Type: "object",
@@ -79,6 +85,14 @@ If you get zero results:
 						"type":        "string",
 						"description": "Natural language search query (required unless list_products is true)",
 					},
+					"project": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter by a single project/product name (e.g., 'PostgreSQL'); combined with project_names",
+					},
+					"version": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter by a single project/product version (e.g., '16'); combined with project_versions",
+					},
 					"project_names": map[string]interface{}{
 						"type":        "array",
 						"items":       map[string]interface{}{"type": "string"},
@@ -103,13 +117,22 @@ If you get zero results:
 			},
 		},
 		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			ctx, ok := args["__context"].(context.Context)
+			if !ok || ctx == nil {
+				ctx = context.Background()
+			}
+			kb, err := newKBBackend(ctx, kbCfg)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to open knowledgebase: %v", err))
+			}
+
 			// Check for list_products mode first
 			if listProducts, ok := args["list_products"].(bool); ok && listProducts {
-				products, err := listKBProducts(kbPath)
+				products, err := kb.products(ctx)
 				if err != nil {
 					return mcp.NewToolError(fmt.Sprintf("Failed to list products: %v", err))
 				}
-				return mcp.NewToolSuccess(products)
+				return mcp.NewToolSuccess(formatKBProducts(products))
 			}
 
 			// Validate query
@@ -124,25 +147,10 @@ If you get zero results:
 			}
 
 			// Get optional parameters
-			var projectNames, projectVersions []string
+			projectNames := kbFilterValues(args, "project", "project_names")
+			projectVersions := kbFilterValues(args, "version", "project_versions")
 			topN := 5
 
-			// Extract project_names array
-			if pn, ok := args["project_names"].([]interface{}); ok {
-				for _, v := range pn {
-					if s, ok := v.(string); ok && s != "" {
-						projectNames = append(projectNames, s)
-					}
-				}
-			}
-			// Extract project_versions array
-			if pv, ok := args["project_versions"].([]interface{}); ok {
-				for _, v := range pv {
-					if s, ok := v.(string); ok && s != "" {
-						projectVersions = append(projectVersions, s)
-					}
-				}
-			}
 			if tn, ok := args["top_n"].(float64); ok {
 				topN = int(tn)
 				if topN < 1 {
@@ -153,14 +161,23 @@ If you get zero results:
 				}
 			}
 
+			progress := progressFromArgs(args)
+			steps := 3.0
+			if kbCfg.Rerank.Enabled {
+				steps = 4.0
+			}
+
 			// Generate query embedding
-			queryEmbedding, provider, err := generateKBQueryEmbedding(cfg, query)
+			progress.Report(1, steps, "Generating query embedding")
+			queryEmbedding, provider, err := generateKBQueryEmbedding(kbCfg, query)
 			if err != nil {
 				return mcp.NewToolError(fmt.Sprintf("Failed to generate query embedding: %v", err))
 			}
 
 			// Search knowledgebase
-			results, err := searchKB(kbPath, queryEmbedding, projectNames, projectVersions, topN, provider)
+			progress.Report(2, steps, "Searching knowledgebase")
+			candidates := kbRerankCandidates(kbCfg.Rerank, topN)
+			results, err := kb.search(ctx, queryEmbedding, provider, projectNames, projectVersions, candidates)
 			if err != nil {
 				return mcp.NewToolError(fmt.Sprintf("Knowledgebase search failed: %v", err))
 			}
@@ -173,17 +190,131 @@ If you get zero results:
 						msg += fmt.Sprintf("; versions: %s", strings.Join(projectVersions, ", "))
 					}
 					msg += ")"
+				} else if len(projectVersions) > 0 {
+					msg += fmt.Sprintf(" (versions: %s)", strings.Join(projectVersions, ", "))
+				}
+				if len(projectNames) > 0 || len(projectVersions) > 0 {
+					if products, err := kb.products(ctx); err == nil {
+						if hint := kbFilterHint(products, projectNames, projectVersions); hint != "" {
+							msg += "\n\n" + hint
+						}
+					}
 				}
 				return mcp.NewToolSuccess(msg)
 			}
 
+			// Rerank the candidates, keeping the most relevant
+			if kbCfg.Rerank.Enabled {
+				progress.Report(3, steps, fmt.Sprintf("Reranking %d results", len(results)))
+			}
+			results, ordering := rerankKBResults(ctx, kbCfg.Rerank, query, results, topN)
+
 			// Format results
-			output := formatKBResults(results, query, projectNames, projectVersions)
+			progress.Report(steps, steps, fmt.Sprintf("Found %d results", len(results)))
+			output := formatKBResults(results, query, projectNames, projectVersions, ordering)
 			return mcp.NewToolSuccess(output)
 		},
 	}
 }
 
+// kbFilterValues returns the non-empty values of a single-value filter
+// argument and its array counterpart, without duplicates
+func kbFilterValues(args map[string]interface{}, single, multiple string) []string {
+	var values []string
+	add := func(v interface{}) {
+		s, ok := v.(string)
+		s = strings.TrimSpace(s)
+		if !ok || s == "" {
+			return
+		}
+		for _, existing := range values {
+			if existing == s {
+				return
+			}
+		}
+		values = append(values, s)
+	}
+
+	add(args[single])
+	if list, ok := args[multiple].([]interface{}); ok {
+		for _, v := range list {
+			add(v)
+		}
+	}
+	return values
+}
+
+// kbFilterHint explains why a filtered search may have found nothing: a
+// project that isn't in the knowledgebase (suggesting similarly named ones),
+// or versions that the filtered projects don't have (listing theirs)
+func kbFilterHint(products []kbProduct, projectNames, projectVersions []string) string {
+	versions := make(map[string][]string)
+	var names []string
+	for _, p := range products {
+		if _, ok := versions[p.Name]; !ok {
+			names = append(names, p.Name)
+			versions[p.Name] = nil
+		}
+		if p.Version != "" {
+			versions[p.Name] = append(versions[p.Name], p.Version)
+		}
+	}
+	hasVersion := func(available []string) bool {
+		for _, v := range projectVersions {
+			for _, a := range available {
+				if v == a {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var hints []string
+	for _, name := range projectNames {
+		if _, ok := versions[name]; ok {
+			continue
+		}
+		var similar []string
+		for _, candidate := range names {
+			a, b := strings.ToLower(candidate), strings.ToLower(name)
+			if strings.Contains(a, b) || strings.Contains(b, a) {
+				similar = append(similar, candidate)
+			}
+		}
+		if len(similar) > 0 {
+			hints = append(hints, fmt.Sprintf("Project %q is not in the knowledgebase. Did you mean: %s?",
+				name, strings.Join(similar, ", ")))
+		} else {
+			hints = append(hints, fmt.Sprintf("Project %q is not in the knowledgebase. Call list_kb_projects for the available projects.", name))
+		}
+	}
+	if len(hints) > 0 || len(projectVersions) == 0 {
+		return strings.Join(hints, "\n")
+	}
+
+	if len(projectNames) == 0 {
+		for _, name := range names {
+			if hasVersion(versions[name]) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("No project has version %s. Call list_kb_projects for the available versions.",
+			strings.Join(projectVersions, " or "))
+	}
+	for _, name := range projectNames {
+		available := versions[name]
+		switch {
+		case hasVersion(available):
+		case len(available) == 0:
+			hints = append(hints, fmt.Sprintf("Project %q has no versioned documentation; search without a version.", name))
+		default:
+			hints = append(hints, fmt.Sprintf("Available versions of %s: %s", name, strings.Join(available, ", ")))
+		}
+	}
+	return strings.Join(hints, "\n")
+}
+
 // KBSearchResult represents a search result from the knowledgebase
 type KBSearchResult struct {
 	Text           string
@@ -193,13 +324,14 @@ type KBSearchResult struct {
 	ProjectVersion string
 	FilePath       string
 	Similarity     float64
+	RerankScore    *float64 // Reranker relevance, when reranked
 }
 
-// listKBProducts returns a formatted list of all products and versions in the knowledgebase
-func listKBProducts(kbPath string) (string, error) {
+// listKBProducts returns all products and versions in a SQLite knowledgebase
+func listKBProducts(kbPath string) ([]kbProduct, error) {
 	db, err := sql.Open("sqlite3", kbPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open knowledgebase: %w", err)
+		return nil, fmt.Errorf("failed to open knowledgebase: %w", err)
 	}
 	defer db.Close()
 
@@ -211,10 +343,24 @@ func listKBProducts(kbPath string) (string, error) {
         ORDER BY project_name, project_version
     `)
 	if err != nil {
-		return "", fmt.Errorf("query failed: %w", err)
+		return nil, fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
+	var products []kbProduct
+	for rows.Next() {
+		var p kbProduct
+		if err := rows.Scan(&p.Name, &p.Version, &p.Chunks); err != nil {
+			continue
+		}
+		products = append(products, p)
+	}
+
+	return products, nil
+}
+
+// formatKBProducts returns a formatted list of the products and versions
+func formatKBProducts(products []kbProduct) string {
 	var sb strings.Builder
 	sb.WriteString("Available Products in Knowledgebase\n")
 	sb.WriteString(strings.Repeat("=", 50))
@@ -223,12 +369,8 @@ func listKBProducts(kbPath string) (string, error) {
 	currentProduct := ""
 	totalChunks := 0
 
-	for rows.Next() {
-		var name, version string
-		var count int
-		if err := rows.Scan(&name, &version, &count); err != nil {
-			continue
-		}
+	for _, p := range products {
+		name, version, count := p.Name, p.Version, p.Chunks
 
 		if name != currentProduct {
 			if currentProduct != "" {
@@ -250,13 +392,12 @@ func listKBProducts(kbPath string) (string, error) {
 	sb.WriteString(strings.Repeat("=", 50))
 	sb.WriteString(fmt.Sprintf("\nTotal: %d chunks across all products\n", totalChunks))
 
-	return sb.String(), nil
+	return sb.String()
 }
 
-func generateKBQueryEmbedding(serverCfg *config.Config, queryText string) ([]float32, string, error) {
+func generateKBQueryEmbedding(kbCfg config.KnowledgebaseConfig, queryText string) ([]float32, string, error) {
 	// Use KB-specific embedding configuration (independent of generate_embeddings tool)
-	kbCfg := serverCfg.Knowledgebase
-	if kbCfg.EmbeddingProvider == "" {
+	if kbCfg.EmbeddingProvider == "" || kbCfg.EmbeddingProvider == config.KnowledgebaseProviderAuto {
 		return nil, "", fmt.Errorf("knowledgebase embedding provider not configured")
 	}
 
@@ -436,7 +577,7 @@ func cosineSimilarity(a, b []float32) float64 {
 	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
 }
 
-func formatKBResults(results []KBSearchResult, query string, projectNames, projectVersions []string) string {
+func formatKBResults(results []KBSearchResult, query string, projectNames, projectVersions []string, ordering string) string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("Knowledgebase Search Results: %q\n", query))
@@ -449,6 +590,9 @@ func formatKBResults(results []KBSearchResult, query string, projectNames, proje
 	} else if len(projectVersions) > 0 {
 		sb.WriteString(fmt.Sprintf("Filter - Versions: %s\n", strings.Join(projectVersions, ", ")))
 	}
+	if ordering != "" {
+		sb.WriteString(ordering + "\n")
+	}
 	sb.WriteString(strings.Repeat("=", 80))
 	sb.WriteString("\n\n")
 
@@ -467,6 +611,9 @@ func formatKBResults(results []KBSearchResult, query string, projectNames, proje
 		if result.Section != "" {
 			sb.WriteString(fmt.Sprintf("Section: %s\n", result.Section))
 		}
+		if result.RerankScore != nil {
+			sb.WriteString(fmt.Sprintf("Relevance: %.3f\n", *result.RerankScore))
+		}
 		sb.WriteString(fmt.Sprintf("Similarity: %.3f\n\n", result.Similarity))
 		sb.WriteString(result.Text)
 		sb.WriteString("\n\n")
@@ -11,9 +11,17 @@
 package tools
 
 import (
+	"context"
+	"database/sql"
 	"encoding/binary"
 	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/kbtypes"
 )
 
 func TestDeserializeEmbedding(t *testing.T) {
@@ -222,7 +230,7 @@ func TestFormatKBResults(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := formatKBResults(tt.results, tt.query, tt.projectNames, tt.projectVersions)
+			got := formatKBResults(tt.results, tt.query, tt.projectNames, tt.projectVersions, "")
 			for _, want := range tt.wantContains {
 				if !containsString(got, want) {
 					t.Errorf("formatKBResults() missing %q in output:\n%s", want, got)
@@ -279,3 +287,252 @@ func findInString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestFormatKBProducts(t *testing.T) {
+	output := formatKBProducts([]kbProduct{
+		{Name: "PostgreSQL", Version: "16", Chunks: 10},
+		{Name: "PostgreSQL", Version: "17", Chunks: 12},
+		{Name: "pgEdge RAG Server", Chunks: 3},
+	})
+
+	for _, want := range []string{
+		"Product: PostgreSQL\n  - Version 16 (10 chunks)\n  - Version 17 (12 chunks)\n",
+		"Product: pgEdge RAG Server\n  - (no version) (3 chunks)\n",
+		"Total: 25 chunks across all products",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestNewKBBackend(t *testing.T) {
+	kb, err := newKBBackend(context.Background(), config.KnowledgebaseConfig{DatabasePath: "/tmp/kb.db"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sqlite, ok := kb.(sqliteKB); !ok || sqlite.path != "/tmp/kb.db" {
+		t.Errorf("expected the SQLite backend, got %#v", kb)
+	}
+
+	_, err = newKBBackend(context.Background(), config.KnowledgebaseConfig{
+		Backend:          config.KnowledgebaseBackendPostgres,
+		ConnectionString: "postgres://localhost:notaport/kb",
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid knowledgebase connection string") {
+		t.Errorf("expected a connection string error, got %v", err)
+	}
+
+	for provider, want := range map[string]string{
+		"openai": "openai_embedding",
+		"Voyage": "voyage_embedding",
+		"ollama": "ollama_embedding",
+		"":       "openai_embedding",
+	} {
+		if got := kbEmbeddingColumn(provider); got != want {
+			t.Errorf("kbEmbeddingColumn(%q) = %q, want %q", provider, got, want)
+		}
+	}
+}
+
+func TestCheckKnowledgebase(t *testing.T) {
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte(strings.Repeat("not a database ", 100)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	otherDB := filepath.Join(dir, "other.db")
+	db, err := sql.Open("sqlite3", otherDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE notes (id integer)"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"missing", filepath.Join(dir, "missing.db"), "does not exist"},
+		{"directory", dir, "is a directory"},
+		{"corrupt", garbage, "not a database"},
+		{"not a knowledgebase", otherDB, "no chunks table"},
+		{"valid", createTestKnowledgebase(t), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Knowledgebase.Enabled = true
+			cfg.Knowledgebase.DatabasePath = tt.path
+
+			_, err := checkKnowledgebase(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Nothing is checked when the tool isn't in use
+	disabled := false
+	cfg := &config.Config{}
+	cfg.Knowledgebase.Enabled = true
+	cfg.Knowledgebase.DatabasePath = filepath.Join(dir, "missing.db")
+	cfg.Builtins.Tools.SearchKnowledgebase = &disabled
+	if _, err := checkKnowledgebase(cfg); err != nil {
+		t.Errorf("expected no check with the tool disabled, got %v", err)
+	}
+}
+
+func TestResolveKBEmbedding(t *testing.T) {
+	models := []kbtypes.EmbeddingModel{
+		{Provider: "ollama", Model: "nomic-embed-text", Dimensions: 768},
+		{Provider: "openai", Model: "text-embedding-3-small", Dimensions: 1536},
+	}
+
+	tests := []struct {
+		name         string
+		provider     string
+		model        string
+		openAIKey    string
+		offline      bool
+		models       []kbtypes.EmbeddingModel
+		wantProvider string
+		wantModel    string
+		wantErr      string
+	}{
+		{name: "auto prefers a cloud provider with a key", provider: "auto", openAIKey: "key", models: models,
+			wantProvider: "openai", wantModel: "text-embedding-3-small"},
+		{name: "auto skips providers without a key", provider: "auto", models: models,
+			wantProvider: "ollama", wantModel: "nomic-embed-text"},
+		{name: "auto skips cloud providers offline", provider: "", openAIKey: "key", offline: true, models: models,
+			wantProvider: "ollama", wantModel: "nomic-embed-text"},
+		{name: "auto restricted by model", provider: "auto", model: "nomic-embed-text", openAIKey: "key", models: models,
+			wantProvider: "ollama", wantModel: "nomic-embed-text"},
+		{name: "auto with no usable provider", provider: "auto", models: models[1:],
+			wantErr: "openai (no OpenAI API key)"},
+		{name: "auto with no embeddings", provider: "auto",
+			wantErr: "contains no embeddings"},
+		{name: "explicit provider fills in the model", provider: "ollama", models: models,
+			wantProvider: "ollama", wantModel: "nomic-embed-text"},
+		{name: "explicit provider not in the knowledgebase", provider: "voyage", models: models,
+			wantErr: "only contains embeddings from ollama (nomic-embed-text), openai (text-embedding-3-small)"},
+		{name: "explicit model mismatch", provider: "ollama", model: "mxbai-embed-large", models: models,
+			wantErr: "made with nomic-embed-text"},
+		{name: "unrecorded model accepts the configured one", provider: "openai", model: "text-embedding-3-large",
+			models: []kbtypes.EmbeddingModel{{Provider: "openai"}}, wantProvider: "openai", wantModel: "text-embedding-3-large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Offline: tt.offline}
+			cfg.Knowledgebase.EmbeddingProvider = tt.provider
+			cfg.Knowledgebase.EmbeddingModel = tt.model
+			cfg.Knowledgebase.EmbeddingOpenAIAPIKey = tt.openAIKey
+
+			kbCfg, err := resolveKBEmbedding(cfg, tt.models)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if kbCfg.EmbeddingProvider != tt.wantProvider || kbCfg.EmbeddingModel != tt.wantModel {
+				t.Errorf("resolved %s/%s, want %s/%s", kbCfg.EmbeddingProvider, kbCfg.EmbeddingModel, tt.wantProvider, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestSQLiteKBEmbeddingModels(t *testing.T) {
+	// A knowledgebase built before models were recorded has no
+	// embedding_models table; its providers are found from the chunks
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE chunks (text TEXT, openai_embedding BLOB, voyage_embedding BLOB, ollama_embedding BLOB)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO chunks VALUES ('a', NULL, x'00', NULL), ('b', NULL, NULL, NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	models, err := sqliteKB{path: path}.embeddingModels(context.Background())
+	if err != nil {
+		t.Fatalf("embeddingModels failed: %v", err)
+	}
+	if len(models) != 1 || models[0] != (kbtypes.EmbeddingModel{Provider: "voyage"}) {
+		t.Errorf("expected only voyage with an unknown model, got %+v", models)
+	}
+
+	// Recorded models are returned for the providers with embeddings
+	models, err = sqliteKB{path: createTestKnowledgebase(t)}.embeddingModels(context.Background())
+	if err != nil {
+		t.Fatalf("embeddingModels failed: %v", err)
+	}
+	want := kbtypes.EmbeddingModel{Provider: "ollama", Model: "nomic-embed-text", Dimensions: 3}
+	if len(models) != 1 || models[0] != want {
+		t.Errorf("expected %+v, got %+v", want, models)
+	}
+}
+
+func TestKBFilterValues(t *testing.T) {
+	args := map[string]interface{}{
+		"project":       " PostgreSQL ",
+		"project_names": []interface{}{"PostgreSQL", "pgEdge", "", 5},
+	}
+	got := kbFilterValues(args, "project", "project_names")
+	if strings.Join(got, "|") != "PostgreSQL|pgEdge" {
+		t.Errorf("kbFilterValues() = %q", got)
+	}
+	if got := kbFilterValues(map[string]interface{}{}, "version", "project_versions"); got != nil {
+		t.Errorf("expected no values, got %q", got)
+	}
+}
+
+func TestKBFilterHint(t *testing.T) {
+	products := []kbProduct{
+		{Name: "PostgreSQL", Version: "17"},
+		{Name: "PostgreSQL", Version: "18"},
+		{Name: "pgEdge RAG Server"},
+	}
+
+	tests := []struct {
+		name     string
+		projects []string
+		versions []string
+		want     string
+	}{
+		{"similar project", []string{"pgEdge"}, nil,
+			`Project "pgEdge" is not in the knowledgebase. Did you mean: pgEdge RAG Server?`},
+		{"unknown project", []string{"MySQL"}, []string{"8"},
+			`Project "MySQL" is not in the knowledgebase. Call list_kb_projects for the available projects.`},
+		{"missing version", []string{"PostgreSQL"}, []string{"16"},
+			"Available versions of PostgreSQL: 17, 18"},
+		{"unversioned project", []string{"pgEdge RAG Server"}, []string{"1.0"},
+			`Project "pgEdge RAG Server" has no versioned documentation; search without a version.`},
+		{"version without project", nil, []string{"16"},
+			"No project has version 16. Call list_kb_projects for the available versions."},
+		{"matching filters", []string{"PostgreSQL"}, []string{"17"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kbFilterHint(products, tt.projects, tt.versions); got != tt.want {
+				t.Errorf("kbFilterHint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// serverLogTimeout bounds the time spent collecting log lines for a failed call
+const serverLogTimeout = 5 * time.Second
+
+// attachServerLog adds the errors and warnings PostgreSQL logged during a
+// failed tool call to its response, which often explains failures that the
+// client only sees as a generic error
+// Collection is best effort; the response is returned unchanged on failure
+func attachServerLog(ctx context.Context, dbClient *database.Client, cfg config.PostgresLogConfig, response mcp.ToolResponse, elapsed time.Duration) mcp.ToolResponse {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), serverLogTimeout)
+	defer cancel()
+
+	serverLog, err := dbClient.RecentServerLog(ctx, cfg, elapsed)
+	if err != nil {
+		logging.Warn("server_log_collection_failed", "error", err)
+		return response
+	}
+	if serverLog == nil {
+		return response
+	}
+
+	response.Content = append(response.Content, mcp.ContentItem{
+		Type: "text",
+		Text: formatServerLog(serverLog),
+	})
+	return response
+}
+
+// formatServerLog renders collected log lines for the LLM
+func formatServerLog(serverLog *database.ServerLog) string {
+	return fmt.Sprintf("PostgreSQL server log (errors and warnings logged during this call, via %s):\n%s",
+		serverLog.Source, strings.Join(serverLog.Lines, "\n"))
+}
@@ -0,0 +1,174 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// SetupForeignServerTool creates the setup_foreign_server tool, which wires
+// another configured database into the current one with postgres_fdw so
+// their tables can be joined in one query
+// The tool writes to the database, so it is disabled unless enabled in the
+// configuration
+func SetupForeignServerTool(dbClient *database.Client, federation *Federation) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "setup_foreign_server",
+			Description: `Make the tables of another configured database queryable from the current
+database with postgres_fdw, so one query can JOIN across both databases.
+
+<usecase>
+Use when:
+- The user wants to combine or compare data from two configured databases
+- Tables were added to the other database since it was set up: running
+  again imports them
+</usecase>
+
+<behavior>
+- dry_run defaults to true: the statements are shown without running them
+- Creates the postgres_fdw extension, a foreign server and a user mapping
+  with the configured database's connection settings, then imports the
+  remote schema's tables as foreign tables into a local schema named after
+  the database (e.g. database "analytics" -> schema analytics)
+- Steps already done are skipped; tables already imported are kept
+- Afterwards, query the tables as <local_schema>.<table>, or pass
+  database=<name> to query_database
+</behavior>
+
+<important>
+- Confirm with the user before running with dry_run=false: it creates
+  objects in the current database and stores the other database's
+  credentials in a user mapping
+- The other database's host must be reachable from the database server
+- Foreign tables show the remote columns at import time; run again after
+  remote schema changes
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"database": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the configured database to make queryable",
+					},
+					"remote_schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema of the other database to import (default: public)",
+						"default":     "public",
+					},
+					"local_schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema of the current database for the foreign tables (default: the database name)",
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Show the statements without running them (default: true)",
+						"default":     true,
+					},
+				},
+				Required: []string{"database"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			name, errResp := ValidateStringParam(args, "database")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			remoteSchema := ValidateOptionalStringParam(args, "remote_schema", "public")
+			localSchema := ValidateOptionalStringParam(args, "local_schema", "")
+			dryRun := ValidateBoolParam(args, "dry_run", true)
+
+			if federation == nil || federation.Lookup == nil {
+				return mcp.NewToolError("Querying other databases is not available")
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			db, err := federation.Lookup(ctx, name)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			target := newFDWTarget(db, remoteSchema, localSchema)
+			state, err := lookupFDWState(ctx, pool, target)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			if err := checkFDWExtension(state, federation.Extensions); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			steps := fdwSteps(target, state)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+
+			tables := len(state.ForeignTables)
+			if !dryRun {
+				tables, err = applyFDWSteps(ctx, pool, target, steps)
+				if err != nil {
+					return mcp.NewToolError(fmt.Sprintf("Failed to set up the foreign server: %v", err))
+				}
+
+				// The foreign tables are new schema metadata for the other tools
+				if err := dbClient.LoadMetadataFor(connStr); err != nil {
+					logging.Warn("setup_foreign_server_metadata_refresh_failed", "error", err)
+				}
+			}
+
+			logging.Info("setup_foreign_server_executed",
+				"database", name,
+				"server", target.Server,
+				"remote_schema", target.RemoteSchema,
+				"local_schema", target.LocalSchema,
+				"foreign_tables", tables,
+				"dry_run", dryRun,
+			)
+
+			if dryRun {
+				sb.WriteString("Dry run, nothing was set up.\n\n")
+			} else {
+				sb.WriteString(fmt.Sprintf("Database %s is set up as foreign server %s.\n\n", name, target.Server))
+			}
+			sb.WriteString(fmt.Sprintf("Remote: %s schema %s\nLocal schema: %s", name, target.RemoteSchema, target.LocalSchema))
+			if !dryRun || tables > 0 {
+				sb.WriteString(fmt.Sprintf(" (%d foreign tables)", tables))
+			}
+			sb.WriteString("\n\n")
+			for _, step := range steps {
+				sb.WriteString(step.Display + ";\n")
+			}
+			if dryRun {
+				sb.WriteString("\nRun again with dry_run=false to set it up.\n")
+			} else {
+				sb.WriteString(fmt.Sprintf("\nQuery the tables as %s.<table>, or with query_database(database=%q, ...).\n",
+					quoteIdentIfNeeded(target.LocalSchema), name))
+			}
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
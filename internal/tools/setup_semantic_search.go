@@ -0,0 +1,516 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/embedding"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+const (
+	defaultEmbeddingBatchSize = 100
+	maxEmbeddingBatchSize     = 1000
+)
+
+// SetupSemanticSearchTool creates the setup_semantic_search tool, which
+// prepares a table for similarity_search in one call: it installs pgvector,
+// adds a vector column, fills it with embeddings of a text column in
+// batches and indexes it
+// The tool writes to the database, so it is disabled unless enabled in the
+// configuration
+func SetupSemanticSearchTool(dbClient *database.Client, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "setup_semantic_search",
+			Description: `Set up semantic search on a text column: install pgvector, add a vector
+column, fill it with embeddings of the text and build a vector index.
+
+<usecase>
+Use when:
+- The user wants to search a table by meaning and get_schema_info shows no
+  vector column for it
+- New rows were added and their embeddings are missing: running again only
+  embeds the rows whose vector column is NULL
+</usecase>
+
+<behavior>
+- dry_run defaults to true: the steps are shown, with the number of rows to
+  embed, without changing the database
+- Steps already done are skipped: an installed extension, an existing vector
+  column with the right dimensions, an index for the distance metric
+- Embeddings are generated with the server's embedding provider and written
+  in batches of batch_size rows, each committed on its own, so an
+  interrupted run can be resumed by running again
+- max_rows limits the rows embedded in one call; the index is built once no
+  rows are left to embed
+</behavior>
+
+<important>
+- Confirm with the user before running with dry_run=false: it alters the
+  table, calls the embedding provider once per row and builds an index that
+  blocks writes to the table
+- The vector extension must be allowed by the server configuration if it is
+  not installed yet
+- Search the new column with similarity_search afterwards
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"table_name": map[string]interface{}{
+						"type":        "string",
+						"description": "Table to set up (can include schema: 'schema.table')",
+					},
+					"text_column": map[string]interface{}{
+						"type":        "string",
+						"description": "Text column whose contents are embedded",
+					},
+					"vector_column": map[string]interface{}{
+						"type":        "string",
+						"description": "Vector column to store the embeddings in (default: <text_column>_embedding)",
+					},
+					"distance_metric": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"cosine", "l2", "inner_product"},
+						"description": "Distance metric the index supports (default: cosine)",
+						"default":     "cosine",
+					},
+					"batch_size": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Rows embedded and committed per batch (default: %d, max: %d)", defaultEmbeddingBatchSize, maxEmbeddingBatchSize),
+						"default":     defaultEmbeddingBatchSize,
+					},
+					"max_rows": map[string]interface{}{
+						"type":        "integer",
+						"description": "Embed at most this many rows in this call; run again to continue (default: 0, no limit)",
+						"default":     0,
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Show the steps without changing the database (default: true)",
+						"default":     true,
+					},
+				},
+				Required: []string{"table_name", "text_column"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			tableName, errResp := ValidateStringParam(args, "table_name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			textColumn, errResp := ValidateStringParam(args, "text_column")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			vectorColumn := ValidateOptionalStringParam(args, "vector_column", embeddingColumnName(textColumn))
+			metric := ValidateOptionalStringParam(args, "distance_metric", "cosine")
+			if metric != "cosine" && metric != "l2" && metric != "inner_product" {
+				return mcp.NewToolError("distance_metric must be 'cosine', 'l2' or 'inner_product'")
+			}
+			batchSize := int(ValidateOptionalNumberParam(args, "batch_size", defaultEmbeddingBatchSize))
+			if batchSize < 1 || batchSize > maxEmbeddingBatchSize {
+				return mcp.NewToolError(fmt.Sprintf("batch_size must be between 1 and %d", maxEmbeddingBatchSize))
+			}
+			maxRows := int64(ValidateOptionalNumberParam(args, "max_rows", 0))
+			if maxRows < 0 {
+				return mcp.NewToolError("max_rows must not be negative")
+			}
+			dryRun := ValidateBoolParam(args, "dry_run", true)
+
+			if !cfg.Embedding.Enabled {
+				return mcp.NewToolError("Embedding generation is not enabled. Please enable it in the server configuration (PGEDGE_EMBEDDING_ENABLED=true) and configure a provider.")
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			tableInfo, err := findTableInMetadataMap(dbClient.GetMetadataFor(connStr), tableName)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("%v\nUse get_schema_info to find the table", err))
+			}
+
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+			progress := progressFromArgs(args)
+
+			provider, err := embedding.NewProvider(embedding.Config{
+				Provider:     cfg.Embedding.Provider,
+				Model:        cfg.Embedding.Model,
+				VoyageAPIKey: cfg.Embedding.VoyageAPIKey,
+				OpenAIAPIKey: cfg.Embedding.OpenAIAPIKey,
+				OllamaURL:    cfg.Embedding.OllamaURL,
+			})
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to initialize embedding provider: %v", err))
+			}
+			dims, err := embeddingDimensions(ctx, provider)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			columnExists, err := checkSemanticSearchColumns(tableInfo, textColumn, vectorColumn, dims)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+
+			var extensionInstalled bool
+			if err := pool.QueryRow(ctx,
+				"SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_extension WHERE extname = 'vector')").Scan(&extensionInstalled); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to check for the vector extension: %v", err))
+			}
+			if !extensionInstalled && !cfg.Extensions.IsAllowed("vector") {
+				return mcp.NewToolError(fmt.Sprintf("The vector extension is not installed and the server configuration does not allow installing it (extensions.allowed: %s)",
+					strings.Join(cfg.Extensions.Allowed, ", ")))
+			}
+
+			qualified := quoteIdentifier(tableInfo.SchemaName) + "." + quoteIdentifier(tableInfo.TableName)
+			toEmbed := int64(0)
+			countSQL := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", qualified, pendingEmbeddingCondition(textColumn, vectorColumn, columnExists))
+			if err := pool.QueryRow(ctx, countSQL).Scan(&toEmbed); err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to count the rows to embed: %v", err))
+			}
+
+			var indexes []vectorIndex
+			if columnExists {
+				indexes, err = lookupVectorIndexes(ctx, pool, tableInfo.SchemaName, tableInfo.TableName)
+				if err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+			}
+			var existingIndex *vectorIndex
+			for i := range indexes {
+				if indexes[i].Column == vectorColumn && indexes[i].Metric() == metric {
+					existingIndex = &indexes[i]
+					break
+				}
+			}
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+			if dryRun {
+				sb.WriteString("Dry run, the database was not changed.\n\n")
+			}
+			sb.WriteString(fmt.Sprintf("Table: %s\nText column: %s\nVector column: %s (%d dimensions)\nEmbedding model: %s (%s)\n\n",
+				tableName, textColumn, vectorColumn, dims, provider.ModelName(), provider.ProviderName()))
+
+			step := 0
+			writeStep := func(format string, a ...interface{}) {
+				step++
+				sb.WriteString(fmt.Sprintf("%d. %s\n", step, fmt.Sprintf(format, a...)))
+			}
+			changed := false
+
+			// 1. The vector extension
+			if extensionInstalled {
+				writeStep("Extension vector is already installed")
+			} else {
+				stmt := createExtensionStatement("vector", "")
+				if !dryRun {
+					if err := execInTx(ctx, pool, stmt); err != nil {
+						return mcp.NewToolError(fmt.Sprintf("Failed to install the vector extension: %v\nStatement: %s", err, stmt))
+					}
+					changed = true
+				}
+				writeStep("%s;", stmt)
+			}
+
+			// 2. The vector column
+			if columnExists {
+				writeStep("Column %s already exists", vectorColumn)
+			} else {
+				stmt := addVectorColumnStatement(tableInfo.SchemaName, tableInfo.TableName, vectorColumn, dims)
+				if !dryRun {
+					if err := execInTx(ctx, pool, stmt); err != nil {
+						return mcp.NewToolError(fmt.Sprintf("Failed to add the vector column: %v\nStatement: %s", err, stmt))
+					}
+					changed = true
+				}
+				writeStep("%s;", stmt)
+			}
+
+			// 3. The embeddings
+			limit := toEmbed
+			if maxRows > 0 && maxRows < limit {
+				limit = maxRows
+			}
+			embedded := int64(0)
+			if toEmbed == 0 {
+				writeStep("Every row with text already has an embedding")
+			} else if dryRun {
+				writeStep("Embed %d row(s) in batches of %d", limit, batchSize)
+			} else {
+				embedded, err = backfillEmbeddings(ctx, pool, provider, progress, qualified, textColumn, vectorColumn, batchSize, limit)
+				if embedded > 0 {
+					changed = true
+				}
+				if err != nil {
+					if changed {
+						if err := dbClient.LoadMetadataFor(connStr); err != nil {
+							logging.Warn("setup_semantic_search_metadata_refresh_failed", "error", err)
+						}
+					}
+					return mcp.NewToolError(fmt.Sprintf("%s\nEmbedding stopped after %d of %d row(s): %v\nThe embedded rows were kept; run again to continue.",
+						sb.String(), embedded, limit, err))
+				}
+				writeStep("Embedded %d row(s) in batches of %d", embedded, batchSize)
+			}
+			remaining := toEmbed - limit
+			if !dryRun {
+				remaining = toEmbed - embedded
+			}
+
+			// 4. The index, once every row is embedded
+			var notes []string
+			switch {
+			case existingIndex != nil:
+				writeStep("Index %s already supports %s distance", existingIndex.Name, metric)
+			case remaining > 0:
+				writeStep("Index not built yet: %d row(s) are left to embed", remaining)
+			default:
+				rows, err := estimateRowCount(ctx, pool, tableInfo.SchemaName, tableInfo.TableName)
+				if err != nil {
+					return mcp.NewToolError(err.Error())
+				}
+				plan, err := planVectorIndex("auto", rows, dims, metric)
+				if err != nil {
+					writeStep("Index not built: %v", err)
+					break
+				}
+				name := vectorIndexName(tableInfo.TableName, vectorColumn, plan.Method, metric)
+				stmt := plan.statement(name, tableInfo.SchemaName, tableInfo.TableName, vectorColumn)
+				if !dryRun {
+					if err := execInTx(ctx, pool, stmt); err != nil {
+						return mcp.NewToolError(fmt.Sprintf("%s\nFailed to create the index: %v\nStatement: %s", sb.String(), err, stmt))
+					}
+					changed = true
+				}
+				writeStep("%s;", stmt)
+				notes = plan.Notes
+			}
+
+			// Schema changes invalidate the cached metadata used by other tools
+			if changed {
+				if err := dbClient.LoadMetadataFor(connStr); err != nil {
+					logging.Warn("setup_semantic_search_metadata_refresh_failed", "error", err)
+				}
+			}
+
+			logging.Info("setup_semantic_search_executed",
+				"table", tableName,
+				"text_column", textColumn,
+				"vector_column", vectorColumn,
+				"dimensions", dims,
+				"to_embed", toEmbed,
+				"embedded", embedded,
+				"dry_run", dryRun,
+			)
+
+			if len(notes) > 0 {
+				sb.WriteString("\nNotes:\n")
+				for _, note := range notes {
+					sb.WriteString("- " + note + "\n")
+				}
+			}
+			switch {
+			case dryRun:
+				sb.WriteString("\nRun again with dry_run=false to make these changes.\n")
+			case remaining > 0:
+				sb.WriteString(fmt.Sprintf("\n%d row(s) are left to embed; run again to continue.\n", remaining))
+			default:
+				sb.WriteString(fmt.Sprintf("\nSemantic search is ready: similarity_search(table_name=%q, query_text=...)\n", tableName))
+			}
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// embeddingColumnName returns the default vector column for a text column,
+// within PostgreSQL's 63 byte limit
+func embeddingColumnName(textColumn string) string {
+	const suffix = "_embedding"
+	if len(textColumn)+len(suffix) > 63 {
+		textColumn = textColumn[:63-len(suffix)]
+	}
+	return textColumn + suffix
+}
+
+// embeddingDimensions returns the dimensions of the provider's embeddings,
+// embedding a sample text when the provider does not know them in advance
+func embeddingDimensions(ctx context.Context, provider embedding.Provider) (int, error) {
+	if dims := provider.Dimensions(); dims > 0 {
+		return dims, nil
+	}
+	vector, err := provider.Embed(ctx, "dimension check")
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+	if len(vector) == 0 {
+		return 0, fmt.Errorf("received empty embedding vector from provider")
+	}
+	return len(vector), nil
+}
+
+// checkSemanticSearchColumns checks that the text column holds text and that
+// the vector column, if the table already has it, is a vector column with the
+// embeddings' dimensions. It reports whether the vector column exists.
+func checkSemanticSearchColumns(tableInfo database.TableInfo, textColumn, vectorColumn string, dims int) (bool, error) {
+	var text, vector *database.ColumnInfo
+	for i := range tableInfo.Columns {
+		switch tableInfo.Columns[i].ColumnName {
+		case textColumn:
+			text = &tableInfo.Columns[i]
+		case vectorColumn:
+			vector = &tableInfo.Columns[i]
+		}
+	}
+
+	if text == nil {
+		return false, fmt.Errorf("table %s.%s has no column %s", tableInfo.SchemaName, tableInfo.TableName, textColumn)
+	}
+	if !isTextDataType(text.DataType) {
+		return false, fmt.Errorf("column %s is %s; the text column must hold text", textColumn, text.DataType)
+	}
+	if textColumn == vectorColumn {
+		return false, fmt.Errorf("vector_column must differ from text_column")
+	}
+	if vector == nil {
+		return false, nil
+	}
+	if !vector.IsVectorColumn {
+		return false, fmt.Errorf("column %s already exists and is %s, not a vector column; choose another vector_column", vectorColumn, vector.DataType)
+	}
+	if vector.VectorDimensions > 0 && vector.VectorDimensions != dims {
+		return false, fmt.Errorf("column %s has %d dimensions but the embedding model produces %d; choose another vector_column",
+			vectorColumn, vector.VectorDimensions, dims)
+	}
+	return true, nil
+}
+
+// addVectorColumnStatement returns the statement that adds the vector column
+func addVectorColumnStatement(schema, table, column string, dims int) string {
+	return fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN %s vector(%d)",
+		quoteIdentIfNeeded(schema), quoteIdentIfNeeded(table), quoteIdentIfNeeded(column), dims)
+}
+
+// pendingEmbeddingCondition selects the rows that have text but no embedding;
+// before the vector column is added, that is every row with text
+func pendingEmbeddingCondition(textColumn, vectorColumn string, columnExists bool) string {
+	cond := fmt.Sprintf("btrim(%s) <> ''", quoteIdentifier(textColumn))
+	if columnExists {
+		cond = fmt.Sprintf("%s IS NULL AND %s", quoteIdentifier(vectorColumn), cond)
+	}
+	return cond
+}
+
+// backfillEmbeddings embeds up to limit rows whose vector column is NULL,
+// committing each batch so work done before a failure is kept. Rows are
+// addressed by ctid, so tables without a primary key work too; a row
+// updated concurrently is picked up again by a later batch.
+func backfillEmbeddings(ctx context.Context, pool *pgxpool.Pool, provider embedding.Provider, progress mcp.ProgressReporter,
+	qualified, textColumn, vectorColumn string, batchSize int, limit int64) (int64, error) {
+	selectSQL := fmt.Sprintf("SELECT ctid::text, %s FROM %s WHERE %s LIMIT $1 FOR UPDATE SKIP LOCKED",
+		quoteIdentifier(textColumn), qualified, pendingEmbeddingCondition(textColumn, vectorColumn, true))
+	updateSQL := fmt.Sprintf("UPDATE %s SET %s = $1::vector WHERE ctid = $2::tid",
+		qualified, quoteIdentifier(vectorColumn))
+
+	var embedded int64
+	for embedded < limit {
+		n := int64(batchSize)
+		if limit-embedded < n {
+			n = limit - embedded
+		}
+		count, err := embedBatch(ctx, pool, provider, selectSQL, updateSQL, n)
+		embedded += count
+		if err != nil {
+			return embedded, err
+		}
+		if count == 0 {
+			break
+		}
+		progress.Report(float64(embedded), float64(limit), fmt.Sprintf("Embedded %d of %d rows", embedded, limit))
+	}
+	return embedded, nil
+}
+
+// embedBatch embeds and stores one batch of rows in a transaction
+func embedBatch(ctx context.Context, pool *pgxpool.Pool, provider embedding.Provider, selectSQL, updateSQL string, n int64) (int64, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // no-op after a successful commit
+	}()
+
+	rows, err := tx.Query(ctx, selectSQL, n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rows to embed: %w", err)
+	}
+	type pendingRow struct{ ctid, text string }
+	batch, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (pendingRow, error) {
+		var r pendingRow
+		err := row.Scan(&r.ctid, &r.text)
+		return r, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rows to embed: %w", err)
+	}
+
+	var updated int64
+	for _, r := range batch {
+		vector, err := provider.Embed(ctx, r.text)
+		if err != nil {
+			return 0, fmt.Errorf("failed to generate embedding: %w", err)
+		}
+		tag, err := tx.Exec(ctx, updateSQL, formatEmbeddingForPostgres(vector), r.ctid)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store embedding: %w", err)
+		}
+		updated += tag.RowsAffected()
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit failed: %w", err)
+	}
+	return updated, nil
+}
+
+// execInTx runs a statement in its own read-write transaction
+func execInTx(ctx context.Context, pool *pgxpool.Pool, stmt string) error {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // no-op after a successful commit
+	}()
+	if _, err := tx.Exec(ctx, stmt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,115 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+)
+
+func TestEmbeddingColumnName(t *testing.T) {
+	if got := embeddingColumnName("body"); got != "body_embedding" {
+		t.Errorf("embeddingColumnName(body) = %q", got)
+	}
+	long := strings.Repeat("c", 60)
+	if got := embeddingColumnName(long); len(got) != 63 || !strings.HasSuffix(got, "_embedding") {
+		t.Errorf("embeddingColumnName(long) = %q (%d bytes)", got, len(got))
+	}
+}
+
+func TestCheckSemanticSearchColumns(t *testing.T) {
+	table := database.TableInfo{
+		SchemaName: "public",
+		TableName:  "docs",
+		Columns: []database.ColumnInfo{
+			{ColumnName: "id", DataType: "integer"},
+			{ColumnName: "body", DataType: "text"},
+			{ColumnName: "title", DataType: "character varying(200)"},
+			{ColumnName: "body_embedding", DataType: "vector(768)", IsVectorColumn: true, VectorDimensions: 768},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		text, vector string
+		dims         int
+		wantExists   bool
+		wantErr      string
+	}{
+		{name: "new column", text: "title", vector: "title_embedding", dims: 768},
+		{name: "existing column", text: "body", vector: "body_embedding", dims: 768, wantExists: true},
+		{name: "missing text column", text: "summary", vector: "summary_embedding", dims: 768, wantErr: "has no column summary"},
+		{name: "not text", text: "id", vector: "id_embedding", dims: 768, wantErr: "must hold text"},
+		{name: "same column", text: "body", vector: "body", dims: 768, wantErr: "must differ"},
+		{name: "not a vector", text: "body", vector: "title", dims: 768, wantErr: "not a vector column"},
+		{name: "other dimensions", text: "body", vector: "body_embedding", dims: 1536, wantErr: "has 768 dimensions but the embedding model produces 1536"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists, err := checkSemanticSearchColumns(table, tt.text, tt.vector, tt.dims)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exists != tt.wantExists {
+				t.Errorf("exists = %v, want %v", exists, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestSemanticSearchStatements(t *testing.T) {
+	if got, want := addVectorColumnStatement("public", "docs", "body_embedding", 1536),
+		"ALTER TABLE public.docs ADD COLUMN body_embedding vector(1536)"; got != want {
+		t.Errorf("addVectorColumnStatement() = %q, want %q", got, want)
+	}
+	if got, want := pendingEmbeddingCondition("body", "body_embedding", false), `btrim("body") <> ''`; got != want {
+		t.Errorf("pendingEmbeddingCondition(new column) = %q, want %q", got, want)
+	}
+	if got, want := pendingEmbeddingCondition("body", "body_embedding", true),
+		`"body_embedding" IS NULL AND btrim("body") <> ''`; got != want {
+		t.Errorf("pendingEmbeddingCondition(existing column) = %q, want %q", got, want)
+	}
+}
+
+func TestSetupSemanticSearchTool_Validation(t *testing.T) {
+	tool := SetupSemanticSearchTool(nil, &config.Config{})
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{"missing text column", map[string]interface{}{"table_name": "docs"}, "text_column"},
+		{"bad metric", map[string]interface{}{"table_name": "docs", "text_column": "body", "distance_metric": "hamming"}, "distance_metric"},
+		{"bad batch size", map[string]interface{}{"table_name": "docs", "text_column": "body", "batch_size": float64(5000)}, "batch_size"},
+		{"embedding disabled", map[string]interface{}{"table_name": "docs", "text_column": "body"}, "Embedding generation is not enabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tool.Handler(tt.args)
+			if err != nil {
+				t.Fatalf("Handler() error = %v", err)
+			}
+			if !resp.IsError || !strings.Contains(resp.Content[0].Text, tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %+v", tt.wantErr, resp)
+			}
+		})
+	}
+}
@@ -23,6 +23,9 @@ import (
 	"pgedge-postgres-mcp/internal/search"
 )
 
+// similaritySearchSteps is the number of progress steps reported by similarity_search
+const similaritySearchSteps = 5
+
 // SimilaritySearchTool creates the similarity_search tool for hybrid semantic + lexical search
 func SimilaritySearchTool(dbClient *database.Client, cfg *config.Config) Tool {
 	return Tool{
@@ -284,7 +287,11 @@ To avoid rate limits (30,000 input tokens/minute):
 				return mcp.NewToolError(errMsg.String())
 			}
 
+			// Long searches report progress to clients that ask for it
+			progress := progressFromArgs(args)
+
 			// Step 3: Sample data for smart column type detection
+			progress.Report(1, similaritySearchSteps, "Sampling table data")
 			sampleData, err := sampleTableData(dbClient, tableName, textCols, 3)
 			if err != nil {
 				// Non-fatal: proceed with default weights
@@ -295,6 +302,7 @@ To avoid rate limits (30,000 input tokens/minute):
 			columnWeights := search.DetectColumnTypes(tableInfo, sampleData)
 
 			// Step 4: Generate query embedding (use the global cfg variable, not the search config)
+			progress.Report(2, similaritySearchSteps, "Generating query embedding")
 			queryEmbedding, err := generateQueryEmbeddingWithConfig(cfg, queryText)
 			if err != nil {
 				var errMsg strings.Builder
@@ -318,6 +326,7 @@ To avoid rate limits (30,000 input tokens/minute):
 			}
 
 			// Step 5: Perform weighted vector search
+			progress.Report(3, similaritySearchSteps, "Searching vector columns")
 			results, err := performWeightedVectorSearch(
 				dbClient,
 				tableName,
@@ -377,6 +386,7 @@ To avoid rate limits (30,000 input tokens/minute):
 			}
 
 			// Step 6: Chunk all results
+			progress.Report(4, similaritySearchSteps, fmt.Sprintf("Ranking %d results", len(results)))
 			allChunks := chunkResults(results, textCols, tableName, searchCfg.ChunkSizeTokens, searchCfg.OverlapTokens)
 
 			// Step 7: Re-rank chunks using BM25
@@ -415,6 +425,7 @@ To avoid rate limits (30,000 input tokens/minute):
 			}
 
 			// Step 10: Format output based on requested format
+			progress.Report(similaritySearchSteps, similaritySearchSteps, "Formatting results")
 			var output string
 			switch outputFormat {
 			case "ids_only":
@@ -430,6 +441,16 @@ To avoid rate limits (30,000 input tokens/minute):
 			sanitizedConn := database.SanitizeConnStr(connStr)
 			result := fmt.Sprintf("Database: %s\nTable: %s\n\n%s", sanitizedConn, tableName, output)
 
+			// Report whether the search could use a vector index
+			if pool := dbClient.GetPoolFor(connStr); pool != nil {
+				indexes, err := lookupVectorIndexes(context.Background(), pool, tableInfo.SchemaName, tableInfo.TableName)
+				if err != nil {
+					logging.Warn("similarity_search_index_lookup_failed", "table", tableName, "error", err)
+				} else {
+					result += "\n" + formatVectorIndexReport(vectorColumnNames(vectorCols), indexes, searchCfg.DistanceMetric)
+				}
+			}
+
 			// Log execution metrics
 			totalTokens := 0
 			for _, chunk := range finalChunks {
@@ -488,6 +509,15 @@ func discoverVectorColumns(tableInfo database.TableInfo) []database.ColumnInfo {
 	return vectorCols
 }
 
+// vectorColumnNames returns the names of vector columns
+func vectorColumnNames(vectorCols []database.ColumnInfo) []string {
+	names := make([]string, len(vectorCols))
+	for i := range vectorCols {
+		names[i] = vectorCols[i].ColumnName
+	}
+	return names
+}
+
 func discoverTextColumns(tableInfo database.TableInfo, vectorCols []database.ColumnInfo) []string {
 	// Try to match vector columns to text columns by name
 	var textCols []string
@@ -667,10 +697,12 @@ func performWeightedVectorSearch(
 
 	// Build weighted distance calculation
 	var weightedParts []string
+	var searchedCols []string
 	weightMap := make(map[string]float64)
 
 	for _, weight := range columnWeights {
 		weightedParts = append(weightedParts, fmt.Sprintf("(%s %s $1::vector) * %f", weight.VectorName, distOp, weight.Weight))
+		searchedCols = append(searchedCols, weight.VectorName)
 		weightMap[weight.VectorName] = weight.Weight
 	}
 
@@ -679,18 +711,27 @@ func performWeightedVectorSearch(
 		for i := range vectorCols {
 			weight := 1.0 / float64(len(vectorCols))
 			weightedParts = append(weightedParts, fmt.Sprintf("(%s %s $1::vector) * %f", vectorCols[i].ColumnName, distOp, weight))
+			searchedCols = append(searchedCols, vectorCols[i].ColumnName)
 			weightMap[vectorCols[i].ColumnName] = weight
 		}
 	}
 
 	weightedDistance := strings.Join(weightedParts, " + ")
 
+	// pgvector indexes only serve ORDER BY <column> <operator> <vector>, so
+	// a single column is ordered by its distance rather than the weighted
+	// expression (the order is the same)
+	orderBy := "weighted_distance"
+	if len(searchedCols) == 1 {
+		orderBy = fmt.Sprintf("%s %s $1::vector", searchedCols[0], distOp)
+	}
+
 	query := fmt.Sprintf(`
         SELECT %s, (%s) as weighted_distance
         FROM %s
-        ORDER BY weighted_distance
+        ORDER BY %s
         LIMIT $2
-    `, colList, weightedDistance, tableName)
+    `, colList, weightedDistance, tableName, orderBy)
 
 	// Convert embedding to PostgreSQL array format
 	embeddingStr := formatEmbeddingForPostgres(queryEmbedding)
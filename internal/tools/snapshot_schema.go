@@ -0,0 +1,205 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+	"pgedge-postgres-mcp/internal/snapshot"
+)
+
+// SnapshotSchemaTool creates the snapshot_schema tool, which saves the DDL
+// of a schema, and optionally its data, as a named snapshot in the data
+// directory
+func SnapshotSchemaTool(dbClient *database.Client, cfg *config.Config) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "snapshot_schema",
+			Description: `Save a schema's DDL (types, sequences, functions, tables, constraints,
+indexes, views, triggers and comments), and optionally its data, as a named
+snapshot on the server.
+
+<usecase>
+Use before trying out a migration or other schema change, so the schema can
+be recreated with restore_schema_snapshot if the change needs to be undone
+or compared with the original.
+</usecase>
+
+<behavior>
+- The schema is read in a single read-only transaction, so the DDL and data
+  are consistent
+- include_data=true also saves every table's rows, up to the server's size
+  limit
+- Objects created by extensions, privileges and ownership are not saved;
+  objects that cannot be saved are listed as warnings
+</behavior>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the snapshot: letters, digits, '_' and '-', e.g. 'before_orders_migration'",
+					},
+					"schema": map[string]interface{}{
+						"type":        "string",
+						"description": "Schema to snapshot (default: public)",
+						"default":     "public",
+					},
+					"include_data": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also save the rows of every table (default: false)",
+						"default":     false,
+					},
+					"replace": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace an existing snapshot with the same name (default: false)",
+						"default":     false,
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			name, errResp := ValidateStringParam(args, "name")
+			if errResp != nil {
+				return *errResp, nil
+			}
+			schema := ValidateOptionalStringParam(args, "schema", "public")
+			includeData := ValidateBoolParam(args, "include_data", false)
+			replace := ValidateBoolParam(args, "replace", false)
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+			pool := dbClient.GetPoolFor(connStr)
+			if pool == nil {
+				return mcp.NewToolError(fmt.Sprintf("Connection pool not found for: %s", database.SanitizeConnStr(connStr)))
+			}
+
+			store := snapshot.NewStoreFromConfig(cfg)
+			writer, err := store.Create(name, replace)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			committed := false
+			defer func() {
+				if !committed {
+					writer.Abort()
+				}
+			}()
+
+			ctx := context.Background()
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			defer tx.Rollback(ctx) //nolint:errcheck // The transaction only reads
+
+			snap, tables, err := readSchemaSnapshot(ctx, tx, schema)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			snap.Database = database.SanitizeConnStr(connStr)
+			snap.CreatedAt = time.Now().UTC()
+
+			if includeData {
+				if err := copySnapshotData(ctx, tx, writer, snap, tables); err != nil {
+					if errors.Is(err, snapshot.ErrTooLarge) {
+						return mcp.NewToolError(fmt.Sprintf("The data of schema %s is larger than the %d MB snapshot limit; snapshot it without data",
+							schema, store.MaxBytes>>20))
+					}
+					return mcp.NewToolError(fmt.Sprintf("Failed to save the data: %v", err))
+				}
+				recordRowsReturned(args, int(snap.Rows()))
+			}
+
+			if err := writer.Commit(snap); err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			committed = true
+
+			logging.Info("snapshot_schema_executed",
+				"name", name,
+				"schema", schema,
+				"tables", len(snap.Tables),
+				"rows", snap.Rows(),
+				"data_bytes", snap.DataBytes,
+			)
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", snap.Database))
+			sb.WriteString(formatSnapshotSummary(snap))
+			sb.WriteString("\nRecreate it with restore_schema_snapshot, into a new schema to compare it with the current one.\n")
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// copySnapshotData saves the rows of each table with data of its own in
+// PostgreSQL's COPY text format
+func copySnapshotData(ctx context.Context, tx pgx.Tx, writer *snapshot.Writer, snap *snapshot.Snapshot, tables []snapshotTableDef) error {
+	for i, t := range tables {
+		columns := t.dataColumns()
+		if t.Partitioned || len(columns) == 0 {
+			continue
+		}
+		file, w, err := writer.DataFile(i)
+		if err != nil {
+			return err
+		}
+		tag, err := tx.Conn().PgConn().CopyTo(ctx, w,
+			fmt.Sprintf("COPY %s (%s) TO STDOUT", t.Ident, strings.Join(columns, ", ")))
+		closeErr := w.Close()
+		if err != nil {
+			if errors.Is(err, snapshot.ErrTooLarge) {
+				return err
+			}
+			return fmt.Errorf("table %s: %w", t.Name, err)
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+		snap.Tables[i].DataFile = file
+		snap.Tables[i].Rows = tag.RowsAffected()
+	}
+	return nil
+}
+
+// formatSnapshotSummary describes a stored snapshot
+func formatSnapshotSummary(snap *snapshot.Snapshot) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Snapshot: %s (schema %s, %s)\n", snap.Name, snap.Schema, snap.CreatedAt.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("Tables: %d\n", len(snap.Tables)))
+	sb.WriteString(fmt.Sprintf("Statements: %d before the data, %d after\n", len(snap.PreData), len(snap.PostData)))
+	if snap.HasData() {
+		sb.WriteString(fmt.Sprintf("Data: %d rows in %d tables (%d bytes)\n", snap.Rows(), countDataTables(snap), snap.DataBytes))
+	} else {
+		sb.WriteString("Data: not included\n")
+	}
+	if len(snap.Warnings) > 0 {
+		sb.WriteString("\nWarnings:\n")
+		for _, w := range snap.Warnings {
+			sb.WriteString("- " + w + "\n")
+		}
+	}
+	return sb.String()
+}
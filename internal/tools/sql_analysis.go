@@ -0,0 +1,1244 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+// This file contains a lightweight, metadata-aware SQL analyzer. It is not a
+// full PostgreSQL parser: it tokenizes the statement, tracks clauses and
+// query nesting, and resolves table and column references against the cached
+// schema metadata. Anything it cannot resolve with confidence is left alone
+// rather than reported as an error.
+
+type sqlTokenKind int
+
+const (
+	tokWord        sqlTokenKind = iota // Keyword or unquoted identifier
+	tokQuotedIdent                     // "Quoted" identifier
+	tokString                          // String literal (including E'' and $$ quoting)
+	tokNumber                          // Numeric literal
+	tokParam                           // Positional parameter ($1)
+	tokPunct                           // Operator or punctuation
+)
+
+// sqlToken is a single lexical token
+type sqlToken struct {
+	kind  sqlTokenKind
+	value string // Identifiers are case-folded like PostgreSQL does; strings are unquoted
+	upper string // Upper-cased value for keyword matching (words only)
+	start int    // Byte offsets of the token in the source text
+	end   int
+}
+
+func (t sqlToken) isPunct(p string) bool {
+	return t.kind == tokPunct && t.value == p
+}
+
+func (t sqlToken) isKeyword(words ...string) bool {
+	if t.kind != tokWord {
+		return false
+	}
+	for _, w := range words {
+		if t.upper == w {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdent reports whether the token can be used as a table, alias or column name
+func (t sqlToken) isIdent() bool {
+	if t.kind == tokQuotedIdent {
+		return true
+	}
+	return t.kind == tokWord && !sqlKeywords[t.upper]
+}
+
+// sqlKeywords are words that are never treated as table, alias or column names
+var sqlKeywords = map[string]bool{
+	"ALL": true, "AND": true, "ANY": true, "ARRAY": true, "AS": true, "ASC": true,
+	"BETWEEN": true, "BOTH": true, "BY": true, "CASE": true, "CAST": true,
+	"COLLATE": true, "CONCURRENTLY": true, "CONFLICT": true, "CROSS": true,
+	"CURRENT": true, "CURRENT_DATE": true, "CURRENT_ROLE": true,
+	"CURRENT_SCHEMA": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true,
+	"CURRENT_USER": true, "DATE": true, "DEFAULT": true, "DELETE": true,
+	"DESC": true, "DISTINCT": true, "DO": true, "ELSE": true, "END": true,
+	"EXCEPT": true, "EXISTS": true, "FALSE": true, "FETCH": true, "FILTER": true,
+	"FIRST": true, "FOLLOWING": true, "FOR": true, "FROM": true, "FULL": true,
+	"GROUP": true, "HAVING": true, "ILIKE": true, "IN": true, "INNER": true,
+	"INSERT": true, "INTERSECT": true, "INTERVAL": true, "INTO": true, "IS": true,
+	"ISNULL": true, "JOIN": true, "LAST": true, "LATERAL": true, "LEADING": true,
+	"LEFT": true, "LIKE": true, "LIMIT": true, "LOCALTIME": true,
+	"LOCALTIMESTAMP": true, "MATCHED": true, "MATERIALIZED": true, "MERGE": true,
+	"NATURAL": true, "NEXT": true, "NOT": true, "NOTHING": true, "NOTNULL": true,
+	"NULL": true, "NULLS": true, "OFFSET": true, "ON": true, "ONLY": true,
+	"OR": true, "ORDER": true, "OUTER": true, "OVER": true, "PARTITION": true,
+	"PRECEDING": true, "PRECISION": true, "RANGE": true, "RECURSIVE": true,
+	"RETURNING": true, "RIGHT": true, "ROW": true, "ROWS": true, "SELECT": true,
+	"SESSION_USER": true, "SET": true, "SIMILAR": true, "SOME": true,
+	"TABLE": true, "TABLESAMPLE": true, "THEN": true, "TIME": true,
+	"TIMESTAMP": true, "TO": true, "TRAILING": true, "TRUE": true,
+	"UNBOUNDED": true, "UNION": true, "UNKNOWN": true, "UPDATE": true,
+	"USER": true, "USING": true, "VALUES": true, "VARYING": true, "WHEN": true,
+	"WHERE": true, "WINDOW": true, "WITH": true, "WITHIN": true,
+	"WITHOUT": true, "ZONE": true,
+}
+
+// tokenizeSQL splits a SQL string into tokens, skipping whitespace and comments
+func tokenizeSQL(sql string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	i := 0
+	n := len(sql)
+
+	for i < n {
+		c := sql[i]
+		start, count := i, len(tokens)
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+
+		case c == '-' && i+1 < n && sql[i+1] == '-':
+			for i < n && sql[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < n && sql[i+1] == '*':
+			// Block comments nest in PostgreSQL
+			depth := 0
+			for {
+				if i+1 >= n {
+					return nil, fmt.Errorf("unterminated block comment")
+				}
+				if sql[i] == '/' && sql[i+1] == '*' {
+					depth++
+					i += 2
+				} else if sql[i] == '*' && sql[i+1] == '/' {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+
+		case c == '\'':
+			value, next, err := scanQuoted(sql, i, '\'', false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{kind: tokString, value: value})
+			i = next
+
+		case (c == 'e' || c == 'E') && i+1 < n && sql[i+1] == '\'':
+			value, next, err := scanQuoted(sql, i+1, '\'', true)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{kind: tokString, value: value})
+			i = next
+
+		case c == '"':
+			value, next, err := scanQuoted(sql, i, '"', false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{kind: tokQuotedIdent, value: value})
+			i = next
+
+		case c == '$' && i+1 < n && isDigit(sql[i+1]):
+			j := i + 1
+			for j < n && isDigit(sql[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: tokParam, value: sql[i:j]})
+			i = j
+
+		case c == '$':
+			// Dollar-quoted string: $tag$ ... $tag$
+			j := i + 1
+			for j < n && isIdentChar(sql[j]) && sql[j] != '$' {
+				j++
+			}
+			if j >= n || sql[j] != '$' {
+				tokens = append(tokens, sqlToken{kind: tokPunct, value: "$"})
+				i++
+				break
+			}
+			tag := sql[i : j+1]
+			end := strings.Index(sql[j+1:], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string")
+			}
+			tokens = append(tokens, sqlToken{kind: tokString, value: sql[j+1 : j+1+end]})
+			i = j + 1 + end + len(tag)
+
+		case isDigit(c) || (c == '.' && i+1 < n && isDigit(sql[i+1])):
+			j := i
+			for j < n && (isDigit(sql[j]) || sql[j] == '.' || sql[j] == '_') {
+				j++
+			}
+			if j < n && (sql[j] == 'e' || sql[j] == 'E') {
+				j++
+				if j < n && (sql[j] == '+' || sql[j] == '-') {
+					j++
+				}
+				for j < n && isDigit(sql[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: tokNumber, value: sql[i:j]})
+			i = j
+
+		case isIdentStart(c):
+			j := i
+			for j < n && isIdentChar(sql[j]) {
+				j++
+			}
+			word := sql[i:j]
+			tokens = append(tokens, sqlToken{
+				kind:  tokWord,
+				value: strings.ToLower(word),
+				upper: strings.ToUpper(word),
+			})
+			i = j
+
+		default:
+			width := 1
+			if i+1 < n {
+				switch sql[i : i+2] {
+				case "::", "<>", "!=", "<=", ">=", "||":
+					width = 2
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: tokPunct, value: sql[i : i+width]})
+			i += width
+		}
+
+		if len(tokens) > count {
+			tokens[count].start = start
+			tokens[count].end = i
+		}
+	}
+
+	return tokens, nil
+}
+
+// scanQuoted reads a quoted string or identifier starting at the opening quote
+// Doubled quotes are unescaped; backslash escapes are honored for E-prefixed strings
+func scanQuoted(sql string, start int, quote byte, backslash bool) (string, int, error) {
+	var sb strings.Builder
+	i := start + 1
+	for i < len(sql) {
+		c := sql[i]
+		if backslash && c == '\\' && i+1 < len(sql) {
+			sb.WriteByte(sql[i+1])
+			i += 2
+			continue
+		}
+		if c == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				sb.WriteByte(quote)
+				i += 2
+				continue
+			}
+			return sb.String(), i + 1, nil
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	if quote == '"' {
+		return "", 0, fmt.Errorf("unterminated quoted identifier")
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
+
+// sqlSourceKind describes what a FROM-list entry refers to
+type sqlSourceKind int
+
+const (
+	sourceTable    sqlSourceKind = iota // Table or view found in the schema metadata
+	sourceCTE                           // Common table expression
+	sourceDerived                       // Subquery in FROM
+	sourceFunction                      // Set-returning function in FROM
+	sourceSystem                        // System catalog (pg_catalog, information_schema)
+	sourceUnknown                       // Table not found in the schema metadata
+)
+
+// sqlSource is a table-like object referenced by the statement
+type sqlSource struct {
+	Schema string
+	Name   string
+	Alias  string
+	Kind   sqlSourceKind
+	Table  *database.TableInfo // Set when Kind is sourceTable
+	target bool                // INSERT target, only visible to target clauses
+	star   bool                // All columns are selected
+}
+
+// displayName returns the qualified name of the source
+func (s *sqlSource) displayName() string {
+	if s.Table != nil {
+		return s.Table.SchemaName + "." + s.Table.TableName
+	}
+	if s.Schema != "" {
+		return s.Schema + "." + s.Name
+	}
+	return s.Name
+}
+
+// sqlColumnRef is a column reference found in the statement
+type sqlColumnRef struct {
+	Qualifier string // Table name or alias, empty if unqualified
+	Name      string
+	Clause    string // Clause the reference appears in
+	Function  string // Enclosing function call, if any
+	Source    *sqlSource
+	Column    *database.ColumnInfo // Set when the column was resolved in the metadata
+	scope     *sqlScope
+}
+
+// sqlScope holds the sources visible to one query level
+type sqlScope struct {
+	parent    *sqlScope
+	sources   []*sqlSource
+	star      bool // SELECT * at this level
+	commaJoin bool // FROM list uses commas
+	hasWhere  bool
+}
+
+// sqlFrame tracks one level of parentheses
+type sqlFrame struct {
+	query    bool       // Top level or subquery
+	clause   string     // Current clause
+	scope    *sqlScope  // Scope for references made in this frame
+	function string     // Function name when the parentheses belong to a call
+	source   *sqlSource // FROM-list entry completed when the frame closes
+}
+
+// sqlAnalysis is the result of analyzing a statement
+type sqlAnalysis struct {
+	StatementType string
+	Statements    int
+	Clauses       []string
+	Sources       []*sqlSource
+	Columns       []*sqlColumnRef
+	Issues        []string
+	Unresolved    []string // Issues for references that fail to resolve, also in Issues
+}
+
+// sqlAnalyzer holds the state used while analyzing a statement
+type sqlAnalyzer struct {
+	toks      []sqlToken
+	metadata  map[string]database.TableInfo
+	result    *sqlAnalysis
+	ctes      map[string]bool
+	aliases   map[string]bool // Output column aliases
+	scopes    []*sqlScope
+	mainIndex int // Index of the main statement keyword
+	issueSeen map[string]bool
+	pending   *sqlSource // Function source waiting for its argument list
+}
+
+// writeStatements are statement types that modify data or schema
+var writeStatements = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+	"TRUNCATE": true, "CREATE": true, "ALTER": true, "DROP": true,
+	"GRANT": true, "REVOKE": true, "COPY": true, "VACUUM": true,
+	"REINDEX": true, "CLUSTER": true, "COMMENT": true, "REFRESH": true,
+}
+
+// targetClauses are the clauses of an INSERT that refer to the target table
+var targetClauses = map[string]bool{
+	"INTO": true, "ON CONFLICT": true, "DO UPDATE": true, "SET": true, "RETURNING": true,
+}
+
+// analyzeSQL analyzes the first statement in sql against the schema metadata
+func analyzeSQL(sql string, metadata map[string]database.TableInfo) (*sqlAnalysis, error) {
+	tokens, err := tokenizeSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := splitStatements(tokens)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no SQL statement found")
+	}
+
+	a := &sqlAnalyzer{
+		toks:      stripExplain(statements[0]),
+		metadata:  metadata,
+		result:    &sqlAnalysis{Statements: len(statements)},
+		ctes:      make(map[string]bool),
+		aliases:   make(map[string]bool),
+		issueSeen: make(map[string]bool),
+	}
+	a.run()
+	return a.result, nil
+}
+
+// stripExplain removes a leading EXPLAIN and its options so the explained
+// statement is analyzed
+func stripExplain(tokens []sqlToken) []sqlToken {
+	if len(tokens) == 0 || !tokens[0].isKeyword("EXPLAIN") {
+		return tokens
+	}
+	i := 1
+	if i < len(tokens) && tokens[i].isPunct("(") {
+		for i < len(tokens) && !tokens[i].isPunct(")") {
+			i++
+		}
+		i++
+	}
+	for i < len(tokens) && tokens[i].isKeyword("ANALYZE", "ANALYSE", "VERBOSE") {
+		i++
+	}
+	if i >= len(tokens) {
+		return tokens
+	}
+	return tokens[i:]
+}
+
+// splitStatements splits tokens on top-level semicolons, dropping empty statements
+func splitStatements(tokens []sqlToken) [][]sqlToken {
+	var statements [][]sqlToken
+	depth := 0
+	start := 0
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case t.isPunct(";") && depth <= 0:
+			if i > start {
+				statements = append(statements, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		statements = append(statements, tokens[start:])
+	}
+	return statements
+}
+
+// addIssue records an issue once
+func (a *sqlAnalyzer) addIssue(format string, args ...interface{}) {
+	issue := fmt.Sprintf(format, args...)
+	if a.issueSeen[issue] {
+		return
+	}
+	a.issueSeen[issue] = true
+	a.result.Issues = append(a.result.Issues, issue)
+}
+
+// addUnresolved records an issue for a reference that does not resolve,
+// which makes the statement fail
+func (a *sqlAnalyzer) addUnresolved(format string, args ...interface{}) {
+	issue := fmt.Sprintf(format, args...)
+	if !a.issueSeen[issue] {
+		a.result.Unresolved = append(a.result.Unresolved, issue)
+	}
+	a.addIssue("%s", issue)
+}
+
+// addClause records a top-level clause once, in order of appearance
+func (a *sqlAnalyzer) addClause(clause string) {
+	for _, c := range a.result.Clauses {
+		if c == clause {
+			return
+		}
+	}
+	a.result.Clauses = append(a.result.Clauses, clause)
+}
+
+func (a *sqlAnalyzer) hasClause(clause string) bool {
+	for _, c := range a.result.Clauses {
+		if c == clause {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *sqlAnalyzer) newScope(parent *sqlScope) *sqlScope {
+	s := &sqlScope{parent: parent}
+	a.scopes = append(a.scopes, s)
+	return s
+}
+
+func (a *sqlAnalyzer) tok(i int) sqlToken {
+	if i < 0 || i >= len(a.toks) {
+		return sqlToken{kind: tokPunct}
+	}
+	return a.toks[i]
+}
+
+// run performs the analysis
+func (a *sqlAnalyzer) run() {
+	a.findStatementType()
+	stmt := a.result.StatementType
+	root := a.newScope(nil)
+
+	if writeStatements[stmt] {
+		a.addIssue("%s statements modify data or schema; read-only tools such as query_database will reject them", stmt)
+	}
+
+	switch stmt {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "MERGE", "VALUES":
+		a.walk(root)
+	case "TRUNCATE", "ALTER", "DROP", "CREATE":
+		a.walkUtility(root)
+	}
+
+	a.resolveColumns()
+	a.checkStatement(root)
+}
+
+// findStatementType determines the statement type and collects CTE names
+func (a *sqlAnalyzer) findStatementType() {
+	first := a.tok(0)
+	if first.kind != tokWord {
+		a.result.StatementType = "UNKNOWN"
+		return
+	}
+	if !first.isKeyword("WITH") {
+		a.result.StatementType = first.upper
+		a.mainIndex = 0
+		return
+	}
+
+	// WITH [RECURSIVE] name [(cols)] AS [[NOT] MATERIALIZED] (...) [, ...] main
+	i := 1
+	if a.tok(i).isKeyword("RECURSIVE") {
+		i++
+	}
+	for i < len(a.toks) {
+		name := a.tok(i)
+		if !name.isIdent() {
+			break
+		}
+		a.ctes[name.value] = true
+		i++
+		if a.tok(i).isPunct("(") {
+			i = a.skipParens(i)
+		}
+		if !a.tok(i).isKeyword("AS") {
+			break
+		}
+		i++
+		if a.tok(i).isKeyword("NOT") {
+			i++
+		}
+		if a.tok(i).isKeyword("MATERIALIZED") {
+			i++
+		}
+		if !a.tok(i).isPunct("(") {
+			break
+		}
+		i = a.skipParens(i)
+		if !a.tok(i).isPunct(",") {
+			break
+		}
+		i++
+	}
+
+	a.mainIndex = i
+	if t := a.tok(i); t.kind == tokWord {
+		a.result.StatementType = t.upper
+	} else {
+		a.result.StatementType = "UNKNOWN"
+	}
+}
+
+// skipParens returns the index just past the parentheses opening at i
+func (a *sqlAnalyzer) skipParens(i int) int {
+	depth := 0
+	for ; i < len(a.toks); i++ {
+		if a.toks[i].isPunct("(") {
+			depth++
+		} else if a.toks[i].isPunct(")") {
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// matchClause recognizes a clause keyword sequence at i
+// Returns the clause name and the number of tokens it spans
+func (a *sqlAnalyzer) matchClause(i int) (string, int) {
+	t := a.tok(i)
+	next := a.tok(i + 1)
+	stmt := a.result.StatementType
+
+	switch t.upper {
+	case "SELECT", "WHERE", "HAVING", "WINDOW", "LIMIT", "OFFSET", "RETURNING",
+		"VALUES", "DELETE", "MERGE", "INSERT":
+		return t.upper, 1
+	case "WITH":
+		if i == 0 {
+			return "WITH", 1
+		}
+	case "FROM":
+		// IS [NOT] DISTINCT FROM is an operator
+		if !a.tok(i - 1).isKeyword("DISTINCT") {
+			return "FROM", 1
+		}
+	case "GROUP", "ORDER":
+		if next.isKeyword("BY") {
+			return t.upper + " BY", 2
+		}
+	case "UNION", "INTERSECT", "EXCEPT":
+		if next.isKeyword("ALL", "DISTINCT") {
+			return t.upper, 2
+		}
+		return t.upper, 1
+	case "JOIN":
+		return "JOIN", 1
+	case "INNER", "CROSS", "LEFT", "RIGHT", "FULL", "NATURAL":
+		n := 1
+		kind := t.upper
+		if t.upper == "NATURAL" && a.tok(i+n).isKeyword("LEFT", "RIGHT", "FULL", "INNER") {
+			n++
+		}
+		if a.tok(i + n).isKeyword("OUTER") {
+			n++
+		}
+		if a.tok(i + n).isKeyword("JOIN") {
+			return kind + " JOIN", n + 1
+		}
+	case "ON":
+		if next.isKeyword("CONFLICT") {
+			return "ON CONFLICT", 2
+		}
+		return "ON", 1
+	case "USING":
+		return "USING", 1
+	case "DO":
+		if next.isKeyword("UPDATE", "NOTHING") {
+			return "DO " + next.upper, 2
+		}
+	case "FOR":
+		// Row locking clauses
+		switch {
+		case next.isKeyword("UPDATE", "SHARE"):
+			return "FOR " + next.upper, 2
+		case next.isKeyword("NO") && a.tok(i+2).isKeyword("KEY"):
+			return "FOR NO KEY UPDATE", 4
+		case next.isKeyword("KEY") && a.tok(i+2).isKeyword("SHARE"):
+			return "FOR KEY SHARE", 3
+		}
+	case "FETCH":
+		if next.isKeyword("FIRST", "NEXT") {
+			return "FETCH", 1
+		}
+	case "UPDATE":
+		if i == a.mainIndex {
+			return "UPDATE", 1
+		}
+	case "INTO":
+		if stmt == "INSERT" || stmt == "MERGE" {
+			return "INTO", 1
+		}
+	case "SET":
+		if stmt == "UPDATE" || stmt == "INSERT" || stmt == "MERGE" {
+			return "SET", 1
+		}
+	}
+	return "", 0
+}
+
+// isTableClause reports whether a clause is followed by table references
+func isTableClause(clause string) bool {
+	switch clause {
+	case "FROM", "UPDATE", "INTO", "USING":
+		return true
+	}
+	return strings.HasSuffix(clause, "JOIN")
+}
+
+// walk analyzes a DML statement
+func (a *sqlAnalyzer) walk(root *sqlScope) {
+	frames := []*sqlFrame{{query: true, scope: root}}
+
+	for i := 0; i < len(a.toks); i++ {
+		t := a.toks[i]
+		f := frames[len(frames)-1]
+
+		switch {
+		case t.isPunct("("):
+			nf := &sqlFrame{clause: f.clause, scope: f.scope}
+			if a.tok(i+1).isKeyword("SELECT", "WITH", "VALUES") {
+				nf.query = true
+				nf.clause = ""
+				nf.scope = a.newScope(f.scope)
+				if f.query && isTableClause(f.clause) {
+					nf.source = &sqlSource{Kind: sourceDerived, Name: "subquery"}
+				}
+			} else if a.pending != nil {
+				nf.source = a.pending
+				a.pending = nil
+			} else if prev := a.tok(i - 1); prev.kind == tokWord && prev.isIdent() {
+				nf.function = prev.value
+			}
+			frames = append(frames, nf)
+			continue
+
+		case t.isPunct(")"):
+			if len(frames) == 1 {
+				continue
+			}
+			closed := f
+			frames = frames[:len(frames)-1]
+			if closed.source != nil {
+				i = a.parseAlias(i+1, closed.source) - 1
+				parent := frames[len(frames)-1]
+				parent.scope.sources = append(parent.scope.sources, closed.source)
+				a.result.Sources = append(a.result.Sources, closed.source)
+			}
+			continue
+
+		case t.isPunct(","):
+			if f.query && f.clause == "FROM" {
+				f.scope.commaJoin = true
+				i = a.parseTableRef(i+1, f) - 1
+			}
+			continue
+
+		case t.isPunct("*"):
+			if f.query && f.clause == "SELECT" {
+				if prev := a.tok(i - 1); prev.isKeyword("SELECT", "DISTINCT", "ALL") || prev.isPunct(",") {
+					f.scope.star = true
+				}
+			}
+			continue
+		}
+
+		if t.kind == tokWord && f.query {
+			if clause, n := a.matchClause(i); clause != "" {
+				a.enterClause(f, clause, len(frames) == 1)
+				i += n - 1
+				if isTableClause(clause) {
+					i = a.parseTableRef(i+1, f) - 1
+				}
+				continue
+			}
+		}
+
+		a.checkToken(i, f)
+
+		if t.isIdent() {
+			i = a.parseColumnRef(i, f)
+		}
+	}
+}
+
+// enterClause updates the frame state for a new clause
+func (a *sqlAnalyzer) enterClause(f *sqlFrame, clause string, topLevel bool) {
+	switch clause {
+	case "UNION", "INTERSECT", "EXCEPT":
+		// Each branch of a set operation has its own FROM list
+		f.scope = a.newScope(f.scope.parent)
+	case "WHERE":
+		f.scope.hasWhere = true
+	}
+	f.clause = clause
+	if topLevel {
+		a.addClause(clause)
+	}
+}
+
+// parseTableRef parses a table reference and its alias starting at i
+// Subqueries and function calls are completed when their parentheses close
+func (a *sqlAnalyzer) parseTableRef(i int, f *sqlFrame) int {
+	for a.tok(i).isKeyword("ONLY", "LATERAL") {
+		i++
+	}
+	if !a.tok(i).isIdent() {
+		return i
+	}
+
+	parts := []string{a.tok(i).value}
+	i++
+	for a.tok(i).isPunct(".") && (a.tok(i+1).kind == tokWord || a.tok(i+1).kind == tokQuotedIdent) {
+		parts = append(parts, a.tok(i+1).value)
+		i += 2
+	}
+
+	src := &sqlSource{Name: parts[len(parts)-1]}
+	if len(parts) > 1 {
+		src.Schema = parts[len(parts)-2]
+	}
+
+	if a.tok(i).isPunct("(") {
+		// Set-returning function such as generate_series(); the alias follows the arguments
+		src.Kind = sourceFunction
+		src.Name = strings.Join(parts, ".")
+		src.Schema = ""
+		a.pending = src
+		return i
+	}
+
+	if a.tok(i).isPunct("*") {
+		i++
+	}
+
+	a.resolveSource(src)
+	switch {
+	case f.clause == "INTO" && a.result.StatementType == "INSERT":
+		src.target = true
+	case f.clause != "TABLE":
+		i = a.parseAlias(i, src)
+	}
+	f.scope.sources = append(f.scope.sources, src)
+	a.result.Sources = append(a.result.Sources, src)
+	return i
+}
+
+// parseAlias parses an optional alias and column alias list at i
+func (a *sqlAnalyzer) parseAlias(i int, src *sqlSource) int {
+	if a.tok(i).isKeyword("AS") {
+		i++
+	}
+	if !a.tok(i).isIdent() {
+		return i
+	}
+	src.Alias = a.tok(i).value
+	i++
+
+	// Column aliases: AS t(a, b)
+	if a.tok(i).isPunct("(") {
+		end := a.skipParens(i)
+		for j := i + 1; j < end-1; j++ {
+			if a.tok(j).isIdent() {
+				a.aliases[a.tok(j).value] = true
+			}
+		}
+		i = end
+	}
+	return i
+}
+
+// resolveSource looks up a table reference in the schema metadata
+func (a *sqlAnalyzer) resolveSource(src *sqlSource) {
+	if src.Schema == "" && a.ctes[src.Name] {
+		src.Kind = sourceCTE
+		return
+	}
+	if src.Schema == "pg_catalog" || src.Schema == "information_schema" ||
+		(src.Schema == "" && strings.HasPrefix(src.Name, "pg_")) {
+		src.Kind = sourceSystem
+		return
+	}
+
+	if src.Schema != "" {
+		if table, ok := a.metadata[src.Schema+"."+src.Name]; ok {
+			src.Kind = sourceTable
+			src.Table = &table
+			return
+		}
+		src.Kind = sourceUnknown
+		a.addUnresolved("Table %s.%s was not found in the schema metadata", src.Schema, src.Name)
+		return
+	}
+
+	// Unqualified names resolve through the default search path (public first)
+	if table, ok := a.metadata["public."+src.Name]; ok {
+		src.Kind = sourceTable
+		src.Table = &table
+		return
+	}
+
+	var schemas []string
+	var match database.TableInfo
+	for _, table := range a.metadata {
+		if table.TableName == src.Name {
+			schemas = append(schemas, table.SchemaName)
+			match = table
+		}
+	}
+	switch len(schemas) {
+	case 0:
+		src.Kind = sourceUnknown
+		a.addUnresolved("Table %s was not found in the schema metadata", src.Name)
+	case 1:
+		src.Kind = sourceTable
+		src.Table = &match
+		if match.SchemaName != "public" {
+			a.addIssue("Table %s is in schema %s; qualify it as %s.%s unless that schema is on the search_path",
+				src.Name, match.SchemaName, match.SchemaName, src.Name)
+		}
+	default:
+		sort.Strings(schemas)
+		src.Kind = sourceUnknown
+		a.addIssue("Table %s exists in several schemas (%s); qualify it with the intended schema",
+			src.Name, strings.Join(schemas, ", "))
+	}
+}
+
+// parseColumnRef records a column reference starting at i and returns the
+// index of its last token
+func (a *sqlAnalyzer) parseColumnRef(i int, f *sqlFrame) int {
+	t := a.tok(i)
+	prev := a.tok(i - 1)
+	next := a.tok(i + 1)
+
+	if prev.isPunct(".") || f.clause == "WITH" {
+		// CTE names and column lists are not column references
+		return i
+	}
+
+	ref := &sqlColumnRef{Clause: f.clause, Function: f.function, scope: f.scope}
+
+	// Qualified reference: alias.column, schema.table.column or alias.*
+	if next.isPunct(".") {
+		parts := []string{t.value}
+		j := i + 1
+		for a.tok(j).isPunct(".") {
+			n := a.tok(j + 1)
+			if n.kind != tokWord && n.kind != tokQuotedIdent && !n.isPunct("*") {
+				break
+			}
+			parts = append(parts, n.value)
+			j += 2
+		}
+		if len(parts) < 2 || a.tok(j).isPunct("(") {
+			// Schema-qualified function call
+			return j - 1
+		}
+		ref.Qualifier = strings.Join(parts[:len(parts)-1], ".")
+		ref.Name = parts[len(parts)-1]
+		a.result.Columns = append(a.result.Columns, ref)
+		return j - 1
+	}
+
+	switch {
+	case next.isPunct("("):
+		return i // Function call
+	case prev.isPunct("::"):
+		return i // Type name
+	case next.kind == tokString:
+		return i // Typed literal such as date '2025-01-01'
+	case f.function == "extract" && prev.isPunct("("):
+		return i // Date field
+	case prev.isKeyword("AS"):
+		if f.query && f.clause == "SELECT" {
+			a.aliases[t.value] = true
+		}
+		return i
+	case f.query && f.clause == "SELECT" && endsExpression(prev):
+		// Implicit output alias: SELECT count(*) total
+		a.aliases[t.value] = true
+		return i
+	}
+
+	ref.Name = t.value
+	a.result.Columns = append(a.result.Columns, ref)
+	return i
+}
+
+// endsExpression reports whether a token can end a select-list expression
+func endsExpression(t sqlToken) bool {
+	switch t.kind {
+	case tokNumber, tokString, tokQuotedIdent, tokParam:
+		return true
+	case tokPunct:
+		return t.value == ")"
+	}
+	return t.isIdent() || t.isKeyword("END", "NULL", "TRUE", "FALSE")
+}
+
+// checkToken looks for problematic patterns around token i
+func (a *sqlAnalyzer) checkToken(i int, f *sqlFrame) {
+	t := a.tok(i)
+	next := a.tok(i + 1)
+
+	switch {
+	case t.isKeyword("LIKE", "ILIKE") && next.kind == tokString &&
+		(strings.HasPrefix(next.value, "%") || strings.HasPrefix(next.value, "_")):
+		a.addIssue("Pattern '%s' starts with a wildcard, so a B-tree index cannot be used; consider a pg_trgm index", next.value)
+
+	case t.isKeyword("NOT") && next.isKeyword("IN") && a.tok(i+2).isPunct("(") && a.tok(i+3).isKeyword("SELECT"):
+		a.addIssue("NOT IN (SELECT ...) returns no rows if the subquery yields a NULL; NOT EXISTS is usually safer and faster")
+
+	case (t.isPunct("=") || t.isPunct("<>") || t.isPunct("!=")) && next.isKeyword("NULL"):
+		a.addIssue("Comparing with %s NULL is never true; use IS NULL or IS NOT NULL", t.value)
+
+	case f.clause == "ORDER BY" && t.kind == tokWord && t.value == "random" && next.isPunct("("):
+		a.addIssue("ORDER BY random() sorts the entire result; consider TABLESAMPLE for large tables")
+	}
+}
+
+// walkUtility finds the tables referenced by TRUNCATE, ALTER, DROP and CREATE INDEX
+func (a *sqlAnalyzer) walkUtility(root *sqlScope) {
+	f := &sqlFrame{query: true, clause: "TABLE", scope: root}
+	stmt := a.result.StatementType
+
+	start := -1
+	switch stmt {
+	case "TRUNCATE":
+		start = 1
+		if a.tok(start).isKeyword("TABLE") {
+			start++
+		}
+	case "ALTER", "DROP":
+		if a.tok(1).isKeyword("TABLE") {
+			start = 2
+		}
+	case "CREATE":
+		// CREATE [UNIQUE] INDEX ... ON table
+		isIndex := false
+		for i := 1; i < len(a.toks); i++ {
+			if a.toks[i].isKeyword("INDEX") {
+				isIndex = true
+			}
+			if isIndex && a.toks[i].isKeyword("ON") {
+				start = i + 1
+				break
+			}
+			if a.toks[i].isPunct("(") {
+				break
+			}
+		}
+	}
+	if start < 0 {
+		return
+	}
+
+	i := start
+	if a.tok(i).isKeyword("IF") {
+		i++
+		if a.tok(i).isKeyword("NOT") {
+			i++
+		}
+		if a.tok(i).isKeyword("EXISTS") {
+			i++
+		}
+	}
+	for {
+		before := len(root.sources)
+		i = a.parseTableRef(i, f)
+		if len(root.sources) == before || !a.tok(i).isPunct(",") {
+			break
+		}
+		i++
+	}
+}
+
+// lookupQualifier finds the source a qualifier refers to
+func lookupQualifier(scope *sqlScope, qualifier string) *sqlSource {
+	for s := scope; s != nil; s = s.parent {
+		for _, src := range s.sources {
+			if src.Alias != "" {
+				if src.Alias == qualifier {
+					return src
+				}
+				continue
+			}
+			if src.Name == qualifier || (src.Schema != "" && src.Schema+"."+src.Name == qualifier) {
+				return src
+			}
+			if src.Table != nil && src.Table.SchemaName+"."+src.Table.TableName == qualifier {
+				return src
+			}
+		}
+	}
+	return nil
+}
+
+// findColumn returns the named column of a table
+func findColumn(table *database.TableInfo, name string) *database.ColumnInfo {
+	for i := range table.Columns {
+		if table.Columns[i].ColumnName == name {
+			return &table.Columns[i]
+		}
+	}
+	return nil
+}
+
+// resolveColumns matches column references to the referenced tables
+func (a *sqlAnalyzer) resolveColumns() {
+	for _, ref := range a.result.Columns {
+		if ref.Qualifier != "" {
+			a.resolveQualified(ref)
+		} else {
+			a.resolveUnqualified(ref)
+		}
+	}
+
+	for _, scope := range a.scopes {
+		if !scope.star {
+			continue
+		}
+		for _, src := range scope.sources {
+			if !src.target {
+				src.star = true
+			}
+		}
+	}
+}
+
+func (a *sqlAnalyzer) resolveQualified(ref *sqlColumnRef) {
+	src := lookupQualifier(ref.scope, ref.Qualifier)
+	if src == nil {
+		// EXCLUDED is the proposed row in INSERT ... ON CONFLICT
+		if ref.Qualifier != "excluded" {
+			a.addUnresolved("%s.%s refers to %s, which is not a table or alias in the FROM clause",
+				ref.Qualifier, ref.Name, ref.Qualifier)
+		}
+		return
+	}
+	ref.Source = src
+	if src.Table == nil {
+		return
+	}
+	if ref.Name == "*" {
+		src.star = true
+		return
+	}
+	if col := findColumn(src.Table, ref.Name); col != nil {
+		ref.Column = col
+		return
+	}
+	a.addUnresolved("Column %s does not exist in %s", ref.Name, src.displayName())
+}
+
+func (a *sqlAnalyzer) resolveUnqualified(ref *sqlColumnRef) {
+	if a.aliases[ref.Name] {
+		return
+	}
+
+	targetOnly := targetClauses[ref.Clause] && a.result.StatementType == "INSERT"
+	visible := 0
+	for s := ref.scope; s != nil; s = s.parent {
+		var matches []*sqlSource
+		opaque := false
+		for _, src := range s.sources {
+			if src.target != targetOnly {
+				continue
+			}
+			visible++
+			if src.Table == nil {
+				opaque = true
+				continue
+			}
+			if findColumn(src.Table, ref.Name) != nil {
+				matches = append(matches, src)
+			}
+		}
+
+		switch {
+		case len(matches) == 1:
+			ref.Source = matches[0]
+			ref.Column = findColumn(matches[0].Table, ref.Name)
+			return
+		case len(matches) > 1:
+			names := make([]string, len(matches))
+			for i, m := range matches {
+				names[i] = m.displayName()
+			}
+			a.addUnresolved("Column reference %s is ambiguous (it exists in %s); qualify it with a table alias",
+				ref.Name, strings.Join(names, " and "))
+			return
+		case opaque:
+			// The column may come from a CTE, subquery or function
+			return
+		}
+	}
+
+	if visible > 0 {
+		a.addUnresolved("Column %s was not found in any referenced table", ref.Name)
+	}
+}
+
+// checkStatement reports statement-level issues
+func (a *sqlAnalyzer) checkStatement(root *sqlScope) {
+	stmt := a.result.StatementType
+
+	if a.result.Statements > 1 {
+		a.addIssue("Input contains %d statements; only the first was analyzed", a.result.Statements)
+	}
+
+	if (stmt == "UPDATE" || stmt == "DELETE") && !root.hasWhere {
+		target := "the table"
+		for _, src := range root.sources {
+			target = src.displayName()
+			break
+		}
+		a.addIssue("%s has no WHERE clause and will affect every row in %s", stmt, target)
+	}
+
+	if (a.hasClause("LIMIT") || a.hasClause("FETCH")) && !a.hasClause("ORDER BY") {
+		a.addIssue("LIMIT without ORDER BY returns an arbitrary subset of rows")
+	}
+
+	for _, scope := range a.scopes {
+		if scope.commaJoin && !scope.hasWhere {
+			a.addIssue("Tables are listed with commas but there is no WHERE clause, producing a Cartesian product")
+		}
+	}
+
+	var starTables []string
+	var unindexed []string
+	seen := make(map[string]bool)
+	for _, src := range a.result.Sources {
+		if src.star && src.Table != nil && !seen["*"+src.displayName()] {
+			seen["*"+src.displayName()] = true
+			starTables = append(starTables, fmt.Sprintf("%s (%d columns)", src.displayName(), len(src.Table.Columns)))
+		}
+	}
+	if len(starTables) > 0 {
+		a.addIssue("SELECT * returns every column of %s; list only the columns you need", strings.Join(starTables, ", "))
+	}
+
+	for _, ref := range a.result.Columns {
+		if ref.Column == nil || (ref.Clause != "WHERE" && ref.Clause != "ON") {
+			continue
+		}
+		name := ref.Source.displayName() + "." + ref.Column.ColumnName
+		if ref.Function != "" && ref.Column.IsIndexed {
+			a.addIssue("%s is wrapped in %s() in a filter; its index is only used if a matching expression index exists",
+				name, ref.Function)
+			continue
+		}
+		if !ref.Column.IsIndexed && !ref.Column.IsPrimaryKey && !seen[name] {
+			seen[name] = true
+			unindexed = append(unindexed, name)
+		}
+	}
+	if len(unindexed) > 0 {
+		a.addIssue("Filter or join columns without an index: %s (large tables may need a sequential scan)",
+			strings.Join(unindexed, ", "))
+	}
+}
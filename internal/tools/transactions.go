@@ -0,0 +1,341 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// DefaultTransactionIdleTimeout is how long a transaction opened with
+// begin_transaction may go without a statement before it is rolled back
+const DefaultTransactionIdleTimeout = 5 * time.Minute
+
+// errTransactionInProgress is returned when a conversation begins a second
+// transaction
+var errTransactionInProgress = errors.New("a transaction is already in progress; commit or roll it back first")
+
+// openTransaction is a transaction pinned to a conversation's connection
+type openTransaction struct {
+	mu         sync.Mutex // Serializes statements; the connection is not safe for concurrent use
+	tx         pgx.Tx
+	client     *database.Client
+	connStr    string
+	readWrite  bool
+	started    time.Time
+	lastUsed   time.Time
+	statements int
+	done       chan struct{} // Closed when the transaction ends
+}
+
+// TransactionManager keeps the transactions opened with begin_transaction.
+// Each holds a connection for its conversation (the MCP session, else the
+// token) until it is committed or rolled back, or is rolled back when the
+// session ends or the transaction is idle too long.
+type TransactionManager struct {
+	mu           sync.Mutex
+	transactions map[string]*openTransaction // By conversation
+	idleTimeout  time.Duration
+}
+
+// NewTransactionManager creates a manager without open transactions
+func NewTransactionManager() *TransactionManager {
+	return &TransactionManager{
+		transactions: make(map[string]*openTransaction),
+		idleTimeout:  DefaultTransactionIdleTimeout,
+	}
+}
+
+// begin opens a transaction for the conversation of ctx
+func (m *TransactionManager) begin(ctx context.Context, client *database.Client, connStr string, readWrite bool) (*openTransaction, error) {
+	key := conversationKey(ctx)
+	if m.current(ctx) != nil {
+		return nil, errTransactionInProgress
+	}
+
+	pool := client.GetPoolFor(connStr)
+	if pool == nil {
+		return nil, fmt.Errorf("connection pool not found for: %s", database.SanitizeConnStr(connStr))
+	}
+	options := pgx.TxOptions{AccessMode: pgx.ReadOnly}
+	if readWrite {
+		options.AccessMode = pgx.ReadWrite
+	}
+	tx, err := pool.BeginTx(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	now := time.Now()
+	txn := &openTransaction{
+		tx:        tx,
+		client:    client,
+		connStr:   connStr,
+		readWrite: readWrite,
+		started:   now,
+		lastUsed:  now,
+		done:      make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.transactions[key]; exists {
+		// Another call began one meanwhile
+		_ = tx.Rollback(context.Background()) //nolint:errcheck // The connection is released either way
+		return nil, errTransactionInProgress
+	}
+	m.transactions[key] = txn
+	go m.watch(key, txn, mcp.SessionDoneFromContext(ctx))
+	return txn, nil
+}
+
+// current returns the conversation's open transaction, or nil
+func (m *TransactionManager) current(ctx context.Context) *openTransaction {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.transactions[conversationKey(ctx)]
+}
+
+// finish commits or rolls back the conversation's open transaction
+func (m *TransactionManager) finish(ctx context.Context, commit bool) (*openTransaction, error) {
+	key := conversationKey(ctx)
+	m.mu.Lock()
+	txn, exists := m.transactions[key]
+	if exists {
+		delete(m.transactions, key)
+	}
+	m.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("no transaction is in progress; start one with begin_transaction")
+	}
+
+	// Wait for a statement still running in the transaction
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	defer close(txn.done)
+
+	if commit {
+		if err := txn.tx.Commit(ctx); err != nil {
+			return txn, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return txn, nil
+	}
+	if err := txn.tx.Rollback(ctx); err != nil {
+		return txn, fmt.Errorf("failed to roll back transaction: %w", err)
+	}
+	return txn, nil
+}
+
+// abandon rolls back a transaction that is still open
+func (m *TransactionManager) abandon(key string, txn *openTransaction, reason string) {
+	m.mu.Lock()
+	if m.transactions[key] != txn {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.transactions, key)
+	m.mu.Unlock()
+
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	_ = txn.tx.Rollback(context.Background()) //nolint:errcheck // The connection is released either way
+	close(txn.done)
+	logging.Info("transaction_rolled_back", "reason", reason, "statements", txn.statements)
+}
+
+// watch rolls back a transaction when its session ends or it is idle for
+// longer than the idle timeout
+func (m *TransactionManager) watch(key string, txn *openTransaction, sessionDone <-chan struct{}) {
+	timer := time.NewTimer(m.idleTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-txn.done:
+			return
+		case <-sessionDone:
+			m.abandon(key, txn, "session ended")
+			return
+		case <-timer.C:
+			if idle := txn.idle(); idle < m.idleTimeout {
+				timer.Reset(m.idleTimeout - idle)
+				continue
+			}
+			m.abandon(key, txn, "idle timeout")
+			return
+		}
+	}
+}
+
+// RollbackAll rolls back every open transaction, releasing their
+// connections; it is called on shutdown before the pools are closed
+func (m *TransactionManager) RollbackAll() {
+	m.mu.Lock()
+	open := make(map[string]*openTransaction, len(m.transactions))
+	for key, txn := range m.transactions {
+		open[key] = txn
+	}
+	m.mu.Unlock()
+
+	for key, txn := range open {
+		m.abandon(key, txn, "shutdown")
+	}
+}
+
+// idle returns how long the transaction has gone without a statement
+func (txn *openTransaction) idle() time.Duration {
+	if !txn.mu.TryLock() {
+		return 0 // A statement is running
+	}
+	defer txn.mu.Unlock()
+	return time.Since(txn.lastUsed)
+}
+
+// savepoint starts a statement in the transaction; the caller must hold
+// txn.mu. A failed statement is rolled back to the savepoint, leaving the
+// transaction usable.
+func (txn *openTransaction) savepoint(ctx context.Context) (pgx.Tx, error) {
+	select {
+	case <-txn.done:
+		return nil, fmt.Errorf("the transaction has ended; start a new one with begin_transaction")
+	default:
+	}
+	txn.lastUsed = time.Now()
+	txn.statements++
+	return txn.tx.Begin(ctx)
+}
+
+// describe summarizes the transaction for tool output
+func (txn *openTransaction) describe() string {
+	mode := "read-only"
+	if txn.readWrite {
+		mode = "read-write"
+	}
+	return fmt.Sprintf("%s transaction on %s, %d statement(s), open for %s",
+		mode, database.SanitizeConnStr(txn.connStr), txn.statements, time.Since(txn.started).Round(time.Second))
+}
+
+// BeginTransactionTool creates the begin_transaction tool
+func BeginTransactionTool(dbClient *database.Client, transactions *TransactionManager) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "begin_transaction",
+			Description: `Start a transaction that later query_database calls in this session run in,
+on a connection held for the session, until commit_transaction or
+rollback_transaction.
+
+<usecase>
+Use when:
+- Several statements must succeed or fail together
+- The user wants to review the effect of changes before they are committed
+</usecase>
+
+<important>
+- Transactions are read-only unless read_write=true
+- Only query_database runs in the transaction; other tools do not see its
+  uncommitted changes
+- A failing statement is rolled back on its own; the transaction stays usable
+- Show the user what changed and ask before calling commit_transaction
+- The transaction is rolled back if it is idle for 5 minutes or the session ends
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"read_write": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Allow the transaction to modify data and schema (default: false)",
+						"default":     false,
+					},
+				},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+			readWrite := ValidateBoolParam(args, "read_write", false)
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+
+			txn, err := transactions.begin(ctx, dbClient, connStr, readWrite)
+			if err != nil {
+				return mcp.NewToolError(fmt.Sprintf("Failed to begin transaction: %v", err))
+			}
+			logging.Info("transaction_started", "read_write", readWrite)
+			return mcp.NewToolSuccess(fmt.Sprintf("Started a %s.\nquery_database calls now run in this transaction until commit_transaction or rollback_transaction.",
+				txn.describe()))
+		},
+	}
+}
+
+// CommitTransactionTool creates the commit_transaction tool
+func CommitTransactionTool(transactions *TransactionManager) Tool {
+	return finishTransactionTool(transactions, true)
+}
+
+// RollbackTransactionTool creates the rollback_transaction tool
+func RollbackTransactionTool(transactions *TransactionManager) Tool {
+	return finishTransactionTool(transactions, false)
+}
+
+// finishTransactionTool creates the commit_transaction or
+// rollback_transaction tool
+func finishTransactionTool(transactions *TransactionManager, commit bool) Tool {
+	name, verb, description := "rollback_transaction", "Rolled back", `Roll back the transaction started with begin_transaction, discarding
+everything it changed, and release its connection.`
+	if commit {
+		name, verb, description = "commit_transaction", "Committed", `Commit the transaction started with begin_transaction, making its changes
+permanent, and release its connection.
+
+<important>
+- Confirm with the user before committing changes to data or schema
+</important>`
+	}
+
+	return Tool{
+		Definition: mcp.Tool{
+			Name:        name,
+			Description: description,
+			InputSchema: mcp.InputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			ctx, ok := args["__context"].(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			txn, err := transactions.finish(ctx, commit)
+			if err != nil {
+				return mcp.NewToolError(err.Error())
+			}
+			logging.Info("transaction_finished", "commit", commit, "statements", txn.statements)
+			return mcp.NewToolSuccess(fmt.Sprintf("%s the %s.", verb, txn.describe()))
+		},
+	}
+}
@@ -0,0 +1,46 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTransactionTools_NoTransaction(t *testing.T) {
+	transactions := NewTransactionManager()
+	ctx := context.Background()
+
+	if txn := transactions.current(ctx); txn != nil {
+		t.Fatalf("expected no open transaction, got %+v", txn)
+	}
+	var none *TransactionManager
+	if txn := none.current(ctx); txn != nil {
+		t.Fatal("expected a nil manager to have no open transaction")
+	}
+
+	for _, tool := range []Tool{CommitTransactionTool(transactions), RollbackTransactionTool(transactions)} {
+		response, err := tool.Handler(map[string]interface{}{"__context": ctx})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tool.Definition.Name, err)
+		}
+		if !response.IsError {
+			t.Errorf("%s: expected an error without an open transaction", tool.Definition.Name)
+		}
+		if !strings.Contains(response.Content[0].Text, "begin_transaction") {
+			t.Errorf("%s: unexpected message %q", tool.Definition.Name, response.Content[0].Text)
+		}
+	}
+
+	// Nothing to roll back
+	transactions.RollbackAll()
+}
@@ -0,0 +1,403 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"pgedge-postgres-mcp/internal/masking"
+)
+
+// updatePreviewRows is the number of changed rows a dry run shows for each
+// UPDATE
+const updatePreviewRows = 10
+
+// updatePreviewValueRunes limits the length of a value shown in a row diff
+const updatePreviewValueRunes = 80
+
+// previewSavepoint protects the transaction from a failed row capture
+const previewSavepoint = "dry_run_preview"
+
+// updatePreview holds the changes of a sample of the rows an UPDATE changed
+type updatePreview struct {
+	Table  string // Target table as written in the statement
+	Rows   int64  // Rows the statement changed
+	Sample []updateRowDiff
+	Error  string // Why the changed values could not be captured
+}
+
+// updateRowDiff is one changed row: its key and the columns whose value changed
+type updateRowDiff struct {
+	Key     string // Primary key values, or the row's position in the sample
+	Changes []columnChange
+}
+
+// columnChange is the value of a column before and after an UPDATE
+type columnChange struct {
+	Column string
+	Before string
+	After  string
+}
+
+// updatePreviewQuery is an UPDATE rewritten to return its before and after
+// row images
+type updatePreviewQuery struct {
+	Table string // Target table as written, for looking up its primary key
+	SQL   string
+}
+
+// buildUpdatePreviewQuery rewrites an UPDATE so it returns the before and
+// after image of a sample of the rows it changes, and the number of rows it
+// changes. The target table is joined to a subquery over itself on each
+// row's physical location; the subquery sees the row as it was before the
+// statement. It only exposes dry_run_* columns, so the statement's own
+// unqualified column references stay unambiguous. Without a FROM list the
+// statement's WHERE clause is repeated in the subquery, so that it reads
+// only the rows the statement changes (a volatile condition, such as one
+// calling random(), may then select fewer rows). Returns false for statements that
+// are not a plain UPDATE.
+func buildUpdatePreviewQuery(sql string, limit int) (*updatePreviewQuery, bool) {
+	toks, err := tokenizeSQL(sql)
+	if err != nil || len(toks) == 0 || !toks[0].isKeyword("UPDATE") {
+		return nil, false
+	}
+
+	c := &ddlCursor{toks: toks, pos: 1}
+	only := c.accept("ONLY")
+	nameStart := c.pos
+	if _, _, ok := c.name(); !ok {
+		return nil, false
+	}
+	table := sql[toks[nameStart].start:toks[c.pos-1].end]
+	ref := sql[toks[c.pos-1].start:toks[c.pos-1].end]
+	if c.peek().isPunct("*") {
+		c.pos++
+	}
+	if c.accept("AS") || c.peek().isIdent() {
+		alias := c.peek()
+		if alias.kind != tokWord && alias.kind != tokQuotedIdent {
+			return nil, false
+		}
+		ref = sql[alias.start:alias.end]
+		c.pos++
+	}
+	if !c.peek().isKeyword("SET") {
+		return nil, false
+	}
+
+	from, where, returning := -1, -1, -1
+	depth := 0
+	for i := c.pos; i < len(toks); i++ {
+		t := toks[i]
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth != 0:
+		case t.isKeyword("FROM") && from < 0 && where < 0 && !toks[i-1].isKeyword("DISTINCT"):
+			from = i
+		case t.isKeyword("WHERE") && where < 0:
+			where = i
+		case t.isKeyword("RETURNING") && returning < 0:
+			returning = i
+		}
+	}
+	if where >= 0 && where+1 < len(toks) && toks[where+1].isKeyword("CURRENT") {
+		return nil, false
+	}
+
+	// Each clause ends where the next one starts
+	end := toks[len(toks)-1].end
+	clauseEnd := func(after ...int) int {
+		for _, i := range after {
+			if i >= 0 {
+				return toks[i].start
+			}
+		}
+		return end
+	}
+
+	condition := ""
+	if where >= 0 {
+		condition = strings.TrimSpace(sql[toks[where].end:clauseEnd(returning)])
+	}
+
+	var sb strings.Builder
+	sb.WriteString("WITH dry_run_update AS (")
+	sb.WriteString(strings.TrimSpace(sql[:clauseEnd(from, where, returning)]))
+	sb.WriteString(fmt.Sprintf(" FROM (SELECT %s.tableoid AS dry_run_tableoid, %s.ctid AS dry_run_ctid, row_to_json(%s)::text AS dry_run_row FROM ", ref, ref, ref))
+	if only {
+		sb.WriteString("ONLY ")
+	}
+	sb.WriteString(table + " AS " + ref)
+	if condition != "" && from < 0 {
+		sb.WriteString(" WHERE " + condition)
+	}
+	sb.WriteString(") AS dry_run_before")
+	if from >= 0 {
+		sb.WriteString(", " + strings.TrimSpace(sql[toks[from].end:clauseEnd(where, returning)]))
+	}
+	sb.WriteString(fmt.Sprintf(" WHERE %s.tableoid = dry_run_before.dry_run_tableoid AND %s.ctid = dry_run_before.dry_run_ctid", ref, ref))
+	if condition != "" {
+		sb.WriteString(" AND (" + condition + ")")
+	}
+	sb.WriteString(fmt.Sprintf(" RETURNING dry_run_before.dry_run_row AS before_row, row_to_json(%s)::text AS after_row)", ref))
+	sb.WriteString(fmt.Sprintf(" SELECT before_row, after_row, count(*) OVER () FROM dry_run_update LIMIT %d", limit))
+	return &updatePreviewQuery{Table: table, SQL: sb.String()}, true
+}
+
+// previewTx runs statements and queries; implemented by pgx.Tx
+type previewTx interface {
+	scriptExecer
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// scriptPreviewer is implemented by executors that capture the row changes
+// of the statements they run
+type scriptPreviewer interface {
+	takePreview() *updatePreview
+}
+
+// previewExecer runs the statements of a dry run, capturing the changed
+// values of a sample of the rows each UPDATE changes
+type previewExecer struct {
+	tx     previewTx
+	masker *masking.Masker // Masks the captured values (nil = disabled)
+	last   *updatePreview
+}
+
+func newPreviewExecer(tx previewTx, masker *masking.Masker) *previewExecer {
+	return &previewExecer{tx: tx, masker: masker}
+}
+
+// Exec runs a statement. UPDATEs run rewritten to return their row images;
+// if that fails, the statement runs unchanged so its own error is reported.
+func (p *previewExecer) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	p.last = nil
+	q, ok := buildUpdatePreviewQuery(sql, updatePreviewRows)
+	if !ok {
+		return p.tx.Exec(ctx, sql, arguments...)
+	}
+
+	if _, err := p.tx.Exec(ctx, "SAVEPOINT "+previewSavepoint); err != nil {
+		return pgconn.CommandTag{}, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	preview, err := queryUpdatePreview(ctx, p.tx, q, arguments, p.masker)
+	if err != nil {
+		if _, rbErr := p.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+previewSavepoint); rbErr != nil {
+			return pgconn.CommandTag{}, fmt.Errorf("failed to roll back to savepoint: %w", rbErr)
+		}
+		tag, execErr := p.tx.Exec(ctx, sql, arguments...)
+		if execErr == nil {
+			p.last = &updatePreview{Table: q.Table, Rows: tag.RowsAffected(), Error: err.Error()}
+		}
+		return tag, execErr
+	}
+	if _, err := p.tx.Exec(ctx, "RELEASE SAVEPOINT "+previewSavepoint); err != nil {
+		return pgconn.CommandTag{}, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	p.last = preview
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", preview.Rows)), nil
+}
+
+// takePreview returns the changes captured for the last statement
+func (p *previewExecer) takePreview() *updatePreview {
+	preview := p.last
+	p.last = nil
+	return preview
+}
+
+// queryUpdatePreview runs a rewritten UPDATE and diffs the row images it
+// returns, identifying rows by the table's primary key. Values are masked
+// like query results of the table.
+func queryUpdatePreview(ctx context.Context, tx previewTx, q *updatePreviewQuery, arguments []any, masker *masking.Masker) (*updatePreview, error) {
+	var table string
+	err := tx.QueryRow(ctx, `
+		SELECT n.nspname || '.' || c.relname
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = $1::regclass`, q.Table).Scan(&table)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT a.attname::text
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY (i.indkey)
+		WHERE i.indrelid = $1::regclass AND i.indisprimary
+		ORDER BY array_position(i.indkey::int2[], a.attnum)`, q.Table)
+	if err != nil {
+		return nil, err
+	}
+	key, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, q.SQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preview := &updatePreview{Table: q.Table}
+	for rows.Next() {
+		var before, after string
+		if err := rows.Scan(&before, &after, &preview.Rows); err != nil {
+			return nil, err
+		}
+		diff, err := diffRowImages(before, after, key, len(preview.Sample)+1, masker, table)
+		if err != nil {
+			return nil, err
+		}
+		preview.Sample = append(preview.Sample, diff)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// jsonField is a field of a JSON object, in the order it appears
+type jsonField struct {
+	Name  string
+	Value json.RawMessage
+}
+
+// decodeRowImage decodes the output of row_to_json, keeping the column order
+func decodeRowImage(image string) ([]jsonField, error) {
+	dec := json.NewDecoder(strings.NewReader(image))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("row image is not a JSON object")
+	}
+	var fields []jsonField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{Name: name, Value: value})
+	}
+	return fields, nil
+}
+
+// diffRowImages compares a row before and after an UPDATE. The row is
+// identified by its primary key values, or by its position in the sample if
+// the table has no primary key. Values are masked by the rules of table.
+func diffRowImages(before, after string, key []string, position int, masker *masking.Masker, table string) (updateRowDiff, error) {
+	oldFields, err := decodeRowImage(before)
+	if err != nil {
+		return updateRowDiff{}, err
+	}
+	newFields, err := decodeRowImage(after)
+	if err != nil {
+		return updateRowDiff{}, err
+	}
+	oldValues := make(map[string]json.RawMessage, len(oldFields))
+	for _, f := range oldFields {
+		oldValues[f.Name] = f.Value
+	}
+
+	diff := updateRowDiff{Key: fmt.Sprintf("#%d", position)}
+	if len(key) > 0 {
+		parts := make([]string, len(key))
+		for i, column := range key {
+			parts[i] = column + "=" + previewValue(oldValues[column], masker, masking.Column{Table: table, Name: column})
+		}
+		diff.Key = strings.Join(parts, ", ")
+	}
+	for _, f := range newFields {
+		old := oldValues[f.Name]
+		if bytes.Equal(old, f.Value) {
+			continue
+		}
+		column := masking.Column{Table: table, Name: f.Name}
+		diff.Changes = append(diff.Changes, columnChange{
+			Column: f.Name,
+			Before: previewValue(old, masker, column),
+			After:  previewValue(f.Value, masker, column),
+		})
+	}
+	return diff, nil
+}
+
+// previewValue formats a JSON value from a row image for display, masked
+// by the rules for its column
+func previewValue(raw json.RawMessage, masker *masking.Masker, column masking.Column) string {
+	var value string
+	if string(raw) == "null" || len(raw) == 0 {
+		return "NULL"
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		value = string(raw)
+	}
+	if masker.Enabled() {
+		rows := [][]interface{}{{value}}
+		masker.Apply([]masking.Column{column}, rows)
+		value = fmt.Sprint(rows[0][0])
+	}
+	runes := []rune(value)
+	if len(runes) > updatePreviewValueRunes {
+		return string(runes[:updatePreviewValueRunes-3]) + "..."
+	}
+	return value
+}
+
+// formatUpdatePreviews shows the changed values captured for the UPDATEs of
+// a dry run
+func formatUpdatePreviews(results []scriptStatement) string {
+	var sb strings.Builder
+	for i, result := range results {
+		preview := result.Preview
+		if preview == nil || result.Status != scriptOK {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\nStatement %d changes %d rows of %s", i+1, preview.Rows, preview.Table))
+		switch {
+		case preview.Error != "":
+			sb.WriteString(fmt.Sprintf("; the changed values could not be captured: %s\n", preview.Error))
+			continue
+		case len(preview.Sample) == 0:
+			sb.WriteString(".\n")
+			continue
+		case int64(len(preview.Sample)) < preview.Rows:
+			sb.WriteString(fmt.Sprintf(", showing %d:\n", len(preview.Sample)))
+		default:
+			sb.WriteString(":\n")
+		}
+		sb.WriteString(BuildTSVRow("row", "column", "before", "after"))
+		for _, diff := range preview.Sample {
+			if len(diff.Changes) == 0 {
+				sb.WriteString("\n" + BuildTSVRow(diff.Key, "(no values changed)", "", ""))
+			}
+			for _, change := range diff.Changes {
+				sb.WriteString("\n" + BuildTSVRow(diff.Key, change.Column, change.Before, change.After))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,236 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"pgedge-postgres-mcp/internal/config"
+	"pgedge-postgres-mcp/internal/masking"
+)
+
+func TestBuildUpdatePreviewQuery(t *testing.T) {
+	for sql, table := range map[string]string{
+		"UPDATE orders SET status = 'shipped'":                                              "orders",
+		`UPDATE ONLY sales."Orders" AS o SET total = total * 1.1 WHERE id = 5 RETURNING id`: `sales."Orders"`,
+	} {
+		q, ok := buildUpdatePreviewQuery(sql, 10)
+		if !ok {
+			t.Errorf("buildUpdatePreviewQuery(%q) should rewrite the statement", sql)
+			continue
+		}
+		if q.Table != table {
+			t.Errorf("Table = %q, want %q", q.Table, table)
+		}
+	}
+
+	for _, sql := range []string{
+		"DELETE FROM orders",
+		"WITH late AS (SELECT id FROM orders) UPDATE orders SET status = 'late' WHERE id IN (SELECT id FROM late)",
+		"UPDATE orders SET status = 'x' WHERE CURRENT OF order_cursor",
+	} {
+		if _, ok := buildUpdatePreviewQuery(sql, 10); ok {
+			t.Errorf("buildUpdatePreviewQuery(%q) should not rewrite the statement", sql)
+		}
+	}
+}
+
+// TestPreviewExecer_Database runs rewritten UPDATEs against PostgreSQL
+func TestPreviewExecer_Database(t *testing.T) {
+	connStr := os.Getenv("TEST_PGEDGE_POSTGRES_CONNECTION_STRING")
+	if connStr == "" {
+		t.Skip("TEST_PGEDGE_POSTGRES_CONNECTION_STRING not set, skipping integration test")
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer conn.Close(ctx)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // the test's changes are discarded
+
+	for _, sql := range []string{
+		"CREATE TEMP TABLE dry_run_orders (id int PRIMARY KEY, customer_id int, status text, total numeric, email text)",
+		"CREATE TEMP TABLE dry_run_customers (id int PRIMARY KEY, vip boolean)",
+		"INSERT INTO dry_run_customers VALUES (1, true), (2, false)",
+		"INSERT INTO dry_run_orders VALUES (1, 1, 'pending', 10.50, 'a@example.com'), (2, 2, 'pending', 20.00, 'b@example.com')",
+	} {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+
+	masker, err := masking.New(&config.MaskingConfig{Enabled: true, Rules: []config.MaskingRule{{Column: "^email$"}}})
+	if err != nil {
+		t.Fatalf("masking.New() error = %v", err)
+	}
+	exec := newPreviewExecer(tx, masker)
+
+	tests := []struct {
+		name string
+		sql  string
+		args []any
+		rows int64
+		want []columnChange
+	}{
+		{
+			name: "unqualified columns",
+			sql:  "UPDATE dry_run_orders SET total = total * 2 WHERE id = 1",
+			rows: 1,
+			want: []columnChange{{Column: "total", Before: "10.50", After: "21.00"}},
+		},
+		{
+			name: "alias and from list",
+			sql:  "UPDATE dry_run_orders o SET status = 'vip' FROM dry_run_customers c WHERE c.id = o.customer_id AND c.vip",
+			rows: 1,
+			want: []columnChange{{Column: "status", Before: "pending", After: "vip"}},
+		},
+		{
+			name: "masked column and parameter",
+			sql:  "UPDATE dry_run_orders SET email = 'new@example.com' WHERE id = $1",
+			args: []any{2},
+			rows: 1,
+			want: []columnChange{{Column: "email", Before: masking.DefaultReplacement, After: masking.DefaultReplacement}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := exec.Exec(ctx, tt.sql, tt.args...)
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			preview := exec.takePreview()
+			if preview == nil || preview.Error != "" {
+				t.Fatalf("expected the changes to be captured, got %+v", preview)
+			}
+			if tag.RowsAffected() != tt.rows || preview.Rows != tt.rows || len(preview.Sample) != 1 {
+				t.Fatalf("unexpected rows: tag %s, preview %+v", tag, preview)
+			}
+			changes := preview.Sample[0].Changes
+			if len(changes) != len(tt.want) {
+				t.Fatalf("Changes = %+v, want %+v", changes, tt.want)
+			}
+			for i := range tt.want {
+				if changes[i] != tt.want[i] {
+					t.Errorf("Changes[%d] = %+v, want %+v", i, changes[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	var status string
+	if err := tx.QueryRow(ctx, "SELECT status FROM dry_run_orders WHERE id = 1").Scan(&status); err != nil || status != "vip" {
+		t.Errorf("expected the UPDATE to be applied, got status %q (%v)", status, err)
+	}
+
+	// A failing UPDATE reports its own error
+	if _, err := exec.Exec(ctx, "UPDATE dry_run_orders SET missing = 1"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected the statement's own error, got %v", err)
+	}
+}
+
+func TestDiffRowImages(t *testing.T) {
+	diff, err := diffRowImages(
+		`{"id":17,"status":"pending","total":10.50,"tags":["a"],"note":null}`,
+		`{"id":17,"status":"shipped","total":10.50,"tags":["a","b"],"note":"`+strings.Repeat("x", 100)+`"}`,
+		[]string{"id"}, 1, nil, "")
+	if err != nil {
+		t.Fatalf("diffRowImages() error = %v", err)
+	}
+	if diff.Key != "id=17" {
+		t.Errorf("Key = %q, want id=17", diff.Key)
+	}
+	want := []columnChange{
+		{Column: "status", Before: "pending", After: "shipped"},
+		{Column: "tags", Before: `["a"]`, After: `["a","b"]`},
+		{Column: "note", Before: "NULL", After: strings.Repeat("x", 77) + "..."},
+	}
+	if len(diff.Changes) != len(want) {
+		t.Fatalf("Changes = %+v, want %+v", diff.Changes, want)
+	}
+	for i := range want {
+		if diff.Changes[i] != want[i] {
+			t.Errorf("Changes[%d] = %+v, want %+v", i, diff.Changes[i], want[i])
+		}
+	}
+
+	diff, err = diffRowImages(`{"a":1,"b":2}`, `{"a":1,"b":2}`, nil, 3, nil, "")
+	if err != nil {
+		t.Fatalf("diffRowImages() error = %v", err)
+	}
+	if diff.Key != "#3" || len(diff.Changes) != 0 {
+		t.Errorf("unexpected diff without a primary key: %+v", diff)
+	}
+
+	if _, err := diffRowImages(`[1]`, `{}`, nil, 1, nil, ""); err == nil {
+		t.Error("expected an error for a row image that is not an object")
+	}
+}
+
+// fakePreviewTx returns a preview for every UPDATE it runs
+type fakePreviewTx struct {
+	fakeScriptTx
+	last *updatePreview
+}
+
+func (f *fakePreviewTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	f.last = nil
+	if strings.HasPrefix(sql, "UPDATE") {
+		f.last = &updatePreview{Table: "orders", Rows: 2, Sample: []updateRowDiff{
+			{Key: "id=1", Changes: []columnChange{{Column: "status", Before: "pending", After: "shipped"}}},
+		}}
+	}
+	return f.fakeScriptTx.Exec(ctx, sql, arguments...)
+}
+
+func (f *fakePreviewTx) takePreview() *updatePreview {
+	return f.last
+}
+
+func TestRunScript_DryRunPreview(t *testing.T) {
+	tx := &fakePreviewTx{}
+	results, aborted := runScript(context.Background(), tx, []string{
+		"UPDATE orders SET status = 'shipped'",
+		"DELETE FROM orders",
+	}, false)
+	if aborted {
+		t.Fatal("expected the script to succeed")
+	}
+	if results[0].Preview == nil || results[1].Preview != nil {
+		t.Fatalf("expected only the UPDATE to have a preview: %+v", results)
+	}
+
+	output := formatUpdatePreviews(results) + formatDryRunOutcome(results, aborted)
+	for _, want := range []string{
+		"Statement 1 changes 2 rows of orders, showing 1:\nrow\tcolumn\tbefore\tafter\nid=1\tstatus\tpending\tshipped\n",
+		"Dry run rolled back: no changes were applied (2 of 2 statements succeeded)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	output = formatUpdatePreviews([]scriptStatement{{Status: scriptOK, Preview: &updatePreview{Table: "orders", Rows: 4, Error: "cannot capture rows of a view"}}})
+	if !strings.Contains(output, "Statement 1 changes 4 rows of orders; the changed values could not be captured: cannot capture rows of a view") {
+		t.Errorf("unexpected output for a failed capture:\n%s", output)
+	}
+}
@@ -0,0 +1,97 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"sync"
+)
+
+// toolUsageKey is the context key for per-call usage accounting
+type toolUsageKey struct{}
+
+// ToolUsage accumulates usage reported by a tool handler during a single call
+// It is used for quota enforcement (e.g. rows returned per token) and for
+// the size metadata of tool responses
+type ToolUsage struct {
+	mu           sync.Mutex
+	rows         int
+	rowsReported bool
+	truncated    bool
+}
+
+// Rows returns the number of rows the tool reported returning
+func (u *ToolUsage) Rows() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.rows
+}
+
+// ReportedRows returns the number of rows returned, and whether the tool
+// returns rows at all
+func (u *ToolUsage) ReportedRows() (int, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.rows, u.rowsReported
+}
+
+// Truncated returns whether the tool reported leaving out results
+func (u *ToolUsage) Truncated() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.truncated
+}
+
+// usageFromArgs returns the usage accumulator of a handler's call, if any
+// args is the handler's argument map; the context is injected by Registry.Execute
+func usageFromArgs(args map[string]interface{}) *ToolUsage {
+	ctx, ok := args["__context"].(context.Context)
+	if !ok || ctx == nil {
+		return nil
+	}
+	return usageFromContext(ctx)
+}
+
+// usageFromContext returns the usage accumulator carried by ctx, if any
+func usageFromContext(ctx context.Context) *ToolUsage {
+	usage, _ := ctx.Value(toolUsageKey{}).(*ToolUsage)
+	return usage
+}
+
+// WithToolUsage returns a context carrying a fresh usage accumulator
+func WithToolUsage(ctx context.Context) (context.Context, *ToolUsage) {
+	usage := &ToolUsage{}
+	return context.WithValue(ctx, toolUsageKey{}, usage), usage
+}
+
+// recordRowsReturned reports rows returned by a tool handler
+func recordRowsReturned(args map[string]interface{}, rows int) {
+	usage := usageFromArgs(args)
+	if usage == nil {
+		return
+	}
+	usage.mu.Lock()
+	usage.rows += rows
+	usage.rowsReported = true
+	usage.mu.Unlock()
+}
+
+// recordTruncated reports that a tool handler left out results, for example
+// rows beyond the query's limit
+func recordTruncated(args map[string]interface{}) {
+	usage := usageFromArgs(args)
+	if usage == nil {
+		return
+	}
+	usage.mu.Lock()
+	usage.truncated = true
+	usage.mu.Unlock()
+}
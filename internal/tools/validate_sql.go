@@ -0,0 +1,352 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/database"
+	"pgedge-postgres-mcp/internal/logging"
+	"pgedge-postgres-mcp/internal/mcp"
+)
+
+// ValidateSQLTool creates the validate_sql tool, which checks SQL before it
+// is run: it is parsed, referenced objects are looked up, statements that
+// can be are planned by the server without running them, and dangerous
+// patterns are flagged
+func ValidateSQLTool(dbClient *database.Client) Tool {
+	return Tool{
+		Definition: mcp.Tool{
+			Name: "validate_sql",
+			Description: `Check SQL you generated before running it, without executing it.
+
+<usecase>
+Use before query_database, execute_script or apply_migration when:
+- You wrote a statement against tables you have not queried yet
+- The statement changes data or schema
+- A previous attempt failed and you rewrote the SQL
+</usecase>
+
+<what_it_checks>
+- The SQL parses; each statement of a script is checked on its own
+- Tables and columns exist (DDL is checked against the schema as earlier
+  statements of the input would leave it)
+- SELECT, INSERT, UPDATE, DELETE and MERGE are planned by the server with
+  EXPLAIN (without ANALYZE), which catches anything the server would reject
+  before running the statement
+- Dangerous patterns: UPDATE or DELETE without WHERE, DROP or TRUNCATE with
+  CASCADE, dropped tables, schemas, databases and columns, TRUNCATE
+</what_it_checks>
+
+<important>
+- Nothing is executed; EXPLAIN runs in a read-only transaction that is rolled back
+- Fix every PROBLEM before running the SQL; show the user every WARNING and
+  confirm the statement is intended
+- Objects created by earlier statements of the input do not exist yet when
+  later statements are planned by the server
+</important>`,
+			InputSchema: mcp.InputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "The SQL statement or script to validate",
+					},
+				},
+				Required: []string{"query"},
+			},
+		},
+		Handler: func(args map[string]interface{}) (mcp.ToolResponse, error) {
+			query, errResp := ValidateStringParam(args, "query")
+			if errResp != nil {
+				return *errResp, nil
+			}
+
+			connStr := dbClient.GetDefaultConnection()
+			if !dbClient.IsMetadataLoadedFor(connStr) {
+				return mcp.NewToolError(mcp.DatabaseNotReadyError)
+			}
+
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("Database: %s\n\n", database.SanitizeConnStr(connStr)))
+
+			validations, err := validateSQL(query, dbClient.GetMetadataFor(connStr))
+			if err != nil {
+				sb.WriteString(fmt.Sprintf("PROBLEM: Could not parse SQL: %v\n\nResult: INVALID. Nothing was executed.\n", err))
+				return mcp.NewToolSuccess(sb.String())
+			}
+			serverNote := explainValidations(context.Background(), dbClient.GetPoolFor(connStr), validations)
+
+			sb.WriteString(formatValidations(validations))
+			if serverNote != "" {
+				sb.WriteString("\nNote: " + serverNote + "\n")
+			}
+
+			problems, warnings := 0, 0
+			for _, v := range validations {
+				problems += len(v.Problems)
+				warnings += len(v.Warnings)
+			}
+			logging.Info("validate_sql_executed",
+				"query_length", len(query),
+				"statements", len(validations),
+				"problems", problems,
+				"warnings", warnings,
+			)
+			return mcp.NewToolSuccess(sb.String())
+		},
+	}
+}
+
+// sqlValidation is the result of validating one statement
+type sqlValidation struct {
+	SQL         string
+	Type        string   // Statement type, such as "DELETE"
+	Explainable bool     // The server can plan it without running it
+	Plan        string   // Summary of the server's plan
+	Problems    []string // Reasons the statement is expected to fail
+	Warnings    []string // Dangerous patterns to confirm with the user
+
+	afterDDL bool // An earlier statement of the input changes the schema
+}
+
+// explainableStatements can be planned with EXPLAIN without running them
+var explainableStatements = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true,
+	"MERGE": true, "VALUES": true, "WITH": true, "TABLE": true,
+}
+
+// validateSQL parses the statements of query and checks them against the
+// schema metadata. DDL is planned in order, so later statements see the
+// tables created or dropped by earlier ones.
+func validateSQL(query string, metadata map[string]database.TableInfo) ([]*sqlValidation, error) {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return nil, err
+	}
+	statements := splitStatements(tokens)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no SQL statement found")
+	}
+
+	planner := &schemaPlanner{
+		metadata: metadata,
+		plan:     &schemaPlan{},
+		tables:   make(map[string]bool),
+		columns:  make(map[string]bool),
+	}
+	afterDDL := false
+	validations := make([]*sqlValidation, 0, len(statements))
+	for _, toks := range statements {
+		text := query[toks[0].start:toks[len(toks)-1].end]
+		v := &sqlValidation{SQL: text, Type: toks[0].upper, afterDDL: afterDDL}
+		if toks[0].isPunct("(") {
+			v.Type = "SELECT"
+		}
+
+		switch {
+		case explainableStatements[v.Type]:
+			v.Explainable = true
+			analysis, err := analyzeSQL(text, metadata)
+			if err != nil {
+				return nil, err
+			}
+			v.Type = analysis.StatementType
+			v.Problems = analysis.Unresolved
+		case v.Type == "CREATE", v.Type == "ALTER", v.Type == "DROP", v.Type == "TRUNCATE":
+			afterDDL = true
+			for _, change := range planner.planStatement(toks) {
+				v.Problems = append(v.Problems, change.Errors...)
+			}
+		}
+		v.Warnings = dangerousPatterns(toks)
+		validations = append(validations, v)
+	}
+	return validations, nil
+}
+
+// dangerousPatterns flags statements that remove data or objects, or
+// change more than they may appear to
+func dangerousPatterns(toks []sqlToken) []string {
+	var warnings []string
+	c := &ddlCursor{toks: toks, pos: 1}
+	first := toks[0].upper
+
+	switch first {
+	case "UPDATE", "DELETE":
+		if first == "DELETE" {
+			c.accept("FROM")
+		}
+		c.accept("ONLY")
+		target := "the table"
+		if schema, name, ok := c.name(); ok {
+			target = qualify(schema, name)
+		}
+		if !containsKeyword(toks, "WHERE") {
+			verb := "changes"
+			if first == "DELETE" {
+				verb = "removes"
+			}
+			warnings = append(warnings, fmt.Sprintf("%s has no WHERE clause and %s every row of %s", first, verb, target))
+		}
+
+	case "DROP":
+		c.accept("MATERIALIZED")
+		object := c.peek().upper
+		switch object {
+		case "TABLE", "SCHEMA", "DATABASE":
+			warnings = append(warnings, fmt.Sprintf("DROP %s permanently removes the %s and all of its data", object, strings.ToLower(object)))
+		}
+		if containsKeyword(toks, "CASCADE") {
+			warnings = append(warnings, "CASCADE also drops every object that depends on the dropped objects, such as views and foreign keys")
+		}
+
+	case "TRUNCATE":
+		warnings = append(warnings, "TRUNCATE removes every row of the table")
+		if containsKeyword(toks, "CASCADE") {
+			warnings = append(warnings, "CASCADE also empties every table with a foreign key referencing the truncated tables")
+		}
+
+	case "ALTER":
+		for _, part := range splitTopLevel(toks) {
+			for i := 0; i+1 < len(part); i++ {
+				if part[i].isKeyword("DROP") && part[i+1].isKeyword("COLUMN") {
+					warnings = append(warnings, "DROP COLUMN permanently removes the column and its data")
+					break
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// explainPlan is the part of EXPLAIN (FORMAT JSON) output summarized by
+// validate_sql
+type explainPlan struct {
+	Plan struct {
+		NodeType     string  `json:"Node Type"`
+		Operation    string  `json:"Operation"`
+		RelationName string  `json:"Relation Name"`
+		TotalCost    float64 `json:"Total Cost"`
+		PlanRows     float64 `json:"Plan Rows"`
+	} `json:"Plan"`
+}
+
+// explainValidations plans the explainable statements with EXPLAIN in a
+// read-only transaction that is rolled back. A statement the server plans
+// has no unresolved references, so the local lookups are replaced by the
+// server's result. Returns a note when the server could not be used.
+func explainValidations(ctx context.Context, pool *pgxpool.Pool, validations []*sqlValidation) string {
+	explainable := false
+	for _, v := range validations {
+		explainable = explainable || v.Explainable
+	}
+	if !explainable {
+		return ""
+	}
+	if pool == nil {
+		return "the server was not available, so the statements were only checked against cached schema metadata"
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Sprintf("the statements were only checked against cached schema metadata: %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck // nothing is committed
+	}()
+	if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+		return fmt.Sprintf("the statements were only checked against cached schema metadata: %v", err)
+	}
+
+	for _, v := range validations {
+		if !v.Explainable {
+			continue
+		}
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return fmt.Sprintf("planning stopped: %v", err)
+		}
+		var data []byte
+		err = savepoint.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+v.SQL).Scan(&data)
+		if rbErr := savepoint.Rollback(ctx); rbErr != nil {
+			return fmt.Sprintf("planning stopped: %v", rbErr)
+		}
+		if err != nil {
+			problem := fmt.Sprintf("The server rejected the statement: %v", err)
+			if v.afterDDL {
+				problem += " (earlier statements of the input were not run, so objects they create do not exist yet)"
+			}
+			v.Problems = append([]string{problem}, v.Problems...)
+			continue
+		}
+		v.Problems = nil
+		v.Plan = summarizeExplainPlan(data)
+	}
+	return ""
+}
+
+// summarizeExplainPlan describes the top node of an EXPLAIN (FORMAT JSON) plan
+func summarizeExplainPlan(data []byte) string {
+	var plans []explainPlan
+	if err := json.Unmarshal(data, &plans); err != nil || len(plans) == 0 {
+		return "planned"
+	}
+	plan := plans[0].Plan
+	node := plan.NodeType
+	if plan.Operation != "" {
+		node = plan.Operation
+	}
+	if plan.RelationName != "" {
+		node += " on " + plan.RelationName
+	}
+	return fmt.Sprintf("%s, cost %.2f, %.0f rows estimated", node, plan.TotalCost, plan.PlanRows)
+}
+
+// formatValidations lists the checks of each statement and a verdict
+func formatValidations(validations []*sqlValidation) string {
+	var sb strings.Builder
+	problems, warnings := 0, 0
+	for i, v := range validations {
+		sb.WriteString(fmt.Sprintf("%d. %s: %s\n", i+1, v.Type, statementPreview(v.SQL)))
+		switch {
+		case v.Plan != "":
+			sb.WriteString(fmt.Sprintf("   Server: OK (EXPLAIN: %s)\n", v.Plan))
+		case !v.Explainable:
+			sb.WriteString("   Server: not checked (only SELECT, INSERT, UPDATE, DELETE and MERGE can be planned without running them)\n")
+		}
+		for _, problem := range v.Problems {
+			sb.WriteString(fmt.Sprintf("   PROBLEM: %s\n", problem))
+		}
+		for _, warning := range v.Warnings {
+			sb.WriteString(fmt.Sprintf("   WARNING: %s\n", warning))
+		}
+		problems += len(v.Problems)
+		warnings += len(v.Warnings)
+	}
+
+	sb.WriteString("\n")
+	switch {
+	case problems > 0:
+		sb.WriteString(fmt.Sprintf("Result: INVALID, %d problem(s) and %d warning(s). Fix the problems before running the SQL.", problems, warnings))
+	case warnings > 0:
+		sb.WriteString(fmt.Sprintf("Result: VALID with %d warning(s). Confirm the warned statements with the user before running them.", warnings))
+	default:
+		sb.WriteString("Result: VALID.")
+	}
+	sb.WriteString(" Nothing was executed.\n")
+	return sb.String()
+}
@@ -0,0 +1,80 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSQL(t *testing.T) {
+	validations, err := validateSQL(`
+		SELECT name FROM customers WHERE id = 1;
+		DELETE FROM orders;
+		UPDATE customers SET nickname = 'x' WHERE id = 1;
+		CREATE TABLE archive (id int);
+		ALTER TABLE archive DROP COLUMN missing;
+		DROP TABLE orders CASCADE`, explainTestMetadata())
+	if err != nil {
+		t.Fatalf("validateSQL() error = %v", err)
+	}
+	if len(validations) != 6 {
+		t.Fatalf("expected 6 statements, got %d", len(validations))
+	}
+
+	if v := validations[0]; !v.Explainable || len(v.Problems) != 0 || len(v.Warnings) != 0 {
+		t.Errorf("expected a clean SELECT, got %+v", v)
+	}
+	if v := validations[1]; len(v.Warnings) != 1 || !strings.Contains(v.Warnings[0], "DELETE has no WHERE clause and removes every row of orders") {
+		t.Errorf("expected a DELETE without WHERE warning, got %+v", v.Warnings)
+	}
+	if v := validations[2]; len(v.Problems) != 1 || !strings.Contains(v.Problems[0], "nickname") {
+		t.Errorf("expected an unknown column problem, got %+v", v.Problems)
+	}
+	if v := validations[3]; v.Explainable || len(v.Problems) != 0 {
+		t.Errorf("expected a valid CREATE TABLE, got %+v", v)
+	}
+	// The table created by the previous statement is known to the planner
+	v := validations[4]
+	if len(v.Problems) != 1 || !strings.Contains(v.Problems[0], "Column missing does not exist in public.archive") {
+		t.Errorf("expected a missing column problem, got %+v", v.Problems)
+	}
+	if len(v.Warnings) != 1 || !strings.Contains(v.Warnings[0], "DROP COLUMN") {
+		t.Errorf("expected a DROP COLUMN warning, got %+v", v.Warnings)
+	}
+	if v := validations[5]; len(v.Warnings) != 2 || !v.afterDDL {
+		t.Errorf("expected DROP TABLE and CASCADE warnings, got %+v", v)
+	}
+
+	output := formatValidations(validations)
+	for _, want := range []string{
+		"1. SELECT: SELECT name FROM customers WHERE id = 1\n",
+		"   WARNING: DELETE has no WHERE clause",
+		"4. CREATE: CREATE TABLE archive (id int)\n   Server: not checked",
+		"Result: INVALID, 2 problem(s) and 4 warning(s).",
+		"Nothing was executed.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	if _, err := validateSQL("SELECT 'unterminated", nil); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestSummarizeExplainPlan(t *testing.T) {
+	data := []byte(`[{"Plan": {"Node Type": "ModifyTable", "Operation": "Delete", "Relation Name": "orders", "Total Cost": 35.5, "Plan Rows": 2550}}]`)
+	if got := summarizeExplainPlan(data); got != "Delete on orders, cost 35.50, 2550 rows estimated" {
+		t.Errorf("summarizeExplainPlan() = %q", got)
+	}
+}
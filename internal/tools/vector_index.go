@@ -0,0 +1,256 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxIndexedVectorDimensions is the largest vector pgvector's HNSW and
+// IVFFlat indexes accept
+const maxIndexedVectorDimensions = 2000
+
+// vectorIndex is an HNSW or IVFFlat index on a vector column
+type vectorIndex struct {
+	Name       string
+	Column     string
+	Method     string // hnsw or ivfflat
+	OpClass    string // e.g. vector_cosine_ops
+	Definition string
+}
+
+// Metric returns the distance metric the index supports
+func (idx vectorIndex) Metric() string {
+	return opClassMetric(idx.OpClass)
+}
+
+// rowQuerier runs queries; implemented by pgxpool.Pool and pgx.Tx
+type rowQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// vectorIndexesSQL lists the valid HNSW and IVFFlat indexes of a table by
+// the column and operator class of their first key
+const vectorIndexesSQL = `
+SELECT i.relname, a.attname, am.amname, opc.opcname,
+       pg_catalog.pg_get_indexdef(i.oid)
+FROM pg_catalog.pg_index x
+JOIN pg_catalog.pg_class t ON t.oid = x.indrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_catalog.pg_class i ON i.oid = x.indexrelid
+JOIN pg_catalog.pg_am am ON am.oid = i.relam
+JOIN pg_catalog.pg_attribute a ON a.attrelid = t.oid AND a.attnum = x.indkey[0]
+JOIN pg_catalog.pg_opclass opc ON opc.oid = x.indclass[0]
+WHERE n.nspname = $1 AND t.relname = $2
+  AND am.amname IN ('hnsw', 'ivfflat')
+  AND x.indisvalid
+ORDER BY i.relname`
+
+// lookupVectorIndexes returns the vector indexes of a table
+func lookupVectorIndexes(ctx context.Context, q rowQuerier, schema, table string) ([]vectorIndex, error) {
+	rows, err := q.Query(ctx, vectorIndexesSQL, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vector indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []vectorIndex
+	for rows.Next() {
+		var idx vectorIndex
+		if err := rows.Scan(&idx.Name, &idx.Column, &idx.Method, &idx.OpClass, &idx.Definition); err != nil {
+			return nil, fmt.Errorf("failed to read vector index: %w", err)
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, rows.Err()
+}
+
+// normalizeDistanceMetric maps the distance_metric values accepted by
+// similarity_search to cosine, l2 or inner_product
+func normalizeDistanceMetric(metric string) string {
+	switch strings.ToLower(metric) {
+	case "l2", "euclidean":
+		return "l2"
+	case "inner_product", "inner":
+		return "inner_product"
+	default:
+		return "cosine"
+	}
+}
+
+// opClassMetric returns the distance metric of a pgvector operator class,
+// such as vector_cosine_ops or halfvec_l2_ops
+func opClassMetric(opClass string) string {
+	name := strings.TrimSuffix(opClass, "_ops")
+	if i := strings.Index(name, "_"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "ip" {
+		return "inner_product"
+	}
+	return name
+}
+
+// metricOpClass returns the vector operator class for a distance metric
+func metricOpClass(metric string) string {
+	switch metric {
+	case "l2":
+		return "vector_l2_ops"
+	case "inner_product":
+		return "vector_ip_ops"
+	default:
+		return "vector_cosine_ops"
+	}
+}
+
+// formatVectorIndexReport describes, for each searched column, whether an
+// index can serve the search. pgvector only uses an index to order by the
+// distance of a single column, so a search that combines several columns
+// scans the table.
+func formatVectorIndexReport(columns []string, indexes []vectorIndex, metric string) string {
+	metric = normalizeDistanceMetric(metric)
+
+	var sb strings.Builder
+	sb.WriteString("Vector Indexes:\n")
+	missing := false
+	for _, column := range columns {
+		var compatible *vectorIndex
+		var other []string
+		suggested := ""
+		for i := range indexes {
+			if indexes[i].Column != column {
+				continue
+			}
+			indexMetric := indexes[i].Metric()
+			if indexMetric == metric {
+				compatible = &indexes[i]
+				break
+			}
+			other = append(other, fmt.Sprintf("%s index %s (%s)", indexes[i].Method, indexes[i].Name, indexMetric))
+			if suggested == "" && normalizeDistanceMetric(indexMetric) == indexMetric {
+				suggested = indexMetric
+			}
+		}
+
+		switch {
+		case compatible != nil:
+			sb.WriteString(fmt.Sprintf("  - %s: %s index %s (%s) matches the distance metric\n",
+				column, compatible.Method, compatible.Name, metric))
+		case len(other) > 0:
+			missing = true
+			sb.WriteString(fmt.Sprintf("  - %s: %s does not support %s distance", column, strings.Join(other, ", "), metric))
+			if suggested != "" {
+				sb.WriteString(fmt.Sprintf("; search with distance_metric=%q to use it", suggested))
+			}
+			sb.WriteString("\n")
+		default:
+			missing = true
+			sb.WriteString(fmt.Sprintf("  - %s: no vector index; every row is compared with the query\n", column))
+		}
+	}
+
+	if len(columns) > 1 {
+		sb.WriteString("  Searches that combine several vector columns compare every row; indexes are only used when the table has one vector column\n")
+	} else if missing {
+		sb.WriteString(fmt.Sprintf("  create_vector_index can build a %s index for this column if it is enabled\n", metric))
+	}
+	return sb.String()
+}
+
+// vectorIndexPlan is the index create_vector_index builds
+type vectorIndexPlan struct {
+	Method  string
+	OpClass string
+	With    []string // Storage parameters, such as "m = 16"
+	Notes   []string
+}
+
+// planVectorIndex chooses an index method and parameters for a vector column
+// from the table's row count, following pgvector's guidance: IVFFlat uses
+// rows/1000 lists up to a million rows and sqrt(rows) beyond, and must be
+// built after the data is loaded; HNSW works on empty tables and gets a
+// larger build candidate list for large tables. method "auto" picks HNSW,
+// or IVFFlat above a million rows, where it builds much faster.
+func planVectorIndex(method string, rows int64, dimensions int, metric string) (*vectorIndexPlan, error) {
+	if dimensions > maxIndexedVectorDimensions {
+		return nil, fmt.Errorf("the column has %d dimensions; pgvector indexes support up to %d (index a halfvec expression instead)",
+			dimensions, maxIndexedVectorDimensions)
+	}
+
+	if method == "" || method == "auto" {
+		method = "hnsw"
+		if rows >= 1000000 {
+			method = "ivfflat"
+		}
+	}
+
+	plan := &vectorIndexPlan{Method: method, OpClass: metricOpClass(metric)}
+	switch method {
+	case "hnsw":
+		efConstruction := 64
+		if rows >= 100000 {
+			efConstruction = 128
+		}
+		plan.With = []string{"m = 16", fmt.Sprintf("ef_construction = %d", efConstruction)}
+		plan.Notes = append(plan.Notes, "Raise hnsw.ef_search (default 40) in a session for better recall at some cost in speed")
+	case "ivfflat":
+		if rows <= 0 {
+			return nil, fmt.Errorf("IVFFlat indexes are trained on existing rows; load the data first or use method=hnsw")
+		}
+		lists := rows / 1000
+		if rows > 1000000 {
+			lists = int64(math.Sqrt(float64(rows)))
+		}
+		if lists < 1 {
+			lists = 1
+		}
+		plan.With = []string{fmt.Sprintf("lists = %d", lists)}
+		probes := int64(math.Sqrt(float64(lists)))
+		if probes < 1 {
+			probes = 1
+		}
+		plan.Notes = append(plan.Notes,
+			fmt.Sprintf("Set ivfflat.probes to about %d (default 1) in a session for better recall", probes),
+			"Rebuild the index after the table grows substantially, as lists are fixed when it is built")
+	default:
+		return nil, fmt.Errorf("method must be 'auto', 'hnsw' or 'ivfflat'")
+	}
+
+	if rows < 10000 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("With about %d rows an exact scan is already fast; the index helps as the table grows", rows))
+	}
+	return plan, nil
+}
+
+// vectorIndexName builds an index name within PostgreSQL's 63 byte limit
+func vectorIndexName(table, column, method, metric string) string {
+	name := fmt.Sprintf("%s_%s_%s_%s_idx", table, column, method, metric)
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// statement returns the CREATE INDEX statement for the plan
+func (p *vectorIndexPlan) statement(name, schema, table, column string) string {
+	stmt := fmt.Sprintf("CREATE INDEX %s ON %s.%s USING %s (%s %s)",
+		quoteIdentIfNeeded(name), quoteIdentIfNeeded(schema), quoteIdentIfNeeded(table),
+		p.Method, quoteIdentIfNeeded(column), p.OpClass)
+	if len(p.With) > 0 {
+		stmt += " WITH (" + strings.Join(p.With, ", ") + ")"
+	}
+	return stmt
+}
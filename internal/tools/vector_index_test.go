@@ -0,0 +1,206 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"pgedge-postgres-mcp/internal/database"
+)
+
+func TestOpClassMetric(t *testing.T) {
+	tests := map[string]string{
+		"vector_cosine_ops":  "cosine",
+		"vector_l2_ops":      "l2",
+		"vector_ip_ops":      "inner_product",
+		"halfvec_cosine_ops": "cosine",
+		"bit_hamming_ops":    "hamming",
+	}
+	for opClass, want := range tests {
+		if got := opClassMetric(opClass); got != want {
+			t.Errorf("opClassMetric(%q) = %q, want %q", opClass, got, want)
+		}
+	}
+
+	for _, metric := range []string{"cosine", "l2", "inner_product"} {
+		if got := opClassMetric(metricOpClass(metric)); got != metric {
+			t.Errorf("metricOpClass(%q) does not round trip: %q", metric, got)
+		}
+	}
+	if normalizeDistanceMetric("euclidean") != "l2" || normalizeDistanceMetric("inner") != "inner_product" ||
+		normalizeDistanceMetric("") != "cosine" {
+		t.Error("normalizeDistanceMetric() does not match the similarity_search aliases")
+	}
+}
+
+func TestFormatVectorIndexReport(t *testing.T) {
+	indexes := []vectorIndex{
+		{Name: "docs_embedding_idx", Column: "embedding", Method: "hnsw", OpClass: "vector_l2_ops"},
+		{Name: "docs_title_idx", Column: "title_embedding", Method: "ivfflat", OpClass: "vector_cosine_ops"},
+	}
+
+	tests := []struct {
+		name    string
+		columns []string
+		metric  string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "compatible index",
+			columns: []string{"embedding"},
+			metric:  "euclidean",
+			want:    []string{"embedding: hnsw index docs_embedding_idx (l2) matches the distance metric"},
+			notWant: []string{"create_vector_index"},
+		},
+		{
+			name:    "index for another metric",
+			columns: []string{"embedding"},
+			metric:  "cosine",
+			want: []string{
+				"hnsw index docs_embedding_idx (l2) does not support cosine distance",
+				`search with distance_metric="l2" to use it`,
+				"create_vector_index can build a cosine index",
+			},
+		},
+		{
+			name:    "no index",
+			columns: []string{"body_embedding"},
+			metric:  "cosine",
+			want:    []string{"body_embedding: no vector index; every row is compared with the query"},
+		},
+		{
+			name:    "several columns",
+			columns: []string{"embedding", "title_embedding"},
+			metric:  "cosine",
+			want:    []string{"title_embedding: ivfflat index docs_title_idx (cosine) matches", "combine several vector columns"},
+			notWant: []string{"create_vector_index"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := formatVectorIndexReport(tt.columns, indexes, tt.metric)
+			if !strings.HasPrefix(out, "Vector Indexes:\n") {
+				t.Errorf("Missing header:\n%s", out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("Output missing %q:\n%s", want, out)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("Output should not contain %q:\n%s", notWant, out)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanVectorIndex(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		rows       int64
+		dimensions int
+		metric     string
+		wantMethod string
+		wantWith   []string
+		wantErr    string
+	}{
+		{"hnsw on a small table", "auto", 5000, 1536, "cosine", "hnsw", []string{"m = 16", "ef_construction = 64"}, ""},
+		{"hnsw on a large table", "hnsw", 500000, 768, "l2", "hnsw", []string{"m = 16", "ef_construction = 128"}, ""},
+		{"hnsw on an empty table", "auto", 0, 384, "cosine", "hnsw", []string{"m = 16", "ef_construction = 64"}, ""},
+		{"auto picks ivfflat for big tables", "auto", 4000000, 384, "inner_product", "ivfflat", []string{"lists = 2000"}, ""},
+		{"ivfflat lists from rows", "ivfflat", 50000, 384, "cosine", "ivfflat", []string{"lists = 50"}, ""},
+		{"ivfflat on a tiny table", "ivfflat", 200, 384, "cosine", "ivfflat", []string{"lists = 1"}, ""},
+		{"ivfflat needs rows", "ivfflat", 0, 384, "cosine", "", nil, "load the data first"},
+		{"too many dimensions", "auto", 1000, 3072, "cosine", "", nil, "up to 2000"},
+		{"unknown method", "diskann", 1000, 384, "cosine", "", nil, "method must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planVectorIndex(tt.method, tt.rows, tt.dimensions, tt.metric)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("planVectorIndex() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planVectorIndex() error = %v", err)
+			}
+			if plan.Method != tt.wantMethod {
+				t.Errorf("Method = %q, want %q", plan.Method, tt.wantMethod)
+			}
+			if !reflect.DeepEqual(plan.With, tt.wantWith) {
+				t.Errorf("With = %v, want %v", plan.With, tt.wantWith)
+			}
+			if plan.OpClass != metricOpClass(tt.metric) {
+				t.Errorf("OpClass = %q for %s", plan.OpClass, tt.metric)
+			}
+		})
+	}
+}
+
+func TestVectorIndexStatement(t *testing.T) {
+	plan, err := planVectorIndex("hnsw", 1000, 1536, "cosine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := vectorIndexName("Documents", "embedding", plan.Method, "cosine")
+	got := plan.statement(name, "public", "Documents", "embedding")
+	want := `CREATE INDEX "Documents_embedding_hnsw_cosine_idx" ON public."Documents" USING hnsw (embedding vector_cosine_ops) WITH (m = 16, ef_construction = 64)`
+	if got != want {
+		t.Errorf("statement() =\n%s\nwant\n%s", got, want)
+	}
+
+	long := vectorIndexName(strings.Repeat("t", 50), "embedding", "ivfflat", "inner_product")
+	if len(long) != 63 {
+		t.Errorf("vectorIndexName() length = %d, want 63", len(long))
+	}
+}
+
+func TestSelectVectorColumn(t *testing.T) {
+	table := database.TableInfo{
+		SchemaName: "public",
+		TableName:  "docs",
+		Columns: []database.ColumnInfo{
+			{ColumnName: "id"},
+			{ColumnName: "embedding", IsVectorColumn: true, VectorDimensions: 384},
+		},
+	}
+
+	col, err := selectVectorColumn(table, "")
+	if err != nil || col.ColumnName != "embedding" {
+		t.Errorf("selectVectorColumn() = %v, %v; want the only vector column", col.ColumnName, err)
+	}
+	if _, err := selectVectorColumn(table, "id"); err == nil || !strings.Contains(err.Error(), "not a vector column") {
+		t.Errorf("Expected an error for a non-vector column, got %v", err)
+	}
+
+	table.Columns = append(table.Columns, database.ColumnInfo{ColumnName: "title_embedding", IsVectorColumn: true})
+	if _, err := selectVectorColumn(table, ""); err == nil || !strings.Contains(err.Error(), "specify column_name") {
+		t.Errorf("Expected an error with several vector columns, got %v", err)
+	}
+	if col, err := selectVectorColumn(table, "title_embedding"); err != nil || col.ColumnName != "title_embedding" {
+		t.Errorf("selectVectorColumn(title_embedding) = %v, %v", col.ColumnName, err)
+	}
+
+	table.Columns = table.Columns[:1]
+	if _, err := selectVectorColumn(table, ""); err == nil {
+		t.Error("Expected an error for a table without vector columns")
+	}
+}
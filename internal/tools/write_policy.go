@@ -0,0 +1,72 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"fmt"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// databaseWrites maps the tools that can modify the database to whether a
+// call with the given arguments does. Dry runs and read-only transactions
+// leave the database unchanged.
+var databaseWrites = map[string]func(args map[string]interface{}) bool{
+	"execute_script": func(args map[string]interface{}) bool {
+		return !ValidateBoolParam(args, "dry_run", false)
+	},
+	"apply_migration": func(args map[string]interface{}) bool {
+		return !ValidateBoolParam(args, "dry_run", true)
+	},
+	"create_vector_index": func(args map[string]interface{}) bool {
+		return !ValidateBoolParam(args, "dry_run", true)
+	},
+	"setup_semantic_search": func(args map[string]interface{}) bool {
+		return !ValidateBoolParam(args, "dry_run", true)
+	},
+	"setup_foreign_server": func(args map[string]interface{}) bool {
+		return !ValidateBoolParam(args, "dry_run", true)
+	},
+	"install_extension":       func(map[string]interface{}) bool { return true },
+	"restore_schema_snapshot": func(map[string]interface{}) bool { return true },
+	"begin_transaction": func(args map[string]interface{}) bool {
+		return ValidateBoolParam(args, "read_write", false)
+	},
+}
+
+// isDatabaseWrite reports whether a tool call can modify the database
+func isDatabaseWrite(name string, args map[string]interface{}) bool {
+	writes, ok := databaseWrites[name]
+	return ok && writes(args)
+}
+
+// changesSchema reports whether a successful tool call can have changed the
+// database's schema, outdating the cached metadata. Read-write transactions
+// change it when they are committed.
+func changesSchema(name string, args map[string]interface{}) bool {
+	switch name {
+	case "commit_transaction":
+		return true
+	case "begin_transaction":
+		return false
+	}
+	return isDatabaseWrite(name, args)
+}
+
+// writePolicyError explains why a tool may not modify a database whose
+// configuration does not allow writes
+func writePolicyError(db *config.NamedDatabaseConfig, tool string) string {
+	reason := fmt.Sprintf("Database %s does not allow writes (allow_writes: false in the server configuration)", db.Name)
+	if db.AllowWrites == nil {
+		reason = fmt.Sprintf("Database %s is a production database, so writes are disabled unless the server configuration sets allow_writes: true for it", db.Name)
+	}
+	return fmt.Sprintf("%s. %s may not modify it; dry runs and read-only transactions are still allowed.", reason, tool)
+}
@@ -0,0 +1,307 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// writeCheckKind is what a verification check looks for
+type writeCheckKind int
+
+const (
+	checkCreated  writeCheckKind = iota // The object exists after CREATE
+	checkDropped                        // The object is gone after DROP
+	checkRowDelta                       // The row count changed by the rows the statement affected
+)
+
+// writeCheck verifies the effect of a data or schema change. Checks are
+// planned from the statement before it runs, since row counts must be
+// taken before and after it.
+type writeCheck struct {
+	Kind       writeCheckKind
+	ObjectType string // "table", "index", ...; for created and dropped objects
+	Object     string // Name as written in the statement
+	Only       bool   // Row counts exclude inheriting tables (DELETE FROM ONLY)
+	Sign       int64  // +1 for INSERT, -1 for DELETE
+	Upsert     bool   // INSERT ... ON CONFLICT, which may update instead of inserting
+	Before     int64  // Rows before the statement
+	Err        string // Why the rows could not be counted before the statement
+}
+
+// writeVerification is the evidence a check found
+type writeVerification struct {
+	Passed bool
+	Detail string
+}
+
+// verifySavepoint protects the transaction from a failed verification query
+const verifySavepoint = "verify_write"
+
+// verifyQueryer runs verification queries; implemented by pgx.Tx
+type verifyQueryer interface {
+	scriptExecer
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// scanInSavepoint runs a verification query in a savepoint, so that a query
+// that fails, for example for lack of privileges, leaves the transaction
+// usable
+func scanInSavepoint(ctx context.Context, q verifyQueryer, sql string, args []any, dest ...any) error {
+	if _, err := q.Exec(ctx, "SAVEPOINT "+verifySavepoint); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if err := q.QueryRow(ctx, sql, args...).Scan(dest...); err != nil {
+		if _, rbErr := q.Exec(ctx, "ROLLBACK TO SAVEPOINT "+verifySavepoint); rbErr != nil {
+			return fmt.Errorf("%v; failed to roll back to savepoint: %w", err, rbErr)
+		}
+		return err
+	}
+	if _, err := q.Exec(ctx, "RELEASE SAVEPOINT "+verifySavepoint); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// verifiedObjectTypes are the relation kinds to_regclass can look up
+var verifiedObjectTypes = map[string]string{
+	"TABLE": "table", "INDEX": "index", "VIEW": "view", "SEQUENCE": "sequence",
+}
+
+// planWriteChecks returns the checks that verify a statement: the created
+// or dropped relations of CREATE and DROP, and the row count of the table
+// of an INSERT or DELETE. Other statements have no automatic check.
+func planWriteChecks(sql string) []*writeCheck {
+	toks, err := tokenizeSQL(sql)
+	if err != nil || len(toks) == 0 {
+		return nil
+	}
+	c := &ddlCursor{toks: toks, pos: 1}
+	nameText := func() (string, bool) {
+		start := c.pos
+		if _, _, ok := c.name(); !ok {
+			return "", false
+		}
+		return sql[toks[start].start:toks[c.pos-1].end], true
+	}
+
+	switch first := toks[0]; {
+	case first.isKeyword("CREATE"):
+		c.accept("OR", "REPLACE")
+		for c.peek().isKeyword("GLOBAL", "LOCAL", "TEMP", "TEMPORARY", "UNLOGGED", "UNIQUE", "MATERIALIZED", "RECURSIVE") {
+			c.pos++
+		}
+		objectType, ok := verifiedObjectTypes[c.peek().upper]
+		if !ok || c.peek().kind != tokWord {
+			return nil
+		}
+		c.pos++
+		c.accept("CONCURRENTLY")
+		c.accept("IF", "NOT", "EXISTS")
+		if c.peek().isKeyword("ON") {
+			return nil // Unnamed index
+		}
+		if name, ok := nameText(); ok {
+			return []*writeCheck{{Kind: checkCreated, ObjectType: objectType, Object: name}}
+		}
+
+	case first.isKeyword("DROP"):
+		c.accept("MATERIALIZED")
+		objectType, ok := verifiedObjectTypes[c.peek().upper]
+		if !ok || c.peek().kind != tokWord {
+			return nil
+		}
+		c.pos++
+		c.accept("CONCURRENTLY")
+		c.accept("IF", "EXISTS")
+		var checks []*writeCheck
+		for {
+			name, ok := nameText()
+			if !ok {
+				break
+			}
+			checks = append(checks, &writeCheck{Kind: checkDropped, ObjectType: objectType, Object: name})
+			if !c.peek().isPunct(",") {
+				break
+			}
+			c.pos++
+		}
+		return checks
+
+	case first.isKeyword("INSERT"):
+		if !c.accept("INTO") {
+			return nil
+		}
+		if name, ok := nameText(); ok {
+			return []*writeCheck{{Kind: checkRowDelta, Object: name, Sign: 1, Upsert: containsKeywords(toks, "ON", "CONFLICT")}}
+		}
+
+	case first.isKeyword("DELETE"):
+		if !c.accept("FROM") {
+			return nil
+		}
+		only := c.accept("ONLY")
+		if name, ok := nameText(); ok {
+			return []*writeCheck{{Kind: checkRowDelta, Object: name, Only: only, Sign: -1}}
+		}
+	}
+	return nil
+}
+
+// containsKeywords reports whether the keywords appear in sequence
+func containsKeywords(toks []sqlToken, words ...string) bool {
+	for i := 0; i+len(words) <= len(toks); i++ {
+		match := true
+		for j, w := range words {
+			if !toks[i+j].isKeyword(w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// countRows counts the rows of the check's table
+func (c *writeCheck) countRows(ctx context.Context, q verifyQueryer) (int64, error) {
+	from := c.Object
+	if c.Only {
+		from = "ONLY " + from
+	}
+	var count int64
+	err := scanInSavepoint(ctx, q, "SELECT count(*) FROM "+from, nil, &count)
+	return count, err
+}
+
+// prepare takes the measurements a check needs before the statement runs
+func (c *writeCheck) prepare(ctx context.Context, q verifyQueryer) {
+	if c.Kind != checkRowDelta {
+		return
+	}
+	before, err := c.countRows(ctx, q)
+	if err != nil {
+		c.Err = err.Error()
+		return
+	}
+	c.Before = before
+}
+
+// verify checks the effect of the statement, which has run with the given
+// command tag
+func (c *writeCheck) verify(ctx context.Context, q verifyQueryer, tag pgconn.CommandTag) writeVerification {
+	switch c.Kind {
+	case checkCreated, checkDropped:
+		var found *string
+		if err := scanInSavepoint(ctx, q, "SELECT to_regclass($1)::text", []any{c.Object}, &found); err != nil {
+			return writeVerification{Detail: fmt.Sprintf("could not look up %s %s: %v", c.ObjectType, c.Object, err)}
+		}
+		if c.Kind == checkCreated {
+			if found == nil {
+				return writeVerification{Detail: fmt.Sprintf("%s %s does not exist (to_regclass returned NULL)", c.ObjectType, c.Object)}
+			}
+			return writeVerification{Passed: true, Detail: fmt.Sprintf("%s %s exists (to_regclass: %s)", c.ObjectType, c.Object, *found)}
+		}
+		if found != nil {
+			return writeVerification{Detail: fmt.Sprintf("%s %s still exists (to_regclass: %s)", c.ObjectType, c.Object, *found)}
+		}
+		return writeVerification{Passed: true, Detail: fmt.Sprintf("%s %s no longer exists (to_regclass returned NULL)", c.ObjectType, c.Object)}
+
+	default:
+		if c.Err != "" {
+			return writeVerification{Detail: fmt.Sprintf("could not count the rows of %s before the statement: %s", c.Object, c.Err)}
+		}
+		after, err := c.countRows(ctx, q)
+		if err != nil {
+			return writeVerification{Detail: fmt.Sprintf("could not count the rows of %s: %v", c.Object, err)}
+		}
+		delta, affected := after-c.Before, tag.RowsAffected()
+		detail := fmt.Sprintf("%s has %d rows, %+d from %d before the statement; %s reported %d rows",
+			c.Object, after, delta, c.Before, tag.String(), affected)
+		passed := delta == c.Sign*affected
+		if c.Upsert {
+			// Rows that conflicted were updated rather than inserted
+			passed = delta >= 0 && delta <= affected
+			detail += " (ON CONFLICT may update rows instead of inserting them)"
+		}
+		return writeVerification{Passed: passed, Detail: detail}
+	}
+}
+
+// verifyWrites runs the checks planned for a statement that has run
+func verifyWrites(ctx context.Context, q verifyQueryer, checks []*writeCheck, tag pgconn.CommandTag) []writeVerification {
+	verifications := make([]writeVerification, 0, len(checks))
+	for _, check := range checks {
+		verifications = append(verifications, check.verify(ctx, q, tag))
+	}
+	return verifications
+}
+
+// formatVerifications renders the verification section of a query_database
+// response
+func formatVerifications(verifications []writeVerification, tag pgconn.CommandTag) string {
+	var sb strings.Builder
+	sb.WriteString("Verification:\n")
+	if len(verifications) == 0 {
+		sb.WriteString(fmt.Sprintf("  No automatic check applies to this statement; the command tag (%s) is the only evidence of its effect.\n", tag.String()))
+		return sb.String()
+	}
+	for _, v := range verifications {
+		status := "FAILED"
+		if v.Passed {
+			status = "PASSED"
+		}
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", status, v.Detail))
+	}
+	return sb.String()
+}
+
+// scriptVerifier is implemented by executors that verify the statements
+// they run
+type scriptVerifier interface {
+	takeVerifications() []writeVerification
+}
+
+// verifyingExecer runs the statements of a query_database script with
+// verify=true, checking the effect of each
+type verifyingExecer struct {
+	tx   verifyQueryer
+	last []writeVerification
+}
+
+// Exec runs a statement between the measurements of its checks
+func (v *verifyingExecer) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	v.last = nil
+	checks := planWriteChecks(sql)
+	for _, check := range checks {
+		check.prepare(ctx, v.tx)
+	}
+	tag, err := v.tx.Exec(ctx, sql, arguments...)
+	if err != nil {
+		return tag, err
+	}
+	v.last = verifyWrites(ctx, v.tx, checks, tag)
+	return tag, nil
+}
+
+// takeVerifications returns the evidence found for the last statement
+func (v *verifyingExecer) takeVerifications() []writeVerification {
+	verifications := v.last
+	v.last = nil
+	return verifications
+}
@@ -0,0 +1,158 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPlanWriteChecks(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []writeCheck
+	}{
+		{"CREATE TABLE app.orders (id int)", []writeCheck{{Kind: checkCreated, ObjectType: "table", Object: "app.orders"}}},
+		{`CREATE UNIQUE INDEX IF NOT EXISTS "Orders_idx" ON orders (id)`, []writeCheck{{Kind: checkCreated, ObjectType: "index", Object: `"Orders_idx"`}}},
+		{"CREATE OR REPLACE TEMP VIEW v AS SELECT 1", []writeCheck{{Kind: checkCreated, ObjectType: "view", Object: "v"}}},
+		{"CREATE MATERIALIZED VIEW mv AS SELECT 1", []writeCheck{{Kind: checkCreated, ObjectType: "view", Object: "mv"}}},
+		{"CREATE INDEX ON orders (id)", nil},
+		{"CREATE FUNCTION f() RETURNS int AS 'SELECT 1' LANGUAGE sql", nil},
+		{"DROP TABLE IF EXISTS a, app.b CASCADE", []writeCheck{
+			{Kind: checkDropped, ObjectType: "table", Object: "a"},
+			{Kind: checkDropped, ObjectType: "table", Object: "app.b"},
+		}},
+		{"INSERT INTO orders (id) VALUES (1)", []writeCheck{{Kind: checkRowDelta, Object: "orders", Sign: 1}}},
+		{"INSERT INTO orders VALUES (1) ON CONFLICT DO NOTHING", []writeCheck{{Kind: checkRowDelta, Object: "orders", Sign: 1, Upsert: true}}},
+		{"DELETE FROM ONLY app.orders WHERE id = 1", []writeCheck{{Kind: checkRowDelta, Object: "app.orders", Only: true, Sign: -1}}},
+		{"UPDATE orders SET note = 'x'", nil},
+		{"SELECT 1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			checks := planWriteChecks(tt.sql)
+			if len(checks) != len(tt.want) {
+				t.Fatalf("planWriteChecks() returned %d checks, want %d", len(checks), len(tt.want))
+			}
+			for i, check := range checks {
+				if *check != tt.want[i] {
+					t.Errorf("check %d = %+v, want %+v", i, *check, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// fakeVerifyTx answers verification queries from fixed results
+type fakeVerifyTx struct {
+	fakeScriptTx
+	counts   []int64 // Returned by successive count(*) queries
+	regclass *string // Returned by to_regclass
+	countErr error
+}
+
+type fakeRow struct {
+	scan func(dest ...any) error
+}
+
+func (r fakeRow) Scan(dest ...any) error { return r.scan(dest...) }
+
+func (f *fakeVerifyTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	f.executed = append(f.executed, sql)
+	return fakeRow{scan: func(dest ...any) error {
+		if strings.Contains(sql, "to_regclass") {
+			*dest[0].(**string) = f.regclass
+			return nil
+		}
+		if f.countErr != nil {
+			return f.countErr
+		}
+		*dest[0].(*int64) = f.counts[0]
+		f.counts = f.counts[1:]
+		return nil
+	}}
+}
+
+func TestWriteCheckVerify(t *testing.T) {
+	ctx := context.Background()
+
+	// A row count that changed by the rows inserted passes
+	tx := &fakeVerifyTx{counts: []int64{10, 13}}
+	check := planWriteChecks("INSERT INTO orders SELECT * FROM staging")[0]
+	check.prepare(ctx, tx)
+	v := check.verify(ctx, tx, pgconn.NewCommandTag("INSERT 0 3"))
+	if !v.Passed || !strings.Contains(v.Detail, "orders has 13 rows, +3 from 10 before the statement; INSERT 0 3 reported 3 rows") {
+		t.Errorf("unexpected verification: %+v", v)
+	}
+
+	// A DELETE whose row count did not drop by the rows reported fails
+	tx = &fakeVerifyTx{counts: []int64{10, 10}}
+	check = planWriteChecks("DELETE FROM orders")[0]
+	check.prepare(ctx, tx)
+	if v := check.verify(ctx, tx, pgconn.NewCommandTag("DELETE 2")); v.Passed {
+		t.Errorf("expected the verification to fail: %+v", v)
+	}
+
+	// Failed counts are rolled back to a savepoint and reported
+	tx = &fakeVerifyTx{countErr: errors.New("permission denied for table orders")}
+	check = planWriteChecks("DELETE FROM orders")[0]
+	check.prepare(ctx, tx)
+	v = check.verify(ctx, tx, pgconn.NewCommandTag("DELETE 2"))
+	if v.Passed || !strings.Contains(v.Detail, "permission denied") {
+		t.Errorf("unexpected verification: %+v", v)
+	}
+	if !strings.Contains(strings.Join(tx.executed, "\n"), "ROLLBACK TO SAVEPOINT "+verifySavepoint) {
+		t.Errorf("expected a rollback to the savepoint, got %v", tx.executed)
+	}
+
+	// Created objects are looked up with to_regclass
+	name := "app.orders"
+	tx = &fakeVerifyTx{regclass: &name}
+	check = planWriteChecks("CREATE TABLE app.orders (id int)")[0]
+	v = check.verify(ctx, tx, pgconn.NewCommandTag("CREATE TABLE"))
+	if !v.Passed || v.Detail != "table app.orders exists (to_regclass: app.orders)" {
+		t.Errorf("unexpected verification: %+v", v)
+	}
+	check = planWriteChecks("DROP TABLE app.orders")[0]
+	if v := check.verify(ctx, tx, pgconn.NewCommandTag("DROP TABLE")); v.Passed {
+		t.Errorf("expected a dropped table that still exists to fail: %+v", v)
+	}
+
+	out := formatVerifications(nil, pgconn.NewCommandTag("UPDATE 4"))
+	if !strings.Contains(out, "No automatic check applies to this statement; the command tag (UPDATE 4)") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestVerifyingExecer(t *testing.T) {
+	tx := &fakeVerifyTx{counts: []int64{5, 6}}
+	results, aborted := runScript(context.Background(), &verifyingExecer{tx: tx}, []string{
+		"INSERT INTO orders VALUES (1)",
+		"UPDATE orders SET note = 'x'",
+	}, true)
+	if aborted {
+		t.Fatal("expected the script to complete")
+	}
+	if len(results[0].Verifications) != 1 || !results[0].Verifications[0].Passed {
+		t.Errorf("expected the INSERT to be verified: %+v", results[0].Verifications)
+	}
+	if len(results[1].Verifications) != 0 {
+		t.Errorf("expected no checks for the UPDATE: %+v", results[1].Verifications)
+	}
+	if out := formatQueryScriptResults(results); !strings.Contains(out, "   Verification PASSED: orders has 6 rows, +1 from 5") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
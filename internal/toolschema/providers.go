@@ -0,0 +1,235 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package toolschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Anthropic returns tool definitions for the Anthropic Messages API, where
+// the schema is passed unchanged as input_schema
+func Anthropic(tools []Tool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		result = append(result, map[string]interface{}{
+			"name":         tool.Name,
+			"description":  tool.Description,
+			"input_schema": tool.InputSchema.Map(),
+		})
+	}
+	return result
+}
+
+// OpenAI returns function tool definitions for the OpenAI Chat Completions
+// API, where the schema is passed unchanged as the function parameters
+func OpenAI(tools []Tool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		result = append(result, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  tool.InputSchema.Map(),
+			},
+		})
+	}
+	return result
+}
+
+// Bedrock returns tool specifications for the Amazon Bedrock Converse API,
+// where the schema is passed unchanged as the JSON input schema
+func Bedrock(tools []Tool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		result = append(result, map[string]interface{}{
+			"toolSpec": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"inputSchema": map[string]interface{}{
+					"json": tool.InputSchema.Map(),
+				},
+			},
+		})
+	}
+	return result
+}
+
+// Describe renders tools as text for models that are told about tools in
+// the system prompt (Ollama models without native tool calling). Parameters are listed in name order with
+// their type, whether they are required, and their constraints; the
+// properties of nested objects, and of objects in arrays, are indented
+// below their parent.
+func Describe(tools []Tool) string {
+	descriptions := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("- %s: %s", tool.Name, tool.Description))
+		if len(tool.InputSchema.Properties) > 0 {
+			sb.WriteString("\n  Parameters:")
+			describeProperties(&sb, tool.InputSchema.Properties, tool.InputSchema.Required, "    ")
+		}
+		descriptions = append(descriptions, sb.String())
+	}
+	return strings.Join(descriptions, "\n")
+}
+
+// describeProperties writes one line per property, recursing into nested
+// object properties
+func describeProperties(sb *strings.Builder, properties map[string]interface{}, required []string, indent string) {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		details := []string{typeName(prop)}
+		if contains(required, name) {
+			details = append(details, "required")
+		}
+		details = append(details, constraints(prop)...)
+
+		sb.WriteString(fmt.Sprintf("\n%s%s (%s)", indent, name, strings.Join(details, ", ")))
+		if desc, ok := prop["description"].(string); ok && desc != "" {
+			sb.WriteString(": " + desc)
+		}
+
+		nested := prop
+		if items, ok := prop["items"].(map[string]interface{}); ok {
+			nested = items
+		}
+		if props, ok := nested["properties"].(map[string]interface{}); ok && len(props) > 0 {
+			describeProperties(sb, props, stringList(nested["required"]), indent+"  ")
+		}
+	}
+}
+
+// typeName describes a property's type, including the item type of arrays
+func typeName(prop map[string]interface{}) string {
+	name := schemaType(prop)
+	if name == "array" {
+		if items, ok := prop["items"].(map[string]interface{}); ok {
+			return "array of " + schemaType(items)
+		}
+	}
+	return name
+}
+
+// schemaType returns the type keyword, which may be a list of types
+func schemaType(prop map[string]interface{}) string {
+	if t, ok := prop["type"].(string); ok {
+		return t
+	}
+	if names := stringList(prop["type"]); len(names) > 0 {
+		return strings.Join(names, " or ")
+	}
+	if _, ok := prop["enum"]; ok {
+		return "enum"
+	}
+	return "any"
+}
+
+// constraintKeywords are rendered in this order, with these labels
+var constraintKeywords = []struct {
+	keyword string
+	label   string
+}{
+	{"minimum", "minimum"},
+	{"maximum", "maximum"},
+	{"exclusiveMinimum", "greater than"},
+	{"exclusiveMaximum", "less than"},
+	{"minLength", "min length"},
+	{"maxLength", "max length"},
+	{"minItems", "min items"},
+	{"maxItems", "max items"},
+	{"pattern", "pattern"},
+	{"format", "format"},
+	{"default", "default"},
+}
+
+// constraints lists the validation keywords of a property
+func constraints(prop map[string]interface{}) []string {
+	var result []string
+	if values := valueList(prop["enum"]); len(values) > 0 {
+		formatted := make([]string, len(values))
+		for i, v := range values {
+			formatted[i] = formatValue(v)
+		}
+		result = append(result, "one of: "+strings.Join(formatted, " | "))
+	}
+	for _, c := range constraintKeywords {
+		if value, ok := prop[c.keyword]; ok {
+			result = append(result, c.label+": "+formatValue(value))
+		}
+	}
+	return result
+}
+
+// formatValue renders a schema value compactly
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return fmt.Sprintf("%g", val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
+
+// valueList returns the items of a list, which is []interface{} when
+// decoded from JSON but may be a typed slice in definitions built in Go
+func valueList(v interface{}) []interface{} {
+	switch list := v.(type) {
+	case []interface{}:
+		return list
+	case []string:
+		result := make([]interface{}, len(list))
+		for i, s := range list {
+			result[i] = s
+		}
+		return result
+	}
+	return nil
+}
+
+// stringList returns the strings in a list
+func stringList(v interface{}) []string {
+	var result []string
+	for _, item := range valueList(v) {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
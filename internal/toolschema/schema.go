@@ -0,0 +1,117 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package toolschema converts MCP tool definitions into the formats the
+// LLM providers expect, keeping the JSON Schema constraints (enums, array
+// item types, nested objects, bounds) that help models call tools correctly.
+package toolschema
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Tool is an MCP tool definition
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	InputSchema Schema `json:"inputSchema"`
+}
+
+// Schema is the JSON schema of a tool's input. Keywords other than type,
+// properties and required (additionalProperties, $defs, ...) are kept in
+// Extra so they survive decoding and re-encoding.
+type Schema struct {
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
+	Required   []string               `json:"required,omitempty"`
+	Extra      map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON encodes the schema with its extra keywords
+func (s Schema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Map())
+}
+
+// UnmarshalJSON decodes a schema, keeping unknown keywords in Extra
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*s = Schema{}
+	for key, value := range raw {
+		switch key {
+		case "type":
+			s.Type, _ = value.(string) //nolint:errcheck // a non-string type is treated as unset
+		case "properties":
+			if props, ok := value.(map[string]interface{}); ok {
+				s.Properties = props
+			}
+		case "required":
+			list, _ := value.([]interface{}) //nolint:errcheck // a malformed list is treated as unset
+			for _, item := range list {
+				if name, ok := item.(string); ok {
+					s.Required = append(s.Required, name)
+				}
+			}
+		default:
+			if s.Extra == nil {
+				s.Extra = make(map[string]interface{})
+			}
+			s.Extra[key] = value
+		}
+	}
+	return nil
+}
+
+// Map returns the schema as a JSON object that providers accept: the type
+// defaults to "object" and properties is never null
+func (s Schema) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(s.Extra)+3)
+	for key, value := range s.Extra {
+		m[key] = value
+	}
+
+	m["type"] = s.Type
+	if s.Type == "" {
+		m["type"] = "object"
+	}
+	m["properties"] = s.Properties
+	if s.Properties == nil {
+		m["properties"] = map[string]interface{}{}
+	}
+	if len(s.Required) > 0 {
+		m["required"] = s.Required
+	}
+	return m
+}
+
+// FromAny converts tool definitions of any JSON-compatible type, such as
+// []mcp.Tool or tools posted to the LLM proxy, into Tools
+func FromAny(tools interface{}) ([]Tool, error) {
+	if tools == nil {
+		return nil, nil
+	}
+	if converted, ok := tools.([]Tool); ok {
+		return converted, nil
+	}
+
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tools: %w", err)
+	}
+	var converted []Tool
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tools: %w", err)
+	}
+	return converted, nil
+}
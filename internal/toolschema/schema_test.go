@@ -0,0 +1,207 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package toolschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleToolJSON has nested objects, enums, arrays and top-level keywords
+// beyond type, properties and required
+const sampleToolJSON = `[{
+  "name": "create_report",
+  "description": "Create a report",
+  "inputSchema": {
+    "type": "object",
+    "additionalProperties": false,
+    "properties": {
+      "format": {"type": "string", "enum": ["csv", "json"], "default": "csv"},
+      "limit": {"type": "integer", "minimum": 1, "maximum": 1000},
+      "columns": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+      "filter": {
+        "type": "object",
+        "description": "Row filter",
+        "properties": {
+          "column": {"type": "string"},
+          "values": {"type": ["string", "number"]}
+        },
+        "required": ["column"]
+      },
+      "sorts": {
+        "type": "array",
+        "items": {
+          "type": "object",
+          "properties": {"direction": {"enum": ["asc", "desc"]}}
+        }
+      }
+    },
+    "required": ["format"]
+  }
+}]`
+
+func sampleTools(t *testing.T) []Tool {
+	t.Helper()
+	var raw interface{}
+	if err := json.Unmarshal([]byte(sampleToolJSON), &raw); err != nil {
+		t.Fatal(err)
+	}
+	tools, err := FromAny(raw)
+	if err != nil {
+		t.Fatalf("FromAny() error = %v", err)
+	}
+	if len(tools) != 1 {
+		t.Fatalf("FromAny() returned %d tools, want 1", len(tools))
+	}
+	return tools
+}
+
+func TestSchemaRoundTrip(t *testing.T) {
+	tool := sampleTools(t)[0]
+
+	if tool.InputSchema.Extra["additionalProperties"] != false {
+		t.Errorf("additionalProperties was dropped: %v", tool.InputSchema.Extra)
+	}
+	if !reflect.DeepEqual(tool.InputSchema.Required, []string{"format"}) {
+		t.Errorf("Required = %v", tool.InputSchema.Required)
+	}
+
+	data, err := json.Marshal(tool.InputSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	var tools []map[string]interface{}
+	if err := json.Unmarshal([]byte(sampleToolJSON), &tools); err != nil {
+		t.Fatal(err)
+	}
+	want := tools[0]["inputSchema"].(map[string]interface{})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Schema changed in a round trip:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestSchemaMap_Defaults(t *testing.T) {
+	m := Schema{}.Map()
+	if m["type"] != "object" {
+		t.Errorf("type = %v, want object", m["type"])
+	}
+	if props, ok := m["properties"].(map[string]interface{}); !ok || props == nil {
+		t.Errorf("properties = %#v, want an empty object", m["properties"])
+	}
+	if _, ok := m["required"]; ok {
+		t.Error("An empty required list should be omitted")
+	}
+
+	data, _ := json.Marshal(Schema{}) //nolint:errcheck // cannot fail
+	if string(data) != `{"properties":{},"type":"object"}` {
+		t.Errorf("Marshal(Schema{}) = %s", data)
+	}
+}
+
+func TestAnthropicAndOpenAI(t *testing.T) {
+	tools := sampleTools(t)
+
+	anthropic := Anthropic(tools)
+	if anthropic[0]["name"] != "create_report" {
+		t.Errorf("Unexpected Anthropic tool: %v", anthropic[0])
+	}
+	schema := anthropic[0]["input_schema"].(map[string]interface{})
+	if schema["additionalProperties"] != false {
+		t.Errorf("input_schema lost additionalProperties: %v", schema)
+	}
+	props := schema["properties"].(map[string]interface{})
+	format := props["format"].(map[string]interface{})
+	if !reflect.DeepEqual(format["enum"], []interface{}{"csv", "json"}) {
+		t.Errorf("input_schema lost the enum: %v", format)
+	}
+
+	openai := OpenAI(tools)
+	if openai[0]["type"] != "function" {
+		t.Errorf("Unexpected OpenAI tool: %v", openai[0])
+	}
+	function := openai[0]["function"].(map[string]interface{})
+	params := function["parameters"].(map[string]interface{})
+	sorts := params["properties"].(map[string]interface{})["sorts"].(map[string]interface{})
+	if items, ok := sorts["items"].(map[string]interface{}); !ok || items["type"] != "object" {
+		t.Errorf("parameters lost the array item schema: %v", sorts)
+	}
+}
+
+func TestBedrock(t *testing.T) {
+	bedrock := Bedrock(sampleTools(t))
+	spec := bedrock[0]["toolSpec"].(map[string]interface{})
+	if spec["name"] != "create_report" || spec["description"] != "Create a report" {
+		t.Errorf("Unexpected Bedrock tool: %v", spec)
+	}
+	schema := spec["inputSchema"].(map[string]interface{})["json"].(map[string]interface{})
+	if schema["additionalProperties"] != false {
+		t.Errorf("inputSchema lost additionalProperties: %v", schema)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	out := Describe(sampleTools(t))
+
+	for _, want := range []string{
+		"- create_report: Create a report\n  Parameters:",
+		"    columns (array of string, min items: 1)",
+		"    filter (object): Row filter",
+		"      column (string, required)",
+		"      values (string or number)",
+		"    format (string, required, one of: csv | json, default: csv)",
+		"    limit (integer, minimum: 1, maximum: 1000)",
+		"    sorts (array of object)",
+		"      direction (enum, one of: asc | desc)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q:\n%s", want, out)
+		}
+	}
+
+	// Parameters are listed in name order
+	if strings.Index(out, "columns") > strings.Index(out, "format") {
+		t.Errorf("Parameters are not sorted:\n%s", out)
+	}
+}
+
+func TestDescribe_GoDefinitions(t *testing.T) {
+	// Definitions built in Go use typed slices rather than []interface{}
+	tools := []Tool{{
+		Name:        "apply",
+		Description: "Apply a migration",
+		InputSchema: Schema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"direction": map[string]interface{}{
+					"type":    "string",
+					"enum":    []string{"up", "down"},
+					"default": "up",
+				},
+				"steps": map[string]interface{}{"type": "integer", "default": 1},
+			},
+		},
+	}}
+
+	out := Describe(tools)
+	if !strings.Contains(out, "direction (string, one of: up | down, default: up)") ||
+		!strings.Contains(out, "steps (integer, default: 1)") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+	if Describe(nil) != "" {
+		t.Error("Expected no output without tools")
+	}
+}
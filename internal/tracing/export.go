@@ -0,0 +1,317 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pgedge-postgres-mcp/internal/logging"
+)
+
+const (
+	// DefaultServiceName is the service.name spans are reported with
+	DefaultServiceName = "pgedge-postgres-mcp"
+
+	// tracesPath is the path of the OTLP/HTTP traces endpoint
+	tracesPath = "/v1/traces"
+
+	queueSize     = 2048             // Spans waiting for export; more are dropped
+	batchSize     = 512              // Spans sent in one request
+	flushInterval = 5 * time.Second  // Longest time a span waits for export
+	exportTimeout = 10 * time.Second // Time allowed for one request
+)
+
+// Config configures a Tracer
+type Config struct {
+	// Endpoint is the OTLP/HTTP collector URL, such as
+	// http://localhost:4318; /v1/traces is appended unless it is present
+	Endpoint string
+
+	Headers        map[string]string // Sent with each export, such as an API key
+	ServiceName    string            // Empty = DefaultServiceName
+	ServiceVersion string
+	SampleRatio    float64 // Fraction of new traces recorded (0 = all)
+	OmitStatements bool    // Leave the SQL text out of query spans
+
+	// Client sends the exports (nil = a client with exportTimeout)
+	Client *http.Client
+}
+
+// Tracer records spans and exports them in batches in the background
+type Tracer struct {
+	cfg     Config
+	url     string
+	spans   chan *Span
+	dropped atomic.Int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// ValidateEndpoint checks a collector URL
+func ValidateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("endpoint must be an http or https URL, such as http://localhost:4318")
+	}
+	return nil
+}
+
+// New creates a tracer and starts exporting the spans it records
+func New(cfg Config) *Tracer {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+	if cfg.SampleRatio <= 0 {
+		cfg.SampleRatio = 1
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: exportTimeout}
+	}
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+	if !strings.HasSuffix(endpoint, tracesPath) {
+		endpoint += tracesPath
+	}
+	t := &Tracer{
+		cfg:   cfg,
+		url:   endpoint,
+		spans: make(chan *Span, queueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Shutdown exports the spans waiting for export and stops the tracer.
+// Spans that end afterwards are not exported.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.stop) })
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue queues an ended span for export, dropping it if the queue is full
+func (t *Tracer) enqueue(s *Span) {
+	select {
+	case t.spans <- s:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// run exports the queued spans in batches until the tracer is shut down
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	flush := func() {
+		if dropped := t.dropped.Swap(0); dropped > 0 {
+			logging.Warn("trace_spans_dropped", "spans", dropped, "reason", "export queue full")
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.export(batch); err != nil {
+			logging.Warn("trace_export_failed", "spans", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-t.spans:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case span := <-t.spans:
+					batch = append(batch, span)
+					if len(batch) >= batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends a batch of spans to the collector
+func (t *Tracer) export(batch []*Span) error {
+	body, err := json.Marshal(t.payload(batch))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.cfg.Client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("export request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) //nolint:errcheck // drained so the connection can be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of an export request; see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type (
+	otlpExport struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              Kind           `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"` // int64 is encoded as a string
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// payload encodes a batch of spans for export
+func (t *Tracer) payload(batch []*Span) otlpExport {
+	resource := []Attr{String("service.name", t.cfg.ServiceName)}
+	if t.cfg.ServiceVersion != "" {
+		resource = append(resource, String("service.version", t.cfg.ServiceVersion))
+	}
+
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           s.sc.TraceID.String(),
+			SpanID:            s.sc.SpanID.String(),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        keyValues(s.attrs),
+			Status:            otlpStatus{Code: s.status, Message: s.statusMessage},
+		}
+		s.mu.Unlock()
+		if s.parent != (SpanID{}) {
+			span.ParentSpanID = s.parent.String()
+		}
+		spans = append(spans, span)
+	}
+
+	return otlpExport{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: keyValues(resource)},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: DefaultServiceName, Version: t.cfg.ServiceVersion},
+			Spans: spans,
+		}},
+	}}}
+}
+
+// keyValues encodes attributes
+func keyValues(attrs []Attr) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var value otlpAnyValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case bool:
+			value.BoolValue = &v
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		kvs = append(kvs, otlpKeyValue{Key: attr.Key, Value: value})
+	}
+	return kvs
+}
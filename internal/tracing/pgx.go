@@ -0,0 +1,112 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tracing
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxStatementLength caps the SQL text recorded in a query span
+const maxStatementLength = 2048
+
+// QueryTracer records each query run within a trace as a span. Set it as
+// the Tracer of a pgx connection configuration.
+type QueryTracer struct{}
+
+// querySpanKey is the context key of a running query's span
+type querySpanKey struct{}
+
+// TraceQueryStart starts the span of a query
+func (QueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	// Queries outside a trace, such as metadata refreshes, are not recorded
+	t := Shared()
+	if t == nil {
+		return ctx
+	}
+	if _, ok := SpanContextFromContext(ctx); !ok {
+		return ctx
+	}
+
+	operation := queryOperation(data.SQL)
+	attrs := []Attr{
+		String("db.system", "postgresql"),
+		String("db.operation.name", operation),
+	}
+	if conn != nil {
+		attrs = append(attrs, String("db.namespace", conn.Config().Database))
+	}
+	if !t.cfg.OmitStatements {
+		attrs = append(attrs, String("db.query.text", truncate(data.SQL, maxStatementLength)))
+	}
+	ctx, span := t.start(ctx, "SQL "+operation, KindClient, attrs)
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+// TraceQueryEnd ends the span of a query
+func (QueryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(querySpanKey{}).(*Span)
+	if !ok {
+		return
+	}
+	if data.Err != nil {
+		span.SetError(data.Err.Error())
+	} else {
+		span.SetAttributes(Int64("db.response.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
+
+// queryOperation returns the first keyword of a statement, such as SELECT,
+// skipping leading comments
+func queryOperation(sql string) string {
+	for {
+		sql = strings.TrimLeftFunc(sql, func(r rune) bool { return unicode.IsSpace(r) || r == '(' })
+		switch {
+		case strings.HasPrefix(sql, "--"):
+			_, rest, found := strings.Cut(sql, "\n")
+			if !found {
+				return "QUERY"
+			}
+			sql = rest
+		case strings.HasPrefix(sql, "/*"):
+			_, rest, found := strings.Cut(sql, "*/")
+			if !found {
+				return "QUERY"
+			}
+			sql = rest
+		default:
+			end := strings.IndexFunc(sql, func(r rune) bool { return !unicode.IsLetter(r) })
+			if end < 0 {
+				end = len(sql)
+			}
+			if end == 0 {
+				return "QUERY"
+			}
+			return strings.ToUpper(sql[:end])
+		}
+	}
+}
+
+// truncate shortens text to at most n bytes, on a character boundary
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n] + "..."
+}
@@ -0,0 +1,235 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package tracing records OpenTelemetry spans along the server's request
+// path - MCP requests, tool calls, SQL queries and LLM calls - and exports
+// them to an OTLP/HTTP collector such as Jaeger or Tempo. Start does
+// nothing until a Tracer is set with SetShared.
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the ID in hex, as in traceparent headers
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the ID in hex, as in traceparent headers
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext is what a span passes on to its children, including those in
+// other processes
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool // Whether the trace is recorded
+}
+
+// Kind is the role of a span, as numbered by OTLP
+type Kind int
+
+// Span kinds
+const (
+	KindInternal Kind = 1 // Work within the server, such as a tool call
+	KindServer   Kind = 2 // A request the server handles
+	KindClient   Kind = 3 // A request the server makes, such as a SQL query
+)
+
+// statusError is the OTLP status code of a failed span
+const statusError = 2
+
+// Attr is an attribute of a span
+type Attr struct {
+	Key   string
+	Value interface{} // string, int64, bool or float64
+}
+
+// String returns a string attribute
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int) Attr {
+	return Attr{Key: key, Value: int64(value)}
+}
+
+// Int64 returns an integer attribute
+func Int64(key string, value int64) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Span is a timed operation within a trace. The methods of a nil Span do
+// nothing, so callers need not check whether tracing is enabled.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID // Zero for the root of a trace
+	name   string
+	kind   Kind
+	start  time.Time
+
+	mu            sync.Mutex
+	attrs         []Attr
+	status        int
+	statusMessage string
+	end           time.Time
+}
+
+// Context returns the span's context
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed, with a description of the failure
+func (s *Span) SetError(message string) {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	s.status = statusError
+	s.statusMessage = message
+	s.mu.Unlock()
+}
+
+// End records the end of the span and queues it for export. Only the first
+// call has an effect.
+func (s *Span) End() {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.tracer.enqueue(s)
+}
+
+// spanContextKey is the context key of the current span's context
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a context whose spans are children of sc
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the context of the current span, if any
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// shared is the tracer Start uses, set with SetShared
+var shared atomic.Pointer[Tracer]
+
+// SetShared sets the tracer that Start uses; nil stops tracing
+func SetShared(t *Tracer) {
+	shared.Store(t)
+}
+
+// Shared returns the tracer set with SetShared, or nil
+func Shared() *Tracer {
+	return shared.Load()
+}
+
+// Start starts a span with the shared tracer, as a child of the context's
+// span or as the root of a new trace. The returned context carries the new
+// span. Without a shared tracer it returns ctx and a nil span.
+func Start(ctx context.Context, name string, kind Kind, attrs ...Attr) (context.Context, *Span) {
+	t := Shared()
+	if t == nil {
+		return ctx, nil
+	}
+	return t.start(ctx, name, kind, attrs)
+}
+
+// start starts a span
+func (t *Tracer) start(ctx context.Context, name string, kind Kind, attrs []Attr) (context.Context, *Span) {
+	span := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		span.sc.TraceID = parent.TraceID
+		span.sc.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		span.sc.TraceID = newTraceID()
+		span.sc.Sampled = t.sample(span.sc.TraceID)
+	}
+	span.sc.SpanID = newSpanID()
+	if span.sc.Sampled {
+		span.attrs = attrs
+	}
+	return ContextWithSpanContext(ctx, span.sc), span
+}
+
+// sample decides whether a new trace is recorded. The decision depends
+// only on the trace ID, as with OpenTelemetry's TraceIDRatioBased sampler.
+func (t *Tracer) sample(id TraceID) bool {
+	if t.cfg.SampleRatio >= 1 {
+		return true
+	}
+	bound := uint64(t.cfg.SampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
+
+// newTraceID returns a random, non-zero trace ID
+func newTraceID() TraceID {
+	var id TraceID
+	for id == (TraceID{}) {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+// newSpanID returns a random, non-zero span ID
+func newSpanID() SpanID {
+	var id SpanID
+	for id == (SpanID{}) {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
@@ -0,0 +1,245 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// collector is a test OTLP/HTTP collector
+type collector struct {
+	mu      sync.Mutex
+	exports []otlpExport
+	headers []http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != tracesPath {
+		http.NotFound(w, r)
+		return
+	}
+	var export otlpExport
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.exports = append(c.exports, export)
+	c.headers = append(c.headers, r.Header.Clone())
+	c.mu.Unlock()
+}
+
+// spans returns the exported spans
+func (c *collector) spans() []otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var spans []otlpSpan
+	for _, export := range c.exports {
+		for _, rs := range export.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+// startTestTracer sets a shared tracer that exports to a test collector
+func startTestTracer(t *testing.T, cfg Config) (*Tracer, *collector) {
+	t.Helper()
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	cfg.Endpoint = srv.URL
+	tracer := New(cfg)
+	SetShared(tracer)
+	t.Cleanup(func() {
+		SetShared(nil)
+		tracer.Shutdown(context.Background()) //nolint:errcheck // already shut down by most tests
+	})
+	return tracer, c
+}
+
+// attr returns the string form of a span attribute
+func attr(span otlpSpan, key string) string {
+	for _, kv := range span.Attributes {
+		if kv.Key != key {
+			continue
+		}
+		switch {
+		case kv.Value.StringValue != nil:
+			return *kv.Value.StringValue
+		case kv.Value.IntValue != nil:
+			return *kv.Value.IntValue
+		}
+	}
+	return ""
+}
+
+func TestStartWithoutTracer(t *testing.T) {
+	SetShared(nil)
+	ctx := context.Background()
+	got, span := Start(ctx, "request", KindServer)
+	if span != nil || got != ctx {
+		t.Fatal("Start should do nothing without a tracer")
+	}
+	// The methods of a nil span do nothing
+	span.SetAttributes(String("a", "b"))
+	span.SetError("failed")
+	span.End()
+}
+
+func TestSpansExported(t *testing.T) {
+	tracer, c := startTestTracer(t, Config{
+		Headers:        map[string]string{"X-Api-Key": "secret"},
+		ServiceVersion: "1.2.3",
+	})
+
+	// Queries outside a trace are not recorded
+	var queries QueryTracer
+	if ctx := queries.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"}); ctx.Value(querySpanKey{}) != nil {
+		t.Error("a query outside a trace should not start one")
+	}
+
+	ctx, root := Start(context.Background(), "mcp tools/call", KindServer, String("rpc.method", "tools/call"))
+	ctx = queries.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT pg_sleep(60)"})
+	queries.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("canceling statement due to statement timeout")})
+	_, child := Start(ctx, "SQL SELECT", KindClient)
+	child.End()
+	child.End() // Only the first End counts
+	root.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	spans := c.spans()
+	if len(spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(spans))
+	}
+	sql, request := spans[0], spans[2]
+	if request.Name != "mcp tools/call" || request.Kind != KindServer || request.ParentSpanID != "" ||
+		attr(request, "rpc.method") != "tools/call" {
+		t.Errorf("unexpected request span: %+v", request)
+	}
+	if sql.TraceID != request.TraceID || sql.ParentSpanID != request.SpanID {
+		t.Errorf("SQL span is not a child of the request span: %+v", sql)
+	}
+	if sql.Name != "SQL SELECT" || sql.Status.Code != statusError || !strings.Contains(sql.Status.Message, "statement timeout") ||
+		attr(sql, "db.query.text") != "SELECT pg_sleep(60)" {
+		t.Errorf("unexpected SQL span: %+v", sql)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if got := c.headers[0].Get("X-Api-Key"); got != "secret" {
+		t.Errorf("X-Api-Key header = %q, want secret", got)
+	}
+	resource := c.exports[0].ResourceSpans[0].Resource.Attributes
+	if len(resource) != 2 || *resource[0].Value.StringValue != DefaultServiceName || *resource[1].Value.StringValue != "1.2.3" {
+		t.Errorf("unexpected resource attributes: %+v", resource)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	tracer := &Tracer{cfg: Config{SampleRatio: 0.5}}
+	low, high := TraceID{}, TraceID{}
+	high[8] = 0xff
+	if !tracer.sample(low) || tracer.sample(high) {
+		t.Error("traces should be sampled by the low half of their ID")
+	}
+
+	// Spans of an unsampled trace are not exported, but pass the trace on
+	tracer, c := startTestTracer(t, Config{SampleRatio: 1e-12})
+	ctx, root := Start(context.Background(), "request", KindServer)
+	for root.Context().Sampled {
+		ctx, root = Start(context.Background(), "request", KindServer)
+	}
+	_, child := Start(ctx, "SQL SELECT", KindClient)
+	if child.Context().TraceID != root.Context().TraceID || child.Context().Sampled {
+		t.Errorf("child of an unsampled span: %+v", child)
+	}
+	child.End()
+	root.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if spans := c.spans(); len(spans) != 0 {
+		t.Errorf("exported %d spans of an unsampled trace", len(spans))
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := parseTraceparent(header)
+	if !ok || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Fatalf("parseTraceparent(%q) = %+v, %v", header, sc, ok)
+	}
+	if !strings.HasPrefix(header, "00-"+sc.TraceID.String()+"-"+sc.SpanID.String()) {
+		t.Errorf("IDs do not match the header: %+v", sc)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, ok := parseTraceparent(invalid); ok {
+			t.Errorf("parseTraceparent(%q) should fail", invalid)
+		}
+	}
+	// Later versions may add fields
+	if _, ok := parseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); !ok {
+		t.Error("parseTraceparent should accept later versions")
+	}
+
+	// An incoming request's trace is continued
+	startTestTracer(t, Config{})
+	h := http.Header{}
+	h.Set(TraceparentHeader, header)
+	_, span := Start(Extract(context.Background(), h), "request", KindServer)
+	if span.Context().TraceID != sc.TraceID || span.parent != sc.SpanID {
+		t.Errorf("span does not continue the incoming trace: %+v", span.Context())
+	}
+}
+
+func TestQueryOperation(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":          "SELECT",
+		"  select * from t": "SELECT",
+		"-- report\nWITH x AS (SELECT 1) TABLE x": "WITH",
+		"/* app */ (SELECT 1) UNION (SELECT 2)":   "SELECT",
+		"EXPLAIN (FORMAT JSON) SELECT 1":          "EXPLAIN",
+		"-- only a comment":                       "QUERY",
+		"":                                        "QUERY",
+	}
+	for sql, want := range tests {
+		if got := queryOperation(sql); got != want {
+			t.Errorf("queryOperation(%q) = %q, want %q", sql, got, want)
+		}
+	}
+
+	if got := truncate("héllo", 2); got != "h..." {
+		t.Errorf("truncate = %q, want %q", got, "h...")
+	}
+}
@@ -0,0 +1,339 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+// Package upload stores files uploaded through the HTTP API, for tools and
+// knowledgebase ingestion to reference by handle
+package upload
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"pgedge-postgres-mcp/internal/config"
+)
+
+// Path is the HTTP path files are uploaded to
+const Path = "/api/upload"
+
+// maxFiles is the number of files accepted in one request
+const maxFiles = 10
+
+var (
+	// ErrTooLarge is returned when a file exceeds the size limit
+	ErrTooLarge = errors.New("file exceeds the size limit")
+
+	// ErrTypeNotAllowed is returned for files whose extension is not allowed
+	ErrTypeNotAllowed = errors.New("file type is not allowed")
+
+	// ErrContentMismatch is returned when a file's content does not match
+	// its extension, such as a binary file named .csv
+	ErrContentMismatch = errors.New("file content does not match its type")
+
+	// ErrNotFound is returned for unknown or expired handles
+	ErrNotFound = errors.New("upload not found")
+)
+
+// contentPrefixes are the detected content types expected for known
+// extensions; files of other allowed types are accepted as they are
+var contentPrefixes = map[string]string{
+	"csv":   "text/",
+	"tsv":   "text/",
+	"json":  "text/",
+	"jsonl": "text/",
+	"txt":   "text/",
+	"md":    "text/",
+	"html":  "text/",
+	"htm":   "text/",
+	"rst":   "text/",
+	"sql":   "text/",
+	"xml":   "text/",
+	"pdf":   "application/pdf",
+}
+
+// Store manages the uploads directory
+type Store struct {
+	Dir          string
+	MaxBytes     int64         // Size limit for each file (0 = unlimited)
+	AllowedTypes []string      // File extensions accepted, without the dot
+	Retention    time.Duration // Age after which files are deleted (0 = keep)
+}
+
+// NewStoreFromConfig returns the store in the uploads directory under the
+// configured data directory
+func NewStoreFromConfig(cfg *config.Config) *Store {
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		execPath, err := os.Executable()
+		if err != nil {
+			execPath = "."
+		}
+		dataDir = config.GetDefaultDataDir(execPath)
+	}
+	return &Store{
+		Dir:          filepath.Join(dataDir, "uploads"),
+		MaxBytes:     int64(cfg.Uploads.MaxSizeMB) << 20,
+		AllowedTypes: cfg.Uploads.AllowedTypes,
+		Retention:    time.Duration(cfg.Uploads.RetentionHours) * time.Hour,
+	}
+}
+
+// Upload describes a stored file
+type Upload struct {
+	Handle      string `json:"handle"` // Name of the stored file, used to reference it
+	Name        string `json:"name"`   // Name of the file as uploaded
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// unsafeNameChars are replaced in file name prefixes
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// handlePattern matches the handles of files created by the store
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+-[0-9]{8}-[0-9]{6}-[0-9a-f]{32}\.[A-Za-z0-9]+$`)
+
+// fileType returns the lower case extension of name, without the dot, if
+// it is allowed
+func (s *Store) fileType(name string) (string, bool) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if ext == "" {
+		return "", false
+	}
+	for _, allowed := range s.AllowedTypes {
+		if strings.EqualFold(allowed, ext) {
+			return ext, true
+		}
+	}
+	return ext, false
+}
+
+// Save stores the contents of r under a new handle, deleting expired
+// uploads first. name is the file name given by the client; its extension
+// must be allowed, and a timestamp and a random suffix make the handle
+// unique and hard to guess.
+func (s *Store) Save(name string, r io.Reader, now time.Time) (Upload, error) {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	ext, ok := s.fileType(name)
+	if !ok {
+		return Upload{}, fmt.Errorf("%w: %q", ErrTypeNotAllowed, name)
+	}
+
+	// Check the content against the extension before writing anything
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return Upload{}, fmt.Errorf("failed to read upload: %w", err)
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if prefix, known := contentPrefixes[ext]; known && !strings.HasPrefix(contentType, prefix) {
+		return Upload{}, fmt.Errorf("%w: %q looks like %s", ErrContentMismatch, name, contentType)
+	}
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return Upload{}, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	s.Cleanup(now)
+
+	prefix := strings.Trim(unsafeNameChars.ReplaceAllString(strings.TrimSuffix(name, filepath.Ext(name)), "_"), "_-")
+	if len(prefix) > 50 {
+		prefix = prefix[:50]
+	}
+	if prefix == "" {
+		prefix = "upload"
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return Upload{}, fmt.Errorf("failed to generate upload handle: %w", err)
+	}
+	handle := fmt.Sprintf("%s-%s-%s.%s", prefix, now.UTC().Format("20060102-150405"), hex.EncodeToString(random), ext)
+	path := filepath.Join(s.Dir, handle)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return Upload{}, fmt.Errorf("failed to create upload file: %w", err)
+	}
+
+	src := io.MultiReader(bytes.NewReader(head), r)
+	if s.MaxBytes > 0 {
+		// Read one byte more than the limit to detect larger files
+		src = io.LimitReader(src, s.MaxBytes+1)
+	}
+	size, err := io.Copy(f, src)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && s.MaxBytes > 0 && size > s.MaxBytes {
+		err = fmt.Errorf("%w: %q is larger than %d bytes", ErrTooLarge, name, s.MaxBytes)
+	}
+	if err != nil {
+		_ = os.Remove(path) //nolint:errcheck // best effort cleanup
+		if errors.Is(err, ErrTooLarge) {
+			return Upload{}, err
+		}
+		return Upload{}, fmt.Errorf("failed to write upload: %w", err)
+	}
+
+	return Upload{Handle: handle, Name: name, Size: size, ContentType: contentType}, nil
+}
+
+// Open returns the path of the file with a handle returned by Save, for
+// tools that read uploads
+func (s *Store) Open(handle string) (string, error) {
+	if !handlePattern.MatchString(handle) {
+		return "", ErrNotFound
+	}
+	path := filepath.Join(s.Dir, handle)
+	info, err := os.Stat(path)
+	if err != nil || (s.Retention > 0 && time.Since(info.ModTime()) > s.Retention) {
+		return "", ErrNotFound
+	}
+	return path, nil
+}
+
+// Remove deletes an upload
+func (s *Store) Remove(handle string) {
+	if handlePattern.MatchString(handle) {
+		_ = os.Remove(filepath.Join(s.Dir, handle)) //nolint:errcheck // best effort cleanup
+	}
+}
+
+// Cleanup deletes uploads older than the retention period and returns the
+// number deleted
+func (s *Store) Cleanup(now time.Time) int {
+	if s.Retention <= 0 {
+		return 0
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return 0
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		if !handlePattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) <= s.Retention {
+			continue
+		}
+		if os.Remove(filepath.Join(s.Dir, entry.Name())) == nil {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// uploadResponse is the response of POST /api/upload
+type uploadResponse struct {
+	Files []Upload `json:"files,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// MaxRequestBytes returns the size limit of an upload request: room for
+// the largest files plus the multipart framing (0 = unlimited)
+func (s *Store) MaxRequestBytes() int64 {
+	if s.MaxBytes <= 0 {
+		return 0
+	}
+	return maxFiles*s.MaxBytes + 1<<20
+}
+
+// HandleUpload serves POST /api/upload: each file part of a
+// multipart/form-data body is stored, and the handles are returned. If any
+// file is rejected, none are kept.
+func (s *Store) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if limit := s.MaxRequestBytes(); limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, uploadResponse{Error: "Expected a multipart/form-data body"})
+		return
+	}
+
+	var files []Upload
+	fail := func(status int, message string) {
+		for _, f := range files {
+			s.Remove(f.Handle)
+		}
+		writeResponse(w, status, uploadResponse{Error: message})
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				fail(http.StatusRequestEntityTooLarge, "Request body is too large")
+			} else {
+				fail(http.StatusBadRequest, "Invalid multipart body")
+			}
+			return
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		if len(files) == maxFiles {
+			fail(http.StatusBadRequest, fmt.Sprintf("At most %d files can be uploaded at once", maxFiles))
+			return
+		}
+
+		upload, err := s.Save(part.FileName(), part, time.Now())
+		_ = part.Close() //nolint:errcheck // the rest of the part is discarded
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			switch {
+			case errors.Is(err, ErrTooLarge), errors.As(err, &maxErr):
+				fail(http.StatusRequestEntityTooLarge, err.Error())
+			case errors.Is(err, ErrTypeNotAllowed), errors.Is(err, ErrContentMismatch):
+				fail(http.StatusUnsupportedMediaType, err.Error())
+			default:
+				fail(http.StatusInternalServerError, "Failed to store the upload")
+			}
+			return
+		}
+		files = append(files, upload)
+	}
+
+	if len(files) == 0 {
+		writeResponse(w, http.StatusBadRequest, uploadResponse{Error: "No files in the request"})
+		return
+	}
+	writeResponse(w, http.StatusCreated, uploadResponse{Files: files})
+}
+
+// writeResponse writes a JSON response with a status
+func writeResponse(w http.ResponseWriter, status int, response uploadResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	//nolint:errcheck // Error would only occur if connection is closed
+	json.NewEncoder(w).Encode(response)
+}
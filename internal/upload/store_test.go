@@ -0,0 +1,236 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package upload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return &Store{
+		Dir:          filepath.Join(t.TempDir(), "uploads"),
+		MaxBytes:     1024,
+		AllowedTypes: []string{"csv", "pdf", "parquet"},
+	}
+}
+
+func TestStoreSave(t *testing.T) {
+	store := newTestStore(t)
+
+	upload, err := store.Save("../Orders 2025.CSV", strings.NewReader("id,total\n1,9.99\n"), time.Now())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if !strings.HasPrefix(upload.Handle, "Orders_2025-") || !strings.HasSuffix(upload.Handle, ".csv") || !handlePattern.MatchString(upload.Handle) {
+		t.Errorf("Unexpected handle %q", upload.Handle)
+	}
+	if upload.Name != "Orders 2025.CSV" || upload.Size != 16 || !strings.HasPrefix(upload.ContentType, "text/plain") {
+		t.Errorf("Unexpected upload %+v", upload)
+	}
+
+	path, err := store.Open(upload.Handle)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if filepath.Dir(path) != store.Dir {
+		t.Errorf("Upload written outside the store: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "id,total\n1,9.99\n" {
+		t.Errorf("Unexpected contents %q, %v", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Upload mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestStoreSaveRejected(t *testing.T) {
+	store := newTestStore(t)
+
+	tests := []struct {
+		name    string
+		content string
+		want    error
+	}{
+		{"script.sh", "#!/bin/sh\n", ErrTypeNotAllowed},
+		{"noextension", "data", ErrTypeNotAllowed},
+		{"data.csv", "\x00\x01\x02\x03binary", ErrContentMismatch},
+		{"report.pdf", "not a pdf", ErrContentMismatch},
+		{"large.csv", strings.Repeat("x", 1025), ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.Save(tt.name, strings.NewReader(tt.content), time.Now()); !errors.Is(err, tt.want) {
+				t.Errorf("Save() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Rejected files are not left behind
+	entries, _ := os.ReadDir(store.Dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected no stored files, found %d", len(entries))
+	}
+
+	// Types without a known content type are accepted as they are
+	if _, err := store.Save("data.parquet", strings.NewReader("PAR1\x00\x01"), time.Now()); err != nil {
+		t.Errorf("Save() error = %v", err)
+	}
+}
+
+func TestStoreOpen(t *testing.T) {
+	store := newTestStore(t)
+	store.Retention = time.Hour
+
+	for _, handle := range []string{"", "../secret", "orders.csv", "x-20250101-120000-" + strings.Repeat("0", 32) + ".csv"} {
+		if _, err := store.Open(handle); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q) error = %v, want ErrNotFound", handle, err)
+		}
+	}
+
+	// Expired uploads are not found, and are deleted by the next upload
+	old, err := store.Save("old.csv", strings.NewReader("a\n"), time.Now())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	oldPath := filepath.Join(store.Dir, old.Handle)
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(oldPath, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open(old.Handle); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() of an expired upload error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Save("new.csv", strings.NewReader("b\n"), time.Now()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Error("Expected the expired upload to be deleted")
+	}
+}
+
+// multipartRequest builds an upload request with a file part for each name
+func multipartRequest(t *testing.T, files map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("purpose", "import"); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		part, err := writer.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, Path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestHandleUpload(t *testing.T) {
+	store := newTestStore(t)
+
+	w := httptest.NewRecorder()
+	store.HandleUpload(w, multipartRequest(t, map[string]string{"a.csv": "id\n1\n", "b.csv": "id\n2\n"}))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Status = %d, want 201: %s", w.Code, w.Body.String())
+	}
+	var response uploadResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Files) != 2 {
+		t.Fatalf("Expected 2 files, got %+v", response)
+	}
+	for _, f := range response.Files {
+		if _, err := store.Open(f.Handle); err != nil {
+			t.Errorf("Open(%q) error = %v", f.Handle, err)
+		}
+	}
+}
+
+func TestHandleUploadRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    func(t *testing.T) *http.Request
+		status int
+	}{
+		{
+			name:   "wrong method",
+			req:    func(t *testing.T) *http.Request { return httptest.NewRequest(http.MethodGet, Path, nil) },
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name: "not multipart",
+			req: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, Path, strings.NewReader("{}"))
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "no files",
+			req:    func(t *testing.T) *http.Request { return multipartRequest(t, nil) },
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "type not allowed",
+			req: func(t *testing.T) *http.Request {
+				return multipartRequest(t, map[string]string{"ok.csv": "id\n", "run.exe": "MZ"})
+			},
+			status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "too large",
+			req: func(t *testing.T) *http.Request {
+				return multipartRequest(t, map[string]string{"big.csv": strings.Repeat("x", 2048)})
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			w := httptest.NewRecorder()
+			store.HandleUpload(w, tt.req(t))
+			if w.Code != tt.status {
+				t.Errorf("Status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+
+			// A rejected request keeps none of its files
+			entries, _ := os.ReadDir(store.Dir)
+			if len(entries) != 0 {
+				t.Errorf("Expected no stored files, found %d", len(entries))
+			}
+		})
+	}
+}
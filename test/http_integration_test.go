@@ -45,7 +45,19 @@ type HTTPMCPServer struct {
 }
 
 // StartHTTPMCPServer starts the MCP server in HTTP mode for testing
+// Authentication is disabled
 func StartHTTPMCPServer(t *testing.T, connString, apiKey, addr string, useTLS bool) (*HTTPMCPServer, error) {
+	return startHTTPMCPServer(t, connString, apiKey, addr, useTLS, []string{"-no-auth"})
+}
+
+// StartAuthenticatedHTTPMCPServer starts the MCP server in HTTP mode with
+// API token authentication, using the given configuration and token files
+func StartAuthenticatedHTTPMCPServer(t *testing.T, connString, apiKey, addr, configFile, tokenFile string) (*HTTPMCPServer, error) {
+	return startHTTPMCPServer(t, connString, apiKey, addr, false, []string{"-config", configFile, "-token-file", tokenFile})
+}
+
+// startHTTPMCPServer starts the MCP server in HTTP mode with extra arguments
+func startHTTPMCPServer(t *testing.T, connString, apiKey, addr string, useTLS bool, extraArgs []string) (*HTTPMCPServer, error) {
 	// Find the binary
 	binaryPath := filepath.Join("..", "bin", "pgedge-postgres-mcp")
 
@@ -65,8 +77,7 @@ func StartHTTPMCPServer(t *testing.T, connString, apiKey, addr string, useTLS bo
 	}
 
 	// Build command line arguments
-	// Disable authentication for testing
-	args := []string{"-http", "-addr", addr, "-no-auth"}
+	args := append([]string{"-http", "-addr", addr}, extraArgs...)
 
 	// Generate self-signed certificates for HTTPS testing
 	if useTLS {
@@ -189,6 +200,12 @@ func (s *HTTPMCPServer) getHTTPClient() *http.Client {
 
 // SendHTTPRequest sends a JSON-RPC request via HTTP POST
 func (s *HTTPMCPServer) SendHTTPRequest(method string, params interface{}) (*MCPResponse, error) {
+	return s.SendAuthenticatedHTTPRequest("", method, params)
+}
+
+// SendAuthenticatedHTTPRequest sends a JSON-RPC request via HTTP POST with
+// an API token; no Authorization header is sent if the token is empty
+func (s *HTTPMCPServer) SendAuthenticatedHTTPRequest(token, method string, params interface{}) (*MCPResponse, error) {
 	req := MCPRequest{
 		JSONRPC: "2.0",
 		ID:      1,
@@ -203,8 +220,17 @@ func (s *HTTPMCPServer) SendHTTPRequest(method string, params interface{}) (*MCP
 
 	s.t.Logf("[HTTP CLIENT] Sending to %s: %s", s.baseURL+"/mcp/v1", string(reqJSON))
 
+	httpReq, err := http.NewRequest(http.MethodPost, s.baseURL+"/mcp/v1", bytes.NewReader(reqJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
 	client := s.getHTTPClient()
-	resp, err := client.Post(s.baseURL+"/mcp/v1", "application/json", bytes.NewReader(reqJSON))
+	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -492,10 +518,7 @@ func testHTTPListTools(t *testing.T, server *HTTPMCPServer) {
 		t.Fatal("tools array not found in result")
 	}
 
-	// We now have 7 tools (removed connection management tools, added execute_explain and count_rows)
-	if len(tools) != 7 {
-		t.Errorf("Expected exactly 7 tools, got %d", len(tools))
-	}
+	checkDefaultTools(t, tools)
 
 	t.Logf("HTTP ListTools test passed, found %d tools", len(tools))
 }
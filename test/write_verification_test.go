@@ -0,0 +1,279 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge Natural Language Agent
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"pgedge-postgres-mcp/internal/auth"
+)
+
+// writeVerificationTable is created and dropped by TestHTTPWriteVerification
+const writeVerificationTable = "write_verification_test"
+
+// TestHTTPWriteVerification reproduces reports of DDL that succeeds but is
+// not applied. It runs DDL and DML through an authenticated HTTP server,
+// where each token has its own database connection, and checks every
+// reported outcome through a separate direct connection: writes reported as
+// committed must be visible, and rejected or rolled back writes must not be.
+func TestHTTPWriteVerification(t *testing.T) {
+	connString := os.Getenv("TEST_PGEDGE_POSTGRES_CONNECTION_STRING")
+	if connString == "" {
+		t.Skip("TEST_PGEDGE_POSTGRES_CONNECTION_STRING not set")
+	}
+
+	apiKey := os.Getenv("TEST_ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		apiKey = "dummy-key-for-testing"
+	}
+
+	// The direct connection used to verify what the server reports
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.Exec(ctx, "DROP TABLE IF EXISTS "+writeVerificationTable); err != nil {
+		t.Fatalf("Failed to drop test table: %v", err)
+	}
+	defer func() { _, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS "+writeVerificationTable) }()
+
+	// Two tokens, so the calls run on two separate database connections
+	dir := t.TempDir()
+	tokenA, tokenB := newTestToken(t), newTestToken(t)
+	store := &auth.TokenStore{}
+	if err := store.AddToken("session-a", auth.HashToken(tokenA), "write verification A", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddToken("session-b", auth.HashToken(tokenB), "write verification B", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "tokens.yaml")
+	if err := auth.SaveTokenStore(tokenFile, store); err != nil {
+		t.Fatalf("Failed to save token file: %v", err)
+	}
+
+	configFile := filepath.Join(dir, "config.yaml")
+	config := "builtins:\n  tools:\n    execute_script: true\n"
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	server, err := StartAuthenticatedHTTPMCPServer(t, connString, apiKey, "localhost:18082", configFile, tokenFile)
+	if err != nil {
+		t.Fatalf("Failed to start HTTP server: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	waitForDatabase(t, server, tokenA)
+	waitForDatabase(t, server, tokenB)
+
+	t.Run("RequestWithoutTokenRejected", func(t *testing.T) {
+		resp, err := server.SendHTTPRequest("tools/list", nil)
+		if err == nil && resp.Error == nil && len(resp.Result) > 0 {
+			t.Error("Expected a request without a token to be rejected")
+		}
+	})
+
+	t.Run("QueryDatabaseRejectsDDL", func(t *testing.T) {
+		text, isError := callToolWithToken(t, server, tokenA, "query_database", map[string]interface{}{
+			"query": "CREATE TABLE " + writeVerificationTable + " (id integer PRIMARY KEY, note text)",
+			"limit": 0,
+		})
+		if !isError || !strings.Contains(strings.ToLower(text), "read-only") {
+			t.Errorf("Expected a read-only error, got (isError=%v): %s", isError, text)
+		}
+		if tableExists(t, pool) {
+			t.Fatal("query_database created the table despite the read-only transaction")
+		}
+	})
+
+	t.Run("ExecuteScriptCommitsDDLAndDML", func(t *testing.T) {
+		text, isError := callToolWithToken(t, server, tokenA, "execute_script", map[string]interface{}{
+			"script": "CREATE TABLE " + writeVerificationTable + " (id integer PRIMARY KEY, note text);" +
+				"INSERT INTO " + writeVerificationTable + " VALUES (1, 'one'), (2, 'two')",
+		})
+		if isError || !strings.Contains(text, "Transaction committed: 2 of 2 statements applied") {
+			t.Fatalf("Expected the script to be committed, got (isError=%v): %s", isError, text)
+		}
+		if !tableExists(t, pool) {
+			t.Fatal("execute_script reported a commit, but the table does not exist")
+		}
+		if count := countRows(t, pool, ""); count != 2 {
+			t.Fatalf("execute_script reported 2 rows inserted, found %d", count)
+		}
+	})
+
+	t.Run("QueryDatabaseRejectsDML", func(t *testing.T) {
+		for _, query := range []string{
+			"INSERT INTO " + writeVerificationTable + " VALUES (3, 'three')",
+			"UPDATE " + writeVerificationTable + " SET note = 'changed'",
+			"WITH deleted AS (DELETE FROM " + writeVerificationTable + " RETURNING id) SELECT count(*) FROM deleted",
+		} {
+			text, isError := callToolWithToken(t, server, tokenA, "query_database", map[string]interface{}{
+				"query": query,
+				"limit": 0,
+			})
+			if !isError || !strings.Contains(strings.ToLower(text), "read-only") {
+				t.Errorf("Expected a read-only error for %q, got (isError=%v): %s", query, isError, text)
+			}
+		}
+		if count := countRows(t, pool, ""); count != 2 {
+			t.Errorf("Expected 2 rows after rejected writes, found %d", count)
+		}
+		if count := countRows(t, pool, "note = 'changed'"); count != 0 {
+			t.Errorf("A rejected UPDATE changed %d rows", count)
+		}
+	})
+
+	t.Run("ExecuteScriptPartialCommit", func(t *testing.T) {
+		// The duplicate key is rolled back to its savepoint; the rest is committed
+		text, isError := callToolWithToken(t, server, tokenA, "execute_script", map[string]interface{}{
+			"script": "ALTER TABLE " + writeVerificationTable + " ADD COLUMN status text;" +
+				"UPDATE " + writeVerificationTable + " SET status = 'done';" +
+				"INSERT INTO " + writeVerificationTable + " VALUES (1, 'duplicate', 'done')",
+			"continue_on_error": true,
+		})
+		if isError || !strings.Contains(text, "Transaction committed: 2 of 3 statements applied") {
+			t.Fatalf("Expected 2 of 3 statements to be committed, got (isError=%v): %s", isError, text)
+		}
+		if count := countRows(t, pool, "status = 'done'"); count != 2 {
+			t.Errorf("Expected the ALTER and UPDATE to be applied to 2 rows, found %d", count)
+		}
+		if count := countRows(t, pool, "note = 'duplicate'"); count != 0 {
+			t.Errorf("The rolled back INSERT was applied")
+		}
+	})
+
+	t.Run("ExecuteScriptRollback", func(t *testing.T) {
+		text, isError := callToolWithToken(t, server, tokenA, "execute_script", map[string]interface{}{
+			"script": "INSERT INTO " + writeVerificationTable + " VALUES (4, 'four', 'new');" +
+				"INSERT INTO " + writeVerificationTable + " VALUES (1, 'duplicate', 'new')",
+		})
+		if isError || !strings.Contains(text, "Transaction rolled back") {
+			t.Fatalf("Expected the script to be rolled back, got (isError=%v): %s", isError, text)
+		}
+		if count := countRows(t, pool, "id = 4"); count != 0 {
+			t.Error("A statement of a rolled back script was applied")
+		}
+	})
+
+	t.Run("CommittedChangesVisibleToOtherSessions", func(t *testing.T) {
+		for name, token := range map[string]string{"session A": tokenA, "session B": tokenB} {
+			text, isError := callToolWithToken(t, server, token, "query_database", map[string]interface{}{
+				"query": "SELECT count(*) AS done_rows FROM " + writeVerificationTable + " WHERE status = 'done'",
+			})
+			if isError {
+				t.Errorf("%s: query failed: %s", name, text)
+				continue
+			}
+			if !strings.Contains(text, "done_rows\n2") {
+				t.Errorf("%s: expected 2 committed rows, got: %s", name, text)
+			}
+		}
+	})
+}
+
+// newTestToken generates an API token
+func newTestToken(t *testing.T) string {
+	t.Helper()
+	token, err := auth.GenerateToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	return token
+}
+
+// waitForDatabase waits until the token's database connection has loaded
+// its metadata
+func waitForDatabase(t *testing.T, server *HTTPMCPServer, token string) {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		text, isError := callToolWithToken(t, server, token, "query_database", map[string]interface{}{
+			"query": "SELECT 1",
+		})
+		if !isError {
+			return
+		}
+		if !strings.Contains(text, "still initializing") {
+			t.Fatalf("Database connection failed: %s", text)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	t.Fatal("Timeout waiting for the database metadata to load")
+}
+
+// callToolWithToken calls a tool and returns the text of its result and
+// whether it is an error
+func callToolWithToken(t *testing.T, server *HTTPMCPServer, token, name string, args map[string]interface{}) (string, bool) {
+	t.Helper()
+	resp, err := server.SendAuthenticatedHTTPRequest(token, "tools/call", map[string]interface{}{
+		"name":      name,
+		"arguments": args,
+	})
+	if err != nil {
+		t.Fatalf("Failed to call %s: %v", name, err)
+	}
+	if resp.Error != nil {
+		return resp.Error.Message, true
+	}
+
+	var result struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("Failed to unmarshal %s result: %v", name, err)
+	}
+	var texts []string
+	for _, item := range result.Content {
+		texts = append(texts, item.Text)
+	}
+	return strings.Join(texts, "\n"), result.IsError
+}
+
+// tableExists checks for the test table through the direct connection
+func tableExists(t *testing.T, pool *pgxpool.Pool) bool {
+	t.Helper()
+	var exists bool
+	if err := pool.QueryRow(context.Background(),
+		"SELECT to_regclass($1) IS NOT NULL", writeVerificationTable).Scan(&exists); err != nil {
+		t.Fatalf("Failed to check for the table: %v", err)
+	}
+	return exists
+}
+
+// countRows counts the test table's rows matching a condition through the
+// direct connection
+func countRows(t *testing.T, pool *pgxpool.Pool, where string) int {
+	t.Helper()
+	query := "SELECT count(*) FROM " + writeVerificationTable
+	if where != "" {
+		query += " WHERE " + where
+	}
+	var count int
+	if err := pool.QueryRow(context.Background(), query).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	return count
+}
@@ -20,6 +20,7 @@ import MainContent from './components/MainContent';
 import ConversationPanel from './components/ConversationPanel';
 import Login from './components/Login';
 import { createPgedgeTheme, loginTheme } from './theme/pgedgeTheme';
+import { pushPreference, PREFERENCES_SYNCED_EVENT } from './lib/preferences-api';
 
 const AppContent = () => {
   const [mode, setMode] = useState(() => {
@@ -37,11 +38,20 @@ const AppContent = () => {
     localStorage.setItem('theme-mode', mode);
   }, [mode]);
 
+  // Apply the theme saved from another session when preferences are synced
+  useEffect(() => {
+    const reload = () => setMode(localStorage.getItem('theme-mode') || 'light');
+    window.addEventListener(PREFERENCES_SYNCED_EVENT, reload);
+    return () => window.removeEventListener(PREFERENCES_SYNCED_EVENT, reload);
+  }, []);
+
   // Create theme using pgEdge theme configuration
   const theme = useMemo(() => createPgedgeTheme(mode), [mode]);
 
   const toggleTheme = () => {
-    setMode((prevMode) => (prevMode === 'light' ? 'dark' : 'light'));
+    const nextMode = mode === 'light' ? 'dark' : 'light';
+    setMode(nextMode);
+    pushPreference('theme-mode', nextMode);
   };
 
   const handleConversationsClick = useCallback(() => {
@@ -17,6 +17,7 @@ import { useLocalStorageBoolean } from '../hooks/useLocalStorage';
 import { useQueryHistory } from '../hooks/useQueryHistory';
 import { useMCPClient } from '../hooks/useMCPClient';
 import { useLLMProviders } from '../hooks/useLLMProviders';
+import { readChatStream, LLMStreamError } from '../lib/llm-stream';
 import MessageList from './MessageList';
 import MessageInput from './MessageInput';
 import ProviderSelector from './ProviderSelector';
@@ -586,6 +587,20 @@ const ChatInterface = ({ conversations }) => {
         const abortController = new AbortController();
         abortControllerRef.current = abortController;
 
+        // Show streamed text in the thinking message
+        const updateThinkingContent = (content) => {
+            setMessages(prev => {
+                const newMessages = [...prev];
+                if (newMessages.length > 0 && newMessages[newMessages.length - 1].isThinking) {
+                    newMessages[newMessages.length - 1] = {
+                        ...newMessages[newMessages.length - 1],
+                        content,
+                    };
+                }
+                return newMessages;
+            });
+        };
+
         try {
             // Build conversation history
             const conversationMessages = [];
@@ -662,6 +677,7 @@ const ChatInterface = ({ conversations }) => {
                         provider: llmProviders.selectedProvider,
                         model: llmProviders.selectedModel,
                         debug: true,
+                        stream: true,
                     }),
                 });
 
@@ -689,11 +705,36 @@ const ChatInterface = ({ conversations }) => {
                     return;
                 }
 
-                if (!llmResponse.ok) {
-                    const errorText = await llmResponse.text();
+                // Render the response as it is generated; errors from the LLM
+                // arrive in the stream after the response has started
+                let llmData = null;
+                let errorText = null;
+                let errorStatus = llmResponse.status;
+                if (llmResponse.ok) {
+                    let streamedText = '';
+                    try {
+                        llmData = await readChatStream(llmResponse, (text) => {
+                            streamedText += text;
+                            updateThinkingContent(streamedText);
+                        });
+                    } catch (error) {
+                        if (!(error instanceof LLMStreamError)) {
+                            throw error;
+                        }
+                        errorText = error.message;
+                        errorStatus = 500;
+                    }
+                    // Text written before tool calls is not part of the final answer
+                    if (streamedText && llmData?.stop_reason !== 'end_turn') {
+                        updateThinkingContent('');
+                    }
+                } else {
+                    errorText = await llmResponse.text();
+                }
 
+                if (errorText !== null) {
                     // Check for rate limit error
-                    if (isRateLimitError(llmResponse.status, errorText)) {
+                    if (isRateLimitError(errorStatus, errorText)) {
                         rateLimitRetryCount++;
                         const rateLimitDetails = parseRateLimitError(errorText);
                         const estimatedTokens = estimateTotalTokens(compactedMessages);
@@ -748,11 +789,9 @@ const ChatInterface = ({ conversations }) => {
                         }
                     }
 
-                    throw new Error(`LLM request failed: ${llmResponse.status} ${errorText}`);
+                    throw new Error(`LLM request failed: ${errorStatus} ${errorText}`);
                 }
 
-                const llmData = await llmResponse.json();
-
                 // Track token usage for rate limit awareness
                 console.log('[Token Debug] llmData.token_usage:', llmData.token_usage);
                 console.log('[Token Debug] llmData.usage:', llmData.usage);
@@ -796,8 +835,9 @@ const ChatInterface = ({ conversations }) => {
                             role: 'assistant',
                             content: finalContent,
                             timestamp: new Date().toISOString(),
-                            provider: llmProviders.selectedProvider,
-                            model: llmProviders.selectedModel,
+                            // The proxy may have failed over to another provider
+                            provider: llmData.provider || llmProviders.selectedProvider,
+                            model: llmData.model || llmProviders.selectedModel,
                             activity: activity,
                             tokenUsage: llmData.token_usage,
                         }];
@@ -1232,8 +1272,9 @@ const ChatInterface = ({ conversations }) => {
                             role: 'assistant',
                             content: finalContent,
                             timestamp: new Date().toISOString(),
-                            provider: llmProviders.selectedProvider,
-                            model: llmProviders.selectedModel,
+                            // The proxy may have failed over to another provider
+                            provider: llmData.provider || llmProviders.selectedProvider,
+                            model: llmData.model || llmProviders.selectedModel,
                             activity: activity,
                             tokenUsage: llmData.token_usage,
                         }];
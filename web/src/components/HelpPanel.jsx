@@ -200,7 +200,7 @@ const HelpPanel = ({ open, onClose, serverInfo }) => {
                     <ListItem>
                         <ListItemText
                             primary="Preferences Saved"
-                            secondary="Your theme, provider, model, and toggle settings are automatically saved and restored on your next visit."
+                            secondary="Your theme, provider, model, database, and toggle settings are automatically saved and restored on your next visit. Unless Sync With CLI is turned off in Preferences, they are also saved to your account and shared with the CLI."
                         />
                     </ListItem>
                 </List>
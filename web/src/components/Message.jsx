@@ -257,6 +257,9 @@ const Message = React.memo(({ message, showActivity, renderMarkdown, debug }) =>
                             {message.tokenUsage.provider === 'openai' && (
                                 <div>🔢 Tokens: Prompt {message.tokenUsage.prompt_tokens}, Completion {message.tokenUsage.completion_tokens}, Total {message.tokenUsage.total_tokens}</div>
                             )}
+                            {message.tokenUsage.provider === 'bedrock' && (
+                                <div>🔢 Tokens: Input {message.tokenUsage.prompt_tokens}, Output {message.tokenUsage.completion_tokens}, Total {message.tokenUsage.total_tokens}</div>
+                            )}
                             {message.tokenUsage.provider === 'ollama' && (
                                 <div>ℹ️ Ollama does not provide token counts</div>
                             )}
@@ -290,7 +293,7 @@ const Message = React.memo(({ message, showActivity, renderMarkdown, debug }) =>
                         >
                             {message.content}
                         </Typography>
-                    ) : message.isThinking ? (
+                    ) : message.isThinking && !message.content ? (
                         <ThinkingIndicator isThinking={true} />
                     ) : renderMarkdown ? (
                         <ReactMarkdown
@@ -10,7 +10,7 @@
  *-------------------------------------------------------------------------
  */
 
-import React from 'react';
+import React, { useState } from 'react';
 import PropTypes from 'prop-types';
 import {
     Popover,
@@ -22,6 +22,7 @@ import {
     useTheme,
     alpha,
 } from '@mui/material';
+import { isSyncEnabled, setSyncEnabled } from '../lib/preferences-api';
 
 const PreferencesPopover = React.memo(({
     anchorEl,
@@ -36,6 +37,12 @@ const PreferencesPopover = React.memo(({
 }) => {
     const theme = useTheme();
     const isDark = theme.palette.mode === 'dark';
+    const [syncEnabled, setSyncEnabledState] = useState(isSyncEnabled);
+
+    const handleSyncChange = (enabled) => {
+        setSyncEnabled(enabled);
+        setSyncEnabledState(enabled);
+    };
 
     const switchStyles = {
         '& .MuiSwitch-switchBase': {
@@ -154,6 +161,26 @@ const PreferencesPopover = React.memo(({
                         }
                         sx={{ mx: 0 }}
                     />
+
+                    <FormControlLabel
+                        control={
+                            <Switch
+                                checked={syncEnabled}
+                                onChange={(e) => handleSyncChange(e.target.checked)}
+                                size="small"
+                                sx={switchStyles}
+                            />
+                        }
+                        label={
+                            <Typography
+                                variant="body2"
+                                sx={{ color: isDark ? '#F1F5F9' : '#374151' }}
+                            >
+                                Sync With CLI
+                            </Typography>
+                        }
+                        sx={{ mx: 0 }}
+                    />
                 </Box>
             </Box>
         </Popover>
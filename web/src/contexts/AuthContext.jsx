@@ -10,6 +10,7 @@
 
 import React, { createContext, useState, useContext, useEffect } from 'react';
 import { MCPClient } from '../lib/mcp-client';
+import { pullPreferences, clearPreferencesSession } from '../lib/preferences-api';
 
 const AuthContext = createContext(null);
 
@@ -53,6 +54,7 @@ export const AuthProvider = ({ children }) => {
       }
 
       const userInfo = await response.json();
+      await pullPreferences(sessionToken);
       setUser({
         authenticated: true,
         username: userInfo.username
@@ -76,6 +78,7 @@ export const AuthProvider = ({ children }) => {
       // Store session token in state and localStorage
       setSessionToken(authResult.sessionToken);
       localStorage.setItem('mcp-session-token', authResult.sessionToken);
+      await pullPreferences(authResult.sessionToken);
 
       // Set user info
       setUser({
@@ -90,6 +93,7 @@ export const AuthProvider = ({ children }) => {
 
   const logout = () => {
     // Clear session token
+    clearPreferencesSession();
     setSessionToken(null);
     localStorage.removeItem('mcp-session-token');
     setUser(null);
@@ -100,6 +104,7 @@ export const AuthProvider = ({ children }) => {
 
   // Force logout without any cleanup (used when session is invalidated)
   const forceLogout = () => {
+    clearPreferencesSession();
     setSessionToken(null);
     localStorage.removeItem('mcp-session-token');
     setUser(null);
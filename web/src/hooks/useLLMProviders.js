@@ -10,6 +10,7 @@
 
 import { useState, useEffect, useRef, useCallback } from 'react';
 import { useLocalStorageString } from './useLocalStorage';
+import { pushPreference } from '../lib/preferences-api';
 
 // Helper functions for per-provider model storage
 const getProviderModelKey = (provider) => `llm-model-${provider}`;
@@ -28,6 +29,7 @@ const setPerProviderModel = (provider, model) => {
     } else {
         localStorage.removeItem(key);
     }
+    pushPreference(key, model);
 };
 
 /**
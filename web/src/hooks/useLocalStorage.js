@@ -9,6 +9,21 @@
  */
 
 import { useState, useEffect } from 'react';
+import { pushPreference, PREFERENCES_SYNCED_EVENT } from '../lib/preferences-api';
+
+/**
+ * Re-read a value when preferences are loaded from the server
+ * @param {Function} read - Reads the value from localStorage
+ * @param {Function} setStoredValue - State setter
+ */
+const useSyncedReload = (read, setStoredValue) => {
+    useEffect(() => {
+        const reload = () => setStoredValue(read());
+        window.addEventListener(PREFERENCES_SYNCED_EVENT, reload);
+        return () => window.removeEventListener(PREFERENCES_SYNCED_EVENT, reload);
+        // eslint-disable-next-line react-hooks/exhaustive-deps
+    }, []);
+};
 
 /**
  * Custom hook for managing localStorage with React state
@@ -19,7 +34,7 @@ import { useState, useEffect } from 'react';
 export const useLocalStorage = (key, initialValue) => {
     // State to store our value
     // Pass initial state function to useState so logic is only executed once
-    const [storedValue, setStoredValue] = useState(() => {
+    const read = () => {
         try {
             // Get from local storage by key
             const item = window.localStorage.getItem(key);
@@ -30,7 +45,9 @@ export const useLocalStorage = (key, initialValue) => {
             console.error(`Error loading ${key} from localStorage:`, error);
             return initialValue;
         }
-    });
+    };
+    const [storedValue, setStoredValue] = useState(read);
+    useSyncedReload(read, setStoredValue);
 
     // Return a wrapped version of useState's setter function that ...
     // ... persists the new value to localStorage.
@@ -42,6 +59,7 @@ export const useLocalStorage = (key, initialValue) => {
             setStoredValue(valueToStore);
             // Save to local storage
             window.localStorage.setItem(key, JSON.stringify(valueToStore));
+            pushPreference(key, valueToStore);
         } catch (error) {
             // A more advanced implementation would handle the error case
             console.error(`Error saving ${key} to localStorage:`, error);
@@ -58,7 +76,7 @@ export const useLocalStorage = (key, initialValue) => {
  * @returns {[string, Function]} - [storedValue, setValue]
  */
 export const useLocalStorageString = (key, initialValue) => {
-    const [storedValue, setStoredValue] = useState(() => {
+    const read = () => {
         try {
             const item = window.localStorage.getItem(key);
             return item !== null ? item : initialValue;
@@ -66,7 +84,9 @@ export const useLocalStorageString = (key, initialValue) => {
             console.error(`Error loading ${key} from localStorage:`, error);
             return initialValue;
         }
-    });
+    };
+    const [storedValue, setStoredValue] = useState(read);
+    useSyncedReload(read, setStoredValue);
 
     const setValue = (value) => {
         try {
@@ -77,6 +97,7 @@ export const useLocalStorageString = (key, initialValue) => {
             } else {
                 window.localStorage.removeItem(key);
             }
+            pushPreference(key, valueToStore);
         } catch (error) {
             console.error(`Error saving ${key} to localStorage:`, error);
         }
@@ -92,7 +113,7 @@ export const useLocalStorageString = (key, initialValue) => {
  * @returns {[boolean, Function]} - [storedValue, setValue]
  */
 export const useLocalStorageBoolean = (key, initialValue) => {
-    const [storedValue, setStoredValue] = useState(() => {
+    const read = () => {
         try {
             const item = window.localStorage.getItem(key);
             return item === null ? initialValue : item === 'true';
@@ -100,13 +121,16 @@ export const useLocalStorageBoolean = (key, initialValue) => {
             console.error(`Error loading ${key} from localStorage:`, error);
             return initialValue;
         }
-    });
+    };
+    const [storedValue, setStoredValue] = useState(read);
+    useSyncedReload(read, setStoredValue);
 
     const setValue = (value) => {
         try {
             const valueToStore = value instanceof Function ? value(storedValue) : value;
             setStoredValue(valueToStore);
             window.localStorage.setItem(key, valueToStore.toString());
+            pushPreference(key, valueToStore);
         } catch (error) {
             console.error(`Error saving ${key} to localStorage:`, error);
         }
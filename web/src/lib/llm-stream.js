@@ -0,0 +1,91 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge MCP Client - LLM Response Streaming
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+/**
+ * Streamed responses from /api/llm/chat are server-sent events: "delta"
+ * events carry text as the LLM generates it, followed by a "done" event
+ * with the complete response or an "error" event. Deltas are provisional;
+ * the "done" event's content replaces them.
+ */
+
+/**
+ * Error reported by the LLM while streaming a response
+ */
+export class LLMStreamError extends Error {
+    constructor(message) {
+        super(message);
+        this.name = 'LLMStreamError';
+    }
+}
+
+/**
+ * Parse one server-sent event block
+ * @param {string} block - Event lines without the blank line that ends them
+ * @returns {{event: string, data: string}|null} - The event, or null for comments
+ */
+const parseEvent = (block) => {
+    let event = 'message';
+    const data = [];
+    for (const line of block.split(/\r?\n/)) {
+        if (line.startsWith('event:')) {
+            event = line.slice(6).trim();
+        } else if (line.startsWith('data:')) {
+            data.push(line.slice(5).replace(/^ /, ''));
+        }
+    }
+    return data.length > 0 ? { event, data: data.join('\n') } : null;
+};
+
+/**
+ * Read a streamed chat response
+ * Aborting the request's signal stops the read and the generation.
+ * @param {Response} response - Fetch response with an event stream body
+ * @param {function(string): void} onText - Called with each piece of text
+ * @returns {Promise<object>} - The complete chat response
+ * @throws {LLMStreamError} - If the LLM fails while generating
+ */
+export const readChatStream = async (response, onText) => {
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+
+    for (;;) {
+        const { value, done } = await reader.read();
+        if (done) {
+            break;
+        }
+        buffer += decoder.decode(value, { stream: true });
+
+        let end;
+        while ((end = buffer.search(/\r?\n\r?\n/)) !== -1) {
+            const block = buffer.slice(0, end);
+            buffer = buffer.slice(end).replace(/^\r?\n\r?\n/, '');
+
+            const parsed = parseEvent(block);
+            if (!parsed) {
+                continue;
+            }
+            const payload = JSON.parse(parsed.data);
+            switch (parsed.event) {
+                case 'delta':
+                    onText(payload.text);
+                    break;
+                case 'done':
+                    return payload;
+                case 'error':
+                    throw new LLMStreamError(payload.error);
+                default:
+                    break;
+            }
+        }
+    }
+
+    throw new LLMStreamError('LLM response stream ended unexpectedly');
+};
@@ -0,0 +1,185 @@
+/*-------------------------------------------------------------------------
+ *
+ * pgEdge MCP Client - Preferences API
+ *
+ * Portions copyright (c) 2025, pgEdge, Inc.
+ * This software is released under The PostgreSQL License
+ *
+ *-------------------------------------------------------------------------
+ */
+
+/**
+ * Preferences are stored in localStorage and shared with the server, so a
+ * provider, model, database or display setting chosen here is also used by
+ * the CLI (and vice versa). Setting the 'preferences-sync' localStorage key
+ * to 'false' keeps this browser's preferences local.
+ */
+
+const BASE_URL = '/api/preferences';
+
+// Window event dispatched after server preferences are written to localStorage
+export const PREFERENCES_SYNCED_EVENT = 'preferences-synced';
+
+const SYNC_DISABLED_KEY = 'preferences-sync';
+const MODEL_KEY_PREFIX = 'llm-model-';
+const PUSH_DELAY_MS = 500;
+
+// localStorage keys of UI settings, by server setting name
+const UI_KEYS = {
+    theme: 'theme-mode',
+    show_activity: 'show-activity',
+    render_markdown: 'render-markdown',
+    debug: 'debug',
+};
+
+let sessionToken = null;
+let pendingPatch = null;
+let pushTimer = null;
+
+/**
+ * Check whether preferences are synced with the server
+ * @returns {boolean}
+ */
+export const isSyncEnabled = () => localStorage.getItem(SYNC_DISABLED_KEY) !== 'false';
+
+/**
+ * Turn syncing with the server on or off for this browser
+ * @param {boolean} enabled
+ */
+export const setSyncEnabled = (enabled) => {
+    if (enabled) {
+        localStorage.removeItem(SYNC_DISABLED_KEY);
+    } else {
+        localStorage.setItem(SYNC_DISABLED_KEY, 'false');
+    }
+};
+
+/**
+ * Make an authenticated request
+ * @param {string} method - HTTP method
+ * @param {object} body - Optional JSON body
+ * @returns {Promise<object>} - The user's preferences
+ */
+const request = async (method, body) => {
+    const response = await fetch(BASE_URL, {
+        method,
+        headers: {
+            'Content-Type': 'application/json',
+            'Authorization': `Bearer ${sessionToken}`,
+        },
+        body: body ? JSON.stringify(body) : undefined,
+    });
+
+    if (!response.ok) {
+        const error = await response.json().catch(() => ({}));
+        throw new Error(error.error || `Failed to sync preferences: ${response.status}`);
+    }
+
+    return response.json();
+};
+
+/**
+ * Write server preferences to localStorage
+ * @param {object} prefs - Preferences returned by the server
+ */
+const applyPreferences = (prefs) => {
+    if (prefs.provider) {
+        localStorage.setItem('llm-provider', prefs.provider);
+    }
+    Object.entries(prefs.provider_models || {}).forEach(([provider, model]) => {
+        if (model) {
+            localStorage.setItem(`${MODEL_KEY_PREFIX}${provider}`, model);
+        }
+    });
+    if (prefs.database) {
+        localStorage.setItem('selected-database', prefs.database);
+    }
+    Object.entries(UI_KEYS).forEach(([name, key]) => {
+        const value = prefs.ui?.[name];
+        if (typeof value === 'boolean' || typeof value === 'string') {
+            localStorage.setItem(key, value.toString());
+        }
+    });
+};
+
+/**
+ * Load the user's preferences from the server into localStorage, and notify
+ * components so they can re-read their settings
+ * @param {string} token - Authentication session token
+ */
+export const pullPreferences = async (token) => {
+    sessionToken = token;
+    if (!token || !isSyncEnabled()) {
+        return;
+    }
+
+    try {
+        applyPreferences(await request('GET'));
+        window.dispatchEvent(new Event(PREFERENCES_SYNCED_EVENT));
+    } catch (error) {
+        console.error('Failed to load preferences:', error);
+    }
+};
+
+/**
+ * Stop syncing when the user logs out
+ */
+export const clearPreferencesSession = () => {
+    sessionToken = null;
+    pendingPatch = null;
+    clearTimeout(pushTimer);
+};
+
+/**
+ * Convert a localStorage change into a partial preferences update
+ * @param {string} key - localStorage key
+ * @param {*} value - New value
+ * @returns {object|null} - Patch, or null if the key is not synced
+ */
+const toPatch = (key, value) => {
+    if (key === 'llm-provider') {
+        return value ? { provider: value } : null;
+    }
+    if (key.startsWith(MODEL_KEY_PREFIX)) {
+        return { provider_models: { [key.slice(MODEL_KEY_PREFIX.length)]: value || null } };
+    }
+    if (key === 'selected-database') {
+        return { database: value || '' };
+    }
+    const name = Object.keys(UI_KEYS).find((n) => UI_KEYS[n] === key);
+    return name ? { ui: { [name]: value } } : null;
+};
+
+/**
+ * Send a changed preference to the server. Changes made in quick succession
+ * are combined into one request.
+ * @param {string} key - localStorage key
+ * @param {*} value - New value
+ */
+export const pushPreference = (key, value) => {
+    if (!sessionToken || !isSyncEnabled()) {
+        return;
+    }
+    const patch = toPatch(key, value);
+    if (!patch) {
+        return;
+    }
+
+    pendingPatch = pendingPatch || {};
+    Object.entries(patch).forEach(([field, fieldValue]) => {
+        if (typeof fieldValue === 'object' && fieldValue !== null) {
+            pendingPatch[field] = { ...pendingPatch[field], ...fieldValue };
+        } else {
+            pendingPatch[field] = fieldValue;
+        }
+    });
+
+    clearTimeout(pushTimer);
+    pushTimer = setTimeout(() => {
+        const body = pendingPatch;
+        pendingPatch = null;
+        request('PATCH', body).catch((error) => {
+            console.error('Failed to save preferences:', error);
+        });
+    }, PUSH_DELAY_MS);
+};